@@ -23,6 +23,7 @@ var (
 	_ DDLNode = &CreateDatabaseStmt{}
 	_ DDLNode = &CreateIndexStmt{}
 	_ DDLNode = &CreateTableStmt{}
+	_ DDLNode = &CreateViewStmt{}
 	_ DDLNode = &DropDatabaseStmt{}
 	_ DDLNode = &DropIndexStmt{}
 	_ DDLNode = &DropTableStmt{}
@@ -271,10 +272,12 @@ func (n *ColumnOption) Accept(v Visitor) (Node, bool) {
 }
 
 // IndexOption is the index options.
-//    KEY_BLOCK_SIZE [=] value
-//  | index_type
-//  | WITH PARSER parser_name
-//  | COMMENT 'string'
+//
+//	  KEY_BLOCK_SIZE [=] value
+//	| index_type
+//	| WITH PARSER parser_name
+//	| COMMENT 'string'
+//
 // See http://dev.mysql.com/doc/refman/5.7/en/create-table.html
 type IndexOption struct {
 	node
@@ -399,6 +402,10 @@ type CreateTableStmt struct {
 	Cols        []*ColumnDef
 	Constraints []*Constraint
 	Options     []*TableOption
+	// ReferTable is the table whose definition is copied by CREATE TABLE ... LIKE.
+	ReferTable *TableName
+	// Select is a SelectStmt or a UnionStmt whose rows are inserted by CREATE TABLE ... SELECT.
+	Select ResultSetNode
 }
 
 // Accept implements Node Accept interface.
@@ -427,6 +434,53 @@ func (n *CreateTableStmt) Accept(v Visitor) (Node, bool) {
 		}
 		n.Constraints[i] = node.(*Constraint)
 	}
+	if n.ReferTable != nil {
+		node, ok = n.ReferTable.Accept(v)
+		if !ok {
+			return n, false
+		}
+		n.ReferTable = node.(*TableName)
+	}
+	if n.Select != nil {
+		node, ok = n.Select.Accept(v)
+		if !ok {
+			return n, false
+		}
+		n.Select = node.(ResultSetNode)
+	}
+	return v.Leave(n)
+}
+
+// CreateViewStmt is a statement to create a view.
+// See https://dev.mysql.com/doc/refman/5.7/en/create-view.html
+type CreateViewStmt struct {
+	ddlNode
+
+	OrReplace bool
+	Security  model.ViewSecurity
+	ViewName  *TableName
+	Cols      []model.CIStr
+	// Select is a SelectStmt or a UnionStmt.
+	Select ResultSetNode
+}
+
+// Accept implements Node Accept interface.
+func (n *CreateViewStmt) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	n = newNode.(*CreateViewStmt)
+	node, ok := n.ViewName.Accept(v)
+	if !ok {
+		return n, false
+	}
+	n.ViewName = node.(*TableName)
+	node, ok = n.Select.Accept(v)
+	if !ok {
+		return n, false
+	}
+	n.Select = node.(ResultSetNode)
 	return v.Leave(n)
 }
 
@@ -437,6 +491,8 @@ type DropTableStmt struct {
 
 	IfExists bool
 	Tables   []*TableName
+	// IsView is true for the DROP VIEW statement.
+	IsView bool
 }
 
 // Accept implements Node Accept interface.
@@ -536,6 +592,7 @@ const (
 	TableOptionDelayKeyWrite
 	TableOptionRowFormat
 	TableOptionStatsPersistent
+	TableOptionStatsNDV
 )
 
 // RowFormat types
@@ -553,6 +610,16 @@ type TableOption struct {
 	Tp        TableOptionType
 	StrValue  string
 	UintValue uint64
+	// StatsNDVs are the hints of TableOptionStatsNDV.
+	StatsNDVs []*StatsNDV
+}
+
+// StatsNDV is the expected number of distinct values of a column or an index declared by the STATS_NDV table
+// option. The optimizer uses it instead of the pseudo estimate when the table has no statistics, 0 removes it.
+type StatsNDV struct {
+	Name    model.CIStr
+	IsIndex bool
+	NDV     uint64
 }
 
 // ColumnPositionType is the type for ColumnPosition.
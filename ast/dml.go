@@ -14,7 +14,9 @@
 package ast
 
 import (
+	"github.com/pingcap/tidb/context"
 	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/util/types"
 )
 
 var (
@@ -28,6 +30,8 @@ var (
 
 	_ Node = &Assignment{}
 	_ Node = &ByItem{}
+	_ Node = &CommonTableExpr{}
+	_ Node = &CTETable{}
 	_ Node = &FieldList{}
 	_ Node = &GroupByClause{}
 	_ Node = &HavingClause{}
@@ -37,10 +41,12 @@ var (
 	_ Node = &OrderByClause{}
 	_ Node = &SelectField{}
 	_ Node = &TableName{}
+	_ Node = &TableFunc{}
 	_ Node = &TableRefsClause{}
 	_ Node = &TableSource{}
 	_ Node = &UnionSelectList{}
 	_ Node = &WildCardField{}
+	_ Node = &WithClause{}
 )
 
 // JoinType is join type, including cross/left/right/full.
@@ -68,6 +74,8 @@ type Join struct {
 	Tp JoinType
 	// On represents join on condition.
 	On *OnCondition
+	// StraightJoin means the join is written as "t1 STRAIGHT_JOIN t2", the left table is always read first.
+	StraightJoin bool
 }
 
 // Accept implements Node Accept interface.
@@ -141,6 +149,18 @@ type IndexHint struct {
 	HintScope  IndexHintScope
 }
 
+// TableOptimizerHint represents an optimizer hint written in a "/*+ ... */" comment right after SELECT,
+// e.g. "SELECT /*+ DISABLE_RULES(join_reorder, agg_pushdown) */ * FROM t".
+type TableOptimizerHint struct {
+	// HintName is the name of the hint, e.g. DISABLE_RULES.
+	HintName model.CIStr
+	// Args is the identifier list in the parentheses.
+	Args []model.CIStr
+	// QBName is the name of the query block the hint is for, it's written as "HINT(@qb t1, t2)" or
+	// "HINT(t1@qb, t2@qb)". The hint is for the query block where it's written if QBName is empty.
+	QBName model.CIStr
+}
+
 // Accept implements Node Accept interface.
 func (n *TableName) Accept(v Visitor) (Node, bool) {
 	newNode, skipChildren := v.Enter(n)
@@ -151,6 +171,138 @@ func (n *TableName) Accept(v Visitor) (Node, bool) {
 	return v.Leave(n)
 }
 
+// TableFunc represents a table function call in the FROM clause,
+// e.g. "SELECT * FROM generate_series(1, 10)" or
+// "SELECT * FROM json_table(@doc, '$[*]' COLUMNS (id INT PATH '$.id')) AS jt".
+type TableFunc struct {
+	node
+	resultSetNode
+
+	FnName model.CIStr
+	Args   []ExprNode
+	// Columns is the column definitions in the COLUMNS clause, it is nil if the function defines its own columns.
+	Columns []*TableFuncColumn
+}
+
+// TableFuncColumn is a column definition in the COLUMNS clause of a table function.
+type TableFuncColumn struct {
+	Name model.CIStr
+	// Tp is the column type, it is nil for a FOR ORDINALITY column.
+	Tp *types.FieldType
+	// Path is the path of the column value, e.g. '$.id'.
+	Path string
+	// ForOrdinality means the column is the row number starting from 1.
+	ForOrdinality bool
+	// OnEmpty is the response to a path matching nothing, e.g. "DEFAULT 'x' ON EMPTY".
+	OnEmpty TableFuncColumnResponse
+	// OnError is the response to a value which can't be converted to the column type, e.g. "ERROR ON ERROR".
+	OnError TableFuncColumnResponse
+}
+
+// TableFuncColumnResponseType is the type for the responses of a table function column.
+type TableFuncColumnResponseType int
+
+// TableFuncColumnResponse types.
+const (
+	TableFuncColumnResponseNull TableFuncColumnResponseType = iota
+	TableFuncColumnResponseDefault
+	TableFuncColumnResponseError
+)
+
+// TableFuncColumnResponse is the ON EMPTY or ON ERROR response of a table function column, it is NULL by default.
+type TableFuncColumnResponse struct {
+	Tp TableFuncColumnResponseType
+	// Default is the value of DEFAULT.
+	Default string
+}
+
+// Accept implements Node Accept interface.
+func (n *TableFunc) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	n = newNode.(*TableFunc)
+	for i, val := range n.Args {
+		node, ok := val.Accept(v)
+		if !ok {
+			return n, false
+		}
+		n.Args[i] = node.(ExprNode)
+	}
+	return v.Leave(n)
+}
+
+// WithClause is the WITH clause of a select or union statement, which defines the common table expressions
+// referred to by the statement.
+// See https://dev.mysql.com/doc/refman/8.0/en/with.html
+type WithClause struct {
+	node
+
+	CTEs []*CommonTableExpr
+}
+
+// Accept implements Node Accept interface.
+func (n *WithClause) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	n = newNode.(*WithClause)
+	for i, cte := range n.CTEs {
+		node, ok := cte.Accept(v)
+		if !ok {
+			return n, false
+		}
+		n.CTEs[i] = node.(*CommonTableExpr)
+	}
+	return v.Leave(n)
+}
+
+// CommonTableExpr is a named temporary result set defined in a WITH clause, e.g. "cte (a, b) AS (SELECT 1, 2)".
+type CommonTableExpr struct {
+	node
+
+	Name model.CIStr
+	// ColNames is the column list of the CTE, it is nil if the columns are named by the query.
+	ColNames []model.CIStr
+	// Query is a *SelectStmt or a *UnionStmt.
+	Query ResultSetNode
+	// RefCount is the number of the references to the CTE, it is counted by the name resolver.
+	// The CTE referred to only once is merged into the query referring to it, the others are materialized.
+	RefCount int
+}
+
+// Accept implements Node Accept interface.
+func (n *CommonTableExpr) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	n = newNode.(*CommonTableExpr)
+	node, ok := n.Query.Accept(v)
+	if !ok {
+		return n, false
+	}
+	n.Query = node.(ResultSetNode)
+	return v.Leave(n)
+}
+
+// CTETable is a reference to a common table expression in the FROM clause.
+// The query of the CTE is visited in the WITH clause, so it isn't a child of the reference.
+type CTETable struct {
+	node
+	resultSetNode
+
+	CTE *CommonTableExpr
+}
+
+// Accept implements Node Accept interface.
+func (n *CTETable) Accept(v Visitor) (Node, bool) {
+	newNode, _ := v.Enter(n)
+	return v.Leave(newNode)
+}
+
 // DeleteTableList is the tablelist used in delete statement multi-table mode.
 type DeleteTableList struct {
 	node
@@ -203,11 +355,27 @@ type TableSource struct {
 	node
 
 	// Source is the source of the data, can be a TableName,
-	// a SelectStmt, a UnionStmt, or a JoinNode.
+	// a TableFunc, a SelectStmt, a UnionStmt, or a JoinNode.
 	Source ResultSetNode
 
 	// AsName is the alias name of the table source.
 	AsName model.CIStr
+
+	// IsView is true if the Source is the select statement expanded from a view.
+	IsView bool
+	// ViewName is the view expanded, the view is expanded again from its definition when the statement is resolved
+	// again, e.g. a prepared statement after the schema changes.
+	ViewName *TableName
+	// ViewRefs are the tables and the views read by the view, which are checked against the privileges of the
+	// definer of the view every time the statement is planned.
+	ViewRefs []*ViewRef
+}
+
+// ViewRef is a table or a view read by the select statement of a view.
+type ViewRef struct {
+	Table *TableName
+	// Columns are the columns of the table read by the view.
+	Columns []*model.ColumnInfo
 }
 
 // Accept implements Node Accept interface.
@@ -245,6 +413,30 @@ const (
 	SelectLockInShareMode
 )
 
+// SelectLockTableList is the table list in "SELECT ... FOR UPDATE OF tbl_name, ...".
+// Only the rows from the listed tables are locked.
+type SelectLockTableList struct {
+	node
+	Tables []*TableName
+}
+
+// Accept implements Node Accept interface.
+func (n *SelectLockTableList) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	n = newNode.(*SelectLockTableList)
+	for i, t := range n.Tables {
+		node, ok := t.Accept(v)
+		if !ok {
+			return n, false
+		}
+		n.Tables[i] = node.(*TableName)
+	}
+	return v.Leave(n)
+}
+
 // WildCardField is a special type of select field content.
 type WildCardField struct {
 	node
@@ -445,6 +637,8 @@ type SelectStmt struct {
 
 	// Distinct represents if the select has distinct option.
 	Distinct bool
+	// StraightJoin means the tables are joined in the order they're listed in the FROM clause.
+	StraightJoin bool
 	// From is the from clause of the query.
 	From *TableRefsClause
 	// Where is the where clause in select statement.
@@ -461,6 +655,22 @@ type SelectStmt struct {
 	Limit *Limit
 	// Lock is the lock type
 	LockTp SelectLockType
+	// LockTables is the table list of "FOR UPDATE OF", nil means the rows from all the tables are locked.
+	LockTables *SelectLockTableList
+	// TableHints is the optimizer hint list of the select statement.
+	TableHints []*TableOptimizerHint
+	// With is the WITH clause of the select statement.
+	With *WithClause
+	// IntoOutfile is the INTO OUTFILE clause, the result is exported to the file on the server instead.
+	IntoOutfile *SelectIntoOutfile
+}
+
+// SelectIntoOutfile is the INTO OUTFILE clause of the select statement.
+// See https://dev.mysql.com/doc/refman/5.7/en/select-into.html
+type SelectIntoOutfile struct {
+	Path       string
+	FieldsInfo *FieldsClause
+	LinesInfo  *LinesClause
 }
 
 // Accept implements Node Accept interface.
@@ -471,6 +681,14 @@ func (n *SelectStmt) Accept(v Visitor) (Node, bool) {
 	}
 
 	n = newNode.(*SelectStmt)
+	if n.With != nil {
+		node, ok := n.With.Accept(v)
+		if !ok {
+			return n, false
+		}
+		n.With = node.(*WithClause)
+	}
+
 	if n.From != nil {
 		node, ok := n.From.Accept(v)
 		if !ok {
@@ -527,6 +745,14 @@ func (n *SelectStmt) Accept(v Visitor) (Node, bool) {
 		n.Limit = node.(*Limit)
 	}
 
+	if n.LockTables != nil {
+		node, ok := n.LockTables.Accept(v)
+		if !ok {
+			return n, false
+		}
+		n.LockTables = node.(*SelectLockTableList)
+	}
+
 	return v.Leave(n)
 }
 
@@ -564,6 +790,8 @@ type UnionStmt struct {
 	SelectList *UnionSelectList
 	OrderBy    *OrderByClause
 	Limit      *Limit
+	// With is the WITH clause of the union statement.
+	With *WithClause
 }
 
 // Accept implements Node Accept interface.
@@ -573,6 +801,13 @@ func (n *UnionStmt) Accept(v Visitor) (Node, bool) {
 		return v.Leave(newNode)
 	}
 	n = newNode.(*UnionStmt)
+	if n.With != nil {
+		node, ok := n.With.Accept(v)
+		if !ok {
+			return n, false
+		}
+		n.With = node.(*WithClause)
+	}
 	if n.SelectList != nil {
 		node, ok := n.SelectList.Accept(v)
 		if !ok {
@@ -640,11 +875,12 @@ const (
 type LoadDataStmt struct {
 	dmlNode
 
-	IsLocal    bool
-	Path       string
-	Table      *TableName
-	FieldsInfo *FieldsClause
-	LinesInfo  *LinesClause
+	IsLocal     bool
+	Path        string
+	Table       *TableName
+	FieldsInfo  *FieldsClause
+	LinesInfo   *LinesClause
+	IgnoreLines uint64
 }
 
 // Accept implements Node Accept interface.
@@ -664,14 +900,14 @@ func (n *LoadDataStmt) Accept(v Visitor) (Node, bool) {
 	return v.Leave(n)
 }
 
-// FieldsClause represents fields references clause in load data statement.
+// FieldsClause represents fields references clause in load data and select into outfile statements.
 type FieldsClause struct {
 	Terminated string
 	Enclosed   byte
 	Escaped    byte
 }
 
-// LinesClause represents lines references clause in load data statement.
+// LinesClause represents lines references clause in load data and select into outfile statements.
 type LinesClause struct {
 	Starting   string
 	Terminated string
@@ -691,6 +927,17 @@ type InsertStmt struct {
 	Priority    int
 	OnDuplicate []*Assignment
 	Select      ResultSetNode
+	// ValuesStream reads the rows after Lists if the VALUES list is too large to be parsed at once, Lists is the
+	// first chunk of the rows then.
+	ValuesStream ValuesStream
+}
+
+// ValuesStream is the VALUES list of a huge INSERT statement which is parsed chunk by chunk, so the AST of all its
+// rows isn't materialized at once.
+type ValuesStream interface {
+	// ReadChunk parses the chunk of the rows at offset, and returns the offset of the next chunk. It returns no rows
+	// after the last chunk. The chunks are read from offset 0 again every time the statement is executed.
+	ReadChunk(ctx context.Context, offset int) (lists [][]ExprNode, next int, err error)
 }
 
 // Accept implements Node Accept interface.
@@ -910,6 +1157,7 @@ const (
 	ShowIndex
 	ShowProcessList
 	ShowCreateDatabase
+	ShowPlanForDigest
 )
 
 // ShowStmt is a statement to provide information about databases, tables, columns and so on.
@@ -925,6 +1173,7 @@ type ShowStmt struct {
 	Flag   int         // Some flag parsed from sql, such as FULL.
 	Full   bool
 	User   string // Used for show grants.
+	Digest string // Used for show plan for.
 
 	// Used by show variables
 	GlobalScope bool
@@ -252,7 +252,8 @@ const (
 	DateSub
 )
 
-// DateArithInterval is the struct of DateArith interval part.
+// DateArithInterval is the interval part of ADDDATE and SUBDATE, the parser passes its interval and unit to the
+// DateArith function as the arguments.
 type DateArithInterval struct {
 	Unit     string
 	Interval ExprNode
@@ -26,12 +26,15 @@ var (
 	_ StmtNode = &BeginStmt{}
 	_ StmtNode = &BinlogStmt{}
 	_ StmtNode = &CommitStmt{}
+	_ StmtNode = &CreateBindingStmt{}
 	_ StmtNode = &CreateUserStmt{}
 	_ StmtNode = &DeallocateStmt{}
 	_ StmtNode = &DoStmt{}
+	_ StmtNode = &DropBindingStmt{}
 	_ StmtNode = &ExecuteStmt{}
 	_ StmtNode = &ExplainStmt{}
 	_ StmtNode = &GrantStmt{}
+	_ StmtNode = &KillStmt{}
 	_ StmtNode = &PrepareStmt{}
 	_ StmtNode = &RollbackStmt{}
 	_ StmtNode = &SetPwdStmt{}
@@ -66,6 +69,16 @@ type AuthOption struct {
 	// TODO: support auth_plugin
 }
 
+// The output formats of ExplainStmt.
+const (
+	// ExplainFormatRow outputs a row for every operator, it's the default format.
+	ExplainFormatRow = "row"
+	// ExplainFormatJSON outputs the plan tree as a JSON document.
+	ExplainFormatJSON = "json"
+	// ExplainFormatDOT outputs the plan tree as a graph in the DOT language of Graphviz.
+	ExplainFormatDOT = "dot"
+)
+
 // ExplainStmt is a statement to provide information about how is SQL statement executed
 // or get columns information in a table.
 // See https://dev.mysql.com/doc/refman/5.7/en/explain.html
@@ -73,6 +86,10 @@ type ExplainStmt struct {
 	stmtNode
 
 	Stmt StmtNode
+	// Analyze means the statement is executed, and the runtime statistics of the operators are reported.
+	Analyze bool
+	// Format is the output format in lower case, it's empty for the default tabular format.
+	Format string
 }
 
 // Accept implements Node Accept interface.
@@ -409,6 +426,58 @@ func (n *DropUserStmt) Accept(v Visitor) (Node, bool) {
 	return v.Leave(n)
 }
 
+// CreateBindingStmt binds a statement to the hints of the hinted statement, so the statements which differ from it
+// only in the literals are planned by the hints. The binding is stored in mysql.bind_info and applies to all the
+// servers, it's written as "CREATE [GLOBAL] BINDING FOR select ... USING select /*+ hints */ ...".
+type CreateBindingStmt struct {
+	stmtNode
+
+	OriginSel *SelectStmt
+	HintedSel *SelectStmt
+}
+
+// Accept implements Node Accept interface.
+func (n *CreateBindingStmt) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	n = newNode.(*CreateBindingStmt)
+	node, ok := n.OriginSel.Accept(v)
+	if !ok {
+		return n, false
+	}
+	n.OriginSel = node.(*SelectStmt)
+	node, ok = n.HintedSel.Accept(v)
+	if !ok {
+		return n, false
+	}
+	n.HintedSel = node.(*SelectStmt)
+	return v.Leave(n)
+}
+
+// DropBindingStmt drops the binding of a statement, it's written as "DROP [GLOBAL] BINDING FOR select ...".
+type DropBindingStmt struct {
+	stmtNode
+
+	OriginSel *SelectStmt
+}
+
+// Accept implements Node Accept interface.
+func (n *DropBindingStmt) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	n = newNode.(*DropBindingStmt)
+	node, ok := n.OriginSel.Accept(v)
+	if !ok {
+		return n, false
+	}
+	n.OriginSel = node.(*SelectStmt)
+	return v.Leave(n)
+}
+
 // DoStmt is the struct for DO statement.
 type DoStmt struct {
 	stmtNode
@@ -433,6 +502,26 @@ func (n *DoStmt) Accept(v Visitor) (Node, bool) {
 	return v.Leave(n)
 }
 
+// KillStmt is a statement to kill a connection or the running statement of it.
+// See https://dev.mysql.com/doc/refman/5.7/en/kill.html
+type KillStmt struct {
+	stmtNode
+
+	// Query is true for KILL QUERY, which interrupts the running statement only.
+	Query        bool
+	ConnectionID uint64
+}
+
+// Accept implements Node Accept interface.
+func (n *KillStmt) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	n = newNode.(*KillStmt)
+	return v.Leave(n)
+}
+
 // AdminStmtType is the type for admin statement.
 type AdminStmtType int
 
@@ -440,6 +529,15 @@ type AdminStmtType int
 const (
 	AdminShowDDL = iota + 1
 	AdminCheckTable
+	AdminShowDDLJobs
+	AdminDiagnose
+	AdminThrottleTable
+	AdminSetStats
+	AdminResetStats
+	AdminShowSlowPlans
+	AdminRecoverIndex
+	AdminCleanupIndex
+	AdminShowDeleteRanges
 )
 
 // AdminStmt is the struct for Admin statement.
@@ -448,6 +546,19 @@ type AdminStmt struct {
 
 	Tp     AdminStmtType
 	Tables []*TableName
+	// Query is the text or the digest of the statement to diagnose.
+	Query string
+	// ThrottleLimit is the max number of rows written to the table per second, 0 removes the throttle.
+	ThrottleLimit uint64
+	// ThrottleFor is how long the table is throttled in ThrottleUnit, 0 means the default duration.
+	ThrottleFor  uint64
+	ThrottleUnit string
+	// StatsColumn is the column whose NDV is set by ADMIN SET STATS, empty means the row count of the table is set.
+	StatsColumn string
+	// StatsValue is the row count or the NDV set by ADMIN SET STATS.
+	StatsValue uint64
+	// Index is the index recovered by ADMIN RECOVER INDEX or cleaned up by ADMIN CLEANUP INDEX.
+	Index string
 }
 
 // Accept implements Node Accpet interface.
@@ -564,7 +675,7 @@ func (i Ident) Full(ctx context.Context) (full Ident) {
 	if i.Schema.O != "" {
 		full.Schema = i.Schema
 	} else {
-		full.Schema = model.NewCIStr(ctx.GetSessionVars().CurrentDB)
+		full.Schema = model.NewTableCIStr(ctx.GetSessionVars().CurrentDB)
 	}
 	return
 }
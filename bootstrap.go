@@ -50,6 +50,11 @@ const (
 		Execute_priv		ENUM('N','Y') NOT NULL  DEFAULT 'N',
 		Index_priv		ENUM('N','Y') NOT NULL  DEFAULT 'N',
 		Create_user_priv	ENUM('N','Y') NOT NULL  DEFAULT 'N',
+		Process_priv		ENUM('N','Y') NOT NULL  DEFAULT 'N',
+		File_priv		ENUM('N','Y') NOT NULL  DEFAULT 'N',
+		Super_priv		ENUM('N','Y') NOT NULL  DEFAULT 'N',
+		Create_view_priv	ENUM('N','Y') NOT NULL  DEFAULT 'N',
+		Show_view_priv		ENUM('N','Y') NOT NULL  DEFAULT 'N',
 		PRIMARY KEY (Host, User));`
 	// CreateDBPrivTable is the SQL statement creates DB scope privilege table in system db.
 	CreateDBPrivTable = `CREATE TABLE if not exists mysql.db (
@@ -66,6 +71,8 @@ const (
 		Index_priv	ENUM('N','Y') Not Null  DEFAULT 'N',
 		Alter_priv	ENUM('N','Y') Not Null  DEFAULT 'N',
 		Execute_priv	ENUM('N','Y') Not Null  DEFAULT 'N',
+		Create_view_priv	ENUM('N','Y') Not Null  DEFAULT 'N',
+		Show_view_priv	ENUM('N','Y') Not Null  DEFAULT 'N',
 		PRIMARY KEY (Host, DB, User));`
 	// CreateTablePrivTable is the SQL statement creates table scope privilege table in system db.
 	CreateTablePrivTable = `CREATE TABLE if not exists mysql.tables_priv (
@@ -115,6 +122,16 @@ const (
   		PRIMARY KEY (help_topic_id),
   		UNIQUE KEY name (name)
 		) ENGINE=InnoDB DEFAULT CHARSET=utf8 STATS_PERSISTENT=0 COMMENT='help topics';`
+
+	// CreateBindInfoTable is the SQL statement creates bind_info table in system db.
+	// Each row binds the normalized text of a statement in the default database to the statement with the hints.
+	CreateBindInfoTable = `CREATE TABLE if not exists mysql.bind_info (
+		original_sql text NOT NULL,
+		bind_sql text NOT NULL,
+		default_db varchar(64) NOT NULL,
+		create_time datetime NOT NULL,
+		update_time datetime NOT NULL
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_bin;`
 )
 
 // Bootstrap initiates system DB for a store.
@@ -147,6 +164,10 @@ const (
 	// Const for TiDB server version 2.
 	version2 = 2
 	version3 = 3
+	version4 = 4
+	version5 = 5
+	version6 = 6
+	version7 = 7
 )
 
 func checkBootstrapped(s Session) (bool, error) {
@@ -216,6 +237,18 @@ func upgrade(s Session) error {
 	if ver < version3 {
 		upgradeToVer3(s)
 	}
+	if ver < version4 {
+		upgradeToVer4(s)
+	}
+	if ver < version5 {
+		upgradeToVer5(s)
+	}
+	if ver < version6 {
+		upgradeToVer6(s)
+	}
+	if ver < version7 {
+		upgradeToVer7(s)
+	}
 
 	updateBootstrapVer(s)
 	_, err = s.Execute("COMMIT")
@@ -264,6 +297,62 @@ func upgradeToVer3(s Session) {
 	mustExecute(s, sql)
 }
 
+// Update to version 4.
+func upgradeToVer4(s Session) {
+	// Version 4 adds the bind_info table for the SQL bindings.
+	mustExecute(s, CreateBindInfoTable)
+}
+
+// Update to version 5.
+func upgradeToVer5(s Session) {
+	// Version 5 adds the global system variables of the cost factors.
+	factorVars := []string{variable.TiDBOptScanFactor, variable.TiDBOptNetworkFactor, variable.TiDBOptSeekFactor,
+		variable.TiDBOptCPUFactor}
+	values := make([]string, 0, len(factorVars))
+	for _, v := range factorVars {
+		value := fmt.Sprintf(`("%s", "%s")`, v, variable.SysVars[v].Value)
+		values = append(values, value)
+	}
+	sql := fmt.Sprintf("INSERT IGNORE INTO %s.%s VALUES %s;", mysql.SystemDB, mysql.GlobalVariablesTable,
+		strings.Join(values, ", "))
+	mustExecute(s, sql)
+}
+
+// Update to version 6.
+func upgradeToVer6(s Session) {
+	// Version 6 adds the PROCESS, FILE and SUPER privileges, the users who can grant the global privileges get them.
+	for _, col := range []string{"Process_priv", "File_priv", "Super_priv"} {
+		sql := fmt.Sprintf("ALTER TABLE %s.%s ADD COLUMN %s ENUM('N','Y') NOT NULL DEFAULT 'N'",
+			mysql.SystemDB, mysql.UserTable, col)
+		_, err := s.Execute(sql)
+		if err != nil && !infoschema.ErrColumnExists.Equal(err) {
+			log.Fatal(err)
+		}
+	}
+	sql := fmt.Sprintf(`UPDATE %s.%s SET Process_priv = "Y", File_priv = "Y", Super_priv = "Y"
+		WHERE Grant_priv = "Y" AND Create_user_priv = "Y"`, mysql.SystemDB, mysql.UserTable)
+	mustExecute(s, sql)
+}
+
+// Update to version 7.
+func upgradeToVer7(s Session) {
+	// Version 7 adds the CREATE VIEW and SHOW VIEW privileges in the global and db scope, the users who can create
+	// the tables get them, like MySQL does.
+	for _, tbl := range []string{mysql.UserTable, mysql.DBTable} {
+		for _, col := range []string{"Create_view_priv", "Show_view_priv"} {
+			sql := fmt.Sprintf("ALTER TABLE %s.%s ADD COLUMN %s ENUM('N','Y') NOT NULL DEFAULT 'N'",
+				mysql.SystemDB, tbl, col)
+			_, err := s.Execute(sql)
+			if err != nil && !infoschema.ErrColumnExists.Equal(err) {
+				log.Fatal(err)
+			}
+		}
+		sql := fmt.Sprintf(`UPDATE %s.%s SET Create_view_priv = "Y", Show_view_priv = "Y" WHERE Create_priv = "Y"`,
+			mysql.SystemDB, tbl)
+		mustExecute(s, sql)
+	}
+}
+
 // Update boostrap version variable in mysql.TiDB table.
 func updateBootstrapVer(s Session) {
 	// Update bootstrap version.
@@ -302,6 +391,8 @@ func doDDLWorks(s Session) {
 	mustExecute(s, CreateTiDBTable)
 	// Create help table.
 	mustExecute(s, CreateHelpTopic)
+	// Create bind_info table.
+	mustExecute(s, CreateBindInfoTable)
 }
 
 // Execute DML statements in bootstrap stage.
@@ -311,7 +402,7 @@ func doDMLWorks(s Session) {
 
 	// Insert a default user with empty password.
 	mustExecute(s, `INSERT INTO mysql.user VALUES
-		("%", "root", "", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y")`)
+		("%", "root", "", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y")`)
 
 	// Init global system variables table.
 	values := make([]string, 0, len(variable.SysVars))
@@ -20,6 +20,7 @@ import (
 	"github.com/pingcap/tidb/context"
 	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/meta"
+	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/parser"
 	"github.com/pingcap/tidb/sessionctx"
 	"github.com/pingcap/tidb/sessionctx/variable"
@@ -36,7 +37,7 @@ func (s *testSessionSuite) TestBootstrap(c *C) {
 	row, err := r.Next()
 	c.Assert(err, IsNil)
 	c.Assert(row, NotNil)
-	match(c, row.Data, []byte("%"), []byte("root"), []byte(""), "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y")
+	match(c, row.Data, []byte("%"), []byte("root"), []byte(""), "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y")
 
 	c.Assert(se.Auth("root@anyhost", []byte(""), []byte("")), IsTrue)
 	mustExecSQL(c, se, "USE test;")
@@ -83,6 +84,40 @@ func (s *testSessionSuite) TestBootstrap(c *C) {
 	c.Assert(err, IsNil)
 }
 
+func (s *testSessionSuite) TestBootstrapCaseSensitiveNames(c *C) {
+	defer testleak.AfterTest(c)()
+	// The server starts with case sensitive names, the system tables are still found by the names in any case.
+	model.SetLowerCaseTableNames(model.CaseSensitiveNames)
+	defer model.SetLowerCaseTableNames(model.CaseInsensitiveNames)
+	dbName := "test_bootstrap_case_sensitive"
+	store := newStore(c, dbName)
+	se := newSession(c, store, dbName)
+	r := mustExecSQL(c, se, "SELECT COUNT(*) from mysql.global_variables;")
+	v, err := r.Next()
+	c.Assert(err, IsNil)
+	c.Assert(v.Data[0].GetInt64(), Equals, int64(len(variable.SysVars)))
+	mustExecSQL(c, se, "SELECT * from MySQL.GLOBAL_VARIABLES;")
+	mustExecSQL(c, se, "SELECT * from mysql.User;")
+	mustExecSQL(c, se, "set @@global.autocommit = 1")
+	c.Assert(se.Auth("root@anyhost", []byte(""), []byte("")), IsTrue)
+
+	// The names of the other tables are case sensitive.
+	mustExecSQL(c, se, "create table t (a int)")
+	mustExecSQL(c, se, "create table T (a int)")
+	mustExecSQL(c, se, "insert T values (1)")
+	r = mustExecSQL(c, se, "select count(*) from t")
+	v, err = r.Next()
+	c.Assert(err, IsNil)
+	c.Assert(v.Data[0].GetInt64(), Equals, int64(0))
+	_, err = se.Execute("create table mysql.USER (a int)")
+	c.Assert(err, NotNil)
+	mustExecSQL(c, se, "drop database "+dbName)
+	se.Close()
+
+	err = store.Close()
+	c.Assert(err, IsNil)
+}
+
 // Create a new session on store but only do ddl works.
 func (s *testSessionSuite) bootstrapWithOnlyDDLWork(store kv.Storage, c *C) {
 	ss := &session{
@@ -117,7 +152,7 @@ func (s *testSessionSuite) TestBootstrapWithError(c *C) {
 	row, err := r.Next()
 	c.Assert(err, IsNil)
 	c.Assert(row, NotNil)
-	match(c, row.Data, []byte("%"), []byte("root"), []byte(""), "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y")
+	match(c, row.Data, []byte("%"), []byte("root"), []byte(""), "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y")
 	mustExecSQL(c, se, "USE test;")
 	// Check privilege tables.
 	mustExecSQL(c, se, "SELECT * from mysql.db;")
@@ -172,6 +207,10 @@ func (s *testSessionSuite) TestUpgrade(c *C) {
 	mustExecSQL(c, se1, `delete from mysql.TiDB where VARIABLE_NAME="tidb_server_version";`)
 	mustExecSQL(c, se1, fmt.Sprintf(`delete from mysql.global_variables where VARIABLE_NAME="%s" or VARIABLE_NAME="%s";`,
 		variable.DistSQLScanConcurrencyVar, variable.DistSQLJoinConcurrencyVar))
+	mustExecSQL(c, se1, "drop table mysql.bind_info")
+	mustExecSQL(c, se1, "alter table mysql.user drop column Super_priv")
+	mustExecSQL(c, se1, "alter table mysql.user drop column Show_view_priv")
+	mustExecSQL(c, se1, "alter table mysql.db drop column Create_view_priv")
 	mustExecSQL(c, se1, `commit;`)
 	delete(storeBootstrapped, store.UUID())
 	// Make sure the version is downgraded.
@@ -196,4 +235,12 @@ func (s *testSessionSuite) TestUpgrade(c *C) {
 	ver, err = getBootstrapVersion(se2)
 	c.Assert(err, IsNil)
 	c.Assert(ver, Equals, int64(currentBootstrapVersion))
+	mustExecSQL(c, se2, "select * from mysql.bind_info")
+	r = mustExecSQL(c, se2, `SELECT Super_priv, Show_view_priv from mysql.user where User="root"`)
+	row, err = r.Next()
+	c.Assert(err, IsNil)
+	c.Assert(row, NotNil)
+	c.Assert(row.Data[0].GetMysqlEnum().String(), Equals, "Y")
+	c.Assert(row.Data[1].GetMysqlEnum().String(), Equals, "Y")
+	mustExecSQL(c, se2, "select Create_view_priv from mysql.db")
 }
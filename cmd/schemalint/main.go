@@ -0,0 +1,156 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/ddl"
+	"github.com/pingcap/tidb/executor"
+	"github.com/pingcap/tidb/infoschema"
+	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/parser"
+	"github.com/pingcap/tidb/plan"
+	"github.com/pingcap/tidb/util/mock"
+)
+
+var (
+	dbName     = flag.String("db", "", "the current database when the scripts start")
+	dumpSchema = flag.Bool("dump", true, "print the CREATE statements of the resulting schema")
+	logLevel   = flag.String("L", "error", "log level")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [flags] [file ...]\n", os.Args[0])
+		fmt.Fprintln(os.Stderr, "Check the SQL scripts against an in-memory schema, the scripts are read from stdin if no file is given.")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	log.SetLevelByString(*logLevel)
+
+	files := flag.Args()
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	ctx := mock.NewContext()
+	ctx.GetSessionVars().CurrentDB = *dbName
+	tracker := ddl.NewSchemaTracker()
+	failed := 0
+	for _, file := range files {
+		stmts, err := parseFile(file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", file, err)
+			os.Exit(1)
+		}
+		for i, stmt := range stmts {
+			if err = check(ctx, tracker, stmt); err != nil {
+				fmt.Fprintf(os.Stderr, "%s: statement %d: %v\n    %s\n", file, i+1, err, strings.TrimSpace(stmt.Text()))
+				failed++
+			}
+		}
+	}
+	if *dumpSchema {
+		dump(tracker.GetInformationSchema())
+	}
+	if failed > 0 {
+		fmt.Fprintf(os.Stderr, "%d statements failed\n", failed)
+		os.Exit(1)
+	}
+}
+
+func parseFile(file string) ([]ast.StmtNode, error) {
+	var data []byte
+	var err error
+	if file == "-" {
+		data, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		data, err = ioutil.ReadFile(file)
+	}
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	stmts, err := parser.New().Parse(string(data), "", "")
+	return stmts, errors.Trace(err)
+}
+
+// check compiles the statement like the server does, the DDL statements are applied to the schema tracker,
+// SHOW CREATE TABLE and SHOW CREATE DATABASE print their results, and the other statements are not executed.
+func check(ctx context.Context, tracker *ddl.SchemaTracker, stmt ast.StmtNode) error {
+	is := tracker.GetInformationSchema()
+	if x, ok := stmt.(*ast.UseStmt); ok {
+		if !is.SchemaExists(model.NewCIStr(x.DBName)) {
+			return infoschema.ErrDatabaseNotExists.Gen("database %s not exists", x.DBName)
+		}
+		ctx.GetSessionVars().CurrentDB = x.DBName
+		return nil
+	}
+	if err := plan.Preprocess(stmt, is, ctx); err != nil {
+		return errors.Trace(err)
+	}
+	if err := plan.Validate(stmt, false); err != nil {
+		return errors.Trace(err)
+	}
+	if _, err := plan.Optimize(ctx, stmt, is); err != nil {
+		return errors.Trace(err)
+	}
+	switch x := stmt.(type) {
+	case ast.DDLNode:
+		return errors.Trace(tracker.Exec(ctx, x))
+	case *ast.ShowStmt:
+		switch x.Tp {
+		case ast.ShowCreateTable:
+			tb, err := is.TableByName(x.Table.Schema, x.Table.Name)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			fmt.Printf("%s;\n", executor.ShowCreateTable(tb))
+		case ast.ShowCreateDatabase:
+			db, ok := is.SchemaByName(model.NewCIStr(x.DBName))
+			if !ok {
+				return infoschema.ErrDatabaseNotExists.Gen("Unknown database '%s'", x.DBName)
+			}
+			fmt.Printf("%s;\n", executor.ShowCreateDatabase(db))
+		}
+	}
+	return nil
+}
+
+// dump prints the databases and tables of the schema ordered by their names.
+func dump(is infoschema.InfoSchema) {
+	dbNames := is.AllSchemaNames()
+	sort.Strings(dbNames)
+	for _, dbName := range dbNames {
+		db, _ := is.SchemaByName(model.NewCIStr(dbName))
+		fmt.Printf("%s;\nUSE `%s`;\n", executor.ShowCreateDatabase(db), db.Name.O)
+		tbNames := make([]string, 0, len(db.Tables))
+		for _, tbInfo := range db.Tables {
+			tbNames = append(tbNames, tbInfo.Name.L)
+		}
+		sort.Strings(tbNames)
+		for _, tbName := range tbNames {
+			tb, _ := is.TableByName(db.Name, model.NewCIStr(tbName))
+			fmt.Printf("%s;\n", executor.ShowCreateTable(tb))
+		}
+	}
+}
@@ -14,6 +14,7 @@
 package ddl
 
 import (
+	"sync"
 	"time"
 
 	"github.com/juju/errors"
@@ -24,13 +25,17 @@ import (
 	"github.com/pingcap/tidb/terror"
 )
 
+// bgJobConcurrency is the max number of the background jobs at the front of the queue which are run concurrently.
+// The jobs delete the data of different tables, whose regions are usually spread over different stores.
+var bgJobConcurrency = 4
+
 // handleBgJobQueue handles the background job queue.
 func (d *ddl) handleBgJobQueue() error {
 	if d.isClosed() {
 		return nil
 	}
 
-	job := &model.Job{}
+	var ranJobs []*model.Job
 	err := kv.RunInNewTxn(d.store, false, func(txn kv.Transaction) error {
 		t := meta.NewMeta(txn)
 		owner, err := d.checkOwner(t, bgJobFlag)
@@ -41,23 +46,32 @@ func (d *ddl) handleBgJobQueue() error {
 			return errors.Trace(err)
 		}
 
-		// Get the first background job and run it.
-		job, err = d.getFirstBgJob(t)
+		// Get the background jobs at the front of the queue and run them concurrently.
+		jobs, err := d.getFirstBgJobs(t, bgJobConcurrency)
 		if err != nil {
 			return errors.Trace(err)
 		}
-		if job == nil {
+		if len(jobs) == 0 {
 			return nil
 		}
 
-		d.runBgJob(t, job)
-		if job.IsFinished() {
-			err = d.finishBgJob(t, job)
-		} else {
-			err = d.updateBgJob(t, job)
+		ranJobs = d.runBgJobs(jobs)
+		for i, job := range jobs {
+			err = d.updateBgJob(t, int64(i), job)
+			if err != nil {
+				return errors.Trace(err)
+			}
 		}
-		if err != nil {
-			return errors.Trace(err)
+		// The queue is only popped from the front, so a finished job waits in the queue
+		// until the jobs before it are finished.
+		for _, job := range jobs {
+			if !job.IsFinished() {
+				break
+			}
+			err = d.finishBgJob(t, job)
+			if err != nil {
+				return errors.Trace(err)
+			}
 		}
 
 		owner.LastUpdateTS = time.Now().UnixNano()
@@ -70,22 +84,44 @@ func (d *ddl) handleBgJobQueue() error {
 	}
 
 	d.hookMu.Lock()
-	d.hook.OnBgJobUpdated(job)
+	for _, job := range ranJobs {
+		d.hook.OnBgJobUpdated(job)
+	}
 	d.hookMu.Unlock()
 
 	return nil
 }
 
+// runBgJobs runs the unfinished background jobs concurrently, and returns the jobs which are run.
+func (d *ddl) runBgJobs(jobs []*model.Job) []*model.Job {
+	var wg sync.WaitGroup
+	ranJobs := make([]*model.Job, 0, len(jobs))
+	for _, job := range jobs {
+		if job.IsFinished() {
+			continue
+		}
+		ranJobs = append(ranJobs, job)
+		wg.Add(1)
+		go func(job *model.Job) {
+			defer wg.Done()
+			d.runBgJob(job)
+		}(job)
+	}
+	wg.Wait()
+	return ranJobs
+}
+
 // runBgJob runs a background job.
-func (d *ddl) runBgJob(t *meta.Meta, job *model.Job) {
+// It deletes the data in its own transactions, so it can run concurrently with other background jobs.
+func (d *ddl) runBgJob(job *model.Job) {
 	job.State = model.JobRunning
 
 	var err error
 	switch job.Type {
 	case model.ActionDropSchema:
-		err = d.delReorgSchema(t, job)
-	case model.ActionDropTable, model.ActionTruncateTable:
-		err = d.delReorgTable(t, job)
+		err = d.delReorgSchema(job)
+	case model.ActionDropTable, model.ActionTruncateTable, model.ActionShadowCopy:
+		err = d.delReorgTable(job)
 	default:
 		job.State = model.JobCancelled
 		err = errInvalidBgJob
@@ -93,7 +129,7 @@ func (d *ddl) runBgJob(t *meta.Meta, job *model.Job) {
 
 	if err != nil {
 		if job.State != model.JobCancelled {
-			log.Errorf("[ddl] run background job err %v", errors.ErrorStack(err))
+			log.Errorf("[ddl] run background job %d err %v", job.ID, errors.ErrorStack(err))
 		}
 		job.Error = toTError(err)
 		job.ErrorCount++
@@ -123,20 +159,34 @@ func (d *ddl) prepareBgJob(t *meta.Meta, ddlJob *model.Job) error {
 // startBgJob starts a background job.
 func (d *ddl) startBgJob(tp model.ActionType) {
 	switch tp {
-	case model.ActionDropSchema, model.ActionDropTable, model.ActionTruncateTable:
+	case model.ActionDropSchema, model.ActionDropTable, model.ActionTruncateTable, model.ActionShadowCopy:
 		asyncNotify(d.bgJobCh)
 	}
 }
 
-// getFirstBgJob gets the first background job.
-func (d *ddl) getFirstBgJob(t *meta.Meta) (*model.Job, error) {
-	job, err := t.GetBgJob(0)
-	return job, errors.Trace(err)
+// getFirstBgJobs gets at most cnt background jobs at the front of the queue.
+func (d *ddl) getFirstBgJobs(t *meta.Meta, cnt int) ([]*model.Job, error) {
+	n, err := t.BgJobQueueLen()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if n > int64(cnt) {
+		n = int64(cnt)
+	}
+	jobs := make([]*model.Job, 0, n)
+	for i := int64(0); i < n; i++ {
+		job, err := t.GetBgJob(i)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
 }
 
-// updateBgJob updates a background job.
-func (d *ddl) updateBgJob(t *meta.Meta, job *model.Job) error {
-	err := t.UpdateBgJob(0, job)
+// updateBgJob updates the background job with index in the queue.
+func (d *ddl) updateBgJob(t *meta.Meta, index int64, job *model.Job) error {
+	err := t.UpdateBgJob(index, job)
 	return errors.Trace(err)
 }
 
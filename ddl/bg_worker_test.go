@@ -14,12 +14,15 @@
 package ddl
 
 import (
+	"sync"
 	"time"
 
 	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/inspectkv"
 	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/meta"
 	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/tablecodec"
 	"github.com/pingcap/tidb/util/testleak"
 )
 
@@ -113,3 +116,81 @@ func (s *testDDLSuite) TestInvalidBgJobType(c *C) {
 	time.Sleep(testLease * 6)
 	verifyBgJobState(c, d, job, model.JobCancelled)
 }
+
+func (s *testDDLSuite) TestRunBgJobsConcurrently(c *C) {
+	defer testleak.AfterTest(c)()
+	store := testCreateStore(c, "test_run_bg_jobs_concurrently")
+	defer store.Close()
+
+	d := newDDL(store, nil, nil, testLease)
+	defer d.close()
+
+	// Use a smaller limit and concurrency to make the jobs run several times.
+	reorgTableDeleteLimit = 10
+	bgJobConcurrency = 2
+	defer func() {
+		reorgTableDeleteLimit = 65536
+		bgJobConcurrency = 4
+	}()
+
+	var mu sync.Mutex
+	var updated []int64
+	tc := &testDDLCallback{}
+	tc.onBgJobUpdated = func(job *model.Job) {
+		mu.Lock()
+		updated = append(updated, job.ID)
+		mu.Unlock()
+	}
+	d.setHook(tc)
+	// Make the background jobs run by the owner at once.
+	testCheckOwner(c, d, true, bgJobFlag)
+
+	// The table of the job 1 has 25 rows, which are deleted by 3 runs, the table of the job 2 has 5 rows,
+	// and the table of the job 3 has no rows.
+	rowCounts := []int{25, 5, 0}
+	err := kv.RunInNewTxn(store, false, func(txn kv.Transaction) error {
+		t := meta.NewMeta(txn)
+		for i, cnt := range rowCounts {
+			tableID := int64(1001 + i)
+			for h := 1; h <= cnt; h++ {
+				err := txn.Set(tablecodec.EncodeRowKeyWithHandle(tableID, int64(h)), []byte("v"))
+				c.Assert(err, IsNil)
+			}
+			job := &model.Job{
+				ID:      int64(i + 1),
+				TableID: tableID,
+				Type:    model.ActionDropTable,
+				Args:    []interface{}{0, nil, tablecodec.EncodeTablePrefix(tableID)},
+			}
+			err := d.prepareBgJob(t, job)
+			c.Assert(err, IsNil)
+		}
+		return nil
+	})
+	c.Assert(err, IsNil)
+	d.startBgJob(model.ActionDropTable)
+
+	time.Sleep(testLease * 100)
+	for i := range rowCounts {
+		verifyBgJobState(c, d, &model.Job{ID: int64(i + 1)}, model.JobDone)
+	}
+	// The jobs 1 and 2 run concurrently, the job 2 is finished first but it's kept in the queue until the job 1
+	// is finished, then the job 3 runs.
+	mu.Lock()
+	c.Assert(updated, DeepEquals, []int64{1, 2, 1, 1, 3})
+	mu.Unlock()
+
+	// All the ranges are deleted.
+	kv.RunInNewTxn(store, false, func(txn kv.Transaction) error {
+		ranges, nextID, err := inspectkv.GetDeleteRanges(txn, 0, len(rowCounts)+1)
+		c.Assert(err, IsNil)
+		c.Assert(nextID, Equals, int64(0))
+		c.Assert(ranges, HasLen, len(rowCounts))
+		for _, r := range ranges {
+			c.Assert(r.Done, IsTrue)
+			c.Assert(r.HasData, IsFalse)
+			c.Assert(r.IsOrphan(), IsFalse)
+		}
+		return nil
+	})
+}
@@ -14,6 +14,7 @@
 package ddl
 
 import (
+	"math"
 	"time"
 
 	"github.com/juju/errors"
@@ -30,7 +31,7 @@ import (
 	"github.com/pingcap/tidb/util/types"
 )
 
-func (d *ddl) adjustColumnOffset(columns []*model.ColumnInfo, indices []*model.IndexInfo, offset int, added bool) {
+func adjustColumnOffset(columns []*model.ColumnInfo, indices []*model.IndexInfo, offset int, added bool) {
 	offsetChanged := make(map[int]int)
 	if added {
 		for i := offset + 1; i < len(columns); i++ {
@@ -59,7 +60,7 @@ func (d *ddl) adjustColumnOffset(columns []*model.ColumnInfo, indices []*model.I
 	}
 }
 
-func (d *ddl) createColumnInfo(tblInfo *model.TableInfo, colInfo *model.ColumnInfo, pos *ast.ColumnPosition) (*model.ColumnInfo, int, error) {
+func createColumnInfo(tblInfo *model.TableInfo, colInfo *model.ColumnInfo, pos *ast.ColumnPosition) (*model.ColumnInfo, int, error) {
 	// Check column name duplicate.
 	cols := tblInfo.Columns
 	position := len(cols)
@@ -116,7 +117,7 @@ func (d *ddl) onAddColumn(t *meta.Meta, job *model.Job) error {
 			return infoschema.ErrColumnExists.Gen("column already exist %s", col.Name)
 		}
 	} else {
-		columnInfo, offset, err = d.createColumnInfo(tblInfo, col, pos)
+		columnInfo, offset, err = createColumnInfo(tblInfo, col, pos)
 		if err != nil {
 			job.State = model.JobCancelled
 			return errors.Trace(err)
@@ -179,7 +180,7 @@ func (d *ddl) onAddColumn(t *meta.Meta, job *model.Job) error {
 		}
 
 		// Adjust column offset.
-		d.adjustColumnOffset(tblInfo.Columns, tblInfo.Indices, offset, true)
+		adjustColumnOffset(tblInfo.Columns, tblInfo.Indices, offset, true)
 		columnInfo.State = model.StatePublic
 		if err = t.UpdateTable(schemaID, tblInfo); err != nil {
 			return errors.Trace(err)
@@ -245,7 +246,7 @@ func (d *ddl) onDropColumn(t *meta.Meta, job *model.Job) error {
 		job.SchemaState = model.StateWriteOnly
 		colInfo.State = model.StateWriteOnly
 		// Set this column's offset to the last and reset all following columns' offsets.
-		d.adjustColumnOffset(tblInfo.Columns, tblInfo.Indices, colInfo.Offset, false)
+		adjustColumnOffset(tblInfo.Columns, tblInfo.Indices, colInfo.Offset, false)
 		err = t.UpdateTable(schemaID, tblInfo)
 	case model.StateWriteOnly:
 		// write only -> delete only
@@ -303,7 +304,7 @@ func (d *ddl) addTableColumn(t table.Table, columnInfo *model.ColumnInfo, reorgI
 
 	for {
 		startTime := time.Now()
-		handles, err := d.getSnapshotRows(t, version, seekHandle)
+		handles, err := d.getSnapshotRows(t, version, seekHandle, math.MaxInt64)
 		if err != nil {
 			return errors.Trace(err)
 		} else if len(handles) == 0 {
@@ -390,6 +391,7 @@ func (d *ddl) backfillColumn(t table.Table, columnInfo *model.ColumnInfo, handle
 	}
 
 	var endIdx int
+	rowCount := reorgInfo.progress.RowCount
 	for len(handles) > 0 {
 		if len(handles) >= defaultSmallBatchCnt {
 			endIdx = defaultSmallBatchCnt
@@ -406,12 +408,13 @@ func (d *ddl) backfillColumn(t table.Table, columnInfo *model.ColumnInfo, handle
 			if err1 != nil {
 				return errors.Trace(err1)
 			}
-			return errors.Trace(reorgInfo.UpdateHandle(txn, nextHandle))
+			return errors.Trace(reorgInfo.UpdateHandle(txn, nextHandle, rowCount+int64(endIdx)))
 		})
 
 		if err != nil {
 			return errors.Trace(err)
 		}
+		rowCount += int64(endIdx)
 		handles = handles[endIdx:]
 	}
 
@@ -63,7 +63,9 @@ var (
 	errIncorrectPrefixKey    = terror.ClassDDL.New(codeIncorrectPrefixKey, "Incorrect prefix key; the used key part isn't a string, the used length is longer than the key part, or the storage engine doesn't support unique prefix keys")
 	errTooLongKey            = terror.ClassDDL.New(codeTooLongKey, fmt.Sprintf("Specified key was too long; max key length is %d bytes", maxPrefixLength))
 	errKeyColumnDoesNotExits = terror.ClassDDL.New(codeKeyColumnDoesNotExits, "this key column doesn't exist in table")
+	errKeyDoesNotExist       = terror.ClassDDL.New(codeKeyDoesNotExist, "this key doesn't exist in table")
 	errDupKeyName            = terror.ClassDDL.New(codeDupKeyName, "duplicate key name")
+	errViewWrongList         = terror.ClassDDL.New(codeViewWrongList, "View's SELECT and view's field list have different column counts")
 
 	// ErrInvalidDBState returns for invalid database state.
 	ErrInvalidDBState = terror.ClassDDL.New(codeInvalidDBState, "invalid database state")
@@ -94,6 +96,8 @@ type DDL interface {
 	DropSchema(ctx context.Context, schema model.CIStr) error
 	CreateTable(ctx context.Context, ident ast.Ident, cols []*ast.ColumnDef,
 		constrs []*ast.Constraint, options []*ast.TableOption) error
+	CreateTableWithLike(ctx context.Context, ident, referIdent ast.Ident) error
+	CreateView(ctx context.Context, ident ast.Ident, cols []*model.ColumnInfo, view *model.ViewInfo, orReplace bool) error
 	DropTable(ctx context.Context, tableIdent ast.Ident) (err error)
 	CreateIndex(ctx context.Context, tableIdent ast.Ident, unique bool, indexName model.CIStr,
 		columnNames []*ast.IndexColName) error
@@ -413,12 +417,13 @@ func setColumnFlagWithConstraint(colMap map[string]*table.Column, v *ast.Constra
 	}
 }
 
-func (d *ddl) buildColumnsAndConstraints(ctx context.Context, colDefs []*ast.ColumnDef,
-	constraints []*ast.Constraint) ([]*table.Column, []*ast.Constraint, error) {
+// buildColumnsAndConstraints builds the columns and the constraints of a table, the column IDs are got from genID.
+func buildColumnsAndConstraints(ctx context.Context, colDefs []*ast.ColumnDef,
+	constraints []*ast.Constraint, genID func() (int64, error)) ([]*table.Column, []*ast.Constraint, error) {
 	var cols []*table.Column
 	colMap := map[string]*table.Column{}
 	for i, colDef := range colDefs {
-		col, cts, err := d.buildColumnAndConstraint(ctx, i, colDef)
+		col, cts, err := buildColumnAndConstraint(ctx, i, colDef, genID)
 		if err != nil {
 			return nil, nil, errors.Trace(err)
 		}
@@ -434,10 +439,11 @@ func (d *ddl) buildColumnsAndConstraints(ctx context.Context, colDefs []*ast.Col
 	return cols, constraints, nil
 }
 
-func (d *ddl) setCharsetCollationFlenDecimal(tp *types.FieldType) {
+func setCharsetCollationFlenDecimal(tp *types.FieldType) {
 	if len(tp.Charset) == 0 {
 		switch tp.Tp {
-		case mysql.TypeString, mysql.TypeVarchar, mysql.TypeVarString, mysql.TypeBlob, mysql.TypeTinyBlob, mysql.TypeMediumBlob, mysql.TypeLongBlob:
+		case mysql.TypeString, mysql.TypeVarchar, mysql.TypeVarString, mysql.TypeBlob, mysql.TypeTinyBlob, mysql.TypeMediumBlob, mysql.TypeLongBlob,
+			mysql.TypeEnum, mysql.TypeSet:
 			tp.Charset, tp.Collate = getDefaultCharsetAndCollate()
 		default:
 			tp.Charset = charset.CharsetBin
@@ -447,21 +453,25 @@ func (d *ddl) setCharsetCollationFlenDecimal(tp *types.FieldType) {
 	// If flen is not assigned, assigned it by type.
 	if tp.Flen == types.UnspecifiedLength {
 		tp.Flen = mysql.GetDefaultFieldLength(tp.Tp)
+		if types.IsTypeInteger(tp.Tp) && mysql.HasUnsignedFlag(tp.Flag) {
+			// The unsigned integers are displayed without the sign.
+			tp.Flen--
+		}
 	}
 	if tp.Decimal == types.UnspecifiedLength {
 		tp.Decimal = mysql.GetDefaultDecimal(tp.Tp)
 	}
 }
 
-func (d *ddl) buildColumnAndConstraint(ctx context.Context, offset int,
-	colDef *ast.ColumnDef) (*table.Column, []*ast.Constraint, error) {
-	d.setCharsetCollationFlenDecimal(colDef.Tp)
+func buildColumnAndConstraint(ctx context.Context, offset int,
+	colDef *ast.ColumnDef, genID func() (int64, error)) (*table.Column, []*ast.Constraint, error) {
+	setCharsetCollationFlenDecimal(colDef.Tp)
 	col, cts, err := columnDefToCol(ctx, offset, colDef)
 	if err != nil {
 		return nil, nil, errors.Trace(err)
 	}
 
-	col.ID, err = d.genGlobalID()
+	col.ID, err = genID()
 	if err != nil {
 		return nil, nil, errors.Trace(err)
 	}
@@ -711,7 +721,7 @@ func setEmptyConstraintName(namesMap map[string]bool, constr *ast.Constraint, fo
 	}
 }
 
-func (d *ddl) checkConstraintNames(constraints []*ast.Constraint) error {
+func checkConstraintNames(constraints []*ast.Constraint) error {
 	constrNames := map[string]bool{}
 	fkNames := map[string]bool{}
 
@@ -742,11 +752,13 @@ func (d *ddl) checkConstraintNames(constraints []*ast.Constraint) error {
 	return nil
 }
 
-func (d *ddl) buildTableInfo(tableName model.CIStr, cols []*table.Column, constraints []*ast.Constraint) (tbInfo *model.TableInfo, err error) {
+// buildTableInfo builds the TableInfo of a new table, the table and index IDs are got from genID.
+func buildTableInfo(tableName model.CIStr, cols []*table.Column, constraints []*ast.Constraint,
+	genID func() (int64, error)) (tbInfo *model.TableInfo, err error) {
 	tbInfo = &model.TableInfo{
 		Name: tableName,
 	}
-	tbInfo.ID, err = d.genGlobalID()
+	tbInfo.ID, err = genID()
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -832,7 +844,7 @@ func (d *ddl) buildTableInfo(tableName model.CIStr, cols []*table.Column, constr
 			// Use btree as default index type.
 			idxInfo.Tp = model.IndexTypeBtree
 		}
-		idxInfo.ID, err = d.genGlobalID()
+		idxInfo.ID, err = genID()
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
@@ -861,17 +873,17 @@ func (d *ddl) CreateTable(ctx context.Context, ident ast.Ident, colDefs []*ast.C
 		return errors.Trace(err)
 	}
 
-	cols, newConstraints, err := d.buildColumnsAndConstraints(ctx, colDefs, constraints)
+	cols, newConstraints, err := buildColumnsAndConstraints(ctx, colDefs, constraints, d.genGlobalID)
 	if err != nil {
 		return errors.Trace(err)
 	}
 
-	err = d.checkConstraintNames(newConstraints)
+	err = checkConstraintNames(newConstraints)
 	if err != nil {
 		return errors.Trace(err)
 	}
 
-	tbInfo, err := d.buildTableInfo(ident.Name, cols, newConstraints)
+	tbInfo, err := buildTableInfo(ident.Name, cols, newConstraints, d.genGlobalID)
 	if err != nil {
 		return errors.Trace(err)
 	}
@@ -883,7 +895,9 @@ func (d *ddl) CreateTable(ctx context.Context, ident ast.Ident, colDefs []*ast.C
 		Args:     []interface{}{tbInfo},
 	}
 
-	d.handleTableOptions(options, tbInfo, schema.ID)
+	if err = handleTableOptions(options, tbInfo); err != nil {
+		return errors.Trace(err)
+	}
 	err = d.doDDLJob(ctx, job)
 	if err == nil {
 		if tbInfo.AutoIncID > 1 {
@@ -896,6 +910,157 @@ func (d *ddl) CreateTable(ctx context.Context, ident ast.Ident, colDefs []*ast.C
 	return errors.Trace(err)
 }
 
+// CreateTableWithLike creates a table with the same columns, indices and options as the refer table. Like MySQL,
+// the foreign keys are not copied, and the auto increment ID starts from the beginning.
+func (d *ddl) CreateTableWithLike(ctx context.Context, ident, referIdent ast.Ident) (err error) {
+	is := d.GetInformationSchema()
+	schema, ok := is.SchemaByName(ident.Schema)
+	if !ok {
+		return infoschema.ErrDatabaseNotExists.Gen("database %s not exists", ident.Schema)
+	}
+	referTbl, err := is.TableByName(referIdent.Schema, referIdent.Name)
+	if err != nil {
+		return infoschema.ErrTableNotExists.Gen("table %s.%s does not exist", referIdent.Schema, referIdent.Name)
+	}
+	if referTbl.Meta().IsView() {
+		return infoschema.ErrWrongObject.Gen("'%s.%s' is not BASE TABLE", referIdent.Schema, referIdent.Name)
+	}
+	if is.TableExists(ident.Schema, ident.Name) {
+		return errors.Trace(infoschema.ErrTableExists)
+	}
+	if err = checkTooLongTable(ident.Name); err != nil {
+		return errors.Trace(err)
+	}
+
+	tbInfo := referTbl.Meta().Clone()
+	tbInfo.Name = ident.Name
+	tbInfo.AutoIncID = 0
+	tbInfo.ForeignKeys = nil
+	// The indices being added or dropped are not copied.
+	tbInfo.Indices = tbInfo.Indices[:0]
+	for _, idx := range referTbl.Meta().Indices {
+		if idx.State == model.StatePublic {
+			tbInfo.Indices = append(tbInfo.Indices, idx.Clone())
+		}
+	}
+	tbInfo.ID, err = d.genGlobalID()
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	job := &model.Job{
+		SchemaID: schema.ID,
+		TableID:  tbInfo.ID,
+		Type:     model.ActionCreateTable,
+		Args:     []interface{}{tbInfo},
+	}
+	err = d.doDDLJob(ctx, job)
+	err = d.callHookOnChanged(err)
+	return errors.Trace(err)
+}
+
+// CreateView creates a view, the columns are the result columns of the select statement of the view.
+// If orReplace is true, the existing view with the same name is replaced.
+func (d *ddl) CreateView(ctx context.Context, ident ast.Ident, cols []*model.ColumnInfo, view *model.ViewInfo,
+	orReplace bool) (err error) {
+	is := d.GetInformationSchema()
+	schema, ok := is.SchemaByName(ident.Schema)
+	if !ok {
+		return infoschema.ErrDatabaseNotExists.Gen("database %s not exists", ident.Schema)
+	}
+	if old, err1 := is.TableByName(ident.Schema, ident.Name); err1 == nil {
+		if !orReplace {
+			return errors.Trace(infoschema.ErrTableExists)
+		}
+		if !old.Meta().IsView() {
+			return infoschema.ErrWrongObject.Gen("'%s.%s' is not VIEW", ident.Schema, ident.Name)
+		}
+	}
+	if err = checkTooLongTable(ident.Name); err != nil {
+		return errors.Trace(err)
+	}
+
+	tbInfo := &model.TableInfo{
+		Name:    ident.Name,
+		Columns: cols,
+		View:    view,
+	}
+	tbInfo.Charset, tbInfo.Collate = getDefaultCharsetAndCollate()
+	tbInfo.ID, err = d.genGlobalID()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	colNames := make(map[string]bool, len(cols))
+	for i, col := range cols {
+		if colNames[col.Name.L] {
+			return infoschema.ErrColumnExists.Gen("duplicate column %s", col.Name)
+		}
+		colNames[col.Name.L] = true
+		if len(col.Name.O) > mysql.MaxColumnNameLength {
+			return ErrTooLongIdent.Gen("too long column %s", col.Name)
+		}
+		col.ID, err = d.genGlobalID()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		col.Offset = i
+		col.State = model.StatePublic
+	}
+
+	job := &model.Job{
+		SchemaID: schema.ID,
+		TableID:  tbInfo.ID,
+		Type:     model.ActionCreateView,
+		Args:     []interface{}{tbInfo, orReplace},
+	}
+
+	err = d.doDDLJob(ctx, job)
+	err = d.callHookOnChanged(err)
+	return errors.Trace(err)
+}
+
+// ResultFieldsToColumnDefs builds the column definitions of the table created by CREATE TABLE ... SELECT from
+// the result fields of the select statement, the columns have no key. The column types are large enough to hold the
+// values of the expressions like MySQL.
+func ResultFieldsToColumnDefs(rfs []*ast.ResultField) []*ast.ColumnDef {
+	colDefs := make([]*ast.ColumnDef, 0, len(rfs))
+	for _, rf := range rfs {
+		name := rf.ColumnAsName
+		if name.L == "" {
+			name = rf.Column.Name
+		}
+		tp := resultColumnType(rf.Expr)
+		fixResultColumnType(&tp)
+		tp.Flag &^= mysql.PriKeyFlag | mysql.UniqueKeyFlag | mysql.MultipleKeyFlag | mysql.AutoIncrementFlag
+		colDefs = append(colDefs, &ast.ColumnDef{
+			Name: &ast.ColumnName{Name: name},
+			Tp:   &tp,
+		})
+	}
+	return colDefs
+}
+
+// ResultFieldsToViewColumns builds the columns of a view from the result fields of its select statement,
+// the columns are named by names if it is not empty, its length must be the same as the result fields.
+func ResultFieldsToViewColumns(rfs []*ast.ResultField, names []model.CIStr) []*model.ColumnInfo {
+	cols := make([]*model.ColumnInfo, 0, len(rfs))
+	for i, rf := range rfs {
+		col := &model.ColumnInfo{
+			Name:      rf.ColumnAsName,
+			FieldType: *rf.Expr.GetType(),
+		}
+		if len(names) > 0 {
+			col.Name = names[i]
+		} else if col.Name.L == "" {
+			col.Name = rf.Column.Name
+		}
+		// The columns of a view have no key.
+		col.Flag &^= mysql.PriKeyFlag | mysql.UniqueKeyFlag | mysql.MultipleKeyFlag | mysql.AutoIncrementFlag
+		cols = append(cols, col)
+	}
+	return cols
+}
+
 // If create table with auto_increment option, we should rebase tableAutoIncID value.
 func (d *ddl) handleAutoIncID(tbInfo *model.TableInfo, schemaID int64) error {
 	alloc := autoid.NewAllocator(d.store, schemaID)
@@ -914,7 +1079,7 @@ func (d *ddl) handleAutoIncID(tbInfo *model.TableInfo, schemaID int64) error {
 }
 
 // Add create table options into TableInfo.
-func (d *ddl) handleTableOptions(options []*ast.TableOption, tbInfo *model.TableInfo, schemaID int64) {
+func handleTableOptions(options []*ast.TableOption, tbInfo *model.TableInfo) error {
 	for _, op := range options {
 		switch op.Tp {
 		case ast.TableOptionAutoIncrement:
@@ -925,11 +1090,52 @@ func (d *ddl) handleTableOptions(options []*ast.TableOption, tbInfo *model.Table
 			tbInfo.Charset = op.StrValue
 		case ast.TableOptionCollate:
 			tbInfo.Charset = op.StrValue
+		case ast.TableOptionStatsNDV:
+			if err := setStatsNDVs(tbInfo, op.StatsNDVs); err != nil {
+				return errors.Trace(err)
+			}
+		}
+	}
+	return nil
+}
+
+// setStatsNDVs sets the NDV hints of the columns and indices of the table.
+func setStatsNDVs(tbInfo *model.TableInfo, ndvs []*ast.StatsNDV) error {
+	for _, ndv := range ndvs {
+		if ndv.IsIndex {
+			idx := findIndexByName(tbInfo.Indices, ndv.Name.L)
+			if idx == nil {
+				return errKeyDoesNotExist.Gen("index %s doesn't exist in table %s", ndv.Name, tbInfo.Name)
+			}
+			idx.StatsNDV = int64(ndv.NDV)
+			continue
 		}
+		col := findCol(tbInfo.Columns, ndv.Name.L)
+		if col == nil {
+			return infoschema.ErrColumnNotExists.Gen("column %s doesn't exist", ndv.Name)
+		}
+		col.StatsNDV = int64(ndv.NDV)
 	}
+	return nil
 }
 
 func (d *ddl) AlterTable(ctx context.Context, ident ast.Ident, specs []*ast.AlterTableSpec) (err error) {
+	return errors.Trace(alterTable(d, ctx, ident, specs))
+}
+
+// tableAlterer applies the specs of ALTER TABLE, it is implemented by the DDLs.
+type tableAlterer interface {
+	AddColumn(ctx context.Context, ti ast.Ident, spec *ast.AlterTableSpec) error
+	DropColumn(ctx context.Context, ti ast.Ident, colName model.CIStr) error
+	ModifyColumn(ctx context.Context, ident ast.Ident, spec *ast.AlterTableSpec) error
+	CreateIndex(ctx context.Context, ti ast.Ident, unique bool, indexName model.CIStr, idxColNames []*ast.IndexColName) error
+	DropIndex(ctx context.Context, ti ast.Ident, indexName model.CIStr) error
+	CreateForeignKey(ctx context.Context, ti ast.Ident, fkName model.CIStr, keys []*ast.IndexColName, refer *ast.ReferenceDef) error
+	DropForeignKey(ctx context.Context, ti ast.Ident, fkName model.CIStr) error
+	SetStatsNDV(ctx context.Context, ti ast.Ident, ndvs []*ast.StatsNDV) error
+}
+
+func alterTable(d tableAlterer, ctx context.Context, ident ast.Ident, specs []*ast.AlterTableSpec) (err error) {
 	// Now we only allow one schema changing at the same time.
 	if len(specs) != 1 {
 		return errRunMultiSchemaChanges
@@ -959,6 +1165,14 @@ func (d *ddl) AlterTable(ctx context.Context, ident ast.Ident, specs []*ast.Alte
 			err = d.DropForeignKey(ctx, ident, model.NewCIStr(spec.Name))
 		case ast.AlterTableModifyColumn:
 			err = d.ModifyColumn(ctx, ident, spec)
+		case ast.AlterTableOption:
+			for _, op := range spec.Options {
+				if op.Tp == ast.TableOptionStatsNDV {
+					if err = d.SetStatsNDV(ctx, ident, op.StatsNDVs); err != nil {
+						break
+					}
+				}
+			}
 		default:
 			// Nothing to do now.
 		}
@@ -1015,7 +1229,7 @@ func (d *ddl) AddColumn(ctx context.Context, ti ast.Ident, spec *ast.AlterTableS
 	// Ingore table constraints now, maybe return error later.
 	// We use length(t.Cols()) as the default offset firstly, later we will change the
 	// column's offset later.
-	col, _, err = d.buildColumnAndConstraint(ctx, len(t.Cols()), spec.Column)
+	col, _, err = buildColumnAndConstraint(ctx, len(t.Cols()), spec.Column, d.genGlobalID)
 	if err != nil {
 		return errors.Trace(err)
 	}
@@ -1063,11 +1277,11 @@ func (d *ddl) DropColumn(ctx context.Context, ti ast.Ident, colName model.CIStr)
 	return errors.Trace(err)
 }
 
-// Modifiable checks if the 'origin' type can be modified to 'to' type with out the need to
+// modifiable checks if the 'origin' type can be modified to 'to' type with out the need to
 // change or check existing data in the table.
 // It returns true if the two types has the same Charset and Collation, the same sign, both are
 // integer types or string types, and new Flen and Decimal must be greater than or equal to origin.
-func (d *ddl) modifiable(origin *types.FieldType, to *types.FieldType) bool {
+func modifiable(origin *types.FieldType, to *types.FieldType) bool {
 	if to.Flen > 0 && to.Flen < origin.Flen {
 		return false
 	}
@@ -1103,7 +1317,8 @@ func (d *ddl) modifiable(origin *types.FieldType, to *types.FieldType) bool {
 }
 
 // ModifyColumn does modification on an existing column, currently we only support limited kind of changes
-// that do not need to change or check data on the table.
+// that do not need to change or check data on the table, unless the table is copied to a shadow table by
+// TiDBDDLShadowCopy.
 func (d *ddl) ModifyColumn(ctx context.Context, ident ast.Ident, spec *ast.AlterTableSpec) error {
 	is := d.infoHandle.Get()
 	schema, ok := is.SchemaByName(ident.Schema)
@@ -1124,9 +1339,12 @@ func (d *ddl) ModifyColumn(ctx context.Context, ident ast.Ident, spec *ast.Alter
 		// Make sure the column definition is simple field type.
 		return errUnsupportedModifyColumn
 	}
-	d.setCharsetCollationFlenDecimal(spec.Column.Tp)
-	if !d.modifiable(&col.FieldType, spec.Column.Tp) {
-		return errUnsupportedModifyColumn
+	setCharsetCollationFlenDecimal(spec.Column.Tp)
+	if !modifiable(&col.FieldType, spec.Column.Tp) {
+		if !ctx.GetSessionVars().DDLShadowCopy || col.IsPKHandleColumn(t.Meta()) {
+			return errUnsupportedModifyColumn
+		}
+		return d.shadowCopyModifyColumn(ctx, schema, t, col, spec.Column.Tp)
 	}
 	newCol := *col
 	newCol.FieldType = *spec.Column.Tp
@@ -1141,6 +1359,36 @@ func (d *ddl) ModifyColumn(ctx context.Context, ident ast.Ident, spec *ast.Alter
 	return errors.Trace(err)
 }
 
+// SetStatsNDV sets the NDV hints of the columns and indices of the table, which only change the estimations of
+// the optimizer.
+func (d *ddl) SetStatsNDV(ctx context.Context, ti ast.Ident, ndvs []*ast.StatsNDV) error {
+	is := d.infoHandle.Get()
+	schema, ok := is.SchemaByName(ti.Schema)
+	if !ok {
+		return errors.Trace(infoschema.ErrDatabaseNotExists)
+	}
+	t, err := is.TableByName(ti.Schema, ti.Name)
+	if err != nil {
+		return errors.Trace(infoschema.ErrTableNotExists)
+	}
+	if t.Meta().IsView() {
+		return infoschema.ErrWrongObject.Gen("'%s.%s' is not BASE TABLE", ti.Schema, ti.Name)
+	}
+	// Check the names before the job is queued.
+	if err = setStatsNDVs(t.Meta().Clone(), ndvs); err != nil {
+		return errors.Trace(err)
+	}
+	job := &model.Job{
+		SchemaID: schema.ID,
+		TableID:  t.Meta().ID,
+		Type:     model.ActionSetStatsNDV,
+		Args:     []interface{}{ndvs},
+	}
+	err = d.doDDLJob(ctx, job)
+	err = d.callHookOnChanged(err)
+	return errors.Trace(err)
+}
+
 // DropTable will proceed even if some table in the list does not exists.
 func (d *ddl) DropTable(ctx context.Context, ti ast.Ident) (err error) {
 	is := d.GetInformationSchema()
@@ -1237,8 +1485,9 @@ func (d *ddl) CreateIndex(ctx context.Context, ti ast.Ident, unique bool, indexN
 	return errors.Trace(err)
 }
 
-func (d *ddl) buildFKInfo(fkName model.CIStr, keys []*ast.IndexColName, refer *ast.ReferenceDef) (*model.FKInfo, error) {
-	fkID, err := d.genGlobalID()
+func buildFKInfo(fkName model.CIStr, keys []*ast.IndexColName, refer *ast.ReferenceDef,
+	genID func() (int64, error)) (*model.FKInfo, error) {
+	fkID, err := genID()
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -1277,7 +1526,7 @@ func (d *ddl) CreateForeignKey(ctx context.Context, ti ast.Ident, fkName model.C
 		return errors.Trace(infoschema.ErrTableNotExists)
 	}
 
-	fkInfo, err := d.buildFKInfo(fkName, keys, refer)
+	fkInfo, err := buildFKInfo(fkName, keys, refer, d.genGlobalID)
 	if err != nil {
 		return errors.Trace(err)
 	}
@@ -1416,6 +1665,18 @@ func (d *ddl) setHook(h Callback) {
 	d.hook = h
 }
 
+// findIndexByName finds index in indices by name.
+func findIndexByName(indices []*model.IndexInfo, name string) *model.IndexInfo {
+	name = strings.ToLower(name)
+	for _, idx := range indices {
+		if idx.Name.L == name {
+			return idx
+		}
+	}
+
+	return nil
+}
+
 // findCol finds column in cols by name.
 func findCol(cols []*model.ColumnInfo, name string) *model.ColumnInfo {
 	name = strings.ToLower(name)
@@ -1458,7 +1719,9 @@ const (
 	codeCantRemoveAllFields   = 1090
 	codeCantDropFieldOrKey    = 1091
 	codeBlobKeyWithoutLength  = 1170
+	codeKeyDoesNotExist       = 1176
 	codeInvalidOnUpdate       = 1294
+	codeViewWrongList         = 1353
 )
 
 func init() {
@@ -1468,11 +1731,13 @@ func init() {
 		codeCantDropFieldOrKey:    mysql.ErrCantDropFieldOrKey,
 		codeInvalidOnUpdate:       mysql.ErrInvalidOnUpdate,
 		codeBlobKeyWithoutLength:  mysql.ErrBlobKeyWithoutLength,
+		codeKeyDoesNotExist:       mysql.ErrKeyDoesNotExits,
 		codeIncorrectPrefixKey:    mysql.ErrWrongSubKey,
 		codeTooLongIdent:          mysql.ErrTooLongIdent,
 		codeTooLongKey:            mysql.ErrTooLongKey,
 		codeKeyColumnDoesNotExits: mysql.ErrKeyColumnDoesNotExits,
 		codeDupKeyName:            mysql.ErrDupKeyName,
+		codeViewWrongList:         mysql.ErrViewWrongList,
 	}
 	terror.ErrClassToMySQLCodes[terror.ClassDDL] = ddlMySQLErrCodes
 }
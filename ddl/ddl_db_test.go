@@ -770,3 +770,104 @@ func (s *testDBSuite) TestTruncateTable(c *C) {
 	}
 	c.Assert(hasOldTableData, IsFalse)
 }
+
+func (s *testDBSuite) TestShadowCopyModifyColumn(c *C) {
+	defer testleak.AfterTest(c)()
+	s.tk = testkit.NewTestKit(c, s.store)
+	s.tk.MustExec("use " + s.schemaName)
+	s.tk.MustExec("create table t3 (c1 int, c2 varchar(20), c3 int, index c2 (c2), unique index c3 (c3))")
+	_, err := s.tk.Exec("alter table t3 modify column c2 bigint")
+	c.Assert(err, NotNil)
+
+	num := defaultBatchSize + 10
+	rows := make(map[int]bool)
+	for i := 0; i < num; i++ {
+		s.mustExec(c, "insert into t3 values (?, ?, ?)", i, i, i)
+		rows[i] = true
+	}
+
+	done := make(chan error, 1)
+	go backgroundExec(s.store, "set @@tidb_ddl_shadow_copy = 1; set @@tidb_ddl_shadow_copy_throttle = 1;"+
+		"alter table t3 modify column c2 bigint", done)
+
+	ticker := time.NewTicker(s.lease / 2)
+	defer ticker.Stop()
+	step := 10
+LOOP:
+	for {
+		select {
+		case err = <-done:
+			c.Assert(err, IsNil, Commentf("err:%v", errors.ErrorStack(err)))
+			break LOOP
+		case <-ticker.C:
+			// delete, update and add some rows during the copy.
+			for i := num; i < num+step; i++ {
+				n := rand.Intn(num)
+				s.mustExec(c, "delete from t3 where c1 = ?", n)
+				delete(rows, n)
+				n = rand.Intn(num)
+				s.mustExec(c, "update t3 set c2 = c2 + 1, c3 = -c3 where c1 = ?", n)
+				s.mustExec(c, "update t3 set c2 = c2 - 1, c3 = -c3 where c1 = ?", n)
+				s.mustExec(c, "insert into t3 values (?, ?, ?)", i, i, i)
+				rows[i] = true
+			}
+			num += step
+		}
+	}
+
+	values := s.showColumns(c, "t3")
+	match(c, values[1][:2], "c2", "bigint(21)")
+	s.mustExec(c, "insert into t3 values (?, ?, ?)", num, int64(1)<<40, num)
+	rows[num] = true
+	matchRows(c, s.mustQuery(c, "select count(*) from t3"), [][]interface{}{{len(rows)}})
+	matchRows(c, s.mustQuery(c, "select count(*) from t3 where c2 = c1 and c3 = c1"), [][]interface{}{{len(rows) - 1}})
+	matchRows(c, s.mustQuery(c, "select c1 from t3 where c2 = ?", int64(1)<<40), [][]interface{}{{num}})
+	for i := range rows {
+		if i < num {
+			matchRows(c, s.mustQuery(c, "select c1 from t3 where c2 = ?", i), [][]interface{}{{i}})
+			break
+		}
+	}
+	_, err = s.tk.Exec("insert into t3 values (?, ?, ?)", num+1, num+1, num)
+	c.Assert(terror.ErrorEqual(err, kv.ErrKeyExists), IsTrue, Commentf("err:%v", err))
+
+	// The job is rolled back if a value can't be converted, and the table is kept.
+	s.tk.MustExec("create table t4 (c1 int, c2 varchar(20))")
+	s.tk.MustExec("insert into t4 values (1, '1'), (2, 'x')")
+	s.tk.MustExec("set @@tidb_ddl_shadow_copy = 1")
+	_, err = s.tk.Exec("alter table t4 modify column c2 int")
+	c.Assert(err, NotNil)
+	values = s.showColumns(c, "t4")
+	match(c, values[1][:2], "c2", "varchar(20)")
+	s.tk.MustExec("insert into t4 values (3, '3')")
+	matchRows(c, s.mustQuery(c, "select c1 from t4 where c2 = 'x'"), [][]interface{}{{2}})
+	s.tk.MustExec("delete from t4 where c1 = 2")
+	s.tk.MustExec("alter table t4 modify column c2 int")
+	matchRows(c, s.mustQuery(c, "select c1, c2 + 1 from t4"), [][]interface{}{{1, 2}, {3, 4}})
+	s.tk.MustExec("set @@tidb_ddl_shadow_copy = 0")
+
+	// The statements don't fail because of the shadow table, the rows which can't be copied roll the job back.
+	s.tk.MustExec("create table t5 (c1 int, c2 varchar(20), unique index c2 (c2))")
+	for i := 0; i < defaultBatchSize; i++ {
+		s.mustExec(c, "insert into t5 values (?, ?)", i, i)
+	}
+	go backgroundExec(s.store, "set @@tidb_ddl_shadow_copy = 1; set @@tidb_ddl_shadow_copy_throttle = 50;"+
+		"alter table t5 modify column c2 int", done)
+	for i := 0; i < 1000; i++ {
+		shadow := s.testGetTable(c, "t5").Meta().Shadow
+		if shadow != nil && shadow.State == model.StateWriteReorganization {
+			break
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+	s.tk.MustExec("insert into t5 values (-1, 'x')")
+	s.tk.MustExec("insert into t5 values (-2, '01')")
+	s.tk.MustExec("update t5 set c2 = 'y' where c1 = 0")
+	err = <-done
+	c.Assert(err, NotNil)
+	values = s.showColumns(c, "t5")
+	match(c, values[1][:2], "c2", "varchar(20)")
+	matchRows(c, s.mustQuery(c, "select count(*) from t5"), [][]interface{}{{defaultBatchSize + 2}})
+	matchRows(c, s.mustQuery(c, "select c1 from t5 where c2 in ('x', 'y', '01') order by c1"),
+		[][]interface{}{{-2}, {-1}, {0}})
+}
@@ -187,11 +187,23 @@ func (d *ddl) finishDDLJob(t *meta.Meta, job *model.Job) error {
 	if err != nil {
 		return errors.Trace(err)
 	}
+	// The reorganization of the job is finished too.
+	if err = t.RemoveDDLReorgHandle(job); err != nil {
+		return errors.Trace(err)
+	}
 	switch job.Type {
 	case model.ActionDropSchema, model.ActionDropTable, model.ActionTruncateTable:
 		if err = d.prepareBgJob(t, job); err != nil {
 			return errors.Trace(err)
 		}
+	case model.ActionShadowCopy:
+		// The data of the table replaced by the shadow table is deleted, the shadow table of a rolled back job
+		// has been deleted.
+		if job.State == model.JobDone {
+			if err = d.prepareBgJob(t, job); err != nil {
+				return errors.Trace(err)
+			}
+		}
 	}
 
 	err = t.AddHistoryDDLJob(job)
@@ -275,7 +287,7 @@ func (d *ddl) handleDDLJobQueue() error {
 
 			// If running job meets error, we will save this error in job Error
 			// and retry later if the job is not cancelled.
-			d.runDDLJob(t, job)
+			d.runDDLJob(txn, t, job)
 			if job.IsFinished() {
 				binloginfo.SetDDLBinlog(txn, job.ID, job.Query)
 				err = d.finishDDLJob(t, job)
@@ -325,7 +337,7 @@ func chooseLeaseTime(n1 time.Duration, n2 time.Duration) time.Duration {
 }
 
 // runDDLJob runs a DDL job.
-func (d *ddl) runDDLJob(t *meta.Meta, job *model.Job) {
+func (d *ddl) runDDLJob(txn kv.Transaction, t *meta.Meta, job *model.Job) {
 	log.Infof("[ddl] run DDL job %s", job)
 	if job.IsFinished() {
 		return
@@ -343,6 +355,8 @@ func (d *ddl) runDDLJob(t *meta.Meta, job *model.Job) {
 		err = d.onDropSchema(t, job)
 	case model.ActionCreateTable:
 		err = d.onCreateTable(t, job)
+	case model.ActionCreateView:
+		err = d.onCreateView(t, job)
 	case model.ActionDropTable:
 		err = d.onDropTable(t, job)
 	case model.ActionAddColumn:
@@ -361,6 +375,10 @@ func (d *ddl) runDDLJob(t *meta.Meta, job *model.Job) {
 		err = d.onDropForeignKey(t, job)
 	case model.ActionTruncateTable:
 		err = d.onTruncateTable(t, job)
+	case model.ActionSetStatsNDV:
+		err = d.onSetStatsNDV(t, job)
+	case model.ActionShadowCopy:
+		err = d.onShadowCopy(txn, t, job)
 	default:
 		// Invalid job, cancel it.
 		job.State = model.JobCancelled
@@ -416,8 +434,8 @@ func updateSchemaVersion(t *meta.Meta, job *model.Job) (int64, error) {
 		Type:     job.Type,
 		SchemaID: job.SchemaID,
 	}
-	if job.Type == model.ActionTruncateTable {
-		// Truncate table has two table ID, should be handled differently.
+	if job.Type == model.ActionTruncateTable || job.Type == model.ActionShadowCopy && job.SchemaState == model.StatePublic {
+		// Truncate table and the swap of the shadow table have two table ID, should be handled differently.
 		err = job.DecodeArgs(&diff.TableID)
 		if err != nil {
 			return 0, errors.Trace(err)
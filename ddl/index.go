@@ -15,6 +15,7 @@ package ddl
 
 import (
 	"math"
+	"sync"
 	"time"
 
 	"github.com/juju/errors"
@@ -369,42 +370,158 @@ func fetchRowColVals(txn kv.Transaction, t table.Table, handle int64, indexInfo
 const defaultBatchCnt = 1024
 const defaultSmallBatchCnt = 128
 
+const defaultReorgWorkerCnt = 8
+
 // How to add index in reorganization state?
 //  1. Generate a snapshot with special version.
-//  2. Traverse the snapshot, get every row in the table.
-//  3. For one row, if the row has been already deleted, skip to next row.
-//  4. If not deleted, check whether index has existed, if existed, skip to next row.
-//  5. If index doesn't exist, create the index and then continue to handle next row.
+//  2. Split the handles of the table into ranges by the regions, backfill the ranges by the workers in parallel.
+//  3. Traverse the snapshot, get every row in the range.
+//  4. For one row, if the row has been already deleted, skip to next row.
+//  5. If not deleted, check whether index has existed, if existed, skip to next row.
+//  6. If index doesn't exist, create the index and then continue to handle next row.
 func (d *ddl) addTableIndex(t table.Table, indexInfo *model.IndexInfo, reorgInfo *reorgInfo, job *model.Job) error {
-	seekHandle := reorgInfo.Handle
-	version := reorgInfo.SnapshotVer
-	count := job.GetRowCount()
+	ranges, err := d.getReorgRanges(t, reorgInfo)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	state := &backfillState{job: job}
+	taskCh := make(chan int, len(ranges))
+	for i, r := range ranges {
+		state.count += r.RowCount
+		if !r.Done {
+			taskCh <- i
+		}
+	}
+	close(taskCh)
+	job.SetRowCount(state.count)
+
+	workerCnt := len(taskCh)
+	if workerCnt > defaultReorgWorkerCnt {
+		workerCnt = defaultReorgWorkerCnt
+	}
+	kvIdx := tables.NewIndex(t.Meta(), indexInfo)
+	var wg sync.WaitGroup
+	for i := 0; i < workerCnt; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range taskCh {
+				err1 := d.backfillIndexRange(t, kvIdx, reorgInfo, idx, ranges[idx], state)
+				if err1 != nil {
+					state.setErr(err1)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Trace(state.err)
+}
 
-	for {
+// backfillState is shared by the workers which backfill the index on the handle ranges in parallel.
+type backfillState struct {
+	sync.Mutex
+	job   *model.Job
+	count int64
+	// err is the first error of the workers, the other workers stop when it's set.
+	err error
+}
+
+func (s *backfillState) addRowCount(n int64) int64 {
+	s.Lock()
+	defer s.Unlock()
+	s.count += n
+	s.job.SetRowCount(s.count)
+	return s.count
+}
+
+func (s *backfillState) setErr(err error) {
+	s.Lock()
+	defer s.Unlock()
+	if s.err == nil {
+		s.err = err
+	}
+}
+
+func (s *backfillState) stopped() bool {
+	s.Lock()
+	defer s.Unlock()
+	return s.err != nil
+}
+
+// backfillIndexRange backfills the index on the idx-th handle range of the reorganization,
+// the checkpoint of the range is saved in the same transaction with each batch of the index data.
+func (d *ddl) backfillIndexRange(t table.Table, kvIdx table.Index, reorgInfo *reorgInfo, idx int, r *model.ReorgRange,
+	state *backfillState) error {
+	for !r.Done {
 		startTime := time.Now()
-		handles, err := d.getSnapshotRows(t, version, seekHandle)
+		handles, err := d.getSnapshotRows(t, reorgInfo.SnapshotVer, r.Handle, r.EndHandle)
 		if err != nil {
 			return errors.Trace(err)
-		} else if len(handles) == 0 {
+		}
+		exhausted := len(handles) < defaultBatchCnt
+		if len(handles) == 0 {
+			next := *r
+			next.Done = true
+			next.UpdateTS = time.Now().UnixNano()
+			err = kv.RunInNewTxn(d.store, true, func(txn kv.Transaction) error {
+				return errors.Trace(meta.NewMeta(txn).UpdateDDLReorgRange(reorgInfo.Job, idx, &next))
+			})
+			if err != nil {
+				return errors.Trace(err)
+			}
+			*r = next
 			return nil
 		}
 
-		count += int64(len(handles))
-		seekHandle = handles[len(handles)-1] + 1
-		err = d.backfillTableIndex(t, indexInfo, handles, reorgInfo)
-		sub := time.Since(startTime).Seconds()
-		if err != nil {
-			log.Warnf("[ddl] added index for %v rows failed, take time %v", count, sub)
-			return errors.Trace(err)
+		var count int64
+		for len(handles) > 0 {
+			if state.stopped() {
+				return nil
+			}
+			endIdx := int(math.Min(float64(defaultSmallBatchCnt), float64(len(handles))))
+			batch := handles[:endIdx]
+			last := batch[len(batch)-1]
+			next := *r
+			next.RowCount += int64(len(batch))
+			next.Done = last >= r.EndHandle || (exhausted && endIdx == len(handles))
+			next.Handle = last
+			if last < math.MaxInt64 {
+				next.Handle = last + 1
+			}
+			next.UpdateTS = time.Now().UnixNano()
+			err = kv.RunInNewTxn(d.store, true, func(txn kv.Transaction) error {
+				if err1 := d.isReorgRunnable(txn, ddlJobFlag); err1 != nil {
+					return errors.Trace(err1)
+				}
+				if err1 := d.backfillIndexInTxn(t, kvIdx, batch, txn); err1 != nil {
+					return errors.Trace(err1)
+				}
+				// Update the checkpoint of the range.
+				return errors.Trace(meta.NewMeta(txn).UpdateDDLReorgRange(reorgInfo.Job, idx, &next))
+			})
+			if err != nil {
+				log.Warnf("[ddl] added index for handle range %d %v failed, take time %v", idx, r,
+					time.Since(startTime).Seconds())
+				return errors.Trace(err)
+			}
+
+			*r = next
+			count = state.addRowCount(int64(len(batch)))
+			handles = handles[endIdx:]
 		}
 
-		job.SetRowCount(count)
+		sub := time.Since(startTime).Seconds()
 		batchHandleDataHistogram.WithLabelValues(batchAddIdx).Observe(sub)
-		log.Infof("[ddl] added index for %v rows, take time %v", count, sub)
+		log.Infof("[ddl] added index for handle range %d %v, take time %v, added index for %v rows in total",
+			idx, r, sub, count)
 	}
+	return nil
 }
 
-func (d *ddl) getSnapshotRows(t table.Table, version uint64, seekHandle int64) ([]int64, error) {
+// getSnapshotRows gets at most defaultBatchCnt handles of the rows from seekHandle to endHandle in the snapshot.
+func (d *ddl) getSnapshotRows(t table.Table, version uint64, seekHandle, endHandle int64) ([]int64, error) {
 	ver := kv.Version{Ver: version}
 	snap, err := d.store.GetSnapshot(ver)
 	if err != nil {
@@ -429,6 +546,9 @@ func (d *ddl) getSnapshotRows(t table.Table, version uint64, seekHandle int64) (
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
+		if handle > endHandle {
+			break
+		}
 
 		handles = append(handles, handle)
 		if len(handles) == defaultBatchCnt {
@@ -449,65 +569,36 @@ func (d *ddl) getSnapshotRows(t table.Table, version uint64, seekHandle int64) (
 
 // backfillIndexInTxn deals with a part of backfilling index data in a Transaction.
 // This part of the index data rows is defaultSmallBatchCnt.
-func (d *ddl) backfillIndexInTxn(t table.Table, kvIdx table.Index, handles []int64, txn kv.Transaction) (int64, error) {
-	nextHandle := handles[0]
+func (d *ddl) backfillIndexInTxn(t table.Table, kvIdx table.Index, handles []int64, txn kv.Transaction) error {
 	for _, handle := range handles {
 		log.Debug("[ddl] backfill index...", handle)
 		rowKey, vals, err := fetchRowColVals(txn, t, handle, kvIdx.Meta())
 		if terror.ErrorEqual(err, kv.ErrNotExist) {
 			// Row doesn't exist, skip it.
-			nextHandle = handle
 			continue
 		}
 		if err != nil {
-			return 0, errors.Trace(err)
+			return errors.Trace(err)
 		}
 
 		exist, _, err := kvIdx.Exist(txn, vals, handle)
 		if err != nil {
-			return 0, errors.Trace(err)
+			return errors.Trace(err)
 		} else if exist {
 			// Index already exists, skip it.
-			nextHandle = handle
 			continue
 		}
 		err = txn.LockKeys(rowKey)
 		if err != nil {
-			return 0, errors.Trace(err)
+			return errors.Trace(err)
 		}
 
 		// Create the index.
 		_, err = kvIdx.Create(txn, vals, handle)
-		if err != nil {
-			return 0, errors.Trace(err)
-		}
-		nextHandle = handle
-	}
-	return nextHandle, nil
-}
-
-func (d *ddl) backfillTableIndex(t table.Table, indexInfo *model.IndexInfo, handles []int64, reorgInfo *reorgInfo) error {
-	kvIdx := tables.NewIndex(t.Meta(), indexInfo)
-	for len(handles) > 0 {
-		endIdx := int(math.Min(float64(defaultSmallBatchCnt), float64(len(handles))))
-		err := kv.RunInNewTxn(d.store, true, func(txn kv.Transaction) error {
-			if err1 := d.isReorgRunnable(txn, ddlJobFlag); err1 != nil {
-				return errors.Trace(err1)
-			}
-			nextHandle, err1 := d.backfillIndexInTxn(t, kvIdx, handles[:endIdx], txn)
-			if err1 != nil {
-				return errors.Trace(err1)
-			}
-			// Update reorg next handle.
-			return errors.Trace(reorgInfo.UpdateHandle(txn, nextHandle))
-		})
 		if err != nil {
 			return errors.Trace(err)
 		}
-
-		handles = handles[endIdx:]
 	}
-
 	return nil
 }
 
@@ -20,9 +20,11 @@ import (
 	"github.com/pingcap/tidb/ast"
 	"github.com/pingcap/tidb/context"
 	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/meta"
 	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/table"
 	"github.com/pingcap/tidb/table/tables"
+	"github.com/pingcap/tidb/tablecodec"
 	"github.com/pingcap/tidb/util/testleak"
 	"github.com/pingcap/tidb/util/types"
 )
@@ -767,3 +769,80 @@ func (s *testIndexSuite) TestAddIndexWithNullColumn(c *C) {
 	d.close()
 	s.d.start()
 }
+
+// testSplitStore splits the records of a table into regions by the handles.
+type testSplitStore struct {
+	kv.Storage
+	tableID int64
+	handles []int64
+	called  bool
+}
+
+// SplitKeys implements the kv.RegionSplitter interface.
+func (s *testSplitStore) SplitKeys(startKey, endKey kv.Key) ([]kv.Key, error) {
+	s.called = true
+	keys := []kv.Key{tablecodec.EncodeTableIndexPrefix(s.tableID, 1)}
+	for _, h := range s.handles {
+		key := tablecodec.EncodeRowKeyWithHandle(s.tableID, h)
+		if key.Cmp(startKey) > 0 && key.Cmp(endKey) < 0 {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func (s *testIndexSuite) TestAddIndexByRanges(c *C) {
+	defer testleak.AfterTest(c)()
+	tblInfo := testTableInfo(c, s.d, "t_ranges", 3)
+	store := &testSplitStore{Storage: s.store, tableID: tblInfo.ID, handles: []int64{100, 200, 250}}
+	d := newDDL(store, nil, nil, testLease)
+	ctx := testNewContext(c, d)
+
+	_, err := ctx.GetTxn(true)
+	c.Assert(err, IsNil)
+	testCreateTable(c, ctx, d, s.dbInfo, tblInfo)
+	t := testGetTable(c, d, s.dbInfo.ID, tblInfo.ID)
+
+	num := 300
+	handles := make([]int64, 0, num)
+	for i := 0; i < num; i++ {
+		h, err1 := t.AddRecord(ctx, types.MakeDatums(i, i, i))
+		c.Assert(err1, IsNil)
+		handles = append(handles, h)
+	}
+	err = ctx.CommitTxn()
+	c.Assert(err, IsNil)
+
+	// Use local ddl for the split store.
+	s.d.close()
+	d.close()
+	d.start()
+
+	job := testCreateIndex(c, ctx, d, s.dbInfo, tblInfo, false, "c2", "c2")
+	testCheckJobDone(c, d, job, true)
+	c.Assert(store.called, IsTrue)
+
+	t = testGetTable(c, d, s.dbInfo.ID, tblInfo.ID)
+	index := tables.FindIndexByColName(t, "c2")
+	c.Assert(index, NotNil)
+	txn, err := ctx.GetTxn(true)
+	c.Assert(err, IsNil)
+	for i, h := range handles {
+		exist, _, err1 := index.Exist(txn, types.MakeDatums(i), h)
+		c.Assert(err1, IsNil)
+		c.Assert(exist, IsTrue, Commentf("handle %d", h))
+	}
+
+	// The ranges are removed with the finished job.
+	ranges, err := meta.NewMeta(txn).GetDDLReorgRanges(job)
+	c.Assert(err, IsNil)
+	c.Assert(ranges, HasLen, 0)
+
+	job = testDropTable(c, ctx, d, s.dbInfo, tblInfo)
+	testCheckJobDone(c, d, job, false)
+	err = ctx.CommitTxn()
+	c.Assert(err, IsNil)
+
+	d.close()
+	s.d.start()
+}
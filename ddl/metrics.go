@@ -40,6 +40,7 @@ var (
 	batchAddCol              = "batch_add_col"
 	batchAddIdx              = "batch_add_idx"
 	batchDelData             = "batch_del_data"
+	batchCopyShadow          = "batch_copy_shadow"
 	batchHandleDataHistogram = prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
 			Namespace: "tidb",
@@ -0,0 +1,144 @@
+// Copyright 2015 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ddl
+
+import (
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/infoschema"
+	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/parser"
+)
+
+// mockDBName is the database of the mocked tables whose CREATE TABLE statements don't specify one.
+const mockDBName = "test"
+
+// mockIDAllocator allocates the IDs of the mocked schema objects sequentially.
+type mockIDAllocator struct {
+	id int64
+}
+
+func (a *mockIDAllocator) genID() (int64, error) {
+	a.id++
+	return a.id, nil
+}
+
+// MockTableInfo builds the TableInfo of a CREATE TABLE statement without a storage, the IDs of the table,
+// its columns and indices are allocated sequentially after lastID. It only serves for test.
+func MockTableInfo(ctx context.Context, stmt *ast.CreateTableStmt, lastID int64) (*model.TableInfo, error) {
+	alloc := &mockIDAllocator{id: lastID}
+	return mockTableInfo(ctx, stmt.Table.Name, stmt.Cols, stmt.Constraints, stmt.Options, alloc)
+}
+
+func mockTableInfo(ctx context.Context, name model.CIStr, colDefs []*ast.ColumnDef, constraints []*ast.Constraint,
+	options []*ast.TableOption, alloc *mockIDAllocator) (*model.TableInfo, error) {
+	if err := checkTooLongTable(name); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if err := checkDuplicateColumn(colDefs); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if err := checkTooLongColumn(colDefs); err != nil {
+		return nil, errors.Trace(err)
+	}
+	cols, newConstraints, err := buildColumnsAndConstraints(ctx, colDefs, constraints, alloc.genID)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if err = checkConstraintNames(newConstraints); err != nil {
+		return nil, errors.Trace(err)
+	}
+	tbInfo, err := buildTableInfo(name, cols, newConstraints, alloc.genID)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if err = handleTableOptions(options, tbInfo); err != nil {
+		return nil, errors.Trace(err)
+	}
+	tbInfo.State = model.StatePublic
+	return tbInfo, nil
+}
+
+// MockInfoSchema builds an InfoSchema from CREATE DATABASE and CREATE TABLE statements without a storage,
+// so the planner and expressions can be tested without a running cluster. The tables whose statements
+// don't specify a database are put in the "test" database. It only serves for test.
+func MockInfoSchema(ctx context.Context, sql string) (infoschema.InfoSchema, error) {
+	stmts, err := parser.New().Parse(sql, "", "")
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	alloc := &mockIDAllocator{}
+	var dbs []*model.DBInfo
+	getDB := func(name model.CIStr) *model.DBInfo {
+		for _, db := range dbs {
+			if db.Name.L == name.L {
+				return db
+			}
+		}
+		return nil
+	}
+	createDB := func(name model.CIStr) *model.DBInfo {
+		id, _ := alloc.genID()
+		db := &model.DBInfo{ID: id, Name: name, State: model.StatePublic}
+		db.Charset, db.Collate = getDefaultCharsetAndCollate()
+		dbs = append(dbs, db)
+		return db
+	}
+	for _, stmt := range stmts {
+		switch x := stmt.(type) {
+		case *ast.CreateDatabaseStmt:
+			name := model.NewCIStr(x.Name)
+			if getDB(name) != nil {
+				if x.IfNotExists {
+					continue
+				}
+				return nil, errors.Trace(infoschema.ErrDatabaseExists)
+			}
+			createDB(name)
+		case *ast.CreateTableStmt:
+			name := x.Table.Schema
+			if name.L == "" {
+				name = model.NewCIStr(mockDBName)
+			}
+			db := getDB(name)
+			if db == nil {
+				db = createDB(name)
+			}
+			if tableExists(db, x.Table.Name) {
+				if x.IfNotExists {
+					continue
+				}
+				return nil, errors.Trace(infoschema.ErrTableExists)
+			}
+			tbInfo, err := mockTableInfo(ctx, x.Table.Name, x.Cols, x.Constraints, x.Options, alloc)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			db.Tables = append(db.Tables, tbInfo)
+		default:
+			return nil, errors.Errorf("unsupported statement %T for mocking the schema", stmt)
+		}
+	}
+	return infoschema.MockInfoSchemaFromDBs(dbs), nil
+}
+
+func tableExists(db *model.DBInfo, name model.CIStr) bool {
+	for _, tbl := range db.Tables {
+		if tbl.Name.L == name.L {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+// Copyright 2015 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ddl
+
+import (
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/infoschema"
+	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/util/mock"
+	"github.com/pingcap/tidb/util/testleak"
+)
+
+var _ = Suite(&testMockSuite{})
+
+type testMockSuite struct {
+}
+
+func (s *testMockSuite) TestMockInfoSchema(c *C) {
+	defer testleak.AfterTest(c)()
+	ctx := mock.NewContext()
+	is, err := MockInfoSchema(ctx, `create table t (a int primary key, b varchar(10) default 'x', c int, index c_b (c, b));
+		create database db1;
+		create table db1.t1 (a bigint, b int, unique key (b)) comment 'tbl';
+		create table if not exists t (a int);`)
+	c.Assert(err, IsNil)
+	c.Assert(is.SchemaExists(model.NewCIStr("test")), IsTrue)
+	c.Assert(is.SchemaExists(model.NewCIStr("db1")), IsTrue)
+
+	tbl, err := is.TableByName(model.NewCIStr("test"), model.NewCIStr("t"))
+	c.Assert(err, IsNil)
+	tblInfo := tbl.Meta()
+	c.Assert(tblInfo.State, Equals, model.StatePublic)
+	c.Assert(tblInfo.PKIsHandle, IsTrue)
+	c.Assert(tblInfo.Columns, HasLen, 3)
+	c.Assert(mysql.HasPriKeyFlag(tblInfo.Columns[0].Flag), IsTrue)
+	c.Assert(tblInfo.Columns[1].DefaultValue, Equals, "x")
+	c.Assert(tblInfo.Indices, HasLen, 1)
+	c.Assert(tblInfo.Indices[0].Name.L, Equals, "c_b")
+	c.Assert(tblInfo.Indices[0].Columns[1].Offset, Equals, 1)
+	found, ok := is.TableByID(tblInfo.ID)
+	c.Assert(ok, IsTrue)
+	c.Assert(found.Meta().Name.L, Equals, "t")
+
+	tbl, err = is.TableByName(model.NewCIStr("db1"), model.NewCIStr("t1"))
+	c.Assert(err, IsNil)
+	tblInfo = tbl.Meta()
+	c.Assert(tblInfo.PKIsHandle, IsFalse)
+	c.Assert(tblInfo.Comment, Equals, "tbl")
+	c.Assert(tblInfo.Indices, HasLen, 1)
+	c.Assert(tblInfo.Indices[0].Unique, IsTrue)
+
+	// The IDs of all the schema objects are unique.
+	ids := make(map[int64]struct{})
+	for _, db := range is.AllSchemas() {
+		ids[db.ID] = struct{}{}
+		for _, tblInfo := range db.Tables {
+			ids[tblInfo.ID] = struct{}{}
+			for _, col := range tblInfo.Columns {
+				ids[col.ID] = struct{}{}
+			}
+			for _, idx := range tblInfo.Indices {
+				ids[idx.ID] = struct{}{}
+			}
+		}
+	}
+	c.Assert(ids, HasLen, 11)
+
+	// The schema can be dumped and loaded back as a fixture.
+	data, err := infoschema.DumpSchema(is)
+	c.Assert(err, IsNil)
+	loaded, err := infoschema.MockInfoSchemaFromJSON(data)
+	c.Assert(err, IsNil)
+	tbl, err = loaded.TableByName(model.NewCIStr("test"), model.NewCIStr("t"))
+	c.Assert(err, IsNil)
+	c.Assert(tbl.Meta().Indices[0].Name.L, Equals, "c_b")
+	c.Assert(tbl.Meta().Columns, HasLen, 3)
+	redumped, err := infoschema.DumpSchema(loaded)
+	c.Assert(err, IsNil)
+	c.Assert(string(redumped), Equals, string(data))
+
+	_, err = MockInfoSchema(ctx, "create table t (a int); create table t (b int)")
+	c.Assert(infoschema.ErrTableExists.Equal(err), IsTrue)
+	_, err = MockInfoSchema(ctx, "create table t (a int, a int)")
+	c.Assert(err, NotNil)
+	_, err = MockInfoSchema(ctx, "drop table t")
+	c.Assert(err, NotNil)
+}
@@ -15,6 +15,7 @@ package ddl
 
 import (
 	"fmt"
+	"math"
 	"sync"
 	"time"
 
@@ -26,6 +27,8 @@ import (
 	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/table"
+	"github.com/pingcap/tidb/tablecodec"
 	"github.com/pingcap/tidb/terror"
 )
 
@@ -274,6 +277,8 @@ type reorgInfo struct {
 	Handle int64
 	d      *ddl
 	first  bool
+	// progress is the progress saved with the last reorganization handle.
+	progress *model.ReorgProgress
 }
 
 func (d *ddl) getReorgInfo(t *meta.Meta, job *model.Job) (*reorgInfo, error) {
@@ -296,11 +301,31 @@ func (d *ddl) getReorgInfo(t *meta.Meta, job *model.Job) (*reorgInfo, error) {
 		}
 
 		job.SnapshotVer = ver.Ver
+		info.progress, err = d.newReorgProgress(t, job, ver)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		// The progress is saved with the job snapshot version.
+		err = t.UpdateDDLReorgProgress(job, info.progress)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
 	} else {
 		info.Handle, err = t.GetDDLReorgHandle(job)
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
+		info.progress, err = t.GetDDLReorgProgress(job)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if info.progress == nil {
+			// The job was started by a server which doesn't save the progress.
+			info.progress, err = d.newReorgProgress(t, job, kv.Version{Ver: job.SnapshotVer})
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+		}
 	}
 
 	if info.Handle > 0 {
@@ -311,7 +336,114 @@ func (d *ddl) getReorgInfo(t *meta.Meta, job *model.Job) (*reorgInfo, error) {
 	return info, errors.Trace(err)
 }
 
-func (r *reorgInfo) UpdateHandle(txn kv.Transaction, handle int64) error {
+// newReorgProgress creates the progress of a reorganization job which starts from the snapshot version.
+// The handle range is from the first handle in the snapshot to the auto ID of the table.
+func (d *ddl) newReorgProgress(t *meta.Meta, job *model.Job, ver kv.Version) (*model.ReorgProgress, error) {
+	progress := &model.ReorgProgress{
+		RowCount: job.GetRowCount(),
+		StartTS:  time.Now().UnixNano(),
+	}
+	progress.UpdateTS = progress.StartTS
+	snap, err := d.store.GetSnapshot(ver)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	prefix := tablecodec.GenTableRecordPrefix(job.TableID)
+	it, err := snap.Seek(prefix)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	defer it.Close()
+	if it.Valid() && it.Key().HasPrefix(prefix) {
+		progress.StartHandle, err = tablecodec.DecodeRowKey(it.Key())
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		progress.Handle = progress.StartHandle
+	}
+	progress.EndHandle, err = t.GetAutoTableID(job.SchemaID, job.TableID)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if progress.EndHandle < progress.StartHandle {
+		progress.EndHandle = progress.StartHandle
+	}
+	return progress, nil
+}
+
+// UpdateHandle saves the reorganization handle and the progress in the same transaction,
+// rowCount is the total number of the processed rows.
+func (r *reorgInfo) UpdateHandle(txn kv.Transaction, handle int64, rowCount int64) error {
 	t := meta.NewMeta(txn)
-	return errors.Trace(t.UpdateDDLReorgHandle(r.Job, handle))
+	err := t.UpdateDDLReorgHandle(r.Job, handle)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	progress := *r.progress
+	progress.Handle = handle
+	progress.RowCount = rowCount
+	progress.UpdateTS = time.Now().UnixNano()
+	err = t.UpdateDDLReorgProgress(r.Job, &progress)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	r.progress = &progress
+	return nil
+}
+
+// getReorgRanges gets the handle ranges of the reorganization which are processed in parallel.
+// The ranges are split by the regions of the records from the reorganization handle at the first time,
+// and they are saved with their checkpoints, so the reorganization can be resumed from them.
+func (d *ddl) getReorgRanges(t table.Table, reorgInfo *reorgInfo) ([]*model.ReorgRange, error) {
+	var ranges []*model.ReorgRange
+	err := kv.RunInNewTxn(d.store, true, func(txn kv.Transaction) error {
+		if err1 := d.isReorgRunnable(txn, ddlJobFlag); err1 != nil {
+			return errors.Trace(err1)
+		}
+		m := meta.NewMeta(txn)
+		var err1 error
+		ranges, err1 = m.GetDDLReorgRanges(reorgInfo.Job)
+		if err1 != nil || len(ranges) > 0 {
+			return errors.Trace(err1)
+		}
+
+		var keys []kv.Key
+		if splitter, ok := d.store.(kv.RegionSplitter); ok {
+			keys, err1 = splitter.SplitKeys(t.RecordKey(reorgInfo.Handle), t.RecordPrefix().PrefixNext())
+			if err1 != nil {
+				return errors.Trace(err1)
+			}
+		}
+		ranges = splitHandleRanges(t, reorgInfo.Handle, keys)
+		for i, r := range ranges {
+			if err1 = m.UpdateDDLReorgRange(reorgInfo.Job, i, r); err1 != nil {
+				return errors.Trace(err1)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	log.Infof("[ddl] job %d reorganizes %d handle ranges", reorgInfo.Job.ID, len(ranges))
+	return ranges, nil
+}
+
+// splitHandleRanges splits the handles from startHandle into ranges by the split keys in ascending order.
+// The keys which aren't the record keys of the table after startHandle are ignored.
+func splitHandleRanges(t table.Table, startHandle int64, keys []kv.Key) []*model.ReorgRange {
+	ranges := make([]*model.ReorgRange, 0, len(keys)+1)
+	for _, key := range keys {
+		if !key.HasPrefix(t.RecordPrefix()) {
+			continue
+		}
+		handle, err := tablecodec.DecodeRowKey(key)
+		if err != nil || handle <= startHandle {
+			// The region boundary may be in the middle of a record key.
+			continue
+		}
+		ranges = append(ranges, &model.ReorgRange{StartHandle: startHandle, EndHandle: handle - 1, Handle: startHandle})
+		startHandle = handle
+	}
+	return append(ranges, &model.ReorgRange{StartHandle: startHandle, EndHandle: math.MaxInt64, Handle: startHandle})
 }
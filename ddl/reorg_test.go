@@ -14,12 +14,15 @@
 package ddl
 
 import (
+	"math"
 	"time"
 
 	. "github.com/pingcap/check"
 	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/meta"
 	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/table/tables"
+	"github.com/pingcap/tidb/tablecodec"
 	"github.com/pingcap/tidb/util/testleak"
 	"github.com/pingcap/tidb/util/types"
 )
@@ -94,7 +97,7 @@ func (s *testDDLSuite) TestReorg(c *C) {
 		var err1 error
 		info, err1 = d.getReorgInfo(t, job)
 		c.Assert(err1, IsNil)
-		err1 = info.UpdateHandle(txn, 1)
+		err1 = info.UpdateHandle(txn, 1, 1)
 		c.Assert(err1, IsNil)
 
 		return nil
@@ -107,6 +110,8 @@ func (s *testDDLSuite) TestReorg(c *C) {
 		info, err1 = d.getReorgInfo(t, job)
 		c.Assert(err1, IsNil)
 		c.Assert(info.Handle, Greater, int64(0))
+		c.Assert(info.progress.Handle, Equals, int64(1))
+		c.Assert(info.progress.RowCount, Equals, int64(1))
 		return nil
 	})
 	c.Assert(err, IsNil)
@@ -164,3 +169,28 @@ func (s *testDDLSuite) TestReorgOwner(c *C) {
 	})
 	c.Assert(err, IsNil)
 }
+
+func (s *testDDLSuite) TestSplitHandleRanges(c *C) {
+	defer testleak.AfterTest(c)()
+	tblInfo := &model.TableInfo{ID: 1}
+	t := tables.MockTableFromMeta(tblInfo)
+
+	ranges := splitHandleRanges(t, 0, nil)
+	c.Assert(ranges, DeepEquals, []*model.ReorgRange{{StartHandle: 0, EndHandle: math.MaxInt64, Handle: 0}})
+
+	keys := []kv.Key{
+		tablecodec.EncodeTableIndexPrefix(tblInfo.ID, 1),
+		t.RecordKey(5),
+		// A region boundary in the middle of a record key.
+		t.RecordPrefix(),
+		t.RecordKey(10),
+		t.RecordKey(100),
+		tablecodec.GenTableRecordPrefix(tblInfo.ID + 1),
+	}
+	ranges = splitHandleRanges(t, 8, keys)
+	c.Assert(ranges, DeepEquals, []*model.ReorgRange{
+		{StartHandle: 8, EndHandle: 9, Handle: 8},
+		{StartHandle: 10, EndHandle: 99, Handle: 10},
+		{StartHandle: 100, EndHandle: math.MaxInt64, Handle: 100},
+	})
+}
@@ -0,0 +1,295 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ddl
+
+import (
+	"strings"
+
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/parser/opcode"
+	"github.com/pingcap/tidb/util/charset"
+	"github.com/pingcap/tidb/util/types"
+)
+
+// divPrecisionIncrement is the number of the decimals a division adds to its dividend, it's the default value of
+// the div_precision_increment variable of MySQL.
+const divPrecisionIncrement = 4
+
+// maxDecimalWidth is the max precision of the decimal type.
+const maxDecimalWidth = 65
+
+// resultColumnType returns the type of the column which stores the values of the expression. The type inferred for
+// the expression is enough to evaluate it, but its length and decimal are often unspecified, so they're derived from
+// the arguments the way MySQL does, otherwise the values would be truncated by the column.
+// See https://dev.mysql.com/doc/refman/5.7/en/create-table-select.html
+func resultColumnType(expr ast.ExprNode) types.FieldType {
+	tp := *expr.GetType()
+	switch x := expr.(type) {
+	case *ast.ParenthesesExpr:
+		return resultColumnType(x.Expr)
+	case *ast.ValueExpr:
+		valueColumnType(x, &tp)
+	case *ast.UnaryOperationExpr:
+		if x.Op == opcode.Minus || x.Op == opcode.Plus {
+			arg := resultColumnType(x.V)
+			if arg.Tp == tp.Tp || types.IsTypeInteger(arg.Tp) && types.IsTypeInteger(tp.Tp) {
+				tp.Flen, tp.Decimal = arg.Flen, arg.Decimal
+			}
+		}
+	case *ast.BinaryOperationExpr:
+		arithColumnType(x, &tp)
+	case *ast.AggregateFuncExpr:
+		aggColumnType(x, &tp)
+	case *ast.FuncCallExpr:
+		funcColumnType(x, &tp)
+	case *ast.FuncCastExpr:
+		if types.IsTypeChar(tp.Tp) && tp.Flen == types.UnspecifiedLength {
+			arg := resultColumnType(x.Expr)
+			tp.Tp, tp.Flen = mysql.TypeVarchar, types.DisplayLength(&arg)
+		}
+	case *ast.CaseExpr:
+		args := make([]ast.ExprNode, 0, len(x.WhenClauses)+1)
+		for _, w := range x.WhenClauses {
+			args = append(args, w.Result)
+		}
+		if x.ElseClause != nil {
+			args = append(args, x.ElseClause)
+		}
+		tp = aggColumnTypes(args)
+	}
+	return tp
+}
+
+func valueColumnType(x *ast.ValueExpr, tp *types.FieldType) {
+	d := x.GetDatum()
+	switch d.Kind() {
+	case types.KindMysqlDecimal:
+		tp.Flen, tp.Decimal = d.GetMysqlDecimal().PrecisionAndFrac()
+	case types.KindFloat64, types.KindFloat32:
+		tp.Tp, tp.Flen, tp.Decimal = mysql.TypeDouble, types.UnspecifiedLength, types.UnspecifiedLength
+	case types.KindString, types.KindBytes:
+		tp.Flen = len(d.GetString())
+	case types.KindInt64, types.KindUint64:
+		if s, err := d.ToString(); err == nil {
+			tp.Flen = len(s)
+		}
+	}
+}
+
+// integerDigits returns the number of the digits of the integer part of the values of the type, or
+// types.UnspecifiedLength if it's unknown.
+func integerDigits(tp *types.FieldType) int {
+	switch {
+	case tp.Tp == mysql.TypeNewDecimal:
+		if tp.Flen == types.UnspecifiedLength || tp.Decimal == types.UnspecifiedLength {
+			return types.UnspecifiedLength
+		}
+		return tp.Flen - tp.Decimal
+	case types.IsTypeInteger(tp.Tp):
+		return types.DisplayLength(tp)
+	}
+	return types.UnspecifiedLength
+}
+
+// decimalDigits returns the number of the decimals of the values of the type, or types.UnspecifiedLength if
+// it's unknown.
+func decimalDigits(tp *types.FieldType) int {
+	switch {
+	case tp.Tp == mysql.TypeNewDecimal:
+		return tp.Decimal
+	case types.IsTypeInteger(tp.Tp):
+		return 0
+	}
+	return types.UnspecifiedLength
+}
+
+func arithColumnType(x *ast.BinaryOperationExpr, tp *types.FieldType) {
+	if tp.Tp != mysql.TypeNewDecimal && !types.IsTypeInteger(tp.Tp) {
+		return
+	}
+	l, r := resultColumnType(x.L), resultColumnType(x.R)
+	li, ri := integerDigits(&l), integerDigits(&r)
+	ld, rd := decimalDigits(&l), decimalDigits(&r)
+	if li == types.UnspecifiedLength || ri == types.UnspecifiedLength {
+		return
+	}
+	var intPart, frac int
+	switch x.Op {
+	case opcode.Plus, opcode.Minus:
+		intPart, frac = max(li, ri)+1, max(ld, rd)
+	case opcode.Mul:
+		intPart, frac = li+ri, ld+rd
+	case opcode.Div:
+		intPart, frac = li+rd, ld+divPrecisionIncrement
+	case opcode.Mod:
+		intPart, frac = max(li, ri), max(ld, rd)
+	default:
+		return
+	}
+	if types.IsTypeInteger(tp.Tp) {
+		tp.Flen = min(intPart, mysql.GetDefaultFieldLength(mysql.TypeLonglong))
+		return
+	}
+	tp.Flen, tp.Decimal = intPart+frac, frac
+}
+
+func aggColumnType(x *ast.AggregateFuncExpr, tp *types.FieldType) {
+	if len(x.Args) == 0 {
+		return
+	}
+	arg := resultColumnType(x.Args[0])
+	name := strings.ToLower(x.F)
+	switch name {
+	case ast.AggFuncMax, ast.AggFuncMin:
+		*tp = arg
+	case ast.AggFuncSum, ast.AggFuncAvg:
+		intPart, frac := integerDigits(&arg), decimalDigits(&arg)
+		if intPart == types.UnspecifiedLength {
+			// The sum of the floats and the strings is a float.
+			tp.Tp, tp.Flen, tp.Decimal = mysql.TypeDouble, types.UnspecifiedLength, types.UnspecifiedLength
+			return
+		}
+		if name == ast.AggFuncSum {
+			// A sum adds at most 22 digits, the number of digits of the max count of the rows.
+			intPart += 22
+		} else {
+			frac += divPrecisionIncrement
+		}
+		tp.Flen, tp.Decimal = intPart+frac, frac
+	}
+}
+
+func funcColumnType(x *ast.FuncCallExpr, tp *types.FieldType) {
+	switch x.FnName.L {
+	case "ifnull", "coalesce", "greatest":
+		*tp = aggColumnTypes(x.Args)
+	case "if":
+		if len(x.Args) == 3 {
+			*tp = aggColumnTypes(x.Args[1:])
+		}
+	case "abs", "nullif":
+		if len(x.Args) > 0 {
+			if arg := resultColumnType(x.Args[0]); arg.Tp == tp.Tp {
+				tp.Flen, tp.Decimal = arg.Flen, arg.Decimal
+			}
+		}
+	case "round":
+		if len(x.Args) == 0 {
+			return
+		}
+		arg := resultColumnType(x.Args[0])
+		if arg.Tp != mysql.TypeNewDecimal {
+			// The integers and the floats are rounded to the same type.
+			*tp = arg
+			return
+		}
+		intPart, frac := integerDigits(&arg), 0
+		if len(x.Args) > 1 {
+			d, ok := x.Args[1].(*ast.ValueExpr)
+			if !ok {
+				return
+			}
+			n, err := d.GetDatum().ToInt64()
+			if err != nil {
+				return
+			}
+			frac = max(0, min(int(n), types.MaxFraction))
+		}
+		if intPart == types.UnspecifiedLength {
+			return
+		}
+		// Rounding may carry into a new integer digit.
+		tp.Tp, tp.Flen, tp.Decimal = mysql.TypeNewDecimal, intPart+frac+1, frac
+	case "concat", "concat_ws":
+		tp.Flen = 0
+		for i, arg := range x.Args {
+			argTp := resultColumnType(arg)
+			l := types.DisplayLength(&argTp)
+			if l == types.UnspecifiedLength {
+				tp.Flen = types.UnspecifiedLength
+				return
+			}
+			if x.FnName.L == "concat_ws" && i == 0 {
+				l *= max(len(x.Args)-2, 0)
+			}
+			tp.Flen += l
+		}
+	case "upper", "ucase", "lower", "lcase", "trim", "ltrim", "rtrim", "reverse", "substring", "left":
+		if len(x.Args) > 0 {
+			arg := resultColumnType(x.Args[0])
+			tp.Flen = types.DisplayLength(&arg)
+		}
+	}
+}
+
+// aggColumnTypes returns the type which holds the values of all the expressions.
+func aggColumnTypes(exprs []ast.ExprNode) types.FieldType {
+	tps := make([]*types.FieldType, 0, len(exprs))
+	for _, expr := range exprs {
+		tp := resultColumnType(expr)
+		tps = append(tps, &tp)
+	}
+	return *types.AggFieldType(tps)
+}
+
+// fixResultColumnType turns the type of the values into a type of the column which can be created: the length and
+// the decimal still unknown are set to the max, and the types that only the values have are replaced.
+func fixResultColumnType(tp *types.FieldType) {
+	switch tp.Tp {
+	case mysql.TypeNewDecimal:
+		if tp.Decimal == types.UnspecifiedLength {
+			tp.Decimal = types.MaxFraction
+		}
+		if tp.Flen == types.UnspecifiedLength {
+			tp.Flen = maxDecimalWidth
+		}
+		tp.Decimal = min(tp.Decimal, types.MaxFraction)
+		// The integer part takes precedence when the precision exceeds the max.
+		intPart := tp.Flen - tp.Decimal
+		tp.Flen = min(tp.Flen, maxDecimalWidth)
+		tp.Decimal = max(0, min(tp.Decimal, tp.Flen-intPart))
+		if tp.Flen == 0 {
+			tp.Flen = 1
+		}
+	case mysql.TypeVarString, mysql.TypeVarchar, mysql.TypeString:
+		if tp.Tp == mysql.TypeVarString {
+			tp.Tp = mysql.TypeVarchar
+		}
+		if tp.Flen == types.UnspecifiedLength {
+			tp.Tp = mysql.TypeLongBlob
+		}
+	case mysql.TypeNull:
+		// MySQL stores the NULLs in a BINARY(0) column.
+		tp.Tp, tp.Flen, tp.Decimal = mysql.TypeString, 0, types.UnspecifiedLength
+		tp.Charset, tp.Collate = charset.CharsetBin, charset.CollationBin
+		tp.Flag |= mysql.BinaryFlag
+	case mysql.TypeUnspecified:
+		tp.Tp, tp.Flen, tp.Decimal = mysql.TypeLongBlob, types.UnspecifiedLength, types.UnspecifiedLength
+	}
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -136,7 +136,7 @@ func getIDs(tables []*model.TableInfo) []int64 {
 	return ids
 }
 
-func (d *ddl) delReorgSchema(t *meta.Meta, job *model.Job) error {
+func (d *ddl) delReorgSchema(job *model.Job) error {
 	var startKey kv.Key
 	var tableIDs []int64
 	if err := job.DecodeArgs(&tableIDs, &startKey); err != nil {
@@ -144,7 +144,7 @@ func (d *ddl) delReorgSchema(t *meta.Meta, job *model.Job) error {
 		return errors.Trace(err)
 	}
 
-	isFinished, err := d.dropSchemaData(tableIDs, startKey, job, t)
+	isFinished, err := d.dropSchemaData(tableIDs, startKey, job)
 	if err != nil {
 		return errors.Trace(err)
 	}
@@ -159,7 +159,7 @@ func (d *ddl) delReorgSchema(t *meta.Meta, job *model.Job) error {
 	return nil
 }
 
-func (d *ddl) dropSchemaData(tIDs []int64, startKey kv.Key, job *model.Job, m *meta.Meta) (bool, error) {
+func (d *ddl) dropSchemaData(tIDs []int64, startKey kv.Key, job *model.Job) (bool, error) {
 	if len(tIDs) == 0 {
 		return true, nil
 	}
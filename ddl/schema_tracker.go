@@ -0,0 +1,639 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ddl
+
+import (
+	"strings"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/infoschema"
+	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/terror"
+)
+
+// SchemaTracker is a DDL which tracks the schema in memory without any storage. The schema changes take effect
+// at once instead of going through the states of the online schema change, and no row is read or written, so it
+// can validate the DDL statements offline, such as linting the SQL or dry running a migration script.
+type SchemaTracker struct {
+	alloc mockIDAllocator
+	dbs   []*model.DBInfo
+	is    infoschema.InfoSchema
+}
+
+var _ DDL = &SchemaTracker{}
+
+// NewSchemaTracker creates a SchemaTracker with no database.
+func NewSchemaTracker() *SchemaTracker {
+	st := &SchemaTracker{}
+	st.is = infoschema.MockInfoSchemaFromDBs(nil)
+	return st
+}
+
+// Exec applies a DDL statement to the tracked schema, it returns the same errors as the statement executed by
+// the server. The statement must be preprocessed by plan.Preprocess and its types must be inferred, so that the
+// table names are qualified by the current database and the result fields of the SELECT statement in it are known.
+func (st *SchemaTracker) Exec(ctx context.Context, stmt ast.DDLNode) error {
+	switch x := stmt.(type) {
+	case *ast.CreateDatabaseStmt:
+		return errors.Trace(st.execCreateDatabase(ctx, x))
+	case *ast.DropDatabaseStmt:
+		return errors.Trace(st.execDropDatabase(ctx, x))
+	case *ast.CreateTableStmt:
+		return errors.Trace(st.execCreateTable(ctx, x))
+	case *ast.CreateViewStmt:
+		return errors.Trace(st.execCreateView(ctx, x))
+	case *ast.DropTableStmt:
+		return errors.Trace(st.execDropTable(ctx, x))
+	case *ast.TruncateTableStmt:
+		return errors.Trace(st.TruncateTable(ctx, ast.Ident{Schema: x.Table.Schema, Name: x.Table.Name}))
+	case *ast.CreateIndexStmt:
+		ident := ast.Ident{Schema: x.Table.Schema, Name: x.Table.Name}
+		return errors.Trace(st.CreateIndex(ctx, ident, x.Unique, model.NewCIStr(x.IndexName), x.IndexColNames))
+	case *ast.DropIndexStmt:
+		ident := ast.Ident{Schema: x.Table.Schema, Name: x.Table.Name}
+		err := st.DropIndex(ctx, ident, model.NewCIStr(x.IndexName))
+		if (infoschema.ErrDatabaseNotExists.Equal(err) || infoschema.ErrTableNotExists.Equal(err)) && x.IfExists {
+			err = nil
+		}
+		return errors.Trace(err)
+	case *ast.AlterTableStmt:
+		return errors.Trace(st.AlterTable(ctx, ast.Ident{Schema: x.Table.Schema, Name: x.Table.Name}, x.Specs))
+	}
+	return errors.Errorf("unsupported statement %T in the schema tracker", stmt)
+}
+
+func (st *SchemaTracker) execCreateDatabase(ctx context.Context, s *ast.CreateDatabaseStmt) error {
+	var opt *ast.CharsetOpt
+	if len(s.Options) != 0 {
+		opt = &ast.CharsetOpt{}
+		for _, val := range s.Options {
+			switch val.Tp {
+			case ast.DatabaseOptionCharset:
+				opt.Chs = val.Value
+			case ast.DatabaseOptionCollate:
+				opt.Col = val.Value
+			}
+		}
+	}
+	err := st.CreateSchema(ctx, model.NewTableCIStr(s.Name), opt)
+	if terror.ErrorEqual(err, infoschema.ErrDatabaseExists) && s.IfNotExists {
+		err = nil
+	}
+	return errors.Trace(err)
+}
+
+func (st *SchemaTracker) execDropDatabase(ctx context.Context, s *ast.DropDatabaseStmt) error {
+	dbName := model.NewTableCIStr(s.Name)
+	err := st.DropSchema(ctx, dbName)
+	if terror.ErrorEqual(err, infoschema.ErrDatabaseNotExists) {
+		if s.IfExists {
+			return nil
+		}
+		return infoschema.ErrDatabaseDropExists.Gen("Can't drop database '%s'; database doesn't exist", s.Name)
+	}
+	if err != nil {
+		return errors.Trace(err)
+	}
+	sessionVars := ctx.GetSessionVars()
+	if model.NewTableCIStr(sessionVars.CurrentDB).L == dbName.L {
+		sessionVars.CurrentDB = ""
+	}
+	return nil
+}
+
+func (st *SchemaTracker) execCreateTable(ctx context.Context, s *ast.CreateTableStmt) error {
+	ident := ast.Ident{Schema: s.Table.Schema, Name: s.Table.Name}
+	var err error
+	switch {
+	case s.ReferTable != nil:
+		referIdent := ast.Ident{Schema: s.ReferTable.Schema, Name: s.ReferTable.Name}
+		err = st.CreateTableWithLike(ctx, ident, referIdent)
+	case s.Select != nil:
+		colDefs := ResultFieldsToColumnDefs(s.Select.GetResultFields())
+		err = st.CreateTable(ctx, ident, colDefs, nil, s.Options)
+	default:
+		err = st.CreateTable(ctx, ident, s.Cols, s.Constraints, s.Options)
+	}
+	if terror.ErrorEqual(err, infoschema.ErrTableExists) {
+		if s.IfNotExists {
+			return nil
+		}
+		return infoschema.ErrTableExists.Gen("CREATE TABLE: table exists %s", ident)
+	}
+	return errors.Trace(err)
+}
+
+func (st *SchemaTracker) execCreateView(ctx context.Context, s *ast.CreateViewStmt) error {
+	ident := ast.Ident{Schema: s.ViewName.Schema, Name: s.ViewName.Name}
+	rfs := s.Select.GetResultFields()
+	if len(s.Cols) > 0 && len(s.Cols) != len(rfs) {
+		return errViewWrongList.Gen("View's SELECT and view's field list have different column counts")
+	}
+	view := &model.ViewInfo{
+		SelectStmt: s.Select.Text(),
+		Definer:    ctx.GetSessionVars().User,
+	}
+	err := st.CreateView(ctx, ident, ResultFieldsToViewColumns(rfs, s.Cols), view, s.OrReplace)
+	if terror.ErrorEqual(err, infoschema.ErrTableExists) {
+		return infoschema.ErrTableExists.Gen("CREATE VIEW: table exists %s", ident)
+	}
+	return errors.Trace(err)
+}
+
+func (st *SchemaTracker) execDropTable(ctx context.Context, s *ast.DropTableStmt) error {
+	var notExistTables []string
+	for _, tn := range s.Tables {
+		ident := ast.Ident{Schema: tn.Schema, Name: tn.Name}
+		_, tbInfo, err := st.getTable(ident)
+		if err != nil {
+			notExistTables = append(notExistTables, ident.String())
+			continue
+		}
+		// DROP TABLE doesn't drop the views, and DROP VIEW doesn't drop the base tables.
+		if tbInfo.IsView() != s.IsView {
+			if s.IsView {
+				return infoschema.ErrWrongObject.Gen("'%s.%s' is not VIEW", tn.Schema, tn.Name)
+			}
+			notExistTables = append(notExistTables, ident.String())
+			continue
+		}
+		if err = st.DropTable(ctx, ident); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	if len(notExistTables) > 0 && !s.IfExists {
+		if s.IsView {
+			return infoschema.ErrTableDropExists.Gen("DROP VIEW: view %s does not exist", strings.Join(notExistTables, ","))
+		}
+		return infoschema.ErrTableDropExists.Gen("DROP TABLE: table %s does not exist", strings.Join(notExistTables, ","))
+	}
+	return nil
+}
+
+// getDB gets the tracked database by its name, it returns nil if the database doesn't exist.
+func (st *SchemaTracker) getDB(name model.CIStr) *model.DBInfo {
+	for _, db := range st.dbs {
+		if db.Name.L == name.L {
+			return db
+		}
+	}
+	return nil
+}
+
+// getTable gets the tracked table and its database. The returned TableInfo must be cloned before being changed,
+// because it is shared by the InfoSchema returned before.
+func (st *SchemaTracker) getTable(ti ast.Ident) (*model.DBInfo, *model.TableInfo, error) {
+	db := st.getDB(ti.Schema)
+	if db == nil {
+		return nil, nil, infoschema.ErrDatabaseNotExists.Gen("database %s not exists", ti.Schema)
+	}
+	for _, tbInfo := range db.Tables {
+		if tbInfo.Name.L == ti.Name.L {
+			return db, tbInfo, nil
+		}
+	}
+	return nil, nil, infoschema.ErrTableNotExists.Gen("table %s does not exist", ti)
+}
+
+// putTable puts the table into the database, it replaces the table with the same name.
+func (st *SchemaTracker) putTable(db *model.DBInfo, tbInfo *model.TableInfo) {
+	tables := make([]*model.TableInfo, 0, len(db.Tables)+1)
+	replaced := false
+	for _, old := range db.Tables {
+		if old.Name.L == tbInfo.Name.L {
+			old = tbInfo
+			replaced = true
+		}
+		tables = append(tables, old)
+	}
+	if !replaced {
+		tables = append(tables, tbInfo)
+	}
+	db.Tables = tables
+	st.rebuild()
+}
+
+// rebuild rebuilds the InfoSchema after the tracked schema is changed.
+func (st *SchemaTracker) rebuild() {
+	st.is = infoschema.MockInfoSchemaFromDBs(st.dbs)
+}
+
+// GetInformationSchema implements the DDL GetInformationSchema interface.
+func (st *SchemaTracker) GetInformationSchema() infoschema.InfoSchema {
+	return st.is
+}
+
+// CreateSchema implements the DDL CreateSchema interface.
+func (st *SchemaTracker) CreateSchema(ctx context.Context, schema model.CIStr, charsetInfo *ast.CharsetOpt) error {
+	if st.getDB(schema) != nil {
+		return errors.Trace(infoschema.ErrDatabaseExists)
+	}
+	if err := checkTooLongSchema(schema); err != nil {
+		return errors.Trace(err)
+	}
+	schemaID, _ := st.alloc.genID()
+	dbInfo := &model.DBInfo{
+		ID:    schemaID,
+		Name:  schema,
+		State: model.StatePublic,
+	}
+	if charsetInfo != nil {
+		dbInfo.Charset = charsetInfo.Chs
+		dbInfo.Collate = charsetInfo.Col
+	} else {
+		dbInfo.Charset, dbInfo.Collate = getDefaultCharsetAndCollate()
+	}
+	st.dbs = append(st.dbs, dbInfo)
+	st.rebuild()
+	return nil
+}
+
+// DropSchema implements the DDL DropSchema interface.
+func (st *SchemaTracker) DropSchema(ctx context.Context, schema model.CIStr) error {
+	if st.getDB(schema) == nil {
+		return errors.Trace(infoschema.ErrDatabaseNotExists)
+	}
+	dbs := make([]*model.DBInfo, 0, len(st.dbs))
+	for _, db := range st.dbs {
+		if db.Name.L != schema.L {
+			dbs = append(dbs, db)
+		}
+	}
+	st.dbs = dbs
+	st.rebuild()
+	return nil
+}
+
+// CreateTable implements the DDL CreateTable interface.
+func (st *SchemaTracker) CreateTable(ctx context.Context, ident ast.Ident, colDefs []*ast.ColumnDef,
+	constraints []*ast.Constraint, options []*ast.TableOption) error {
+	db := st.getDB(ident.Schema)
+	if db == nil {
+		return infoschema.ErrDatabaseNotExists.Gen("database %s not exists", ident.Schema)
+	}
+	if tableExists(db, ident.Name) {
+		return errors.Trace(infoschema.ErrTableExists)
+	}
+	tbInfo, err := mockTableInfo(ctx, ident.Name, colDefs, constraints, options, &st.alloc)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	st.putTable(db, tbInfo)
+	return nil
+}
+
+// CreateTableWithLike implements the DDL CreateTableWithLike interface.
+func (st *SchemaTracker) CreateTableWithLike(ctx context.Context, ident, referIdent ast.Ident) error {
+	db := st.getDB(ident.Schema)
+	if db == nil {
+		return infoschema.ErrDatabaseNotExists.Gen("database %s not exists", ident.Schema)
+	}
+	_, referTbInfo, err := st.getTable(referIdent)
+	if err != nil {
+		return infoschema.ErrTableNotExists.Gen("table %s.%s does not exist", referIdent.Schema, referIdent.Name)
+	}
+	if referTbInfo.IsView() {
+		return infoschema.ErrWrongObject.Gen("'%s.%s' is not BASE TABLE", referIdent.Schema, referIdent.Name)
+	}
+	if tableExists(db, ident.Name) {
+		return errors.Trace(infoschema.ErrTableExists)
+	}
+	if err = checkTooLongTable(ident.Name); err != nil {
+		return errors.Trace(err)
+	}
+	tbInfo := referTbInfo.Clone()
+	tbInfo.ID, _ = st.alloc.genID()
+	tbInfo.Name = ident.Name
+	tbInfo.AutoIncID = 0
+	tbInfo.ForeignKeys = nil
+	st.putTable(db, tbInfo)
+	return nil
+}
+
+// CreateView implements the DDL CreateView interface.
+func (st *SchemaTracker) CreateView(ctx context.Context, ident ast.Ident, cols []*model.ColumnInfo,
+	view *model.ViewInfo, orReplace bool) error {
+	db := st.getDB(ident.Schema)
+	if db == nil {
+		return infoschema.ErrDatabaseNotExists.Gen("database %s not exists", ident.Schema)
+	}
+	if _, old, err := st.getTable(ident); err == nil {
+		if !orReplace {
+			return errors.Trace(infoschema.ErrTableExists)
+		}
+		if !old.IsView() {
+			return infoschema.ErrWrongObject.Gen("'%s.%s' is not VIEW", ident.Schema, ident.Name)
+		}
+	}
+	if err := checkTooLongTable(ident.Name); err != nil {
+		return errors.Trace(err)
+	}
+	tbInfo := &model.TableInfo{
+		Name:    ident.Name,
+		Columns: cols,
+		View:    view,
+		State:   model.StatePublic,
+	}
+	tbInfo.Charset, tbInfo.Collate = getDefaultCharsetAndCollate()
+	tbInfo.ID, _ = st.alloc.genID()
+	colNames := make(map[string]bool, len(cols))
+	for i, col := range cols {
+		if colNames[col.Name.L] {
+			return infoschema.ErrColumnExists.Gen("duplicate column %s", col.Name)
+		}
+		colNames[col.Name.L] = true
+		if len(col.Name.O) > mysql.MaxColumnNameLength {
+			return ErrTooLongIdent.Gen("too long column %s", col.Name)
+		}
+		col.ID, _ = st.alloc.genID()
+		col.Offset = i
+		col.State = model.StatePublic
+	}
+	st.putTable(db, tbInfo)
+	return nil
+}
+
+// DropTable implements the DDL DropTable interface.
+func (st *SchemaTracker) DropTable(ctx context.Context, ti ast.Ident) error {
+	db, _, err := st.getTable(ti)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	tables := make([]*model.TableInfo, 0, len(db.Tables))
+	for _, tbInfo := range db.Tables {
+		if tbInfo.Name.L != ti.Name.L {
+			tables = append(tables, tbInfo)
+		}
+	}
+	db.Tables = tables
+	st.rebuild()
+	return nil
+}
+
+// TruncateTable implements the DDL TruncateTable interface. Like the server, the truncated table gets a new ID.
+func (st *SchemaTracker) TruncateTable(ctx context.Context, ti ast.Ident) error {
+	db, tbInfo, err := st.getTable(ti)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	tbInfo = tbInfo.Clone()
+	tbInfo.ID, _ = st.alloc.genID()
+	st.putTable(db, tbInfo)
+	return nil
+}
+
+// AlterTable implements the DDL AlterTable interface.
+func (st *SchemaTracker) AlterTable(ctx context.Context, ident ast.Ident, specs []*ast.AlterTableSpec) error {
+	return errors.Trace(alterTable(st, ctx, ident, specs))
+}
+
+// AddColumn adds a new column to the tracked table.
+func (st *SchemaTracker) AddColumn(ctx context.Context, ti ast.Ident, spec *ast.AlterTableSpec) error {
+	err := checkColumnConstraint(spec.Column.Options)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	db, tbInfo, err := st.getTable(ti)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	colName := spec.Column.Name.Name.O
+	if findCol(tbInfo.Columns, colName) != nil {
+		return infoschema.ErrColumnExists.Gen("column %s already exists", colName)
+	}
+	if len(colName) > mysql.MaxColumnNameLength {
+		return ErrTooLongIdent.Gen("too long column %s", colName)
+	}
+	col, _, err := buildColumnAndConstraint(ctx, len(tbInfo.Columns), spec.Column, st.alloc.genID)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	tbInfo = tbInfo.Clone()
+	colInfo, offset, err := createColumnInfo(tbInfo, col.ToInfo(), spec.Position)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	adjustColumnOffset(tbInfo.Columns, tbInfo.Indices, offset, true)
+	colInfo.State = model.StatePublic
+	st.putTable(db, tbInfo)
+	return nil
+}
+
+// DropColumn drops a column from the tracked table, the column covered by an index can't be dropped.
+func (st *SchemaTracker) DropColumn(ctx context.Context, ti ast.Ident, colName model.CIStr) error {
+	db, tbInfo, err := st.getTable(ti)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	colInfo := findCol(tbInfo.Columns, colName.L)
+	if colInfo == nil {
+		return ErrCantDropFieldOrKey.Gen("column %s doesn't exist", colName)
+	}
+	if len(tbInfo.Columns) == 1 {
+		return ErrCantRemoveAllFields.Gen("can't drop only column %s in table %s", colName, tbInfo.Name)
+	}
+	for _, indexInfo := range tbInfo.Indices {
+		for _, col := range indexInfo.Columns {
+			if col.Name.L == colName.L {
+				return errCantDropColWithIndex.Gen("can't drop column %s with index %s covered now",
+					colName, indexInfo.Name)
+			}
+		}
+	}
+	tbInfo = tbInfo.Clone()
+	adjustColumnOffset(tbInfo.Columns, tbInfo.Indices, colInfo.Offset, false)
+	newColumns := make([]*model.ColumnInfo, 0, len(tbInfo.Columns))
+	for _, col := range tbInfo.Columns {
+		if col.Name.L != colName.L {
+			newColumns = append(newColumns, col)
+		}
+	}
+	tbInfo.Columns = newColumns
+	st.putTable(db, tbInfo)
+	return nil
+}
+
+// ModifyColumn modifies the type of a column of the tracked table, only the changes which the server supports
+// are allowed.
+func (st *SchemaTracker) ModifyColumn(ctx context.Context, ident ast.Ident, spec *ast.AlterTableSpec) error {
+	db, tbInfo, err := st.getTable(ident)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	colName := spec.Column.Name.Name
+	col := findCol(tbInfo.Columns, colName.L)
+	if col == nil {
+		return infoschema.ErrColumnNotExists.Gen("column %s doesn't exist", colName.O)
+	}
+	if spec.Constraint != nil || spec.Position.Tp != ast.ColumnPositionNone ||
+		len(spec.Column.Options) != 0 || spec.Column.Tp == nil {
+		return errUnsupportedModifyColumn
+	}
+	setCharsetCollationFlenDecimal(spec.Column.Tp)
+	if !modifiable(&col.FieldType, spec.Column.Tp) &&
+		(!ctx.GetSessionVars().DDLShadowCopy || (mysql.HasPriKeyFlag(col.Flag) && tbInfo.PKIsHandle)) {
+		return errUnsupportedModifyColumn
+	}
+	tbInfo = tbInfo.Clone()
+	findCol(tbInfo.Columns, colName.L).FieldType = *spec.Column.Tp
+	st.putTable(db, tbInfo)
+	return nil
+}
+
+// CreateIndex implements the DDL CreateIndex interface.
+func (st *SchemaTracker) CreateIndex(ctx context.Context, ti ast.Ident, unique bool, indexName model.CIStr,
+	idxColNames []*ast.IndexColName) error {
+	db, tbInfo, err := st.getTable(ti)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	// Deal with anonymous index.
+	if len(indexName.L) == 0 {
+		t, _ := st.is.TableByName(ti.Schema, ti.Name)
+		indexName = getAnonymousIndex(t, idxColNames[0].Column.Name)
+	}
+	for _, idx := range tbInfo.Indices {
+		if idx.Name.L == indexName.L {
+			return errDupKeyName.Gen("index already exist %s", indexName)
+		}
+	}
+	indexID, _ := st.alloc.genID()
+	tbInfo = tbInfo.Clone()
+	indexInfo, err := buildIndexInfo(tbInfo, unique, indexName, indexID, idxColNames)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	indexInfo.State = model.StatePublic
+	tbInfo.Indices = append(tbInfo.Indices, indexInfo)
+	addIndexColumnFlag(tbInfo, indexInfo)
+	st.putTable(db, tbInfo)
+	return nil
+}
+
+// DropIndex implements the DDL DropIndex interface.
+func (st *SchemaTracker) DropIndex(ctx context.Context, ti ast.Ident, indexName model.CIStr) error {
+	db, tbInfo, err := st.getTable(ti)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	tbInfo = tbInfo.Clone()
+	var indexInfo *model.IndexInfo
+	newIndices := make([]*model.IndexInfo, 0, len(tbInfo.Indices))
+	for _, idx := range tbInfo.Indices {
+		if idx.Name.L == indexName.L {
+			indexInfo = idx
+		} else {
+			newIndices = append(newIndices, idx)
+		}
+	}
+	if indexInfo == nil {
+		return ErrCantDropFieldOrKey.Gen("index %s doesn't exist", indexName)
+	}
+	tbInfo.Indices = newIndices
+	dropIndexColumnFlag(tbInfo, indexInfo)
+	st.putTable(db, tbInfo)
+	return nil
+}
+
+// CreateForeignKey adds a foreign key to the tracked table, like the server, the foreign key is only recorded.
+func (st *SchemaTracker) CreateForeignKey(ctx context.Context, ti ast.Ident, fkName model.CIStr,
+	keys []*ast.IndexColName, refer *ast.ReferenceDef) error {
+	db, tbInfo, err := st.getTable(ti)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	fkInfo, err := buildFKInfo(fkName, keys, refer, st.alloc.genID)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	fkInfo.State = model.StatePublic
+	tbInfo = tbInfo.Clone()
+	tbInfo.ForeignKeys = append(tbInfo.ForeignKeys, fkInfo)
+	st.putTable(db, tbInfo)
+	return nil
+}
+
+// DropForeignKey drops a foreign key from the tracked table.
+func (st *SchemaTracker) DropForeignKey(ctx context.Context, ti ast.Ident, fkName model.CIStr) error {
+	db, tbInfo, err := st.getTable(ti)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	tbInfo = tbInfo.Clone()
+	found := false
+	fks := make([]*model.FKInfo, 0, len(tbInfo.ForeignKeys))
+	for _, fk := range tbInfo.ForeignKeys {
+		if fk.Name.L == fkName.L {
+			found = true
+		} else {
+			fks = append(fks, fk)
+		}
+	}
+	if !found {
+		return infoschema.ErrForeignKeyNotExists.Gen("foreign key %s doesn't exist", fkName)
+	}
+	tbInfo.ForeignKeys = fks
+	st.putTable(db, tbInfo)
+	return nil
+}
+
+// SetStatsNDV sets the NDV hints of the columns and indices of the tracked table.
+func (st *SchemaTracker) SetStatsNDV(ctx context.Context, ti ast.Ident, ndvs []*ast.StatsNDV) error {
+	db, tbInfo, err := st.getTable(ti)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if tbInfo.IsView() {
+		return infoschema.ErrWrongObject.Gen("'%s.%s' is not BASE TABLE", ti.Schema, ti.Name)
+	}
+	tbInfo = tbInfo.Clone()
+	if err = setStatsNDVs(tbInfo, ndvs); err != nil {
+		return errors.Trace(err)
+	}
+	st.putTable(db, tbInfo)
+	return nil
+}
+
+// SetLease implements the DDL SetLease interface, the schema tracker has no lease.
+func (st *SchemaTracker) SetLease(lease time.Duration) {}
+
+// GetLease implements the DDL GetLease interface.
+func (st *SchemaTracker) GetLease() time.Duration {
+	return 0
+}
+
+// Stats implements the DDL Stats interface, the schema tracker has no job.
+func (st *SchemaTracker) Stats() (map[string]interface{}, error) {
+	return make(map[string]interface{}), nil
+}
+
+// GetScope implements the DDL GetScope interface.
+func (st *SchemaTracker) GetScope(status string) variable.ScopeFlag {
+	return variable.DefaultScopeFlag
+}
+
+// Stop implements the DDL Stop interface.
+func (st *SchemaTracker) Stop() error {
+	return nil
+}
+
+// Start implements the DDL Start interface.
+func (st *SchemaTracker) Start() error {
+	return nil
+}
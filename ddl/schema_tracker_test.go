@@ -0,0 +1,190 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ddl
+
+import (
+	"github.com/juju/errors"
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/infoschema"
+	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/parser"
+	"github.com/pingcap/tidb/plan"
+	"github.com/pingcap/tidb/terror"
+	"github.com/pingcap/tidb/util/mock"
+	"github.com/pingcap/tidb/util/testleak"
+)
+
+var _ = Suite(&testSchemaTrackerSuite{})
+
+type testSchemaTrackerSuite struct {
+}
+
+// trackerExec compiles the statements like the server does and applies the DDL statements to the tracker.
+func trackerExec(ctx context.Context, st *SchemaTracker, sql string) error {
+	stmts, err := parser.New().Parse(sql, "", "")
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for _, stmt := range stmts {
+		is := st.GetInformationSchema()
+		if x, ok := stmt.(*ast.UseStmt); ok {
+			ctx.GetSessionVars().CurrentDB = x.DBName
+			continue
+		}
+		if err = plan.Preprocess(stmt, is, ctx); err != nil {
+			return errors.Trace(err)
+		}
+		if _, err = plan.Optimize(ctx, stmt, is); err != nil {
+			return errors.Trace(err)
+		}
+		if x, ok := stmt.(ast.DDLNode); ok {
+			if err = st.Exec(ctx, x); err != nil {
+				return errors.Trace(err)
+			}
+		}
+	}
+	return nil
+}
+
+func trackedTable(c *C, st *SchemaTracker, db, tbl string) *model.TableInfo {
+	t, err := st.GetInformationSchema().TableByName(model.NewCIStr(db), model.NewCIStr(tbl))
+	c.Assert(err, IsNil)
+	return t.Meta()
+}
+
+func colNames(tbInfo *model.TableInfo) []string {
+	names := make([]string, 0, len(tbInfo.Columns))
+	for i, col := range tbInfo.Columns {
+		if col.Offset != i {
+			return nil
+		}
+		names = append(names, col.Name.L)
+	}
+	return names
+}
+
+func (s *testSchemaTrackerSuite) TestSchemaTracker(c *C) {
+	defer testleak.AfterTest(c)()
+	ctx := mock.NewContext()
+	st := NewSchemaTracker()
+
+	err := trackerExec(ctx, st, `create database db; use db;
+		create table t (a int primary key, b int, c varchar(10) default 'x') auto_increment = 10;
+		create index b on t (b);
+		alter table t add column d int after a;
+		alter table t modify column b bigint;
+		alter table t add unique (c);
+		alter table t stats_ndv = (d = 10, index c = 20);`)
+	c.Assert(err, IsNil)
+	tbInfo := trackedTable(c, st, "db", "t")
+	c.Assert(tbInfo.State, Equals, model.StatePublic)
+	c.Assert(tbInfo.AutoIncID, Equals, int64(10))
+	c.Assert(colNames(tbInfo), DeepEquals, []string{"a", "d", "b", "c"})
+	c.Assert(tbInfo.Columns[2].Tp, Equals, mysql.TypeLonglong)
+	c.Assert(tbInfo.Indices, HasLen, 2)
+	c.Assert(tbInfo.Indices[0].Columns[0].Offset, Equals, 2)
+	c.Assert(tbInfo.Indices[1].Name.L, Equals, "c")
+	c.Assert(tbInfo.Indices[1].Columns[0].Offset, Equals, 3)
+	c.Assert(mysql.HasUniKeyFlag(tbInfo.Columns[3].Flag), IsTrue)
+	c.Assert(tbInfo.Columns[1].StatsNDV, Equals, int64(10))
+	c.Assert(tbInfo.Indices[1].StatsNDV, Equals, int64(20))
+	oldIS := st.GetInformationSchema()
+
+	// The failed changes leave the schema unchanged.
+	cases := []struct {
+		sql string
+		err *terror.Error
+	}{
+		{"create table t (a int)", infoschema.ErrTableExists},
+		{"create database db", infoschema.ErrDatabaseExists},
+		{"create index b on t (b)", errDupKeyName},
+		{"create index e on t (e)", errKeyColumnDoesNotExits},
+		{"alter table t drop column b", errCantDropColWithIndex},
+		{"alter table t drop column e", ErrCantDropFieldOrKey},
+		{"alter table t add column b int", infoschema.ErrColumnExists},
+		{"alter table t modify column b int", errUnsupportedModifyColumn},
+		{"drop index e on t", ErrCantDropFieldOrKey},
+		{"drop table t1, t2", infoschema.ErrTableDropExists},
+		{"drop view t", infoschema.ErrWrongObject},
+		{"drop database db1", infoschema.ErrDatabaseDropExists},
+		{"create view v (x) as select a, b from t", errViewWrongList},
+		{"alter table t drop foreign key fk", infoschema.ErrForeignKeyNotExists},
+		{"alter table t stats_ndv = (e = 10)", infoschema.ErrColumnNotExists},
+		{"alter table t stats_ndv = (index e = 10)", errKeyDoesNotExist},
+	}
+	for _, ca := range cases {
+		err = trackerExec(ctx, st, ca.sql)
+		c.Assert(ca.err.Equal(err), IsTrue, Commentf("%s: %v", ca.sql, err))
+	}
+	c.Assert(st.GetInformationSchema(), Equals, oldIS)
+	c.Assert(trackerExec(ctx, st, `create table if not exists t (a int); drop table if exists t1;
+		drop database if exists db1`), IsNil)
+
+	err = trackerExec(ctx, st, `drop index b on t; alter table t drop column b;
+		alter table t add constraint fk foreign key (d) references t (a);
+		create table t1 like t;
+		create table t2 as select a, c as e from t where a > 1;
+		create view v as select a, d from t;
+		truncate table t1;`)
+	c.Assert(err, IsNil)
+	// The InfoSchema returned before is not changed.
+	c.Assert(oldIS.SchemaTables(model.NewCIStr("db")), HasLen, 1)
+	tbInfo = trackedTable(c, st, "db", "t")
+	c.Assert(colNames(tbInfo), DeepEquals, []string{"a", "d", "c"})
+	c.Assert(tbInfo.Indices, HasLen, 1)
+	c.Assert(tbInfo.Indices[0].Columns[0].Offset, Equals, 2)
+	c.Assert(tbInfo.ForeignKeys, HasLen, 1)
+	c.Assert(tbInfo.ForeignKeys[0].RefCols[0].L, Equals, "a")
+	t1Info := trackedTable(c, st, "db", "t1")
+	c.Assert(colNames(t1Info), DeepEquals, []string{"a", "d", "c"})
+	c.Assert(t1Info.Indices, HasLen, 1)
+	c.Assert(t1Info.ForeignKeys, HasLen, 0)
+	c.Assert(t1Info.AutoIncID, Equals, int64(0))
+	c.Assert(t1Info.ID, Not(Equals), tbInfo.ID)
+	t2Info := trackedTable(c, st, "db", "t2")
+	c.Assert(colNames(t2Info), DeepEquals, []string{"a", "e"})
+	c.Assert(t2Info.PKIsHandle, IsFalse)
+	c.Assert(t2Info.Columns[1].Tp, Equals, mysql.TypeVarchar)
+	vInfo := trackedTable(c, st, "db", "v")
+	c.Assert(vInfo.IsView(), IsTrue)
+	c.Assert(colNames(vInfo), DeepEquals, []string{"a", "d"})
+	c.Assert(vInfo.View.SelectStmt, Equals, "select a, d from t")
+
+	// The column types of CREATE TABLE ... SELECT are the same as the ones of the server.
+	err = trackerExec(ctx, st, `create table sel_v (a int, b varchar(10), c decimal(10,3));
+		create table sel_w select a+c as s, c*2 as m, avg(a) as v, a/3 as d, round(c, 1) as r,
+		ifnull(a, c) as f, concat(b, 'x') as t, cast(a as char) as x, null as n from sel_v`)
+	c.Assert(err, IsNil)
+	var colTypes []string
+	for _, col := range trackedTable(c, st, "db", "sel_w").Columns {
+		colTypes = append(colTypes, col.CompactStr())
+	}
+	c.Assert(colTypes, DeepEquals, []string{"decimal(15,3)", "decimal(11,3)", "decimal(15,4)", "decimal(15,4)",
+		"decimal(9,1)", "decimal(14,3)", "varchar(11)", "varchar(11)", "binary(0)"})
+	c.Assert(trackerExec(ctx, st, "drop table sel_v, sel_w"), IsNil)
+
+	// The views and tables are only dropped by the matching statements.
+	c.Assert(trackerExec(ctx, st, "drop table v"), NotNil)
+	c.Assert(trackerExec(ctx, st, "drop view v; drop table t2"), IsNil)
+	c.Assert(st.GetInformationSchema().SchemaTables(model.NewCIStr("db")), HasLen, 2)
+
+	c.Assert(trackerExec(ctx, st, "drop database db"), IsNil)
+	c.Assert(ctx.GetSessionVars().CurrentDB, Equals, "")
+	c.Assert(st.GetInformationSchema().AllSchemas(), HasLen, 0)
+	err = trackerExec(ctx, st, "create table t (a int)")
+	c.Assert(infoschema.ErrDatabaseNotExists.Equal(err), IsTrue)
+}
@@ -0,0 +1,336 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ddl
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/infoschema"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/meta"
+	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/table"
+	"github.com/pingcap/tidb/table/tables"
+	"github.com/pingcap/tidb/tablecodec"
+	"github.com/pingcap/tidb/terror"
+	"github.com/pingcap/tidb/util/types"
+)
+
+// shadowCopyModifyColumn changes the type of the column by copying the table to a shadow table with the new column
+// type. The shadow table goes through the states like an index:
+//  1. In delete only state, the rows removed from the table are removed from the shadow table.
+//  2. In write only state, the rows written to the table are written to the shadow table too.
+//  3. In write reorganization state, the rows of the table are copied to the shadow table in small transactions,
+//     each followed by a pause of TiDBDDLShadowCopyThrottle milliseconds.
+//  4. Then the shadow table replaces the table in one schema change, with the name and the auto ID of the table,
+//     and the data of the table is deleted by a background job.
+//
+// If a row can't be converted to the new column type, or it breaks a unique index, the job is rolled back and the
+// shadow table is deleted. The statements never fail because of the shadow table, they mark it broken instead, and
+// the mark is checked with each copied batch and with the swap.
+func (d *ddl) shadowCopyModifyColumn(ctx context.Context, schema *model.DBInfo, t table.Table, col *table.Column,
+	tp *types.FieldType) error {
+	val, err := ctx.GetSessionVars().GetTiDBSystemVar(variable.TiDBDDLShadowCopyThrottle)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	throttle, err := strconv.ParseInt(val, 10, 64)
+	if err != nil || throttle < 0 {
+		return errors.Errorf("invalid value %s for %s", val, variable.TiDBDDLShadowCopyThrottle)
+	}
+	shadowID, err := d.genGlobalID()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	newCol := *col
+	newCol.FieldType = *tp
+	job := &model.Job{
+		SchemaID: schema.ID,
+		TableID:  t.Meta().ID,
+		Type:     model.ActionShadowCopy,
+		Args:     []interface{}{shadowID, &newCol, throttle},
+	}
+	err = d.doDDLJob(ctx, job)
+	err = d.callHookOnChanged(err)
+	return errors.Trace(err)
+}
+
+func (d *ddl) onShadowCopy(txn kv.Transaction, t *meta.Meta, job *model.Job) error {
+	// Handle rollback job.
+	if job.State == model.JobRollback {
+		return d.rollbackShadowCopy(t, job)
+	}
+
+	schemaID := job.SchemaID
+	tblInfo, err := d.getTableInfo(t, job)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	var (
+		shadowID int64
+		newCol   = &model.ColumnInfo{}
+		throttle int64
+	)
+	err = job.DecodeArgs(&shadowID, newCol, &throttle)
+	if err != nil {
+		job.State = model.JobCancelled
+		return errors.Trace(err)
+	}
+
+	shadow := tblInfo.Shadow
+	if shadow == nil {
+		oldCol := findCol(tblInfo.Columns, newCol.Name.L)
+		if oldCol == nil || oldCol.State != model.StatePublic {
+			job.State = model.JobCancelled
+			return infoschema.ErrColumnNotExists.Gen("column %s doesn't exist", newCol.Name)
+		}
+		shadow = tblInfo.Clone()
+		shadow.ID = shadowID
+		shadow.State = model.StateNone
+		*findCol(shadow.Columns, newCol.Name.L) = *newCol
+		tblInfo.Shadow = shadow
+	}
+
+	switch shadow.State {
+	case model.StateNone:
+		// none -> delete only
+		job.SchemaState = model.StateDeleteOnly
+		shadow.State = model.StateDeleteOnly
+		err = t.UpdateTable(schemaID, tblInfo)
+	case model.StateDeleteOnly:
+		// delete only -> write only
+		job.SchemaState = model.StateWriteOnly
+		shadow.State = model.StateWriteOnly
+		err = t.UpdateTable(schemaID, tblInfo)
+	case model.StateWriteOnly:
+		// write only -> reorganization
+		job.SchemaState = model.StateWriteReorganization
+		shadow.State = model.StateWriteReorganization
+		// Initialize SnapshotVer to 0 for later reorganization check.
+		job.SnapshotVer = 0
+		err = t.UpdateTable(schemaID, tblInfo)
+	case model.StateWriteReorganization:
+		// reorganization -> public
+		reorgInfo, err := d.getReorgInfo(t, job)
+		if err != nil || reorgInfo.first {
+			// If we run reorg firstly, we should update the job snapshot version
+			// and then run the reorg next time.
+			return errors.Trace(err)
+		}
+
+		tbl, err := d.getTable(schemaID, tblInfo)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		err = d.runReorgJob(func() error {
+			return d.copyShadowTable(tbl, reorgInfo, job, time.Duration(throttle)*time.Millisecond)
+		})
+		if terror.ErrorEqual(err, errWaitReorgTimeout) {
+			// If the timeout happens, we should return.
+			// Then check for the owner and re-wait job to finish.
+			return nil
+		}
+		if err == nil {
+			// The rows written by the statements are checked in the transaction of the swap, so no statement can
+			// break the shadow table before the swap.
+			err = tables.CheckShadowBroken(txn, tbl)
+		}
+		if err != nil {
+			if isShadowCopyDataError(err) {
+				log.Warnf("[ddl] run DDL job %v err %v, convert job to rollback job", job, err)
+				err = d.convertShadowCopy2RollbackJob(t, job, tblInfo, err)
+			}
+			return errors.Trace(err)
+		}
+		return errors.Trace(d.replaceByShadowTable(t, job, tblInfo))
+	default:
+		err = ErrInvalidTableState.Gen("invalid shadow table state %v", shadow.State)
+	}
+	if err != nil {
+		return errors.Trace(err)
+	}
+	_, err = updateSchemaVersion(t, job)
+	return errors.Trace(err)
+}
+
+// replaceByShadowTable replaces the table by its shadow table in one schema change.
+func (d *ddl) replaceByShadowTable(t *meta.Meta, job *model.Job, tblInfo *model.TableInfo) error {
+	schemaID := job.SchemaID
+	shadow := tblInfo.Shadow
+	// The handles of the rows are kept, so the shadow table allocates the IDs after the table.
+	autoID, err := t.GetAutoTableID(schemaID, tblInfo.ID)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if err = t.DropTable(schemaID, tblInfo.ID); err != nil {
+		return errors.Trace(err)
+	}
+	shadow.State = model.StatePublic
+	if err = t.CreateTable(schemaID, shadow); err != nil {
+		return errors.Trace(err)
+	}
+	if _, err = t.GenAutoTableID(schemaID, shadow.ID, autoID); err != nil {
+		return errors.Trace(err)
+	}
+
+	// Finish this job.
+	job.SchemaState = model.StatePublic
+	ver, err := updateSchemaVersion(t, job)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	job.State = model.JobDone
+	addTableHistoryInfo(job, ver, shadow)
+	startKey := tablecodec.EncodeTablePrefix(tblInfo.ID)
+	job.Args = append(job.Args, startKey)
+	return nil
+}
+
+// isShadowCopyDataError checks whether the error is caused by the data of the table, which can't be copied to the
+// shadow table by retrying.
+func isShadowCopyDataError(err error) bool {
+	return terror.ErrorEqual(err, kv.ErrKeyExists) || terror.ErrorEqual(err, table.ErrTruncatedWrongValue)
+}
+
+func (d *ddl) convertShadowCopy2RollbackJob(t *meta.Meta, job *model.Job, tblInfo *model.TableInfo,
+	cause error) error {
+	job.State = model.JobRollback
+	// The rows are removed from the shadow table before it's deleted, like the index of a rolled back add index job.
+	tblInfo.Shadow.State = model.StateDeleteOnly
+	job.SchemaState = model.StateDeleteOnly
+	err := t.UpdateTable(job.SchemaID, tblInfo)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if _, err = updateSchemaVersion(t, job); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(cause)
+}
+
+// rollbackShadowCopy deletes the data of the shadow table and then the shadow table.
+func (d *ddl) rollbackShadowCopy(t *meta.Meta, job *model.Job) error {
+	tblInfo, err := d.getTableInfo(t, job)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if tblInfo.Shadow != nil {
+		prefix := tablecodec.EncodeTablePrefix(tblInfo.Shadow.ID)
+		err = d.runReorgJob(func() error {
+			deleteAll := -1
+			_, _, err1 := d.delKeysWithStartKey(prefix, prefix, ddlJobFlag, job, deleteAll)
+			return errors.Trace(err1)
+		})
+		if terror.ErrorEqual(err, errWaitReorgTimeout) {
+			// If the timeout happens, we should return.
+			// Then check for the owner and re-wait job to finish.
+			return nil
+		}
+		if err != nil {
+			return errors.Trace(err)
+		}
+		tblInfo.Shadow = nil
+		if err = t.UpdateTable(job.SchemaID, tblInfo); err != nil {
+			return errors.Trace(err)
+		}
+	}
+
+	// Finish this job.
+	job.SchemaState = model.StateNone
+	ver, err := updateSchemaVersion(t, job)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	job.State = model.JobRollbackDone
+	addTableHistoryInfo(job, ver, tblInfo)
+	return nil
+}
+
+// copyShadowTable copies the rows of the table in the snapshot of the reorganization to the shadow table.
+// The rows added after the snapshot are written to the shadow table by the statements.
+func (d *ddl) copyShadowTable(t table.Table, reorgInfo *reorgInfo, job *model.Job, throttle time.Duration) error {
+	seekHandle := reorgInfo.Handle
+	version := reorgInfo.SnapshotVer
+	count := job.GetRowCount()
+
+	for {
+		startTime := time.Now()
+		handles, err := d.getSnapshotRows(t, version, seekHandle, math.MaxInt64)
+		if err != nil {
+			return errors.Trace(err)
+		} else if len(handles) == 0 {
+			return nil
+		}
+
+		count += int64(len(handles))
+		seekHandle = handles[len(handles)-1] + 1
+		err = d.backfillShadowTable(t, handles, reorgInfo, throttle)
+		sub := time.Since(startTime).Seconds()
+		if err != nil {
+			log.Warnf("[ddl] copied %v rows to the shadow table failed, take time %v", count, sub)
+			return errors.Trace(err)
+		}
+
+		job.SetRowCount(count)
+		batchHandleDataHistogram.WithLabelValues(batchCopyShadow).Observe(sub)
+		log.Infof("[ddl] copied %v rows to the shadow table, take time %v", count, sub)
+	}
+}
+
+// backfillShadowTable copies the rows of the handles in the transactions of defaultSmallBatchCnt rows, and pauses
+// for the throttle after each transaction. Each transaction checks whether a statement has marked the shadow table
+// broken.
+func (d *ddl) backfillShadowTable(t table.Table, handles []int64, reorgInfo *reorgInfo, throttle time.Duration) error {
+	var endIdx int
+	rowCount := reorgInfo.progress.RowCount
+	for len(handles) > 0 {
+		if len(handles) >= defaultSmallBatchCnt {
+			endIdx = defaultSmallBatchCnt
+		} else {
+			endIdx = len(handles)
+		}
+
+		err := kv.RunInNewTxn(d.store, true, func(txn kv.Transaction) error {
+			if err := d.isReorgRunnable(txn, ddlJobFlag); err != nil {
+				return errors.Trace(err)
+			}
+			if err := tables.CheckShadowBroken(txn, t); err != nil {
+				return errors.Trace(err)
+			}
+
+			for _, handle := range handles[:endIdx] {
+				if err := tables.CopyShadowRecord(txn, t, handle); err != nil {
+					return errors.Trace(err)
+				}
+			}
+			return errors.Trace(reorgInfo.UpdateHandle(txn, handles[endIdx-1], rowCount+int64(endIdx)))
+		})
+		if err != nil {
+			return errors.Trace(err)
+		}
+		rowCount += int64(endIdx)
+		handles = handles[endIdx:]
+		if throttle > 0 {
+			time.Sleep(throttle)
+		}
+	}
+
+	return nil
+}
@@ -15,6 +15,7 @@ package ddl
 
 import (
 	"github.com/juju/errors"
+	"github.com/pingcap/tidb/ast"
 	"github.com/pingcap/tidb/infoschema"
 	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/meta"
@@ -77,6 +78,74 @@ func (d *ddl) onCreateTable(t *meta.Meta, job *model.Job) error {
 	}
 }
 
+func (d *ddl) onCreateView(t *meta.Meta, job *model.Job) error {
+	schemaID := job.SchemaID
+	tbInfo := &model.TableInfo{}
+	var orReplace bool
+	if err := job.DecodeArgs(tbInfo, &orReplace); err != nil {
+		// Invalid arguments, cancel this job.
+		job.State = model.JobCancelled
+		return errors.Trace(err)
+	}
+
+	tbInfo.State = model.StateNone
+
+	// Check this view's database.
+	tables, err := t.ListTables(schemaID)
+	if terror.ErrorEqual(err, meta.ErrDBNotExists) {
+		job.State = model.JobCancelled
+		return errors.Trace(infoschema.ErrDatabaseNotExists)
+	} else if err != nil {
+		return errors.Trace(err)
+	}
+
+	// Check the table with the same name.
+	var oldTbInfo *model.TableInfo
+	for _, tbl := range tables {
+		if tbl.Name.L != tbInfo.Name.L {
+			continue
+		}
+		if !orReplace {
+			job.State = model.JobCancelled
+			return errors.Trace(infoschema.ErrTableExists)
+		}
+		if !tbl.IsView() {
+			job.State = model.JobCancelled
+			return errors.Trace(infoschema.ErrWrongObject)
+		}
+		// The view is replaced in place, so it keeps the table ID.
+		oldTbInfo = tbl
+		tbInfo.ID = tbl.ID
+		job.TableID = tbl.ID
+	}
+
+	ver, err := updateSchemaVersion(t, job)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	switch tbInfo.State {
+	case model.StateNone:
+		// none -> public
+		job.SchemaState = model.StatePublic
+		tbInfo.State = model.StatePublic
+		if oldTbInfo != nil {
+			err = t.UpdateTable(schemaID, tbInfo)
+		} else {
+			err = t.CreateTable(schemaID, tbInfo)
+		}
+		if err != nil {
+			return errors.Trace(err)
+		}
+		// Finish this job.
+		job.State = model.JobDone
+		addTableHistoryInfo(job, ver, tbInfo)
+		return nil
+	default:
+		return ErrInvalidTableState.Gen("invalid table state %v", tbInfo.State)
+	}
+}
+
 func (d *ddl) onDropTable(t *meta.Meta, job *model.Job) error {
 	schemaID := job.SchemaID
 	tableID := job.TableID
@@ -134,7 +203,7 @@ func (d *ddl) onDropTable(t *meta.Meta, job *model.Job) error {
 // Maximum number of keys to delete for each reorg table job run.
 var reorgTableDeleteLimit = 65536
 
-func (d *ddl) delReorgTable(t *meta.Meta, job *model.Job) error {
+func (d *ddl) delReorgTable(job *model.Job) error {
 	var startKey kv.Key
 	if err := job.DecodeArgs(&startKey); err != nil {
 		job.State = model.JobCancelled
@@ -236,3 +305,32 @@ func (d *ddl) onTruncateTable(t *meta.Meta, job *model.Job) error {
 	job.Args = append(job.Args, startKey)
 	return nil
 }
+
+func (d *ddl) onSetStatsNDV(t *meta.Meta, job *model.Job) error {
+	var ndvs []*ast.StatsNDV
+	if err := job.DecodeArgs(&ndvs); err != nil {
+		job.State = model.JobCancelled
+		return errors.Trace(err)
+	}
+	tblInfo, err := d.getTableInfo(t, job)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if err = setStatsNDVs(tblInfo, ndvs); err != nil {
+		job.State = model.JobCancelled
+		return errors.Trace(err)
+	}
+	err = t.UpdateTable(job.SchemaID, tblInfo)
+	if err != nil {
+		job.State = model.JobCancelled
+		return errors.Trace(err)
+	}
+	ver, err := updateSchemaVersion(t, job)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	job.SchemaState = model.StatePublic
+	job.State = model.JobDone
+	addTableHistoryInfo(job, ver, tblInfo)
+	return nil
+}
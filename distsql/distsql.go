@@ -60,6 +60,9 @@ type PartialResult interface {
 	// Next returns the next row of the sub result.
 	// If no more row to return, data would be nil.
 	Next() (handle int64, data []types.Datum, err error)
+	// NextLazy returns the next row of the sub result whose columns are decoded on demand.
+	// The row is only valid until the next call. If no more row to return, row would be nil.
+	NextLazy() (handle int64, row *LazyRow, err error)
 	// Close closes the partial result.
 	Close() error
 }
@@ -158,6 +161,8 @@ type partialResult struct {
 
 	done    chan error
 	fetched bool
+	// lazyRow is reused by NextLazy to save the allocations.
+	lazyRow LazyRow
 }
 
 func (pr *partialResult) fetch() {
@@ -190,64 +195,80 @@ var dummyData = make([]types.Datum, 0)
 // Next returns the next row of the sub result.
 // If no more row to return, data would be nil.
 func (pr *partialResult) Next() (handle int64, data []types.Datum, err error) {
+	handle, rowData, ok, err := pr.nextRowData()
+	if err != nil || !ok {
+		return 0, nil, err
+	}
+	if !pr.ignoreData {
+		data, err = tablecodec.DecodeValues(rowData, pr.fields, pr.index)
+		if err != nil {
+			return 0, nil, errors.Trace(err)
+		}
+	}
+	if data == nil {
+		// When no column is referenced, the data may be nil, like 'select count(*) from t'.
+		// In this case, we need to create a zero length datum slice,
+		// as caller will check if data is nil to finish iteration.
+		// data = make([]types.Datum, 0)
+		data = dummyData
+	}
+	return handle, data, nil
+}
+
+// NextLazy returns the next row of the sub result whose columns are decoded on demand.
+// The row is only valid until the next call. If no more row to return, row would be nil.
+func (pr *partialResult) NextLazy() (handle int64, row *LazyRow, err error) {
+	handle, rowData, ok, err := pr.nextRowData()
+	if err != nil || !ok {
+		return 0, nil, err
+	}
+	if pr.ignoreData {
+		rowData = nil
+	}
+	if err = pr.lazyRow.reset(rowData, pr.fields); err != nil {
+		return 0, nil, errors.Trace(err)
+	}
+	return handle, &pr.lazyRow, nil
+}
+
+// nextRowData returns the handle and the encoded values of the next row, ok is false if no more row to return.
+func (pr *partialResult) nextRowData() (handle int64, rowData []byte, ok bool, err error) {
 	if !pr.fetched {
 		err = <-pr.done
 		pr.fetched = true
 		if err != nil {
-			return 0, nil, err
+			return 0, nil, false, err
 		}
 	}
 	if len(pr.resp.Chunks) > 0 {
 		// For new resp rows structure.
 		chunk := pr.getChunk()
 		if chunk == nil {
-			return 0, nil, nil
+			return 0, nil, false, nil
 		}
 		rowMeta := chunk.RowsMeta[pr.cursor]
-		if !pr.ignoreData {
-			rowData := chunk.RowsData[pr.dataOffset : pr.dataOffset+rowMeta.Length]
-			data, err = tablecodec.DecodeValues(rowData, pr.fields, pr.index)
-			if err != nil {
-				return 0, nil, errors.Trace(err)
-			}
-			pr.dataOffset += rowMeta.Length
-		}
-		if data == nil {
-			data = dummyData
-		}
+		rowData = chunk.RowsData[pr.dataOffset : pr.dataOffset+rowMeta.Length]
+		pr.dataOffset += rowMeta.Length
 		if !pr.aggregate {
 			handle = rowMeta.Handle
 		}
 		pr.cursor++
-		return
+		return handle, rowData, true, nil
 	}
 	if pr.cursor >= len(pr.resp.Rows) {
-		return 0, nil, nil
+		return 0, nil, false, nil
 	}
 	row := pr.resp.Rows[pr.cursor]
-	if !pr.ignoreData {
-		data, err = tablecodec.DecodeValues(row.Data, pr.fields, pr.index)
-		if err != nil {
-			return 0, nil, errors.Trace(err)
-		}
-	}
-	if data == nil {
-		// When no column is referenced, the data may be nil, like 'select count(*) from t'.
-		// In this case, we need to create a zero length datum slice,
-		// as caller will check if data is nil to finish iteration.
-		// data = make([]types.Datum, 0)
-		data = dummyData
-	}
 	if !pr.aggregate {
 		handleBytes := row.GetHandle()
 		_, datum, err := codec.DecodeOne(handleBytes)
 		if err != nil {
-			return 0, nil, errors.Trace(err)
+			return 0, nil, false, errors.Trace(err)
 		}
 		handle = datum.GetInt64()
 	}
 	pr.cursor++
-	return
+	return handle, row.Data, true, nil
 }
 
 func (pr *partialResult) getChunk() *tipb.Chunk {
@@ -274,7 +295,9 @@ func (pr *partialResult) Close() error {
 // conncurrency: The max concurrency for underlying coprocessor request.
 // keepOrder: If the result should returned in key order. For example if we need keep data in order by
 //            scan index, we should set keepOrder to true.
-func Select(client kv.Client, req *tipb.SelectRequest, keyRanges []kv.KeyRange, concurrency int, keepOrder bool) (SelectResult, error) {
+// killed: The kill signal of the session, the request is interrupted once it's set to 1.
+func Select(client kv.Client, req *tipb.SelectRequest, keyRanges []kv.KeyRange, concurrency int, keepOrder bool,
+	killed *uint32) (SelectResult, error) {
 	var err error
 	startTs := time.Now()
 	defer func() {
@@ -288,7 +311,7 @@ func Select(client kv.Client, req *tipb.SelectRequest, keyRanges []kv.KeyRange,
 	}()
 
 	// Convert tipb.*Request to kv.Request.
-	kvReq, err1 := composeRequest(req, keyRanges, concurrency, keepOrder)
+	kvReq, err1 := composeRequest(req, keyRanges, concurrency, keepOrder, killed)
 	if err1 != nil {
 		err = errors.Trace(err1)
 		return nil, err
@@ -325,11 +348,13 @@ func Select(client kv.Client, req *tipb.SelectRequest, keyRanges []kv.KeyRange,
 }
 
 // Convert tipb.Request to kv.Request.
-func composeRequest(req *tipb.SelectRequest, keyRanges []kv.KeyRange, concurrency int, keepOrder bool) (*kv.Request, error) {
+func composeRequest(req *tipb.SelectRequest, keyRanges []kv.KeyRange, concurrency int, keepOrder bool,
+	killed *uint32) (*kv.Request, error) {
 	kvReq := &kv.Request{
 		Concurrency: concurrency,
 		KeepOrder:   keepOrder,
 		KeyRanges:   keyRanges,
+		Killed:      killed,
 	}
 	if req.IndexInfo != nil {
 		kvReq.Tp = kv.ReqTypeIndex
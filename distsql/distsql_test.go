@@ -25,6 +25,7 @@ import (
 	. "github.com/pingcap/check"
 	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/util/codec"
 	"github.com/pingcap/tidb/util/testleak"
 	"github.com/pingcap/tidb/util/types"
 	"github.com/pingcap/tipb/go-tipb"
@@ -89,6 +90,48 @@ func (s *testTableCodecSuite) TestGoroutineLeak(c *C) {
 	c.Error("distsql goroutine leak!")
 }
 
+func (s *testTableCodecSuite) TestNextLazy(c *C) {
+	defer testleak.AfterTest(c)()
+	fields := []*types.FieldType{types.NewFieldType(mysql.TypeLonglong), types.NewFieldType(mysql.TypeBlob)}
+	chunk := tipb.Chunk{}
+	for i, text := range []string{"a", "bb"} {
+		data, err := codec.EncodeValue(nil, types.NewIntDatum(int64(i)), types.NewBytesDatum([]byte(text)))
+		c.Assert(err, IsNil)
+		chunk.RowsData = append(chunk.RowsData, data...)
+		chunk.RowsMeta = append(chunk.RowsMeta, tipb.RowMeta{Handle: int64(i + 1), Length: int64(len(data))})
+	}
+	pr := &partialResult{
+		fields:  fields,
+		resp:    &tipb.SelectResponse{Chunks: []tipb.Chunk{chunk}},
+		fetched: true,
+	}
+
+	h, row, err := pr.NextLazy()
+	c.Assert(err, IsNil)
+	c.Assert(h, Equals, int64(1))
+	c.Assert(row.Len(), Equals, 2)
+	// Only the first column is decoded.
+	data, err := row.DecodeColumns([]int{0})
+	c.Assert(err, IsNil)
+	c.Assert(data[0].GetInt64(), Equals, int64(0))
+	c.Assert(data[1].IsNull(), IsTrue)
+	data, err = row.Datums()
+	c.Assert(err, IsNil)
+	c.Assert(data[1].GetBytes(), BytesEquals, []byte("a"))
+
+	h, row, err = pr.NextLazy()
+	c.Assert(err, IsNil)
+	c.Assert(h, Equals, int64(2))
+	data, err = row.DecodeColumns([]int{1})
+	c.Assert(err, IsNil)
+	c.Assert(data[0].IsNull(), IsTrue)
+	c.Assert(data[1].GetBytes(), BytesEquals, []byte("bb"))
+
+	_, row, err = pr.NextLazy()
+	c.Assert(err, IsNil)
+	c.Assert(row, IsNil)
+}
+
 type mockResponse struct {
 	count int
 }
@@ -0,0 +1,91 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package distsql
+
+import (
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/tablecodec"
+	"github.com/pingcap/tidb/util/types"
+)
+
+// LazyRow is a row of a partial result whose columns are cut from the row data but decoded on demand, so the
+// columns which are never read aren't decoded, e.g. the wide text columns of the rows filtered out by a condition
+// on another column.
+type LazyRow struct {
+	fields  []*types.FieldType
+	values  [][]byte
+	datums  []types.Datum
+	decoded []bool
+}
+
+// reset cuts the row data into the column values, the buffers of the values are reused.
+func (r *LazyRow) reset(data []byte, fields []*types.FieldType) error {
+	var err error
+	r.fields = fields
+	r.values, err = tablecodec.CutValues(data, fields, r.values[:0])
+	if err != nil {
+		return errors.Trace(err)
+	}
+	// The datums are returned to the caller, so they are allocated for each row.
+	r.datums = make([]types.Datum, len(r.values))
+	if cap(r.decoded) < len(r.values) {
+		r.decoded = make([]bool, len(r.values))
+	}
+	r.decoded = r.decoded[:len(r.values)]
+	for i := range r.decoded {
+		r.decoded[i] = false
+	}
+	return nil
+}
+
+// Len returns the number of the columns in the row.
+func (r *LazyRow) Len() int {
+	return len(r.values)
+}
+
+// DecodeColumns decodes the columns at the offsets and returns the datums of the row, in which the columns not
+// decoded yet are NULLs.
+func (r *LazyRow) DecodeColumns(offsets []int) ([]types.Datum, error) {
+	for _, i := range offsets {
+		if i >= len(r.values) || r.decoded[i] {
+			continue
+		}
+		d, err := tablecodec.DecodeColumnValue(r.values[i], r.fields[i])
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		r.datums[i] = d
+		r.decoded[i] = true
+	}
+	return r.datums, nil
+}
+
+// Datums decodes all the columns and returns the datums of the row, which is nil if the row has no column.
+func (r *LazyRow) Datums() ([]types.Datum, error) {
+	if len(r.values) == 0 {
+		return nil, nil
+	}
+	for i := range r.values {
+		if r.decoded[i] {
+			continue
+		}
+		d, err := tablecodec.DecodeColumnValue(r.values[i], r.fields[i])
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		r.datums[i] = d
+		r.decoded[i] = true
+	}
+	return r.datums, nil
+}
@@ -77,6 +77,9 @@ func (e *Evaluator) Eval(expr *tipb.Expr) (types.Datum, error) {
 		return e.evalCoalesce(expr)
 	case tipb.ExprType_IsNull:
 		return e.evalIsNull(expr)
+	// time functions
+	case tipb.ExprType_DateAdd, tipb.ExprType_DateSub:
+		return e.evalDateArith(expr)
 	}
 	return types.Datum{}, nil
 }
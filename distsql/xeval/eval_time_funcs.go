@@ -0,0 +1,44 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xeval
+
+import (
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/util/types"
+	"github.com/pingcap/tipb/go-tipb"
+)
+
+// evalDateArith evaluates DateAdd and DateSub, whose children are the date, the interval and the unit.
+func (e *Evaluator) evalDateArith(expr *tipb.Expr) (types.Datum, error) {
+	if len(expr.Children) != 3 {
+		return types.Datum{}, ErrInvalid.Gen("%s needs 3 operands but got %d", tipb.ExprType_name[int32(expr.GetTp())], len(expr.Children))
+	}
+	args := make([]types.Datum, 0, len(expr.Children))
+	for _, child := range expr.Children {
+		d, err := e.Eval(child)
+		if err != nil {
+			return types.Datum{}, errors.Trace(err)
+		}
+		args = append(args, d)
+	}
+	unit, err := args[2].ToString()
+	if err != nil {
+		return types.Datum{}, errors.Trace(err)
+	}
+	d, err := types.DateArith(args[0], args[1], unit, expr.GetTp() == tipb.ExprType_DateSub)
+	if err != nil {
+		return types.Datum{}, ErrInvalid.Gen("%s %s", tipb.ExprType_name[int32(expr.GetTp())], err)
+	}
+	return d, nil
+}
@@ -0,0 +1,68 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xeval
+
+import (
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/util/types"
+	"github.com/pingcap/tipb/go-tipb"
+)
+
+func (s *testEvalSuite) TestEvalDateArith(c *C) {
+	date, err := types.ParseTime("2017-01-31 10:10:10", mysql.TypeDatetime, 0)
+	c.Assert(err, IsNil)
+	row := map[int64]types.Datum{
+		1: types.NewDatum(date),
+		2: types.NewIntDatum(-1),
+	}
+	xevaluator := &Evaluator{Row: row}
+	cases := []struct {
+		expr   *tipb.Expr
+		result interface{}
+	}{
+		{
+			expr:   buildExpr(tipb.ExprType_DateAdd, columnExpr(1), types.NewIntDatum(1), types.NewStringDatum("MONTH")),
+			result: "2017-02-28 10:10:10",
+		},
+		{
+			expr:   buildExpr(tipb.ExprType_DateSub, columnExpr(1), columnExpr(2), types.NewStringDatum("DAY")),
+			result: "2017-02-01 10:10:10",
+		},
+		{
+			expr:   buildExpr(tipb.ExprType_DateAdd, columnExpr(1), types.NewStringDatum("1:1"), types.NewStringDatum("HOUR_MINUTE")),
+			result: "2017-01-31 11:11:10",
+		},
+		{
+			expr:   buildExpr(tipb.ExprType_DateAdd, columnExpr(1), types.NewIntDatum(8000), types.NewStringDatum("YEAR")),
+			result: nil,
+		},
+		{
+			expr:   buildExpr(tipb.ExprType_DateAdd, columnExpr(1), types.Datum{}, types.NewStringDatum("DAY")),
+			result: nil,
+		},
+	}
+	for _, ca := range cases {
+		result, err := xevaluator.Eval(ca.expr)
+		c.Assert(err, IsNil)
+		if ca.result == nil {
+			c.Assert(result.IsNull(), IsTrue)
+			continue
+		}
+		c.Assert(result.GetMysqlTime().String(), Equals, ca.result)
+	}
+
+	_, err = xevaluator.Eval(buildExpr(tipb.ExprType_DateAdd, columnExpr(1), types.NewIntDatum(1)))
+	c.Assert(err, NotNil)
+}
@@ -60,7 +60,7 @@ var Funcs = map[string]Func{
 	ast.CurrentDate:      {builtinCurrentDate, 0, 0},
 	ast.CurrentTime:      {builtinCurrentTime, 0, 1},
 	ast.Date:             {builtinDate, 1, 1},
-	ast.DateArith:        {builtinDateArith, 3, 3},
+	ast.DateArith:        {builtinDateArith, 4, 4},
 	ast.DateFormat:       {builtinDateFormat, 2, 2},
 	ast.CurrentTimestamp: {builtinNow, 0, 1},
 	ast.Curtime:          {builtinCurrentTime, 0, 1},
@@ -171,6 +171,28 @@ var Funcs = map[string]Func{
 	ast.GetVar:     {builtinGetVar, 1, 1},
 }
 
+// BuiltinFuncFactory creates a builtin function which keeps scratch buffers across the rows,
+// so every expression must create its own one.
+type BuiltinFuncFactory func() BuiltinFunc
+
+// StatefulFuncs holds the builtin functions that can reuse per-expression scratch buffers.
+// The ones in Funcs are still used when there is no expression to own the buffers, e.g. constant folding.
+var StatefulFuncs = map[string]BuiltinFuncFactory{
+	ast.Like:       newLikeFunc,
+	ast.Regexp:     newRegexpFunc,
+	ast.DateFormat: newDateFormatFunc,
+	ast.DateArith:  newDateArithFunc,
+	ast.Convert:    newConvertFunc,
+}
+
+// NewBuiltinFunc returns the function to evaluate the builtin function named funcName for an expression.
+func NewBuiltinFunc(funcName string, f Func) BuiltinFunc {
+	if newFunc, ok := StatefulFuncs[funcName]; ok {
+		return newFunc()
+	}
+	return f.F
+}
+
 // DynamicFuncs are those functions that
 // use input parameter ctx or
 // return an uncertain result would not be constant folded
@@ -187,6 +209,10 @@ var DynamicFuncs = map[string]int{
 	"sleep":          0,
 	ast.GetVar:       0,
 	ast.SetVar:       0,
+	// strcmp, from_unixtime and round depend on tidb_compat_version, see compat.go.
+	"strcmp":        0,
+	"from_unixtime": 0,
+	"round":         0,
 }
 
 // See http://dev.mysql.com/doc/refman/5.7/en/comparison-operators.html#function_coalesce
@@ -20,7 +20,6 @@ package evaluator
 import (
 	"github.com/juju/errors"
 	"github.com/pingcap/tidb/context"
-	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/util/types"
 )
 
@@ -91,6 +90,6 @@ func builtinLastInsertID(args []types.Datum, ctx context.Context) (d types.Datum
 }
 
 func builtinVersion(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
-	d.SetString(mysql.ServerVersion)
+	d.SetString(compatServerVersions[compatVersion(ctx)])
 	return d, nil
 }
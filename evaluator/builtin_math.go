@@ -23,6 +23,7 @@ import (
 
 	"github.com/juju/errors"
 	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/sessionctx/variable"
 	"github.com/pingcap/tidb/util/types"
 )
 
@@ -95,12 +96,7 @@ func builtinPow(args []types.Datum, _ context.Context) (d types.Datum, err error
 }
 
 // See http://dev.mysql.com/doc/refman/5.7/en/mathematical-functions.html#function_round
-func builtinRound(args []types.Datum, _ context.Context) (d types.Datum, err error) {
-	x, err := args[0].ToFloat64()
-	if err != nil {
-		return d, errors.Trace(err)
-	}
-
+func builtinRound(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
 	dec := 0
 	if len(args) == 2 {
 		y, err1 := args[1].ToInt64()
@@ -109,6 +105,14 @@ func builtinRound(args []types.Datum, _ context.Context) (d types.Datum, err err
 		}
 		dec = int(y)
 	}
+	if compatVersion(ctx) != variable.CompatNative {
+		return roundCompat(args[0], dec)
+	}
+
+	x, err := args[0].ToFloat64()
+	if err != nil {
+		return d, errors.Trace(err)
+	}
 	d.SetFloat64(types.Round(x, dec))
 	return d, nil
 }
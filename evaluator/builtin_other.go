@@ -15,7 +15,9 @@ package evaluator
 
 import (
 	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/juju/errors"
@@ -148,8 +150,53 @@ func builtinCaseWhen(args []types.Datum, _ context.Context) (d types.Datum, err
 	return
 }
 
+// likePattern is a compiled LIKE pattern, the buffers are reused when the pattern changes.
+type likePattern struct {
+	pattern  []byte
+	escape   byte
+	compiled bool
+	patChars []byte
+	patTypes []byte
+}
+
+// compile compiles the pattern if it differs from the last compiled one.
+func (p *likePattern) compile(pattern string, escape byte) {
+	if p.compiled && p.escape == escape && string(p.pattern) == pattern {
+		return
+	}
+	// The pattern may point to the memory of a row, so we keep a copy of it.
+	p.pattern = append(p.pattern[:0], pattern...)
+	p.escape = escape
+	p.patChars, p.patTypes = compilePatternWithBuffer(pattern, escape, p.patChars, p.patTypes)
+	p.compiled = true
+}
+
+// likePatternPool reuses the compiled patterns for the LIKE functions which don't belong to an expression,
+// e.g. the ones used in constant folding.
+var likePatternPool = sync.Pool{
+	New: func() interface{} {
+		return &likePattern{}
+	},
+}
+
 // See http://dev.mysql.com/doc/refman/5.7/en/string-comparison-functions.html
 func builtinLike(args []types.Datum, _ context.Context) (d types.Datum, err error) {
+	p := likePatternPool.Get().(*likePattern)
+	d, err = evalLike(args, p)
+	likePatternPool.Put(p)
+	return
+}
+
+// newLikeFunc creates a LIKE function which keeps its compiled pattern,
+// so a constant pattern is compiled only once instead of for every row.
+func newLikeFunc() BuiltinFunc {
+	p := &likePattern{}
+	return func(args []types.Datum, _ context.Context) (types.Datum, error) {
+		return evalLike(args, p)
+	}
+}
+
+func evalLike(args []types.Datum, p *likePattern) (d types.Datum, err error) {
 	if args[0].IsNull() {
 		return
 	}
@@ -159,7 +206,6 @@ func builtinLike(args []types.Datum, _ context.Context) (d types.Datum, err erro
 		return d, errors.Trace(err)
 	}
 
-	// TODO: We don't need to compile pattern if it has been compiled or it is static.
 	if args[1].IsNull() {
 		return
 	}
@@ -168,15 +214,47 @@ func builtinLike(args []types.Datum, _ context.Context) (d types.Datum, err erro
 		return d, errors.Trace(err)
 	}
 	escape := byte(args[2].GetInt64())
-	patChars, patTypes := compilePattern(patternStr, escape)
-	match := doMatch(valStr, patChars, patTypes)
+	p.compile(patternStr, escape)
+	match := doMatch(valStr, p.patChars, p.patTypes)
 	d.SetInt64(boolToInt64(match))
 	return
 }
 
+// regexpCache keeps the last compiled regular expression.
+type regexpCache struct {
+	pattern []byte
+	re      *regexp.Regexp
+}
+
+// compile compiles the pattern if it differs from the last compiled one.
+func (c *regexpCache) compile(pattern string) (*regexp.Regexp, error) {
+	if c.re != nil && string(c.pattern) == pattern {
+		return c.re, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	c.pattern = append(c.pattern[:0], pattern...)
+	c.re = re
+	return re, nil
+}
+
 // See http://dev.mysql.com/doc/refman/5.7/en/regexp.html#operator_regexp
 func builtinRegexp(args []types.Datum, _ context.Context) (d types.Datum, err error) {
-	// TODO: We don't need to compile pattern if it has been compiled or it is static.
+	return evalRegexp(args, &regexpCache{})
+}
+
+// newRegexpFunc creates a REGEXP function which keeps its compiled regular expression,
+// so a constant pattern is compiled only once instead of for every row.
+func newRegexpFunc() BuiltinFunc {
+	c := &regexpCache{}
+	return func(args []types.Datum, _ context.Context) (types.Datum, error) {
+		return evalRegexp(args, c)
+	}
+}
+
+func evalRegexp(args []types.Datum, c *regexpCache) (d types.Datum, err error) {
 	if args[0].IsNull() || args[1].IsNull() {
 		return
 	}
@@ -189,7 +267,7 @@ func builtinRegexp(args []types.Datum, _ context.Context) (d types.Datum, err er
 	if err != nil {
 		return d, errors.Errorf("non-string Expression in LIKE: %v (Value of type %T)", args[1], args[1])
 	}
-	re, err := regexp.Compile(patternStr)
+	re, err := c.compile(patternStr)
 	if err != nil {
 		return d, errors.Trace(err)
 	}
@@ -233,6 +311,90 @@ func builtinIn(args []types.Datum, _ context.Context) (d types.Datum, err error)
 	return
 }
 
+// InSetThreshold is the length of the constant IN list above which the list is probed by an InSet
+// instead of being compared with one by one.
+const InSetThreshold = 64
+
+// InSet is the hash set of the constant values of an IN list.
+// Only the lists of integers or the lists of strings are hashed, the other ones are compared with one by one.
+type InSet struct {
+	values  map[string]struct{}
+	isInt   bool
+	hasNull bool
+	buf     []byte
+}
+
+// NewInSet creates an InSet for values, it returns nil if the values can't be hashed.
+func NewInSet(values []types.Datum) *InSet {
+	s := &InSet{values: make(map[string]struct{}, len(values))}
+	var kindSet bool
+	for _, v := range values {
+		if v.IsNull() {
+			s.hasNull = true
+			continue
+		}
+		isInt, ok := inSetKind(v)
+		if !ok {
+			return nil
+		}
+		if !kindSet {
+			s.isInt, kindSet = isInt, true
+		} else if isInt != s.isInt {
+			return nil
+		}
+		s.values[string(s.key(v))] = struct{}{}
+	}
+	return s
+}
+
+// inSetKind returns whether d is hashed as an integer, ok is false if d can't be hashed.
+func inSetKind(d types.Datum) (isInt bool, ok bool) {
+	switch d.Kind() {
+	case types.KindInt64, types.KindUint64:
+		return true, true
+	case types.KindString, types.KindBytes:
+		return false, true
+	}
+	return false, false
+}
+
+// key returns the hash key of d. The integers are keyed by their values regardless of the signedness,
+// so a negative int64 never equals to an uint64.
+func (s *InSet) key(d types.Datum) []byte {
+	s.buf = s.buf[:0]
+	switch d.Kind() {
+	case types.KindInt64:
+		if v := d.GetInt64(); v < 0 {
+			return strconv.AppendInt(s.buf, v, 10)
+		}
+		return strconv.AppendUint(s.buf, uint64(d.GetInt64()), 10)
+	case types.KindUint64:
+		return strconv.AppendUint(s.buf, d.GetUint64(), 10)
+	}
+	return append(s.buf, d.GetBytes()...)
+}
+
+// Check evaluates "target IN (values)" like builtinIn. It returns false for ok if target can't be probed by the set,
+// the caller should compare target with the values one by one instead.
+func (s *InSet) Check(target types.Datum) (d types.Datum, ok bool) {
+	if target.IsNull() {
+		return d, true
+	}
+	isInt, ok := inSetKind(target)
+	if !ok || (isInt != s.isInt && len(s.values) > 0) {
+		return d, false
+	}
+	if _, ok := s.values[string(s.key(target))]; ok {
+		d.SetInt64(1)
+		return d, true
+	}
+	if s.hasNull {
+		return d, true
+	}
+	d.SetInt64(0)
+	return d, true
+}
+
 func builtinLogicXor(args []types.Datum, _ context.Context) (d types.Datum, err error) {
 	leftDatum := args[0]
 	righDatum := args[1]
@@ -257,55 +419,7 @@ func builtinLogicXor(args []types.Datum, _ context.Context) (d types.Datum, err
 }
 
 func compareFuncFactory(op opcode.Op) BuiltinFunc {
-	return func(args []types.Datum, _ context.Context) (d types.Datum, err error) {
-		var a, b = args[0], args[1]
-		if op != opcode.NullEQ {
-			a, b, err = types.CoerceDatum(a, b)
-			if err != nil {
-				return d, errors.Trace(err)
-			}
-		}
-		if a.IsNull() || b.IsNull() {
-			// for <=>, if a and b are both nil, return true.
-			// if a or b is nil, return false.
-			if op == opcode.NullEQ {
-				if a.IsNull() && b.IsNull() {
-					d.SetInt64(oneI64)
-				} else {
-					d.SetInt64(zeroI64)
-				}
-			}
-			return
-		}
-
-		n, err := a.CompareDatum(b)
-		if err != nil {
-			return d, errors.Trace(err)
-		}
-		var result bool
-		switch op {
-		case opcode.LT:
-			result = n < 0
-		case opcode.LE:
-			result = n <= 0
-		case opcode.EQ, opcode.NullEQ:
-			result = n == 0
-		case opcode.GT:
-			result = n > 0
-		case opcode.GE:
-			result = n >= 0
-		case opcode.NE:
-			result = n != 0
-		default:
-			return d, ErrInvalidOperation.Gen("invalid op %v in comparison operation", op)
-		}
-		if result {
-			d.SetInt64(oneI64)
-		} else {
-			d.SetInt64(zeroI64)
-		}
-		return
-	}
+	return withScratchPool(compareScratchFunc(op))
 }
 
 func bitOpFactory(op opcode.Op) BuiltinFunc {
@@ -348,41 +462,7 @@ func bitOpFactory(op opcode.Op) BuiltinFunc {
 }
 
 func arithmeticFuncFactory(op opcode.Op) BuiltinFunc {
-	return func(args []types.Datum, _ context.Context) (d types.Datum, err error) {
-		a, err := types.CoerceArithmetic(args[0])
-		if err != nil {
-			return d, errors.Trace(err)
-		}
-
-		b, err := types.CoerceArithmetic(args[1])
-		if err != nil {
-			return d, errors.Trace(err)
-		}
-		a, b, err = types.CoerceDatum(a, b)
-		if err != nil {
-			return d, errors.Trace(err)
-		}
-		if a.IsNull() || b.IsNull() {
-			return
-		}
-
-		switch op {
-		case opcode.Plus:
-			return types.ComputePlus(a, b)
-		case opcode.Minus:
-			return types.ComputeMinus(a, b)
-		case opcode.Mul:
-			return types.ComputeMul(a, b)
-		case opcode.Div:
-			return types.ComputeDiv(a, b)
-		case opcode.Mod:
-			return types.ComputeMod(a, b)
-		case opcode.IntDiv:
-			return types.ComputeIntDiv(a, b)
-		default:
-			return d, ErrInvalidOperation.Gen("invalid op %v in arithmetic operation", op)
-		}
-	}
+	return withScratchPool(arithmeticScratchFunc(op))
 }
 
 func builtinRow(row []types.Datum, _ context.Context) (d types.Datum, err error) {
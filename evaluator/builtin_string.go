@@ -29,6 +29,7 @@ import (
 	"github.com/pingcap/tidb/ast"
 	"github.com/pingcap/tidb/context"
 	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/sessionctx/variable"
 	"github.com/pingcap/tidb/util/charset"
 	"github.com/pingcap/tidb/util/hack"
 	"github.com/pingcap/tidb/util/stringutil"
@@ -237,7 +238,7 @@ func builtinUpper(args []types.Datum, _ context.Context) (d types.Datum, err err
 }
 
 // See https://dev.mysql.com/doc/refman/5.7/en/string-comparison-functions.html
-func builtinStrcmp(args []types.Datum, _ context.Context) (d types.Datum, err error) {
+func builtinStrcmp(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
 	if args[0].IsNull() || args[1].IsNull() {
 		return d, nil
 	}
@@ -249,6 +250,9 @@ func builtinStrcmp(args []types.Datum, _ context.Context) (d types.Datum, err er
 	if err != nil {
 		return d, errors.Trace(err)
 	}
+	if v := compatVersion(ctx); v == variable.CompatMySQL56 || v == variable.CompatMySQL57 {
+		left, right = strings.TrimRight(left, " "), strings.TrimRight(right, " ")
+	}
 	res := types.CompareString(left, right)
 	d.SetInt64(int64(res))
 	return d, nil
@@ -281,6 +285,38 @@ func builtinReplace(args []types.Datum, _ context.Context) (d types.Datum, err e
 
 // See https://dev.mysql.com/doc/refman/5.7/en/cast-functions.html#function_convert
 func builtinConvert(args []types.Datum, _ context.Context) (d types.Datum, err error) {
+	return evalConvert(args, &charsetDecoder{})
+}
+
+// charsetDecoder keeps the decoder of the last charset converted from.
+type charsetDecoder struct {
+	charset string
+	decoder transform.Transformer
+}
+
+// get returns the decoder of the charset, it returns nil if the charset is unknown.
+func (c *charsetDecoder) get(cs string) transform.Transformer {
+	if c.decoder != nil && c.charset == cs {
+		return c.decoder
+	}
+	encoding, _ := charset.Lookup(cs)
+	if encoding == nil {
+		return nil
+	}
+	c.charset, c.decoder = cs, encoding.NewDecoder()
+	return c.decoder
+}
+
+// newConvertFunc creates a CONVERT function which keeps its decoder, so the decoder of a constant charset is
+// created only once instead of for every row.
+func newConvertFunc() BuiltinFunc {
+	c := &charsetDecoder{}
+	return func(args []types.Datum, _ context.Context) (types.Datum, error) {
+		return evalConvert(args, c)
+	}
+}
+
+func evalConvert(args []types.Datum, c *charsetDecoder) (d types.Datum, err error) {
 	// Casting nil to any type returns nil
 	if args[0].Kind() != types.KindString {
 		return d, nil
@@ -297,12 +333,12 @@ func builtinConvert(args []types.Datum, _ context.Context) (d types.Datum, err e
 		return d, nil
 	}
 
-	encoding, _ := charset.Lookup(Charset)
-	if encoding == nil {
+	decoder := c.get(Charset)
+	if decoder == nil {
 		return d, errors.Errorf("unknown encoding: %s", Charset)
 	}
 
-	target, _, err := transform.String(encoding.NewDecoder(), str)
+	target, _, err := transform.String(decoder, str)
 	if err != nil {
 		log.Errorf("Convert %s to %s with error: %v", str, Charset, err)
 		return d, errors.Trace(err)
@@ -118,3 +118,39 @@ func (s *testEvaluatorSuite) TestLock(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(v.GetInt64(), Equals, int64(1))
 }
+
+func (s *testEvaluatorSuite) TestInSet(c *C) {
+	defer testleak.AfterTest(c)()
+
+	tbl := []struct {
+		values  []interface{}
+		targets []interface{}
+	}{
+		{[]interface{}{1, 3, int64(-5), uint64(7)}, []interface{}{1, 2, uint64(3), int64(-5), uint64(7), nil}},
+		{[]interface{}{1, nil, uint64(18446744073709551615)}, []interface{}{1, 2, int64(-1), uint64(18446744073709551615)}},
+		{[]interface{}{"a", []byte("b"), "abc"}, []interface{}{"a", "A", []byte("abc"), "ab", nil}},
+		{[]interface{}{"a", nil}, []interface{}{"a", "b"}},
+		{[]interface{}{nil}, []interface{}{"a", 1, nil}},
+	}
+	for _, t := range tbl {
+		values := types.MakeDatums(t.values...)
+		set := NewInSet(values)
+		c.Assert(set, NotNil)
+		for _, target := range types.MakeDatums(t.targets...) {
+			got, ok := set.Check(target)
+			c.Assert(ok, IsTrue)
+			expect, err := builtinIn(append([]types.Datum{target}, values...), nil)
+			c.Assert(err, IsNil)
+			c.Assert(got, testutil.DatumEquals, expect, Commentf("%v in %v", target.GetValue(), t.values))
+		}
+	}
+
+	// The values of different types or the types can't be hashed are compared one by one.
+	c.Assert(NewInSet(types.MakeDatums(1, "a")), IsNil)
+	c.Assert(NewInSet(types.MakeDatums(1, 1.5)), IsNil)
+	set := NewInSet(types.MakeDatums(1, 2))
+	_, ok := set.Check(types.NewDatum("1"))
+	c.Assert(ok, IsFalse)
+	_, ok = set.Check(types.NewDatum(1.0))
+	c.Assert(ok, IsFalse)
+}
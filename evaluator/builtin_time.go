@@ -20,14 +20,15 @@ package evaluator
 import (
 	"fmt"
 	"math"
-	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/juju/errors"
 	"github.com/pingcap/tidb/ast"
 	"github.com/pingcap/tidb/context"
 	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/sessionctx/variable"
 	"github.com/pingcap/tidb/util/types"
 )
 
@@ -280,37 +281,92 @@ func convertDateFormat(arg types.Datum, b byte) (types.Datum, error) {
 
 // See http://dev.mysql.com/doc/refman/5.7/en/date-and-time-functions.html#function_date-format
 func builtinDateFormat(args []types.Datum, _ context.Context) (types.Datum, error) {
-	var (
-		isPercent bool
-		ret       []byte
-		d         types.Datum
-	)
+	f := dateFormatPool.Get().(*dateFormat)
+	d, err := evalDateFormat(args, f)
+	dateFormatPool.Put(f)
+	return d, errors.Trace(err)
+}
 
-	// TODO: Some invalid format like 2000-00-01(the month is 0) will return null.
-	for _, b := range []byte(args[1].GetString()) {
+// dateFormatToken is a part of a compiled DATE_FORMAT format, the literal bytes or a specifier.
+type dateFormatToken struct {
+	literal   []byte
+	specifier byte
+}
+
+// dateFormat is a compiled DATE_FORMAT format, the buffers are reused when the format changes.
+type dateFormat struct {
+	format   []byte
+	compiled bool
+	tokens   []dateFormatToken
+	buf      []byte
+}
+
+// compile compiles the format if it differs from the last compiled one.
+func (f *dateFormat) compile(format string) {
+	if f.compiled && string(f.format) == format {
+		return
+	}
+	// The format may point to the memory of a row, so we keep a copy of it.
+	f.format = append(f.format[:0], format...)
+	f.tokens = f.tokens[:0]
+	var isPercent bool
+	for i := 0; i < len(f.format); i++ {
+		b := f.format[i]
 		if isPercent {
-			if b == '%' {
-				ret = append(ret, b)
-			} else {
-				str, err := convertDateFormat(args[0], b)
-				if err != nil {
-					return types.Datum{}, errors.Trace(err)
-				}
-				if str.IsNull() {
-					return types.Datum{}, nil
-				}
-				ret = append(ret, str.GetString()...)
-			}
 			isPercent = false
+			if b != '%' {
+				f.tokens = append(f.tokens, dateFormatToken{specifier: b})
+				continue
+			}
+		} else if b == '%' {
+			isPercent = true
 			continue
 		}
-		if b == '%' {
-			isPercent = true
-		} else {
-			ret = append(ret, b)
+		if n := len(f.tokens); n > 0 && f.tokens[n-1].specifier == 0 {
+			f.tokens[n-1].literal = append(f.tokens[n-1].literal, b)
+			continue
+		}
+		f.tokens = append(f.tokens, dateFormatToken{literal: []byte{b}})
+	}
+	f.compiled = true
+}
+
+// dateFormatPool reuses the compiled formats for the DATE_FORMAT functions which don't belong to an expression,
+// e.g. the ones used in constant folding.
+var dateFormatPool = sync.Pool{
+	New: func() interface{} {
+		return &dateFormat{}
+	},
+}
+
+// newDateFormatFunc creates a DATE_FORMAT function which keeps its compiled format and its result buffer,
+// so a constant format is compiled only once instead of for every row.
+func newDateFormatFunc() BuiltinFunc {
+	f := &dateFormat{}
+	return func(args []types.Datum, _ context.Context) (types.Datum, error) {
+		return evalDateFormat(args, f)
+	}
+}
+
+func evalDateFormat(args []types.Datum, f *dateFormat) (d types.Datum, err error) {
+	// TODO: Some invalid format like 2000-00-01(the month is 0) will return null.
+	f.compile(args[1].GetString())
+	f.buf = f.buf[:0]
+	for _, token := range f.tokens {
+		if token.specifier == 0 {
+			f.buf = append(f.buf, token.literal...)
+			continue
+		}
+		str, err := convertDateFormat(args[0], token.specifier)
+		if err != nil {
+			return d, errors.Trace(err)
+		}
+		if str.IsNull() {
+			return d, nil
 		}
+		f.buf = append(f.buf, str.GetString()...)
 	}
-	d.SetString(string(ret))
+	d.SetString(string(f.buf))
 	return d, nil
 }
 
@@ -607,12 +663,12 @@ func builtinYearWeek(args []types.Datum, _ context.Context) (types.Datum, error)
 }
 
 // See http://dev.mysql.com/doc/refman/5.7/en/date-and-time-functions.html#function_from-unixtime
-func builtinFromUnixTime(args []types.Datum, _ context.Context) (d types.Datum, err error) {
+func builtinFromUnixTime(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
 	unixTimeStamp, err := args[0].ToDecimal()
 	if err != nil {
 		return d, errors.Trace(err)
 	}
-	// 0 <= unixTimeStamp <= INT32_MAX
+	// 0 <= unixTimeStamp <= INT32_MAX, or maxUnixTimestamp80 if MySQL 8.0 is emulated.
 	if unixTimeStamp.IsNegative() {
 		return
 	}
@@ -623,7 +679,11 @@ func builtinFromUnixTime(args []types.Datum, _ context.Context) (d types.Datum,
 	if err != nil {
 		return d, errors.Trace(err)
 	}
-	if integralPart > int64(math.MaxInt32) {
+	maxTimestamp := int64(math.MaxInt32)
+	if compatVersion(ctx) == variable.CompatMySQL80 {
+		maxTimestamp = maxUnixTimestamp80
+	}
+	if integralPart > maxTimestamp {
 		return
 	}
 	// Split the integral part and fractional part of a decimal timestamp.
@@ -789,104 +849,67 @@ func builtinDateArith(args []types.Datum, ctx context.Context) (d types.Datum, e
 	// Op is used for distinguishing date_add and date_sub.
 	// args[0] -> Op
 	// args[1] -> Date
-	// args[2] -> DateArithInterval
-	// health check for date and interval
-	if args[1].IsNull() {
-		return d, nil
-	}
-	nodeDate := args[1]
-	nodeInterval := args[2].GetInterface().(ast.DateArithInterval)
-	nodeIntervalIntervalDatum := nodeInterval.Interval.GetDatum()
-	if nodeIntervalIntervalDatum.IsNull() {
-		return d, nil
-	}
-	// parse date
-	fieldType := mysql.TypeDate
-	var resultField *types.FieldType
-	switch nodeDate.Kind() {
-	case types.KindMysqlTime:
-		x := nodeDate.GetMysqlTime()
-		if (x.Type == mysql.TypeDatetime) || (x.Type == mysql.TypeTimestamp) {
-			fieldType = mysql.TypeDatetime
-		}
-	case types.KindString:
-		x := nodeDate.GetString()
-		if !types.IsDateFormat(x) {
-			fieldType = mysql.TypeDatetime
-		}
-	case types.KindInt64:
-		x := nodeDate.GetInt64()
-		if t, err1 := types.ParseTimeFromInt64(x); err1 == nil {
-			if (t.Type == mysql.TypeDatetime) || (t.Type == mysql.TypeTimestamp) {
-				fieldType = mysql.TypeDatetime
-			}
-		}
-	}
-	if types.IsClockUnit(nodeInterval.Unit) {
-		fieldType = mysql.TypeDatetime
-	}
-	resultField = types.NewFieldType(fieldType)
-	resultField.Decimal = types.MaxFsp
-	value, err := nodeDate.ConvertTo(resultField)
-	if err != nil {
-		return d, ErrInvalidOperation.Gen("DateArith invalid args, need date but get %T", nodeDate)
-	}
-	if value.IsNull() {
-		return d, ErrInvalidOperation.Gen("DateArith invalid args, need date but get %v", value.GetValue())
-	}
-	if value.Kind() != types.KindMysqlTime {
-		return d, ErrInvalidOperation.Gen("DateArith need time type, but got %T", value.GetValue())
-	}
-	result := value.GetMysqlTime()
-	// parse interval
-	var interval string
-	if strings.ToLower(nodeInterval.Unit) == "day" {
-		day, err1 := parseDayInterval(*nodeIntervalIntervalDatum)
-		if err1 != nil {
-			return d, ErrInvalidOperation.Gen("DateArith invalid day interval, need int but got %T", nodeIntervalIntervalDatum.GetString())
-		}
-		interval = fmt.Sprintf("%d", day)
-	} else {
-		if nodeIntervalIntervalDatum.Kind() == types.KindString {
-			interval = fmt.Sprintf("%v", nodeIntervalIntervalDatum.GetString())
-		} else {
-			ii, err1 := nodeIntervalIntervalDatum.ToInt64()
-			if err1 != nil {
-				return d, errors.Trace(err1)
-			}
-			interval = fmt.Sprintf("%v", ii)
-		}
-	}
-	year, month, day, duration, err := types.ExtractTimeValue(nodeInterval.Unit, interval)
-	if err != nil {
-		return d, errors.Trace(err)
-	}
+	// args[2] -> Interval
+	// args[3] -> Unit
 	op := args[0].GetInterface().(ast.DateArithType)
-	if op == ast.DateSub {
-		year, month, day, duration = -year, -month, -day, -duration
-	}
-	result.Time = result.Time.Add(duration)
-	result.Time = result.Time.AddDate(int(year), int(month), int(day))
-	if result.Time.Nanosecond() == 0 {
-		result.Fsp = 0
+	d, err = types.DateArith(args[1], args[2], args[3].GetString(), op == ast.DateSub)
+	if err != nil {
+		return d, ErrInvalidOperation.Gen("DateArith %s", err)
 	}
-	d.SetMysqlTime(result)
 	return d, nil
 }
 
-var reg = regexp.MustCompile(`[\d]+`)
+// dateInterval is the last parsed interval of a date arithmetic function.
+type dateInterval struct {
+	interval types.Datum
+	buf      []byte
+	unit     string
+	parsed   bool
+	iv       types.DateInterval
+}
 
-func parseDayInterval(value types.Datum) (int64, error) {
-	switch value.Kind() {
+// parse parses the interval of the unit if it differs from the last parsed one.
+func (p *dateInterval) parse(interval types.Datum, unit string) (types.DateInterval, error) {
+	if p.parsed && p.unit == unit && p.interval.Kind() == interval.Kind() {
+		if cmp, err := p.interval.CompareDatum(interval); err == nil && cmp == 0 {
+			return p.iv, nil
+		}
+	}
+	iv, err := types.ParseDateInterval(interval, unit)
+	if err != nil {
+		return iv, errors.Trace(err)
+	}
+	// The interval may point to the memory of a row, so we keep a copy of it.
+	p.interval = interval
+	switch interval.Kind() {
 	case types.KindString:
-		vs := value.GetString()
-		s := strings.ToLower(vs)
-		if s == "false" {
-			return 0, nil
-		} else if s == "true" {
-			return 1, nil
+		p.buf = append(p.buf[:0], interval.GetBytes()...)
+		p.interval.SetBytesAsString(p.buf)
+	case types.KindBytes:
+		p.buf = append(p.buf[:0], interval.GetBytes()...)
+		p.interval.SetBytes(p.buf)
+	}
+	p.unit, p.parsed, p.iv = unit, true, iv
+	return iv, nil
+}
+
+// newDateArithFunc creates a DATE_ADD or DATE_SUB function which keeps its parsed interval,
+// so a constant interval is parsed only once instead of for every row.
+func newDateArithFunc() BuiltinFunc {
+	p := &dateInterval{}
+	return func(args []types.Datum, _ context.Context) (d types.Datum, err error) {
+		if args[1].IsNull() || args[2].IsNull() {
+			return d, nil
 		}
-		value.SetString(reg.FindString(vs))
+		op := args[0].GetInterface().(ast.DateArithType)
+		unit := args[3].GetString()
+		iv, err := p.parse(args[2], unit)
+		if err == nil {
+			d, err = types.DateArithInterval(args[1], iv, unit, op == ast.DateSub)
+		}
+		if err != nil {
+			return d, ErrInvalidOperation.Gen("DateArith %s", err)
+		}
+		return d, nil
 	}
-	return value.ToInt64()
 }
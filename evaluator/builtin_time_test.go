@@ -21,6 +21,7 @@ import (
 	. "github.com/pingcap/check"
 	"github.com/pingcap/tidb/ast"
 	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/util/mock"
 	"github.com/pingcap/tidb/util/testleak"
 	"github.com/pingcap/tidb/util/testutil"
@@ -521,30 +522,64 @@ func (s *testEvaluatorSuite) TestDateArith(c *C) {
 		{"2011-11-11 00:00:00", 10, "HOUR", "2011-11-11 10:00:00", "2011-11-10 14:00:00", false},
 		{"2011-11-11 00:00:00", 10, "MINUTE", "2011-11-11 00:10:00", "2011-11-10 23:50:00", false},
 		{"2011-11-11 00:00:00", 10, "SECOND", "2011-11-11 00:00:10", "2011-11-10 23:59:50", false},
+		// tests for omitted leftmost fields and any delimiters
+		{"2011-11-11", "10", "SECOND_MICROSECOND", "2011-11-11 00:00:00.100000", "2011-11-10 23:59:59.900000", false},
+		{"2011-11-11", "10.0000", "MINUTE_MICROSECOND", "2011-11-11 00:00:10", "2011-11-10 23:59:50", false},
+		{"2011-11-11", "10:10:10", "MINUTE_MICROSECOND", "2011-11-11 00:10:10.100000", "2011-11-10 23:49:49.900000", false},
+		{"2011-11-11 10:10:10", "10", "MINUTE_SECOND", "2011-11-11 10:10:20", "2011-11-11 10:10:00", false},
+		{"2011-11-11 10:10:10", "1", "YEAR_MONTH", "2011-12-11 10:10:10", "2011-10-11 10:10:10", false},
+		{"2011-11-11 10:10:10", "1 1", "DAY_MICROSECOND", "2011-11-11 10:10:11.100000", "2011-11-11 10:10:08.900000", false},
+		{"2011-11-11 10:10:10", "1 1:1:1.5", "DAY_MICROSECOND", "2011-11-12 11:11:11.500000", "2011-11-10 09:09:08.500000", false},
+		{"2011-11-11 10:10:10", "1@1", "HOUR_MINUTE", "2011-11-11 11:11:10", "2011-11-11 09:09:10", false},
+		// tests for negative intervals
+		{"2011-11-11 10:10:10", "-1 10", "DAY_HOUR", "2011-11-10 00:10:10", "2011-11-12 20:10:10", false},
+		{"2011-11-11 10:10:10", "-10:10", "HOUR_MINUTE", "2011-11-11 00:00:10", "2011-11-11 20:20:10", false},
+		{"2011-11-11 10:10:10", "-1.5", "SECOND_MICROSECOND", "2011-11-11 10:10:08.500000", "2011-11-11 10:10:11.500000", false},
+		{"2011-11-11 10:10:10", -1, "WEEK", "2011-11-04 10:10:10", "2011-11-18 10:10:10", false},
+		{"2011-11-11 10:10:10", "-1", "QUARTER", "2011-08-11 10:10:10", "2012-02-11 10:10:10", false},
+		// tests for numeric intervals with fractional part
+		{"2011-11-11 10:10:10", 1.5, "SECOND", "2011-11-11 10:10:11.500000", "2011-11-11 10:10:08.500000", false},
+		{"2011-11-11 10:10:10", "1.5", "SECOND", "2011-11-11 10:10:11.500000", "2011-11-11 10:10:08.500000", false},
+		{"2011-11-11 10:10:10", 1.5, "HOUR_MINUTE", "2011-11-11 11:15:10", "2011-11-11 09:05:10", false},
+		{"2011-11-11", "-1", "DAY", "2011-11-10", "2011-11-12", false},
+		{"2011-11-11", -1.5, "DAY", "2011-11-09", "2011-11-13", false},
+		// tests for the day clamped to the end of the month
+		{"2011-01-31", 1, "MONTH", "2011-02-28", "2010-12-31", false},
+		{"2012-01-31", 1, "MONTH", "2012-02-29", "2011-12-31", false},
+		{"2012-02-29", 1, "YEAR", "2013-02-28", "2011-02-28", false},
+		{"2011-05-31 10:10:10", "1", "QUARTER", "2011-08-31 10:10:10", "2011-02-28 10:10:10", false},
+		{"2011-03-31", "1-1", "YEAR_MONTH", "2012-04-30", "2010-02-28", false},
+		{"2011-03-31", 1, "DAY", "2011-04-01", "2011-03-30", false},
+		// tests for the large intervals and the results out of range
+		{"2011-11-11 10:10:10", 87600000, "HOUR", nil, nil, false},
+		{"2011-11-11 10:10:10", 4000000, "HOUR", "2468-03-06 02:10:10", "1555-07-18 18:10:10", false},
+		{"2011-11-11 10:10:10", "9223372036854775807", "MICROSECOND", nil, nil, false},
+		{"2011-11-11 10:10:10", "-3600000001", "MICROSECOND", "2011-11-11 09:10:09.999999", "2011-11-11 11:10:10.000001", false},
+		{"2011-11-11", 1e20, "SECOND", nil, nil, false},
+		{"9999-12-31", 1, "DAY", nil, "9999-12-30", false},
+		{"0000-01-01", 1, "MONTH", "0000-02-01", nil, false},
+		{"2011-11-11", 9999999999, "YEAR", nil, nil, false},
+		{"2011-11-11", "10000", "YEAR", nil, nil, false},
+		{"2011-11-11", "99999999999999999999", "MONTH", nil, nil, false},
 		// tests for invalid input
 		{"2011-11-11", "abc1000", "MICROSECOND", nil, nil, true},
 		{"20111111 10:10:10", "1", "DAY", nil, nil, true},
-		{"2011-11-11", "10", "SECOND_MICROSECOND", nil, nil, true},
-		{"2011-11-11", "10.0000", "MINUTE_MICROSECOND", nil, nil, true},
-		{"2011-11-11", "10:10:10", "MINUTE_MICROSECOND", nil, nil, true},
+		{"2011-11-11", "10:10:10:10", "MINUTE_MICROSECOND", nil, nil, true},
+		{"2011-11-11", "abc", "HOUR_MINUTE", nil, nil, true},
+		{"2011-11-11", "1", "HOUR_YEAR", nil, nil, true},
 	}
 
 	// run the test cases
 	for _, t := range tests {
 		op := ast.NewValueExpr(ast.DateAdd)
-		dateArithInterval := ast.NewValueExpr(
-			ast.DateArithInterval{
-				Unit:     t.Unit,
-				Interval: ast.NewValueExpr(t.Interval),
-			},
-		)
 		date := ast.NewValueExpr(t.Date)
 		expr := &ast.FuncCallExpr{
 			FnName: model.NewCIStr("DATE_ARITH"),
 			Args: []ast.ExprNode{
 				op,
 				date,
-				dateArithInterval,
+				ast.NewValueExpr(t.Interval),
+				ast.NewValueExpr(t.Unit),
 			},
 		}
 		ast.SetFlag(expr)
@@ -570,7 +605,7 @@ func (s *testEvaluatorSuite) TestDateArith(c *C) {
 		} else {
 			c.Assert(err, IsNil)
 			if v.IsNull() {
-				c.Assert(nil, Equals, t.AddResult)
+				c.Assert(nil, Equals, t.SubResult)
 			} else {
 				c.Assert(v.Kind(), Equals, types.KindMysqlTime)
 				value := v.GetMysqlTime()
@@ -578,4 +613,63 @@ func (s *testEvaluatorSuite) TestDateArith(c *C) {
 			}
 		}
 	}
+
+	// The result keeps the fsp of the date, and uses the max fsp if the interval has fractional seconds.
+	fspTests := []struct {
+		Interval interface{}
+		Unit     string
+		Result   string
+	}{
+		{1, "SECOND", "2011-11-11 10:10:11.123"},
+		{1, "DAY", "2011-11-12 10:10:10.123"},
+		{"1.000001", "SECOND_MICROSECOND", "2011-11-11 10:10:11.123001"},
+	}
+	date, err := types.ParseTime("2011-11-11 10:10:10.123", mysql.TypeDatetime, 3)
+	c.Assert(err, IsNil)
+	for _, t := range fspTests {
+		expr := &ast.FuncCallExpr{
+			FnName: model.NewCIStr("DATE_ARITH"),
+			Args: []ast.ExprNode{
+				ast.NewValueExpr(ast.DateAdd),
+				ast.NewValueExpr(date),
+				ast.NewValueExpr(t.Interval),
+				ast.NewValueExpr(t.Unit),
+			},
+		}
+		ast.SetFlag(expr)
+		v, err := Eval(ctx, expr)
+		c.Assert(err, IsNil)
+		c.Assert(v.GetMysqlTime().String(), Equals, t.Result)
+	}
+
+	// The function of an expression keeps the parsed interval, every case is evaluated twice to reuse it.
+	dateArith := NewBuiltinFunc(ast.DateArith, Funcs[ast.DateArith])
+	for _, t := range tests {
+		for _, op := range []ast.DateArithType{ast.DateAdd, ast.DateAdd, ast.DateSub, ast.DateSub} {
+			expected := t.AddResult
+			if op == ast.DateSub {
+				expected = t.SubResult
+			}
+			v, err := dateArith(types.MakeDatums(op, t.Date, t.Interval, t.Unit), ctx)
+			if t.error {
+				c.Assert(err, NotNil)
+				continue
+			}
+			c.Assert(err, IsNil)
+			if v.IsNull() {
+				c.Assert(expected, IsNil, Commentf("%v", t))
+			} else {
+				c.Assert(v.GetMysqlTime().String(), Equals, expected, Commentf("%v", t))
+			}
+		}
+	}
+	// The interval is kept by the function, so it must not be affected when its memory is reused.
+	interval := []byte("10")
+	v, err := dateArith(types.MakeDatums(ast.DateAdd, "2011-11-11", interval, "DAY"), ctx)
+	c.Assert(err, IsNil)
+	c.Assert(v.GetMysqlTime().String(), Equals, "2011-11-21")
+	copy(interval, "20")
+	v, err = dateArith(types.MakeDatums(ast.DateAdd, "2011-11-11", interval, "DAY"), ctx)
+	c.Assert(err, IsNil)
+	c.Assert(v.GetMysqlTime().String(), Equals, "2011-12-01")
 }
@@ -0,0 +1,104 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package evaluator
+
+import (
+	"math"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/util/types"
+)
+
+// The builtin functions below behave like the MySQL version selected by tidb_compat_version,
+// they keep the TiDB behavior if it's empty.
+//
+//  VERSION()       returns the version of the emulated MySQL server, e.g. "5.6.1-TiDB-1.0".
+//  STRCMP()        ignores the trailing spaces like the PAD SPACE collations, the default ones of MySQL 5.6 and 5.7.
+//                  The trailing spaces are compared by MySQL 8.0, its default collation utf8mb4_0900_ai_ci is NO PAD.
+//  FROM_UNIXTIME() accepts the timestamps up to 32536771199.999999 (3001-01-18 23:59:59.999999 UTC) like MySQL 8.0,
+//                  the timestamps larger than 2147483647 are NULL in MySQL 5.6 and 5.7.
+//  ROUND()         rounds the exact values, the integers and the decimals, half away from zero and keeps their types,
+//                  and rounds the approximate values half to even by rint() like all the MySQL versions. TiDB rounds
+//                  all the values as doubles half away from zero, so ROUND(1.005, 2) is 1 and ROUND(2.5E0) is 3.
+//
+// The JSON comparison of MySQL 5.7 and 8.0 isn't emulated, there is no JSON type to compare.
+//
+// A function whose behavior changes between the MySQL versions should read the emulated version by compatVersion, be
+// added to the list above and to DynamicFuncs, so it isn't constant folded without the session.
+
+// compatVersion returns the MySQL version emulated by the builtin functions.
+func compatVersion(ctx context.Context) variable.CompatVersion {
+	if ctx == nil {
+		return variable.CompatNative
+	}
+	return ctx.GetSessionVars().CompatVersion
+}
+
+// compatServerVersions are the server versions returned by VERSION() for the emulated MySQL versions.
+var compatServerVersions = map[variable.CompatVersion]string{
+	variable.CompatNative:  mysql.ServerVersion,
+	variable.CompatMySQL56: "5.6.1-TiDB-1.0",
+	variable.CompatMySQL57: "5.7.1-TiDB-1.0",
+	variable.CompatMySQL80: "8.0.1-TiDB-1.0",
+}
+
+// maxUnixTimestamp80 is the max timestamp accepted by FROM_UNIXTIME() of MySQL 8.0.
+const maxUnixTimestamp80 = 32536771199
+
+// roundCompat rounds arg to dec decimal places like MySQL.
+func roundCompat(arg types.Datum, dec int) (d types.Datum, err error) {
+	switch arg.Kind() {
+	case types.KindNull:
+		return d, nil
+	case types.KindInt64, types.KindUint64, types.KindMysqlDecimal:
+		if dec >= 0 && arg.Kind() != types.KindMysqlDecimal {
+			return arg, nil
+		}
+		x, err := arg.ToDecimal()
+		if err != nil {
+			return d, errors.Trace(err)
+		}
+		to := new(types.MyDecimal)
+		if err = x.Round(to, dec); err != nil {
+			return d, errors.Trace(err)
+		}
+		switch arg.Kind() {
+		case types.KindInt64:
+			i, err := to.ToInt()
+			if err != nil {
+				return d, errors.Trace(err)
+			}
+			d.SetInt64(i)
+		case types.KindUint64:
+			u, err := to.ToUint()
+			if err != nil {
+				return d, errors.Trace(err)
+			}
+			d.SetUint64(u)
+		default:
+			d.SetMysqlDecimal(to)
+		}
+		return d, nil
+	}
+	x, err := arg.ToFloat64()
+	if err != nil {
+		return d, errors.Trace(err)
+	}
+	shift := math.Pow10(dec)
+	d.SetFloat64(math.RoundToEven(x*shift) / shift)
+	return d, nil
+}
@@ -0,0 +1,80 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package evaluator
+
+import (
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/util/mock"
+	"github.com/pingcap/tidb/util/testleak"
+	"github.com/pingcap/tidb/util/types"
+)
+
+func (s *testEvaluatorSuite) TestCompatVersion(c *C) {
+	defer testleak.AfterTest(c)()
+	ctx := mock.NewContext()
+	call := func(name string, args ...interface{}) *types.Datum {
+		f := &ast.FuncCallExpr{FnName: model.NewCIStr(name)}
+		for _, arg := range args {
+			f.Args = append(f.Args, ast.NewValueExpr(arg))
+		}
+		d, err := Eval(ctx, f)
+		c.Assert(err, IsNil)
+		return &d
+	}
+
+	tbl := []struct {
+		version      string
+		serverVer    string
+		strcmp       int64
+		fromUnixNull bool
+		round        []string
+	}{
+		{"", mysql.ServerVersion, 1, true, []string{"1", "3", "-3", "20"}},
+		{"5.6", "5.6.1-TiDB-1.0", 0, true, []string{"1.01", "2", "-2", "20"}},
+		{"5.7", "5.7.1-TiDB-1.0", 0, true, []string{"1.01", "2", "-2", "20"}},
+		{"8.0", "8.0.1-TiDB-1.0", 1, false, []string{"1.01", "2", "-2", "20"}},
+	}
+	for _, t := range tbl {
+		err := ctx.GetSessionVars().SetSystemVar(variable.TiDBCompatVersion, types.NewStringDatum(t.version))
+		c.Assert(err, IsNil)
+		c.Assert(call("version").GetString(), Equals, t.serverVer)
+		c.Assert(call("strcmp", "a  ", "a").GetInt64(), Equals, t.strcmp, Commentf("%s", t.version))
+		c.Assert(call("strcmp", "a ", "b").GetInt64(), Equals, int64(-1))
+		c.Assert(call("from_unixtime", 2147483648).IsNull(), Equals, t.fromUnixNull, Commentf("%s", t.version))
+		c.Assert(call("from_unixtime", 2147483647).IsNull(), IsFalse)
+		c.Assert(call("from_unixtime", 32536771200).IsNull(), IsTrue)
+		rounds := []*types.Datum{
+			call("round", types.NewDecFromStringForTest("1.005"), 2),
+			call("round", 2.5),
+			call("round", -2.5),
+			call("round", 15, -1),
+		}
+		for i, d := range rounds {
+			s, err := d.ToString()
+			c.Assert(err, IsNil)
+			c.Assert(s, Equals, t.round[i], Commentf("%s round %d", t.version, i))
+		}
+		if t.version != "" {
+			c.Assert(call("round", nil).IsNull(), IsTrue)
+		}
+	}
+
+	err := ctx.GetSessionVars().SetSystemVar(variable.TiDBCompatVersion, types.NewStringDatum("5.5"))
+	c.Assert(variable.ErrWrongValueForVar.Equal(err), IsTrue)
+	c.Assert(ctx.GetSessionVars().CompatVersion, Equals, variable.CompatMySQL80)
+}
@@ -28,9 +28,21 @@ const (
 
 // Handle escapes and wild cards convert pattern characters and pattern types.
 func compilePattern(pattern string, escape byte) (patChars, patTypes []byte) {
+	return compilePatternWithBuffer(pattern, escape, nil, nil)
+}
+
+// compilePatternWithBuffer is like compilePattern, but it compiles the pattern into charsBuf and typesBuf
+// if they are large enough, so the caller can reuse them for different patterns.
+func compilePatternWithBuffer(pattern string, escape byte, charsBuf, typesBuf []byte) (patChars, patTypes []byte) {
 	var lastAny bool
-	patChars = make([]byte, len(pattern))
-	patTypes = make([]byte, len(pattern))
+	if cap(charsBuf) < len(pattern) {
+		charsBuf = make([]byte, len(pattern))
+	}
+	if cap(typesBuf) < len(pattern) {
+		typesBuf = make([]byte, len(pattern))
+	}
+	patChars = charsBuf[:len(pattern)]
+	patTypes = typesBuf[:len(pattern)]
 	patLen := 0
 	for i := 0; i < len(pattern); i++ {
 		var tp byte
@@ -779,6 +779,81 @@ func (s *testEvaluatorSuite) TestLike(c *C) {
 	s.runTests(c, cases)
 }
 
+func (s *testEvaluatorSuite) TestStatefulLikeAndRegexp(c *C) {
+	defer testleak.AfterTest(c)()
+	like := NewBuiltinFunc(ast.Like, Funcs[ast.Like])
+	regexp := NewBuiltinFunc(ast.Regexp, Funcs[ast.Regexp])
+	tbl := []struct {
+		input   string
+		pattern string
+		like    int64
+		regexp  int64
+	}{
+		{"abc", "a%", 1, 0},
+		{"bcd", "a%", 0, 0},
+		{"a%", "a%", 1, 1},
+		{"abc", "_bc", 1, 0},
+		{"bbc", "_bc", 1, 0},
+		{"abc", "^a.c$", 0, 1},
+		{"abd", "^a.c$", 0, 0},
+		{"abc", "abc", 1, 1},
+	}
+	for _, t := range tbl {
+		// The pattern is kept by the functions, so they must not be affected when its memory is reused.
+		pattern := []byte(t.pattern)
+		d, err := like(types.MakeDatums(t.input, pattern, '\\'), nil)
+		c.Assert(err, IsNil)
+		c.Assert(d.GetInt64(), Equals, t.like, Commentf("%v", t))
+		d, err = regexp(types.MakeDatums(t.input, pattern), nil)
+		c.Assert(err, IsNil)
+		c.Assert(d.GetInt64(), Equals, t.regexp, Commentf("%v", t))
+		copy(pattern, "xxxxx")
+	}
+	d, err := like(types.MakeDatums(nil, "a%", '\\'), nil)
+	c.Assert(err, IsNil)
+	c.Assert(d.IsNull(), IsTrue)
+	_, err = regexp(types.MakeDatums("a", "("), nil)
+	c.Assert(err, NotNil)
+}
+
+func (s *testEvaluatorSuite) TestStatefulDateFormatAndConvert(c *C) {
+	defer testleak.AfterTest(c)()
+	dateFormat := NewBuiltinFunc(ast.DateFormat, Funcs[ast.DateFormat])
+	convert := NewBuiltinFunc(ast.Convert, Funcs[ast.Convert])
+	tbl := []struct {
+		date   string
+		format string
+		result string
+	}{
+		{"2010-01-07 23:12:34.12345", "%Y-%m-%d", "2010-01-07"},
+		{"2012-12-21 23:12:34.123456", "%Y-%m-%d", "2012-12-21"},
+		{"2012-12-21 23:12:34.123456", "%H:%i %%a%", "23:12 %a"},
+		{"2012-12-21 23:12:34.123456", "%H:%i %%a%", "23:12 %a"},
+		{"2012-12-21 23:12:34.123456", "abc", "abc"},
+		{"2012-12-21 23:12:34.123456", "", ""},
+		{"2012-12-21 23:12:34.123456", "%Y%Y", "20122012"},
+	}
+	for _, t := range tbl {
+		// The format is kept by the function, so it must not be affected when its memory is reused.
+		format := []byte(t.format)
+		d, err := dateFormat(types.MakeDatums(t.date, format), nil)
+		c.Assert(err, IsNil)
+		c.Assert(d.GetString(), Equals, t.result, Commentf("%v", t))
+		copy(format, "xxxxx")
+	}
+	d, err := dateFormat(types.MakeDatums(nil, "%Y"), nil)
+	c.Assert(err, IsNil)
+	c.Assert(d.IsNull(), IsTrue)
+
+	for _, cs := range []string{"utf8", "gbk", "utf8", "ascii", "latin1"} {
+		d, err = convert(types.MakeDatums("haha", cs), nil)
+		c.Assert(err, IsNil)
+		c.Assert(d.GetString(), Equals, "haha")
+	}
+	_, err = convert(types.MakeDatums("haha", "unknown"), nil)
+	c.Assert(err, NotNil)
+}
+
 func (s *testEvaluatorSuite) TestRegexp(c *C) {
 	defer testleak.AfterTest(c)()
 	tbl := []struct {
@@ -0,0 +1,283 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package evaluator
+
+import (
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/parser/opcode"
+	"github.com/pingcap/tidb/util/types"
+)
+
+// Scratch is the buffers an expression reuses to evaluate its builtin function across the rows.
+// The temporary values, e.g. the operands converted to decimals, are always kept in them,
+// and so is the result if the function is evaluated in place.
+type Scratch struct {
+	// decs hold the operands converted to decimals.
+	decs [2]types.MyDecimal
+	// dec and buf hold the decimal and string results which are evaluated in place.
+	dec types.MyDecimal
+	buf []byte
+}
+
+// ScratchFunc is a builtin function which reuses the scratch buffers of an expression. If inPlace is true,
+// the result may refer to the buffers, then it is only valid until the function is evaluated again.
+type ScratchFunc func(args []types.Datum, ctx context.Context, s *Scratch, inPlace bool) (types.Datum, error)
+
+// ScratchFuncs holds the builtin functions which can reuse the scratch buffers of an expression.
+var ScratchFuncs = map[string]ScratchFunc{
+	ast.GE:     compareScratchFunc(opcode.GE),
+	ast.LE:     compareScratchFunc(opcode.LE),
+	ast.EQ:     compareScratchFunc(opcode.EQ),
+	ast.NE:     compareScratchFunc(opcode.NE),
+	ast.LT:     compareScratchFunc(opcode.LT),
+	ast.GT:     compareScratchFunc(opcode.GT),
+	ast.NullEQ: compareScratchFunc(opcode.NullEQ),
+	ast.Plus:   arithmeticScratchFunc(opcode.Plus),
+	ast.Minus:  arithmeticScratchFunc(opcode.Minus),
+	ast.Mod:    arithmeticScratchFunc(opcode.Mod),
+	ast.Div:    arithmeticScratchFunc(opcode.Div),
+	ast.Mul:    arithmeticScratchFunc(opcode.Mul),
+	ast.IntDiv: arithmeticScratchFunc(opcode.IntDiv),
+	ast.Concat: concatScratchFunc,
+	ast.Lower:  caseScratchFunc(strings.ToLower, 'A', 'Z'),
+	ast.Lcase:  caseScratchFunc(strings.ToLower, 'A', 'Z'),
+	ast.Upper:  caseScratchFunc(strings.ToUpper, 'a', 'z'),
+	ast.Ucase:  caseScratchFunc(strings.ToUpper, 'a', 'z'),
+}
+
+// ArgConsumers holds the builtin functions whose results never refer to the memory of their arguments,
+// so their arguments can be evaluated in place, the values are consumed before they are evaluated again.
+var ArgConsumers = map[string]bool{
+	ast.GE:        true,
+	ast.LE:        true,
+	ast.EQ:        true,
+	ast.NE:        true,
+	ast.LT:        true,
+	ast.GT:        true,
+	ast.NullEQ:    true,
+	ast.Plus:      true,
+	ast.Minus:     true,
+	ast.Mod:       true,
+	ast.Div:       true,
+	ast.Mul:       true,
+	ast.IntDiv:    true,
+	ast.AndAnd:    true,
+	ast.OrOr:      true,
+	ast.LogicXor:  true,
+	ast.UnaryNot:  true,
+	ast.IsNull:    true,
+	ast.IsTruth:   true,
+	ast.IsFalsity: true,
+	ast.Like:      true,
+	ast.Regexp:    true,
+	ast.Concat:    true,
+	ast.Lower:     true,
+	ast.Lcase:     true,
+	ast.Upper:     true,
+	ast.Ucase:     true,
+	ast.Length:    true,
+}
+
+// scratchPool reuses the scratch buffers for the builtin functions which don't belong to an expression,
+// e.g. the ones used in constant folding.
+var scratchPool = sync.Pool{
+	New: func() interface{} {
+		return &Scratch{}
+	},
+}
+
+// withScratchPool makes a builtin function of f which takes its scratch buffers from scratchPool,
+// its result is never evaluated in place.
+func withScratchPool(f ScratchFunc) BuiltinFunc {
+	return func(args []types.Datum, ctx context.Context) (types.Datum, error) {
+		s := scratchPool.Get().(*Scratch)
+		d, err := f(args, ctx, s, false)
+		scratchPool.Put(s)
+		return d, err
+	}
+}
+
+func compareScratchFunc(op opcode.Op) ScratchFunc {
+	return func(args []types.Datum, _ context.Context, s *Scratch, _ bool) (d types.Datum, err error) {
+		var a, b = args[0], args[1]
+		if op != opcode.NullEQ {
+			a, b, err = types.CoerceDatumWithBuffer(a, b, &s.decs)
+			if err != nil {
+				return d, errors.Trace(err)
+			}
+		}
+		if a.IsNull() || b.IsNull() {
+			// for <=>, if a and b are both nil, return true.
+			// if a or b is nil, return false.
+			if op == opcode.NullEQ {
+				if a.IsNull() && b.IsNull() {
+					d.SetInt64(oneI64)
+				} else {
+					d.SetInt64(zeroI64)
+				}
+			}
+			return
+		}
+
+		n, err := a.CompareDatum(b)
+		if err != nil {
+			return d, errors.Trace(err)
+		}
+		var result bool
+		switch op {
+		case opcode.LT:
+			result = n < 0
+		case opcode.LE:
+			result = n <= 0
+		case opcode.EQ, opcode.NullEQ:
+			result = n == 0
+		case opcode.GT:
+			result = n > 0
+		case opcode.GE:
+			result = n >= 0
+		case opcode.NE:
+			result = n != 0
+		default:
+			return d, ErrInvalidOperation.Gen("invalid op %v in comparison operation", op)
+		}
+		if result {
+			d.SetInt64(oneI64)
+		} else {
+			d.SetInt64(zeroI64)
+		}
+		return
+	}
+}
+
+func arithmeticScratchFunc(op opcode.Op) ScratchFunc {
+	return func(args []types.Datum, _ context.Context, s *Scratch, inPlace bool) (d types.Datum, err error) {
+		a, err := types.CoerceArithmetic(args[0])
+		if err != nil {
+			return d, errors.Trace(err)
+		}
+
+		b, err := types.CoerceArithmetic(args[1])
+		if err != nil {
+			return d, errors.Trace(err)
+		}
+		a, b, err = types.CoerceDatumWithBuffer(a, b, &s.decs)
+		if err != nil {
+			return d, errors.Trace(err)
+		}
+		if a.IsNull() || b.IsNull() {
+			return
+		}
+
+		var to *types.MyDecimal
+		if inPlace {
+			to = &s.dec
+		}
+		switch op {
+		case opcode.Plus:
+			return types.ComputePlusTo(a, b, to)
+		case opcode.Minus:
+			return types.ComputeMinusTo(a, b, to)
+		case opcode.Mul:
+			return types.ComputeMulTo(a, b, to)
+		case opcode.Div:
+			if a.Kind() != types.KindFloat64 {
+				// The operands are divided as decimals, convert the integers in the buffers.
+				if err = s.intToDecimal(&a, 0); err != nil {
+					return d, errors.Trace(err)
+				}
+				if err = s.intToDecimal(&b, 1); err != nil {
+					return d, errors.Trace(err)
+				}
+			}
+			return types.ComputeDivTo(a, b, to)
+		case opcode.Mod:
+			return types.ComputeMod(a, b)
+		case opcode.IntDiv:
+			return types.ComputeIntDiv(a, b)
+		default:
+			return d, ErrInvalidOperation.Gen("invalid op %v in arithmetic operation", op)
+		}
+	}
+}
+
+// intToDecimal converts an integer operand to the decimal decs[i].
+func (s *Scratch) intToDecimal(d *types.Datum, i int) error {
+	if d.Kind() != types.KindInt64 && d.Kind() != types.KindUint64 {
+		return nil
+	}
+	if err := types.ConvertDatumToDecimalTo(*d, &s.decs[i]); err != nil {
+		return errors.Trace(err)
+	}
+	d.SetMysqlDecimal(&s.decs[i])
+	return nil
+}
+
+func concatScratchFunc(args []types.Datum, ctx context.Context, s *Scratch, inPlace bool) (d types.Datum, err error) {
+	if !inPlace {
+		return builtinConcat(args, ctx)
+	}
+	s.buf = s.buf[:0]
+	for _, a := range args {
+		if a.IsNull() {
+			return d, nil
+		}
+		var ss string
+		ss, err = a.ToString()
+		if err != nil {
+			return d, errors.Trace(err)
+		}
+		s.buf = append(s.buf, ss...)
+	}
+	d.SetBytesAsString(s.buf)
+	return d, nil
+}
+
+// caseScratchFunc makes the scratch function of LOWER or UPPER. The case of the ASCII letters between from and to
+// is flipped in the buffer, and the strings which are not ASCII are converted by convert. The result is copied out of
+// the buffer if it is not evaluated in place, so it never refers to the memory of the argument.
+func caseScratchFunc(convert func(string) string, from, to byte) ScratchFunc {
+	return func(args []types.Datum, _ context.Context, s *Scratch, inPlace bool) (d types.Datum, err error) {
+		if args[0].IsNull() {
+			return d, nil
+		}
+		str, err := args[0].ToString()
+		if err != nil {
+			return d, errors.Trace(err)
+		}
+		s.buf = s.buf[:0]
+		for i := 0; i < len(str); i++ {
+			c := str[i]
+			if c >= utf8.RuneSelf {
+				s.buf = append(s.buf[:0], convert(str)...)
+				break
+			}
+			if c >= from && c <= to {
+				c ^= 'a' - 'A'
+			}
+			s.buf = append(s.buf, c)
+		}
+		if inPlace {
+			d.SetBytesAsString(s.buf)
+		} else {
+			d.SetString(string(s.buf))
+		}
+		return d, nil
+	}
+}
@@ -14,20 +14,53 @@
 package executor
 
 import (
+	"sync/atomic"
+	"time"
+
 	"github.com/juju/errors"
 	"github.com/pingcap/tidb/ast"
 	"github.com/pingcap/tidb/context"
 	"github.com/pingcap/tidb/expression"
 	"github.com/pingcap/tidb/infoschema"
+	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/plan"
+	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/util/chunk"
+	"github.com/pingcap/tidb/util/memory"
+	"github.com/pingcap/tidb/util/planbaseline"
 )
 
 // recordSet wraps an executor, implements ast.RecordSet interface
 type recordSet struct {
-	fields   []*ast.ResultField
-	executor Executor
-	schema   expression.Schema
+	fields     []*ast.ResultField
+	executor   Executor
+	schema     expression.Schema
+	memTracker *memory.Tracker
+	// release releases the admission of the statement, it's released as soon as the execution finishes, see
+	// finish.
+	release func()
+	// killed is the kill signal of the statement, the rows are still read with it after the other statements of
+	// the session start, see Suspend.
+	killed *uint32
+
+	// baseline is not nil if the plan is observed for the plan baselines, the execution is observed after all
+	// the rows are read.
+	baseline  *baselineInfo
+	text      string
+	startTime time.Time
+	drained   bool
+
+	// ctx, plan and stmtText are used to record the execution in the statement summary.
+	ctx      context.Context
+	plan     plan.Plan
+	stmtText string
+
+	// chunk is not nil if the rows of the executor are read in chunks, the rows from chunkIdx are not returned yet.
+	// lastChunk is true if the executor has no more row after the chunk.
+	chunk     *chunk.Chunk
+	chunkIdx  int
+	lastChunk bool
 }
 
 func (a *recordSet) Fields() ([]*ast.ResultField, error) {
@@ -49,15 +82,116 @@ func (a *recordSet) Fields() ([]*ast.ResultField, error) {
 }
 
 func (a *recordSet) Next() (*ast.Row, error) {
+	if atomic.LoadUint32(a.killed) == 1 {
+		return nil, errors.Trace(kv.ErrQueryInterrupted)
+	}
+	if a.chunk != nil {
+		return a.nextInChunk()
+	}
 	row, err := a.executor.Next()
 	if err != nil || row == nil {
+		a.drained = err == nil
+		a.finish()
+		logMemQuotaExceeded(err, a.memTracker)
 		return nil, errors.Trace(err)
 	}
 	return &ast.Row{Data: row.Data}, nil
 }
 
+// nextInChunk returns the next row of the chunk, the next chunk is read after all the rows of the chunk are returned.
+func (a *recordSet) nextInChunk() (*ast.Row, error) {
+	for a.chunkIdx >= a.chunk.NumRows() {
+		if a.lastChunk {
+			a.drained = true
+			a.finish()
+			return nil, nil
+		}
+		err := nextChunk(a.executor, a.chunk)
+		if err != nil {
+			a.finish()
+			logMemQuotaExceeded(err, a.memTracker)
+			return nil, errors.Trace(err)
+		}
+		a.chunkIdx = 0
+		a.lastChunk = !a.chunk.IsFull()
+	}
+	row := a.chunk.GetRow(a.chunkIdx)
+	a.chunkIdx++
+	return &ast.Row{Data: row.GetDatumRow()}, nil
+}
+
+// finish releases the admission of the statement once all the rows are read or the execution fails, so the client
+// which doesn't close the record set right away doesn't hold the slot of a running statement.
+func (a *recordSet) finish() {
+	a.release()
+	a.release = func() {}
+}
+
 func (a *recordSet) Close() error {
-	return a.executor.Close()
+	err := a.executor.Close()
+	a.memTracker.Close()
+	a.finish()
+	if a.baseline != nil && a.drained && err == nil {
+		planbaseline.GlobalManager.Observe(a.baseline.store, a.baseline.digest, a.text, a.baseline.hints,
+			time.Since(a.startTime))
+	}
+	if err == nil {
+		observeStmtSummary(a.ctx, a.plan, a.stmtText, time.Since(a.startTime))
+	}
+	return errors.Trace(err)
+}
+
+// SuspendableRecordSet is a record set whose rows are read after the other statements of the session start, e.g.
+// the rows of an open cursor are read by COM_STMT_FETCH.
+type SuspendableRecordSet interface {
+	ast.RecordSet
+	// Suspend is called when the reading of the rows stops until Resume is called. The admission of the statement
+	// is released so the idle record set doesn't block the other statements, and KILL QUERY doesn't interrupt the
+	// statement until it's resumed.
+	Suspend()
+	// Resume makes the statement of the record set the running statement of the session again.
+	Resume()
+}
+
+// Suspend implements the SuspendableRecordSet Suspend interface.
+func (a *recordSet) Suspend() {
+	a.finish()
+	if a.ctx.GetSessionVars().Killed == a.killed {
+		a.ctx.GetSessionVars().ResetKilled()
+	}
+}
+
+// Resume implements the SuspendableRecordSet Resume interface. A statement killed before keeps its kill signal, so
+// it can't be resumed.
+func (a *recordSet) Resume() {
+	a.ctx.GetSessionVars().SetKillSignal(a.killed)
+}
+
+// isKilled returns whether the running statement of the session is killed by KILL QUERY or KILL CONNECTION.
+func isKilled(ctx context.Context) bool {
+	return atomic.LoadUint32(ctx.GetSessionVars().Killed) == 1
+}
+
+// killCheckInterval is how often a waiting statement checks whether it's killed.
+const killCheckInterval = 10 * time.Millisecond
+
+// sleepUnlessKilled pauses the statement of the session for the duration, it returns kv.ErrQueryInterrupted as soon
+// as the statement is killed.
+func sleepUnlessKilled(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	ticker := time.NewTicker(killCheckInterval)
+	defer ticker.Stop()
+	for {
+		if isKilled(ctx) {
+			return errors.Trace(kv.ErrQueryInterrupted)
+		}
+		select {
+		case <-timer.C:
+			return nil
+		case <-ticker.C:
+		}
+	}
 }
 
 // statement implements the ast.Statement interface, it builds a plan.Plan to an ast.Statement.
@@ -67,6 +201,8 @@ type statement struct {
 	plan  plan.Plan
 	text  string
 	isDDL bool
+	// baseline is not nil if the plan baselines are enabled for the statement.
+	baseline *baselineInfo
 }
 
 func (a *statement) OriginText() string {
@@ -87,20 +223,49 @@ func (a *statement) IsDDL() bool {
 // like the INSERT, UPDATE statements, it executes in this function, if the Executor returns
 // result, execution is done after this function returns, in the returned ast.RecordSet Next method.
 func (a *statement) Exec(ctx context.Context) (ast.RecordSet, error) {
+	startTime := time.Now()
+	if show, ok := a.plan.(*plan.Show); !ok || show.Tp != ast.ShowWarnings {
+		// The warnings of the previous statement are kept for SHOW WARNINGS.
+		ctx.GetSessionVars().Warnings = nil
+	}
 	b := newExecutorBuilder(ctx, a.is)
+	b.memTracker = memory.GlobalArbiter.NewTracker(a.text)
+	quota, err := getNonNegativeSessionVar(ctx, variable.TiDBMemQuotaQuery)
+	if err != nil {
+		b.memTracker.Close()
+		return nil, errors.Trace(err)
+	}
+	b.memTracker.SetQuota(quota)
+	maxChunkSize, err := getNonNegativeSessionVar(ctx, variable.TiDBMaxChunkSize)
+	if err != nil {
+		b.memTracker.Close()
+		return nil, errors.Trace(err)
+	}
 	e := b.build(a.plan)
 	if b.err != nil {
+		b.memTracker.Close()
 		return nil, errors.Trace(b.err)
 	}
 
 	// ExecuteExec is not a real Executor, we only use it to build another Executor from a prepared statement.
+	p, text := a.plan, a.text
 	if executorExec, ok := e.(*ExecuteExec); ok {
 		err := executorExec.Build()
 		if err != nil {
+			b.memTracker.Close()
 			return nil, errors.Trace(err)
 		}
 		stmtCount(executorExec.Stmt)
 		e = executorExec.StmtExec
+		p = executorExec.Plan
+		text = executorExec.Stmt.Text()
+	}
+	// The plan is kept for the slow query log, see LastPlanDigest.
+	ctx.SetValue(LastPlanVarKey, p)
+	release, err := admit(ctx, p, a.text)
+	if err != nil {
+		b.memTracker.Close()
+		return nil, errors.Trace(err)
 	}
 
 	// Fields or Schema are only used for statements that return result set.
@@ -111,14 +276,21 @@ func (a *statement) Exec(ctx context.Context) (ast.RecordSet, error) {
 		case *DeleteExec, *InsertExec, *UpdateExec, *ReplaceExec, *LoadData, *DDLExec:
 			snapshotTS := ctx.GetSessionVars().SnapshotTS
 			if snapshotTS != 0 {
+				release()
 				return nil, errors.New("can not execute write statement when 'tidb_snapshot' is set")
 			}
 		}
 
+		defer release()
+		defer b.memTracker.Close()
 		defer e.Close()
 		for {
+			if isKilled(ctx) {
+				return nil, errors.Trace(kv.ErrQueryInterrupted)
+			}
 			row, err := e.Next()
 			if err != nil {
+				logMemQuotaExceeded(err, b.memTracker)
 				return nil, errors.Trace(err)
 			}
 			// Even though there isn't any result set, the row is still used to indicate if there is
@@ -126,13 +298,27 @@ func (a *statement) Exec(ctx context.Context) (ast.RecordSet, error) {
 			// For example, the UPDATE statement updates a single row on a Next call, we keep calling Next until
 			// There is no more rows to update.
 			if row == nil {
+				observeStmtSummary(ctx, p, text, time.Since(startTime))
 				return nil, nil
 			}
 		}
 	}
 
-	return &recordSet{
-		executor: e,
-		schema:   e.Schema(),
-	}, nil
+	rs := &recordSet{
+		executor:   e,
+		schema:     e.Schema(),
+		memTracker: b.memTracker,
+		release:    release,
+		killed:     ctx.GetSessionVars().Killed,
+		baseline:   a.baseline,
+		text:       a.text,
+		startTime:  startTime,
+		ctx:        ctx,
+		plan:       p,
+		stmtText:   text,
+	}
+	if maxChunkSize > 0 && supportChunk(e) {
+		rs.chunk = newChunk(e, int(maxChunkSize))
+	}
+	return rs, nil
 }
@@ -0,0 +1,254 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"io"
+	"math"
+
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/expression"
+	"github.com/pingcap/tidb/inspectkv"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/sessionctx"
+	"github.com/pingcap/tidb/table"
+	"github.com/pingcap/tidb/terror"
+	"github.com/pingcap/tidb/util/types"
+)
+
+// repairIndexBatchSize is the number of the rows or the index entries repaired in a transaction.
+const repairIndexBatchSize = 256
+
+// RecoverIndexExec represents an admin recover index executor.
+// It scans the rows of the table in the latest snapshot, and adds the missing index entries of every
+// batch of the rows in a new transaction, so the entries added by the committed batches are kept if it fails.
+// It returns the number of the added entries and the number of the scanned rows.
+type RecoverIndexExec struct {
+	ctx    context.Context
+	schema expression.Schema
+	table  table.Table
+	index  table.Index
+	done   bool
+}
+
+// Schema implements the Executor Schema interface.
+func (e *RecoverIndexExec) Schema() expression.Schema {
+	return e.schema
+}
+
+// Next implements the Executor Next interface.
+func (e *RecoverIndexExec) Next() (*Row, error) {
+	if e.done {
+		return nil, nil
+	}
+	e.done = true
+
+	// The rows are repaired in the transactions of the batches rather than the transaction of the session.
+	store := sessionctx.GetDomain(e.ctx).Store()
+	ver, err := store.CurrentVersion()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	var added, scanned int64
+	handle := int64(math.MinInt64)
+	for {
+		records, next, err := inspectkv.ScanSnapshotTableRecord(store, ver, e.table, handle, repairIndexBatchSize)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if len(records) == 0 {
+			break
+		}
+		handles := make([]int64, 0, len(records))
+		for _, r := range records {
+			handles = append(handles, r.Handle)
+		}
+		var cnt int
+		err = kv.RunInNewTxn(store, true, func(txn kv.Transaction) error {
+			var err1 error
+			cnt, err1 = inspectkv.RecoverIndex(txn, e.table, e.index, handles)
+			return errors.Trace(err1)
+		})
+		if err != nil {
+			log.Warnf("[admin] recover index %s of table %s failed at handle %d, added %d entries in %d rows",
+				e.index.Meta().Name, e.table.Meta().Name, handle, added, scanned)
+			return nil, errors.Trace(err)
+		}
+		added += int64(cnt)
+		scanned += int64(len(records))
+		log.Infof("[admin] recover index %s of table %s, added %d entries in %d rows", e.index.Meta().Name,
+			e.table.Meta().Name, added, scanned)
+		// The next handle overflows after the max handle.
+		if len(records) < repairIndexBatchSize || next == math.MinInt64 {
+			break
+		}
+		handle = next
+	}
+	return &Row{Data: types.MakeDatums(added, scanned)}, nil
+}
+
+// Close implements the Executor Close interface.
+func (e *RecoverIndexExec) Close() error {
+	return nil
+}
+
+// CleanupIndexExec represents an admin cleanup index executor.
+// It scans the index entries in the latest snapshot, and removes the entries of every batch which don't
+// match the rows in a new transaction, so the entries removed by the committed batches are kept if it fails.
+// It returns the number of the removed entries and the number of the scanned entries.
+type CleanupIndexExec struct {
+	ctx    context.Context
+	schema expression.Schema
+	table  table.Table
+	index  table.Index
+	done   bool
+}
+
+// Schema implements the Executor Schema interface.
+func (e *CleanupIndexExec) Schema() expression.Schema {
+	return e.schema
+}
+
+// Next implements the Executor Next interface.
+func (e *CleanupIndexExec) Next() (*Row, error) {
+	if e.done {
+		return nil, nil
+	}
+	e.done = true
+
+	store := sessionctx.GetDomain(e.ctx).Store()
+	ver, err := store.CurrentVersion()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	snapshot, err := store.GetSnapshot(ver)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	it, err := e.index.SeekFirst(snapshot)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	defer it.Close()
+
+	var removed, scanned int64
+	entries := make([]*inspectkv.RecordData, 0, repairIndexBatchSize)
+	for {
+		vals, h, err := it.Next()
+		eof := terror.ErrorEqual(err, io.EOF)
+		if err != nil && !eof {
+			return nil, errors.Trace(err)
+		}
+		if !eof {
+			entries = append(entries, &inspectkv.RecordData{Handle: h, Values: vals})
+		}
+		if len(entries) == repairIndexBatchSize || (eof && len(entries) > 0) {
+			var cnt int
+			err = kv.RunInNewTxn(store, true, func(txn kv.Transaction) error {
+				var err1 error
+				cnt, err1 = inspectkv.CleanupIndex(txn, e.table, e.index, entries)
+				return errors.Trace(err1)
+			})
+			if err != nil {
+				log.Warnf("[admin] cleanup index %s of table %s failed, removed %d entries in %d entries",
+					e.index.Meta().Name, e.table.Meta().Name, removed, scanned)
+				return nil, errors.Trace(err)
+			}
+			removed += int64(cnt)
+			scanned += int64(len(entries))
+			log.Infof("[admin] cleanup index %s of table %s, removed %d entries in %d entries", e.index.Meta().Name,
+				e.table.Meta().Name, removed, scanned)
+			entries = entries[:0]
+		}
+		if eof {
+			break
+		}
+	}
+	return &Row{Data: types.MakeDatums(removed, scanned)}, nil
+}
+
+// Close implements the Executor Close interface.
+func (e *CleanupIndexExec) Close() error {
+	return nil
+}
+
+// DeleteRangesBatchSize is the number of the history background jobs ADMIN SHOW DELETE RANGES reads at a time.
+var DeleteRangesBatchSize = 256
+
+// ShowDeleteRangesExec represents an admin show delete ranges executor.
+// It shows the ranges of the tables deleted by the background jobs in the queue and the done jobs in the history,
+// whether the ranges still have data, and whether they are orphans, i.e. the deletion is recorded as done but the
+// range still has data. The history is read in batches, so a long history isn't loaded at once.
+type ShowDeleteRangesExec struct {
+	schema expression.Schema
+	ctx    context.Context
+
+	txn    kv.Transaction
+	ranges []*inspectkv.DeleteRange
+	cursor int
+	// nextID is the start job ID of the next batch of the history, it's 0 after the last batch.
+	nextID  int64
+	started bool
+}
+
+// Schema implements the Executor Schema interface.
+func (e *ShowDeleteRangesExec) Schema() expression.Schema {
+	return e.schema
+}
+
+// Next implements the Executor Next interface.
+func (e *ShowDeleteRangesExec) Next() (*Row, error) {
+	for e.cursor >= len(e.ranges) {
+		if e.started && e.nextID == 0 {
+			return nil, nil
+		}
+		if err := e.fetchBatch(); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	r := e.ranges[e.cursor]
+	e.cursor++
+	return &Row{Data: types.MakeDatums(r.Job.ID, r.Job.Type.String(), r.TableID, r.Job.State.String(),
+		r.HasData, r.IsOrphan())}, nil
+}
+
+func (e *ShowDeleteRangesExec) fetchBatch() error {
+	if e.txn == nil {
+		// The ranges are read in a new transaction rather than the transaction of the session, which has been
+		// committed when the rows are fetched.
+		txn, err := sessionctx.GetDomain(e.ctx).Store().Begin()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		e.txn = txn
+	}
+	var err error
+	e.ranges, e.nextID, err = inspectkv.GetDeleteRanges(e.txn, e.nextID, DeleteRangesBatchSize)
+	e.cursor = 0
+	e.started = true
+	return errors.Trace(err)
+}
+
+// Close implements the Executor Close interface.
+func (e *ShowDeleteRangesExec) Close() error {
+	e.ranges, e.cursor, e.nextID, e.started = nil, 0, 0, false
+	if e.txn == nil {
+		return nil
+	}
+	err := e.txn.Rollback()
+	e.txn = nil
+	return errors.Trace(err)
+}
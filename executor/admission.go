@@ -0,0 +1,152 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/plan"
+	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/util/admission"
+)
+
+// maxPointRanges is the max number of the point ranges of a scan regarded as a point read, e.g. a long IN list on
+// the primary key reads as many rows as a scan.
+const maxPointRanges = 64
+
+// The status variables of the admission control.
+const (
+	statusRunningStatements  = "Statement_queue_running"
+	statusQueuedStatements   = "Statement_queue_waiting"
+	statusWaitedStatements   = "Statement_queue_waited_total"
+	statusTimedOutStatements = "Statement_queue_timeout_total"
+)
+
+func init() {
+	variable.RegisterStatistics(admissionStats{})
+}
+
+// admit admits the statement of the plan by admission.GlobalController, it may wait in the queue of the running
+// statements until it's killed. The returned function must be called when the statement finishes.
+func admit(ctx context.Context, p plan.Plan, text string) (func(), error) {
+	class := admissionClass(ctx, p)
+	release, ok := admission.GlobalController.Admit(class, func() bool { return isKilled(ctx) })
+	if !ok {
+		if isKilled(ctx) {
+			return nil, errors.Trace(kv.ErrQueryInterrupted)
+		}
+		log.Warnf("[%d] the statement isn't admitted before the queue timeout: %s",
+			ctx.GetSessionVars().ConnectionID, text)
+		return nil, errors.Trace(ErrQueueTimeout)
+	}
+	return release, nil
+}
+
+// admissionClass classifies the statement of the plan. The internal statements are exempt, or they may wait for
+// the slots held by the user statements which execute them.
+func admissionClass(ctx context.Context, p plan.Plan) admission.Class {
+	if ctx.GetSessionVars().InRestrictedSQL {
+		return admission.ClassExempt
+	}
+	switch x := p.(type) {
+	case *plan.Simple, *plan.DDL, *plan.Show, *plan.ShowDDL, *plan.ShowDDLJobs, *plan.CheckTable,
+		*plan.RecoverIndex, *plan.CleanupIndex, *plan.Diagnose, *plan.ShowSlowPlans, *plan.ThrottleTable,
+		*plan.SetStats, *plan.ResetStats, *plan.Prepare, *plan.Deallocate,
+		*plan.ShowDeleteRanges:
+		return admission.ClassExempt
+	case *plan.Explain:
+		if !x.Analyze {
+			return admission.ClassExempt
+		}
+		return admissionClass(ctx, x.StmtPlan)
+	case *plan.SelectInto:
+		return admissionClass(ctx, x.Target)
+	case *plan.LoadData:
+		return admission.ClassScan
+	}
+	if isPointPlan(p) {
+		return admission.ClassPoint
+	}
+	return admission.ClassScan
+}
+
+// isPointPlan checks if the plan only reads the rows by the handles or the unique keys, the plan without any
+// data source, e.g. "select 1" or "insert ... values", is a point plan too.
+func isPointPlan(p plan.Plan) bool {
+	switch x := p.(type) {
+	case *plan.PointGet:
+		return true
+	case *plan.BatchPointGet:
+		return len(x.Handles)+len(x.IndexValues) <= maxPointRanges
+	case *plan.PhysicalTableScan:
+		if len(x.Ranges) == 0 || len(x.Ranges) > maxPointRanges {
+			return false
+		}
+		for _, r := range x.Ranges {
+			if r.LowVal != r.HighVal {
+				return false
+			}
+		}
+		return true
+	case *plan.PhysicalIndexScan:
+		if !x.Index.Unique || len(x.Ranges) == 0 || len(x.Ranges) > maxPointRanges {
+			return false
+		}
+		for _, r := range x.Ranges {
+			// The NULL values of a unique index aren't unique.
+			if !r.IsPoint() || len(r.LowVal) != len(x.Index.Columns) {
+				return false
+			}
+			for _, d := range r.LowVal {
+				if d.IsNull() {
+					return false
+				}
+			}
+		}
+		return true
+	case *plan.PhysicalIndexMerge:
+		return false
+	case *plan.PhysicalApply:
+		if !isPointPlan(x.InnerPlan) {
+			return false
+		}
+	}
+	for _, child := range p.GetChildren() {
+		if !isPointPlan(child) {
+			return false
+		}
+	}
+	return true
+}
+
+// admissionStats exposes the statistics of admission.GlobalController as the status variables.
+type admissionStats struct{}
+
+// GetScope implements the variable.Statistics GetScope interface.
+func (admissionStats) GetScope(status string) variable.ScopeFlag {
+	return variable.DefaultScopeFlag
+}
+
+// Stats implements the variable.Statistics Stats interface.
+func (admissionStats) Stats() (map[string]interface{}, error) {
+	stats := admission.GlobalController.Stats()
+	return map[string]interface{}{
+		statusRunningStatements:  stats.Running,
+		statusQueuedStatements:   stats.Queued,
+		statusWaitedStatements:   stats.Waited,
+		statusTimedOutStatements: stats.TimedOut,
+	}, nil
+}
@@ -0,0 +1,203 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"hash/crc32"
+	"sync"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/expression"
+	"github.com/pingcap/tidb/util/types"
+)
+
+// groupedRow is a source row with its encoded group key.
+type groupedRow struct {
+	row      *Row
+	groupKey []byte
+}
+
+// hashAggWorker aggregates the rows of a partition of the groups. The groups are partitioned by the hash of
+// their keys, so the partitions of the workers are disjoint and their results need no merge.
+type hashAggWorker struct {
+	aggFuncs []expression.AggregationFunction
+	groupMap map[string]bool
+	groups   [][]byte
+	rowsCh   chan []groupedRow
+	mem      memoryUsage
+}
+
+// hashAggErr records the first error of the goroutines of the parallel hash aggregation, and stops the others.
+type hashAggErr struct {
+	once sync.Once
+	err  error
+	done chan struct{}
+}
+
+func (r *hashAggErr) report(err error) {
+	r.once.Do(func() {
+		r.err = err
+		close(r.done)
+	})
+}
+
+// execParallel reads all the rows from Src and aggregates them by multiple goroutines. The partial workers
+// evaluate the group keys of the rows and dispatch the rows by the group keys to the final workers, and each
+// final worker updates the aggregate functions of its own groups.
+func (e *HashAggExec) execParallel() error {
+	aggErr := &hashAggErr{done: make(chan struct{})}
+	inputCh := make(chan []*Row, e.concurrency)
+	e.workers = make([]*hashAggWorker, e.concurrency)
+	for i := range e.workers {
+		w := &hashAggWorker{
+			groupMap: make(map[string]bool),
+			rowsCh:   make(chan []groupedRow, e.concurrency),
+			mem:      memoryUsage{tracker: e.mem.getTracker(), killed: e.mem.killed},
+		}
+		for _, af := range e.AggFuncs {
+			args := make([]expression.Expression, 0, len(af.GetArgs()))
+			for _, arg := range af.GetArgs() {
+				args = append(args, arg.Clone())
+			}
+			newAf := expression.NewAggFunction(af.GetName(), args, af.IsDistinct())
+			newAf.SetMode(af.GetMode())
+			w.aggFuncs = append(w.aggFuncs, newAf)
+		}
+		e.workers[i] = w
+	}
+
+	var partialWg, finalWg sync.WaitGroup
+	for i := 0; i < e.concurrency; i++ {
+		groupByItems := make([]expression.Expression, 0, len(e.GroupByItems))
+		for _, item := range e.GroupByItems {
+			groupByItems = append(groupByItems, item.Clone())
+		}
+		partialWg.Add(1)
+		go e.runPartialWorker(groupByItems, inputCh, aggErr, &partialWg)
+	}
+	for _, w := range e.workers {
+		finalWg.Add(1)
+		go e.runFinalWorker(w, aggErr, &finalWg)
+	}
+	go func() {
+		partialWg.Wait()
+		for _, w := range e.workers {
+			close(w.rowsCh)
+		}
+	}()
+
+	e.fetchAggRows(inputCh, aggErr)
+	close(inputCh)
+	finalWg.Wait()
+	return errors.Trace(aggErr.err)
+}
+
+// fetchAggRows reads the rows from Src by batches and sends them to the partial workers.
+func (e *HashAggExec) fetchAggRows(inputCh chan<- []*Row, aggErr *hashAggErr) {
+	for {
+		rows := make([]*Row, 0, batchSize)
+		for len(rows) < batchSize {
+			row, err := e.reader.next(e.Src)
+			if err != nil {
+				aggErr.report(errors.Trace(err))
+				return
+			}
+			if row == nil {
+				break
+			}
+			rows = append(rows, row)
+		}
+		if len(rows) == 0 {
+			return
+		}
+		select {
+		case inputCh <- rows:
+		case <-aggErr.done:
+			return
+		}
+		if len(rows) < batchSize {
+			return
+		}
+	}
+}
+
+// runPartialWorker evaluates the group keys of the rows and dispatches them to the final workers.
+func (e *HashAggExec) runPartialWorker(groupByItems []expression.Expression, inputCh <-chan []*Row, aggErr *hashAggErr, wg *sync.WaitGroup) {
+	defer wg.Done()
+	for rows := range inputCh {
+		partitions := make([][]groupedRow, len(e.workers))
+		for _, row := range rows {
+			groupKey, err := e.evalGroupKey(groupByItems, row)
+			if err != nil {
+				aggErr.report(errors.Trace(err))
+				return
+			}
+			idx := crc32.ChecksumIEEE(groupKey) % uint32(len(e.workers))
+			partitions[idx] = append(partitions[idx], groupedRow{row: row, groupKey: groupKey})
+		}
+		for i, partition := range partitions {
+			if len(partition) == 0 {
+				continue
+			}
+			select {
+			case e.workers[i].rowsCh <- partition:
+			case <-aggErr.done:
+				return
+			}
+		}
+	}
+}
+
+// runFinalWorker updates the aggregate functions of the groups of the worker.
+func (e *HashAggExec) runFinalWorker(w *hashAggWorker, aggErr *hashAggErr, wg *sync.WaitGroup) {
+	defer wg.Done()
+	for rows := range w.rowsCh {
+		for _, r := range rows {
+			if _, ok := w.groupMap[string(r.groupKey)]; !ok {
+				if err := w.mem.consume(2*int64(len(r.groupKey)) + int64(len(w.aggFuncs))*datumMemUsage); err != nil {
+					aggErr.report(errors.Trace(err))
+					return
+				}
+				w.groupMap[string(r.groupKey)] = true
+				w.groups = append(w.groups, r.groupKey)
+			}
+			for _, af := range w.aggFuncs {
+				if err := af.Update(r.row.Data, r.groupKey, e.ctx); err != nil {
+					aggErr.report(errors.Trace(err))
+					return
+				}
+			}
+		}
+	}
+}
+
+// nextParallel returns the results of the groups of the final workers one by one.
+func (e *HashAggExec) nextParallel() (*Row, error) {
+	for e.currentWorkerIndex < len(e.workers) {
+		w := e.workers[e.currentWorkerIndex]
+		if e.currentGroupIndex >= len(w.groups) {
+			e.currentWorkerIndex++
+			e.currentGroupIndex = 0
+			continue
+		}
+		retRow := &Row{Data: make([]types.Datum, 0, len(w.aggFuncs))}
+		groupKey := w.groups[e.currentGroupIndex]
+		for _, af := range w.aggFuncs {
+			retRow.Data = append(retRow.Data, af.GetGroupResult(groupKey))
+		}
+		e.currentGroupIndex++
+		return retRow, nil
+	}
+	return nil, nil
+}
@@ -158,6 +158,10 @@ func (s *testSuite) TestAggregation(c *C) {
 	result.Check(testkit.Rows("0"))
 	result = tk.MustQuery("select sum(b.c), count(b.d), a.c from t a left join t b on a.c = b.d group by b.d order by b.d")
 	result.Check(testkit.Rows("<nil> 0 <nil>", "8 12 1", "5 2 3"))
+	result = tk.MustQuery("select avg(b.c), count(*), a.c from t a left join t b on a.c = b.d group by a.c order by a.c")
+	result.Check(testkit.Rows("<nil> 1 <nil>", "1.0000 12 1", "2.5000 2 3", "<nil> 1 4"))
+	result = tk.MustQuery("select avg(a.c), max(b.c), b.d from t a right join t b on a.c = b.d group by b.d order by b.d")
+	result.Check(testkit.Rows("1.0000 1 1", "<nil> 3 2", "3.0000 4 3"))
 	// This two cases prove that having always resolve name from field list firstly.
 	result = tk.MustQuery("select 1-d as d from t having d < 0 order by d desc")
 	result.Check(testkit.Rows("-1", "-1", "-2", "-2"))
@@ -241,6 +245,11 @@ func (s *testSuite) TestAggregation(c *C) {
 	result.Check(testkit.Rows("4", "2", "5"))
 	result = tk.MustQuery("select min(distinct b) from (select * from t1) t group by a")
 	result.Check(testkit.Rows("1", "2", "3"))
+	tk.MustExec("insert into t1 (a, b) values (1, null), (1, null), (4, null), (4, 2), (4, 2)")
+	result = tk.MustQuery("select a, count(distinct b), sum(distinct b), count(b), sum(b) from t1 group by a")
+	result.Check(testkit.Rows("1 2 5 3 6", "2 1 2 2 4", "3 2 8 4 16", "4 1 2 2 4"))
+	result = tk.MustQuery("select count(distinct b), avg(distinct b) from t1 where a = 3")
+	result.Check(testkit.Rows("2 4.0000"))
 	tk.MustExec("drop table if exists t1")
 	tk.MustExec("create table t1(a int, b int, index(b, a))")
 	tk.MustExec("insert into t1 (a, b) values (1, 1),(2, 2),(3, 3),(1, 4), (1,1),(3, 5), (2,2), (3,5), (3,3)")
@@ -269,6 +278,107 @@ func (s *testSuite) TestAggregation(c *C) {
 	result.Check(testkit.Rows("<nil>", "<nil>"))
 }
 
+func (s *testSuite) TestDistinctAggPushDown(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (a int, b int, c int)")
+	tk.MustExec("insert into t values (1, 1, 1), (1, 1, 2), (1, 2, 3), (2, 2, 4), (2, null, 5), (2, 2, 6), (3, null, 7)")
+
+	// The distinct rows of the group-by columns and the distinct argument are collected by the coprocessor.
+	var ids []string
+	for _, row := range tk.MustQuery("explain select count(distinct b) from t group by a").Rows() {
+		ids = append(ids, fmt.Sprintf("%v", row[0])[:7])
+	}
+	c.Assert(ids, DeepEquals, []string{"TableSc", "HashAgg", "HashAgg"})
+
+	tk.MustQuery("select a, count(distinct b), sum(distinct b), max(b), min(distinct b) from t group by a order by a").
+		Check(testkit.Rows("1 2 3 2 1", "2 1 2 2 2", "3 0 <nil> <nil> <nil>"))
+	tk.MustQuery("select count(distinct b), avg(distinct b) from t").Check(testkit.Rows("2 1.5000"))
+	tk.MustQuery("select count(distinct b) from t where c > 2 group by a order by a").Check(testkit.Rows("1", "1", "0"))
+	tk.MustQuery("select count(distinct b) from t where c > 10").Check(testkit.Rows("0"))
+	// The non-distinct aggregate functions on other columns can't be computed on the distinct rows.
+	tk.MustQuery("select a, count(distinct b), count(c) from t group by a order by a").Check(testkit.Rows("1 2 3", "2 1 3", "3 0 1"))
+}
+
+func (s *testSuite) TestParallelHashAgg(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (a int primary key, b int, c varchar(10))")
+	tk.MustExec("insert t select generate_series, generate_series % 10, generate_series % 3 from generate_series(1, 1000)")
+	tk.MustExec("set @@tidb_hash_agg_concurrency = 4")
+
+	tk.MustQuery("select b, count(*), sum(a), count(distinct c), min(a) from t group by b order by b").Check(testkit.Rows(
+		"0 100 50500 3 10", "1 100 49600 3 1", "2 100 49700 3 2", "3 100 49800 3 3", "4 100 49900 3 4",
+		"5 100 50000 3 5", "6 100 50100 3 6", "7 100 50200 3 7", "8 100 50300 3 8", "9 100 50400 3 9"))
+	tk.MustQuery("select count(*) from (select a % 300 as k from t group by k) k").Check(testkit.Rows("300"))
+	tk.MustQuery("select count(*) from t where a > 2000 group by b").Check(testkit.Rows())
+	tk.MustQuery("select count(*) from t").Check(testkit.Rows("1000"))
+
+	tk.MustExec("set @@tidb_hash_agg_concurrency = 0")
+	_, err := tk.Exec("select count(*) from t group by b")
+	c.Assert(err, NotNil)
+}
+
+func (s *testSuite) TestEliminateAggregation(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (a int primary key, b int, c int not null, d int not null, unique key (c, d))")
+	tk.MustExec("insert into t values (1, 1, 1, 1), (2, null, 1, 2), (3, 3, 2, 1)")
+
+	tk.MustQuery("select distinct a, b from t order by a").Check(testkit.Rows("1 1", "2 <nil>", "3 3"))
+	tk.MustQuery("select distinct c, d from t where b is not null order by c").Check(testkit.Rows("1 1", "2 1"))
+	tk.MustQuery("select a, max(b), min(c), count(*), count(distinct c) from t group by a order by a").
+		Check(testkit.Rows("1 1 1 1 1", "2 <nil> 1 1 1", "3 3 2 1 1"))
+	tk.MustQuery("select c, d, count(b), sum(b) from t group by c, d order by c, d").
+		Check(testkit.Rows("1 1 1 1", "1 2 0 <nil>", "2 1 1 3"))
+	tk.MustQuery("select d from t group by c, d having count(*) = 1 order by d").Check(testkit.Rows("1", "1", "2"))
+	// The distinct columns aren't a unique key.
+	tk.MustQuery("select distinct c from t order by c").Check(testkit.Rows("1", "2"))
+}
+
+func (s *testSuite) TestStreamDistinct(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t, o")
+	tk.MustExec("create table t (a int primary key, b int, c int, index idx_b(b), index idx_cb(c, b))")
+	tk.MustExec("insert t select generate_series, generate_series div 7, generate_series % 5 from generate_series(1, 300)")
+	tk.MustExec("create table o (x int)")
+	tk.MustExec("insert o values (1), (40), (2)")
+
+	// The rows read from the indexes are sorted by the distinct columns.
+	tk.MustQuery("select count(*) from (select distinct b from t use index(idx_b)) s").Check(testkit.Rows("43"))
+	tk.MustQuery("select count(*) from (select distinct b from t ignore index(idx_b)) s").Check(testkit.Rows("43"))
+	tk.MustQuery("select count(*) from (select distinct c, b from t use index(idx_cb)) s").Check(testkit.Rows("215"))
+	tk.MustQuery("select count(*) from (select distinct c, b from t ignore index(idx_cb)) s").Check(testkit.Rows("215"))
+	tk.MustQuery("select distinct c from t use index(idx_cb) order by c desc").Check(testkit.Rows("4", "3", "2", "1", "0"))
+	tk.MustQuery("select distinct b from t use index(idx_b) where b > 40").Check(testkit.Rows("41", "42"))
+
+	// The streaming distinct and aggregation are executed again for every outer row.
+	tk.MustQuery("select x, (select count(*) from (select distinct b from t use index(idx_b) where t.b >= o.x and t.b <= 3) s) from o order by x").
+		Check(testkit.Rows("1 3", "2 2", "40 0"))
+	tk.MustQuery("select x, (select count(*) from t use index(idx_b) where t.b >= o.x and t.b <= 3 group by b order by b desc limit 1) from o order by x").
+		Check(testkit.Rows("1 7", "2 7", "40 <nil>"))
+}
+
 func (s *testSuite) TestStreamAgg(c *C) {
 	col := &expression.Column{
 		Index: 1,
@@ -0,0 +1,203 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/meta"
+	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/table"
+	"github.com/pingcap/tidb/tablecodec"
+	"github.com/pingcap/tidb/util/types"
+)
+
+// ANALYZE TABLE reads the whole table, which can overload the storage at peak time. So it checks its limits
+// every analyzeCheckInterval rows: it pauses while the storage is busy, and it's interrupted when it runs out
+// of time or the storage stays busy for analyzeMaxPause. An interrupted ANALYZE saves its progress with the
+// statistics of the table, and the next ANALYZE of the table resumes from it.
+var (
+	analyzeCheckInterval = 256
+	analyzePauseInterval = 100 * time.Millisecond
+	analyzeMaxPause      = 10 * time.Second
+)
+
+// analyzeThrottle checks the limits of an ANALYZE TABLE.
+type analyzeThrottle struct {
+	// deadline is zero if the time is unlimited.
+	deadline   time.Time
+	maxLatency time.Duration
+	maxPending int64
+	// reporter is nil if the storage can't report its load.
+	reporter kv.LoadReporter
+}
+
+func newAnalyzeThrottle(ctx context.Context) (*analyzeThrottle, error) {
+	vars := ctx.GetSessionVars()
+	maxTime, err := getAnalyzeLimit(vars, variable.TiDBAnalyzeMaxExecutionTime)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	maxLatency, err := getAnalyzeLimit(vars, variable.TiDBAnalyzeMaxCopLatency)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	maxPending, err := getAnalyzeLimit(vars, variable.TiDBAnalyzeMaxCopPending)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	t := &analyzeThrottle{
+		maxLatency: time.Duration(maxLatency) * time.Millisecond,
+		maxPending: maxPending,
+	}
+	if maxTime > 0 {
+		t.deadline = time.Now().Add(time.Duration(maxTime) * time.Millisecond)
+	}
+	if maxLatency > 0 || maxPending > 0 {
+		t.reporter, _ = ctx.GetClient().(kv.LoadReporter)
+	}
+	return t, nil
+}
+
+func getAnalyzeLimit(vars *variable.SessionVars, name string) (int64, error) {
+	val, err := vars.GetTiDBSystemVar(name)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	limit, err := strconv.ParseInt(val, 10, 64)
+	if err != nil || limit < 0 {
+		return 0, errors.Errorf("invalid value %s for %s", val, name)
+	}
+	return limit, nil
+}
+
+// busy checks whether the storage is overloaded.
+func (t *analyzeThrottle) busy() bool {
+	if t.reporter == nil {
+		return false
+	}
+	latency, pending := t.reporter.Load()
+	return (t.maxLatency > 0 && latency > t.maxLatency) || (t.maxPending > 0 && pending > t.maxPending)
+}
+
+// wait pauses until the storage is not busy. It returns the reason if the ANALYZE should be interrupted, or
+// kv.ErrQueryInterrupted if it's killed.
+func (t *analyzeThrottle) wait(ctx context.Context) (string, error) {
+	var paused time.Duration
+	for {
+		if isKilled(ctx) {
+			return "", errors.Trace(kv.ErrQueryInterrupted)
+		}
+		if !t.deadline.IsZero() && time.Now().After(t.deadline) {
+			return "it exceeds " + variable.TiDBAnalyzeMaxExecutionTime, nil
+		}
+		if !t.busy() {
+			return "", nil
+		}
+		if paused >= analyzeMaxPause {
+			return "the storage is busy", nil
+		}
+		if err := sleepUnlessKilled(ctx, analyzePauseInterval); err != nil {
+			return "", errors.Trace(err)
+		}
+		paused += analyzePauseInterval
+	}
+}
+
+// analyzeState is the progress of an ANALYZE TABLE.
+type analyzeState struct {
+	lastHandle int64
+	count      int64
+	samples    [][]types.Datum
+}
+
+func columnIDs(cols []*table.Column) []int64 {
+	ids := make([]int64, 0, len(cols))
+	for _, col := range cols {
+		ids = append(ids, col.ID)
+	}
+	return ids
+}
+
+// loadAnalyzeState reads the saved progress of the table, it returns nil if the table should be analyzed from
+// the beginning.
+func loadAnalyzeState(txn kv.Transaction, tableID int64, cols []*table.Column) (*analyzeState, error) {
+	p, err := meta.NewMeta(txn).GetAnalyzeProgress(tableID)
+	if err != nil || p == nil {
+		return nil, errors.Trace(err)
+	}
+	colIDs := columnIDs(cols)
+	if !equalIDs(p.ColumnIDs, colIDs) {
+		log.Infof("[analyze] table %d is altered, discard the progress of the interrupted analyze", tableID)
+		return nil, nil
+	}
+	fts := make(map[int64]*types.FieldType, len(cols))
+	for _, col := range cols {
+		fts[col.ID] = &col.FieldType
+	}
+	state := &analyzeState{lastHandle: p.LastHandle, count: p.Count, samples: make([][]types.Datum, 0, len(p.Samples))}
+	for _, data := range p.Samples {
+		vals, err := tablecodec.DecodeRow(data, fts)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		row := make([]types.Datum, len(colIDs))
+		for i, id := range colIDs {
+			row[i] = vals[id]
+		}
+		state.samples = append(state.samples, row)
+	}
+	return state, nil
+}
+
+func equalIDs(a, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// saveAnalyzeState saves the progress of the interrupted ANALYZE of the table with the statistics, in a transaction
+// of its own since the transaction of the statement is rolled back.
+func saveAnalyzeState(store kv.Storage, tableID int64, cols []*table.Column, state *analyzeState) error {
+	colIDs := columnIDs(cols)
+	p := &model.AnalyzeProgress{
+		ColumnIDs:  colIDs,
+		LastHandle: state.lastHandle,
+		Count:      state.count,
+		Samples:    make([][]byte, 0, len(state.samples)),
+	}
+	for _, row := range state.samples {
+		data, err := tablecodec.EncodeRow(row, colIDs)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		p.Samples = append(p.Samples, data)
+	}
+	err := kv.RunInNewTxn(store, true, func(txn kv.Transaction) error {
+		return errors.Trace(meta.NewMeta(txn).SetAnalyzeProgress(tableID, p))
+	})
+	return errors.Trace(err)
+}
@@ -0,0 +1,111 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"container/list"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/expression"
+	"github.com/pingcap/tidb/util/codec"
+	"github.com/pingcap/tidb/util/types"
+)
+
+// applyCache memoizes the inner rows of an apply by the values of the correlated columns. When the memory of
+// the cached rows exceeds the capacity, the least recently used entries are evicted. An entry may only hold the
+// leading inner rows if the apply stops reading the inner rows early, e.g. when an "in" subquery finds a match.
+type applyCache struct {
+	capacity int64
+	used     int64
+	entries  map[string]*list.Element
+	lru      *list.List
+	mem      memoryUsage
+}
+
+type applyCacheEntry struct {
+	key  string
+	rows []*Row
+	size int64
+	// complete is false if the rows are only the leading inner rows.
+	complete bool
+}
+
+func newApplyCache(capacity int64, mem memoryUsage) *applyCache {
+	return &applyCache{
+		capacity: capacity,
+		entries:  make(map[string]*list.Element),
+		lru:      list.New(),
+		mem:      mem,
+	}
+}
+
+// applyCacheKey encodes the current values of the correlated columns.
+func applyCacheKey(cols []*expression.CorrelatedColumn) ([]byte, error) {
+	values := make([]types.Datum, 0, len(cols))
+	for _, col := range cols {
+		values = append(values, *col.Data)
+	}
+	key, err := codec.EncodeValue(nil, values...)
+	return key, errors.Trace(err)
+}
+
+// get returns the inner rows cached by the key, whether they are all the inner rows, and whether they are found.
+func (c *applyCache) get(key []byte) (rows []*Row, complete bool, ok bool) {
+	elem, ok := c.entries[string(key)]
+	if !ok {
+		return nil, false, false
+	}
+	c.lru.MoveToFront(elem)
+	entry := elem.Value.(*applyCacheEntry)
+	return entry.rows, entry.complete, true
+}
+
+// put caches the inner rows by the key, complete is false if they are only the leading inner rows. The entry of
+// the key is replaced, and the rows larger than the capacity aren't cached.
+func (c *applyCache) put(key []byte, rows []*Row, complete bool) error {
+	if elem, ok := c.entries[string(key)]; ok {
+		c.remove(elem)
+	}
+	size := int64(len(key))
+	for _, row := range rows {
+		size += rowMemUsage(row)
+	}
+	if size > c.capacity {
+		return nil
+	}
+	for c.used+size > c.capacity {
+		c.remove(c.lru.Back())
+	}
+	entry := &applyCacheEntry{key: string(key), rows: rows, size: size, complete: complete}
+	c.entries[entry.key] = c.lru.PushFront(entry)
+	c.used += size
+	return errors.Trace(c.mem.consume(size))
+}
+
+func (c *applyCache) remove(elem *list.Element) {
+	entry := elem.Value.(*applyCacheEntry)
+	c.lru.Remove(elem)
+	delete(c.entries, entry.key)
+	c.used -= entry.size
+	// Releasing memory never cancels the statement, the cancellation is returned when consuming it.
+	c.mem.consume(-entry.size)
+}
+
+// clear removes all the entries and releases their memory, it's called when the apply is closed.
+func (c *applyCache) clear() {
+	c.entries = make(map[string]*list.Element)
+	c.lru.Init()
+	c.used = 0
+	c.mem.close()
+}
@@ -15,6 +15,7 @@ package executor
 
 import (
 	"math"
+	"strconv"
 	"strings"
 
 	"github.com/juju/errors"
@@ -24,7 +25,12 @@ import (
 	"github.com/pingcap/tidb/infoschema"
 	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/plan"
+	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/table"
+	"github.com/pingcap/tidb/table/tables"
+	"github.com/pingcap/tidb/util/memory"
 	"github.com/pingcap/tidb/util/types"
 )
 
@@ -35,6 +41,13 @@ type executorBuilder struct {
 	is  infoschema.InfoSchema
 	// If there is any error during Executor building process, err is set.
 	err error
+	// runtimeStats is set when the executors are built for EXPLAIN ANALYZE, then every executor is wrapped
+	// to record its runtime statistics.
+	runtimeStats runtimeStatsColl
+	// memTracker tracks the memory consumed by the executors of the statement.
+	memTracker *memory.Tracker
+	// cteStorages stores the rows of the materialized common table expressions, which are shared by the references.
+	cteStorages map[*plan.CTESource]*cteStorage
 }
 
 func newExecutorBuilder(ctx context.Context, is infoschema.InfoSchema) *executorBuilder {
@@ -44,12 +57,35 @@ func newExecutorBuilder(ctx context.Context, is infoschema.InfoSchema) *executor
 	}
 }
 
+// newMemoryUsage returns the memory usage of the executor of the plan id, which is tracked by the tracker of
+// the statement and interrupted by KILL QUERY.
+func (b *executorBuilder) newMemoryUsage(id string) memoryUsage {
+	return memoryUsage{
+		tracker: b.memTracker.NewChild(id),
+		parent:  b.memTracker,
+		label:   id,
+		killed:  b.ctx.GetSessionVars().Killed,
+	}
+}
+
 func (b *executorBuilder) build(p plan.Plan) Executor {
+	e := b.buildExecutor(p)
+	if b.runtimeStats == nil || b.err != nil || e == nil {
+		return e
+	}
+	return b.runtimeStats.wrap(p, e)
+}
+
+func (b *executorBuilder) buildExecutor(p plan.Plan) Executor {
 	switch v := p.(type) {
 	case nil:
 		return nil
 	case *plan.CheckTable:
 		return b.buildCheckTable(v)
+	case *plan.RecoverIndex:
+		return b.buildRecoverIndex(v)
+	case *plan.CleanupIndex:
+		return b.buildCleanupIndex(v)
 	case *plan.DDL:
 		return b.buildDDL(v)
 	case *plan.Deallocate:
@@ -64,6 +100,8 @@ func (b *executorBuilder) build(p plan.Plan) Executor {
 		return b.buildExplain(v)
 	case *plan.Insert:
 		return b.buildInsert(v)
+	case *plan.SelectInto:
+		return b.buildSelectInto(v)
 	case *plan.LoadData:
 		return b.buildLoadData(v)
 	case *plan.Limit:
@@ -74,6 +112,24 @@ func (b *executorBuilder) build(p plan.Plan) Executor {
 		return b.buildSelectLock(v)
 	case *plan.ShowDDL:
 		return b.buildShowDDL(v)
+	case *plan.ShowDDLJobs:
+		return b.buildShowDDLJobs(v)
+	case *plan.ShowDeleteRanges:
+		return b.buildShowDeleteRanges(v)
+	case *plan.ShowSlowPlans:
+		return b.buildShowSlowPlans(v)
+	case *plan.Diagnose:
+		return b.buildDiagnose(v)
+	case *plan.ThrottleTable:
+		return b.buildThrottleTable(v)
+	case *plan.SetStats:
+		return b.buildSetStats(v)
+	case *plan.ResetStats:
+		return b.buildResetStats(v)
+	case *plan.PointGet:
+		return b.buildPointGet(v)
+	case *plan.BatchPointGet:
+		return b.buildBatchPointGet(v)
 	case *plan.Show:
 		return b.buildShow(v)
 	case *plan.Simple:
@@ -88,6 +144,10 @@ func (b *executorBuilder) build(p plan.Plan) Executor {
 		return b.buildUnionScanExec(v)
 	case *plan.PhysicalHashJoin:
 		return b.buildJoin(v)
+	case *plan.PhysicalMergeJoin:
+		return b.buildMergeJoin(v)
+	case *plan.PhysicalIndexJoin:
+		return b.buildIndexLookUpJoin(v)
 	case *plan.PhysicalHashSemiJoin:
 		return b.buildSemiJoin(v)
 	case *plan.Selection:
@@ -100,8 +160,20 @@ func (b *executorBuilder) build(p plan.Plan) Executor {
 		return b.buildTableScan(v)
 	case *plan.PhysicalIndexScan:
 		return b.buildIndexScan(v)
+	case *plan.PhysicalIndexMerge:
+		return b.buildIndexMerge(v)
 	case *plan.TableDual:
 		return b.buildTableDual(v)
+	case *plan.GenerateSeries:
+		return b.buildGenerateSeries(v)
+	case *plan.JSONTable:
+		return b.buildJSONTable(v)
+	case *plan.Profile:
+		return b.buildProfile(v)
+	case *plan.RowHistory:
+		return b.buildRowHistory(v)
+	case *plan.CTE:
+		return b.buildCTE(v)
 	case *plan.PhysicalApply:
 		return b.buildApply(v)
 	case *plan.Exists:
@@ -127,6 +199,105 @@ func (b *executorBuilder) buildShowDDL(v *plan.ShowDDL) Executor {
 	}
 }
 
+func (b *executorBuilder) buildShowDDLJobs(v *plan.ShowDDLJobs) Executor {
+	return &ShowDDLJobsExec{
+		ctx:    b.ctx,
+		schema: v.GetSchema(),
+	}
+}
+
+func (b *executorBuilder) buildShowDeleteRanges(v *plan.ShowDeleteRanges) Executor {
+	return &ShowDeleteRangesExec{
+		ctx:    b.ctx,
+		schema: v.GetSchema(),
+	}
+}
+
+func (b *executorBuilder) buildShowSlowPlans(v *plan.ShowSlowPlans) Executor {
+	return &ShowSlowPlansExec{
+		schema: v.GetSchema(),
+		ctx:    b.ctx,
+	}
+}
+
+func (b *executorBuilder) buildDiagnose(v *plan.Diagnose) Executor {
+	return &DiagnoseExec{
+		ctx:    b.ctx,
+		is:     b.is,
+		schema: v.GetSchema(),
+		query:  v.Query,
+	}
+}
+
+func (b *executorBuilder) buildPointGet(v *plan.PointGet) Executor {
+	tbl, ok := b.is.TableByID(v.Table.ID)
+	if !ok {
+		b.err = errors.Trace(infoschema.ErrTableNotExists)
+		return nil
+	}
+	e := &PointGetExec{
+		ctx:         b.ctx,
+		schema:      v.GetSchema(),
+		table:       tbl,
+		handle:      v.Handle,
+		indexValues: v.IndexValues,
+	}
+	for _, col := range v.Columns {
+		e.columns = append(e.columns, table.ToColumn(col))
+	}
+	if v.Index != nil {
+		e.index = tables.NewIndex(v.Table, v.Index)
+	}
+	return e
+}
+
+func (b *executorBuilder) buildBatchPointGet(v *plan.BatchPointGet) Executor {
+	tbl, ok := b.is.TableByID(v.Table.ID)
+	if !ok {
+		b.err = errors.Trace(infoschema.ErrTableNotExists)
+		return nil
+	}
+	e := &BatchPointGetExec{
+		ctx:         b.ctx,
+		schema:      v.GetSchema(),
+		table:       tbl,
+		handles:     v.Handles,
+		indexValues: v.IndexValues,
+	}
+	for _, col := range v.Columns {
+		e.columns = append(e.columns, table.ToColumn(col))
+	}
+	if v.Index != nil {
+		e.index = tables.NewIndex(v.Table, v.Index)
+	}
+	return e
+}
+
+func (b *executorBuilder) buildThrottleTable(v *plan.ThrottleTable) Executor {
+	return &ThrottleTableExec{
+		ctx:      b.ctx,
+		table:    v.Table,
+		limit:    v.Limit,
+		duration: v.Duration,
+	}
+}
+
+func (b *executorBuilder) buildSetStats(v *plan.SetStats) Executor {
+	return &SetStatsExec{
+		ctx:    b.ctx,
+		table:  v.Table,
+		column: v.Column,
+		value:  v.Value,
+	}
+}
+
+func (b *executorBuilder) buildResetStats(v *plan.ResetStats) Executor {
+	return &ResetStatsExec{
+		ctx:    b.ctx,
+		tables: v.Tables,
+	}
+}
+
 func (b *executorBuilder) buildCheckTable(v *plan.CheckTable) Executor {
 	return &CheckTableExec{
 		tables: v.Tables,
@@ -134,6 +305,34 @@ func (b *executorBuilder) buildCheckTable(v *plan.CheckTable) Executor {
 	}
 }
 
+func (b *executorBuilder) buildRecoverIndex(v *plan.RecoverIndex) Executor {
+	tbl, ok := b.is.TableByID(v.Table.ID)
+	if !ok {
+		b.err = errors.Trace(infoschema.ErrTableNotExists)
+		return nil
+	}
+	return &RecoverIndexExec{
+		ctx:    b.ctx,
+		schema: v.GetSchema(),
+		table:  tbl,
+		index:  tables.NewIndex(v.Table, v.Index),
+	}
+}
+
+func (b *executorBuilder) buildCleanupIndex(v *plan.CleanupIndex) Executor {
+	tbl, ok := b.is.TableByID(v.Table.ID)
+	if !ok {
+		b.err = errors.Trace(infoschema.ErrTableNotExists)
+		return nil
+	}
+	return &CleanupIndexExec{
+		ctx:    b.ctx,
+		schema: v.GetSchema(),
+		table:  tbl,
+		index:  tables.NewIndex(v.Table, v.Index),
+	}
+}
+
 func (b *executorBuilder) buildDeallocate(v *plan.Deallocate) Executor {
 	return &DeallocateExec{
 		ctx:  b.ctx,
@@ -153,6 +352,7 @@ func (b *executorBuilder) buildSelectLock(v *plan.SelectLock) Executor {
 	e := &SelectLockExec{
 		Src:    src,
 		Lock:   v.Lock,
+		Tables: v.Tables,
 		ctx:    b.ctx,
 		schema: v.GetSchema(),
 	}
@@ -171,7 +371,7 @@ func (b *executorBuilder) buildLimit(v *plan.Limit) Executor {
 }
 
 func (b *executorBuilder) buildDistinct(v *plan.Distinct) Executor {
-	return &DistinctExec{Src: b.build(v.GetChildByIndex(0)), schema: v.GetSchema()}
+	return &DistinctExec{Src: b.build(v.GetChildByIndex(0)), schema: v.GetSchema(), sorted: v.Sorted}
 }
 
 func (b *executorBuilder) buildPrepare(v *plan.Prepare) Executor {
@@ -180,26 +380,29 @@ func (b *executorBuilder) buildPrepare(v *plan.Prepare) Executor {
 		IS:      b.is,
 		Name:    v.Name,
 		SQLText: v.SQLText,
+		SQLVar:  v.SQLVar,
 	}
 }
 
 func (b *executorBuilder) buildExecute(v *plan.Execute) Executor {
 	return &ExecuteExec{
-		Ctx:       b.ctx,
-		IS:        b.is,
-		Name:      v.Name,
-		UsingVars: v.UsingVars,
-		ID:        v.ID,
+		Ctx:        b.ctx,
+		IS:         b.is,
+		Name:       v.Name,
+		UsingVars:  v.UsingVars,
+		ID:         v.ID,
+		memTracker: b.memTracker,
 	}
 }
 
 func (b *executorBuilder) buildShow(v *plan.Show) Executor {
 	e := &ShowExec{
 		Tp:          v.Tp,
-		DBName:      model.NewCIStr(v.DBName),
+		DBName:      model.NewTableCIStr(v.DBName),
 		Table:       v.Table,
 		Column:      v.Column,
 		User:        v.User,
+		Digest:      v.Digest,
 		Flag:        v.Flag,
 		Full:        v.Full,
 		GlobalScope: v.GlobalScope,
@@ -223,10 +426,11 @@ func (b *executorBuilder) buildSimple(v *plan.Simple) Executor {
 
 func (b *executorBuilder) buildInsert(v *plan.Insert) Executor {
 	ivs := &InsertValues{
-		ctx:     b.ctx,
-		Columns: v.Columns,
-		Lists:   v.Lists,
-		Setlist: v.Setlist,
+		ctx:          b.ctx,
+		Columns:      v.Columns,
+		Lists:        v.Lists,
+		Setlist:      v.Setlist,
+		ValuesStream: v.ValuesStream,
 	}
 	if len(v.GetChildren()) > 0 {
 		ivs.SelectExec = b.build(v.GetChildByIndex(0))
@@ -260,9 +464,21 @@ func (b *executorBuilder) buildInsert(v *plan.Insert) Executor {
 	}
 	// fields is used to evaluate values expr.
 	insert.fields = ts.GetResultFields()
+	if b.ctx.GetSessionVars().BatchInsert {
+		insert.batchSize = b.dmlBatchSize()
+	}
 	return insert
 }
 
+// dmlBatchSize returns the number of the rows written in each transaction by a statement split into batches.
+func (b *executorBuilder) dmlBatchSize() int64 {
+	batchSize, err := getNonNegativeSessionVar(b.ctx, variable.TiDBDMLBatchSize)
+	if err != nil {
+		b.err = errors.Trace(err)
+	}
+	return batchSize
+}
+
 func (b *executorBuilder) buildLoadData(v *plan.LoadData) Executor {
 	tbl, ok := b.is.TableByID(v.Table.TableInfo.ID)
 	if !ok {
@@ -270,15 +486,22 @@ func (b *executorBuilder) buildLoadData(v *plan.LoadData) Executor {
 		return nil
 	}
 
+	batchSize := b.dmlBatchSize()
+	if b.err != nil {
+		return nil
+	}
+
 	return &LoadData{
 		IsLocal: v.IsLocal,
 		loadDataInfo: &LoadDataInfo{
-			row:        make([]types.Datum, len(tbl.Cols())),
-			insertVal:  &InsertValues{ctx: b.ctx, Table: tbl},
-			Path:       v.Path,
-			Table:      tbl,
-			FieldsInfo: v.FieldsInfo,
-			LinesInfo:  v.LinesInfo,
+			row:         make([]types.Datum, len(tbl.Cols())),
+			insertVal:   &InsertValues{ctx: b.ctx, Table: tbl},
+			Path:        v.Path,
+			Table:       tbl,
+			FieldsInfo:  v.FieldsInfo,
+			LinesInfo:   v.LinesInfo,
+			IgnoreLines: v.IgnoreLines,
+			BatchSize:   batchSize,
 		},
 	}
 }
@@ -299,15 +522,52 @@ func (b *executorBuilder) buildGrant(grant *ast.GrantStmt) Executor {
 	}
 }
 
+func (b *executorBuilder) buildSelectInto(v *plan.SelectInto) Executor {
+	src := b.build(v.Target)
+	if b.err != nil {
+		return nil
+	}
+	return &SelectIntoExec{
+		ctx:        b.ctx,
+		src:        src,
+		path:       v.Path,
+		fieldsInfo: v.FieldsInfo,
+		linesInfo:  v.LinesInfo,
+	}
+}
+
 func (b *executorBuilder) buildDDL(v *plan.DDL) Executor {
 	return &DDLExec{Statement: v.Statement, ctx: b.ctx, is: b.is}
 }
 
 func (b *executorBuilder) buildExplain(v *plan.Explain) Executor {
-	return &ExplainExec{
+	e := &ExplainExec{
 		StmtPlan: v.StmtPlan,
 		schema:   v.GetSchema(),
+		format:   v.Format,
+	}
+	if !v.Analyze {
+		return e
+	}
+	switch v.StmtPlan.(type) {
+	case *plan.Insert, *plan.Update, *plan.Delete:
+		// In history read mode, we can not do write operations.
+		if b.ctx.GetSessionVars().SnapshotTS != 0 {
+			b.err = errors.New("can not execute write statement when 'tidb_snapshot' is set")
+			return nil
+		}
+	}
+	maxChunkSize, err := getNonNegativeSessionVar(b.ctx, variable.TiDBMaxChunkSize)
+	if err != nil {
+		b.err = errors.Trace(err)
+		return nil
 	}
+	e.maxChunkSize = int(maxChunkSize)
+	b.runtimeStats = make(runtimeStatsColl)
+	e.stmtExec = b.build(v.StmtPlan)
+	e.runtimeStats = b.runtimeStats
+	b.runtimeStats = nil
+	return e
 }
 
 func (b *executorBuilder) buildUnionScanExec(v *plan.PhysicalUnionScan) *UnionScanExec {
@@ -316,12 +576,16 @@ func (b *executorBuilder) buildUnionScanExec(v *plan.PhysicalUnionScan) *UnionSc
 		return nil
 	}
 	us := &UnionScanExec{ctx: b.ctx, Src: src, schema: v.GetSchema()}
+	// The source is wrapped when the runtime statistics are collected.
+	if rs, ok := src.(*runtimeStatsExec); ok {
+		src = rs.Executor
+	}
 	switch x := src.(type) {
 	case *XSelectTableExec:
 		us.desc = x.desc
 		us.dirty = getDirtyDB(b.ctx).getDirtyTable(x.table.Meta().ID)
 		us.condition = v.Condition
-		us.buildAndSortAddedRows(x.table, x.asName)
+		us.buildAndSortAddedRows(x.table, x.asName, x.Columns)
 	case *XSelectIndexExec:
 		us.desc = x.indexPlan.Desc
 		for _, ic := range x.indexPlan.Index.Columns {
@@ -334,13 +598,40 @@ func (b *executorBuilder) buildUnionScanExec(v *plan.PhysicalUnionScan) *UnionSc
 		}
 		us.dirty = getDirtyDB(b.ctx).getDirtyTable(x.table.Meta().ID)
 		us.condition = v.Condition
-		us.buildAndSortAddedRows(x.table, x.asName)
+		us.buildAndSortAddedRows(x.table, x.asName, x.indexPlan.Columns)
+	case *XSelectIndexMergeExec:
+		us.dirty = getDirtyDB(b.ctx).getDirtyTable(x.table.Meta().ID)
+		us.condition = v.Condition
+		us.buildAndSortAddedRows(x.table, x.asName, x.columns)
 	default:
 		b.err = ErrUnknownPlan.Gen("Unknown Plan %T", src)
 	}
 	return us
 }
 
+// joinKeyType returns the type which the join keys of the types a and b are converted to before they are hashed.
+// It's the merged type of them, except that an enum or set is compared with a number by its numeric value, so
+// they are converted to double rather than the merged string type.
+func joinKeyType(a, b *types.FieldType) *types.FieldType {
+	if (isEnumOrSetType(a.Tp) && isNumericType(b.Tp)) || (isEnumOrSetType(b.Tp) && isNumericType(a.Tp)) {
+		return types.NewFieldType(mysql.TypeDouble)
+	}
+	return types.NewFieldType(types.MergeFieldType(a.Tp, b.Tp))
+}
+
+func isEnumOrSetType(tp byte) bool {
+	return tp == mysql.TypeEnum || tp == mysql.TypeSet
+}
+
+func isNumericType(tp byte) bool {
+	switch tp {
+	case mysql.TypeTiny, mysql.TypeShort, mysql.TypeInt24, mysql.TypeLong, mysql.TypeLonglong, mysql.TypeYear,
+		mysql.TypeFloat, mysql.TypeDouble, mysql.TypeDecimal, mysql.TypeNewDecimal:
+		return true
+	}
+	return false
+}
+
 func (b *executorBuilder) buildJoin(v *plan.PhysicalHashJoin) Executor {
 	var leftHashKey, rightHashKey []*expression.Column
 	var targetTypes []*types.FieldType
@@ -349,7 +640,7 @@ func (b *executorBuilder) buildJoin(v *plan.PhysicalHashJoin) Executor {
 		rn, _ := eqCond.Args[1].(*expression.Column)
 		leftHashKey = append(leftHashKey, ln)
 		rightHashKey = append(rightHashKey, rn)
-		targetTypes = append(targetTypes, types.NewFieldType(types.MergeFieldType(ln.GetType().Tp, rn.GetType().Tp)))
+		targetTypes = append(targetTypes, joinKeyType(ln.GetType(), rn.GetType()))
 	}
 	e := &HashJoinExec{
 		schema:        v.GetSchema(),
@@ -358,7 +649,17 @@ func (b *executorBuilder) buildJoin(v *plan.PhysicalHashJoin) Executor {
 		ctx:           b.ctx,
 		targetTypes:   targetTypes,
 		concurrency:   v.Concurrency,
+		keepOrder:     v.KeepOrder,
 		defaultValues: v.DefaultValues,
+		mem:           b.newMemoryUsage(v.GetID()),
+	}
+	concurrency, err := getHashJoinConcurrency(b.ctx)
+	if err != nil {
+		b.err = errors.Trace(err)
+		return nil
+	}
+	if concurrency > 0 {
+		e.concurrency = concurrency
 	}
 	if v.SmallTable == 1 {
 		e.smallFilter = expression.ComposeCNFCondition(v.RightConditions)
@@ -383,6 +684,12 @@ func (b *executorBuilder) buildJoin(v *plan.PhysicalHashJoin) Executor {
 		e.smallExec = b.build(v.GetChildByIndex(1))
 		e.bigExec = b.build(v.GetChildByIndex(0))
 	}
+	maxInSize, err := getNonNegativeSessionVar(b.ctx, variable.TiDBHashJoinRuntimeFilter)
+	if err != nil {
+		b.err = errors.Trace(err)
+		return nil
+	}
+	e.runtimeFilter = newRuntimeFilter(e, int(maxInSize))
 	for i := 0; i < e.concurrency; i++ {
 		ctx := &hashJoinCtx{}
 		if e.bigFilter != nil {
@@ -398,6 +705,111 @@ func (b *executorBuilder) buildJoin(v *plan.PhysicalHashJoin) Executor {
 	return e
 }
 
+// getHashJoinConcurrency gets the number of the hash join workers from the session variable, 0 means the number
+// decided by the plan.
+func getHashJoinConcurrency(ctx context.Context) (int, error) {
+	val, err := ctx.GetSessionVars().GetTiDBSystemVar(variable.TiDBHashJoinConcurrency)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	concurrency, err := strconv.Atoi(val)
+	if err != nil || concurrency < 0 {
+		return 0, errors.Errorf("invalid value %s for %s", val, variable.TiDBHashJoinConcurrency)
+	}
+	return concurrency, nil
+}
+
+func (b *executorBuilder) buildMergeJoin(v *plan.PhysicalMergeJoin) Executor {
+	var leftKeys, rightKeys []*expression.Column
+	for _, eqCond := range v.EqualConditions {
+		ln, _ := eqCond.Args[0].(*expression.Column)
+		rn, _ := eqCond.Args[1].(*expression.Column)
+		leftKeys = append(leftKeys, ln)
+		rightKeys = append(rightKeys, rn)
+	}
+	e := &MergeJoinExec{
+		ctx:           b.ctx,
+		schema:        v.GetSchema(),
+		otherFilter:   expression.ComposeCNFCondition(v.OtherConditions),
+		outer:         v.JoinType == plan.LeftOuterJoin || v.JoinType == plan.RightOuterJoin,
+		defaultValues: v.DefaultValues,
+		mem:           b.newMemoryUsage(v.GetID()),
+	}
+	// The rows of the right outer join are output in the order of the right child.
+	if v.JoinType == plan.RightOuterJoin {
+		e.outerFilter = expression.ComposeCNFCondition(v.RightConditions)
+		e.innerFilter = expression.ComposeCNFCondition(v.LeftConditions)
+		e.outerKeys, e.innerKeys = rightKeys, leftKeys
+		e.outerExec = b.build(v.GetChildByIndex(1))
+		e.innerExec = b.build(v.GetChildByIndex(0))
+	} else {
+		e.leftOuter = true
+		e.outerFilter = expression.ComposeCNFCondition(v.LeftConditions)
+		e.innerFilter = expression.ComposeCNFCondition(v.RightConditions)
+		e.outerKeys, e.innerKeys = leftKeys, rightKeys
+		e.outerExec = b.build(v.GetChildByIndex(0))
+		e.innerExec = b.build(v.GetChildByIndex(1))
+	}
+	return e
+}
+
+func (b *executorBuilder) buildIndexLookUpJoin(v *plan.PhysicalIndexJoin) Executor {
+	var outerKeys, innerKeys []*expression.Column
+	var targetTypes []*types.FieldType
+	for _, eqCond := range v.EqualConditions {
+		outerKey, _ := eqCond.Args[v.OuterIndex].(*expression.Column)
+		innerKey, _ := eqCond.Args[1-v.OuterIndex].(*expression.Column)
+		outerKeys = append(outerKeys, outerKey)
+		innerKeys = append(innerKeys, innerKey)
+		targetTypes = append(targetTypes, joinKeyType(outerKey.GetType(), innerKey.GetType()))
+	}
+	e := &IndexLookUpJoinExec{
+		ctx:           b.ctx,
+		is:            b.is,
+		schema:        v.GetSchema(),
+		joinPlan:      v,
+		outerKeys:     outerKeys,
+		innerKeys:     innerKeys,
+		otherFilter:   expression.ComposeCNFCondition(v.OtherConditions),
+		outer:         v.JoinType == plan.LeftOuterJoin || v.JoinType == plan.RightOuterJoin,
+		leftOuter:     v.OuterIndex == 0,
+		defaultValues: v.DefaultValues,
+		innerColumns:  len(v.GetChildByIndex(1 - v.OuterIndex).GetSchema()),
+		targetTypes:   targetTypes,
+		memTracker:    b.memTracker,
+	}
+	if e.leftOuter {
+		e.outerFilter = expression.ComposeCNFCondition(v.LeftConditions)
+		e.innerFilter = expression.ComposeCNFCondition(v.RightConditions)
+	} else {
+		e.outerFilter = expression.ComposeCNFCondition(v.RightConditions)
+		e.innerFilter = expression.ComposeCNFCondition(v.LeftConditions)
+	}
+	e.batchSize, b.err = getPositiveSessionVar(b.ctx, variable.TiDBIndexJoinBatchSize)
+	if b.err != nil {
+		return nil
+	}
+	e.concurrency, b.err = getPositiveSessionVar(b.ctx, variable.TiDBIndexLookUpJoinConcurrency)
+	if b.err != nil {
+		return nil
+	}
+	e.outerExec = b.build(v.GetChildByIndex(v.OuterIndex))
+	return e
+}
+
+// getPositiveSessionVar gets the option of the executor from the session variable, it must be positive.
+func getPositiveSessionVar(ctx context.Context, name string) (int, error) {
+	val, err := ctx.GetSessionVars().GetTiDBSystemVar(name)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	option, err := strconv.Atoi(val)
+	if err != nil || option <= 0 {
+		return 0, errors.Errorf("invalid value %s for %s", val, name)
+	}
+	return option, nil
+}
+
 func (b *executorBuilder) buildSemiJoin(v *plan.PhysicalHashSemiJoin) Executor {
 	var leftHashKey, rightHashKey []*expression.Column
 	var targetTypes []*types.FieldType
@@ -406,7 +818,7 @@ func (b *executorBuilder) buildSemiJoin(v *plan.PhysicalHashSemiJoin) Executor {
 		rn, _ := eqCond.Args[1].(*expression.Column)
 		leftHashKey = append(leftHashKey, ln)
 		rightHashKey = append(rightHashKey, rn)
-		targetTypes = append(targetTypes, types.NewFieldType(types.MergeFieldType(ln.GetType().Tp, rn.GetType().Tp)))
+		targetTypes = append(targetTypes, joinKeyType(ln.GetType(), rn.GetType()))
 	}
 	e := &HashSemiJoinExec{
 		schema:       v.GetSchema(),
@@ -421,6 +833,7 @@ func (b *executorBuilder) buildSemiJoin(v *plan.PhysicalHashSemiJoin) Executor {
 		smallHashKey: rightHashKey,
 		auxMode:      v.WithAux,
 		anti:         v.Anti,
+		nullAware:    v.NullAware,
 		targetTypes:  targetTypes,
 	}
 	return e
@@ -428,32 +841,78 @@ func (b *executorBuilder) buildSemiJoin(v *plan.PhysicalHashSemiJoin) Executor {
 
 func (b *executorBuilder) buildAggregation(v *plan.PhysicalAggregation) Executor {
 	src := b.build(v.GetChildByIndex(0))
+	chunkSize, err := getNonNegativeSessionVar(b.ctx, variable.TiDBMaxChunkSize)
+	if err != nil {
+		b.err = errors.Trace(err)
+		return nil
+	}
+	reader := rowReader{chunkSize: int(chunkSize)}
 	if v.AggType == plan.StreamedAgg {
-		return &StreamAggExec{
+		e := &StreamAggExec{
 			Src:          src,
+			reader:       reader,
 			schema:       v.GetSchema(),
 			ctx:          b.ctx,
 			AggFuncs:     v.AggFuncs,
 			GroupByItems: v.GroupByItems,
 		}
+		if v.DistinctSorted {
+			// The distinct aggregate functions are executed as the non-distinct ones,
+			// the executor skips the duplicated arguments by itself.
+			e.AggFuncs = make([]expression.AggregationFunction, 0, len(v.AggFuncs))
+			e.distinctFuncs = make([]bool, 0, len(v.AggFuncs))
+			for _, af := range v.AggFuncs {
+				distinct := af.IsDistinct()
+				if distinct {
+					e.distinctArg = af.GetArgs()[0]
+					af = expression.NewAggFunction(af.GetName(), af.GetArgs(), false)
+				}
+				e.AggFuncs = append(e.AggFuncs, af)
+				e.distinctFuncs = append(e.distinctFuncs, distinct)
+			}
+		}
+		return e
 	}
-	return &HashAggExec{
+	e := &HashAggExec{
 		Src:          src,
+		reader:       reader,
 		schema:       v.GetSchema(),
 		ctx:          b.ctx,
 		AggFuncs:     v.AggFuncs,
 		GroupByItems: v.GroupByItems,
 		aggType:      v.AggType,
 		hasGby:       v.HasGby,
+		mem:          b.newMemoryUsage(v.GetID()),
+	}
+	e.concurrency, b.err = getPositiveSessionVar(b.ctx, variable.TiDBHashAggConcurrency)
+	if b.err != nil {
+		return nil
 	}
+	return e
 }
 
 func (b *executorBuilder) buildSelection(v *plan.Selection) Executor {
+	src := b.build(v.GetChildByIndex(0))
+	conditions := plan.SortConditionsByRank(v.Conditions)
+	// The selection on a table scan or on the table rows of an index lookup is evaluated by the scan on the lazily
+	// decoded rows.
+	switch x := src.(type) {
+	case *XSelectTableExec:
+		if !x.aggregate {
+			x.filter = newLazyFilter(conditions)
+			return x
+		}
+	case *XSelectIndexExec:
+		if !x.aggregate && !x.singleReadMode {
+			x.filter = newLazyFilter(conditions)
+			return x
+		}
+	}
 	exec := &SelectionExec{
-		Src:       b.build(v.GetChildByIndex(0)),
-		Condition: expression.ComposeCNFCondition(v.Conditions),
-		schema:    v.GetSchema(),
-		ctx:       b.ctx,
+		Src:        src,
+		Conditions: conditions,
+		schema:     v.GetSchema(),
+		ctx:        b.ctx,
 	}
 	return exec
 }
@@ -471,6 +930,45 @@ func (b *executorBuilder) buildTableDual(v *plan.TableDual) Executor {
 	return &TableDualExec{schema: v.GetSchema()}
 }
 
+func (b *executorBuilder) buildGenerateSeries(v *plan.GenerateSeries) Executor {
+	return &GenerateSeriesExec{
+		schema: v.GetSchema(),
+		start:  v.Start,
+		stop:   v.Stop,
+		step:   v.Step,
+	}
+}
+
+func (b *executorBuilder) buildJSONTable(v *plan.JSONTable) Executor {
+	return &JSONTableExec{
+		schema:  v.GetSchema(),
+		ctx:     b.ctx,
+		doc:     v.Doc,
+		rowPath: v.RowPath,
+		columns: v.Columns,
+	}
+}
+
+func (b *executorBuilder) buildProfile(v *plan.Profile) Executor {
+	return &ProfileExec{
+		ctx:      b.ctx,
+		schema:   v.GetSchema(),
+		kind:     v.Kind,
+		duration: v.Duration,
+	}
+}
+
+func (b *executorBuilder) buildRowHistory(v *plan.RowHistory) Executor {
+	return &RowHistoryExec{
+		schema:  v.GetSchema(),
+		ctx:     b.ctx,
+		dbID:    v.DBID,
+		table:   v.Table,
+		columns: v.Columns,
+		handle:  v.Handle,
+	}
+}
+
 func (b *executorBuilder) getStartTS() uint64 {
 	startTS := b.ctx.GetSessionVars().SnapshotTS
 	if startTS == 0 {
@@ -560,12 +1058,59 @@ func (b *executorBuilder) buildIndexScan(v *plan.PhysicalIndexScan) Executor {
 			byItems:        v.GbyItemsPB,
 		}
 		st.scanConcurrency, b.err = getScanConcurrency(b.ctx)
+		if b.err != nil {
+			return nil
+		}
+		st.lookupConcurrency, b.err = getPositiveSessionVar(b.ctx, variable.TiDBIndexLookupConcurrency)
+		if b.err != nil {
+			return nil
+		}
+		st.lookupSize, b.err = getPositiveSessionVar(b.ctx, variable.TiDBIndexLookupSize)
+		if b.err != nil {
+			return nil
+		}
 		return st
 	}
 	b.err = errors.New("not implement yet")
 	return nil
 }
 
+func (b *executorBuilder) buildIndexMerge(v *plan.PhysicalIndexMerge) Executor {
+	startTS := b.getStartTS()
+	if b.err != nil {
+		return nil
+	}
+	table, _ := b.is.TableByID(v.Table.ID)
+	client := b.ctx.GetClient()
+	memDB := infoschema.IsMemoryDB(v.DBName.L)
+	if memDB || !client.SupportRequestType(kv.ReqTypeIndex, 0) || !client.SupportRequestType(kv.ReqTypeSelect, 0) {
+		b.err = errors.New("not implement yet")
+		return nil
+	}
+	e := &XSelectIndexMergeExec{
+		table:   table,
+		asName:  v.TableAsName,
+		ctx:     b.ctx,
+		startTS: startTS,
+		schema:  v.GetSchema(),
+		columns: v.Columns,
+		where:   v.ConditionPBExpr,
+	}
+	e.scanConcurrency, b.err = getScanConcurrency(b.ctx)
+	for _, partial := range v.PartialPlans {
+		e.partialExecs = append(e.partialExecs, &XSelectIndexExec{
+			tableInfo:       v.Table,
+			ctx:             b.ctx,
+			asName:          v.TableAsName,
+			table:           table,
+			indexPlan:       partial,
+			startTS:         startTS,
+			scanConcurrency: e.scanConcurrency,
+		})
+	}
+	return e
+}
+
 func (b *executorBuilder) buildSort(v *plan.Sort) Executor {
 	src := b.build(v.GetChildByIndex(0))
 	if v.ExecLimit != nil {
@@ -578,11 +1123,18 @@ func (b *executorBuilder) buildSort(v *plan.Sort) Executor {
 			limit: v.ExecLimit,
 		}
 	}
+	memQuota, err := getNonNegativeSessionVar(b.ctx, variable.TiDBMemQuotaSort)
+	if err != nil {
+		b.err = errors.Trace(err)
+		return nil
+	}
 	return &SortExec{
-		Src:     src,
-		ByItems: v.ByItems,
-		ctx:     b.ctx,
-		schema:  v.GetSchema(),
+		Src:      src,
+		ByItems:  v.ByItems,
+		ctx:      b.ctx,
+		schema:   v.GetSchema(),
+		mem:      b.newMemoryUsage(v.GetID()),
+		memQuota: memQuota,
 	}
 }
 
@@ -593,6 +1145,7 @@ func (b *executorBuilder) buildApply(v *plan.PhysicalApply) Executor {
 		innerExec:   b.build(v.InnerPlan),
 		outerSchema: v.OuterSchema,
 		Src:         src,
+		lateral:     v.Lateral,
 	}
 	if v.Checker != nil {
 		apply.checker = &conditionChecker{
@@ -602,9 +1155,33 @@ func (b *executorBuilder) buildApply(v *plan.PhysicalApply) Executor {
 			ctx:     b.ctx,
 		}
 	}
+	if v.CacheInner {
+		capacity, err := getNonNegativeSessionVar(b.ctx, variable.TiDBApplyCacheCapacity)
+		if err != nil {
+			b.err = errors.Trace(err)
+			return nil
+		}
+		if capacity > 0 {
+			apply.cache = newApplyCache(capacity, b.newMemoryUsage(v.GetID()))
+		}
+	}
 	return apply
 }
 
+// getNonNegativeSessionVar gets the size of the executor from the session variable, such as the capacity of
+// the apply cache, it must not be negative.
+func getNonNegativeSessionVar(ctx context.Context, name string) (int64, error) {
+	val, err := ctx.GetSessionVars().GetTiDBSystemVar(name)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	size, err := strconv.ParseInt(val, 10, 64)
+	if err != nil || size < 0 {
+		return 0, errors.Errorf("invalid value %s for %s", val, name)
+	}
+	return size, nil
+}
+
 func (b *executorBuilder) buildExists(v *plan.Exists) Executor {
 	return &ExistsExec{
 		schema: v.GetSchema(),
@@ -631,11 +1208,20 @@ func (b *executorBuilder) buildUnion(v *plan.Union) Executor {
 	e := &UnionExec{
 		schema: v.GetSchema(),
 		Srcs:   make([]Executor, len(v.GetChildren())),
+		ctx:    b.ctx,
 	}
 	for i, sel := range v.GetChildren() {
 		selExec := b.build(sel)
 		e.Srcs[i] = selExec
 	}
+	e.concurrency, b.err = getPositiveSessionVar(b.ctx, variable.TiDBUnionConcurrency)
+	if b.err != nil {
+		return nil
+	}
+	if v.SideEffects {
+		// The session isn't thread-safe, the children changing it are executed one by one.
+		e.concurrency = 1
+	}
 	return e
 }
 
@@ -652,12 +1238,35 @@ func (b *executorBuilder) buildDummyScan(v *plan.PhysicalDummyScan) Executor {
 
 func (b *executorBuilder) buildDelete(v *plan.Delete) Executor {
 	selExec := b.build(v.GetChildByIndex(0))
-	return &DeleteExec{
+	e := &DeleteExec{
 		ctx:          b.ctx,
 		SelectExec:   selExec,
 		Tables:       v.Tables,
 		IsMultiTable: v.IsMultiTable,
 	}
+	if b.ctx.GetSessionVars().BatchDelete {
+		e.batchSize = b.dmlBatchSize()
+	}
+	return e
+}
+
+func (b *executorBuilder) buildCTE(v *plan.CTE) Executor {
+	storage, ok := b.cteStorages[v.Source]
+	if !ok {
+		src := b.build(v.Source.Plan)
+		if b.err != nil {
+			return nil
+		}
+		storage = &cteStorage{src: src, mem: b.newMemoryUsage(v.Source.Plan.GetID())}
+		if b.cteStorages == nil {
+			b.cteStorages = make(map[*plan.CTESource]*cteStorage)
+		}
+		b.cteStorages[v.Source] = storage
+	}
+	return &CTEExec{
+		schema:  v.GetSchema(),
+		storage: storage,
+	}
 }
 
 func (b *executorBuilder) buildCache(v *plan.Cache) Executor {
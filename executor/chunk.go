@@ -0,0 +1,119 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/util/chunk"
+)
+
+var (
+	_ chunkExecutor = &LimitExec{}
+	_ chunkExecutor = &ProjectionExec{}
+	_ chunkExecutor = &SelectionExec{}
+	_ chunkExecutor = &SortExec{}
+	_ chunkExecutor = &TopnExec{}
+	_ chunkExecutor = &XSelectTableExec{}
+	_ chunkExecutor = &XSelectIndexExec{}
+	_ chunkExecutor = &runtimeStatsExec{}
+)
+
+// chunkExecutor is an executor which can return its rows in chunks instead of one by one, which saves the
+// interface calls and the allocations of the rows. The rows in the chunks don't have the row keys, so the
+// executors which update or lock the rows still read them by Next, and so do the executors whose rows may be
+// updated, like the joins, unless they're read in chunks themselves.
+//
+// The table and index readers, the selections, the projections, the limits and the sorts return their rows in
+// chunks, and the aggregations read the rows of their sources in chunks, since their rows never have row keys.
+// The other executors return their rows by Next, which nextChunk appends to the chunks of their parents.
+type chunkExecutor interface {
+	Executor
+	// supportChunk checks if the executor can return its rows by NextChunk.
+	supportChunk() bool
+	// NextChunk fills chk with the next rows, chk is full unless there is no more row, so it isn't called again
+	// after it returns a chunk which isn't full.
+	NextChunk(chk *chunk.Chunk) error
+}
+
+// supportChunk checks if e can return its rows in chunks.
+func supportChunk(e Executor) bool {
+	ce, ok := e.(chunkExecutor)
+	return ok && ce.supportChunk()
+}
+
+// newChunk creates a chunk for the rows of e.
+func newChunk(e Executor, capacity int) *chunk.Chunk {
+	return chunk.New(len(e.Schema()), capacity)
+}
+
+// nextChunk resets chk and fills it with the next rows of e. If e can't return its rows in chunks, its rows are
+// read by Next and appended to chk.
+func nextChunk(e Executor, chk *chunk.Chunk) error {
+	chk.Reset()
+	if supportChunk(e) {
+		return errors.Trace(e.(chunkExecutor).NextChunk(chk))
+	}
+	for !chk.IsFull() {
+		row, err := e.Next()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if row == nil {
+			return nil
+		}
+		chk.AppendDatums(row.Data)
+	}
+	return nil
+}
+
+// rowReader reads all the rows of the source of an executor which doesn't keep their row keys, like the
+// aggregations. The rows are read in chunks of chunkSize rows if the source can return them so, and they're
+// copied out of the chunks, so they can be kept after the next chunk is read.
+type rowReader struct {
+	chunkSize int
+	chk       *chunk.Chunk
+	idx       int
+	done      bool
+}
+
+// next returns the next row of src, it returns nil if there is no more row.
+func (r *rowReader) next(src Executor) (*Row, error) {
+	if r.chunkSize <= 0 || !supportChunk(src) {
+		row, err := src.Next()
+		return row, errors.Trace(err)
+	}
+	if r.chk == nil {
+		r.chk = newChunk(src, r.chunkSize)
+	}
+	for r.idx >= r.chk.NumRows() {
+		if r.done {
+			return nil, nil
+		}
+		if err := nextChunk(src, r.chk); err != nil {
+			return nil, errors.Trace(err)
+		}
+		r.idx = 0
+		r.done = !r.chk.IsFull()
+	}
+	row := r.chk.GetRow(r.idx)
+	r.idx++
+	return &Row{Data: row.GetDatumRow()}, nil
+}
+
+// reset makes the reader read the source again after the source is closed.
+func (r *rowReader) reset() {
+	r.chk = nil
+	r.idx = 0
+	r.done = false
+}
@@ -14,14 +14,23 @@
 package executor
 
 import (
+	"fmt"
+
 	"github.com/juju/errors"
 	"github.com/ngaut/log"
 	"github.com/pingcap/tidb/ast"
 	"github.com/pingcap/tidb/context"
 	"github.com/pingcap/tidb/infoschema"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/parser"
 	"github.com/pingcap/tidb/plan"
 	"github.com/pingcap/tidb/sessionctx"
 	"github.com/pingcap/tidb/sessionctx/binloginfo"
+	"github.com/pingcap/tidb/util/bindinfo"
+	"github.com/pingcap/tidb/util/planbaseline"
+	"github.com/pingcap/tidb/util/sqlexec"
 )
 
 // Compiler compiles an ast.StmtNode to a stmt.Statement.
@@ -178,16 +187,161 @@ func (c *Compiler) Compile(ctx context.Context, node ast.StmtNode) (ast.Statemen
 	if err := plan.Validate(node, false); err != nil {
 		return nil, errors.Trace(err)
 	}
+	if sel := bindableSelect(node); sel != nil {
+		applyBinding(ctx, sel)
+	}
+	var baseline *baselineInfo
+	sel, isSelect := node.(*ast.SelectStmt)
+	if isSelect && sessVar.PlanBaseline && !sessVar.InRestrictedSQL {
+		baseline = newBaselineInfo(ctx, sel)
+	}
 	p, err := plan.Optimize(ctx, node, is)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
+	if baseline != nil {
+		p, err = applyPlanBaseline(ctx, sel, is, p, baseline)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if baseline.hints == "" {
+			baseline = nil
+		}
+	}
 	_, isDDL := node.(ast.DDLNode)
 	sa := &statement{
-		is:    is,
-		plan:  p,
-		text:  node.Text(),
-		isDDL: isDDL,
+		is:       is,
+		plan:     p,
+		text:     node.Text(),
+		isDDL:    isDDL,
+		baseline: baseline,
 	}
 	return sa, nil
 }
+
+// bindableSelect returns the SELECT statement which may be planned by a binding, including the explained one.
+func bindableSelect(node ast.StmtNode) *ast.SelectStmt {
+	if explain, ok := node.(*ast.ExplainStmt); ok {
+		node = explain.Stmt
+	}
+	sel, _ := node.(*ast.SelectStmt)
+	return sel
+}
+
+// applyBinding makes the statement planned by the hints of its binding. The statements with the hints written by
+// the users are left as they are.
+func applyBinding(ctx context.Context, sel *ast.SelectStmt) {
+	if len(sel.TableHints) > 0 || ctx.Value(context.Initing) != nil {
+		return
+	}
+	store := sessionctx.GetDomain(ctx).Store()
+	loadBindings(ctx, store)
+	if bindinfo.GlobalHandle.IsEmpty(store) {
+		return
+	}
+	binding := bindinfo.GlobalHandle.Match(store, parser.Normalize(sel.Text()), ctx.GetSessionVars().CurrentDB)
+	if binding != nil {
+		sel.TableHints = copyHints(binding.Hints())
+	}
+}
+
+// loadBindings reloads the bindings from mysql.bind_info if they expire. They aren't loaded in an explicit
+// transaction, whose snapshot may be stale.
+func loadBindings(ctx context.Context, store kv.Storage) {
+	if ctx.GetSessionVars().GetStatusFlag(mysql.ServerStatusInTrans) || !bindinfo.GlobalHandle.StartLoading(store) {
+		return
+	}
+	bindings, err := queryBindings(ctx)
+	if err != nil {
+		log.Errorf("[BINDING] failed to load the bindings: %v", errors.ErrorStack(err))
+	}
+	bindinfo.GlobalHandle.FinishLoading(store, bindings, err)
+}
+
+func queryBindings(ctx context.Context) ([]*bindinfo.Binding, error) {
+	sql := fmt.Sprintf("SELECT original_sql, bind_sql, default_db, create_time, update_time FROM %s.%s;",
+		mysql.SystemDB, mysql.BindInfoTable)
+	rs, err := ctx.(sqlexec.RestrictedSQLExecutor).ExecRestrictedSQL(ctx, sql)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	defer rs.Close()
+	var bindings []*bindinfo.Binding
+	for {
+		row, err := rs.Next()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if row == nil {
+			return bindings, nil
+		}
+		binding, err := bindinfo.NewBinding(row.Data[0].GetString(), row.Data[1].GetString(),
+			row.Data[2].GetString(), row.Data[3].GetMysqlTime().Time, row.Data[4].GetMysqlTime().Time)
+		if err != nil {
+			// A broken binding doesn't prevent the others from being used.
+			log.Warnf("[BINDING] skip the binding of %s: %v", row.Data[0].GetString(), err)
+			continue
+		}
+		bindings = append(bindings, binding)
+	}
+}
+
+// baselineInfo identifies the plan of a statement for the plan baselines.
+type baselineInfo struct {
+	store  kv.Storage
+	digest string
+	hints  string
+}
+
+// newBaselineInfo returns the baselineInfo of the statement if it's observed for the plan baselines. The statements
+// with the hints written by the users or their bindings are neither observed nor changed.
+func newBaselineInfo(ctx context.Context, sel *ast.SelectStmt) *baselineInfo {
+	if len(sel.TableHints) > 0 {
+		return nil
+	}
+	store := sessionctx.GetDomain(ctx).Store()
+	if err := planbaseline.GlobalManager.Load(store); err != nil {
+		log.Errorf("[PLAN BASELINE] failed to load the baselines: %v", errors.ErrorStack(err))
+		return nil
+	}
+	return &baselineInfo{store: store, digest: parser.Digest(sel.Text())}
+}
+
+// applyPlanBaseline makes the statement fall back to its baseline plan if the plan chosen by the optimizer is a
+// regressed plan of the statement. The decision is made for every execution, the statement planned by another plan
+// runs by it.
+func applyPlanBaseline(ctx context.Context, sel *ast.SelectStmt, is infoschema.InfoSchema, p plan.Plan,
+	baseline *baselineInfo) (plan.Plan, error) {
+	baseline.hints = plan.GenHints(p)
+	if baseline.hints == "" {
+		return p, nil
+	}
+	fallbackHints, err := planbaseline.GlobalManager.FallbackHints(baseline.digest, baseline.hints)
+	if err != nil {
+		log.Errorf("[PLAN BASELINE] failed to parse the baseline hints of %s: %v", baseline.digest, errors.ErrorStack(err))
+		return p, nil
+	}
+	if fallbackHints == nil {
+		return p, nil
+	}
+	// The hints are only set for planning, the statement is left as it's written.
+	sel.TableHints = fallbackHints
+	defer func() { sel.TableHints = nil }()
+	fallback, err := plan.Optimize(ctx, sel, is)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	baseline.hints = plan.GenHints(fallback)
+	return fallback, nil
+}
+
+// copyHints copies the hints, so the hints set in a statement don't share the memory with the cached ones.
+func copyHints(hints []*ast.TableOptimizerHint) []*ast.TableOptimizerHint {
+	copied := make([]*ast.TableOptimizerHint, 0, len(hints))
+	for _, hint := range hints {
+		h := *hint
+		h.Args = append([]model.CIStr(nil), hint.Args...)
+		copied = append(copied, &h)
+	}
+	return copied
+}
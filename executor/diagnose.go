@@ -0,0 +1,236 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/expression"
+	"github.com/pingcap/tidb/infoschema"
+	"github.com/pingcap/tidb/meta"
+	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/parser"
+	"github.com/pingcap/tidb/plan"
+	"github.com/pingcap/tidb/plan/statistics"
+	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/store/tikv/oracle"
+	"github.com/pingcap/tidb/util/types"
+)
+
+// diagnoseSlowQueryLimit is the max number of the recent slow queries output by ADMIN DIAGNOSE.
+const diagnoseSlowQueryLimit = 10
+
+// diagnoseVars are the system variables which affect the plan or the execution of a statement.
+var diagnoseVars = []string{
+	"sql_mode",
+	"autocommit",
+	variable.TiDBSnapshot,
+	variable.TiDBOptDisableRules,
+	variable.DistSQLScanConcurrencyVar,
+	variable.DistSQLJoinConcurrencyVar,
+	variable.TiDBSkipConstraintCheck,
+	variable.TiDBIndexLookupConcurrency,
+	variable.TiDBIndexLookupSize,
+	variable.TiDBIndexJoinBatchSize,
+	variable.TiDBIndexLookUpJoinConcurrency,
+	variable.TiDBHashJoinConcurrency,
+	variable.TiDBHashAggConcurrency,
+	variable.TiDBHashJoinRuntimeFilter,
+	variable.TiDBApplyCacheCapacity,
+	variable.TiDBMemQuotaSort,
+	variable.TiDBMemQuotaQuery,
+	variable.TiDBMaxChunkSize,
+	variable.TiDBCartesianJoin,
+	variable.TiDBMaxEstimatedRows,
+	variable.TiDBPlanBaseline,
+	variable.TiDBOptScanFactor,
+	variable.TiDBOptNetworkFactor,
+	variable.TiDBOptSeekFactor,
+	variable.TiDBOptCPUFactor,
+}
+
+// DiagnoseExec represents an admin diagnose executor.
+// It bundles the diagnostic information of a statement into a single result set, i.e. the current plan,
+// the statistics freshness of the tables it reads, the relevant system variables and its recent slow queries.
+// The statement is given by its text or by its digest, which is looked up in the slow query log.
+type DiagnoseExec struct {
+	schema expression.Schema
+	ctx    context.Context
+	is     infoschema.InfoSchema
+	query  string
+
+	rows   []*Row
+	cursor int
+	done   bool
+}
+
+// Schema implements the Executor Schema interface.
+func (e *DiagnoseExec) Schema() expression.Schema {
+	return e.schema
+}
+
+// Next implements the Executor Next interface.
+func (e *DiagnoseExec) Next() (*Row, error) {
+	if !e.done {
+		if err := e.fetchAll(); err != nil {
+			return nil, errors.Trace(err)
+		}
+		e.done = true
+	}
+	if e.cursor >= len(e.rows) {
+		return nil, nil
+	}
+	row := e.rows[e.cursor]
+	e.cursor++
+	return row, nil
+}
+
+// Close implements the Executor Close interface.
+func (e *DiagnoseExec) Close() error {
+	return nil
+}
+
+func (e *DiagnoseExec) appendRow(category, name, value string) {
+	e.rows = append(e.rows, &Row{Data: types.MakeDatums(category, name, value)})
+}
+
+func (e *DiagnoseExec) fetchAll() error {
+	// The users without the SUPER or PROCESS privilege only see their own slow queries.
+	user, err := sqlUserFilter(e.ctx)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	sql := e.query
+	if parser.IsDigest(sql) {
+		queries := SlowQueryLog.Find(sql, 1, user)
+		if len(queries) == 0 {
+			return ErrDiagnoseNotFound.Gen("Can't find the statement of the digest %s in the slow query log", sql)
+		}
+		sql = queries[0].SQL
+	}
+	normalized, digest := parser.NormalizeDigest(sql)
+	e.appendRow("statement", "digest", digest)
+	e.appendRow("statement", "normalized", normalized)
+	e.appendRow("statement", "text", sql)
+
+	charset, collation := e.ctx.GetSessionVars().GetCharsetInfo()
+	stmt, err := parser.New().ParseOneStmt(sql, charset, collation)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	// The statement may fail to plan, e.g. its tables have been dropped. The error is output instead of the plan,
+	// so the other information is still available.
+	if err = plan.PrepareStmt(e.is, e.ctx, stmt); err != nil {
+		e.appendRow("plan", "error", err.Error())
+	} else if p, err := plan.Optimize(e.ctx, stmt, e.is); err != nil {
+		e.appendRow("plan", "error", err.Error())
+	} else if err = e.fetchPlan(p); err != nil {
+		return errors.Trace(err)
+	}
+
+	if err = e.fetchStats(stmt); err != nil {
+		return errors.Trace(err)
+	}
+	if err = e.fetchVars(); err != nil {
+		return errors.Trace(err)
+	}
+	for _, q := range SlowQueryLog.Find(digest, diagnoseSlowQueryLimit, user) {
+		value := fmt.Sprintf("duration: %v, connection: %d, db: %s, sql: %s", q.Duration, q.ConnID, q.DB, q.SQL)
+		e.appendRow("slow_query", q.Start.Format(time.RFC3339), value)
+	}
+	return nil
+}
+
+func (e *DiagnoseExec) fetchPlan(p plan.Plan) error {
+	for _, child := range explainChildren(p) {
+		if err := e.fetchPlan(child); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	info, err := json.Marshal(p)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	e.appendRow("plan", p.GetID(), string(info))
+	return nil
+}
+
+// fetchStats outputs whether the tables read by the statement have been analyzed, and when.
+func (e *DiagnoseExec) fetchStats(stmt ast.StmtNode) error {
+	collector := &tableNameCollector{}
+	stmt.Accept(collector)
+	txn, err := e.ctx.GetTxn(false)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	m := meta.NewMeta(txn)
+	visited := make(map[int64]bool)
+	for _, tn := range collector.tables {
+		if visited[tn.TableInfo.ID] {
+			continue
+		}
+		visited[tn.TableInfo.ID] = true
+		state, err := tableStatsState(m, tn.TableInfo)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if override := e.ctx.GetSessionVars().StatsOverrides[tn.TableInfo.ID]; override != nil {
+			state = fmt.Sprintf("%s, overridden by ADMIN SET STATS in the session", state)
+		}
+		e.appendRow("stats", fmt.Sprintf("%s.%s", tn.Schema.O, tn.Name.O), state)
+	}
+	return nil
+}
+
+func tableStatsState(m *meta.Meta, tblInfo *model.TableInfo) (string, error) {
+	tpb, err := m.GetTableStats(tblInfo.ID)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	if tpb == nil {
+		return "never analyzed, pseudo statistics are used", nil
+	}
+	stats, err := statistics.TableFromPB(tblInfo, tpb)
+	if err != nil {
+		return "outdated, the table has been altered after analyzing", nil
+	}
+	analyzed := time.Unix(0, oracle.ExtractPhysical(uint64(stats.TS))*int64(time.Millisecond))
+	return fmt.Sprintf("analyzed at %s, %d rows", analyzed.Format(time.RFC3339), stats.Count), nil
+}
+
+func (e *DiagnoseExec) fetchVars() error {
+	sessionVars := e.ctx.GetSessionVars()
+	for _, name := range diagnoseVars {
+		sv := sessionVars.GetSystemVar(name)
+		if !sv.IsNull() {
+			e.appendRow("variable", name, sv.GetString())
+			continue
+		}
+		if v := variable.GetSysVar(name); v != nil && v.Scope&variable.ScopeGlobal != 0 {
+			value, err := sessionVars.GlobalVarsAccessor.GetGlobalSysVar(name)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			e.appendRow("variable", name, value)
+			continue
+		}
+		e.appendRow("variable", name, variable.SysVars[name].Value)
+	}
+	return nil
+}
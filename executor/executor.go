@@ -15,45 +15,75 @@ package executor
 
 import (
 	"container/heap"
+	"encoding/json"
+	"fmt"
 	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/juju/errors"
+	"github.com/ngaut/log"
 	"github.com/pingcap/tidb/ast"
 	"github.com/pingcap/tidb/context"
 	"github.com/pingcap/tidb/expression"
 	"github.com/pingcap/tidb/infoschema"
 	"github.com/pingcap/tidb/inspectkv"
 	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/meta"
 	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/plan"
 	"github.com/pingcap/tidb/sessionctx"
 	"github.com/pingcap/tidb/sessionctx/forupdate"
+	"github.com/pingcap/tidb/store/tikv/oracle"
 	"github.com/pingcap/tidb/table"
 	"github.com/pingcap/tidb/tablecodec"
 	"github.com/pingcap/tidb/terror"
+	"github.com/pingcap/tidb/util/chunk"
 	"github.com/pingcap/tidb/util/codec"
 	"github.com/pingcap/tidb/util/distinct"
+	"github.com/pingcap/tidb/util/jsonpath"
+	"github.com/pingcap/tidb/util/memory"
+	"github.com/pingcap/tidb/util/profile"
 	"github.com/pingcap/tidb/util/types"
 )
 
 var (
 	_ Executor = &ApplyExec{}
 	_ Executor = &CheckTableExec{}
+	_ Executor = &RecoverIndexExec{}
+	_ Executor = &CleanupIndexExec{}
+	_ Executor = &CTEExec{}
+	_ Executor = &DiagnoseExec{}
+	_ Executor = &ThrottleTableExec{}
+	_ Executor = &SetStatsExec{}
+	_ Executor = &ResetStatsExec{}
 	_ Executor = &DistinctExec{}
 	_ Executor = &DummyScanExec{}
 	_ Executor = &ExistsExec{}
+	_ Executor = &GenerateSeriesExec{}
 	_ Executor = &HashAggExec{}
 	_ Executor = &HashJoinExec{}
 	_ Executor = &HashSemiJoinExec{}
+	_ Executor = &IndexLookUpJoinExec{}
+	_ Executor = &JSONTableExec{}
+	_ Executor = &ProfileExec{}
+	_ Executor = &RowHistoryExec{}
 	_ Executor = &LimitExec{}
 	_ Executor = &MaxOneRowExec{}
+	_ Executor = &MergeJoinExec{}
 	_ Executor = &ProjectionExec{}
 	_ Executor = &ReverseExec{}
 	_ Executor = &SelectionExec{}
 	_ Executor = &SelectLockExec{}
 	_ Executor = &ShowDDLExec{}
+	_ Executor = &ShowDDLJobsExec{}
+	_ Executor = &ShowDeleteRangesExec{}
+	_ Executor = &ShowSlowPlansExec{}
+	_ Executor = &SelectIntoExec{}
+	_ Executor = &PointGetExec{}
+	_ Executor = &BatchPointGetExec{}
 	_ Executor = &SortExec{}
 	_ Executor = &StreamAggExec{}
 	_ Executor = &TableDualExec{}
@@ -65,24 +95,62 @@ var (
 
 // Error instances.
 var (
-	ErrUnknownPlan     = terror.ClassExecutor.New(CodeUnknownPlan, "Unknown plan")
-	ErrPrepareMulti    = terror.ClassExecutor.New(CodePrepareMulti, "Can not prepare multiple statements")
-	ErrStmtNotFound    = terror.ClassExecutor.New(CodeStmtNotFound, "Prepared statement not found")
-	ErrSchemaChanged   = terror.ClassExecutor.New(CodeSchemaChanged, "Schema has changed")
-	ErrWrongParamCount = terror.ClassExecutor.New(CodeWrongParamCount, "Wrong parameter count")
-	ErrRowKeyCount     = terror.ClassExecutor.New(CodeRowKeyCount, "Wrong row key entry count")
-	ErrPrepareDDL      = terror.ClassExecutor.New(CodePrepareDDL, "Can not prepare DDL statements")
+	ErrUnknownPlan          = terror.ClassExecutor.New(CodeUnknownPlan, "Unknown plan")
+	ErrPrepareMulti         = terror.ClassExecutor.New(CodePrepareMulti, "Can not prepare multiple statements")
+	ErrStmtNotFound         = terror.ClassExecutor.New(CodeStmtNotFound, "Prepared statement not found")
+	ErrSchemaChanged        = terror.ClassExecutor.New(CodeSchemaChanged, "Schema has changed")
+	ErrWrongParamCount      = terror.ClassExecutor.New(CodeWrongParamCount, "Wrong parameter count")
+	ErrRowKeyCount          = terror.ClassExecutor.New(CodeRowKeyCount, "Wrong row key entry count")
+	ErrPrepareDDL           = terror.ClassExecutor.New(CodePrepareDDL, "Can not prepare DDL statements")
+	ErrInvalidJSONText      = terror.ClassExecutor.New(CodeInvalidJSONText, "Invalid JSON text")
+	ErrAnalyzeInterrupted   = terror.ClassExecutor.New(CodeAnalyzeInterrupted, "Analyze is interrupted")
+	ErrMemoryExceeded       = terror.ClassExecutor.New(CodeMemoryExceeded, "Query execution was interrupted, the server is out of memory")
+	ErrUnsupportedPs        = terror.ClassExecutor.New(CodeUnsupportedPs, "This command is not supported in the prepared statement protocol yet")
+	ErrViewWrongList        = terror.ClassExecutor.New(CodeViewWrongList, "View's SELECT and view's field list have different column counts")
+	ErrDiagnoseNotFound     = terror.ClassExecutor.New(CodeDiagnoseNotFound, "Can't find the statement of the digest in the slow query log")
+	ErrInvalidBinding       = terror.ClassExecutor.New(CodeInvalidBinding, "Invalid binding")
+	ErrQueueTimeout         = terror.ClassExecutor.New(CodeQueueTimeout, "Query execution was interrupted, the statement waited too long in the queue of the running statements")
+	ErrMemQuotaExceeded     = terror.ClassExecutor.New(CodeMemQuotaExceeded, "Query execution was interrupted, the statement consumes more memory than tidb_mem_quota_query")
+	ErrFileExists           = terror.ClassExecutor.New(CodeFileExists, "File already exists")
+	ErrAdminCheckTable      = terror.ClassExecutor.New(CodeAdminCheckTable, "Table data and indices are inconsistent")
+	ErrNoSuchThread         = terror.ClassExecutor.New(CodeNoSuchThread, "Unknown thread id")
+	ErrStatementTooLarge    = terror.ClassExecutor.New(CodeStatementTooLarge, "The statement is larger than tidb_max_statement_size")
+	ErrTooManyParams        = terror.ClassExecutor.New(CodeTooManyParams, "Prepared statement contains too many placeholders")
+	ErrKillDenied           = terror.ClassExecutor.New(CodeKillDenied, "You are not owner of thread")
+	ErrWriteThrottled       = terror.ClassExecutor.New(CodeWriteThrottled, "Writes to the table are throttled")
+	ErrSpecificAccessDenied = terror.ClassExecutor.New(CodeSpecificAccessDenied, "Access denied; you need (at least one of) the privilege(s) for this operation")
+	ErrBatchNotAtomic       = terror.ClassExecutor.New(CodeBatchNotAtomic, "The statement is committed in batches, it isn't atomic")
+	ErrMissingJSONValue     = terror.ClassExecutor.New(CodeMissingJSONValue, "Missing value for JSON_TABLE column")
 )
 
 // Error codes.
 const (
-	CodeUnknownPlan     terror.ErrCode = 1
-	CodePrepareMulti    terror.ErrCode = 2
-	CodeStmtNotFound    terror.ErrCode = 3
-	CodeSchemaChanged   terror.ErrCode = 4
-	CodeWrongParamCount terror.ErrCode = 5
-	CodeRowKeyCount     terror.ErrCode = 6
-	CodePrepareDDL      terror.ErrCode = 7
+	CodeUnknownPlan          terror.ErrCode = 1
+	CodePrepareMulti         terror.ErrCode = 2
+	CodeStmtNotFound         terror.ErrCode = 3
+	CodeSchemaChanged        terror.ErrCode = 4
+	CodeWrongParamCount      terror.ErrCode = 5
+	CodeRowKeyCount          terror.ErrCode = 6
+	CodePrepareDDL           terror.ErrCode = 7
+	CodeInvalidJSONText      terror.ErrCode = 8
+	CodeAnalyzeInterrupted   terror.ErrCode = 9
+	CodeMemoryExceeded       terror.ErrCode = 10
+	CodeUnsupportedPs        terror.ErrCode = 11
+	CodeViewWrongList        terror.ErrCode = 12
+	CodeDiagnoseNotFound     terror.ErrCode = 13
+	CodeInvalidBinding       terror.ErrCode = 14
+	CodeQueueTimeout         terror.ErrCode = 15
+	CodeMemQuotaExceeded     terror.ErrCode = 16
+	CodeFileExists           terror.ErrCode = 17
+	CodeAdminCheckTable      terror.ErrCode = 18
+	CodeNoSuchThread         terror.ErrCode = 19
+	CodeStatementTooLarge    terror.ErrCode = 20
+	CodeTooManyParams        terror.ErrCode = 21
+	CodeKillDenied           terror.ErrCode = 22
+	CodeWriteThrottled       terror.ErrCode = 23
+	CodeSpecificAccessDenied terror.ErrCode = 24
+	CodeBatchNotAtomic       terror.ErrCode = 25
+	CodeMissingJSONValue     terror.ErrCode = 26
 	// MySQL error code
 	CodeCannotUser terror.ErrCode = 1396
 )
@@ -179,15 +247,86 @@ func (e *ShowDDLExec) Close() error {
 	return nil
 }
 
+// ShowDDLJobsExec represents a show DDL jobs executor.
+// It shows the jobs in the DDL job queue, and the progress of the reorganization jobs,
+// i.e. the processed rows, the handle range to process, the speed in rows/s and the estimated remaining time.
+type ShowDDLJobsExec struct {
+	schema expression.Schema
+	ctx    context.Context
+
+	jobs   []*inspectkv.DDLJobInfo
+	cursor int
+	done   bool
+}
+
+// Schema implements the Executor Schema interface.
+func (e *ShowDDLJobsExec) Schema() expression.Schema {
+	return e.schema
+}
+
+// Next implements the Executor Next interface.
+func (e *ShowDDLJobsExec) Next() (*Row, error) {
+	if !e.done {
+		txn, err := e.ctx.GetTxn(false)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		e.jobs, err = inspectkv.GetDDLJobs(txn)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		e.done = true
+	}
+	if e.cursor >= len(e.jobs) {
+		return nil, nil
+	}
+	info := e.jobs[e.cursor]
+	e.cursor++
+
+	job := info.Job
+	row := &Row{}
+	row.Data = types.MakeDatums(
+		job.ID,
+		job.Type.String(),
+		job.SchemaState.String(),
+		job.SchemaID,
+		job.TableID,
+		job.GetRowCount(),
+		nil,
+		nil,
+		nil,
+		job.State.String(),
+	)
+	if p := info.Progress; p != nil {
+		row.Data[5].SetInt64(p.RowCount)
+		row.Data[6].SetString(fmt.Sprintf("[%d,%d]", p.Handle, p.EndHandle))
+		row.Data[7].SetFloat64(p.Speed())
+		if eta, ok := p.ETA(); ok {
+			row.Data[8].SetString((eta / time.Second * time.Second).String())
+		}
+	}
+	return row, nil
+}
+
+// Close implements the Executor Close interface.
+func (e *ShowDDLJobsExec) Close() error {
+	return nil
+}
+
 // CheckTableExec represents a check table executor.
 // It is built from the "admin check table" statement, and it checks if the
-// index matches the records in the table.
+// index matches the records in the table. The indices of a table are checked
+// in parallel in the snapshot of the transaction, and all the inconsistent
+// handles of the indices are reported, at most checkTableReportLimit ones for
+// an index.
 type CheckTableExec struct {
 	tables []*ast.TableName
 	ctx    context.Context
 	done   bool
 }
 
+const checkTableReportLimit = 10
+
 // Schema implements the Executor Schema interface.
 func (e *CheckTableExec) Schema() expression.Schema {
 	return nil
@@ -199,30 +338,70 @@ func (e *CheckTableExec) Next() (*Row, error) {
 		return nil, nil
 	}
 
-	dbName := model.NewCIStr(e.ctx.GetSessionVars().CurrentDB)
-	is := sessionctx.GetDomain(e.ctx).InfoSchema()
+	dbName := model.NewTableCIStr(e.ctx.GetSessionVars().CurrentDB)
+	dom := sessionctx.GetDomain(e.ctx)
+	is := dom.InfoSchema()
+	txn, err := e.ctx.GetTxn(false)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	snapshot, err := dom.Store().GetSnapshot(kv.Version{Ver: txn.StartTS()})
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
 
+	var reports []string
 	for _, t := range e.tables {
 		tb, err := is.TableByName(dbName, t.Name)
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
-		for _, idx := range tb.Indices() {
-			txn, err := e.ctx.GetTxn(false)
-			if err != nil {
-				return nil, errors.Trace(err)
+		results, err := checkTableIndices(snapshot, tb)
+		if err != nil {
+			return nil, errors.Errorf("%v err:%v", t.Name, err)
+		}
+		for i, result := range results {
+			if len(result) == 0 {
+				continue
 			}
-			err = inspectkv.CompareIndexData(txn, tb, idx)
-			if err != nil {
-				return nil, errors.Errorf("%v err:%v", t.Name, err)
+			items := make([]string, 0, len(result))
+			for _, inconsistency := range result {
+				items = append(items, inconsistency.String())
 			}
+			reports = append(reports, fmt.Sprintf("table %s index %s: %s", t.Name, tb.Indices()[i].Meta().Name,
+				strings.Join(items, "; ")))
 		}
 	}
 	e.done = true
+	if len(reports) > 0 {
+		return nil, ErrAdminCheckTable.Gen("%s", strings.Join(reports, "; "))
+	}
 
 	return nil, nil
 }
 
+// checkTableIndices checks the indices of the table in parallel, and returns the inconsistencies of each index.
+func checkTableIndices(snapshot kv.Snapshot, tb table.Table) ([][]*inspectkv.Inconsistency, error) {
+	indices := tb.Indices()
+	results := make([][]*inspectkv.Inconsistency, len(indices))
+	errs := make([]error, len(indices))
+	var wg sync.WaitGroup
+	for i, idx := range indices {
+		wg.Add(1)
+		go func(i int, idx table.Index) {
+			defer wg.Done()
+			results[i], errs[i] = inspectkv.CheckIndex(snapshot, tb, idx, checkTableReportLimit)
+		}(i, idx)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	return results, nil
+}
+
 // Close implements plan.Plan Close interface.
 func (e *CheckTableExec) Close() error {
 	return nil
@@ -234,11 +413,16 @@ func (e *CheckTableExec) Close() error {
 // After the execution, the keys are buffered in transaction, and will be sent to KV
 // when doing commit. If there is any key already locked by another transaction,
 // the transaction will rollback and retry.
+// For "SELECT .. FOR UPDATE OF t1, t2" statement, only the row keys from the listed tables are locked.
 type SelectLockExec struct {
 	Src    Executor
 	Lock   ast.SelectLockType
+	Tables []*ast.TableName
 	ctx    context.Context
 	schema expression.Schema
+
+	// tblMap maps the table ID to the table alias names of Tables.
+	tblMap map[int64][]string
 }
 
 // Schema implements the Executor Schema interface.
@@ -256,12 +440,21 @@ func (e *SelectLockExec) Next() (*Row, error) {
 		return nil, nil
 	}
 	if len(row.RowKeys) != 0 && e.Lock == ast.SelectLockForUpdate {
+		if len(e.Tables) > 0 && e.tblMap == nil {
+			e.tblMap = make(map[int64][]string, len(e.Tables))
+			for _, t := range e.Tables {
+				e.tblMap[t.TableInfo.ID] = append(e.tblMap[t.TableInfo.ID], t.Name.L)
+			}
+		}
 		forupdate.SetForUpdate(e.ctx)
 		txn, err := e.ctx.GetTxn(false)
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
 		for _, k := range row.RowKeys {
+			if e.tblMap != nil && !isMatchTableName(k, e.tblMap) {
+				continue
+			}
 			lockKey := tablecodec.EncodeRowKeyWithHandle(k.Tbl.Meta().ID, k.Handle)
 			err = txn.LockKeys(lockKey)
 			if err != nil {
@@ -285,6 +478,12 @@ type LimitExec struct {
 	Count  uint64
 	Idx    uint64
 	schema expression.Schema
+
+	// childChunk is the source chunk whose rows from childIdx are not read yet, childDone is true if the source
+	// has no more row.
+	childChunk *chunk.Chunk
+	childIdx   int
+	childDone  bool
 }
 
 // Schema implements the Executor Schema interface.
@@ -318,9 +517,47 @@ func (e *LimitExec) Next() (*Row, error) {
 	return srcRow, nil
 }
 
+func (e *LimitExec) supportChunk() bool {
+	return true
+}
+
+// NextChunk implements the chunkExecutor NextChunk interface.
+func (e *LimitExec) NextChunk(chk *chunk.Chunk) error {
+	if e.childChunk == nil {
+		// The source doesn't read more rows than the limit needs.
+		capacity := chk.Capacity()
+		if e.Offset+e.Count < uint64(capacity) {
+			capacity = int(e.Offset + e.Count)
+		}
+		e.childChunk = newChunk(e.Src, capacity)
+	}
+	for !chk.IsFull() && e.Idx < e.Offset+e.Count {
+		if e.childIdx >= e.childChunk.NumRows() {
+			if e.childDone {
+				return nil
+			}
+			if err := nextChunk(e.Src, e.childChunk); err != nil {
+				return errors.Trace(err)
+			}
+			e.childIdx = 0
+			e.childDone = !e.childChunk.IsFull()
+		}
+		for ; e.childIdx < e.childChunk.NumRows() && !chk.IsFull() && e.Idx < e.Offset+e.Count; e.childIdx++ {
+			if e.Idx >= e.Offset {
+				chk.AppendRow(e.childChunk.GetRow(e.childIdx))
+			}
+			e.Idx++
+		}
+	}
+	return nil
+}
+
 // Close implements the Executor Close interface.
 func (e *LimitExec) Close() error {
 	e.Idx = 0
+	e.childChunk = nil
+	e.childIdx = 0
+	e.childDone = false
 	return e.Src.Close()
 }
 
@@ -334,10 +571,15 @@ type orderByRow struct {
 // It ignores duplicate rows from source Executor by using a *distinct.Checker which maintains
 // a map to check duplication.
 // Because every distinct row will be added to the map, the memory usage might be very high.
+// If the source is sorted by all the columns, it only compares with the previous row instead.
 type DistinctExec struct {
 	Src     Executor
 	checker *distinct.Checker
 	schema  expression.Schema
+
+	// sorted means the input is sorted by all the columns, so a row is duplicated if it equals the previous one.
+	sorted  bool
+	lastRow *Row
 }
 
 // Schema implements the Executor Schema interface.
@@ -347,7 +589,7 @@ func (e *DistinctExec) Schema() expression.Schema {
 
 // Next implements the Executor Next interface.
 func (e *DistinctExec) Next() (*Row, error) {
-	if e.checker == nil {
+	if e.checker == nil && !e.sorted {
 		e.checker = distinct.CreateDistinctChecker()
 	}
 	for {
@@ -358,7 +600,7 @@ func (e *DistinctExec) Next() (*Row, error) {
 		if row == nil {
 			return nil, nil
 		}
-		ok, err := e.checker.Check(types.DatumsToInterfaces(row.Data))
+		ok, err := e.check(row)
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
@@ -369,8 +611,36 @@ func (e *DistinctExec) Next() (*Row, error) {
 	}
 }
 
+// check returns true if the row isn't a duplicated one.
+func (e *DistinctExec) check(row *Row) (bool, error) {
+	if !e.sorted {
+		ok, err := e.checker.Check(types.DatumsToInterfaces(row.Data))
+		return ok, errors.Trace(err)
+	}
+	if e.lastRow != nil {
+		duplicated := true
+		for i, d := range row.Data {
+			c, err := d.CompareDatum(e.lastRow.Data[i])
+			if err != nil {
+				return false, errors.Trace(err)
+			}
+			if c != 0 {
+				duplicated = false
+				break
+			}
+		}
+		if duplicated {
+			return false, nil
+		}
+	}
+	e.lastRow = row
+	return true, nil
+}
+
 // Close implements the Executor Close interface.
 func (e *DistinctExec) Close() error {
+	e.checker = nil
+	e.lastRow = nil
 	return e.Src.Close()
 }
 
@@ -428,12 +698,26 @@ func init() {
 			return d, errors.Trace(err)
 		}
 		if row == nil {
-			return
+			// The subquery proved to return at most one row has no MaxOneRow to fill the NULLs.
+			return make([]types.Datum, len(p.GetSchema())), nil
 		}
 		return row.Data, nil
 	}
 	tableMySQLErrCodes := map[terror.ErrCode]uint16{
-		CodeCannotUser: mysql.ErrCannotUser,
+		CodeCannotUser:           mysql.ErrCannotUser,
+		CodeMemoryExceeded:       mysql.ErrQueryInterrupted,
+		CodeQueueTimeout:         mysql.ErrQueryInterrupted,
+		CodeMemQuotaExceeded:     mysql.ErrQueryInterrupted,
+		CodeUnsupportedPs:        mysql.ErrUnsupportedPs,
+		CodeViewWrongList:        mysql.ErrViewWrongList,
+		CodeFileExists:           mysql.ErrFileExists,
+		CodeNoSuchThread:         mysql.ErrNoSuchThread,
+		CodeStatementTooLarge:    mysql.ErrNetPacketTooLarge,
+		CodeTooManyParams:        mysql.ErrPsManyParam,
+		CodeKillDenied:           mysql.ErrKillDenied,
+		CodeSpecificAccessDenied: mysql.ErrSpecificAccessDenied,
+		CodeBatchNotAtomic:       mysql.ErrUnknown,
+		CodeMissingJSONValue:     mysql.ErrMissingJSONTableValue,
 	}
 	terror.ErrClassToMySQLCodes[terror.ClassExecutor] = tableMySQLErrCodes
 }
@@ -461,6 +745,12 @@ type HashJoinExec struct {
 	finished bool
 	// for sync multiple join workers.
 	wg sync.WaitGroup
+	// closeCh is closed by Close to stop the goroutines fetching and joining the big table rows, which may be
+	// blocked if the result rows aren't all read, e.g. by a limit. fetchWg waits for the goroutine fetching the
+	// big table rows.
+	closeCh  chan struct{}
+	fetchWg  sync.WaitGroup
+	fetching bool
 
 	// Concurrent channels.
 	concurrency      int
@@ -471,6 +761,21 @@ type HashJoinExec struct {
 	// Channels for output.
 	resultErr  chan error
 	resultRows chan *Row
+
+	// keepOrder means the result rows are output in the order of the big table rows. Each join worker sends
+	// the result rows of a batch of big table rows to its own channel, and Next reads the channels in turn, the
+	// same order in which the batches are dispatched to the workers.
+	keepOrder      bool
+	batchResults   []chan []*Row
+	batchResult    []*Row
+	batchResultIdx int
+
+	// mem tracks the memory of the hash table.
+	mem memoryUsage
+
+	// runtimeFilter is not nil if the big table is filtered by the join keys of the small table, the rows of the
+	// big table are fetched after the hash table is built then.
+	runtimeFilter *runtimeFilter
 }
 
 // hashJoinCtx holds the variables needed to do a hash join in one of many concurrent goroutines.
@@ -484,11 +789,51 @@ type hashJoinCtx struct {
 
 // Close implements the Executor Close interface.
 func (e *HashJoinExec) Close() error {
+	if e.closeCh != nil {
+		close(e.closeCh)
+		e.wg.Wait()
+		e.fetchWg.Wait()
+		e.closeCh = nil
+	}
+	if !e.fetching {
+		// The big table is closed by the goroutine fetching its rows if it's started.
+		if err := e.bigExec.Close(); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	e.fetching = false
 	e.prepared = false
 	e.cursor = 0
+	e.mem.close()
 	return e.smallExec.Close()
 }
 
+// sendRows sends the rows to the channel, it returns false if the join is closed.
+func (e *HashJoinExec) sendRows(ch chan<- []*Row, rows []*Row) bool {
+	select {
+	case ch <- rows:
+		return true
+	case <-e.closeCh:
+		return false
+	}
+}
+
+// sendErr sends the error of a goroutine of the join to the channel, the error is dropped if there is already an
+// error or the join is closed.
+func sendErr(ch chan<- error, err error) {
+	select {
+	case ch <- err:
+	default:
+	}
+}
+
+// startFetchBigExec starts the goroutine fetching the big table rows.
+func (e *HashJoinExec) startFetchBigExec() {
+	e.fetching = true
+	e.fetchWg.Add(1)
+	go e.fetchBigExec()
+}
+
 // makeJoinRow simply creates a new row that appends row b to row a.
 func makeJoinRow(a *Row, b *Row) *Row {
 	ret := &Row{
@@ -544,6 +889,7 @@ func (e *HashJoinExec) fetchBigExec() {
 			close(cn)
 		}
 		e.bigExec.Close()
+		e.fetchWg.Done()
 	}()
 	curBatchSize := 1
 	for {
@@ -555,7 +901,7 @@ func (e *HashJoinExec) fetchBigExec() {
 		for i := 0; i < curBatchSize; i++ {
 			row, err := e.bigExec.Next()
 			if err != nil {
-				e.bigTableErr <- errors.Trace(err)
+				sendErr(e.bigTableErr, errors.Trace(err))
 				done = true
 				break
 			}
@@ -566,7 +912,9 @@ func (e *HashJoinExec) fetchBigExec() {
 			rows = append(rows, row)
 		}
 		idx := cnt % e.concurrency
-		e.bigTableRows[idx] <- rows
+		if !e.sendRows(e.bigTableRows[idx], rows) {
+			break
+		}
 		cnt++
 		if done {
 			break
@@ -579,6 +927,7 @@ func (e *HashJoinExec) fetchBigExec() {
 
 // prepare runs the first time when 'Next' is called, it starts one worker goroutine to fetch rows from the big table,
 // and reads all data from the small table to build a hash table, then starts multiple join worker goroutines.
+// If the big table is filtered by a runtime filter, the worker is started after the hash table is built.
 func (e *HashJoinExec) prepare() error {
 	e.finished = false
 	e.bigTableRows = make([]chan []*Row, e.concurrency)
@@ -586,9 +935,14 @@ func (e *HashJoinExec) prepare() error {
 		e.bigTableRows[i] = make(chan []*Row, e.concurrency*batchSize)
 	}
 	e.bigTableErr = make(chan error, 1)
+	e.closeCh = make(chan struct{})
 
-	// Start a worker to fetch big table rows.
-	go e.fetchBigExec()
+	if e.runtimeFilter == nil {
+		// Start a worker to fetch big table rows.
+		e.startFetchBigExec()
+	} else {
+		e.runtimeFilter.reset()
+	}
 
 	e.hashTable = make(map[string][]*Row)
 	e.cursor = 0
@@ -619,15 +973,37 @@ func (e *HashJoinExec) prepare() error {
 		if hasNull {
 			continue
 		}
+		if e.runtimeFilter != nil {
+			if err = e.runtimeFilter.collect(e.hashJoinContexts[0].datumBuffer); err != nil {
+				return errors.Trace(err)
+			}
+		}
+		if err = e.mem.consume(rowMemUsage(row) + int64(len(hashcode))); err != nil {
+			return errors.Trace(err)
+		}
 		if rows, ok := e.hashTable[string(hashcode)]; !ok {
 			e.hashTable[string(hashcode)] = []*Row{row}
 		} else {
 			e.hashTable[string(hashcode)] = append(rows, row)
 		}
 	}
+	if e.runtimeFilter != nil {
+		if err := e.runtimeFilter.pushDown(); err != nil {
+			return errors.Trace(err)
+		}
+		e.startFetchBigExec()
+	}
 
 	e.resultRows = make(chan *Row, e.concurrency*1000)
 	e.resultErr = make(chan error, 1)
+	if e.keepOrder {
+		e.batchResults = make([]chan []*Row, e.concurrency)
+		for i := 0; i < e.concurrency; i++ {
+			e.batchResults[i] = make(chan []*Row, e.concurrency)
+		}
+		e.batchResult = nil
+		e.batchResultIdx = 0
+	}
 
 	e.wg = sync.WaitGroup{}
 	for i := 0; i < e.concurrency; i++ {
@@ -646,8 +1022,14 @@ func (e *HashJoinExec) waitJoinWorkersAndCloseResultChan() {
 	e.hashTable = nil
 }
 
-// doJoin does join job in one goroutine.
+// runJoinWorker does join job in one goroutine.
 func (e *HashJoinExec) runJoinWorker(idx int) {
+	defer func() {
+		if e.keepOrder {
+			close(e.batchResults[idx])
+		}
+		e.wg.Done()
+	}()
 	for {
 		var (
 			bigRows []*Row
@@ -657,28 +1039,44 @@ func (e *HashJoinExec) runJoinWorker(idx int) {
 		select {
 		case bigRows, ok = <-e.bigTableRows[idx]:
 		case err = <-e.bigTableErr:
+		case <-e.closeCh:
+			return
 		}
 		if err != nil {
-			e.resultErr <- errors.Trace(err)
+			sendErr(e.resultErr, errors.Trace(err))
 			break
 		}
 		if !ok || e.finished {
 			break
 		}
+		var results []*Row
 		for _, bigRow := range bigRows {
-			succ := e.joinOneBigRow(e.hashJoinContexts[idx], bigRow)
+			rows, succ := e.joinOneBigRow(e.hashJoinContexts[idx], bigRow)
 			if !succ {
-				break
+				return
+			}
+			if e.keepOrder {
+				results = append(results, rows...)
+				continue
+			}
+			for _, r := range rows {
+				select {
+				case e.resultRows <- r:
+				case <-e.closeCh:
+					return
+				}
 			}
 		}
+		if e.keepOrder && !e.sendRows(e.batchResults[idx], results) {
+			return
+		}
 	}
-	e.wg.Done()
 }
 
-// joinOneBigRow creates result rows from a row in a big table and sends them to resultRows channel.
+// joinOneBigRow creates result rows from a row in a big table.
 // Every matching row generates a result row.
 // If there are no matching rows and it is outer join, a null filled result row is created.
-func (e *HashJoinExec) joinOneBigRow(ctx *hashJoinCtx, bigRow *Row) bool {
+func (e *HashJoinExec) joinOneBigRow(ctx *hashJoinCtx, bigRow *Row) ([]*Row, bool) {
 	var (
 		matchedRows []*Row
 		err         error
@@ -687,25 +1085,21 @@ func (e *HashJoinExec) joinOneBigRow(ctx *hashJoinCtx, bigRow *Row) bool {
 	if e.bigFilter != nil {
 		bigMatched, err = expression.EvalBool(ctx.bigFilter, bigRow.Data, e.ctx)
 		if err != nil {
-			e.resultErr <- errors.Trace(err)
-			return false
+			sendErr(e.resultErr, errors.Trace(err))
+			return nil, false
 		}
 	}
 	if bigMatched {
 		matchedRows, err = e.constructMatchedRows(ctx, bigRow)
 		if err != nil {
-			e.resultErr <- errors.Trace(err)
-			return false
+			sendErr(e.resultErr, errors.Trace(err))
+			return nil, false
 		}
 	}
-	for _, r := range matchedRows {
-		e.resultRows <- r
-	}
 	if len(matchedRows) == 0 && e.outer {
-		r := e.fillRowWithDefaultValues(bigRow)
-		e.resultRows <- r
+		matchedRows = append(matchedRows, e.fillRowWithDefaultValues(bigRow))
 	}
-	return true
+	return matchedRows, true
 }
 
 // constructMatchedRows creates matching result rows from a row in the big table.
@@ -767,6 +1161,9 @@ func (e *HashJoinExec) Next() (*Row, error) {
 			return nil, errors.Trace(err)
 		}
 	}
+	if e.keepOrder {
+		return e.nextInOrder()
+	}
 	var (
 		row *Row
 		err error
@@ -786,116 +1183,798 @@ func (e *HashJoinExec) Next() (*Row, error) {
 	return row, nil
 }
 
-// HashSemiJoinExec implements the hash join algorithm for semi join.
-type HashSemiJoinExec struct {
-	hashTable    map[string][]*Row
-	smallHashKey []*expression.Column
-	bigHashKey   []*expression.Column
-	smallExec    Executor
-	bigExec      Executor
-	prepared     bool
-	ctx          context.Context
-	smallFilter  expression.Expression
-	bigFilter    expression.Expression
-	otherFilter  expression.Expression
-	schema       expression.Schema
-	// In auxMode, the result row always returns with an extra column which stores a boolean
-	// or NULL value to indicate if this row is matched.
-	auxMode           bool
-	targetTypes       []*types.FieldType
-	smallTableHasNull bool
-	// If anti is true, semi join only output the unmatched row.
-	anti bool
+// nextInOrder returns the next result row in the order of the big table. The batches of the big table rows are
+// dispatched to the workers in turn, so the results of the batches are read from the workers in the same turn.
+func (e *HashJoinExec) nextInOrder() (*Row, error) {
+	for e.cursor >= len(e.batchResult) {
+		var (
+			rows []*Row
+			err  error
+			ok   bool
+		)
+		select {
+		case rows, ok = <-e.batchResults[e.batchResultIdx]:
+		case err = <-e.resultErr:
+		}
+		if err != nil {
+			e.finished = true
+			return nil, errors.Trace(err)
+		}
+		if !ok {
+			// The worker may stop because of an error.
+			select {
+			case err = <-e.resultErr:
+				e.finished = true
+				return nil, errors.Trace(err)
+			default:
+			}
+			return nil, nil
+		}
+		e.batchResult = rows
+		e.cursor = 0
+		e.batchResultIdx = (e.batchResultIdx + 1) % e.concurrency
+	}
+	row := e.batchResult[e.cursor]
+	e.cursor++
+	return row, nil
+}
+
+// MergeJoinExec implements the sort merge join algorithm for inner/ outer join.
+// Both children return the rows ordered by the join keys, so only the inner rows with the same keys are kept
+// in memory, instead of building a hash table on all the rows of a child.
+type MergeJoinExec struct {
+	ctx           context.Context
+	schema        expression.Schema
+	outerExec     Executor
+	innerExec     Executor
+	outerKeys     []*expression.Column
+	innerKeys     []*expression.Column
+	outerFilter   expression.Expression
+	innerFilter   expression.Expression
+	otherFilter   expression.Expression
+	outer         bool
+	leftOuter     bool
+	defaultValues []types.Datum
+
+	// innerGroup holds the inner rows whose keys are innerGroupKeys, the keys are nil if there are no more inner rows.
+	innerGroup     []*Row
+	innerGroupKeys []types.Datum
+	// innerNext is the inner row read after the group, innerNextKeys are its keys.
+	innerNext     *Row
+	innerNextKeys []types.Datum
+	innerDone     bool
+
+	resultRows []*Row
+	cursor     int
+
+	// mem tracks the memory of the inner group.
+	mem memoryUsage
+}
+
+// Schema implements the Executor Schema interface.
+func (e *MergeJoinExec) Schema() expression.Schema {
+	return e.schema
 }
 
 // Close implements the Executor Close interface.
-func (e *HashSemiJoinExec) Close() error {
-	e.prepared = false
-	e.hashTable = make(map[string][]*Row)
-	e.smallTableHasNull = false
-	err := e.smallExec.Close()
+func (e *MergeJoinExec) Close() error {
+	e.innerGroup, e.innerGroupKeys = nil, nil
+	e.innerNext, e.innerNextKeys = nil, nil
+	e.innerDone = false
+	e.resultRows = nil
+	e.cursor = 0
+	e.mem.close()
+	err := e.outerExec.Close()
 	if err != nil {
 		return errors.Trace(err)
 	}
-	return e.bigExec.Close()
+	return errors.Trace(e.innerExec.Close())
 }
 
-// Schema implements the Executor Schema interface.
-func (e *HashSemiJoinExec) Schema() expression.Schema {
-	return e.schema
+// Next implements the Executor Next interface.
+func (e *MergeJoinExec) Next() (*Row, error) {
+	for e.cursor >= len(e.resultRows) {
+		outerRow, err := e.outerExec.Next()
+		if err != nil || outerRow == nil {
+			return nil, errors.Trace(err)
+		}
+		e.resultRows, e.cursor = e.resultRows[:0], 0
+		if err = e.joinOuterRow(outerRow); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	row := e.resultRows[e.cursor]
+	e.cursor++
+	return row, nil
 }
 
-// Prepare runs the first time when 'Next' is called and it reads all data from the small table and stores
-// them in a hash table.
-func (e *HashSemiJoinExec) prepare() error {
-	e.hashTable = make(map[string][]*Row)
-	for {
-		row, err := e.smallExec.Next()
+// joinOuterRow joins an outer row with the inner rows of the same keys.
+// If there are no matching rows and it is outer join, a null filled result row is created.
+func (e *MergeJoinExec) joinOuterRow(outerRow *Row) error {
+	matched := true
+	var err error
+	if e.outerFilter != nil {
+		matched, err = expression.EvalBool(e.outerFilter, outerRow.Data, e.ctx)
 		if err != nil {
 			return errors.Trace(err)
 		}
-		if row == nil {
-			e.smallExec.Close()
-			break
+	}
+	var keys []types.Datum
+	if matched {
+		keys, err = evalJoinKeys(e.outerKeys, outerRow)
+		if err != nil {
+			return errors.Trace(err)
 		}
-
-		matched := true
-		if e.smallFilter != nil {
-			matched, err = expression.EvalBool(e.smallFilter, row.Data, e.ctx)
-			if err != nil {
-				return errors.Trace(err)
-			}
-			if !matched {
-				continue
-			}
+	}
+	if keys != nil {
+		if err = e.seekInnerGroup(keys); err != nil {
+			return errors.Trace(err)
 		}
-		hasNull, hashcode, err := getHashKey(e.smallHashKey, row, e.targetTypes, make([]types.Datum, len(e.smallHashKey)), nil)
+		cmp, err := compareJoinKeys(keys, e.innerGroupKeys)
 		if err != nil {
 			return errors.Trace(err)
 		}
-		if hasNull {
-			e.smallTableHasNull = true
-			continue
+		for i := 0; cmp == 0 && i < len(e.innerGroup); i++ {
+			row := e.makeJoinRow(outerRow, e.innerGroup[i])
+			if e.otherFilter != nil {
+				matched, err = expression.EvalBool(e.otherFilter, row.Data, e.ctx)
+				if err != nil {
+					return errors.Trace(err)
+				}
+				if !matched {
+					continue
+				}
+			}
+			e.resultRows = append(e.resultRows, row)
 		}
-		if rows, ok := e.hashTable[string(hashcode)]; !ok {
-			e.hashTable[string(hashcode)] = []*Row{row}
-		} else {
-			e.hashTable[string(hashcode)] = append(rows, row)
+	}
+	if len(e.resultRows) == 0 && e.outer {
+		innerRow := &Row{
+			Data: make([]types.Datum, len(e.innerExec.Schema())),
 		}
+		copy(innerRow.Data, e.defaultValues)
+		e.resultRows = append(e.resultRows, e.makeJoinRow(outerRow, innerRow))
 	}
-
-	e.prepared = true
 	return nil
 }
 
-func (e *HashSemiJoinExec) rowIsMatched(bigRow *Row) (matched bool, hasNull bool, err error) {
-	hasNull, hashcode, err := getHashKey(e.bigHashKey, bigRow, e.targetTypes, make([]types.Datum, len(e.smallHashKey)), nil)
-	if err != nil {
-		return false, false, errors.Trace(err)
+func (e *MergeJoinExec) makeJoinRow(outerRow, innerRow *Row) *Row {
+	if e.leftOuter {
+		return makeJoinRow(outerRow, innerRow)
 	}
-	if hasNull {
+	return makeJoinRow(innerRow, outerRow)
+}
+
+// seekInnerGroup reads the inner rows until the group of the keys, or the first group of the greater keys.
+// The outer keys are ascending, so the group is kept if its keys are not less than the keys.
+func (e *MergeJoinExec) seekInnerGroup(keys []types.Datum) error {
+	if e.innerGroupKeys != nil {
+		cmp, err := compareJoinKeys(e.innerGroupKeys, keys)
+		if err != nil || cmp >= 0 {
+			return errors.Trace(err)
+		}
+	} else if e.innerDone {
+		return nil
+	}
+	e.innerGroup, e.innerGroupKeys = e.innerGroup[:0], nil
+	e.mem.release()
+	for {
+		row, rowKeys, err := e.nextInnerRow()
+		if err != nil || row == nil {
+			return errors.Trace(err)
+		}
+		cmp, err := compareJoinKeys(rowKeys, keys)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if cmp >= 0 {
+			e.innerGroup, e.innerGroupKeys = append(e.innerGroup, row), rowKeys
+			break
+		}
+	}
+	for {
+		if err := e.mem.consume(rowMemUsage(e.innerGroup[len(e.innerGroup)-1])); err != nil {
+			return errors.Trace(err)
+		}
+		row, rowKeys, err := e.nextInnerRow()
+		if err != nil || row == nil {
+			return errors.Trace(err)
+		}
+		cmp, err := compareJoinKeys(rowKeys, e.innerGroupKeys)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if cmp != 0 {
+			e.innerNext, e.innerNextKeys = row, rowKeys
+			return nil
+		}
+		e.innerGroup = append(e.innerGroup, row)
+	}
+}
+
+// nextInnerRow returns the next inner row which passes the inner filter and has no null keys.
+func (e *MergeJoinExec) nextInnerRow() (*Row, []types.Datum, error) {
+	if e.innerNext != nil {
+		row, keys := e.innerNext, e.innerNextKeys
+		e.innerNext, e.innerNextKeys = nil, nil
+		return row, keys, nil
+	}
+	for !e.innerDone {
+		row, err := e.innerExec.Next()
+		if err != nil {
+			return nil, nil, errors.Trace(err)
+		}
+		if row == nil {
+			e.innerDone = true
+			break
+		}
+		if e.innerFilter != nil {
+			matched, err := expression.EvalBool(e.innerFilter, row.Data, e.ctx)
+			if err != nil {
+				return nil, nil, errors.Trace(err)
+			}
+			if !matched {
+				continue
+			}
+		}
+		keys, err := evalJoinKeys(e.innerKeys, row)
+		if err != nil {
+			return nil, nil, errors.Trace(err)
+		}
+		if keys != nil {
+			return row, keys, nil
+		}
+	}
+	return nil, nil, nil
+}
+
+// evalJoinKeys evaluates the join keys of a row, it returns nil if one of the keys is null.
+func evalJoinKeys(cols []*expression.Column, row *Row) ([]types.Datum, error) {
+	keys := make([]types.Datum, len(cols))
+	for i, col := range cols {
+		var err error
+		keys[i], err = col.Eval(row.Data, nil)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if keys[i].IsNull() {
+			return nil, nil
+		}
+	}
+	return keys, nil
+}
+
+// compareJoinKeys compares the join keys, the nil keys are greater than any keys.
+func compareJoinKeys(a, b []types.Datum) (int, error) {
+	if a == nil || b == nil {
+		if a == nil && b == nil {
+			return 0, nil
+		} else if a == nil {
+			return 1, nil
+		}
+		return -1, nil
+	}
+	for i := range a {
+		cmp, err := a[i].CompareDatum(b[i])
+		if err != nil || cmp != 0 {
+			return cmp, errors.Trace(err)
+		}
+	}
+	return 0, nil
+}
+
+// IndexLookUpJoinExec implements the index nested loop join algorithm for inner/ outer join.
+// The outer rows are read in batches. The inner rows of a batch are read by looking up the index of the inner table
+// with the join keys of the batch, then they are joined with the outer rows of the batch by a hash table.
+// The batches are looked up by concurrent workers, and the result rows are returned in the order of the outer rows.
+type IndexLookUpJoinExec struct {
+	ctx           context.Context
+	is            infoschema.InfoSchema
+	schema        expression.Schema
+	joinPlan      *plan.PhysicalIndexJoin
+	outerExec     Executor
+	outerKeys     []*expression.Column
+	innerKeys     []*expression.Column
+	outerFilter   expression.Expression
+	innerFilter   expression.Expression
+	otherFilter   expression.Expression
+	outer         bool
+	leftOuter     bool
+	defaultValues []types.Datum
+	// innerColumns is the number of the columns of the inner child.
+	innerColumns int
+	// targetTypes are the types that both the outer keys and the inner keys are converted to before hashing.
+	targetTypes []*types.FieldType
+	batchSize   int
+	concurrency int
+	// memTracker is the tracker of the statement, the executors of the inner child are built with it.
+	memTracker *memory.Tracker
+
+	prepared  bool
+	outerDone bool
+	// taskCh sends the tasks to Next in the order of the outer rows.
+	taskCh  chan *lookUpJoinTask
+	closeCh chan struct{}
+	wg      sync.WaitGroup
+
+	task   *lookUpJoinTask
+	cursor int
+}
+
+// lookUpJoinTask is a batch of the outer rows to be joined.
+type lookUpJoinTask struct {
+	outerRows []*Row
+	// outerHashKeys are the hash keys of the outer rows, a key is nil if the row can't match any inner rows.
+	outerHashKeys [][]byte
+	// innerExec reads the inner rows of the join keys of the batch, it's nil if there are no keys.
+	innerExec  Executor
+	resultRows []*Row
+	err        error
+	// done is closed when the task is joined.
+	done chan struct{}
+}
+
+// Schema implements the Executor Schema interface.
+func (e *IndexLookUpJoinExec) Schema() expression.Schema {
+	return e.schema
+}
+
+// Close implements the Executor Close interface.
+func (e *IndexLookUpJoinExec) Close() error {
+	if e.prepared {
+		close(e.closeCh)
+		e.wg.Wait()
+		e.prepared = false
+	}
+	e.outerDone = false
+	e.task, e.cursor = nil, 0
+	return errors.Trace(e.outerExec.Close())
+}
+
+// Next implements the Executor Next interface.
+func (e *IndexLookUpJoinExec) Next() (*Row, error) {
+	if !e.prepared {
+		e.prepare()
+	}
+	for e.task == nil || e.cursor >= len(e.task.resultRows) {
+		task, ok := <-e.taskCh
+		if !ok {
+			return nil, nil
+		}
+		<-task.done
+		if task.err != nil {
+			return nil, errors.Trace(task.err)
+		}
+		e.task, e.cursor = task, 0
+	}
+	row := e.task.resultRows[e.cursor]
+	e.cursor++
+	return row, nil
+}
+
+// prepare starts a goroutine to read the outer rows in batches, and the workers to look up the batches.
+func (e *IndexLookUpJoinExec) prepare() {
+	e.taskCh = make(chan *lookUpJoinTask, e.concurrency)
+	e.closeCh = make(chan struct{})
+	workCh := make(chan *lookUpJoinTask, e.concurrency)
+	e.wg.Add(e.concurrency + 1)
+	go e.fetchOuterRows(workCh)
+	for i := 0; i < e.concurrency; i++ {
+		go e.runLookUpWorker(workCh)
+	}
+	e.prepared = true
+}
+
+// fetchOuterRows reads the outer rows in batches, and sends the task of every batch to Next and to the workers.
+func (e *IndexLookUpJoinExec) fetchOuterRows(workCh chan<- *lookUpJoinTask) {
+	defer func() {
+		close(e.taskCh)
+		close(workCh)
+		e.wg.Done()
+	}()
+	for {
+		task := &lookUpJoinTask{done: make(chan struct{})}
+		task.err = e.buildTask(task)
+		if task.err != nil {
+			close(task.done)
+		} else if len(task.outerRows) == 0 {
+			return
+		}
+		select {
+		case e.taskCh <- task:
+		case <-e.closeCh:
+			closeLookUpTask(task)
+			return
+		}
+		if task.err != nil {
+			return
+		}
+		select {
+		case workCh <- task:
+		case <-e.closeCh:
+			closeLookUpTask(task)
+			return
+		}
+	}
+}
+
+// buildTask reads a batch of the outer rows, and builds the executor which reads the inner rows of their keys.
+func (e *IndexLookUpJoinExec) buildTask(task *lookUpJoinTask) error {
+	var lookUpValues []types.Datum
+	vals := make([]types.Datum, len(e.outerKeys))
+	for !e.outerDone && len(task.outerRows) < e.batchSize {
+		row, err := e.outerExec.Next()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if row == nil {
+			e.outerDone = true
+			break
+		}
+		task.outerRows = append(task.outerRows, row)
+		task.outerHashKeys = append(task.outerHashKeys, nil)
+		if e.outerFilter != nil {
+			matched, err := expression.EvalBool(e.outerFilter, row.Data, e.ctx)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			if !matched {
+				continue
+			}
+		}
+		hasNull, key, err := getHashKey(e.outerKeys, row, e.targetTypes, vals, nil)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if hasNull {
+			continue
+		}
+		task.outerHashKeys[len(task.outerHashKeys)-1] = key
+		// The inner child is looked up by the first key.
+		lookUpValues = append(lookUpValues, vals[0])
+	}
+	innerPlan, err := e.joinPlan.LookUpInnerPlan(lookUpValues)
+	if err != nil || innerPlan == nil {
+		return errors.Trace(err)
+	}
+	b := newExecutorBuilder(e.ctx, e.is)
+	b.memTracker = e.memTracker
+	task.innerExec = b.build(innerPlan)
+	return errors.Trace(b.err)
+}
+
+// runLookUpWorker joins the tasks with their inner rows until the tasks are sent out.
+func (e *IndexLookUpJoinExec) runLookUpWorker(workCh <-chan *lookUpJoinTask) {
+	defer e.wg.Done()
+	var innerFilter, otherFilter expression.Expression
+	if e.innerFilter != nil {
+		innerFilter = e.innerFilter.Clone()
+	}
+	if e.otherFilter != nil {
+		otherFilter = e.otherFilter.Clone()
+	}
+	for task := range workCh {
+		select {
+		case <-e.closeCh:
+			closeLookUpTask(task)
+		default:
+			task.err = e.joinTask(task, innerFilter, otherFilter)
+		}
+		close(task.done)
+	}
+}
+
+// joinTask reads the inner rows of a task into a hash table, and joins the outer rows with them.
+// If there are no matching rows and it is outer join, a null filled result row is created.
+func (e *IndexLookUpJoinExec) joinTask(task *lookUpJoinTask, innerFilter, otherFilter expression.Expression) error {
+	hashTable := make(map[string][]*Row)
+	if task.innerExec != nil {
+		err := e.buildHashTable(task.innerExec, innerFilter, hashTable)
+		closeErr := task.innerExec.Close()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if closeErr != nil {
+			return errors.Trace(closeErr)
+		}
+	}
+	for i, outerRow := range task.outerRows {
+		matchedCount := len(task.resultRows)
+		if key := task.outerHashKeys[i]; key != nil {
+			for _, innerRow := range hashTable[string(key)] {
+				row := e.makeJoinRow(outerRow, innerRow)
+				if otherFilter != nil {
+					matched, err := expression.EvalBool(otherFilter, row.Data, e.ctx)
+					if err != nil {
+						return errors.Trace(err)
+					}
+					if !matched {
+						continue
+					}
+				}
+				task.resultRows = append(task.resultRows, row)
+			}
+		}
+		if len(task.resultRows) == matchedCount && e.outer {
+			innerRow := &Row{
+				Data: make([]types.Datum, e.innerColumns),
+			}
+			copy(innerRow.Data, e.defaultValues)
+			task.resultRows = append(task.resultRows, e.makeJoinRow(outerRow, innerRow))
+		}
+	}
+	return nil
+}
+
+// buildHashTable reads the inner rows which pass the inner filter into the hash table by their keys.
+func (e *IndexLookUpJoinExec) buildHashTable(innerExec Executor, innerFilter expression.Expression, hashTable map[string][]*Row) error {
+	vals := make([]types.Datum, len(e.innerKeys))
+	for {
+		row, err := innerExec.Next()
+		if err != nil || row == nil {
+			return errors.Trace(err)
+		}
+		if innerFilter != nil {
+			matched, err := expression.EvalBool(innerFilter, row.Data, e.ctx)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			if !matched {
+				continue
+			}
+		}
+		hasNull, key, err := getHashKey(e.innerKeys, row, e.targetTypes, vals, nil)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if hasNull {
+			continue
+		}
+		hashTable[string(key)] = append(hashTable[string(key)], row)
+	}
+}
+
+func (e *IndexLookUpJoinExec) makeJoinRow(outerRow, innerRow *Row) *Row {
+	if e.leftOuter {
+		return makeJoinRow(outerRow, innerRow)
+	}
+	return makeJoinRow(innerRow, outerRow)
+}
+
+// closeLookUpTask closes the inner executor of a task which won't be joined.
+func closeLookUpTask(task *lookUpJoinTask) {
+	if task.innerExec != nil {
+		task.innerExec.Close()
+	}
+}
+
+// HashSemiJoinExec implements the hash join algorithm for semi join.
+type HashSemiJoinExec struct {
+	hashTable    map[string][]*Row
+	smallHashKey []*expression.Column
+	bigHashKey   []*expression.Column
+	smallExec    Executor
+	bigExec      Executor
+	prepared     bool
+	ctx          context.Context
+	smallFilter  expression.Expression
+	bigFilter    expression.Expression
+	otherFilter  expression.Expression
+	schema       expression.Schema
+	// In auxMode, the result row always returns with an extra column which stores a boolean
+	// or NULL value to indicate if this row is matched.
+	auxMode     bool
+	targetTypes []*types.FieldType
+	// If anti is true, semi join only output the unmatched row.
+	anti bool
+	// If nullAware is true, the join conditions are the comparisons of "a [not] in (subq)", the result is NULL
+	// instead of false if no row matches but a comparison is NULL. Otherwise, e.g. for EXISTS, the result is never NULL.
+	nullAware bool
+	// smallTableEmpty means no row of the small table takes part in the join, the result is false even if the
+	// comparisons of the big row are NULL.
+	smallTableEmpty bool
+	// nullRows are the rows of the small table whose hash keys or smallFilter are NULL, they're kept only in
+	// nullAware mode.
+	nullRows []*Row
+}
+
+// Close implements the Executor Close interface.
+func (e *HashSemiJoinExec) Close() error {
+	e.prepared = false
+	e.hashTable = make(map[string][]*Row)
+	e.nullRows = nil
+	err := e.smallExec.Close()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return e.bigExec.Close()
+}
+
+// Schema implements the Executor Schema interface.
+func (e *HashSemiJoinExec) Schema() expression.Schema {
+	return e.schema
+}
+
+// evalBoolOrNull evaluates the expression as a boolean like expression.EvalBool, but it tells NULL from false.
+func evalBoolOrNull(expr expression.Expression, row []types.Datum, ctx context.Context) (val bool, isNull bool, err error) {
+	d, err := expr.Eval(row, ctx)
+	if err != nil {
+		return false, false, errors.Trace(err)
+	}
+	if d.IsNull() {
 		return false, true, nil
 	}
-	rows, ok := e.hashTable[string(hashcode)]
-	if !ok {
-		return
+	i, err := d.ToBool()
+	if err != nil {
+		return false, false, errors.Trace(err)
 	}
-	// match eq condition
-	for _, smallRow := range rows {
-		matched = true
-		if e.otherFilter != nil {
-			var matchedRow *Row
-			matchedRow = makeJoinRow(bigRow, smallRow)
-			matched, err = expression.EvalBool(e.otherFilter, matchedRow.Data, e.ctx)
+	return i != 0, false, nil
+}
+
+// hashKeyDatums evaluates the hash key of the row like getHashKey, but the NULL values are kept.
+func (e *HashSemiJoinExec) hashKeyDatums(cols []*expression.Column, row *Row) (vals []types.Datum, hasNull bool, err error) {
+	vals = make([]types.Datum, len(cols))
+	for i, col := range cols {
+		vals[i], err = col.Eval(row.Data, nil)
+		if err != nil {
+			return nil, false, errors.Trace(err)
+		}
+		if vals[i].IsNull() {
+			hasNull = true
+			continue
+		}
+		if e.targetTypes[i].Tp != col.RetType.Tp {
+			vals[i], err = vals[i].ConvertTo(e.targetTypes[i])
+			if err != nil {
+				return nil, false, errors.Trace(err)
+			}
+		}
+	}
+	return vals, hasNull, nil
+}
+
+// Prepare runs the first time when 'Next' is called and it reads all data from the small table and stores
+// them in a hash table.
+func (e *HashSemiJoinExec) prepare() error {
+	e.hashTable = make(map[string][]*Row)
+	e.nullRows = nil
+	e.smallTableEmpty = true
+	for {
+		row, err := e.smallExec.Next()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if row == nil {
+			e.smallExec.Close()
+			break
+		}
+
+		matched, isNull := true, false
+		if e.smallFilter != nil {
+			matched, isNull, err = evalBoolOrNull(e.smallFilter, row.Data, e.ctx)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			if !matched && !(isNull && e.nullAware) {
+				continue
+			}
+		}
+		e.smallTableEmpty = false
+		hasNull, hashcode, err := getHashKey(e.smallHashKey, row, e.targetTypes, make([]types.Datum, len(e.smallHashKey)), nil)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if hasNull || isNull {
+			if e.nullAware {
+				e.nullRows = append(e.nullRows, row)
+			}
+			continue
+		}
+		if rows, ok := e.hashTable[string(hashcode)]; !ok {
+			e.hashTable[string(hashcode)] = []*Row{row}
+		} else {
+			e.hashTable[string(hashcode)] = append(rows, row)
+		}
+	}
+
+	e.prepared = true
+	return nil
+}
+
+// rowIsMatched checks if the big row matches a row of the small table. In nullAware mode, it returns isNull if no
+// row matches but the join conditions with a row may be NULL.
+func (e *HashSemiJoinExec) rowIsMatched(bigRow *Row) (matched bool, isNull bool, err error) {
+	bigKey, hasNull, err := e.hashKeyDatums(e.bigHashKey, bigRow)
+	if err != nil {
+		return false, false, errors.Trace(err)
+	}
+	if !hasNull {
+		var hashcode []byte
+		if len(bigKey) > 0 {
+			hashcode, err = codec.EncodeValue(nil, bigKey...)
 			if err != nil {
 				return false, false, errors.Trace(err)
 			}
 		}
-		if matched {
-			return
+		// match eq condition
+		for _, smallRow := range e.hashTable[string(hashcode)] {
+			if e.otherFilter == nil {
+				return true, false, nil
+			}
+			var otherNull bool
+			matched, otherNull, err = evalBoolOrNull(e.otherFilter, makeJoinRow(bigRow, smallRow).Data, e.ctx)
+			if err != nil {
+				return false, false, errors.Trace(err)
+			}
+			if matched {
+				return true, false, nil
+			}
+			isNull = isNull || (otherNull && e.nullAware)
+		}
+	}
+	if !e.nullAware || isNull {
+		return false, isNull, nil
+	}
+	// The comparisons are NULL if the keys are equal except the NULL values, the rows of the hash table are
+	// checked only if the big key has NULL values.
+	if hasNull {
+		allNull := true
+		for _, d := range bigKey {
+			allNull = allNull && d.IsNull()
+		}
+		// The small table isn't empty, see Next.
+		if allNull && e.otherFilter == nil {
+			return false, true, nil
+		}
+		for _, rows := range e.hashTable {
+			isNull, err = e.anyMayMatch(bigKey, bigRow, rows)
+			if err != nil || isNull {
+				return false, isNull, errors.Trace(err)
+			}
+		}
+	}
+	isNull, err = e.anyMayMatch(bigKey, bigRow, e.nullRows)
+	return false, isNull, errors.Trace(err)
+}
+
+// anyMayMatch checks if the join conditions of the big row and any of the small rows aren't false.
+func (e *HashSemiJoinExec) anyMayMatch(bigKey []types.Datum, bigRow *Row, smallRows []*Row) (bool, error) {
+	for _, smallRow := range smallRows {
+		ok, err := e.mayMatch(bigKey, bigRow, smallRow)
+		if err != nil || ok {
+			return ok, errors.Trace(err)
 		}
 	}
-	return
+	return false, nil
+}
+
+// mayMatch checks if the join conditions of the big row and the small row aren't false, the caller makes sure
+// they aren't true.
+func (e *HashSemiJoinExec) mayMatch(bigKey []types.Datum, bigRow, smallRow *Row) (bool, error) {
+	smallKey, _, err := e.hashKeyDatums(e.smallHashKey, smallRow)
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	for i := range bigKey {
+		if bigKey[i].IsNull() || smallKey[i].IsNull() {
+			continue
+		}
+		cmp, err := bigKey[i].CompareDatum(smallKey[i])
+		if err != nil {
+			return false, errors.Trace(err)
+		}
+		if cmp != 0 {
+			return false, nil
+		}
+	}
+	if e.otherFilter != nil {
+		matched, isNull, err := evalBoolOrNull(e.otherFilter, makeJoinRow(bigRow, smallRow).Data, e.ctx)
+		if err != nil {
+			return false, errors.Trace(err)
+		}
+		return matched || isNull, nil
+	}
+	return true, nil
 }
 
 // Next implements the Executor Next interface.
@@ -916,22 +1995,25 @@ func (e *HashSemiJoinExec) Next() (*Row, error) {
 			return nil, nil
 		}
 
-		matched := true
+		matched, isNull := true, false
 		if e.bigFilter != nil {
-			matched, err = expression.EvalBool(e.bigFilter, bigRow.Data, e.ctx)
+			matched, isNull, err = evalBoolOrNull(e.bigFilter, bigRow.Data, e.ctx)
 			if err != nil {
 				return nil, errors.Trace(err)
 			}
+			isNull = isNull && e.nullAware
 		}
-		isNull := false
-		if matched {
-			matched, isNull, err = e.rowIsMatched(bigRow)
+		if (matched || isNull) && !e.smallTableEmpty {
+			var rowMatched, rowIsNull bool
+			rowMatched, rowIsNull, err = e.rowIsMatched(bigRow)
 			if err != nil {
 				return nil, errors.Trace(err)
 			}
-		}
-		if !matched && e.smallTableHasNull {
-			isNull = true
+			// A NULL bigFilter makes the result NULL unless it's false for every row.
+			isNull = rowIsNull || (isNull && rowMatched)
+			matched = rowMatched && !isNull
+		} else {
+			matched, isNull = false, false
 		}
 		if e.anti && !isNull {
 			matched = !matched
@@ -957,6 +2039,7 @@ func (e *HashSemiJoinExec) Next() (*Row, error) {
 // and updates all the items in AggFuncs.
 type HashAggExec struct {
 	Src               Executor
+	reader            rowReader
 	schema            expression.Schema
 	executed          bool
 	hasGby            bool
@@ -967,6 +2050,14 @@ type HashAggExec struct {
 	groups            [][]byte
 	currentGroupIndex int
 	GroupByItems      []expression.Expression
+	// mem tracks the memory of the groups.
+	mem memoryUsage
+
+	// concurrency is the number of the partial workers and the final workers of the parallel aggregation,
+	// the rows are aggregated in the current goroutine if it's not greater than 1.
+	concurrency        int
+	workers            []*hashAggWorker
+	currentWorkerIndex int
 }
 
 // Close implements the Executor Close interface.
@@ -977,9 +2068,23 @@ func (e *HashAggExec) Close() error {
 	for _, agg := range e.AggFuncs {
 		agg.Clear()
 	}
+	// The workers share the tracker of the executor, which is detached after their memory is released.
+	for _, w := range e.workers {
+		w.mem.release()
+	}
+	e.mem.close()
+	e.workers = nil
+	e.currentWorkerIndex = 0
+	e.reader.reset()
 	return e.Src.Close()
 }
 
+// parallel checks whether the rows are aggregated by multiple goroutines. The aggregation without group by has
+// only one group, so it's not parallel.
+func (e *HashAggExec) parallel() bool {
+	return e.concurrency > 1 && e.hasGby && e.Src != nil
+}
+
 // Schema implements the Executor Schema interface.
 func (e *HashAggExec) Schema() expression.Schema {
 	return e.schema
@@ -987,6 +2092,15 @@ func (e *HashAggExec) Schema() expression.Schema {
 
 // Next implements the Executor Next interface.
 func (e *HashAggExec) Next() (*Row, error) {
+	if e.parallel() {
+		if !e.executed {
+			e.executed = true
+			if err := e.execParallel(); err != nil {
+				return nil, errors.Trace(err)
+			}
+		}
+		return e.nextParallel()
+	}
 	// In this stage we consider all data from src as a single group.
 	if !e.executed {
 		e.groupMap = make(map[string]bool)
@@ -1021,8 +2135,14 @@ func (e *HashAggExec) Next() (*Row, error) {
 }
 
 func (e *HashAggExec) getGroupKey(row *Row) ([]byte, error) {
+	return e.evalGroupKey(e.GroupByItems, row)
+}
+
+// evalGroupKey evaluates the group key of the row by the group by items, the parallel workers evaluate the
+// clones of GroupByItems.
+func (e *HashAggExec) evalGroupKey(groupByItems []expression.Expression, row *Row) ([]byte, error) {
 	if e.aggType == plan.FinalAgg {
-		val, err := e.GroupByItems[0].Eval(row.Data, e.ctx)
+		val, err := groupByItems[0].Eval(row.Data, e.ctx)
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
@@ -1031,8 +2151,8 @@ func (e *HashAggExec) getGroupKey(row *Row) ([]byte, error) {
 	if !e.hasGby {
 		return []byte{}, nil
 	}
-	vals := make([]types.Datum, 0, len(e.GroupByItems))
-	for _, item := range e.GroupByItems {
+	vals := make([]types.Datum, 0, len(groupByItems))
+	for _, item := range groupByItems {
 		v, err := item.Eval(row.Data, e.ctx)
 		if err != nil {
 			return nil, errors.Trace(err)
@@ -1051,7 +2171,7 @@ func (e *HashAggExec) getGroupKey(row *Row) ([]byte, error) {
 func (e *HashAggExec) innerNext() (ret bool, err error) {
 	var srcRow *Row
 	if e.Src != nil {
-		srcRow, err = e.Src.Next()
+		srcRow, err = e.reader.next(e.Src)
 		if err != nil {
 			return false, errors.Trace(err)
 		}
@@ -1070,6 +2190,10 @@ func (e *HashAggExec) innerNext() (ret bool, err error) {
 		return false, errors.Trace(err)
 	}
 	if _, ok := e.groupMap[string(groupKey)]; !ok {
+		// The group key is kept in both the map and the slice, and every aggregate function keeps a context.
+		if err = e.mem.consume(2*int64(len(groupKey)) + int64(len(e.AggFuncs))*datumMemUsage); err != nil {
+			return false, errors.Trace(err)
+		}
 		e.groupMap[string(groupKey)] = true
 		e.groups = append(e.groups, groupKey)
 	}
@@ -1083,25 +2207,37 @@ func (e *HashAggExec) innerNext() (ret bool, err error) {
 // It assumes all the input datas is sorted by group by key.
 // When Next() is called, it will return a result for the same group.
 type StreamAggExec struct {
-	Src                Executor
-	schema             expression.Schema
-	executed           bool
-	hasData            bool
-	ctx                context.Context
-	AggFuncs           []expression.AggregationFunction
-	GroupByItems       []expression.Expression
-	curGroupEncodedKey []byte
-	curGroupKey        []types.Datum
-	tmpGroupKey        []types.Datum
+	Src          Executor
+	reader       rowReader
+	schema       expression.Schema
+	executed     bool
+	hasData      bool
+	ctx          context.Context
+	AggFuncs     []expression.AggregationFunction
+	GroupByItems []expression.Expression
+	curGroupKey  []types.Datum
+	tmpGroupKey  []types.Datum
+
+	// distinctArg is the argument of the distinct aggregate functions when the input is also sorted by it in every group.
+	// distinctFuncs marks these functions, they are built as the non-distinct ones and only updated by the
+	// rows whose distinctArg differs from the previous row in the same group.
+	distinctArg       expression.Expression
+	distinctFuncs     []bool
+	lastDistinctValue types.Datum
+	hasLastDistinct   bool
 }
 
 // Close implements the Executor Close interface.
 func (e *StreamAggExec) Close() error {
 	e.executed = false
 	e.hasData = false
+	e.hasLastDistinct = false
+	// The first row of the next execution starts a new group.
+	e.curGroupKey = nil
 	for _, agg := range e.AggFuncs {
 		agg.Clear()
 	}
+	e.reader.reset()
 	return e.Src.Close()
 }
 
@@ -1117,7 +2253,7 @@ func (e *StreamAggExec) Next() (*Row, error) {
 	}
 	retRow := &Row{Data: make([]types.Datum, 0, len(e.AggFuncs))}
 	for {
-		row, err := e.Src.Next()
+		row, err := e.reader.next(e.Src)
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
@@ -1140,7 +2276,14 @@ func (e *StreamAggExec) Next() (*Row, error) {
 		if e.executed {
 			break
 		}
-		for _, af := range e.AggFuncs {
+		duplicated, err := e.isDuplicatedDistinct(row, newGroup)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		for i, af := range e.AggFuncs {
+			if duplicated && e.distinctFuncs[i] {
+				continue
+			}
 			err = af.StreamUpdate(row.Data, e.ctx)
 			if err != nil {
 				return nil, errors.Trace(err)
@@ -1184,12 +2327,30 @@ func (e *StreamAggExec) meetNewGroup(row *Row) (bool, error) {
 		return false, nil
 	}
 	e.curGroupKey = e.tmpGroupKey
-	var err error
-	e.curGroupEncodedKey, err = codec.EncodeValue(e.curGroupEncodedKey[0:0:cap(e.curGroupEncodedKey)], e.curGroupKey...)
+	return !firstGroup, nil
+}
+
+// isDuplicatedDistinct checks if the distinct argument of the row equals the previous one in the same group.
+// Since the input is sorted by it in every group, the row can be skipped by the distinct aggregate functions.
+func (e *StreamAggExec) isDuplicatedDistinct(row *Row, newGroup bool) (bool, error) {
+	if e.distinctArg == nil {
+		return false, nil
+	}
+	v, err := e.distinctArg.Eval(row.Data, e.ctx)
 	if err != nil {
 		return false, errors.Trace(err)
 	}
-	return !firstGroup, nil
+	if !newGroup && e.hasLastDistinct {
+		c, err := v.CompareDatum(e.lastDistinctValue)
+		if err != nil {
+			return false, errors.Trace(err)
+		}
+		if c == 0 {
+			return true, nil
+		}
+	}
+	e.lastDistinctValue, e.hasLastDistinct = v, true
+	return false, nil
 }
 
 // ProjectionExec represents a select fields executor.
@@ -1199,6 +2360,12 @@ type ProjectionExec struct {
 	executed bool
 	ctx      context.Context
 	exprs    []expression.Expression
+
+	childChunk *chunk.Chunk
+	childDone  bool
+	// datums and values are the buffers of a source row and a result row when the rows are read in chunks.
+	datums []types.Datum
+	values []types.Datum
 }
 
 // Schema implements the Executor Schema interface.
@@ -1240,8 +2407,57 @@ func (e *ProjectionExec) Next() (retRow *Row, err error) {
 	return row, nil
 }
 
+func (e *ProjectionExec) supportChunk() bool {
+	return true
+}
+
+// NextChunk implements the chunkExecutor NextChunk interface. The source chunk has the same capacity as chk,
+// so all its rows are projected to chk.
+func (e *ProjectionExec) NextChunk(chk *chunk.Chunk) error {
+	if e.Src == nil {
+		if e.executed {
+			return nil
+		}
+		e.executed = true
+		return errors.Trace(e.project(chk, nil))
+	}
+	if e.childChunk == nil {
+		e.childChunk = newChunk(e.Src, chk.Capacity())
+	}
+	if e.childDone {
+		return nil
+	}
+	if err := nextChunk(e.Src, e.childChunk); err != nil {
+		return errors.Trace(err)
+	}
+	e.childDone = !e.childChunk.IsFull()
+	for i := 0; i < e.childChunk.NumRows(); i++ {
+		e.datums = e.childChunk.GetRow(i).GetDatums(e.datums[:0])
+		if err := e.project(chk, e.datums); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+// project evaluates the expressions on a source row and appends the result row to chk.
+func (e *ProjectionExec) project(chk *chunk.Chunk, datums []types.Datum) error {
+	e.values = e.values[:0]
+	for _, expr := range e.exprs {
+		val, err := expr.Eval(datums, e.ctx)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		e.values = append(e.values, val)
+	}
+	chk.AppendDatums(e.values)
+	return nil
+}
+
 // Close implements the Executor Close interface.
 func (e *ProjectionExec) Close() error {
+	e.childChunk = nil
+	e.childDone = false
 	if e.Src != nil {
 		return e.Src.Close()
 	}
@@ -1278,12 +2494,393 @@ func (e *TableDualExec) Close() error {
 	return nil
 }
 
+// GenerateSeriesExec represents a generate_series table function executor.
+type GenerateSeriesExec struct {
+	schema  expression.Schema
+	start   int64
+	stop    int64
+	step    int64
+	cursor  int64
+	started bool
+	done    bool
+}
+
+// Schema implements the Executor Schema interface.
+func (e *GenerateSeriesExec) Schema() expression.Schema {
+	return e.schema
+}
+
+// Next implements the Executor Next interface.
+func (e *GenerateSeriesExec) Next() (*Row, error) {
+	if !e.started {
+		e.cursor = e.start
+		e.started = true
+	}
+	if e.done || (e.step > 0 && e.cursor > e.stop) || (e.step < 0 && e.cursor < e.stop) {
+		return nil, nil
+	}
+	row := &Row{Data: []types.Datum{types.NewIntDatum(e.cursor)}}
+	next := e.cursor + e.step
+	// Stop when the next value overflows, it must be beyond the stop value.
+	if (e.step > 0 && next < e.cursor) || (e.step < 0 && next > e.cursor) {
+		e.done = true
+	}
+	e.cursor = next
+	return row, nil
+}
+
+// Close implements the Executor Close interface.
+func (e *GenerateSeriesExec) Close() error {
+	e.started = false
+	e.done = false
+	return nil
+}
+
+// JSONTableExec represents a json_table table function executor.
+type JSONTableExec struct {
+	schema  expression.Schema
+	ctx     context.Context
+	doc     expression.Expression
+	rowPath *jsonpath.Path
+	columns []*plan.JSONTableColumn
+	values  []interface{}
+	cursor  int
+	started bool
+}
+
+// Schema implements the Executor Schema interface.
+func (e *JSONTableExec) Schema() expression.Schema {
+	return e.schema
+}
+
+// Next implements the Executor Next interface.
+func (e *JSONTableExec) Next() (*Row, error) {
+	if !e.started {
+		if err := e.evalRows(); err != nil {
+			return nil, errors.Trace(err)
+		}
+		e.started = true
+	}
+	if e.cursor >= len(e.values) {
+		return nil, nil
+	}
+	value := e.values[e.cursor]
+	e.cursor++
+	row := &Row{Data: make([]types.Datum, 0, len(e.columns))}
+	for i, col := range e.columns {
+		var d types.Datum
+		var err error
+		if col.Path == nil {
+			d = types.NewIntDatum(int64(e.cursor))
+			d, err = d.ConvertTo(col.Tp)
+		} else {
+			d, err = e.evalColumn(col, e.schema[i].ColName, value)
+		}
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		row.Data = append(row.Data, d)
+	}
+	return row, nil
+}
+
+// evalColumn evaluates a path column on the value of a row. A path matching nothing is handled by the ON EMPTY
+// response of the column, and a value which can't be converted to the column type by the ON ERROR response,
+// which is NULL with a warning by default.
+func (e *JSONTableExec) evalColumn(col *plan.JSONTableColumn, name model.CIStr, value interface{}) (types.Datum, error) {
+	values := col.Path.Eval(value)
+	if len(values) == 0 {
+		switch col.OnEmpty.Tp {
+		case ast.TableFuncColumnResponseDefault:
+			return col.OnEmpty.Default, nil
+		case ast.TableFuncColumnResponseError:
+			return types.Datum{}, ErrMissingJSONValue.Gen("Missing value for JSON_TABLE column '%s'", name.O)
+		}
+		return types.Datum{}, nil
+	}
+	d, err := jsonToDatum(values)
+	if err == nil {
+		d, err = d.ConvertTo(col.Tp)
+	}
+	if err == nil {
+		return d, nil
+	}
+	switch col.OnError.Tp {
+	case ast.TableFuncColumnResponseDefault:
+		return col.OnError.Default, nil
+	case ast.TableFuncColumnResponseError:
+		return types.Datum{}, errors.Trace(err)
+	}
+	e.ctx.GetSessionVars().AppendWarning(err)
+	return types.Datum{}, nil
+}
+
+// evalRows evaluates the document and finds the values of the rows, a NULL document has no rows.
+func (e *JSONTableExec) evalRows() error {
+	d, err := e.doc.Eval(nil, e.ctx)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if d.IsNull() {
+		return nil
+	}
+	text, err := d.ToString()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	doc, err := jsonpath.Decode(text)
+	if err != nil {
+		return ErrInvalidJSONText.Gen("%s", err)
+	}
+	e.values = e.rowPath.Eval(doc)
+	return nil
+}
+
+// jsonToDatum converts the values matched by a column path to a datum. A column matching an object, an array or more than one value is the JSON text of the match.
+func jsonToDatum(values []interface{}) (types.Datum, error) {
+	if len(values) == 0 {
+		return types.Datum{}, nil
+	}
+	if len(values) == 1 {
+		switch x := values[0].(type) {
+		case nil:
+			return types.Datum{}, nil
+		case bool:
+			if x {
+				return types.NewIntDatum(1), nil
+			}
+			return types.NewIntDatum(0), nil
+		case string:
+			return types.NewStringDatum(x), nil
+		case json.Number:
+			if v, err := x.Int64(); err == nil {
+				return types.NewIntDatum(v), nil
+			}
+			dec := new(types.MyDecimal)
+			if err := dec.FromString([]byte(x.String())); err != nil {
+				// The number is out of the range of decimal, keep it as float.
+				f, err := x.Float64()
+				if err != nil {
+					return types.Datum{}, errors.Trace(err)
+				}
+				return types.NewFloat64Datum(f), nil
+			}
+			return types.NewDecimalDatum(dec), nil
+		}
+	}
+	var text string
+	var err error
+	if len(values) == 1 {
+		text, err = jsonpath.Encode(values[0])
+	} else {
+		text, err = jsonpath.Encode(values)
+	}
+	if err != nil {
+		return types.Datum{}, errors.Trace(err)
+	}
+	return types.NewStringDatum(text), nil
+}
+
+// Close implements the Executor Close interface.
+func (e *JSONTableExec) Close() error {
+	e.values = nil
+	e.cursor = 0
+	e.started = false
+	return nil
+}
+
+// ProfileExec represents a tidb_profile table function executor.
+type ProfileExec struct {
+	ctx      context.Context
+	schema   expression.Schema
+	kind     string
+	duration time.Duration
+	profile  *profile.Profile
+	cursor   int
+}
+
+// Schema implements the Executor Schema interface.
+func (e *ProfileExec) Schema() expression.Schema {
+	return e.schema
+}
+
+// Next implements the Executor Next interface.
+func (e *ProfileExec) Next() (*Row, error) {
+	if e.profile == nil {
+		var err error
+		if e.kind == plan.ProfileCPU {
+			log.Infof("[profile] capture the cpu profile in %v", e.duration)
+			e.profile, err = profile.CPU(e.duration, func() bool { return isKilled(e.ctx) })
+		} else {
+			e.profile, err = profile.Heap()
+		}
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if isKilled(e.ctx) {
+			e.profile = nil
+			return nil, errors.Trace(kv.ErrQueryInterrupted)
+		}
+	}
+	if e.cursor >= len(e.profile.Frames) {
+		return nil, nil
+	}
+	f := e.profile.Frames[e.cursor]
+	e.cursor++
+	percent := func(v int64) float64 {
+		if e.profile.Total == 0 {
+			return 0
+		}
+		return float64(v) * 100 / float64(e.profile.Total)
+	}
+	return &Row{Data: types.MakeDatums(f.Name, f.File, f.Flat, percent(f.Flat), f.Cum, percent(f.Cum))}, nil
+}
+
+// Close implements the Executor Close interface.
+func (e *ProfileExec) Close() error {
+	e.profile = nil
+	e.cursor = 0
+	return nil
+}
+
+// RowHistoryExec represents a tidb_row_history table function executor.
+type RowHistoryExec struct {
+	schema   expression.Schema
+	ctx      context.Context
+	dbID     int64
+	table    *model.TableInfo
+	columns  []*model.ColumnInfo
+	handle   int64
+	versions []kv.KeyVersion
+	started  bool
+	cursor   int
+}
+
+// Schema implements the Executor Schema interface.
+func (e *RowHistoryExec) Schema() expression.Schema {
+	return e.schema
+}
+
+// Next implements the Executor Next interface.
+func (e *RowHistoryExec) Next() (*Row, error) {
+	if !e.started {
+		err := e.readVersions()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		e.started = true
+	}
+	if e.cursor >= len(e.versions) {
+		return nil, nil
+	}
+	v := e.versions[e.cursor]
+	e.cursor++
+	// The physical part of a version is the commit time in milliseconds.
+	ms := oracle.ExtractPhysical(v.Version.Ver)
+	commitTime := types.Time{Time: time.Unix(0, ms*int64(time.Millisecond)), Type: mysql.TypeDatetime, Fsp: 3}
+	data := make([]types.Datum, 3, 3+len(e.columns))
+	data[0].SetUint64(v.Version.Ver)
+	data[1].SetMysqlTime(commitTime)
+	if v.Value == nil {
+		data[2].SetInt64(1)
+		return &Row{Data: append(data, make([]types.Datum, len(e.columns))...)}, nil
+	}
+	data[2].SetInt64(0)
+	// The version is decoded with the columns of the table when it's committed, the columns whose types are
+	// changed after it are converted to their current types.
+	tblInfo, err := e.tableAt(v.Version)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	colTps := make(map[int64]*types.FieldType, len(tblInfo.Columns))
+	for _, col := range tblInfo.Columns {
+		colTps[col.ID] = &col.FieldType
+	}
+	values, err := tablecodec.DecodeRow(v.Value, colTps)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	for _, col := range e.columns {
+		if e.table.PKIsHandle && mysql.HasPriKeyFlag(col.Flag) {
+			if mysql.HasUnsignedFlag(col.Flag) {
+				data = append(data, types.NewUintDatum(uint64(e.handle)))
+			} else {
+				data = append(data, types.NewIntDatum(e.handle))
+			}
+			continue
+		}
+		// The column added after the version is NULL.
+		d := values[col.ID]
+		if tp, ok := colTps[col.ID]; ok && !d.IsNull() && (tp.Tp != col.Tp || tp.Flen != col.Flen || tp.Decimal != col.Decimal) {
+			d, err = d.ConvertTo(&col.FieldType)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+		}
+		data = append(data, d)
+	}
+	return &Row{Data: data}, nil
+}
+
+// tableAt returns the table info of the row when the version is committed.
+func (e *RowHistoryExec) tableAt(ver kv.Version) (*model.TableInfo, error) {
+	snapshot, err := sessionctx.GetDomain(e.ctx).Store().GetSnapshot(ver)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	tblInfo, err := meta.NewSnapshotMeta(snapshot).GetTable(e.dbID, e.table.ID)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if tblInfo == nil {
+		return e.table, nil
+	}
+	return tblInfo, nil
+}
+
+// readVersions reads the versions of the row visible to the statement.
+func (e *RowHistoryExec) readVersions() error {
+	store := sessionctx.GetDomain(e.ctx).Store()
+	reader, ok := store.(kv.HistoryReader)
+	if !ok {
+		return errors.Trace(kv.ErrNotImplemented)
+	}
+	ver := kv.Version{Ver: e.ctx.GetSessionVars().SnapshotTS}
+	if ver.Ver == 0 {
+		var err error
+		ver, err = store.CurrentVersion()
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
+	key := tablecodec.EncodeRowKeyWithHandle(e.table.ID, e.handle)
+	var err error
+	e.versions, err = reader.GetHistory(key, ver)
+	return errors.Trace(err)
+}
+
+// Close implements the Executor Close interface.
+func (e *RowHistoryExec) Close() error {
+	e.versions = nil
+	e.started = false
+	e.cursor = 0
+	return nil
+}
+
 // SelectionExec represents a filter executor.
 type SelectionExec struct {
-	Src       Executor
-	Condition expression.Expression
-	ctx       context.Context
-	schema    expression.Schema
+	Src Executor
+	// Conditions are the CNF items of the filter, they're evaluated in order until one of them isn't true.
+	Conditions []expression.Expression
+	ctx        context.Context
+	schema     expression.Schema
+
+	// childChunk is the source chunk whose rows from childIdx are not filtered yet, childDone is true if the
+	// source has no more row.
+	childChunk *chunk.Chunk
+	childIdx   int
+	childDone  bool
+	datums     []types.Datum
 }
 
 // Schema implements the Executor Schema interface.
@@ -1301,7 +2898,7 @@ func (e *SelectionExec) Next() (*Row, error) {
 		if srcRow == nil {
 			return nil, nil
 		}
-		match, err := expression.EvalBool(e.Condition, srcRow.Data, e.ctx)
+		match, err := e.match(srcRow.Data)
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
@@ -1311,8 +2908,56 @@ func (e *SelectionExec) Next() (*Row, error) {
 	}
 }
 
+func (e *SelectionExec) supportChunk() bool {
+	return true
+}
+
+// NextChunk implements the chunkExecutor NextChunk interface.
+func (e *SelectionExec) NextChunk(chk *chunk.Chunk) error {
+	if e.childChunk == nil {
+		e.childChunk = newChunk(e.Src, chk.Capacity())
+	}
+	for !chk.IsFull() {
+		if e.childIdx >= e.childChunk.NumRows() {
+			if e.childDone {
+				return nil
+			}
+			if err := nextChunk(e.Src, e.childChunk); err != nil {
+				return errors.Trace(err)
+			}
+			e.childIdx = 0
+			e.childDone = !e.childChunk.IsFull()
+		}
+		for ; e.childIdx < e.childChunk.NumRows() && !chk.IsFull(); e.childIdx++ {
+			row := e.childChunk.GetRow(e.childIdx)
+			e.datums = row.GetDatums(e.datums[:0])
+			match, err := e.match(e.datums)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			if match {
+				chk.AppendRow(row)
+			}
+		}
+	}
+	return nil
+}
+
+func (e *SelectionExec) match(data []types.Datum) (bool, error) {
+	for _, cond := range e.Conditions {
+		match, err := expression.EvalBool(cond, data, e.ctx)
+		if err != nil || !match {
+			return false, errors.Trace(err)
+		}
+	}
+	return true, nil
+}
+
 // Close implements the Executor Close interface.
 func (e *SelectionExec) Close() error {
+	e.childChunk = nil
+	e.childIdx = 0
+	e.childDone = false
 	return e.Src.Close()
 }
 
@@ -1463,13 +3108,31 @@ type SortExec struct {
 	fetched bool
 	err     error
 	schema  expression.Schema
+	// mem tracks the memory of the buffered rows.
+	mem memoryUsage
+	// memQuota is the max memory in bytes of the buffered rows, they are sorted and spilled to a temporary
+	// file when it's exceeded, 0 means no limit.
+	memQuota int64
+	// bufferedBytes is the memory in bytes of the buffered rows.
+	bufferedBytes int64
+	// runs are the sorted runs spilled to the temporary files, they are merged by merger.
+	runs   []*sortRun
+	merger *sortRunHeap
+	// rowKeyTables are the tables of the row keys of the spilled rows, the row keys refer to them by index.
+	rowKeyTables []*RowKeyEntry
 }
 
 // Close implements the Executor Close interface.
 func (e *SortExec) Close() error {
 	e.fetched = false
 	e.Rows = nil
-	return e.Src.Close()
+	e.mem.close()
+	e.bufferedBytes = 0
+	err := e.closeRuns()
+	if err1 := e.Src.Close(); err == nil {
+		err = err1
+	}
+	return errors.Trace(err)
 }
 
 // Schema implements the Executor Schema interface.
@@ -1489,9 +3152,13 @@ func (e *SortExec) Swap(i, j int) {
 
 // Less implements sort.Interface Less interface.
 func (e *SortExec) Less(i, j int) bool {
+	return e.lessRow(e.Rows[i], e.Rows[j])
+}
+
+func (e *SortExec) lessRow(row1, row2 *orderByRow) bool {
 	for index, by := range e.ByItems {
-		v1 := e.Rows[i].key[index]
-		v2 := e.Rows[j].key[index]
+		v1 := row1.key[index]
+		v2 := row2.key[index]
 
 		ret, err := v1.CompareDatum(v2)
 		if err != nil {
@@ -1509,39 +3176,105 @@ func (e *SortExec) Less(i, j int) bool {
 			return false
 		}
 	}
-
-	return false
+
+	return false
+}
+
+// Next implements the Executor Next interface.
+func (e *SortExec) Next() (*Row, error) {
+	if !e.fetched {
+		if err := e.fetchRows(e.Src.Next); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	return e.nextSorted()
+}
+
+func (e *SortExec) supportChunk() bool {
+	return true
+}
+
+// NextChunk implements the chunkExecutor NextChunk interface. The rows of the source are read in chunks too,
+// since the sorted rows are returned without the row keys.
+func (e *SortExec) NextChunk(chk *chunk.Chunk) error {
+	if !e.fetched {
+		reader := &rowReader{chunkSize: chk.Capacity()}
+		err := e.fetchRows(func() (*Row, error) {
+			return reader.next(e.Src)
+		})
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
+	for !chk.IsFull() {
+		row, err := e.nextSorted()
+		if err != nil || row == nil {
+			return errors.Trace(err)
+		}
+		chk.AppendDatums(row.Data)
+	}
+	return nil
 }
 
-// Next implements the Executor Next interface.
-func (e *SortExec) Next() (*Row, error) {
-	if !e.fetched {
-		for {
-			srcRow, err := e.Src.Next()
+// fetchRows reads all the rows by next and sorts them.
+func (e *SortExec) fetchRows(next func() (*Row, error)) error {
+	for {
+		srcRow, err := next()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if srcRow == nil {
+			break
+		}
+		orderRow := &orderByRow{
+			row: srcRow,
+			key: make([]types.Datum, len(e.ByItems)),
+		}
+		for i, byItem := range e.ByItems {
+			orderRow.key[i], err = byItem.Expr.Eval(srcRow.Data, e.ctx)
 			if err != nil {
-				return nil, errors.Trace(err)
-			}
-			if srcRow == nil {
-				break
+				return errors.Trace(err)
 			}
-			orderRow := &orderByRow{
-				row: srcRow,
-				key: make([]types.Datum, len(e.ByItems)),
+		}
+		usage := rowMemUsage(srcRow) + datumsMemUsage(orderRow.key)
+		err = e.mem.consume(usage)
+		// The sort can release its memory by spilling the rows when the statement exceeds its memory quota, or
+		// when the memory arbiter asks the statement to spill.
+		quotaExceeded := ErrMemQuotaExceeded.Equal(err)
+		if err != nil && !quotaExceeded {
+			return errors.Trace(err)
+		}
+		e.Rows = append(e.Rows, orderRow)
+		e.bufferedBytes += usage
+		if quotaExceeded || e.mem.tracker.SpillRequested() || (e.memQuota > 0 && e.bufferedBytes > e.memQuota) {
+			if err = e.spill(); err != nil {
+				return errors.Trace(err)
 			}
-			for i, byItem := range e.ByItems {
-				orderRow.key[i], err = byItem.Expr.Eval(srcRow.Data, e.ctx)
-				if err != nil {
-					return nil, errors.Trace(err)
-				}
+			if quotaExceeded && e.mem.tracker.QuotaExceeded() {
+				return errors.Trace(ErrMemQuotaExceeded)
 			}
-			e.Rows = append(e.Rows, orderRow)
 		}
+	}
+	if len(e.runs) > 0 {
+		// The rest rows are spilled too, so all the rows are merged from the runs.
+		if err := e.prepareMerge(); err != nil {
+			return errors.Trace(err)
+		}
+	} else {
 		sort.Sort(e)
-		e.fetched = true
 	}
+	e.fetched = true
+	return nil
+}
+
+// nextSorted returns the next sorted row.
+func (e *SortExec) nextSorted() (*Row, error) {
 	if e.err != nil {
 		return nil, errors.Trace(e.err)
 	}
+	if e.merger != nil {
+		return e.nextMerged()
+	}
 	if e.Idx >= len(e.Rows) {
 		return nil, nil
 	}
@@ -1605,57 +3338,91 @@ func (e *TopnExec) Pop() interface{} {
 // Next implements the Executor Next interface.
 func (e *TopnExec) Next() (*Row, error) {
 	if !e.fetched {
-		e.Idx = int(e.limit.Offset)
-		e.totalCount = int(e.limit.Offset + e.limit.Count)
-		e.Rows = make([]*orderByRow, 0, e.totalCount+1)
-		e.heapSize = 0
-		for {
-			srcRow, err := e.Src.Next()
+		if err := e.fetchRows(e.Src.Next); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	return e.nextSorted(), nil
+}
+
+// NextChunk implements the chunkExecutor NextChunk interface.
+func (e *TopnExec) NextChunk(chk *chunk.Chunk) error {
+	if !e.fetched {
+		reader := &rowReader{chunkSize: chk.Capacity()}
+		err := e.fetchRows(func() (*Row, error) {
+			return reader.next(e.Src)
+		})
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
+	for !chk.IsFull() {
+		row := e.nextSorted()
+		if row == nil {
+			return nil
+		}
+		chk.AppendDatums(row.Data)
+	}
+	return nil
+}
+
+// fetchRows reads all the rows by next and keeps the Top-N rows of them.
+func (e *TopnExec) fetchRows(next func() (*Row, error)) error {
+	e.Idx = int(e.limit.Offset)
+	e.totalCount = int(e.limit.Offset + e.limit.Count)
+	e.Rows = make([]*orderByRow, 0, e.totalCount+1)
+	e.heapSize = 0
+	for {
+		srcRow, err := next()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if srcRow == nil {
+			break
+		}
+		// build orderRow from srcRow.
+		orderRow := &orderByRow{
+			row: srcRow,
+			key: make([]types.Datum, len(e.ByItems)),
+		}
+		for i, byItem := range e.ByItems {
+			orderRow.key[i], err = byItem.Expr.Eval(srcRow.Data, e.ctx)
 			if err != nil {
-				return nil, errors.Trace(err)
-			}
-			if srcRow == nil {
-				break
-			}
-			// build orderRow from srcRow.
-			orderRow := &orderByRow{
-				row: srcRow,
-				key: make([]types.Datum, len(e.ByItems)),
-			}
-			for i, byItem := range e.ByItems {
-				orderRow.key[i], err = byItem.Expr.Eval(srcRow.Data, e.ctx)
-				if err != nil {
-					return nil, errors.Trace(err)
-				}
-			}
-			if e.totalCount == e.heapSize {
-				// An equivalent of Push and Pop. We don't use the standard Push and Pop
-				// to reduce the number of comparisons.
-				e.Rows = append(e.Rows, orderRow)
-				if e.Less(0, e.heapSize) {
-					e.Swap(0, e.heapSize)
-					heap.Fix(e, 0)
-				}
-				e.Rows = e.Rows[:e.heapSize]
-			} else {
-				heap.Push(e, orderRow)
+				return errors.Trace(err)
 			}
 		}
-		if e.limit.Offset == 0 {
-			sort.Sort(&e.SortExec)
-		} else {
-			for i := 0; i < int(e.limit.Count) && e.Len() > 0; i++ {
-				heap.Pop(e)
+		if e.totalCount == e.heapSize {
+			// An equivalent of Push and Pop. We don't use the standard Push and Pop
+			// to reduce the number of comparisons.
+			e.Rows = append(e.Rows, orderRow)
+			if e.Less(0, e.heapSize) {
+				e.Swap(0, e.heapSize)
+				heap.Fix(e, 0)
 			}
+			e.Rows = e.Rows[:e.heapSize]
+		} else {
+			heap.Push(e, orderRow)
+		}
+	}
+	if e.limit.Offset == 0 {
+		sort.Sort(&e.SortExec)
+	} else {
+		for i := 0; i < int(e.limit.Count) && e.Len() > 0; i++ {
+			heap.Pop(e)
 		}
-		e.fetched = true
 	}
+	e.fetched = true
+	return nil
+}
+
+// nextSorted returns the next row of the Top-N rows.
+func (e *TopnExec) nextSorted() *Row {
 	if e.Idx >= len(e.Rows) {
-		return nil, nil
+		return nil
 	}
 	row := e.Rows[e.Idx].row
 	e.Idx++
-	return row, nil
+	return row
 }
 
 // ApplyExec represents apply executor.
@@ -1668,6 +3435,23 @@ type ApplyExec struct {
 	// checker checks if an Src row with an inner row matches the condition,
 	// and if it needs to check more inner rows.
 	checker *conditionChecker
+	// lateral means every inner row is joined with the Src row, and the Src rows without inner rows are skipped.
+	lateral  bool
+	outerRow *Row
+	// cache memoizes the inner rows by the values of the outerSchema, it's nil if the inner results can't be cached.
+	// When the cache is used, the cached inner rows of the current Src row are read from innerRows, or the inner
+	// rows are read from the inner executor and recorded in innerRows to be cached while they're returned.
+	cache       *applyCache
+	cacheKey    []byte
+	innerRows   []*Row
+	innerCursor int
+	// partial is true if the cached innerRows are only the leading inner rows.
+	partial bool
+	// streaming is true if the inner rows are read from the inner executor, recording is false if they're larger
+	// than the cache, recordSize is the memory of the recorded rows.
+	streaming  bool
+	recording  bool
+	recordSize int64
 }
 
 // conditionChecker checks if all or any of the row match this condition.
@@ -1714,11 +3498,115 @@ func (e *ApplyExec) Close() error {
 	if e.checker != nil {
 		e.checker.dataHasNull = false
 	}
+	if e.outerRow != nil || e.streaming {
+		e.outerRow = nil
+		if err := e.closeInner(); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	if e.cache != nil {
+		e.cache.clear()
+	}
 	return e.Src.Close()
 }
 
+// openInner prepares to read the inner rows for the current values of the outerSchema. If the cache is used,
+// the inner rows are read from it, or they are read from the inner executor and memoized while they're returned.
+func (e *ApplyExec) openInner() error {
+	if e.cache == nil {
+		return nil
+	}
+	key, err := applyCacheKey(e.outerSchema)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	e.cacheKey = key
+	e.innerCursor = 0
+	if rows, complete, ok := e.cache.get(key); ok {
+		e.innerRows = rows
+		e.partial = !complete
+		return nil
+	}
+	e.streamInner()
+	return nil
+}
+
+// streamInner starts to read the inner rows from the inner executor, they're recorded to be cached.
+func (e *ApplyExec) streamInner() {
+	e.streaming, e.recording = true, true
+	e.innerRows, e.partial = nil, false
+	e.recordSize = int64(len(e.cacheKey))
+}
+
+func (e *ApplyExec) nextInner() (*Row, error) {
+	if e.cache == nil {
+		return e.innerExec.Next()
+	}
+	if !e.streaming {
+		if e.innerCursor < len(e.innerRows) {
+			row := e.innerRows[e.innerCursor]
+			e.innerCursor++
+			return row, nil
+		}
+		if !e.partial {
+			return nil, nil
+		}
+		// Only the leading inner rows are cached, which are only read partially by the checker, so the inner
+		// rows are read again from the first one, the ones already checked don't change the result.
+		e.streamInner()
+	}
+	row, err := e.innerExec.Next()
+	if err != nil {
+		e.recording = false
+		return nil, errors.Trace(err)
+	}
+	if row == nil {
+		e.streaming = false
+		if err = e.innerExec.Close(); err != nil {
+			return nil, errors.Trace(err)
+		}
+		if e.recording {
+			e.recording = false
+			err = e.cache.put(e.cacheKey, e.innerRows, true)
+		}
+		e.innerRows = nil
+		return nil, errors.Trace(err)
+	}
+	if e.recording {
+		e.recordSize += rowMemUsage(row)
+		if e.recordSize > e.cache.capacity {
+			e.recording = false
+			e.innerRows = nil
+		} else {
+			e.innerRows = append(e.innerRows, row)
+		}
+	}
+	return row, nil
+}
+
+func (e *ApplyExec) closeInner() error {
+	if e.cache == nil {
+		return e.innerExec.Close()
+	}
+	var err error
+	if e.streaming {
+		// The inner rows aren't read to the end, the ones read are cached as the leading inner rows.
+		e.streaming = false
+		err = e.innerExec.Close()
+		if err == nil && e.recording && len(e.innerRows) > 0 {
+			err = e.cache.put(e.cacheKey, e.innerRows, false)
+		}
+	}
+	e.recording = false
+	e.innerRows = nil
+	return errors.Trace(err)
+}
+
 // Next implements the Executor Next interface.
 func (e *ApplyExec) Next() (*Row, error) {
+	if e.lateral {
+		return e.nextLateral()
+	}
 	srcRow, err := e.Src.Next()
 	if err != nil {
 		return nil, errors.Trace(err)
@@ -1726,21 +3614,27 @@ func (e *ApplyExec) Next() (*Row, error) {
 	if srcRow == nil {
 		return nil, nil
 	}
+	for _, col := range e.outerSchema {
+		idx := col.Index
+		*col.Data = srcRow.Data[idx]
+	}
+	if err = e.openInner(); err != nil {
+		return nil, errors.Trace(err)
+	}
 	for {
-		for _, col := range e.outerSchema {
-			idx := col.Index
-			*col.Data = srcRow.Data[idx]
-		}
-		innerRow, err := e.innerExec.Next()
+		innerRow, err := e.nextInner()
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
 		trimLen := len(srcRow.Data)
 		if innerRow != nil {
 			srcRow.Data = append(srcRow.Data, innerRow.Data...)
+		} else if e.checker == nil {
+			// The inner plan proved to return at most one row has no MaxOneRow to fill the NULLs.
+			srcRow.Data = append(srcRow.Data, make([]types.Datum, len(e.innerExec.Schema()))...)
 		}
 		if e.checker == nil {
-			e.innerExec.Close()
+			e.closeInner()
 			return srcRow, nil
 		}
 		if innerRow == nil {
@@ -1758,7 +3652,7 @@ func (e *ApplyExec) Next() (*Row, error) {
 			}
 			srcRow.Data = append(srcRow.Data, result)
 			e.checker.reset()
-			e.innerExec.Close()
+			e.closeInner()
 			return srcRow, nil
 		}
 		finished, data, err := e.checker.check(srcRow.Data)
@@ -1768,13 +3662,52 @@ func (e *ApplyExec) Next() (*Row, error) {
 		srcRow.Data = srcRow.Data[:trimLen]
 		if finished {
 			e.checker.reset()
-			e.innerExec.Close()
+			e.closeInner()
 			srcRow.Data = append(srcRow.Data, data)
 			return srcRow, nil
 		}
 	}
 }
 
+// nextLateral returns the next Src row joined with an inner row.
+func (e *ApplyExec) nextLateral() (*Row, error) {
+	for {
+		if e.outerRow == nil {
+			srcRow, err := e.Src.Next()
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			if srcRow == nil {
+				return nil, nil
+			}
+			for _, col := range e.outerSchema {
+				*col.Data = srcRow.Data[col.Index]
+			}
+			if err = e.openInner(); err != nil {
+				return nil, errors.Trace(err)
+			}
+			e.outerRow = srcRow
+		}
+		innerRow, err := e.nextInner()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if innerRow == nil {
+			e.outerRow = nil
+			if err = e.closeInner(); err != nil {
+				return nil, errors.Trace(err)
+			}
+			continue
+		}
+		row := &Row{
+			Data:    make([]types.Datum, 0, len(e.outerRow.Data)+len(innerRow.Data)),
+			RowKeys: e.outerRow.RowKeys,
+		}
+		row.Data = append(append(row.Data, e.outerRow.Data...), innerRow.Data...)
+		return row, nil
+	}
+}
+
 // ExistsExec represents exists executor.
 type ExistsExec struct {
 	schema    expression.Schema
@@ -1883,14 +3816,40 @@ func (e *TrimExec) Next() (*Row, error) {
 }
 
 // UnionExec represents union executor.
-// UnionExec has multiple source Executors, it executes them sequentially, and do conversion to the same type
-// as source Executors may has different field type, we need to do conversion.
+// UnionExec has multiple source Executors, it executes them sequentially, and do conversion to the aggregated type
+// of the union as source Executors may has different field type, we need to do conversion.
+// If the concurrency is greater than 1, at most concurrency source Executors are executed concurrently by the
+// workers, the rows are still returned in the order of the source Executors.
 type UnionExec struct {
 	schema expression.Schema
 	Srcs   []Executor
+	ctx    context.Context
 	cursor int
+
+	concurrency int
+	started     bool
+	// Each worker sends the batches of the rows of its source Executor to its own channel, Next reads the channels
+	// in the order of the source Executors.
+	results  []chan unionResult
+	batch    []*Row
+	batchIdx int
+	finishCh chan struct{}
+	wg       sync.WaitGroup
+}
+
+// unionResult is a batch of the rows of a source Executor of UnionExec, or the error of it.
+type unionResult struct {
+	rows []*Row
+	err  error
 }
 
+const (
+	// unionBatchSize is the number of the rows of a batch sent by a union worker.
+	unionBatchSize = 128
+	// unionBatchBuffer is the number of the batches buffered by a union worker, before its rows are read.
+	unionBatchBuffer = 4
+)
+
 // Schema implements the Executor Schema interface.
 func (e *UnionExec) Schema() expression.Schema {
 	return e.schema
@@ -1898,6 +3857,9 @@ func (e *UnionExec) Schema() expression.Schema {
 
 // Next implements the Executor Next interface.
 func (e *UnionExec) Next() (*Row, error) {
+	if e.concurrency > 1 && len(e.Srcs) > 1 {
+		return e.nextConcurrent()
+	}
 	for {
 		if e.cursor >= len(e.Srcs) {
 			return nil, nil
@@ -1911,24 +3873,130 @@ func (e *UnionExec) Next() (*Row, error) {
 			e.cursor++
 			continue
 		}
-		if e.cursor != 0 {
-			for i := range row.Data {
-				// The column value should be casted as the same type of the first select statement in corresponding position.
-				col := e.schema[i]
-				var val types.Datum
-				val, err = row.Data[i].ConvertTo(col.RetType)
-				if err != nil {
-					return nil, errors.Trace(err)
-				}
-				row.Data[i] = val
-			}
+		if err = e.convertRow(row); err != nil {
+			return nil, errors.Trace(err)
 		}
 		return row, nil
 	}
 }
 
+// convertRow casts the column values as the aggregated types of the union.
+func (e *UnionExec) convertRow(row *Row) error {
+	for i := range row.Data {
+		// The column value should be casted as the aggregated type of the union in corresponding position.
+		col := e.schema[i]
+		val, err := row.Data[i].ConvertTo(col.RetType)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		row.Data[i] = val
+	}
+	return nil
+}
+
+func (e *UnionExec) nextConcurrent() (*Row, error) {
+	if !e.started {
+		// The transaction is begun before the workers use it.
+		if _, err := e.ctx.GetTxn(false); err != nil {
+			return nil, errors.Trace(err)
+		}
+		e.startWorkers()
+	}
+	for e.batchIdx >= len(e.batch) {
+		if e.cursor >= len(e.Srcs) {
+			return nil, nil
+		}
+		result, ok := <-e.results[e.cursor]
+		if !ok {
+			e.cursor++
+			continue
+		}
+		if result.err != nil {
+			return nil, errors.Trace(result.err)
+		}
+		e.batch, e.batchIdx = result.rows, 0
+	}
+	row := e.batch[e.batchIdx]
+	e.batchIdx++
+	return row, nil
+}
+
+// startWorkers starts the workers of the source Executors in order, at most concurrency of them run at the same
+// time. The worker of the source Executor being read is always running, so the workers blocked by their full
+// channels can't block the union.
+func (e *UnionExec) startWorkers() {
+	e.started = true
+	e.finishCh = make(chan struct{})
+	e.results = make([]chan unionResult, len(e.Srcs))
+	for i := range e.Srcs {
+		e.results[i] = make(chan unionResult, unionBatchBuffer)
+	}
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		tokens := make(chan struct{}, e.concurrency)
+		for i := range e.Srcs {
+			select {
+			case tokens <- struct{}{}:
+			case <-e.finishCh:
+				return
+			}
+			e.wg.Add(1)
+			go e.runWorker(i, tokens)
+		}
+	}()
+}
+
+// runWorker reads the rows of the i-th source Executor and sends them in batches.
+func (e *UnionExec) runWorker(i int, tokens chan struct{}) {
+	defer func() {
+		close(e.results[i])
+		<-tokens
+		e.wg.Done()
+	}()
+	send := func(result unionResult) bool {
+		select {
+		case e.results[i] <- result:
+			return true
+		case <-e.finishCh:
+			return false
+		}
+	}
+	rows := make([]*Row, 0, unionBatchSize)
+	for {
+		row, err := e.Srcs[i].Next()
+		if err == nil && row != nil {
+			err = e.convertRow(row)
+		}
+		if err != nil {
+			send(unionResult{err: errors.Trace(err)})
+			return
+		}
+		if row == nil {
+			if len(rows) > 0 {
+				send(unionResult{rows: rows})
+			}
+			return
+		}
+		rows = append(rows, row)
+		if len(rows) == unionBatchSize {
+			if !send(unionResult{rows: rows}) {
+				return
+			}
+			rows = make([]*Row, 0, unionBatchSize)
+		}
+	}
+}
+
 // Close implements the Executor Close interface.
 func (e *UnionExec) Close() error {
+	if e.started {
+		// The workers are stopped before the source Executors are closed.
+		close(e.finishCh)
+		e.wg.Wait()
+		e.started = false
+		e.results, e.batch, e.batchIdx = nil, nil, 0
+	}
 	e.cursor = 0
 	for _, sel := range e.Srcs {
 		er := sel.Close()
@@ -1960,6 +4028,102 @@ func (e *DummyScanExec) Next() (*Row, error) {
 	return nil, nil
 }
 
+// cteStorage stores the rows of a materialized common table expression. The rows are read from the source
+// executor on demand, so the references can be executed in any order, or concurrently, e.g. by a hash join.
+type cteStorage struct {
+	sync.Mutex
+	src  Executor
+	rows []*Row
+	done bool
+	mem  memoryUsage
+	// readers is the number of the references which are reading the rows. The source executor is closed when
+	// the last one is closed before all the rows are read, e.g. by a limit.
+	readers int
+}
+
+func (s *cteStorage) open() {
+	s.Lock()
+	s.readers++
+	s.Unlock()
+}
+
+// close closes the source executor if it's the last reader and the source executor isn't finished, the rows read
+// are discarded, they are read again from the beginning if the common table expression is read again.
+func (s *cteStorage) close() error {
+	s.Lock()
+	defer s.Unlock()
+	s.readers--
+	if s.readers > 0 || s.done {
+		return nil
+	}
+	s.rows = nil
+	s.mem.close()
+	return errors.Trace(s.src.Close())
+}
+
+// row returns the idx-th row of the common table expression, it returns nil if there are not so many rows.
+func (s *cteStorage) row(idx int) (*Row, error) {
+	s.Lock()
+	defer s.Unlock()
+	for idx >= len(s.rows) && !s.done {
+		row, err := s.src.Next()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if row == nil {
+			s.done = true
+			return nil, errors.Trace(s.src.Close())
+		}
+		if err = s.mem.consume(rowMemUsage(row)); err != nil {
+			return nil, errors.Trace(err)
+		}
+		s.rows = append(s.rows, row)
+	}
+	if idx >= len(s.rows) {
+		return nil, nil
+	}
+	return s.rows[idx], nil
+}
+
+// CTEExec represents a reference to a materialized common table expression.
+// All the references to the same common table expression read the rows from the same storage.
+type CTEExec struct {
+	schema  expression.Schema
+	storage *cteStorage
+	cursor  int
+	reading bool
+}
+
+// Schema implements the Executor Schema interface.
+func (e *CTEExec) Schema() expression.Schema {
+	return e.schema
+}
+
+// Next implements the Executor Next interface.
+func (e *CTEExec) Next() (*Row, error) {
+	if !e.reading {
+		e.storage.open()
+		e.reading = true
+	}
+	row, err := e.storage.row(e.cursor)
+	if err != nil || row == nil {
+		return nil, errors.Trace(err)
+	}
+	e.cursor++
+	return row, nil
+}
+
+// Close implements the Executor Close interface.
+// The stored rows are kept if all of them are read, so the reference can be read again, e.g. by an apply.
+func (e *CTEExec) Close() error {
+	e.cursor = 0
+	if !e.reading {
+		return nil
+	}
+	e.reading = false
+	return errors.Trace(e.storage.close())
+}
+
 // CacheExec represents Cache executor.
 // it stores the return values of the executor of its child node.
 type CacheExec struct {
@@ -14,11 +14,14 @@
 package executor
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/juju/errors"
+	"github.com/ngaut/log"
 	"github.com/pingcap/tidb/ast"
 	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/ddl"
 	"github.com/pingcap/tidb/expression"
 	"github.com/pingcap/tidb/infoschema"
 	"github.com/pingcap/tidb/model"
@@ -27,6 +30,7 @@ import (
 	"github.com/pingcap/tidb/sessionctx"
 	"github.com/pingcap/tidb/sessionctx/variable"
 	"github.com/pingcap/tidb/terror"
+	"github.com/pingcap/tidb/util/sqlexec"
 	"github.com/pingcap/tidb/util/types"
 )
 
@@ -57,6 +61,8 @@ func (e *DDLExec) Next() (*Row, error) {
 		err = e.executeCreateDatabase(x)
 	case *ast.CreateTableStmt:
 		err = e.executeCreateTable(x)
+	case *ast.CreateViewStmt:
+		err = e.executeCreateView(x)
 	case *ast.CreateIndexStmt:
 		err = e.executeCreateIndex(x)
 	case *ast.DropDatabaseStmt:
@@ -99,7 +105,7 @@ func (e *DDLExec) executeCreateDatabase(s *ast.CreateDatabaseStmt) error {
 			}
 		}
 	}
-	err := sessionctx.GetDomain(e.ctx).DDL().CreateSchema(e.ctx, model.NewCIStr(s.Name), opt)
+	err := sessionctx.GetDomain(e.ctx).DDL().CreateSchema(e.ctx, model.NewTableCIStr(s.Name), opt)
 	if err != nil {
 		if terror.ErrorEqual(err, infoschema.ErrDatabaseExists) && s.IfNotExists {
 			err = nil
@@ -110,7 +116,16 @@ func (e *DDLExec) executeCreateDatabase(s *ast.CreateDatabaseStmt) error {
 
 func (e *DDLExec) executeCreateTable(s *ast.CreateTableStmt) error {
 	ident := ast.Ident{Schema: s.Table.Schema, Name: s.Table.Name}
-	err := sessionctx.GetDomain(e.ctx).DDL().CreateTable(e.ctx, ident, s.Cols, s.Constraints, s.Options)
+	var err error
+	switch {
+	case s.ReferTable != nil:
+		referIdent := ast.Ident{Schema: s.ReferTable.Schema, Name: s.ReferTable.Name}
+		err = sessionctx.GetDomain(e.ctx).DDL().CreateTableWithLike(e.ctx, ident, referIdent)
+	case s.Select != nil:
+		err = e.executeCreateTableSelect(ident, s)
+	default:
+		err = sessionctx.GetDomain(e.ctx).DDL().CreateTable(e.ctx, ident, s.Cols, s.Constraints, s.Options)
+	}
 	if terror.ErrorEqual(err, infoschema.ErrTableExists) {
 		if s.IfNotExists {
 			return nil
@@ -120,6 +135,118 @@ func (e *DDLExec) executeCreateTable(s *ast.CreateTableStmt) error {
 	return errors.Trace(err)
 }
 
+// executeCreateTableSelect creates a table by the result columns of the select statement, and inserts the rows
+// of the select statement into it by the transactions which start after the table is created. The table is
+// dropped if the rows fail to be inserted, so either both of them or neither of them are done.
+func (e *DDLExec) executeCreateTableSelect(ident ast.Ident, s *ast.CreateTableStmt) error {
+	colDefs := ddl.ResultFieldsToColumnDefs(s.Select.GetResultFields())
+	d := sessionctx.GetDomain(e.ctx).DDL()
+	err := d.CreateTable(e.ctx, ident, colDefs, nil, s.Options)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	sql := fmt.Sprintf("INSERT INTO `%s`.`%s` %s", ident.Schema.O, ident.Name.O, s.Select.Text())
+	err = e.ctx.CommitTxn()
+	if err == nil {
+		// The rows are inserted in batches so a large result doesn't exceed the size limit of a transaction, the
+		// committed batches are dropped with the table if the rest fail.
+		sessVars := e.ctx.GetSessionVars()
+		batchInsert := sessVars.BatchInsert
+		sessVars.BatchInsert = true
+		_, err = e.ctx.(sqlexec.RestrictedSQLExecutor).ExecRestrictedSQL(e.ctx, sql)
+		sessVars.BatchInsert = batchInsert
+	}
+	if err == nil {
+		err = e.ctx.CommitTxn()
+	}
+	if err != nil {
+		if err1 := e.ctx.RollbackTxn(); err1 != nil {
+			log.Errorf("rollback the rows of CREATE TABLE %s ... SELECT failed, err: %v", ident, err1)
+		}
+		if err1 := d.DropTable(e.ctx, ident); err1 != nil {
+			log.Errorf("drop the table of CREATE TABLE %s ... SELECT failed, err: %v", ident, err1)
+		}
+		return errors.Trace(err)
+	}
+	return nil
+}
+
+func (e *DDLExec) executeCreateView(s *ast.CreateViewStmt) error {
+	ident := ast.Ident{Schema: s.ViewName.Schema, Name: s.ViewName.Name}
+	schema, ok := e.is.SchemaByName(ident.Schema)
+	if !ok {
+		return infoschema.ErrDatabaseNotExists.Gen("database %s not exists", ident.Schema)
+	}
+	// Check Privilege
+	privChecker := privilege.GetPrivilegeChecker(e.ctx)
+	hasPriv, err := privChecker.Check(e.ctx, schema, nil, mysql.CreateViewPriv)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if !hasPriv {
+		return errors.Errorf("You do not have the privilege to create view %s.%s.", ident.Schema, ident.Name)
+	}
+	// Replacing a view drops the old one.
+	if old, err1 := e.is.TableByName(ident.Schema, ident.Name); err1 == nil && s.OrReplace {
+		hasPriv, err = privChecker.Check(e.ctx, schema, old.Meta(), mysql.DropPriv)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if !hasPriv {
+			return errors.Errorf("You do not have the privilege to drop view %s.%s.", ident.Schema, ident.Name)
+		}
+	}
+	// The user who can query a view with SQL SECURITY DEFINER reads the tables as the definer, so the definer must be
+	// able to read them.
+	collector := &tableNameCollector{}
+	s.Select.Accept(collector)
+	for _, tn := range collector.tables {
+		if infoschema.IsMemoryDB(tn.Schema.L) {
+			continue
+		}
+		hasPriv, err = privChecker.Check(e.ctx, tn.DBInfo, tn.TableInfo, mysql.SelectPriv)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if !hasPriv {
+			return errors.Errorf("You do not have the privilege to select from table %s.%s.", tn.Schema, tn.Name)
+		}
+	}
+
+	rfs := s.Select.GetResultFields()
+	if len(s.Cols) > 0 && len(s.Cols) != len(rfs) {
+		return ErrViewWrongList.Gen("View's SELECT and view's field list have different column counts")
+	}
+	cols := ddl.ResultFieldsToViewColumns(rfs, s.Cols)
+	view := &model.ViewInfo{
+		SelectStmt: s.Select.Text(),
+		Definer:    e.ctx.GetSessionVars().User,
+		Security:   s.Security,
+	}
+	err = sessionctx.GetDomain(e.ctx).DDL().CreateView(e.ctx, ident, cols, view, s.OrReplace)
+	if terror.ErrorEqual(err, infoschema.ErrTableExists) {
+		return infoschema.ErrTableExists.Gen("CREATE VIEW: table exists %s", ident)
+	}
+	return errors.Trace(err)
+}
+
+// tableNameCollector collects the resolved table names read by a statement.
+type tableNameCollector struct {
+	tables []*ast.TableName
+}
+
+func (c *tableNameCollector) Enter(in ast.Node) (ast.Node, bool) {
+	return in, false
+}
+
+func (c *tableNameCollector) Leave(in ast.Node) (ast.Node, bool) {
+	if x, ok := in.(*ast.TableName); ok && x.TableInfo != nil {
+		c.tables = append(c.tables, x)
+	}
+	return in, true
+}
+
 func (e *DDLExec) executeCreateIndex(s *ast.CreateIndexStmt) error {
 	ident := ast.Ident{Schema: s.Table.Schema, Name: s.Table.Name}
 	err := sessionctx.GetDomain(e.ctx).DDL().CreateIndex(e.ctx, ident, s.Unique, model.NewCIStr(s.IndexName), s.IndexColNames)
@@ -127,7 +254,7 @@ func (e *DDLExec) executeCreateIndex(s *ast.CreateIndexStmt) error {
 }
 
 func (e *DDLExec) executeDropDatabase(s *ast.DropDatabaseStmt) error {
-	dbName := model.NewCIStr(s.Name)
+	dbName := model.NewTableCIStr(s.Name)
 	err := sessionctx.GetDomain(e.ctx).DDL().DropSchema(e.ctx, dbName)
 	if terror.ErrorEqual(err, infoschema.ErrDatabaseNotExists) {
 		if s.IfExists {
@@ -137,7 +264,7 @@ func (e *DDLExec) executeDropDatabase(s *ast.DropDatabaseStmt) error {
 		}
 	}
 	sessionVars := e.ctx.GetSessionVars()
-	if err == nil && strings.ToLower(sessionVars.CurrentDB) == dbName.L {
+	if err == nil && model.NewTableCIStr(sessionVars.CurrentDB).L == dbName.L {
 		sessionVars.CurrentDB = ""
 		err = sessionVars.SetSystemVar(variable.CharsetDatabase, types.NewStringDatum("utf8"))
 		if err != nil {
@@ -169,6 +296,14 @@ func (e *DDLExec) executeDropTable(s *ast.DropTableStmt) error {
 		} else if err != nil {
 			return errors.Trace(err)
 		}
+		// DROP TABLE doesn't drop the views, and DROP VIEW doesn't drop the base tables.
+		if tb.Meta().IsView() != s.IsView {
+			if s.IsView {
+				return infoschema.ErrWrongObject.Gen("'%s.%s' is not VIEW", tn.Schema, tn.Name)
+			}
+			notExistTables = append(notExistTables, fullti.String())
+			continue
+		}
 		// Check Privilege
 		privChecker := privilege.GetPrivilegeChecker(e.ctx)
 		hasPriv, err := privChecker.Check(e.ctx, schema, tb.Meta(), mysql.DropPriv)
@@ -187,6 +322,9 @@ func (e *DDLExec) executeDropTable(s *ast.DropTableStmt) error {
 		}
 	}
 	if len(notExistTables) > 0 && !s.IfExists {
+		if s.IsView {
+			return infoschema.ErrTableDropExists.Gen("DROP VIEW: view %s does not exist", strings.Join(notExistTables, ","))
+		}
 		return infoschema.ErrTableDropExists.Gen("DROP TABLE: table %s does not exist", strings.Join(notExistTables, ","))
 	}
 	return nil
@@ -17,6 +17,8 @@ import (
 	"fmt"
 
 	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/plan"
 	"github.com/pingcap/tidb/util/testkit"
 	"github.com/pingcap/tidb/util/testleak"
 )
@@ -130,6 +132,240 @@ func (s *testSuite) TestCreateDropTable(c *C) {
 	tk.MustExec("drop table drop_test")
 }
 
+func (s *testSuite) TestCreateTableLikeAndSelect(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists like_t, like_u, like_v, sel_t, sel_u, sel_bad")
+	tk.MustExec("create table like_t (a int primary key auto_increment, b int not null, c int, unique key uk(b), index idx(c)) comment = 'like'")
+	tk.MustExec("insert like_t (b, c) values (10, 1), (20, 2)")
+
+	// The definition is copied, but not the rows or the auto increment ID.
+	tk.MustExec("create table like_u like like_t")
+	tk.MustQuery("select count(*) from like_u").Check(testkit.Rows("0"))
+	tk.MustExec("insert like_u (b, c) values (30, 3)")
+	tk.MustQuery("select a, b, c from like_u").Check(testkit.Rows("1 30 3"))
+	_, err := tk.Exec("insert like_u (b, c) values (30, 4)")
+	c.Assert(err, NotNil)
+	tk.MustQuery("select b from like_u use index (idx) where c = 3").Check(testkit.Rows("30"))
+	tk.MustExec("create table if not exists like_u (like like_t)")
+	_, err = tk.Exec("create table like_u like like_t")
+	c.Assert(err, NotNil)
+	_, err = tk.Exec("create table like_v like like_none")
+	c.Assert(err, NotNil)
+
+	tk.MustExec("create table sel_t as select a, b as x, c + 1 as d from like_t where c > 1")
+	tk.MustQuery("select a, x, d from sel_t").Check(testkit.Rows("2 20 3"))
+	// The new table has no key, so the rows with the same values can be inserted.
+	tk.MustExec("insert sel_t values (2, 20, 3)")
+	tk.MustQuery("select count(*) from sel_t").Check(testkit.Rows("2"))
+	tk.MustExec("create table sel_u select like_t.b, like_u.c from like_t, like_u where like_t.b > 10")
+	tk.MustQuery("select b, c from sel_u").Check(testkit.Rows("20 3"))
+	tk.MustExec("create table if not exists sel_u select 1")
+	tk.MustQuery("select count(*) from sel_u").Check(testkit.Rows("1"))
+
+	// The table isn't created if the columns are invalid, and it's dropped if the rows fail to be inserted.
+	_, err = tk.Exec("create table sel_bad select a, a from like_t")
+	c.Assert(err, NotNil)
+	_, err = tk.Exec("create table sel_bad select a, (select b from like_t) as b from like_t")
+	c.Assert(err, NotNil)
+	_, err = tk.Exec("select * from sel_bad")
+	c.Assert(err, NotNil)
+
+	// The column types are large enough to hold the values of the expressions.
+	tk.MustExec("drop table if exists sel_v, sel_w")
+	tk.MustExec("create table sel_v (a int, b varchar(10), c decimal(10,3))")
+	tk.MustExec("insert sel_v values (7, 'abc', 1.125)")
+	tk.MustExec(`create table sel_w select a+c as s, c*2 as m, avg(a) as v, a/3 as d, round(c, 1) as r,
+		ifnull(a, c) as f, concat(b, 'x') as t, cast(a as char) as x, null as n from sel_v`)
+	tk.MustQuery("select s, m, v, d, r, f, t = 'abcx', x = '7', n from sel_w").Check(testkit.Rows("8.125 2.250 7.0000 2.3333 1.1 7.000 1 1 <nil>"))
+	tk.MustQuery("select column_type from information_schema.columns where table_name = 'sel_w' order by ordinal_position").Check(testkit.Rows(
+		"decimal(15,3)", "decimal(11,3)", "decimal(15,4)", "decimal(15,4)", "decimal(9,1)", "decimal(14,3)",
+		"varchar(11)", "varchar(11)", "binary(0)"))
+
+	// The rows are inserted in batches.
+	tk.MustExec("set @@session.tidb_dml_batch_size = 2")
+	tk.MustExec("insert sel_v values (1, 'a', 1), (2, 'b', 2), (3, 'c', 3), (4, 'd', 4)")
+	tk.MustExec("create table sel_bad select a from sel_v")
+	tk.MustQuery("select count(*) from sel_bad").Check(testkit.Rows("5"))
+	tk.MustExec("set @@session.tidb_dml_batch_size = 20000")
+	tk.MustExec("drop table like_t, like_u, sel_t, sel_u, sel_v, sel_w, sel_bad")
+}
+
+func (s *testSuite) TestCreateDropView(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists view_t, view_s")
+	tk.MustExec("create table view_t (a int, b int)")
+	tk.MustExec("create table view_s (a int, c int)")
+	tk.MustExec("insert view_t values (1, 10), (2, 20), (3, 30)")
+	tk.MustExec("insert view_s values (1, 100), (3, 300)")
+
+	tk.MustExec("create view view_v as select a, b from view_t where a > 1")
+	tk.MustQuery("select * from view_v").Check(testkit.Rows("2 20", "3 30"))
+	tk.MustQuery("select b from view_v where a = 3").Check(testkit.Rows("30"))
+	tk.MustQuery("select view_v.b, view_s.c from view_v join view_s on view_v.a = view_s.a").Check(testkit.Rows("30 300"))
+	tk.MustQuery("select x.a from view_v as x where x.b > 20").Check(testkit.Rows("3"))
+
+	// The column list renames the columns of the view.
+	tk.MustExec("create view view_w (x, y) as select a, b + 1 from view_t")
+	tk.MustQuery("select y from view_w where x = 1").Check(testkit.Rows("11"))
+	_, err := tk.Exec("create view view_bad (x) as select a, b from view_t")
+	c.Assert(err, NotNil)
+
+	// A view can be built on another view.
+	tk.MustExec("create view view_n as select x from view_w where y > 11")
+	tk.MustQuery("select * from view_n").Check(testkit.Rows("2", "3"))
+
+	_, err = tk.Exec("create view view_v as select 1")
+	c.Assert(err, NotNil)
+	_, err = tk.Exec("create view view_t as select 1")
+	c.Assert(err, NotNil)
+	_, err = tk.Exec("create or replace view view_t as select 1")
+	c.Assert(err, NotNil)
+	tk.MustExec("create or replace view view_v as select a from view_t where a < 2")
+	tk.MustQuery("select * from view_v").Check(testkit.Rows("1"))
+
+	// A view can't be written.
+	_, err = tk.Exec("insert view_v values (4)")
+	c.Assert(err, NotNil)
+	_, err = tk.Exec("delete from view_v")
+	c.Assert(err, NotNil)
+
+	tk.MustQuery("show full tables like 'view_v'").Check(testkit.Rows("view_v VIEW"))
+	tk.MustQuery("show create table view_w").Check(testkit.Rows(
+		"view_w CREATE VIEW `view_w` (`x`,`y`) AS select a, b + 1 from view_t"))
+	tk.MustQuery("select table_name, view_definition from information_schema.views where table_schema = 'test' and table_name = 'view_v'").Check(
+		testkit.Rows("view_v select a from view_t where a < 2"))
+	tk.MustQuery("select table_type from information_schema.tables where table_schema = 'test' and table_name = 'view_v'").Check(
+		testkit.Rows("VIEW"))
+
+	// The view becomes invalid when its base table changes.
+	tk.MustExec("create view view_star as select * from view_s")
+	tk.MustExec("alter table view_s add column d int")
+	_, err = tk.Exec("select * from view_star")
+	c.Assert(err, NotNil)
+
+	_, err = tk.Exec("drop view view_t")
+	c.Assert(err, NotNil)
+	_, err = tk.Exec("drop table view_v")
+	c.Assert(err, NotNil)
+	tk.MustExec("drop view view_v, view_w, view_n, view_star")
+	tk.MustExec("drop view if exists view_v")
+	tk.MustExec("drop table view_t, view_s")
+}
+
+func (s *testSuite) TestViewPrivileges(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists view_p")
+	tk.MustExec("create table view_p (a int, b int, c int)")
+	tk.MustExec("insert view_p values (1, 10, 100)")
+	tk.MustExec("create user 'view_definer'@'localhost', 'view_reader'@'localhost', 'view_other'@'localhost'")
+	tk.MustExec("grant create view on test.* to 'view_definer'@'localhost'")
+	tk.MustExec("grant select on test.view_p to 'view_definer'@'localhost'")
+	// The privileges are loaded once for a session, so a new session sees the changes of the privileges.
+	newSession := func(user string) *testkit.TestKit {
+		tk := testkit.NewTestKit(c, s.store)
+		tk.MustExec("use test")
+		tk.Se.(context.Context).GetSessionVars().User = user
+		return tk
+	}
+	tkd := newSession("view_definer@localhost")
+	tkd.MustExec("create view view_pv as select a, b from view_p")
+	tkd.MustExec("create view view_pn as select b from view_pv")
+	tk.MustExec("grant select on test.view_pv to 'view_reader'@'localhost'")
+	tk.MustExec("grant select on test.view_pn to 'view_reader'@'localhost'")
+	// REVOKE isn't supported yet.
+	tk.MustExec("update mysql.tables_priv set table_priv = '' where user = 'view_definer'")
+
+	// The reader reads the tables of the view with the privileges of the definer, down to the columns.
+	tk.MustExec("grant select (a) on test.view_p to 'view_definer'@'localhost'")
+	_, err := newSession("view_reader@localhost").Exec("select * from view_pv")
+	c.Assert(plan.ErrViewInvalid.Equal(err), IsTrue, Commentf("err %v", err))
+	tk.MustExec("grant select (b) on test.view_p to 'view_definer'@'localhost'")
+	tkr := newSession("view_reader@localhost")
+	tkr.MustQuery("select * from view_pv").Check(testkit.Rows("1 10"))
+	// The nested view is read by the definer of the outer view.
+	_, err = tkr.Exec("select * from view_pn")
+	c.Assert(plan.ErrViewInvalid.Equal(err), IsTrue, Commentf("err %v", err))
+	tk.MustExec("grant select on test.view_pv to 'view_definer'@'localhost'")
+	newSession("view_reader@localhost").MustQuery("select * from view_pn").Check(testkit.Rows("10"))
+	_, err = newSession("view_other@localhost").Exec("select * from view_pv")
+	c.Assert(err, NotNil)
+
+	// The prepared statement expands the view again after the view is replaced.
+	tk.MustExec("prepare stmt_pv from 'select * from view_pv'")
+	tk.MustQuery("execute stmt_pv").Check(testkit.Rows("1 10"))
+	tk.MustExec("create or replace view view_pv (a, b) as select b, a from view_p")
+	tk.MustQuery("execute stmt_pv").Check(testkit.Rows("10 1"))
+	tk.MustQuery("execute stmt_pv").Check(testkit.Rows("10 1"))
+
+	tk.MustExec("drop user 'view_definer'@'localhost', 'view_reader'@'localhost', 'view_other'@'localhost'")
+	tk.MustExec("drop view view_pv, view_pn")
+	tk.MustExec("drop table view_p")
+}
+
+func (s *testSuite) TestViewSecurity(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists view_s")
+	tk.MustExec("create table view_s (a int)")
+	tk.MustExec("insert view_s values (1)")
+	tk.MustExec("create user 'view_sec_definer'@'localhost', 'view_sec_reader'@'localhost'")
+	tk.MustExec("grant select on test.view_s to 'view_sec_definer'@'localhost'")
+	newSession := func(user string) *testkit.TestKit {
+		tk := testkit.NewTestKit(c, s.store)
+		tk.MustExec("use test")
+		tk.Se.(context.Context).GetSessionVars().User = user
+		return tk
+	}
+
+	// CREATE VIEW needs the CREATE VIEW privilege, and replacing a view needs the DROP privilege.
+	_, err := newSession("view_sec_definer@localhost").Exec("create view view_sd as select a from view_s")
+	c.Assert(err, NotNil)
+	tk.MustExec("grant create view on test.* to 'view_sec_definer'@'localhost'")
+	tkd := newSession("view_sec_definer@localhost")
+	tkd.MustExec("create view view_sd as select a from view_s")
+	tkd.MustExec("create sql security invoker view view_si as select a from view_s")
+	_, err = tkd.Exec("create or replace view view_sd as select a from view_s")
+	c.Assert(err, NotNil)
+	tk.MustExec("grant select on test.view_sd to 'view_sec_reader'@'localhost'")
+	tk.MustExec("grant select on test.view_si to 'view_sec_reader'@'localhost'")
+
+	// The view with SQL SECURITY INVOKER reads the tables with the privileges of the reader.
+	tkr := newSession("view_sec_reader@localhost")
+	tkr.MustQuery("select * from view_sd").Check(testkit.Rows("1"))
+	_, err = tkr.Exec("select * from view_si")
+	c.Assert(plan.ErrViewInvalid.Equal(err), IsTrue, Commentf("err %v", err))
+	// The plan and the definition of a view are only shown to the users who can show the view and read its tables.
+	_, err = tkr.Exec("explain select * from view_sd")
+	c.Assert(plan.ErrViewNoExplain.Equal(err), IsTrue, Commentf("err %v", err))
+	rs, err := tkr.Exec("show create table view_sd")
+	c.Assert(err, IsNil)
+	_, err = rs.Next()
+	c.Assert(err, NotNil)
+	rs.Close()
+	tkr.MustQuery("select view_definition, security_type from information_schema.views where table_name like 'view_s_' order by table_name").
+		Check(testkit.Rows(" DEFINER", " INVOKER"))
+	newSession("view_sec_definer@localhost").MustQuery("select view_definition from information_schema.views where table_name = 'view_sd'").
+		Check(testkit.Rows("select a from view_s"))
+
+	tk.MustExec("grant show view on test.* to 'view_sec_reader'@'localhost'")
+	tk.MustExec("grant select on test.view_s to 'view_sec_reader'@'localhost'")
+	tkr = newSession("view_sec_reader@localhost")
+	tkr.MustQuery("select * from view_si").Check(testkit.Rows("1"))
+	tkr.MustExec("explain select * from view_sd")
+	tkr.MustQuery("show create table view_si").Check(testkit.Rows("view_si CREATE SQL SECURITY INVOKER VIEW `view_si` (`a`) AS select a from view_s"))
+
+	tk.MustExec("drop user 'view_sec_definer'@'localhost', 'view_sec_reader'@'localhost'")
+	tk.MustExec("drop view view_sd, view_si")
+	tk.MustExec("drop table view_s")
+}
+
 func (s *testSuite) TestCreateDropIndex(c *C) {
 	defer testleak.AfterTest(c)()
 	tk := testkit.NewTestKit(c, s.store)
@@ -33,6 +33,7 @@ import (
 	"github.com/pingcap/tidb/sessionctx/variable"
 	"github.com/pingcap/tidb/table"
 	"github.com/pingcap/tidb/tablecodec"
+	"github.com/pingcap/tidb/util/chunk"
 	"github.com/pingcap/tidb/util/codec"
 	"github.com/pingcap/tidb/util/types"
 	"github.com/pingcap/tipb/go-tipb"
@@ -40,6 +41,9 @@ import (
 
 const defaultConcurrency int = 10
 
+// defaultIndexLookupSize is the max number of the handles of a lookup table task if tidb_index_lookup_size is unset.
+const defaultIndexLookupSize = 20480
+
 func resultRowToRow(t table.Table, h int64, data []types.Datum, tableAsName *model.CIStr) *Row {
 	entry := &RowKeyEntry{
 		Handle:      h,
@@ -49,12 +53,10 @@ func resultRowToRow(t table.Table, h int64, data []types.Datum, tableAsName *mod
 	return &Row{Data: data, RowKeys: []*RowKeyEntry{entry}}
 }
 
-// BaseLookupTableTaskSize represents base number of handles for a lookupTableTask.
+// BaseLookupTableTaskSize represents base number of handles for a lookupTableTask, the size of the tasks doubles up
+// to tidb_index_lookup_size.
 var BaseLookupTableTaskSize = 1024
 
-// MaxLookupTableTaskSize represents max number of handles for a lookupTableTask.
-var MaxLookupTableTaskSize = 20480
-
 // lookupTableTask is created from a partial result of an index request which
 // contains the handles in those index keys.
 type lookupTableTask struct {
@@ -189,6 +191,10 @@ func convertIndexRangeTypes(ran *plan.IndexRange, fieldTypes []*types.FieldType)
 			ran.LowVal[i].SetBytes([]byte{})
 			continue
 		}
+		if isEnumOrSetType(fieldTypes[i].Tp) {
+			// The ranges of an enum or set column are built by its numeric values, see plan.rangeBuilder.
+			continue
+		}
 		converted, err := ran.LowVal[i].ConvertTo(fieldTypes[i])
 		if err != nil {
 			return errors.Trace(err)
@@ -215,7 +221,7 @@ func convertIndexRangeTypes(ran *plan.IndexRange, fieldTypes []*types.FieldType)
 		break
 	}
 	for i := range ran.HighVal {
-		if ran.HighVal[i].Kind() == types.KindMaxValue {
+		if ran.HighVal[i].Kind() == types.KindMaxValue || isEnumOrSetType(fieldTypes[i].Tp) {
 			continue
 		}
 		converted, err := ran.HighVal[i].ConvertTo(fieldTypes[i])
@@ -317,8 +323,8 @@ func closeAll(objs ...Closeable) error {
 // by kv.Client, we only need to pass the concurrency parameter.
 //
 // We also make a higher level of concurrency by doing index request in a background goroutine. The index goroutine
-// starts multple worker goroutines and fetches handles from each index partial request, builds lookup table tasks
-// and sends the task to 'workerCh'.
+// starts tidb_index_lookup_concurrency worker goroutines and fetches handles from each index partial request, builds
+// lookup table tasks of at most tidb_index_lookup_size handles and sends the task to 'workerCh'.
 //
 // Each worker goroutine receives tasks through the 'workerCh', then executes the task.
 // After finishing the task, the workers send the task to a taskChan. At the outer most Executor.Next method,
@@ -360,6 +366,14 @@ type XSelectIndexExec struct {
 	aggregate bool
 
 	scanConcurrency int
+	// lookupConcurrency is the number of the workers which execute the lookup table tasks, lookupSize is the max
+	// number of the handles of a task.
+	lookupConcurrency int
+	lookupSize        int
+
+	// filter is the conditions of the selection above which can't be pushed down, it's evaluated by the workers
+	// on the rows read from the table.
+	filter lazyFilter
 }
 
 // Schema implements Exec Schema interface.
@@ -393,12 +407,61 @@ func (e *XSelectIndexExec) Next() (*Row, error) {
 	return e.nextForDoubleRead()
 }
 
+func (e *XSelectIndexExec) supportChunk() bool {
+	// The rows of the aggregation pushed down are the partial results, which don't match the schema.
+	return !e.aggregate
+}
+
+// NextChunk implements the chunkExecutor NextChunk interface.
+func (e *XSelectIndexExec) NextChunk(chk *chunk.Chunk) error {
+	for !chk.IsFull() {
+		if e.indexPlan.LimitCount != nil && e.returnedRows >= uint64(*e.indexPlan.LimitCount) {
+			return nil
+		}
+		e.returnedRows++
+		var rowData []types.Datum
+		if e.singleReadMode {
+			_, data, err := e.nextDataForSingleRead()
+			if err != nil {
+				return errors.Trace(err)
+			}
+			rowData = data
+		} else {
+			row, err := e.nextForDoubleRead()
+			if err != nil {
+				return errors.Trace(err)
+			}
+			if row != nil {
+				rowData = row.Data
+			}
+		}
+		if rowData == nil {
+			return nil
+		}
+		chk.AppendDatums(rowData)
+	}
+	return nil
+}
+
 func (e *XSelectIndexExec) nextForSingleRead() (*Row, error) {
+	h, rowData, err := e.nextDataForSingleRead()
+	if err != nil || rowData == nil {
+		return nil, errors.Trace(err)
+	}
+	if e.aggregate {
+		return &Row{Data: rowData}, nil
+	}
+	return resultRowToRow(e.table, h, rowData, e.asName), nil
+}
+
+// nextDataForSingleRead returns the handle and the data of the next row read from the index, the data is nil if
+// there is no more row.
+func (e *XSelectIndexExec) nextDataForSingleRead() (int64, []types.Datum, error) {
 	if e.result == nil {
 		var err error
 		e.result, err = e.doIndexRequest()
 		if err != nil {
-			return nil, errors.Trace(err)
+			return 0, nil, errors.Trace(err)
 		}
 		if e.aggregate {
 			// The returned rows should be aggregate partial result.
@@ -412,17 +475,17 @@ func (e *XSelectIndexExec) nextForSingleRead() (*Row, error) {
 			var err error
 			e.partialResult, err = e.result.Next()
 			if err != nil {
-				return nil, errors.Trace(err)
+				return 0, nil, errors.Trace(err)
 			}
 			if e.partialResult == nil {
 				// Finished.
-				return nil, nil
+				return 0, nil, nil
 			}
 		}
 		// Get a row from partial result.
 		h, rowData, err := e.partialResult.Next()
 		if err != nil {
-			return nil, errors.Trace(err)
+			return 0, nil, errors.Trace(err)
 		}
 		if rowData == nil {
 			// Finish current partial result and get the next one.
@@ -430,10 +493,9 @@ func (e *XSelectIndexExec) nextForSingleRead() (*Row, error) {
 			continue
 		}
 		if e.aggregate {
-			return &Row{Data: rowData}, nil
+			return h, rowData, nil
 		}
-		rowData = e.indexRowToTableRow(h, rowData)
-		return resultRowToRow(e.table, h, rowData, e.asName), nil
+		return h, e.indexRowToTableRow(h, rowData), nil
 	}
 }
 
@@ -490,25 +552,18 @@ func (e *XSelectIndexExec) nextForDoubleRead() (*Row, error) {
 	}
 }
 
-const concurrencyLimit int = 30
-
-// addWorker adds a worker for lookupTableTask.
-// It's not thread-safe and should be called in fetchHandles goroutine only.
-func addWorker(e *XSelectIndexExec, ch chan *lookupTableTask, concurrency *int) {
-	if *concurrency <= concurrencyLimit {
-		go e.pickAndExecTask(ch)
-		*concurrency = *concurrency + 1
-	}
-}
-
 func (e *XSelectIndexExec) fetchHandles(idxResult distsql.SelectResult, ch chan<- *lookupTableTask) {
 	defer close(ch)
 
-	workCh := make(chan *lookupTableTask, 1)
+	concurrency := e.lookupConcurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	workCh := make(chan *lookupTableTask, concurrency)
 	defer close(workCh)
-
-	var concurrency int
-	addWorker(e, workCh, &concurrency)
+	for i := 0; i < concurrency; i++ {
+		go e.pickAndExecTask(workCh)
+	}
 
 	totalHandles := 0
 	startTs := time.Now()
@@ -528,16 +583,7 @@ func (e *XSelectIndexExec) fetchHandles(idxResult distsql.SelectResult, ch chan<
 		totalHandles += len(handles)
 		tasks := e.buildTableTasks(handles)
 		for _, task := range tasks {
-			if concurrency < len(tasks) {
-				addWorker(e, workCh, &concurrency)
-			}
-
-			select {
-			case workCh <- task:
-			default:
-				addWorker(e, workCh, &concurrency)
-				workCh <- task
-			}
+			workCh <- task
 			ch <- task
 		}
 	}
@@ -586,23 +632,29 @@ func (e *XSelectIndexExec) doIndexRequest() (distsql.SelectResult, error) {
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
-	return distsql.Select(e.ctx.GetClient(), selIdxReq, keyRanges, concurrency, !e.indexPlan.OutOfOrder)
+	return distsql.Select(e.ctx.GetClient(), selIdxReq, keyRanges, concurrency, !e.indexPlan.OutOfOrder,
+		e.ctx.GetSessionVars().Killed)
 }
 
 func (e *XSelectIndexExec) buildTableTasks(handles []int64) []*lookupTableTask {
 	// Build tasks with increasing batch size.
 	var taskSizes []int
 	total := len(handles)
+	maxSize := e.lookupSize
+	if maxSize < 1 {
+		maxSize = defaultIndexLookupSize
+	}
 	batchSize := BaseLookupTableTaskSize
 	for total > 0 {
+		if batchSize > maxSize {
+			batchSize = maxSize
+		}
 		if batchSize > total {
 			batchSize = total
 		}
 		taskSizes = append(taskSizes, batchSize)
 		total -= batchSize
-		if batchSize < MaxLookupTableTaskSize {
-			batchSize *= 2
-		}
+		batchSize *= 2
 	}
 
 	var indexOrder map[int64]int
@@ -685,7 +737,16 @@ func (e *XSelectIndexExec) extractRowsFromTableResult(t table.Table, tblResult d
 func (e *XSelectIndexExec) extractRowsFromPartialResult(t table.Table, partialResult distsql.PartialResult) ([]*Row, error) {
 	var rows []*Row
 	for {
-		h, rowData, err := partialResult.Next()
+		var (
+			h       int64
+			rowData []types.Datum
+			err     error
+		)
+		if e.filter.enabled() {
+			h, rowData, err = e.filter.next(partialResult, e.ctx)
+		} else {
+			h, rowData, err = partialResult.Next()
+		}
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
@@ -716,7 +777,8 @@ func (e *XSelectIndexExec) doTableRequest(handles []int64) (distsql.SelectResult
 	selTableReq.GroupBy = e.byItems
 	keyRanges := tableHandlesToKVRanges(e.table.Meta().ID, handles)
 
-	resp, err := distsql.Select(e.ctx.GetClient(), selTableReq, keyRanges, e.scanConcurrency, false)
+	resp, err := distsql.Select(e.ctx.GetClient(), selTableReq, keyRanges, e.scanConcurrency, false,
+		e.ctx.GetSessionVars().Killed)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -728,6 +790,149 @@ func (e *XSelectIndexExec) doTableRequest(handles []int64) (distsql.SelectResult
 	return resp, nil
 }
 
+// XSelectIndexMergeExec represents the DistSQL index merge executor.
+// It reads the handles from the index of every partial plan one by one and removes the duplicated ones, then reads
+// the rows of these handles from the table by a single table request in the handle order.
+type XSelectIndexMergeExec struct {
+	table   table.Table
+	asName  *model.CIStr
+	ctx     context.Context
+	startTS uint64
+	schema  expression.Schema
+	columns []*model.ColumnInfo
+	where   *tipb.Expr
+
+	// partialExecs are only used to do the index requests.
+	partialExecs []*XSelectIndexExec
+
+	result        distsql.SelectResult
+	partialResult distsql.PartialResult
+	// finished means there is no handle read from the indices, so the table request isn't sent.
+	finished bool
+
+	scanConcurrency int
+}
+
+// Schema implements the Executor Schema interface.
+func (e *XSelectIndexMergeExec) Schema() expression.Schema {
+	return e.schema
+}
+
+// Close implements the Executor Close interface.
+func (e *XSelectIndexMergeExec) Close() error {
+	err := closeAll(e.result, e.partialResult)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	e.result = nil
+	e.partialResult = nil
+	e.finished = false
+	return nil
+}
+
+// Next implements the Executor Next interface.
+func (e *XSelectIndexMergeExec) Next() (*Row, error) {
+	if e.finished {
+		return nil, nil
+	}
+	if e.result == nil {
+		handles, err := e.fetchHandles()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if len(handles) == 0 {
+			e.finished = true
+			return nil, nil
+		}
+		err = e.doTableRequest(handles)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	for {
+		// Get partial result.
+		if e.partialResult == nil {
+			var err error
+			e.partialResult, err = e.result.Next()
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			if e.partialResult == nil {
+				// Finished.
+				return nil, nil
+			}
+		}
+		// Get a row from partial result.
+		h, rowData, err := e.partialResult.Next()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if rowData == nil {
+			// Finish the current partial result and get the next one.
+			e.partialResult = nil
+			continue
+		}
+		return resultRowToRow(e.table, h, rowData, e.asName), nil
+	}
+}
+
+// fetchHandles returns the sorted union of the handles read from the indices.
+func (e *XSelectIndexMergeExec) fetchHandles() ([]int64, error) {
+	handleSet := make(map[int64]struct{})
+	for _, partialExec := range e.partialExecs {
+		idxResult, err := partialExec.doIndexRequest()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		idxResult.IgnoreData()
+		idxResult.Fetch()
+		for {
+			handles, finish, err := extractHandlesFromIndexResult(idxResult)
+			if err != nil {
+				idxResult.Close()
+				return nil, errors.Trace(err)
+			}
+			if finish {
+				break
+			}
+			for _, h := range handles {
+				handleSet[h] = struct{}{}
+			}
+		}
+		err = idxResult.Close()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	handles := make([]int64, 0, len(handleSet))
+	for h := range handleSet {
+		handles = append(handles, h)
+	}
+	sort.Sort(int64Slice(handles))
+	return handles, nil
+}
+
+func (e *XSelectIndexMergeExec) doTableRequest(handles []int64) error {
+	selTableReq := new(tipb.SelectRequest)
+	selTableReq.StartTs = e.startTS
+	selTableReq.TimeZoneOffset = proto.Int64(timeZoneOffset())
+	selTableReq.TableInfo = &tipb.TableInfo{
+		TableId: e.table.Meta().ID,
+		Columns: distsql.ColumnsToProto(e.columns, e.table.Meta().PKIsHandle),
+	}
+	selTableReq.Where = e.where
+	keyRanges := tableHandlesToKVRanges(e.table.Meta().ID, handles)
+	var err error
+	// Keep the handle order, so the rows can be merged with the dirty rows of the transaction by UnionScanExec.
+	e.result, err = distsql.Select(e.ctx.GetClient(), selTableReq, keyRanges, e.scanConcurrency, true,
+		e.ctx.GetSessionVars().Killed)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	e.result.Fetch()
+	return nil
+}
+
 // XSelectTableExec represents the DistSQL select table executor.
 // Its execution is pushed down to KV layer.
 type XSelectTableExec struct {
@@ -766,9 +971,82 @@ type XSelectTableExec struct {
 	aggFields []*types.FieldType
 	aggregate bool
 
+	// filter is the conditions of the selection above which can't be pushed down.
+	filter lazyFilter
+
 	scanConcurrency int
 }
 
+// lazyFilter evaluates the conditions of a selection on the lazily decoded rows of a partial result, so a column of
+// the rows filtered out is decoded only if a condition reads it.
+type lazyFilter struct {
+	conditions []expression.Expression
+	// columns are the offsets of the columns read by each condition.
+	columns [][]int
+}
+
+func newLazyFilter(conditions []expression.Expression) lazyFilter {
+	f := lazyFilter{conditions: conditions, columns: make([][]int, len(conditions))}
+	for i, cond := range conditions {
+		f.columns[i] = columnOffsets(cond, nil)
+	}
+	return f
+}
+
+func (f *lazyFilter) enabled() bool {
+	return len(f.conditions) > 0
+}
+
+// next returns the next row of the partial result which matches the conditions. The conditions are evaluated in
+// order, each of them decodes the columns it reads, the other columns are decoded only if the row matches. The
+// data is nil if there is no more row.
+func (f *lazyFilter) next(pr distsql.PartialResult, ctx context.Context) (int64, []types.Datum, error) {
+	for {
+		h, row, err := pr.NextLazy()
+		if err != nil || row == nil {
+			return 0, nil, errors.Trace(err)
+		}
+		match := true
+		for i, cond := range f.conditions {
+			data, err := row.DecodeColumns(f.columns[i])
+			if err != nil {
+				return 0, nil, errors.Trace(err)
+			}
+			match, err = expression.EvalBool(cond, data, ctx)
+			if err != nil {
+				return 0, nil, errors.Trace(err)
+			}
+			if !match {
+				break
+			}
+		}
+		if !match {
+			continue
+		}
+		data, err := row.Datums()
+		if err != nil {
+			return 0, nil, errors.Trace(err)
+		}
+		if data == nil {
+			// A nil row means the partial result is finished.
+			data = []types.Datum{}
+		}
+		return h, data, nil
+	}
+}
+
+func columnOffsets(expr expression.Expression, offsets []int) []int {
+	switch x := expr.(type) {
+	case *expression.Column:
+		offsets = append(offsets, x.Index)
+	case *expression.ScalarFunction:
+		for _, arg := range x.Args {
+			offsets = columnOffsets(arg, offsets)
+		}
+	}
+	return offsets
+}
+
 // Schema implements the Executor Schema interface.
 func (e *XSelectTableExec) Schema() expression.Schema {
 	return e.schema
@@ -797,7 +1075,8 @@ func (e *XSelectTableExec) doRequest() error {
 
 	kvRanges := tableRangesToKVRanges(e.table.Meta().ID, e.ranges)
 	concurrency := e.scanConcurrency
-	e.result, err = distsql.Select(e.ctx.GetClient(), selReq, kvRanges, concurrency, e.keepOrder)
+	e.result, err = distsql.Select(e.ctx.GetClient(), selReq, kvRanges, concurrency, e.keepOrder,
+		e.ctx.GetSessionVars().Killed)
 	if err != nil {
 		return errors.Trace(err)
 	}
@@ -824,13 +1103,43 @@ func (e *XSelectTableExec) Close() error {
 
 // Next implements the Executor interface.
 func (e *XSelectTableExec) Next() (*Row, error) {
+	h, rowData, err := e.nextData()
+	if err != nil || rowData == nil {
+		return nil, errors.Trace(err)
+	}
+	if e.aggregate {
+		// compose aggreagte row
+		return &Row{Data: rowData}, nil
+	}
+	return resultRowToRow(e.table, h, rowData, e.asName), nil
+}
+
+func (e *XSelectTableExec) supportChunk() bool {
+	// The rows of the aggregation pushed down are the partial results, which don't match the schema.
+	return !e.aggregate
+}
+
+// NextChunk implements the chunkExecutor NextChunk interface.
+func (e *XSelectTableExec) NextChunk(chk *chunk.Chunk) error {
+	for !chk.IsFull() {
+		_, rowData, err := e.nextData()
+		if err != nil || rowData == nil {
+			return errors.Trace(err)
+		}
+		chk.AppendDatums(rowData)
+	}
+	return nil
+}
+
+// nextData returns the handle and the data of the next row, the data is nil if there is no more row.
+func (e *XSelectTableExec) nextData() (int64, []types.Datum, error) {
 	if e.limitCount != nil && e.returnedRows >= uint64(*e.limitCount) {
-		return nil, nil
+		return 0, nil, nil
 	}
 	if e.result == nil {
 		err := e.doRequest()
 		if err != nil {
-			return nil, errors.Trace(err)
+			return 0, nil, errors.Trace(err)
 		}
 	}
 	for {
@@ -840,11 +1149,11 @@ func (e *XSelectTableExec) Next() (*Row, error) {
 			startTs := time.Now()
 			e.partialResult, err = e.result.Next()
 			if err != nil {
-				return nil, errors.Trace(err)
+				return 0, nil, errors.Trace(err)
 			}
 			if e.partialResult == nil {
 				// Finished.
-				return nil, nil
+				return 0, nil, nil
 			}
 			duration := time.Since(startTs)
 			connID := e.ctx.GetSessionVars().ConnectionID
@@ -855,9 +1164,18 @@ func (e *XSelectTableExec) Next() (*Row, error) {
 			}
 		}
 		// Get a row from partial result.
-		h, rowData, err := e.partialResult.Next()
+		var (
+			h       int64
+			rowData []types.Datum
+			err     error
+		)
+		if e.filter.enabled() {
+			h, rowData, err = e.filter.next(e.partialResult, e.ctx)
+		} else {
+			h, rowData, err = e.partialResult.Next()
+		}
 		if err != nil {
-			return nil, errors.Trace(err)
+			return 0, nil, errors.Trace(err)
 		}
 		if rowData == nil {
 			// Finish the current partial result and get the next one.
@@ -865,11 +1183,7 @@ func (e *XSelectTableExec) Next() (*Row, error) {
 			continue
 		}
 		e.returnedRows++
-		if e.aggregate {
-			// compose aggreagte row
-			return &Row{Data: rowData}, nil
-		}
-		return resultRowToRow(e.table, h, rowData, e.asName), nil
+		return h, rowData, nil
 	}
 }
 
@@ -14,9 +14,19 @@
 package executor
 
 import (
+	"time"
+
 	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/expression"
 	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/meta"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/store/localstore"
+	"github.com/pingcap/tidb/store/localstore/goleveldb"
+	"github.com/pingcap/tidb/table"
 	"github.com/pingcap/tidb/tablecodec"
+	"github.com/pingcap/tidb/util/mock"
+	"github.com/pingcap/tidb/util/types"
 )
 
 var _ = Suite(&testExecSuite{})
@@ -61,3 +71,264 @@ func (s *testExecSuite) TestMergeHandles(c *C) {
 		c.Assert(kr.EndKey, DeepEquals, ekr.EndKey)
 	}
 }
+
+type mockLoadReporter struct {
+	latency time.Duration
+	pending int64
+	// loads is the number of calls of Load.
+	loads int
+	// recoverAfter makes the storage not busy after the given calls of Load if it's positive.
+	recoverAfter int
+}
+
+func (r *mockLoadReporter) Load() (time.Duration, int64) {
+	r.loads++
+	if r.recoverAfter > 0 && r.loads > r.recoverAfter {
+		return 0, 0
+	}
+	return r.latency, r.pending
+}
+
+func (s *testExecSuite) TestAnalyzeThrottle(c *C) {
+	defer func(interval, maxPause time.Duration) {
+		analyzePauseInterval, analyzeMaxPause = interval, maxPause
+	}(analyzePauseInterval, analyzeMaxPause)
+	analyzePauseInterval = time.Millisecond
+	analyzeMaxPause = 5 * time.Millisecond
+	ctx := mock.NewContext()
+	wait := func(t *analyzeThrottle) string {
+		reason, err := t.wait(ctx)
+		c.Assert(err, IsNil)
+		return reason
+	}
+
+	// The storage is not busy.
+	reporter := &mockLoadReporter{latency: time.Second, pending: 10}
+	t := &analyzeThrottle{maxLatency: 2 * time.Second, maxPending: 10, reporter: reporter}
+	c.Assert(wait(t), Equals, "")
+	c.Assert(reporter.loads, Equals, 1)
+
+	// The storage recovers after a pause.
+	reporter = &mockLoadReporter{latency: 3 * time.Second, recoverAfter: 3}
+	t = &analyzeThrottle{maxLatency: 2 * time.Second, reporter: reporter}
+	c.Assert(wait(t), Equals, "")
+	c.Assert(reporter.loads, Equals, 4)
+
+	// The storage stays busy.
+	reporter = &mockLoadReporter{pending: 11}
+	t = &analyzeThrottle{maxPending: 10, reporter: reporter}
+	c.Assert(wait(t), Equals, "the storage is busy")
+	c.Assert(reporter.loads, Equals, 6)
+
+	// The storage is never checked without the limits.
+	reporter = &mockLoadReporter{latency: time.Hour, pending: 100}
+	t = &analyzeThrottle{reporter: reporter}
+	c.Assert(wait(t), Equals, "")
+
+	// Out of time.
+	t = &analyzeThrottle{deadline: time.Now().Add(-time.Second)}
+	c.Assert(wait(t), Equals, "it exceeds tidb_analyze_max_execution_time")
+
+	// The pause is interrupted once the statement is killed.
+	analyzeMaxPause = time.Minute
+	t = &analyzeThrottle{maxPending: 10, reporter: &mockLoadReporter{pending: 11}}
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		ctx.GetSessionVars().Kill()
+	}()
+	_, err := t.wait(ctx)
+	c.Assert(kv.ErrQueryInterrupted.Equal(err), IsTrue)
+}
+
+func (s *testExecSuite) TestAnalyzeState(c *C) {
+	driver := localstore.Driver{Driver: goleveldb.MemoryDriver{}}
+	store, err := driver.Open("memory")
+	c.Assert(err, IsNil)
+	defer store.Close()
+	newCol := func(id int64, tp byte) *table.Column {
+		return &table.Column{ID: id, FieldType: *types.NewFieldType(tp)}
+	}
+	cols := []*table.Column{newCol(1, mysql.TypeLonglong), newCol(2, mysql.TypeVarchar)}
+	load := func(cols []*table.Column) *analyzeState {
+		txn, err := store.Begin()
+		c.Assert(err, IsNil)
+		defer txn.Rollback()
+		state, err := loadAnalyzeState(txn, 1, cols)
+		c.Assert(err, IsNil)
+		return state
+	}
+	c.Assert(load(cols), IsNil)
+	// The strings are read from the records as bytes.
+	samples := [][]types.Datum{types.MakeDatums(1, []byte("a")), types.MakeDatums(2, nil)}
+	err = saveAnalyzeState(store, 1, cols, &analyzeState{lastHandle: 10, count: 10, samples: samples})
+	c.Assert(err, IsNil)
+	// The progress is read from the store, it survives the restart of the server.
+	state := load(cols)
+	c.Assert(state, NotNil)
+	c.Assert(state.lastHandle, Equals, int64(10))
+	c.Assert(state.count, Equals, int64(10))
+	c.Assert(state.samples, DeepEquals, samples)
+
+	// The progress is discarded if the columns are changed.
+	c.Assert(load([]*table.Column{cols[0], newCol(3, mysql.TypeVarchar)}), IsNil)
+
+	// The progress is removed with the statistics built from it.
+	err = kv.RunInNewTxn(store, false, func(txn kv.Transaction) error {
+		return meta.NewMeta(txn).RemoveAnalyzeProgress(1)
+	})
+	c.Assert(err, IsNil)
+	c.Assert(load(cols), IsNil)
+
+	state = &analyzeState{}
+	for i := 0; i < maxSampleCount+10; i++ {
+		state.collectSample(types.MakeDatums(i))
+	}
+	c.Assert(state.count, Equals, int64(maxSampleCount+10))
+	c.Assert(state.samples, HasLen, maxSampleCount)
+}
+
+func (s *testExecSuite) TestApplyCache(c *C) {
+	rows := func(vals ...int64) []*Row {
+		var rs []*Row
+		for _, v := range vals {
+			rs = append(rs, &Row{Data: types.MakeDatums(v)})
+		}
+		return rs
+	}
+	key := func(v int64) []byte {
+		col := &expression.CorrelatedColumn{Data: new(types.Datum)}
+		col.Data.SetInt64(v)
+		k, err := applyCacheKey([]*expression.CorrelatedColumn{col})
+		c.Assert(err, IsNil)
+		return k
+	}
+	entrySize := int64(len(key(1))) + rowMemUsage(rows(1)[0])*2
+	cache := newApplyCache(entrySize*2, memoryUsage{})
+	c.Assert(cache.put(key(1), rows(1, 2), true), IsNil)
+	c.Assert(cache.put(key(2), rows(3, 4), true), IsNil)
+	got, complete, ok := cache.get(key(1))
+	c.Assert(ok, IsTrue)
+	c.Assert(complete, IsTrue)
+	c.Assert(got, HasLen, 2)
+	// The key 2 is the least recently used one, it's evicted.
+	c.Assert(cache.put(key(3), rows(5, 6), true), IsNil)
+	_, _, ok = cache.get(key(2))
+	c.Assert(ok, IsFalse)
+	_, _, ok = cache.get(key(1))
+	c.Assert(ok, IsTrue)
+	_, _, ok = cache.get(key(3))
+	c.Assert(ok, IsTrue)
+	// The empty results are cached too, and the results larger than the capacity aren't cached.
+	c.Assert(cache.put(key(4), nil, true), IsNil)
+	got, _, ok = cache.get(key(4))
+	c.Assert(ok, IsTrue)
+	c.Assert(got, HasLen, 0)
+	c.Assert(cache.put(key(5), rows(1, 2, 3, 4, 5), true), IsNil)
+	_, _, ok = cache.get(key(5))
+	c.Assert(ok, IsFalse)
+	c.Assert(cache.used, LessEqual, cache.capacity)
+	// The leading rows are replaced by all the rows.
+	c.Assert(cache.put(key(4), rows(7), false), IsNil)
+	got, complete, _ = cache.get(key(4))
+	c.Assert(got, HasLen, 1)
+	c.Assert(complete, IsFalse)
+	c.Assert(cache.put(key(4), rows(7, 8), true), IsNil)
+	got, complete, _ = cache.get(key(4))
+	c.Assert(got, HasLen, 2)
+	c.Assert(complete, IsTrue)
+	c.Assert(cache.used, LessEqual, cache.capacity)
+
+	cache.clear()
+	c.Assert(cache.used, Equals, int64(0))
+	_, _, ok = cache.get(key(1))
+	c.Assert(ok, IsFalse)
+}
+
+// mockRowsExec returns the rows and counts the calls of Next.
+type mockRowsExec struct {
+	rows   []*Row
+	cursor int
+	nexts  int
+}
+
+func (e *mockRowsExec) Schema() expression.Schema {
+	return nil
+}
+
+func (e *mockRowsExec) Next() (*Row, error) {
+	e.nexts++
+	if e.cursor >= len(e.rows) {
+		return nil, nil
+	}
+	row := e.rows[e.cursor]
+	e.cursor++
+	return row, nil
+}
+
+func (e *mockRowsExec) Close() error {
+	e.cursor = 0
+	return nil
+}
+
+func (s *testExecSuite) TestApplyCacheStream(c *C) {
+	rows := func(vals ...int64) []*Row {
+		var rs []*Row
+		for _, v := range vals {
+			rs = append(rs, &Row{Data: types.MakeDatums(v)})
+		}
+		return rs
+	}
+	newApply := func(inner *mockRowsExec) *ApplyExec {
+		return &ApplyExec{
+			Src:         &mockRowsExec{rows: rows(1, 1)},
+			outerSchema: []*expression.CorrelatedColumn{{Data: new(types.Datum)}},
+			innerExec:   inner,
+			cache:       newApplyCache(1<<20, memoryUsage{}),
+		}
+	}
+
+	// The inner rows are returned while they're read and cached.
+	inner := &mockRowsExec{rows: rows(10, 20, 30)}
+	e := newApply(inner)
+	e.lateral = true
+	row, err := e.Next()
+	c.Assert(err, IsNil)
+	c.Assert(row.Data[1].GetInt64(), Equals, int64(10))
+	c.Assert(inner.nexts, Equals, 1)
+	for i := 0; i < 5; i++ {
+		row, err = e.Next()
+		c.Assert(err, IsNil)
+		c.Assert(row, NotNil)
+	}
+	row, err = e.Next()
+	c.Assert(err, IsNil)
+	c.Assert(row, IsNil)
+	// The inner rows of the second outer row are read from the cache.
+	c.Assert(inner.nexts, Equals, 4)
+	c.Assert(e.Close(), IsNil)
+
+	// The checker stops at the first inner row, which is cached as the leading rows.
+	inner = &mockRowsExec{rows: rows(10, 20, 30)}
+	e = newApply(inner)
+	e.checker = &conditionChecker{cond: &expression.Constant{Value: types.NewIntDatum(1)}, ctx: mock.NewContext()}
+	for i := 0; i < 2; i++ {
+		row, err = e.Next()
+		c.Assert(err, IsNil)
+		c.Assert(row.Data[1].GetInt64(), Equals, int64(1))
+	}
+	c.Assert(inner.nexts, Equals, 1)
+	_, complete, ok := e.cache.get(e.cacheKey)
+	c.Assert(ok, IsTrue)
+	c.Assert(complete, IsFalse)
+	// The leading rows aren't enough, the inner rows are read again and all of them are cached.
+	e.checker.all = true
+	e.Src = &mockRowsExec{rows: rows(1)}
+	row, err = e.Next()
+	c.Assert(err, IsNil)
+	c.Assert(row.Data[1].GetInt64(), Equals, int64(1))
+	c.Assert(inner.nexts, Equals, 5)
+	rs, complete, _ := e.cache.get(e.cacheKey)
+	c.Assert(complete, IsTrue)
+	c.Assert(rs, HasLen, 3)
+	c.Assert(e.Close(), IsNil)
+}
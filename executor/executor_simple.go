@@ -17,6 +17,7 @@ import (
 	"fmt"
 	"math/rand"
 	"strings"
+	"time"
 
 	"github.com/juju/errors"
 	"github.com/ngaut/log"
@@ -25,14 +26,19 @@ import (
 	"github.com/pingcap/tidb/evaluator"
 	"github.com/pingcap/tidb/expression"
 	"github.com/pingcap/tidb/infoschema"
+	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/meta"
 	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/parser"
 	"github.com/pingcap/tidb/plan/statistics"
+	"github.com/pingcap/tidb/privilege"
 	"github.com/pingcap/tidb/sessionctx"
 	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/table"
 	"github.com/pingcap/tidb/terror"
 	"github.com/pingcap/tidb/util"
+	"github.com/pingcap/tidb/util/bindinfo"
 	"github.com/pingcap/tidb/util/charset"
 	"github.com/pingcap/tidb/util/sqlexec"
 	"github.com/pingcap/tidb/util/types"
@@ -83,6 +89,12 @@ func (e *SimpleExec) Next() (*Row, error) {
 		err = e.executeSetPwd(x)
 	case *ast.AnalyzeTableStmt:
 		err = e.executeAnalyzeTable(x)
+	case *ast.CreateBindingStmt:
+		err = e.executeCreateBinding(x)
+	case *ast.DropBindingStmt:
+		err = e.executeDropBinding(x)
+	case *ast.KillStmt:
+		err = e.executeKill(x)
 	case *ast.BinlogStmt:
 		// We just ignore it.
 		return nil, nil
@@ -100,7 +112,7 @@ func (e *SimpleExec) Close() error {
 }
 
 func (e *SimpleExec) executeUse(s *ast.UseStmt) error {
-	dbname := model.NewCIStr(s.DBName)
+	dbname := model.NewTableCIStr(s.DBName)
 	dbinfo, exists := sessionctx.GetDomain(e.ctx).InfoSchema().SchemaByName(dbname)
 	if !exists {
 		return infoschema.ErrDatabaseNotExists.Gen("database %s not exists", dbname)
@@ -280,6 +292,32 @@ func (e *SimpleExec) executeDo(s *ast.DoStmt) error {
 	return nil
 }
 
+// executeKill kills the connection or its running statement. The user can kill the connections of the same user,
+// the connections of the other users can be killed with the SUPER or PROCESS privilege.
+func (e *SimpleExec) executeKill(s *ast.KillStmt) error {
+	sm := sessionctx.GetSessionManager(e.ctx)
+	if sm == nil {
+		return ErrNoSuchThread.Gen("Unknown thread id: %d", s.ConnectionID)
+	}
+	user, ok := sm.ConnectionUser(s.ConnectionID)
+	if !ok {
+		return ErrNoSuchThread.Gen("Unknown thread id: %d", s.ConnectionID)
+	}
+	if curUser := e.ctx.GetSessionVars().User; curUser != "" && user != strings.Split(curUser, "@")[0] {
+		hasPriv, err := privilege.CheckGlobal(e.ctx, mysql.SuperPriv, mysql.ProcessPriv)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if !hasPriv {
+			return ErrKillDenied.Gen("You are not owner of thread %d", s.ConnectionID)
+		}
+	}
+	if !sm.Kill(s.ConnectionID, s.Query) {
+		return ErrNoSuchThread.Gen("Unknown thread id: %d", s.ConnectionID)
+	}
+	return nil
+}
+
 func (e *SimpleExec) executeBegin(s *ast.BeginStmt) error {
 	_, err := e.ctx.GetTxn(true)
 	if err != nil {
@@ -403,14 +441,100 @@ func (e *SimpleExec) executeSetPwd(s *ast.SetPwdStmt) error {
 	return errors.Trace(err)
 }
 
+// checkBindingPriv checks the SUPER privilege to create or drop a binding, since the bindings change the plans of
+// the statements of all the users.
+func (e *SimpleExec) checkBindingPriv() error {
+	hasPriv, err := privilege.CheckGlobal(e.ctx, mysql.SuperPriv)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if !hasPriv {
+		return ErrSpecificAccessDenied.Gen("Access denied; you need (at least one of) the %s privilege(s) for this operation", "SUPER")
+	}
+	return nil
+}
+
+func (e *SimpleExec) executeCreateBinding(s *ast.CreateBindingStmt) error {
+	if err := e.checkBindingPriv(); err != nil {
+		return errors.Trace(err)
+	}
+	originalSQL := parser.Normalize(s.OriginSel.Text())
+	if parser.Normalize(s.HintedSel.Text()) != originalSQL {
+		return ErrInvalidBinding.Gen("The bound statement must be the same as the original statement except the hints")
+	}
+	if len(s.HintedSel.TableHints) == 0 {
+		return ErrInvalidBinding.Gen("The bound statement has no hints")
+	}
+	defaultDB := e.ctx.GetSessionVars().CurrentDB
+	exists, err := bindingExists(e.ctx, originalSQL, defaultDB)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	now := quoteString(time.Now().Format(types.TimeFormat))
+	var sql string
+	if exists {
+		sql = fmt.Sprintf(`UPDATE %s.%s SET bind_sql = %s, update_time = %s WHERE original_sql = %s AND default_db = %s;`,
+			mysql.SystemDB, mysql.BindInfoTable, quoteString(s.HintedSel.Text()), now, quoteString(originalSQL),
+			quoteString(defaultDB))
+	} else {
+		sql = fmt.Sprintf(`INSERT INTO %s.%s VALUES (%s, %s, %s, %s, %s);`, mysql.SystemDB, mysql.BindInfoTable,
+			quoteString(originalSQL), quoteString(s.HintedSel.Text()), quoteString(defaultDB), now, now)
+	}
+	_, err = e.ctx.(sqlexec.RestrictedSQLExecutor).ExecRestrictedSQL(e.ctx, sql)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	bindinfo.GlobalHandle.Invalidate()
+	return nil
+}
+
+func (e *SimpleExec) executeDropBinding(s *ast.DropBindingStmt) error {
+	if err := e.checkBindingPriv(); err != nil {
+		return errors.Trace(err)
+	}
+	sql := fmt.Sprintf(`DELETE FROM %s.%s WHERE original_sql = %s AND default_db = %s;`, mysql.SystemDB,
+		mysql.BindInfoTable, quoteString(parser.Normalize(s.OriginSel.Text())),
+		quoteString(e.ctx.GetSessionVars().CurrentDB))
+	_, err := e.ctx.(sqlexec.RestrictedSQLExecutor).ExecRestrictedSQL(e.ctx, sql)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	bindinfo.GlobalHandle.Invalidate()
+	return nil
+}
+
+func bindingExists(ctx context.Context, originalSQL, defaultDB string) (bool, error) {
+	sql := fmt.Sprintf(`SELECT * FROM %s.%s WHERE original_sql = %s AND default_db = %s;`, mysql.SystemDB,
+		mysql.BindInfoTable, quoteString(originalSQL), quoteString(defaultDB))
+	rs, err := ctx.(sqlexec.RestrictedSQLExecutor).ExecRestrictedSQL(ctx, sql)
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	defer rs.Close()
+	row, err := rs.Next()
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	return row != nil, nil
+}
+
+// quoteString quotes s as a string literal of the restricted SQL.
+func quoteString(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
+
 func (e *SimpleExec) executeFlushTable(s *ast.FlushTableStmt) error {
 	// TODO: A dummy implement
 	return nil
 }
 
 func (e *SimpleExec) executeAnalyzeTable(s *ast.AnalyzeTableStmt) error {
+	throttle, err := newAnalyzeThrottle(e.ctx)
+	if err != nil {
+		return errors.Trace(err)
+	}
 	for _, table := range s.TableNames {
-		err := e.createStatisticsForTable(table)
+		err := e.createStatisticsForTable(table, throttle)
 		if err != nil {
 			return errors.Trace(err)
 		}
@@ -423,57 +547,75 @@ const (
 	defaultBucketCount = 256
 )
 
-func (e *SimpleExec) createStatisticsForTable(tn *ast.TableName) error {
-	var tableName string
-	if tn.Schema.L == "" {
-		tableName = tn.Name.L
-	} else {
-		tableName = tn.Schema.L + "." + tn.Name.L
+func (e *SimpleExec) createStatisticsForTable(tn *ast.TableName, throttle *analyzeThrottle) error {
+	t, ok := sessionctx.GetDomain(e.ctx).InfoSchema().TableByID(tn.TableInfo.ID)
+	if !ok {
+		return infoschema.ErrTableNotExists.Gen("Table '%s' doesn't exist", tn.Name.O)
 	}
-	sql := "select * from " + tableName
-	result, err := e.ctx.(sqlexec.RestrictedSQLExecutor).ExecRestrictedSQL(e.ctx, sql)
+	cols := t.Cols()
+	txn, err := e.ctx.GetTxn(false)
 	if err != nil {
 		return errors.Trace(err)
 	}
-	count, samples, err := e.collectSamples(result)
-	result.Close()
+	// The rows are read in the order of their handles, so an interrupted ANALYZE can resume after the last handle.
+	startKey := t.RecordPrefix()
+	state, err := loadAnalyzeState(txn, tn.TableInfo.ID, cols)
 	if err != nil {
 		return errors.Trace(err)
 	}
-	err = e.buildStatisticsAndSaveToKV(tn, count, samples)
+	if state != nil {
+		startKey = t.RecordKey(state.lastHandle).PrefixNext()
+	} else {
+		state = &analyzeState{}
+	}
+	var reason string
+	err = t.IterRecords(e.ctx, startKey, cols, func(h int64, rec []types.Datum, _ []*table.Column) (bool, error) {
+		if state.count > 0 && state.count%int64(analyzeCheckInterval) == 0 {
+			var err error
+			reason, err = throttle.wait(e.ctx)
+			if err != nil || reason != "" {
+				return false, errors.Trace(err)
+			}
+		}
+		state.collectSample(rec)
+		state.lastHandle = h
+		return true, nil
+	})
+	if terror.ErrorEqual(err, kv.ErrQueryInterrupted) {
+		// A killed ANALYZE resumes like an interrupted one.
+		if err1 := saveAnalyzeState(sessionctx.GetDomain(e.ctx).Store(), tn.TableInfo.ID, cols, state); err1 != nil {
+			log.Errorf("[analyze] save the progress of table %d failed: %v", tn.TableInfo.ID, errors.ErrorStack(err1))
+		}
+	}
 	if err != nil {
 		return errors.Trace(err)
 	}
-	return nil
+	if reason != "" {
+		if err = saveAnalyzeState(sessionctx.GetDomain(e.ctx).Store(), tn.TableInfo.ID, cols, state); err != nil {
+			return errors.Trace(err)
+		}
+		return ErrAnalyzeInterrupted.Gen("ANALYZE TABLE %s is interrupted after %d rows because %s, run it again to resume",
+			tn.Name.O, state.count, reason)
+	}
+	return errors.Trace(e.buildStatisticsAndSaveToKV(tn, state.count, state.samples))
 }
 
-// collectSamples collects sample from the result set, using Reservoir Sampling algorithm.
+// collectSample collects a row into the samples, using Reservoir Sampling algorithm.
 // See https://en.wikipedia.org/wiki/Reservoir_sampling
-func (e *SimpleExec) collectSamples(result ast.RecordSet) (count int64, samples []*ast.Row, err error) {
-	for {
-		var row *ast.Row
-		row, err = result.Next()
-		if err != nil {
-			return count, samples, errors.Trace(err)
-		}
-		if row == nil {
-			break
-		}
-		if len(samples) < maxSampleCount {
-			samples = append(samples, row)
-		} else {
-			shouldAdd := rand.Int63n(count) < maxSampleCount
-			if shouldAdd {
-				idx := rand.Intn(maxSampleCount)
-				samples[idx] = row
-			}
+func (s *analyzeState) collectSample(row []types.Datum) {
+	if len(s.samples) < maxSampleCount {
+		s.samples = append(s.samples, row)
+	} else {
+		shouldAdd := rand.Int63n(s.count) < maxSampleCount
+		if shouldAdd {
+			idx := rand.Intn(maxSampleCount)
+			s.samples[idx] = row
 		}
-		count++
 	}
-	return count, samples, nil
+	s.count++
 }
 
-func (e *SimpleExec) buildStatisticsAndSaveToKV(tn *ast.TableName, count int64, sampleRows []*ast.Row) error {
+func (e *SimpleExec) buildStatisticsAndSaveToKV(tn *ast.TableName, count int64, sampleRows [][]types.Datum) error {
 	txn, err := e.ctx.GetTxn(false)
 	if err != nil {
 		return errors.Trace(err)
@@ -492,19 +634,20 @@ func (e *SimpleExec) buildStatisticsAndSaveToKV(tn *ast.TableName, count int64,
 	if err != nil {
 		return errors.Trace(err)
 	}
-	return nil
+	// The progress of the interrupted ANALYZE is removed with the statistics built from it.
+	return errors.Trace(m.RemoveAnalyzeProgress(tn.TableInfo.ID))
 }
 
-func rowsToColumnSamples(rows []*ast.Row) [][]types.Datum {
+func rowsToColumnSamples(rows [][]types.Datum) [][]types.Datum {
 	if len(rows) == 0 {
 		return nil
 	}
-	columnSamples := make([][]types.Datum, len(rows[0].Data))
+	columnSamples := make([][]types.Datum, len(rows[0]))
 	for i := range columnSamples {
 		columnSamples[i] = make([]types.Datum, len(rows))
 	}
 	for j, row := range rows {
-		for i, val := range row.Data {
+		for i, val := range row {
 			columnSamples[i][j] = val
 		}
 	}
@@ -17,7 +17,10 @@ import (
 	"fmt"
 
 	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb"
 	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/executor"
+	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/meta"
 	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/mysql"
@@ -123,6 +126,16 @@ func (s *testSuite) TestSetVar(c *C) {
 
 	// For mysql jdbc driver issue.
 	tk.MustQuery(`select @@session.tx_read_only;`).Check(testkit.Rows("0"))
+
+	tk.MustExec(`set @@tidb_compat_version = "5.7"`)
+	tk.MustQuery(`select strcmp("a ", "a"), from_unixtime(2147483648) is null`).Check(testkit.Rows("0 1"))
+	tk.MustQuery(`select round(1.005, 2), round(2.5e0), round(12, -1)`).Check(testkit.Rows("1.01 2 10"))
+	tk.MustExec(`set @@tidb_compat_version = "8.0"`)
+	tk.MustQuery(`select strcmp("a ", "a"), from_unixtime(2147483648) is null`).Check(testkit.Rows("1 0"))
+	_, err = tk.Exec(`set @@tidb_compat_version = "8.1"`)
+	c.Assert(variable.ErrWrongValueForVar.Equal(err), IsTrue)
+	tk.MustExec(`set @@tidb_compat_version = ""`)
+	tk.MustQuery(`select strcmp("a ", "a")`).Check(testkit.Rows("1"))
 }
 
 func (s *testSuite) TestSetCharset(c *C) {
@@ -154,6 +167,83 @@ func (s *testSuite) TestDo(c *C) {
 	tk.MustExec("do 1, 2")
 }
 
+type mockSessionManager struct {
+	sessions map[uint64]tidb.Session
+	users    map[uint64]string
+	killed   map[uint64]bool
+}
+
+func (sm *mockSessionManager) Kill(connectionID uint64, query bool) bool {
+	se, ok := sm.sessions[connectionID]
+	if !ok {
+		return false
+	}
+	se.Kill()
+	sm.killed[connectionID] = !query
+	return true
+}
+
+func (sm *mockSessionManager) ConnectionUser(connectionID uint64) (string, bool) {
+	if _, ok := sm.sessions[connectionID]; !ok {
+		return "", false
+	}
+	return sm.users[connectionID], true
+}
+
+func (s *testSuite) TestKill(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (a int)")
+	tk.MustExec("insert t values (1), (2), (3)")
+	tk1 := testkit.NewTestKit(c, s.store)
+	tk1.MustExec("use test")
+	tk1.Se.SetConnectionID(1)
+
+	sm := &mockSessionManager{
+		sessions: map[uint64]tidb.Session{1: tk1.Se},
+		users:    map[uint64]string{1: "root"},
+		killed:   make(map[uint64]bool),
+	}
+	_, err := tk.Exec("kill 1")
+	c.Assert(executor.ErrNoSuchThread.Equal(err), IsTrue)
+	sessionctx.BindSessionManager(tk.Se.(context.Context), sm)
+	_, err = tk.Exec("kill query 2")
+	c.Assert(executor.ErrNoSuchThread.Equal(err), IsTrue)
+
+	rs, err := tk1.Exec("select a from t")
+	c.Assert(err, IsNil)
+	tk.MustExec("kill query 1")
+	c.Assert(sm.killed[1], IsFalse)
+	_, err = tidb.GetRows(rs)
+	c.Assert(kv.ErrQueryInterrupted.Equal(err), IsTrue)
+	c.Assert(rs.Close(), IsNil)
+
+	// The next statement is not interrupted.
+	tk1.MustQuery("select count(*) from t").Check(testkit.Rows("3"))
+	tk.MustExec("kill connection 1")
+	c.Assert(sm.killed[1], IsTrue)
+
+	// The connections of the other users can't be killed without the SUPER or PROCESS privilege.
+	tk.MustExec("create user 'kill_user'@'localhost'")
+	tk2 := testkit.NewTestKit(c, s.store)
+	tk2.MustExec("use test")
+	tk2.Se.(context.Context).GetSessionVars().User = "kill_user@localhost"
+	sessionctx.BindSessionManager(tk2.Se.(context.Context), sm)
+	sm.killed = make(map[uint64]bool)
+	_, err = tk2.Exec("kill query 1")
+	c.Assert(executor.ErrKillDenied.Equal(err), IsTrue)
+	c.Assert(sm.killed, HasLen, 0)
+	sm.users[1] = "kill_user"
+	tk2.MustExec("kill query 1")
+	c.Assert(sm.killed[1], IsFalse)
+	tk.MustExec("drop user 'kill_user'@'localhost'")
+}
+
 func (s *testSuite) TestTransaction(c *C) {
 	defer testleak.AfterTest(c)()
 	tk := testkit.NewTestKit(c, s.store)
@@ -265,4 +355,18 @@ func (s *testSuite) TestAnalyzeTable(c *C) {
 	tStats, err := statistics.TableFromPB(t.Meta(), tpb)
 	c.Check(err, IsNil)
 	c.Check(tStats, NotNil)
+
+	// The limits don't interrupt the ANALYZE of a small table.
+	tk.MustQuery("select @@tidb_analyze_max_execution_time, @@tidb_analyze_max_cop_latency, @@tidb_analyze_max_cop_pending").
+		Check(testkit.Rows("0 0 0"))
+	tk.MustExec("set @@tidb_analyze_max_execution_time = 60000, @@tidb_analyze_max_cop_latency = 1000, @@tidb_analyze_max_cop_pending = 100")
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (a int, b varchar(10))")
+	tk.MustExec("insert t values (1, 'a'), (2, 'b'), (-1, null)")
+	tk.MustExec("analyze table t")
+	tk.MustExec("set @@tidb_analyze_max_execution_time = -1")
+	_, err = tk.Exec("analyze table t")
+	c.Check(err, NotNil)
+	tk.MustExec("drop table t")
 }
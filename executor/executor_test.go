@@ -14,13 +14,18 @@
 package executor_test
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"regexp"
+	"runtime"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/juju/errors"
 	"github.com/ngaut/log"
 	. "github.com/pingcap/check"
 	"github.com/pingcap/tidb"
@@ -28,11 +33,20 @@ import (
 	"github.com/pingcap/tidb/executor"
 	"github.com/pingcap/tidb/inspectkv"
 	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/meta"
 	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/parser"
 	"github.com/pingcap/tidb/plan"
 	"github.com/pingcap/tidb/sessionctx"
+	"github.com/pingcap/tidb/sessionctx/variable"
 	"github.com/pingcap/tidb/store/tikv"
+	"github.com/pingcap/tidb/tablecodec"
+	"github.com/pingcap/tidb/terror"
+	"github.com/pingcap/tidb/util/admission"
+	"github.com/pingcap/tidb/util/bindinfo"
+	"github.com/pingcap/tidb/util/memory"
+	"github.com/pingcap/tidb/util/planbaseline"
 	"github.com/pingcap/tidb/util/testkit"
 	"github.com/pingcap/tidb/util/testleak"
 	"github.com/pingcap/tidb/util/types"
@@ -121,6 +135,16 @@ func (s *testSuite) TestAdmin(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(row, IsNil)
 
+	// The DDL job queue is empty after the DDL jobs are done.
+	r, err = tk.Exec("admin show ddl jobs")
+	c.Assert(err, IsNil)
+	fields, err := r.Fields()
+	c.Assert(err, IsNil)
+	c.Assert(fields, HasLen, 10)
+	row, err = r.Next()
+	c.Assert(err, IsNil)
+	c.Assert(row, IsNil)
+
 	// check table test
 	tk.MustExec("create table admin_test1 (c1 int, c2 int default 1, index (c1))")
 	tk.MustExec("insert admin_test1 (c1) values (21),(22)")
@@ -144,6 +168,440 @@ func (s *testSuite) TestAdmin(c *C) {
 	c.Assert(err, IsNil)
 	r, err = tk.Exec("admin check table admin_test")
 	c.Assert(err, NotNil)
+	c.Assert(terror.ErrorEqual(err, executor.ErrAdminCheckTable), IsTrue)
+	c.Assert(err.Error(), Equals, "[executor:18]table admin_test index c1: handle 1 mismatched index entry, "+
+		"index:(10) != record:(1)")
+
+	// All the inconsistencies of the indices are reported.
+	tk.MustExec("create table admin_test2 (c1 int, c2 int, index i1 (c1), unique index i2 (c2))")
+	tk.MustExec("insert admin_test2 values (1, 1), (2, 2), (3, 3)")
+	tb, err = sessionctx.GetDomain(ctx).InfoSchema().TableByName(model.NewCIStr("test"), model.NewCIStr("admin_test2"))
+	c.Assert(err, IsNil)
+	txn, err = s.store.Begin()
+	c.Assert(err, IsNil)
+	err = tb.Indices()[0].Delete(txn, types.MakeDatums(int64(2)), 2)
+	c.Assert(err, IsNil)
+	_, err = tb.Indices()[1].Create(txn, types.MakeDatums(int64(4)), 4)
+	c.Assert(err, IsNil)
+	err = txn.Commit()
+	c.Assert(err, IsNil)
+	_, err = tk.Exec("admin check table admin_test1, admin_test2")
+	c.Assert(terror.ErrorEqual(err, executor.ErrAdminCheckTable), IsTrue)
+	c.Assert(err.Error(), Equals, "[executor:18]table admin_test2 index i1: handle 2 missing index entry, "+
+		"index:<nil> != record:(2); table admin_test2 index i2: handle 4 dangling index entry, index:(4) != record:<nil>")
+}
+
+func (s *testSuite) TestAdminRecoverIndex(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists admin_test")
+	tk.MustExec("create table admin_test (c1 int, c2 int, index i1 (c1), unique index i2 (c2))")
+	tk.MustExec("insert admin_test values (1, 1), (2, 2), (3, 3), (4, 4), (NULL, NULL)")
+	tk.MustQuery("admin recover index admin_test i1").Check(testkit.Rows("0 5"))
+
+	ctx := tk.Se.(context.Context)
+	tb, err := sessionctx.GetDomain(ctx).InfoSchema().TableByName(model.NewCIStr("test"), model.NewCIStr("admin_test"))
+	c.Assert(err, IsNil)
+	txn, err := s.store.Begin()
+	c.Assert(err, IsNil)
+	err = tb.Indices()[0].Delete(txn, types.MakeDatums(int64(2)), 2)
+	c.Assert(err, IsNil)
+	err = tb.Indices()[0].Delete(txn, types.MakeDatums(nil), 5)
+	c.Assert(err, IsNil)
+	err = tb.Indices()[1].Delete(txn, types.MakeDatums(int64(4)), 4)
+	c.Assert(err, IsNil)
+	err = txn.Commit()
+	c.Assert(err, IsNil)
+	_, err = tk.Exec("admin check table admin_test")
+	c.Assert(terror.ErrorEqual(err, executor.ErrAdminCheckTable), IsTrue, Commentf("%v", err))
+
+	tk.MustQuery("admin recover index admin_test i1").Check(testkit.Rows("2 5"))
+	tk.MustQuery("admin recover index admin_test I2").Check(testkit.Rows("1 5"))
+	tk.MustExec("admin check table admin_test")
+	tk.MustQuery("select c1 from admin_test use index (i1) where c1 >= 2").Check(testkit.Rows("2", "3", "4"))
+
+	_, err = tk.Exec("admin recover index admin_test i3")
+	c.Assert(terror.ErrorEqual(err, plan.ErrKeyDoesNotExist), IsTrue)
+	c.Assert(err.Error(), Equals, "[optimizer:26]Key 'i3' doesn't exist in table 'admin_test'")
+}
+
+func (s *testSuite) TestAdminCleanupIndex(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists admin_test")
+	tk.MustExec("create table admin_test (c1 int, c2 int, index i1 (c1), unique index i2 (c2))")
+	tk.MustExec("insert admin_test values (1, 1), (2, 2), (3, 3)")
+	tk.MustQuery("admin cleanup index admin_test i2").Check(testkit.Rows("0 3"))
+
+	ctx := tk.Se.(context.Context)
+	tb, err := sessionctx.GetDomain(ctx).InfoSchema().TableByName(model.NewCIStr("test"), model.NewCIStr("admin_test"))
+	c.Assert(err, IsNil)
+	txn, err := s.store.Begin()
+	c.Assert(err, IsNil)
+	// The dangling entries.
+	_, err = tb.Indices()[0].Create(txn, types.MakeDatums(int64(4)), 4)
+	c.Assert(err, IsNil)
+	_, err = tb.Indices()[1].Create(txn, types.MakeDatums(int64(5)), 5)
+	c.Assert(err, IsNil)
+	// The mismatched entries.
+	_, err = tb.Indices()[0].Create(txn, types.MakeDatums(int64(10)), 1)
+	c.Assert(err, IsNil)
+	_, err = tb.Indices()[1].Create(txn, types.MakeDatums(int64(20)), 2)
+	c.Assert(err, IsNil)
+	err = txn.Commit()
+	c.Assert(err, IsNil)
+	_, err = tk.Exec("admin check table admin_test")
+	c.Assert(terror.ErrorEqual(err, executor.ErrAdminCheckTable), IsTrue, Commentf("%v", err))
+
+	tk.MustQuery("admin cleanup index admin_test i1").Check(testkit.Rows("2 5"))
+	tk.MustQuery("admin cleanup index admin_test i2").Check(testkit.Rows("2 5"))
+	tk.MustExec("admin check table admin_test")
+	tk.MustQuery("admin cleanup index admin_test i1").Check(testkit.Rows("0 3"))
+	tk.MustQuery("select c2 from admin_test use index (i2) where c2 > 0").Check(testkit.Rows("1", "2", "3"))
+}
+
+func (s *testSuite) TestAdminShowDeleteRanges(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	// The done background job of dropping a table is added to the history directly, so the test doesn't depend on
+	// when the background worker runs it.
+	const jobID, tableID = 1 << 40, 1 << 40
+	err := kv.RunInNewTxn(s.store, true, func(txn kv.Transaction) error {
+		job := &model.Job{ID: jobID, TableID: tableID, Type: model.ActionDropTable, State: model.JobDone}
+		return errors.Trace(meta.NewMeta(txn).AddHistoryBgJob(job))
+	})
+	c.Assert(err, IsNil)
+	// The history is read in batches.
+	executor.DeleteRangesBatchSize = 1
+	defer func() { executor.DeleteRangesBatchSize = 256 }()
+
+	// Returns the state, HAS_DATA and ORPHAN of the range of the job.
+	getRange := func() []string {
+		for _, row := range tk.MustQuery("admin show delete ranges").Rows() {
+			if fmt.Sprintf("%v", row[0]) == fmt.Sprintf("%d", jobID) {
+				c.Assert(fmt.Sprintf("%v", row[2]), Equals, fmt.Sprintf("%d", tableID))
+				return []string{fmt.Sprintf("%v", row[3]), fmt.Sprintf("%v", row[4]), fmt.Sprintf("%v", row[5])}
+			}
+		}
+		return nil
+	}
+	c.Assert(getRange(), DeepEquals, []string{model.JobDone.String(), "0", "0"})
+
+	// The data left in the range of the dropped table is reported as an orphan.
+	key := tablecodec.EncodeRowKeyWithHandle(tableID, 1)
+	txn, err := s.store.Begin()
+	c.Assert(err, IsNil)
+	err = txn.Set(key, []byte("v"))
+	c.Assert(err, IsNil)
+	err = txn.Commit()
+	c.Assert(err, IsNil)
+	c.Assert(getRange(), DeepEquals, []string{model.JobDone.String(), "1", "1"})
+
+	txn, err = s.store.Begin()
+	c.Assert(err, IsNil)
+	err = txn.Delete(key)
+	c.Assert(err, IsNil)
+	err = txn.Commit()
+	c.Assert(err, IsNil)
+	c.Assert(getRange(), DeepEquals, []string{model.JobDone.String(), "0", "0"})
+}
+
+func (s *testSuite) TestAdminDiagnose(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (a int primary key, b int)")
+	tk.MustExec("insert t values (1, 1), (2, 2)")
+
+	sql := "select * from t where a = 1"
+	digest := parser.Digest(sql)
+	categories := func(rows [][]interface{}) map[string]int {
+		m := make(map[string]int)
+		for _, row := range rows {
+			m[fmt.Sprintf("%s", row[0])]++
+		}
+		return m
+	}
+	rows := tk.MustQuery("admin diagnose 'select * from t where a = 2'").Rows()
+	c.Assert(rows[0][2], Equals, digest)
+	c.Assert(rows[1][2], Equals, "select * from t where a = ?")
+	m := categories(rows)
+	c.Assert(m["plan"], Greater, 0)
+	c.Assert(m["stats"], Equals, 1)
+	c.Assert(m["variable"], Greater, 0)
+	c.Assert(m["slow_query"], Equals, 0)
+	for _, row := range rows {
+		if row[0] == "stats" {
+			c.Assert(row[1], Equals, "test.t")
+			c.Assert(row[2], Equals, "never analyzed, pseudo statistics are used")
+		}
+	}
+
+	// The statement of a digest is looked up in the slow query log.
+	r, err := tk.Exec(fmt.Sprintf("admin diagnose '%s'", digest))
+	c.Assert(err, IsNil)
+	_, err = r.Next()
+	c.Assert(terror.ErrorEqual(err, executor.ErrDiagnoseNotFound), IsTrue)
+	c.Assert(r.Close(), IsNil)
+	executor.SlowQueryLog.Add(&executor.SlowQuery{
+		Start:    time.Now(),
+		Duration: 2 * time.Second,
+		ConnID:   1,
+		DB:       "test",
+		SQL:      sql,
+	})
+	tk.MustExec("analyze table t")
+	rows = tk.MustQuery(fmt.Sprintf("admin diagnose '%s'", digest)).Rows()
+	c.Assert(rows[2][2], Equals, sql)
+	m = categories(rows)
+	c.Assert(m["slow_query"], Equals, 1)
+	for _, row := range rows {
+		if row[0] == "stats" {
+			c.Assert(strings.HasPrefix(fmt.Sprintf("%s", row[2]), "analyzed at"), IsTrue, Commentf("%v", row))
+		}
+	}
+
+	// The slow queries of the other users are only shown with the SUPER or PROCESS privilege.
+	tk.MustExec("create user 'diagnose_user'@'localhost'")
+	tk.MustExec("grant select on test.* to 'diagnose_user'@'localhost'")
+	newSession := func() *testkit.TestKit {
+		tk := testkit.NewTestKit(c, s.store)
+		tk.MustExec("use test")
+		tk.Se.(context.Context).GetSessionVars().User = "diagnose_user@localhost"
+		return tk
+	}
+	tku := newSession()
+	r, err = tku.Exec(fmt.Sprintf("admin diagnose '%s'", digest))
+	c.Assert(err, IsNil)
+	_, err = r.Next()
+	c.Assert(terror.ErrorEqual(err, executor.ErrDiagnoseNotFound), IsTrue)
+	c.Assert(r.Close(), IsNil)
+	c.Assert(categories(tku.MustQuery(fmt.Sprintf("admin diagnose '%s'", sql)).Rows())["slow_query"], Equals, 0)
+	tk.MustExec("grant process on *.* to 'diagnose_user'@'localhost'")
+	tku = newSession()
+	c.Assert(categories(tku.MustQuery(fmt.Sprintf("admin diagnose '%s'", digest)).Rows())["slow_query"], Equals, 1)
+	tk.MustExec("drop user 'diagnose_user'@'localhost'")
+
+	// The plan error is output instead of the plan.
+	tk.MustExec("drop table t")
+	rows = tk.MustQuery("admin diagnose 'select * from t'").Rows()
+	m = categories(rows)
+	c.Assert(m["plan"], Equals, 1)
+	c.Assert(m["stats"], Equals, 0)
+}
+
+func (s *testSuite) TestAdminShowSlowPlans(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (a int primary key, b int)")
+
+	// The plan of the statement changes after the index is added.
+	addSlowQuery := func(sql string, d time.Duration) string {
+		tk.MustQuery(sql)
+		planDigest := executor.LastPlanDigest(tk.Se.Value(executor.LastPlanVarKey))
+		c.Assert(planDigest, Not(Equals), "")
+		executor.SlowQueryLog.Add(&executor.SlowQuery{
+			Start:      time.Now(),
+			Duration:   d,
+			ConnID:     1,
+			DB:         "test",
+			SQL:        sql,
+			PlanDigest: planDigest,
+		})
+		return planDigest
+	}
+	stable := addSlowQuery("select count(*) from t where a > 10", 10*time.Second)
+	addSlowQuery("select count(*) from t where a > 20", 10*time.Second)
+	scan := addSlowQuery("select * from t where b = 1", 2*time.Second)
+	tk.MustExec("alter table t add index b (b)")
+	lookup := addSlowQuery("select * from t where b = 2", 3*time.Second)
+	c.Assert(scan, Not(Equals), lookup)
+
+	var rows [][]string
+	for _, row := range tk.MustQuery("admin show slow plans").Rows() {
+		switch row[1] {
+		case stable, scan, lookup:
+			var fields []string
+			for _, v := range row[:8] {
+				fields = append(fields, fmt.Sprintf("%v", v))
+			}
+			rows = append(rows, fields)
+		}
+	}
+	c.Assert(rows, HasLen, 3)
+	// The unstable statement comes first, and its slower plan comes first.
+	digest := parser.Digest("select * from t where b = 1")
+	c.Assert(rows[0], DeepEquals, []string{digest, lookup, "select * from t where b = ?",
+		"select * from t where b = 2", "2", "1", "3", "3"})
+	c.Assert(rows[1], DeepEquals, []string{digest, scan, "select * from t where b = ?",
+		"select * from t where b = 1", "2", "1", "2", "2"})
+	c.Assert(rows[2], DeepEquals, []string{parser.Digest("select count(*) from t where a > 10"), stable,
+		"select count ( * ) from t where a > ?", "select count(*) from t where a > 20", "1", "2", "10", "10"})
+
+	// The slow queries of the other users are only shown with the SUPER or PROCESS privilege.
+	tk.MustExec("create user 'slow_user'@'localhost'")
+	executor.SlowQueryLog.Add(&executor.SlowQuery{
+		Start:      time.Now(),
+		Duration:   time.Second,
+		ConnID:     2,
+		User:       "slow_user",
+		DB:         "test",
+		SQL:        "select * from t where b = 3",
+		PlanDigest: lookup,
+	})
+	newSession := func() *testkit.TestKit {
+		tk := testkit.NewTestKit(c, s.store)
+		tk.MustExec("use test")
+		tk.Se.(context.Context).GetSessionVars().User = "slow_user@localhost"
+		return tk
+	}
+	tku := newSession()
+	userRows := tku.MustQuery("admin show slow plans").Rows()
+	c.Assert(userRows, HasLen, 1)
+	c.Assert(userRows[0][3], Equals, "select * from t where b = 3")
+	c.Assert(len(tk.MustQuery("admin show slow plans").Rows()), Greater, 1)
+	tk.MustExec("grant process on *.* to 'slow_user'@'localhost'")
+	tku = newSession()
+	c.Assert(len(tku.MustQuery("admin show slow plans").Rows()), Greater, 1)
+	tk.MustExec("drop user 'slow_user'@'localhost'")
+}
+
+func (s *testSuite) TestAdminSetStats(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t1, t2")
+	tk.MustExec("create table t1 (a int primary key, b int, c int, key (b))")
+	tk.MustExec("create table t2 (a int primary key, b int, c int, key (b))")
+
+	planIDs := func(sql string) string {
+		var ids []string
+		for _, row := range tk.MustQuery("explain " + sql).Rows() {
+			id := row[0].(string)
+			ids = append(ids, id[:strings.LastIndex(id, "_")])
+		}
+		return strings.Join(ids, ",")
+	}
+	estRows := func(sql string) uint64 {
+		var root struct {
+			EstRows uint64 `json:"estRows"`
+		}
+		rows := tk.MustQuery("explain format = 'json' " + sql).Rows()
+		c.Assert(json.Unmarshal([]byte(rows[0][0].(string)), &root), IsNil)
+		return root.EstRows
+	}
+	join := "select * from t1, t2 where t1.a = t2.b"
+	c.Assert(planIDs(join), Equals, "TableScan,IndexScan,MergeJoin")
+
+	// The small table is the build side of the hash join.
+	tk.MustExec("admin set stats t1 rows 10")
+	tk.MustExec("admin set stats test.t2 rows 100000000")
+	c.Assert(planIDs(join), Equals, "TableScan,TableScan,HashRightJoin")
+	c.Assert(estRows("select * from t1"), Equals, uint64(10))
+	// The index is not selective if the column has few distinct values.
+	tk.MustExec("admin set stats t1 rows 100000000")
+	c.Assert(planIDs("select * from t1 where b = 1"), Equals, "IndexScan")
+	c.Assert(estRows("select * from t1 where b = 1"), Equals, uint64(100000))
+	tk.MustExec("admin set stats t1 column b distinct 2")
+	c.Assert(planIDs("select * from t1 where b = 1"), Equals, "TableScan")
+	for _, row := range tk.MustQuery("admin diagnose 'select * from t1'").Rows() {
+		if row[0] == "stats" {
+			c.Assert(row[2], Equals, "never analyzed, pseudo statistics are used, overridden by ADMIN SET STATS in the session")
+		}
+	}
+
+	// The overrides are only visible in the session.
+	tk2 := testkit.NewTestKit(c, s.store)
+	tk2.MustExec("use test")
+	rows := tk2.MustQuery("explain " + join).Rows()
+	c.Assert(rows[len(rows)-1][0], Equals, "MergeJoin_14")
+
+	tk.MustExec("admin reset stats t1")
+	c.Assert(estRows("select * from t1"), Equals, uint64(10000000))
+	c.Assert(estRows("select * from t2"), Equals, uint64(100000000))
+	tk.MustExec("admin reset stats")
+	c.Assert(planIDs(join), Equals, "TableScan,IndexScan,MergeJoin")
+
+	_, err := tk.Exec("admin set stats t1 column d distinct 10")
+	c.Assert(err, NotNil)
+	_, err = tk.Exec("admin set stats t3 rows 10")
+	c.Assert(err, NotNil)
+}
+
+func (s *testSuite) TestStatsNDV(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (a int primary key, b int, c int, d int, key bc (b, c)) stats_ndv = (b = 2)")
+	planIDs := func(sql string) string {
+		var ids []string
+		for _, row := range tk.MustQuery("explain " + sql).Rows() {
+			id := row[0].(string)
+			ids = append(ids, id[:strings.LastIndex(id, "_")])
+		}
+		return strings.Join(ids, ",")
+	}
+	// The index isn't selective if the column has few distinct values.
+	c.Assert(planIDs("select * from t where b = 1"), Equals, "TableScan")
+	tk.MustQuery("show create table t").Check(testkit.Rows("t CREATE TABLE `t` (\n" +
+		"  `a` int(11) NOT NULL,\n" +
+		"  `b` int(11) DEFAULT NULL,\n" +
+		"  `c` int(11) DEFAULT NULL,\n" +
+		"  `d` int(11) DEFAULT NULL,\n" +
+		" PRIMARY KEY (`a`),\n" +
+		"  KEY `bc` (`b`,`c`)\n" +
+		") ENGINE=InnoDB STATS_NDV=(`b`=2)"))
+
+	// The hints are changed by ALTER TABLE, 0 removes a hint.
+	tk.MustExec("alter table t stats_ndv = (b = 0, index bc = 4)")
+	c.Assert(planIDs("select * from t where b = 1"), Equals, "IndexScan")
+	// The hint of the index is used when all its columns are equal to constants.
+	rows := tk.MustQuery("explain format = 'json' select * from t where b = 1 and c = 1").Rows()
+	var root struct {
+		EstRows uint64 `json:"estRows"`
+	}
+	c.Assert(json.Unmarshal([]byte(rows[0][0].(string)), &root), IsNil)
+	c.Assert(root.EstRows, Equals, uint64(2500000))
+	is := sessionctx.GetDomain(tk.Se.(context.Context)).InfoSchema()
+	tbl, err := is.TableByName(model.NewCIStr("test"), model.NewCIStr("t"))
+	c.Assert(err, IsNil)
+	c.Assert(tbl.Meta().Columns[1].StatsNDV, Equals, int64(0))
+	c.Assert(tbl.Meta().Indices[0].StatsNDV, Equals, int64(4))
+
+	_, err = tk.Exec("alter table t stats_ndv = (e = 10)")
+	c.Assert(err, NotNil)
+	_, err = tk.Exec("alter table t stats_ndv = (index d = 10)")
+	c.Assert(err, NotNil)
+	_, err = tk.Exec("create table t1 (a int) stats_ndv = (b = 10)")
+	c.Assert(err, NotNil)
 }
 
 func (s *testSuite) fillData(tk *testkit.TestKit, table string) {
@@ -329,6 +787,21 @@ func (s *testSuite) TestSelectOrderBy(c *C) {
 	r.Check(testkit.Rows("0", "-1", "-2"))
 	r = tk.MustQuery("select t.d from t order by d;")
 	r.Check(testkit.Rows("1", "2", "3"))
+
+	// Test topn pushed down to the coprocessor.
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (a int primary key, b int, c int, index idx_b(b))")
+	tk.MustExec("insert t values (1, 3, 1), (2, 1, 2), (3, 4, 3), (4, 1, 4), (5, 5, null), (6, null, 6)")
+	r = tk.MustQuery("select a from t order by c limit 2")
+	r.Check(testkit.Rows("5", "1"))
+	r = tk.MustQuery("select a from t where a > 1 order by b desc, a limit 3")
+	r.Check(testkit.Rows("5", "3", "2"))
+	r = tk.MustQuery("select a from t order by b, a desc limit 1, 2")
+	r.Check(testkit.Rows("4", "2"))
+	r = tk.MustQuery("select a from t where b > 1 order by b + c limit 2")
+	r.Check(testkit.Rows("5", "1"))
+	r = tk.MustQuery("select a from t order by c limit 0")
+	r.Check(testkit.Rows())
 }
 
 func (s *testSuite) TestSelectDistinct(c *C) {
@@ -485,6 +958,140 @@ func (s *testSuite) TestUnion(c *C) {
 	r.Check(testkit.Rows("abc", "1"))
 
 	tk.MustExec("commit")
+
+	// The order and the limit above the union all are pushed into every select.
+	tk.MustExec("drop table if exists union_test")
+	tk.MustExec("create table union_test(id int primary key, c int, key c (c))")
+	tk.MustExec("insert union_test values (1, 5), (2, 4), (3, 3), (4, 2), (5, 1)")
+	r = tk.MustQuery("select * from (select id from union_test union all select c from union_test) t order by id limit 3")
+	r.Check(testkit.Rows("1", "1", "2"))
+	r = tk.MustQuery("select * from (select id from union_test union all select c from union_test) t order by id desc limit 1, 2")
+	r.Check(testkit.Rows("5", "4"))
+	r = tk.MustQuery("select * from (select id + 10 as x from union_test union all select c from union_test) t order by x limit 2")
+	r.Check(testkit.Rows("1", "2"))
+	r = tk.MustQuery("select * from (select id, c from union_test union all select c, id from union_test) t where c > 3 order by id")
+	r.Check(testkit.Rows("1 5", "1 5", "2 4", "2 4"))
+	r = tk.MustQuery("select count(*) from (select * from (select id from union_test union all select c from union_test) t limit 7) t")
+	r.Check(testkit.Rows("7"))
+
+	// The result types are aggregated from all the selects.
+	tk.MustExec("drop table if exists union_test")
+	tk.MustExec("create table union_test(a tinyint unsigned, b tinyint, c decimal(5,2), d varchar(3), e datetime(3))")
+	tk.MustExec("insert union_test values (255, -128, 1.25, 'abc', '2016-10-10 10:10:10.123')")
+	r = tk.MustQuery("select a from union_test union all select b from union_test")
+	r.Check(testkit.Rows("255", "-128"))
+	r = tk.MustQuery("select b from union_test union all select c from union_test")
+	r.Check(testkit.Rows("-128.00", "1.25"))
+	r = tk.MustQuery("select c from union_test union all select d from union_test")
+	r.Check(testkit.Rows("1.25", "abc"))
+	r = tk.MustQuery("select d from union_test union all select e from union_test")
+	r.Check(testkit.Rows("abc", "2016-10-10 10:10:10.123"))
+	r = tk.MustQuery("select cast(1 as unsigned) union all select -1")
+	r.Check(testkit.Rows("1", "-1"))
+	r = tk.MustQuery("select null union all select a from union_test")
+	r.Check(testkit.Rows("<nil>", "255"))
+}
+
+func (s *testSuite) TestCommonTableExprClose(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (a int primary key, b int)")
+	var values []string
+	for i := 0; i < 5000; i++ {
+		values = append(values, fmt.Sprintf("(%d, %d)", i, i))
+	}
+	tk.MustExec("insert t values " + strings.Join(values, ", "))
+
+	// The source of the CTE is closed when its references stop reading it early.
+	queries := []string{
+		"with c as (select a, b from t) select 0 from c as x, c as y limit 1",
+		"with c as (select a, b from t) select 0 from (select a from c union all select b from c) u limit 1",
+		"with c as (select a, b from t) select 0 from c where a in (select b from c) limit 1",
+		"with c as (select x.a, y.b from t as x, t as y) select 0 from " +
+			"(select a from c union all select b from c) u limit 1",
+	}
+	for _, sql := range queries {
+		tk.MustQuery(sql).Check(testkit.Rows("0"))
+	}
+	countBefore := runtime.NumGoroutine()
+	for i := 0; i < 5; i++ {
+		for _, sql := range queries {
+			tk.MustQuery(sql).Check(testkit.Rows("0"))
+		}
+	}
+	for i := 0; i < 100; i++ {
+		if runtime.NumGoroutine()-countBefore < 3 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	countAfter := runtime.NumGoroutine()
+	c.Assert(countAfter-countBefore < 3, IsTrue, Commentf("goroutines %d -> %d", countBefore, countAfter))
+	// The rows are read again after the early close.
+	tk.MustQuery("with c as (select a from t) select count(*) from c as x, (select a from c limit 2) as y").
+		Check(testkit.Rows("10000"))
+}
+
+func (s *testSuite) TestUnionConcurrency(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists union_test")
+	tk.MustExec("create table union_test (id int primary key, v int)")
+	var values []string
+	for i := 0; i < 1000; i++ {
+		values = append(values, fmt.Sprintf("(%d, %d)", i, i%7))
+	}
+	tk.MustExec("insert union_test values " + strings.Join(values, ", "))
+
+	// The rows are returned in the order of the children, the same as the sequential execution.
+	queries := []string{
+		"select id from union_test where v = 1 union all select id from union_test where v = 2 union all " +
+			"select v from union_test where id < 300 union all select id from union_test where id > 900",
+		"select id from union_test where id < 10 union all select id from union_test limit 3",
+		"select v from union_test union select v + 1 from union_test order by v",
+		"select count(*) from (select id from union_test union all select id from union_test union all " +
+			"select id from union_test union all select id from union_test union all select id from union_test) t",
+	}
+	for _, sql := range queries {
+		tk.MustExec("set @@tidb_union_concurrency = 1")
+		expected := tk.MustQuery(sql).Rows()
+		tk.MustExec("set @@tidb_union_concurrency = 2")
+		tk.MustQuery(sql).Check(expected)
+	}
+	tk.MustQuery("select count(*) from (select id from union_test union all select id from union_test union all " +
+		"select id from union_test) t").Check(testkit.Rows("3000"))
+
+	// The uncommitted rows are read by all the children.
+	tk.MustExec("begin")
+	tk.MustExec("insert union_test values (1000, 1), (1001, 2)")
+	tk.MustQuery("select id from union_test where id >= 999 union all select id from union_test where id > 999").
+		Check(testkit.Rows("999", "1000", "1001", "1000", "1001"))
+	tk.MustExec("rollback")
+
+	// The children assigning the user variables are executed one by one on the session, which isn't thread-safe.
+	tk.MustExec("set @@tidb_union_concurrency = 4")
+	tk.MustQuery("select @a := id from union_test where v = 1 union all select @a := id from union_test " +
+		"where v = 2 union all select @a := id from union_test where id < 3 union all select @a := 3").
+		Check(tk.MustQuery("select id from union_test where v = 1 union all select id from union_test where v = 2 " +
+			"union all select id from union_test where id < 3 union all select 3").Rows())
+	tk.MustQuery("select @a").Check(testkit.Rows("3"))
+	tk.MustQuery("select id from union_test where id < 2 union all select last_insert_id(id + 10) from union_test " +
+		"where id < 2 union all select last_insert_id(7)").Check(testkit.Rows("0", "1", "10", "11", "7"))
+	tk.MustQuery("select last_insert_id()").Check(testkit.Rows("7"))
+
+	_, err := tk.Exec("set @@tidb_union_concurrency = 0")
+	c.Assert(err, IsNil)
+	_, err = tk.Exec("select id from union_test union all select id from union_test")
+	c.Assert(err, NotNil)
 }
 
 func (s *testSuite) TestIn(c *C) {
@@ -507,6 +1114,80 @@ func (s *testSuite) TestIn(c *C) {
 	tk.MustQuery(queryStr).Check(testkit.Rows("7"))
 }
 
+func (s *testSuite) TestWideIn(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec(`drop table if exists t`)
+	tk.MustExec(`create table t (c1 int primary key, c2 int, key c (c2));`)
+	for i := 0; i <= 200; i++ {
+		tk.MustExec(fmt.Sprintf("insert t values(%d, %d)", i, i))
+	}
+	// The list is long enough to be fused into ranges and probed by a hash set.
+	values := []string{"150", "5", "20"}
+	strValues := make([]string, 0, 90)
+	for i := 10; i < 100; i++ {
+		values = append(values, fmt.Sprintf("%d", i))
+		strValues = append(strValues, fmt.Sprintf("'%d'", i))
+	}
+	list := strings.Join(values, ", ")
+	tk.MustQuery("select count(*) from t where c1 in (" + list + ")").Check(testkit.Rows("92"))
+	tk.MustQuery("select count(*) from t where c2 in (" + list + ")").Check(testkit.Rows("92"))
+	tk.MustQuery("select count(*) from t where abs(c2) in (" + list + ")").Check(testkit.Rows("92"))
+	tk.MustQuery("select count(*) from t where abs(c2) not in (" + list + ")").Check(testkit.Rows("109"))
+	tk.MustQuery("select c1 from t where c2 in (" + list + ") and c1 > 98").Check(testkit.Rows("99", "150"))
+	tk.MustQuery("select count(*) from t where abs(c2) in (" + strings.Join(strValues, ", ") + ")").Check(testkit.Rows("90"))
+
+	// NULL in the list matches nothing, and makes NOT IN never true.
+	tk.MustQuery("select count(*) from t where c2 in (NULL, 1)").Check(testkit.Rows("1"))
+	tk.MustQuery("select count(*) from t where abs(c2) in (NULL, " + list + ")").Check(testkit.Rows("92"))
+	tk.MustQuery("select count(*) from t where abs(c2) not in (NULL, " + list + ")").Check(testkit.Rows("0"))
+}
+
+func (s *testSuite) TestCommonTableExpr(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t, c")
+	tk.MustExec("create table t (a int primary key, b int)")
+	tk.MustExec("insert t values (1, 10), (2, 20), (3, 30)")
+	tk.MustExec("create table c (x int)")
+	tk.MustExec("insert c values (100)")
+
+	// A CTE referred to once is merged into the outer query.
+	tk.MustQuery("with c as (select a, b from t where a > 1) select b from c order by b").Check(testkit.Rows("20", "30"))
+	tk.MustQuery("with c (x, y) as (select a, b from t) select y from c where x = 2").Check(testkit.Rows("20"))
+	// The CTE shadows the table of the same name, unless the table is qualified by the database.
+	tk.MustQuery("with c as (select a as x from t) select count(*) from c").Check(testkit.Rows("3"))
+	tk.MustQuery("with c as (select a as x from t) select x from test.c").Check(testkit.Rows("100"))
+	// A CTE can refer to the ones defined before it.
+	tk.MustQuery("with c1 as (select a from t), c2 as (select a + 1 as a from c1) select sum(a) from c2").Check(testkit.Rows("9"))
+	// A CTE referred to more than once is materialized.
+	tk.MustQuery("with c as (select a, b from t) select x.a, y.b from c as x join c as y on x.a + 1 = y.a order by x.a").
+		Check(testkit.Rows("1 20", "2 30"))
+	tk.MustQuery("with c as (select a, b from t where a < 3) select a from c where a in (select a + 1 from c)").Check(testkit.Rows("2"))
+	tk.MustQuery("with c as (select max(a) as m from t) select m from c union all select m + 1 from c").Check(testkit.Rows("3", "4"))
+	tk.MustQuery("with c as (select a from t) select a from t where exists (select 1 from c where c.a = t.a + 1) order by a").
+		Check(testkit.Rows("1", "2"))
+	tk.MustQuery("select (with c as (select a from t) select count(*) from c as x, c as y)").Check(testkit.Rows("9"))
+
+	_, err := tk.Exec("with c as (select 1), c as (select 2) select * from c")
+	c.Assert(err, NotNil)
+	_, err = tk.Exec("with c (x, y) as (select a from t) select * from c")
+	c.Assert(err, NotNil)
+	// The CTEs can't refer to the later ones or the outer query.
+	_, err = tk.Exec("with c1 as (select * from c2), c2 as (select 1) select * from c1")
+	c.Assert(err, NotNil)
+	_, err = tk.Exec("select * from t where a in (with c as (select b from t as t1 where t1.b = t.b) select b from c)")
+	c.Assert(err, NotNil)
+}
+
 func (s *testSuite) TestTablePKisHandleScan(c *C) {
 	defer func() {
 		s.cleanEnv(c)
@@ -580,93 +1261,1147 @@ func (s *testSuite) TestJoin(c *C) {
 	}()
 	tk := testkit.NewTestKit(c, s.store)
 	tk.MustExec("use test")
-	tk.MustExec("drop table if exists t")
-	tk.MustExec("create table t (c int)")
-	tk.MustExec("insert t values (1)")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (c int)")
+	tk.MustExec("insert t values (1)")
+	cases := []struct {
+		sql    string
+		result [][]interface{}
+	}{
+		{
+			"select 1 from t as a left join t as b on 0",
+			testkit.Rows("1"),
+		},
+		{
+			"select 1 from t as a join t as b on 1",
+			testkit.Rows("1"),
+		},
+	}
+	for _, ca := range cases {
+		result := tk.MustQuery(ca.sql)
+		result.Check(ca.result)
+	}
+
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("drop table if exists t1")
+	tk.MustExec("create table t(c1 int, c2 int)")
+	tk.MustExec("create table t1(c1 int, c2 int)")
+	tk.MustExec("insert into t values(1,1),(2,2)")
+	tk.MustExec("insert into t1 values(2,3),(4,4)")
+	result := tk.MustQuery("select * from t left outer join t1 on t.c1 = t1.c1 where t.c1 = 1 or t1.c2 > 20")
+	result.Check(testkit.Rows("1 1 <nil> <nil>"))
+	result = tk.MustQuery("select * from t1 right outer join t on t.c1 = t1.c1 where t.c1 = 1 or t1.c2 > 20")
+	result.Check(testkit.Rows("<nil> <nil> 1 1"))
+	result = tk.MustQuery("select * from t right outer join t1 on t.c1 = t1.c1 where t.c1 = 1 or t1.c2 > 20")
+	result.Check(testkit.Rows())
+	result = tk.MustQuery("select * from t left outer join t1 on t.c1 = t1.c1 where t1.c1 = 3 or false")
+	result.Check(testkit.Rows())
+	result = tk.MustQuery("select * from t left outer join t1 on t.c1 = t1.c1 and t.c1 != 1 order by t1.c1")
+	result.Check(testkit.Rows("1 1 <nil> <nil>", "2 2 2 3"))
+
+	tk.MustExec("drop table if exists t1")
+	tk.MustExec("drop table if exists t2")
+	tk.MustExec("drop table if exists t3")
+
+	tk.MustExec("create table t1 (c1 int, c2 int)")
+	tk.MustExec("create table t2 (c1 int, c2 int)")
+	tk.MustExec("create table t3 (c1 int, c2 int)")
+
+	tk.MustExec("insert into t1 values (1,1), (2,2), (3,3)")
+	tk.MustExec("insert into t2 values (1,1), (3,3), (5,5)")
+	tk.MustExec("insert into t3 values (1,1), (5,5), (9,9)")
+
+	result = tk.MustQuery("select * from t1 left join t2 on t1.c1 = t2.c1 right join t3 on t2.c1 = t3.c1 order by t1.c1, t1.c2, t2.c1, t2.c2, t3.c1, t3.c2;")
+	result.Check(testkit.Rows("<nil> <nil> <nil> <nil> 5 5", "<nil> <nil> <nil> <nil> 9 9", "1 1 1 1 1 1"))
+	// The outer joins are converted to inner joins by the null-rejecting conditions.
+	result = tk.MustQuery("select t1.c1, t2.c1 from t1 left join t2 on t1.c1 = t2.c1 where t2.c2 = t1.c2 or t2.c2 > 4 order by t1.c1")
+	result.Check(testkit.Rows("1 1", "3 3"))
+	result = tk.MustQuery("select t1.c1, t2.c1, t3.c1 from t1 left join t2 on t1.c1 = t2.c1 join t3 on t2.c2 = t3.c2 order by t1.c1")
+	result.Check(testkit.Rows("1 1 1"))
+	// The join condition of the embedding outer join doesn't reject the null-extended rows of its outer table.
+	result = tk.MustQuery("select t1.c1, t2.c1, t3.c1 from (t1 left join t2 on t1.c1 = t2.c1) left join (t3 join t3 as t4 on t3.c1 = t4.c1) on t2.c2 = t3.c2 order by t1.c1")
+	result.Check(testkit.Rows("1 1 1", "2 <nil> <nil>", "3 3 <nil>"))
+	result = tk.MustQuery("select straight_join t1.c1, t3.c1 from t1, t2, t3 where t1.c1 = t2.c1 and t2.c1 = t3.c1")
+	result.Check(testkit.Rows("1 1"))
+	result = tk.MustQuery("select t1.c1, t2.c1 from t1 straight_join t2 on t1.c1 = t2.c1 straight_join t3 order by t1.c1, t3.c1")
+	result.Check(testkit.Rows("1 1", "1 1", "1 1", "3 3", "3 3", "3 3"))
+
+	tk.MustExec("drop table if exists t1")
+	tk.MustExec("create table t1 (c1 int)")
+	tk.MustExec("insert into t1 values (1), (1), (1)")
+	result = tk.MustQuery("select * from t1 a join t1 b on a.c1 = b.c1;")
+	result.Check(testkit.Rows("1 1", "1 1", "1 1", "1 1", "1 1", "1 1", "1 1", "1 1", "1 1"))
+
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("drop table if exists t1")
+	tk.MustExec("create table t(c1 int,c2 double)")
+	tk.MustExec("create table t1(c1 double,c2 int)")
+	tk.MustExec("insert into t values (1, 2), (1, NULL)")
+	tk.MustExec("insert into t1 values (1, 2), (1, NULL)")
+	result = tk.MustQuery("select * from t a , t1 b where (a.c1, a.c2) = (b.c1, b.c2);")
+	result.Check(testkit.Rows("1 2 1 2"))
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("drop table if exists t1")
+	tk.MustExec("create table t(c1 int, index k(c1))")
+	tk.MustExec("create table t1(c1 int)")
+	tk.MustExec("insert into t values (1),(2),(3),(4),(5),(6),(7)")
+	tk.MustExec("insert into t1 values (1),(2),(3),(4),(5),(6),(7)")
+	result = tk.MustQuery("select a.c1 from t a , t1 b where a.c1 = b.c1 order by a.c1;")
+	result.Check(testkit.Rows("1", "2", "3", "4", "5", "6", "7"))
+	result = tk.MustQuery("select a.c1 from t a , (select * from t1 limit 3) b where a.c1 = b.c1 order by b.c1;")
+	result.Check(testkit.Rows("1", "2", "3"))
+
+	plan.AllowCartesianProduct = false
+	_, err := tk.Exec("select * from t, t1")
+	c.Check(plan.ErrCartesianProductUnsupported.Equal(err), IsTrue)
+	_, err = tk.Exec("select * from t left join t1 on 1")
+	c.Check(plan.ErrCartesianProductUnsupported.Equal(err), IsTrue)
+	_, err = tk.Exec("select * from t right join t1 on 1")
+	c.Check(plan.ErrCartesianProductUnsupported.Equal(err), IsTrue)
+	plan.AllowCartesianProduct = true
+
+}
+
+func (s *testSuite) TestPlanGuards(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t, t1")
+	tk.MustExec("create table t (a int primary key, b int)")
+	tk.MustExec("create table t1 (a int primary key, b int)")
+	values := make([]string, 0, 20)
+	for i := 0; i < 20; i++ {
+		values = append(values, fmt.Sprintf("(%d, %d)", i, i%5))
+	}
+	tk.MustExec("insert t values " + strings.Join(values, ","))
+	tk.MustExec("insert t1 values " + strings.Join(values, ","))
+
+	// The cartesian products are allowed by default.
+	tk.MustQuery("select count(*) from t, t1").Check(testkit.Rows("400"))
+	tk.MustExec("set @@tidb_cartesian_join = 'warn'")
+	tk.MustQuery("select count(*) from t, t1").Check(testkit.Rows("400"))
+	tk.MustExec("set @@tidb_cartesian_join = 'reject'")
+	for _, sql := range []string{
+		"select * from t, t1",
+		"select * from t left join t1 on t.b > t1.b",
+		"select * from t where t.a in (select t.a from t, t1 where t1.b = 1)",
+	} {
+		_, err := tk.Exec(sql)
+		c.Assert(plan.ErrCartesianProductRejected.Equal(err), IsTrue, Commentf("for %s", sql))
+	}
+	tk.MustQuery("select count(*) from t, t1 where t.a = t1.a").Check(testkit.Rows("20"))
+	tk.MustQuery("select count(*) from t where t.b > all (select t1.b from t1 where t1.a < 3)").Check(testkit.Rows("8"))
+	tk.MustExec("set @@tidb_cartesian_join = 'deny'")
+	_, err := tk.Exec("select * from t, t1")
+	c.Assert(plan.ErrWrongArguments.Equal(err), IsTrue)
+	tk.MustExec("set @@tidb_cartesian_join = 'allow'")
+
+	// The tables are estimated by the pseudo statistics, the scans are 10000000 rows and the cartesian product is
+	// far more.
+	tk.MustExec("set @@tidb_max_estimated_rows = 100000000")
+	tk.MustQuery("select count(*) from t where b = 1").Check(testkit.Rows("4"))
+	_, err = tk.Exec("select count(*) from t, t1")
+	c.Assert(plan.ErrTooManyEstimatedRows.Equal(err), IsTrue)
+	tk.MustExec("set @@tidb_max_estimated_rows = 1000000")
+	_, err = tk.Exec("select * from t where exists (select * from t1 where t1.b > t.b)")
+	c.Assert(plan.ErrTooManyEstimatedRows.Equal(err), IsTrue)
+	c.Assert(errors.Cause(err).(*terror.Error).ToSQLError().Code, Equals, uint16(mysql.ErrTooBigSelect))
+	tk.MustExec("set @@tidb_max_estimated_rows = 0")
+	tk.MustQuery("select count(*) from t, t1").Check(testkit.Rows("400"))
+	tk.MustExec("set @@tidb_max_estimated_rows = -1")
+	_, err = tk.Exec("select * from t")
+	c.Assert(plan.ErrWrongArguments.Equal(err), IsTrue)
+	tk.MustExec("set @@tidb_max_estimated_rows = 0")
+
+	// An invalid cost factor fails the statements to be optimized, but it can be corrected.
+	tk.MustExec("set @@tidb_opt_cpu_factor = 'abc'")
+	_, err = tk.Exec("select * from t")
+	c.Assert(plan.ErrWrongArguments.Equal(err), IsTrue)
+	tk.MustExec("set @@tidb_opt_cpu_factor = 0.5")
+	tk.MustQuery("select count(*) from t where b = 1").Check(testkit.Rows("4"))
+}
+
+func (s *testSuite) TestOrderByAndHavingResolution(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t, t2")
+	tk.MustExec("create table t (a int primary key, b int, c int)")
+	tk.MustExec("create table t2 (a int, b int)")
+	tk.MustExec("insert t values (1, 3, 1), (2, 2, 1), (3, 1, 2)")
+	tk.MustExec("insert t2 values (1, 1), (2, 2)")
+
+	tk.MustQuery("select c, count(*) from t group by c order by 2 desc, 1").Check(testkit.Rows("1 2", "2 1"))
+	tk.MustQuery("select a as b, b as a from t order by a").Check(testkit.Rows("3 1", "2 2", "1 3"))
+	tk.MustQuery("select b as a from t order by a + 0").Check(testkit.Rows("3", "2", "1"))
+	tk.MustQuery("select a as x from t group by x order by x desc").Check(testkit.Rows("3", "2", "1"))
+	tk.MustQuery("select count(*) as b from t group by b having b = 1").Check(testkit.Rows("1"))
+	tk.MustQuery("select a, a from t order by a").Check(testkit.Rows("1 1", "2 2", "3 3"))
+	// The subqueries in the order by and having clauses can refer to the outer columns.
+	tk.MustQuery("select a from t order by (select max(t2.b) from t2 where t2.a = t.a), a").Check(testkit.Rows("3", "1", "2"))
+	tk.MustQuery("select a from t where exists (select 1 from t2 having max(t2.b) > t.a)").Check(testkit.Rows("1"))
+	tk.MustQuery("select distinct c from t order by c desc").Check(testkit.Rows("2", "1"))
+	// A qualified name only matches the field of the table it names, the alias of a self join is another table.
+	tk.MustQuery("select t.a, x.a from t join t x on t.b = x.c order by t.a, x.a").Check(testkit.Rows("2 3", "3 1", "3 2"))
+	tk.MustQuery("select t.a, x.a from t join t x on t.b = x.c order by x.a desc").Check(testkit.Rows("2 3", "3 2", "3 1"))
+	// The expressions of DISTINCT ORDER BY can refer to the columns in the select list however they're written.
+	tk.MustQuery("select distinct b from t order by abs(b)").Check(testkit.Rows("1", "2", "3"))
+	tk.MustQuery("select distinct a, b from t order by a + b, a").Check(testkit.Rows("1 3", "2 2", "3 1"))
+	tk.MustQuery("select distinct b as x from t order by abs(t.b) desc").Check(testkit.Rows("3", "2", "1"))
+	tk.MustQuery("select distinct t.b from t order by -b").Check(testkit.Rows("3", "2", "1"))
+	// The subqueries of a grouped select can refer to the grouped columns.
+	tk.MustQuery("select c, (select max(t2.b) from t2 where t2.a = t.c) from t group by c").Check(testkit.Rows("1 1", "2 2"))
+	tk.MustQuery("select c from t group by c having (select count(*) from t2 where t2.a = t.c) > 0").Check(testkit.Rows("1", "2"))
+	tk.MustQuery("select c from t group by c order by (select t2.b from t2 where t2.a = t.c) desc").Check(testkit.Rows("2", "1"))
+	// The grouped subqueries can refer to the outer columns in the having clause.
+	tk.MustQuery("select (select count(*) from t2 group by t2.a having t2.a = t.b) from t").Check(testkit.Rows("<nil>", "1", "1"))
+	tk.MustQuery("select a from t where b in (select t2.a from t2 group by t2.a having t2.a = t.b)").Check(testkit.Rows("2", "3"))
+
+	for _, ca := range []struct {
+		sql string
+		err *terror.Error
+		msg string
+	}{
+		{"select a from t order by 2", plan.ErrUnknownColumn, "Unknown column '2' in 'order clause'"},
+		{"select a from t order by 0", plan.ErrUnknownColumn, "Unknown column '0' in 'order clause'"},
+		{"select a from t group by 2", plan.ErrUnknownColumn, "Unknown column '2' in 'group statement'"},
+		{"select c from t group by c having b > 1", plan.ErrUnknownColumn, "Unknown column 'b' in 'having clause'"},
+		{"select d from t", plan.ErrUnknownColumn, "Unknown column 'd' in 'field list'"},
+		{"select a from t where t.d = 1", plan.ErrUnknownColumn, "Unknown column 't.d' in 'where clause'"},
+		{"select * from t join t2 on t.a = t2.c", plan.ErrUnknownColumn, "Unknown column 't2.c' in 'on clause'"},
+		{"select a, b as a from t order by a", plan.ErrAmbiguousColumn, "Column 'a' in order clause is ambiguous"},
+		{"select t.a, t2.a from t, t2 where t.a = t2.a order by a", plan.ErrAmbiguousColumn, "Column 'a' in order clause is ambiguous"},
+		{"select t.a, x.a from t join t x on t.b = x.c order by a", plan.ErrAmbiguousColumn, "Column 'a' in order clause is ambiguous"},
+		{"select b from t, t2", plan.ErrAmbiguousColumn, "Column 'b' in field list is ambiguous"},
+		{"select distinct c from t order by b", plan.ErrFieldInOrderNotSelect, "Expression #1 of ORDER BY clause is not in SELECT list, references column 'b' which is not in SELECT list; this is incompatible with DISTINCT"},
+		{"select distinct b from t order by c, abs(c)", plan.ErrFieldInOrderNotSelect, "Expression #1 of ORDER BY clause is not in SELECT list, references column 'c' which is not in SELECT list; this is incompatible with DISTINCT"},
+		{"select distinct t.b from t, t2 where t.a = t2.a order by abs(t2.b)", plan.ErrFieldInOrderNotSelect, "Expression #1 of ORDER BY clause is not in SELECT list, references column 't2.b' which is not in SELECT list; this is incompatible with DISTINCT"},
+	} {
+		_, err := tk.Exec(ca.sql)
+		c.Assert(ca.err.Equal(err), IsTrue, Commentf("for %s: %v", ca.sql, err))
+		c.Assert(err.Error(), Matches, ".*"+regexp.QuoteMeta(ca.msg), Commentf("for %s", ca.sql))
+	}
+}
+
+func (s *testSuite) TestOnlyFullGroupBy(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t, t2")
+	tk.MustExec("create table t (a int primary key, b int, c int)")
+	tk.MustExec("create table t2 (a int, b int)")
+	tk.MustExec("insert t values (1, 3, 1), (2, 2, 1), (3, 1, 2)")
+	tk.MustExec("insert t2 values (1, 1), (2, 2)")
+
+	// The nonaggregated columns are allowed without ONLY_FULL_GROUP_BY.
+	tk.MustQuery("select c, b from t group by c order by c").Check(testkit.Rows("1 3", "2 1"))
+	tk.MustQuery("select count(*), b from t").Check(testkit.Rows("3 3"))
+
+	tk.MustExec("set sql_mode = 'ONLY_FULL_GROUP_BY,STRICT_TRANS_TABLES'")
+	for _, ca := range []struct {
+		sql  string
+		rows [][]interface{}
+	}{
+		// The handle is grouped by, so the other columns of the table are functionally dependent on it.
+		{"select a, b from t group by a", testkit.Rows("1 3", "2 2", "3 1")},
+		{"select t.b from t, t2 where t.a = t2.a group by t.a", testkit.Rows("3", "2")},
+		{"select a + 1 from t group by a + 1", testkit.Rows("2", "3", "4")},
+		{"select a + 1 as x from t group by x", testkit.Rows("2", "3", "4")},
+		{"select c, 1 + c from t group by c", testkit.Rows("1 2", "2 3")},
+		{"select c from t group by c order by 1", testkit.Rows("1", "2")},
+		{"select c from t group by c order by count(*), c", testkit.Rows("2", "1")},
+		{"select c as x from t group by c having x > 1", testkit.Rows("2")},
+		{"select max(b), c from t group by c order by max(b)", testkit.Rows("1 2", "3 1")},
+		{"select c, (select max(t2.b) from t2) from t group by c", testkit.Rows("1 2", "2 2")},
+		{"select c, (select max(t2.b) from t2 where t2.a = t.c) from t group by c", testkit.Rows("1 1", "2 2")},
+		{"select c + 1, (select c + 1) from t group by c + 1", testkit.Rows("2 2", "3 3")},
+		{"select c from t group by c having (select max(t2.b) from t2 where t2.a = b) > 1", testkit.Rows("1", "2")},
+	} {
+		tk.MustQuery(ca.sql).Check(ca.rows)
+	}
+	for _, ca := range []struct {
+		sql string
+		err *terror.Error
+		msg string
+	}{
+		{"select c, b from t group by c", plan.ErrFieldNotInGroupBy, "Expression #2 of SELECT list is not in GROUP BY clause and contains nonaggregated column 'test.t.b'"},
+		{"select a + 1, b from t group by a + 1", plan.ErrFieldNotInGroupBy, "Expression #2 of SELECT list"},
+		{"select t2.b from t, t2 where t.a = t2.a group by t.a", plan.ErrFieldNotInGroupBy, "Expression #1 of SELECT list is not in GROUP BY clause and contains nonaggregated column 'test.t2.b'"},
+		{"select c from t group by c order by c, b", plan.ErrFieldNotInGroupBy, "Expression #2 of ORDER BY clause"},
+		{"select c, b > any (select b from t2) from t group by c", plan.ErrFieldNotInGroupBy, "Expression #2 of SELECT list"},
+		// The outer columns referred by the subqueries are checked too.
+		{"select c, (select b) from t group by c", plan.ErrFieldNotInGroupBy, "Expression #2 of SELECT list is not in GROUP BY clause and contains nonaggregated column 'test.t.b'"},
+		{"select c, exists (select 1 from t2 where t2.a = t.b) from t group by c", plan.ErrFieldNotInGroupBy, "Expression #2 of SELECT list"},
+		{"select c from t group by c order by (select t2.b from t2 where t2.a = t.b)", plan.ErrFieldNotInGroupBy, "Expression #1 of ORDER BY clause"},
+		{"select count(*), b from t", plan.ErrMixOfGroupFuncAndFields, "In aggregated query without GROUP BY, expression #2 of SELECT list contains nonaggregated column 'test.t.b'"},
+	} {
+		_, err := tk.Exec(ca.sql)
+		c.Assert(ca.err.Equal(err), IsTrue, Commentf("for %s: %v", ca.sql, err))
+		c.Assert(err.Error(), Matches, ".*"+regexp.QuoteMeta(ca.msg)+".*", Commentf("for %s", ca.sql))
+	}
+	tk.MustExec("set sql_mode = 'STRICT_TRANS_TABLES,NO_ENGINE_SUBSTITUTION'")
+}
+
+func (s *testSuite) TestPlanBaseline(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (a int, b int, index idx(b))")
+	tk.MustExec("insert into t values (1, 1), (2, 2), (3, 3)")
+	defer func(minExecutions uint64) {
+		planbaseline.GlobalManager.MinExecutions = minExecutions
+	}(planbaseline.GlobalManager.MinExecutions)
+	planbaseline.GlobalManager.MinExecutions = 2
+
+	// The statements aren't observed if the plan baselines are disabled.
+	tk.MustQuery("select a from t where b = 1").Check(testkit.Rows("1"))
+	tk.MustQuery("select a from t where b = 1").Check(testkit.Rows("1"))
+	baselinesSQL := "select hints, executions, fallback from information_schema.tidb_plan_baselines where sql = '%s'"
+	tk.MustQuery(fmt.Sprintf(baselinesSQL, "select a from t where b = ?")).Check(testkit.Rows())
+
+	tk.MustExec("set @@tidb_plan_baseline = 1")
+	tk.MustQuery("select a from t where b = 1").Check(testkit.Rows("1"))
+	tk.MustQuery(fmt.Sprintf(baselinesSQL, "select a from t where b = ?")).Check(testkit.Rows())
+	tk.MustQuery("select a from t where b = 2").Check(testkit.Rows("2"))
+	tk.MustQuery(fmt.Sprintf(baselinesSQL, "select a from t where b = ?")).Check(testkit.Rows("use_index(t, idx) 2 0"))
+	// The statements with the hints written by the users are ignored.
+	tk.MustQuery("select /*+ use_index(t) */ a from t where b = 1").Check(testkit.Rows("1"))
+	tk.MustQuery(fmt.Sprintf(baselinesSQL, "select a from t where b = ?")).Check(testkit.Rows("use_index(t, idx) 2 0"))
+
+	// The baseline is persisted.
+	err := kv.RunInNewTxn(s.store, false, func(txn kv.Transaction) error {
+		baselines, err := meta.NewMeta(txn).ListPlanBaselines()
+		c.Assert(err, IsNil)
+		found := false
+		for _, b := range baselines {
+			if b.SQL == "select a from t where b = ?" {
+				found = true
+				c.Assert(b.Hints, Equals, "use_index(t, idx)")
+			}
+		}
+		c.Assert(found, IsTrue)
+		return nil
+	})
+	c.Assert(err, IsNil)
+
+	// The baseline is a table scan, then the index plan regresses, so the statement falls back to the table scan.
+	sql := "select a from t where b > 1 and a > 1"
+	digest := parser.Digest(sql)
+	for i := 0; i < 2; i++ {
+		planbaseline.GlobalManager.Observe(s.store, digest, sql, "use_index(t)", time.Millisecond)
+	}
+	for i := 0; i < 2; i++ {
+		planbaseline.GlobalManager.Observe(s.store, digest, sql, "use_index(t, idx)", time.Second)
+	}
+	tk.MustQuery(fmt.Sprintf(baselinesSQL, "select a from t where b > ? and a > ?")).Check(testkit.Rows("use_index(t) 2 1"))
+	tk.MustQuery(sql).Check(testkit.Rows("2", "3"))
+	tk.MustQuery(fmt.Sprintf(baselinesSQL, "select a from t where b > ? and a > ?")).Check(testkit.Rows("use_index(t) 3 1"))
+	tk.MustQuery("select type, hints, latency, baseline_hints, baseline_latency from information_schema.tidb_plan_baseline_events limit 2").Check(testkit.Rows(
+		"fallback use_index(t, idx) 1 use_index(t) 0.001",
+		"capture use_index(t) 0.001 <nil> <nil>",
+	))
+	tk.MustExec("set @@tidb_plan_baseline = 0")
+}
+
+func (s *testSuite) TestBinding(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (a int, b int, index idx(b))")
+	tk.MustExec("insert into t values (1, 1), (2, 2), (3, 3)")
+	scanOf := func(sql string) string {
+		for _, row := range tk.MustQuery("explain " + sql).Rows() {
+			id := row[0].(string)
+			if strings.HasPrefix(id, "IndexScan_") || strings.HasPrefix(id, "TableScan_") {
+				return id[:strings.Index(id, "_")]
+			}
+		}
+		return ""
+	}
+	c.Assert(scanOf("select a from t where b = 1"), Equals, "IndexScan")
+
+	tk.MustExec("create global binding for select a from t where b = 1 using select /*+ use_index(t) */ a from t where b = 1")
+	rows := tk.MustQuery("select original_sql, bind_sql, default_db from mysql.bind_info").Rows()
+	c.Assert(rows, HasLen, 1)
+	c.Assert(rows[0][0], BytesEquals, []byte("select a from t where b = ?"))
+	c.Assert(rows[0][1], BytesEquals, []byte("select /*+ use_index(t) */ a from t where b = 1"))
+	c.Assert(rows[0][2], BytesEquals, []byte("test"))
+	// The statements which differ only in the literals are planned by the binding.
+	c.Assert(scanOf("SELECT a FROM t WHERE b = 2"), Equals, "TableScan")
+	tk.MustQuery("select a from t where b = 2").Check(testkit.Rows("2"))
+	// The hints written by the users take precedence.
+	c.Assert(scanOf("select /*+ use_index(t, idx) */ a from t where b = 2"), Equals, "IndexScan")
+
+	// Creating the binding again replaces it.
+	tk.MustExec("create binding for select a from t where b = 3 using select /*+ use_index(t, idx) */ a from t where b = 3")
+	rows = tk.MustQuery("select bind_sql from mysql.bind_info").Rows()
+	c.Assert(rows, HasLen, 1)
+	c.Assert(rows[0][0], BytesEquals, []byte("select /*+ use_index(t, idx) */ a from t where b = 3"))
+	c.Assert(scanOf("select a from t where b = 2"), Equals, "IndexScan")
+
+	for _, sql := range []string{
+		"create binding for select a from t where b = 1 using select /*+ use_index(t) */ a from t where b = 1 and a = 1",
+		"create binding for select a from t where b = 1 using select a from t where b = 1",
+	} {
+		_, err := tk.Exec(sql)
+		c.Assert(executor.ErrInvalidBinding.Equal(err), IsTrue, Commentf("for %s: %v", sql, err))
+	}
+	_, err := tk.Exec("create binding for select a from t_not_exists using select /*+ use_index(t_not_exists) */ a from t_not_exists")
+	c.Assert(err, NotNil)
+
+	// The bindings changed by the other servers are seen after they are reloaded.
+	tk.MustExec("update mysql.bind_info set bind_sql = 'select /*+ use_index(t) */ a from t where b = 1'")
+	c.Assert(scanOf("select a from t where b = 2"), Equals, "IndexScan")
+	bindinfo.GlobalHandle.Invalidate()
+	c.Assert(scanOf("select a from t where b = 2"), Equals, "TableScan")
+
+	tk.MustExec("drop global binding for select a from t where b = 1")
+	tk.MustQuery("select * from mysql.bind_info").Check(testkit.Rows())
+	c.Assert(scanOf("select a from t where b = 2"), Equals, "IndexScan")
+
+	// The bindings change the plans of all the users, so they need SUPER.
+	tk.MustExec("create user 'binding_user'@'localhost'")
+	newSession := func() *testkit.TestKit {
+		tk := testkit.NewTestKit(c, s.store)
+		tk.MustExec("use test")
+		tk.Se.(context.Context).GetSessionVars().User = "binding_user@localhost"
+		return tk
+	}
+	tku := newSession()
+	for _, sql := range []string{
+		"create binding for select a from t where b = 1 using select /*+ use_index(t) */ a from t where b = 1",
+		"drop binding for select a from t where b = 1",
+	} {
+		_, err = tku.Exec(sql)
+		c.Assert(executor.ErrSpecificAccessDenied.Equal(err), IsTrue, Commentf("for %s: %v", sql, err))
+	}
+	tk.MustQuery("select * from mysql.bind_info").Check(testkit.Rows())
+	tk.MustExec("grant super on *.* to 'binding_user'@'localhost'")
+	tku = newSession()
+	tku.MustExec("create binding for select a from t where b = 1 using select /*+ use_index(t) */ a from t where b = 1")
+	tku.MustExec("drop binding for select a from t where b = 1")
+	tk.MustExec("drop user 'binding_user'@'localhost'")
+}
+
+func (s *testSuite) TestDisableOptimizerRules(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t1, t2")
+	tk.MustExec("create table t1 (c1 int, c2 int)")
+	tk.MustExec("create table t2 (c1 int, c2 int)")
+	tk.MustExec("insert t1 values (1, 1), (2, 2), (3, 3)")
+	tk.MustExec("insert t2 values (1, 10), (2, 20), (2, 30)")
+
+	sqls := []string{
+		"select t1.c1, t2.c2 from t1, t2 where t1.c1 = t2.c1 and t1.c2 > 1 order by t2.c2",
+		"select /*+ DISABLE_RULES(predicate_push_down, join_reorder) */ t1.c1, t2.c2 from t1, t2 where t1.c1 = t2.c1 and t1.c2 > 1 order by t2.c2",
+	}
+	for _, sql := range sqls {
+		tk.MustQuery(sql).Check(testkit.Rows("2 20", "2 30"))
+	}
+	sql := "select sum(t2.c2) from t1 join t2 on t1.c1 = t2.c1 group by t1.c1 order by t1.c1"
+	tk.MustQuery(sql).Check(testkit.Rows("10", "50"))
+
+	tk.MustQuery("select @@tidb_opt_disable_rules").Check(testkit.Rows(""))
+	tk.MustExec("set @@tidb_opt_disable_rules = 'agg_pushdown, eliminate_projection'")
+	tk.MustQuery("select @@tidb_opt_disable_rules").Check(testkit.Rows("agg_pushdown, eliminate_projection"))
+	tk.MustQuery(sql).Check(testkit.Rows("10", "50"))
+
+	tk.MustExec("set @@tidb_opt_disable_rules = 'no_such_rule'")
+	_, err := tk.Exec(sql)
+	c.Assert(terror.ErrorEqual(err, plan.ErrUnknownRule), IsTrue)
+	tk.MustExec("set @@tidb_opt_disable_rules = ''")
+	_, err = tk.Exec("select /*+ DISABLE_RULES(no_such_rule) */ * from t1")
+	c.Assert(terror.ErrorEqual(err, plan.ErrUnknownRule), IsTrue)
+	tk.MustQuery(sql).Check(testkit.Rows("10", "50"))
+}
+
+func (s *testSuite) TestOptimizerHints(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t1, t2, t3")
+	tk.MustExec("create table t1 (c1 int, c2 int, index idx(c2))")
+	tk.MustExec("create table t2 (c1 int, c2 int)")
+	tk.MustExec("create table t3 (c1 int, c2 int)")
+	tk.MustExec("insert t1 values (1, 1), (2, 2), (3, 3)")
+	tk.MustExec("insert t2 values (1, 10), (2, 20), (2, 30)")
+	tk.MustExec("insert t3 values (10, 100), (30, 300)")
+
+	sqls := []string{
+		"select t1.c1, t2.c2 from t1, t2 where t1.c1 = t2.c1 and t1.c2 > 1 order by t2.c2",
+		"select /*+ USE_INDEX(t1, idx) */ t1.c1, t2.c2 from t1, t2 where t1.c1 = t2.c1 and t1.c2 > 1 order by t2.c2",
+		"select /*+ IGNORE_INDEX(t1, idx) HASH_JOIN(t1) */ t1.c1, t2.c2 from t1, t2 where t1.c1 = t2.c1 and t1.c2 > 1 order by t2.c2",
+		"select /*+ HASH_JOIN(t2) LEADING(t2, t1) */ t1.c1, t2.c2 from t1 join t2 on t1.c1 = t2.c1 where t1.c2 > 1 order by t2.c2",
+		"select /*+ MERGE_JOIN(t1) INL_JOIN(t2) */ t1.c1, t2.c2 from t1, t2 where t1.c1 = t2.c1 and t1.c2 > 1 order by t2.c2",
+	}
+	for _, sql := range sqls {
+		tk.MustQuery(sql).Check(testkit.Rows("2 20", "2 30"))
+	}
+	tk.MustQuery("select /*+ LEADING(t3, t1) */ t1.c1, t3.c2 from t1, t2, t3 where t1.c1 = t2.c1 and t2.c2 = t3.c1 order by t1.c1").Check(testkit.Rows("1 100", "2 300"))
+}
+
+func (s *testSuite) TestMergeJoin(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t1, t2")
+	tk.MustExec("create table t1 (a int primary key, b int, c int, index idx_c(c))")
+	tk.MustExec("create table t2 (a int primary key, b int, c int, index idx_c(c))")
+	tk.MustExec("insert t1 values (1, 1, 1), (2, 2, null), (3, 2, 3), (5, 5, 3)")
+	tk.MustExec("insert t2 values (1, 10, 3), (3, 2, null), (4, 2, 3), (5, 50, 5)")
+
+	tk.MustQuery("select t1.a, t2.b from t1 join t2 on t1.a = t2.a").Check(testkit.Rows("1 10", "3 2", "5 50"))
+	tk.MustQuery("select t1.a, t2.b from t1 left join t2 on t1.a = t2.a").Check(testkit.Rows("1 10", "2 <nil>", "3 2", "5 50"))
+	tk.MustQuery("select t1.a, t2.a from t1 right join t2 on t1.a = t2.a").Check(testkit.Rows("1 1", "3 3", "<nil> 4", "5 5"))
+	tk.MustQuery("select t1.a, t2.a from t1 left join t2 on t1.a = t2.a and t1.b < t2.b").Check(testkit.Rows("1 1", "2 <nil>", "3 <nil>", "5 5"))
+	tk.MustQuery("select t1.a, t2.a from t1 left join t2 on t1.a = t2.a and t2.b > 5 where t1.b > 1").Check(testkit.Rows("2 <nil>", "3 <nil>", "5 5"))
+	tk.MustQuery("select t1.a, t2.a from t1 join t2 on t1.a = t2.a order by t2.a desc").Check(testkit.Rows("5 5", "3 3", "1 1"))
+	// Both inputs have duplicated and null join keys.
+	tk.MustQuery("select t1.a, t2.a from t1 join t2 on t1.c = t2.c order by t1.a, t2.a").Check(testkit.Rows("3 1", "3 4", "5 1", "5 4"))
+	tk.MustQuery("select /*+ MERGE_JOIN(t1) */ t1.a, t2.a from t1 join t2 on t1.b = t2.b order by t1.a, t2.a").Check(testkit.Rows("2 3", "2 4", "3 3", "3 4"))
+	tk.MustQuery("select /*+ MERGE_JOIN(t1) */ t1.a, t2.a from t1 left join t2 on t1.c = t2.c order by t1.a, t2.a").Check(testkit.Rows("1 <nil>", "2 <nil>", "3 1", "3 4", "5 1", "5 4"))
+	tk.MustQuery("select /*+ MERGE_JOIN(t1) */ t1.a, t2.a from t1 right join t2 on t1.b = t2.b order by t2.a, t1.a").Check(testkit.Rows("<nil> 1", "2 3", "3 3", "2 4", "3 4", "<nil> 5"))
+	tk.MustQuery("select /*+ MERGE_JOIN(t1) */ count(*) from t1 join t2 on t1.b = t2.b and t1.a < t2.a").Check(testkit.Rows("3"))
+}
+
+func (s *testSuite) TestIndexLookUpJoin(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t1, t2, t3")
+	tk.MustExec("create table t1 (a int primary key, b int, c int, index idx_c(c))")
+	tk.MustExec("create table t2 (a int primary key, b int, c int, index idx_c(c))")
+	tk.MustExec("create table t3 (a int primary key, b int, index idx_b(b))")
+	tk.MustExec("insert t1 values (1, 1, 1), (2, 2, null), (3, 2, 3), (5, 5, 3)")
+	tk.MustExec("insert t2 values (1, 10, 3), (3, 2, null), (4, 2, 3), (5, 50, 5)")
+	tk.MustExec("insert t3 select generate_series, generate_series % 7 from generate_series(1, 100)")
+	// Small batches make the outer rows be looked up by several concurrent tasks.
+	tk.MustExec("set @@tidb_index_join_batch_size = 2")
+	tk.MustExec("set @@tidb_index_lookup_join_concurrency = 2")
+
+	tk.MustQuery("select /*+ INL_JOIN(t2) */ t1.a, t2.b from t1 join t2 on t1.a = t2.a").Check(testkit.Rows("1 10", "3 2", "5 50"))
+	tk.MustQuery("select /*+ INL_JOIN(t2) */ t1.a, t2.a from t1 left join t2 on t1.c = t2.c order by t1.a, t2.a").Check(testkit.Rows("1 <nil>", "2 <nil>", "3 1", "3 4", "5 1", "5 4"))
+	tk.MustQuery("select /*+ INL_JOIN(t1) */ t1.a, t2.a from t1 right join t2 on t1.c = t2.c order by t2.a, t1.a").Check(testkit.Rows("3 1", "5 1", "<nil> 3", "3 4", "5 4", "<nil> 5"))
+	tk.MustQuery("select /*+ INL_JOIN(t2) */ t1.a, t2.a from t1 left join t2 on t1.a = t2.a and t1.b < t2.b").Check(testkit.Rows("1 1", "2 <nil>", "3 <nil>", "5 5"))
+	tk.MustQuery("select /*+ INL_JOIN(t2) */ t1.a, t2.a from t1 left join t2 on t1.a = t2.a and t2.b > 5 where t1.b > 1").Check(testkit.Rows("2 <nil>", "3 <nil>", "5 5"))
+	tk.MustQuery("select /*+ INL_JOIN(t3) */ count(*) from t1 join t3 on t1.a = t3.b").Check(testkit.Rows("58"))
+	tk.MustQuery("select /*+ INL_JOIN(t3) */ t1.a from t1 join t3 on t1.a = t3.b limit 2").Check(testkit.Rows("1", "1"))
+
+	// The inner rows of the dirty transaction are looked up too.
+	tk.MustExec("begin")
+	tk.MustExec("insert t2 values (6, 6, 1)")
+	tk.MustQuery("select /*+ INL_JOIN(t2) */ t1.a, t2.a from t1 join t2 on t1.c = t2.c order by t1.a, t2.a").Check(testkit.Rows("1 6", "3 1", "3 4", "5 1", "5 4"))
+	tk.MustExec("rollback")
+
+	tk.MustExec("set @@tidb_index_join_batch_size = 0")
+	_, err := tk.Exec("select /*+ INL_JOIN(t2) */ t1.a from t1 join t2 on t1.a = t2.a")
+	c.Assert(err, NotNil)
+}
+
+func (s *testSuite) TestHashJoinConcurrency(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t1, t2")
+	tk.MustExec("create table t1 (a int primary key, b int)")
+	tk.MustExec("create table t2 (a int primary key, b int)")
+	tk.MustExec("insert t1 select generate_series, generate_series % 7 from generate_series(1, 300)")
+	tk.MustExec("insert t2 select generate_series, generate_series * 10 from generate_series(1, 5)")
+	tk.MustExec("set @@tidb_hash_join_concurrency = 3")
+
+	tk.MustQuery("select /*+ HASH_JOIN(t2) */ count(*), sum(t2.b) from t1 join t2 on t1.b = t2.a").Check(testkit.Rows("215 6450"))
+	// The rows of the big table are probed by several workers, and merged in the order of the big table.
+	result := tk.MustQuery("select /*+ HASH_JOIN(t2) */ t1.a, t2.b from t1 left join t2 on t1.b = t2.a order by t1.a")
+	var expected []string
+	for i := 1; i <= 300; i++ {
+		if i%7 >= 1 && i%7 <= 5 {
+			expected = append(expected, fmt.Sprintf("%d %d", i, i%7*10))
+		} else {
+			expected = append(expected, fmt.Sprintf("%d <nil>", i))
+		}
+	}
+	result.Check(testkit.Rows(expected...))
+
+	tk.MustExec("set @@tidb_hash_join_concurrency = -1")
+	_, err := tk.Exec("select /*+ HASH_JOIN(t2) */ t1.a from t1 join t2 on t1.b = t2.a")
+	c.Assert(err, NotNil)
+}
+
+func (s *testSuite) TestHashJoinRuntimeFilter(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t1, t2")
+	tk.MustExec("create table t1 (a int primary key, b int, c char(10))")
+	tk.MustExec("create table t2 (a int, b int, c char(10))")
+	tk.MustExec("insert t1 select generate_series, generate_series, generate_series from generate_series(1, 100)")
+	tk.MustExec("insert t2 values (5, 5, '5'), (50, 50, '50'), (500, 500, '500')")
+
+	// scanRows returns the number of the rows read by the scan of the big table t1.
+	scanRows := func(sql string) interface{} {
+		for _, row := range tk.MustQuery("explain analyze " + sql).Rows() {
+			if strings.Contains(row[1].(string), `"table": "t1"`) {
+				return row[4]
+			}
+		}
+		return nil
+	}
+	cases := []struct {
+		sql    string
+		result []string
+		in     int64
+		minMax int64
+	}{
+		// The handle ranges of t1 are filtered.
+		{"select count(*), sum(t1.b) from t1 join t2 on t1.a = t2.a", []string{"2 55"}, 2, 96},
+		{"select count(*), sum(t1.b) from t1 join t2 on t1.b = t2.b", []string{"2 55"}, 2, 96},
+		// The strings between '5' and '500' are only '5' and '50'.
+		{"select count(*), sum(t1.b) from t1 join t2 on t1.c = t2.c", []string{"2 55"}, 2, 2},
+		{"select count(*), sum(t1.b) from t1 join t2 on t1.b = t2.b and t1.c = t2.c", []string{"2 55"}, 2, 2},
+		// No row of t1 can be joined if t2 is empty.
+		{"select count(*), sum(t1.b) from t1 join t2 on t1.a = t2.a where t2.b > 1000", []string{"0 <nil>"}, 0, 0},
+		// The unmatched rows of the outer table are output.
+		{"select count(*) from t1 left join t2 on t1.a = t2.a", []string{"100"}, 100, 100},
+	}
+	for _, ca := range cases {
+		tk.MustExec("set @@tidb_hash_join_runtime_filter = 1024")
+		tk.MustQuery(ca.sql).Check(testkit.Rows(ca.result...))
+		c.Assert(scanRows(ca.sql), Equals, ca.in, Commentf("for %s", ca.sql))
+		// The min and max keys filter the scan if the keys exceed the limit.
+		tk.MustExec("set @@tidb_hash_join_runtime_filter = 1")
+		tk.MustQuery(ca.sql).Check(testkit.Rows(ca.result...))
+		c.Assert(scanRows(ca.sql), Equals, ca.minMax, Commentf("for %s", ca.sql))
+		tk.MustExec("set @@tidb_hash_join_runtime_filter = 0")
+		tk.MustQuery(ca.sql).Check(testkit.Rows(ca.result...))
+		c.Assert(scanRows(ca.sql), Equals, int64(100), Commentf("for %s", ca.sql))
+	}
+
+	// The filter is derived again for each execution of the join.
+	tk.MustExec("set @@tidb_hash_join_runtime_filter = 1024")
+	tk.MustQuery("select t2.a, (select count(*) from t1 join t2 u on t1.b = u.b where u.a <= t2.a) from t2 order by t2.a").
+		Check(testkit.Rows("5 1", "50 2", "500 2"))
+	tk.MustExec("set @@tidb_hash_join_runtime_filter = -1")
+	_, err := tk.Exec("select t1.b from t1 join t2 on t1.a = t2.a")
+	c.Assert(err, NotNil)
+}
+
+func (s *testSuite) TestChunk(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (a int primary key, b int, c double, d decimal(10, 2), key idx_b (b))")
+	tk.MustExec("insert t select generate_series, generate_series % 3, generate_series * 1.5, generate_series * 0.25 from generate_series(1, 100)")
+	tk.MustExec("insert t values (101, null, null, null)")
+
+	queries := []string{
+		"select a, b, c, d from t",
+		"select a + 1, b * 2, c, d from t where b = 1",
+		"select a from t where a > 10 limit 7, 13",
+		"select a, d from t where b is null or a < 5 limit 3",
+		"select 1, 'x'",
+		"select a, b from t where a in (3, 50, 90) limit 0, 100",
+		"select a, b, c from t order by b desc, c",
+		"select a, c from t where a > 20 order by c desc limit 5, 10",
+		"select b, count(*), sum(c), max(d) from t group by b order by b",
+		"select count(*), sum(a), avg(d) from t",
+		"select count(distinct b), sum(distinct b) from t",
+		"select b from t use index(idx_b) where b > 0 limit 50",
+		"select a, c from t use index(idx_b) where b = 1 order by a limit 3, 20",
+	}
+	var expected [][][]interface{}
+	tk.MustExec("set @@tidb_max_chunk_size = 0")
+	for _, sql := range queries {
+		expected = append(expected, tk.MustQuery(sql).Rows())
+	}
+	// The rows are passed in chunks of different sizes, the chunks of the sources may be larger than the rows
+	// returned by the limits and the selections.
+	for _, size := range []int{1, 2, 3, 1024} {
+		tk.MustExec(fmt.Sprintf("set @@tidb_max_chunk_size = %d", size))
+		for i, sql := range queries {
+			c.Assert(tk.MustQuery(sql).Rows(), DeepEquals, expected[i], Commentf("for %s with size %d", sql, size))
+		}
+		rows := tk.MustQuery("explain analyze select a from t where b = 2 limit 20").Rows()
+		c.Assert(rows[len(rows)-1][4], Equals, int64(20))
+	}
+
+	// The sorted rows are updated by their row keys.
+	tk.MustExec("set @@tidb_max_chunk_size = 2")
+	tk.MustExec("update t set d = -1 where b = 2 order by c desc limit 3")
+	tk.MustQuery("select a from t where d = -1 order by a").Check(testkit.Rows("92", "95", "98"))
+
+	_, err := tk.Exec("set @@tidb_max_chunk_size = -1")
+	c.Assert(variable.ErrWrongValueForVar.Equal(err), IsTrue)
+	tk.MustQuery("select @@tidb_max_chunk_size").Check(testkit.Rows("2"))
+	c.Assert(tk.MustQuery("select a from t").Rows(), HasLen, 101)
+}
+
+func (s *testSuite) TestEnumAndSet(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t, u")
+	tk.MustExec("create table t (id int primary key, e enum('b', 'a', 'c'), s set('y', 'x'), key idx_e (e), key idx_s (s))")
+	tk.MustExec("insert t values (1, 'a', 'x'), (2, 'b', 'y'), (3, 'a', 'x,y'), (4, 'c', 'y'), (5, null, null), (6, 'b', 'y,x')")
+
+	// The values are grouped, distinct and sorted by their numeric values.
+	tk.MustQuery("select e, count(*) from t group by e order by e").Check(testkit.Rows("<nil> 1", "b 2", "a 2", "c 1"))
+	tk.MustQuery("select distinct s from t order by s").Check(testkit.Rows("<nil>", "y", "x", "y,x"))
+	tk.MustQuery("select count(distinct e), count(distinct s) from t").Check(testkit.Rows("3 3"))
+	tk.MustQuery("select e from t use index(idx_e) where e in ('c', 'a', 'b') order by e").Check(testkit.Rows("b", "b", "a", "a", "c"))
+	// Max and min compare the values by their names.
+	tk.MustQuery("select max(e), min(e), max(s), min(s) from t").Check(testkit.Rows("c a y,x x"))
+	tk.MustQuery("select s + 0, max(e), min(e) from t group by s order by s").Check(testkit.Rows("<nil> <nil> <nil>", "1 c b", "2 a a", "3 b a"))
+
+	// The values are compared with the strings by their names and with the numbers by their numeric values, the
+	// ranges of the indexes match the same rows as the table scans.
+	tk.MustQuery("select id from t use index(idx_e) where e > 'a' order by id").Check(testkit.Rows("2", "4", "6"))
+	tk.MustQuery("select id from t use index(idx_e) where e > 1 order by id").Check(testkit.Rows("1", "3", "4"))
+	tk.MustQuery("select id from t use index(idx_s) where s > 'x' order by id").Check(testkit.Rows("2", "3", "4", "6"))
+	conditions := []string{
+		"e = 'a'", "e = 2", "e > 'a'", "e > 1", "e < 'c'", "e between 'a' and 'b'", "e in ('c', 'b', 'a', 1)",
+		"e in ('a', 'z')", "e = 'z'", "e = 'A'", "e = 7", "e > 1.5", "e <= -1", "e != 'a'", "e like 'a%'",
+		"e = 'a' or e > 2", "e <=> 'c'", "e is null", "s = 'y,x'", "s = 'x,y'", "s > 'x'", "s = 3", "s in ('x,y', 'y')",
+	}
+	for _, cond := range conditions {
+		for _, index := range []string{"idx_e", "idx_s"} {
+			expected := tk.MustQuery("select id from t use index() where " + cond + " order by id").Rows()
+			sql := fmt.Sprintf("select id from t use index(%s) where %s order by id", index, cond)
+			c.Assert(tk.MustQuery(sql).Rows(), DeepEquals, expected, Commentf("for %s", sql))
+		}
+	}
+
+	// The join keys are compared in the same way.
+	tk.MustExec("create table u (i int, v varchar(10))")
+	tk.MustExec("insert u values (2, 'a'), (3, 'c'), (1, 'b'), (4, 'd')")
+	tk.MustQuery("select t.id, u.i from t join u on t.e = u.i order by t.id").Check(testkit.Rows("1 2", "2 1", "3 2", "4 3", "6 1"))
+	tk.MustQuery("select t.id, u.i from t join u on t.e = u.v order by t.id").Check(testkit.Rows("1 2", "2 1", "3 2", "4 3", "6 1"))
+	tk.MustQuery("select t.id from t where t.e in (select i from u) order by t.id").Check(testkit.Rows("1", "2", "3", "4", "6"))
+}
+
+func (s *testSuite) TestApplyCacheResults(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t1, t2")
+	tk.MustExec("create table t1 (a int primary key, b int)")
+	tk.MustExec("create table t2 (a int primary key, b int)")
+	tk.MustExec("insert t1 values (1, 1), (2, 1), (3, 2), (4, 2), (5, null), (6, 3)")
+	tk.MustExec("insert t2 values (1, 1), (2, 2), (3, 2), (4, null)")
+
+	check := func() {
+		tk.MustQuery("select a, (select count(*) from t2 where t2.b < t1.b) from t1 order by a").
+			Check(testkit.Rows("1 0", "2 0", "3 1", "4 1", "5 0", "6 3"))
+		// The outer rows with the same b share the inner rows, but the condition is checked with their own a.
+		tk.MustQuery("select a from t1 where a - 1 < any (select t2.a from t2 where t2.b <= t1.b) order by a").
+			Check(testkit.Rows("1", "3"))
+		tk.MustQuery("select a from t1 where exists (select 1 from t2 where t2.b > t1.b) order by a").
+			Check(testkit.Rows("1", "2"))
+	}
+	check()
+	// The cache is disabled.
+	tk.MustExec("set @@tidb_apply_cache_capacity = 0")
+	check()
+	// The cache holds the inner rows of one outer value only.
+	tk.MustExec("set @@tidb_apply_cache_capacity = 256")
+	check()
+
+	tk.MustExec("set @@tidb_apply_cache_capacity = -1")
+	_, err := tk.Exec("select a, (select count(*) from t2 where t2.b < t1.b) from t1")
+	c.Assert(err, NotNil)
+}
+
+func (s *testSuite) TestSortSpill(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	dir, err := ioutil.TempDir("", "sort-spill")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+	executor.SortSpillDir = dir
+	defer func() { executor.SortSpillDir = "" }()
+
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (a int primary key, b varchar(20), c decimal(10, 2), d datetime, e double)")
+	// The values of b, c and d are in the reversed order of a, because 43 * 7 % 50 = 1.
+	var rows, descRows []string
+	for i := 0; i < 50; i++ {
+		v := i * 7 % 50
+		tk.MustExec(fmt.Sprintf("insert t values (%d, 'b%02d', %d.5, '2017-01-%02d 10:00:00', null)", i, v, v, v%28+1))
+		rows = append(rows, fmt.Sprintf("%d.50 2017-01-%02d 10:00:00 <nil>", i, i%28+1))
+		descRows = append(descRows, fmt.Sprintf("%d", (49-i)*43%50))
+	}
+	check := func() {
+		tk.MustQuery("select c, d, e from t order by c").Check(testkit.Rows(rows...))
+		tk.MustQuery("select a from t order by b desc, a").Check(testkit.Rows(descRows...))
+	}
+	check()
+	// Every few rows are spilled as a sorted run, and the runs are merged.
+	tk.MustExec("set @@tidb_mem_quota_sort = 1024")
+	check()
+	files, err := ioutil.ReadDir(dir)
+	c.Assert(err, IsNil)
+	c.Assert(files, HasLen, 0)
+
+	tk.MustExec("set @@tidb_mem_quota_sort = -1")
+	_, err = tk.Exec("select a from t order by b")
+	c.Assert(err, NotNil)
+}
+
+func (s *testSuite) TestGenerateSeries(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustQuery("select * from generate_series(1, 5)").Check(testkit.Rows("1", "2", "3", "4", "5"))
+	tk.MustQuery("select * from generate_series(10, 1, -4)").Check(testkit.Rows("10", "6", "2"))
+	tk.MustQuery("select * from generate_series(5, 1)").Check(testkit.Rows())
+	tk.MustQuery("select s.generate_series * 2 from generate_series(1, 1 + 2) as s where s.generate_series > 1").Check(testkit.Rows("4", "6"))
+	tk.MustQuery("select generate_series from generate_series(1, 10, 3) order by generate_series desc limit 2").Check(testkit.Rows("10", "7"))
+	tk.MustQuery("select count(*), sum(generate_series) from generate_series(1, 1e4)").Check(testkit.Rows("10000 50005000"))
+	tk.MustQuery("select * from generate_series(9223372036854775806, 9223372036854775807)").Check(testkit.Rows("9223372036854775806", "9223372036854775807"))
+
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (a int primary key, b int, index idx_b(b))")
+	tk.MustExec("insert into t select generate_series, generate_series % 3 from generate_series(1, 100)")
+	tk.MustQuery("select count(*), count(distinct b) from t").Check(testkit.Rows("100 3"))
+	tk.MustQuery("select t.a from t join generate_series(1, 20, 10) g on t.a = g.generate_series").Check(testkit.Rows("1", "11"))
+	tk.MustQuery("select a.generate_series, b.generate_series from generate_series(1, 2) a, generate_series(1, 2) b where a.generate_series < b.generate_series").Check(testkit.Rows("1 2"))
+
+	_, err := tk.Exec("select * from generate_series(1, 10, 0)")
+	c.Assert(terror.ErrorEqual(err, plan.ErrWrongArguments), IsTrue)
+	_, err = tk.Exec("select * from generate_series(1, null)")
+	c.Assert(terror.ErrorEqual(err, plan.ErrWrongArguments), IsTrue)
+	_, err = tk.Exec("select * from t, generate_series(1, t.a)")
+	c.Assert(err, NotNil)
+	_, err = tk.Exec("select * from generate_series(1)")
+	c.Assert(terror.ErrorEqual(err, plan.ErrWrongParamCount), IsTrue)
+	_, err = tk.Exec("select * from no_such_func(1, 2)")
+	c.Assert(terror.ErrorEqual(err, plan.ErrUnknownTableFunc), IsTrue)
+}
+
+func (s *testSuite) TestJSONTable(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	doc := `'[{"a": 1, "b": "x", "c": {"d": [1, 2]}}, {"a": 2.5, "b": true}, {"a": "3", "e": null}]'`
+	tk.MustQuery("select * from json_table(" + doc + ", '$[*]' columns (id for ordinality, a int path '$.a', b varchar(10) path '$.b')) as j").
+		Check(testkit.Rows("1 1 x", "2 3 1", "3 3 <nil>"))
+	tk.MustQuery("select j.c, j.d from json_table(" + doc + ", '$[0]' columns (c text path '$.c', d text path '$.c.d[*]')) j").
+		Check(testkit.Rows(`{"d":[1,2]} [1,2]`))
+	tk.MustQuery("select a from json_table(" + doc + ", '$[*]' columns (a decimal(4,1) path '$.a')) j where a > 1 order by a desc").
+		Check(testkit.Rows("3.0", "2.5"))
+	tk.MustQuery("select * from json_table('{}', '$.x[*]' columns (a int path '$')) j").Check(testkit.Rows())
+	tk.MustQuery("select * from json_table(null, '$' columns (a int path '$')) j").Check(testkit.Rows())
+
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (id int primary key, doc varchar(100))")
+	tk.MustExec(`insert t values (1, '{"tags": ["a", "b"]}'), (2, '{"tags": []}'), (3, null), (4, '{"tags": ["c"]}')`)
+	tk.MustQuery("select t.id, j.n, j.tag from t, json_table(t.doc, '$.tags[*]' columns (n for ordinality, tag char(1) path '$')) as j order by t.id, j.n").
+		Check(testkit.Rows("1 1 a", "1 2 b", "4 1 c"))
+	tk.MustQuery("select t.id, j.tag from t join json_table(t.doc, '$.tags[*]' columns (tag char(1) path '$')) j on j.tag != 'a' order by t.id").
+		Check(testkit.Rows("1 b", "4 c"))
+	tk.MustQuery("select id, (select count(*) from json_table(t.doc, '$.tags[*]' columns (tag char(1) path '$')) j) from t order by id").
+		Check(testkit.Rows("1 2", "2 0", "3 0", "4 1"))
+
+	_, err := tk.Exec("select * from json_table('[]', '$')")
+	c.Assert(terror.ErrorEqual(err, plan.ErrWrongArguments), IsTrue)
+	_, err = tk.Exec("select * from generate_series(1, 2) columns (a int path '$')")
+	c.Assert(err, NotNil)
+	_, err = tk.Exec("select * from json_table('[]' columns (a int path '$')) j")
+	c.Assert(terror.ErrorEqual(err, plan.ErrWrongParamCount), IsTrue)
+	_, err = tk.Exec("select * from json_table('[]', '$.' columns (a int path '$')) j")
+	c.Assert(terror.ErrorEqual(err, plan.ErrWrongArguments), IsTrue)
+	_, err = tk.Exec("select * from json_table('[]', '$' columns (a int path 'a')) j")
+	c.Assert(terror.ErrorEqual(err, plan.ErrWrongArguments), IsTrue)
+	_, err = tk.Exec("select * from json_table('[]', '$' columns (a int path '$', a int path '$')) j")
+	c.Assert(terror.ErrorEqual(err, plan.ErrDupFieldName), IsTrue)
+	_, err = tk.Exec("select * from t left join json_table(t.doc, '$' columns (a int path '$')) j on true")
+	c.Assert(terror.ErrorEqual(err, plan.ErrUnsupportedType), IsTrue)
+	r, err := tk.Exec("select * from json_table('[1', '$' columns (a int path '$')) j")
+	c.Assert(err, IsNil)
+	_, err = tidb.GetRows(r)
+	c.Assert(terror.ErrorEqual(err, executor.ErrInvalidJSONText), IsTrue)
+
+	// The values which can't be converted to the column types are NULL with warnings by default.
+	tk.MustQuery(`select * from json_table('[{"a": "abc", "b": "abcdef"}, {"a": "1", "b": "ab"}]', '$[*]' columns (a int path '$.a', b varchar(2) path '$.b')) j`).
+		Check(testkit.Rows("<nil> <nil>", "1 ab"))
+	tk.MustQuery("show warnings").Check(testkit.Rows("Warning 1105 value has been truncated", "Warning 1406 Data Too Long, field len 2, data len 6"))
+	tk.MustQuery(`select * from json_table('{"a": "abc"}', '$' columns (a int path '$.a' default '-1' on error, b int path '$.b' default '7' on empty)) j`).
+		Check(testkit.Rows("-1 7"))
+	tk.MustQuery(`select * from json_table('{"a": "abc"}', '$' columns (a int path '$.b' null on empty error on error, b varchar(2) path '$.b' default 'x' on empty)) j`).
+		Check(testkit.Rows("<nil> x"))
+	r, err = tk.Exec(`select * from json_table('{"a": "abc"}', '$' columns (a int path '$.a' error on error)) j`)
+	c.Assert(err, IsNil)
+	_, err = tidb.GetRows(r)
+	c.Assert(terror.ErrorEqual(err, types.ErrValueTruncated), IsTrue)
+	r, err = tk.Exec(`select * from json_table('{"a": "abc"}', '$' columns (b int path '$.b' error on empty)) j`)
+	c.Assert(err, IsNil)
+	_, err = tidb.GetRows(r)
+	c.Assert(terror.ErrorEqual(err, executor.ErrMissingJSONValue), IsTrue)
+	_, err = tk.Exec(`select * from json_table('{}', '$' columns (a int path '$.a' default 'x' on empty)) j`)
+	c.Assert(terror.ErrorEqual(err, plan.ErrInvalidDefault), IsTrue)
+}
+
+func (s *testSuite) TestProfile(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustQuery("select count(*) > 0, round(sum(flat_percent)) from tidb_profile('heap')").Check(testkit.Rows("1 100"))
+	tk.MustQuery("select count(*) from tidb_profile('Heap') where flat > cum or cum_percent > 100").Check(testkit.Rows("0"))
+	tk.MustQuery("select count(*) from (select name from tidb_profile('heap') order by cum desc limit 3) p").Check(testkit.Rows("3"))
+	tk.MustQuery("select count(*) from tidb_profile('cpu', 0.1) where flat > cum or cum_percent > 100").Check(testkit.Rows("0"))
+
+	for _, sql := range []string{
+		"select * from tidb_profile('disk')",
+		"select * from tidb_profile('heap', 1)",
+		"select * from tidb_profile('cpu', 0)",
+		"select * from tidb_profile('cpu', 11)",
+		"select * from tidb_profile('cpu', null)",
+	} {
+		_, err := tk.Exec(sql)
+		c.Assert(terror.ErrorEqual(err, plan.ErrWrongArguments), IsTrue, Commentf("for %s", sql))
+	}
+	_, err := tk.Exec("select * from tidb_profile()")
+	c.Assert(terror.ErrorEqual(err, plan.ErrWrongParamCount), IsTrue)
+
+	// The capture of the CPU profile stops once the statement is killed.
+	rs, err := tk.Exec("select * from tidb_profile('cpu', 10)")
+	c.Assert(err, IsNil)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		tk.Se.Kill()
+	}()
+	start := time.Now()
+	_, err = tidb.GetRows(rs)
+	c.Assert(kv.ErrQueryInterrupted.Equal(err), IsTrue)
+	c.Assert(time.Since(start) < 5*time.Second, IsTrue)
+	c.Assert(rs.Close(), IsNil)
+
+	// The profiles are only captured for the users with SUPER or PROCESS.
+	tk.MustExec("create user 'profile_user'@'localhost'")
+	defer tk.MustExec("drop user 'profile_user'@'localhost'")
+	tku := testkit.NewTestKit(c, s.store)
+	tku.MustExec("use test")
+	tku.Se.(context.Context).GetSessionVars().User = "profile_user@localhost"
+	_, err = tku.Exec("select * from tidb_profile('heap')")
+	c.Assert(plan.ErrSpecificAccessDenied.Equal(err), IsTrue, Commentf("err %v", err))
+	tk.MustExec("grant process on *.* to 'profile_user'@'localhost'")
+	tku = testkit.NewTestKit(c, s.store)
+	tku.MustExec("use test")
+	tku.Se.(context.Context).GetSessionVars().User = "profile_user@localhost"
+	tku.MustQuery("select count(*) > 0 from tidb_profile('heap')").Check(testkit.Rows("1"))
+}
+
+func (s *testSuite) TestRowHistory(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t, t1")
+	tk.MustExec("create table t (id int primary key, v int not null)")
+	tk.MustExec("insert t values (1, 10), (2, 90)")
+	tk.MustExec("update t set v = 20 where id = 1")
+	tk.MustExec("delete from t where id = 1")
+	tk.MustExec("insert t values (1, 30)")
+
+	result := tk.MustQuery("select _tidb_deleted, id, v from tidb_row_history('t', 1)")
+	result.Check(testkit.Rows("0 1 30", "1 <nil> <nil>", "0 1 20", "0 1 10"))
+	result = tk.MustQuery("select count(distinct _tidb_commit_ts), count(_tidb_commit_time) from tidb_row_history('test.t', 1)")
+	result.Check(testkit.Rows("4 4"))
+	tk.MustQuery("select v from tidb_row_history('t', 2) where _tidb_deleted = 0").Check(testkit.Rows("90"))
+	tk.MustQuery("select * from tidb_row_history('t', 3)").Check(testkit.Rows())
+
+	tk.MustExec("alter table t add column w int")
+	tk.MustExec("update t set w = 5 where id = 1")
+	tk.MustQuery("select v, w from tidb_row_history('t', 1) where _tidb_deleted = 0").Check(testkit.Rows("30 5", "30 <nil>", "20 <nil>", "10 <nil>"))
+	// The versions are decoded with the columns when they're committed.
+	tk.MustExec("alter table t modify w bigint")
+	tk.MustExec("update t set v = 40 where id = 1")
+	tk.MustQuery("select v, w from tidb_row_history('t', 1) where _tidb_deleted = 0").Check(testkit.Rows("40 5", "30 5", "30 <nil>", "20 <nil>", "10 <nil>"))
+
+	// The table without the integer primary key uses the hidden handle.
+	tk.MustExec("create table t1 (a int, b int)")
+	tk.MustExec("insert t1 values (1, 1)")
+	tk.MustExec("update t1 set b = 2")
+	tk.MustQuery("select a, b from tidb_row_history('t1', 1)").Check(testkit.Rows("1 2", "1 1"))
+
+	_, err := tk.Exec("select * from tidb_row_history(1, 1)")
+	c.Assert(terror.ErrorEqual(err, plan.ErrWrongArguments), IsTrue)
+	_, err = tk.Exec("select * from tidb_row_history('t2', 1)")
+	c.Assert(err, NotNil)
+	_, err = tk.Exec("select * from tidb_row_history('t')")
+	c.Assert(terror.ErrorEqual(err, plan.ErrWrongParamCount), IsTrue)
+}
+
+func (s *testSuite) TestLowerCaseTableNames(c *C) {
+	defer func() {
+		model.SetLowerCaseTableNames(model.CaseInsensitiveNames)
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+
+	// The names are stored as given and compared case insensitively by default.
+	tk.MustExec("create database CaseDB")
+	tk.MustExec("create table casedb.MyTable (a int)")
+	tk.MustExec("insert CASEDB.mytable values (1)")
+	tk.MustQuery("select MYTABLE.a from casedb.MyTable").Check(testkit.Rows("1"))
+	tk.MustQuery("show tables from casedb").Check(testkit.Rows("MyTable"))
+	tk.MustQuery("select table_schema, table_name from information_schema.tables where table_schema = 'CaseDB'").
+		Check(testkit.Rows("CaseDB MyTable"))
+	tk.MustExec("drop database casedb")
+
+	// The names are compared case sensitively.
+	model.SetLowerCaseTableNames(model.CaseSensitiveNames)
+	tk.MustExec("create database CaseDB")
+	tk.MustExec("create table CaseDB.T (a int)")
+	tk.MustExec("create table CaseDB.t (a int)")
+	tk.MustExec("insert CaseDB.T values (1)")
+	tk.MustExec("insert CaseDB.t values (2)")
+	tk.MustQuery("select T.a from CaseDB.T").Check(testkit.Rows("1"))
+	tk.MustQuery("select x.a from CaseDB.t as x").Check(testkit.Rows("2"))
+	tk.MustQuery("show tables from CaseDB").Check(testkit.Rows("T", "t"))
+	_, err := tk.Exec("select a from casedb.T")
+	c.Assert(err, NotNil)
+	_, err = tk.Exec("select t.a from CaseDB.T")
+	c.Assert(err, NotNil)
+	_, err = tk.Exec("select X.a from CaseDB.t as x")
+	c.Assert(err, NotNil)
+	tk.MustExec("use CaseDB")
+	tk.MustQuery("select a from T").Check(testkit.Rows("1"))
+	// The names of the information schema are always case insensitive.
+	tk.MustQuery("select count(*) from INFORMATION_SCHEMA.TABLES where table_schema = 'CaseDB'").Check(testkit.Rows("2"))
+	tk.MustQuery("select count(*) from information_schema.tables where table_schema = 'CaseDB'").Check(testkit.Rows("2"))
+	tk.MustExec("drop database CaseDB")
+
+	// The names are stored in lowercase.
+	model.SetLowerCaseTableNames(model.LowerCaseNames)
+	tk.MustExec("create database CaseDB")
+	tk.MustExec("create table CaseDB.MyTable (a int)")
+	tk.MustExec("insert casedb.MYTABLE values (1)")
+	tk.MustQuery("select MyTable.a from CASEDB.mytable").Check(testkit.Rows("1"))
+	tk.MustQuery("show databases like 'casedb'").Check(testkit.Rows("casedb"))
+	tk.MustQuery("show tables from CaseDB").Check(testkit.Rows("mytable"))
+	tk.MustQuery("select table_schema, table_name from information_schema.tables where table_schema = 'casedb'").
+		Check(testkit.Rows("casedb mytable"))
+	tk.MustExec("drop database CaseDB")
+}
+func (s *testSuite) TestDecorrelateSubquery(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t1, t2")
+	tk.MustExec("create table t1 (a int, b int)")
+	tk.MustExec("create table t2 (a int, b int)")
+	tk.MustExec("insert t1 values (1, 1), (2, 2), (3, null), (null, 4)")
+	tk.MustExec("insert t2 values (1, 1), (1, 2), (2, null), (null, 3), (4, 4)")
+
 	cases := []struct {
 		sql    string
-		result [][]interface{}
+		result []string
 	}{
 		{
-			"select 1 from t as a left join t as b on 0",
-			testkit.Rows("1"),
+			sql:    "select a from t1 where b in (select t2.b from t2 where t2.a = t1.a) order by a",
+			result: []string{"1"},
 		},
 		{
-			"select 1 from t as a join t as b on 1",
-			testkit.Rows("1"),
+			sql:    "select a from t1 where b + 1 = any (select t2.b from t2 where t2.a = t1.a) order by a",
+			result: []string{"1"},
+		},
+		{
+			sql:    "select a from t1 where b in (select t2.b from t2 where t2.a = t1.a and t2.b > 0) and a > 0 order by a",
+			result: []string{"1"},
+		},
+		{
+			sql:    "select a from t1 where b not in (select t2.b from t2 where t2.a = t1.a) order by a",
+			result: []string{"<nil>", "3"},
+		},
+		{
+			sql:    "select a, b in (select t2.b from t2 where t2.a = t1.a) from t1 order by a",
+			result: []string{"<nil> 0", "1 1", "2 <nil>", "3 0"},
+		},
+		{
+			sql:    "select a, (select count(*) from t2 where t2.a = t1.a) from t1 order by a",
+			result: []string{"<nil> 0", "1 2", "2 1", "3 0"},
+		},
+		{
+			sql:    "select a, (select sum(t2.b) from t2 where t2.a = t1.a), (select ifnull(max(t2.b), -1) from t2 where t2.a = t1.a) from t1 order by a",
+			result: []string{"<nil> <nil> -1", "1 3 2", "2 <nil> -1", "3 <nil> -1"},
+		},
+		{
+			sql:    "select a from t1 where (select count(t2.b) from t2 where t2.a = t1.a) = 0 order by a",
+			result: []string{"<nil>", "2", "3"},
+		},
+		{
+			sql:    "select a from t1 where b > (select min(t2.b) from t2 where t2.a = t1.a and t2.b is not null) order by a",
+			result: []string{},
 		},
 	}
 	for _, ca := range cases {
-		result := tk.MustQuery(ca.sql)
-		result.Check(ca.result)
+		tk.MustQuery(ca.sql).Check(testkit.Rows(ca.result...))
+		tk.MustQuery(strings.Replace(ca.sql, "select", "select /*+ DISABLE_RULES(decorrelate) */", 1)).Check(testkit.Rows(ca.result...))
 	}
-
-	tk.MustExec("drop table if exists t")
-	tk.MustExec("drop table if exists t1")
-	tk.MustExec("create table t(c1 int, c2 int)")
-	tk.MustExec("create table t1(c1 int, c2 int)")
-	tk.MustExec("insert into t values(1,1),(2,2)")
-	tk.MustExec("insert into t1 values(2,3),(4,4)")
-	result := tk.MustQuery("select * from t left outer join t1 on t.c1 = t1.c1 where t.c1 = 1 or t1.c2 > 20")
-	result.Check(testkit.Rows("1 1 <nil> <nil>"))
-	result = tk.MustQuery("select * from t1 right outer join t on t.c1 = t1.c1 where t.c1 = 1 or t1.c2 > 20")
-	result.Check(testkit.Rows("<nil> <nil> 1 1"))
-	result = tk.MustQuery("select * from t right outer join t1 on t.c1 = t1.c1 where t.c1 = 1 or t1.c2 > 20")
-	result.Check(testkit.Rows())
-	result = tk.MustQuery("select * from t left outer join t1 on t.c1 = t1.c1 where t1.c1 = 3 or false")
-	result.Check(testkit.Rows())
-	result = tk.MustQuery("select * from t left outer join t1 on t.c1 = t1.c1 and t.c1 != 1 order by t1.c1")
-	result.Check(testkit.Rows("1 1 <nil> <nil>", "2 2 2 3"))
-
-	tk.MustExec("drop table if exists t1")
-	tk.MustExec("drop table if exists t2")
-	tk.MustExec("drop table if exists t3")
-
-	tk.MustExec("create table t1 (c1 int, c2 int)")
-	tk.MustExec("create table t2 (c1 int, c2 int)")
-	tk.MustExec("create table t3 (c1 int, c2 int)")
-
-	tk.MustExec("insert into t1 values (1,1), (2,2), (3,3)")
-	tk.MustExec("insert into t2 values (1,1), (3,3), (5,5)")
-	tk.MustExec("insert into t3 values (1,1), (5,5), (9,9)")
-
-	result = tk.MustQuery("select * from t1 left join t2 on t1.c1 = t2.c1 right join t3 on t2.c1 = t3.c1 order by t1.c1, t1.c2, t2.c1, t2.c2, t3.c1, t3.c2;")
-	result.Check(testkit.Rows("<nil> <nil> <nil> <nil> 5 5", "<nil> <nil> <nil> <nil> 9 9", "1 1 1 1 1 1"))
-
-	tk.MustExec("drop table if exists t1")
-	tk.MustExec("create table t1 (c1 int)")
-	tk.MustExec("insert into t1 values (1), (1), (1)")
-	result = tk.MustQuery("select * from t1 a join t1 b on a.c1 = b.c1;")
-	result.Check(testkit.Rows("1 1", "1 1", "1 1", "1 1", "1 1", "1 1", "1 1", "1 1", "1 1"))
-
-	tk.MustExec("drop table if exists t")
-	tk.MustExec("drop table if exists t1")
-	tk.MustExec("create table t(c1 int,c2 double)")
-	tk.MustExec("create table t1(c1 double,c2 int)")
-	tk.MustExec("insert into t values (1, 2), (1, NULL)")
-	tk.MustExec("insert into t1 values (1, 2), (1, NULL)")
-	result = tk.MustQuery("select * from t a , t1 b where (a.c1, a.c2) = (b.c1, b.c2);")
-	result.Check(testkit.Rows("1 2 1 2"))
-	tk.MustExec("drop table if exists t")
-	tk.MustExec("drop table if exists t1")
-	tk.MustExec("create table t(c1 int, index k(c1))")
-	tk.MustExec("create table t1(c1 int)")
-	tk.MustExec("insert into t values (1),(2),(3),(4),(5),(6),(7)")
-	tk.MustExec("insert into t1 values (1),(2),(3),(4),(5),(6),(7)")
-	result = tk.MustQuery("select a.c1 from t a , t1 b where a.c1 = b.c1 order by a.c1;")
-	result.Check(testkit.Rows("1", "2", "3", "4", "5", "6", "7"))
-	result = tk.MustQuery("select a.c1 from t a , (select * from t1 limit 3) b where a.c1 = b.c1 order by b.c1;")
-	result.Check(testkit.Rows("1", "2", "3"))
-
-	plan.AllowCartesianProduct = false
-	_, err := tk.Exec("select * from t, t1")
-	c.Check(plan.ErrCartesianProductUnsupported.Equal(err), IsTrue)
-	_, err = tk.Exec("select * from t left join t1 on 1")
-	c.Check(plan.ErrCartesianProductUnsupported.Equal(err), IsTrue)
-	_, err = tk.Exec("select * from t right join t1 on 1")
-	c.Check(plan.ErrCartesianProductUnsupported.Equal(err), IsTrue)
-	plan.AllowCartesianProduct = true
-
 }
 
 func (s *testSuite) TestMultiJoin(c *C) {
@@ -814,6 +2549,105 @@ func (s *testSuite) TestIndexScan(c *C) {
 	result.Check(testkit.Rows())
 }
 
+func (s *testSuite) TestIndexLookupConcurrency(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (a int primary key, b int, c int, index idx_b (b))")
+	var values []string
+	for i := 0; i < 500; i++ {
+		values = append(values, fmt.Sprintf("(%d, %d, %d)", i, (i*37)%500, i%3))
+	}
+	tk.MustExec("insert t values " + strings.Join(values, ", "))
+
+	queries := []string{
+		"select * from t use index (idx_b) where b >= 100 order by b",
+		"select * from t use index (idx_b) where b < 300 and c = 1 order by a",
+		"select a, c from t use index (idx_b) where b > 50 order by b limit 7",
+		"select count(c) from t use index (idx_b) where b between 10 and 400",
+	}
+	for _, sql := range queries {
+		tk.MustExec("set @@tidb_index_lookup_concurrency = 1")
+		tk.MustExec("set @@tidb_index_lookup_size = 20480")
+		expected := tk.MustQuery(sql).Rows()
+		tk.MustExec("set @@tidb_index_lookup_concurrency = 5")
+		tk.MustExec("set @@tidb_index_lookup_size = 3")
+		tk.MustQuery(sql).Check(expected)
+	}
+	tk.MustQuery("select count(c) from t use index (idx_b) where b >= 0").Check(testkit.Rows("500"))
+
+	tk.MustExec("set @@tidb_index_lookup_size = 0")
+	_, err := tk.Exec("select * from t use index (idx_b) where b > 10")
+	c.Assert(err, NotNil)
+	tk.MustExec("set @@tidb_index_lookup_size = 20480")
+	tk.MustExec("set @@tidb_index_lookup_concurrency = -1")
+	_, err = tk.Exec("select * from t use index (idx_b) where b > 10")
+	c.Assert(err, NotNil)
+}
+
+func (s *testSuite) TestNullEQIndexScan(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (a int primary key, b int, c int, index idx_b_c(b, c))")
+	tk.MustExec("insert t values (1, null, 1), (2, null, null), (3, 0, 1), (4, 1, null), (5, 1, 2), (6, 2, 2)")
+	tk.MustQuery("select a from t use index (idx_b_c) where b <=> 1 order by a").Check(testkit.Rows("4", "5"))
+	tk.MustQuery("select a from t use index (idx_b_c) where b <=> null order by a").Check(testkit.Rows("1", "2"))
+	tk.MustQuery("select a from t use index (idx_b_c) where b <=> 1 and c <=> null").Check(testkit.Rows("4"))
+	tk.MustQuery("select a from t use index (idx_b_c) where not (b <=> 1) order by a").Check(testkit.Rows("1", "2", "3", "6"))
+	tk.MustQuery("select a from t use index (idx_b_c) where not (b <=> null) order by a").Check(testkit.Rows("3", "4", "5", "6"))
+	tk.MustQuery("select a from t use index (idx_b_c) where b is true order by a").Check(testkit.Rows("4", "5", "6"))
+	tk.MustQuery("select a from t use index (idx_b_c) where b is false or b is null order by a").Check(testkit.Rows("1", "2", "3"))
+	tk.MustQuery("select a from t use index (idx_b_c) where b is not false order by a").Check(testkit.Rows("1", "2", "4", "5", "6"))
+	tk.MustQuery("select a from t where a <=> 3 and b <=> c - 1").Check(testkit.Rows("3"))
+	tk.MustQuery("select a from t where b = c and c <=> 2").Check(testkit.Rows("6"))
+}
+
+func (s *testSuite) TestIndexMerge(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (a int, b int, c int, index idx_a (a), index idx_b (b))")
+	tk.MustExec("insert t values (1, 1, 1), (1, 2, 2), (2, 2, 3), (3, 3, 4), (4, 1, 5), (null, 2, 6)")
+
+	hasIndexMerge := func(sql string) bool {
+		for _, row := range tk.MustQuery("explain " + sql).Rows() {
+			if strings.HasPrefix(row[0].(string), "IndexMerge_") {
+				return true
+			}
+		}
+		return false
+	}
+	sql := "select * from t where a = 1 or b = 2"
+	c.Assert(hasIndexMerge(sql), IsTrue)
+	tk.MustQuery(sql).Check(testkit.Rows("1 1 1", "1 2 2", "2 2 3", "<nil> 2 6"))
+	sql = "select c from t where (a > 3 or b = 3) and c > 4"
+	c.Assert(hasIndexMerge(sql), IsTrue)
+	tk.MustQuery(sql).Check(testkit.Rows("5"))
+	tk.MustQuery("select c from t where a = 5 or b = 5").Check(testkit.Rows())
+
+	// The rows changed in the transaction are merged.
+	tk.MustExec("begin")
+	tk.MustExec("insert t values (5, 5, 7)")
+	tk.MustExec("delete from t where c = 2")
+	tk.MustExec("update t set a = 1 where c = 4")
+	c.Assert(hasIndexMerge("select c from t where a = 1 or b = 5"), IsTrue)
+	tk.MustQuery("select c from t where a = 1 or b = 5").Check(testkit.Rows("1", "4", "7"))
+	tk.MustExec("rollback")
+}
+
 func (s *testSuite) TestSubquerySameTable(c *C) {
 	defer func() {
 		s.cleanEnv(c)
@@ -901,6 +2735,40 @@ func (s *testSuite) TestInSubquery(c *C) {
 	tk.MustQuery("select a from t1 where (a in (select a from t1))").Check(testkit.Rows("281.37"))
 }
 
+func (s *testSuite) TestNullAwareSemiJoin(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t, s, e")
+	tk.MustExec("create table t (id int, a int, b int)")
+	tk.MustExec("create table s (c int, d int)")
+	tk.MustExec("create table e (x int)")
+	tk.MustExec("insert t values (1, 1, 1), (2, 2, NULL), (3, NULL, 3)")
+	tk.MustExec("insert s values (1, 1), (NULL, 2)")
+
+	tk.MustQuery("select id, a in (select c from s), a not in (select c from s) from t order by id").Check(testkit.Rows(
+		"1 1 0", "2 <nil> <nil>", "3 <nil> <nil>"))
+	tk.MustQuery("select id from t where a not in (select c from s where c is not null) order by id").Check(testkit.Rows("2"))
+	// NULL is in or not in an empty set definitely.
+	tk.MustQuery("select id, a in (select x from e), a not in (select x from e) from t order by id").Check(testkit.Rows(
+		"1 0 1", "2 0 1", "3 0 1"))
+	tk.MustQuery("select id from t where a not in (select x from e) order by id").Check(testkit.Rows("1", "2", "3"))
+	// Only the columns which aren't NULL are compared.
+	tk.MustQuery("select id, (a, b) in (select c, d from s), (a, b) not in (select c, d from s) from t order by id").Check(testkit.Rows(
+		"1 1 0", "2 <nil> <nil>", "3 0 1"))
+	// The conditions on the outer table or the inner table only.
+	tk.MustQuery("select id, a in (select 1 from s) from t order by id").Check(testkit.Rows("1 1", "2 0", "3 <nil>"))
+	tk.MustQuery("select id from t where a not in (select 1 from s) order by id").Check(testkit.Rows("2"))
+	tk.MustQuery("select id from t where 2 not in (select c from s)").Check(testkit.Rows())
+	tk.MustQuery("select id from t where 3 not in (select c from s where c is not null) order by id").Check(testkit.Rows("1", "2", "3"))
+	// EXISTS is never NULL.
+	tk.MustQuery("select id, exists (select 1 from s where s.c = t.a) from t order by id").Check(testkit.Rows("1 1", "2 0", "3 0"))
+	tk.MustQuery("select id from t where not exists (select 1 from s where s.c = t.a) order by id").Check(testkit.Rows("2", "3"))
+}
+
 func (s *testSuite) TestDefaultNull(c *C) {
 	defer func() {
 		s.cleanEnv(c)
@@ -1083,6 +2951,24 @@ func (s *testSuite) TestBuiltin(c *C) {
 		{".*", "abcd", 1},
 	}
 	patternMatching(c, tk, "regexp", testCases)
+
+	// for the control functions pushed down to the coprocessor
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (a int, b int, c varchar(10), d varchar(10))")
+	tk.MustExec("insert t values (1, null, 'x', null), (2, 2, null, 'y'), (3, 0, 'z', 'z')")
+	result = tk.MustQuery("select a from t where case when b then b else a end = 2")
+	result.Check(testkit.Rows("2"))
+	result = tk.MustQuery("select a from t where if(b, c, d) is null")
+	result.Check(testkit.Rows("1", "2"))
+	result = tk.MustQuery("select a from t where ifnull(c, d) = 'y'")
+	result.Check(testkit.Rows("2"))
+	result = tk.MustQuery("select a from t where coalesce(b, a) = 1")
+	result.Check(testkit.Rows("1"))
+	// The results which need a conversion are evaluated in TiDB.
+	result = tk.MustQuery("select a from t where if(b, c, a) = '3'")
+	result.Check(testkit.Rows("3"))
+	result = tk.MustQuery("select a from t where coalesce(c, b) = '2'")
+	result.Check(testkit.Rows("2"))
 }
 
 func (s *testSuite) TestToPBExpr(c *C) {
@@ -1137,6 +3023,22 @@ func (s *testSuite) TestToPBExpr(c *C) {
 	result.Check(testkit.Rows("1"))
 	result = tk.MustQuery("select * from t where not(a != 1 and a != 2)")
 	result.Check(testkit.Rows("1", "2"))
+
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (a datetime, b int)")
+	tk.MustExec("insert t values ('2017-01-31 10:10:10', 1)")
+	tk.MustExec("insert t values ('2016-02-29 00:00:00', -1)")
+	result = tk.MustQuery("select b from t where a + interval 1 month = '2017-02-28 10:10:10'")
+	result.Check(testkit.Rows("1"))
+	result = tk.MustQuery("select b from t where a - interval b year = '2017-02-28'")
+	result.Check(testkit.Rows("-1"))
+	result = tk.MustQuery("select b from t where interval -b day + a = '2017-01-30 10:10:10'")
+	result.Check(testkit.Rows("1"))
+	result = tk.MustQuery("select b from t where date_sub(a, interval b * 10000 year) is null")
+	result.Check(testkit.Rows("1", "-1"))
+	result = tk.MustQuery("select a + interval b day, adddate(a, interval b hour), a - interval '1 1' day_hour from t")
+	result.Check(testkit.Rows("2017-02-01 10:10:10 2017-01-31 11:10:10 2017-01-30 09:10:10",
+		"2016-02-28 00:00:00 2016-02-28 23:00:00 2016-02-27 23:00:00"))
 }
 
 func (s *testSuite) TestDatumXAPI(c *C) {
@@ -1282,6 +3184,34 @@ func (s *testSuite) TestSubquery(c *C) {
 	tk.MustExec("insert t values(10), (8), (7), (9), (11)")
 	result = tk.MustQuery("select * from t where 9 in (select c from t s where s.c < t.c limit 3)")
 	result.Check(testkit.Rows("10"))
+	result = tk.MustQuery("select c from (select c, (select s.c from t s where s.c = t.c limit 1) x from t) k where c > 9")
+	result.Check(testkit.Rows("10", "11"))
+	result = tk.MustQuery("select x + 1 from (select c * 2 as x from t) k where x > 20")
+	result.Check(testkit.Rows("23"))
+	// The unused subquery is still evaluated for every row.
+	rs, err := tk.Exec("select c from (select c, (select s.c from t s where s.c > t.c) x from t) k")
+	c.Assert(err, IsNil)
+	_, err = tidb.GetRows(rs)
+	c.Assert(err, NotNil)
+
+	// The subqueries on the unique keys return NULL if no row is matched.
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t(a int primary key, b int, c int, d varchar(10), unique key(c, d))")
+	tk.MustExec("insert t values(1, 10, 1, 'a'), (2, 20, 1, 'b'), (3, 30, 2, 'a')")
+	result = tk.MustQuery("select a, (select s.b from t s where s.a = t.a + 1) from t")
+	result.Check(testkit.Rows("1 20", "2 30", "3 <nil>"))
+	result = tk.MustQuery("select a, (select s.b from t s where s.c = t.a and s.d = 'a') from t")
+	result.Check(testkit.Rows("1 10", "2 30", "3 <nil>"))
+	result = tk.MustQuery("select (select b from t where a = 4), (select b from t where a = 2)")
+	result.Check(testkit.Rows("<nil> 20"))
+	result = tk.MustQuery("select a from t where (b, c) = (select s.b, s.c from t s where s.a = t.a)")
+	result.Check(testkit.Rows("1", "2", "3"))
+	result = tk.MustQuery("select a from t where (1, 1) = (select s.a, s.c from t s where s.a = 4)")
+	result.Check(testkit.Rows())
+	rs, err = tk.Exec("select a, (select s.b from t s where s.c = t.c) from t")
+	c.Assert(err, IsNil)
+	_, err = tidb.GetRows(rs)
+	c.Assert(err, NotNil)
 }
 
 func (s *testSuite) TestNewTableDual(c *C) {
@@ -1316,6 +3246,50 @@ func (s *testSuite) TestTableScan(c *C) {
 	result.Check(testkit.Rows(rowStr1, rowStr2))
 }
 
+func (s *testSuite) TestInfoSchemaColumnsAndStatistics(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists info_t")
+	tk.MustExec(`create table info_t (id bigint unsigned not null auto_increment primary key, d decimal(10, 2) comment 'price',
+		name varchar(20), b blob, ts datetime(3), e enum('a', 'bcd'), key idx_name (name(10), d))`)
+	result := tk.MustQuery(`select column_name, ordinal_position, is_nullable, character_maximum_length, character_octet_length,
+		numeric_precision, numeric_scale, datetime_precision, character_set_name, collation_name, column_type, extra, column_comment
+		from information_schema.columns where table_schema = 'test' and table_name = 'info_t'`)
+	result.Check(testkit.Rows(
+		"id 1 NO <nil> <nil> 20 0 <nil> <nil> <nil> bigint(20) unsigned auto_increment ",
+		"d 2 YES <nil> <nil> 10 2 <nil> <nil> <nil> decimal(10,2)  price",
+		"name 3 YES 20 60 <nil> <nil> <nil> utf8 utf8_unicode_ci varchar(20)  ",
+		"b 4 YES 65535 65535 <nil> <nil> <nil> <nil> <nil> blob  ",
+		"ts 5 YES <nil> <nil> <nil> <nil> 3 <nil> <nil> datetime(3)  ",
+		"e 6 YES 3 9 <nil> <nil> <nil> utf8 utf8_unicode_ci enum('a','bcd')  ",
+	))
+	result = tk.MustQuery(`select index_name, seq_in_index, column_name, cardinality, sub_part, nullable
+		from information_schema.statistics where table_schema = 'test' and table_name = 'info_t'`)
+	result.Check(testkit.Rows(
+		"PRIMARY 1 id <nil> <nil> ",
+		"idx_name 1 name <nil> 10 YES",
+		"idx_name 2 d <nil> <nil> YES",
+	))
+	tk.MustExec("insert into info_t (d, name) values (1, 'a'), (1, 'b'), (2, 'b')")
+	tk.MustExec("analyze table info_t")
+	result = tk.MustQuery(`select index_name, seq_in_index, cardinality
+		from information_schema.statistics where table_schema = 'test' and table_name = 'info_t'`)
+	result.Check(testkit.Rows("PRIMARY 1 3", "idx_name 1 2", "idx_name 2 2"))
+	// The cached statistics are replaced when the table is analyzed again.
+	tk.MustExec("insert into info_t (d, name) values (3, 'c'), (4, 'd')")
+	tk.MustExec("analyze table info_t")
+	result = tk.MustQuery(`select index_name, seq_in_index, cardinality
+		from information_schema.statistics where table_schema = 'test' and table_name = 'info_t'`)
+	result.Check(testkit.Rows("PRIMARY 1 5", "idx_name 1 4", "idx_name 2 4"))
+
+	tk.MustExec("drop table if exists info_u")
+	tk.MustExec("create table info_u (a tinyint unsigned, b int unsigned, c bigint unsigned, d int)")
+	result = tk.MustQuery(`select column_type from information_schema.columns where table_schema = 'test' and table_name = 'info_u'`)
+	result.Check(testkit.Rows("tinyint(3) unsigned", "int(10) unsigned", "bigint(20) unsigned", "int(11)"))
+	c.Assert(tk.MustQuery("show create table info_u").Rows()[0][1], Matches, `(?s).*`+"`c` bigint\\(20\\) UNSIGNED"+`.*`)
+}
+
 func (s *testSuite) TestAdapterStatement(c *C) {
 	defer testleak.AfterTest(c)()
 	se, err := tidb.CreateSession(s.store)
@@ -1457,3 +3431,295 @@ func (s *testSuite) TestHistoryRead(c *C) {
 	tk.MustExec("set @@tidb_snapshot = ''")
 	tk.MustQuery("select * from history_read order by a").Check(testkit.Rows("2 <nil>", "4 <nil>", "8 8", "9 9"))
 }
+
+func (s *testSuite) TestMemoryArbiter(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (a int, b varchar(100))")
+	for i := 0; i < 100; i++ {
+		tk.MustExec(fmt.Sprintf("insert t values (%d, '%s')", i, strings.Repeat("x", i)))
+	}
+
+	consumed := memory.GlobalArbiter.BytesConsumed()
+	memory.GlobalArbiter.SetLimit(consumed + 1000)
+	defer memory.GlobalArbiter.SetLimit(0)
+	queries := []string{
+		"select count(*) from t group by b",
+		"select * from t t1 join t t2 on t1.b = t2.b",
+	}
+	for _, sql := range queries {
+		rs, err := tk.Exec(sql)
+		c.Assert(err, IsNil)
+		_, err = tidb.GetRows(rs)
+		c.Assert(executor.ErrMemoryExceeded.Equal(err), IsTrue, Commentf("sql: %s", sql))
+		// The memory of a finished statement is released.
+		c.Assert(memory.GlobalArbiter.BytesConsumed(), Equals, consumed)
+	}
+	// The sort spills the rows when the server approaches the limit instead of being canceled.
+	memory.GlobalArbiter.SetLimit(consumed + 4000)
+	rows := tk.MustQuery("select a from t order by b desc").Rows()
+	c.Assert(rows, HasLen, 100)
+	c.Assert(rows[0][0], Equals, int64(99))
+	c.Assert(rows[99][0], Equals, int64(0))
+	c.Assert(memory.GlobalArbiter.BytesConsumed(), Equals, consumed)
+	tk.MustQuery("select a from t order by b limit 1").Check(testkit.Rows("0"))
+
+	memory.GlobalArbiter.SetLimit(0)
+	queries = append(queries, "select * from t order by b")
+	for _, sql := range queries {
+		c.Assert(tk.MustQuery(sql).Rows(), HasLen, 100)
+		c.Assert(memory.GlobalArbiter.BytesConsumed(), Equals, consumed)
+	}
+}
+
+func (s *testSuite) TestMemQuotaQuery(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (a int, b varchar(100))")
+	for i := 0; i < 100; i++ {
+		tk.MustExec(fmt.Sprintf("insert t values (%d, '%s')", i, strings.Repeat("x", i)))
+	}
+
+	consumed := memory.GlobalArbiter.BytesConsumed()
+	tk.MustExec("set @@tidb_mem_quota_query = 1000")
+	for _, sql := range []string{
+		"select count(*) from t group by b",
+		"select t1.a from t t1 join t t2 on t1.b = t2.b",
+	} {
+		rs, err := tk.Exec(sql)
+		c.Assert(err, IsNil)
+		_, err = tidb.GetRows(rs)
+		c.Assert(executor.ErrMemQuotaExceeded.Equal(err), IsTrue, Commentf("sql: %s", sql))
+		c.Assert(memory.GlobalArbiter.BytesConsumed(), Equals, consumed)
+	}
+	// The sort spills the rows instead of failing.
+	rows := tk.MustQuery("select a from t order by b desc").Rows()
+	c.Assert(rows, HasLen, 100)
+	c.Assert(rows[0][0], Equals, int64(99))
+	c.Assert(rows[99][0], Equals, int64(0))
+	c.Assert(memory.GlobalArbiter.BytesConsumed(), Equals, consumed)
+
+	tk.MustExec("set @@tidb_mem_quota_query = 0")
+	c.Assert(tk.MustQuery("select count(*) from t group by b").Rows(), HasLen, 100)
+	// The invalid values are rejected when they are set, so the session can still run the statements.
+	for _, val := range []string{"-1", "'abc'", "1.5"} {
+		_, err := tk.Exec("set @@tidb_mem_quota_query = " + val)
+		c.Assert(variable.ErrWrongValueForVar.Equal(err), IsTrue, Commentf("value %s", val))
+	}
+	tk.MustQuery("select @@tidb_mem_quota_query").Check(testkit.Rows("0"))
+	c.Assert(tk.MustQuery("select count(*) from t group by b").Rows(), HasLen, 100)
+}
+
+func (s *testSuite) TestPointGet(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t, u")
+	tk.MustExec("create table t (a int primary key, b varchar(10), c int, d int, unique index b (b), unique index c_d (c, d))")
+	tk.MustExec("insert t values (1, 'x', 1, 1), (2, 'y', 1, 2), (3, null, null, 3)")
+
+	tk.MustQuery("select * from t where a = 2").Check(testkit.Rows(fmt.Sprintf("2 %v 1 2", []byte("y"))))
+	tk.MustQuery("select d, a from t where 3 = a").Check(testkit.Rows("3 3"))
+	tk.MustQuery("select * from t where a = 4").Check(testkit.Rows())
+	tk.MustQuery("select * from t where a = -1").Check(testkit.Rows())
+	tk.MustQuery("select a from t where b = 'y'").Check(testkit.Rows("2"))
+	tk.MustQuery("select a from t where b = 'z'").Check(testkit.Rows())
+	tk.MustQuery("select a from t where c = 1 and d = 2").Check(testkit.Rows("2"))
+	tk.MustQuery("select a from t where d = 1 and c = 1").Check(testkit.Rows("1"))
+	tk.MustQuery("select a from t where c = 1 and d = 3").Check(testkit.Rows())
+	// The values which can't be used as the key as they are.
+	tk.MustQuery("select a from t where a = '1'").Check(testkit.Rows("1"))
+	tk.MustQuery("select a from t where a = 1.0").Check(testkit.Rows("1"))
+
+	rs, err := tk.Exec("select a as x, t.b from t where a = 1")
+	c.Assert(err, IsNil)
+	fields, err := rs.Fields()
+	c.Assert(err, IsNil)
+	c.Assert(fields, HasLen, 2)
+	c.Assert(fields[0].Column.Name.L, Equals, "x")
+	c.Assert(fields[1].Column.Name.L, Equals, "b")
+	c.Assert(fields[1].TableAsName.L, Equals, "t")
+	rs.Close()
+
+	// The uncommitted changes of the transaction are read.
+	tk.MustExec("begin")
+	tk.MustExec("insert t values (4, 'z', 2, 2)")
+	tk.MustExec("delete from t where a = 1")
+	tk.MustExec("update t set b = 'w' where a = 2")
+	tk.MustQuery("select b from t where a = 4").Check(testkit.Rows(fmt.Sprintf("%v", []byte("z"))))
+	tk.MustQuery("select b from t where a = 1").Check(testkit.Rows())
+	tk.MustQuery("select a from t where b = 'x'").Check(testkit.Rows())
+	tk.MustQuery("select a from t where b = 'w'").Check(testkit.Rows("2"))
+	tk.MustExec("rollback")
+	tk.MustQuery("select a from t where b = 'x'").Check(testkit.Rows("1"))
+
+	tk.MustExec("prepare stmt from 'select b from t where a = ?'")
+	tk.MustExec("set @a = 2")
+	tk.MustQuery("execute stmt using @a").Check(testkit.Rows(fmt.Sprintf("%v", []byte("y"))))
+	tk.MustExec("set @a = 3")
+	tk.MustQuery("execute stmt using @a").Check(testkit.Rows("<nil>"))
+
+	tk.MustExec("create table u (a bigint unsigned primary key, b int)")
+	tk.MustExec("insert u values (18446744073709551615, 1), (1, 2)")
+	tk.MustQuery("select b from u where a = 18446744073709551615").Check(testkit.Rows("1"))
+	tk.MustQuery("select b from u where a = 1").Check(testkit.Rows("2"))
+}
+
+func (s *testSuite) TestBatchPointGet(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t, u")
+	tk.MustExec("create table t (a int primary key, b varchar(10), c int, unique index b (b), unique index c (c))")
+	tk.MustExec("insert t values (1, 'x', 1), (2, 'y', 2), (3, null, 3), (4, 'w', null)")
+
+	rows := tk.MustQuery("explain select * from t where a in (1, 2)").Rows()
+	c.Assert(rows[0][0], Matches, "BatchPointGet_.*")
+	// The rows are read in the order of the handles or the index keys once.
+	tk.MustQuery("select a, c from t where a in (3, 1, 5, 1)").Check(testkit.Rows("1 1", "3 3"))
+	tk.MustQuery("select a from t where a in (null)").Check(testkit.Rows())
+	tk.MustQuery("select a from t where b in ('y', 'x', 'z', 'x')").Check(testkit.Rows("1", "2"))
+	tk.MustQuery("select a from t where c in (3, 2, null)").Check(testkit.Rows("2", "3"))
+	tk.MustQuery("select a from t where a in (1, '2', 2.5)").Check(testkit.Rows("1", "2"))
+
+	// The uncommitted changes of the transaction are read.
+	tk.MustExec("begin")
+	tk.MustExec("insert t values (5, 'v', 5)")
+	tk.MustExec("delete from t where a = 1")
+	tk.MustExec("update t set b = 'u' where a = 2")
+	tk.MustQuery("select a from t where a in (1, 2, 5)").Check(testkit.Rows("2", "5"))
+	tk.MustQuery("select a from t where b in ('x', 'y', 'u', 'v')").Check(testkit.Rows("2", "5"))
+	tk.MustExec("rollback")
+	tk.MustQuery("select a from t where b in ('x', 'y', 'u', 'v')").Check(testkit.Rows("1", "2"))
+
+	tk.MustExec("prepare stmt from 'select a from t where a in (?, ?)'")
+	tk.MustExec("set @a = 3, @b = 1")
+	tk.MustQuery("execute stmt using @a, @b").Check(testkit.Rows("1", "3"))
+
+	tk.MustExec("create table u (a bigint unsigned primary key, b int)")
+	tk.MustExec("insert u values (18446744073709551615, 1), (1, 2)")
+	tk.MustQuery("select b from u where a in (1, 18446744073709551615)").Check(testkit.Rows("1", "2"))
+}
+
+func (s *testSuite) TestAdmission(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (a int primary key, b int, c int, unique index ub(b), index ic(c))")
+	tk.MustExec("insert t values (1, 1, 1), (2, 2, 2), (3, 3, 3)")
+
+	admission.GlobalController.SetLimit(1)
+	admission.GlobalController.SetQueueTimeout(0)
+	defer func() {
+		admission.GlobalController.SetLimit(0)
+		admission.GlobalController.SetQueueTimeout(admission.DefaultQueueTimeout)
+	}()
+	// The scan holds the only slot until it's closed.
+	rs, err := tk.Exec("select * from t")
+	c.Assert(err, IsNil)
+	for _, sql := range []string{
+		"select * from t where c = 1",
+		"select * from t where a > 1",
+		"select * from t where b is null",
+		"select count(*) from t",
+		"update t set c = 1 where c = 2",
+	} {
+		_, err = tk.Exec(sql)
+		c.Assert(executor.ErrQueueTimeout.Equal(err), IsTrue, Commentf("sql: %s", sql))
+	}
+	// The point reads and writes and the management statements aren't queued.
+	tk.MustQuery("select b from t where a = 1").Check(testkit.Rows("1"))
+	tk.MustQuery("select a from t where b in (2, 3)").Check(testkit.Rows("2", "3"))
+	tk.MustQuery("select 1").Check(testkit.Rows("1"))
+	tk.MustExec("update t set c = 10 where a = 1")
+	tk.MustExec("insert t values (4, 4, 4)")
+	tk.MustExec("set @a = 1")
+	tk.MustQuery("show status like 'Statement_queue_running'").Check(testkit.Rows("Statement_queue_running 1"))
+	tk.MustQuery("explain select * from t")
+	tk.MustExec("prepare stmt from 'select * from t where a = ?'")
+	tk.MustQuery("execute stmt using @a").Check(testkit.Rows("1 1 10"))
+	tk.MustExec("prepare stmt from 'select * from t where c = ?'")
+	_, err = tk.Exec("execute stmt using @a")
+	c.Assert(executor.ErrQueueTimeout.Equal(err), IsTrue)
+
+	// The queued statement leaves the queue once it's killed.
+	admission.GlobalController.SetQueueTimeout(time.Minute)
+	tk1 := testkit.NewTestKit(c, s.store)
+	tk1.MustExec("use test")
+	go func() {
+		for admission.GlobalController.Stats().Queued == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		tk1.Se.Kill()
+	}()
+	_, err = tk1.Exec("select * from t where c = 1")
+	c.Assert(kv.ErrQueryInterrupted.Equal(err), IsTrue)
+	c.Assert(admission.GlobalController.Stats().Queued, Equals, 0)
+
+	c.Assert(rs.Close(), IsNil)
+	tk.MustQuery("select count(*) from t").Check(testkit.Rows("4"))
+	tk.MustQuery("show status like 'Statement_queue_running'").Check(testkit.Rows("Statement_queue_running 0"))
+
+	// The slot is released once all the rows are read, before the record set is closed.
+	rs, err = tk.Exec("select * from t")
+	c.Assert(err, IsNil)
+	for {
+		row, err := rs.Next()
+		c.Assert(err, IsNil)
+		if row == nil {
+			break
+		}
+	}
+	tk.MustQuery("select count(*) from t").Check(testkit.Rows("4"))
+	c.Assert(rs.Close(), IsNil)
+	tk.MustQuery("show status like 'Statement_queue_running'").Check(testkit.Rows("Statement_queue_running 0"))
+}
+
+func (s *testSuite) TestLazyDecodeFilter(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (a int primary key, b text, c int, d datetime)")
+	tk.MustExec("insert into t values (1, repeat('x', 1000), 1, '2016-01-01 00:00:00'), (2, 'y', null, '2016-01-02 00:00:00'), (3, 'zz', 3, null)")
+
+	// The conditions can't be pushed down, they are evaluated by the table scan on the lazily decoded rows.
+	tk.MustQuery("select a, d from t where concat(b, 'x') = 'yx' and c is null").Check(testkit.Rows("2 2016-01-02 00:00:00"))
+	tk.MustQuery("select a, length(b) from t where d is not null order by a").Check(testkit.Rows("1 1000", "2 1"))
+	tk.MustQuery("select a, length(b) from t where d is null and concat(c) = '3'").Check(testkit.Rows("3 2"))
+	tk.MustQuery("select count(*) from t where c is null or length(b) > 100").Check(testkit.Rows("2"))
+	tk.MustQuery("select a from t where concat(b, 'x') = 'x'").Check(testkit.Rows())
+	// The conditions are evaluated on the table rows read by the index lookup, in the index order.
+	tk.MustExec("create index c on t (c)")
+	tk.MustQuery("select a, length(b) from t use index (c) where c > 0 and concat(b, 'x') != 'yx' order by c desc").Check(testkit.Rows("3 2", "1 1000"))
+	tk.MustQuery("select a from t use index (c) where c > 0 and concat(b, 'x') = 'zzx'").Check(testkit.Rows("3"))
+	tk.MustQuery("select a from t use index (c) where c > 0 and concat(b, 'x') = 'x'").Check(testkit.Rows())
+	// The rows in the transaction are merged by the union scan above the table scan.
+	tk.MustExec("begin")
+	tk.MustExec("insert into t values (4, 'y', null, null)")
+	tk.MustQuery("select a from t where concat(b, 'x') = 'yx' and c is null order by a").Check(testkit.Rows("2", "4"))
+	tk.MustExec("rollback")
+}
@@ -15,6 +15,9 @@ package executor
 
 import (
 	"bytes"
+	"io"
+	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/juju/errors"
@@ -25,6 +28,7 @@ import (
 	"github.com/pingcap/tidb/expression"
 	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/privilege"
 	"github.com/pingcap/tidb/table"
 	"github.com/pingcap/tidb/terror"
 	"github.com/pingcap/tidb/util/types"
@@ -38,6 +42,53 @@ var (
 	_ Executor = &LoadData{}
 )
 
+// batchDMLKeyType is a dummy type to avoid naming collision in context.
+type batchDMLKeyType int
+
+// String defines a Stringer function for debugging and pretty printing.
+func (k batchDMLKeyType) String() string {
+	return "batch_dml"
+}
+
+// BatchDMLKey is set when the transaction of a statement is committed in batches, then the transaction can't be
+// retried, or the rows committed in the previous batches would be written again.
+const BatchDMLKey batchDMLKeyType = 0
+
+// dmlBatch splits the rows written by a statement into the transactions of a batch size, so a huge statement
+// doesn't exceed the size limit of a transaction.
+type dmlBatch struct {
+	// rows is the number of the rows written in the current transaction.
+	rows int64
+	// commits is the number of the transactions committed by the statement.
+	commits int
+}
+
+// add counts a written row, and commits the transaction when size rows are written in it, 0 size writes all the
+// rows in one transaction. The rows are committed only with the transaction if it's started explicitly.
+func (b *dmlBatch) add(ctx context.Context, size int64) error {
+	if size == 0 || !ctx.GetSessionVars().ShouldAutocommit() {
+		return nil
+	}
+	b.rows++
+	if b.rows < size {
+		return nil
+	}
+	b.rows = 0
+	if b.commits == 0 {
+		log.Warnf("[%d] the statement is split into the transactions of %d rows, it isn't atomic",
+			ctx.GetSessionVars().ConnectionID, size)
+		ctx.GetSessionVars().AppendWarning(ErrBatchNotAtomic.Gen("The statement is committed in batches of %d rows, it isn't atomic", size))
+	}
+	b.commits++
+	ctx.SetValue(BatchDMLKey, true)
+	if err := ctx.CommitTxn(); err != nil {
+		return errors.Trace(err)
+	}
+	// The key is cleared with the committed transaction, the rest of the statement can't be retried either.
+	ctx.SetValue(BatchDMLKey, true)
+	return nil
+}
+
 func updateRecord(ctx context.Context, h int64, oldData, newData []types.Datum, assignFlag []bool, t table.Table, offset int, onDuplicateUpdate bool) error {
 	cols := t.Cols()
 	touched := make(map[int]bool, len(cols))
@@ -55,13 +106,13 @@ func updateRecord(ctx context.Context, h int64, oldData, newData []types.Datum,
 		colIndex := i - offset
 		col := cols[colIndex]
 		if col.IsPKHandleColumn(t.Meta()) {
-			newHandle = newData[i]
+			newHandle = newData[colIndex]
 		}
 		if mysql.HasAutoIncrementFlag(col.Flag) {
-			if newData[i].IsNull() {
+			if newData[colIndex].IsNull() {
 				return errors.Errorf("Column '%v' cannot be null", col.Name.O)
 			}
-			val, err := newData[i].ToInt64()
+			val, err := newData[colIndex].ToInt64()
 			if err != nil {
 				return errors.Trace(err)
 			}
@@ -117,6 +168,8 @@ func updateRecord(ctx context.Context, h int64, oldData, newData []types.Datum,
 			return errors.Trace(err)
 		}
 		_, err = t.AddRecord(ctx, newData)
+		// The whole row and all the indices are rewritten for the new handle.
+		touched = nil
 	} else {
 		// Update record to new value and update index.
 		err = t.UpdateRecord(ctx, h, oldData, newData, touched)
@@ -128,6 +181,9 @@ func updateRecord(ctx context.Context, h int64, oldData, newData []types.Datum,
 	tid := t.Meta().ID
 	dirtyDB.deleteRow(tid, h)
 	dirtyDB.addRow(tid, h, newData)
+	if err = recordWrite(ctx, t, newData, touched); err != nil {
+		return errors.Trace(err)
+	}
 
 	// Record affected rows.
 	if !onDuplicateUpdate {
@@ -146,6 +202,9 @@ type DeleteExec struct {
 	ctx          context.Context
 	Tables       []*ast.TableName
 	IsMultiTable bool
+	// batchSize is the number of the rows deleted in each transaction, see variable.TiDBBatchDelete.
+	batchSize int64
+	batch     dmlBatch
 
 	finished bool
 }
@@ -207,6 +266,9 @@ func (e *DeleteExec) Next() (*Row, error) {
 			if err != nil {
 				return nil, errors.Trace(err)
 			}
+			if err = e.batch.add(e.ctx, e.batchSize); err != nil {
+				return nil, errors.Trace(err)
+			}
 		}
 	}
 	return nil, nil
@@ -240,6 +302,9 @@ func (e *DeleteExec) removeRow(ctx context.Context, t table.Table, h int64, data
 		return errors.Trace(err)
 	}
 	getDirtyDB(ctx).deleteRow(t.Meta().ID, h)
+	if err = recordWrite(ctx, t, data, nil); err != nil {
+		return errors.Trace(err)
+	}
 	ctx.GetSessionVars().AddAffectedRows(1)
 	return nil
 }
@@ -262,11 +327,16 @@ func NewLoadDataInfo(row []types.Datum, ctx context.Context, tbl table.Table) *L
 type LoadDataInfo struct {
 	row       []types.Datum
 	insertVal *InsertValues
+	batch     dmlBatch
 
 	Path       string
 	Table      table.Table
 	FieldsInfo *ast.FieldsClause
 	LinesInfo  *ast.LinesClause
+	// IgnoreLines is the number of the lines at the start of the file which are skipped.
+	IgnoreLines uint64
+	// BatchSize is the number of the rows inserted in each transaction, 0 inserts all the rows in one transaction.
+	BatchSize int64
 }
 
 // getValidData returns prevData and curData that starts from starting symbol.
@@ -401,11 +471,20 @@ func (e *LoadDataInfo) InsertData(prevData, curData []byte) ([]byte, error) {
 			line = curData[len(e.LinesInfo.Starting):]
 			curData = nil
 		}
+		if e.IgnoreLines > 0 {
+			e.IgnoreLines--
+			continue
+		}
 
 		rawCols := bytes.Split(line, []byte(e.FieldsInfo.Terminated))
 		cols = escapeCols(rawCols)
-		e.insertData(cols)
+		if err := e.insertData(cols); err != nil {
+			return nil, errors.Trace(err)
+		}
 		e.insertVal.currRow++
+		if err := e.batch.add(e.insertVal.ctx, e.BatchSize); err != nil {
+			return nil, errors.Trace(err)
+		}
 	}
 	if e.insertVal.lastInsertID != 0 {
 		e.insertVal.ctx.GetSessionVars().LastInsertID = e.insertVal.lastInsertID
@@ -462,7 +541,9 @@ func escapeChar(c byte) (byte, bool) {
 	return c, false
 }
 
-func (e *LoadDataInfo) insertData(cols []string) {
+// insertData inserts the row of the columns, the row is skipped with a warning if it can't be inserted. It only
+// returns kv.ErrQueryInterrupted when the statement is killed.
+func (e *LoadDataInfo) insertData(cols []string) error {
 	for i := 0; i < len(e.row); i++ {
 		if i >= len(cols) {
 			e.row[i].SetString("")
@@ -473,14 +554,70 @@ func (e *LoadDataInfo) insertData(cols []string) {
 	row, err := e.insertVal.fillRowData(e.Table.Cols(), e.row, true)
 	if err != nil {
 		log.Warnf("Load Data: insert data:%v failed:%v", e.row, errors.ErrorStack(err))
-		return
+		return nil
 	}
 	_, err = e.Table.AddRecord(e.insertVal.ctx, row)
 	if err != nil {
 		log.Warnf("Load Data: insert data:%v failed:%v", row, errors.ErrorStack(err))
+		return nil
 	}
+	return errors.Trace(recordWrite(e.insertVal.ctx, e.Table, row, nil))
 }
 
+// SecureFilePriv is the directory of the files LOAD DATA INFILE reads and SELECT ... INTO OUTFILE writes on the
+// server, the files out of it can't be accessed, and both statements are not allowed if it's empty.
+var SecureFilePriv string
+
+// secureFilePath returns the absolute path of the file on the server, the relative path is in SecureFilePriv.
+// The symbolic links are resolved, so a link in the directory can't lead to a file out of it. The file written by
+// SELECT ... INTO OUTFILE doesn't exist yet, the directory of it is resolved then.
+func secureFilePath(path string) (string, error) {
+	if SecureFilePriv == "" {
+		return "", errors.New("the secure file directory isn't set")
+	}
+	dir, err := filepath.Abs(SecureFilePriv)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	dir, err = filepath.EvalSymlinks(dir)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	path = filepath.Clean(path)
+	realPath, err := filepath.EvalSymlinks(path)
+	if os.IsNotExist(err) {
+		var parent string
+		parent, err = filepath.EvalSymlinks(filepath.Dir(path))
+		realPath = filepath.Join(parent, filepath.Base(path))
+	}
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	rel, err := filepath.Rel(dir, realPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", errors.Errorf("file %s isn't in the secure file directory %s", path, dir)
+	}
+	return realPath, nil
+}
+
+// checkFilePriv checks the FILE privilege to read or write the files on the server.
+func checkFilePriv(ctx context.Context) error {
+	hasPriv, err := privilege.CheckGlobal(ctx, mysql.FilePriv)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if !hasPriv {
+		return ErrSpecificAccessDenied.Gen("Access denied; you need (at least one of) the %s privilege(s) for this operation", "FILE")
+	}
+	return nil
+}
+
+// loadDataReadSize is the size of the chunks LOAD DATA INFILE reads the files on the server by.
+const loadDataReadSize = 64 * 1024
+
 // LoadData represents a load data executor.
 type LoadData struct {
 	IsLocal      bool
@@ -499,15 +636,19 @@ func (k loadDataVarKeyType) String() string {
 const LoadDataVarKey loadDataVarKeyType = 0
 
 // Next implements the Executor Next interface.
+// The file of LOAD DATA LOCAL INFILE is sent by the client after the statement, so the server inserts its rows
+// with the LoadDataInfo saved in the context. The file of LOAD DATA INFILE is read on the server right away.
 func (e *LoadData) Next() (*Row, error) {
-	// TODO: support load data without local field.
-	if !e.IsLocal {
-		return nil, errors.New("Load Data: don't support load data without local field")
-	}
 	// TODO: support lines terminated is "".
 	if len(e.loadDataInfo.LinesInfo.Terminated) == 0 {
 		return nil, errors.New("Load Data: don't support load data terminated is nil")
 	}
+	if e.loadDataInfo.Path == "" {
+		return nil, errors.New("Load Data: infile path is empty")
+	}
+	if !e.IsLocal {
+		return nil, errors.Trace(e.loadFile())
+	}
 
 	ctx := e.loadDataInfo.insertVal.ctx
 	val := ctx.Value(LoadDataVarKey)
@@ -515,14 +656,48 @@ func (e *LoadData) Next() (*Row, error) {
 		ctx.SetValue(LoadDataVarKey, nil)
 		return nil, errors.New("Load Data: previous load data option isn't closed normal")
 	}
-	if e.loadDataInfo.Path == "" {
-		return nil, errors.New("Load Data: infile path is empty")
-	}
 	ctx.SetValue(LoadDataVarKey, e.loadDataInfo)
 
 	return nil, nil
 }
 
+// loadFile inserts the rows of the file on the server, the relative path is in SecureFilePriv.
+func (e *LoadData) loadFile() error {
+	if err := checkFilePriv(e.loadDataInfo.insertVal.ctx); err != nil {
+		return errors.Trace(err)
+	}
+	path, err := secureFilePath(e.loadDataInfo.Path)
+	if err != nil {
+		return errors.Annotate(err, "Load Data")
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer file.Close()
+
+	var prevData []byte
+	for {
+		// The rest of the data returned by InsertData refers to the buffer, so each chunk is read to a new buffer.
+		curData := make([]byte, loadDataReadSize)
+		n, err := io.ReadFull(file, curData)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return errors.Trace(err)
+		}
+		if n == 0 {
+			if len(prevData) == 0 {
+				return nil
+			}
+			_, err = e.loadDataInfo.InsertData(prevData, nil)
+			return errors.Trace(err)
+		}
+		prevData, err = e.loadDataInfo.InsertData(prevData, curData[:n])
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
+}
+
 // Schema implements the Executor Schema interface.
 func (e *LoadData) Schema() expression.Schema {
 	return nil
@@ -545,6 +720,15 @@ type InsertValues struct {
 	Lists     [][]ast.ExprNode
 	Setlist   []*ast.Assignment
 	IsPrepare bool
+
+	// ValuesStream reads the rows after Lists chunk by chunk, see ast.ValuesStream.
+	ValuesStream ast.ValuesStream
+	// streamOffset is the offset of the next chunk of ValuesStream.
+	streamOffset int
+	// rowOffset is the number of the rows in the chunks before Lists.
+	rowOffset int
+	// valueCount is the value count of the first row, all the chunks must have the same one.
+	valueCount int
 }
 
 // InsertExec represents an insert executor.
@@ -556,6 +740,9 @@ type InsertExec struct {
 
 	Priority int
 	Ignore   bool
+	// batchSize is the number of the rows inserted in each transaction, see variable.TiDBBatchInsert.
+	batchSize int64
+	batch     dmlBatch
 
 	finished bool
 }
@@ -574,47 +761,35 @@ func (e *InsertExec) Next() (*Row, error) {
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
-	txn, err := e.ctx.GetTxn(false)
-	if err != nil {
-		return nil, errors.Trace(err)
-	}
 	toUpdateColumns, err := getOnDuplicateUpdateColumns(e.OnDuplicate, e.Table)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
 
-	var rows [][]types.Datum
-	if e.SelectExec != nil {
-		rows, err = e.getRowsSelect(cols)
-	} else {
-		rows, err = e.getRows(cols)
-	}
-	if err != nil {
-		return nil, errors.Trace(err)
-	}
-
-	for _, row := range rows {
-		if len(e.OnDuplicate) == 0 && !e.Ignore {
-			txn.SetOption(kv.PresumeKeyNotExists, nil)
+	for {
+		var rows [][]types.Datum
+		if e.SelectExec != nil {
+			rows, err = e.getRowsSelect(cols)
+		} else {
+			rows, err = e.getRows(cols)
 		}
-		h, err := e.Table.AddRecord(e.ctx, row)
-		txn.DelOption(kv.PresumeKeyNotExists)
-		if err == nil {
-			getDirtyDB(e.ctx).addRow(e.Table.Meta().ID, h, row)
-			continue
+		if err != nil {
+			return nil, errors.Trace(err)
 		}
-
-		if len(e.OnDuplicate) == 0 || !terror.ErrorEqual(err, kv.ErrKeyExists) {
-			// If you use the IGNORE keyword, errors that occur while executing the INSERT statement are ignored.
-			// For example, without IGNORE, a row that duplicates an existing UNIQUE index or PRIMARY KEY value in
-			// the table causes a duplicate-key error and the statement is aborted. With IGNORE, the row is discarded and no error occurs.
-			if e.Ignore {
-				continue
+		for _, row := range rows {
+			if err = e.insertRow(row, toUpdateColumns); err != nil {
+				return nil, errors.Trace(err)
+			}
+			if err = e.batch.add(e.ctx, e.batchSize); err != nil {
+				return nil, errors.Trace(err)
 			}
-			return nil, errors.Trace(err)
 		}
-		if err = e.onDuplicateUpdate(row, h, toUpdateColumns); err != nil {
-			return nil, errors.Trace(err)
+		more, err1 := e.nextValuesChunk()
+		if err1 != nil {
+			return nil, errors.Trace(err1)
+		}
+		if !more {
+			break
 		}
 	}
 
@@ -625,6 +800,34 @@ func (e *InsertExec) Next() (*Row, error) {
 	return nil, nil
 }
 
+func (e *InsertExec) insertRow(row []types.Datum, toUpdateColumns map[int]*ast.Assignment) error {
+	// The transaction changes after a batch is committed.
+	txn, err := e.ctx.GetTxn(false)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if len(e.OnDuplicate) == 0 && !e.Ignore {
+		txn.SetOption(kv.PresumeKeyNotExists, nil)
+	}
+	h, err := e.Table.AddRecord(e.ctx, row)
+	txn.DelOption(kv.PresumeKeyNotExists)
+	if err == nil {
+		getDirtyDB(e.ctx).addRow(e.Table.Meta().ID, h, row)
+		return errors.Trace(recordWrite(e.ctx, e.Table, row, nil))
+	}
+
+	if len(e.OnDuplicate) == 0 || !terror.ErrorEqual(err, kv.ErrKeyExists) {
+		// If you use the IGNORE keyword, errors that occur while executing the INSERT statement are ignored.
+		// For example, without IGNORE, a row that duplicates an existing UNIQUE index or PRIMARY KEY value in
+		// the table causes a duplicate-key error and the statement is aborted. With IGNORE, the row is discarded and no error occurs.
+		if e.Ignore {
+			return nil
+		}
+		return errors.Trace(err)
+	}
+	return errors.Trace(e.onDuplicateUpdate(row, h, toUpdateColumns))
+}
+
 // Close implements the Executor Close interface.
 func (e *InsertExec) Close() error {
 	if e.SelectExec != nil {
@@ -740,12 +943,14 @@ func (e *InsertValues) getRows(cols []*table.Column) (rows [][]types.Datum, err
 	}
 
 	rows = make([][]types.Datum, len(e.Lists))
-	length := len(e.Lists[0])
+	if e.rowOffset == 0 {
+		e.valueCount = len(e.Lists[0])
+	}
 	for i, list := range e.Lists {
-		if err = e.checkValueCount(length, len(list), i, cols); err != nil {
+		if err = e.checkValueCount(e.valueCount, len(list), e.rowOffset+i, cols); err != nil {
 			return nil, errors.Trace(err)
 		}
-		e.currRow = i
+		e.currRow = e.rowOffset + i
 		rows[i], err = e.getRow(cols, list, defaultVals)
 		if err != nil {
 			return nil, errors.Trace(err)
@@ -754,6 +959,24 @@ func (e *InsertValues) getRows(cols []*table.Column) (rows [][]types.Datum, err
 	return
 }
 
+// nextValuesChunk replaces Lists with the next chunk of the rows of ValuesStream, so the rows are evaluated and
+// written chunk by chunk. It returns false if there are no more rows.
+func (e *InsertValues) nextValuesChunk() (bool, error) {
+	if e.ValuesStream == nil || e.SelectExec != nil {
+		return false, nil
+	}
+	lists, next, err := e.ValuesStream.ReadChunk(e.ctx, e.streamOffset)
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	if len(lists) == 0 {
+		return false, nil
+	}
+	e.rowOffset += len(e.Lists)
+	e.Lists, e.streamOffset = lists, next
+	return true, nil
+}
+
 func (e *InsertValues) getRow(cols []*table.Column, list []ast.ExprNode, defaultVals map[string]types.Datum) ([]types.Datum, error) {
 	vals := make([]types.Datum, len(list))
 	var err error
@@ -1000,16 +1223,36 @@ func (e *ReplaceExec) Next() (*Row, error) {
 		return nil, errors.Trace(err)
 	}
 
-	var rows [][]types.Datum
-	if e.SelectExec != nil {
-		rows, err = e.getRowsSelect(cols)
-	} else {
-		rows, err = e.getRows(cols)
+	for {
+		var rows [][]types.Datum
+		if e.SelectExec != nil {
+			rows, err = e.getRowsSelect(cols)
+		} else {
+			rows, err = e.getRows(cols)
+		}
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if err = e.replaceRows(rows); err != nil {
+			return nil, errors.Trace(err)
+		}
+		more, err1 := e.nextValuesChunk()
+		if err1 != nil {
+			return nil, errors.Trace(err1)
+		}
+		if !more {
+			break
+		}
 	}
-	if err != nil {
-		return nil, errors.Trace(err)
+
+	if e.lastInsertID != 0 {
+		e.ctx.GetSessionVars().LastInsertID = e.lastInsertID
 	}
+	e.finished = true
+	return nil, nil
+}
 
+func (e *ReplaceExec) replaceRows(rows [][]types.Datum) error {
 	/*
 	 * MySQL uses the following algorithm for REPLACE (and LOAD DATA ... REPLACE):
 	 *  1. Try to insert the new row into the table
@@ -1032,19 +1275,22 @@ func (e *ReplaceExec) Next() (*Row, error) {
 		h, err1 := e.Table.AddRecord(e.ctx, row)
 		if err1 == nil {
 			getDirtyDB(e.ctx).addRow(e.Table.Meta().ID, h, row)
+			if err1 = recordWrite(e.ctx, e.Table, row, nil); err1 != nil {
+				return errors.Trace(err1)
+			}
 			idx++
 			continue
 		}
 		if err1 != nil && !terror.ErrorEqual(err1, kv.ErrKeyExists) {
-			return nil, errors.Trace(err1)
+			return errors.Trace(err1)
 		}
 		oldRow, err1 := e.Table.Row(e.ctx, h)
 		if err1 != nil {
-			return nil, errors.Trace(err1)
+			return errors.Trace(err1)
 		}
 		rowUnchanged, err1 := types.EqualDatums(oldRow, row)
 		if err1 != nil {
-			return nil, errors.Trace(err1)
+			return errors.Trace(err1)
 		}
 		if rowUnchanged {
 			// If row unchanged, we do not need to do insert.
@@ -1055,17 +1301,15 @@ func (e *ReplaceExec) Next() (*Row, error) {
 		// Remove current row and try replace again.
 		err1 = e.Table.RemoveRecord(e.ctx, h, oldRow)
 		if err1 != nil {
-			return nil, errors.Trace(err1)
+			return errors.Trace(err1)
 		}
 		getDirtyDB(e.ctx).deleteRow(e.Table.Meta().ID, h)
+		if err1 = recordWrite(e.ctx, e.Table, oldRow, nil); err1 != nil {
+			return errors.Trace(err1)
+		}
 		e.ctx.GetSessionVars().AddAffectedRows(1)
 	}
-
-	if e.lastInsertID != 0 {
-		e.ctx.GetSessionVars().LastInsertID = e.lastInsertID
-	}
-	e.finished = true
-	return nil, nil
+	return nil
 }
 
 // UpdateExec represents a new update executor.
@@ -1073,9 +1317,12 @@ type UpdateExec struct {
 	SelectExec  Executor
 	OrderedList []*expression.Assignment
 
-	// Map for unique (Table, handle) pair.
-	updatedRowKeys map[table.Table]map[int64]struct{}
-	ctx            context.Context
+	// updatedRowKeys is the set of the rows updated by each alias of the tables, each of them updates a row once.
+	updatedRowKeys map[aliasHandle]struct{}
+	// updatedRows is the data of the rows updated by an alias of a table joined with itself, another alias updates
+	// the row later on the updated data instead of the joined one.
+	updatedRows map[tableHandle][]types.Datum
+	ctx         context.Context
 
 	rows        []*Row          // The rows fetched from TableExec.
 	newRowsData [][]types.Datum // The new values to be set.
@@ -1106,35 +1353,102 @@ func (e *UpdateExec) Next() (*Row, error) {
 		return nil, nil
 	}
 	if e.updatedRowKeys == nil {
-		e.updatedRowKeys = make(map[table.Table]map[int64]struct{})
+		e.updatedRowKeys = make(map[aliasHandle]struct{})
+		e.updatedRows = make(map[tableHandle][]types.Datum)
 	}
 	row := e.rows[e.cursor]
 	newData := e.newRowsData[e.cursor]
+	// A row of a table which is joined with itself is updated once by the assignments of all its aliases in the
+	// joined row, so the assignments of the entries of the same table row are merged into the first one.
+	assignFlag = append([]bool(nil), assignFlag...)
+	firstOffsets := make(map[tableHandle]int, len(row.RowKeys))
+	tableEntries := make(map[int64]int, len(row.RowKeys))
+	entries := make([]*RowKeyEntry, 0, len(row.RowKeys))
 	for _, entry := range row.RowKeys {
-		tbl := entry.Tbl
-		if e.updatedRowKeys[tbl] == nil {
-			e.updatedRowKeys[tbl] = make(map[int64]struct{})
+		tableEntries[entry.Tbl.Meta().ID]++
+		offset := e.getTableOffset(*entry)
+		cols := len(entry.Tbl.WritableCols())
+		if !hasAssignment(assignFlag, offset, cols) {
+			// The row isn't updated by this alias, it may be updated by another one later.
+			continue
+		}
+		key := tableHandle{tableID: entry.Tbl.Meta().ID, handle: entry.Handle}
+		aliasKey := aliasHandle{tableHandle: key, offset: offset}
+		if _, ok := e.updatedRowKeys[aliasKey]; ok {
+			// Each matched row is updated once by an alias, even if it matches the conditions multiple times.
+			continue
+		}
+		e.updatedRowKeys[aliasKey] = struct{}{}
+		first, ok := firstOffsets[key]
+		if !ok {
+			firstOffsets[key] = offset
+			entries = append(entries, entry)
+			continue
 		}
+		for i := 0; i < cols; i++ {
+			if assignFlag[offset+i] {
+				newData[first+i] = newData[offset+i]
+				assignFlag[first+i] = true
+			}
+		}
+	}
+	for _, entry := range entries {
+		tbl := entry.Tbl
 		offset := e.getTableOffset(*entry)
 		handle := entry.Handle
-		oldData := row.Data[offset : offset+len(tbl.WritableCols())]
-		newTableData := newData[offset : offset+len(tbl.WritableCols())]
-		_, ok := e.updatedRowKeys[tbl][handle]
-		if ok {
-			// Each matched row is updated once, even if it matches the conditions multiple times.
-			continue
+		cols := len(tbl.WritableCols())
+		oldData := row.Data[offset : offset+cols]
+		newTableData := newData[offset : offset+cols]
+		key := tableHandle{tableID: tbl.Meta().ID, handle: handle}
+		if updated, ok := e.updatedRows[key]; ok {
+			// The row is updated by another alias before, the columns not assigned by this one keep the updated
+			// values.
+			oldData = updated
+			newTableData = make([]types.Datum, cols)
+			for i := range newTableData {
+				if assignFlag[offset+i] {
+					newTableData[i] = newData[offset+i]
+				} else {
+					newTableData[i] = updated[i]
+				}
+			}
 		}
 		// Update row
 		err1 := updateRecord(e.ctx, handle, oldData, newTableData, assignFlag, tbl, offset, false)
 		if err1 != nil {
 			return nil, errors.Trace(err1)
 		}
-		e.updatedRowKeys[tbl][handle] = struct{}{}
+		if tableEntries[key.tableID] > 1 {
+			e.updatedRows[key] = append([]types.Datum(nil), newTableData...)
+		}
 	}
 	e.cursor++
 	return &Row{}, nil
 }
 
+// tableHandle identifies a row of a table.
+type tableHandle struct {
+	tableID int64
+	handle  int64
+}
+
+// aliasHandle identifies a row of a table by one of the aliases of the table, which is the offset of its columns
+// in the joined rows.
+type aliasHandle struct {
+	tableHandle
+	offset int
+}
+
+// hasAssignment checks whether any of the cols columns from the offset is assigned.
+func hasAssignment(assignFlag []bool, offset, cols int) bool {
+	for i := offset; i < offset+cols && i < len(assignFlag); i++ {
+		if assignFlag[i] {
+			return true
+		}
+	}
+	return false
+}
+
 func getUpdateColumns(assignList []*expression.Assignment) ([]bool, error) {
 	assignFlag := make([]bool, len(assignList))
 	for i, v := range assignList {
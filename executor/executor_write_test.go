@@ -14,14 +14,23 @@
 package executor_test
 
 import (
+	"bytes"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
 
 	. "github.com/pingcap/check"
 	"github.com/pingcap/tidb/ast"
 	"github.com/pingcap/tidb/context"
 	"github.com/pingcap/tidb/executor"
 	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/plan"
 	"github.com/pingcap/tidb/sessionctx"
+	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/terror"
+	"github.com/pingcap/tidb/util/hotspot"
 	"github.com/pingcap/tidb/util/testkit"
 	"github.com/pingcap/tidb/util/testleak"
 	"github.com/pingcap/tidb/util/types"
@@ -478,6 +487,29 @@ func (s *testSuite) TestMultipleTableUpdate(c *C) {
 
 	r = tk.MustQuery("select * from t1")
 	r.Check(testkit.Rows("10", "10"))
+
+	// A row matched more than once is updated only once, with the assignments of all its aliases.
+	tk.MustExec("drop table if exists t1, t2")
+	tk.MustExec("create table t1 (id int primary key, v int, w int)")
+	tk.MustExec("insert into t1 values (1, 1, 1), (2, 2, 2), (3, 3, 3)")
+	tk.MustExec("update t1 b join t1 a on a.id = b.id + 1 set a.v = a.v + 100")
+	tk.CheckExecResult(2, 0)
+	tk.MustQuery("select * from t1").Check(testkit.Rows("1 1 1", "2 102 2", "3 103 3"))
+	tk.MustExec("update t1 a join t1 b on a.id = b.id set a.v = 7, b.w = b.w + 10")
+	tk.CheckExecResult(3, 0)
+	tk.MustQuery("select * from t1").Check(testkit.Rows("1 7 11", "2 7 12", "3 7 13"))
+	tk.MustExec("update t1 a join t1 b on a.id = b.id set b.id = b.id + 10")
+	tk.MustQuery("select id from t1").Check(testkit.Rows("11", "12", "13"))
+	// A row updated by an alias in a joined row is still updated by another alias in a later joined row.
+	tk.MustExec("delete from t1")
+	tk.MustExec("insert into t1 values (1, 0, 0), (2, 0, 0), (3, 0, 0)")
+	tk.MustExec("update t1 a join t1 b on a.id = b.id + 1 set a.v = 1, b.w = 1")
+	tk.MustQuery("select * from t1").Check(testkit.Rows("1 0 1", "2 1 1", "3 1 0"))
+
+	_, err := tk.Exec("update t1 a join t1 b on a.id = b.id set a.v = 1 order by a.id")
+	c.Assert(terror.ErrorEqual(err, plan.ErrWrongUsage), IsTrue)
+	_, err = tk.Exec("update t1 a join t1 b on a.id = b.id set a.v = 1 limit 1")
+	c.Assert(terror.ErrorEqual(err, plan.ErrWrongUsage), IsTrue)
 }
 
 func (s *testSuite) TestDelete(c *C) {
@@ -513,6 +545,60 @@ func (s *testSuite) TestDelete(c *C) {
 	tk.CheckExecResult(1, 0)
 }
 
+func (s *testSuite) TestBatchInsertDelete(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (a int primary key)")
+	tk.MustExec("set @@tidb_dml_batch_size = 2")
+
+	// The statement is atomic without tidb_batch_insert.
+	_, err := tk.Exec("insert t values (1), (2), (3), (1)")
+	c.Assert(err, NotNil)
+	tk.MustQuery("select count(*) from t").Check(testkit.Rows("0"))
+
+	// The rows are committed every 2 rows, the committed ones are kept when the statement fails.
+	tk.MustExec("set @@tidb_batch_insert = 1")
+	_, err = tk.Exec("insert t values (1), (2), (3), (1)")
+	c.Assert(err, NotNil)
+	tk.MustQuery("select a from t").Check(testkit.Rows("1", "2"))
+	tk.MustExec("insert t values (3), (4), (5)")
+	tk.CheckExecResult(3, 0)
+	warning := testkit.Rows("Warning 1105 The statement is committed in batches of 2 rows, it isn't atomic")
+	tk.MustQuery("show warnings").Check(warning)
+	tk.MustQuery("show warnings").Check(warning)
+	tk.MustQuery("select count(*) from t").Check(testkit.Rows("5"))
+	tk.MustQuery("show warnings").Check(testkit.Rows())
+	tk.MustExec("insert t select a + 10 from t")
+	tk.CheckExecResult(5, 0)
+	tk.MustQuery("select count(*) from t").Check(testkit.Rows("10"))
+
+	// The rows are written in one transaction in an explicit transaction.
+	tk.MustExec("set @@tidb_batch_delete = 1")
+	tk.MustExec("begin")
+	tk.MustExec("insert t values (6), (7), (8)")
+	tk.MustExec("delete from t where a > 10")
+	tk.MustQuery("show warnings").Check(testkit.Rows())
+	tk.MustExec("rollback")
+	tk.MustQuery("select count(*) from t").Check(testkit.Rows("10"))
+
+	tk.MustExec("delete from t where a > 10")
+	tk.CheckExecResult(5, 0)
+	tk.MustQuery("select a from t").Check(testkit.Rows("1", "2", "3", "4", "5"))
+
+	// 0 batch size writes all the rows in one transaction.
+	tk.MustExec("set @@tidb_dml_batch_size = 0")
+	_, err = tk.Exec("insert t values (6), (7), (8), (1)")
+	c.Assert(err, NotNil)
+	tk.MustQuery("select count(*) from t").Check(testkit.Rows("5"))
+	tk.MustExec("set @@tidb_batch_insert = 0, @@tidb_batch_delete = 0")
+	tk.MustQuery("select @@tidb_batch_insert, @@tidb_batch_delete").Check(testkit.Rows("0 0"))
+}
+
 func (s *testSuite) fillDataMultiTable(tk *testkit.TestKit) {
 	tk.MustExec("use test")
 	tk.MustExec("drop table if exists t1, t2, t3")
@@ -544,6 +630,16 @@ func (s *testSuite) TestMultiTableDelete(c *C) {
 	// Select data
 	r := tk.MustQuery("select * from t3")
 	c.Assert(r.Rows(), HasLen, 3)
+
+	tk.MustExec("insert into t1 values (1, 1), (2, 2)")
+	tk.MustExec("insert into t2 values (1, 1), (1, 2)")
+	tk.MustExec("delete t1.*, test.t2.* from t1, t2 where t1.id = t2.id")
+	tk.CheckExecResult(3, 0)
+	tk.MustQuery("select id from t1 where id < 10").Check(testkit.Rows("2"))
+	tk.MustQuery("select id from t2").Check(testkit.Rows("22", "23"))
+
+	_, err := tk.Exec("delete t1, t4 from t1, t2 where t1.id = t2.id")
+	c.Assert(terror.ErrorEqual(err, plan.ErrUnknownTable), IsTrue)
 }
 
 func (s *testSuite) TestQualifiedDelete(c *C) {
@@ -794,6 +890,148 @@ func (s *testSuite) TestLoadDataEscape(c *C) {
 	checkCases(cases, ld, c, tk, ctx, selectSQL, deleteSQL)
 }
 
+func (s *testSuite) TestLoadDataIgnoreLines(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test; drop table if exists load_data_test;")
+	tk.MustExec("create table load_data_test (id int primary key, c1 int)")
+	tk.MustExec("load data local infile '/tmp/nonexistence.csv' into table load_data_test")
+	ctx := tk.Se.(context.Context)
+	ld := makeLoadDataInfo(2, ctx, c)
+	ld.IgnoreLines = 2
+	ld.BatchSize = 2
+	// The ignored lines may span the chunks of the data.
+	data, err := ld.InsertData(nil, []byte("id\tc1\n--"))
+	c.Assert(err, IsNil)
+	data, err = ld.InsertData(data, []byte("\n1\t1\n2\t2\n3"))
+	c.Assert(err, IsNil)
+	_, err = ld.InsertData(data, nil)
+	c.Assert(err, IsNil)
+	c.Assert(ctx.CommitTxn(), IsNil)
+	tk.MustQuery("select * from load_data_test").Check(testkit.Rows("1 1", "2 2", "3 0"))
+}
+
+func (s *testSuite) TestLoadDataServerFile(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	dir, err := ioutil.TempDir("", "tidb-load-data")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "t.csv")
+	c.Assert(ioutil.WriteFile(path, []byte("a,b\n1,10\n2,20\n3,30"), 0644), IsNil)
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test; drop table if exists load_data_test;")
+	tk.MustExec("create table load_data_test (id int primary key, c1 int)")
+
+	// The files on the server can't be read without the secure file directory.
+	_, err = tk.Exec(fmt.Sprintf("load data infile '%s' into table load_data_test", path))
+	c.Assert(err, NotNil)
+	executor.SecureFilePriv = dir
+	defer func() { executor.SecureFilePriv = "" }()
+	_, err = tk.Exec("load data infile '../t.csv' into table load_data_test")
+	c.Assert(err, NotNil)
+	// A link in the directory can't lead to a file out of it, and a file name starting with ".." is in it.
+	outDir, err := ioutil.TempDir("", "tidb-load-data-out")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(outDir)
+	c.Assert(ioutil.WriteFile(filepath.Join(outDir, "t.csv"), []byte("4,40"), 0644), IsNil)
+	c.Assert(os.Symlink(filepath.Join(outDir, "t.csv"), filepath.Join(dir, "link.csv")), IsNil)
+	_, err = tk.Exec("load data infile 'link.csv' into table load_data_test")
+	c.Assert(err, NotNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(dir, "..t.csv"), nil, 0644), IsNil)
+	tk.MustExec("load data infile '..t.csv' into table load_data_test")
+
+	// LOAD DATA INFILE needs the FILE privilege.
+	tk.MustExec("create user 'load_data_user'@'localhost'")
+	defer tk.MustExec("drop user 'load_data_user'@'localhost'")
+	tk.MustExec("grant insert on test.* to 'load_data_user'@'localhost'")
+	tku := testkit.NewTestKit(c, s.store)
+	tku.MustExec("use test")
+	tku.Se.(context.Context).GetSessionVars().User = "load_data_user@localhost"
+	_, err = tku.Exec("load data infile 't.csv' into table load_data_test")
+	c.Assert(executor.ErrSpecificAccessDenied.Equal(err), IsTrue, Commentf("err %v", err))
+
+	tk.MustExec("set @@tidb_dml_batch_size = 2")
+	// The rows are loaded in one transaction in an explicit transaction.
+	tk.MustExec("begin")
+	tk.MustExec("load data infile 't.csv' into table load_data_test fields terminated by ',' ignore 1 lines")
+	tk.MustExec("rollback")
+	tk.MustQuery("select count(*) from load_data_test").Check(testkit.Rows("0"))
+
+	tk.MustExec(fmt.Sprintf("load data infile '%s' into table load_data_test fields terminated by ',' ignore 1 lines", path))
+	tk.MustQuery("select * from load_data_test").Check(testkit.Rows("1 10", "2 20", "3 30"))
+	_, err = tk.Exec("set @@tidb_dml_batch_size = -1")
+	c.Assert(err, IsNil)
+	_, err = tk.Exec("load data infile 't.csv' into table load_data_test")
+	c.Assert(err, NotNil)
+}
+
+func (s *testSuite) TestSelectIntoOutfile(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	dir, err := ioutil.TempDir("", "tidb-select-into")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test; drop table if exists t;")
+	tk.MustExec("create table t (a int primary key, b varchar(20), c double)")
+	tk.MustExec(`insert t values (1, 'x', 1.5), (2, null, 2), (3, 'a,b"\\c', null)`)
+
+	// The files can't be written without the secure file directory.
+	_, err = tk.Exec(fmt.Sprintf("select * from t into outfile '%s'", filepath.Join(dir, "t.txt")))
+	c.Assert(err, NotNil)
+	executor.SecureFilePriv = dir
+	defer func() { executor.SecureFilePriv = "" }()
+	_, err = tk.Exec("select * from t into outfile '../t.txt'")
+	c.Assert(err, NotNil)
+
+	checkFile := func(name, expected string) {
+		data, err1 := ioutil.ReadFile(filepath.Join(dir, name))
+		c.Assert(err1, IsNil)
+		c.Assert(string(data), Equals, expected)
+	}
+	tk.MustExec("select * from t into outfile 't.txt'")
+	c.Assert(tk.Se.AffectedRows(), Equals, uint64(3))
+	checkFile("t.txt", "1\tx\t1.5\n2\t\\N\t2\n3\ta,b\"\\\\c\t\\N\n")
+	tk.MustExec(`select a, b from t where a > 1 order by a desc into outfile 't.csv'
+		fields terminated by ',' enclosed by '"' lines starting by '>' terminated by '\r\n'`)
+	checkFile("t.csv", ">\"3\",\"a\\,b\\\"\\\\c\"\r\n>\"2\",\\N\r\n")
+	tk.MustExec("select a from t where a = 1 into outfile 't1.txt' fields escaped by ''")
+	checkFile("t1.txt", "1\n")
+	tk.MustExec("select b from t where a = 2 into outfile 't2.txt' fields escaped by ''")
+	checkFile("t2.txt", "NULL\n")
+
+	// An existing file isn't overwritten.
+	_, err = tk.Exec("select * from t into outfile 't.txt'")
+	c.Assert(terror.ErrorEqual(err, executor.ErrFileExists), IsTrue)
+	checkFile("t.txt", "1\tx\t1.5\n2\t\\N\t2\n3\ta,b\"\\\\c\t\\N\n")
+
+	// SELECT ... INTO OUTFILE needs the FILE privilege.
+	tk.MustExec("create user 'select_into_user'@'localhost'")
+	defer tk.MustExec("drop user 'select_into_user'@'localhost'")
+	tk.MustExec("grant select on test.* to 'select_into_user'@'localhost'")
+	tku := testkit.NewTestKit(c, s.store)
+	tku.MustExec("use test")
+	tku.Se.(context.Context).GetSessionVars().User = "select_into_user@localhost"
+	_, err = tku.Exec("select * from t into outfile 't4.txt'")
+	c.Assert(executor.ErrSpecificAccessDenied.Equal(err), IsTrue, Commentf("err %v", err))
+	_, err = os.Stat(filepath.Join(dir, "t4.txt"))
+	c.Assert(os.IsNotExist(err), IsTrue)
+
+	// The file can be read back by LOAD DATA INFILE.
+	tk.MustExec("create table t_load (a int primary key, b varchar(20))")
+	tk.MustExec("select a, b from t where a != 2 into outfile 't3.txt'")
+	tk.MustExec("load data infile 't3.txt' into table t_load")
+	tk.MustQuery(`select a from t_load where b in ('x', 'a,b"\\c')`).Check(testkit.Rows("1", "3"))
+}
+
 func makeLoadDataInfo(column int, ctx context.Context, c *C) (ld *executor.LoadDataInfo) {
 	domain := sessionctx.GetDomain(ctx)
 	is := domain.InfoSchema()
@@ -807,3 +1045,181 @@ func makeLoadDataInfo(column int, ctx context.Context, c *C) (ld *executor.LoadD
 	ld.LinesInfo = lines
 	return
 }
+
+func (s *testSuite) TestWriteHotspots(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t_hot")
+	tk.MustExec("create table t_hot (a int primary key, b int, c int, index idx_b(b), index idx_c(c))")
+	is := sessionctx.GetDomain(tk.Se.(context.Context)).InfoSchema()
+	tbl, err := is.TableByName(model.NewCIStr("test"), model.NewCIStr("t_hot"))
+	c.Assert(err, IsNil)
+	tblInfo := tbl.Meta()
+	keys := []hotspot.Key{
+		{TableID: tblInfo.ID},
+		{TableID: tblInfo.ID, IndexID: tblInfo.Indices[0].ID},
+		{TableID: tblInfo.ID, IndexID: tblInfo.Indices[1].ID},
+	}
+	// The counters are global in the server, so only their increments are checked.
+	base := hotspot.GlobalRecorder.Stats()
+	checkWrites := func(writes ...uint64) {
+		stats := hotspot.GlobalRecorder.Stats()
+		for i, key := range keys {
+			c.Assert(stats[key].Writes-base[key].Writes, Equals, writes[i], Commentf("%v", key))
+		}
+	}
+
+	tk.MustExec("insert t_hot values (1, 1, 1), (2, 2, 2), (3, 3, 3)")
+	checkWrites(3, 3, 3)
+	// Only the updated indices are written.
+	tk.MustExec("update t_hot set b = 10 where a = 1")
+	checkWrites(4, 4, 3)
+	tk.MustExec("delete from t_hot where a = 2")
+	checkWrites(5, 5, 4)
+	tk.MustExec("replace t_hot values (3, 3, 30)")
+	checkWrites(7, 7, 6)
+	tk.MustQuery("select index_name from information_schema.tidb_write_hotspots where table_schema = 'test' and table_name = 't_hot' order by index_name").
+		Check(testkit.Rows("<nil>", "idx_b", "idx_c"))
+
+	// The transaction of tk conflicts with the update of tk1 and is retried.
+	tk1 := testkit.NewTestKit(c, s.store)
+	tk1.MustExec("use test")
+	tk.MustExec("begin")
+	tk.MustExec("update t_hot set c = 100 where a = 1")
+	tk1.MustExec("update t_hot set c = 200 where a = 1")
+	tk.MustExec("commit")
+	tk.MustQuery("select c from t_hot where a = 1").Check(testkit.Rows("100"))
+	stats := hotspot.GlobalRecorder.Stats()
+	c.Assert(stats[keys[0]].Conflicts-base[keys[0]].Conflicts, Equals, uint64(1))
+	// 4 transactions above, the update of tk1, and the two commits of tk.
+	c.Assert(stats[keys[0]].Txns-base[keys[0]].Txns, Equals, uint64(7))
+
+	tk.MustExec("admin throttle table t_hot limit 50 for 1 minute")
+	tk.MustQuery("select throttle_limit from information_schema.tidb_write_hotspots where table_name = 't_hot' and index_name is null").
+		Check(testkit.Rows("50"))
+	// The writes may burst to the limit.
+	tk.MustExec("insert t_hot values (4, 4, 4), (5, 5, 5), (6, 6, 6), (7, 7, 7), (8, 8, 8), (9, 9, 9)")
+	// The statement exceeding the limit fails without waiting in its transaction.
+	tk.MustExec("admin throttle table t_hot limit 1 for 1 minute")
+	tk1.MustExec("insert t_hot values (10, 10, 10)")
+	start := time.Now()
+	_, err = tk1.Exec("insert t_hot values (11, 11, 11), (12, 12, 12)")
+	c.Assert(executor.ErrWriteThrottled.Equal(err), IsTrue, Commentf("err %v", err))
+	c.Assert(time.Since(start) < time.Second, IsTrue)
+	tk.MustQuery("select count(*) from t_hot where a >= 10").Check(testkit.Rows("1"))
+	// Only the users with the SUPER privilege can throttle the tables.
+	tk.MustExec("create user 'throttle_user'@'localhost'")
+	tk2 := testkit.NewTestKit(c, s.store)
+	tk2.MustExec("use test")
+	tk2.Se.(context.Context).GetSessionVars().User = "throttle_user@localhost"
+	_, err = tk2.Exec("admin throttle table t_hot limit 0")
+	c.Assert(executor.ErrSpecificAccessDenied.Equal(err), IsTrue, Commentf("err %v", err))
+	tk.MustExec("drop user 'throttle_user'@'localhost'")
+	tk.MustQuery("select throttle_limit from information_schema.tidb_write_hotspots where table_name = 't_hot' and index_name is null").
+		Check(testkit.Rows("1"))
+	tk.MustExec("admin throttle table t_hot limit 0")
+	tk.MustQuery("select throttle_limit from information_schema.tidb_write_hotspots where table_name = 't_hot' and index_name is null").
+		Check(testkit.Rows("<nil>"))
+
+	_, err = tk.Exec("admin throttle table t_hot limit 10 for 1 month")
+	c.Assert(terror.ErrorEqual(err, plan.ErrWrongArguments), IsTrue)
+	_, err = tk.Exec("admin throttle table t_none limit 10")
+	c.Assert(err, NotNil)
+}
+
+func (s *testSuite) TestStreamInsert(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t_stream")
+	tk.MustExec("create table t_stream (id int primary key auto_increment, a int, b int default 7, unique key (a))")
+	tk.MustExec("set @@tidb_stream_insert_size = 100")
+
+	// The rows are more than 2 chunks.
+	var buf bytes.Buffer
+	buf.WriteString("insert into t_stream (a, b) values ")
+	for i := 0; i < 2500; i++ {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		if i%2 == 0 {
+			fmt.Fprintf(&buf, "(%d, default)", i)
+		} else {
+			fmt.Fprintf(&buf, "(%d, %d + 1)", i, i)
+		}
+	}
+	buf.WriteString(";")
+	tk.MustExec(buf.String())
+	tk.MustQuery("select count(*), sum(b), max(id) from t_stream").Check(testkit.Rows("2500 1572500 2500"))
+	tk.MustQuery("select b from t_stream where a in (2000, 2001)").Check(testkit.Rows("7", "2002"))
+
+	// ON DUPLICATE KEY UPDATE applies to the rows of all the chunks.
+	buf.Reset()
+	buf.WriteString("insert into t_stream (a) values ")
+	for i := 0; i < 2500; i++ {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		fmt.Fprintf(&buf, "(%d)", i+1000)
+	}
+	buf.WriteString(" on duplicate key update b = 0")
+	tk.MustExec(buf.String())
+	tk.MustQuery("select count(*), sum(b = 0), max(a) from t_stream").Check(testkit.Rows("3500 1500 3499"))
+	tk.MustExec("insert into t_stream (a) values (5000)")
+	tk.MustQuery("select last_insert_id() > 2500").Check(testkit.Rows("1"))
+
+	buf.Reset()
+	buf.WriteString("replace into t_stream (id, a, b) values ")
+	for i := 0; i < 2500; i++ {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		fmt.Fprintf(&buf, "(%d, %d, 1)", i+1, i)
+	}
+	tk.MustExec(buf.String())
+	tk.MustQuery("select count(*), sum(b = 1) from t_stream").Check(testkit.Rows("3501 2500"))
+
+	// The value count of the rows of all the chunks must be the same.
+	buf.Reset()
+	buf.WriteString("insert into t_stream (a) values ")
+	for i := 0; i < 2500; i++ {
+		fmt.Fprintf(&buf, "(%d), ", i+10000)
+	}
+	buf.WriteString("(1, 2)")
+	tk.MustExec("begin")
+	_, err := tk.Exec(buf.String())
+	c.Assert(err, NotNil)
+	c.Assert(err.Error(), Matches, ".*at row 2501.*")
+	tk.MustExec("rollback")
+	tk.MustQuery("select count(*) from t_stream").Check(testkit.Rows("3501"))
+
+	tk.MustExec("set @@tidb_max_statement_size = 50")
+	_, err = tk.Exec("insert into t_stream (a) values (20000), (20001), (20002), (20003)")
+	c.Assert(executor.ErrStatementTooLarge.Equal(err), IsTrue)
+	_, err = tk.Exec("prepare stmt from 'insert into t_stream (a) values (?), (?), (?), (?)'")
+	c.Assert(executor.ErrStatementTooLarge.Equal(err), IsTrue)
+	// The SET statements aren't limited, and the invalid limits are rejected when they are set.
+	tk.MustExec("set @@tidb_max_statement_size = 5")
+	_, err = tk.Exec("select 1")
+	c.Assert(executor.ErrStatementTooLarge.Equal(err), IsTrue)
+	for _, sql := range []string{
+		"set @@tidb_max_statement_size = -1",
+		"set @@tidb_max_statement_params = -1",
+		"set @@tidb_stream_insert_size = 'abc'",
+	} {
+		_, err = tk.Exec(sql)
+		c.Assert(variable.ErrWrongValueForVar.Equal(err), IsTrue, Commentf("sql: %s", sql))
+	}
+	tk.MustExec("  SET @@tidb_max_statement_size = 0")
+	tk.MustExec("set @@tidb_max_statement_params = 3")
+	_, err = tk.Exec("prepare stmt from 'insert into t_stream (a) values (?), (?), (?), (?)'")
+	c.Assert(executor.ErrTooManyParams.Equal(err), IsTrue)
+	tk.MustExec("prepare stmt from 'insert into t_stream (a) values (?), (?), (?)'")
+}
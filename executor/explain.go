@@ -14,11 +14,16 @@
 package executor
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
+	"time"
 
 	"github.com/juju/errors"
+	"github.com/pingcap/tidb/ast"
 	"github.com/pingcap/tidb/expression"
 	"github.com/pingcap/tidb/plan"
+	"github.com/pingcap/tidb/util/chunk"
 	"github.com/pingcap/tidb/util/types"
 )
 
@@ -29,6 +34,14 @@ type ExplainExec struct {
 	schema   expression.Schema
 	rows     []*Row
 	cursor   int
+	// format is the output format, see ast.ExplainFormatRow, ast.ExplainFormatJSON and ast.ExplainFormatDOT.
+	format string
+
+	// stmtExec is the executor of the statement for EXPLAIN ANALYZE, it runs before the plan is explained.
+	stmtExec     Executor
+	runtimeStats runtimeStatsColl
+	// maxChunkSize is the capacity of the chunks the rows of stmtExec are read in, 0 reads them one by one.
+	maxChunkSize int
 }
 
 // Schema implements the Executor Schema interface.
@@ -36,8 +49,32 @@ func (e *ExplainExec) Schema() expression.Schema {
 	return e.schema
 }
 
+// explainChildren returns the plans explained as the children of p.
+func explainChildren(p plan.Plan) []plan.Plan {
+	switch x := p.(type) {
+	case *plan.PhysicalApply:
+		// The inner plan of an apply is not its child, but it's executed for every outer row.
+		children := make([]plan.Plan, 0, len(p.GetChildren())+1)
+		children = append(children, p.GetChildren()...)
+		return append(children, x.InnerPlan)
+	case *plan.PhysicalIndexMerge:
+		// The partial plans read the handles for the index merge.
+		children := make([]plan.Plan, 0, len(x.PartialPlans))
+		for _, partial := range x.PartialPlans {
+			children = append(children, partial)
+		}
+		return children
+	case *plan.CTE:
+		// The source of a common table expression is shared by the references, it's explained only once.
+		if x.Producer {
+			return []plan.Plan{x.Source.Plan}
+		}
+	}
+	return p.GetChildren()
+}
+
 func (e *ExplainExec) prepareExplainInfo(p plan.Plan, parent plan.Plan) error {
-	for _, child := range p.GetChildren() {
+	for _, child := range explainChildren(p) {
 		err := e.prepareExplainInfo(child, p)
 		if err != nil {
 			return errors.Trace(err)
@@ -54,14 +91,128 @@ func (e *ExplainExec) prepareExplainInfo(p plan.Plan, parent plan.Plan) error {
 	row := &Row{
 		Data: types.MakeDatums(p.GetID(), string(explain), parentStr),
 	}
+	if e.stmtExec != nil {
+		// The operators which are never executed have no statistics, e.g. the ones under a limit of 0.
+		var actRows, loops int64
+		var elapsed time.Duration
+		if stats, ok := e.runtimeStats[p]; ok {
+			actRows, loops, elapsed = stats.rows, stats.loops, stats.time
+		}
+		row.Data = append(row.Data, types.MakeDatums(p.EstimatedRowCount(), actRows, loops, elapsed.String())...)
+	}
 	e.rows = append(e.rows, row)
 	return nil
 }
 
+// explainNode is a node of the plan tree output in the JSON format.
+type explainNode struct {
+	ID      string  `json:"id"`
+	EstRows uint64  `json:"estRows"`
+	Cost    float64 `json:"cost"`
+	// Info is the access details of the plan, e.g. the table and the conditions of a table scan.
+	Info     json.RawMessage `json:"info"`
+	Children []*explainNode  `json:"children,omitempty"`
+}
+
+func buildExplainNode(p plan.Plan) (*explainNode, error) {
+	info, err := json.Marshal(p)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	node := &explainNode{
+		ID:      p.GetID(),
+		EstRows: p.EstimatedRowCount(),
+		Cost:    p.EstimatedCost(),
+		Info:    info,
+	}
+	for _, child := range explainChildren(p) {
+		childNode, err := buildExplainNode(child)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		node.Children = append(node.Children, childNode)
+	}
+	return node, nil
+}
+
+func (e *ExplainExec) prepareJSONInfo() error {
+	node, err := buildExplainNode(e.StmtPlan)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	explain, err := json.MarshalIndent(node, "", "    ")
+	if err != nil {
+		return errors.Trace(err)
+	}
+	e.rows = append(e.rows, &Row{Data: types.MakeDatums(string(explain))})
+	return nil
+}
+
+// prepareDotInfo outputs the plan tree as a directed graph in the DOT language, an edge points from a plan to
+// its child. It can be rendered by Graphviz, e.g. "dot -Tpng".
+func (e *ExplainExec) prepareDotInfo() {
+	buffer := bytes.NewBufferString(fmt.Sprintf("digraph %q {\n", e.StmtPlan.GetID()))
+	var writeNode func(p plan.Plan)
+	writeNode = func(p plan.Plan) {
+		label := fmt.Sprintf("%s\nestRows: %d\ncost: %.2f", p.GetID(), p.EstimatedRowCount(), p.EstimatedCost())
+		fmt.Fprintf(buffer, "%q [label=%q];\n", p.GetID(), label)
+		for _, child := range explainChildren(p) {
+			fmt.Fprintf(buffer, "%q -> %q;\n", p.GetID(), child.GetID())
+			writeNode(child)
+		}
+	}
+	writeNode(e.StmtPlan)
+	buffer.WriteString("}\n")
+	e.rows = append(e.rows, &Row{Data: types.MakeDatums(buffer.String())})
+}
+
+// runStmt executes the statement of EXPLAIN ANALYZE to collect the runtime statistics.
+func (e *ExplainExec) runStmt() error {
+	var chk *chunk.Chunk
+	if e.maxChunkSize > 0 && supportChunk(e.stmtExec) {
+		chk = newChunk(e.stmtExec, e.maxChunkSize)
+	}
+	for {
+		var (
+			done bool
+			err  error
+		)
+		if chk != nil {
+			err = nextChunk(e.stmtExec, chk)
+			done = !chk.IsFull()
+		} else {
+			var row *Row
+			row, err = e.stmtExec.Next()
+			done = row == nil
+		}
+		if err != nil {
+			e.stmtExec.Close()
+			return errors.Trace(err)
+		}
+		if done {
+			break
+		}
+	}
+	return errors.Trace(e.stmtExec.Close())
+}
+
 // Next implements Execution Next interface.
 func (e *ExplainExec) Next() (*Row, error) {
 	if e.cursor == 0 {
-		err := e.prepareExplainInfo(e.StmtPlan, nil)
+		if e.stmtExec != nil {
+			if err := e.runStmt(); err != nil {
+				return nil, errors.Trace(err)
+			}
+		}
+		var err error
+		switch e.format {
+		case ast.ExplainFormatJSON:
+			err = e.prepareJSONInfo()
+		case ast.ExplainFormatDOT:
+			e.prepareDotInfo()
+		default:
+			err = e.prepareExplainInfo(e.StmtPlan, nil)
+		}
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
@@ -79,3 +230,75 @@ func (e *ExplainExec) Close() error {
 	e.rows = nil
 	return nil
 }
+
+// runtimeStats is the runtime statistics of an operator.
+type runtimeStats struct {
+	// rows is the number of the rows returned in all the loops.
+	rows int64
+	// loops is the number of times the operator runs, e.g. the inner operator of an apply runs for every outer row.
+	loops int64
+	// time is the wall time spent in the operator, including the time spent in its children.
+	time time.Duration
+}
+
+// runtimeStatsColl collects the runtime statistics of the operators by their plans.
+type runtimeStatsColl map[plan.Plan]*runtimeStats
+
+// wrap returns an executor which records the runtime statistics of e for p.
+func (c runtimeStatsColl) wrap(p plan.Plan, e Executor) Executor {
+	stats, ok := c[p]
+	if !ok {
+		stats = &runtimeStats{}
+		c[p] = stats
+	}
+	return &runtimeStatsExec{Executor: e, stats: stats}
+}
+
+// runtimeStatsExec records the runtime statistics of the executor it wraps.
+type runtimeStatsExec struct {
+	Executor
+	stats *runtimeStats
+	// running is true from the first Next call to the Close call of a loop.
+	running bool
+}
+
+// Next implements the Executor Next interface.
+func (e *runtimeStatsExec) Next() (*Row, error) {
+	if !e.running {
+		e.running = true
+		e.stats.loops++
+	}
+	start := time.Now()
+	row, err := e.Executor.Next()
+	e.stats.time += time.Since(start)
+	if row != nil {
+		e.stats.rows++
+	}
+	return row, errors.Trace(err)
+}
+
+func (e *runtimeStatsExec) supportChunk() bool {
+	return supportChunk(e.Executor)
+}
+
+// NextChunk implements the chunkExecutor NextChunk interface.
+func (e *runtimeStatsExec) NextChunk(chk *chunk.Chunk) error {
+	if !e.running {
+		e.running = true
+		e.stats.loops++
+	}
+	start := time.Now()
+	err := e.Executor.(chunkExecutor).NextChunk(chk)
+	e.stats.time += time.Since(start)
+	e.stats.rows += int64(chk.NumRows())
+	return errors.Trace(err)
+}
+
+// Close implements the Executor Close interface.
+func (e *runtimeStatsExec) Close() error {
+	e.running = false
+	start := time.Now()
+	err := e.Executor.Close()
+	e.stats.time += time.Since(start)
+	return errors.Trace(err)
+}
@@ -14,7 +14,13 @@
 package executor_test
 
 import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
 	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/plan"
+	"github.com/pingcap/tidb/terror"
 	"github.com/pingcap/tidb/util/testkit"
 	"github.com/pingcap/tidb/util/testleak"
 )
@@ -86,7 +92,7 @@ func (s *testSuite) TestExplain(c *C) {
 		{
 			"select * from t2 order by c2",
 			[]string{
-				"TableScan_6", "Sort_3",
+				"TableScan_5", "Sort_3",
 			},
 			[]string{
 				"Sort_3", "",
@@ -111,7 +117,7 @@ func (s *testSuite) TestExplain(c *C) {
         }
     ],
     "limit": null,
-    "child": "TableScan_6"
+    "child": "TableScan_5"
 }`,
 			},
 		},
@@ -167,7 +173,7 @@ func (s *testSuite) TestExplain(c *C) {
 		{
 			"select * from t1 left join t2 on t1.c2 = t2.c1 where t1.c1 > 1",
 			[]string{
-				"TableScan_8", "TableScan_10", "HashLeftJoin_7",
+				"TableScan_8", "TableScan_9", "HashLeftJoin_7",
 			},
 			[]string{
 				"HashLeftJoin_7", "HashLeftJoin_7", "",
@@ -205,7 +211,7 @@ func (s *testSuite) TestExplain(c *C) {
     "rightCond": null,
     "otherCond": null,
     "leftPlan": "TableScan_8",
-    "rightPlan": "TableScan_10"
+    "rightPlan": "TableScan_9"
 }`,
 			},
 		},
@@ -271,10 +277,10 @@ func (s *testSuite) TestExplain(c *C) {
 		{
 			"select count(b.c2) from t1 a, t2 b where a.c1 = b.c2 group by a.c1",
 			[]string{
-				"TableScan_10", "TableScan_12", "HashAgg_14", "HashLeftJoin_9", "HashAgg_17",
+				"TableScan_18", "TableScan_12", "HashAgg_13", "IndexJoin_19", "HashAgg_20",
 			},
 			[]string{
-				"HashLeftJoin_9", "HashAgg_14", "HashLeftJoin_9", "HashAgg_17", "",
+				"IndexJoin_19", "HashAgg_13", "IndexJoin_19", "HashAgg_20", "",
 			},
 			[]string{`{
     "db": "test",
@@ -323,8 +329,9 @@ func (s *testSuite) TestExplain(c *C) {
     "leftCond": null,
     "rightCond": null,
     "otherCond": null,
-    "leftPlan": "TableScan_10",
-    "rightPlan": "HashAgg_14"
+    "outerPlan": "HashAgg_13",
+    "leftPlan": "TableScan_18",
+    "rightPlan": "HashAgg_13"
 }`,
 				`{
     "AggFuncs": [
@@ -333,7 +340,55 @@ func (s *testSuite) TestExplain(c *C) {
     "GroupByItems": [
         "a.c1"
     ],
-    "child": "HashLeftJoin_9"
+    "child": "IndexJoin_19"
+}`,
+			},
+		},
+		{
+			"select * from (select c1 from t1 union all select 'abcdefghijkl' from t2) t",
+			[]string{
+				"TableScan_7", "TableScan_9", "Projection_5", "Union_1",
+			},
+			[]string{
+				"Union_1", "Projection_5", "Union_1", "",
+			},
+			[]string{
+				`{
+    "db": "test",
+    "table": "t1",
+    "desc": false,
+    "keep order": false,
+    "push down info": {
+        "limit": 0,
+        "access conditions": null,
+        "filter conditions": null
+    }
+}`,
+				`{
+    "db": "test",
+    "table": "t2",
+    "desc": false,
+    "keep order": false,
+    "push down info": {
+        "limit": 0,
+        "access conditions": null,
+        "filter conditions": null
+    }
+}`,
+				`{
+    "exprs": [
+        "abcdefghijkl"
+    ],
+    "child": "TableScan_9"
+}`,
+				`{
+    "children": [
+        "TableScan_7",
+        "Projection_5"
+    ],
+    "result types": [
+        "varchar CHARACTER SET utf8 COLLATE utf8_general_ci"
+    ]
 }`,
 			},
 		},
@@ -347,3 +402,122 @@ func (s *testSuite) TestExplain(c *C) {
 		result.Check(testkit.Rows(resultList...))
 	}
 }
+
+func (s *testSuite) TestExplainAnalyze(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t1, t2")
+	tk.MustExec("create table t1 (c1 int primary key, c2 int)")
+	tk.MustExec("create table t2 (c1 int, c2 int)")
+	tk.MustExec("insert into t1 values (1, 1), (2, 2), (3, 3), (4, 4)")
+	tk.MustExec("insert into t2 values (1, 1), (2, 1), (3, 2)")
+
+	cases := []struct {
+		sql string
+		// result is the ID, ParentID, EstRows, ActRows and Loops of the operators.
+		result []string
+	}{
+		{
+			"select * from t1 where c2 > 1",
+			[]string{
+				"TableScan_4  8000000 3 1",
+			},
+		},
+		{
+			"select * from t1 where c2 > 1 limit 1",
+			[]string{
+				"TableScan_5  1 1 1",
+			},
+		},
+		{
+			"select c1, (select count(*) from t2 where t2.c2 = t1.c1) from t1",
+			[]string{
+				"TableScan_10 HashLeftJoin_9 10000000 4 1",
+				"TableScan_11 HashAgg_12 10000000 2 1",
+				"HashAgg_12 HashLeftJoin_9 1000000 2 1",
+				"HashLeftJoin_9 Projection_2 2147483647 4 1",
+				"Projection_2  2147483647 4 1",
+			},
+		},
+		{
+			"select c1, (select c2 from t2 where t2.c1 = t1.c1 limit 1) from t1",
+			[]string{
+				"TableScan_11 PhysicalAppy_10 10000000 4 1",
+				"TableScan_9 Cache_13 10000000 3 1",
+				"Cache_13 Selection_4 10000000 9 4",
+				"Selection_4 Limit_12 8000000 3 4",
+				"Limit_12 Projection_5 1 3 4",
+				"Projection_5 PhysicalAppy_10 1 3 4",
+				"PhysicalAppy_10  10000000 4 1",
+			},
+		},
+		{
+			"update t2 set c2 = 3 where c1 > 1",
+			[]string{
+				"TableScan_4 Update_3 8000000 2 1",
+				"Update_3  8000000 2 1",
+			},
+		},
+	}
+	for _, ca := range cases {
+		rows := tk.MustQuery("explain analyze " + ca.sql).Rows()
+		var result []string
+		for _, row := range rows {
+			c.Assert(row, HasLen, 7)
+			c.Assert(row[6], Not(Equals), "")
+			result = append(result, fmt.Sprintf("%v %v %v %v %v", row[0], row[2], row[3], row[4], row[5]))
+		}
+		c.Assert(result, DeepEquals, ca.result, Commentf("for %s", ca.sql))
+	}
+	tk.MustQuery("select * from t2").Check(testkit.Rows("1 1", "2 3", "3 3"))
+}
+
+func (s *testSuite) TestExplainFormat(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t1, t2")
+	tk.MustExec("create table t1 (c1 int primary key, c2 int)")
+	tk.MustExec("create table t2 (c1 int, c2 int)")
+
+	type node struct {
+		ID       string  `json:"id"`
+		EstRows  uint64  `json:"estRows"`
+		Children []*node `json:"children"`
+	}
+	rows := tk.MustQuery("explain format = 'json' select * from t1 where c1 > 1 order by c2 limit 3").Rows()
+	c.Assert(rows, HasLen, 1)
+	var root node
+	c.Assert(json.Unmarshal([]byte(rows[0][0].(string)), &root), IsNil)
+	c.Assert(root.ID, Equals, "Sort_8")
+	c.Assert(root.EstRows, Equals, uint64(3))
+	c.Assert(root.Children, HasLen, 1)
+	c.Assert(root.Children[0].ID, Equals, "TableScan_7")
+	c.Assert(root.Children[0].Children, HasLen, 0)
+
+	rows = tk.MustQuery("explain format = dot select c1, (select c2 from t2 where t2.c1 = t1.c1 limit 1) from t1").Rows()
+	c.Assert(rows, HasLen, 1)
+	dot := rows[0][0].(string)
+	c.Assert(strings.HasPrefix(dot, `digraph "PhysicalAppy_10" {`), IsTrue)
+	c.Assert(strings.HasSuffix(dot, "}\n"), IsTrue)
+	for _, edge := range []string{
+		`"PhysicalAppy_10" -> "TableScan_11";`,
+		`"PhysicalAppy_10" -> "Projection_5";`,
+		`"Projection_5" -> "Limit_12";`,
+		`"Limit_12" -> "Selection_4";`,
+		`"Selection_4" -> "Cache_13";`,
+		`"Cache_13" -> "TableScan_9";`,
+	} {
+		c.Assert(strings.Contains(dot, edge), IsTrue, Commentf("for %s", edge))
+	}
+
+	_, err := tk.Exec("explain format = 'xml' select * from t1")
+	c.Assert(terror.ErrorEqual(err, plan.ErrUnknownExplainFormat), IsTrue)
+}
@@ -493,7 +493,7 @@ func (e *GrantExec) getTargetSchema() (*model.DBInfo, error) {
 		}
 	}
 	//check if db exists
-	schema := model.NewCIStr(dbName)
+	schema := model.NewTableCIStr(dbName)
 	is := sessionctx.GetDomain(e.ctx).InfoSchema()
 	db, ok := is.SchemaByName(schema)
 	if !ok {
@@ -508,7 +508,7 @@ func (e *GrantExec) getTargetSchemaAndTable() (*model.DBInfo, table.Table, error
 	if err != nil {
 		return nil, nil, errors.Trace(err)
 	}
-	name := model.NewCIStr(e.Level.TableName)
+	name := model.NewTableCIStr(e.Level.TableName)
 	is := sessionctx.GetDomain(e.ctx).InfoSchema()
 	tbl, err := is.TableByName(db.Name, name)
 	if err != nil {
@@ -0,0 +1,137 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/expression"
+	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/privilege"
+	"github.com/pingcap/tidb/table"
+	"github.com/pingcap/tidb/util/hotspot"
+	"github.com/pingcap/tidb/util/types"
+)
+
+// handleSize is the size of the handle in a row key or an index entry.
+const handleSize = 8
+
+// recordWrite records the row written to the table and the entries written to its indices in the hotspot
+// counters, touched is the offsets of the updated columns, nil means the whole row is written or deleted.
+// If the writes of the table are throttled and the row exceeds the limit, it returns ErrWriteThrottled rather than
+// waiting with the locks and the memory of the transaction held, so the client backs off and retries.
+func recordWrite(ctx context.Context, t table.Table, row []types.Datum, touched map[int]bool) error {
+	tid := t.Meta().ID
+	if !hotspot.GlobalRecorder.Reserve(tid) {
+		return ErrWriteThrottled.Gen("Writes to table %s are throttled, retry later", t.Meta().Name.O)
+	}
+	hotspot.GlobalRecorder.RecordWrite(hotspot.Key{TableID: tid}, handleSize+datumsWriteSize(row))
+	for _, idx := range t.Indices() {
+		size := uint64(handleSize)
+		idxTouched := touched == nil
+		for _, ic := range idx.Meta().Columns {
+			idxTouched = idxTouched || touched[ic.Offset]
+			if ic.Offset < len(row) {
+				size += datumsWriteSize(row[ic.Offset : ic.Offset+1])
+			}
+		}
+		if idxTouched {
+			hotspot.GlobalRecorder.RecordWrite(hotspot.Key{TableID: tid, IndexID: idx.Meta().ID}, size)
+		}
+	}
+	return nil
+}
+
+// datumsWriteSize estimates the size of the datums when they are written.
+func datumsWriteSize(datums []types.Datum) uint64 {
+	var size uint64
+	for i := range datums {
+		switch datums[i].Kind() {
+		case types.KindNull:
+			size++
+		case types.KindString, types.KindBytes:
+			size += uint64(len(datums[i].GetBytes()))
+		default:
+			size += 8
+		}
+	}
+	return size
+}
+
+// ThrottleTableExec represents an admin throttle table executor.
+// It limits the rows written to a table per second in the server until the duration elapses, the statements
+// writing more rows than the limit fail with ErrWriteThrottled. It requires the SUPER privilege.
+type ThrottleTableExec struct {
+	ctx      context.Context
+	table    *model.TableInfo
+	limit    uint64
+	duration time.Duration
+	done     bool
+}
+
+// Schema implements the Executor Schema interface.
+func (e *ThrottleTableExec) Schema() expression.Schema {
+	return nil
+}
+
+// Next implements the Executor Next interface.
+func (e *ThrottleTableExec) Next() (*Row, error) {
+	if e.done {
+		return nil, nil
+	}
+	e.done = true
+	ok, err := privilege.CheckGlobal(e.ctx, mysql.SuperPriv)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if !ok {
+		return nil, ErrSpecificAccessDenied.Gen("Access denied; you need (at least one of) the %s privilege(s) for this operation", "SUPER")
+	}
+	if e.limit == 0 {
+		log.Infof("[hotspot] remove the throttle of the table %s", e.table.Name.O)
+		hotspot.GlobalRecorder.SetThrottle(e.table.ID, 0, 0)
+		return nil, nil
+	}
+	d := e.duration
+	if d == 0 {
+		d = hotspot.DefaultThrottleDuration
+	}
+	log.Infof("[hotspot] throttle the writes of the table %s to %d rows per second for %v", e.table.Name.O, e.limit, d)
+	hotspot.GlobalRecorder.SetThrottle(e.table.ID, e.limit, d)
+	return nil, nil
+}
+
+// Close implements the Executor Close interface.
+func (e *ThrottleTableExec) Close() error {
+	return nil
+}
+
+// RecordTxnWrites records the transaction of the context in the hotspot counters of the tables it writes,
+// conflict is true if it fails to commit by a write conflict.
+func RecordTxnWrites(ctx context.Context, conflict bool) {
+	x := ctx.Value(DirtyDBKey)
+	if x == nil {
+		return
+	}
+	udb := x.(*dirtyDB)
+	tids := make([]int64, 0, len(udb.tables))
+	for tid := range udb.tables {
+		tids = append(tids, tid)
+	}
+	hotspot.GlobalRecorder.RecordTxn(tids, conflict)
+}
@@ -0,0 +1,130 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/parser"
+	"github.com/pingcap/tidb/plan"
+	"github.com/pingcap/tidb/sessionctx"
+	"github.com/pingcap/tidb/sessionctx/variable"
+)
+
+// streamInsertChunkRows is the number of the rows parsed at once by the VALUES list of a streamed INSERT statement.
+var streamInsertChunkRows = 1024
+
+// CheckStatementSize checks the size of the text of the statements against tidb_max_statement_size. The SET
+// statements aren't checked, so a session with a too small limit can still raise it.
+func CheckStatementSize(ctx context.Context, sql string) error {
+	limit, err := getNonNegativeSessionVar(ctx, variable.TiDBMaxStatementSize)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if limit > 0 && int64(len(sql)) > limit && !isSetStmt(sql) {
+		return ErrStatementTooLarge.Gen("The statement is %d bytes, larger than tidb_max_statement_size %d", len(sql), limit)
+	}
+	return nil
+}
+
+// isSetStmt checks if the text starts with the SET keyword, it's checked before the statements are parsed.
+func isSetStmt(sql string) bool {
+	sql = strings.TrimLeftFunc(sql, unicode.IsSpace)
+	if len(sql) < 4 || !strings.EqualFold(sql[:3], "set") {
+		return false
+	}
+	return unicode.IsSpace(rune(sql[3])) || sql[3] == '@'
+}
+
+// ParseStreamInsert parses sql as an INSERT or REPLACE ... VALUES statement whose rows are parsed chunk by chunk
+// while it's executed, if sql is larger than tidb_stream_insert_size. ok is false if sql isn't streamed, the caller
+// should parse it as usual then.
+func ParseStreamInsert(ctx context.Context, p *parser.Parser, sql, charset, collation string) (stmt ast.StmtNode, ok bool, err error) {
+	limit, err := getNonNegativeSessionVar(ctx, variable.TiDBStreamInsertSize)
+	if err != nil {
+		return nil, false, errors.Trace(err)
+	}
+	if limit == 0 || int64(len(sql)) <= limit {
+		return nil, false, nil
+	}
+	text, ok := parser.SplitInsertValues(sql)
+	if !ok {
+		return nil, false, nil
+	}
+	rows, next := text.NextRows(0, streamInsertChunkRows)
+	insert, err := parseInsertChunk(p, &text, rows, charset, collation)
+	if err != nil {
+		return nil, false, errors.Trace(err)
+	}
+	insert.SetText(sql)
+	if next < len(text.Rows) {
+		insert.ValuesStream = &insertValuesStream{
+			text:      text,
+			start:     next,
+			charset:   charset,
+			collation: collation,
+		}
+	}
+	return insert, true, nil
+}
+
+// parseInsertChunk parses the statement with the header and the suffix of text around rows.
+func parseInsertChunk(p *parser.Parser, text *parser.InsertValuesText, rows, charset, collation string) (*ast.InsertStmt, error) {
+	stmt, err := p.ParseOneStmt(text.Header+" "+rows+" "+text.Suffix, charset, collation)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	insert, ok := stmt.(*ast.InsertStmt)
+	if !ok {
+		return nil, errors.Errorf("invalid chunk of the INSERT statement %T", stmt)
+	}
+	return insert, nil
+}
+
+// insertValuesStream implements ast.ValuesStream, it parses the rows after the first chunk of the text of a huge
+// INSERT statement.
+type insertValuesStream struct {
+	text parser.InsertValuesText
+	// start is the offset of the rows after the first chunk in text.Rows.
+	start     int
+	charset   string
+	collation string
+}
+
+// ReadChunk implements ast.ValuesStream ReadChunk interface.
+func (s *insertValuesStream) ReadChunk(ctx context.Context, offset int) ([][]ast.ExprNode, int, error) {
+	if offset == 0 {
+		offset = s.start
+	}
+	if offset >= len(s.text.Rows) {
+		return nil, offset, nil
+	}
+	rows, next := s.text.NextRows(offset, streamInsertChunkRows)
+	// The parser of the session may be parsing other statements, e.g. the restricted SQL.
+	insert, err := parseInsertChunk(parser.New(), &s.text, rows, s.charset, s.collation)
+	if err != nil {
+		return nil, 0, errors.Trace(err)
+	}
+	if err = plan.Preprocess(insert, sessionctx.GetDomain(ctx).InfoSchema(), ctx); err != nil {
+		return nil, 0, errors.Trace(err)
+	}
+	if err = plan.Validate(insert, false); err != nil {
+		return nil, 0, errors.Trace(err)
+	}
+	return insert.Lists, next, nil
+}
@@ -0,0 +1,114 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/util/memory"
+	"github.com/pingcap/tidb/util/types"
+)
+
+var (
+	datumMemUsage  = int64(unsafe.Sizeof(types.Datum{}))
+	rowMemOverhead = int64(unsafe.Sizeof(Row{}))
+)
+
+// memoryUsage records the memory consumed by an executor in the tracker of the statement,
+// so the memory can be released when the executor is closed.
+type memoryUsage struct {
+	tracker *memory.Tracker
+	// parent and label create the tracker of the executor as a child of the tracker of the statement. The tracker
+	// is detached when the executor is closed, and created again if the executor is reopened. If parent is nil,
+	// tracker is shared with another executor, and it isn't detached.
+	parent   *memory.Tracker
+	label    string
+	consumed int64
+	// killed is the kill signal of the session, so the executors consuming the rows of their children in a loop
+	// are interrupted by KILL QUERY.
+	killed *uint32
+}
+
+// getTracker returns the tracker of the executor, which is created if it's detached.
+func (m *memoryUsage) getTracker() *memory.Tracker {
+	if m.tracker == nil && m.parent != nil {
+		m.tracker = m.parent.NewChild(m.label)
+	}
+	return m.tracker
+}
+
+// consume records that the executor consumes more memory, it returns ErrMemoryExceeded if the statement
+// is canceled by the memory arbiter, ErrMemQuotaExceeded if the statement exceeds its memory quota, or
+// kv.ErrQueryInterrupted if the statement is killed.
+func (m *memoryUsage) consume(bytes int64) error {
+	if tracker := m.getTracker(); tracker != nil {
+		tracker.Consume(bytes)
+		m.consumed += bytes
+	}
+	if m.killed != nil && atomic.LoadUint32(m.killed) == 1 {
+		return errors.Trace(kv.ErrQueryInterrupted)
+	}
+	if m.tracker.Canceled() {
+		return errors.Trace(ErrMemoryExceeded)
+	}
+	if m.tracker.QuotaExceeded() {
+		return errors.Trace(ErrMemQuotaExceeded)
+	}
+	return nil
+}
+
+// release releases all the memory consumed by the executor.
+func (m *memoryUsage) release() {
+	m.tracker.Consume(-m.consumed)
+	m.consumed = 0
+}
+
+// close releases all the memory consumed by the executor, and detaches its tracker from the statement when the
+// executor is closed.
+func (m *memoryUsage) close() {
+	m.release()
+	if m.parent != nil {
+		m.tracker.Detach()
+		m.tracker = nil
+	}
+}
+
+// rowMemUsage estimates the memory used by a row.
+func rowMemUsage(row *Row) int64 {
+	return rowMemOverhead + datumsMemUsage(row.Data)
+}
+
+// datumsMemUsage estimates the memory used by datums, only the content of strings and bytes is counted
+// besides the datums themselves.
+func datumsMemUsage(datums []types.Datum) int64 {
+	usage := int64(len(datums)) * datumMemUsage
+	for i := range datums {
+		switch datums[i].Kind() {
+		case types.KindString, types.KindBytes:
+			usage += int64(len(datums[i].GetBytes()))
+		}
+	}
+	return usage
+}
+
+// logMemQuotaExceeded logs the memory consumed by each executor if the statement fails for exceeding its memory quota.
+func logMemQuotaExceeded(err error, tracker *memory.Tracker) {
+	if ErrMemQuotaExceeded.Equal(err) {
+		log.Warnf("[memory] the statement exceeds tidb_mem_quota_query, consumed: %s", tracker)
+	}
+}
@@ -0,0 +1,232 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sort"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/expression"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/table"
+	"github.com/pingcap/tidb/table/tables"
+	"github.com/pingcap/tidb/tablecodec"
+	"github.com/pingcap/tidb/util/types"
+)
+
+// PointGetExec reads at most one row by the handle or the unique index key, see plan.PointGet.
+type PointGetExec struct {
+	ctx     context.Context
+	schema  expression.Schema
+	table   table.Table
+	columns []*table.Column
+	// index is nil if the row is read by handle.
+	index       table.Index
+	indexValues []types.Datum
+	handle      int64
+	done        bool
+}
+
+// Schema implements the Executor Schema interface.
+func (e *PointGetExec) Schema() expression.Schema {
+	return e.schema
+}
+
+// Next implements the Executor Next interface.
+func (e *PointGetExec) Next() (*Row, error) {
+	if e.done {
+		return nil, nil
+	}
+	e.done = true
+	handle := e.handle
+	if e.index != nil {
+		txn, err := e.ctx.GetTxn(false)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		key, _, err := e.index.GenIndexKey(e.indexValues, 0)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		value, err := txn.Get(key)
+		if kv.IsErrNotFound(err) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		handle, err = decodeHandle(value)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	data, err := e.table.RowWithCols(e.ctx, handle, e.columns)
+	if kv.IsErrNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &Row{Data: data}, nil
+}
+
+// Close implements the Executor Close interface.
+func (e *PointGetExec) Close() error {
+	return nil
+}
+
+// BatchPointGetExec reads the rows by the handles or the unique index keys with at most two KV batch gets,
+// see plan.BatchPointGet. The rows are returned in the order of the handles or the index keys without duplications.
+type BatchPointGetExec struct {
+	ctx     context.Context
+	schema  expression.Schema
+	table   table.Table
+	columns []*table.Column
+	// index is nil if the rows are read by handles.
+	index       table.Index
+	indexValues [][]types.Datum
+	handles     []int64
+
+	fetched bool
+	rows    []*Row
+	cursor  int
+}
+
+// Schema implements the Executor Schema interface.
+func (e *BatchPointGetExec) Schema() expression.Schema {
+	return e.schema
+}
+
+// Next implements the Executor Next interface.
+func (e *BatchPointGetExec) Next() (*Row, error) {
+	if !e.fetched {
+		if err := e.fetchRows(); err != nil {
+			return nil, errors.Trace(err)
+		}
+		e.fetched = true
+	}
+	if e.cursor >= len(e.rows) {
+		return nil, nil
+	}
+	row := e.rows[e.cursor]
+	e.cursor++
+	return row, nil
+}
+
+func (e *BatchPointGetExec) fetchRows() error {
+	txn, err := e.ctx.GetTxn(false)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	var handles []int64
+	if e.index != nil {
+		keys := make([]kv.Key, 0, len(e.indexValues))
+		for _, values := range e.indexValues {
+			key, _, err := e.index.GenIndexKey(values, 0)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			keys = append(keys, key)
+		}
+		keys = sortUniqueKeys(keys)
+		values, err := txn.BatchGet(keys)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		handles = make([]int64, 0, len(values))
+		for _, key := range keys {
+			value, ok := values[string(key)]
+			if !ok {
+				continue
+			}
+			handle, err := decodeHandle(value)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			handles = append(handles, handle)
+		}
+	} else {
+		handles = make([]int64, 0, len(e.handles))
+		handles = append(handles, e.handles...)
+		sort.Sort(int64Slice(handles))
+		n := 0
+		for i, handle := range handles {
+			if i == 0 || handle != handles[n-1] {
+				handles[n] = handle
+				n++
+			}
+		}
+		handles = handles[:n]
+	}
+
+	keys := make([]kv.Key, 0, len(handles))
+	for _, handle := range handles {
+		keys = append(keys, tablecodec.EncodeRowKeyWithHandle(e.table.Meta().ID, handle))
+	}
+	values, err := txn.BatchGet(keys)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	e.rows = make([]*Row, 0, len(values))
+	for i, key := range keys {
+		value, ok := values[string(key)]
+		if !ok {
+			continue
+		}
+		data, err := tables.DecodeRawRowData(e.table.Meta(), handles[i], e.columns, value)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		e.rows = append(e.rows, &Row{Data: data})
+	}
+	return nil
+}
+
+// Close implements the Executor Close interface.
+func (e *BatchPointGetExec) Close() error {
+	e.fetched = false
+	e.rows = nil
+	e.cursor = 0
+	return nil
+}
+
+// sortUniqueKeys sorts the keys and removes the duplicated ones.
+func sortUniqueKeys(keys []kv.Key) []kv.Key {
+	sort.Sort(keySlice(keys))
+	n := 0
+	for i, key := range keys {
+		if i == 0 || key.Cmp(keys[n-1]) != 0 {
+			keys[n] = key
+			n++
+		}
+	}
+	return keys[:n]
+}
+
+type keySlice []kv.Key
+
+func (p keySlice) Len() int           { return len(p) }
+func (p keySlice) Less(i, j int) bool { return p[i].Cmp(p[j]) < 0 }
+func (p keySlice) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+
+// decodeHandle decodes the handle in the value of a unique index entry.
+func decodeHandle(data []byte) (int64, error) {
+	var h int64
+	buf := bytes.NewBuffer(data)
+	err := binary.Read(buf, binary.BigEndian, &h)
+	return h, errors.Trace(err)
+}
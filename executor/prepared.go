@@ -15,6 +15,7 @@ package executor
 
 import (
 	"sort"
+	"strings"
 
 	"github.com/juju/errors"
 	"github.com/pingcap/tidb/ast"
@@ -22,9 +23,12 @@ import (
 	"github.com/pingcap/tidb/evaluator"
 	"github.com/pingcap/tidb/expression"
 	"github.com/pingcap/tidb/infoschema"
+	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/parser"
 	"github.com/pingcap/tidb/plan"
 	"github.com/pingcap/tidb/sessionctx"
+	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/util/memory"
 	"github.com/pingcap/tidb/util/sqlexec"
 )
 
@@ -70,6 +74,131 @@ type Prepared struct {
 	Stmt          ast.StmtNode
 	Params        []*ast.ParamMarkerExpr
 	SchemaVersion int64
+
+	// resultShape is the result columns of the statement when it is prepared,
+	// the client may have cached them, so a schema change must not change them.
+	resultShape []resultColumn
+}
+
+// resultColumn is the name and type of a result column that the client sees.
+type resultColumn struct {
+	name string
+	// tableColumn is false if the column is computed from an expression, its type depends on
+	// the parameters and can not be decided at prepare time.
+	tableColumn bool
+	tp          byte
+	unsigned    bool
+}
+
+// getResultShape returns the result columns of a resolved statement, it returns nil if
+// the statement does not return rows.
+func getResultShape(stmt ast.StmtNode) []resultColumn {
+	rs, ok := stmt.(ast.ResultSetNode)
+	if !ok {
+		return nil
+	}
+	fields := rs.GetResultFields()
+	shape := make([]resultColumn, 0, len(fields))
+	for _, field := range fields {
+		col := resultColumn{name: field.ColumnAsName.L}
+		if col.name == "" && field.Column != nil {
+			// The result fields expanded from a wildcard have no alias.
+			col.name = field.Column.Name.L
+		}
+		if field.Column != nil && field.Column.ID != 0 {
+			col.tableColumn = true
+			col.tp = field.Column.Tp
+			col.unsigned = mysql.HasUnsignedFlag(field.Column.Flag)
+		}
+		shape = append(shape, col)
+	}
+	return shape
+}
+
+// isResultShapeCompatible checks whether the result columns after a schema change are still
+// the same as the ones the client got when the statement was prepared.
+func isResultShapeCompatible(origin, current []resultColumn) bool {
+	if len(origin) != len(current) {
+		return false
+	}
+	for i := range origin {
+		if origin[i].name != current[i].name || origin[i].tableColumn != current[i].tableColumn {
+			return false
+		}
+		if origin[i].tableColumn && (origin[i].tp != current[i].tp || origin[i].unsigned != current[i].unsigned) {
+			return false
+		}
+	}
+	return true
+}
+
+// preparableDDLNames are the names of the DDL statements which can be prepared, they can be excluded by the
+// tidb_prepare_excluded_ddl variable. The other DDL statements, e.g. CREATE VIEW, can't be prepared.
+var preparableDDLNames = map[string]struct{}{
+	"create_database": {},
+	"drop_database":   {},
+	"create_table":    {},
+	"drop_table":      {},
+	"create_index":    {},
+	"drop_index":      {},
+	"alter_table":     {},
+	"truncate_table":  {},
+}
+
+func ddlStmtName(stmt ast.DDLNode) string {
+	switch stmt.(type) {
+	case *ast.CreateDatabaseStmt:
+		return "create_database"
+	case *ast.DropDatabaseStmt:
+		return "drop_database"
+	case *ast.CreateTableStmt:
+		return "create_table"
+	case *ast.DropTableStmt:
+		return "drop_table"
+	case *ast.CreateIndexStmt:
+		return "create_index"
+	case *ast.DropIndexStmt:
+		return "drop_index"
+	case *ast.AlterTableStmt:
+		return "alter_table"
+	case *ast.TruncateTableStmt:
+		return "truncate_table"
+	}
+	return ""
+}
+
+// checkPreparable checks whether the statement can be prepared, it's the same for the PREPARE statement and the
+// binary protocol.
+func checkPreparable(vars *variable.SessionVars, stmt ast.StmtNode) error {
+	switch stmt.(type) {
+	case *ast.PrepareStmt, *ast.ExecuteStmt, *ast.DeallocateStmt:
+		return ErrUnsupportedPs
+	}
+	ddl, ok := stmt.(ast.DDLNode)
+	if !ok {
+		return nil
+	}
+	excluded, err := vars.GetTiDBSystemVar(variable.TiDBPrepareExcludedDDL)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	name := ddlStmtName(ddl)
+	if _, ok := preparableDDLNames[name]; !ok {
+		return ErrPrepareDDL
+	}
+	for _, s := range strings.Split(excluded, ",") {
+		s = strings.ToLower(strings.TrimSpace(s))
+		if s == "" {
+			continue
+		}
+		if _, ok := preparableDDLNames[s]; !ok {
+			return ErrPrepareDDL.Gen("Unknown DDL statement '%s' in %s", s, variable.TiDBPrepareExcludedDDL)
+		}
+		if s == name {
+			return ErrPrepareDDL
+		}
+	}
+	return nil
 }
 
 // PrepareExec represents a PREPARE executor.
@@ -78,6 +207,8 @@ type PrepareExec struct {
 	Ctx     context.Context
 	Name    string
 	SQLText string
+	// SQLVar is the user variable holding the statement of "PREPARE stmt FROM @var".
+	SQLVar *ast.VariableExpr
 
 	ID         uint32
 	ParamCount int
@@ -113,6 +244,24 @@ func (e *PrepareExec) DoPrepare() {
 			return
 		}
 	}
+	if e.SQLVar != nil {
+		val, err := evaluator.Eval(e.Ctx, e.SQLVar)
+		if err != nil {
+			e.Err = errors.Trace(err)
+			return
+		}
+		if val.IsNull() {
+			// The same as MySQL, a NULL variable is prepared as "NULL", which is a syntax error.
+			e.SQLText = "NULL"
+		} else if e.SQLText, err = val.ToString(); err != nil {
+			e.Err = errors.Trace(err)
+			return
+		}
+	}
+	if err := CheckStatementSize(e.Ctx, e.SQLText); err != nil {
+		e.Err = errors.Trace(err)
+		return
+	}
 	charset, collation := vars.GetCharsetInfo()
 	var (
 		stmts []ast.StmtNode
@@ -132,8 +281,8 @@ func (e *PrepareExec) DoPrepare() {
 		return
 	}
 	stmt := stmts[0]
-	if _, ok := stmt.(ast.DDLNode); ok {
-		e.Err = ErrPrepareDDL
+	if err = checkPreparable(vars, stmt); err != nil {
+		e.Err = errors.Trace(err)
 		return
 	}
 	var extractor paramMarkerExtractor
@@ -145,6 +294,16 @@ func (e *PrepareExec) DoPrepare() {
 	sorter := &paramMarkerSorter{markers: extractor.markers}
 	sort.Sort(sorter)
 	e.ParamCount = len(sorter.markers)
+	limit, err := getNonNegativeSessionVar(e.Ctx, variable.TiDBMaxStatementParams)
+	if err != nil {
+		e.Err = errors.Trace(err)
+		return
+	}
+	if limit > 0 && int64(e.ParamCount) > limit {
+		e.Err = ErrTooManyParams.Gen("Prepared statement contains %d placeholders, more than tidb_max_statement_params %d",
+			e.ParamCount, limit)
+		return
+	}
 	prepared := &Prepared{
 		Stmt:          stmt,
 		Params:        sorter.markers,
@@ -156,6 +315,7 @@ func (e *PrepareExec) DoPrepare() {
 		e.Err = errors.Trace(err)
 		return
 	}
+	prepared.resultShape = getResultShape(stmt)
 
 	if e.ID == 0 {
 		e.ID = vars.GetNextPreparedStmtID()
@@ -177,6 +337,10 @@ type ExecuteExec struct {
 	ID        uint32
 	StmtExec  Executor
 	Stmt      ast.StmtNode
+	// Plan is the plan of the prepared statement, it's built by Build.
+	Plan plan.Plan
+
+	memTracker *memory.Tracker
 }
 
 // Schema implements the Executor Schema interface.
@@ -224,25 +388,39 @@ func (e *ExecuteExec) Build() error {
 
 	ast.ResetEvaluatedFlag(prepared.Stmt)
 	if prepared.SchemaVersion != e.IS.SchemaMetaVersion() {
-		// If the schema version has changed we need to prepare it again,
-		// if this time it failed, the real reason for the error is schema changed.
-		err := plan.PrepareStmt(e.IS, e.Ctx, prepared.Stmt)
-		if err != nil {
-			return ErrSchemaChanged.Gen("Schema change caused error: %s", err.Error())
+		if err := e.rePrepare(prepared); err != nil {
+			return errors.Trace(err)
 		}
-		prepared.SchemaVersion = e.IS.SchemaMetaVersion()
 	}
 	p, err := plan.Optimize(e.Ctx, prepared.Stmt, e.IS)
 	if err != nil {
 		return errors.Trace(err)
 	}
 	b := newExecutorBuilder(e.Ctx, e.IS)
+	b.memTracker = e.memTracker
 	stmtExec := b.build(p)
 	if b.err != nil {
 		return errors.Trace(b.err)
 	}
 	e.StmtExec = stmtExec
 	e.Stmt = prepared.Stmt
+	e.Plan = p
+	return nil
+}
+
+// rePrepare prepares the statement again against the current schema, so the statement can still be
+// executed after a schema change as long as the columns it uses can be resolved and its result columns
+// are not changed, e.g. adding a column or an index never breaks it.
+func (e *ExecuteExec) rePrepare(prepared *Prepared) error {
+	// If this time it failed, the real reason for the error is schema changed.
+	err := plan.PrepareStmt(e.IS, e.Ctx, prepared.Stmt)
+	if err != nil {
+		return ErrSchemaChanged.Gen("Schema change caused error: %s", err.Error())
+	}
+	if !isResultShapeCompatible(prepared.resultShape, getResultShape(prepared.Stmt)) {
+		return ErrSchemaChanged.Gen("Schema change caused error: result columns of the prepared statement changed")
+	}
+	prepared.SchemaVersion = e.IS.SchemaMetaVersion()
 	return nil
 }
 
@@ -286,5 +464,8 @@ func CompileExecutePreparedStmt(ctx context.Context, ID uint32, args ...interfac
 		is:   sessionctx.GetDomain(ctx).InfoSchema(),
 		plan: execPlan,
 	}
+	if prepared, ok := ctx.GetSessionVars().PreparedStmts[ID].(*Prepared); ok {
+		sa.text = prepared.Stmt.Text()
+	}
 	return sa
 }
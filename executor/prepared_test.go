@@ -14,6 +14,8 @@
 package executor_test
 
 import (
+	"fmt"
+
 	. "github.com/pingcap/check"
 	"github.com/pingcap/tidb/executor"
 	"github.com/pingcap/tidb/util/testkit"
@@ -79,3 +81,99 @@ func (s *testSuite) TestPrepared(c *C) {
 	exec.Next()
 	exec.Close()
 }
+
+func (s *testSuite) TestPreparedSchemaChange(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists prepare_schema")
+	tk.MustExec("create table prepare_schema (id int primary key, c1 int, c2 int)")
+	tk.MustExec("insert prepare_schema values (1, 1, 1), (2, 2, 2)")
+	tk.MustExec(`prepare stmt_all from 'select * from prepare_schema where id > ?'`)
+	tk.MustExec(`prepare stmt_c1 from 'select c1 from prepare_schema where c2 > ?'`)
+	tk.MustExec(`prepare stmt_insert from 'insert prepare_schema (id, c1) values (?, 3)'`)
+	tk.MustExec("set @a = 1")
+	tk.MustQuery("execute stmt_all using @a").Check(testkit.Rows("2 2 2"))
+	tk.MustQuery("execute stmt_c1 using @a").Check(testkit.Rows("2"))
+
+	// Adding a column or an index does not change the result columns, the statements are prepared again silently.
+	tk.MustExec("alter table prepare_schema add column c3 int default 3")
+	tk.MustExec("create index idx_c2 on prepare_schema (c2)")
+	tk.MustQuery("execute stmt_all using @a").Check(testkit.Rows("2 2 2"))
+	tk.MustQuery("execute stmt_c1 using @a").Check(testkit.Rows("2"))
+	tk.MustExec("set @b = 3")
+	tk.MustExec("execute stmt_insert using @b")
+	tk.MustQuery("select * from prepare_schema where id = 3").Check(testkit.Rows("3 3 <nil> 3"))
+
+	// Drop a column which is not used by the statements.
+	tk.MustExec("alter table prepare_schema drop column c3")
+	tk.MustQuery("execute stmt_all using @a").Check(testkit.Rows("2 2 2", "3 3 <nil>"))
+	tk.MustExec("set @b = 4")
+	tk.MustExec("execute stmt_insert using @b")
+
+	// Recreate c2 with another type, the result columns of stmt_all change.
+	tk.MustExec("drop index idx_c2 on prepare_schema")
+	tk.MustExec("alter table prepare_schema drop column c2")
+	_, err := tk.Exec("execute stmt_c1 using @a")
+	c.Assert(executor.ErrSchemaChanged.Equal(err), IsTrue)
+	tk.MustExec("alter table prepare_schema add column c2 varchar(10) default '9'")
+	_, err = tk.Exec("execute stmt_all using @a")
+	c.Assert(executor.ErrSchemaChanged.Equal(err), IsTrue)
+	tk.MustQuery("execute stmt_c1 using @a").Check(testkit.Rows("1", "2", "3", "3"))
+}
+
+func (s *testSuite) TestPreparedText(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists prepare_text, prepare_ddl")
+	tk.MustExec("create table prepare_text (id int primary key, c1 int)")
+	tk.MustExec("insert prepare_text values (1, 1), (2, 2)")
+
+	// The statement is read from the user variable when it's prepared.
+	tk.MustExec("set @sql = concat('select c1 from prepare_text', ' where id = ?')")
+	tk.MustExec("prepare stmt_var from @sql")
+	tk.MustExec("set @sql = 'select 1'")
+	tk.MustExec("set @a = 2")
+	tk.MustQuery("execute stmt_var using @a").Check(testkit.Rows("2"))
+	_, err := tk.Exec("prepare stmt_null from @no_such_var")
+	c.Assert(err, NotNil)
+
+	// The prepared statement commands can't be prepared.
+	for _, sql := range []string{"prepare stmt from 'select 1'", "execute stmt_var using @a", "deallocate prepare stmt_var"} {
+		_, err = tk.Exec(fmt.Sprintf(`prepare stmt_nested from "%s"`, sql))
+		c.Assert(executor.ErrUnsupportedPs.Equal(err), IsTrue, Commentf("for %s", sql))
+		_, _, _, err = tk.Se.PrepareStmt(sql)
+		c.Assert(executor.ErrUnsupportedPs.Equal(err), IsTrue, Commentf("for %s", sql))
+	}
+
+	// The DDL statements can be prepared if they aren't excluded.
+	_, err = tk.Exec("prepare stmt_ddl from 'create table prepare_ddl (a int)'")
+	c.Assert(executor.ErrPrepareDDL.Equal(err), IsTrue)
+	tk.MustExec("set @@tidb_prepare_excluded_ddl = 'drop_table, drop_database'")
+	tk.MustExec("prepare stmt_ddl from 'create table prepare_ddl (a int)'")
+	tk.MustExec("execute stmt_ddl")
+	tk.MustExec("insert prepare_ddl values (1)")
+	tk.MustQuery("select * from prepare_ddl").Check(testkit.Rows("1"))
+	_, _, _, err = tk.Se.PrepareStmt("drop table prepare_ddl")
+	c.Assert(executor.ErrPrepareDDL.Equal(err), IsTrue)
+	tk.MustExec("set @@tidb_prepare_excluded_ddl = 'drop_tables'")
+	_, _, _, err = tk.Se.PrepareStmt("drop table prepare_ddl")
+	c.Assert(executor.ErrPrepareDDL.Equal(err), IsTrue)
+	tk.MustExec("set @@tidb_prepare_excluded_ddl = ''")
+	// The DDL statements not in the allowlist can't be prepared even if nothing is excluded.
+	_, err = tk.Exec("prepare stmt_view from 'create view prepare_view as select 1'")
+	c.Assert(executor.ErrPrepareDDL.Equal(err), IsTrue)
+	_, _, _, err = tk.Se.PrepareStmt("create view prepare_view as select 1")
+	c.Assert(executor.ErrPrepareDDL.Equal(err), IsTrue)
+	tk.MustExec("prepare stmt_ddl from 'drop table prepare_ddl'")
+	tk.MustExec("execute stmt_ddl")
+	_, err = tk.Exec("select * from prepare_ddl")
+	c.Assert(err, NotNil)
+}
@@ -0,0 +1,255 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"sort"
+
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/expression"
+	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/plan"
+	"github.com/pingcap/tidb/util/codec"
+	"github.com/pingcap/tidb/util/types"
+	"github.com/pingcap/tipb/go-tipb"
+)
+
+// runtimeFilter filters the rows of the big table of a hash join by the join keys of the small table. It's
+// derived after the hash table is built, and pushed down to the table scan of the big table before its
+// requests are sent, so the rows which can't be joined are skipped by the coprocessor, and the regions out of
+// the handle ranges of the join keys aren't read at all.
+type runtimeFilter struct {
+	scan *XSelectTableExec
+	// keys are the join keys of the big table which can be filtered, and the offsets of them in the hash keys.
+	keys    []*expression.Column
+	offsets []int
+	// maxInSize is the max number of the distinct values of a join key which are pushed down as an IN list,
+	// the min and max values are pushed down instead if it's exceeded.
+	maxInSize int
+
+	// The ranges and the conditions of the scan before the filter is pushed down, the filter is derived again
+	// for each execution of the join.
+	ranges []plan.TableRange
+	where  *tipb.Expr
+	filter lazyFilter
+
+	// The values of each join key of the small table.
+	hasValue []bool
+	min, max []types.Datum
+	values   []map[string]types.Datum
+}
+
+// newRuntimeFilter returns a runtime filter of the hash join, or nil if the big table can't be filtered.
+func newRuntimeFilter(e *HashJoinExec, maxInSize int) *runtimeFilter {
+	if e.outer || maxInSize <= 0 {
+		// The unmatched rows of the outer table are output too.
+		return nil
+	}
+	bigExec := e.bigExec
+	if stats, ok := bigExec.(*runtimeStatsExec); ok {
+		bigExec = stats.Executor
+	}
+	scan, ok := bigExec.(*XSelectTableExec)
+	if !ok || scan.aggregate || scan.limitCount != nil {
+		return nil
+	}
+	f := &runtimeFilter{scan: scan, maxInSize: maxInSize}
+	for i, key := range e.bigHashKey {
+		// The key values of the small table are compared to the column of the big table as they are.
+		if key.ID == 0 || key.RetType.Tp != e.targetTypes[i].Tp || e.smallHashKey[i].RetType.Tp != key.RetType.Tp {
+			continue
+		}
+		switch key.RetType.Tp {
+		case mysql.TypeTiny, mysql.TypeShort, mysql.TypeInt24, mysql.TypeLong, mysql.TypeLonglong, mysql.TypeYear,
+			mysql.TypeVarchar, mysql.TypeVarString, mysql.TypeString:
+			f.keys = append(f.keys, key)
+			f.offsets = append(f.offsets, i)
+		}
+	}
+	if len(f.keys) == 0 {
+		return nil
+	}
+	f.ranges, f.where, f.filter = scan.ranges, scan.where, scan.filter
+	return f
+}
+
+// reset clears the values of the join keys before the hash table is built.
+func (f *runtimeFilter) reset() {
+	f.hasValue = make([]bool, len(f.keys))
+	f.min = make([]types.Datum, len(f.keys))
+	f.max = make([]types.Datum, len(f.keys))
+	f.values = make([]map[string]types.Datum, len(f.keys))
+	for i := range f.values {
+		f.values[i] = make(map[string]types.Datum)
+	}
+}
+
+// collect records the join key values of a row of the small table.
+func (f *runtimeFilter) collect(keyValues []types.Datum) error {
+	for i, offset := range f.offsets {
+		val := keyValues[offset]
+		if !f.hasValue[i] {
+			f.min[i], f.max[i] = val, val
+			f.hasValue[i] = true
+		} else {
+			cmp, err := val.CompareDatum(f.min[i])
+			if err != nil {
+				return errors.Trace(err)
+			}
+			if cmp < 0 {
+				f.min[i] = val
+			}
+			cmp, err = val.CompareDatum(f.max[i])
+			if err != nil {
+				return errors.Trace(err)
+			}
+			if cmp > 0 {
+				f.max[i] = val
+			}
+		}
+		if f.values[i] == nil {
+			continue
+		}
+		key, err := codec.EncodeValue(nil, val)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		f.values[i][string(key)] = val
+		if len(f.values[i]) > f.maxInSize {
+			f.values[i] = nil
+		}
+	}
+	return nil
+}
+
+// pushDown pushes the filter down to the table scan of the big table.
+func (f *runtimeFilter) pushDown() error {
+	scan := f.scan
+	scan.ranges, scan.where, scan.filter = f.ranges, f.where, f.filter
+	var conditions []expression.Expression
+	for i, key := range f.keys {
+		if !f.hasValue[i] {
+			// The small table is empty, no row of the big table can be joined.
+			scan.ranges = nil
+			log.Debugf("[runtime filter] skip the scan of %s", scan.tableInfo.Name)
+			return nil
+		}
+		var values []types.Datum
+		if f.values[i] != nil {
+			values = make([]types.Datum, 0, len(f.values[i]))
+			for _, val := range f.values[i] {
+				values = append(values, val)
+			}
+			if err := types.SortDatums(values); err != nil {
+				return errors.Trace(err)
+			}
+		}
+		if f.isHandle(key) && f.min[i].Kind() == types.KindInt64 && f.max[i].Kind() == types.KindInt64 {
+			scan.ranges = filterTableRanges(scan.ranges, f.min[i].GetInt64(), f.max[i].GetInt64(), values)
+			continue
+		}
+		cond, err := runtimeFilterCondition(key, f.min[i], f.max[i], values)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		conditions = append(conditions, cond)
+	}
+	if len(conditions) == 0 {
+		return nil
+	}
+	pbExpr, _, remained := plan.ExpressionsToPB(conditions, scan.ctx.GetClient())
+	if pbExpr != nil {
+		if scan.where != nil {
+			pbExpr = &tipb.Expr{
+				Tp:       tipb.ExprType_And,
+				Children: []*tipb.Expr{scan.where, pbExpr}}
+		}
+		scan.where = pbExpr
+	}
+	if len(remained) > 0 {
+		filters := make([]expression.Expression, 0, len(scan.filter.conditions)+len(remained))
+		filters = append(filters, scan.filter.conditions...)
+		scan.filter = newLazyFilter(append(filters, remained...))
+	}
+	log.Debugf("[runtime filter] push down %v to the scan of %s", conditions, scan.tableInfo.Name)
+	return nil
+}
+
+// isHandle checks if the join key is the integer primary key, which is the handle of the rows.
+func (f *runtimeFilter) isHandle(key *expression.Column) bool {
+	if !f.scan.tableInfo.PKIsHandle || mysql.HasUnsignedFlag(key.RetType.Flag) {
+		return false
+	}
+	for _, col := range f.scan.tableInfo.Columns {
+		if col.ID == key.ID {
+			return mysql.HasPriKeyFlag(col.Flag) && col.State == model.StatePublic
+		}
+	}
+	return false
+}
+
+// filterTableRanges returns the parts of the ranges between low and high, or the points of the ranges in the
+// handles if the handles are not nil. The handles are sorted.
+func filterTableRanges(ranges []plan.TableRange, low, high int64, handles []types.Datum) []plan.TableRange {
+	var result []plan.TableRange
+	if handles != nil {
+		for _, h := range handles {
+			handle := h.GetInt64()
+			idx := sort.Search(len(ranges), func(i int) bool { return ranges[i].HighVal >= handle })
+			if idx < len(ranges) && ranges[idx].LowVal <= handle {
+				result = append(result, plan.TableRange{LowVal: handle, HighVal: handle})
+			}
+		}
+		return result
+	}
+	for _, ran := range ranges {
+		if ran.HighVal < low || ran.LowVal > high {
+			continue
+		}
+		if ran.LowVal < low {
+			ran.LowVal = low
+		}
+		if ran.HighVal > high {
+			ran.HighVal = high
+		}
+		result = append(result, ran)
+	}
+	return result
+}
+
+// runtimeFilterCondition returns the condition "key in (values)", or "key >= min and key <= max" if the values
+// are nil.
+func runtimeFilterCondition(key *expression.Column, min, max types.Datum, values []types.Datum) (expression.Expression, error) {
+	retType := types.NewFieldType(mysql.TypeLonglong)
+	if values != nil {
+		args := make([]expression.Expression, 0, len(values)+1)
+		args = append(args, key)
+		for _, val := range values {
+			args = append(args, &expression.Constant{Value: val, RetType: key.RetType})
+		}
+		return expression.NewFunction(ast.In, retType, args...)
+	}
+	ge, err := expression.NewFunction(ast.GE, retType, key, &expression.Constant{Value: min, RetType: key.RetType})
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	le, err := expression.NewFunction(ast.LE, retType, key, &expression.Constant{Value: max, RetType: key.RetType})
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return expression.NewFunction(ast.AndAnd, retType, ge, le)
+}
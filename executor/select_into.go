@@ -0,0 +1,157 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"bufio"
+	"os"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/expression"
+	"github.com/pingcap/tidb/util/types"
+)
+
+// SelectIntoExec represents a select into outfile executor.
+// It writes the rows of the query to a new file on the server in SecureFilePriv, the fields of a row are separated
+// by the FIELDS TERMINATED BY string and enclosed by the ENCLOSED BY character, and each row starts with the
+// LINES STARTING BY string and ends with the LINES TERMINATED BY string.
+// See https://dev.mysql.com/doc/refman/5.7/en/select-into.html
+type SelectIntoExec struct {
+	ctx        context.Context
+	src        Executor
+	path       string
+	fieldsInfo *ast.FieldsClause
+	linesInfo  *ast.LinesClause
+
+	done bool
+}
+
+// Schema implements the Executor Schema interface.
+// The rows are written to the file, so no result set is returned.
+func (e *SelectIntoExec) Schema() expression.Schema {
+	return nil
+}
+
+// Next implements the Executor Next interface.
+func (e *SelectIntoExec) Next() (*Row, error) {
+	if e.done {
+		return nil, nil
+	}
+	e.done = true
+	if err := checkFilePriv(e.ctx); err != nil {
+		return nil, errors.Trace(err)
+	}
+	path, err := secureFilePath(e.path)
+	if err != nil {
+		return nil, errors.Annotate(err, "Select Into")
+	}
+	// An existing file is never overwritten.
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if os.IsExist(err) {
+		return nil, ErrFileExists.Gen("File '%s' already exists", e.path)
+	}
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	err = e.writeRows(file)
+	if err1 := file.Close(); err == nil {
+		err = err1
+	}
+	if err != nil {
+		// The partial file is removed, so the statement can be retried.
+		os.Remove(path)
+		return nil, errors.Trace(err)
+	}
+	return nil, nil
+}
+
+func (e *SelectIntoExec) writeRows(file *os.File) error {
+	w := bufio.NewWriter(file)
+	cols := len(e.src.Schema())
+	var buf []byte
+	for {
+		row, err := e.src.Next()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if row == nil {
+			break
+		}
+		buf = append(buf[:0], e.linesInfo.Starting...)
+		for i, d := range row.Data[:cols] {
+			if i > 0 {
+				buf = append(buf, e.fieldsInfo.Terminated...)
+			}
+			buf, err = e.appendField(buf, d)
+			if err != nil {
+				return errors.Trace(err)
+			}
+		}
+		buf = append(buf, e.linesInfo.Terminated...)
+		if _, err = w.Write(buf); err != nil {
+			return errors.Trace(err)
+		}
+		e.ctx.GetSessionVars().AddAffectedRows(1)
+	}
+	return errors.Trace(w.Flush())
+}
+
+// appendField appends the field to buf. NULL is written as the escape character followed by 'N', or as "NULL" if
+// there isn't an escape character. The escape character, the enclosing character, the first characters of the
+// terminators and NUL are escaped in the other values, so the file can be read back by LOAD DATA INFILE.
+func (e *SelectIntoExec) appendField(buf []byte, d types.Datum) ([]byte, error) {
+	escaped, enclosed := e.fieldsInfo.Escaped, e.fieldsInfo.Enclosed
+	if d.IsNull() {
+		if escaped == 0 {
+			return append(buf, "NULL"...), nil
+		}
+		return append(buf, escaped, 'N'), nil
+	}
+	str, err := d.ToString()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if enclosed != 0 {
+		buf = append(buf, enclosed)
+	}
+	for i := 0; i < len(str); i++ {
+		c := str[i]
+		if escaped != 0 {
+			switch {
+			case c == 0:
+				buf = append(buf, escaped, '0')
+				continue
+			case c == escaped || (enclosed != 0 && c == enclosed) || isTerminatorStart(c, e.fieldsInfo.Terminated) ||
+				isTerminatorStart(c, e.linesInfo.Terminated):
+				buf = append(buf, escaped)
+			}
+		}
+		buf = append(buf, c)
+	}
+	if enclosed != 0 {
+		buf = append(buf, enclosed)
+	}
+	return buf, nil
+}
+
+func isTerminatorStart(c byte, terminator string) bool {
+	return len(terminator) > 0 && terminator[0] == c
+}
+
+// Close implements the Executor Close interface.
+func (e *SelectIntoExec) Close() error {
+	return e.src.Close()
+}
@@ -24,12 +24,16 @@ import (
 	"github.com/pingcap/tidb/context"
 	"github.com/pingcap/tidb/expression"
 	"github.com/pingcap/tidb/infoschema"
+	"github.com/pingcap/tidb/meta"
 	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/plan/statistics"
 	"github.com/pingcap/tidb/privilege"
 	"github.com/pingcap/tidb/sessionctx/variable"
 	"github.com/pingcap/tidb/table"
+	"github.com/pingcap/tidb/terror"
 	"github.com/pingcap/tidb/util/charset"
+	"github.com/pingcap/tidb/util/stmtsummary"
 	"github.com/pingcap/tidb/util/types"
 )
 
@@ -42,6 +46,7 @@ type ShowExec struct {
 	Flag   int             // Some flag parsed from sql, such as FULL.
 	Full   bool
 	User   string // Used for show grants.
+	Digest string // Used for show plan for.
 
 	// Used by show variables
 	GlobalScope bool
@@ -96,6 +101,8 @@ func (e *ShowExec) fetchAll() error {
 		return e.fetchShowGrants()
 	case ast.ShowIndex:
 		return e.fetchShowIndex()
+	case ast.ShowPlanForDigest:
+		return e.fetchShowPlanForDigest()
 	case ast.ShowProcedureStatus:
 		return e.fetchShowProcedureStatus()
 	case ast.ShowStatus:
@@ -108,7 +115,9 @@ func (e *ShowExec) fetchAll() error {
 		return e.fetchShowTriggers()
 	case ast.ShowVariables:
 		return e.fetchShowVariables()
-	case ast.ShowWarnings, ast.ShowProcessList:
+	case ast.ShowWarnings:
+		return e.fetchShowWarnings()
+	case ast.ShowProcessList:
 		// empty result
 	}
 	return nil
@@ -129,6 +138,21 @@ func (e *ShowExec) fetchShowEngines() error {
 	return nil
 }
 
+// fetchShowWarnings shows the warnings of the latest executed statement.
+func (e *ShowExec) fetchShowWarnings() error {
+	for _, warn := range e.ctx.GetSessionVars().Warnings {
+		var m *mysql.SQLError
+		if te, ok := errors.Cause(warn).(*terror.Error); ok {
+			m = te.ToSQLError()
+		} else {
+			m = mysql.NewErrf(mysql.ErrUnknown, "%s", warn.Error())
+		}
+		row := &Row{Data: types.MakeDatums("Warning", int64(m.Code), m.Message)}
+		e.rows = append(e.rows, row)
+	}
+	return nil
+}
+
 func (e *ShowExec) fetchShowDatabases() error {
 	dbs := e.is.AllSchemaNames()
 	// TODO: let information_schema be the first database
@@ -145,16 +169,22 @@ func (e *ShowExec) fetchShowTables() error {
 	}
 	// sort for tables
 	var tableNames []string
+	views := make(map[string]bool)
 	for _, v := range e.is.SchemaTables(e.DBName) {
 		tableNames = append(tableNames, v.Meta().Name.O)
+		if v.Meta().IsView() {
+			views[v.Meta().Name.O] = true
+		}
 	}
 	sort.Strings(tableNames)
 	for _, v := range tableNames {
 		data := types.MakeDatums(v)
 		if e.Full {
-			// TODO: support "VIEW" later if we have supported view feature.
-			// now, just use "BASE TABLE".
-			data = append(data, types.NewDatum("BASE TABLE"))
+			if views[v] {
+				data = append(data, types.NewDatum("VIEW"))
+			} else {
+				data = append(data, types.NewDatum("BASE TABLE"))
+			}
 		}
 		e.rows = append(e.rows, &Row{Data: data})
 	}
@@ -171,14 +201,72 @@ func (e *ShowExec) fetchShowTableStatus() error {
 	sort.Sort(table.Slice(tables))
 
 	for _, t := range tables {
+		stats, err := e.getTableStats(t.Meta())
+		if err != nil {
+			return errors.Trace(err)
+		}
+		// The sizes are estimated by the statistics, they are all zero if the table has not been analyzed.
+		var rowCount, avgRowLength, dataLength, indexLength int64
+		if stats != nil {
+			rowCount = stats.Count
+			avgRowLength = int64(stats.AvgRowSize())
+			dataLength = rowCount * avgRowLength
+			for _, idx := range t.Meta().Indices {
+				var idxRowSize float64
+				for _, col := range idx.Columns {
+					idxRowSize += stats.Columns[col.Offset].AvgSize()
+				}
+				// Every index entry also stores the row handle.
+				indexLength += rowCount * int64(idxRowSize+8)
+			}
+		}
 		now := types.CurrentTime(mysql.TypeDatetime)
-		data := types.MakeDatums(t.Meta().Name.O, "InnoDB", "10", "Compact", 100, 100, 100, 100, 100, 100, 100,
-			now, now, now, "utf8_general_ci", "", "", t.Meta().Comment)
+		data := types.MakeDatums(
+			t.Meta().Name.O,   // Name
+			"InnoDB",          // Engine
+			"10",              // Version
+			"Compact",         // Row_format
+			rowCount,          // Rows
+			avgRowLength,      // Avg_row_length
+			dataLength,        // Data_length
+			0,                 // Max_data_length
+			indexLength,       // Index_length
+			0,                 // Data_free
+			nil,               // Auto_increment
+			now,               // Create_time
+			now,               // Update_time
+			now,               // Check_time
+			"utf8_general_ci", // Collation
+			"",                // Checksum
+			"",                // Create_options
+			t.Meta().Comment,  // Comment
+		)
 		e.rows = append(e.rows, &Row{Data: data})
 	}
 	return nil
 }
 
+// getTableStats gets the statistics of the table built by ANALYZE TABLE, it returns nil if the table has not been analyzed.
+func (e *ShowExec) getTableStats(tblInfo *model.TableInfo) (*statistics.Table, error) {
+	txn, err := e.ctx.GetTxn(false)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	tpb, err := meta.NewMeta(txn).GetTableStats(tblInfo.ID)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if tpb == nil {
+		return nil, nil
+	}
+	stats, err := statistics.TableFromPB(tblInfo, tpb)
+	if err != nil {
+		// The statistics is out of date if the table has been altered after analyzing.
+		return nil, nil
+	}
+	return stats, nil
+}
+
 func (e *ShowExec) fetchShowColumns() error {
 	tb, err := e.getTable()
 	if err != nil {
@@ -227,6 +315,10 @@ func (e *ShowExec) fetchShowIndex() error {
 	if err != nil {
 		return errors.Trace(err)
 	}
+	stats, err := e.getTableStats(tb.Meta())
+	if err != nil {
+		return errors.Trace(err)
+	}
 	if tb.Meta().PKIsHandle {
 		var pkCol *table.Column
 		for _, col := range tb.Cols() {
@@ -235,14 +327,18 @@ func (e *ShowExec) fetchShowIndex() error {
 				break
 			}
 		}
+		var cardinality interface{}
+		if stats != nil {
+			cardinality = stats.IndexCardinality([]int{pkCol.Offset}, 1, true)
+		}
 		data := types.MakeDatums(
 			tb.Meta().Name.O, // Table
 			0,                // Non_unique
 			"PRIMARY",        // Key_name
 			1,                // Seq_in_index
 			pkCol.Name.O,     // Column_name
-			"utf8_bin",       // Colation
-			0,                // Cardinality
+			"A",              // Collation
+			cardinality,      // Cardinality
 			nil,              // Sub_part
 			nil,              // Packed
 			"",               // Null
@@ -253,6 +349,10 @@ func (e *ShowExec) fetchShowIndex() error {
 		e.rows = append(e.rows, &Row{Data: data})
 	}
 	for _, idx := range tb.Indices() {
+		offsets := make([]int, len(idx.Meta().Columns))
+		for i, col := range idx.Meta().Columns {
+			offsets[i] = col.Offset
+		}
 		for i, col := range idx.Meta().Columns {
 			nonUniq := 1
 			if idx.Meta().Unique {
@@ -262,20 +362,28 @@ func (e *ShowExec) fetchShowIndex() error {
 			if col.Length != types.UnspecifiedLength {
 				subPart = col.Length
 			}
+			var cardinality interface{}
+			if stats != nil {
+				cardinality = stats.IndexCardinality(offsets, i+1, idx.Meta().Unique)
+			}
+			nullable := "YES"
+			if mysql.HasNotNullFlag(tb.Cols()[col.Offset].Flag) {
+				nullable = ""
+			}
 			data := types.MakeDatums(
-				tb.Meta().Name.O,  // Table
-				nonUniq,           // Non_unique
-				idx.Meta().Name.O, // Key_name
-				i+1,               // Seq_in_index
-				col.Name.O,        // Column_name
-				"utf8_bin",        // Colation
-				0,                 // Cardinality
-				subPart,           // Sub_part
-				nil,               // Packed
-				"YES",             // Null
+				tb.Meta().Name.O,       // Table
+				nonUniq,                // Non_unique
+				idx.Meta().Name.O,      // Key_name
+				i+1,                    // Seq_in_index
+				col.Name.O,             // Column_name
+				"A",                    // Collation
+				cardinality,            // Cardinality
+				subPart,                // Sub_part
+				nil,                    // Packed
+				nullable,               // Null
 				idx.Meta().Tp.String(), // Index_type
-				"",                 // Comment
-				idx.Meta().Comment, // Index_comment
+				"",                     // Comment
+				idx.Meta().Comment,     // Index_comment
 			)
 			e.rows = append(e.rows, &Row{Data: data})
 		}
@@ -361,6 +469,38 @@ func (e *ShowExec) fetchShowCreateTable() error {
 	if err != nil {
 		return errors.Trace(err)
 	}
+	if tb.Meta().IsView() {
+		if err = e.checkShowView(tb.Meta()); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	data := types.MakeDatums(tb.Meta().Name.O, ShowCreateTable(tb))
+	e.rows = append(e.rows, &Row{Data: data})
+	return nil
+}
+
+// checkShowView checks the privilege to show the definition of a view.
+func (e *ShowExec) checkShowView(tbInfo *model.TableInfo) error {
+	checker := privilege.GetPrivilegeChecker(e.ctx)
+	if checker == nil {
+		return nil
+	}
+	db := e.Table.DBInfo
+	hasPriv, err := checker.Check(e.ctx, db, tbInfo, mysql.ShowViewPriv)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if !hasPriv {
+		return errors.Errorf("You do not have the privilege to show view %s.%s.", db.Name, tbInfo.Name)
+	}
+	return nil
+}
+
+// ShowCreateTable returns the CREATE TABLE or CREATE VIEW statement of the table shown by SHOW CREATE TABLE.
+func ShowCreateTable(tb table.Table) string {
+	if tb.Meta().IsView() {
+		return showCreateView(tb.Meta())
+	}
 
 	// TODO: let the result more like MySQL.
 	var buf bytes.Buffer
@@ -475,13 +615,38 @@ func (e *ShowExec) fetchShowCreateTable() error {
 		buf.WriteString(fmt.Sprintf(" AUTO_INCREMENT=%d", tb.Meta().AutoIncID))
 	}
 
+	var ndvs []string
+	for _, col := range tb.Meta().Columns {
+		if col.StatsNDV > 0 {
+			ndvs = append(ndvs, fmt.Sprintf("`%s`=%d", col.Name.O, col.StatsNDV))
+		}
+	}
+	for _, idx := range tb.Meta().Indices {
+		if idx.StatsNDV > 0 {
+			ndvs = append(ndvs, fmt.Sprintf("INDEX `%s`=%d", idx.Name.O, idx.StatsNDV))
+		}
+	}
+	if len(ndvs) > 0 {
+		buf.WriteString(fmt.Sprintf(" STATS_NDV=(%s)", strings.Join(ndvs, ",")))
+	}
+
 	if len(tb.Meta().Comment) > 0 {
 		buf.WriteString(fmt.Sprintf(" COMMENT='%s'", tb.Meta().Comment))
 	}
+	return buf.String()
+}
 
-	data := types.MakeDatums(tb.Meta().Name.O, buf.String())
-	e.rows = append(e.rows, &Row{Data: data})
-	return nil
+func showCreateView(tbInfo *model.TableInfo) string {
+	cols := make([]string, 0, len(tbInfo.Columns))
+	for _, col := range tbInfo.Columns {
+		cols = append(cols, col.Name.O)
+	}
+	security := ""
+	if tbInfo.View.Security == model.SecurityInvoker {
+		security = "SQL SECURITY INVOKER "
+	}
+	return fmt.Sprintf("CREATE %sVIEW `%s` (`%s`) AS %s", security, tbInfo.Name.O, strings.Join(cols, "`,`"),
+		tbInfo.View.SelectStmt)
 }
 
 // Compose show create database result.
@@ -490,16 +655,19 @@ func (e *ShowExec) fetchShowCreateDatabase() error {
 	if !ok {
 		return infoschema.ErrDatabaseNotExists.Gen("Unknown database '%s'", e.DBName.O)
 	}
+	data := types.MakeDatums(db.Name.O, ShowCreateDatabase(db))
+	e.rows = append(e.rows, &Row{Data: data})
+	return nil
+}
 
+// ShowCreateDatabase returns the CREATE DATABASE statement of the database shown by SHOW CREATE DATABASE.
+func ShowCreateDatabase(db *model.DBInfo) string {
 	var buf bytes.Buffer
 	fmt.Fprintf(&buf, "CREATE DATABASE `%s`", db.Name.O)
 	if s := db.Charset; len(s) > 0 {
 		fmt.Fprintf(&buf, " /* !40100 DEFAULT CHARACTER SET %s */", s)
 	}
-
-	data := types.MakeDatums(db.Name.O, buf.String())
-	e.rows = append(e.rows, &Row{Data: data})
-	return nil
+	return buf.String()
 }
 
 func (e *ShowExec) fetchShowCollation() error {
@@ -539,6 +707,31 @@ func (e *ShowExec) fetchShowGrants() error {
 	return nil
 }
 
+// fetchShowPlanForDigest outputs the sample plans of the statement kept in stmtsummary.GlobalSummary, the most
+// recently executed one comes first. The users without the SUPER or PROCESS privilege only see their own plans.
+func (e *ShowExec) fetchShowPlanForDigest() error {
+	user, err := sqlUserFilter(e.ctx)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for _, p := range stmtsummary.GlobalSummary.Plans(e.Digest, user) {
+		data := types.MakeDatums(
+			p.Digest,
+			p.User,
+			p.PlanDigest,
+			p.SampleSQL,
+			p.ExecCount,
+			p.SumLatency.Seconds()/float64(p.ExecCount),
+			p.MaxLatency.Seconds(),
+			types.Time{Time: p.FirstSeen, Type: mysql.TypeDatetime},
+			types.Time{Time: p.LastSeen, Type: mysql.TypeDatetime},
+			p.Plan,
+		)
+		e.rows = append(e.rows, &Row{Data: data})
+	}
+	return nil
+}
+
 func (e *ShowExec) fetchShowTriggers() error {
 	return nil
 }
@@ -14,8 +14,13 @@
 package executor_test
 
 import (
+	"fmt"
+
 	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/parser"
 	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/util/stmtsummary"
 	"github.com/pingcap/tidb/util/testkit"
 	"github.com/pingcap/tidb/util/testleak"
 )
@@ -76,22 +81,35 @@ func (s *testSuite) TestShow(c *C) {
 	result = tk.MustQuery(testSQL)
 	c.Check(result.Rows(), HasLen, 2)
 	expectedRow = []interface{}{
-		"show_index", int64(0), "PRIMARY", int64(1), "id", "utf8_bin",
-		int64(0), nil, nil, "", "BTREE", "", ""}
+		"show_index", int64(0), "PRIMARY", int64(1), "id", "A",
+		nil, nil, nil, "", "BTREE", "", ""}
 	row = result.Rows()[0]
 	c.Check(row, HasLen, len(expectedRow))
 	for i, r := range row {
 		c.Check(r, Equals, expectedRow[i])
 	}
 	expectedRow = []interface{}{
-		"show_index", int64(1), "cIdx", int64(1), "c", "utf8_bin",
-		int64(0), nil, nil, "YES", "HASH", "", "index_comment_for_cIdx"}
+		"show_index", int64(1), "cIdx", int64(1), "c", "A",
+		nil, nil, nil, "YES", "HASH", "", "index_comment_for_cIdx"}
 	row = result.Rows()[1]
 	c.Check(row, HasLen, len(expectedRow))
 	for i, r := range row {
 		c.Check(r, Equals, expectedRow[i])
 	}
 
+	// Cardinality and table status are estimated by the statistics after analyzing.
+	tk.MustExec("insert into show_index values (1, 1), (2, 1), (3, 2)")
+	tk.MustExec("analyze table show_index")
+	result = tk.MustQuery("SHOW index from show_index")
+	c.Check(result.Rows()[0][6], Equals, int64(3))
+	c.Check(result.Rows()[1][6], Equals, int64(2))
+	result = tk.MustQuery("SHOW table status like 'show_index'")
+	row = result.Rows()[0]
+	c.Check(row[4], Equals, int64(3))
+	c.Check(row[5], Equals, int64(16))
+	c.Check(row[6], Equals, int64(48))
+	c.Check(row[8], Equals, int64(48))
+
 	// For show like with escape
 	testSQL = `show tables like 'show\_test'`
 	result = tk.MustQuery(testSQL)
@@ -185,3 +203,69 @@ func (s *testSuite) TestForeignKeyInShowCreateTable(c *C) {
 	}
 
 }
+
+func (s *testSuite) TestShowPlanForDigest(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (a int, b int, index idx(a))")
+	tk.MustExec("insert t values (1, 1), (2, 2)")
+	digest := parser.Digest("select * from t where a = 1")
+	// The summary is cleared when it's disabled.
+	defer stmtsummary.GlobalSummary.SetMaxStatements(stmtsummary.DefaultMaxStatements)
+	stmtsummary.GlobalSummary.SetMaxStatements(0)
+	tk.MustQuery("select * from t where a = 1").Check(testkit.Rows("1 1"))
+	tk.MustQuery(fmt.Sprintf("show plan for '%s'", digest)).Check(testkit.Rows())
+	stmtsummary.GlobalSummary.SetMaxStatements(stmtsummary.DefaultMaxStatements)
+
+	tk.MustQuery("select * from t where a = 1").Check(testkit.Rows("1 1"))
+	tk.MustQuery("select * from t where a = 2").Check(testkit.Rows("2 2"))
+	rows := tk.MustQuery(fmt.Sprintf("show plan for '%s'", digest)).Rows()
+	c.Assert(rows, HasLen, 1)
+	c.Assert(rows[0][0], Equals, digest)
+	c.Assert(rows[0][1], Equals, "")
+	c.Assert(rows[0][3], Equals, "select * from t where a = 1")
+	c.Assert(rows[0][4], Equals, uint64(2))
+	c.Assert(rows[0][9], Matches, `(?s).*"id": "IndexScan_\d+".*`)
+	indexPlanDigest := rows[0][2]
+
+	// The plan is changed, the previous one is kept.
+	tk.MustExec("alter table t drop index idx")
+	tk.MustQuery("select * from t where a = 1").Check(testkit.Rows("1 1"))
+	tk.MustExec("prepare stmt from 'select * from t where a = ?'")
+	tk.MustExec("set @a = 2")
+	tk.MustQuery("execute stmt using @a").Check(testkit.Rows("2 2"))
+	rows = tk.MustQuery(fmt.Sprintf("show plan for '%s'", digest)).Rows()
+	c.Assert(rows, HasLen, 2)
+	c.Assert(rows[0][2], Not(Equals), indexPlanDigest)
+	c.Assert(rows[0][4], Equals, uint64(2))
+	c.Assert(rows[0][9], Not(Matches), `(?s).*IndexScan.*`)
+	c.Assert(rows[1][2], Equals, indexPlanDigest)
+
+	tk.MustQuery("show plan for 'unknown'").Check(testkit.Rows())
+	tk.MustQuery(fmt.Sprintf("show plan for '%s'", parser.Digest("show plan for 'unknown'"))).Check(testkit.Rows())
+
+	// The plans of the other users are only shown with the SUPER or PROCESS privilege.
+	tk.MustExec("create user 'plan_user'@'localhost'")
+	tk.MustExec("grant select on test.* to 'plan_user'@'localhost'")
+	newSession := func() *testkit.TestKit {
+		tk := testkit.NewTestKit(c, s.store)
+		tk.MustExec("use test")
+		tk.Se.(context.Context).GetSessionVars().User = "plan_user@localhost"
+		return tk
+	}
+	tku := newSession()
+	tku.MustQuery("select * from t where a = 1").Check(testkit.Rows("1 1"))
+	rows = tku.MustQuery(fmt.Sprintf("show plan for '%s'", digest)).Rows()
+	c.Assert(rows, HasLen, 1)
+	c.Assert(rows[0][1], Equals, "plan_user")
+	c.Assert(tk.MustQuery(fmt.Sprintf("show plan for '%s'", digest)).Rows(), HasLen, 3)
+	tk.MustExec("grant process on *.* to 'plan_user'@'localhost'")
+	tku = newSession()
+	c.Assert(tku.MustQuery(fmt.Sprintf("show plan for '%s'", digest)).Rows(), HasLen, 3)
+	tk.MustExec("drop user 'plan_user'@'localhost'")
+}
@@ -0,0 +1,287 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/expression"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/parser"
+	"github.com/pingcap/tidb/plan"
+	"github.com/pingcap/tidb/privilege"
+	"github.com/pingcap/tidb/util/types"
+)
+
+// slowQueryLogSize is the number of the recent slow queries kept in memory.
+const slowQueryLogSize = 1024
+
+// SlowQuery is an entry of the slow query log.
+type SlowQuery struct {
+	Start    time.Time
+	Duration time.Duration
+	ConnID   uint64
+	// User is the name of the user who runs the query.
+	User string
+	DB   string
+	SQL  string
+	// Digest is the digest of the SQL, see parser.Digest.
+	Digest string
+	// PlanDigest is the digest of the plan of the last statement in the SQL, see LastPlanDigest.
+	PlanDigest string
+}
+
+// lastPlanVarKeyType is a dummy type to avoid naming collision in context.
+type lastPlanVarKeyType int
+
+// String defines a Stringer function for debugging and pretty printing.
+func (k lastPlanVarKeyType) String() string {
+	return "last_plan_var"
+}
+
+// LastPlanVarKey is a variable key for the plan of the last statement executed in the session.
+const LastPlanVarKey lastPlanVarKeyType = 0
+
+// LastPlanDigest returns the plan digest of the value of LastPlanVarKey, or "" if no plan is kept.
+func LastPlanDigest(v interface{}) string {
+	p, ok := v.(plan.Plan)
+	if !ok {
+		return ""
+	}
+	return planDigest(p)
+}
+
+// SlowQueryLog keeps the recent slow queries of the server in memory, so they can be found by ADMIN DIAGNOSE.
+var SlowQueryLog = &slowQueryLog{queries: make([]*SlowQuery, slowQueryLogSize)}
+
+// slowQueryLog is a ring buffer of the slow queries.
+type slowQueryLog struct {
+	sync.RWMutex
+	queries []*SlowQuery
+	// next is the position of the next query, the oldest query is overwritten when the buffer is full.
+	next int
+}
+
+// Add adds a slow query to the log, its digest is computed if it's not set.
+func (l *slowQueryLog) Add(q *SlowQuery) {
+	if q.Digest == "" {
+		q.Digest = parser.Digest(q.SQL)
+	}
+	l.Lock()
+	l.queries[l.next] = q
+	l.next = (l.next + 1) % len(l.queries)
+	l.Unlock()
+}
+
+// Find returns at most limit recent slow queries of the digest run by the user, or by all the users if the user is
+// empty. The latest one comes first.
+func (l *slowQueryLog) Find(digest string, limit int, user string) []*SlowQuery {
+	l.RLock()
+	defer l.RUnlock()
+	var found []*SlowQuery
+	for i := 1; i <= len(l.queries) && len(found) < limit; i++ {
+		q := l.queries[(l.next-i+len(l.queries))%len(l.queries)]
+		if q == nil {
+			break
+		}
+		if q.Digest == digest && (user == "" || q.User == user) {
+			found = append(found, q)
+		}
+	}
+	return found
+}
+
+// all returns all the slow queries in the log run by the user, or by all the users if the user is empty. The oldest
+// one comes first.
+func (l *slowQueryLog) all(user string) []*SlowQuery {
+	l.RLock()
+	defer l.RUnlock()
+	var queries []*SlowQuery
+	for i := 0; i < len(l.queries); i++ {
+		q := l.queries[(l.next+i)%len(l.queries)]
+		if q != nil && (user == "" || q.User == user) {
+			queries = append(queries, q)
+		}
+	}
+	return queries
+}
+
+// sessionUserName returns the name of the user of the session, or "" if the session isn't authenticated.
+func sessionUserName(ctx context.Context) string {
+	return strings.Split(ctx.GetSessionVars().User, "@")[0]
+}
+
+// sqlUserFilter returns the user whose statements can be seen by the user of the session, or "" if the statements of
+// all the users can be seen. Like SHOW PROCESSLIST, the statements of the other users are only seen with the SUPER or
+// PROCESS privilege, since their texts may contain sensitive data.
+func sqlUserFilter(ctx context.Context) (string, error) {
+	user := sessionUserName(ctx)
+	if user == "" {
+		return "", nil
+	}
+	hasPriv, err := privilege.CheckGlobal(ctx, mysql.SuperPriv, mysql.ProcessPriv)
+	if err != nil || hasPriv {
+		return "", errors.Trace(err)
+	}
+	return user, nil
+}
+
+// slowPlanCluster is the slow queries of a statement digest executed with the same plan.
+type slowPlanCluster struct {
+	digest     string
+	planDigest string
+	// sample is the text of the latest query in the cluster.
+	sample     string
+	execCount  int64
+	sumLatency time.Duration
+	maxLatency time.Duration
+	firstSeen  time.Time
+	lastSeen   time.Time
+}
+
+// slowStatement is the slow queries of a statement digest, clustered by their plans.
+type slowStatement struct {
+	clusters   []*slowPlanCluster
+	sumLatency time.Duration
+}
+
+// clusterSlowQueries groups the slow queries by the statement digest and then by the plan digest.
+// The statements executed with more than one plan come first as their plans are unstable, then the statements
+// are ordered by their total latency, and so are the plans of a statement.
+func clusterSlowQueries(queries []*SlowQuery) []*slowStatement {
+	stmts := make(map[string]*slowStatement)
+	clusters := make(map[[2]string]*slowPlanCluster)
+	var ordered []*slowStatement
+	for _, q := range queries {
+		stmt, ok := stmts[q.Digest]
+		if !ok {
+			stmt = &slowStatement{}
+			stmts[q.Digest] = stmt
+			ordered = append(ordered, stmt)
+		}
+		key := [2]string{q.Digest, q.PlanDigest}
+		c, ok := clusters[key]
+		if !ok {
+			c = &slowPlanCluster{digest: q.Digest, planDigest: q.PlanDigest, firstSeen: q.Start}
+			clusters[key] = c
+			stmt.clusters = append(stmt.clusters, c)
+		}
+		c.sample = q.SQL
+		c.execCount++
+		c.sumLatency += q.Duration
+		if q.Duration > c.maxLatency {
+			c.maxLatency = q.Duration
+		}
+		if q.Start.Before(c.firstSeen) {
+			c.firstSeen = q.Start
+		}
+		if q.Start.After(c.lastSeen) {
+			c.lastSeen = q.Start
+		}
+		stmt.sumLatency += q.Duration
+	}
+	for _, stmt := range ordered {
+		sort.Stable(slowPlanClusters(stmt.clusters))
+	}
+	sort.Stable(slowStatements(ordered))
+	return ordered
+}
+
+type slowPlanClusters []*slowPlanCluster
+
+func (s slowPlanClusters) Len() int           { return len(s) }
+func (s slowPlanClusters) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s slowPlanClusters) Less(i, j int) bool { return s[i].sumLatency > s[j].sumLatency }
+
+type slowStatements []*slowStatement
+
+func (s slowStatements) Len() int      { return len(s) }
+func (s slowStatements) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s slowStatements) Less(i, j int) bool {
+	unstableI, unstableJ := len(s[i].clusters) > 1, len(s[j].clusters) > 1
+	if unstableI != unstableJ {
+		return unstableI
+	}
+	return s[i].sumLatency > s[j].sumLatency
+}
+
+// ShowSlowPlansExec represents an admin show slow plans executor.
+// It reports the slow queries in SlowQueryLog clustered by the statement digest and the plan digest, so the
+// statements whose plans diverge, i.e. the unstable plans, can be found. A row is output for each plan of a
+// statement, and PLAN_COUNT is the number of the plans of the statement. The users without the SUPER or PROCESS
+// privilege only see their own queries.
+type ShowSlowPlansExec struct {
+	schema expression.Schema
+	ctx    context.Context
+
+	rows   []*Row
+	cursor int
+	done   bool
+}
+
+// Schema implements the Executor Schema interface.
+func (e *ShowSlowPlansExec) Schema() expression.Schema {
+	return e.schema
+}
+
+// Next implements the Executor Next interface.
+func (e *ShowSlowPlansExec) Next() (*Row, error) {
+	if !e.done {
+		if err := e.fetchAll(); err != nil {
+			return nil, errors.Trace(err)
+		}
+		e.done = true
+	}
+	if e.cursor >= len(e.rows) {
+		return nil, nil
+	}
+	row := e.rows[e.cursor]
+	e.cursor++
+	return row, nil
+}
+
+// Close implements the Executor Close interface.
+func (e *ShowSlowPlansExec) Close() error {
+	return nil
+}
+
+func (e *ShowSlowPlansExec) fetchAll() error {
+	user, err := sqlUserFilter(e.ctx)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for _, stmt := range clusterSlowQueries(SlowQueryLog.all(user)) {
+		for _, c := range stmt.clusters {
+			normalized := parser.Normalize(c.sample)
+			e.rows = append(e.rows, &Row{Data: types.MakeDatums(
+				c.digest,
+				c.planDigest,
+				normalized,
+				c.sample,
+				len(stmt.clusters),
+				c.execCount,
+				c.sumLatency.Seconds()/float64(c.execCount),
+				c.maxLatency.Seconds(),
+				types.Time{Time: c.firstSeen, Type: mysql.TypeDatetime},
+				types.Time{Time: c.lastSeen, Type: mysql.TypeDatetime},
+			)})
+		}
+	}
+	return nil
+}
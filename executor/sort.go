@@ -0,0 +1,419 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+	"github.com/pingcap/tidb/util/codec"
+	"github.com/pingcap/tidb/util/types"
+)
+
+// SortSpillDir is the directory of the temporary files of the sorted runs, the default directory for
+// temporary files is used if it's empty.
+var SortSpillDir string
+
+// sortRun is a sorted run of the rows spilled to a temporary file.
+type sortRun struct {
+	file   *os.File
+	reader *bufio.Reader
+	// row is the current row of the run, it's nil if the run is exhausted.
+	row *orderByRow
+}
+
+// sortRunHeap merges the sorted runs, the run with the least current row is on the top.
+type sortRunHeap struct {
+	e    *SortExec
+	runs []*sortRun
+}
+
+// Len implements heap.Interface Len interface.
+func (h *sortRunHeap) Len() int {
+	return len(h.runs)
+}
+
+// Less implements heap.Interface Less interface.
+func (h *sortRunHeap) Less(i, j int) bool {
+	return h.e.lessRow(h.runs[i].row, h.runs[j].row)
+}
+
+// Swap implements heap.Interface Swap interface.
+func (h *sortRunHeap) Swap(i, j int) {
+	h.runs[i], h.runs[j] = h.runs[j], h.runs[i]
+}
+
+// Push implements heap.Interface Push interface.
+func (h *sortRunHeap) Push(x interface{}) {
+	h.runs = append(h.runs, x.(*sortRun))
+}
+
+// Pop implements heap.Interface Pop interface.
+func (h *sortRunHeap) Pop() interface{} {
+	run := h.runs[len(h.runs)-1]
+	h.runs = h.runs[:len(h.runs)-1]
+	return run
+}
+
+// spill sorts the buffered rows and writes them to a temporary file as a sorted run, then the memory of the
+// rows is released.
+func (e *SortExec) spill() error {
+	sort.Sort(e)
+	if e.err != nil {
+		return errors.Trace(e.err)
+	}
+	file, err := ioutil.TempFile(SortSpillDir, "tidb-sort-")
+	if err != nil {
+		return errors.Trace(err)
+	}
+	run := &sortRun{file: file}
+	e.runs = append(e.runs, run)
+
+	startTime := time.Now()
+	w := bufio.NewWriter(file)
+	var (
+		buf    []byte
+		lenBuf [binary.MaxVarintLen64]byte
+	)
+	for _, r := range e.Rows {
+		buf, err = e.encodeSpilledRow(buf[:0], r)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		n := binary.PutUvarint(lenBuf[:], uint64(len(buf)))
+		if _, err = w.Write(lenBuf[:n]); err != nil {
+			return errors.Trace(err)
+		}
+		if _, err = w.Write(buf); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	if err = w.Flush(); err != nil {
+		return errors.Trace(err)
+	}
+	if _, err = file.Seek(0, 0); err != nil {
+		return errors.Trace(err)
+	}
+	run.reader = bufio.NewReader(file)
+	log.Infof("[sort] spill %d rows of %d bytes to %s in %v", len(e.Rows), e.bufferedBytes, file.Name(),
+		time.Since(startTime))
+
+	e.Rows = nil
+	e.mem.release()
+	e.bufferedBytes = 0
+	return nil
+}
+
+// prepareMerge spills the rest rows, and reads the first row of each run to merge the runs.
+func (e *SortExec) prepareMerge() error {
+	if len(e.Rows) > 0 {
+		if err := e.spill(); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	e.merger = &sortRunHeap{e: e, runs: make([]*sortRun, 0, len(e.runs))}
+	for _, run := range e.runs {
+		if err := e.readRunRow(run); err != nil {
+			return errors.Trace(err)
+		}
+		if run.row != nil {
+			e.merger.runs = append(e.merger.runs, run)
+		}
+	}
+	heap.Init(e.merger)
+	return errors.Trace(e.err)
+}
+
+// nextMerged returns the least current row of the runs, and moves the run to its next row.
+func (e *SortExec) nextMerged() (*Row, error) {
+	if e.merger.Len() == 0 {
+		return nil, nil
+	}
+	run := e.merger.runs[0]
+	row := run.row.row
+	if err := e.readRunRow(run); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if run.row == nil {
+		heap.Pop(e.merger)
+	} else {
+		heap.Fix(e.merger, 0)
+	}
+	if e.err != nil {
+		return nil, errors.Trace(e.err)
+	}
+	return row, nil
+}
+
+// readRunRow reads the next row of the run, the row is set to nil at the end of the run.
+func (e *SortExec) readRunRow(run *sortRun) error {
+	n, err := binary.ReadUvarint(run.reader)
+	if err == io.EOF {
+		run.row = nil
+		return nil
+	}
+	if err != nil {
+		return errors.Trace(err)
+	}
+	buf := make([]byte, n)
+	if _, err = io.ReadFull(run.reader, buf); err != nil {
+		return errors.Trace(err)
+	}
+	run.row, err = e.decodeSpilledRow(buf)
+	return errors.Trace(err)
+}
+
+// closeRuns closes and removes the temporary files of the runs.
+func (e *SortExec) closeRuns() error {
+	var err error
+	for _, run := range e.runs {
+		if err1 := run.file.Close(); err1 != nil && err == nil {
+			err = err1
+		}
+		if err1 := os.Remove(run.file.Name()); err1 != nil && err == nil {
+			err = err1
+		}
+	}
+	e.runs = nil
+	e.merger = nil
+	e.rowKeyTables = nil
+	return errors.Trace(err)
+}
+
+// encodeSpilledRow encodes the data, the sort key and the row keys of the row.
+func (e *SortExec) encodeSpilledRow(b []byte, r *orderByRow) ([]byte, error) {
+	b, err := encodeSpilledDatums(b, r.row.Data)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	b, err = encodeSpilledDatums(b, r.key)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	b = codec.EncodeUvarint(b, uint64(len(r.row.RowKeys)))
+	for _, entry := range r.row.RowKeys {
+		b = codec.EncodeUvarint(b, uint64(e.rowKeyTableIndex(entry)))
+		b = codec.EncodeVarint(b, entry.Handle)
+	}
+	return b, nil
+}
+
+func (e *SortExec) decodeSpilledRow(b []byte) (*orderByRow, error) {
+	b, data, err := decodeSpilledDatums(b)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	b, key, err := decodeSpilledDatums(b)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	b, n, err := codec.DecodeUvarint(b)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	r := &orderByRow{row: &Row{Data: data}, key: key}
+	for i := uint64(0); i < n; i++ {
+		var idx uint64
+		b, idx, err = codec.DecodeUvarint(b)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if idx >= uint64(len(e.rowKeyTables)) {
+			return nil, errors.Errorf("invalid table index %d of the spilled row key", idx)
+		}
+		entry := *e.rowKeyTables[idx]
+		b, entry.Handle, err = codec.DecodeVarint(b)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		r.row.RowKeys = append(r.row.RowKeys, &entry)
+	}
+	return r, nil
+}
+
+// rowKeyTableIndex returns the index of the table of the row key in rowKeyTables, the table is added if it's
+// not there.
+func (e *SortExec) rowKeyTableIndex(entry *RowKeyEntry) int {
+	for i, t := range e.rowKeyTables {
+		if t.Tbl == entry.Tbl && t.TableAsName == entry.TableAsName {
+			return i
+		}
+	}
+	e.rowKeyTables = append(e.rowKeyTables, &RowKeyEntry{Tbl: entry.Tbl, TableAsName: entry.TableAsName})
+	return len(e.rowKeyTables) - 1
+}
+
+// encodeSpilledDatums encodes the datums with their kinds, so they are decoded as the same datums. Unlike
+// codec.EncodeValue, strings aren't decoded as bytes, and times are decoded with their types.
+func encodeSpilledDatums(b []byte, datums []types.Datum) ([]byte, error) {
+	b = codec.EncodeUvarint(b, uint64(len(datums)))
+	for _, d := range datums {
+		b = append(b, d.Kind())
+		switch d.Kind() {
+		case types.KindNull, types.KindMinNotNull, types.KindMaxValue:
+		case types.KindInt64:
+			b = codec.EncodeVarint(b, d.GetInt64())
+		case types.KindUint64:
+			b = codec.EncodeUvarint(b, d.GetUint64())
+		case types.KindFloat32, types.KindFloat64:
+			b = codec.EncodeFloat(b, d.GetFloat64())
+		case types.KindString, types.KindBytes:
+			b = codec.EncodeCompactBytes(b, d.GetBytes())
+		case types.KindMysqlDecimal:
+			b = codec.EncodeDecimal(b, d)
+		case types.KindMysqlDuration:
+			dur := d.GetMysqlDuration()
+			b = codec.EncodeVarint(b, int64(dur.Duration))
+			b = codec.EncodeVarint(b, int64(dur.Fsp))
+		case types.KindMysqlTime:
+			t := d.GetMysqlTime()
+			b = codec.EncodeUvarint(b, t.ToPackedUint())
+			b = append(b, t.Type)
+			b = codec.EncodeVarint(b, int64(t.Fsp))
+		case types.KindMysqlEnum:
+			enum := d.GetMysqlEnum()
+			b = codec.EncodeCompactBytes(b, []byte(enum.Name))
+			b = codec.EncodeUvarint(b, enum.Value)
+		case types.KindMysqlSet:
+			set := d.GetMysqlSet()
+			b = codec.EncodeCompactBytes(b, []byte(set.Name))
+			b = codec.EncodeUvarint(b, set.Value)
+		case types.KindMysqlBit:
+			bit := d.GetMysqlBit()
+			b = codec.EncodeUvarint(b, bit.Value)
+			b = codec.EncodeVarint(b, int64(bit.Width))
+		case types.KindMysqlHex:
+			b = codec.EncodeVarint(b, d.GetMysqlHex().Value)
+		default:
+			return nil, errors.Errorf("unsupported kind %d of the spilled datum", d.Kind())
+		}
+	}
+	return b, nil
+}
+
+func decodeSpilledDatums(b []byte) ([]byte, []types.Datum, error) {
+	b, n, err := codec.DecodeUvarint(b)
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	datums := make([]types.Datum, n)
+	for i := range datums {
+		if len(b) == 0 {
+			return nil, nil, errors.New("insufficient bytes to decode the spilled datum")
+		}
+		kind := b[0]
+		b = b[1:]
+		var (
+			iv int64
+			uv uint64
+			bv []byte
+		)
+		switch kind {
+		case types.KindNull:
+		case types.KindMinNotNull:
+			datums[i] = types.MinNotNullDatum()
+		case types.KindMaxValue:
+			datums[i] = types.MaxValueDatum()
+		case types.KindInt64:
+			b, iv, err = codec.DecodeVarint(b)
+			datums[i].SetInt64(iv)
+		case types.KindUint64:
+			b, uv, err = codec.DecodeUvarint(b)
+			datums[i].SetUint64(uv)
+		case types.KindFloat32, types.KindFloat64:
+			var f float64
+			b, f, err = codec.DecodeFloat(b)
+			if kind == types.KindFloat32 {
+				datums[i].SetFloat32(float32(f))
+			} else {
+				datums[i].SetFloat64(f)
+			}
+		case types.KindString, types.KindBytes:
+			b, bv, err = codec.DecodeCompactBytes(b)
+			if kind == types.KindString {
+				datums[i].SetString(string(bv))
+			} else {
+				datums[i].SetBytes(append([]byte(nil), bv...))
+			}
+		case types.KindMysqlDecimal:
+			b, datums[i], err = codec.DecodeDecimal(b)
+		case types.KindMysqlDuration:
+			var fsp int64
+			b, iv, err = codec.DecodeVarint(b)
+			if err == nil {
+				b, fsp, err = codec.DecodeVarint(b)
+			}
+			datums[i].SetMysqlDuration(types.Duration{Duration: time.Duration(iv), Fsp: int(fsp)})
+		case types.KindMysqlTime:
+			b, datums[i], err = decodeSpilledTime(b)
+		case types.KindMysqlEnum:
+			b, bv, err = codec.DecodeCompactBytes(b)
+			if err == nil {
+				b, uv, err = codec.DecodeUvarint(b)
+			}
+			datums[i].SetMysqlEnum(types.Enum{Name: string(bv), Value: uv})
+		case types.KindMysqlSet:
+			b, bv, err = codec.DecodeCompactBytes(b)
+			if err == nil {
+				b, uv, err = codec.DecodeUvarint(b)
+			}
+			datums[i].SetMysqlSet(types.Set{Name: string(bv), Value: uv})
+		case types.KindMysqlBit:
+			b, uv, err = codec.DecodeUvarint(b)
+			if err == nil {
+				b, iv, err = codec.DecodeVarint(b)
+			}
+			datums[i].SetMysqlBit(types.Bit{Value: uv, Width: int(iv)})
+		case types.KindMysqlHex:
+			b, iv, err = codec.DecodeVarint(b)
+			datums[i].SetMysqlHex(types.Hex{Value: iv})
+		default:
+			return nil, nil, errors.Errorf("unsupported kind %d of the spilled datum", kind)
+		}
+		if err != nil {
+			return nil, nil, errors.Trace(err)
+		}
+	}
+	return b, datums, nil
+}
+
+func decodeSpilledTime(b []byte) ([]byte, types.Datum, error) {
+	var d types.Datum
+	b, packed, err := codec.DecodeUvarint(b)
+	if err != nil {
+		return nil, d, errors.Trace(err)
+	}
+	if len(b) == 0 {
+		return nil, d, errors.New("insufficient bytes to decode the spilled time")
+	}
+	t := types.Time{Type: b[0]}
+	b, fsp, err := codec.DecodeVarint(b[1:])
+	if err != nil {
+		return nil, d, errors.Trace(err)
+	}
+	t.Fsp = int(fsp)
+	if err = t.FromPackedUint(packed); err != nil {
+		return nil, d, errors.Trace(err)
+	}
+	d.SetMysqlTime(t)
+	return b, d, nil
+}
@@ -0,0 +1,100 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/expression"
+	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/sessionctx/variable"
+)
+
+// SetStatsExec represents an admin set stats executor.
+// It overrides the row count of a table or the NDV of a column for the optimizer of the session, so the plans for
+// a table of that size can be checked with EXPLAIN without loading the data.
+type SetStatsExec struct {
+	ctx    context.Context
+	table  *model.TableInfo
+	column *model.ColumnInfo
+	value  int64
+	done   bool
+}
+
+// Schema implements the Executor Schema interface.
+func (e *SetStatsExec) Schema() expression.Schema {
+	return nil
+}
+
+// Next implements the Executor Next interface.
+func (e *SetStatsExec) Next() (*Row, error) {
+	if e.done {
+		return nil, nil
+	}
+	e.done = true
+	vars := e.ctx.GetSessionVars()
+	if vars.StatsOverrides == nil {
+		vars.StatsOverrides = make(map[int64]*variable.StatsOverride)
+	}
+	override := vars.StatsOverrides[e.table.ID]
+	if override == nil {
+		override = &variable.StatsOverride{NDVs: make(map[int64]int64)}
+		vars.StatsOverrides[e.table.ID] = override
+	}
+	if e.column == nil {
+		override.Count = e.value
+	} else {
+		override.NDVs[e.column.ID] = e.value
+	}
+	return nil, nil
+}
+
+// Close implements the Executor Close interface.
+func (e *SetStatsExec) Close() error {
+	return nil
+}
+
+// ResetStatsExec represents an admin reset stats executor.
+// It removes the statistics overrides of the tables, or all of them, from the session.
+type ResetStatsExec struct {
+	ctx    context.Context
+	tables []*model.TableInfo
+	done   bool
+}
+
+// Schema implements the Executor Schema interface.
+func (e *ResetStatsExec) Schema() expression.Schema {
+	return nil
+}
+
+// Next implements the Executor Next interface.
+func (e *ResetStatsExec) Next() (*Row, error) {
+	if e.done {
+		return nil, nil
+	}
+	e.done = true
+	vars := e.ctx.GetSessionVars()
+	if len(e.tables) == 0 {
+		vars.StatsOverrides = nil
+		return nil, nil
+	}
+	for _, tbl := range e.tables {
+		delete(vars.StatsOverrides, tbl.ID)
+	}
+	return nil, nil
+}
+
+// Close implements the Executor Close interface.
+func (e *ResetStatsExec) Close() error {
+	return nil
+}
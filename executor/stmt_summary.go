@@ -0,0 +1,113 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/parser"
+	"github.com/pingcap/tidb/plan"
+	"github.com/pingcap/tidb/util/stmtsummary"
+)
+
+// observeStmtSummary records the execution of the statement in stmtsummary.GlobalSummary. The statements without
+// an interesting plan, e.g. SET and the DDLs, and the internal statements aren't recorded.
+func observeStmtSummary(ctx context.Context, p plan.Plan, text string, latency time.Duration) {
+	if text == "" || !stmtsummary.GlobalSummary.Enabled() || ctx.GetSessionVars().InRestrictedSQL {
+		return
+	}
+	switch p.(type) {
+	case *plan.Simple, *plan.DDL, *plan.Show, *plan.ShowDDL, *plan.ShowDDLJobs, *plan.CheckTable,
+		*plan.RecoverIndex, *plan.CleanupIndex, *plan.Diagnose, *plan.ShowSlowPlans, *plan.ThrottleTable,
+		*plan.SetStats, *plan.ResetStats, *plan.Prepare, *plan.Deallocate, *plan.Explain, *plan.LoadData,
+		*plan.SelectInto, *plan.ShowDeleteRanges:
+		return
+	}
+	normalized, digest := parser.NormalizeDigest(text)
+	err := stmtsummary.GlobalSummary.Observe(&stmtsummary.Execution{
+		Digest:        digest,
+		NormalizedSQL: normalized,
+		SQL:           text,
+		User:          sessionUserName(ctx),
+		PlanDigest:    planDigest(p),
+		EncodePlan: func() (string, error) {
+			node, err := buildExplainNode(p)
+			if err != nil {
+				return "", errors.Trace(err)
+			}
+			encoded, err := json.MarshalIndent(node, "", "    ")
+			return string(encoded), errors.Trace(err)
+		},
+		Latency: latency,
+	})
+	if err != nil {
+		log.Warnf("[%d] failed to record the statement summary: %v", ctx.GetSessionVars().ConnectionID, err)
+	}
+}
+
+// planDigest returns the hex encoded SHA-256 hash of the shape of the plan, i.e. the types of the plans in the
+// tree and the tables and the indexes they read, so the plans which only differ in the ranges or the estimations
+// have the same digest.
+func planDigest(p plan.Plan) string {
+	var buf bytes.Buffer
+	writePlanShape(&buf, p)
+	return fmt.Sprintf("%x", sha256.Sum256(buf.Bytes()))
+}
+
+func writePlanShape(buf *bytes.Buffer, p plan.Plan) {
+	// The ID of a plan is its type followed by a number, which may change with the plans explored.
+	buf.WriteString(strings.TrimRight(p.GetID(), "_0123456789"))
+	switch x := p.(type) {
+	case *plan.PhysicalTableScan:
+		fmt.Fprintf(buf, "[%s]", x.Table.Name.L)
+	case *plan.PhysicalIndexScan:
+		fmt.Fprintf(buf, "[%s.%s]", x.Table.Name.L, x.Index.Name.L)
+	case *plan.PhysicalIndexMerge:
+		for _, partial := range x.PartialPlans {
+			fmt.Fprintf(buf, "[%s.%s]", partial.Table.Name.L, partial.Index.Name.L)
+		}
+	case *plan.PointGet:
+		fmt.Fprintf(buf, "[%s", x.Table.Name.L)
+		if x.Index != nil {
+			fmt.Fprintf(buf, ".%s", x.Index.Name.L)
+		}
+		buf.WriteByte(']')
+	case *plan.BatchPointGet:
+		fmt.Fprintf(buf, "[%s", x.Table.Name.L)
+		if x.Index != nil {
+			fmt.Fprintf(buf, ".%s", x.Index.Name.L)
+		}
+		buf.WriteByte(']')
+	}
+	children := explainChildren(p)
+	if len(children) == 0 {
+		return
+	}
+	buf.WriteByte('(')
+	for i, child := range children {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		writePlanShape(buf, child)
+	}
+	buf.WriteByte(')')
+}
@@ -239,7 +239,7 @@ func (us *UnionScanExec) compare(a, b *Row) (int, error) {
 	return cmp, nil
 }
 
-func (us *UnionScanExec) buildAndSortAddedRows(t table.Table, asName *model.CIStr) error {
+func (us *UnionScanExec) buildAndSortAddedRows(t table.Table, asName *model.CIStr, columns []*model.ColumnInfo) error {
 	us.addedRows = make([]*Row, 0, len(us.dirty.addedRows))
 	for h, data := range us.dirty.addedRows {
 		var newData []types.Datum
@@ -247,12 +247,6 @@ func (us *UnionScanExec) buildAndSortAddedRows(t table.Table, asName *model.CISt
 			newData = data
 		} else {
 			newData = make([]types.Datum, 0, len(us.Src.Schema()))
-			var columns []*model.ColumnInfo
-			if t, ok := us.Src.(*XSelectTableExec); ok {
-				columns = t.Columns
-			} else {
-				columns = us.Src.(*XSelectIndexExec).indexPlan.Columns
-			}
 			for _, col := range columns {
 				newData = append(newData, data[col.Offset])
 			}
@@ -768,7 +768,7 @@ func (mmf *maxMinFunction) Update(row []types.Datum, groupKey []byte, ectx conte
 		return nil
 	}
 	var c int
-	c, err = ctx.Value.CompareDatum(value)
+	c, err = compareMaxMinValue(ctx.Value, value)
 	if err != nil {
 		return errors.Trace(err)
 	}
@@ -796,7 +796,7 @@ func (mmf *maxMinFunction) StreamUpdate(row []types.Datum, ectx context.Context)
 		return nil
 	}
 	var c int
-	c, err = ctx.Value.CompareDatum(value)
+	c, err = compareMaxMinValue(ctx.Value, value)
 	if err != nil {
 		return errors.Trace(err)
 	}
@@ -806,6 +806,19 @@ func (mmf *maxMinFunction) StreamUpdate(row []types.Datum, ectx context.Context)
 	return nil
 }
 
+// compareMaxMinValue compares the values of max and min. Like MySQL, the enum and set values are compared by their
+// names rather than their numeric values.
+func compareMaxMinValue(a, b types.Datum) (int, error) {
+	switch a.Kind() {
+	case types.KindMysqlEnum, types.KindMysqlSet:
+		switch b.Kind() {
+		case types.KindMysqlEnum, types.KindMysqlSet:
+			return types.CompareString(a.GetString(), b.GetString()), nil
+		}
+	}
+	return a.CompareDatum(b)
+}
+
 type firstRowFunction struct {
 	aggFunction
 }
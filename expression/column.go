@@ -84,6 +84,12 @@ type Column struct {
 
 	// Only used for execution.
 	Index int
+
+	// hashcode caches the result of HashCode. The planner may change FromID or Position of a column,
+	// so we also keep the values it is computed from and compute it again if they are changed.
+	hashcode         []byte
+	hashcodeFromID   string
+	hashcodePosition int
 }
 
 // Equal implements Expression interface.
@@ -140,8 +146,12 @@ func (col *Column) Decorrelate(_ Schema) Expression {
 
 // HashCode implements Expression interface.
 func (col *Column) HashCode() []byte {
+	if col.hashcode != nil && col.hashcodeFromID == col.FromID && col.hashcodePosition == col.Position {
+		return col.hashcode
+	}
 	var bytes []byte
 	bytes, _ = codec.EncodeValue(bytes, types.NewStringDatum(col.FromID), types.NewIntDatum(int64(col.Position)))
+	col.hashcode, col.hashcodeFromID, col.hashcodePosition = bytes, col.FromID, col.Position
 	return bytes
 }
 
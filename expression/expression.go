@@ -39,7 +39,7 @@ type Expression interface {
 	// Clone copies an expression totally.
 	Clone() Expression
 
-	// HashCode create the hashcode for expression
+	// HashCode create the hashcode for expression, the result may be cached so it should not be modified.
 	HashCode() []byte
 
 	// Equal checks whether two expressions are equal.
@@ -18,6 +18,7 @@ import (
 	"fmt"
 
 	"github.com/juju/errors"
+	"github.com/pingcap/tidb/ast"
 	"github.com/pingcap/tidb/context"
 	"github.com/pingcap/tidb/evaluator"
 	"github.com/pingcap/tidb/model"
@@ -33,6 +34,20 @@ type ScalarFunction struct {
 	RetType   *types.FieldType
 	Function  evaluator.BuiltinFunc
 	ArgValues []types.Datum
+
+	// hashcode caches the result of HashCode, it is reset by SetArg.
+	hashcode []byte
+
+	// inSet is the hash set of the constant list if the function is a long IN list, it is built at the first time the
+	// function is evaluated.
+	inSet      *evaluator.InSet
+	inSetBuilt bool
+
+	// scratchFunc is used instead of Function if the builtin function can reuse the scratch buffers across the rows.
+	scratchFunc evaluator.ScratchFunc
+	scratch     *evaluator.Scratch
+	// argsInPlace is true if the function consumes its arguments, so they can be evaluated in place.
+	argsInPlace bool
 }
 
 // String implements fmt.Stringer interface.
@@ -92,12 +107,23 @@ func NewFunction(funcName string, retType *types.FieldType, args ...Expression)
 	}
 	funcArgs := make([]Expression, len(args))
 	copy(funcArgs, args)
-	return &ScalarFunction{
+	sf := &ScalarFunction{
 		Args:      funcArgs,
 		FuncName:  model.NewCIStr(funcName),
 		RetType:   retType,
-		Function:  f.F,
-		ArgValues: make([]types.Datum, len(funcArgs))}, nil
+		Function:  evaluator.NewBuiltinFunc(funcName, f),
+		ArgValues: make([]types.Datum, len(funcArgs))}
+	sf.initScratch()
+	return sf, nil
+}
+
+// initScratch sets up the scratch buffers of the function.
+func (sf *ScalarFunction) initScratch() {
+	if f, ok := evaluator.ScratchFuncs[sf.FuncName.L]; ok {
+		sf.scratchFunc = f
+		sf.scratch = &evaluator.Scratch{}
+	}
+	sf.argsInPlace = evaluator.ArgConsumers[sf.FuncName.L]
 }
 
 //ScalarFuncs2Exprs converts []*ScalarFunction to []Expression.
@@ -111,11 +137,17 @@ func ScalarFuncs2Exprs(funcs []*ScalarFunction) []Expression {
 
 // Clone implements Expression interface.
 func (sf *ScalarFunction) Clone() Expression {
+	function := sf.Function
+	// The function may keep scratch buffers, so the clone should have its own one.
+	if factory, ok := evaluator.StatefulFuncs[sf.FuncName.L]; ok {
+		function = factory()
+	}
 	newFunc := &ScalarFunction{
 		FuncName:  sf.FuncName,
-		Function:  sf.Function,
+		Function:  function,
 		RetType:   sf.RetType,
 		ArgValues: make([]types.Datum, len(sf.Args))}
+	newFunc.initScratch()
 	newFunc.Args = make([]Expression, 0, len(sf.Args))
 	for _, arg := range sf.Args {
 		newFunc.Args = append(newFunc.Args, arg.Clone())
@@ -134,6 +166,9 @@ func (sf *ScalarFunction) Equal(e Expression) bool {
 	if !ok {
 		return false
 	}
+	if sf == fun {
+		return true
+	}
 	if sf.FuncName.L != fun.FuncName.L {
 		return false
 	}
@@ -161,25 +196,81 @@ func (sf *ScalarFunction) IsCorrelated() bool {
 // Decorrelate implements Expression interface.
 func (sf *ScalarFunction) Decorrelate(schema Schema) Expression {
 	for i, arg := range sf.Args {
-		sf.Args[i] = arg.Decorrelate(schema)
+		sf.SetArg(i, arg.Decorrelate(schema))
 	}
 	return sf
 }
 
 // Eval implements Expression interface.
 func (sf *ScalarFunction) Eval(row []types.Datum, ctx context.Context) (types.Datum, error) {
+	return sf.eval(row, ctx, false)
+}
+
+// eval evaluates the function. If inPlace is true, the result may refer to the scratch buffers of the function,
+// so it must be consumed before the function is evaluated again.
+func (sf *ScalarFunction) eval(row []types.Datum, ctx context.Context, inPlace bool) (types.Datum, error) {
+	if sf.FuncName.L == ast.In {
+		if d, ok, err := sf.evalInSet(row, ctx); ok || err != nil {
+			return d, errors.Trace(err)
+		}
+	}
 	var err error
 	for i, arg := range sf.Args {
-		sf.ArgValues[i], err = arg.Eval(row, ctx)
+		if f, ok := arg.(*ScalarFunction); ok && sf.argsInPlace {
+			sf.ArgValues[i], err = f.eval(row, ctx, true)
+		} else {
+			sf.ArgValues[i], err = arg.Eval(row, ctx)
+		}
 		if err != nil {
 			return types.Datum{}, errors.Trace(err)
 		}
 	}
+	if sf.scratchFunc != nil {
+		return sf.scratchFunc(sf.ArgValues, ctx, sf.scratch, inPlace)
+	}
 	return sf.Function(sf.ArgValues, ctx)
 }
 
+// evalInSet evaluates a long IN list of constants by probing its hash set. It returns false for ok if the function
+// should be evaluated by comparing with the list one by one.
+func (sf *ScalarFunction) evalInSet(row []types.Datum, ctx context.Context) (d types.Datum, ok bool, err error) {
+	if !sf.inSetBuilt {
+		sf.inSetBuilt = true
+		sf.inSet = buildInSet(sf.Args[1:])
+	}
+	if sf.inSet == nil {
+		return d, false, nil
+	}
+	target, err := sf.Args[0].Eval(row, ctx)
+	if err != nil {
+		return d, false, errors.Trace(err)
+	}
+	d, ok = sf.inSet.Check(target)
+	return d, ok, nil
+}
+
+// buildInSet builds the hash set of the IN list if it is long and all of its values are constants.
+func buildInSet(list []Expression) *evaluator.InSet {
+	if len(list) <= evaluator.InSetThreshold {
+		return nil
+	}
+	values := make([]types.Datum, 0, len(list))
+	for _, e := range list {
+		c, ok := e.(*Constant)
+		if !ok {
+			return nil
+		}
+		values = append(values, c.Value)
+	}
+	return evaluator.NewInSet(values)
+}
+
 // HashCode implements Expression interface.
+// The hash code is cached, so the arguments must be replaced by SetArg instead of being assigned to Args.
 func (sf *ScalarFunction) HashCode() []byte {
+	if sf.hashcode != nil {
+		return sf.hashcode
+	}
 	var bytes []byte
 	v := make([]types.Datum, 0, len(sf.Args)+1)
 	bytes, _ = codec.EncodeValue(bytes, types.NewStringDatum(sf.FuncName.L))
@@ -187,11 +278,18 @@ func (sf *ScalarFunction) HashCode() []byte {
 	for _, arg := range sf.Args {
 		v = append(v, types.NewBytesDatum(arg.HashCode()))
 	}
-	bytes = bytes[:0]
-	bytes, _ = codec.EncodeValue(bytes, v...)
+	bytes, _ = codec.EncodeValue(nil, v...)
+	sf.hashcode = bytes
 	return bytes
 }
 
+// SetArg replaces the ith argument with arg and resets the cached hash code.
+// The planner rewrites the arguments recursively, so the functions on the path to a changed one are all reset.
+func (sf *ScalarFunction) SetArg(i int, arg Expression) {
+	sf.Args[i] = arg
+	sf.hashcode = nil
+}
+
 // ResolveIndices implements Expression interface.
 func (sf *ScalarFunction) ResolveIndices(schema Schema) {
 	for _, arg := range sf.Args {
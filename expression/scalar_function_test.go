@@ -0,0 +1,164 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package expression
+
+import (
+	"testing"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/util/testleak"
+	"github.com/pingcap/tidb/util/types"
+)
+
+func TestT(t *testing.T) {
+	CustomVerboseFlag = true
+	TestingT(t)
+}
+
+var _ = Suite(&testScalarFunctionSuite{})
+
+type testScalarFunctionSuite struct {
+}
+
+func newFunctionForTest(funcName string, args ...Expression) Expression {
+	f, err := NewFunction(funcName, types.NewFieldType(mysql.TypeUnspecified), args...)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+// filterScanRows are the rows of (int, decimal, string, string) to scan.
+func filterScanRows(n int, strs ...string) [][]types.Datum {
+	rows := make([][]types.Datum, 0, n)
+	for i := 0; i < n; i++ {
+		rows = append(rows, types.MakeDatums(i, types.NewDecFromInt(int64(i)), strs[i%len(strs)], strs[(i+1)%len(strs)]))
+	}
+	return rows
+}
+
+// filterScanExprs are the filters of the scan:
+// c0 > 1.5, c1 * 2 > c0 + 3, c0 / c1 >= 1, upper(concat(c2, c3)) = 'AB', lower(c3) like 'ä%'.
+func filterScanExprs() []Expression {
+	cols := make([]Expression, 0, 4)
+	for i := 0; i < 4; i++ {
+		cols = append(cols, &Column{Index: i})
+	}
+	return []Expression{
+		newFunctionForTest(ast.GT, cols[0], &Constant{Value: types.NewDecimalDatum(types.NewDecFromStringForTest("1.5"))}),
+		newFunctionForTest(ast.GT,
+			newFunctionForTest(ast.Mul, cols[1], &Constant{Value: types.NewIntDatum(2)}),
+			newFunctionForTest(ast.Plus, cols[0], &Constant{Value: types.NewIntDatum(3)})),
+		newFunctionForTest(ast.GE,
+			newFunctionForTest(ast.Div, cols[0], cols[1]), &Constant{Value: types.NewIntDatum(1)}),
+		newFunctionForTest(ast.EQ,
+			newFunctionForTest(ast.Upper, newFunctionForTest(ast.Concat, cols[2], cols[3])),
+			&Constant{Value: types.NewStringDatum("AB")}),
+		newFunctionForTest(ast.Like,
+			newFunctionForTest(ast.Lower, cols[3]),
+			&Constant{Value: types.NewStringDatum("ä%")},
+			&Constant{Value: types.NewIntDatum('\\')}),
+	}
+}
+
+func (s *testScalarFunctionSuite) TestEvalInPlace(c *C) {
+	defer testleak.AfterTest(c)()
+	rows := filterScanRows(6, "a", "b", "Ä")
+	exprs := filterScanExprs()
+	expected := [][]interface{}{
+		{0, 0, nil, 1, 0},
+		{0, 0, 1, 0, 1},
+		{1, 0, 1, 0, 0},
+		{1, 0, 1, 1, 0},
+		{1, 1, 1, 0, 1},
+		{1, 1, 1, 0, 0},
+	}
+	for i, row := range rows {
+		for j, expr := range exprs {
+			d, err := expr.Eval(row, nil)
+			c.Assert(err, IsNil)
+			expectedDatum := types.NewDatum(expected[i][j])
+			c.Assert(d.GetValue(), Equals, expectedDatum.GetValue(), Commentf("row %d, expr %s", i, expr))
+		}
+	}
+
+	// The results which are not consumed by another function must not share the scratch buffers.
+	for _, expr := range []Expression{
+		newFunctionForTest(ast.Mul, &Column{Index: 1}, &Constant{Value: types.NewIntDatum(2)}),
+		newFunctionForTest(ast.Upper, newFunctionForTest(ast.Concat, &Column{Index: 2}, &Column{Index: 3})),
+	} {
+		for _, f := range []Expression{expr, expr.Clone()} {
+			first, err := f.Eval(rows[1], nil)
+			c.Assert(err, IsNil)
+			firstStr, err := first.ToString()
+			c.Assert(err, IsNil)
+			_, err = f.Eval(rows[4], nil)
+			c.Assert(err, IsNil)
+			str, err := first.ToString()
+			c.Assert(err, IsNil)
+			c.Assert(str, Equals, firstStr)
+		}
+	}
+}
+
+func (s *testScalarFunctionSuite) TestFilterScanAllocs(c *C) {
+	defer testleak.AfterTest(c)()
+	rows := filterScanRows(100, "a", "b")
+	exprs := filterScanExprs()
+	var err error
+	allocs := testing.AllocsPerRun(10, func() {
+		for _, row := range rows {
+			for _, expr := range exprs {
+				if _, err = expr.Eval(row, nil); err != nil {
+					return
+				}
+			}
+		}
+	})
+	c.Assert(err, IsNil)
+	c.Assert(allocs, Equals, float64(0))
+}
+
+func BenchmarkFilterScan(b *testing.B) {
+	rows := filterScanRows(1024, "a", "b", "Ä")
+	exprs := filterScanExprs()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, row := range rows {
+			for _, expr := range exprs {
+				if _, err := expr.Eval(row, nil); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	}
+}
+
+func (s *testScalarFunctionSuite) TestHashCode(c *C) {
+	defer testleak.AfterTest(c)()
+	inner := newFunctionForTest(ast.Plus, &Column{FromID: "t", Position: 1}, &Constant{Value: types.NewIntDatum(1)})
+	outer := newFunctionForTest(ast.GT, inner, &Constant{Value: types.NewIntDatum(2)}).(*ScalarFunction)
+	code := outer.HashCode()
+	c.Assert(outer.HashCode(), DeepEquals, code)
+
+	// Replacing an argument resets the cached hash codes on the path to it.
+	inner.(*ScalarFunction).SetArg(0, &Column{FromID: "t", Position: 2})
+	outer.SetArg(0, inner)
+	c.Assert(outer.HashCode(), Not(DeepEquals), code)
+	outer.SetArg(0, newFunctionForTest(ast.Plus, &Column{FromID: "t", Position: 1}, &Constant{Value: types.NewIntDatum(1)}))
+	c.Assert(outer.HashCode(), DeepEquals, code)
+}
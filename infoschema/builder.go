@@ -52,6 +52,13 @@ func (b *Builder) ApplyDiff(m *meta.Meta, diff *model.SchemaDiff) error {
 	case model.ActionTruncateTable:
 		oldTableID = diff.OldTableID
 		newTableID = diff.TableID
+	case model.ActionShadowCopy:
+		// The shadow table replaces the table in the last schema change.
+		oldTableID = diff.TableID
+		newTableID = diff.TableID
+		if tableIDIsValid(diff.OldTableID) {
+			oldTableID = diff.OldTableID
+		}
 	default:
 		oldTableID = diff.TableID
 		newTableID = diff.TableID
@@ -158,7 +165,7 @@ func (b *Builder) applyCreateTable(m *meta.Meta, roDBInfo *model.DBInfo, tableID
 		return errors.Trace(err)
 	}
 	tableNames := b.is.schemaMap[roDBInfo.Name.L]
-	tableNames.tables[tblInfo.Name.L] = tbl
+	tableNames.tables[tableNames.tableKey(tblInfo.Name)] = tbl
 	bucketIdx := tableBucketIdx(tableID)
 	sortedTables := b.is.sortedTablesBuckets[bucketIdx]
 	sortedTables = append(sortedTables, tbl)
@@ -175,7 +182,7 @@ func (b *Builder) applyDropTable(di *model.DBInfo, tableID int64) {
 		return
 	}
 	if tableNames, ok := b.is.schemaMap[di.Name.L]; ok {
-		delete(tableNames.tables, sortedTables[idx].Meta().Name.L)
+		delete(tableNames.tables, tableNames.tableKey(sortedTables[idx].Meta().Name))
 	}
 	// Remove the table in sorted table slice.
 	b.is.sortedTablesBuckets[bucketIdx] = append(sortedTables[0:idx], sortedTables[idx+1:]...)
@@ -241,7 +248,7 @@ func (b *Builder) createSchemaTablesForDB(di *model.DBInfo) error {
 		if err != nil {
 			return errors.Trace(err)
 		}
-		schTbls.tables[t.Name.L] = tbl
+		schTbls.tables[schTbls.tableKey(t.Name)] = tbl
 		sortedTables := b.is.sortedTablesBuckets[tableBucketIdx(t.ID)]
 		b.is.sortedTablesBuckets[tableBucketIdx(t.ID)] = append(sortedTables, tbl)
 	}
@@ -261,7 +268,7 @@ func (b *Builder) createSchemaTablesForPerfSchemaDB() {
 		if !ok {
 			continue
 		}
-		perfSchemaTblNames.tables[t.Name.L] = tbl
+		perfSchemaTblNames.tables[perfSchemaTblNames.tableKey(t.Name)] = tbl
 		bucketIdx := tableBucketIdx(t.ID)
 		b.is.sortedTablesBuckets[bucketIdx] = append(b.is.sortedTablesBuckets[bucketIdx], tbl)
 	}
@@ -275,7 +282,7 @@ func (b *Builder) createSchemaTablesForInfoSchemaDB() {
 	b.is.schemaMap[infoSchemaDB.Name.L] = infoSchemaSchemaTables
 	for _, t := range infoSchemaDB.Tables {
 		tbl := createInfoSchemaTable(b.handle, t)
-		infoSchemaSchemaTables.tables[t.Name.L] = tbl
+		infoSchemaSchemaTables.tables[infoSchemaSchemaTables.tableKey(t.Name)] = tbl
 		bucketIdx := tableBucketIdx(t.ID)
 		b.is.sortedTablesBuckets[bucketIdx] = append(b.is.sortedTablesBuckets[bucketIdx], tbl)
 	}
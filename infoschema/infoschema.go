@@ -14,7 +14,9 @@
 package infoschema
 
 import (
+	"encoding/json"
 	"sort"
+	"strings"
 	"sync/atomic"
 
 	"github.com/juju/errors"
@@ -52,6 +54,8 @@ var (
 	ErrColumnExists = terror.ClassSchema.New(codeColumnExists, "Duplicate column")
 	// ErrIndexExists returns for index already exists.
 	ErrIndexExists = terror.ClassSchema.New(codeIndexExists, "Duplicate Index")
+	// ErrWrongObject returns for the table that is not the expected type, e.g. dropping a base table as a view.
+	ErrWrongObject = terror.ClassSchema.New(codeWrongObject, "wrong object")
 )
 
 // InfoSchema is the interface used to retrieve the schema information.
@@ -121,20 +125,28 @@ type infoSchema struct {
 
 // MockInfoSchema only serves for test.
 func MockInfoSchema(tbList []*model.TableInfo) InfoSchema {
+	dbInfo := &model.DBInfo{ID: 0, Name: model.NewCIStr("test"), Tables: tbList}
+	return MockInfoSchemaFromDBs([]*model.DBInfo{dbInfo})
+}
+
+// MockInfoSchemaFromDBs builds an InfoSchema from the schema information without a storage,
+// the tables are built by table.MockTableFromMeta. It only serves for test and the schema tracker of DDL.
+func MockInfoSchemaFromDBs(dbs []*model.DBInfo) InfoSchema {
 	result := &infoSchema{}
 	result.schemaMap = make(map[string]*schemaTables)
 	result.sortedTablesBuckets = make([]sortedTables, bucketCount)
-	dbInfo := &model.DBInfo{ID: 0, Name: model.NewCIStr("test"), Tables: tbList}
-	tableNames := &schemaTables{
-		dbInfo: dbInfo,
-		tables: make(map[string]table.Table),
-	}
-	result.schemaMap["test"] = tableNames
-	for _, tb := range tbList {
-		tbl := table.MockTableFromMeta(tb)
-		tableNames.tables[tb.Name.L] = tbl
-		bucketIdx := tableBucketIdx(tb.ID)
-		result.sortedTablesBuckets[bucketIdx] = append(result.sortedTablesBuckets[bucketIdx], tbl)
+	for _, dbInfo := range dbs {
+		tableNames := &schemaTables{
+			dbInfo: dbInfo,
+			tables: make(map[string]table.Table),
+		}
+		result.schemaMap[dbInfo.Name.L] = tableNames
+		for _, tb := range dbInfo.Tables {
+			tbl := table.MockTableFromMeta(tb)
+			tableNames.tables[tableNames.tableKey(tb.Name)] = tbl
+			bucketIdx := tableBucketIdx(tb.ID)
+			result.sortedTablesBuckets[bucketIdx] = append(result.sortedTablesBuckets[bucketIdx], tbl)
+		}
 	}
 	for i := range result.sortedTablesBuckets {
 		sort.Sort(result.sortedTablesBuckets[i])
@@ -142,10 +154,82 @@ func MockInfoSchema(tbList []*model.TableInfo) InfoSchema {
 	return result
 }
 
+type sortedSchemas []*model.DBInfo
+
+func (s sortedSchemas) Len() int           { return len(s) }
+func (s sortedSchemas) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s sortedSchemas) Less(i, j int) bool { return s[i].ID < s[j].ID }
+
+// dumpedSchema is the JSON form of a schema, the tables of DBInfo aren't encoded by default.
+type dumpedSchema struct {
+	*model.DBInfo
+	Tables []*model.TableInfo `json:"tables"`
+}
+
+// DumpSchema encodes all the schemas of the InfoSchema and their tables into JSON ordered by the schema ID,
+// the result can be loaded back by MockInfoSchemaFromJSON as a test fixture.
+func DumpSchema(is InfoSchema) ([]byte, error) {
+	dbs := is.Clone()
+	sort.Sort(sortedSchemas(dbs))
+	dumped := make([]dumpedSchema, 0, len(dbs))
+	for _, db := range dbs {
+		dumped = append(dumped, dumpedSchema{DBInfo: db, Tables: db.Tables})
+	}
+	data, err := json.MarshalIndent(dumped, "", "\t")
+	return data, errors.Trace(err)
+}
+
+// MockInfoSchemaFromJSON builds an InfoSchema from the JSON dumped by DumpSchema without a storage.
+// It only serves for test.
+func MockInfoSchemaFromJSON(data []byte) (InfoSchema, error) {
+	var dumped []dumpedSchema
+	if err := json.Unmarshal(data, &dumped); err != nil {
+		return nil, errors.Trace(err)
+	}
+	dbs := make([]*model.DBInfo, 0, len(dumped))
+	for _, d := range dumped {
+		if d.DBInfo == nil {
+			continue
+		}
+		d.DBInfo.Tables = d.Tables
+		dbs = append(dbs, d.DBInfo)
+	}
+	return MockInfoSchemaFromDBs(dbs), nil
+}
+
 var _ InfoSchema = (*infoSchema)(nil)
 
+// lookupSchema looks up the schema by the name. The names of the system databases and their tables are case
+// insensitive even if the names are case sensitive by lower_case_table_names.
+func (is *infoSchema) lookupSchema(schema model.CIStr) (*schemaTables, bool) {
+	if tbNames, ok := is.schemaMap[schema.L]; ok {
+		return tbNames, true
+	}
+	if isSystemDB(schema.L) {
+		tbNames, ok := is.schemaMap[strings.ToLower(schema.L)]
+		return tbNames, ok
+	}
+	return nil, false
+}
+
+// lookupTable looks up the table of the schema by the name.
+func (st *schemaTables) lookupTable(table model.CIStr) (table.Table, bool) {
+	t, ok := st.tables[st.tableKey(table)]
+	return t, ok
+}
+
+// tableKey returns the key of the table in the tables of the schema, the names of the tables of the system
+// databases are stored in lowercase, e.g. mysql.GLOBAL_VARIABLES is created and read as mysql.global_variables
+// by the bootstrap.
+func (st *schemaTables) tableKey(name model.CIStr) string {
+	if isSystemDB(st.dbInfo.Name.L) {
+		return strings.ToLower(name.L)
+	}
+	return name.L
+}
+
 func (is *infoSchema) SchemaByName(schema model.CIStr) (val *model.DBInfo, ok bool) {
-	tableNames, ok := is.schemaMap[schema.L]
+	tableNames, ok := is.lookupSchema(schema)
 	if !ok {
 		return
 	}
@@ -157,13 +241,13 @@ func (is *infoSchema) SchemaMetaVersion() int64 {
 }
 
 func (is *infoSchema) SchemaExists(schema model.CIStr) bool {
-	_, ok := is.schemaMap[schema.L]
+	_, ok := is.lookupSchema(schema)
 	return ok
 }
 
 func (is *infoSchema) TableByName(schema, table model.CIStr) (t table.Table, err error) {
-	if tbNames, ok := is.schemaMap[schema.L]; ok {
-		if t, ok = tbNames.tables[table.L]; ok {
+	if tbNames, ok := is.lookupSchema(schema); ok {
+		if t, ok = tbNames.lookupTable(table); ok {
 			return
 		}
 	}
@@ -171,8 +255,8 @@ func (is *infoSchema) TableByName(schema, table model.CIStr) (t table.Table, err
 }
 
 func (is *infoSchema) TableExists(schema, table model.CIStr) bool {
-	if tbNames, ok := is.schemaMap[schema.L]; ok {
-		if _, ok = tbNames.tables[table.L]; ok {
+	if tbNames, ok := is.lookupSchema(schema); ok {
+		if _, ok = tbNames.lookupTable(table); ok {
 			return true
 		}
 	}
@@ -220,7 +304,7 @@ func (is *infoSchema) AllSchemas() (schemas []*model.DBInfo) {
 }
 
 func (is *infoSchema) SchemaTables(schema model.CIStr) (tables []table.Table) {
-	schemaTables, ok := is.schemaMap[schema.L]
+	schemaTables, ok := is.lookupSchema(schema)
 	if !ok {
 		return
 	}
@@ -297,6 +381,7 @@ const (
 	codeBadTable       = 1051
 	codeColumnExists   = 1060
 	codeIndexExists    = 1831
+	codeWrongObject    = 1347
 )
 
 func init() {
@@ -312,6 +397,7 @@ func init() {
 		codeBadTable:            mysql.ErrBadTable,
 		codeColumnExists:        mysql.ErrDupFieldName,
 		codeIndexExists:         mysql.ErrDupIndex,
+		codeWrongObject:         mysql.ErrWrongObject,
 	}
 	terror.ErrClassToMySQLCodes[terror.ClassSchema] = schemaMySQLErrCodes
 	initInfoSchemaDB()
@@ -341,7 +427,12 @@ func initInfoSchemaDB() {
 	}
 }
 
-// IsMemoryDB checks if the db is in memory.
+// IsMemoryDB checks if the db is in memory. The name is case insensitive.
 func IsMemoryDB(dbName string) bool {
-	return dbName == "information_schema" || dbName == "performance_schema"
+	return strings.EqualFold(dbName, "information_schema") || strings.EqualFold(dbName, "performance_schema")
+}
+
+// isSystemDB checks if the db is a memory db or the mysql db, whose names are always case insensitive.
+func isSystemDB(dbName string) bool {
+	return IsMemoryDB(dbName) || strings.EqualFold(dbName, mysql.SystemDB)
 }
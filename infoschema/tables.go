@@ -16,15 +16,22 @@ package infoschema
 import (
 	"fmt"
 	"sort"
+	"sync"
 
 	"github.com/juju/errors"
+	"github.com/ngaut/log"
 	"github.com/pingcap/tidb/context"
 	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/meta"
 	"github.com/pingcap/tidb/meta/autoid"
 	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/plan/statistics"
+	"github.com/pingcap/tidb/privilege"
 	"github.com/pingcap/tidb/table"
 	"github.com/pingcap/tidb/util/charset"
+	"github.com/pingcap/tidb/util/hotspot"
+	"github.com/pingcap/tidb/util/planbaseline"
 	"github.com/pingcap/tidb/util/types"
 )
 
@@ -41,6 +48,10 @@ const (
 	tablePartitions    = "PARTITIONS"
 	tableKeyColumm     = "KEY_COLUMN_USAGE"
 	tableReferConst    = "REFERENTIAL_CONSTRAINTS"
+	tableViews         = "VIEWS"
+	tableWriteHotspots = "TIDB_WRITE_HOTSPOTS"
+	tablePlanBaselines = "TIDB_PLAN_BASELINES"
+	tableBaselineEvts  = "TIDB_PLAN_BASELINE_EVENTS"
 )
 
 type columnInfo struct {
@@ -127,7 +138,7 @@ var columnsCols = []columnInfo{
 	{"TABLE_SCHEMA", mysql.TypeVarchar, 64, 0, nil, nil},
 	{"TABLE_NAME", mysql.TypeVarchar, 64, 0, nil, nil},
 	{"COLUMN_NAME", mysql.TypeVarchar, 64, 0, nil, nil},
-	{"ORIGINAL_POSITION", mysql.TypeLonglong, 64, 0, nil, nil},
+	{"ORDINAL_POSITION", mysql.TypeLonglong, 64, 0, nil, nil},
 	{"COLUMN_DEFAULT", mysql.TypeBlob, 196606, 0, nil, nil},
 	{"IS_NULLABLE", mysql.TypeVarchar, 3, 0, nil, nil},
 	{"DATA_TYPE", mysql.TypeVarchar, 64, 0, nil, nil},
@@ -231,6 +242,20 @@ var referConstCols = []columnInfo{
 	{"REFERENCED_TABLE_NAME", mysql.TypeVarchar, 64, mysql.NotNullFlag, nil, nil},
 }
 
+// See https://dev.mysql.com/doc/refman/5.7/en/views-table.html
+var viewsCols = []columnInfo{
+	{"TABLE_CATALOG", mysql.TypeVarchar, 512, mysql.NotNullFlag, nil, nil},
+	{"TABLE_SCHEMA", mysql.TypeVarchar, 64, mysql.NotNullFlag, nil, nil},
+	{"TABLE_NAME", mysql.TypeVarchar, 64, mysql.NotNullFlag, nil, nil},
+	{"VIEW_DEFINITION", mysql.TypeLongBlob, types.UnspecifiedLength, mysql.NotNullFlag, nil, nil},
+	{"CHECK_OPTION", mysql.TypeVarchar, 8, mysql.NotNullFlag, nil, nil},
+	{"IS_UPDATABLE", mysql.TypeVarchar, 3, mysql.NotNullFlag, nil, nil},
+	{"DEFINER", mysql.TypeVarchar, 77, mysql.NotNullFlag, nil, nil},
+	{"SECURITY_TYPE", mysql.TypeVarchar, 7, mysql.NotNullFlag, nil, nil},
+	{"CHARACTER_SET_CLIENT", mysql.TypeVarchar, 32, mysql.NotNullFlag, nil, nil},
+	{"COLLATION_CONNECTION", mysql.TypeVarchar, 32, mysql.NotNullFlag, nil, nil},
+}
+
 // See https://dev.mysql.com/doc/refman/5.7/en/partitions-table.html
 var partitionsCols = []columnInfo{
 	{"TABLE_CATALOG", mysql.TypeVarchar, 512, 0, nil, nil},
@@ -339,6 +364,34 @@ func dataForTables(schemas []*model.DBInfo) [][]types.Datum {
 	rows := [][]types.Datum{}
 	for _, schema := range schemas {
 		for _, table := range schema.Tables {
+			if table.IsView() {
+				// The storage related columns are NULL for a view.
+				record := types.MakeDatums(
+					catalogVal,    // TABLE_CATALOG
+					schema.Name.O, // TABLE_SCHEMA
+					table.Name.O,  // TABLE_NAME
+					"VIEW",        // TABLE_TYPE
+					nil,           // ENGINE
+					nil,           // VERSION
+					nil,           // ROW_FORMAT
+					nil,           // TABLE_ROWS
+					nil,           // AVG_ROW_LENGTH
+					nil,           // DATA_LENGTH
+					nil,           // MAX_DATA_LENGTH
+					nil,           // INDEX_LENGTH
+					nil,           // DATA_FREE
+					nil,           // AUTO_INCREMENT
+					nil,           // CREATE_TIME
+					nil,           // UPDATE_TIME
+					nil,           // CHECK_TIME
+					nil,           // TABLE_COLLATION
+					nil,           // CHECKSUM
+					nil,           // CREATE_OPTIONS
+					"VIEW",        // TABLE_COMMENT
+				)
+				rows = append(rows, record)
+				continue
+			}
 			record := types.MakeDatums(
 				catalogVal,          // TABLE_CATALOG
 				schema.Name.O,       // TABLE_SCHEMA
@@ -368,6 +421,206 @@ func dataForTables(schemas []*model.DBInfo) [][]types.Datum {
 	return rows
 }
 
+func dataForViews(ctx context.Context, schemas []*model.DBInfo) [][]types.Datum {
+	rows := [][]types.Datum{}
+	for _, schema := range schemas {
+		for _, table := range schema.Tables {
+			if !table.IsView() {
+				continue
+			}
+			record := types.MakeDatums(
+				catalogVal,                         // TABLE_CATALOG
+				schema.Name.O,                      // TABLE_SCHEMA
+				table.Name.O,                       // TABLE_NAME
+				viewDefinition(ctx, schema, table), // VIEW_DEFINITION
+				"NONE",                             // CHECK_OPTION
+				"NO",                               // IS_UPDATABLE
+				table.View.Definer,                 // DEFINER
+				table.View.Security.String(),       // SECURITY_TYPE
+				table.Charset,                      // CHARACTER_SET_CLIENT
+				table.Collate,                      // COLLATION_CONNECTION
+			)
+			rows = append(rows, record)
+		}
+	}
+	return rows
+}
+
+// writeHotspotsCols are the columns of TIDB_WRITE_HOTSPOTS, which ranks the tables and indices by their write rates
+// in the server. The transaction and throttle columns are NULL for the indices.
+var writeHotspotsCols = []columnInfo{
+	{"TABLE_SCHEMA", mysql.TypeVarchar, 64, mysql.NotNullFlag, nil, nil},
+	{"TABLE_NAME", mysql.TypeVarchar, 64, mysql.NotNullFlag, nil, nil},
+	{"INDEX_NAME", mysql.TypeVarchar, 64, 0, nil, nil},
+	{"WRITE_QPS", mysql.TypeDouble, 22, 0, nil, nil},
+	{"WRITE_BYTES_PER_SEC", mysql.TypeDouble, 22, 0, nil, nil},
+	{"WRITES", mysql.TypeLonglong, 21, 0, nil, nil},
+	{"WRITE_BYTES", mysql.TypeLonglong, 21, 0, nil, nil},
+	{"TXNS", mysql.TypeLonglong, 21, 0, nil, nil},
+	{"CONFLICTS", mysql.TypeLonglong, 21, 0, nil, nil},
+	{"CONFLICT_RATE", mysql.TypeDouble, 22, 0, nil, nil},
+	{"THROTTLE_LIMIT", mysql.TypeLonglong, 21, 0, nil, nil},
+	{"THROTTLE_EXPIRE", mysql.TypeDatetime, 19, 0, nil, nil},
+}
+
+type writeHotspot struct {
+	stats  hotspot.Stats
+	record []types.Datum
+}
+
+// writeHotspotsSorter sorts the hotspots by the write rate and then the number of the writes, in descending order.
+type writeHotspotsSorter []writeHotspot
+
+func (s writeHotspotsSorter) Len() int {
+	return len(s)
+}
+
+func (s writeHotspotsSorter) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+func (s writeHotspotsSorter) Less(i, j int) bool {
+	if s[i].stats.WriteQPS != s[j].stats.WriteQPS {
+		return s[i].stats.WriteQPS > s[j].stats.WriteQPS
+	}
+	return s[i].stats.Writes > s[j].stats.Writes
+}
+
+func dataForWriteHotspots(schemas []*model.DBInfo) [][]types.Datum {
+	allStats := hotspot.GlobalRecorder.Stats()
+	throttles := hotspot.GlobalRecorder.Throttles()
+	var hotspots []writeHotspot
+	for _, schema := range schemas {
+		for _, table := range schema.Tables {
+			stats, ok := allStats[hotspot.Key{TableID: table.ID}]
+			throttle, throttled := throttles[table.ID]
+			if !ok && !throttled {
+				continue
+			}
+			record := types.MakeDatums(
+				schema.Name.O,          // TABLE_SCHEMA
+				table.Name.O,           // TABLE_NAME
+				nil,                    // INDEX_NAME
+				stats.WriteQPS,         // WRITE_QPS
+				stats.WriteBytesPerSec, // WRITE_BYTES_PER_SEC
+				stats.Writes,           // WRITES
+				stats.WriteBytes,       // WRITE_BYTES
+				stats.Txns,             // TXNS
+				stats.Conflicts,        // CONFLICTS
+				nil,                    // CONFLICT_RATE
+				nil,                    // THROTTLE_LIMIT
+				nil,                    // THROTTLE_EXPIRE
+			)
+			if stats.Txns > 0 {
+				record[9].SetFloat64(float64(stats.Conflicts) / float64(stats.Txns))
+			}
+			if throttled {
+				record[10].SetUint64(throttle.Limit)
+				record[11].SetMysqlTime(types.Time{Time: throttle.Expire, Type: mysql.TypeDatetime})
+			}
+			hotspots = append(hotspots, writeHotspot{stats: stats, record: record})
+			for _, index := range table.Indices {
+				stats, ok := allStats[hotspot.Key{TableID: table.ID, IndexID: index.ID}]
+				if !ok {
+					continue
+				}
+				record := types.MakeDatums(
+					schema.Name.O,          // TABLE_SCHEMA
+					table.Name.O,           // TABLE_NAME
+					index.Name.O,           // INDEX_NAME
+					stats.WriteQPS,         // WRITE_QPS
+					stats.WriteBytesPerSec, // WRITE_BYTES_PER_SEC
+					stats.Writes,           // WRITES
+					stats.WriteBytes,       // WRITE_BYTES
+					nil,                    // TXNS
+					nil,                    // CONFLICTS
+					nil,                    // CONFLICT_RATE
+					nil,                    // THROTTLE_LIMIT
+					nil,                    // THROTTLE_EXPIRE
+				)
+				hotspots = append(hotspots, writeHotspot{stats: stats, record: record})
+			}
+		}
+	}
+	sort.Stable(writeHotspotsSorter(hotspots))
+	rows := make([][]types.Datum, 0, len(hotspots))
+	for _, h := range hotspots {
+		rows = append(rows, h.record)
+	}
+	return rows
+}
+
+// planBaselinesCols are the columns of TIDB_PLAN_BASELINES, which lists the baseline plans of the statements loaded
+// in the server. The latencies are in seconds. FALLBACK is 1 if the statement has regressed plans, which fall back
+// to the baseline plan.
+var planBaselinesCols = []columnInfo{
+	{"DIGEST", mysql.TypeVarchar, 64, mysql.NotNullFlag, nil, nil},
+	{"SQL", mysql.TypeBlob, -1, mysql.NotNullFlag, nil, nil},
+	{"HINTS", mysql.TypeBlob, -1, mysql.NotNullFlag, nil, nil},
+	{"LATENCY", mysql.TypeDouble, 22, 0, nil, nil},
+	{"EXECUTIONS", mysql.TypeLonglong, 21, 0, nil, nil},
+	{"UPDATE_TIME", mysql.TypeDatetime, 19, 0, nil, nil},
+	{"FALLBACK", mysql.TypeTiny, 1, 0, nil, nil},
+}
+
+// planBaselineEventsCols are the columns of TIDB_PLAN_BASELINE_EVENTS, which lists the recent captures, evolutions
+// and fallbacks of the baselines in the server, the latest one comes first.
+var planBaselineEventsCols = []columnInfo{
+	{"TIME", mysql.TypeDatetime, 19, 0, nil, nil},
+	{"TYPE", mysql.TypeVarchar, 16, mysql.NotNullFlag, nil, nil},
+	{"DIGEST", mysql.TypeVarchar, 64, mysql.NotNullFlag, nil, nil},
+	{"SQL", mysql.TypeBlob, -1, mysql.NotNullFlag, nil, nil},
+	{"HINTS", mysql.TypeBlob, -1, 0, nil, nil},
+	{"LATENCY", mysql.TypeDouble, 22, 0, nil, nil},
+	{"BASELINE_HINTS", mysql.TypeBlob, -1, 0, nil, nil},
+	{"BASELINE_LATENCY", mysql.TypeDouble, 22, 0, nil, nil},
+}
+
+func dataForPlanBaselines() [][]types.Datum {
+	var rows [][]types.Datum
+	for _, b := range planbaseline.GlobalManager.Baselines() {
+		fallback := 0
+		if b.Fallback {
+			fallback = 1
+		}
+		updateTime := types.Time{Time: b.UpdateTime, Type: mysql.TypeDatetime}
+		record := types.MakeDatums(
+			b.Digest,            // DIGEST
+			b.SQL,               // SQL
+			b.Hints,             // HINTS
+			b.Latency.Seconds(), // LATENCY
+			b.Executions,        // EXECUTIONS
+			updateTime,          // UPDATE_TIME
+			fallback,            // FALLBACK
+		)
+		rows = append(rows, record)
+	}
+	return rows
+}
+
+func dataForPlanBaselineEvents() [][]types.Datum {
+	var rows [][]types.Datum
+	for _, e := range planbaseline.GlobalManager.Events() {
+		eventTime := types.Time{Time: e.Time, Type: mysql.TypeDatetime}
+		record := types.MakeDatums(
+			eventTime,           // TIME
+			e.Type,              // TYPE
+			e.Digest,            // DIGEST
+			e.SQL,               // SQL
+			e.Hints,             // HINTS
+			e.Latency.Seconds(), // LATENCY
+			nil,                 // BASELINE_HINTS
+			nil,                 // BASELINE_LATENCY
+		)
+		if e.Type != planbaseline.EventCapture {
+			record[6].SetString(e.BaselineHints)
+			record[7].SetFloat64(e.BaselineLatency.Seconds())
+		}
+		rows = append(rows, record)
+	}
+	return rows
+}
+
 func dataForColumns(schemas []*model.DBInfo) [][]types.Datum {
 	rows := [][]types.Datum{}
 	for _, schema := range schemas {
@@ -381,18 +634,59 @@ func dataForColumns(schemas []*model.DBInfo) [][]types.Datum {
 	return rows
 }
 
+// integerPrecision is the NUMERIC_PRECISION of the signed integer types.
+var integerPrecision = map[byte]int{
+	mysql.TypeTiny:     3,
+	mysql.TypeShort:    5,
+	mysql.TypeInt24:    7,
+	mysql.TypeLong:     10,
+	mysql.TypeLonglong: 19,
+}
+
 func dataForColumnsInTable(schema *model.DBInfo, tbl *model.TableInfo) [][]types.Datum {
 	rows := [][]types.Datum{}
 	for i, col := range tbl.Columns {
-		colLen := col.Flen
-		if colLen == types.UnspecifiedLength {
-			colLen = mysql.GetDefaultFieldLength(col.Tp)
-		}
-		decimal := col.Decimal
-		if decimal == types.UnspecifiedLength {
-			decimal = 0
+		var (
+			charMaxLen, charOctetLen, charsetName, collationName interface{}
+			numericPrecision, numericScale, datetimePrecision    interface{}
+		)
+		switch col.Tp {
+		case mysql.TypeTiny, mysql.TypeShort, mysql.TypeInt24, mysql.TypeLong, mysql.TypeLonglong:
+			precision := integerPrecision[col.Tp]
+			if col.Tp == mysql.TypeLonglong && mysql.HasUnsignedFlag(col.Flag) {
+				precision++
+			}
+			numericPrecision, numericScale = precision, 0
+		case mysql.TypeDecimal, mysql.TypeNewDecimal:
+			numericPrecision, numericScale = defaultIfUnspecified(col.Flen, 10), defaultIfUnspecified(col.Decimal, 0)
+		case mysql.TypeFloat, mysql.TypeDouble:
+			numericPrecision = 12
+			if col.Tp == mysql.TypeDouble {
+				numericPrecision = 22
+			}
+			if col.Flen != types.UnspecifiedLength && col.Decimal != types.UnspecifiedLength {
+				numericPrecision, numericScale = col.Flen, col.Decimal
+			}
+		case mysql.TypeBit:
+			numericPrecision = defaultIfUnspecified(col.Flen, 1)
+		case mysql.TypeDatetime, mysql.TypeTimestamp, mysql.TypeDuration:
+			datetimePrecision = defaultIfUnspecified(col.Decimal, 0)
+		case mysql.TypeString, mysql.TypeVarchar, mysql.TypeVarString, mysql.TypeTinyBlob, mysql.TypeBlob,
+			mysql.TypeMediumBlob, mysql.TypeLongBlob, mysql.TypeEnum, mysql.TypeSet:
+			colLen := characterLength(&col.FieldType)
+			charMaxLen, charOctetLen = colLen, colLen
+			if col.Charset != "" && col.Charset != charset.CharsetBin {
+				charOctetLen = colLen * int64(charsetMaxlen(col.Charset))
+				charsetName, collationName = col.Charset, col.Collate
+			}
 		}
 		columnType := col.FieldType.CompactStr()
+		if mysql.HasUnsignedFlag(col.Flag) {
+			columnType += " unsigned"
+		}
+		if mysql.HasZerofillFlag(col.Flag) {
+			columnType += " zerofill"
+		}
 		columnDesc := table.NewColDesc(table.ToColumn(col))
 		var columnDefault interface{}
 		if columnDesc.DefaultValue != nil {
@@ -403,33 +697,80 @@ func dataForColumnsInTable(schema *model.DBInfo, tbl *model.TableInfo) [][]types
 			schema.Name.O,                        // TABLE_SCHEMA
 			tbl.Name.O,                           // TABLE_NAME
 			col.Name.O,                           // COLUMN_NAME
-			i+1,                                  // ORIGINAL_POSITION
+			i+1,                                  // ORDINAL_POSITION
 			columnDefault,                        // COLUMN_DEFAULT
 			columnDesc.Null,                      // IS_NULLABLE
 			types.TypeToStr(col.Tp, col.Charset), // DATA_TYPE
-			colLen,                            // CHARACTER_MAXIMUM_LENGTH
-			colLen,                            // CHARACTER_OCTET_LENGTH
-			decimal,                           // NUMERIC_PRECISION
-			0,                                 // NUMERIC_SCALE
-			0,                                 // DATETIME_PRECISION
-			col.Charset,                       // CHARACTER_SET_NAME
-			col.Collate,                       // COLLATION_NAME
-			columnType,                        // COLUMN_TYPE
-			columnDesc.Key,                    // COLUMN_KEY
-			columnDesc.Extra,                  // EXTRA
-			"select,insert,update,references", // PRIVILEGES
-			"", // COLUMN_COMMENT
+			charMaxLen,                           // CHARACTER_MAXIMUM_LENGTH
+			charOctetLen,                         // CHARACTER_OCTET_LENGTH
+			numericPrecision,                     // NUMERIC_PRECISION
+			numericScale,                         // NUMERIC_SCALE
+			datetimePrecision,                    // DATETIME_PRECISION
+			charsetName,                          // CHARACTER_SET_NAME
+			collationName,                        // COLLATION_NAME
+			columnType,                           // COLUMN_TYPE
+			columnDesc.Key,                       // COLUMN_KEY
+			columnDesc.Extra,                     // EXTRA
+			"select,insert,update,references",    // PRIVILEGES
+			col.Comment,                          // COLUMN_COMMENT
 		)
 		rows = append(rows, record)
 	}
 	return rows
 }
 
-func dataForStatistics(schemas []*model.DBInfo) [][]types.Datum {
+func defaultIfUnspecified(length, defaultLength int) int {
+	if length == types.UnspecifiedLength {
+		return defaultLength
+	}
+	return length
+}
+
+// characterLength returns the maximum length in characters of a string column.
+func characterLength(ft *types.FieldType) int64 {
+	switch ft.Tp {
+	case mysql.TypeTinyBlob:
+		return 255
+	case mysql.TypeBlob:
+		return 65535
+	case mysql.TypeMediumBlob:
+		return 16777215
+	case mysql.TypeLongBlob:
+		return 4294967295
+	case mysql.TypeEnum, mysql.TypeSet:
+		var length int64
+		for _, e := range ft.Elems {
+			if ft.Tp == mysql.TypeEnum && int64(len(e)) > length {
+				length = int64(len(e))
+			}
+			if ft.Tp == mysql.TypeSet {
+				length += int64(len(e)) + 1
+			}
+		}
+		if ft.Tp == mysql.TypeSet && length > 0 {
+			// There is no separator after the last element.
+			length--
+		}
+		return length
+	}
+	return int64(defaultIfUnspecified(ft.Flen, 1))
+}
+
+// charsetMaxlen returns the maximum byte length of a character in the charset.
+func charsetMaxlen(cs string) int {
+	for _, desc := range charset.GetAllCharsets() {
+		if desc.Name == cs {
+			return desc.Maxlen
+		}
+	}
+	return 1
+}
+
+func dataForStatistics(ctx context.Context, schemas []*model.DBInfo) [][]types.Datum {
 	rows := [][]types.Datum{}
 	for _, schema := range schemas {
 		for _, table := range schema.Tables {
-			rs := dataForStatisticsInTable(schema, table)
+			rs := dataForStatisticsInTable(schema, table, getTableStats(ctx, table))
 			for _, r := range rs {
 				rows = append(rows, r)
 			}
@@ -438,11 +779,70 @@ func dataForStatistics(schemas []*model.DBInfo) [][]types.Datum {
 	return rows
 }
 
-func dataForStatisticsInTable(schema *model.DBInfo, table *model.TableInfo) [][]types.Datum {
+// getTableStats loads the statistics of the table saved by ANALYZE TABLE.
+// It returns nil if the table has never been analyzed or the statistics can't be loaded.
+func getTableStats(ctx context.Context, tbl *model.TableInfo) *statistics.Table {
+	if ctx == nil {
+		return nil
+	}
+	txn, err := ctx.GetTxn(false)
+	if err != nil {
+		log.Warnf("[infoschema] get txn for table %s statistics failed: %v", tbl.Name.O, errors.ErrorStack(err))
+		return nil
+	}
+	tpb, err := meta.NewMeta(txn).GetTableStats(tbl.ID)
+	if err != nil || tpb == nil {
+		return nil
+	}
+	statsCache.Lock()
+	defer statsCache.Unlock()
+	if cached, ok := statsCache.tables[tbl.ID]; ok && cached.ts == tpb.GetTs() && cached.info == tbl {
+		return cached.stats
+	}
+	stats, err := statistics.TableFromPB(tbl, tpb)
+	if err != nil {
+		log.Warnf("[infoschema] load table %s statistics failed: %v", tbl.Name.O, errors.ErrorStack(err))
+		return nil
+	}
+	if len(statsCache.tables) >= maxCachedStats {
+		statsCache.tables = make(map[int64]*cachedStats)
+	}
+	statsCache.tables[tbl.ID] = &cachedStats{ts: tpb.GetTs(), info: tbl, stats: stats}
+	return stats
+}
+
+// maxCachedStats is the max number of the tables whose statistics are cached.
+const maxCachedStats = 1024
+
+// cachedStats is the statistics decoded for a table info, it's decoded again if the table is analyzed or altered.
+type cachedStats struct {
+	ts    int64
+	info  *model.TableInfo
+	stats *statistics.Table
+}
+
+// statsCache caches the decoded statistics of the tables by their IDs, so the statistics of all the tables aren't
+// decoded by every query of STATISTICS.
+var statsCache = struct {
+	sync.Mutex
+	tables map[int64]*cachedStats
+}{tables: make(map[int64]*cachedStats)}
+
+// indexCardinality estimates the number of distinct values of the first prefixLen columns of an index.
+// It returns nil if there is no statistics for the table.
+func indexCardinality(stats *statistics.Table, offsets []int, prefixLen int, unique bool) interface{} {
+	if stats == nil {
+		return nil
+	}
+	return stats.IndexCardinality(offsets, prefixLen, unique)
+}
+
+func dataForStatisticsInTable(schema *model.DBInfo, table *model.TableInfo, stats *statistics.Table) [][]types.Datum {
 	rows := [][]types.Datum{}
 	if table.PKIsHandle {
 		for _, col := range table.Columns {
 			if mysql.HasPriKeyFlag(col.Flag) {
+				cardinality := indexCardinality(stats, []int{col.Offset}, 1, true)
 				record := types.MakeDatums(
 					catalogVal,    // TABLE_CATALOG
 					schema.Name.O, // TABLE_SCHEMA
@@ -453,7 +853,7 @@ func dataForStatisticsInTable(schema *model.DBInfo, table *model.TableInfo) [][]
 					1,             // SEQ_IN_INDEX
 					col.Name.O,    // COLUMN_NAME
 					"A",           // COLLATION
-					0,             // CARDINALITY
+					cardinality,   // CARDINALITY
 					nil,           // SUB_PART
 					nil,           // PACKED
 					"",            // NULLABLE
@@ -474,12 +874,21 @@ func dataForStatisticsInTable(schema *model.DBInfo, table *model.TableInfo) [][]
 		if index.Unique {
 			nonUnique = "0"
 		}
+		offsets := make([]int, len(index.Columns))
+		for i, key := range index.Columns {
+			offsets[i] = key.Offset
+		}
 		for i, key := range index.Columns {
 			col := nameToCol[key.Name.L]
 			nullable := "YES"
 			if mysql.HasNotNullFlag(col.Flag) {
 				nullable = ""
 			}
+			var subPart interface{}
+			if key.Length != types.UnspecifiedLength {
+				subPart = key.Length
+			}
+			cardinality := indexCardinality(stats, offsets, i+1, index.Unique)
 			record := types.MakeDatums(
 				catalogVal,    // TABLE_CATALOG
 				schema.Name.O, // TABLE_SCHEMA
@@ -490,13 +899,13 @@ func dataForStatisticsInTable(schema *model.DBInfo, table *model.TableInfo) [][]
 				i+1,           // SEQ_IN_INDEX
 				key.Name.O,    // COLUMN_NAME
 				"A",           // COLLATION
-				0,             // CARDINALITY
-				nil,           // SUB_PART
+				cardinality,   // CARDINALITY
+				subPart,       // SUB_PART
 				nil,           // PACKED
 				nullable,      // NULLABLE
 				"BTREE",       // INDEX_TYPE
 				"",            // COMMENT
-				"",            // INDEX_COMMENT
+				index.Comment, // INDEX_COMMENT
 			)
 			rows = append(rows, record)
 		}
@@ -516,6 +925,10 @@ var tableNameToColumns = map[string]([]columnInfo){
 	tablePartitions:    partitionsCols,
 	tableKeyColumm:     keyColumnUsageCols,
 	tableReferConst:    referConstCols,
+	tableViews:         viewsCols,
+	tableWriteHotspots: writeHotspotsCols,
+	tablePlanBaselines: planBaselinesCols,
+	tableBaselineEvts:  planBaselineEventsCols,
 }
 
 func createInfoSchemaTable(handle *Handle, meta *model.TableInfo) *infoschemaTable {
@@ -544,6 +957,23 @@ func (s schemasSorter) Len() int {
 	return len(s)
 }
 
+// viewDefinition returns the select statement of a view, or an empty string if the user can't show the view and
+// isn't its definer, like MySQL.
+func viewDefinition(ctx context.Context, schema *model.DBInfo, table *model.TableInfo) string {
+	if ctx == nil || ctx.GetSessionVars().User == table.View.Definer {
+		return table.View.SelectStmt
+	}
+	checker := privilege.GetPrivilegeChecker(ctx)
+	if checker == nil {
+		return table.View.SelectStmt
+	}
+	hasPriv, err := checker.Check(ctx, schema, table, mysql.ShowViewPriv)
+	if err != nil || !hasPriv {
+		return ""
+	}
+	return table.View.SelectStmt
+}
+
 func (s schemasSorter) Swap(i, j int) {
 	s[i], s[j] = s[j], s[i]
 }
@@ -565,7 +995,7 @@ func (it *infoschemaTable) getRows(ctx context.Context, cols []*table.Column) []
 	case tableColumns:
 		fullRows = dataForColumns(dbs)
 	case tableStatistics:
-		fullRows = dataForStatistics(dbs)
+		fullRows = dataForStatistics(ctx, dbs)
 	case tableCharacterSets:
 		fullRows = dataForCharacterSets()
 	case tableCollations:
@@ -575,6 +1005,14 @@ func (it *infoschemaTable) getRows(ctx context.Context, cols []*table.Column) []
 	case tablePartitions:
 	case tableKeyColumm:
 	case tableReferConst:
+	case tableViews:
+		fullRows = dataForViews(ctx, dbs)
+	case tableWriteHotspots:
+		fullRows = dataForWriteHotspots(dbs)
+	case tablePlanBaselines:
+		fullRows = dataForPlanBaselines()
+	case tableBaselineEvts:
+		fullRows = dataForPlanBaselineEvents()
 	}
 	if len(cols) == len(it.cols) {
 		return fullRows
@@ -14,8 +14,11 @@
 package inspectkv
 
 import (
+	"encoding/binary"
+	"fmt"
 	"io"
 	"reflect"
+	"strings"
 
 	"github.com/juju/errors"
 	"github.com/ngaut/log"
@@ -68,6 +71,40 @@ func GetDDLInfo(txn kv.Transaction) (*DDLInfo, error) {
 	return info, nil
 }
 
+// DDLJobInfo is a DDL job with its reorganization progress.
+type DDLJobInfo struct {
+	Job *model.Job
+	// Progress is nil if the job has no reorganization progress.
+	Progress *model.ReorgProgress
+}
+
+// GetDDLJobs returns the DDL jobs in the queue with their reorganization progress.
+func GetDDLJobs(txn kv.Transaction) ([]*DDLJobInfo, error) {
+	t := meta.NewMeta(txn)
+	jobs, err := t.GetAllDDLJobs()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	infos := make([]*DDLJobInfo, 0, len(jobs))
+	for _, job := range jobs {
+		info := &DDLJobInfo{Job: job}
+		info.Progress, err = t.GetDDLReorgProgress(job)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if info.Progress != nil {
+			var ranges []*model.ReorgRange
+			ranges, err = t.GetDDLReorgRanges(job)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			info.Progress.MergeRanges(ranges)
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
 // GetBgDDLInfo returns background DDL information.
 func GetBgDDLInfo(txn kv.Transaction) (*DDLInfo, error) {
 	var err error
@@ -90,6 +127,102 @@ func GetBgDDLInfo(txn kv.Transaction) (*DDLInfo, error) {
 	return info, nil
 }
 
+// DeleteRange is the key range of a table whose data is deleted by a background job.
+type DeleteRange struct {
+	Job     *model.Job
+	TableID int64
+	// Done is true if the deletion of the range is recorded as done.
+	Done bool
+	// HasData is true if the range still has data.
+	HasData bool
+}
+
+// IsOrphan returns true if the deletion of the range is recorded as done but the range still has data. The range of
+// a job in the queue isn't an orphan whether it has data or not, because the job may be running.
+func (r *DeleteRange) IsOrphan() bool {
+	return r.Done && r.HasData
+}
+
+// GetDeleteRanges returns the ranges deleted by the done background jobs in the history from the job ID startID,
+// with whether the ranges still have data in txn. At most limit jobs are read, nextID is the start job ID of the
+// next batch, or 0 if there are no more jobs. The ranges of the background jobs in the queue are returned with the
+// first batch, whose startID is 0.
+func GetDeleteRanges(txn kv.Transaction, startID int64, limit int) (ranges []*DeleteRange, nextID int64, err error) {
+	t := meta.NewMeta(txn)
+	var jobs []*model.Job
+	if startID == 0 {
+		jobs, err = t.GetAllBgJobs()
+		if err != nil {
+			return nil, 0, errors.Trace(err)
+		}
+	}
+	historyJobs, err := t.GetHistoryBgJobs(startID, limit)
+	if err != nil {
+		return nil, 0, errors.Trace(err)
+	}
+	if len(historyJobs) == limit {
+		nextID = historyJobs[len(historyJobs)-1].ID + 1
+	}
+	for _, job := range historyJobs {
+		if job.IsDone() {
+			jobs = append(jobs, job)
+		}
+	}
+
+	for _, job := range jobs {
+		ids, err := bgJobTableIDs(t, job)
+		if err != nil {
+			return nil, 0, errors.Trace(err)
+		}
+		for _, id := range ids {
+			r := &DeleteRange{Job: job, TableID: id, Done: job.IsDone()}
+			r.HasData, err = prefixHasData(txn, tablecodec.EncodeTablePrefix(id))
+			if err != nil {
+				return nil, 0, errors.Trace(err)
+			}
+			ranges = append(ranges, r)
+		}
+	}
+	return ranges, nextID, nil
+}
+
+// bgJobTableIDs returns the IDs of the tables whose data is deleted by the background job.
+func bgJobTableIDs(t *meta.Meta, job *model.Job) ([]int64, error) {
+	switch job.Type {
+	case model.ActionDropTable, model.ActionTruncateTable, model.ActionShadowCopy:
+		return []int64{job.TableID}, nil
+	case model.ActionDropSchema:
+	default:
+		return nil, nil
+	}
+
+	var ids []int64
+	if !job.IsDone() {
+		// The arguments are the IDs of the tables left to delete and the start key to delete.
+		var startKey kv.Key
+		err := job.DecodeArgs(&ids, &startKey)
+		return ids, errors.Trace(err)
+	}
+	// The done job doesn't keep the IDs of the tables, they are kept in the history DDL job of dropping the schema.
+	ddlJob, err := t.GetHistoryDDLJob(job.ID)
+	if err != nil || ddlJob == nil {
+		return nil, errors.Trace(err)
+	}
+	var ver int64
+	dbInfo := &model.DBInfo{}
+	err = ddlJob.DecodeArgs(&ver, dbInfo, &ids)
+	return ids, errors.Trace(err)
+}
+
+func prefixHasData(retriever kv.Retriever, prefix kv.Key) (bool, error) {
+	it, err := retriever.Seek(prefix)
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	defer it.Close()
+	return it.Valid() && it.Key().HasPrefix(prefix), nil
+}
+
 func nextIndexVals(data []types.Datum) []types.Datum {
 	// Add 0x0 to the end of data.
 	return append(data, types.Datum{})
@@ -243,6 +376,227 @@ func checkRecordAndIndex(txn kv.Transaction, t table.Table, idx table.Index) err
 	return nil
 }
 
+// InconsistencyKind is the kind of an inconsistency between the rows of a table and an index of the table.
+type InconsistencyKind int
+
+// Inconsistency kinds.
+const (
+	// MissingIndex means a row of the table has no entry in the index.
+	MissingIndex InconsistencyKind = iota + 1
+	// DanglingIndex means an entry of the index points to a row which doesn't exist.
+	DanglingIndex
+	// MismatchedIndex means the values of an entry of the index differ from the values of its row.
+	MismatchedIndex
+)
+
+// String implements fmt.Stringer interface.
+func (k InconsistencyKind) String() string {
+	switch k {
+	case MissingIndex:
+		return "missing index entry"
+	case DanglingIndex:
+		return "dangling index entry"
+	case MismatchedIndex:
+		return "mismatched index entry"
+	}
+	return "unknown"
+}
+
+// Inconsistency is an inconsistency between a row of a table and an index of the table.
+type Inconsistency struct {
+	Kind   InconsistencyKind
+	Handle int64
+	// Index is the values of the index entry, it's nil if the entry is missing.
+	Index []types.Datum
+	// Record is the values of the indexed columns of the row, it's nil if the row doesn't exist.
+	Record []types.Datum
+}
+
+// String implements fmt.Stringer interface.
+func (i *Inconsistency) String() string {
+	return fmt.Sprintf("handle %d %s, index:%s != record:%s", i.Handle, i.Kind, datumsString(i.Index),
+		datumsString(i.Record))
+}
+
+func datumsString(vals []types.Datum) string {
+	if vals == nil {
+		return "<nil>"
+	}
+	strs := make([]string, 0, len(vals))
+	for _, val := range vals {
+		if val.IsNull() {
+			strs = append(strs, "NULL")
+			continue
+		}
+		str, err := val.ToString()
+		if err != nil {
+			str = fmt.Sprintf("%v", val.GetValue())
+		}
+		strs = append(strs, str)
+	}
+	return "(" + strings.Join(strs, ", ") + ")"
+}
+
+// CheckIndex checks whether the index and the rows of the table are consistent, it scans the index and the rows
+// and returns at most limit inconsistencies, or all of them if limit is 0. Unlike CompareIndexData, it reads by a
+// retriever, so several indices can be checked in parallel in a snapshot, and it doesn't stop at the first
+// inconsistency.
+func CheckIndex(retriever kv.Retriever, t table.Table, idx table.Index, limit int) ([]*Inconsistency, error) {
+	cols := make([]*table.Column, len(idx.Meta().Columns))
+	for i, col := range idx.Meta().Columns {
+		cols[i] = t.Cols()[col.Offset]
+	}
+	var result []*Inconsistency
+	full := func() bool {
+		return limit > 0 && len(result) >= limit
+	}
+
+	// Check the index entries against the rows.
+	it, err := idx.SeekFirst(retriever)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	defer it.Close()
+	mismatched := make(map[int64]bool)
+	for !full() {
+		idxVals, h, err := it.Next()
+		if terror.ErrorEqual(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, errors.Trace(err)
+		}
+		recordVals, err := rowWithCols(retriever, t, h, cols)
+		if terror.ErrorEqual(err, kv.ErrNotExist) {
+			result = append(result, &Inconsistency{Kind: DanglingIndex, Handle: h, Index: idxVals})
+			continue
+		}
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if !reflect.DeepEqual(idxVals, recordVals) {
+			result = append(result, &Inconsistency{Kind: MismatchedIndex, Handle: h, Index: idxVals, Record: recordVals})
+			mismatched[h] = true
+		}
+	}
+
+	// Check the rows against the index entries, the rows whose entries are mismatched have been reported.
+	filterFunc := func(h int64, vals []types.Datum, cols []*table.Column) (bool, error) {
+		if full() {
+			return false, nil
+		}
+		if mismatched[h] {
+			return true, nil
+		}
+		exist, err := indexEntryExists(retriever, idx, vals, h)
+		if err != nil {
+			return false, errors.Trace(err)
+		}
+		if !exist {
+			result = append(result, &Inconsistency{Kind: MissingIndex, Handle: h, Record: vals})
+		}
+		return true, nil
+	}
+	err = iterRecords(retriever, t, t.RecordKey(0), cols, filterFunc)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return result, nil
+}
+
+// indexEntryExists checks whether the index has the entry of the values for the handle.
+func indexEntryExists(retriever kv.Retriever, idx table.Index, vals []types.Datum, h int64) (bool, error) {
+	key, distinct, err := idx.GenIndexKey(vals, h)
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	value, err := retriever.Get(key)
+	if kv.IsErrNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	// The value of the entry of a distinct index is the big endian encoded handle.
+	if distinct {
+		if len(value) != 8 {
+			return false, errors.Errorf("invalid handle %q of index %s", value, idx.Meta().Name)
+		}
+		return int64(binary.BigEndian.Uint64(value)) == h, nil
+	}
+	return true, nil
+}
+
+// RecoverIndex adds the missing entries of the index for the rows of the handles in txn, and returns the number of
+// the added entries. The rows which don't exist in txn are skipped, and kv.ErrKeyExists is returned if the entry
+// of a unique index is taken by another handle.
+func RecoverIndex(txn kv.Transaction, t table.Table, idx table.Index, handles []int64) (int, error) {
+	cols := make([]*table.Column, len(idx.Meta().Columns))
+	for i, col := range idx.Meta().Columns {
+		cols[i] = t.Cols()[col.Offset]
+	}
+	var added int
+	for _, h := range handles {
+		vals, err := rowWithCols(txn, t, h, cols)
+		if terror.ErrorEqual(err, kv.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return 0, errors.Trace(err)
+		}
+		exist, _, err := idx.Exist(txn, vals, h)
+		if err != nil {
+			return 0, errors.Trace(err)
+		}
+		if exist {
+			continue
+		}
+		// Lock the row to conflict with the transactions which update it.
+		if err = txn.LockKeys(t.RecordKey(h)); err != nil {
+			return 0, errors.Trace(err)
+		}
+		if _, err = idx.Create(txn, vals, h); err != nil {
+			return 0, errors.Trace(err)
+		}
+		added++
+	}
+	return added, nil
+}
+
+// CleanupIndex removes the entries of the index which don't match the rows in txn, and returns the number of
+// the removed entries. The entries which have been removed or replaced in txn are skipped.
+func CleanupIndex(txn kv.Transaction, t table.Table, idx table.Index, entries []*RecordData) (int, error) {
+	cols := make([]*table.Column, len(idx.Meta().Columns))
+	for i, col := range idx.Meta().Columns {
+		cols[i] = t.Cols()[col.Offset]
+	}
+	var removed int
+	for _, entry := range entries {
+		exist, err := indexEntryExists(txn, idx, entry.Values, entry.Handle)
+		if err != nil {
+			return 0, errors.Trace(err)
+		}
+		if !exist {
+			continue
+		}
+		recordVals, err := rowWithCols(txn, t, entry.Handle, cols)
+		if err == nil && reflect.DeepEqual(entry.Values, recordVals) {
+			continue
+		}
+		if err != nil && !terror.ErrorEqual(err, kv.ErrNotExist) {
+			return 0, errors.Trace(err)
+		}
+		// Lock the row to conflict with the transactions which write it.
+		if err = txn.LockKeys(t.RecordKey(entry.Handle)); err != nil {
+			return 0, errors.Trace(err)
+		}
+		if err = idx.Delete(txn, entry.Values, entry.Handle); err != nil {
+			return 0, errors.Trace(err)
+		}
+		removed++
+	}
+	return removed, nil
+}
+
 func scanTableData(retriever kv.Retriever, t table.Table, cols []*table.Column, startHandle, limit int64) (
 	[]*RecordData, int64, error) {
 	var records []*RecordData
@@ -475,7 +829,10 @@ func iterRecords(retriever kv.Retriever, t table.Table, startKey kv.Key, cols []
 
 		rk := t.RecordKey(handle)
 		err = kv.NextUntil(it, util.RowKeyPrefixFilter(rk))
-		if err != nil {
+		// The iterator of a snapshot returns kv.ErrNotExist at the end of the data.
+		if terror.ErrorEqual(err, kv.ErrNotExist) {
+			break
+		} else if err != nil {
 			return errors.Trace(err)
 		}
 	}
@@ -189,6 +189,96 @@ func (s *testSuite) TestGetBgDDLInfo(c *C) {
 	c.Assert(err, IsNil)
 }
 
+func (s *testSuite) TestGetDeleteRanges(c *C) {
+	defer testleak.AfterTest(c)()
+	txn, err := s.store.Begin()
+	c.Assert(err, IsNil)
+	defer txn.Rollback()
+	t := meta.NewMeta(txn)
+
+	// The tables 11 and 13 have data, the tables 12 and 14 have no data.
+	for _, id := range []int64{11, 13} {
+		err = txn.Set(tablecodec.EncodeRowKeyWithHandle(id, 1), []byte("v"))
+		c.Assert(err, IsNil)
+	}
+	jobs := []*model.Job{
+		{ID: 1, TableID: 11, Type: model.ActionDropTable, State: model.JobRunning},
+		{ID: 2, TableID: 12, Type: model.ActionTruncateTable, State: model.JobNone},
+		{ID: 6, Type: model.ActionDropSchema, State: model.JobNone, Args: []interface{}{[]int64{11}}},
+	}
+	for _, job := range jobs {
+		err = t.EnQueueBgJob(job)
+		c.Assert(err, IsNil)
+	}
+	historyJobs := []*model.Job{
+		{ID: 3, TableID: 13, Type: model.ActionDropTable, State: model.JobDone},
+		{ID: 4, Type: model.ActionDropSchema, State: model.JobDone, Args: []interface{}{nil, nil}},
+		{ID: 5, TableID: 13, Type: model.ActionDropTable, State: model.JobCancelled},
+	}
+	for _, job := range historyJobs {
+		err = t.AddHistoryBgJob(job)
+		c.Assert(err, IsNil)
+	}
+	// The done background job of dropping the schema gets the IDs of the tables from the history DDL job.
+	err = t.AddHistoryDDLJob(&model.Job{ID: 4, Type: model.ActionDropSchema, State: model.JobDone,
+		Args: []interface{}{1, &model.DBInfo{ID: 2}, []int64{14}}})
+	c.Assert(err, IsNil)
+
+	all, nextID, err := GetDeleteRanges(txn, 0, 10)
+	c.Assert(err, IsNil)
+	c.Assert(nextID, Equals, int64(0))
+	// Skip the background job added by TestGetBgDDLInfo.
+	var ranges []*DeleteRange
+	for _, r := range all {
+		if r.Job.ID > 0 {
+			ranges = append(ranges, r)
+		}
+	}
+	c.Assert(ranges, HasLen, 5)
+	expected := []struct {
+		jobID   int64
+		tableID int64
+		done    bool
+		hasData bool
+		orphan  bool
+	}{
+		// The ranges of the jobs in the queue aren't orphans, the jobs may be running.
+		{1, 11, false, true, false},
+		{2, 12, false, false, false},
+		{6, 11, false, true, false},
+		{3, 13, true, true, true},
+		{4, 14, true, false, false},
+	}
+	for i, r := range ranges {
+		e := expected[i]
+		c.Assert(r.Job.ID, Equals, e.jobID)
+		c.Assert(r.TableID, Equals, e.tableID)
+		c.Assert(r.Done, Equals, e.done)
+		c.Assert(r.HasData, Equals, e.hasData)
+		c.Assert(r.IsOrphan(), Equals, e.orphan)
+	}
+
+	// The history is read in batches, the jobs in the queue are only in the first batch.
+	batch, nextID, err := GetDeleteRanges(txn, 0, 1)
+	c.Assert(err, IsNil)
+	c.Assert(nextID, Equals, int64(4))
+	c.Assert(batch, HasLen, len(all)-1)
+	batch, nextID, err = GetDeleteRanges(txn, nextID, 1)
+	c.Assert(err, IsNil)
+	c.Assert(nextID, Equals, int64(5))
+	c.Assert(batch, HasLen, 1)
+	c.Assert(batch[0].TableID, Equals, int64(14))
+	// The cancelled job fills the batch but it deletes no range.
+	batch, nextID, err = GetDeleteRanges(txn, nextID, 1)
+	c.Assert(err, IsNil)
+	c.Assert(nextID, Equals, int64(6))
+	c.Assert(batch, HasLen, 0)
+	batch, nextID, err = GetDeleteRanges(txn, nextID, 1)
+	c.Assert(err, IsNil)
+	c.Assert(nextID, Equals, int64(0))
+	c.Assert(batch, HasLen, 0)
+}
+
 func (s *testSuite) TestScan(c *C) {
 	defer testleak.AfterTest(c)()
 	alloc := autoid.NewAllocator(s.store, s.dbInfo.ID)
@@ -405,6 +495,31 @@ func (s *testSuite) testIndex(c *C, tb table.Table, idx table.Index) {
 	c.Assert(err, NotNil)
 	diffMsg = newDiffRetError("index", nil, record1)
 	c.Assert(err.Error(), DeepEquals, diffMsg)
+
+	// set data to:
+	// index     data (handle, data): (1, 10), (2, 20), (3, 30), (6, 60)
+	// table     data (handle, data): (1, 10), (2, 21), (3, 30), (4, 40), (5, 30)
+	_, err = idx.Create(txn, types.MakeDatums(int64(60)), 6)
+	c.Assert(err, IsNil)
+	key = tablecodec.EncodeRowKey(tb.Meta().ID, codec.EncodeInt(nil, 2))
+	setColValue(c, txn, key, types.NewDatum(int64(21)))
+	err = txn.Commit()
+	c.Assert(err, IsNil)
+
+	ver, err := s.store.CurrentVersion()
+	c.Assert(err, IsNil)
+	snap, err := s.store.GetSnapshot(ver)
+	c.Assert(err, IsNil)
+	result, err := CheckIndex(snap, tb, idx, 0)
+	c.Assert(err, IsNil)
+	c.Assert(result, HasLen, 4)
+	c.Assert(result[0].String(), Equals, "handle 2 mismatched index entry, index:(20) != record:(21)")
+	c.Assert(*result[1], DeepEquals, Inconsistency{Kind: DanglingIndex, Handle: 6, Index: types.MakeDatums(int64(60))})
+	c.Assert(*result[2], DeepEquals, Inconsistency{Kind: MissingIndex, Handle: 4, Record: types.MakeDatums(int64(40))})
+	c.Assert(result[3].String(), Equals, "handle 5 missing index entry, index:<nil> != record:(30)")
+	result, err = CheckIndex(snap, tb, idx, 2)
+	c.Assert(err, IsNil)
+	c.Assert(result, HasLen, 2)
 }
 
 func setColValue(c *C, txn kv.Transaction, key kv.Key, v types.Datum) {
@@ -32,6 +32,7 @@ const (
 	codeInvalidTxn                                = 8
 	codeNotCommitted                              = 9
 	codeNotImplemented                            = 10
+	codeQueryInterrupted                          = 11
 
 	codeKeyExists = 1062
 )
@@ -63,11 +64,14 @@ var (
 	ErrKeyExists = terror.ClassKV.New(codeKeyExists, "key already exist")
 	// ErrNotImplemented returns when a function is not implemented yet.
 	ErrNotImplemented = terror.ClassKV.New(codeNotImplemented, "not implemented")
+	// ErrQueryInterrupted returns when the statement is interrupted by KILL QUERY or KILL CONNECTION.
+	ErrQueryInterrupted = terror.ClassKV.New(codeQueryInterrupted, "Query execution was interrupted")
 )
 
 func init() {
 	kvMySQLErrCodes := map[terror.ErrCode]uint16{
-		codeKeyExists: mysql.ErrDupEntry,
+		codeKeyExists:        mysql.ErrDupEntry,
+		codeQueryInterrupted: mysql.ErrQueryInterrupted,
 	}
 	terror.ErrClassToMySQLCodes[terror.ClassKV] = kvMySQLErrCodes
 }
@@ -15,6 +15,8 @@ package kv
 
 import (
 	"io"
+	"sync/atomic"
+	"time"
 )
 
 // Transaction options
@@ -71,6 +73,9 @@ type MemBuffer RetrieverMutator
 // This is not thread safe.
 type Transaction interface {
 	RetrieverMutator
+	// BatchGet gets a batch of values, the uncommitted changes of the transaction are read too.
+	// The keys which don't exist are absent from the result.
+	BatchGet(keys []Key) (map[string][]byte, error)
 	// Commit commits the transaction operations to KV store.
 	Commit() error
 	// Rollback undoes the transaction operations to KV store.
@@ -99,10 +104,43 @@ type Client interface {
 	SupportRequestType(reqType, subType int64) bool
 }
 
+// LoadReporter is implemented by the clients which can report the recent load of the storage,
+// so the background jobs can back off when the storage is busy.
+type LoadReporter interface {
+	// Load returns the moving average latency of the recent coprocessor requests,
+	// and the number of coprocessor requests in flight.
+	Load() (latency time.Duration, pending int64)
+}
+
+// RegionSplitter is implemented by the storages which split the data into regions by the keys,
+// so the background jobs can process the regions of a key range in parallel.
+type RegionSplitter interface {
+	// SplitKeys returns the start keys of the regions in the key range [startKey, endKey),
+	// except the region which contains startKey, in ascending order.
+	SplitKeys(startKey, endKey Key) ([]Key, error)
+}
+
+// KeyVersion is a committed version of a key.
+type KeyVersion struct {
+	Version Version
+	// Value is nil if the key is deleted in the version.
+	Value []byte
+}
+
+// HistoryReader is implemented by the storages which can read the history versions of a key,
+// so the changes of a row can be audited. The versions older than the GC safe point may be collected already.
+type HistoryReader interface {
+	// GetHistory returns the versions of the key committed no later than ver, the latest version first.
+	GetHistory(key Key, ver Version) ([]KeyVersion, error)
+}
+
 // ReqTypes.
 const (
 	ReqTypeSelect = 101
 	ReqTypeIndex  = 102
+	// ReqTypeHistory is supported if the storage of the client can read the history versions of a key, see
+	// HistoryReader.
+	ReqTypeHistory = 103
 
 	ReqSubTypeBasic   = 0
 	ReqSubTypeDesc    = 10000
@@ -125,6 +163,14 @@ type Request struct {
 	// ResponseIterator.Next is called. If concurrency is greater than 1, the request will be
 	// sent to multiple storage units concurrently.
 	Concurrency int
+	// Killed is the kill signal of the session, the request is interrupted with ErrQueryInterrupted
+	// once it's set to 1. It's accessed atomically, a nil Killed never interrupts the request.
+	Killed *uint32
+}
+
+// IsKilled returns whether the request is interrupted by KILL QUERY or KILL CONNECTION.
+func (req *Request) IsKilled() bool {
+	return req.Killed != nil && atomic.LoadUint32(req.Killed) == 1
 }
 
 // Response represents the response returned from KV layer.
@@ -23,6 +23,8 @@ import (
 // Also, it provides some transaction related utilities.
 type UnionStore interface {
 	MemBuffer
+	// BatchGet gets a batch of values from the buffer, then the missing ones from the snapshot.
+	BatchGet(keys []Key) (map[string][]byte, error)
 	// CheckLazyConditionPairs loads all lazy values from store then checks if all values are matched.
 	// Lazy condition pairs should be checked before transaction commit.
 	CheckLazyConditionPairs() error
@@ -163,6 +165,37 @@ func (us *unionStore) Get(k Key) ([]byte, error) {
 	return v, nil
 }
 
+// BatchGet implements the UnionStore BatchGet interface.
+func (us *unionStore) BatchGet(keys []Key) (map[string][]byte, error) {
+	m := make(map[string][]byte, len(keys))
+	var missing []Key
+	for _, k := range keys {
+		v, err := us.MemBuffer.Get(k)
+		if IsErrNotFound(err) {
+			missing = append(missing, k)
+			continue
+		}
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		// An empty value in the buffer means the key is deleted.
+		if len(v) > 0 {
+			m[string(k)] = v
+		}
+	}
+	if len(missing) == 0 {
+		return m, nil
+	}
+	values, err := us.snapshot.BatchGet(missing)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	for k, v := range values {
+		m[k] = v
+	}
+	return m, nil
+}
+
 // markLazyConditionPair marks a kv pair for later check.
 // If condition not match, should return e as error.
 func (us *unionStore) markLazyConditionPair(k Key, v []byte, e error) {
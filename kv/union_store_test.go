@@ -57,6 +57,24 @@ func (s *testUnionStoreSuite) TestDelete(c *C) {
 	c.Assert(v, BytesEquals, []byte("2"))
 }
 
+func (s *testUnionStoreSuite) TestBatchGet(c *C) {
+	defer testleak.AfterTest(c)()
+	s.store.Set([]byte("1"), []byte("1"))
+	s.store.Set([]byte("2"), []byte("2"))
+	s.store.Set([]byte("3"), []byte("3"))
+	s.us.Set([]byte("2"), []byte("x"))
+	s.us.Delete([]byte("3"))
+	s.us.Set([]byte("4"), []byte("4"))
+
+	m, err := s.us.BatchGet([]Key{Key("1"), Key("2"), Key("3"), Key("4"), Key("5")})
+	c.Assert(err, IsNil)
+	c.Assert(m, DeepEquals, map[string][]byte{"1": []byte("1"), "2": []byte("x"), "4": []byte("4")})
+
+	m, err = s.us.BatchGet([]Key{Key("2"), Key("4")})
+	c.Assert(err, IsNil)
+	c.Assert(m, HasLen, 2)
+}
+
 func (s *testUnionStoreSuite) TestSeek(c *C) {
 	defer testleak.AfterTest(c)()
 	s.store.Set([]byte("1"), []byte("1"))
@@ -62,7 +62,9 @@ var (
 	mTableIDPrefix    = "TID"
 	mBootstrapKey     = []byte("BootstrapKey")
 	mTableStatsPrefix = "TStats"
+	mAnalyzePrefix    = "TAnalyze"
 	mSchemaDiffPrefix = "Diff"
+	mPlanBaselines    = []byte("PlanBaselines")
 )
 
 var (
@@ -428,6 +430,8 @@ func (m *Meta) GetTable(dbID int64, tableID int64) (*model.TableInfo, error) {
 //	DDLJobList: list jobs
 //	DDLJobHistory: hash
 //	DDLJobReorg: hash
+//	DDLJobReorgProgress: hash
+//	DDLJobReorgRanges:jobID: hash
 //
 // for multi DDL workers, only one can become the owner
 // to operate DDL jobs, and dispatch them to MR Jobs.
@@ -437,6 +441,9 @@ var (
 	mDDLJobListKey    = []byte("DDLJobList")
 	mDDLJobHistoryKey = []byte("DDLJobHistory")
 	mDDLJobReorgKey   = []byte("DDLJobReorg")
+
+	mDDLJobReorgProgressKey  = []byte("DDLJobReorgProgress")
+	mDDLJobReorgRangesPrefix = "DDLJobReorgRanges"
 )
 
 func (m *Meta) getJobOwner(key []byte) (*model.Owner, error) {
@@ -524,6 +531,27 @@ func (m *Meta) updateDDLJob(index int64, job *model.Job, key []byte) error {
 	return m.txn.LSet(key, index, b)
 }
 
+// GetAllDDLJobs returns all the DDL jobs in the queue.
+func (m *Meta) GetAllDDLJobs() ([]*model.Job, error) {
+	return m.getAllDDLJobs(mDDLJobListKey)
+}
+
+func (m *Meta) getAllDDLJobs(key []byte) ([]*model.Job, error) {
+	n, err := m.txn.LLen(key)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	jobs := make([]*model.Job, 0, n)
+	for i := int64(0); i < n; i++ {
+		job, err := m.getDDLJob(key, i)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
 // UpdateDDLJob updates the DDL job with index.
 func (m *Meta) UpdateDDLJob(index int64, job *model.Job) error {
 	return m.updateDDLJob(index, job, mDDLJobListKey)
@@ -572,7 +600,11 @@ func (m *Meta) GetHistoryDDLJob(id int64) (*model.Job, error) {
 
 // GetAllHistoryDDLJobs gets all history DDL jobs.
 func (m *Meta) GetAllHistoryDDLJobs() ([]*model.Job, error) {
-	pairs, err := m.txn.HGetAll(mDDLJobHistoryKey)
+	return m.getAllHistoryDDLJobs(mDDLJobHistoryKey)
+}
+
+func (m *Meta) getAllHistoryDDLJobs(key []byte) ([]*model.Job, error) {
+	pairs, err := m.txn.HGetAll(key)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -626,18 +658,81 @@ func (m *Meta) UpdateDDLReorgHandle(job *model.Job, handle int64) error {
 	return errors.Trace(err)
 }
 
-// RemoveDDLReorgHandle removes the job reorganization handle.
+// RemoveDDLReorgHandle removes the job reorganization handle, progress and ranges.
 func (m *Meta) RemoveDDLReorgHandle(job *model.Job) error {
 	err := m.txn.HDel(mDDLJobReorgKey, m.jobIDKey(job.ID))
+	if err != nil {
+		return errors.Trace(err)
+	}
+	err = m.txn.HDel(mDDLJobReorgProgressKey, m.jobIDKey(job.ID))
+	if err != nil {
+		return errors.Trace(err)
+	}
+	err = m.txn.HClear(m.reorgRangesKey(job.ID))
+	return errors.Trace(err)
+}
+
+func (m *Meta) reorgRangesKey(jobID int64) []byte {
+	return []byte(fmt.Sprintf("%s:%d", mDDLJobReorgRangesPrefix, jobID))
+}
+
+// UpdateDDLReorgRange saves the idx-th handle range of the job reorganization with its checkpoint.
+func (m *Meta) UpdateDDLReorgRange(job *model.Job, idx int, r *model.ReorgRange) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	// The big endian encoded index keeps the ranges in order.
+	err = m.txn.HSet(m.reorgRangesKey(job.ID), m.jobIDKey(int64(idx)), data)
 	return errors.Trace(err)
 }
 
+// GetDDLReorgRanges gets the handle ranges of the job reorganization in order,
+// it returns nil if the ranges don't exist.
+func (m *Meta) GetDDLReorgRanges(job *model.Job) ([]*model.ReorgRange, error) {
+	pairs, err := m.txn.HGetAll(m.reorgRangesKey(job.ID))
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	var ranges []*model.ReorgRange
+	for _, pair := range pairs {
+		r := &model.ReorgRange{}
+		if err = json.Unmarshal(pair.Value, r); err != nil {
+			return nil, errors.Trace(err)
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges, nil
+}
+
 // GetDDLReorgHandle gets the latest processed handle.
 func (m *Meta) GetDDLReorgHandle(job *model.Job) (int64, error) {
 	value, err := m.txn.HGetInt64(mDDLJobReorgKey, m.jobIDKey(job.ID))
 	return value, errors.Trace(err)
 }
 
+// UpdateDDLReorgProgress saves the job reorganization progress,
+// it should be saved with the reorganization handle in the same transaction.
+func (m *Meta) UpdateDDLReorgProgress(job *model.Job, progress *model.ReorgProgress) error {
+	data, err := json.Marshal(progress)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	err = m.txn.HSet(mDDLJobReorgProgressKey, m.jobIDKey(job.ID), data)
+	return errors.Trace(err)
+}
+
+// GetDDLReorgProgress gets the job reorganization progress, it returns nil if the progress doesn't exist.
+func (m *Meta) GetDDLReorgProgress(job *model.Job) (*model.ReorgProgress, error) {
+	value, err := m.txn.HGet(mDDLJobReorgProgressKey, m.jobIDKey(job.ID))
+	if err != nil || value == nil {
+		return nil, errors.Trace(err)
+	}
+	progress := &model.ReorgProgress{}
+	err = json.Unmarshal(value, progress)
+	return progress, errors.Trace(err)
+}
+
 // DDL background job structure
 //	BgJobOnwer: []byte
 //	BgJobList: list jobs
@@ -685,6 +780,33 @@ func (m *Meta) GetHistoryBgJob(id int64) (*model.Job, error) {
 	return m.getHistoryDDLJob(mBgJobHistoryKey, id)
 }
 
+// GetAllBgJobs gets all background jobs in the queue.
+func (m *Meta) GetAllBgJobs() ([]*model.Job, error) {
+	return m.getAllDDLJobs(mBgJobListKey)
+}
+
+// GetAllHistoryBgJobs gets all history background jobs.
+func (m *Meta) GetAllHistoryBgJobs() ([]*model.Job, error) {
+	return m.getAllHistoryDDLJobs(mBgJobHistoryKey)
+}
+
+// GetHistoryBgJobs gets at most limit history background jobs from the job ID startID, in the order of the IDs.
+func (m *Meta) GetHistoryBgJobs(startID int64, limit int) ([]*model.Job, error) {
+	pairs, err := m.txn.HGetRange(mBgJobHistoryKey, m.jobIDKey(startID), limit)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	jobs := make([]*model.Job, 0, len(pairs))
+	for _, pair := range pairs {
+		job := &model.Job{}
+		if err = job.Decode(pair.Value); err != nil {
+			return nil, errors.Trace(err)
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
 // DeQueueBgJob pops a background job from the list.
 func (m *Meta) DeQueueBgJob() (*model.Job, error) {
 	return m.deQueueDDLJob(mBgJobListKey)
@@ -736,6 +858,35 @@ func (m *Meta) GetTableStats(tableID int64) (*statistics.TablePB, error) {
 	return tpb, nil
 }
 
+func (m *Meta) analyzeKey(tableID int64) []byte {
+	return []byte(fmt.Sprintf("%s:%d", mAnalyzePrefix, tableID))
+}
+
+// SetAnalyzeProgress saves the progress of the interrupted ANALYZE of a table.
+func (m *Meta) SetAnalyzeProgress(tableID int64, p *model.AnalyzeProgress) error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(m.txn.Set(m.analyzeKey(tableID), data))
+}
+
+// GetAnalyzeProgress gets the progress of the interrupted ANALYZE of a table, it returns nil if there is none.
+func (m *Meta) GetAnalyzeProgress(tableID int64) (*model.AnalyzeProgress, error) {
+	data, err := m.txn.Get(m.analyzeKey(tableID))
+	if err != nil || len(data) == 0 {
+		return nil, errors.Trace(err)
+	}
+	p := &model.AnalyzeProgress{}
+	err = json.Unmarshal(data, p)
+	return p, errors.Trace(err)
+}
+
+// RemoveAnalyzeProgress removes the progress of the interrupted ANALYZE of a table.
+func (m *Meta) RemoveAnalyzeProgress(tableID int64) error {
+	return errors.Trace(m.txn.Clear(m.analyzeKey(tableID)))
+}
+
 func (m *Meta) schemaDiffKey(schemaVersion int64) []byte {
 	return []byte(fmt.Sprintf("%s:%d", mSchemaDiffPrefix, schemaVersion))
 }
@@ -766,6 +917,38 @@ func (m *Meta) SetSchemaDiff(schemaVersion int64, diff *model.SchemaDiff) error
 	return errors.Trace(err)
 }
 
+// SetPlanBaseline sets the plan baseline of a statement digest.
+func (m *Meta) SetPlanBaseline(baseline *model.PlanBaseline) error {
+	data, err := json.Marshal(baseline)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(m.txn.HSet(mPlanBaselines, []byte(baseline.Digest), data))
+}
+
+// DropPlanBaseline drops the plan baseline of a statement digest.
+func (m *Meta) DropPlanBaseline(digest string) error {
+	return errors.Trace(m.txn.HDel(mPlanBaselines, []byte(digest)))
+}
+
+// ListPlanBaselines lists all the plan baselines.
+func (m *Meta) ListPlanBaselines() ([]*model.PlanBaseline, error) {
+	res, err := m.txn.HGetAll(mPlanBaselines)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	baselines := make([]*model.PlanBaseline, 0, len(res))
+	for _, r := range res {
+		baseline := &model.PlanBaseline{}
+		err = json.Unmarshal(r.Value, baseline)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		baselines = append(baselines, baseline)
+	}
+	return baselines, nil
+}
+
 // meta error codes.
 const (
 	codeInvalidTableKey terror.ErrCode = 1
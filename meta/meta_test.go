@@ -14,6 +14,7 @@
 package meta_test
 
 import (
+	"math"
 	"testing"
 	"time"
 
@@ -181,6 +182,39 @@ func (s *testSuite) TestMeta(c *C) {
 	readDiff, err := t.GetSchemaDiff(schemaDiff.Version)
 	c.Assert(readDiff, DeepEquals, schemaDiff)
 
+	// Test case for PlanBaseline.
+	baseline := &model.PlanBaseline{
+		Digest:     "d1",
+		SQL:        "select * from t where a = ?",
+		Hints:      "use_index(t, idx)",
+		Latency:    time.Millisecond,
+		Executions: 3,
+		UpdateTime: time.Date(2016, 10, 1, 0, 0, 0, 0, time.UTC),
+	}
+	err = t.SetPlanBaseline(baseline)
+	c.Assert(err, IsNil)
+	baselines, err := t.ListPlanBaselines()
+	c.Assert(err, IsNil)
+	c.Assert(baselines, DeepEquals, []*model.PlanBaseline{baseline})
+	err = t.DropPlanBaseline(baseline.Digest)
+	c.Assert(err, IsNil)
+	baselines, err = t.ListPlanBaselines()
+	c.Assert(err, IsNil)
+	c.Assert(baselines, HasLen, 0)
+
+	// Test case for AnalyzeProgress.
+	progress := &model.AnalyzeProgress{ColumnIDs: []int64{1, 2}, LastHandle: 10, Count: 10, Samples: [][]byte{{1}}}
+	err = t.SetAnalyzeProgress(1, progress)
+	c.Assert(err, IsNil)
+	readProgress, err := t.GetAnalyzeProgress(1)
+	c.Assert(err, IsNil)
+	c.Assert(readProgress, DeepEquals, progress)
+	err = t.RemoveAnalyzeProgress(1)
+	c.Assert(err, IsNil)
+	readProgress, err = t.GetAnalyzeProgress(1)
+	c.Assert(err, IsNil)
+	c.Assert(readProgress, IsNil)
+
 	err = txn.Commit()
 	c.Assert(err, IsNil)
 }
@@ -261,8 +295,45 @@ func (s *testSuite) TestDDL(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(h, Equals, int64(1))
 
+	progress, err := t.GetDDLReorgProgress(job)
+	c.Assert(err, IsNil)
+	c.Assert(progress, IsNil)
+	progress = &model.ReorgProgress{RowCount: 10, StartHandle: 1, EndHandle: 100, Handle: 10, StartTS: 1, UpdateTS: 2}
+	err = t.UpdateDDLReorgProgress(job, progress)
+	c.Assert(err, IsNil)
+	p, err := t.GetDDLReorgProgress(job)
+	c.Assert(err, IsNil)
+	c.Assert(p, DeepEquals, progress)
+
+	ranges, err := t.GetDDLReorgRanges(job)
+	c.Assert(err, IsNil)
+	c.Assert(ranges, HasLen, 0)
+	r0 := &model.ReorgRange{StartHandle: 0, EndHandle: 255, Handle: 0}
+	r1 := &model.ReorgRange{StartHandle: 256, EndHandle: math.MaxInt64, Handle: 256}
+	// The ranges are returned in the order of the indices rather than the order of the updates.
+	err = t.UpdateDDLReorgRange(job, 256, r1)
+	c.Assert(err, IsNil)
+	err = t.UpdateDDLReorgRange(job, 0, r0)
+	c.Assert(err, IsNil)
+	r0.Handle, r0.RowCount, r0.Done = 256, 200, true
+	err = t.UpdateDDLReorgRange(job, 0, r0)
+	c.Assert(err, IsNil)
+	ranges, err = t.GetDDLReorgRanges(job)
+	c.Assert(err, IsNil)
+	c.Assert(ranges, DeepEquals, []*model.ReorgRange{r0, r1})
+
+	jobs, err := t.GetAllDDLJobs()
+	c.Assert(err, IsNil)
+	c.Assert(jobs, DeepEquals, []*model.Job{job})
+
 	err = t.RemoveDDLReorgHandle(job)
 	c.Assert(err, IsNil)
+	p, err = t.GetDDLReorgProgress(job)
+	c.Assert(err, IsNil)
+	c.Assert(p, IsNil)
+	ranges, err = t.GetDDLReorgRanges(job)
+	c.Assert(err, IsNil)
+	c.Assert(ranges, HasLen, 0)
 
 	v, err = t.DeQueueDDLJob()
 	c.Assert(err, IsNil)
@@ -305,6 +376,9 @@ func (s *testSuite) TestDDL(c *C) {
 	bgJob.ID = 2
 	err = t.UpdateBgJob(0, bgJob)
 	c.Assert(err, IsNil)
+	bgJobs, err := t.GetAllBgJobs()
+	c.Assert(err, IsNil)
+	c.Assert(bgJobs, DeepEquals, []*model.Job{bgJob})
 
 	v, err = t.DeQueueBgJob()
 	c.Assert(err, IsNil)
@@ -315,6 +389,9 @@ func (s *testSuite) TestDDL(c *C) {
 	v, err = t.GetHistoryBgJob(2)
 	c.Assert(err, IsNil)
 	c.Assert(v, DeepEquals, bgJob)
+	bgJobs, err = t.GetAllHistoryBgJobs()
+	c.Assert(err, IsNil)
+	c.Assert(bgJobs, DeepEquals, []*model.Job{bgJob})
 
 	err = txn.Commit()
 	c.Assert(err, IsNil)
@@ -17,6 +17,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/juju/errors"
 	"github.com/pingcap/tidb/terror"
@@ -40,6 +41,9 @@ const (
 	ActionDropForeignKey
 	ActionTruncateTable
 	ActionModifyColumn
+	ActionCreateView
+	ActionSetStatsNDV
+	ActionShadowCopy
 )
 
 func (action ActionType) String() string {
@@ -68,6 +72,12 @@ func (action ActionType) String() string {
 		return "truncate table"
 	case ActionModifyColumn:
 		return "modify column"
+	case ActionCreateView:
+		return "create view"
+	case ActionSetStatsNDV:
+		return "set stats ndv"
+	case ActionShadowCopy:
+		return "shadow copy"
 	default:
 		return "none"
 	}
@@ -202,6 +212,95 @@ func (s JobState) String() string {
 	}
 }
 
+// ReorgProgress is the progress of a reorganization job. It is saved with the reorganization handle
+// in the same transaction, so it's consistent with the checkpoint the job resumes from.
+type ReorgProgress struct {
+	// The number of rows that are processed.
+	RowCount int64 `json:"row_count"`
+	// StartHandle is the first handle to process.
+	StartHandle int64 `json:"start_handle"`
+	// EndHandle is the upper bound of the handles to process, it's estimated by the auto ID of the table.
+	EndHandle int64 `json:"end_handle"`
+	// Handle is the last processed handle.
+	Handle int64 `json:"handle"`
+	// unix nano seconds
+	StartTS  int64 `json:"start_ts"`
+	UpdateTS int64 `json:"update_ts"`
+}
+
+// Speed returns the number of rows processed per second.
+func (p *ReorgProgress) Speed() float64 {
+	elapsed := time.Duration(p.UpdateTS - p.StartTS).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(p.RowCount) / elapsed
+}
+
+// ETA estimates the remaining time by the processed fraction of the handle range.
+// It returns false if the remaining time can't be estimated yet.
+func (p *ReorgProgress) ETA() (time.Duration, bool) {
+	total := p.EndHandle - p.StartHandle
+	done := p.Handle - p.StartHandle
+	if total <= 0 || done <= 0 || p.UpdateTS <= p.StartTS {
+		return 0, false
+	}
+	if done >= total {
+		return 0, true
+	}
+	elapsed := float64(p.UpdateTS - p.StartTS)
+	return time.Duration(elapsed * float64(total-done) / float64(done)), true
+}
+
+// String implements fmt.Stringer interface.
+func (p *ReorgProgress) String() string {
+	return fmt.Sprintf("RowCount:%d, Handle:%d, HandleRange:[%d,%d]", p.RowCount, p.Handle, p.StartHandle, p.EndHandle)
+}
+
+// MergeRanges updates the progress by the handle ranges which are processed in parallel,
+// the processed handles of all the ranges are counted from the start handle.
+func (p *ReorgProgress) MergeRanges(ranges []*ReorgRange) {
+	if len(ranges) == 0 {
+		return
+	}
+	p.RowCount = 0
+	var done int64
+	for _, r := range ranges {
+		p.RowCount += r.RowCount
+		done += r.Handle - r.StartHandle
+		if r.UpdateTS > p.UpdateTS {
+			p.UpdateTS = r.UpdateTS
+		}
+	}
+	p.Handle = p.StartHandle + done
+	if p.Handle > p.EndHandle {
+		p.Handle = p.EndHandle
+	}
+}
+
+// ReorgRange is a handle range of a reorganization job, which is processed by a worker in parallel with the
+// other ranges. The checkpoint of a range is saved independently of the other ranges.
+type ReorgRange struct {
+	// StartHandle is the first handle of the range.
+	StartHandle int64 `json:"start_handle"`
+	// EndHandle is the last handle of the range, it's included.
+	EndHandle int64 `json:"end_handle"`
+	// Handle is the next handle to process.
+	Handle int64 `json:"handle"`
+	// The number of rows that are processed.
+	RowCount int64 `json:"row_count"`
+	// Done is true if all the handles of the range are processed.
+	Done bool `json:"done"`
+	// unix nano seconds
+	UpdateTS int64 `json:"update_ts"`
+}
+
+// String implements fmt.Stringer interface.
+func (r *ReorgRange) String() string {
+	return fmt.Sprintf("HandleRange:[%d,%d], Handle:%d, RowCount:%d, Done:%v", r.StartHandle, r.EndHandle, r.Handle,
+		r.RowCount, r.Done)
+}
+
 // Owner is for DDL Owner.
 type Owner struct {
 	OwnerID string `json:"owner_id"`
@@ -15,7 +15,10 @@ package model
 
 import (
 	"strings"
+	"sync/atomic"
+	"time"
 
+	"github.com/juju/errors"
 	"github.com/pingcap/tidb/util/types"
 )
 
@@ -65,6 +68,9 @@ type ColumnInfo struct {
 	types.FieldType `json:"type"`
 	State           SchemaState `json:"state"`
 	Comment         string      `json:"comment"`
+	// StatsNDV is the expected number of distinct values of the column declared by the STATS_NDV table option,
+	// 0 means it isn't declared.
+	StatsNDV int64 `json:"stats_ndv"`
 }
 
 // Clone clones ColumnInfo.
@@ -87,6 +93,11 @@ type TableInfo struct {
 	PKIsHandle  bool          `json:"pk_is_handle"`
 	Comment     string        `json:"comment"`
 	AutoIncID   int64         `json:"auto_inc_id"`
+	// View is not nil if the table is a view.
+	View *ViewInfo `json:"view"`
+	// Shadow is the copy of the table with the altered columns, which is built by a shadow copy DDL job.
+	// It has its own table ID, and its state tells how the rows written to the table are written to it.
+	Shadow *TableInfo `json:"shadow"`
 }
 
 // Clone clones TableInfo.
@@ -108,9 +119,57 @@ func (t *TableInfo) Clone() *TableInfo {
 		nt.ForeignKeys[i] = t.ForeignKeys[i].Clone()
 	}
 
+	if t.View != nil {
+		nt.View = t.View.Clone()
+	}
+
+	if t.Shadow != nil {
+		nt.Shadow = t.Shadow.Clone()
+	}
+
 	return &nt
 }
 
+// IsView checks if the table is a view.
+func (t *TableInfo) IsView() bool {
+	return t.View != nil
+}
+
+// ViewInfo provides meta data describing a view. The columns of the view are stored as the columns of the table,
+// and the select statement is parsed and expanded to a derived table when the view is referenced.
+type ViewInfo struct {
+	// SelectStmt is the text of the select statement that defines the view.
+	SelectStmt string `json:"view_select"`
+	// Definer is the user who created the view.
+	Definer string `json:"view_definer"`
+	// Security tells whose privileges read the tables of the view.
+	Security ViewSecurity `json:"view_security"`
+}
+
+// ViewSecurity is the SQL SECURITY characteristic of a view.
+type ViewSecurity byte
+
+const (
+	// SecurityDefiner means the tables of the view are read with the privileges of the definer of the view.
+	SecurityDefiner ViewSecurity = iota
+	// SecurityInvoker means the tables of the view are read with the privileges of the user reading the view.
+	SecurityInvoker
+)
+
+// String implements fmt.Stringer interface.
+func (s ViewSecurity) String() string {
+	if s == SecurityInvoker {
+		return "INVOKER"
+	}
+	return "DEFINER"
+}
+
+// Clone clones ViewInfo.
+func (v *ViewInfo) Clone() *ViewInfo {
+	nv := *v
+	return &nv
+}
+
 // IndexColumn provides index column info.
 type IndexColumn struct {
 	Name   CIStr `json:"name"`   // Index name
@@ -157,6 +216,9 @@ type IndexInfo struct {
 	State   SchemaState    `json:"state"`
 	Comment string         `json:"comment"`    // Comment
 	Tp      IndexType      `json:"index_type"` // Index type: Btree or Hash
+	// StatsNDV is the expected number of distinct values of the index declared by the STATS_NDV table option,
+	// 0 means it isn't declared.
+	StatsNDV int64 `json:"stats_ndv"`
 }
 
 // Clone clones IndexInfo.
@@ -223,6 +285,19 @@ func (db *DBInfo) Clone() *DBInfo {
 	return &newInfo
 }
 
+// PlanBaseline is the best known plan of a statement, the statement falls back to it if a new plan regresses.
+type PlanBaseline struct {
+	// Digest is the digest of the normalized statement, SQL is the normalized statement.
+	Digest string `json:"digest"`
+	SQL    string `json:"sql"`
+	// Hints are the optimizer hints which reproduce the plan, e.g. "use_index(t, idx), hash_join(t1)".
+	Hints string `json:"hints"`
+	// Latency is the average execution time of the plan in Executions executions.
+	Latency    time.Duration `json:"latency"`
+	Executions uint64        `json:"executions"`
+	UpdateTime time.Time     `json:"update_time"`
+}
+
 // CIStr is case insensitive string.
 type CIStr struct {
 	O string `json:"O"` // Original string.
@@ -240,3 +315,60 @@ func NewCIStr(s string) (cs CIStr) {
 	cs.L = strings.ToLower(s)
 	return
 }
+
+// The modes of lower_case_table_names, which decide how the database names, the table names and the table aliases
+// are stored and compared.
+const (
+	// CaseSensitiveNames stores the names as given and compares them case sensitively.
+	CaseSensitiveNames = 0
+	// LowerCaseNames stores the names in lowercase and compares them case insensitively.
+	LowerCaseNames = 1
+	// CaseInsensitiveNames stores the names as given and compares them case insensitively.
+	CaseInsensitiveNames = 2
+)
+
+var lowerCaseTableNames int32 = CaseInsensitiveNames
+
+// SetLowerCaseTableNames sets the lower_case_table_names mode of the server. It should be set before any database
+// is created, the names stored in another mode may not be found.
+func SetLowerCaseTableNames(mode int) error {
+	if mode < CaseSensitiveNames || mode > CaseInsensitiveNames {
+		return errors.Errorf("invalid lower_case_table_names %d, it should be 0, 1 or 2", mode)
+	}
+	atomic.StoreInt32(&lowerCaseTableNames, int32(mode))
+	return nil
+}
+
+// GetLowerCaseTableNames gets the lower_case_table_names mode of the server.
+func GetLowerCaseTableNames() int {
+	return int(atomic.LoadInt32(&lowerCaseTableNames))
+}
+
+// NewTableCIStr creates a new CIStr for a database name, a table name or a table alias by the lower_case_table_names
+// mode. Its L is the same as O if the names are case sensitive, and both are lowercase if the names are stored in
+// lowercase.
+func NewTableCIStr(s string) (cs CIStr) {
+	switch GetLowerCaseTableNames() {
+	case CaseSensitiveNames:
+		cs.O, cs.L = s, s
+	case LowerCaseNames:
+		cs.L = strings.ToLower(s)
+		cs.O = cs.L
+	default:
+		cs = NewCIStr(s)
+	}
+	return
+}
+
+// AnalyzeProgress is the progress of an interrupted ANALYZE TABLE, which is saved so that the next ANALYZE of the
+// table resumes from it.
+type AnalyzeProgress struct {
+	// ColumnIDs are the IDs of the analyzed columns, the progress is discarded once they are changed.
+	ColumnIDs []int64 `json:"column_ids"`
+	// LastHandle is the handle of the last row read.
+	LastHandle int64 `json:"last_handle"`
+	// Count is the number of rows read.
+	Count int64 `json:"count"`
+	// Samples are the sampled rows, encoded as the records of the table.
+	Samples [][]byte `json:"samples"`
+}
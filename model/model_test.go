@@ -14,7 +14,9 @@
 package model
 
 import (
+	"math"
 	"testing"
+	"time"
 
 	. "github.com/pingcap/check"
 	"github.com/pingcap/tidb/util/types"
@@ -37,6 +39,24 @@ func (*testSuite) TestT(c *C) {
 	c.Assert(abc.String(), Equals, "aBC")
 }
 
+func (*testSuite) TestTableCIStr(c *C) {
+	defer SetLowerCaseTableNames(CaseInsensitiveNames)
+	c.Assert(GetLowerCaseTableNames(), Equals, CaseInsensitiveNames)
+	c.Assert(NewTableCIStr("aBC"), Equals, NewCIStr("aBC"))
+
+	err := SetLowerCaseTableNames(CaseSensitiveNames)
+	c.Assert(err, IsNil)
+	c.Assert(NewTableCIStr("aBC"), Equals, CIStr{O: "aBC", L: "aBC"})
+
+	err = SetLowerCaseTableNames(LowerCaseNames)
+	c.Assert(err, IsNil)
+	c.Assert(NewTableCIStr("aBC"), Equals, CIStr{O: "abc", L: "abc"})
+
+	c.Assert(SetLowerCaseTableNames(3), NotNil)
+	c.Assert(SetLowerCaseTableNames(-1), NotNil)
+	c.Assert(GetLowerCaseTableNames(), Equals, LowerCaseNames)
+}
+
 func (*testSuite) TestClone(c *C) {
 	column := &ColumnInfo{
 		ID:           1,
@@ -111,6 +131,50 @@ func (*testSuite) TestJobCodec(c *C) {
 	c.Assert(job.IsRunning(), IsFalse)
 }
 
+func (*testSuite) TestReorgProgress(c *C) {
+	p := &ReorgProgress{StartHandle: 1, EndHandle: 101, Handle: 1}
+	c.Assert(p.Speed(), Equals, float64(0))
+	_, ok := p.ETA()
+	c.Assert(ok, IsFalse)
+
+	p.StartTS = int64(time.Second)
+	p.UpdateTS = int64(3 * time.Second)
+	p.Handle = 26
+	p.RowCount = 50
+	c.Assert(p.Speed(), Equals, float64(25))
+	eta, ok := p.ETA()
+	c.Assert(ok, IsTrue)
+	c.Assert(eta, Equals, 6*time.Second)
+	c.Assert(len(p.String()), Greater, 0)
+
+	p.Handle = 200
+	eta, ok = p.ETA()
+	c.Assert(ok, IsTrue)
+	c.Assert(eta, Equals, time.Duration(0))
+}
+
+func (*testSuite) TestReorgProgressMergeRanges(c *C) {
+	p := &ReorgProgress{StartHandle: 1, EndHandle: 101, Handle: 1, RowCount: 7, StartTS: int64(time.Second)}
+	p.MergeRanges(nil)
+	c.Assert(p.Handle, Equals, int64(1))
+	c.Assert(p.RowCount, Equals, int64(7))
+
+	ranges := []*ReorgRange{
+		{StartHandle: 1, EndHandle: 50, Handle: 11, RowCount: 10, UpdateTS: int64(2 * time.Second)},
+		{StartHandle: 51, EndHandle: math.MaxInt64, Handle: 66, RowCount: 15, UpdateTS: int64(3 * time.Second)},
+	}
+	p.MergeRanges(ranges)
+	c.Assert(p.Handle, Equals, int64(26))
+	c.Assert(p.RowCount, Equals, int64(25))
+	c.Assert(p.UpdateTS, Equals, int64(3*time.Second))
+	c.Assert(len(ranges[0].String()), Greater, 0)
+
+	ranges[1].Handle = 200
+	ranges[1].Done = true
+	p.MergeRanges(ranges)
+	c.Assert(p.Handle, Equals, int64(101))
+}
+
 func (testSuite) TestState(c *C) {
 	schemaTbl := []SchemaState{
 		StateDeleteOnly,
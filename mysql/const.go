@@ -46,6 +46,14 @@ const (
 	ServerPSOutParams              uint16 = 0x1000
 )
 
+// Cursor types of COM_STMT_EXECUTE.
+const (
+	CursorTypeNoCursor   byte = 0x00
+	CursorTypeReadOnly   byte = 0x01
+	CursorTypeForUpdate  byte = 0x02
+	CursorTypeScrollable byte = 0x04
+)
+
 // Identifier length limitations.
 const (
 	MaxTableNameLength    int = 64
@@ -143,6 +151,8 @@ const (
 	GlobalStatusTable = "GLOBAL_STATUS"
 	// TiDBTable is the table contains tidb info.
 	TiDBTable = "tidb"
+	// BindInfoTable is the table contains the SQL bindings.
+	BindInfoTable = "bind_info"
 )
 
 // PrivilegeType  privilege
@@ -174,6 +184,17 @@ const (
 	ExecutePriv
 	// IndexPriv is the privilege to create/drop index.
 	IndexPriv
+	// ProcessPriv is the privilege to see the statements of the other users and kill their connections.
+	ProcessPriv
+	// FilePriv is the privilege to read and write the files on the server.
+	FilePriv
+	// SuperPriv is the privilege to run the administrative operations, e.g. kill the connections of the other
+	// users or change the global settings of the server.
+	SuperPriv
+	// CreateViewPriv is the privilege to create views.
+	CreateViewPriv
+	// ShowViewPriv is the privilege to see the definitions of views, e.g. by SHOW CREATE VIEW or EXPLAIN.
+	ShowViewPriv
 	// AllPriv is the privilege for all actions.
 	AllPriv
 )
@@ -192,6 +213,11 @@ var Priv2UserCol = map[PrivilegeType]string{
 	AlterPriv:      "Alter_priv",
 	ExecutePriv:    "Execute_priv",
 	IndexPriv:      "Index_priv",
+	ProcessPriv:    "Process_priv",
+	FilePriv:       "File_priv",
+	SuperPriv:      "Super_priv",
+	CreateViewPriv: "Create_view_priv",
+	ShowViewPriv:   "Show_view_priv",
 }
 
 // Col2PrivType is the privilege tables column name to privilege type.
@@ -208,10 +234,15 @@ var Col2PrivType = map[string]PrivilegeType{
 	"Alter_priv":       AlterPriv,
 	"Execute_priv":     ExecutePriv,
 	"Index_priv":       IndexPriv,
+	"Process_priv":     ProcessPriv,
+	"File_priv":        FilePriv,
+	"Super_priv":       SuperPriv,
+	"Create_view_priv": CreateViewPriv,
+	"Show_view_priv":   ShowViewPriv,
 }
 
 // AllGlobalPrivs is all the privileges in global scope.
-var AllGlobalPrivs = []PrivilegeType{SelectPriv, InsertPriv, UpdatePriv, DeletePriv, CreatePriv, DropPriv, GrantPriv, AlterPriv, ShowDBPriv, ExecutePriv, IndexPriv, CreateUserPriv}
+var AllGlobalPrivs = []PrivilegeType{SelectPriv, InsertPriv, UpdatePriv, DeletePriv, CreatePriv, DropPriv, GrantPriv, AlterPriv, ShowDBPriv, ExecutePriv, IndexPriv, CreateUserPriv, ProcessPriv, FilePriv, SuperPriv, CreateViewPriv, ShowViewPriv}
 
 // Priv2Str is the map for privilege to string.
 var Priv2Str = map[PrivilegeType]string{
@@ -227,6 +258,11 @@ var Priv2Str = map[PrivilegeType]string{
 	AlterPriv:      "Alter",
 	ExecutePriv:    "Execute",
 	IndexPriv:      "Index",
+	ProcessPriv:    "Process",
+	FilePriv:       "File",
+	SuperPriv:      "Super",
+	CreateViewPriv: "Create View",
+	ShowViewPriv:   "Show View",
 }
 
 // Priv2SetStr is the map for privilege to string.
@@ -258,7 +294,7 @@ var SetStr2Priv = map[string]PrivilegeType{
 }
 
 // AllDBPrivs is all the privileges in database scope.
-var AllDBPrivs = []PrivilegeType{SelectPriv, InsertPriv, UpdatePriv, DeletePriv, CreatePriv, DropPriv, GrantPriv, AlterPriv, ExecutePriv, IndexPriv}
+var AllDBPrivs = []PrivilegeType{SelectPriv, InsertPriv, UpdatePriv, DeletePriv, CreatePriv, DropPriv, GrantPriv, AlterPriv, ExecutePriv, IndexPriv, CreateViewPriv, ShowViewPriv}
 
 // AllTablePrivs is all the privileges in table scope.
 var AllTablePrivs = []PrivilegeType{SelectPriv, InsertPriv, UpdatePriv, DeletePriv, CreatePriv, DropPriv, GrantPriv, AlterPriv, IndexPriv}
@@ -246,4 +246,6 @@ var MySQLState = map[uint16]string{
 	ErrAlterOperationNotSupported:          "0A000",
 	ErrAlterOperationNotSupportedReason:    "0A000",
 	ErrDupUnknownInIndex:                   "23000",
+	ErrFieldInOrderNotSelect:               "HY000",
+	ErrMissingJSONTableValue:               "22035",
 }
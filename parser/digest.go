@@ -0,0 +1,76 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+)
+
+// Normalize returns the normalized text of a SQL statement, so the statements which only differ in the literals,
+// the comments, the spaces or the case of the words have the same text. The literals are replaced with "?", and
+// the other tokens are lowercased and separated by a single space, e.g. "SELECT * FROM t WHERE a = 1" is
+// normalized to "select * from t where a = ?".
+func Normalize(sql string) string {
+	s := NewScanner(sql)
+	var buf bytes.Buffer
+	for {
+		tok, pos, lit := s.scan()
+		if tok == 0 {
+			break
+		}
+		if buf.Len() > 0 {
+			buf.WriteByte(' ')
+		}
+		switch tok {
+		case intLit, floatLit, decLit, hexLit, bitLit, stringLit:
+			buf.WriteByte('?')
+			continue
+		}
+		if lit == "" && pos.Offset < s.r.pos().Offset {
+			// Some operators are scanned without the literal text.
+			lit = sql[pos.Offset:s.r.pos().Offset]
+		}
+		buf.WriteString(strings.ToLower(lit))
+	}
+	return strings.TrimSuffix(buf.String(), " ;")
+}
+
+// Digest returns the hex encoded SHA-256 hash of the normalized text of a SQL statement.
+func Digest(sql string) string {
+	_, digest := NormalizeDigest(sql)
+	return digest
+}
+
+// NormalizeDigest returns both the normalized text and the digest of a SQL statement, so the statement is
+// normalized only once.
+func NormalizeDigest(sql string) (normalized, digest string) {
+	normalized = Normalize(sql)
+	return normalized, fmt.Sprintf("%x", sha256.Sum256([]byte(normalized)))
+}
+
+// IsDigest checks if s looks like a statement digest returned by Digest.
+func IsDigest(s string) bool {
+	if len(s) != sha256.Size*2 {
+		return false
+	}
+	for _, c := range s {
+		if !isDigit(c) && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,154 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"strings"
+	"unicode"
+)
+
+// InsertValuesText is the text of an INSERT or REPLACE ... VALUES statement split around its rows, so the rows of a
+// huge statement can be parsed chunk by chunk instead of parsing the AST of all of them at once.
+type InsertValuesText struct {
+	// Header is the text before the rows, e.g. "INSERT INTO t (a, b) VALUES".
+	Header string
+	// Rows is the text of the rows, e.g. "(1, 2), (3, 4)".
+	Rows string
+	// Suffix is the text after the rows, e.g. "ON DUPLICATE KEY UPDATE b = 1".
+	Suffix string
+}
+
+// SplitInsertValues splits the text of an INSERT or REPLACE ... VALUES statement. ok is false if sql isn't a single
+// statement like that, or it can't be split by the tokens, e.g. it has MySQL-specific comments, the callers should
+// parse it as usual then.
+func SplitInsertValues(sql string) (text InsertValuesText, ok bool) {
+	if strings.Contains(sql, "/*!") {
+		return text, false
+	}
+	s := NewScanner(sql)
+	tok, _, lit := s.scan()
+	if tok != identifier || (!strings.EqualFold(lit, "insert") && !strings.EqualFold(lit, "replace")) {
+		return text, false
+	}
+	// Find the VALUES keyword out of the parentheses of the column list.
+	for depth := 0; ; {
+		tok, _, lit = s.scan()
+		if tok == 0 || tok == unicode.ReplacementChar || tok == ';' {
+			return text, false
+		}
+		if tok == '(' {
+			depth++
+		} else if tok == ')' {
+			depth--
+		} else if tok == identifier && depth == 0 {
+			if strings.EqualFold(lit, "values") || strings.EqualFold(lit, "value") {
+				break
+			}
+			if strings.EqualFold(lit, "select") || strings.EqualFold(lit, "set") {
+				return text, false
+			}
+		}
+	}
+	rowsStart := s.r.pos().Offset
+	text.Header = sql[:rowsStart]
+	// Each row is a parenthesized list, the rows are separated by commas.
+	for {
+		tok, _, _ = s.scan()
+		if tok != '(' {
+			return text, false
+		}
+		if !skipParentheses(s) {
+			return text, false
+		}
+		rowsEnd := s.r.pos().Offset
+		tok, pos, lit := s.scan()
+		if tok == ',' {
+			continue
+		}
+		text.Rows = sql[rowsStart:rowsEnd]
+		switch {
+		case tok == 0:
+			return text, true
+		case tok == ';':
+			if tok, _, _ = s.scan(); tok != 0 {
+				return text, false
+			}
+			return text, true
+		case tok == identifier && strings.EqualFold(lit, "on"):
+			suffixEnd, ok := scanStmtEnd(s)
+			if !ok {
+				return text, false
+			}
+			text.Suffix = sql[pos.Offset:suffixEnd]
+			return text, true
+		}
+		return text, false
+	}
+}
+
+// skipParentheses scans to the right parenthesis which matches the scanned left one.
+func skipParentheses(s *Scanner) bool {
+	depth := 1
+	for depth > 0 {
+		tok, _, _ := s.scan()
+		switch tok {
+		case 0, unicode.ReplacementChar, ';':
+			return false
+		case '(':
+			depth++
+		case ')':
+			depth--
+		}
+	}
+	return true
+}
+
+// scanStmtEnd scans to the end of the statement and returns the end offset of its text, the statement can only be
+// followed by a semicolon.
+func scanStmtEnd(s *Scanner) (int, bool) {
+	end := s.r.pos().Offset
+	for {
+		tok, _, _ := s.scan()
+		switch tok {
+		case 0:
+			return end, true
+		case unicode.ReplacementChar:
+			return 0, false
+		case ';':
+			tok, _, _ = s.scan()
+			return end, tok == 0
+		}
+		end = s.r.pos().Offset
+	}
+}
+
+// NextRows returns the text of at most n rows in Rows starting from offset, and the offset of the rows after them.
+// The offset is len(Rows) after the last rows.
+func (t *InsertValuesText) NextRows(offset, n int) (rows string, next int) {
+	s := NewScanner(t.Rows[offset:])
+	for i := 0; i < n; i++ {
+		if tok, _, _ := s.scan(); tok != '(' {
+			break
+		}
+		skipParentheses(s)
+		end := s.r.pos().Offset
+		if tok, _, _ := s.scan(); tok != ',' {
+			return t.Rows[offset:], len(t.Rows)
+		}
+		if i == n-1 {
+			return t.Rows[offset : offset+end], offset + s.r.pos().Offset
+		}
+	}
+	return t.Rows[offset:], len(t.Rows)
+}
@@ -37,6 +37,8 @@ type Scanner struct {
 
 	errs         []error
 	stmtStartPos int
+	// lastTok is the last token returned by Lex, we use it to recognize the optimizer hint comment.
+	lastTok int
 
 	// for scanning such kind of comment: /*! MySQL-specific code */
 	specialComment *Scanner
@@ -53,6 +55,7 @@ func (s *Scanner) reset(sql string) {
 	s.buf.Reset()
 	s.errs = s.errs[:0]
 	s.stmtStartPos = 0
+	s.lastTok = 0
 }
 
 func (s *Scanner) stmtText() string {
@@ -96,6 +99,7 @@ func (s *Scanner) Lex(v *yySymType) int {
 			tok = tok1
 		}
 	}
+	s.lastTok = tok
 
 	switch tok {
 	case intLit:
@@ -108,7 +112,7 @@ func (s *Scanner) Lex(v *yySymType) int {
 		return toHex(s, v, lit)
 	case bitLit:
 		return toBit(s, v, lit)
-	case userVar, sysVar, cast, sysDate, curDate, extract:
+	case userVar, sysVar, cast, sysDate, curDate, extract, strcmp:
 		v.item = lit
 		return tok
 	case null:
@@ -260,6 +264,11 @@ func startWithSlash(s *Scanner) (tok int, pos Pos, lit string) {
 		// See http://dev.mysql.com/doc/refman/5.7/en/comments.html
 		// Convert "/*!VersionNumber MySQL-specific-code */" to "MySQL-specific-code".
 		comment := s.r.data(&pos)
+		// Optimizer hints are only recognized right after the SELECT keyword, like "SELECT /*+ ... */".
+		// See https://dev.mysql.com/doc/refman/5.7/en/optimizer-hints.html
+		if strings.HasPrefix(comment, "/*+") && s.lastTok == selectKwd {
+			return hintComment, pos, comment
+		}
 		if strings.HasPrefix(comment, "/*!") {
 			sql := specCodePattern.ReplaceAllStringFunc(comment, trimComment)
 			s.specialComment = NewScanner(sql)
@@ -17,6 +17,8 @@ import (
 	"bytes"
 	"strings"
 
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/util/charset"
 	"github.com/pingcap/tidb/util/hack"
 )
@@ -152,6 +154,7 @@ var tokenMap = map[string]int{
 	"AVG_ROW_LENGTH":      avgRowLength,
 	"BEGIN":               begin,
 	"BETWEEN":             between,
+	"BINDING":             binding,
 	"BINLOG":              binlog,
 	"BOTH":                both,
 	"BTREE":               btree,
@@ -165,6 +168,7 @@ var tokenMap = map[string]int{
 	"CHARSET":             charsetKwd,
 	"CHECK":               check,
 	"CHECKSUM":            checksum,
+	"CLEANUP":             cleanup,
 	"COALESCE":            coalesce,
 	"COLLATE":             collate,
 	"COLLATION":           collation,
@@ -206,11 +210,13 @@ var tokenMap = map[string]int{
 	"DDL":                 ddl,
 	"DEALLOCATE":          deallocate,
 	"DEFAULT":             defaultKwd,
+	"DEFINER":             definer,
 	"DELAYED":             delayed,
 	"DELAY_KEY_WRITE":     delayKeyWrite,
 	"DELETE":              deleteKwd,
 	"DESC":                desc,
 	"DESCRIBE":            describe,
+	"DIAGNOSE":            diagnose,
 	"DISABLE":             disable,
 	"DISTINCT":            distinct,
 	"DIV":                 div,
@@ -220,12 +226,14 @@ var tokenMap = map[string]int{
 	"DUPLICATE":           duplicate,
 	"DYNAMIC":             dynamic,
 	"ELSE":                elseKwd,
+	"EMPTY":               empty,
 	"ENABLE":              enable,
 	"ENCLOSED":            enclosed,
 	"END":                 end,
 	"ENGINE":              engine,
 	"ENGINES":             engines,
 	"ENUM":                enum,
+	"ERROR":               errorKwd,
 	"ESCAPE":              escape,
 	"ESCAPED":             escaped,
 	"EXECUTE":             execute,
@@ -234,11 +242,13 @@ var tokenMap = map[string]int{
 	"EXTRACT":             extract,
 	"FALSE":               falseKwd,
 	"FIELDS":              fields,
+	"FILE":                file,
 	"FIRST":               first,
 	"FIXED":               fixed,
 	"FOREIGN":             foreign,
 	"FOR":                 forKwd,
 	"FORCE":               force,
+	"FORMAT":              format,
 	"FOUND_ROWS":          foundRows,
 	"FROM":                from,
 	"FROM_UNIXTIME":       fromUnixTime,
@@ -270,14 +280,17 @@ var tokenMap = map[string]int{
 	"INNER":               inner,
 	"INSERT":              insert,
 	"INTERVAL":            interval,
+	"INVOKER":             invoker,
 	"INTO":                into,
 	"IS":                  is,
 	"ISNULL":              isNull,
 	"ISOLATION":           isolation,
+	"JOBS":                jobs,
 	"JOIN":                join,
 	"KEY":                 key,
 	"KEY_BLOCK_SIZE":      keyBlockSize,
 	"KEYS":                keys,
+	"KILL":                kill,
 	"LAST_INSERT_ID":      lastInsertID,
 	"LEADING":             leading,
 	"LEFT":                left,
@@ -311,25 +324,35 @@ var tokenMap = map[string]int{
 	"NO_WRITE_TO_BINLOG":  noWriteToBinLog,
 	"NULL":                null,
 	"NULLIF":              nullIf,
+	"OF":                  of,
 	"OFFSET":              offset,
 	"ON":                  on,
 	"ONLY":                only,
 	"OPTION":              option,
 	"OR":                  or,
 	"ORDER":               order,
+	"ORDINALITY":          ordinality,
 	"OUTER":               outer,
+	"OUTFILE":             outfile,
 	"PASSWORD":            password,
+	"PATH":                path,
+	"PLAN":                plan,
+	"PLANS":               plans,
 	"POW":                 pow,
 	"POWER":               power,
 	"PREPARE":             prepare,
 	"PRIMARY":             primary,
 	"PRIVILEGES":          privileges,
 	"PROCEDURE":           procedure,
+	"PROCESS":             process,
 	"PROCESSLIST":         processlist,
 	"QUARTER":             quarter,
+	"QUERY":               query,
 	"QUICK":               quick,
 	"RAND":                rand,
+	"RANGES":              ranges,
 	"READ":                read,
+	"RECOVER":             recover,
 	"REDUNDANT":           redundant,
 	"REFERENCES":          references,
 	"REGEXP":              regexpKwd,
@@ -337,17 +360,20 @@ var tokenMap = map[string]int{
 	"REPEAT":              repeat,
 	"REPEATABLE":          repeatable,
 	"REPLACE":             replace,
+	"RESET":               reset,
 	"RIGHT":               right,
 	"RLIKE":               rlike,
 	"ROLLBACK":            rollback,
 	"ROUND":               round,
 	"ROW":                 row,
 	"ROW_FORMAT":          rowFormat,
+	"ROWS":                rows,
 	"RTRIM":               rtrim,
 	"REVERSE":             reverse,
 	"SCHEMA":              schema,
 	"SCHEMAS":             schemas,
 	"SECOND":              second,
+	"SECURITY":            security,
 	"SELECT":              selectKwd,
 	"SERIALIZABLE":        serializable,
 	"SESSION":             session,
@@ -355,25 +381,31 @@ var tokenMap = map[string]int{
 	"SHARE":               share,
 	"SHOW":                show,
 	"SLEEP":               sleep,
+	"SLOW":                slow,
 	"SIGNED":              signed,
 	"SNAPSHOT":            snapshot,
 	"SOME":                some,
 	"SPACE":               space,
 	"START":               start,
 	"STARTING":            starting,
+	"STATS":               stats,
 	"STATS_PERSISTENT":    statsPersistent,
+	"STATS_NDV":           statsNDV,
 	"STATUS":              status,
+	"STRAIGHT_JOIN":       straightJoin,
 	"SUBDATE":             subDate,
 	"STRCMP":              strcmp,
 	"SUBSTR":              substring,
 	"SUBSTRING":           substring,
 	"SUBSTRING_INDEX":     substringIndex,
 	"SUM":                 sum,
+	"SUPER":               super,
 	"SYSDATE":             sysDate,
 	"TABLE":               tableKwd,
 	"TABLES":              tables,
 	"TERMINATED":          terminated,
 	"THEN":                then,
+	"THROTTLE":            throttle,
 	"TO":                  to,
 	"TRAILING":            trailing,
 	"TRANSACTION":         transaction,
@@ -410,6 +442,7 @@ var tokenMap = map[string]int{
 	"YEARWEEK":            yearweek,
 	"ZEROFILL":            zerofill,
 	"SQL_CALC_FOUND_ROWS": calcFoundRows,
+	"SQL":                 sql,
 	"SQL_CACHE":           sqlCache,
 	"SQL_NO_CACHE":        sqlNoCache,
 	"CURRENT_TIMESTAMP":   currentTs,
@@ -496,3 +529,73 @@ func handleIdent(lval *yySymType) int {
 	lval.item = cs
 	return underscoreCS
 }
+
+// selectStmtOpts is the options between SELECT and the field list of a select statement.
+type selectStmtOpts struct {
+	distinct     bool
+	straightJoin bool
+	tableHints   []*ast.TableOptimizerHint
+}
+
+type selectLockOpt struct {
+	tp     ast.SelectLockType
+	tables *ast.SelectLockTableList
+}
+
+// parseOptimizerHints parses an optimizer hint comment like "/*+ DISABLE_RULES(join_reorder, agg_pushdown) */".
+// Like MySQL, we stop at the first malformed hint and ignore the rest instead of reporting a syntax error.
+// See https://dev.mysql.com/doc/refman/5.7/en/optimizer-hints.html
+func parseOptimizerHints(comment string) []*ast.TableOptimizerHint {
+	text := strings.TrimSuffix(strings.TrimPrefix(comment, "/*+"), "*/")
+	var hints []*ast.TableOptimizerHint
+	for {
+		text = strings.TrimLeft(text, " \t\r\n,")
+		nameEnd := strings.IndexFunc(text, func(ch rune) bool { return !isIdentChar(ch) })
+		if nameEnd <= 0 {
+			return hints
+		}
+		name := text[:nameEnd]
+		text = strings.TrimLeft(text[nameEnd:], " \t\r\n")
+		argsEnd := strings.IndexByte(text, ')')
+		if !strings.HasPrefix(text, "(") || argsEnd < 0 {
+			return hints
+		}
+		hint := &ast.TableOptimizerHint{HintName: model.NewCIStr(name)}
+		if parseHintArgs(hint, text[1:argsEnd]) {
+			hints = append(hints, hint)
+		}
+		text = text[argsEnd+1:]
+	}
+}
+
+// parseHintArgs parses the arguments of a hint and the query block they are for, it returns false if the
+// arguments are for different query blocks.
+func parseHintArgs(hint *ast.TableOptimizerHint, text string) bool {
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "@") {
+		// "@qb t1, t2": the query block name is followed by a space or the first comma.
+		end := strings.IndexAny(text, " \t\r\n,")
+		if end < 0 {
+			end = len(text)
+		}
+		hint.QBName = model.NewCIStr(strings.Trim(text[1:end], "`"))
+		text = text[end:]
+	}
+	for _, arg := range strings.Split(text, ",") {
+		arg = strings.TrimSpace(arg)
+		// "t1@qb": the table is in the query block qb.
+		if at := strings.IndexByte(arg, '@'); at >= 0 {
+			qbName := model.NewCIStr(strings.Trim(strings.TrimSpace(arg[at+1:]), "`"))
+			if hint.QBName.L != "" && hint.QBName.L != qbName.L {
+				return false
+			}
+			hint.QBName = qbName
+			arg = strings.TrimSpace(arg[:at])
+		}
+		arg = strings.Trim(arg, "`")
+		if arg != "" {
+			hint.Args = append(hint.Args, model.NewCIStr(arg))
+		}
+	}
+	return true
+}
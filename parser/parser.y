@@ -50,6 +50,7 @@ import (
 	invalid		"a special token never used by parser, used by lexer to indicate error"
 	andand		"&&"
 	oror		"||"
+	hintComment	"optimizer hint comment"
 
 	/* the following tokens belong to ReservedKeyword*/
 	add		"ADD"
@@ -125,12 +126,14 @@ import (
 	integerType	"INTEGER"
 	interval	"INTERVAL"
 	into		"INTO"
+	outfile		"OUTFILE"
 	is		"IS"
 	insert		"INSERT"
 	intType		"INT"
 	join		"JOIN"
 	key		"KEY"
 	keys		"KEYS"
+	kill		"KILL"
 	leading		"LEADING"
 	left		"LEFT"
 	like		"LIKE"
@@ -178,6 +181,7 @@ import (
 	show		"SHOW"
 	smallIntType	"SMALLINT"
 	starting	"STARTING"
+	straightJoin	"STRAIGHT_JOIN"
 	tableKwd	"TABLE"
 	terminated	"TERMINATED"
 	then		"THEN"
@@ -270,6 +274,7 @@ import (
 	yearweek	"YEARWEEK"
 	round		"ROUND"
 	statsPersistent	"STATS_PERSISTENT"
+	statsNDV	"STATS_NDV"
 	getLock		"GET_LOCK"
 	releaseLock	"RELEASE_LOCK"
 
@@ -283,6 +288,7 @@ import (
 	avgRowLength	"AVG_ROW_LENGTH"
 	avg		"AVG"
 	begin		"BEGIN"
+	binding		"BINDING"
 	binlog		"BINLOG"
 	bitType		"BIT"
 	booleanType	"BOOLEAN"
@@ -305,27 +311,44 @@ import (
 	dateType	"DATE"
 	datetimeType	"DATETIME"
 	deallocate	"DEALLOCATE"
+	definer		"DEFINER"
 	delayKeyWrite	"DELAY_KEY_WRITE"
 	disable		"DISABLE"
 	do		"DO"
 	duplicate	"DUPLICATE"
 	dynamic		"DYNAMIC"
+	empty		"EMPTY"
 	enable		"ENABLE"
 	end		"END"
 	engine		"ENGINE"
 	engines		"ENGINES"
+	errorKwd	"ERROR"
 	escape 		"ESCAPE"
 	execute		"EXECUTE"
 	fields		"FIELDS"
+	file		"FILE"
 	first		"FIRST"
 	fixed		"FIXED"
 	flush		"FLUSH"
+	format		"FORMAT"
 	full		"FULL"
 	function	"FUNCTION"
 	hash		"HASH"
 	identified	"IDENTIFIED"
 	isolation	"ISOLATION"
 	indexes		"INDEXES"
+	jobs		"JOBS"
+	diagnose	"DIAGNOSE"
+	throttle	"THROTTLE"
+	stats		"STATS"
+	rows		"ROWS"
+	reset		"RESET"
+	slow		"SLOW"
+	plans		"PLANS"
+	recover		"RECOVER"
+	cleanup		"CLEANUP"
+	ranges		"RANGES"
+	invoker		"INVOKER"
 	keyBlockSize	"KEY_BLOCK_SIZE"
 	local		"LOCAL"
 	level		"LEVEL"
@@ -336,30 +359,39 @@ import (
 	names		"NAMES"
 	national	"NATIONAL"
 	no		"NO"
+	of		"OF"
 	offset		"OFFSET"
 	only		"ONLY"
+	ordinality	"ORDINALITY"
 	password	"PASSWORD"
+	path		"PATH"
+	plan		"PLAN"
 	prepare		"PREPARE"
 	privileges	"PRIVILEGES"
+	process		"PROCESS"
 	processlist	"PROCESSLIST"
 	quarter		"QUARTER"
 	quick		"QUICK"
+	query		"QUERY"
 	redundant	"REDUNDANT"
 	repeatable	"REPEATABLE"
 	reverse		"REVERSE"
 	rollback	"ROLLBACK"
 	row 		"ROW"
 	rowFormat	"ROW_FORMAT"
+	security	"SECURITY"
 	serializable	"SERIALIZABLE"
 	session		"SESSION"
 	share		"SHARE"
 	signed		"SIGNED"
 	snapshot	"SNAPSHOT"
 	space 		"SPACE"
+	sql		"SQL"
 	sqlCache	"SQL_CACHE"
 	sqlNoCache	"SQL_NO_CACHE"
 	start		"START"
 	status		"STATUS"
+	super		"SUPER"
 	some 		"SOME"
 	global		"GLOBAL"
 	tables		"TABLES"
@@ -433,6 +465,8 @@ import (
 	ColumnSetValue		"insert statement set value by column name"
 	ColumnSetValueList	"insert statement set value by column name list"
 	CommitStmt		"COMMIT statement"
+	CommonTableExpr		"common table expression"
+	CommonTableExprList	"common table expression list"
 	CompareOp		"Compare opcode"
 	ColumnOption		"column definition option"
 	ColumnOptionList	"column definition option list"
@@ -447,7 +481,11 @@ import (
 	DatabaseOptionList	"CREATE Database specification list"
 	DatabaseOptionListOpt	"CREATE Database specification list opt"
 	CreateTableStmt		"CREATE TABLE statement"
+	CreateTableSelect	"SELECT statement of CREATE TABLE"
+	CreateBindingStmt	"CREATE BINDING statement"
 	CreateUserStmt		"CREATE User statement"
+	CreateViewSelect	"SELECT statement of CREATE VIEW"
+	CreateViewStmt		"CREATE VIEW statement"
 	DateArithOpt		"Date arith dateadd or datesub option"
 	DateArithMultiFormsOpt	"Date arith adddate or subdate option"
 	DateArithInterval       "Date arith interval part"
@@ -459,6 +497,8 @@ import (
 	DeleteFromStmt		"DELETE FROM statement"
 	DistinctOpt		"Distinct option"
 	DoStmt			"Do statement"
+	BindingSym		"BINDING or GLOBAL BINDING"
+	DropBindingStmt		"DROP BINDING statement"
 	DropDatabaseStmt	"DROP DATABASE statement"
 	DropIndexStmt		"DROP INDEX statement"
 	DropTableStmt		"DROP TABLE statement"
@@ -512,11 +552,14 @@ import (
 	IndexOption		"Index Option"
 	IndexType		"index type"
 	IndexTypeOpt		"Optional index type"
+	IgnoreLines		"Ignore num(int) lines"
 	InsertIntoStmt		"INSERT INTO statement"
 	InsertValues		"Rest part of INSERT/REPLACE INTO statement"
 	JoinTable 		"join table"
+	KillStmt		"Kill statement"
 	JoinType		"join type"
 	LikeEscapeOpt 		"like escape option"
+	LikeTableWithOrWithoutParen	"LIKE table_name or ( LIKE table_name )"
 	LimitClause		"LIMIT clause"
 	Lines			"Lines clause"
 	LinesTerminated		"Lines terminated by"
@@ -534,6 +577,8 @@ import (
 	OptFull			"Full or empty"
 	Order			"ORDER BY clause optional collation specification"
 	OrderBy			"ORDER BY clause"
+	OrReplace		"OR REPLACE or empty"
+	ViewSecurityOpt		"SQL SECURITY of a view or empty"
 	ByItem			"BY item"
 	OrderByOptional		"Optional ORDER BY clause optional"
 	ByList			"BY list"
@@ -558,9 +603,11 @@ import (
 	RollbackStmt		"ROLLBACK statement"
 	RowFormat		"Row format option"
 	SelectLockOpt		"FOR UPDATE or LOCK IN SHARE MODE,"
+	SelectIntoStmt		"SELECT INTO OUTFILE statement"
 	SelectStmt		"SELECT statement"
 	SelectStmtCalcFoundRows	"SELECT statement optional SQL_CALC_FOUND_ROWS"
 	SelectStmtSQLCache	"SELECT statement optional SQL_CAHCE/SQL_NO_CACHE"
+	SelectStmtStraightJoin	"SELECT statement optional STRAIGHT_JOIN"
 	SelectStmtDistinct	"SELECT statement optional DISTINCT clause"
 	SelectStmtFieldList	"SELECT statement field list"
 	SelectStmtLimit		"SELECT statement optional LIMIT clause"
@@ -577,6 +624,8 @@ import (
 	Statement		"statement"
 	StatementList		"statement list"
 	StatsPersistentVal	"stats_persistent value"
+	StatsNDV		"stats_ndv hint"
+	StatsNDVList		"stats_ndv hint list"
 	StringName		"string literal or identifier"
 	StringList 		"string list"
 	ExplainableStmt		"explainable statement"
@@ -591,17 +640,26 @@ import (
 	TableLock		"Table name and lock type"
 	TableLockList		"Table lock list"
 	TableName		"Table name"
+	TableNameOptWild	"Table name with optional wildcard"
 	TableNameList		"Table name list"
+	TableAliasRefList	"table alias reference list"
 	TableNameListOpt	"Table name list opt"
 	TableOption		"create table option"
 	TableOptionList		"create table option list"
 	TableOptionListOpt	"create table option list opt"
+	TableOptimizerHints	"Table level optimizer hints"
+	TableFuncColumn		"table function column definition"
+	TableFuncColumnList	"table function column definition list"
+	TableFuncColumnResponse		"table function column ON EMPTY or ON ERROR response"
+	TableFuncColumnResponseListOpt	"table function column ON EMPTY and ON ERROR responses"
 	TableRef 		"table reference"
 	TableRefs 		"table references"
 	TrimDirection		"Trim string direction"
 	TruncateTableStmt	"TRANSACTION TABLE statement"
 	UnionOpt		"Union Option(empty/ALL/DISTINCT)"
 	UnionStmt		"Union select state ment"
+	ViewColumnList		"column name list of CREATE VIEW"
+	ViewColumnListOpt	"optional column name list of CREATE VIEW"
 	UnionClauseList		"Union select clause list"
 	UnionSelect		"Union (select) item"
 	UnlockTablesStmt	"Unlock tables statement"
@@ -620,6 +678,7 @@ import (
 	WhereClauseOptional	"Optinal WHERE clause"
 	WhenClause		"When clause"
 	WhenClauseList		"When clause list"
+	WithClause		"WITH clause"
 	WithReadLockOpt		"With Read Lock opt"
 	ElseOpt			"Optional else clause"
 	ExpressionOpt		"Optional expression"
@@ -676,6 +735,7 @@ import (
 	logAnd			"logical and operator"
 	logOr			"logical or operator"
 	FieldsOrColumns 	"Fields or columns"
+	LinesOrRows		"Lines or rows"
 
 %type	<ident>
 	Identifier			"identifier or unreserved keyword"
@@ -703,7 +763,7 @@ import (
 %precedence lowerThanKey
 %precedence key
 
-%left   join inner cross left right full
+%left   join straightJoin inner cross left right full
 /* A dummy token to force the priority of TableRef production in a join. */
 %left   tableRefPriority
 %precedence lowerThanOn
@@ -944,11 +1004,11 @@ ColumnName:
 	}
 |	Identifier '.' IdentifierOrReservedKeyword
 	{
-		$$ = &ast.ColumnName{Table: model.NewCIStr($1), Name: model.NewCIStr($3)}
+		$$ = &ast.ColumnName{Table: model.NewTableCIStr($1), Name: model.NewCIStr($3)}
 	}
 |	Identifier '.' Identifier '.' IdentifierOrReservedKeyword
 	{
-		$$ = &ast.ColumnName{Schema: model.NewCIStr($1), Table: model.NewCIStr($3), Name: model.NewCIStr($5)}
+		$$ = &ast.ColumnName{Schema: model.NewTableCIStr($1), Table: model.NewTableCIStr($3), Name: model.NewCIStr($5)}
 	}
 
 ColumnNameList:
@@ -1345,7 +1405,7 @@ CreateDatabaseStmt:
 DBName:
 	Identifier
   {
-    $$ = $1
+    $$ = model.NewTableCIStr($1).O
   }
 
 DatabaseOption:
@@ -1374,6 +1434,80 @@ DatabaseOptionList:
 		$$ = append($1.([]*ast.DatabaseOption), $2.(*ast.DatabaseOption))
 	}
 
+/*******************************************************************
+ *
+ *  Create View Statement
+ *
+ *  Example:
+ *      CREATE OR REPLACE SQL SECURITY INVOKER VIEW v (a, b) AS SELECT c, d FROM t WHERE c > 1
+ *******************************************************************/
+CreateViewStmt:
+	"CREATE" OrReplace ViewSecurityOpt "VIEW" TableName ViewColumnListOpt "AS" CreateViewSelect
+	{
+		sel := $8.(ast.ResultSetNode)
+		// The lookahead token has been scanned when the select statement is reduced, so the select statement
+		// ends before it.
+		sel.SetText(parser.src[yyS[yypt].offset:parser.endOffset(&parser.yylval)])
+		$$ = &ast.CreateViewStmt{
+			OrReplace: $2.(bool),
+			Security:  $3.(model.ViewSecurity),
+			ViewName:  $5.(*ast.TableName),
+			Cols:      $6.([]model.CIStr),
+			Select:    sel,
+		}
+	}
+
+ViewSecurityOpt:
+	{
+		$$ = model.SecurityDefiner
+	}
+|	"SQL" "SECURITY" "DEFINER"
+	{
+		$$ = model.SecurityDefiner
+	}
+|	"SQL" "SECURITY" "INVOKER"
+	{
+		$$ = model.SecurityInvoker
+	}
+
+CreateViewSelect:
+	SelectStmt
+	{
+		$$ = $1
+	}
+|	UnionStmt
+	{
+		$$ = $1
+	}
+
+OrReplace:
+	{
+		$$ = false
+	}
+|	"OR" "REPLACE"
+	{
+		$$ = true
+	}
+
+ViewColumnListOpt:
+	{
+		$$ = []model.CIStr(nil)
+	}
+|	'(' ViewColumnList ')'
+	{
+		$$ = $2.([]model.CIStr)
+	}
+
+ViewColumnList:
+	Identifier
+	{
+		$$ = []model.CIStr{model.NewCIStr($1)}
+	}
+|	ViewColumnList ',' Identifier
+	{
+		$$ = append($1.([]model.CIStr), model.NewCIStr($3))
+	}
+
 /*******************************************************************
  *
  *  Create Table Statement
@@ -1415,6 +1549,58 @@ CreateTableStmt:
 			Options:        $8.([]*ast.TableOption),
 		}
 	}
+|	"CREATE" "TABLE" IfNotExists TableName LikeTableWithOrWithoutParen
+	{
+		$$ = &ast.CreateTableStmt{
+			Table:          $4.(*ast.TableName),
+			IfNotExists:    $3.(bool),
+			ReferTable:     $5.(*ast.TableName),
+		}
+	}
+|	"CREATE" "TABLE" IfNotExists TableName CreateTableSelect
+	{
+		$$ = &ast.CreateTableStmt{
+			Table:          $4.(*ast.TableName),
+			IfNotExists:    $3.(bool),
+			Options:        []*ast.TableOption{},
+			Select:         $5.(ast.ResultSetNode),
+		}
+	}
+|	"CREATE" "TABLE" IfNotExists TableName TableOptionList CreateTableSelect
+	{
+		$$ = &ast.CreateTableStmt{
+			Table:          $4.(*ast.TableName),
+			IfNotExists:    $3.(bool),
+			Options:        $5.([]*ast.TableOption),
+			Select:         $6.(ast.ResultSetNode),
+		}
+	}
+
+LikeTableWithOrWithoutParen:
+	"LIKE" TableName
+	{
+		$$ = $2
+	}
+|	'(' "LIKE" TableName ')'
+	{
+		$$ = $3
+	}
+
+CreateTableSelect:
+	"AS" CreateViewSelect
+	{
+		sel := $2.(ast.ResultSetNode)
+		// The lookahead token has been scanned when the select statement is reduced, so the select statement
+		// ends before it.
+		sel.SetText(parser.src[yyS[yypt].offset:parser.endOffset(&parser.yylval)])
+		$$ = sel
+	}
+|	CreateViewSelect
+	{
+		sel := $1.(ast.ResultSetNode)
+		sel.SetText(parser.src[yyS[yypt].offset:parser.endOffset(&parser.yylval)])
+		$$ = sel
+	}
 
 Default:
 	"DEFAULT" Expression
@@ -1444,6 +1630,31 @@ DoStmt:
 		}
 	}
 
+/******************************************************************
+ * Kill statement
+ * See https://dev.mysql.com/doc/refman/5.7/en/kill.html
+ ******************************************************************/
+KillStmt:
+	"KILL" LengthNum
+	{
+		$$ = &ast.KillStmt{
+			ConnectionID: $2.(uint64),
+		}
+	}
+|	"KILL" "CONNECTION" LengthNum
+	{
+		$$ = &ast.KillStmt{
+			ConnectionID: $3.(uint64),
+		}
+	}
+|	"KILL" "QUERY" LengthNum
+	{
+		$$ = &ast.KillStmt{
+			Query:        true,
+			ConnectionID: $3.(uint64),
+		}
+	}
+
 /*******************************************************************
  *
  *  Delete Statement
@@ -1472,7 +1683,7 @@ DeleteFromStmt:
 
 		$$ = x
 	}
-|	"DELETE" LowPriorityOptional QuickOptional IgnoreOptional TableNameList "FROM" TableRefs WhereClauseOptional
+|	"DELETE" LowPriorityOptional QuickOptional IgnoreOptional TableAliasRefList "FROM" TableRefs WhereClauseOptional
 	{
 		// Multiple Table
 		x := &ast.DeleteStmt{
@@ -1489,7 +1700,7 @@ DeleteFromStmt:
 		}
 		$$ = x
 	}
-|	"DELETE" LowPriorityOptional QuickOptional IgnoreOptional "FROM" TableNameList "USING" TableRefs WhereClauseOptional
+|	"DELETE" LowPriorityOptional QuickOptional IgnoreOptional "FROM" TableAliasRefList "USING" TableRefs WhereClauseOptional
 	{
 		// Multiple Table
 		x := &ast.DeleteStmt{
@@ -1532,11 +1743,48 @@ DropTableStmt:
 	}
 
 DropViewStmt:
-	"DROP" "VIEW" "IF" "EXISTS" TableNameList
+	"DROP" "VIEW" TableNameList
+	{
+		$$ = &ast.DropTableStmt{Tables: $3.([]*ast.TableName), IsView: true}
+	}
+|	"DROP" "VIEW" "IF" "EXISTS" TableNameList
+	{
+		$$ = &ast.DropTableStmt{IfExists: true, Tables: $5.([]*ast.TableName), IsView: true}
+	}
+
+/*******************************************************************
+ *
+ *  Create Binding Statement
+ *
+ *  Example:
+ *      CREATE GLOBAL BINDING FOR SELECT * FROM t WHERE a = 1 USING SELECT (the hints) * FROM t WHERE a = 1
+ *      The hints of the second statement are written in the optimizer hint comment.
+ *******************************************************************/
+CreateBindingStmt:
+	"CREATE" BindingSym "FOR" SelectStmt "USING" SelectStmt
+	{
+		originSel := $4.(*ast.SelectStmt)
+		originSel.SetText(parser.src[yyS[yypt-2].offset:parser.endOffset(&yyS[yypt-1])])
+		// The lookahead token has been scanned when the hinted statement is reduced, so the statement ends before it.
+		hintedSel := $6.(*ast.SelectStmt)
+		hintedSel.SetText(parser.src[yyS[yypt].offset:parser.endOffset(&parser.yylval)])
+		$$ = &ast.CreateBindingStmt{OriginSel: originSel, HintedSel: hintedSel}
+	}
+
+DropBindingStmt:
+	"DROP" BindingSym "FOR" SelectStmt
 	{
-		$$ = &ast.DoStmt{}
+		originSel := $4.(*ast.SelectStmt)
+		originSel.SetText(parser.src[yyS[yypt].offset:parser.endOffset(&parser.yylval)])
+		$$ = &ast.DropBindingStmt{OriginSel: originSel}
 	}
 
+BindingSym:
+	"BINDING"
+	{}
+|	"GLOBAL" "BINDING"
+	{}
+
 DropUserStmt:
     "DROP" "USER" UsernameList
     {
@@ -1586,7 +1834,22 @@ ExplainStmt:
 	}
 |	ExplainSym ExplainableStmt
 	{
-		$$ = &ast.ExplainStmt{Stmt: $2.(ast.StmtNode)}
+		stmt := $2.(ast.StmtNode)
+		// The text of the explained statement is used to match its binding.
+		stmt.SetText(parser.src[yyS[yypt].offset:parser.endOffset(&parser.yylval)])
+		$$ = &ast.ExplainStmt{Stmt: stmt}
+	}
+|	ExplainSym "ANALYZE" ExplainableStmt
+	{
+		stmt := $3.(ast.StmtNode)
+		stmt.SetText(parser.src[yyS[yypt].offset:parser.endOffset(&parser.yylval)])
+		$$ = &ast.ExplainStmt{Stmt: stmt, Analyze: true}
+	}
+|	ExplainSym "FORMAT" "=" StringName ExplainableStmt
+	{
+		stmt := $5.(ast.StmtNode)
+		stmt.SetText(parser.src[yyS[yypt].offset:parser.endOffset(&parser.yylval)])
+		$$ = &ast.ExplainStmt{Stmt: stmt, Format: strings.ToLower($4.(string))}
 	}
 
 LengthNum:
@@ -1817,12 +2080,12 @@ Field:
 	}
 |	Identifier '.' '*'
 	{
-		wildCard := &ast.WildCardField{Table: model.NewCIStr($1)}
+		wildCard := &ast.WildCardField{Table: model.NewTableCIStr($1)}
 		$$ = &ast.SelectField{WildCard: wildCard}
 	}
 |	Identifier '.' Identifier '.' '*'
 	{
-		wildCard := &ast.WildCardField{Schema: model.NewCIStr($1), Table: model.NewCIStr($3)}
+		wildCard := &ast.WildCardField{Schema: model.NewTableCIStr($1), Table: model.NewTableCIStr($3)}
 		$$ = &ast.SelectField{WildCard: wildCard}
 	}
 |	Expression FieldAsNameOpt
@@ -1998,6 +2261,9 @@ UnReservedKeyword:
 |	"MIN_ROWS" | "NATIONAL" | "ROW" | "ROW_FORMAT" | "QUARTER" | "GRANTS" | "TRIGGERS" | "DELAY_KEY_WRITE" | "ISOLATION"
 |	"REPEATABLE" | "COMMITTED" | "UNCOMMITTED" | "ONLY" | "SERIALIZABLE" | "LEVEL" | "VARIABLES" | "SQL_CACHE" | "INDEXES" | "PROCESSLIST"
 |	"SQL_NO_CACHE" | "DISABLE"  | "ENABLE" | "REVERSE" | "SPACE" | "PRIVILEGES" | "NO" | "BINLOG" | "FUNCTION" | "VIEW" | "MODIFY"
+|	"ORDINALITY" | "PATH" | "FORMAT" | "OF" | "JOBS" | "DIAGNOSE" | "THROTTLE" | "STATS" | "ROWS" | "RESET" | "BINDING" | "PLAN"
+|	"SLOW" | "PLANS" | "RECOVER" | "CLEANUP" | "RANGES" | "QUERY" | "PROCESS" | "FILE" | "SUPER" | "SQL" | "SECURITY"
+|	"DEFINER" | "INVOKER" | "EMPTY" | "ERROR"
 
 ReservedKeyword:
 "ADD" | "ALL" | "ALTER" | "ANALYZE" | "AND" | "AS" | "ASC" | "BETWEEN" | "BIGINT"
@@ -2009,13 +2275,13 @@ ReservedKeyword:
 | "EXISTS" | "EXPLAIN" | "FALSE" | "FLOAT" | "FOR" | "FORCE" | "FOREIGN" | "FROM"
 | "FULLTEXT" | "GRANT" | "GROUP" | "HAVING" | "HOUR_MICROSECOND" | "HOUR_MINUTE"
 | "HOUR_SECOND" | "IF" | "IGNORE" | "IN" | "INDEX" | "INFILE" | "INNER" | "INSERT" | "INT" | "INTO" | "INTEGER"
-| "INTERVAL" | "IS" | "JOIN" | "KEY" | "KEYS" | "LEADING" | "LEFT" | "LIKE" | "LIMIT" | "LINES" | "LOAD"
+| "INTERVAL" | "IS" | "JOIN" | "KEY" | "KEYS" | "KILL" | "LEADING" | "LEFT" | "LIKE" | "LIMIT" | "LINES" | "LOAD"
 | "LOCALTIME" | "LOCALTIMESTAMP" | "LOCK" | "LONGBLOB" | "LONGTEXT" | "MEDIUMBLOB" | "MEDIUMINT" | "MEDIUMTEXT"
 | "MINUTE_MICROSECOND" | "MINUTE_SECOND" | "MOD" | "NOT" | "NO_WRITE_TO_BINLOG" | "NULL" | "NUMERIC"
-| "ON" | "OPTION" | "OR" | "ORDER" | "OUTER" | "PRECISION" | "PRIMARY" | "PROCEDURE" | "READ" | "REAL"
+| "ON" | "OPTION" | "OR" | "ORDER" | "OUTER" | "OUTFILE" | "PRECISION" | "PRIMARY" | "PROCEDURE" | "READ" | "REAL"
 | "REFERENCES" | "REGEXP" | "REPEAT" | "REPLACE" | "RESTRICT" | "RIGHT" | "RLIKE"
 | "SCHEMA" | "SCHEMAS" | "SECOND_MICROSECOND" | "SELECT" | "SET" | "SHOW" | "SMALLINT"
-| "STARTING" | "TABLE" | "TERMINATED" | "THEN" | "TINYBLOB" | "TINYINT" | "TINYTEXT" | "TO"
+| "STARTING" | "STRAIGHT_JOIN" | "TABLE" | "TERMINATED" | "THEN" | "TINYBLOB" | "TINYINT" | "TINYTEXT" | "TO"
 | "TRAILING" | "TRUE" | "UNION" | "UNIQUE" | "UNLOCK" | "UNSIGNED"
 | "UPDATE" | "USE" | "USING" | "UTC_DATE" | "VALUES" | "VARBINARY" | "VARCHAR"
 | "WHEN" | "WHERE" | "WRITE" | "XOR" | "YEAR_MONTH" | "ZEROFILL"
@@ -2031,7 +2297,7 @@ NotKeywordToken:
 |	"MAX" | "MICROSECOND" | "MIN" |	"MINUTE" | "NULLIF" | "MONTH" | "MONTHNAME" | "NOW" | "POW" | "POWER" | "RAND"
 |	"SECOND" | "SLEEP" | "SQL_CALC_FOUND_ROWS" | "SUBDATE" | "SUBSTRING" %prec lowerThanLeftParen | "SUBSTRING_INDEX"
 |	"SUM" | "TRIM" | "RTRIM" | "UCASE" | "UPPER" | "VERSION" | "WEEKDAY" | "WEEKOFYEAR" | "YEARWEEK" | "ROUND"
-|	"STATS_PERSISTENT" | "GET_LOCK" | "RELEASE_LOCK" | "CEIL" | "CEILING" | "FROM_UNIXTIME"
+|	"STATS_PERSISTENT" | "STATS_NDV" | "GET_LOCK" | "RELEASE_LOCK" | "CEIL" | "CEILING" | "FROM_UNIXTIME"
 
 /************************************************************************************
  *
@@ -2562,33 +2828,26 @@ FunctionCallNonKeyword:
 	}
 |	DateArithOpt '(' Expression ',' "INTERVAL" Expression TimeUnit ')'
 	{
-		op := ast.NewValueExpr($1)
-		dateArithInterval := ast.NewValueExpr(
-			ast.DateArithInterval{
-				Unit: $7,
-				Interval: $6.(ast.ExprNode),
-			},
-		)
-
 		$$ = &ast.FuncCallExpr{
 			FnName: model.NewCIStr("DATE_ARITH"),
 			Args: []ast.ExprNode{
-				op,
+				ast.NewValueExpr($1),
 				$3.(ast.ExprNode),
-				dateArithInterval,
+				$6.(ast.ExprNode),
+				ast.NewValueExpr($7),
 			},
 		}
 	}
 |	DateArithMultiFormsOpt '(' Expression ',' DateArithInterval')'
 	{
-		op := ast.NewValueExpr($1)
-		dateArithInterval := ast.NewValueExpr($5)
+		dateArithInterval := $5.(ast.DateArithInterval)
 		$$ = &ast.FuncCallExpr{
 			FnName: model.NewCIStr("DATE_ARITH"),
 			Args: []ast.ExprNode{
-				op,
+				ast.NewValueExpr($1),
 				$3.(ast.ExprNode),
-				dateArithInterval,
+				dateArithInterval.Interval,
+				ast.NewValueExpr(dateArithInterval.Unit),
 			},
 		}
 	}
@@ -3115,6 +3374,42 @@ PrimaryFactor:
 	{
 		$$ = &ast.BinaryOperationExpr{Op: opcode.Minus, L: $1.(ast.ExprNode), R: $3.(ast.ExprNode)}
 	}
+|	PrimaryFactor '+' "INTERVAL" Expression TimeUnit %prec '+'
+	{
+		$$ = &ast.FuncCallExpr{
+			FnName: model.NewCIStr("DATE_ARITH"),
+			Args: []ast.ExprNode{
+				ast.NewValueExpr(ast.DateAdd),
+				$1.(ast.ExprNode),
+				$4.(ast.ExprNode),
+				ast.NewValueExpr($5),
+			},
+		}
+	}
+|	PrimaryFactor '-' "INTERVAL" Expression TimeUnit %prec '-'
+	{
+		$$ = &ast.FuncCallExpr{
+			FnName: model.NewCIStr("DATE_ARITH"),
+			Args: []ast.ExprNode{
+				ast.NewValueExpr(ast.DateSub),
+				$1.(ast.ExprNode),
+				$4.(ast.ExprNode),
+				ast.NewValueExpr($5),
+			},
+		}
+	}
+|	"INTERVAL" Expression TimeUnit '+' PrimaryFactor %prec '+'
+	{
+		$$ = &ast.FuncCallExpr{
+			FnName: model.NewCIStr("DATE_ARITH"),
+			Args: []ast.ExprNode{
+				ast.NewValueExpr(ast.DateAdd),
+				$5.(ast.ExprNode),
+				$2.(ast.ExprNode),
+				ast.NewValueExpr($3),
+			},
+		}
+	}
 |	PrimaryFactor '*' PrimaryFactor %prec '*'
 	{
 		$$ = &ast.BinaryOperationExpr{Op: opcode.Mul, L: $1.(ast.ExprNode), R: $3.(ast.ExprNode)}
@@ -3171,11 +3466,11 @@ LowPriorityOptional:
 TableName:
 	Identifier
 	{
-		$$ = &ast.TableName{Name:model.NewCIStr($1)}
+		$$ = &ast.TableName{Name:model.NewTableCIStr($1)}
 	}
 |	IdentifierOrReservedKeyword '.' IdentifierOrReservedKeyword
 	{
-		$$ = &ast.TableName{Schema:model.NewCIStr($1),	Name:model.NewCIStr($3)}
+		$$ = &ast.TableName{Schema:model.NewTableCIStr($1),	Name:model.NewTableCIStr($3)}
 	}
 
 TableNameList:
@@ -3189,6 +3484,34 @@ TableNameList:
 		$$ = append($1.([]*ast.TableName), $3.(*ast.TableName))
 	}
 
+TableNameOptWild:
+	Identifier
+	{
+		$$ = &ast.TableName{Name:model.NewTableCIStr($1)}
+	}
+|	IdentifierOrReservedKeyword '.' '*'
+	{
+		$$ = &ast.TableName{Name:model.NewTableCIStr($1)}
+	}
+|	IdentifierOrReservedKeyword '.' IdentifierOrReservedKeyword
+	{
+		$$ = &ast.TableName{Schema:model.NewTableCIStr($1),	Name:model.NewTableCIStr($3)}
+	}
+|	IdentifierOrReservedKeyword '.' IdentifierOrReservedKeyword '.' '*'
+	{
+		$$ = &ast.TableName{Schema:model.NewTableCIStr($1),	Name:model.NewTableCIStr($3)}
+	}
+
+TableAliasRefList:
+	TableNameOptWild
+	{
+		$$ = []*ast.TableName{$1.(*ast.TableName)}
+	}
+|	TableAliasRefList ',' TableNameOptWild
+	{
+		$$ = append($1.([]*ast.TableName), $3.(*ast.TableName))
+	}
+
 QuickOptional:
 	%prec lowerThanQuick
 	{
@@ -3289,18 +3612,22 @@ RollbackStmt:
 SelectStmt:
 	"SELECT" SelectStmtOpts SelectStmtFieldList SelectStmtLimit SelectLockOpt
 	{
+		opts := $2.(*selectStmtOpts)
 		st := &ast.SelectStmt {
-			Distinct:      $2.(bool),
+			Distinct:      opts.distinct,
+			StraightJoin:  opts.straightJoin,
+			TableHints:    opts.tableHints,
 			Fields:        $3.(*ast.FieldList),
-			LockTp:	       $5.(ast.SelectLockType),
 		}
+		lock := $5.(*selectLockOpt)
+		st.LockTp, st.LockTables = lock.tp, lock.tables
 		lastField := st.Fields.Fields[len(st.Fields.Fields)-1]
 		if lastField.Expr != nil && lastField.AsName.O == "" {
 			src := parser.src
 			var lastEnd int
 			if $4 != nil {
 				lastEnd = yyS[yypt-1].offset-1
-			} else if $5 != ast.SelectLockNone {
+			} else if lock.tp != ast.SelectLockNone {
 				lastEnd = yyS[yypt].offset-1
 			} else {
 				lastEnd = len(src)
@@ -3317,11 +3644,15 @@ SelectStmt:
 	}
 |	"SELECT" SelectStmtOpts SelectStmtFieldList FromDual WhereClauseOptional SelectStmtLimit SelectLockOpt
 	{
+		opts := $2.(*selectStmtOpts)
 		st := &ast.SelectStmt {
-			Distinct:      $2.(bool),
+			Distinct:      opts.distinct,
+			StraightJoin:  opts.straightJoin,
+			TableHints:    opts.tableHints,
 			Fields:        $3.(*ast.FieldList),
-			LockTp:	       $7.(ast.SelectLockType),
 		}
+		lock := $7.(*selectLockOpt)
+		st.LockTp, st.LockTables = lock.tp, lock.tables
 		lastField := st.Fields.Fields[len(st.Fields.Fields)-1]
 		if lastField.Expr != nil && lastField.AsName.O == "" {
 			lastEnd := yyS[yypt-3].offset-1
@@ -3339,12 +3670,16 @@ SelectStmt:
 	TableRefsClause WhereClauseOptional SelectStmtGroup HavingClause OrderByOptional
 	SelectStmtLimit SelectLockOpt
 	{
+		opts := $2.(*selectStmtOpts)
 		st := &ast.SelectStmt{
-			Distinct:	$2.(bool),
+			Distinct:	opts.distinct,
+			StraightJoin:	opts.straightJoin,
+			TableHints:	opts.tableHints,
 			Fields:		$3.(*ast.FieldList),
 			From:		$5.(*ast.TableRefsClause),
-			LockTp:		$11.(ast.SelectLockType),
 		}
+		lock := $11.(*selectLockOpt)
+		st.LockTp, st.LockTables = lock.tp, lock.tables
 
 		lastField := st.Fields.Fields[len(st.Fields.Fields)-1]
 		if lastField.Expr != nil && lastField.AsName.O == "" {
@@ -3374,6 +3709,42 @@ SelectStmt:
 
 		$$ = st
 	}
+|	WithClause SelectStmt
+	{
+		st := $2.(*ast.SelectStmt)
+		if st.With != nil {
+			yylex.Errorf("Multiple WITH clauses are not allowed")
+			return 1
+		}
+		st.With = $1.(*ast.WithClause)
+		$$ = st
+	}
+
+WithClause:
+	"WITH" CommonTableExprList
+	{
+		$$ = &ast.WithClause{CTEs: $2.([]*ast.CommonTableExpr)}
+	}
+
+CommonTableExprList:
+	CommonTableExpr
+	{
+		$$ = []*ast.CommonTableExpr{$1.(*ast.CommonTableExpr)}
+	}
+|	CommonTableExprList ',' CommonTableExpr
+	{
+		$$ = append($1.([]*ast.CommonTableExpr), $3.(*ast.CommonTableExpr))
+	}
+
+CommonTableExpr:
+	Identifier ViewColumnListOpt "AS" SubSelect
+	{
+		$$ = &ast.CommonTableExpr{
+			Name:     model.NewCIStr($1),
+			ColNames: $2.([]model.CIStr),
+			Query:    $4.(*ast.SubqueryExpr).Query,
+		}
+	}
 
 FromDual:
 	"FROM" "DUAL"
@@ -3432,6 +3803,16 @@ TableFactor:
 		tn.IndexHints = $3.([]*ast.IndexHint)
 		$$ = &ast.TableSource{Source: tn, AsName: $2.(model.CIStr)}
 	}
+|	Identifier '(' ExpressionListOpt ')' TableAsNameOpt
+	{
+		tf := &ast.TableFunc{FnName: model.NewCIStr($1), Args: $3.([]ast.ExprNode)}
+		$$ = &ast.TableSource{Source: tf, AsName: $5.(model.CIStr)}
+	}
+|	Identifier '(' ExpressionList "COLUMNS" '(' TableFuncColumnList ')' ')' TableAsNameOpt
+	{
+		tf := &ast.TableFunc{FnName: model.NewCIStr($1), Args: $3.([]ast.ExprNode), Columns: $6.([]*ast.TableFuncColumn)}
+		$$ = &ast.TableSource{Source: tf, AsName: $9.(model.CIStr)}
+	}
 |	'(' SelectStmt ')' TableAsName
 	{
 		st := $2.(*ast.SelectStmt)
@@ -3448,6 +3829,61 @@ TableFactor:
 		$$ = $2
 	}
 
+TableFuncColumnList:
+	TableFuncColumn
+	{
+		$$ = []*ast.TableFuncColumn{$1.(*ast.TableFuncColumn)}
+	}
+|	TableFuncColumnList ',' TableFuncColumn
+	{
+		$$ = append($1.([]*ast.TableFuncColumn), $3.(*ast.TableFuncColumn))
+	}
+
+TableFuncColumn:
+	Identifier Type "PATH" stringLit TableFuncColumnResponseListOpt
+	{
+		col := $5.(*ast.TableFuncColumn)
+		col.Name = model.NewCIStr($1)
+		col.Tp = $2.(*types.FieldType)
+		col.Path = $4
+		$$ = col
+	}
+|	Identifier "FOR" "ORDINALITY"
+	{
+		$$ = &ast.TableFuncColumn{Name: model.NewCIStr($1), ForOrdinality: true}
+	}
+
+TableFuncColumnResponseListOpt:
+	{
+		$$ = &ast.TableFuncColumn{}
+	}
+|	TableFuncColumnResponse "ON" "EMPTY"
+	{
+		$$ = &ast.TableFuncColumn{OnEmpty: $1.(ast.TableFuncColumnResponse)}
+	}
+|	TableFuncColumnResponse "ON" "ERROR"
+	{
+		$$ = &ast.TableFuncColumn{OnError: $1.(ast.TableFuncColumnResponse)}
+	}
+|	TableFuncColumnResponse "ON" "EMPTY" TableFuncColumnResponse "ON" "ERROR"
+	{
+		$$ = &ast.TableFuncColumn{OnEmpty: $1.(ast.TableFuncColumnResponse), OnError: $4.(ast.TableFuncColumnResponse)}
+	}
+
+TableFuncColumnResponse:
+	"NULL"
+	{
+		$$ = ast.TableFuncColumnResponse{Tp: ast.TableFuncColumnResponseNull}
+	}
+|	"DEFAULT" stringLit
+	{
+		$$ = ast.TableFuncColumnResponse{Tp: ast.TableFuncColumnResponseDefault, Default: $2}
+	}
+|	"ERROR"
+	{
+		$$ = ast.TableFuncColumnResponse{Tp: ast.TableFuncColumnResponseError}
+	}
+
 TableAsNameOpt:
 	{
 		$$ = model.CIStr{}
@@ -3460,11 +3896,11 @@ TableAsNameOpt:
 TableAsName:
 	Identifier
 	{
-		$$ = model.NewCIStr($1)
+		$$ = model.NewTableCIStr($1)
 	}
 |	"AS" Identifier
 	{
-		$$ = model.NewCIStr($2)
+		$$ = model.NewTableCIStr($2)
 	}
 
 IndexHintType:
@@ -3560,6 +3996,15 @@ JoinTable:
 		on := &ast.OnCondition{Expr: $7.(ast.ExprNode)}
 		$$ = &ast.Join{Left: $1.(ast.ResultSetNode), Right: $5.(ast.ResultSetNode), Tp: $2.(ast.JoinType), On: on}
 	}
+|	TableRef "STRAIGHT_JOIN" TableRef %prec tableRefPriority
+	{
+		$$ = &ast.Join{Left: $1.(ast.ResultSetNode), Right: $3.(ast.ResultSetNode), Tp: ast.CrossJoin, StraightJoin: true}
+	}
+|	TableRef "STRAIGHT_JOIN" TableRef "ON" Expression
+	{
+		on := &ast.OnCondition{Expr: $5.(ast.ExprNode)}
+		$$ = &ast.Join{Left: $1.(ast.ResultSetNode), Right: $3.(ast.ResultSetNode), Tp: ast.CrossJoin, On: on, StraightJoin: true}
+	}
 	/* Support Using */
 
 JoinType:
@@ -3622,10 +4067,24 @@ SelectStmtDistinct:
 	}
 
 SelectStmtOpts:
-	SelectStmtDistinct SelectStmtSQLCache SelectStmtCalcFoundRows
+	TableOptimizerHints SelectStmtDistinct SelectStmtStraightJoin SelectStmtSQLCache SelectStmtCalcFoundRows
 	{
 		// TODO: return calc_found_rows opt and support more other options
-		$$ = $1
+		opts := &selectStmtOpts{distinct: $2.(bool), straightJoin: $3.(bool)}
+		if $1 != nil {
+			opts.tableHints = $1.([]*ast.TableOptimizerHint)
+		}
+		$$ = opts
+	}
+
+TableOptimizerHints:
+	/* empty */
+	{
+		$$ = nil
+	}
+|	hintComment
+	{
+		$$ = parseOptimizerHints($1)
 	}
 
 SelectStmtCalcFoundRows:
@@ -3637,6 +4096,15 @@ SelectStmtCalcFoundRows:
 	{
 		$$ = true
 	}
+SelectStmtStraightJoin:
+	{
+		$$ = false
+	}
+|	"STRAIGHT_JOIN"
+	{
+		$$ = true
+	}
+
 SelectStmtSQLCache:
 	%prec lowerThanSQLCache
 	{
@@ -3686,18 +4154,38 @@ SubSelect:
 	}
 
 // See https://dev.mysql.com/doc/refman/5.7/en/innodb-locking-reads.html
+// See https://dev.mysql.com/doc/refman/5.7/en/select-into.html
+SelectIntoStmt:
+	SelectStmt "INTO" "OUTFILE" stringLit Fields Lines
+	{
+		st := $1.(*ast.SelectStmt)
+		st.IntoOutfile = &ast.SelectIntoOutfile{
+			Path:       $4,
+			FieldsInfo: $5.(*ast.FieldsClause),
+			LinesInfo:  $6.(*ast.LinesClause),
+		}
+		$$ = st
+	}
+
 SelectLockOpt:
 	/* empty */
 	{
-		$$ = ast.SelectLockNone
+		$$ = &selectLockOpt{tp: ast.SelectLockNone}
 	}
 |	"FOR" "UPDATE"
 	{
-		$$ = ast.SelectLockForUpdate
+		$$ = &selectLockOpt{tp: ast.SelectLockForUpdate}
+	}
+|	"FOR" "UPDATE" "OF" TableNameList
+	{
+		$$ = &selectLockOpt{
+			tp:     ast.SelectLockForUpdate,
+			tables: &ast.SelectLockTableList{Tables: $4.([]*ast.TableName)},
+		}
 	}
 |	"LOCK" "IN" "SHARE" "MODE"
 	{
-		$$ = ast.SelectLockInShareMode
+		$$ = &selectLockOpt{tp: ast.SelectLockInShareMode}
 	}
 
 // See https://dev.mysql.com/doc/refman/5.7/en/union.html
@@ -3710,6 +4198,7 @@ UnionStmt:
 		endOffset := parser.endOffset(&yyS[yypt-2])
 		parser.setLastSelectFieldText(lastSelect, endOffset)
 		union.SelectList.Selects = append(union.SelectList.Selects, $4.(*ast.SelectStmt))
+		parser.moveUnionWith(union)
 		$$ = union
 	}
 |	UnionClauseList "UNION" UnionOpt '(' SelectStmt ')' OrderByOptional SelectStmtLimit
@@ -3729,6 +4218,7 @@ UnionStmt:
 		if $8 != nil {
 			union.Limit = $8.(*ast.Limit)
 		}
+		parser.moveUnionWith(union)
 		$$ = union
 	}
 
@@ -3971,6 +4461,18 @@ AdminStmt:
 	{
 		$$ = &ast.AdminStmt{Tp: ast.AdminShowDDL}
 	}
+|	"ADMIN" "SHOW" "DDL" "JOBS"
+	{
+		$$ = &ast.AdminStmt{Tp: ast.AdminShowDDLJobs}
+	}
+|	"ADMIN" "SHOW" "SLOW" "PLANS"
+	{
+		$$ = &ast.AdminStmt{Tp: ast.AdminShowSlowPlans}
+	}
+|	"ADMIN" "SHOW" "DELETE" "RANGES"
+	{
+		$$ = &ast.AdminStmt{Tp: ast.AdminShowDeleteRanges}
+	}
 |	"ADMIN" "CHECK" "TABLE" TableNameList
 	{
 		$$ = &ast.AdminStmt{
@@ -3978,6 +4480,75 @@ AdminStmt:
 			Tables: $4.([]*ast.TableName),
 		}
 	}
+|	"ADMIN" "DIAGNOSE" stringLit
+	{
+		$$ = &ast.AdminStmt{
+			Tp:	ast.AdminDiagnose,
+			Query:	$3,
+		}
+	}
+|	"ADMIN" "THROTTLE" "TABLE" TableName "LIMIT" LengthNum
+	{
+		$$ = &ast.AdminStmt{
+			Tp:		ast.AdminThrottleTable,
+			Tables:		[]*ast.TableName{$4.(*ast.TableName)},
+			ThrottleLimit:	$6.(uint64),
+		}
+	}
+|	"ADMIN" "THROTTLE" "TABLE" TableName "LIMIT" LengthNum "FOR" LengthNum TimeUnit
+	{
+		$$ = &ast.AdminStmt{
+			Tp:		ast.AdminThrottleTable,
+			Tables:		[]*ast.TableName{$4.(*ast.TableName)},
+			ThrottleLimit:	$6.(uint64),
+			ThrottleFor:	$8.(uint64),
+			ThrottleUnit:	$9,
+		}
+	}
+|	"ADMIN" "SET" "STATS" TableName "ROWS" LengthNum
+	{
+		$$ = &ast.AdminStmt{
+			Tp:		ast.AdminSetStats,
+			Tables:		[]*ast.TableName{$4.(*ast.TableName)},
+			StatsValue:	$6.(uint64),
+		}
+	}
+|	"ADMIN" "SET" "STATS" TableName "COLUMN" Identifier "DISTINCT" LengthNum
+	{
+		$$ = &ast.AdminStmt{
+			Tp:		ast.AdminSetStats,
+			Tables:		[]*ast.TableName{$4.(*ast.TableName)},
+			StatsColumn:	$6,
+			StatsValue:	$8.(uint64),
+		}
+	}
+|	"ADMIN" "RESET" "STATS"
+	{
+		$$ = &ast.AdminStmt{Tp: ast.AdminResetStats}
+	}
+|	"ADMIN" "RESET" "STATS" TableNameList
+	{
+		$$ = &ast.AdminStmt{
+			Tp:	ast.AdminResetStats,
+			Tables:	$4.([]*ast.TableName),
+		}
+	}
+|	"ADMIN" "RECOVER" "INDEX" TableName Identifier
+	{
+		$$ = &ast.AdminStmt{
+			Tp:	ast.AdminRecoverIndex,
+			Tables:	[]*ast.TableName{$4.(*ast.TableName)},
+			Index:	$5,
+		}
+	}
+|	"ADMIN" "CLEANUP" "INDEX" TableName Identifier
+	{
+		$$ = &ast.AdminStmt{
+			Tp:	ast.AdminCleanupIndex,
+			Tables:	[]*ast.TableName{$4.(*ast.TableName)},
+			Index:	$5,
+		}
+	}
 
 /****************************Show Statement*******************************/
 ShowStmt:
@@ -4033,6 +4604,13 @@ ShowStmt:
 			Tp: ast.ShowProcessList,
 		}
 	}
+|	"SHOW" "PLAN" "FOR" stringLit
+	{
+		$$ = &ast.ShowStmt{
+			Tp:	ast.ShowPlanForDigest,
+			Digest:	$4,
+		}
+	}
 
 ShowIndexKwd:
 	"INDEX"
@@ -4258,8 +4836,11 @@ Statement:
 |	CreateDatabaseStmt
 |	CreateIndexStmt
 |	CreateTableStmt
+|	CreateBindingStmt
 |	CreateUserStmt
+|	CreateViewStmt
 |	DoStmt
+|	DropBindingStmt
 |	DropDatabaseStmt
 |	DropIndexStmt
 |	DropTableStmt
@@ -4268,11 +4849,13 @@ Statement:
 |	FlushStmt
 |	GrantStmt
 |	InsertIntoStmt
+|	KillStmt
 |	LoadDataStmt
 |	PreparedStmt
 |	RollbackStmt
 |	ReplaceIntoStmt
 |	SelectStmt
+|	SelectIntoStmt
 |	UnionStmt
 |	SetStmt
 |	ShowStmt
@@ -4429,6 +5012,10 @@ TableOption:
 	{
 		$$ = &ast.TableOption{Tp: ast.TableOptionStatsPersistent}
 	}
+|	"STATS_NDV" EqOpt '(' StatsNDVList ')'
+	{
+		$$ = &ast.TableOption{Tp: ast.TableOptionStatsNDV, StatsNDVs: $4.([]*ast.StatsNDV)}
+	}
 
 StatsPersistentVal:
 	"DEFAULT"
@@ -4436,6 +5023,26 @@ StatsPersistentVal:
 |	LengthNum
 	{}
 
+StatsNDVList:
+	StatsNDV
+	{
+		$$ = []*ast.StatsNDV{$1.(*ast.StatsNDV)}
+	}
+|	StatsNDVList ',' StatsNDV
+	{
+		$$ = append($1.([]*ast.StatsNDV), $3.(*ast.StatsNDV))
+	}
+
+StatsNDV:
+	Identifier eq LengthNum
+	{
+		$$ = &ast.StatsNDV{Name: model.NewCIStr($1), NDV: $3.(uint64)}
+	}
+|	"INDEX" Identifier eq LengthNum
+	{
+		$$ = &ast.StatsNDV{Name: model.NewCIStr($2), IsIndex: true, NDV: $4.(uint64)}
+	}
+
 TableOptionListOpt:
 	{
 		$$ = []*ast.TableOption{}
@@ -5111,6 +5718,10 @@ PrivType:
 	{
 		$$ = mysql.CreateUserPriv
 	}
+|	"CREATE" "VIEW"
+	{
+		$$ = mysql.CreateViewPriv
+	}
 |	"DELETE"
 	{
 		$$ = mysql.DeletePriv
@@ -5123,6 +5734,10 @@ PrivType:
 	{
 		$$ = mysql.ExecutePriv
 	}
+|	"FILE"
+	{
+		$$ = mysql.FilePriv
+	}
 |	"INDEX"
 	{
 		$$ = mysql.IndexPriv
@@ -5131,6 +5746,10 @@ PrivType:
 	{
 		$$ = mysql.InsertPriv
 	}
+|	"PROCESS"
+	{
+		$$ = mysql.ProcessPriv
+	}
 |	"SELECT"
 	{
 		$$ = mysql.SelectPriv
@@ -5139,6 +5758,14 @@ PrivType:
 	{
 		$$ = mysql.ShowDBPriv
 	}
+|	"SHOW" "VIEW"
+	{
+		$$ = mysql.ShowViewPriv
+	}
+|	"SUPER"
+	{
+		$$ = mysql.SuperPriv
+	}
 |	"UPDATE"
 	{
 		$$ = mysql.UpdatePriv
@@ -5197,11 +5824,12 @@ PrivLevel:
  * See https://dev.mysql.com/doc/refman/5.7/en/load-data.html
  *******************************************************************************************/
 LoadDataStmt:
-	"LOAD" "DATA" LocalOpt "INFILE" stringLit "INTO" "TABLE" TableName Fields Lines
+	"LOAD" "DATA" LocalOpt "INFILE" stringLit "INTO" "TABLE" TableName Fields Lines IgnoreLines
 	{
 		x := &ast.LoadDataStmt{
-			Path:       $5,
-			Table:      $8.(*ast.TableName),
+			Path:        $5,
+			Table:       $8.(*ast.TableName),
+			IgnoreLines: $11.(uint64),
 		}
 		if $3 != nil {
 			x.IsLocal = true
@@ -5247,10 +5875,15 @@ Fields:
 		}else if len(str) != 0 {
 			enclosed = str[0]
 		}
+		// ESCAPED BY '' means there isn't an escape character.
+		var escaped byte
+		if len(escape) != 0 {
+			escaped = escape[0]
+		}
 		$$ = &ast.FieldsClause{
 			Terminated: $2.(string),
 			Enclosed:   enclosed,
-			Escaped:    escape[0],
+			Escaped:    escaped,
 		}
 	}
 
@@ -5311,6 +5944,18 @@ LinesTerminated:
 		$$ = $3
 	}
 
+IgnoreLines:
+	{
+		$$ = uint64(0)
+	}
+|	"IGNORE" LengthNum LinesOrRows
+	{
+		$$ = $2
+	}
+
+LinesOrRows:
+	"LINES" | "ROWS"
+
 
 /*********************************************************************
  * Lock/Unlock Tables
@@ -21,6 +21,8 @@ import (
 
 	. "github.com/pingcap/check"
 	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/util/testleak"
 )
 
@@ -48,13 +50,13 @@ func (s *testParserSuite) TestSimple(c *C) {
 		"exists", "explain", "false", "float", "for", "force", "foreign", "from",
 		"fulltext", "grant", "group", "having", "hour_microsecond", "hour_minute",
 		"hour_second", "if", "ignore", "in", "index", "infile", "inner", "insert", "int", "into", "integer",
-		"interval", "is", "join", "key", "keys", "leading", "left", "like", "limit", "lines", "load",
+		"interval", "is", "join", "key", "keys", "kill", "leading", "left", "like", "limit", "lines", "load",
 		"localtime", "localtimestamp", "lock", "longblob", "longtext", "mediumblob", "mediumint", "mediumtext",
 		"minute_microsecond", "minute_second", "mod", "not", "no_write_to_binlog", "null", "numeric",
 		"on", "option", "or", "order", "outer", "precision", "primary", "procedure", "read", "real",
 		"references", "regexp", "repeat", "replace", "restrict", "right", "rlike",
 		"schema", "schemas", "second_microsecond", "select", "set", "show", "smallint",
-		"starting", "table", "terminated", "then", "tinyblob", "tinyint", "tinytext", "to",
+		"starting", "straight_join", "table", "terminated", "then", "tinyblob", "tinyint", "tinytext", "to",
 		"trailing", "true", "union", "unique", "unlock", "unsigned",
 		"update", "use", "using", "utc_date", "values", "varbinary", "varchar",
 		"when", "where", "write", "xor", "year_month", "zerofill",
@@ -88,7 +90,8 @@ func (s *testParserSuite) TestSimple(c *C) {
 		"curtime", "variables", "dayname", "version", "btree", "hash", "row_format", "dynamic", "fixed", "compressed",
 		"compact", "redundant", "sql_no_cache sql_no_cache", "sql_cache sql_cache", "action", "round",
 		"enable", "disable", "reverse", "space", "privileges", "get_lock", "release_lock", "sleep", "no", "greatest",
-		"binlog", "hex", "unhex", "function", "indexes", "from_unixtime", "processlist",
+		"binlog", "hex", "unhex", "function", "indexes", "from_unixtime", "processlist", "plan", "query",
+		"process", "file", "super", "sql", "security", "definer", "invoker",
 	}
 	for _, kw := range unreservedKws {
 		src := fmt.Sprintf("SELECT %s FROM tbl;", kw)
@@ -276,10 +279,25 @@ func (s *testParserSuite) TestDMLStmt(c *C) {
 		{"load data local infile '/tmp/t.csv' into table t lines starting by 'ab' terminated by 'xy'", true},
 		{"load data local infile '/tmp/t.csv' into table t fields terminated by 'ab' lines terminated by 'xy'", true},
 		{"load data local infile '/tmp/t.csv' into table t terminated by 'xy' fields terminated by 'ab'", false},
+		{"load data local infile '/tmp/t.csv' into table t ignore 1 lines", true},
+		{"load data local infile '/tmp/t.csv' into table t fields terminated by ',' lines terminated by '\\n' ignore 2 rows", true},
+		{"load data local infile '/tmp/t.csv' into table t ignore lines", false},
+		{"load data local infile '/tmp/t.csv' into table t ignore 1 lines fields terminated by 'ab'", false},
+		{"select * from t into outfile '/tmp/t.csv'", true},
+		{"select a, b from t where a > 1 order by a limit 10 into outfile 't.csv' fields terminated by ',' enclosed by '\"' lines terminated by '\\r\\n'", true},
+		{"select 1 into outfile 't.csv' lines starting by '>'", true},
+		{"select 1 into outfile 't.csv' fields escaped by ''", true},
+		{"select * from t into outfile", false},
+		{"select * from (select * from t into outfile 't.csv') t", false},
 
 		// Select for update
 		{"SELECT * from t for update", true},
 		{"SELECT * from t lock in share mode", true},
+		{"SELECT * from t1 join t2 on t1.a = t2.a for update of t1", true},
+		{"SELECT * from t1 as a, db.t2 for update of a, db.t2", true},
+		{"SELECT * from t for update of", false},
+		{"SELECT * from t lock in share mode of t", false},
+		{"SELECT 1 as of", true},
 
 		// For alter table
 		{"ALTER TABLE t ADD COLUMN a SMALLINT UNSIGNED", true},
@@ -294,10 +312,38 @@ func (s *testParserSuite) TestDMLStmt(c *C) {
 		{"select * from t1 join t2 left join t3 on t2.id = t3.id", true},
 		{"select * from t1 right join t2 on t1.id = t2.id left join t3 on t3.id = t2.id", true},
 		{"select * from t1 right join t2 on t1.id = t2.id left join t3", false},
+		{"select * from t1 straight_join t2 on t1.id = t2.id straight_join t3", true},
+		{"select distinct straight_join * from t1, t2 where t1.id = t2.id", true},
+		{"select straight_join distinct * from t1, t2", false},
+		{"select * from t1 left straight_join t2 on t1.id = t2.id", false},
 
 		// For admin
 		{"admin show ddl;", true},
+		{"admin show ddl jobs;", true},
+		{"admin show slow plans;", true},
+		{"admin show slow;", false},
+		{"admin show delete ranges;", true},
+		{"admin show delete;", false},
 		{"admin check table t1, t2;", true},
+		{"admin diagnose 'select * from t where a = 1';", true},
+		{"admin diagnose;", false},
+		{"admin throttle table t1 limit 100;", true},
+		{"admin throttle table test.t1 limit 100 for 10 minute;", true},
+		{"admin throttle table t1 limit 0;", true},
+		{"admin throttle table t1, t2 limit 100;", false},
+		{"admin throttle table t1;", false},
+		{"admin set stats t1 rows 100000000;", true},
+		{"admin set stats test.t1 column c distinct 1000;", true},
+		{"admin set stats t1 column c rows 1000;", false},
+		{"admin set stats t1, t2 rows 10;", false},
+		{"admin reset stats;", true},
+		{"admin reset stats t1, test.t2;", true},
+		{"admin recover index t1 idx;", true},
+		{"admin recover index test.t1 idx;", true},
+		{"admin recover index t1;", false},
+		{"admin cleanup index t1 idx;", true},
+		{"admin cleanup index t1, t2 idx;", false},
+		{"select recover, cleanup from t;", true},
 
 		// For on duplicate key update
 		{"INSERT INTO t (a,b,c) VALUES (1,2,3),(4,5,6) ON DUPLICATE KEY UPDATE c=VALUES(a)+VALUES(b);", true},
@@ -315,6 +361,9 @@ func (s *testParserSuite) TestDMLStmt(c *C) {
 		{"DELETE t1, t2 FROM t1 INNER JOIN t2 INNER JOIN t3 WHERE t1.id=t2.id AND t2.id=t3.id;", true},
 		{"DELETE FROM t1, t2 USING t1 INNER JOIN t2 INNER JOIN t3 WHERE t1.id=t2.id AND t2.id=t3.id;", true},
 		{"DELETE t1, t2 FROM t1 INNER JOIN t2 INNER JOIN t3 WHERE t1.id=t2.id AND t2.id=t3.id limit 10;", false},
+		{"DELETE t1.*, test.t2.* FROM t1 INNER JOIN t2 WHERE t1.id=t2.id;", true},
+		{"DELETE FROM t1.*, t2 USING t1 INNER JOIN t2 WHERE t1.id=t2.id;", true},
+		{"DELETE t1.* FROM t1.*;", false},
 
 		// For update statement
 		{"UPDATE t SET id = id + 1 ORDER BY id DESC;", true},
@@ -339,6 +388,16 @@ func (s *testParserSuite) TestDMLStmt(c *C) {
 
 		{`ANALYZE TABLE t`, true},
 
+		// For explain
+		{"explain select * from t", true},
+		{"explain analyze select * from t where c > 1", true},
+		{"explain analyze update t set c = 1", true},
+		{"explain analyze t", false},
+		{"explain format = 'json' select * from t", true},
+		{"explain format = dot delete from t where c = 1", true},
+		{"explain format = json t", false},
+		{"select format from t", true},
+
 		// For Binlog stmt
 		{`BINLOG '
 BxSFVw8JAAAA8QAAAPUAAAAAAAQANS41LjQ0LU1hcmlhREItbG9nAAAAAAAAAAAAAAAAAAAAAAAA
@@ -393,6 +452,8 @@ func (s *testParserSuite) TestDBAStmt(c *C) {
 		// For show create table
 		{"show create table test.t", true},
 		{"show create table t", true},
+		{"show plan for 'a9c2f1'", true},
+		{"show plan for select 1", false},
 
 		// set
 		// user defined
@@ -446,6 +507,14 @@ func (s *testParserSuite) TestDBAStmt(c *C) {
 		{"flush table with read lock", true},
 		{"flush tables tbl1, tbl2, tbl3", true},
 		{"flush tables tbl1, tbl2, tbl3 with read lock", true},
+
+		// For KILL statement
+		{"kill 23123", true},
+		{"kill connection 23123", true},
+		{"kill query 23123", true},
+		{"kill", false},
+		{"kill query", false},
+		{"kill query -1", false},
 	}
 	s.RunTest(c, table)
 }
@@ -698,6 +767,18 @@ func (s *testParserSuite) TestBuiltin(c *C) {
 		{`select adddate("2011-11-11 10:10:10.123456", 0.10)`, true},
 		{`select adddate("2011-11-11 10:10:10.123456", "11,11")`, true},
 
+		// For the interval operators
+		{`select c + interval 1 day from t`, true},
+		{`select c - interval d + 1 month from t`, true},
+		{`select interval -1 year + c from t`, true},
+		{`select c + interval 1 day + interval 1 hour from t`, true},
+		{`select * from t where c > now() - interval "1:1" hour_minute`, true},
+		{`select date_add(c, interval d day), adddate(c, interval -d week) from t`, true},
+		{`select c + interval 1 from t`, false},
+		{`select interval 1 day from t`, false},
+		{`select 1 - interval 1 day + c from t`, true},
+		{`select interval 1 day - c from t`, false},
+
 		// For date_sub
 		{`select date_sub("2011-11-11 10:10:10.123456", interval 10 microsecond)`, true},
 		{`select date_sub("2011-11-11 10:10:10.123456", interval 10 second)`, true},
@@ -745,6 +826,18 @@ func (s *testParserSuite) TestBuiltin(c *C) {
 		{`select adddate("2011-11-11 10:10:10.123456", 0.10)`, true},
 		{`select adddate("2011-11-11 10:10:10.123456", "11,11")`, true},
 
+		// For the interval operators
+		{`select c + interval 1 day from t`, true},
+		{`select c - interval d + 1 month from t`, true},
+		{`select interval -1 year + c from t`, true},
+		{`select c + interval 1 day + interval 1 hour from t`, true},
+		{`select * from t where c > now() - interval "1:1" hour_minute`, true},
+		{`select date_add(c, interval d day), adddate(c, interval -d week) from t`, true},
+		{`select c + interval 1 from t`, false},
+		{`select interval 1 day from t`, false},
+		{`select 1 - interval 1 day + c from t`, true},
+		{`select interval 1 day - c from t`, false},
+
 		// For misc functions
 		{`SELECT GET_LOCK('lock1',10);`, true},
 		{`SELECT RELEASE_LOCK('lock1');`, true},
@@ -839,6 +932,11 @@ func (s *testParserSuite) TestDDL(c *C) {
 		{"create table t (c int) STATS_PERSISTENT = default", true},
 		{"create table t (c int) STATS_PERSISTENT = 0", true},
 		{"create table t (c int) STATS_PERSISTENT = 1", true},
+		{"create table t (c int, key (c)) STATS_NDV = (c = 10, index c = 10)", true},
+		{"create table t (c int) STATS_NDV (`c`=10)", true},
+		{"create table t (c int) STATS_NDV = ()", false},
+		{"create table t (c int) STATS_NDV = c = 10", false},
+		{"alter table t STATS_NDV = (c = 0)", true},
 		// For check clause
 		{"create table t (c1 bool, c2 bool, check (c1 in (0, 1)), check (c2 in (0, 1)))", true},
 		{"CREATE TABLE Customer (SD integer CHECK (SD > 0), First_Name varchar(30));", true},
@@ -863,6 +961,26 @@ func (s *testParserSuite) TestDDL(c *C) {
 		{"drop table if exists xxx", true},
 		{"drop table if not exists xxx", false},
 		{"drop view if exists xxx", true},
+		{"drop view xxx", true},
+		{"create view v as select * from t", true},
+		{"create or replace view v as select a, b from t where a > 1", true},
+		{"create view v (x, y) as select a, b from t", true},
+		{"create view v (x, y) as select a from t union select b from t", true},
+		{"create view v () as select 1", false},
+		{"create view v as", false},
+		{"create sql security definer view v as select * from t", true},
+		{"create or replace sql security invoker view v as select * from t", true},
+		{"create sql security view v as select * from t", false},
+		{"create view v sql security invoker as select * from t", false},
+		// For create table like and create table select
+		{"create table t like t1", true},
+		{"create table if not exists t (like db.t1)", true},
+		{"create table t like", false},
+		{"create table t (like t1, a int)", false},
+		{"create table t select * from t1", true},
+		{"create table t as select a, b from t1 where a > 1", true},
+		{"create table if not exists t engine = innodb as select a from t1 union select b from t2", true},
+		{"create table t as", false},
 		// For issue 974
 		{`CREATE TABLE address (
 		id bigint(20) NOT NULL AUTO_INCREMENT,
@@ -953,6 +1071,14 @@ union_id int(11) DEFAULT '0',
 PRIMARY KEY (union_name)) ENGINE=MyISAM DEFAULT CHARSET=binary;`, true},
 	}
 	s.RunTest(c, table)
+
+	parser := New()
+	stmt, err := parser.ParseOneStmt("create table t as select a from t1 where a > 1", "", "")
+	c.Assert(err, IsNil)
+	c.Assert(stmt.(*ast.CreateTableStmt).Select.Text(), Equals, "select a from t1 where a > 1")
+	stmt, err = parser.ParseOneStmt("create table t (like t1)", "", "")
+	c.Assert(err, IsNil)
+	c.Assert(stmt.(*ast.CreateTableStmt).ReferTable.Name.L, Equals, "t1")
 }
 
 func (s *testParserSuite) TestType(c *C) {
@@ -1010,6 +1136,8 @@ func (s *testParserSuite) TestPrivilege(c *C) {
 		{"GRANT SELECT ON db2.invoice TO 'jeffrey'@'localhost';", true},
 		{"GRANT ALL ON *.* TO 'someuser'@'somehost';", true},
 		{"GRANT SELECT, INSERT ON *.* TO 'someuser'@'somehost';", true},
+		{"GRANT PROCESS, FILE, SUPER ON *.* TO 'someuser'@'somehost';", true},
+		{"GRANT CREATE VIEW, SHOW VIEW ON mydb.* TO 'someuser'@'somehost';", true},
 		{"GRANT ALL ON mydb.* TO 'someuser'@'somehost';", true},
 		{"GRANT SELECT, INSERT ON mydb.* TO 'someuser'@'somehost';", true},
 		{"GRANT ALL ON mydb.mytbl TO 'someuser'@'somehost';", true},
@@ -1077,6 +1205,34 @@ func (s *testParserSuite) TestUnion(c *C) {
 	s.RunTest(c, table)
 }
 
+func (s *testParserSuite) TestCommonTableExpr(c *C) {
+	defer testleak.AfterTest(c)()
+	table := []testCase{
+		{"with c as (select 1) select * from c", true},
+		{"with c (a, b) as (select 1, 2) select a, b from c", true},
+		{"with c1 as (select 1), c2 as (select * from c1) select * from c2", true},
+		{"with c as (select 1 union select 2) select * from c", true},
+		{"with c as (select 1) select * from c union select * from c", true},
+		{"select * from (with c as (select 1) select * from c) as t", true},
+		{"select (with c as (select 1) select * from c)", true},
+		{"insert into t with c as (select 1) select * from c", true},
+		{"with c as select 1 select * from c", false},
+		{"with c () as (select 1) select * from c", false},
+		{"with c as (select 1) with d as (select 2) select 1", false},
+		{"with c as (select 1)", false},
+	}
+	s.RunTest(c, table)
+
+	// The WITH clause before a union is visible to all the select statements.
+	parser := New()
+	stmt, err := parser.ParseOneStmt("with c as (select 1) select * from c union select * from c", "", "")
+	c.Assert(err, IsNil)
+	union := stmt.(*ast.UnionStmt)
+	c.Assert(union.With, NotNil)
+	c.Assert(union.With.CTEs[0].Name.L, Equals, "c")
+	c.Assert(union.SelectList.Selects[0].With, IsNil)
+}
+
 func (s *testParserSuite) TestLikeEscape(c *C) {
 	defer testleak.AfterTest(c)()
 	table := []testCase{
@@ -1117,6 +1273,146 @@ func (s *testParserSuite) TestIndexHint(c *C) {
 	s.RunTest(c, table)
 }
 
+func (s *testParserSuite) TestOptimizerHints(c *C) {
+	defer testleak.AfterTest(c)()
+	table := []testCase{
+		{`select /*+ DISABLE_RULES(join_reorder) */ * from t`, true},
+		{`select /*+ DISABLE_RULES(join_reorder, agg_pushdown) */ distinct c from t where c > 1`, true},
+		{`select /*+ */ 1`, true},
+		{`select /*+ not a hint */ 1`, true},
+		{`select 1 /*+ DISABLE_RULES(join_reorder) */ from t`, true},
+		{`select * from t where c in (select /*+ DISABLE_RULES(join_reorder) */ c from t)`, true},
+		{`select /*+ DISABLE_RULES(join_reorder) */ * from t union select /*+ DISABLE_RULES(agg_pushdown) */ * from t`, true},
+		{`select /*+ USE_INDEX(t1, idx1, idx2) IGNORE_INDEX(t2, idx3) */ * from t t1, t t2`, true},
+		{`select /*+ HASH_JOIN(t1) MERGE_JOIN(t2, t3) INL_JOIN(t4) LEADING(t3, t1) */ * from t1, t2, t3, t4`, true},
+	}
+	s.RunTest(c, table)
+
+	parser := New()
+	stmt, err := parser.ParseOneStmt("select /*+ DISABLE_RULES(join_reorder, `agg_pushdown`) Bad_Hint(t) broken */ c from t", "", "")
+	c.Assert(err, IsNil)
+	hints := stmt.(*ast.SelectStmt).TableHints
+	c.Assert(hints, HasLen, 2)
+	c.Assert(hints[0].HintName.L, Equals, "disable_rules")
+	c.Assert(hints[0].Args, DeepEquals, []model.CIStr{model.NewCIStr("join_reorder"), model.NewCIStr("agg_pushdown")})
+	c.Assert(hints[1].HintName.O, Equals, "Bad_Hint")
+
+	stmt, err = parser.ParseOneStmt("select 1 /*+ DISABLE_RULES(join_reorder) */", "", "")
+	c.Assert(err, IsNil)
+	c.Assert(stmt.(*ast.SelectStmt).TableHints, HasLen, 0)
+
+	stmt, err = parser.ParseOneStmt("select /*+ STRAIGHT_JOIN() */ straight_join * from t1 straight_join t2", "", "")
+	c.Assert(err, IsNil)
+	sel := stmt.(*ast.SelectStmt)
+	c.Assert(sel.StraightJoin, IsTrue)
+	c.Assert(sel.TableHints, HasLen, 1)
+	c.Assert(sel.TableHints[0].HintName.L, Equals, "straight_join")
+	c.Assert(sel.TableHints[0].Args, HasLen, 0)
+	c.Assert(sel.From.TableRefs.StraightJoin, IsTrue)
+
+	// The hints for the other query blocks.
+	stmt, err = parser.ParseOneStmt("select /*+ QB_NAME(qb1) USE_INDEX(@qb2 t1, idx) HASH_JOIN(t1@qb2, `t2` @ `qb2`) LEADING(t1@qb1, t2@qb2) */ 1", "", "")
+	c.Assert(err, IsNil)
+	hints = stmt.(*ast.SelectStmt).TableHints
+	c.Assert(hints, HasLen, 3)
+	c.Assert(hints[0].QBName.L, Equals, "")
+	c.Assert(hints[0].Args, DeepEquals, []model.CIStr{model.NewCIStr("qb1")})
+	c.Assert(hints[1].QBName.L, Equals, "qb2")
+	c.Assert(hints[1].Args, DeepEquals, []model.CIStr{model.NewCIStr("t1"), model.NewCIStr("idx")})
+	c.Assert(hints[2].QBName.L, Equals, "qb2")
+	c.Assert(hints[2].Args, DeepEquals, []model.CIStr{model.NewCIStr("t1"), model.NewCIStr("t2")})
+}
+
+func (s *testParserSuite) TestBinding(c *C) {
+	defer testleak.AfterTest(c)()
+	table := []testCase{
+		{`create binding for select * from t where a = 1 using select /*+ use_index(t, a) */ * from t where a = 1`, true},
+		{`create global binding for select * from t using select /*+ use_index(t) */ * from t`, true},
+		{`create session binding for select * from t using select * from t`, false},
+		{`create binding for select * from t`, false},
+		{`create binding for select * from t using select * from t union select * from t`, false},
+		{`drop binding for select * from t where a = 1`, true},
+		{`drop global binding for select * from t`, true},
+		{`drop binding select * from t`, false},
+		{`create table binding (binding int)`, true},
+	}
+	s.RunTest(c, table)
+
+	parser := New()
+	stmts, err := parser.Parse("create global binding for SELECT * FROM t WHERE a = 1  USING select /*+ use_index(t, a) */ * from t where a = 1 ;drop binding for select 1", "", "")
+	c.Assert(err, IsNil)
+	c.Assert(stmts, HasLen, 2)
+	create := stmts[0].(*ast.CreateBindingStmt)
+	c.Assert(create.OriginSel.Text(), Equals, "SELECT * FROM t WHERE a = 1")
+	c.Assert(create.HintedSel.Text(), Equals, "select /*+ use_index(t, a) */ * from t where a = 1")
+	c.Assert(create.HintedSel.TableHints, HasLen, 1)
+	drop := stmts[1].(*ast.DropBindingStmt)
+	c.Assert(drop.OriginSel.Text(), Equals, "select 1")
+
+	stmt, err := parser.ParseOneStmt("explain select * from t where a = 1", "", "")
+	c.Assert(err, IsNil)
+	c.Assert(stmt.(*ast.ExplainStmt).Stmt.Text(), Equals, "select * from t where a = 1")
+}
+
+func (s *testParserSuite) TestTableFunc(c *C) {
+	defer testleak.AfterTest(c)()
+	table := []testCase{
+		{"select * from generate_series(1, 10)", true},
+		{"select * from generate_series(1, 10, 2) as s", true},
+		{"select s.generate_series from generate_series(1, 1e7) s where s.generate_series > 5", true},
+		{"select * from t join generate_series(1, 3) g on t.c = g.generate_series", true},
+		{"insert into t select * from generate_series(1, 100)", true},
+		{"select * from generate_series()", true},
+		{"select * from generate_series(1, 10) use index (a)", false},
+		{"select * from json_table('[1, 2]', '$[*]' columns (a int path '$')) as jt", true},
+		{"select * from t, json_table(t.doc, '$.items[*]' columns (id for ordinality, name varchar(10) path '$.name', price decimal(10, 2) path '$.price')) jt", true},
+		{"select * from json_table(@doc, '$' columns (path int path '$.path', ordinality int path '$.ordinality')) as jt", true},
+		{"select * from json_table('[1]', '$[*]' columns ()) as jt", false},
+		{"select * from json_table('[1]', '$[*]' columns (a int)) as jt", false},
+		{"select * from json_table('[1]', '$[*]' columns (a int path 1)) as jt", false},
+		{"select * from json_table('{}', '$' columns (a int path '$.a' null on empty, b int path '$.b' error on error)) as jt", true},
+		{"select * from json_table('{}', '$' columns (a varchar(2) path '$.b' default 'x' on empty default 'y' on error)) as jt", true},
+		{"select * from json_table('{}', '$' columns (empty int path '$.empty', error int path '$.error')) as jt", true},
+		{"select * from json_table('{}', '$' columns (a int path '$.a' error on error null on empty)) as jt", false},
+		{"select * from json_table('{}', '$' columns (a int path '$.a' default 1 on empty)) as jt", false},
+		{"select * from json_table('{}', '$' columns (id for ordinality null on empty)) as jt", false},
+	}
+	s.RunTest(c, table)
+
+	parser := New()
+	stmt, err := parser.ParseOneStmt("select * from generate_series(1, 10, 2) as s", "", "")
+	c.Assert(err, IsNil)
+	ts := stmt.(*ast.SelectStmt).From.TableRefs.Left.(*ast.TableSource)
+	c.Assert(ts.AsName.L, Equals, "s")
+	tf, ok := ts.Source.(*ast.TableFunc)
+	c.Assert(ok, IsTrue)
+	c.Assert(tf.FnName.L, Equals, "generate_series")
+	c.Assert(tf.Args, HasLen, 3)
+	c.Assert(tf.Columns, IsNil)
+
+	stmt, err = parser.ParseOneStmt("select * from json_table(@doc, '$[*]' columns (id for ordinality, name varchar(10) path '$.name')) jt", "", "")
+	c.Assert(err, IsNil)
+	ts = stmt.(*ast.SelectStmt).From.TableRefs.Left.(*ast.TableSource)
+	c.Assert(ts.AsName.L, Equals, "jt")
+	tf = ts.Source.(*ast.TableFunc)
+	c.Assert(tf.FnName.L, Equals, "json_table")
+	c.Assert(tf.Args, HasLen, 2)
+	c.Assert(tf.Columns, HasLen, 2)
+	c.Assert(tf.Columns[0].ForOrdinality, IsTrue)
+	c.Assert(tf.Columns[1].Name.L, Equals, "name")
+	c.Assert(tf.Columns[1].Tp.Tp, Equals, mysql.TypeVarchar)
+	c.Assert(tf.Columns[1].Path, Equals, "$.name")
+	c.Assert(tf.Columns[1].OnEmpty.Tp, Equals, ast.TableFuncColumnResponseNull)
+	c.Assert(tf.Columns[1].OnError.Tp, Equals, ast.TableFuncColumnResponseNull)
+
+	stmt, err = parser.ParseOneStmt("select * from json_table(@doc, '$' columns (a int path '$.a' default '0' on empty error on error)) jt", "", "")
+	c.Assert(err, IsNil)
+	tf = stmt.(*ast.SelectStmt).From.TableRefs.Left.(*ast.TableSource).Source.(*ast.TableFunc)
+	c.Assert(tf.Columns, HasLen, 1)
+	c.Assert(tf.Columns[0].OnEmpty, Equals, ast.TableFuncColumnResponse{Tp: ast.TableFuncColumnResponseDefault, Default: "0"})
+	c.Assert(tf.Columns[0].OnError.Tp, Equals, ast.TableFuncColumnResponseError)
+}
+
 func (s *testParserSuite) TestEscape(c *C) {
 	defer testleak.AfterTest(c)()
 	table := []testCase{
@@ -1131,6 +1427,29 @@ func (s *testParserSuite) TestEscape(c *C) {
 	s.RunTest(c, table)
 }
 
+func (s *testParserSuite) TestNormalize(c *C) {
+	defer testleak.AfterTest(c)()
+	table := []struct {
+		sql        string
+		normalized string
+	}{
+		{"SELECT * FROM t WHERE a = 1", "select * from t where a = ?"},
+		{"select *  from T where A=2 and b = 'x' /* comment */;", "select * from t where a = ? and b = ?"},
+		{"select a-1.5, 0x10, b'1' from t", "select a - ? , ? , ? from t"},
+		{"insert into t values (1, \"a\")", "insert into t values ( ? , ? )"},
+	}
+	for _, t := range table {
+		c.Assert(Normalize(t.sql), Equals, t.normalized, Commentf("%s", t.sql))
+	}
+	c.Assert(Digest("select 1"), Equals, Digest("SELECT 2;"))
+	c.Assert(Digest("select 1"), Not(Equals), Digest("select 1 from t"))
+	c.Assert(IsDigest(Digest("select 1")), IsTrue)
+	normalized, digest := NormalizeDigest("SELECT 1")
+	c.Assert(normalized, Equals, "select ?")
+	c.Assert(digest, Equals, Digest("select 1"))
+	c.Assert(IsDigest("select 1"), IsFalse)
+}
+
 func (s *testParserSuite) TestInsertStatementMemoryAllocation(c *C) {
 	sql := "insert t values (1)" + strings.Repeat(",(1)", 1000)
 	var oldStats, newStats runtime.MemStats
@@ -1141,6 +1460,50 @@ func (s *testParserSuite) TestInsertStatementMemoryAllocation(c *C) {
 	c.Assert(int(newStats.TotalAlloc-oldStats.TotalAlloc), Less, 1024*500)
 }
 
+func (s *testParserSuite) TestSplitInsertValues(c *C) {
+	table := []struct {
+		sql    string
+		ok     bool
+		header string
+		rows   string
+		suffix string
+	}{
+		{"insert into t values (1), (2)", true, "insert into t values", " (1), (2)", ""},
+		{"INSERT t (a, b) VALUE (1, 'x,)'), ((2), f(3));", true, "INSERT t (a, b) VALUE", " (1, 'x,)'), ((2), f(3))", ""},
+		{"replace into t(a) values (1),(2) on duplicate key update a = values(a) ;", true, "replace into t(a) values", " (1),(2)",
+			"on duplicate key update a = values(a)"},
+		{"insert into t values ()", true, "insert into t values", " ()", ""},
+		{"insert into t select 1", false, "", "", ""},
+		{"insert into t set a = 1", false, "", "", ""},
+		{"insert into t values (1); select 1", false, "", "", ""},
+		{"insert into t values (1) (2)", false, "", "", ""},
+		{"insert into t values (1", false, "", "", ""},
+		{"insert /*!40000 ignore */ into t values (1)", false, "", "", ""},
+		{"select 1", false, "", "", ""},
+	}
+	for _, t := range table {
+		text, ok := SplitInsertValues(t.sql)
+		c.Assert(ok, Equals, t.ok, Commentf("%s", t.sql))
+		if !ok {
+			continue
+		}
+		c.Assert(text.Header, Equals, t.header, Commentf("%s", t.sql))
+		c.Assert(text.Rows, Equals, t.rows, Commentf("%s", t.sql))
+		c.Assert(text.Suffix, Equals, t.suffix, Commentf("%s", t.sql))
+	}
+
+	text, ok := SplitInsertValues("insert into t values (1), ('(2)'), (3)")
+	c.Assert(ok, IsTrue)
+	rows, next := text.NextRows(0, 2)
+	c.Assert(rows, Equals, " (1), ('(2)')")
+	rows, next = text.NextRows(next, 2)
+	c.Assert(rows, Equals, " (3)")
+	c.Assert(next, Equals, len(text.Rows))
+	rows, next = text.NextRows(0, 5)
+	c.Assert(rows, Equals, text.Rows)
+	c.Assert(next, Equals, len(text.Rows))
+}
+
 func BenchmarkParse(b *testing.B) {
 	var table = []string{
 		"insert into t values (1), (2), (3)",
@@ -125,6 +125,15 @@ func (parser *Parser) setLastSelectFieldText(st *ast.SelectStmt, lastEnd int) {
 	}
 }
 
+// moveUnionWith moves the WITH clause parsed with the first select statement of a union to the union, so the common
+// table expressions are visible to all the select statements.
+func (parser *Parser) moveUnionWith(union *ast.UnionStmt) {
+	first := union.SelectList.Selects[0]
+	if first.With != nil {
+		union.With, first.With = first.With, nil
+	}
+}
+
 func (parser *Parser) startOffset(v *yySymType) int {
 	return v.offset
 }
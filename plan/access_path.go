@@ -0,0 +1,154 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"strings"
+
+	"github.com/ngaut/log"
+	"github.com/pingcap/tidb/expression"
+	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/mysql"
+)
+
+// accessPath describes a way to read a DataSource by the dimensions skyline pruning compares.
+type accessPath struct {
+	// index is nil for the table scan.
+	index *model.IndexInfo
+	// accessCols are the names of the columns whose conditions are used to build the ranges.
+	accessCols map[string]struct{}
+	// matchOrder is true if the rows are returned in the order of the required property.
+	matchOrder bool
+	// singleRead is true if the rows are read without looking up the table by the handles.
+	singleRead bool
+}
+
+func (path *accessPath) name() string {
+	if path.index == nil {
+		return "table scan"
+	}
+	return "index " + path.index.Name.O
+}
+
+func (path *accessPath) accessColNames() string {
+	names := make([]string, 0, len(path.accessCols))
+	for name := range path.accessCols {
+		names = append(names, name)
+	}
+	return "[" + strings.Join(names, ",") + "]"
+}
+
+// dominates checks if the path is at least as good as the other path in every dimension and better in one of them,
+// so the other path can never be cheaper whatever the statistics say.
+func (path *accessPath) dominates(other *accessPath) bool {
+	for name := range other.accessCols {
+		if _, ok := path.accessCols[name]; !ok {
+			return false
+		}
+	}
+	if (other.matchOrder && !path.matchOrder) || (other.singleRead && !path.singleRead) {
+		return false
+	}
+	return len(path.accessCols) > len(other.accessCols) || path.matchOrder != other.matchOrder ||
+		path.singleRead != other.singleRead
+}
+
+// skylinePruning removes the indices which are dominated by another index or the table scan before their costs are
+// estimated. The reason why an index is pruned is logged at the debug level.
+func (p *DataSource) skylinePruning(prop *requiredProperty, indices []*model.IndexInfo, includeTableScan bool) []*model.IndexInfo {
+	sel, _ := p.GetParentByIndex(0).(*Selection)
+	paths := make([]*accessPath, 0, len(indices)+1)
+	for _, index := range indices {
+		paths = append(paths, p.buildIndexAccessPath(prop, sel, index))
+	}
+	if includeTableScan {
+		// The table scan is kept anyway, it only prunes the indices.
+		paths = append(paths, p.buildTableAccessPath(prop, sel))
+	}
+	kept := make([]*model.IndexInfo, 0, len(indices))
+	for i, path := range paths[:len(indices)] {
+		var dominator *accessPath
+		for j, other := range paths {
+			if i != j && other.dominates(path) {
+				dominator = other
+				break
+			}
+		}
+		if dominator != nil {
+			log.Debugf("[PLAN] %s of table %s is pruned, it's dominated by %s: access columns %s vs %s, match order %v vs %v, single read %v vs %v",
+				path.name(), p.Table.Name.O, dominator.name(), path.accessColNames(), dominator.accessColNames(),
+				path.matchOrder, dominator.matchOrder, path.singleRead, dominator.singleRead)
+			continue
+		}
+		kept = append(kept, path.index)
+	}
+	return kept
+}
+
+func (p *DataSource) buildIndexAccessPath(prop *requiredProperty, sel *Selection, index *model.IndexInfo) *accessPath {
+	// The index scan is only used to detach the conditions, so it doesn't take a plan ID.
+	is := &PhysicalIndexScan{Index: index, Table: p.Table, Columns: p.Columns}
+	path := &accessPath{
+		index:      index,
+		accessCols: make(map[string]struct{}),
+		singleRead: isCoveringIndex(is.Columns, index.Columns, is.Table.PKIsHandle),
+	}
+	if sel != nil {
+		conds := make([]expression.Expression, 0, len(sel.Conditions))
+		for _, cond := range sel.Conditions {
+			conds = append(conds, cond.Clone())
+		}
+		accessConds, _ := detachIndexScanConditions(conds, is)
+		// The eq and in conditions are on the first accessInAndEqCount columns, the rest are on the next column.
+		accessColCount := is.accessInAndEqCount
+		if len(accessConds) > accessColCount {
+			accessColCount++
+		}
+		for _, col := range index.Columns[:accessColCount] {
+			path.accessCols[col.Name.L] = struct{}{}
+		}
+	}
+	if len(prop.props) > 0 {
+		path.matchOrder, _ = is.matchOrder(prop)
+	}
+	return path
+}
+
+func (p *DataSource) buildTableAccessPath(prop *requiredProperty, sel *Selection) *accessPath {
+	path := &accessPath{
+		accessCols: make(map[string]struct{}),
+		singleRead: true,
+	}
+	if !p.Table.PKIsHandle {
+		return path
+	}
+	var pkName string
+	for _, col := range p.Table.Columns {
+		if mysql.HasPriKeyFlag(col.Flag) {
+			pkName = col.Name.L
+			break
+		}
+	}
+	if sel != nil {
+		conds := make([]expression.Expression, 0, len(sel.Conditions))
+		for _, cond := range sel.Conditions {
+			conds = append(conds, cond.Clone())
+		}
+		if accessConds, _ := detachTableScanConditions(conds, p.Table); len(accessConds) > 0 {
+			path.accessCols[pkName] = struct{}{}
+		}
+	}
+	path.matchOrder = len(prop.props) == 1 && prop.props[0].col.ColName.L == pkName
+	return path
+}
@@ -31,17 +31,16 @@ type aggPushDownSolver struct {
 // isDecomposable checks if an aggregate function is decomposable. An aggregation function $F$ is decomposable
 // if there exist aggregation functions F_1 and F_2 such that F(S_1 union all S_2) = F_2(F_1(S_1),F_1(S_2)),
 // where S_1 and S_2 are two sets of values. We call S_1 and S_2 partial groups.
-// It's easy to see that max, min, first row is decomposable, no matter whether it's distinct, but sum(distinct) and
-// count(distinct) is not.
-// Currently we don't support avg and concat.
+// It's easy to see that max, min, first row is decomposable, no matter whether it's distinct, but sum(distinct),
+// count(distinct) and avg(distinct) is not. Avg is decomposed to count and sum.
+// Currently we don't support concat.
 func (a *aggPushDownSolver) isDecomposable(fun expression.AggregationFunction) bool {
 	switch fun.GetName() {
-	case ast.AggFuncAvg, ast.AggFuncGroupConcat:
-		// TODO: Support avg push down.
+	case ast.AggFuncGroupConcat:
 		return false
 	case ast.AggFuncMax, ast.AggFuncMin, ast.AggFuncFirstRow:
 		return true
-	case ast.AggFuncSum, ast.AggFuncCount:
+	case ast.AggFuncSum, ast.AggFuncCount, ast.AggFuncAvg:
 		return !fun.IsDistinct()
 	default:
 		return false
@@ -172,8 +171,21 @@ func (a *aggPushDownSolver) checkValidJoin(join *Join) bool {
 // decompose splits an aggregate function to two parts: a final mode function and a partial mode function. Currently
 // there are no differences between partial mode and complete mode, so we can confuse them.
 func (a *aggPushDownSolver) decompose(aggFunc expression.AggregationFunction, schema expression.Schema, id string) ([]expression.AggregationFunction, expression.Schema) {
-	// Result is a slice because avg should be decomposed to sum and count. Currently we don't process this case.
-	result := []expression.AggregationFunction{aggFunc.Clone()}
+	// Result is a slice because avg should be decomposed to count and sum, which are the arguments of the final avg.
+	var result []expression.AggregationFunction
+	if aggFunc.GetName() == ast.AggFuncAvg {
+		var countArgs, sumArgs []expression.Expression
+		for _, arg := range aggFunc.GetArgs() {
+			countArgs = append(countArgs, arg.Clone())
+			sumArgs = append(sumArgs, arg.Clone())
+		}
+		result = []expression.AggregationFunction{
+			expression.NewAggFunction(ast.AggFuncCount, countArgs, false),
+			expression.NewAggFunction(ast.AggFuncSum, sumArgs, false),
+		}
+	} else {
+		result = []expression.AggregationFunction{aggFunc.Clone()}
+	}
 	for _, aggFunc := range result {
 		schema = append(schema, &expression.Column{
 			ColName:  model.NewCIStr(fmt.Sprintf("join_agg_%d", len(schema))), // useless but for debug
@@ -187,6 +199,51 @@ func (a *aggPushDownSolver) decompose(aggFunc expression.AggregationFunction, sc
 	return result, schema
 }
 
+// isUniqueKey checks if the group-by columns contain a unique key of the child, which means there are no duplicated
+// group-by values and pushing the aggregation down can't reduce the rows joined. Only the data source is checked.
+func (a *aggPushDownSolver) isUniqueKey(child LogicalPlan, gbyCols []*expression.Column) bool {
+	ds, ok := child.(*DataSource)
+	if !ok {
+		return false
+	}
+	return containsUniqueKey(ds, gbyCols)
+}
+
+// containsUniqueKey checks if the columns contain the handle or a not null unique index of the data source.
+func containsUniqueKey(ds *DataSource, gbyCols []*expression.Column) bool {
+	gbyColNames := make(map[string]bool, len(gbyCols))
+	for _, col := range gbyCols {
+		if idx := ds.GetSchema().GetIndex(col); idx != -1 {
+			gbyColNames[ds.Columns[idx].Name.L] = true
+		}
+	}
+	if ds.Table.PKIsHandle {
+		for _, col := range ds.Table.Columns {
+			if mysql.HasPriKeyFlag(col.Flag) && gbyColNames[col.Name.L] {
+				return true
+			}
+		}
+	}
+	for _, idx := range ds.Table.Indices {
+		if !idx.Unique || idx.State != model.StatePublic {
+			continue
+		}
+		unique := true
+		for _, idxCol := range idx.Columns {
+			col := ds.Table.Columns[idxCol.Offset]
+			// The NULL values in a unique index can be duplicated.
+			if !gbyColNames[idxCol.Name.L] || !mysql.HasNotNullFlag(col.Flag) || idxCol.Length != types.UnspecifiedLength {
+				unique = false
+				break
+			}
+		}
+		if unique {
+			return true
+		}
+	}
+	return false
+}
+
 func (a *aggPushDownSolver) allFirstRow(aggFuncs []expression.AggregationFunction) bool {
 	for _, fun := range aggFuncs {
 		if fun.GetName() != ast.AggFuncFirstRow {
@@ -201,7 +258,7 @@ func (a *aggPushDownSolver) allFirstRow(aggFuncs []expression.AggregationFunctio
 // operator.
 func (a *aggPushDownSolver) tryToPushDownAgg(aggFuncs []expression.AggregationFunction, gbyCols []*expression.Column, join *Join, childIdx int) LogicalPlan {
 	child := join.GetChildByIndex(childIdx).(LogicalPlan)
-	if a.allFirstRow(aggFuncs) {
+	if a.allFirstRow(aggFuncs) || a.isUniqueKey(child, gbyCols) {
 		return child
 	}
 	agg := &Aggregation{
@@ -261,7 +318,8 @@ func (a *aggPushDownSolver) getDefaultValues(agg *Aggregation) ([]types.Datum, b
 
 func (a *aggPushDownSolver) checkAnyCountAndSum(aggFuncs []expression.AggregationFunction) bool {
 	for _, fun := range aggFuncs {
-		if fun.GetName() == ast.AggFuncSum || fun.GetName() == ast.AggFuncCount {
+		switch fun.GetName() {
+		case ast.AggFuncSum, ast.AggFuncCount, ast.AggFuncAvg:
 			return true
 		}
 	}
@@ -0,0 +1,114 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/expression"
+)
+
+// simplifyBoolean removes tautologies and contradictions from a list of CNF conditions.
+// e.g. "a > 1 and 1" becomes "a > 1", "a > 1 or 0" becomes "a > 1", "not(not(a > 1))" becomes "a > 1",
+// and "not(a > 1 and b < 2)" becomes "a <= 1 or b >= 2".
+// If one of the conditions is always false, the whole list is replaced by that single constant.
+// The duplicated conditions are removed, e.g. "a > 1 and a > 1" becomes "a > 1".
+func simplifyBoolean(conditions []expression.Expression) []expression.Expression {
+	result := make([]expression.Expression, 0, len(conditions))
+	interner := make(exprInterner, len(conditions))
+	for _, cond := range conditions {
+		cond = simplifyBooleanExpr(pushDownNot(cond, false))
+		for _, item := range expression.SplitCNFItems(cond) {
+			if con, ok := item.(*expression.Constant); ok {
+				// A NULL condition filters all the rows just like a false one.
+				isTrue, known := constantTruth(con)
+				if known && isTrue {
+					continue
+				}
+				if !known && !con.Value.IsNull() {
+					result = append(result, item)
+					continue
+				}
+				return []expression.Expression{con}
+			}
+			if _, dup := interner.intern(item); dup {
+				continue
+			}
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// simplifyBooleanExpr folds the constant operands of logical operators in an expression.
+// NOT should have been pushed down before, so we only need to deal with AND and OR here.
+func simplifyBooleanExpr(expr expression.Expression) expression.Expression {
+	f, ok := expr.(*expression.ScalarFunction)
+	if !ok {
+		return expr
+	}
+	switch f.FuncName.L {
+	case ast.AndAnd, ast.OrOr:
+		isAnd := f.FuncName.L == ast.AndAnd
+		left, right := simplifyBooleanExpr(f.Args[0]), simplifyBooleanExpr(f.Args[1])
+		if newExpr, ok := foldLogicOperand(left, right, isAnd); ok {
+			return newExpr
+		}
+		if newExpr, ok := foldLogicOperand(right, left, isAnd); ok {
+			return newExpr
+		}
+		f.SetArg(0, left)
+		f.SetArg(1, right)
+		return f
+	case ast.UnaryNot:
+		arg := simplifyBooleanExpr(f.Args[0])
+		if _, ok := arg.(*expression.Constant); ok {
+			newExpr, _ := expression.NewFunction(ast.UnaryNot, f.RetType, arg)
+			return newExpr
+		}
+		f.SetArg(0, arg)
+		return f
+	}
+	return expr
+}
+
+// foldLogicOperand tries to fold "operand AND other" or "operand OR other" when operand is a constant.
+// For AND, a true operand yields other and a false operand yields false.
+// For OR, a false operand yields other and a true operand yields true.
+func foldLogicOperand(operand, other expression.Expression, isAnd bool) (expression.Expression, bool) {
+	con, ok := operand.(*expression.Constant)
+	if !ok {
+		return nil, false
+	}
+	isTrue, known := constantTruth(con)
+	if !known {
+		return nil, false
+	}
+	if isTrue == isAnd {
+		return other, true
+	}
+	return con, true
+}
+
+// constantTruth returns the boolean value of a constant, known is false if the constant is NULL
+// or cannot be converted to a boolean value.
+func constantTruth(con *expression.Constant) (isTrue bool, known bool) {
+	if con.Value.IsNull() {
+		return false, false
+	}
+	i, err := con.Value.ToBool()
+	if err != nil {
+		return false, false
+	}
+	return i != 0, true
+}
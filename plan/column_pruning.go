@@ -91,6 +91,14 @@ func (p *Sort) PruneColumns(parentUsedCols []*expression.Column) {
 	p.SetSchema(p.GetChildByIndex(0).GetSchema())
 }
 
+// PruneColumns implements LogicalPlan interface.
+// All the columns are used to check the duplicated rows, even if the parent doesn't use them.
+func (p *Distinct) PruneColumns(_ []*expression.Column) {
+	child := p.GetChildByIndex(0).(LogicalPlan)
+	child.PruneColumns(p.GetSchema())
+	p.SetSchema(child.GetSchema())
+}
+
 // PruneColumns implements LogicalPlan interface.
 func (p *Union) PruneColumns(parentUsedCols []*expression.Column) {
 	used := getUsedList(parentUsedCols, p.GetSchema())
@@ -129,6 +137,26 @@ func (p *DataSource) PruneColumns(parentUsedCols []*expression.Column) {
 func (p *TableDual) PruneColumns(_ []*expression.Column) {
 }
 
+// PruneColumns implements LogicalPlan interface.
+func (p *GenerateSeries) PruneColumns(_ []*expression.Column) {
+}
+
+// PruneColumns implements LogicalPlan interface.
+func (p *JSONTable) PruneColumns(_ []*expression.Column) {
+}
+
+// PruneColumns implements LogicalPlan interface.
+func (p *Profile) PruneColumns(_ []*expression.Column) {
+}
+
+// PruneColumns implements LogicalPlan interface.
+func (p *RowHistory) PruneColumns(_ []*expression.Column) {
+}
+
+// PruneColumns implements LogicalPlan interface.
+func (p *CTE) PruneColumns(_ []*expression.Column) {
+}
+
 // PruneColumns implements LogicalPlan interface.
 func (p *Trim) PruneColumns(parentUsedCols []*expression.Column) {
 	used := getUsedList(parentUsedCols, p.schema)
@@ -197,18 +225,21 @@ func (p *Join) PruneColumns(parentUsedCols []*expression.Column) {
 // Then after pruning inner plan, the childOuterUsedCols schema in apply becomes (id).
 // Now there're two columns in parentUsedCols, c is the column from Apply's child ---- TableScan, but extra isn't.
 // So only c in parentUsedCols and id in outerSchema can be passed to TableScan.
+// The inner plan is pruned the same way, only the inner columns used by the parent or the checker are kept.
 func (p *Apply) PruneColumns(parentUsedCols []*expression.Column) {
 	child := p.GetChildByIndex(0).(LogicalPlan)
-	var usedCols []*expression.Column
+	var usedCols, innerUsedCols []*expression.Column
 	if p.Checker != nil {
 		parentUsedCols = append(parentUsedCols, extractColumns(p.Checker.Condition)...)
 	}
 	for _, col := range parentUsedCols {
 		if child.GetSchema().GetIndex(col) != -1 {
 			usedCols = append(usedCols, col)
+		} else if p.InnerPlan.GetSchema().GetIndex(col) != -1 {
+			innerUsedCols = append(innerUsedCols, col)
 		}
 	}
-	p.InnerPlan.PruneColumns(p.InnerPlan.GetSchema())
+	p.InnerPlan.PruneColumns(innerUsedCols)
 	corCols := p.InnerPlan.extractCorrelatedCols()
 	for _, corCol := range corCols {
 		idx := child.GetSchema().GetIndex(&corCol.Column)
@@ -0,0 +1,125 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"sort"
+
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/expression"
+	"github.com/pingcap/tidb/util/types"
+)
+
+// The estimated costs of evaluating a function once, an operand costs nothing.
+const (
+	cheapFuncCost     = 1.0
+	stringCompareCost = 3.0
+	defaultFuncCost   = 5.0
+	likeCost          = 20.0
+	regexpCost        = 50.0
+)
+
+// cheapFuncs are the functions which only do simple arithmetic or logical operations.
+var cheapFuncs = map[string]struct{}{
+	ast.AndAnd:     {},
+	ast.OrOr:       {},
+	ast.LogicXor:   {},
+	ast.UnaryNot:   {},
+	ast.IsNull:     {},
+	ast.IsTruth:    {},
+	ast.IsFalsity:  {},
+	ast.Plus:       {},
+	ast.Minus:      {},
+	ast.Mul:        {},
+	ast.UnaryMinus: {},
+	ast.UnaryPlus:  {},
+}
+
+// compareFuncs are the functions which compare their arguments.
+var compareFuncs = map[string]struct{}{
+	ast.EQ:     {},
+	ast.NE:     {},
+	ast.LT:     {},
+	ast.LE:     {},
+	ast.GT:     {},
+	ast.GE:     {},
+	ast.NullEQ: {},
+	ast.In:     {},
+}
+
+// SortConditionsByRank returns the CNF conditions in the order they should be evaluated with short-circuit,
+// i.e. the cheap and selective ones first, so a row is filtered out by evaluating as few conditions as possible.
+// A condition is ranked by cost / (1 - selectivity), which is the expected cost of the condition to filter out
+// a row. The conditions with the same rank keep their original order, and the input isn't changed.
+func SortConditionsByRank(conditions []expression.Expression) []expression.Expression {
+	items := make(rankedConditions, 0, len(conditions))
+	for _, cond := range conditions {
+		selectivity := 0.9
+		if f, ok := cond.(*expression.ScalarFunction); ok {
+			selectivity = conditionSelectivity(f)
+		}
+		items = append(items, rankedCondition{cond: cond, rank: evalCost(cond) / (1 - selectivity)})
+	}
+	sort.Stable(items)
+	sorted := make([]expression.Expression, 0, len(items))
+	for _, item := range items {
+		sorted = append(sorted, item.cond)
+	}
+	return sorted
+}
+
+type rankedCondition struct {
+	cond expression.Expression
+	rank float64
+}
+
+type rankedConditions []rankedCondition
+
+func (s rankedConditions) Len() int           { return len(s) }
+func (s rankedConditions) Less(i, j int) bool { return s[i].rank < s[j].rank }
+func (s rankedConditions) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// evalCost estimates the cost of evaluating an expression for a row.
+func evalCost(expr expression.Expression) float64 {
+	f, ok := expr.(*expression.ScalarFunction)
+	if !ok {
+		return 0
+	}
+	cost := 0.0
+	for _, arg := range f.Args {
+		cost += evalCost(arg)
+	}
+	name := f.FuncName.L
+	if _, ok := cheapFuncs[name]; ok {
+		return cost + cheapFuncCost
+	}
+	if _, ok := compareFuncs[name]; ok {
+		// Comparing strings involves the collation, it's more expensive than comparing numbers.
+		for _, arg := range f.Args {
+			// The type of a row is nil.
+			ft := arg.GetType()
+			if ft != nil && (types.IsTypeChar(ft.Tp) || types.IsTypeBlob(ft.Tp)) {
+				return cost + stringCompareCost*float64(len(f.Args)-1)
+			}
+		}
+		return cost + cheapFuncCost*float64(len(f.Args)-1)
+	}
+	switch name {
+	case ast.Like:
+		return cost + likeCost
+	case ast.Regexp:
+		return cost + regexpCost
+	}
+	return cost + defaultFuncCost
+}
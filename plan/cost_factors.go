@@ -0,0 +1,72 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"strconv"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/sessionctx/variable"
+)
+
+// costFactors are the factors of the cost model, they can be recalibrated by the session variables for the
+// hardware, e.g. the scan factor of an all-flash TiKV cluster is lower.
+type costFactors struct {
+	// scan is the cost of reading a row in TiKV.
+	scan float64
+	// network is the cost of sending a row from TiKV to TiDB.
+	network float64
+	// seek is the cost of looking up the inner rows of an outer row by the index nested loop join.
+	seek float64
+	// cpu is the cost of processing a row in TiDB, e.g. sorting or aggregating it.
+	cpu float64
+}
+
+var defaultCostFactors = &costFactors{
+	scan:    0.5,
+	network: 1.0,
+	seek:    10.0,
+	cpu:     0.9,
+}
+
+// scanRow returns the cost of reading a row in TiKV and sending it back.
+func (f *costFactors) scanRow() float64 {
+	return f.scan + f.network
+}
+
+// loadCostFactors loads the cost factors from the session variables.
+func loadCostFactors(ctx context.Context) (*costFactors, error) {
+	f := &costFactors{}
+	for _, v := range []struct {
+		name  string
+		value *float64
+	}{
+		{variable.TiDBOptScanFactor, &f.scan},
+		{variable.TiDBOptNetworkFactor, &f.network},
+		{variable.TiDBOptSeekFactor, &f.seek},
+		{variable.TiDBOptCPUFactor, &f.cpu},
+	} {
+		val, err := ctx.GetSessionVars().GetTiDBSystemVar(v.name)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		factor, err := strconv.ParseFloat(val, 64)
+		if err != nil || factor < 0 {
+			return nil, ErrWrongArguments.Gen("Incorrect value '%s' for %s, it should be a non-negative number", val, v.name)
+		}
+		*v.value = factor
+	}
+	return f, nil
+}
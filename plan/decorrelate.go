@@ -0,0 +1,268 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"fmt"
+
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/expression"
+	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/util/types"
+)
+
+// A correlated subquery is executed by Apply, which runs the inner plan once for every outer row.
+// When the inner plan only refers to the outer plan by equal conditions like "inner.a = outer.a",
+// these conditions can be pulled up as join conditions, so the subquery runs only once:
+// "a in (select b from t2 where t2.c = t1.c)" becomes a semi join on "a = b and t2.c = t1.c", and
+// "(select sum(b) from t2 where t2.c = t1.c)" becomes a left outer join with "select sum(b), c from t2 group by c".
+
+// canDecorrelate checks whether all the correlated conditions of p can be pulled up by pullUpCorrelatedConds.
+func canDecorrelate(p LogicalPlan, outerSchema expression.Schema) bool {
+	if !p.IsCorrelated() {
+		return true
+	}
+	switch x := p.(type) {
+	case *Selection:
+		for _, cond := range x.Conditions {
+			if cond.IsCorrelated() && !isCorrelatedEqCond(cond, outerSchema) {
+				return false
+			}
+		}
+	case *Projection:
+		for _, expr := range x.Exprs {
+			if expr.IsCorrelated() {
+				return false
+			}
+		}
+	default:
+		return false
+	}
+	return canDecorrelate(p.GetChildByIndex(0).(LogicalPlan), outerSchema)
+}
+
+// isCorrelatedEqCond checks whether the condition is an equality between an inner column and a column of outer schema.
+func isCorrelatedEqCond(cond expression.Expression, outerSchema expression.Schema) bool {
+	f, ok := cond.(*expression.ScalarFunction)
+	if !ok || f.FuncName.L != ast.EQ {
+		return false
+	}
+	l, r := f.Args[0], f.Args[1]
+	if _, ok := l.(*expression.CorrelatedColumn); ok {
+		l, r = r, l
+	}
+	_, ok = l.(*expression.Column)
+	corCol, isCorCol := r.(*expression.CorrelatedColumn)
+	return ok && isCorCol && outerSchema.GetIndex(&corCol.Column) != -1
+}
+
+// pullUpCorrelatedConds removes the correlated conditions from p and returns them decorrelated by the outer schema,
+// the columns of the conditions are exposed by the returned plan. canDecorrelate must be checked before.
+func pullUpCorrelatedConds(p LogicalPlan, outerSchema expression.Schema) (LogicalPlan, []expression.Expression) {
+	if !p.IsCorrelated() {
+		return p, nil
+	}
+	child, conds := pullUpCorrelatedConds(p.GetChildByIndex(0).(LogicalPlan), outerSchema)
+	switch x := p.(type) {
+	case *Selection:
+		rest := make([]expression.Expression, 0, len(x.Conditions))
+		for _, cond := range x.Conditions {
+			if cond.IsCorrelated() {
+				conds = append(conds, cond.Decorrelate(outerSchema))
+			} else {
+				rest = append(rest, cond)
+			}
+		}
+		if len(rest) == 0 {
+			child.SetParents()
+			return child, conds
+		}
+		x.Conditions = rest
+		x.correlated = false
+	case *Projection:
+		// The inner columns of the pulled conditions may be pruned by the projection, so we expose them.
+		for _, col := range extractColumnsOfConds(conds) {
+			if child.GetSchema().GetIndex(col) == -1 {
+				continue
+			}
+			if idx := findColumnInExprs(x.Exprs, col); idx != -1 {
+				conds = replaceColumn(conds, col, x.schema[idx])
+				continue
+			}
+			newCol := &expression.Column{
+				FromID:  x.id,
+				ColName: col.ColName,
+				TblName: col.TblName,
+				DBName:  col.DBName,
+				RetType: col.RetType,
+			}
+			x.Exprs = append(x.Exprs, col.Clone())
+			x.schema = append(x.schema, newCol)
+			// The positions of projection columns start from 1, see buildProjection.
+			newCol.Position = len(x.schema)
+			conds = replaceColumn(conds, col, newCol)
+		}
+		x.correlated = false
+	}
+	p.SetChildren(child)
+	child.SetParents(p)
+	return p, conds
+}
+
+// decorrelateScalarAgg converts a correlated scalar subquery with aggregation, whose plan is
+// MaxOneRow->Projection->Aggregation, into a left outer join of the outer plan and the aggregation grouped by the
+// correlated columns. The projection is kept above the join, so the returned plan has the same schema as an Apply.
+func (b *planBuilder) decorrelateScalarAgg(outerPlan, subq LogicalPlan) (LogicalPlan, bool) {
+	proj, ok := subq.GetChildByIndex(0).(*Projection)
+	if !ok {
+		return nil, false
+	}
+	agg, ok := proj.GetChildByIndex(0).(*Aggregation)
+	if !ok || len(agg.GroupByItems) > 0 {
+		return nil, false
+	}
+	for _, expr := range proj.Exprs {
+		if expr.IsCorrelated() {
+			return nil, false
+		}
+	}
+	for _, aggFunc := range agg.AggFuncs {
+		for _, arg := range aggFunc.GetArgs() {
+			if arg.IsCorrelated() {
+				return nil, false
+			}
+		}
+	}
+	outerSchema := outerPlan.GetSchema()
+	aggChild := agg.GetChildByIndex(0).(LogicalPlan)
+	if !canDecorrelate(aggChild, outerSchema) {
+		return nil, false
+	}
+	inner, conds := pullUpCorrelatedConds(aggChild, outerSchema)
+	if len(conds) == 0 {
+		return nil, false
+	}
+	// Group the aggregation by the inner columns of the join conditions, and output them by first_row.
+	for _, col := range extractColumnsOfConds(conds) {
+		if inner.GetSchema().GetIndex(col) == -1 {
+			continue
+		}
+		position := len(agg.AggFuncs)
+		newCol := &expression.Column{
+			FromID:   agg.id,
+			ColName:  model.NewCIStr(fmt.Sprintf("%s_col_%d", agg.id, position)),
+			Position: position,
+			RetType:  col.RetType,
+		}
+		agg.AggFuncs = append(agg.AggFuncs, expression.NewAggFunction(ast.AggFuncFirstRow, []expression.Expression{col.Clone()}, false))
+		agg.GroupByItems = append(agg.GroupByItems, col.Clone())
+		agg.schema = append(agg.schema, newCol)
+		conds = replaceColumn(conds, col, newCol)
+	}
+	agg.collectGroupByColumns()
+	agg.SetChildren(inner)
+	inner.SetParents(agg)
+	agg.correlated = false
+	agg.SetParents()
+
+	join := &Join{
+		JoinType:        LeftOuterJoin,
+		baseLogicalPlan: newBaseLogicalPlan(Jn, b.allocator),
+		// The aggregation results of the unmatched outer rows are the ones of an empty input.
+		DefaultValues: getEmptyAggResults(agg),
+	}
+	join.self = join
+	join.initID()
+	join.correlated = outerPlan.IsCorrelated()
+	join.EqualConditions, join.LeftConditions, join.RightConditions, join.OtherConditions = extractOnCondition(conds, outerPlan, agg)
+	join.SetSchema(append(outerSchema.Clone(), agg.schema.Clone()...))
+	addChild(join, outerPlan)
+	addChild(join, agg)
+
+	exprs := make([]expression.Expression, 0, len(outerSchema)+len(proj.Exprs))
+	for _, col := range outerSchema {
+		exprs = append(exprs, col.Clone())
+	}
+	proj.Exprs = append(exprs, proj.Exprs...)
+	innerSchema := proj.schema.Clone()
+	for _, col := range innerSchema {
+		col.IsAggOrSubq = true
+	}
+	proj.SetSchema(append(outerSchema.Clone(), innerSchema...))
+	proj.SetParents()
+	proj.SetChildren(join)
+	join.SetParents(proj)
+	proj.correlated = join.correlated
+	return proj, true
+}
+
+// getEmptyAggResults returns the results of the aggregation functions on an empty input,
+// only COUNT returns 0 and the others return NULL.
+func getEmptyAggResults(agg *Aggregation) []types.Datum {
+	values := make([]types.Datum, len(agg.AggFuncs))
+	for i, aggFunc := range agg.AggFuncs {
+		if aggFunc.GetName() == ast.AggFuncCount {
+			values[i].SetInt64(0)
+		}
+	}
+	return values
+}
+
+// extractColumnsOfConds returns the distinct columns used by the conditions.
+func extractColumnsOfConds(conds []expression.Expression) []*expression.Column {
+	var result []*expression.Column
+	seen := make(map[string]bool)
+	for _, cond := range conds {
+		for _, col := range extractColumns(cond) {
+			key := string(col.HashCode())
+			if !seen[key] {
+				seen[key] = true
+				result = append(result, col)
+			}
+		}
+	}
+	return result
+}
+
+// findColumnInExprs returns the index of the expression which is exactly the column, or -1 if not found.
+func findColumnInExprs(exprs []expression.Expression, col *expression.Column) int {
+	for i, expr := range exprs {
+		if c, ok := expr.(*expression.Column); ok && c.Equal(col) {
+			return i
+		}
+	}
+	return -1
+}
+
+// replaceColumn replaces the column from with the column to in the conditions.
+func replaceColumn(conds []expression.Expression, from, to *expression.Column) []expression.Expression {
+	for i, cond := range conds {
+		conds[i] = replaceColumnInExpr(cond, from, to)
+	}
+	return conds
+}
+
+func replaceColumnInExpr(expr expression.Expression, from, to *expression.Column) expression.Expression {
+	switch v := expr.(type) {
+	case *expression.Column:
+		if v.Equal(from) {
+			return to.Clone()
+		}
+	case *expression.ScalarFunction:
+		for i, arg := range v.Args {
+			v.SetArg(i, replaceColumnInExpr(arg, from, to))
+		}
+	}
+	return expr
+}
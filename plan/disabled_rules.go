@@ -0,0 +1,85 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"strings"
+
+	"github.com/juju/errors"
+)
+
+// The logical rules that can be disabled by the tidb_opt_disable_rules variable or the DISABLE_RULES hint,
+// so that an optimizer regression can be bisected in production without code changes.
+const (
+	rulePredicatePushDown   = "predicate_push_down"
+	ruleJoinReorder         = "join_reorder"
+	ruleAggPushDown         = "agg_pushdown"
+	ruleEliminateProjection = "eliminate_projection"
+	ruleDecorrelate         = "decorrelate"
+	rulePointGet            = "point_get"
+	ruleEliminateMaxOneRow  = "eliminate_max_one_row"
+	ruleEliminateAgg        = "eliminate_agg"
+)
+
+var optimizerRules = map[string]bool{
+	rulePredicatePushDown:   true,
+	ruleJoinReorder:         true,
+	ruleAggPushDown:         true,
+	ruleEliminateProjection: true,
+	ruleDecorrelate:         true,
+	rulePointGet:            true,
+	ruleEliminateMaxOneRow:  true,
+	ruleEliminateAgg:        true,
+}
+
+// hintDisableRules is the hint to disable logical rules for a query, e.g. "SELECT /*+ DISABLE_RULES(join_reorder) */ ...".
+const hintDisableRules = "disable_rules"
+
+// ruleSet is a set of logical rule names.
+type ruleSet map[string]bool
+
+// add adds the rules to the set, the names are case insensitive.
+func (s ruleSet) add(names ...string) error {
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if !optimizerRules[name] {
+			return ErrUnknownRule.Gen("Unknown optimizer rule '%s'", name)
+		}
+		s[name] = true
+	}
+	return nil
+}
+
+// disableRules adds the rules to the disabled rule set of the builder.
+func (b *planBuilder) disableRules(names ...string) {
+	if b.disabledRules == nil {
+		b.disabledRules = make(ruleSet)
+	}
+	if err := b.disabledRules.add(names...); err != nil {
+		b.err = errors.Trace(err)
+	}
+}
+
+// disableJoinReorder marks all the joins in the plan as reordered, so the join reorder solver will skip them.
+func disableJoinReorder(p LogicalPlan) {
+	if join, ok := p.(*Join); ok {
+		join.reordered = true
+	}
+	for _, child := range p.GetChildren() {
+		disableJoinReorder(child.(LogicalPlan))
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/expression"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/util/types"
+)
+
+// eliminateAggregation removes the DISTINCTs and turns the aggregations into projections if the distinct or
+// group-by columns contain a unique non-null key of the input, so every group has exactly one row, e.g.
+// "select distinct pk, b from t" or "select pk, max(b) from t group by pk".
+func eliminateAggregation(p LogicalPlan) LogicalPlan {
+	if apply, ok := p.(*Apply); ok {
+		apply.InnerPlan = eliminateAggregation(apply.InnerPlan)
+	}
+	children := make([]Plan, 0, len(p.GetChildren()))
+	for _, child := range p.GetChildren() {
+		newChild := eliminateAggregation(child.(LogicalPlan))
+		newChild.SetParents(p)
+		children = append(children, newChild)
+	}
+	p.SetChildren(children...)
+	switch x := p.(type) {
+	case *Distinct:
+		child := x.GetChildByIndex(0).(LogicalPlan)
+		if hasUniqueKey(child, child.GetSchema()) {
+			child.SetParents(x.GetParents()...)
+			return child
+		}
+	case *Aggregation:
+		if proj := convertAggToProj(x); proj != nil {
+			return proj
+		}
+	}
+	return p
+}
+
+// convertAggToProj returns the projection which computes the aggregation functions on the single row of each
+// group, or nil if the groups may have more than one row or some functions can't be computed on a single row.
+func convertAggToProj(agg *Aggregation) *Projection {
+	if len(agg.GroupByItems) == 0 {
+		return nil
+	}
+	gbyCols := make([]*expression.Column, 0, len(agg.GroupByItems))
+	for _, item := range agg.GroupByItems {
+		if col, ok := item.(*expression.Column); ok {
+			gbyCols = append(gbyCols, col)
+		}
+	}
+	child := agg.GetChildByIndex(0).(LogicalPlan)
+	if !hasUniqueKey(child, gbyCols) {
+		return nil
+	}
+	exprs := make([]expression.Expression, 0, len(agg.AggFuncs))
+	for _, fun := range agg.AggFuncs {
+		args := fun.GetArgs()
+		switch fun.GetName() {
+		case ast.AggFuncFirstRow, ast.AggFuncMax, ast.AggFuncMin:
+			exprs = append(exprs, args[0])
+		case ast.AggFuncCount:
+			// The count of a single row is 0 if any argument is NULL.
+			for _, arg := range args {
+				if !isNotNullExpr(arg) {
+					return nil
+				}
+			}
+			exprs = append(exprs, &expression.Constant{
+				Value:   types.NewIntDatum(1),
+				RetType: types.NewFieldType(mysql.TypeLonglong),
+			})
+		default:
+			// The result types of sum, avg and group_concat differ from their arguments.
+			return nil
+		}
+	}
+	proj := &Projection{
+		Exprs:           exprs,
+		baseLogicalPlan: newBaseLogicalPlan(Proj, agg.allocator),
+	}
+	proj.self = proj
+	// The projection keeps the id of the aggregation, so the parents still refer to the columns of its schema.
+	proj.id = agg.id
+	proj.correlated = agg.correlated
+	proj.SetSchema(agg.GetSchema())
+	proj.SetChildren(child)
+	child.SetParents(proj)
+	proj.SetParents(agg.GetParents()...)
+	return proj
+}
+
+// hasUniqueKey checks if the columns contain a unique key of the plan whose columns are not null, so no two rows
+// of the plan have the same values on the columns.
+func hasUniqueKey(p LogicalPlan, cols []*expression.Column) bool {
+	switch x := p.(type) {
+	case *DataSource:
+		return containsUniqueKey(x, cols)
+	case *Projection:
+		childCols := make([]*expression.Column, 0, len(cols))
+		for _, col := range cols {
+			idx := x.GetSchema().GetIndex(col)
+			if idx == -1 {
+				continue
+			}
+			if childCol, ok := x.Exprs[idx].(*expression.Column); ok {
+				childCols = append(childCols, childCol)
+			}
+		}
+		cols = childCols
+	case *Selection, *Sort, *Limit, *Trim:
+	default:
+		return false
+	}
+	return hasUniqueKey(p.GetChildByIndex(0).(LogicalPlan), cols)
+}
+
+func isNotNullExpr(expr expression.Expression) bool {
+	switch x := expr.(type) {
+	case *expression.Constant:
+		return !x.Value.IsNull()
+	case *expression.Column:
+		return x.RetType != nil && mysql.HasNotNullFlag(x.RetType.Flag)
+	}
+	return false
+}
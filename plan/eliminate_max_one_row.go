@@ -0,0 +1,161 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/expression"
+	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/util/types"
+)
+
+// eliminateMaxOneRow removes the MaxOneRow operators whose child is proved to return at most one row, e.g. the
+// scalar subquery "(select b from t s where s.pk = t.c)", so the number of rows isn't checked at runtime. The apply
+// and the uncorrelated subquery evaluation fill the NULLs if such a subquery returns no row, like MaxOneRow does.
+func eliminateMaxOneRow(p LogicalPlan) LogicalPlan {
+	if apply, ok := p.(*Apply); ok {
+		apply.InnerPlan = eliminateMaxOneRow(apply.InnerPlan)
+	}
+	children := make([]Plan, 0, len(p.GetChildren()))
+	for _, child := range p.GetChildren() {
+		newChild := eliminateMaxOneRow(child.(LogicalPlan))
+		newChild.SetParents(p)
+		children = append(children, newChild)
+	}
+	p.SetChildren(children...)
+	if _, ok := p.(*MaxOneRow); ok {
+		child := p.GetChildByIndex(0).(LogicalPlan)
+		if atMostOneRow(child) {
+			child.SetParents(p.GetParents()...)
+			return child
+		}
+	}
+	return p
+}
+
+// atMostOneRow checks if the plan returns at most one row whatever the data is.
+func atMostOneRow(p LogicalPlan) bool {
+	switch x := p.(type) {
+	case *MaxOneRow, *TableDual:
+		return true
+	case *Aggregation:
+		if len(x.GroupByItems) == 0 {
+			return true
+		}
+	case *Limit:
+		if x.Count <= 1 {
+			return true
+		}
+	case *Selection:
+		if ds, ok := x.GetChildByIndex(0).(*DataSource); ok && matchUniqueKey(ds, x.Conditions) {
+			return true
+		}
+	case *Projection, *Sort, *Trim:
+	default:
+		return false
+	}
+	return atMostOneRow(p.GetChildByIndex(0).(LogicalPlan))
+}
+
+// matchUniqueKey checks if the conditions contain the equal conditions on all the columns of the handle or a unique
+// index of the data source, each of which compares the column with a value that is the same for all the rows.
+// A NULL value never equals a column, so the NULLs in a unique index don't matter.
+func matchUniqueKey(ds *DataSource, conditions []expression.Expression) bool {
+	eqCols := make(map[string]struct{})
+	for _, cond := range conditions {
+		if col := getEqualKeyColumn(ds, cond); col != nil {
+			eqCols[col.Name.L] = struct{}{}
+		}
+	}
+	if len(eqCols) == 0 {
+		return false
+	}
+	if ds.Table.PKIsHandle {
+		for _, col := range ds.Table.Columns {
+			if mysql.HasPriKeyFlag(col.Flag) {
+				if _, ok := eqCols[col.Name.L]; ok {
+					return true
+				}
+			}
+		}
+	}
+	for _, index := range ds.Table.Indices {
+		if !index.Unique || index.State != model.StatePublic {
+			continue
+		}
+		matched := true
+		for _, idxCol := range index.Columns {
+			if _, ok := eqCols[idxCol.Name.L]; !ok {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// getEqualKeyColumn returns the column of the data source if the condition is "column = value", where the value
+// doesn't refer to the columns of the data source or call the dynamic functions, and it's compared with the column
+// without any conversion that may make different keys equal, e.g. an integer key compared with a float.
+func getEqualKeyColumn(ds *DataSource, cond expression.Expression) *model.ColumnInfo {
+	f, ok := cond.(*expression.ScalarFunction)
+	if !ok || f.FuncName.L != ast.EQ {
+		return nil
+	}
+	for i, arg := range f.Args {
+		col, ok := arg.(*expression.Column)
+		if !ok {
+			continue
+		}
+		idx := ds.GetSchema().GetIndex(col)
+		if idx == -1 {
+			continue
+		}
+		value := f.Args[1-i]
+		if len(extractColumns(value)) > 0 || hasDynamicFunc(value) || !isSameKeyType(col.GetType(), value.GetType()) {
+			return nil
+		}
+		return ds.Columns[idx]
+	}
+	return nil
+}
+
+func isSameKeyType(key, value *types.FieldType) bool {
+	if key == nil || value == nil {
+		return false
+	}
+	if isIntegerType(key.Tp) {
+		return isIntegerType(value.Tp)
+	}
+	if isStringType(key.Tp) {
+		return isStringType(value.Tp)
+	}
+	return false
+}
+
+func isIntegerType(tp byte) bool {
+	switch tp {
+	case mysql.TypeTiny, mysql.TypeShort, mysql.TypeInt24, mysql.TypeLong, mysql.TypeLonglong:
+		return true
+	}
+	return false
+}
+
+func isStringType(tp byte) bool {
+	return types.IsTypeChar(tp) || types.IsTypeBlob(tp) || tp == mysql.TypeVarString
+}
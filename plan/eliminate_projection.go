@@ -61,3 +61,49 @@ func projectionCanBeEliminated(p *Projection) bool {
 	}
 	return true
 }
+
+// mergeProjection merges every projection into its parent projection by substituting the columns of the child in the
+// expressions of the parent, so the redundant projections built for the subqueries and the select fields don't carry
+// the rows twice. It runs after the column pruning, so the child only outputs the columns the parent uses.
+func mergeProjection(p LogicalPlan) {
+	for _, child := range p.GetChildren() {
+		mergeProjection(child.(LogicalPlan))
+	}
+	if apply, ok := p.(*Apply); ok {
+		mergeProjection(apply.InnerPlan)
+	}
+	proj, ok := p.(*Projection)
+	if !ok {
+		return
+	}
+	child, ok := proj.GetChildByIndex(0).(*Projection)
+	if !ok || !projectionCanBeMerged(proj, child) {
+		return
+	}
+	for i, expr := range proj.Exprs {
+		proj.Exprs[i] = columnSubstitute(expr.Clone(), child.GetSchema(), child.Exprs)
+	}
+	RemovePlan(child)
+}
+
+// projectionCanBeMerged checks if the child projection can be merged into the parent projection. A column of the
+// child which isn't a column or a constant must be referred by the parent only once, otherwise the function, which
+// may be expensive or not deterministic, is evaluated more than once for a row.
+func projectionCanBeMerged(parent, child *Projection) bool {
+	refCounts := make([]int, len(child.Exprs))
+	for _, expr := range parent.Exprs {
+		for _, col := range extractColumns(expr) {
+			idx := child.GetSchema().GetIndex(col)
+			if idx == -1 {
+				return false
+			}
+			refCounts[idx]++
+		}
+	}
+	for i, expr := range child.Exprs {
+		if _, ok := expr.(*expression.ScalarFunction); ok && refCounts[i] > 1 {
+			return false
+		}
+	}
+	return true
+}
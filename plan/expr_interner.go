@@ -0,0 +1,114 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"github.com/pingcap/tidb/evaluator"
+	"github.com/pingcap/tidb/expression"
+)
+
+// exprInterner maps the hash codes of expressions to the first expressions interned with them.
+// The optimizer passes often rebuild identical expressions, after interning they share one instance,
+// so the duplicated ones can be found by a map lookup instead of comparing them with each other.
+type exprInterner map[string]expression.Expression
+
+// intern returns the interned expression which is identical to expr, and whether expr is a duplicate of it.
+// The expressions containing non-deterministic functions like rand() are never regarded as duplicates.
+func (in exprInterner) intern(expr expression.Expression) (expression.Expression, bool) {
+	if hasDynamicFunc(expr) {
+		return expr, false
+	}
+	key := string(expr.HashCode())
+	if interned, ok := in[key]; ok && interned.Equal(expr) {
+		return interned, true
+	}
+	in[key] = expr
+	return expr, false
+}
+
+// hasDynamicFuncInPlan checks if the expressions of the plan or its descendants contain non-deterministic functions.
+func hasDynamicFuncInPlan(p LogicalPlan) bool {
+	var exprs []expression.Expression
+	switch x := p.(type) {
+	case *Projection:
+		exprs = x.Exprs
+	case *Selection:
+		exprs = x.Conditions
+	case *Join:
+		for _, cond := range x.EqualConditions {
+			exprs = append(exprs, cond)
+		}
+		exprs = append(exprs, x.LeftConditions...)
+		exprs = append(exprs, x.RightConditions...)
+		exprs = append(exprs, x.OtherConditions...)
+	case *Aggregation:
+		exprs = append(exprs, x.GroupByItems...)
+		for _, fun := range x.AggFuncs {
+			exprs = append(exprs, fun.GetArgs()...)
+		}
+	case *Sort:
+		for _, item := range x.ByItems {
+			exprs = append(exprs, item.Expr)
+		}
+	case *JSONTable:
+		exprs = append(exprs, x.Doc)
+	case *Profile:
+		// The profile is captured again at each execution.
+		return true
+	case *Apply:
+		if hasDynamicFuncInPlan(x.InnerPlan) {
+			return true
+		}
+		if x.Checker != nil {
+			exprs = append(exprs, x.Checker.Condition)
+		}
+	}
+	for _, expr := range exprs {
+		if hasDynamicFunc(expr) {
+			return true
+		}
+	}
+	for _, child := range p.GetChildren() {
+		if hasDynamicFuncInPlan(child.(LogicalPlan)) {
+			return true
+		}
+	}
+	return false
+}
+
+// lookup returns the interned expression which is identical to expr.
+func (in exprInterner) lookup(expr expression.Expression) (expression.Expression, bool) {
+	interned, ok := in[string(expr.HashCode())]
+	if ok && interned.Equal(expr) {
+		return interned, true
+	}
+	return nil, false
+}
+
+// hasDynamicFunc checks if the expression contains functions whose results may differ for the same arguments.
+func hasDynamicFunc(expr expression.Expression) bool {
+	f, ok := expr.(*expression.ScalarFunction)
+	if !ok {
+		return false
+	}
+	if _, ok := evaluator.DynamicFuncs[f.FuncName.L]; ok {
+		return true
+	}
+	for _, arg := range f.Args {
+		if hasDynamicFunc(arg) {
+			return true
+		}
+	}
+	return false
+}
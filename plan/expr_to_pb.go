@@ -24,7 +24,9 @@ import (
 	"github.com/pingcap/tipb/go-tipb"
 )
 
-func expressionsToPB(exprs []expression.Expression, client kv.Client) (pbExpr *tipb.Expr, pushed []expression.Expression, remained []expression.Expression) {
+// ExpressionsToPB converts the expressions which can be pushed down to the coprocessor to an AND expression in
+// protobuf, the others are returned as remained.
+func ExpressionsToPB(exprs []expression.Expression, client kv.Client) (pbExpr *tipb.Expr, pushed []expression.Expression, remained []expression.Expression) {
 	for _, expr := range exprs {
 		v := exprToPB(client, expr)
 		if v == nil {
@@ -128,6 +130,8 @@ func scalarFuncToPBExpr(client kv.Client, expr *expression.ScalarFunction) *tipb
 		return bitwiseFuncToPBExpr(client, expr)
 	case ast.Case, ast.Coalesce, ast.If, ast.Ifnull, ast.IsNull, ast.Nullif:
 		return builtinFuncToPBExpr(client, expr)
+	case ast.DateArith:
+		return dateArithToPBExpr(client, expr)
 	default:
 		return nil
 	}
@@ -360,6 +364,9 @@ func aggFuncToPBExpr(client kv.Client, aggFunc expression.AggregationFunction) *
 }
 
 func builtinFuncToPBExpr(client kv.Client, expr *expression.ScalarFunction) *tipb.Expr {
+	if !resultTypesAgree(expr) {
+		return nil
+	}
 	switch expr.FuncName.L {
 	case ast.Case, ast.If, ast.Ifnull, ast.Nullif:
 		return controlFuncsToPBExpr(client, expr)
@@ -370,6 +377,81 @@ func builtinFuncToPBExpr(client kv.Client, expr *expression.ScalarFunction) *tip
 	}
 }
 
+// Result type classes of the pushed down functions, see resultTypeClass.
+const (
+	resultClassNull = iota
+	resultClassInt
+	resultClassUint
+	resultClassReal
+	resultClassDecimal
+	resultClassString
+	resultClassTime
+	resultClassDuration
+	resultClassUnknown
+)
+
+func resultTypeClass(ft *types.FieldType) int {
+	switch ft.Tp {
+	case mysql.TypeNull:
+		return resultClassNull
+	case mysql.TypeTiny, mysql.TypeShort, mysql.TypeInt24, mysql.TypeLong, mysql.TypeLonglong, mysql.TypeYear:
+		if mysql.HasUnsignedFlag(ft.Flag) {
+			return resultClassUint
+		}
+		return resultClassInt
+	case mysql.TypeFloat, mysql.TypeDouble:
+		return resultClassReal
+	case mysql.TypeNewDecimal:
+		return resultClassDecimal
+	case mysql.TypeVarchar, mysql.TypeVarString, mysql.TypeString,
+		mysql.TypeTinyBlob, mysql.TypeMediumBlob, mysql.TypeBlob, mysql.TypeLongBlob:
+		return resultClassString
+	case mysql.TypeDate, mysql.TypeDatetime, mysql.TypeTimestamp:
+		return resultClassTime
+	case mysql.TypeDuration:
+		return resultClassDuration
+	}
+	return resultClassUnknown
+}
+
+// resultTypesAgree checks if the results of a control function, e.g. the branches of a CASE, have the same type.
+// The coprocessor returns the chosen result as it is, instead of converting it to the return type of the function
+// like TiDB does, so the results which need a conversion, e.g. IF(c, 1, 'a'), can't be pushed down.
+func resultTypesAgree(expr *expression.ScalarFunction) bool {
+	var results []expression.Expression
+	switch expr.FuncName.L {
+	case ast.Case:
+		// The arguments are the pairs of the condition and the result, followed by the optional else result.
+		for i := 1; i < len(expr.Args); i += 2 {
+			results = append(results, expr.Args[i])
+		}
+		if len(expr.Args)%2 == 1 {
+			results = append(results, expr.Args[len(expr.Args)-1])
+		}
+	case ast.If:
+		results = expr.Args[1:]
+	case ast.Ifnull, ast.Coalesce:
+		results = expr.Args
+	default:
+		return true
+	}
+	class := resultClassNull
+	for _, result := range results {
+		c := resultTypeClass(result.GetType())
+		if c == resultClassUnknown {
+			return false
+		}
+		if c == resultClassNull {
+			continue
+		}
+		if class != resultClassNull && class != c {
+			return false
+		}
+		class = c
+	}
+	return true
+}
+
 func otherFuncsToPBExpr(client kv.Client, expr *expression.ScalarFunction) *tipb.Expr {
 	var tp tipb.ExprType
 	switch expr.FuncName.L {
@@ -410,3 +492,28 @@ func convertToPBExpr(client kv.Client, expr *expression.ScalarFunction, tp tipb.
 	}
 	return &tipb.Expr{Tp: tp, Children: children}
 }
+
+// dateArithToPBExpr converts the DateArith function to DateAdd or DateSub, whose children are the date, the interval
+// and the unit.
+func dateArithToPBExpr(client kv.Client, expr *expression.ScalarFunction) *tipb.Expr {
+	op, ok := expr.Args[0].(*expression.Constant)
+	if !ok {
+		return nil
+	}
+	tp := tipb.ExprType_DateAdd
+	if op.Value.GetInterface() == ast.DateSub {
+		tp = tipb.ExprType_DateSub
+	}
+	if !client.SupportRequestType(kv.ReqTypeSelect, int64(tp)) {
+		return nil
+	}
+	children := make([]*tipb.Expr, 0, len(expr.Args)-1)
+	for _, arg := range expr.Args[1:] {
+		pbArg := exprToPB(client, arg)
+		if pbArg == nil {
+			return nil
+		}
+		children = append(children, pbArg)
+	}
+	return &tipb.Expr{Tp: tp, Children: children}
+}
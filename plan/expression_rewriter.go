@@ -146,6 +146,7 @@ func (er *expressionRewriter) Enter(inNode ast.Node) (ast.Node, bool) {
 }
 
 func (er *expressionRewriter) handleCompareSubquery(v *ast.CompareSubqueryExpr) (ast.Node, bool) {
+	asScalar := er.asScalar
 	v.L.Accept(er)
 	if er.err != nil {
 		return v, true
@@ -202,12 +203,31 @@ func (er *expressionRewriter) handleCompareSubquery(v *ast.CompareSubqueryExpr)
 			return v, true
 		}
 	}
+	// "a = any (subq)" in a filter is the same as "a in (subq)".
+	if !asScalar && !v.All && v.Op == opcode.EQ && er.tryToDecorrelateIn(np, checkCondition) {
+		return v, true
+	}
 	er.p = er.b.buildApply(er.p, np, &ApplyConditionChecker{Condition: checkCondition, All: v.All})
 	// The parent expression only use the last column in schema, which represents whether the condition is matched.
 	er.ctxStack[len(er.ctxStack)-1] = er.p.GetSchema()[len(er.p.GetSchema())-1]
 	return v, true
 }
 
+// tryToDecorrelateIn converts a correlated "a in (subq)" filter into a semi join when the correlated conditions of
+// the subquery are all equalities. We don't do this when the result is used as a scalar, including NOT IN, because
+// a NULL of the correlated conditions only filters out the inner row, while a NULL of the comparison makes the result
+// NULL, the null-aware semi join can't tell them apart.
+func (er *expressionRewriter) tryToDecorrelateIn(np LogicalPlan, checkCondition expression.Expression) bool {
+	if er.b.disabledRules[ruleDecorrelate] || !canDecorrelate(np, er.p.GetSchema()) {
+		return false
+	}
+	inner, conds := pullUpCorrelatedConds(np, er.p.GetSchema())
+	conds = append(expression.SplitCNFItems(checkCondition), conds...)
+	er.p = er.b.buildSemiJoin(er.p, inner, conds, false, false, false)
+	er.ctxStack = er.ctxStack[:len(er.ctxStack)-1]
+	return true
+}
+
 func (er *expressionRewriter) handleExistSubquery(v *ast.ExistsSubqueryExpr) (ast.Node, bool) {
 	subq, ok := v.Sel.(*ast.SubqueryExpr)
 	if !ok {
@@ -221,7 +241,7 @@ func (er *expressionRewriter) handleExistSubquery(v *ast.ExistsSubqueryExpr) (as
 	np = er.b.buildExists(np)
 	if np.IsCorrelated() {
 		if sel, ok := np.GetChildByIndex(0).(*Selection); ok && !sel.GetChildByIndex(0).IsCorrelated() {
-			er.p = er.b.buildSemiJoin(er.p, sel.GetChildByIndex(0).(LogicalPlan), sel.Conditions, er.asScalar, false)
+			er.p = er.b.buildSemiJoin(er.p, sel.GetChildByIndex(0).(LogicalPlan), sel.Conditions, er.asScalar, false, false)
 			if !er.asScalar {
 				return v, true
 			}
@@ -231,7 +251,7 @@ func (er *expressionRewriter) handleExistSubquery(v *ast.ExistsSubqueryExpr) (as
 		}
 		er.ctxStack = append(er.ctxStack, er.p.GetSchema()[len(er.p.GetSchema())-1])
 	} else {
-		physicalPlan, err := doOptimize(np, er.b.ctx, er.b.allocator)
+		physicalPlan, err := doOptimize(np, er.b.ctx, er.b.allocator, er.b.disabledRules)
 		d, err := EvalSubquery(physicalPlan, er.b.is, er.b.ctx)
 		if err != nil {
 			er.err = errors.Trace(err)
@@ -283,7 +303,7 @@ func (er *expressionRewriter) handleInSubquery(v *ast.PatternInExpr) (ast.Node,
 	// a not in (subq) will be rewrited as a != all(subq).
 	checkCondition, err := constructBinaryOpFunction(lexpr, rexpr, ast.EQ)
 	if !np.IsCorrelated() {
-		er.p = er.b.buildSemiJoin(er.p, np, expression.SplitCNFItems(checkCondition), asScalar, v.Not)
+		er.p = er.b.buildSemiJoin(er.p, np, expression.SplitCNFItems(checkCondition), asScalar, v.Not, true)
 		if asScalar {
 			col := er.p.GetSchema()[len(er.p.GetSchema())-1]
 			er.ctxStack[len(er.ctxStack)-1] = col
@@ -292,13 +312,16 @@ func (er *expressionRewriter) handleInSubquery(v *ast.PatternInExpr) (ast.Node,
 		}
 		return v, true
 	}
-	if v.Not {
-		checkCondition, _ = expression.NewFunction(ast.UnaryNot, &v.Type, checkCondition)
-	}
 	if err != nil {
 		er.err = errors.Trace(err)
 		return v, true
 	}
+	if !asScalar && !v.Not && er.tryToDecorrelateIn(np, checkCondition) {
+		return v, true
+	}
+	if v.Not {
+		checkCondition, _ = expression.NewFunction(ast.UnaryNot, &v.Type, checkCondition)
+	}
 	er.p = er.b.buildApply(er.p, np, &ApplyConditionChecker{Condition: checkCondition, All: v.Not})
 	// The parent expression only use the last column in schema, which represents whether the condition is matched.
 	er.ctxStack[len(er.ctxStack)-1] = er.p.GetSchema()[len(er.p.GetSchema())-1]
@@ -306,6 +329,14 @@ func (er *expressionRewriter) handleInSubquery(v *ast.PatternInExpr) (ast.Node,
 
 }
 
+// tryToDecorrelateScalar converts a correlated scalar subquery with aggregation into an outer join.
+func (er *expressionRewriter) tryToDecorrelateScalar(np LogicalPlan) (LogicalPlan, bool) {
+	if er.b.disabledRules[ruleDecorrelate] {
+		return nil, false
+	}
+	return er.b.decorrelateScalarAgg(er.p, np)
+}
+
 func (er *expressionRewriter) handleScalarSubquery(v *ast.SubqueryExpr) (ast.Node, bool) {
 	np := er.buildSubquery(v)
 	if er.err != nil {
@@ -313,7 +344,11 @@ func (er *expressionRewriter) handleScalarSubquery(v *ast.SubqueryExpr) (ast.Nod
 	}
 	np = er.b.buildMaxOneRow(np)
 	if np.IsCorrelated() {
-		er.p = er.b.buildApply(er.p, np, nil)
+		if p, ok := er.tryToDecorrelateScalar(np); ok {
+			er.p = p
+		} else {
+			er.p = er.b.buildApply(er.p, np, nil)
+		}
 		if len(np.GetSchema()) > 1 {
 			newCols := make([]expression.Expression, 0, len(np.GetSchema()))
 			for _, col := range np.GetSchema() {
@@ -330,7 +365,7 @@ func (er *expressionRewriter) handleScalarSubquery(v *ast.SubqueryExpr) (ast.Nod
 		}
 		return v, true
 	}
-	physicalPlan, err := doOptimize(np, er.b.ctx, er.b.allocator)
+	physicalPlan, err := doOptimize(np, er.b.ctx, er.b.allocator, er.b.disabledRules)
 	d, err := EvalSubquery(physicalPlan, er.b.is, er.b.ctx)
 	if err != nil {
 		er.err = errors.Trace(err)
@@ -0,0 +1,224 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/expression"
+)
+
+// fullGroupByChecker checks the select list, having clause and order by clause of an aggregated query
+// under the ONLY_FULL_GROUP_BY SQL mode. A column outside the aggregate functions must be a group by column,
+// be a part of a group by expression, or belong to a table whose unique key is grouped by.
+type fullGroupByChecker struct {
+	b          *planBuilder
+	p          LogicalPlan
+	fields     []*ast.SelectField
+	hasGroupBy bool
+	gbyCols    []*expression.Column
+	// gbyExprs are the group by expressions which are not columns, they're interned so an expression can be
+	// matched with them by its hash code.
+	gbyExprs exprInterner
+	// dependentSchemas are the schemas of the data sources whose rows are determined by the group by columns.
+	dependentSchemas []expression.Schema
+	// outerFields are the result fields of the tables in the FROM clause, the columns of a subquery referring to
+	// them are the outer columns, which are checked like the columns of the query.
+	outerFields map[*ast.ResultField]bool
+	// subqueryDepth is the number of the subqueries enclosing the expression being checked.
+	subqueryDepth int
+
+	// clause and item locate the expression being checked for the error message.
+	clause string
+	item   int
+	// aliasVisible is true in the having and order by clauses, which may refer to the select fields by their names.
+	aliasVisible bool
+	err          error
+}
+
+func (b *planBuilder) checkOnlyFullGroupBy(p LogicalPlan, sel *ast.SelectStmt, gbyExprs []expression.Expression) {
+	c := &fullGroupByChecker{
+		b:          b,
+		p:          p,
+		fields:     sel.Fields.Fields,
+		hasGroupBy: sel.GroupBy != nil,
+		gbyExprs:   make(exprInterner),
+	}
+	for _, expr := range gbyExprs {
+		if col, ok := expr.(*expression.Column); ok {
+			c.gbyCols = append(c.gbyCols, col)
+		} else {
+			// The non-deterministic expressions are kept too, "rand()" in the select list matches "group by rand()".
+			c.gbyExprs[string(expr.HashCode())] = expr
+		}
+	}
+	if len(c.gbyCols) > 0 {
+		c.collectDependentSchemas(p)
+	}
+	if sel.From != nil {
+		c.outerFields = make(map[*ast.ResultField]bool)
+		for _, ts := range appendTableSources(nil, sel.From.TableRefs) {
+			for _, rf := range ts.GetResultFields() {
+				c.outerFields[rf] = true
+			}
+		}
+	}
+	c.clause = "SELECT list"
+	for i, field := range c.fields {
+		if field.Auxiliary {
+			continue
+		}
+		c.check(i+1, field.Expr)
+	}
+	c.aliasVisible = true
+	if sel.Having != nil {
+		c.clause = "HAVING clause"
+		c.check(1, sel.Having.Expr)
+	}
+	if sel.OrderBy != nil {
+		c.clause = "ORDER BY clause"
+		for i, item := range sel.OrderBy.Items {
+			c.check(i+1, item.Expr)
+		}
+	}
+	if c.err != nil {
+		b.err = c.err
+	}
+}
+
+func (c *fullGroupByChecker) collectDependentSchemas(p Plan) {
+	if ds, ok := p.(*DataSource); ok {
+		if containsUniqueKey(ds, c.gbyCols) {
+			c.dependentSchemas = append(c.dependentSchemas, ds.GetSchema())
+		}
+		return
+	}
+	for _, child := range p.GetChildren() {
+		c.collectDependentSchemas(child)
+	}
+}
+
+func (c *fullGroupByChecker) check(item int, expr ast.ExprNode) {
+	if c.err != nil {
+		return
+	}
+	c.item = item
+	expr.Accept(c)
+}
+
+// Enter implements Visitor interface.
+func (c *fullGroupByChecker) Enter(in ast.Node) (ast.Node, bool) {
+	switch v := in.(type) {
+	case *ast.AggregateFuncExpr, *ast.PositionExpr:
+		// The positions refer to the select fields, which are checked by themselves.
+		return in, true
+	case *ast.SubqueryExpr:
+		c.subqueryDepth++
+		v.Query.Accept(c)
+		c.subqueryDepth--
+		return in, true
+	case *ast.ColumnNameExpr:
+		c.checkColumn(v)
+		return in, true
+	case ast.ExprNode:
+		if c.matchGbyExpr(v) {
+			return in, true
+		}
+	}
+	return in, false
+}
+
+// Leave implements Visitor interface.
+func (c *fullGroupByChecker) Leave(in ast.Node) (ast.Node, bool) {
+	return in, c.err == nil
+}
+
+// matchGbyExpr checks if the expression is one of the group by expressions which are not columns.
+func (c *fullGroupByChecker) matchGbyExpr(expr ast.ExprNode) bool {
+	if len(c.gbyExprs) == 0 || expr.GetFlag()&ast.FlagHasReference == 0 ||
+		expr.GetFlag()&(ast.FlagHasSubquery|ast.FlagHasAggregateFunc) != 0 {
+		return false
+	}
+	if c.subqueryDepth > 0 && !c.onlyOuterColumns(expr) {
+		return false
+	}
+	newExpr, _, err := c.b.rewrite(expr, c.p, nil, true)
+	if err != nil {
+		// The expression may refer to the aliases, its columns are checked one by one.
+		return false
+	}
+	_, ok := c.gbyExprs.lookup(newExpr)
+	return ok
+}
+
+// onlyOuterColumns checks if all the columns of an expression in a subquery are the outer columns.
+func (c *fullGroupByChecker) onlyOuterColumns(expr ast.ExprNode) bool {
+	checker := &outerColumnChecker{outerFields: c.outerFields, onlyOuter: true}
+	expr.Accept(checker)
+	return checker.onlyOuter
+}
+
+func (c *fullGroupByChecker) checkColumn(v *ast.ColumnNameExpr) {
+	if c.subqueryDepth > 0 && !c.outerFields[v.Refer] {
+		// The columns of the tables in the subquery are checked by the subquery itself.
+		return
+	}
+	if c.aliasVisible && v.Name.Table.L == "" {
+		for _, field := range c.fields {
+			if !field.Auxiliary && field.AsName.L == v.Name.Name.L {
+				return
+			}
+		}
+	}
+	col, err := c.p.GetSchema().FindColumn(v.Name)
+	if err != nil || col == nil {
+		// The ambiguous, unknown and outer columns are left to be resolved later.
+		return
+	}
+	for _, gbyCol := range c.gbyCols {
+		if gbyCol.Equal(col) {
+			return
+		}
+	}
+	for _, schema := range c.dependentSchemas {
+		if schema.GetIndex(col) != -1 {
+			return
+		}
+	}
+	if c.hasGroupBy {
+		c.err = ErrFieldNotInGroupBy.Gen("Expression #%d of %s is not in GROUP BY clause and contains nonaggregated column '%s' which is not functionally dependent on columns in GROUP BY clause; this is incompatible with sql_mode=only_full_group_by",
+			c.item, c.clause, col)
+		return
+	}
+	c.err = ErrMixOfGroupFuncAndFields.Gen("In aggregated query without GROUP BY, expression #%d of %s contains nonaggregated column '%s'; this is incompatible with sql_mode=only_full_group_by",
+		c.item, c.clause, col)
+}
+
+// outerColumnChecker checks if all the columns of an expression are in outerFields.
+type outerColumnChecker struct {
+	outerFields map[*ast.ResultField]bool
+	onlyOuter   bool
+}
+
+// Enter implements Visitor interface.
+func (c *outerColumnChecker) Enter(in ast.Node) (ast.Node, bool) {
+	if v, ok := in.(*ast.ColumnNameExpr); ok && !c.outerFields[v.Refer] {
+		c.onlyOuter = false
+	}
+	return in, !c.onlyOuter
+}
+
+// Leave implements Visitor interface.
+func (c *outerColumnChecker) Leave(in ast.Node) (ast.Node, bool) {
+	return in, c.onlyOuter
+}
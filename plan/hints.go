@@ -0,0 +1,436 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"strings"
+
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/model"
+)
+
+// The optimizer hints written in a "/*+ ... */" comment right after SELECT. They are an escape hatch for the
+// queries on which the statistics mislead the optimizer. Tables are referred by their aliases in the query
+// block of the hint, and the hints on unknown tables are ignored like MySQL does.
+const (
+	// USE_INDEX(t, idx1, idx2) is the same as "t USE INDEX(idx1, idx2)".
+	hintUseIndex = "use_index"
+	// IGNORE_INDEX(t, idx1, idx2) is the same as "t IGNORE INDEX(idx1, idx2)".
+	hintIgnoreIndex = "ignore_index"
+	// HASH_JOIN(t1, t2) makes a join with one of the tables use hash join, and builds the hash table on it.
+	hintHashJoin = "hash_join"
+	// MERGE_JOIN(t1, t2) prefers sort merge join for the joins with one of the tables.
+	hintMergeJoin = "merge_join"
+	// INL_JOIN(t1, t2) prefers index nested loop join with one of the tables as the inner side.
+	hintINLJoin = "inl_join"
+	// LEADING(t1, t2) joins the tables first in the given order, then the other tables by cost.
+	hintLeading = "leading"
+	// STRAIGHT_JOIN() is the same as "SELECT STRAIGHT_JOIN", the tables are joined in the order they're listed.
+	hintStraightJoin = "straight_join"
+	// QB_NAME(qb) names the query block where it's written, so that the hints in the other query blocks can be
+	// written for it, e.g. "USE_INDEX(@qb t, idx)" or "HASH_JOIN(t@qb)". A hint only applies to the tables of
+	// its query block, not to the ones of the subqueries in the block.
+	hintQBName = "qb_name"
+)
+
+// queryBlockHints collects the hints written for other query blocks in a statement.
+type queryBlockHints struct {
+	// names maps the query blocks to their names given by QB_NAME.
+	names map[*ast.SelectStmt]string
+	// hints maps the query block names to the hints written for them.
+	hints map[string][]*ast.TableOptimizerHint
+}
+
+// Enter implements ast.Visitor interface.
+func (q *queryBlockHints) Enter(in ast.Node) (ast.Node, bool) {
+	sel, ok := in.(*ast.SelectStmt)
+	if !ok {
+		return in, false
+	}
+	for _, hint := range sel.TableHints {
+		if hint.HintName.L == hintQBName && hint.QBName.L == "" && len(hint.Args) == 1 {
+			if _, ok := q.names[sel]; !ok {
+				q.names[sel] = hint.Args[0].L
+			}
+		}
+		if hint.QBName.L != "" {
+			q.hints[hint.QBName.L] = append(q.hints[hint.QBName.L], hint)
+		}
+	}
+	return in, false
+}
+
+// Leave implements ast.Visitor interface.
+func (q *queryBlockHints) Leave(in ast.Node) (ast.Node, bool) {
+	return in, true
+}
+
+// collectQueryBlockHints collects the query block names and the hints for them in the statement.
+func (b *planBuilder) collectQueryBlockHints(node ast.Node) {
+	b.qbHints = &queryBlockHints{
+		names: make(map[*ast.SelectStmt]string),
+		hints: make(map[string][]*ast.TableOptimizerHint),
+	}
+	node.Accept(b.qbHints)
+}
+
+// getSelectHints returns the hints of a query block, they are the hints written in the block for itself and the
+// hints written in any block for it by its name.
+func (b *planBuilder) getSelectHints(sel *ast.SelectStmt) []*ast.TableOptimizerHint {
+	hints := make([]*ast.TableOptimizerHint, 0, len(sel.TableHints))
+	for _, hint := range sel.TableHints {
+		if hint.QBName.L == "" {
+			hints = append(hints, hint)
+		}
+	}
+	if b.qbHints == nil {
+		return hints
+	}
+	if name, ok := b.qbHints.names[sel]; ok {
+		hints = append(hints, b.qbHints.hints[name]...)
+	}
+	return hints
+}
+
+// tableHintInfo stores the optimizer hints of a query block.
+type tableHintInfo struct {
+	// indexHints maps the table alias to its index hints.
+	indexHints      map[string][]*ast.IndexHint
+	hashJoinTables  []model.CIStr
+	mergeJoinTables []model.CIStr
+	inlJoinTables   []model.CIStr
+	leadingTables   []model.CIStr
+	// straightJoin means the joins of the query block are neither reordered nor swapped, see Join.straightJoin.
+	straightJoin bool
+}
+
+// newTableHintInfo collects the hints of a query block, the hints other than the table hints are skipped.
+func newTableHintInfo(hints []*ast.TableOptimizerHint) *tableHintInfo {
+	info := &tableHintInfo{}
+	for _, hint := range hints {
+		switch hint.HintName.L {
+		case hintUseIndex, hintIgnoreIndex:
+			if len(hint.Args) == 0 {
+				continue
+			}
+			indexHint := &ast.IndexHint{
+				IndexNames: hint.Args[1:],
+				HintType:   ast.HintUse,
+				HintScope:  ast.HintForScan,
+			}
+			if hint.HintName.L == hintIgnoreIndex {
+				indexHint.HintType = ast.HintIgnore
+			}
+			if info.indexHints == nil {
+				info.indexHints = make(map[string][]*ast.IndexHint)
+			}
+			tblName := model.NewTableCIStr(hint.Args[0].O).L
+			info.indexHints[tblName] = append(info.indexHints[tblName], indexHint)
+		case hintHashJoin:
+			info.hashJoinTables = append(info.hashJoinTables, hintTableNames(hint.Args)...)
+		case hintMergeJoin:
+			info.mergeJoinTables = append(info.mergeJoinTables, hintTableNames(hint.Args)...)
+		case hintINLJoin:
+			info.inlJoinTables = append(info.inlJoinTables, hintTableNames(hint.Args)...)
+		case hintLeading:
+			info.leadingTables = hintTableNames(hint.Args)
+		case hintStraightJoin:
+			info.straightJoin = true
+		}
+	}
+	return info
+}
+
+// hintTableNames converts the arguments of a hint to the table names, which are compared by lower_case_table_names.
+func hintTableNames(args []model.CIStr) []model.CIStr {
+	names := make([]model.CIStr, 0, len(args))
+	for _, arg := range args {
+		names = append(names, model.NewTableCIStr(arg.O))
+	}
+	return names
+}
+
+// pushTableHints makes the hints of a query block visible while the block is being built.
+func (b *planBuilder) pushTableHints(hints []*ast.TableOptimizerHint) {
+	b.tableHintInfo = append(b.tableHintInfo, newTableHintInfo(hints))
+}
+
+func (b *planBuilder) popTableHints() {
+	b.tableHintInfo = b.tableHintInfo[:len(b.tableHintInfo)-1]
+}
+
+// getTableHints returns the hints of the query block being built, it returns nil outside of a SELECT.
+func (b *planBuilder) getTableHints() *tableHintInfo {
+	if len(b.tableHintInfo) == 0 {
+		return nil
+	}
+	return b.tableHintInfo[len(b.tableHintInfo)-1]
+}
+
+// getPlanTableName returns the alias of the single table that p reads, or an empty name if p reads
+// no table or more than one.
+func getPlanTableName(p LogicalPlan) model.CIStr {
+	var name model.CIStr
+	for i, col := range p.GetSchema() {
+		if i > 0 && col.TblName.L != name.L {
+			return model.CIStr{}
+		}
+		name = col.TblName
+	}
+	return name
+}
+
+// matchTableName checks whether p reads one of the tables.
+func matchTableName(p LogicalPlan, tables []model.CIStr) bool {
+	name := getPlanTableName(p)
+	if name.L == "" {
+		return false
+	}
+	for _, table := range tables {
+		if table.L == name.L {
+			return true
+		}
+	}
+	return false
+}
+
+// preferHashJoin checks whether the HASH_JOIN hint is written for one of the children of the join.
+func (p *Join) preferHashJoin() bool {
+	if p.hintInfo == nil {
+		return false
+	}
+	return matchTableName(p.GetChildByIndex(0).(LogicalPlan), p.hintInfo.hashJoinTables) ||
+		matchTableName(p.GetChildByIndex(1).(LogicalPlan), p.hintInfo.hashJoinTables)
+}
+
+// preferMergeJoin checks whether the MERGE_JOIN hint is written for one of the children of the join.
+func (p *Join) preferMergeJoin() bool {
+	if p.hintInfo == nil {
+		return false
+	}
+	return matchTableName(p.GetChildByIndex(0).(LogicalPlan), p.hintInfo.mergeJoinTables) ||
+		matchTableName(p.GetChildByIndex(1).(LogicalPlan), p.hintInfo.mergeJoinTables)
+}
+
+// preferINLJoin checks whether the INL_JOIN hint is written for the child of the join, so it's preferred to be
+// the inner side of an index nested loop join.
+func (p *Join) preferINLJoin(childIdx int) bool {
+	if p.hintInfo == nil {
+		return false
+	}
+	return matchTableName(p.GetChildByIndex(childIdx).(LogicalPlan), p.hintInfo.inlJoinTables)
+}
+
+// getHashJoinBuildSide returns the index of the child that the HASH_JOIN hint builds the hash table on,
+// or -1 if the hint doesn't choose one.
+func (p *Join) getHashJoinBuildSide() int {
+	if p.hintInfo == nil || len(p.hintInfo.hashJoinTables) == 0 {
+		return -1
+	}
+	left := matchTableName(p.GetChildByIndex(0).(LogicalPlan), p.hintInfo.hashJoinTables)
+	right := matchTableName(p.GetChildByIndex(1).(LogicalPlan), p.hintInfo.hashJoinTables)
+	if left == right {
+		return -1
+	}
+	if left {
+		return 0
+	}
+	return 1
+}
+
+// getLeadingPlans returns the indices of the plans in the join group in the order of the LEADING hint.
+// The hint is ignored if some of its tables are not in the group.
+func getLeadingPlans(group []LogicalPlan, hintInfo *tableHintInfo) []int {
+	if hintInfo == nil || len(hintInfo.leadingTables) < 2 {
+		return nil
+	}
+	leading := make([]int, 0, len(hintInfo.leadingTables))
+	for _, table := range hintInfo.leadingTables {
+		idx := -1
+		for i, p := range group {
+			if matchTableName(p, []model.CIStr{table}) {
+				idx = i
+				break
+			}
+		}
+		if idx == -1 {
+			return nil
+		}
+		for _, i := range leading {
+			if i == idx {
+				return nil
+			}
+		}
+		leading = append(leading, idx)
+	}
+	return leading
+}
+
+// addIndexHints adds the USE_INDEX and IGNORE_INDEX hints on the table to the index hints of p.
+func (p *DataSource) addIndexHints(hintInfo *tableHintInfo) {
+	if hintInfo == nil {
+		return
+	}
+	name := p.Table.Name
+	if p.TableAsName != nil && p.TableAsName.L != "" {
+		name = *p.TableAsName
+	}
+	hints := hintInfo.indexHints[name.L]
+	if len(hints) == 0 {
+		return
+	}
+	// The index hints of the table name are shared by the statement, so we don't append to them in place.
+	p.indexHints = append(append([]*ast.IndexHint(nil), p.indexHints...), hints...)
+}
+
+// GenHints returns the optimizer hints which reproduce the access paths, the join algorithms and the join order of
+// the tables in the outermost query block of a physical plan, e.g. "use_index(t1, idx), hash_join(t2), leading(t1, t2)".
+// The tables in the subqueries are in other query blocks, and a table whose alias appears more than once in the plan
+// is skipped because the hints can't tell them apart. LEADING only describes the left-deep join trees, so the join
+// order of a bushy tree is left to the optimizer.
+func GenHints(p Plan) string {
+	g := &hintsGenerator{aliases: make(map[string]int)}
+	g.countAliases(p)
+	g.gen(p)
+	if len(g.leading) > 1 && !g.bushy {
+		g.hints = append(g.hints, hintLeading+"("+strings.Join(g.leading, ", ")+")")
+	}
+	return strings.Join(g.hints, ", ")
+}
+
+type hintsGenerator struct {
+	// aliases counts the occurrences of the table aliases in the plan, including the ones of the subqueries.
+	aliases map[string]int
+	hints   []string
+	// leading is the tables of the outermost query block in the order they are joined.
+	leading []string
+	// bushy is true if the right child of a join is not a single table.
+	bushy bool
+}
+
+// scanAlias returns the alias of the table read by a scan plan, ok is false if p isn't a scan.
+func scanAlias(p Plan) (alias string, ok bool) {
+	var tbl *model.TableInfo
+	var asName *model.CIStr
+	switch x := p.(type) {
+	case *PhysicalTableScan:
+		tbl, asName = x.Table, x.TableAsName
+	case *PhysicalIndexScan:
+		tbl, asName = x.Table, x.TableAsName
+	case *PhysicalIndexMerge:
+		tbl, asName = x.Table, x.TableAsName
+	default:
+		return "", false
+	}
+	if asName != nil && asName.L != "" {
+		return asName.L, true
+	}
+	return tbl.Name.L, true
+}
+
+func (g *hintsGenerator) countAliases(p Plan) {
+	if alias, ok := scanAlias(p); ok {
+		g.aliases[alias]++
+	}
+	if apply, ok := p.(*PhysicalApply); ok {
+		g.countAliases(apply.InnerPlan)
+	}
+	for _, child := range p.GetChildren() {
+		g.countAliases(child)
+	}
+}
+
+// singleTable returns the alias of the only table read by p in the outermost query block, or "" if p reads more
+// than one table or a table whose alias isn't unique.
+func (g *hintsGenerator) singleTable(p Plan) string {
+	if alias, ok := scanAlias(p); ok {
+		if g.aliases[alias] != 1 {
+			return ""
+		}
+		return alias
+	}
+	children := p.GetChildren()
+	if _, ok := p.(*PhysicalHashSemiJoin); ok {
+		children = children[:1]
+	}
+	if len(children) != 1 {
+		return ""
+	}
+	return g.singleTable(children[0])
+}
+
+func (g *hintsGenerator) gen(p Plan) {
+	var joinHint string
+	switch x := p.(type) {
+	case *PhysicalTableScan, *PhysicalIndexScan, *PhysicalIndexMerge:
+		alias, _ := scanAlias(p)
+		if g.aliases[alias] != 1 {
+			return
+		}
+		args := []string{quoteHintName(alias)}
+		switch y := x.(type) {
+		case *PhysicalIndexScan:
+			args = append(args, quoteHintName(y.Index.Name.L))
+		case *PhysicalIndexMerge:
+			for _, partial := range y.PartialPlans {
+				args = append(args, quoteHintName(partial.Index.Name.L))
+			}
+		}
+		// An empty index list means the table is scanned.
+		g.hints = append(g.hints, hintUseIndex+"("+strings.Join(args, ", ")+")")
+		g.leading = append(g.leading, quoteHintName(alias))
+		return
+	case *PhysicalHashJoin:
+		joinHint = g.joinHint(x, hintHashJoin, x.SmallTable)
+	case *PhysicalMergeJoin:
+		joinHint = g.joinHint(x, hintMergeJoin, 0)
+		if joinHint == "" {
+			joinHint = g.joinHint(x, hintMergeJoin, 1)
+		}
+	case *PhysicalIndexJoin:
+		joinHint = g.joinHint(x, hintINLJoin, 1-x.OuterIndex)
+	case *PhysicalHashSemiJoin:
+		// The inner child is the subquery.
+		g.gen(x.GetChildByIndex(0))
+		return
+	}
+	for _, child := range p.GetChildren() {
+		g.gen(child)
+	}
+	if joinHint != "" {
+		g.hints = append(g.hints, joinHint)
+	}
+}
+
+// joinHint returns the join hint written for the child of the join, or "" if the child isn't a single table.
+func (g *hintsGenerator) joinHint(join Plan, hintName string, childIdx int) string {
+	if g.singleTable(join.GetChildByIndex(1)) == "" {
+		g.bushy = true
+	}
+	name := g.singleTable(join.GetChildByIndex(childIdx))
+	if name == "" {
+		return ""
+	}
+	return hintName + "(" + quoteHintName(name) + ")"
+}
+
+// quoteHintName quotes the name in the hints if it isn't a plain identifier.
+func quoteHintName(name string) string {
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_') {
+			return "`" + strings.Replace(name, "`", "``", -1) + "`"
+		}
+	}
+	return name
+}
@@ -0,0 +1,161 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"math"
+
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+	"github.com/pingcap/tidb/expression"
+	"github.com/pingcap/tidb/infoschema"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/util/types"
+)
+
+// getLookUpDataSource returns the table read by p if p can be the inner child of an index nested loop join,
+// i.e. p reads a table with or without a selection on it.
+func getLookUpDataSource(p LogicalPlan) *DataSource {
+	if sel, ok := p.(*Selection); ok {
+		p = sel.GetChildByIndex(0).(LogicalPlan)
+	}
+	ds, _ := p.(*DataSource)
+	return ds
+}
+
+// convert2LookUpScan converts p to the scan which can be looked up by the key for an index nested loop join. It's
+// the table scan if the key is the handle, or the scan of an index whose first column is the key. The ranges of the
+// scan are built by the join keys when it's executed, so the conditions on p only filter the rows.
+// It returns nil if p can't be looked up by the key.
+func (p *DataSource) convert2LookUpScan(key *expression.Column) (*physicalPlanInfo, error) {
+	client := p.ctx.GetClient()
+	offset := p.GetSchema().GetIndex(key)
+	if offset == -1 || client == nil || infoschema.IsMemoryDB(p.DBName.L) {
+		return nil, nil
+	}
+	colInfo := p.Columns[offset]
+	indices, includeTableScan := availableIndices(p.indexHints, p.Table)
+	var (
+		scan    PhysicalPlan
+		source  *physicalTableSource
+		reqType int64
+	)
+	if includeTableScan && p.Table.PKIsHandle && mysql.HasPriKeyFlag(colInfo.Flag) {
+		ts, err := p.newTableScan()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		ts.Ranges = []TableRange{{math.MinInt64, math.MaxInt64}}
+		ts.pkCol = key
+		scan, source, reqType = ts, &ts.physicalTableSource, kv.ReqTypeSelect
+	} else {
+		index := findLookUpIndex(indices, colInfo, p.Columns, p.Table.PKIsHandle)
+		if index == nil {
+			return nil, nil
+		}
+		is, err := p.newIndexScan(index)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		rb := rangeBuilder{}
+		is.Ranges = rb.buildIndexRanges(fullRange, types.NewFieldType(mysql.TypeNull))
+		is.DoubleRead = !isCoveringIndex(is.Columns, is.Index.Columns, is.Table.PKIsHandle)
+		scan, source, reqType = is, &is.physicalTableSource, kv.ReqTypeIndex
+	}
+	if !client.SupportRequestType(reqType, 0) {
+		return nil, nil
+	}
+	rowCount := uint64(p.statisticTable.Count)
+	resultPlan := scan
+	if sel, ok := p.GetParentByIndex(0).(*Selection); ok {
+		newSel := *sel
+		conds := make([]expression.Expression, 0, len(sel.Conditions))
+		for _, cond := range sel.Conditions {
+			conds = append(conds, cond.Clone())
+		}
+		source.ConditionPBExpr, source.conditions, newSel.Conditions = ExpressionsToPB(conds, client)
+		if source.ConditionPBExpr != nil {
+			rowCount = uint64(float64(rowCount) * selectionFactor)
+		}
+		if len(newSel.Conditions) > 0 {
+			newSel.SetChildren(scan)
+			newSel.onTable = true
+			resultPlan = &newSel
+		}
+	}
+	return resultPlan.matchProperty(&requiredProperty{}, &physicalPlanInfo{count: rowCount}), nil
+}
+
+// findLookUpIndex finds the index whose first column is the column, the covering one is preferred.
+func findLookUpIndex(indices []*model.IndexInfo, colInfo *model.ColumnInfo, columns []*model.ColumnInfo, pkIsHandle bool) *model.IndexInfo {
+	var found *model.IndexInfo
+	for _, index := range indices {
+		// A prefix index can't be looked up by the whole value.
+		first := index.Columns[0]
+		if first.Name.L != colInfo.Name.L || first.Length != types.UnspecifiedLength {
+			continue
+		}
+		if isCoveringIndex(columns, index.Columns, pkIsHandle) {
+			return index
+		}
+		if found == nil {
+			found = index
+		}
+	}
+	return found
+}
+
+// LookUpInnerPlan returns a copy of the inner child which only reads the rows whose lookup keys are in the values,
+// the lookup key is the inner key of the first equal condition. It returns nil if there are no values.
+// The values are sorted in place.
+func (p *PhysicalIndexJoin) LookUpInnerPlan(values []types.Datum) (PhysicalPlan, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	return copyLookUpPlan(p.children[1-p.OuterIndex].(PhysicalPlan), values)
+}
+
+// copyLookUpPlan copies the plans from p to the scan, and builds the ranges of the scan by the values.
+func copyLookUpPlan(p PhysicalPlan, values []types.Datum) (PhysicalPlan, error) {
+	rb := rangeBuilder{}
+	switch x := p.(type) {
+	case *PhysicalTableScan:
+		ts := *x
+		ts.Ranges = rb.buildTableRanges(rb.buildFromValues(values))
+		if rb.err != nil {
+			return nil, errors.Trace(rb.err)
+		}
+		return &ts, nil
+	case *PhysicalIndexScan:
+		is := *x
+		tp := &x.Table.Columns[x.Index.Columns[0].Offset].FieldType
+		is.Ranges = rb.fuseIndexRanges(rb.buildIndexRanges(rb.buildFromValues(values), tp), tp)
+		if rb.err != nil {
+			// The values out of the range of the column can't be converted, so the index is scanned in the full
+			// range, the unmatched rows are filtered by the join.
+			log.Warnf("[PLAN] can't build the lookup ranges of the index %s: %v", x.Index.Name.O, rb.err)
+			is.Ranges = x.Ranges
+		}
+		return &is, nil
+	}
+	child, err := copyLookUpPlan(p.GetChildByIndex(0).(PhysicalPlan), values)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	np := p.Copy()
+	np.SetChildren(child)
+	return np, nil
+}
@@ -14,7 +14,7 @@
 package plan
 
 import (
-	"sort"
+	"math"
 
 	"github.com/ngaut/log"
 	"github.com/pingcap/tidb/ast"
@@ -46,137 +46,292 @@ func findColumnIndexByGroup(groups []LogicalPlan, col *expression.Column) int {
 	return -1
 }
 
+// joinReorderDPThreshold is the max size of a join group that we reorder by dynamic programming,
+// a larger group is reordered greedily because the dynamic programming costs O(3^n).
+const joinReorderDPThreshold = 10
+
+// joinReOrderSolver reorders a group of inner joins by cost. The cost of a join tree is the sum of the
+// estimated row counts of all its joins, so the joins that filter out the most rows are done first.
 type joinReOrderSolver struct {
-	graph      []edgeList
-	group      []LogicalPlan
-	visited    []bool
+	group []LogicalPlan
+	// rowCounts stores the estimated row count of every plan in the group after its own filters.
+	rowCounts []float64
+	// edges[i][j] is true if there is an equal condition between group[i] and group[j].
+	edges      [][]bool
 	resultJoin LogicalPlan
-	groupRank  []*rankInfo
 	allocator  *idAllocator
+	hintInfo   *tableHintInfo
 }
 
-type edgeList []*rankInfo
-
-func (l edgeList) Len() int {
-	return len(l)
-}
-
-func (l edgeList) Less(i, j int) bool {
-	return l[i].rate < l[j].rate
+// joinNode is a join tree over some plans of the group. It is only turned into a Join plan when it is chosen.
+type joinNode struct {
+	left, right *joinNode
+	// leaf is the index of the plan in the group if the node is a leaf.
+	leaf     int
+	members  []int
+	mask     uint64
+	rowCount float64
+	cost     float64
 }
 
-func (l edgeList) Swap(i, j int) {
-	l[i], l[j] = l[j], l[i]
+// reorderJoin extracts all the equal conditions between the plans in the group and composes them to a graph.
+// Then it searches the join tree with the lowest cost, exhaustively by dynamic programming for a small group
+// and greedily for a large one. The plans which are not connected by any equal condition are joined last.
+func (e *joinReOrderSolver) reorderJoin(group []LogicalPlan, conds []expression.Expression) {
+	e.group = group
+	e.rowCounts = make([]float64, len(group))
+	e.edges = make([][]bool, len(group))
+	for i, p := range group {
+		e.rowCounts[i] = estimateRowCount(p)
+		e.edges[i] = make([]bool, len(group))
+	}
+	for _, cond := range conds {
+		f, ok := cond.(*expression.ScalarFunction)
+		if !ok {
+			continue
+		}
+		if f.FuncName.L == ast.EQ {
+			lCol, lok := f.Args[0].(*expression.Column)
+			rCol, rok := f.Args[1].(*expression.Column)
+			if lok && rok {
+				lID := findColumnIndexByGroup(group, lCol)
+				rID := findColumnIndexByGroup(group, rCol)
+				if lID != rID && lID != -1 && rID != -1 {
+					e.edges[lID][rID] = true
+					e.edges[rID][lID] = true
+					continue
+				}
+			}
+		}
+		// A condition on a single plan of the group filters its rows.
+		id := -1
+		for _, col := range extractColumns(f) {
+			idx := findColumnIndexByGroup(group, col)
+			if id != -1 && idx != id {
+				id = -1
+				break
+			}
+			id = idx
+		}
+		if id != -1 {
+			e.rowCounts[id] *= conditionSelectivity(f)
+		}
+	}
+	leaves := make([]*joinNode, len(group))
+	for i := range group {
+		leaves[i] = &joinNode{leaf: i, members: []int{i}, mask: 1 << uint(i), rowCount: e.rowCounts[i]}
+	}
+	var best *joinNode
+	if leading := getLeadingPlans(group, e.hintInfo); leading != nil {
+		// The plans of the LEADING hint are joined first in order, then the result is joined with the others greedily.
+		node := leaves[leading[0]]
+		for _, i := range leading[1:] {
+			node = e.join(node, leaves[i])
+		}
+		nodes := []*joinNode{node}
+		for _, leaf := range leaves {
+			if node.mask&leaf.mask == 0 {
+				nodes = append(nodes, leaf)
+			}
+		}
+		best = e.greedyJoin(nodes)
+	} else if len(group) <= joinReorderDPThreshold {
+		best = e.greedyJoin(e.dpJoin(leaves))
+	} else {
+		best = e.greedyJoin(leaves)
+	}
+	e.resultJoin = e.buildJoin(best)
 }
 
-type rankInfo struct {
-	nodeID int
-	rate   float64
+// conditionSelectivity estimates the rate of rows that satisfy a condition.
+func conditionSelectivity(f *expression.ScalarFunction) float64 {
+	switch f.FuncName.L {
+	case ast.EQ:
+		return 0.1
+	case ast.LT, ast.LE, ast.GE, ast.GT:
+		return 0.3
+	// TODO: Estimate it more precisely in future.
+	default:
+		return 0.9
+	}
 }
 
-func (e *joinReOrderSolver) Less(i, j int) bool {
-	return e.groupRank[i].rate < e.groupRank[j].rate
+// estimateRowCount roughly estimates the row count of a logical plan by the statistics of its data sources.
+func estimateRowCount(p LogicalPlan) float64 {
+	children := p.GetChildren()
+	switch x := p.(type) {
+	case *DataSource:
+		return float64(x.statisticTable.Count)
+	case *TableDual:
+		return 1
+	case *GenerateSeries:
+		return float64(x.rowCount())
+	case *JSONTable:
+		return jsonTableRowCount
+	case *Profile:
+		return profileRowCount
+	case *RowHistory:
+		return rowHistoryRowCount
+	case *CTE:
+		return estimateRowCount(x.Source.logic)
+	case *Selection:
+		return estimateRowCount(children[0].(LogicalPlan)) * selectionFactor
+	case *Aggregation:
+		if len(x.GroupByItems) == 0 {
+			return 1
+		}
+		return estimateRowCount(children[0].(LogicalPlan)) * aggFactor
+	case *Limit:
+		return math.Min(estimateRowCount(children[0].(LogicalPlan)), float64(x.Offset+x.Count))
+	case *Join:
+		lCount := estimateRowCount(children[0].(LogicalPlan))
+		rCount := estimateRowCount(children[1].(LogicalPlan))
+		switch {
+		case x.JoinType == SemiJoin || x.JoinType == SemiJoinWithAux:
+			return lCount
+		case len(x.EqualConditions) == 0 && x.JoinType == InnerJoin:
+			return lCount * rCount
+		default:
+			return math.Max(lCount, rCount)
+		}
+	case *Union:
+		count := 0.0
+		for _, child := range children {
+			count += estimateRowCount(child.(LogicalPlan))
+		}
+		return count
+	}
+	if len(children) == 0 {
+		return 1
+	}
+	return estimateRowCount(children[0].(LogicalPlan))
 }
 
-func (e *joinReOrderSolver) Swap(i, j int) {
-	e.groupRank[i], e.groupRank[j] = e.groupRank[j], e.groupRank[i]
+// join makes a join node of two join trees. We assume the join keys are unique on the smaller side of
+// every equal condition, so each of them reduces the row count by the larger side.
+func (e *joinReOrderSolver) join(l, r *joinNode) *joinNode {
+	rowCount := l.rowCount * r.rowCount
+	for _, i := range l.members {
+		for _, j := range r.members {
+			if e.edges[i][j] {
+				rowCount /= math.Max(math.Max(e.rowCounts[i], e.rowCounts[j]), 1)
+			}
+		}
+	}
+	rowCount = math.Max(rowCount, 1)
+	members := make([]int, 0, len(l.members)+len(r.members))
+	members = append(append(members, l.members...), r.members...)
+	return &joinNode{
+		left:     l,
+		right:    r,
+		members:  members,
+		mask:     l.mask | r.mask,
+		rowCount: rowCount,
+		cost:     l.cost + r.cost + rowCount,
+	}
 }
 
-func (e *joinReOrderSolver) Len() int {
-	return len(e.groupRank)
+// connected checks if there is an equal condition between two join trees.
+func (e *joinReOrderSolver) connected(l, r *joinNode) bool {
+	for _, i := range l.members {
+		for _, j := range r.members {
+			if e.edges[i][j] {
+				return true
+			}
+		}
+	}
+	return false
 }
 
-// reorderJoin implements a simple join reorder algorithm. It will extract all the equal conditions and compose them to a graph.
-// Then walk through the graph and pick the nodes connected by some edges to compose a join tree.
-// We will pick the node with least result set as early as possible.
-func (e *joinReOrderSolver) reorderJoin(group []LogicalPlan, conds []expression.Expression) {
-	e.graph = make([]edgeList, len(group))
-	e.group = group
-	e.visited = make([]bool, len(group))
-	e.resultJoin = nil
-	e.groupRank = make([]*rankInfo, len(group))
-	for i := 0; i < len(e.groupRank); i++ {
-		e.groupRank[i] = &rankInfo{
-			nodeID: i,
-			rate:   1.0,
-		}
+// dpJoin finds the cheapest join tree of every connected sub graph by dynamic programming over the subsets
+// of the group, a cartesian join is never considered here. It returns the best trees of the connected components.
+func (e *joinReOrderSolver) dpJoin(leaves []*joinNode) []*joinNode {
+	best := make([]*joinNode, 1<<uint(len(leaves)))
+	for _, leaf := range leaves {
+		best[leaf.mask] = leaf
 	}
-	for _, cond := range conds {
-		if f, ok := cond.(*expression.ScalarFunction); ok {
-			if f.FuncName.L == ast.EQ {
-				lCol, lok := f.Args[0].(*expression.Column)
-				rCol, rok := f.Args[1].(*expression.Column)
-				if lok && rok {
-					lID := findColumnIndexByGroup(group, lCol)
-					rID := findColumnIndexByGroup(group, rCol)
-					if lID != rID {
-						e.graph[lID] = append(e.graph[lID], &rankInfo{nodeID: rID})
-						e.graph[rID] = append(e.graph[rID], &rankInfo{nodeID: lID})
-						continue
-					}
-				}
+	for set := uint64(1); set < uint64(len(best)); set++ {
+		lowest := set & -set
+		if set == lowest {
+			continue
+		}
+		// Enumerate every partition of the set once by requiring the left part to contain the lowest member.
+		for sub := (set - 1) & set; sub > 0; sub = (sub - 1) & set {
+			if sub&lowest == 0 {
+				continue
 			}
-			id := -1
-			rate := 1.0
-			cols := extractColumns(f)
-			for _, col := range cols {
-				idx := findColumnIndexByGroup(group, col)
-				if id == -1 {
-					switch f.FuncName.L {
-					case ast.EQ:
-						rate *= 0.1
-					case ast.LT, ast.LE, ast.GE, ast.GT:
-						rate *= 0.3
-					// TODO: Estimate it more precisely in future.
-					default:
-						rate *= 0.9
-					}
-					id = idx
-				} else {
-					id = -1
-					break
-				}
+			l, r := best[sub], best[set^sub]
+			if l == nil || r == nil || !e.connected(l, r) {
+				continue
 			}
-			if id != -1 {
-				e.groupRank[id].rate *= rate
+			if node := e.join(l, r); best[set] == nil || node.cost < best[set].cost {
+				best[set] = node
 			}
 		}
 	}
-	for _, node := range e.graph {
-		for _, edge := range node {
-			edge.rate = e.groupRank[edge.nodeID].rate
+	// Return the best trees of the connected components in the order of their first members.
+	var components []*joinNode
+	var covered uint64
+	for _, leaf := range leaves {
+		if leaf.mask&covered == 0 {
+			component := e.connectedComponent(leaf.leaf)
+			components = append(components, best[component])
+			covered |= component
 		}
 	}
-	sort.Sort(e)
-	for _, edge := range e.graph {
-		sort.Sort(edge)
-	}
-	var cartesianJoinGroup []LogicalPlan
-	for j := 0; j < len(e.groupRank); j++ {
-		i := e.groupRank[j].nodeID
-		if !e.visited[i] {
-			e.resultJoin = e.group[i]
-			e.walkGraphAndComposeJoin(i)
-			cartesianJoinGroup = append(cartesianJoinGroup, e.resultJoin)
+	return components
+}
+
+// connectedComponent returns the members connected to the i-th plan of the group as a bit set.
+func (e *joinReOrderSolver) connectedComponent(i int) uint64 {
+	component := uint64(1) << uint(i)
+	stack := []int{i}
+	for len(stack) > 0 {
+		u := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for v, connected := range e.edges[u] {
+			if connected && component&(1<<uint(v)) == 0 {
+				component |= 1 << uint(v)
+				stack = append(stack, v)
+			}
 		}
 	}
-	e.makeBushyJoin(cartesianJoinGroup)
+	return component
 }
 
-// Make cartesian join as bushy tree.
-func (e *joinReOrderSolver) makeBushyJoin(cartesianJoinGroup []LogicalPlan) {
-	for len(cartesianJoinGroup) > 1 {
-		resultJoinGroup := make([]LogicalPlan, 0, len(cartesianJoinGroup))
-		for i := 0; i < len(cartesianJoinGroup); i += 2 {
-			if i+1 == len(cartesianJoinGroup) {
-				resultJoinGroup = append(resultJoinGroup, cartesianJoinGroup[i])
+// greedyJoin joins the pair of trees with the least result rows repeatedly until only one tree remains.
+// The trees connected by equal conditions are always joined before the cartesian joins.
+func (e *joinReOrderSolver) greedyJoin(nodes []*joinNode) *joinNode {
+	for len(nodes) > 1 {
+		var best *joinNode
+		bestI, bestJ := -1, -1
+		for _, allowCartesian := range []bool{false, true} {
+			for i := 0; i < len(nodes); i++ {
+				for j := i + 1; j < len(nodes); j++ {
+					if !allowCartesian && !e.connected(nodes[i], nodes[j]) {
+						continue
+					}
+					if node := e.join(nodes[i], nodes[j]); best == nil || node.rowCount < best.rowCount {
+						best, bestI, bestJ = node, i, j
+					}
+				}
+			}
+			if best != nil {
 				break
 			}
-			resultJoinGroup = append(resultJoinGroup, e.newJoin(cartesianJoinGroup[i], cartesianJoinGroup[i+1]))
 		}
-		cartesianJoinGroup = resultJoinGroup
+		nodes[bestI] = best
+		nodes = append(nodes[:bestJ], nodes[bestJ+1:]...)
 	}
-	e.resultJoin = cartesianJoinGroup[0]
+	return nodes[0]
+}
+
+// buildJoin turns a join tree into logical plans.
+func (e *joinReOrderSolver) buildJoin(node *joinNode) LogicalPlan {
+	if node.left == nil {
+		return e.group[node.leaf]
+	}
+	return e.newJoin(e.buildJoin(node.left), e.buildJoin(node.right))
 }
 
 func (e *joinReOrderSolver) newJoin(lChild, rChild LogicalPlan) *Join {
@@ -184,6 +339,7 @@ func (e *joinReOrderSolver) newJoin(lChild, rChild LogicalPlan) *Join {
 		JoinType:        InnerJoin,
 		reordered:       true,
 		baseLogicalPlan: newBaseLogicalPlan(Jn, e.allocator),
+		hintInfo:        e.hintInfo,
 	}
 	join.self = join
 	join.initID()
@@ -193,15 +349,3 @@ func (e *joinReOrderSolver) newJoin(lChild, rChild LogicalPlan) *Join {
 	rChild.SetParents(join)
 	return join
 }
-
-// walkGraph implements a dfs algorithm. Each time it picks a edge with lowest rate, which has been sorted before.
-func (e *joinReOrderSolver) walkGraphAndComposeJoin(u int) {
-	e.visited[u] = true
-	for _, edge := range e.graph[u] {
-		v := edge.nodeID
-		if !e.visited[v] {
-			e.resultJoin = e.newJoin(e.resultJoin, e.group[v])
-			e.walkGraphAndComposeJoin(v)
-		}
-	}
-}
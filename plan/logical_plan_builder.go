@@ -24,8 +24,19 @@ import (
 	"github.com/pingcap/tidb/util/types"
 )
 
+// idAllocator is shared by the plans built in an optimization, so it also carries the cost factors of the
+// optimization.
 type idAllocator struct {
-	id int
+	id      int
+	factors *costFactors
+}
+
+// costFactors returns the cost factors of the optimization, the defaults are used if they're not loaded.
+func (a *idAllocator) costFactors() *costFactors {
+	if a == nil || a.factors == nil {
+		return defaultCostFactors
+	}
+	return a.factors
 }
 
 func (a *idAllocator) allocID() string {
@@ -42,7 +53,10 @@ func (p *Aggregation) collectGroupByColumns() {
 	}
 }
 
-func (b *planBuilder) buildAggregation(p LogicalPlan, aggFuncList []*ast.AggregateFuncExpr, gbyItems []expression.Expression) (LogicalPlan, map[int]int) {
+// buildAggregation builds the aggregation of the functions grouped by the items, if outerCols is true, the columns of
+// p are output too, so the subqueries can refer to them as the outer columns.
+func (b *planBuilder) buildAggregation(p LogicalPlan, aggFuncList []*ast.AggregateFuncExpr, gbyItems []expression.Expression,
+	outerCols bool) (LogicalPlan, map[int]int) {
 	agg := &Aggregation{
 		AggFuncs:        make([]expression.AggregationFunction, 0, len(aggFuncList)),
 		ctx:             b.ctx,
@@ -82,14 +96,36 @@ func (b *planBuilder) buildAggregation(p LogicalPlan, aggFuncList []*ast.Aggrega
 			position := len(agg.AggFuncs)
 			aggIndexMap[i] = position
 			agg.AggFuncs = append(agg.AggFuncs, newFunc)
+			var tblName model.CIStr
+			if col, ok := newFunc.GetArgs()[0].(*expression.Column); ok && aggFunc.F == ast.AggFuncFirstRow {
+				// The subqueries in the having and order by clauses may refer to it by the table name.
+				tblName = col.TblName
+			}
 			schema = append(schema, &expression.Column{
 				FromID:      agg.id,
+				TblName:     tblName,
 				ColName:     model.NewCIStr(fmt.Sprintf("%s_col_%d", agg.id, position)),
 				Position:    position,
 				IsAggOrSubq: true,
 				RetType:     aggFunc.GetType()})
 		}
 	}
+	// The subqueries may refer to the columns of the grouped rows as the outer columns, for example:
+	// "select c, (select max(t2.b) from t2 where t2.a = t.c) from t group by c", so the columns of the child are
+	// output by the firstrow functions too, the ones not referred to are pruned.
+	if outerCols {
+		for _, col := range p.GetSchema() {
+			position := len(agg.AggFuncs)
+			agg.AggFuncs = append(agg.AggFuncs, expression.NewAggFunction(ast.AggFuncFirstRow, []expression.Expression{col.Clone()}, false))
+			schema = append(schema, &expression.Column{
+				FromID:   agg.id,
+				DBName:   col.DBName,
+				TblName:  col.TblName,
+				ColName:  col.ColName,
+				Position: position,
+				RetType:  col.RetType})
+		}
+	}
 	agg.GroupByItems = gbyItems
 	agg.SetSchema(schema)
 	agg.collectGroupByColumns()
@@ -101,6 +137,16 @@ func (b *planBuilder) buildResultSetNode(node ast.ResultSetNode) LogicalPlan {
 	case *ast.Join:
 		return b.buildJoin(x)
 	case *ast.TableSource:
+		if x.IsView {
+			checker := b.checkView(x)
+			if b.err != nil {
+				return nil
+			}
+			b.viewScopes = append(b.viewScopes, viewScope{name: x.ViewName, checker: checker})
+			defer func() {
+				b.viewScopes = b.viewScopes[:len(b.viewScopes)-1]
+			}()
+		}
 		var p LogicalPlan
 		switch v := x.Source.(type) {
 		case *ast.SelectStmt:
@@ -109,6 +155,10 @@ func (b *planBuilder) buildResultSetNode(node ast.ResultSetNode) LogicalPlan {
 			p = b.buildUnion(v)
 		case *ast.TableName:
 			p = b.buildDataSource(v)
+		case *ast.TableFunc:
+			p = b.buildTableFunc(v)
+		case *ast.CTETable:
+			p = b.buildCTE(v)
 		default:
 			b.err = ErrUnsupportedType.Gen("unsupported table source type %T", v)
 			return nil
@@ -118,6 +168,14 @@ func (b *planBuilder) buildResultSetNode(node ast.ResultSetNode) LogicalPlan {
 		}
 		if v, ok := p.(*DataSource); ok {
 			v.TableAsName = &x.AsName
+			v.addIndexHints(b.getTableHints())
+		}
+		if x.IsView {
+			// The columns of a view are named by the view rather than the select statement.
+			schema := p.GetSchema()
+			for i, rf := range x.GetResultFields() {
+				schema[i].ColName = rf.ColumnAsName
+			}
 		}
 		if x.AsName.L != "" {
 			schema := p.GetSchema()
@@ -207,13 +265,35 @@ func (b *planBuilder) buildJoin(join *ast.Join) LogicalPlan {
 		return b.buildResultSetNode(join.Left)
 	}
 	leftPlan := b.buildResultSetNode(join.Left)
-	rightPlan := b.buildResultSetNode(join.Right)
+	var rightPlan LogicalPlan
+	if ts, ok := join.Right.(*ast.TableSource); ok && isTableFunc(ts) {
+		if b.err != nil {
+			return nil
+		}
+		// A table function can refer to the tables on its left, which are outer columns while it's being built.
+		b.outerSchemas = append(b.outerSchemas, leftPlan.GetSchema())
+		rightPlan = b.buildResultSetNode(join.Right)
+		b.outerSchemas = b.outerSchemas[:len(b.outerSchemas)-1]
+		if b.err != nil {
+			return nil
+		}
+		if isCorrelatedWith(rightPlan, leftPlan.GetSchema()) {
+			return b.buildLateralJoin(join, leftPlan, rightPlan)
+		}
+	} else {
+		rightPlan = b.buildResultSetNode(join.Right)
+	}
 	newSchema := append(leftPlan.GetSchema().Clone(), rightPlan.GetSchema().Clone()...)
 	joinPlan := &Join{baseLogicalPlan: newBaseLogicalPlan(Jn, b.allocator)}
 	joinPlan.self = joinPlan
 	joinPlan.initID()
 	joinPlan.SetSchema(newSchema)
+	joinPlan.hintInfo = b.getTableHints()
 	joinPlan.correlated = leftPlan.IsCorrelated() || rightPlan.IsCorrelated()
+	if join.StraightJoin || (joinPlan.hintInfo != nil && joinPlan.hintInfo.straightJoin) {
+		joinPlan.straightJoin = true
+		joinPlan.reordered = true
+	}
 	if join.On != nil {
 		onExpr, _, err := b.rewrite(join.On.Expr, joinPlan, nil, false)
 		if err != nil {
@@ -246,6 +326,37 @@ func (b *planBuilder) buildJoin(join *ast.Join) LogicalPlan {
 	return joinPlan
 }
 
+func isTableFunc(ts *ast.TableSource) bool {
+	_, ok := ts.Source.(*ast.TableFunc)
+	return ok
+}
+
+// isCorrelatedWith checks whether p refers to the columns of the schema as correlated columns.
+func isCorrelatedWith(p LogicalPlan, schema expression.Schema) bool {
+	for _, corCol := range p.extractCorrelatedCols() {
+		if schema.GetIndex(&corCol.Column) != -1 {
+			return true
+		}
+	}
+	return false
+}
+
+// buildLateralJoin builds the join with a table function referring to the tables on its left,
+// the table function is executed for every row of the left plan by a lateral Apply.
+func (b *planBuilder) buildLateralJoin(join *ast.Join, leftPlan, rightPlan LogicalPlan) LogicalPlan {
+	if join.Tp != ast.CrossJoin {
+		b.err = ErrUnsupportedType.Gen("Unsupported outer join with a table function referring to the tables on its left")
+		return nil
+	}
+	ap := b.buildApply(leftPlan, rightPlan, nil).(*Apply)
+	ap.Lateral = true
+	ap.SetSchema(append(leftPlan.GetSchema().Clone(), rightPlan.GetSchema().Clone()...))
+	if join.On == nil {
+		return ap
+	}
+	return b.buildSelection(ap, join.On.Expr, nil)
+}
+
 func (b *planBuilder) buildSelection(p LogicalPlan, where ast.ExprNode, AggMapper map[*ast.AggregateFuncExpr]int) LogicalPlan {
 	conditions := splitWhere(where)
 	expressions := make([]expression.Expression, 0, len(conditions))
@@ -302,6 +413,10 @@ func (b *planBuilder) buildProjection(p LogicalPlan, fields []*ast.SelectField,
 			if astCol, ok := getInnerFromParentheses(field.Expr).(*ast.ColumnNameExpr); ok {
 				colName = astCol.Name.Name
 				tblName = astCol.Name.Table
+				if tblName.L == "" {
+					// The subqueries in the order by and having clauses may refer to it by the table name.
+					tblName = c.TblName
+				}
 			} else {
 				colName = c.ColName
 				tblName = c.TblName
@@ -311,6 +426,10 @@ func (b *planBuilder) buildProjection(p LogicalPlan, fields []*ast.SelectField,
 			if agg, ok := field.Expr.(*ast.AggregateFuncExpr); ok && agg.F == ast.AggFuncFirstRow {
 				if col, ok := agg.Args[0].(*ast.ColumnNameExpr); ok {
 					colName = col.Name.Name
+					tblName = col.Name.Table
+					if c, ok := newExpr.(*expression.Column); ok && tblName.L == "" {
+						tblName = c.TblName
+					}
 				}
 			} else {
 				innerExpr := getInnerFromParentheses(field.Expr)
@@ -356,6 +475,9 @@ func (b *planBuilder) buildUnion(union *ast.UnionStmt) LogicalPlan {
 	for i, sel := range union.SelectList.Selects {
 		u.children[i] = b.buildSelect(sel)
 		u.correlated = u.correlated || u.children[i].IsCorrelated()
+		checker := &sideEffectChecker{}
+		sel.Accept(checker)
+		u.SideEffects = u.SideEffects || checker.found
 	}
 	firstSchema := u.children[0].GetSchema().Clone()
 	for _, sel := range u.children {
@@ -363,28 +485,17 @@ func (b *planBuilder) buildUnion(union *ast.UnionStmt) LogicalPlan {
 			b.err = errors.New("The used SELECT statements have a different number of columns")
 			return nil
 		}
-		for i, col := range sel.GetSchema() {
-			/*
-			 * The lengths of the columns in the UNION result take into account the values retrieved by all of the SELECT statements
-			 * SELECT REPEAT('a',1) UNION SELECT REPEAT('b',10);
-			 * +---------------+
-			 * | REPEAT('a',1) |
-			 * +---------------+
-			 * | a             |
-			 * | bbbbbbbbbb    |
-			 * +---------------+
-			 */
-			if col.RetType.Flen > firstSchema[i].RetType.Flen {
-				firstSchema[i].RetType.Flen = col.RetType.Flen
-			}
-			// For select nul union select "abc", we should not convert "abc" to nil.
-			// And the result field type should be VARCHAR.
-			if firstSchema[i].RetType.Tp == 0 || firstSchema[i].RetType.Tp == mysql.TypeNull {
-				firstSchema[i].RetType.Tp = col.RetType.Tp
-			}
-		}
 		sel.SetParents(u)
 	}
+	// The type of a column in the UNION result is aggregated from the types of the column in all the SELECTs, e.g.
+	// "select 1 union select 'abc'" is a VARCHAR, and "select cast(1 as unsigned) union select -1" is a BIGINT.
+	for i, col := range firstSchema {
+		tps := make([]*types.FieldType, 0, len(u.children))
+		for _, sel := range u.children {
+			tps = append(tps, sel.GetSchema()[i].RetType)
+		}
+		col.RetType = types.AggFieldType(tps)
+	}
 	for _, v := range firstSchema {
 		v.FromID = u.id
 		v.DBName = model.NewCIStr("")
@@ -405,6 +516,63 @@ func (b *planBuilder) buildUnion(union *ast.UnionStmt) LogicalPlan {
 	return p
 }
 
+// sideEffectChecker checks if a statement changes the state of the session when it's executed.
+type sideEffectChecker struct {
+	found bool
+}
+
+// Enter implements Visitor interface.
+func (c *sideEffectChecker) Enter(in ast.Node) (ast.Node, bool) {
+	switch v := in.(type) {
+	case *ast.VariableExpr:
+		c.found = c.found || (!v.IsSystem && v.Value != nil)
+	case *ast.FuncCallExpr:
+		// LAST_INSERT_ID(expr) sets the last insert id of the session.
+		c.found = c.found || (v.FnName.L == ast.LastInsertId && len(v.Args) > 0)
+	}
+	return in, c.found
+}
+
+// Leave implements Visitor interface.
+func (c *sideEffectChecker) Leave(in ast.Node) (ast.Node, bool) {
+	return in, !c.found
+}
+
+// subqueryChecker checks if an expression contains a subquery.
+type subqueryChecker struct {
+	found bool
+}
+
+// Enter implements Visitor interface.
+func (c *subqueryChecker) Enter(in ast.Node) (ast.Node, bool) {
+	if _, ok := in.(*ast.SubqueryExpr); ok {
+		c.found = true
+	}
+	return in, c.found
+}
+
+// Leave implements Visitor interface.
+func (c *subqueryChecker) Leave(in ast.Node) (ast.Node, bool) {
+	return in, !c.found
+}
+
+// hasSubquery checks if the select fields, the having clause or the order by clause of the select contain a subquery.
+func hasSubquery(sel *ast.SelectStmt) bool {
+	checker := &subqueryChecker{}
+	for _, field := range sel.Fields.Fields {
+		field.Expr.Accept(checker)
+	}
+	if sel.Having != nil {
+		sel.Having.Expr.Accept(checker)
+	}
+	if sel.OrderBy != nil {
+		for _, item := range sel.OrderBy.Items {
+			item.Expr.Accept(checker)
+		}
+	}
+	return checker.found
+}
+
 // ByItems wraps a "by" item.
 type ByItems struct {
 	Expr expression.Expression
@@ -495,7 +663,7 @@ func resolveFromSelectFields(v *ast.ColumnNameExpr, fields []*ast.SelectField, i
 				index = i
 			} else if !colMatch(matchedExpr.(*ast.ColumnNameExpr).Name, curCol.Name) &&
 				!colMatch(curCol.Name, matchedExpr.(*ast.ColumnNameExpr).Name) {
-				return -1, errors.Errorf("Column '%s' in field list is ambiguous", curCol.Name.Name.L)
+				return -1, ErrAmbiguousColumn.Gen("Column '%s' in field list is ambiguous", curCol.Name.Name.L)
 			}
 		}
 	}
@@ -514,6 +682,11 @@ type havingAndOrderbyExprResolver struct {
 	aggMapper    map[*ast.AggregateFuncExpr]int
 	colMapper    map[*ast.ColumnNameExpr]int
 	gbyItems     []*ast.ByItem
+	outerSchemas []expression.Schema
+	// distinct is true when the select statement is SELECT DISTINCT, its order by items can only refer to the
+	// columns in the select list. orderItem is the 1-based position of the order by item being visited.
+	distinct  bool
+	orderItem int
 }
 
 // Enter implements Visitor interface.
@@ -532,6 +705,23 @@ func (a *havingAndOrderbyExprResolver) Enter(n ast.Node) (node ast.Node, skipChi
 	return n, false
 }
 
+func (a *havingAndOrderbyExprResolver) clauseName() string {
+	if a.orderBy {
+		return "order clause"
+	}
+	return "having clause"
+}
+
+func (a *havingAndOrderbyExprResolver) resolveFromOuterSchemas(v *ast.ColumnNameExpr) bool {
+	for i := len(a.outerSchemas) - 1; i >= 0; i-- {
+		col, err := a.outerSchemas[i].FindColumn(v.Name)
+		if col != nil && err == nil {
+			return true
+		}
+	}
+	return false
+}
+
 func (a *havingAndOrderbyExprResolver) resolveFromSchema(v *ast.ColumnNameExpr, schema expression.Schema) (int, error) {
 	col, err := schema.FindColumn(v.Name)
 	if err != nil {
@@ -546,7 +736,16 @@ func (a *havingAndOrderbyExprResolver) resolveFromSchema(v *ast.ColumnNameExpr,
 		Name:   col.ColName,
 	}
 	for i, field := range a.selectFields {
-		if c, ok := field.Expr.(*ast.ColumnNameExpr); ok && colMatch(newColName, c.Name) {
+		c, ok := field.Expr.(*ast.ColumnNameExpr)
+		if !ok {
+			continue
+		}
+		if colMatch(newColName, c.Name) {
+			return i, nil
+		}
+		// The column in the select list may be written without the table name or renamed by an alias,
+		// e.g. "select distinct b as x from t order by abs(t.b)".
+		if fieldCol, err := schema.FindColumn(c.Name); err == nil && fieldCol != nil && fieldCol.Equal(col) {
 			return i, nil
 		}
 	}
@@ -610,7 +809,16 @@ func (a *havingAndOrderbyExprResolver) Leave(n ast.Node) (node ast.Node, ok bool
 			return node, false
 		}
 		if index == -1 {
-			a.err = errors.Errorf("Unknown Column %s", v.Name.Name.L)
+			// The subqueries may refer to the columns of the outer queries, they are rewritten as correlated columns.
+			if a.resolveFromOuterSchemas(v) {
+				return n, true
+			}
+			a.err = ErrUnknownColumn.Gen("Unknown column '%s' in '%s'", columnNameString(v.Name), a.clauseName())
+			return node, false
+		}
+		if a.orderBy && a.distinct && !a.inAggFunc && a.selectFields[index].Auxiliary {
+			a.err = ErrFieldInOrderNotSelect.Gen("Expression #%d of ORDER BY clause is not in SELECT list, references column '%s' which is not in SELECT list; this is incompatible with DISTINCT",
+				a.orderItem, columnNameString(v.Name))
 			return node, false
 		}
 		if a.inAggFunc {
@@ -628,6 +836,7 @@ func (b *planBuilder) resolveHavingAndOrderBy(sel *ast.SelectStmt, p LogicalPlan
 		selectFields: sel.Fields.Fields,
 		aggMapper:    make(map[*ast.AggregateFuncExpr]int),
 		colMapper:    b.colMapper,
+		outerSchemas: b.outerSchemas,
 	}
 	if sel.GroupBy != nil {
 		extractor.gbyItems = sel.GroupBy.Items
@@ -645,9 +854,11 @@ func (b *planBuilder) resolveHavingAndOrderBy(sel *ast.SelectStmt, p LogicalPlan
 	extractor.aggMapper = make(map[*ast.AggregateFuncExpr]int)
 	extractor.orderBy = true
 	extractor.inExpr = false
+	extractor.distinct = sel.Distinct
 	// Extract agg funcs from order by clause.
 	if sel.OrderBy != nil {
-		for _, item := range sel.OrderBy.Items {
+		for i, item := range sel.OrderBy.Items {
+			extractor.orderItem = i + 1
 			n, ok := item.Expr.Accept(extractor)
 			if !ok {
 				b.err = errors.Trace(extractor.err)
@@ -717,7 +928,7 @@ func (g *gbyResolver) Leave(inNode ast.Node) (ast.Node, bool) {
 		if v.N >= 1 && v.N <= len(g.fields) {
 			return g.fields[v.N-1].Expr, true
 		}
-		g.err = errors.Errorf("Unknown column '%d' in 'group statement'", v.N)
+		g.err = ErrUnknownColumn.Gen("Unknown column '%d' in 'group statement'", v.N)
 		return inNode, false
 	}
 	return inNode, true
@@ -777,6 +988,21 @@ func (b *planBuilder) unfoldWildStar(p LogicalPlan, selectFields []*ast.SelectFi
 }
 
 func (b *planBuilder) buildSelect(sel *ast.SelectStmt) LogicalPlan {
+	for _, hint := range sel.TableHints {
+		if hint.HintName.L == hintDisableRules {
+			for _, arg := range hint.Args {
+				b.disableRules(arg.L)
+			}
+		}
+	}
+	if b.err != nil {
+		return nil
+	}
+	b.pushTableHints(b.getSelectHints(sel))
+	defer b.popTableHints()
+	if sel.StraightJoin {
+		b.getTableHints().straightJoin = true
+	}
 	hasAgg := b.detectSelectAgg(sel)
 	var (
 		p                             LogicalPlan
@@ -802,6 +1028,12 @@ func (b *planBuilder) buildSelect(sel *ast.SelectStmt) LogicalPlan {
 			return nil
 		}
 	}
+	if hasAgg && b.ctx.GetSessionVars().OnlyFullGroupBy {
+		b.checkOnlyFullGroupBy(p, sel, gbyCols)
+		if b.err != nil {
+			return nil
+		}
+	}
 	// We must resolve having and order by clause before build projection,
 	// because when the query is "select a+1 as b from t having sum(b) < 0", we must replace sum(b) to sum(a+1),
 	// which only can be done before building projection and extracting Agg functions.
@@ -813,7 +1045,11 @@ func (b *planBuilder) buildSelect(sel *ast.SelectStmt) LogicalPlan {
 		}
 	}
 	if sel.LockTp != ast.SelectLockNone {
-		p = b.buildSelectLock(p, sel.LockTp)
+		var lockTables []*ast.TableName
+		if sel.LockTables != nil {
+			lockTables = sel.LockTables.Tables
+		}
+		p = b.buildSelectLock(p, sel.LockTp, lockTables)
 	}
 	if hasAgg {
 		aggFuncs, totalMap = b.extractAggFuncs(sel.Fields.Fields)
@@ -821,7 +1057,7 @@ func (b *planBuilder) buildSelect(sel *ast.SelectStmt) LogicalPlan {
 			return nil
 		}
 		var aggIndexMap map[int]int
-		p, aggIndexMap = b.buildAggregation(p, aggFuncs, gbyCols)
+		p, aggIndexMap = b.buildAggregation(p, aggFuncs, gbyCols, hasSubquery(sel))
 		for k, v := range totalMap {
 			totalMap[k] = aggIndexMap[v]
 		}
@@ -874,6 +1110,61 @@ func (b *planBuilder) buildTrim(p LogicalPlan, len int) LogicalPlan {
 	return trim
 }
 
+// buildCTE builds the plan of a reference to a common table expression. The query of a CTE referred to only once
+// is merged into the outer query like a derived table, the others are materialized and shared by the references.
+func (b *planBuilder) buildCTE(ct *ast.CTETable) LogicalPlan {
+	cte := ct.CTE
+	rfs := ct.GetResultFields()
+	if cte.RefCount <= 1 {
+		p := b.buildCTEQuery(cte)
+		if b.err != nil {
+			return nil
+		}
+		schema := p.GetSchema()
+		for i, rf := range rfs {
+			schema[i].ColName = rf.ColumnAsName
+		}
+		return p
+	}
+	src, ok := b.cteSources[cte]
+	if !ok {
+		logic := b.buildCTEQuery(cte)
+		if b.err != nil {
+			return nil
+		}
+		src = &CTESource{Name: cte.Name, logic: logic, ctx: b.ctx, disabled: b.disabledRules}
+		if b.cteSources == nil {
+			b.cteSources = make(map[*ast.CommonTableExpr]*CTESource)
+		}
+		b.cteSources[cte] = src
+	}
+	p := &CTE{baseLogicalPlan: newBaseLogicalPlan(CTETbl, b.allocator), Source: src}
+	p.self = p
+	p.initID()
+	srcSchema := src.logic.GetSchema()
+	schema := make(expression.Schema, 0, len(rfs))
+	for i, rf := range rfs {
+		schema = append(schema, &expression.Column{
+			FromID:   p.GetID(),
+			ColName:  rf.ColumnAsName,
+			TblName:  cte.Name,
+			RetType:  srcSchema[i].RetType,
+			Position: i,
+		})
+	}
+	p.SetSchema(schema)
+	return p
+}
+
+// buildCTEQuery builds the query of a common table expression, which can't refer to the outer query.
+func (b *planBuilder) buildCTEQuery(cte *ast.CommonTableExpr) LogicalPlan {
+	outerSchemas := b.outerSchemas
+	b.outerSchemas = nil
+	p := b.buildResultSetNode(cte.Query)
+	b.outerSchemas = outerSchemas
+	return p
+}
+
 func (b *planBuilder) buildTableDual() LogicalPlan {
 	dual := &TableDual{baseLogicalPlan: newBaseLogicalPlan(Dual, b.allocator)}
 	dual.self = dual
@@ -882,6 +1173,9 @@ func (b *planBuilder) buildTableDual() LogicalPlan {
 }
 
 func (b *planBuilder) getTableStats(table *model.TableInfo) *statistics.Table {
+	if override := b.ctx.GetSessionVars().StatsOverrides[table.ID]; override != nil {
+		return statistics.OverriddenTable(table, override.Count, override.NDVs)
+	}
 	// TODO: Currently we always return a pseudo table for good performance. We will use a cache in future.
 	return statistics.PseudoTable(table)
 }
@@ -895,6 +1189,7 @@ func (b *planBuilder) buildDataSource(tn *ast.TableName) LogicalPlan {
 		ctx:             b.ctx,
 		table:           tn,
 		Table:           tn.TableInfo,
+		indexHints:      tn.IndexHints,
 		baseLogicalPlan: newBaseLogicalPlan(Ts, b.allocator),
 		statisticTable:  statisticTable,
 	}
@@ -998,7 +1293,7 @@ func (b *planBuilder) buildMaxOneRow(p LogicalPlan) LogicalPlan {
 	return maxOneRow
 }
 
-func (b *planBuilder) buildSemiJoin(outerPlan, innerPlan LogicalPlan, onCondition []expression.Expression, asScalar bool, not bool) LogicalPlan {
+func (b *planBuilder) buildSemiJoin(outerPlan, innerPlan LogicalPlan, onCondition []expression.Expression, asScalar, not, nullAware bool) LogicalPlan {
 	joinPlan := &Join{baseLogicalPlan: newBaseLogicalPlan(Jn, b.allocator)}
 	joinPlan.self = joinPlan
 	joinPlan.initID()
@@ -1025,6 +1320,7 @@ func (b *planBuilder) buildSemiJoin(outerPlan, innerPlan LogicalPlan, onConditio
 		joinPlan.JoinType = SemiJoin
 	}
 	joinPlan.anti = not
+	joinPlan.nullAware = nullAware
 	joinPlan.SetChildren(outerPlan, innerPlan)
 	outerPlan.SetParents(joinPlan)
 	innerPlan.SetParents(joinPlan)
@@ -1032,6 +1328,17 @@ func (b *planBuilder) buildSemiJoin(outerPlan, innerPlan LogicalPlan, onConditio
 }
 
 func (b *planBuilder) buildUpdate(update *ast.UpdateStmt) LogicalPlan {
+	// The rows of the multiple tables are updated in the order they are joined.
+	if isMultiTableRefs(update.TableRefs.TableRefs) {
+		if update.Order != nil {
+			b.err = ErrWrongUsage.Gen("Incorrect usage of UPDATE and ORDER BY")
+			return nil
+		}
+		if update.Limit != nil {
+			b.err = ErrWrongUsage.Gen("Incorrect usage of UPDATE and LIMIT")
+			return nil
+		}
+	}
 	sel := &ast.SelectStmt{Fields: &ast.FieldList{}, From: update.TableRefs, Where: update.Where, OrderBy: update.Order, Limit: update.Limit}
 	p := b.buildResultSetNode(sel.From.TableRefs)
 	if b.err != nil {
@@ -1069,6 +1376,17 @@ func (b *planBuilder) buildUpdate(update *ast.UpdateStmt) LogicalPlan {
 	return updt
 }
 
+// isMultiTableRefs checks whether there are more than one table in the table references.
+func isMultiTableRefs(node ast.ResultSetNode) bool {
+	switch x := node.(type) {
+	case *ast.Join:
+		return x.Right != nil || isMultiTableRefs(x.Left)
+	case *ast.TableSource:
+		return isMultiTableRefs(x.Source)
+	}
+	return false
+}
+
 func (b *planBuilder) buildUpdateLists(list []*ast.Assignment, p LogicalPlan) ([]*expression.Assignment, LogicalPlan) {
 	schema := p.GetSchema()
 	newList := make([]*expression.Assignment, len(schema))
@@ -16,6 +16,7 @@ package plan
 import (
 	"fmt"
 	"sort"
+	"strconv"
 	"strings"
 	"testing"
 
@@ -116,7 +117,8 @@ func mockResolve(node ast.Node) error {
 			State: model.StatePublic,
 		},
 		{
-			Name: model.NewCIStr("f_g"),
+			Name:   model.NewCIStr("f_g"),
+			Unique: true,
 			Columns: []*model.IndexColumn{
 				{
 					Name:   model.NewCIStr("f"),
@@ -461,6 +463,16 @@ func (s *testPlanSuite) TestPredicatePushDown(c *C) {
 		{
 			sql:   "select * from t ta left outer join t tb on ta.d = tb.d and ta.a > 1 where ta.d = 0",
 			first: "Join{DataScan(t)->DataScan(t)}->Selection->Projection",
+			best:  "Join{DataScan(t)->Selection->DataScan(t)->Selection}->Projection",
+		},
+		{
+			sql:   "select * from t ta left outer join t tb on ta.d = tb.d and ta.a > 1 where ta.b = 0",
+			first: "Join{DataScan(t)->DataScan(t)}->Selection->Projection",
+			best:  "Join{DataScan(t)->Selection->DataScan(t)}->Projection",
+		},
+		{
+			sql:   "select * from t ta right outer join t tb on ta.d = tb.d and tb.d > 1",
+			first: "Join{DataScan(t)->DataScan(t)}->Projection",
 			best:  "Join{DataScan(t)->Selection->DataScan(t)}->Projection",
 		},
 		{
@@ -509,10 +521,15 @@ func (s *testPlanSuite) TestPredicatePushDown(c *C) {
 			best:  "UnionAll{DataScan(t)->Selection->Projection->DataScan(t)->Selection->Projection->DataScan(t)->Selection->Projection}->Projection",
 		},
 		{
-			sql:   "select (select count(*) from t where t.a = k.a) from t k",
+			sql:   "select /*+ DISABLE_RULES(decorrelate) */ (select count(*) from t where t.a = k.a) from t k",
 			first: "DataScan(t)->Apply(DataScan(t)->Selection->Aggr(count(1))->Projection->MaxOneRow)->Projection",
 			best:  "DataScan(t)->Apply(DataScan(t)->Selection->Aggr(count(1))->Projection->MaxOneRow)->Projection",
 		},
+		{
+			sql:   "select (select count(*) from t where t.a = k.a) from t k",
+			first: "Join{DataScan(t)->DataScan(t)->Aggr(count(1),firstrow(test.t.a))}->Projection->Projection",
+			best:  "Join{DataScan(t)->DataScan(t)->Aggr(count(1),firstrow(test.t.a))}->Projection->Projection",
+		},
 		{
 			sql:   "select a from t where exists(select 1 from t as x where x.a < t.a)",
 			first: "Join{DataScan(t)->DataScan(t)}->Projection",
@@ -528,6 +545,16 @@ func (s *testPlanSuite) TestPredicatePushDown(c *C) {
 			first: "Join{DataScan(t)->DataScan(t)}->Selection->Projection",
 			best:  "Join{DataScan(t)->Selection->DataScan(t)->Selection}->Projection",
 		},
+		{
+			sql:   "select a from t where 1 in (select x.c from t as x)",
+			first: "Join{DataScan(t)->DataScan(t)->Projection}->Projection",
+			best:  "Join{DataScan(t)->DataScan(t)->Selection->Projection}->Projection",
+		},
+		{
+			sql:   "select a from t where 1 not in (select x.c from t as x)",
+			first: "Join{DataScan(t)->DataScan(t)->Projection}->Projection",
+			best:  "Join{DataScan(t)->DataScan(t)->Projection}->Projection",
+		},
 		{
 			sql:   "select a from t where exists(select 1 from t as x where x.a = t.a) and exists(select 1 from t as x where x.a = t.a)",
 			first: "Join{Join{DataScan(t)->DataScan(t)}->DataScan(t)}->Projection",
@@ -589,6 +616,80 @@ func (s *testPlanSuite) TestPredicatePushDown(c *C) {
 	}
 }
 
+func collectJoinTypes(p Plan, types []JoinType) []JoinType {
+	if join, ok := p.(*Join); ok {
+		types = append(types, join.JoinType)
+	}
+	for _, child := range p.GetChildren() {
+		types = collectJoinTypes(child, types)
+	}
+	return types
+}
+
+func (s *testPlanSuite) TestOuterJoinSimplify(c *C) {
+	defer testleak.AfterTest(c)()
+	cases := []struct {
+		sql       string
+		joinTypes []JoinType
+	}{
+		{
+			sql:       "select * from t ta left join t tb on ta.d = tb.d where tb.c > 0",
+			joinTypes: []JoinType{InnerJoin},
+		},
+		{
+			sql:       "select * from t ta right join t tb on ta.d = tb.d where ta.c > 0",
+			joinTypes: []JoinType{InnerJoin},
+		},
+		{
+			sql:       "select * from t ta left join t tb on ta.d = tb.d where tb.c > 0 or tb.d < 0",
+			joinTypes: []JoinType{InnerJoin},
+		},
+		{
+			sql:       "select * from t ta left join t tb on ta.d = tb.d where (tb.c > 0 and ta.b = 1) or (tb.d < 0 and ta.b = 2)",
+			joinTypes: []JoinType{InnerJoin},
+		},
+		{
+			sql:       "select * from t ta left join t tb on ta.d = tb.d where tb.c > 0 or ta.b = 1",
+			joinTypes: []JoinType{LeftOuterJoin},
+		},
+		{
+			sql:       "select * from t ta left join t tb on ta.d = tb.d where tb.c is null",
+			joinTypes: []JoinType{LeftOuterJoin},
+		},
+		{
+			sql:       "select * from t ta left join t tb on ta.d = tb.d where ta.c > 0",
+			joinTypes: []JoinType{LeftOuterJoin},
+		},
+		// The join condition of the embedding outer join doesn't filter its outer table.
+		{
+			sql:       "select * from (t ta left join t tb on ta.a = tb.a) left join (t tc join t td on tc.b = td.b) on tb.c = tc.c",
+			joinTypes: []JoinType{LeftOuterJoin, LeftOuterJoin, InnerJoin},
+		},
+		{
+			sql:       "select * from t tc join (t ta left join t tb on ta.a = tb.a) on tb.c = tc.c",
+			joinTypes: []JoinType{InnerJoin, InnerJoin},
+		},
+	}
+	for _, ca := range cases {
+		comment := Commentf("for %s", ca.sql)
+		stmt, err := s.ParseOneStmt(ca.sql, "", "")
+		c.Assert(err, IsNil, comment)
+
+		err = mockResolve(stmt)
+		c.Assert(err, IsNil)
+
+		builder := &planBuilder{
+			allocator: new(idAllocator),
+			ctx:       mock.NewContext(),
+		}
+		p := builder.build(stmt)
+		c.Assert(builder.err, IsNil)
+		_, _, err = p.(LogicalPlan).PredicatePushDown(nil)
+		c.Assert(err, IsNil)
+		c.Assert(collectJoinTypes(p, nil), DeepEquals, ca.joinTypes, comment)
+	}
+}
+
 func (s *testPlanSuite) TestJoinReOrder(c *C) {
 	defer testleak.AfterTest(c)()
 	cases := []struct {
@@ -597,27 +698,67 @@ func (s *testPlanSuite) TestJoinReOrder(c *C) {
 	}{
 		{
 			sql:  "select * from t t1, t t2, t t3, t t4, t t5, t t6 where t1.a = t2.b and t2.a = t3.b and t3.c = t4.a and t4.d = t2.c and t5.d = t6.d",
-			best: "LeftHashJoin{LeftHashJoin{LeftHashJoin{LeftHashJoin{Table(t)->Table(t)}(t1.a,t2.b)->Table(t)}(t2.a,t3.b)->Table(t)}(t3.c,t4.a)(t2.c,t4.d)->LeftHashJoin{Table(t)->Table(t)}(t5.d,t6.d)}->Projection",
+			best: "LeftHashJoin{RightHashJoin{Table(t)->LeftHashJoin{LeftHashJoin{Table(t)->Table(t)}(t2.c,t4.d)->Table(t)}(t2.a,t3.b)(t4.a,t3.c)}(t1.a,t2.b)->LeftHashJoin{Table(t)->Table(t)}(t5.d,t6.d)}->Projection",
 		},
 		{
 			sql:  "select * from t t1, t t2, t t3, t t4, t t5, t t6, t t7, t t8 where t1.a = t8.a",
-			best: "LeftHashJoin{LeftHashJoin{LeftHashJoin{LeftHashJoin{Table(t)->Table(t)}(t1.a,t8.a)->Table(t)}->LeftHashJoin{Table(t)->Table(t)}}->LeftHashJoin{LeftHashJoin{Table(t)->Table(t)}->Table(t)}}->Projection",
+			best: "LeftHashJoin{LeftHashJoin{LeftHashJoin{MergeJoin{Table(t)->Table(t)}(t1.a,t8.a)->Table(t)}->Table(t)}->LeftHashJoin{LeftHashJoin{Table(t)->Table(t)}->LeftHashJoin{Table(t)->Table(t)}}}->Projection",
 		},
 		{
 			sql:  "select * from t t1, t t2, t t3, t t4, t t5 where t1.a = t5.a and t5.a = t4.a and t4.a = t3.a and t3.a = t2.a and t2.a = t1.a and t1.a = t3.a and t2.a = t4.a and t5.b < 8",
-			best: "LeftHashJoin{LeftHashJoin{LeftHashJoin{RightHashJoin{Table(t)->Selection->Table(t)}(t5.a,t1.a)->Table(t)}(t1.a,t2.a)->Table(t)}(t2.a,t3.a)(t1.a,t3.a)->Table(t)}(t5.a,t4.a)(t3.a,t4.a)(t2.a,t4.a)->Projection",
+			best: "LeftHashJoin{LeftHashJoin{MergeJoin{MergeJoin{Table(t)->Table(t)->Selection}(t1.a,t5.a)->Table(t)}(t5.a,t4.a)->Table(t)}(t4.a,t3.a)(t1.a,t3.a)->Table(t)}(t3.a,t2.a)(t1.a,t2.a)(t4.a,t2.a)->Projection",
 		},
 		{
 			sql:  "select * from t t1, t t2, t t3, t t4, t t5 where t1.a = t5.a and t5.a = t4.a and t4.a = t3.a and t3.a = t2.a and t2.a = t1.a and t1.a = t3.a and t2.a = t4.a and t3.b = 1 and t4.a = 1",
-			best: "LeftHashJoin{RightHashJoin{RightHashJoin{Table(t)->Selection->Table(t)}->LeftHashJoin{Table(t)->Table(t)}}->Table(t)}->Projection",
+			best: "LeftHashJoin{LeftHashJoin{LeftHashJoin{Table(t)->Table(t)->Selection}->Table(t)}->LeftHashJoin{Table(t)->Table(t)}}->Projection",
+		},
+		{
+			sql:  "select * from t t1, t t2, t t3, t t4 where t1.a = t2.a and t1.b = t3.a and t1.c = t4.a and t4.b = 1 and t3.c > 1",
+			best: "MergeJoin{LeftHashJoin{LeftHashJoin{Table(t)->Table(t)->Selection}(t1.c,t4.a)->Index(t.c_d_e)[(1,+inf]]}(t1.b,t3.a)->Table(t)}(t1.a,t2.a)->Projection",
+		},
+		{
+			sql:  "select * from t t1, t t2, t t3, t t4, t t5, t t6, t t7, t t8, t t9, t t10, t t11, t t12 where t1.a = t2.a and t2.b = t3.a and t3.b = t4.a and t4.b = t5.a and t5.b = t6.a and t6.b = t7.a and t7.b = t8.a and t8.b = t9.a and t9.b = t10.a and t10.b = t11.a and t11.b = t12.a and t12.c = 1",
+			best: "RightHashJoin{Table(t)->RightHashJoin{Table(t)->RightHashJoin{Table(t)->RightHashJoin{Table(t)->RightHashJoin{Table(t)->RightHashJoin{Table(t)->RightHashJoin{Table(t)->RightHashJoin{Table(t)->RightHashJoin{Table(t)->RightHashJoin{Table(t)->LeftHashJoin{Table(t)->Index(t.c_d_e)[[1,1]]}(t11.b,t12.a)}(t10.b,t11.a)}(t9.b,t10.a)}(t8.b,t9.a)}(t7.b,t8.a)}(t6.b,t7.a)}(t5.b,t6.a)}(t4.b,t5.a)}(t3.b,t4.a)}(t2.b,t3.a)}(t1.a,t2.a)->Projection",
+		},
+		{
+			sql:  "select /*+ LEADING(t3, t1) */ * from t t1, t t2, t t3 where t1.a = t2.a and t2.b = t3.b",
+			best: "LeftHashJoin{LeftHashJoin{Table(t)->Table(t)}->Table(t)}(t1.a,t2.a)(t3.b,t2.b)->Projection",
+		},
+		{
+			sql:  "select /*+ LEADING(t3, t9) */ * from t t1, t t2, t t3 where t1.a = t2.a and t2.b = t3.b",
+			best: "LeftHashJoin{MergeJoin{Table(t)->Table(t)}(t1.a,t2.a)->Table(t)}(t2.b,t3.b)->Projection",
+		},
+		{
+			sql:  "select straight_join * from t t1, t t2, t t3 where t1.a = t2.a and t2.b = t3.b and t3.c = 1",
+			best: "LeftHashJoin{MergeJoin{Table(t)->Table(t)}(t1.a,t2.a)->Index(t.c_d_e)[[1,1]]}(t2.b,t3.b)->Projection",
+		},
+		{
+			sql:  "select /*+ STRAIGHT_JOIN() */ * from t t1, t t2, t t3 where t1.a = t2.a and t2.b = t3.b and t3.c = 1",
+			best: "LeftHashJoin{MergeJoin{Table(t)->Table(t)}(t1.a,t2.a)->Index(t.c_d_e)[[1,1]]}(t2.b,t3.b)->Projection",
+		},
+		{
+			sql:  "select * from t t1 straight_join t t2 straight_join t t3 where t1.a = t2.a and t2.b = t3.b and t3.c = 1",
+			best: "LeftHashJoin{MergeJoin{Table(t)->Table(t)}(t1.a,t2.a)->Index(t.c_d_e)[[1,1]]}(t2.b,t3.b)->Projection",
+		},
+		{
+			sql:  "select * from t t1, t t2, t t3 where t1.a = t2.a and t2.b = t3.b and t3.c = 1",
+			best: "MergeJoin{Table(t)->LeftHashJoin{Table(t)->Index(t.c_d_e)[[1,1]]}(t2.b,t3.b)}(t1.a,t2.a)->Projection",
+		},
+		{
+			sql:  "select /*+ DISABLE_RULES(decorrelate) */ * from t o where o.b in (select t3.c from t t1, t t2, t t3 where t1.a = t3.a and t2.a = t3.a and t2.a = o.a)",
+			best: "Table(t)->Apply(MergeJoin{Table(t)->Cache->MergeJoin{Table(t)->Cache->Selection->Table(t)->Cache}(t2.a,t3.a)}(t1.a,t3.a)->Projection)->Selection->Projection",
+		},
+		{
+			sql:  "select /*+ DISABLE_RULES(decorrelate) */ * from t o where o.b in (select t3.c from t t1, t t2, t t3 where t1.a = t3.a and t2.a = t3.a and t2.a = o.a and t1.a = 1)",
+			best: "Table(t)->Apply(LeftHashJoin{LeftHashJoin{Table(t)->Table(t)}->Cache->Table(t)->Cache->Selection}->Projection)->Selection->Projection",
 		},
 		{
 			sql:  "select * from t o where o.b in (select t3.c from t t1, t t2, t t3 where t1.a = t3.a and t2.a = t3.a and t2.a = o.a)",
-			best: "Table(t)->Apply(LeftHashJoin{RightHashJoin{Table(t)->Cache->Selection->Table(t)->Cache}(t2.a,t3.a)->Table(t)->Cache}(t3.a,t1.a)->Projection)->Selection->Projection",
+			best: "SemiJoin{Table(t)->MergeJoin{MergeJoin{Table(t)->Table(t)}(t1.a,t3.a)->Table(t)}(t3.a,t2.a)->Projection}->Projection",
 		},
 		{
 			sql:  "select * from t o where o.b in (select t3.c from t t1, t t2, t t3 where t1.a = t3.a and t2.a = t3.a and t2.a = o.a and t1.a = 1)",
-			best: "Table(t)->Apply(LeftHashJoin{LeftHashJoin{Table(t)->Table(t)}->Cache->Table(t)->Cache->Selection}->Projection)->Selection->Projection",
+			best: "SemiJoin{Table(t)->LeftHashJoin{LeftHashJoin{Table(t)->Table(t)}->Table(t)}->Projection}->Projection",
 		},
 	}
 	for _, ca := range cases {
@@ -706,6 +847,22 @@ func (s *testPlanSuite) TestLogicalPlan(c *C) {
 			sql:  "select sum(a.a) from t a right join t b on a.c = b.c",
 			best: "Join{DataScan(t)->Aggr(sum(a.a),firstrow(a.c))->DataScan(t)}->Aggr(sum(join_agg_0))->Projection",
 		},
+		{
+			sql:  "select avg(b.d), max(a.d) from t a left join t b on a.c = b.c",
+			best: "Join{DataScan(t)->DataScan(t)->Aggr(count(b.d),sum(b.d),firstrow(b.c))}->Aggr(avg(join_agg_0, join_agg_1),max(a.d))->Projection",
+		},
+		{
+			sql:  "select avg(a.d), b.e from t a, t b where a.c = b.c group by b.e",
+			best: "Join{DataScan(t)->Aggr(count(a.d),sum(a.d),firstrow(a.c))->DataScan(t)}->Aggr(avg(join_agg_0, join_agg_1),firstrow(b.e))->Projection",
+		},
+		{
+			sql:  "select sum(b.d) from t a, t b where a.c = b.a",
+			best: "Join{DataScan(t)->DataScan(t)}->Aggr(sum(b.d))->Projection",
+		},
+		{
+			sql:  "select sum(b.d) from t a, t b where a.c = b.a and a.d = b.d group by b.e",
+			best: "Join{DataScan(t)->DataScan(t)}->Aggr(sum(b.d))->Projection",
+		},
 	}
 	for _, ca := range cases {
 		comment := Commentf("for %s", ca.sql)
@@ -945,6 +1102,72 @@ func (s *testPlanSuite) TestRefine(c *C) {
 	}
 }
 
+func (s *testPlanSuite) TestFuseRanges(c *C) {
+	defer testleak.AfterTest(c)()
+	// inList returns the IN list of the integers in [low, high] and the extra ones.
+	inList := func(low, high int, extra ...int) string {
+		values := make([]string, 0, high-low+1+len(extra))
+		for i := low; i <= high; i++ {
+			values = append(values, strconv.Itoa(i))
+		}
+		for _, v := range extra {
+			values = append(values, strconv.Itoa(v))
+		}
+		return "(" + strings.Join(values, ", ") + ")"
+	}
+	cases := []struct {
+		sql  string
+		best string
+	}{
+		{
+			sql:  "select a from t where a in " + inList(1, 70, 100, 5, -1),
+			best: "Table(t)->Projection",
+		},
+		{
+			sql:  "select a from t where c in " + inList(1, 70, 100, 5, -1),
+			best: "Index(t.c_d_e)[[-1,-1] [1,70] [100,100]]->Projection",
+		},
+		{
+			sql:  "select a from t where c = 1 and d in " + inList(11, 80),
+			best: "Index(t.c_d_e)[[1 11,1 80]]->Projection",
+		},
+		{
+			sql:  "select a from t where c in (1, 2) and d in " + inList(11, 80),
+			best: "Index(t.c_d_e)[[1 11,1 80] [2 11,2 80]]->Projection",
+		},
+		{
+			sql:  "select a from t where c in (1, 2, 3) and d in (4, 5)",
+			best: "Index(t.c_d_e)[[1 4,1 4] [1 5,1 5] [2 4,2 4] [2 5,2 5] [3 4,3 4] [3 5,3 5]]->Projection",
+		},
+	}
+	for _, ca := range cases {
+		comment := Commentf("for %s", ca.sql)
+		stmt, err := s.ParseOneStmt(ca.sql, "", "")
+		c.Assert(err, IsNil, comment)
+
+		err = mockResolve(stmt)
+		c.Assert(err, IsNil)
+
+		builder := &planBuilder{
+			allocator: new(idAllocator),
+			ctx:       mock.NewContext(),
+		}
+		p := builder.build(stmt).(LogicalPlan)
+		c.Assert(builder.err, IsNil)
+
+		_, p, err = p.PredicatePushDown(nil)
+		c.Assert(err, IsNil)
+		p.PruneColumns(p.GetSchema())
+		p.ResolveIndicesAndCorCols()
+		info, err := p.convert2PhysicalPlan(&requiredProperty{})
+		c.Assert(err, IsNil)
+		c.Assert(ToString(info.p), Equals, ca.best, comment)
+		if ts, ok := info.p.GetChildren()[0].(*PhysicalTableScan); ok {
+			c.Assert(fmt.Sprintf("%v", ts.Ranges), Equals, "[{-1 -1} {1 70} {100 100}]", comment)
+		}
+	}
+}
+
 func (s *testPlanSuite) TestColumnPruning(c *C) {
 	defer testleak.AfterTest(c)()
 	cases := []struct {
@@ -1079,106 +1302,368 @@ func (s *testPlanSuite) TestColumnPruning(c *C) {
 	}
 }
 
-func (s *testPlanSuite) TestAllocID(c *C) {
-	pA := &DataSource{baseLogicalPlan: newBaseLogicalPlan(Ts, new(idAllocator))}
-
-	pB := &DataSource{baseLogicalPlan: newBaseLogicalPlan(Ts, new(idAllocator))}
-
-	pA.initID()
-	pB.initID()
-	c.Assert(pA.id, Equals, pB.id)
-}
-
-func (s *testPlanSuite) TestRangeBuilder(c *C) {
+func (s *testPlanSuite) TestMergeProjection(c *C) {
 	defer testleak.AfterTest(c)()
-	rb := &rangeBuilder{}
-
 	cases := []struct {
-		exprStr   string
-		resultStr string
+		sql  string
+		best string
+		ans  map[string][]string
 	}{
 		{
-			exprStr:   "a = 1",
-			resultStr: "[[1 1]]",
+			sql:  "select a + 1 from (select b + c as a from t) t1",
+			best: "Table(t)->Projection",
+			ans: map[string][]string{
+				"TableScan_4": {"b", "c"},
+			},
 		},
 		{
-			exprStr:   "1 = a",
-			resultStr: "[[1 1]]",
+			sql:  "select x, x + 1 from (select b as x from t) t1",
+			best: "Table(t)->Projection",
+			ans: map[string][]string{
+				"TableScan_4": {"b"},
+			},
 		},
+		// The function would be evaluated twice for a row if they were merged.
 		{
-			exprStr:   "a != 1",
-			resultStr: "[[-inf 1) (1 +inf]]",
+			sql:  "select x + 1, x + 2 from (select b + c as x from t) t1",
+			best: "Table(t)->Projection->Projection",
+			ans: map[string][]string{
+				"TableScan_4": {"b", "c"},
+			},
 		},
 		{
-			exprStr:   "1 != a",
-			resultStr: "[[-inf 1) (1 +inf]]",
+			sql:  "select /*+ DISABLE_RULES(eliminate_projection) */ a + 1 from (select b + c as a from t) t1",
+			best: "Table(t)->Projection->Projection",
+			ans: map[string][]string{
+				"TableScan_4": {"b", "c"},
+			},
 		},
+		// The inner plan of the apply only reads the columns used by the conditions.
 		{
-			exprStr:   "a > 1",
-			resultStr: "[(1 +inf]]",
+			sql:  "select a from (select a, (select s.b from t s where s.c = t.c limit 1) x from t) t1",
+			best: "Table(t)->Apply(Table(t)->Cache->Selection->Limit->Projection)->Projection",
+			ans: map[string][]string{
+				"TableScan_10": {"c"},
+				"TableScan_13": {"a", "c"},
+			},
 		},
 		{
-			exprStr:   "1 < a",
-			resultStr: "[(1 +inf]]",
+			sql:  "select t1.a from t t1, t t2 where t1.b = all (select s.b from t s where s.c = t2.c)",
+			best: "LeftHashJoin{Table(t)->Table(t)}->Apply(Table(t)->Cache->Selection->Projection)->Selection->Projection",
+			ans: map[string][]string{
+				"TableScan_11": {"b", "c"},
+				"TableScan_14": {"a", "b"},
+				"TableScan_15": {"c"},
+			},
 		},
+	}
+	for _, ca := range cases {
+		comment := Commentf("for %s", ca.sql)
+		stmt, err := s.ParseOneStmt(ca.sql, "", "")
+		c.Assert(err, IsNil, comment)
+
+		err = mockResolve(stmt)
+		c.Assert(err, IsNil)
+
+		builder := &planBuilder{
+			allocator: new(idAllocator),
+			ctx:       mock.NewContext(),
+			colMapper: make(map[*ast.ColumnNameExpr]int),
+		}
+		p := builder.build(stmt)
+		c.Assert(builder.err, IsNil, comment)
+		pp, err := doOptimize(p.(LogicalPlan), builder.ctx, builder.allocator, builder.disabledRules)
+		c.Assert(err, IsNil, comment)
+		c.Assert(ToString(pp), Equals, ca.best, comment)
+		checkDataSourceCols(pp, c, ca.ans, comment)
+	}
+}
+
+func (s *testPlanSuite) TestEliminateMaxOneRow(c *C) {
+	defer testleak.AfterTest(c)()
+	cases := []struct {
+		sql  string
+		best string
+	}{
 		{
-			exprStr:   "a >= 1",
-			resultStr: "[[1 +inf]]",
+			sql:  "select a, (select s.b from t s where s.a = t.c) from t",
+			best: "Table(t)->Apply(Table(t)->Cache->Selection->Projection)->Projection",
 		},
 		{
-			exprStr:   "1 <= a",
-			resultStr: "[[1 +inf]]",
+			sql:  "select a, (select s.b from t s where s.f = t.f and s.g = t.g) from t",
+			best: "Table(t)->Apply(Table(t)->Cache->Selection->Projection)->Projection",
 		},
 		{
-			exprStr:   "a < 1",
-			resultStr: "[[-inf 1)]",
+			sql:  "select a from t where b = (select s.b from t s where s.a = t.c and s.b > 1 order by s.b)",
+			best: "Table(t)->Apply(Table(t)->Cache->Selection->Projection->Sort)->Selection->Projection",
 		},
 		{
-			exprStr:   "1 > a",
-			resultStr: "[[-inf 1)]",
+			sql:  "select a, (select s.b from t s where s.c = t.c limit 1) from t",
+			best: "Table(t)->Apply(Table(t)->Cache->Selection->Limit->Projection)->Projection",
 		},
+		// Only a part of the unique index is matched.
 		{
-			exprStr:   "a <= 1",
-			resultStr: "[[-inf 1]]",
+			sql:  "select a, (select s.b from t s where s.f = t.f) from t",
+			best: "Table(t)->Apply(Table(t)->Cache->Selection->Projection->MaxOneRow)->Projection",
 		},
+		// The index isn't unique.
 		{
-			exprStr:   "1 >= a",
-			resultStr: "[[-inf 1]]",
+			sql:  "select a, (select s.b from t s where s.c = t.c and s.d = t.d and s.e = t.e) from t",
+			best: "Table(t)->Apply(Table(t)->Cache->Selection->Projection->MaxOneRow)->Projection",
 		},
+		// The integer key is compared as a decimal.
 		{
-			exprStr:   "(a)",
-			resultStr: "[[-inf 0) (0 +inf]]",
+			sql:  "select a, (select s.b from t s where s.a = t.c + 1.5) from t",
+			best: "Table(t)->Apply(Table(t)->Cache->Selection->Projection->MaxOneRow)->Projection",
 		},
 		{
-			exprStr:   "a in (1, 3, NULL, 2)",
-			resultStr: "[[<nil> <nil>] [1 1] [2 2] [3 3]]",
+			sql:  "select a, (select s.b from t s where s.a = t.c + connection_id()) from t",
+			best: "Table(t)->Apply(Table(t)->Cache->Selection->Projection->MaxOneRow)->Projection",
 		},
 		{
-			exprStr:   `a IN (8,8,81,45)`,
-			resultStr: `[[8 8] [45 45] [81 81]]`,
+			sql:  "select a, (select s.b from t s where s.a > t.c) from t",
+			best: "Table(t)->Apply(Table(t)->Cache->Selection->Projection->MaxOneRow)->Projection",
 		},
 		{
-			exprStr:   "a between 1 and 2",
-			resultStr: "[[1 2]]",
+			sql:  "select /*+ DISABLE_RULES(eliminate_max_one_row) */ a, (select s.b from t s where s.a = t.c) from t",
+			best: "Table(t)->Apply(Table(t)->Cache->Selection->Projection->MaxOneRow)->Projection",
 		},
+	}
+	for _, ca := range cases {
+		comment := Commentf("for %s", ca.sql)
+		stmt, err := s.ParseOneStmt(ca.sql, "", "")
+		c.Assert(err, IsNil, comment)
+
+		err = mockResolve(stmt)
+		c.Assert(err, IsNil)
+
+		builder := &planBuilder{
+			allocator: new(idAllocator),
+			ctx:       mock.NewContext(),
+			colMapper: make(map[*ast.ColumnNameExpr]int),
+		}
+		p := builder.build(stmt)
+		c.Assert(builder.err, IsNil, comment)
+		pp, err := doOptimize(p.(LogicalPlan), builder.ctx, builder.allocator, builder.disabledRules)
+		c.Assert(err, IsNil, comment)
+		c.Assert(ToString(pp), Equals, ca.best, comment)
+	}
+}
+
+func (s *testPlanSuite) TestEliminateAggregation(c *C) {
+	defer testleak.AfterTest(c)()
+	cases := []struct {
+		sql  string
+		best string
+	}{
 		{
-			exprStr:   "a not between 1 and 2",
-			resultStr: "[[-inf 1) (2 +inf]]",
+			sql:  "select distinct a, b from t",
+			best: "Table(t)",
 		},
 		{
-			exprStr:   "a not between null and 0",
-			resultStr: "[(0 +inf]]",
+			sql:  "select a, max(b), min(c), count(1) from t where b > 1 group by a",
+			best: "Table(t)->Selection->Projection",
 		},
 		{
-			exprStr:   "a between 2 and 1",
-			resultStr: "[]",
+			sql:  "select b from t group by b, a having count(*) > 1",
+			best: "Table(t)->Projection->Selection->Projection->Trim",
 		},
 		{
-			exprStr:   "a not between 2 and 1",
-			resultStr: "[[-inf +inf]]",
+			sql:  "select distinct x from (select a as x, b from t) s",
+			best: "Table(t)",
 		},
+		// The group-by columns aren't a unique key.
 		{
-			exprStr:   "a IS NULL",
+			sql:  "select distinct b from t",
+			best: "Table(t)->Distinct",
+		},
+		// The columns of the unique index f_g are nullable.
+		{
+			sql:  "select distinct f, g from t",
+			best: "Index(t.f_g)[[<nil>,+inf]]->StreamDistinct",
+		},
+		// The sum of a single row is a decimal.
+		{
+			sql:  "select a, sum(b) from t group by a",
+			best: "Table(t)->StreamAgg",
+		},
+		// The count of a NULL is 0.
+		{
+			sql:  "select a, count(b) from t group by a",
+			best: "Table(t)->StreamAgg",
+		},
+		{
+			sql:  "select /*+ DISABLE_RULES(eliminate_agg) */ distinct a, b from t",
+			best: "Table(t)->Distinct",
+		},
+	}
+	for _, ca := range cases {
+		comment := Commentf("for %s", ca.sql)
+		stmt, err := s.ParseOneStmt(ca.sql, "", "")
+		c.Assert(err, IsNil, comment)
+
+		err = mockResolve(stmt)
+		c.Assert(err, IsNil)
+
+		builder := &planBuilder{
+			allocator: new(idAllocator),
+			ctx:       mock.NewContext(),
+			colMapper: make(map[*ast.ColumnNameExpr]int),
+		}
+		p := builder.build(stmt)
+		c.Assert(builder.err, IsNil, comment)
+		pp, err := doOptimize(p.(LogicalPlan), builder.ctx, builder.allocator, builder.disabledRules)
+		c.Assert(err, IsNil, comment)
+		c.Assert(ToString(pp), Equals, ca.best, comment)
+	}
+}
+
+func (s *testPlanSuite) TestCommonTableExpr(c *C) {
+	defer testleak.AfterTest(c)()
+	cases := []struct {
+		sql  string
+		best string
+	}{
+		// The CTE referred to once is merged, so the predicate is pushed down to the table.
+		{
+			sql:  "with c as (select a, b from t) select b from c where a = 1",
+			best: "Table(t)->Projection",
+		},
+		{
+			sql:  "with c (x) as (select a from t) select c.x from c, t where c.x = t.a",
+			best: "MergeJoin{Table(t)->Table(t)}(c.x,test.t.a)->Projection",
+		},
+		// The CTE referred to more than once is materialized.
+		{
+			sql:  "with c as (select a, b from t where c > 1) select x.b from c as x, c as y where x.a = y.b",
+			best: "LeftHashJoin{CTE(c)->CTE(c)}(x.a,y.b)->Projection",
+		},
+		{
+			sql:  "with c as (select a from t) select a from c where a in (select a + 1 from c)",
+			best: "SemiJoin{CTE(c)->CTE(c)->Projection}",
+		},
+	}
+	for _, ca := range cases {
+		comment := Commentf("for %s", ca.sql)
+		stmt, err := s.ParseOneStmt(ca.sql, "", "")
+		c.Assert(err, IsNil, comment)
+
+		err = mockResolve(stmt)
+		c.Assert(err, IsNil, comment)
+
+		builder := &planBuilder{
+			allocator: new(idAllocator),
+			ctx:       mock.NewContext(),
+			colMapper: make(map[*ast.ColumnNameExpr]int),
+		}
+		p := builder.build(stmt)
+		c.Assert(builder.err, IsNil, comment)
+		pp, err := doOptimize(p.(LogicalPlan), builder.ctx, builder.allocator, builder.disabledRules)
+		c.Assert(err, IsNil, comment)
+		c.Assert(ToString(pp), Equals, ca.best, comment)
+	}
+}
+
+func (s *testPlanSuite) TestAllocID(c *C) {
+	pA := &DataSource{baseLogicalPlan: newBaseLogicalPlan(Ts, new(idAllocator))}
+
+	pB := &DataSource{baseLogicalPlan: newBaseLogicalPlan(Ts, new(idAllocator))}
+
+	pA.initID()
+	pB.initID()
+	c.Assert(pA.id, Equals, pB.id)
+}
+
+func (s *testPlanSuite) TestRangeBuilder(c *C) {
+	defer testleak.AfterTest(c)()
+	rb := &rangeBuilder{}
+
+	cases := []struct {
+		exprStr   string
+		resultStr string
+	}{
+		{
+			exprStr:   "a = 1",
+			resultStr: "[[1 1]]",
+		},
+		{
+			exprStr:   "1 = a",
+			resultStr: "[[1 1]]",
+		},
+		{
+			exprStr:   "a != 1",
+			resultStr: "[[-inf 1) (1 +inf]]",
+		},
+		{
+			exprStr:   "1 != a",
+			resultStr: "[[-inf 1) (1 +inf]]",
+		},
+		{
+			exprStr:   "a > 1",
+			resultStr: "[(1 +inf]]",
+		},
+		{
+			exprStr:   "1 < a",
+			resultStr: "[(1 +inf]]",
+		},
+		{
+			exprStr:   "a >= 1",
+			resultStr: "[[1 +inf]]",
+		},
+		{
+			exprStr:   "1 <= a",
+			resultStr: "[[1 +inf]]",
+		},
+		{
+			exprStr:   "a < 1",
+			resultStr: "[[-inf 1)]",
+		},
+		{
+			exprStr:   "1 > a",
+			resultStr: "[[-inf 1)]",
+		},
+		{
+			exprStr:   "a <= 1",
+			resultStr: "[[-inf 1]]",
+		},
+		{
+			exprStr:   "1 >= a",
+			resultStr: "[[-inf 1]]",
+		},
+		{
+			exprStr:   "(a)",
+			resultStr: "[[-inf 0) (0 +inf]]",
+		},
+		{
+			exprStr:   "a in (1, 3, NULL, 2)",
+			resultStr: "[[1 1] [2 2] [3 3]]",
+		},
+		{
+			exprStr:   `a IN (8,8,81,45)`,
+			resultStr: `[[8 8] [45 45] [81 81]]`,
+		},
+		{
+			exprStr:   "a between 1 and 2",
+			resultStr: "[[1 2]]",
+		},
+		{
+			exprStr:   "a not between 1 and 2",
+			resultStr: "[[-inf 1) (2 +inf]]",
+		},
+		{
+			exprStr:   "a not between null and 0",
+			resultStr: "[(0 +inf]]",
+		},
+		{
+			exprStr:   "a between 2 and 1",
+			resultStr: "[]",
+		},
+		{
+			exprStr:   "a not between 2 and 1",
+			resultStr: "[[-inf +inf]]",
+		},
+		{
+			exprStr:   "a IS NULL",
 			resultStr: "[[<nil> <nil>]]",
 		},
 		{
@@ -1241,6 +1726,30 @@ func (s *testPlanSuite) TestRangeBuilder(c *C) {
 			exprStr:   `a > NULL`,
 			resultStr: `[]`,
 		},
+		{
+			exprStr:   "a <=> 1",
+			resultStr: "[[1 1]]",
+		},
+		{
+			exprStr:   "1 <=> a",
+			resultStr: "[[1 1]]",
+		},
+		{
+			exprStr:   "a <=> NULL",
+			resultStr: "[[<nil> <nil>]]",
+		},
+		{
+			exprStr:   "not (a <=> 1)",
+			resultStr: "[[<nil> <nil>] [-inf 1) (1 +inf]]",
+		},
+		{
+			exprStr:   "not (a <=> NULL)",
+			resultStr: "[[-inf +inf]]",
+		},
+		{
+			exprStr:   "a <=> 1 or a <=> NULL or a IS FALSE",
+			resultStr: "[[<nil> <nil>] [0 0] [1 1]]",
+		},
 	}
 
 	for _, ca := range cases {
@@ -1345,13 +1854,16 @@ func (s *testPlanSuite) TestConstantFolding(c *C) {
 }
 
 func checkDataSourceCols(p Plan, c *C, ans map[string][]string, comment CommentInterface) {
-	switch p.(type) {
+	switch x := p.(type) {
 	case *PhysicalTableScan:
 		colList, ok := ans[p.GetID()]
 		c.Assert(ok, IsTrue, comment)
+		c.Assert(p.GetSchema(), HasLen, len(colList), comment)
 		for i, colName := range colList {
 			c.Assert(colName, Equals, p.GetSchema()[i].ColName.L, comment)
 		}
+	case *PhysicalApply:
+		checkDataSourceCols(x.InnerPlan, c, ans, comment)
 	}
 	for _, child := range p.GetChildren() {
 		checkDataSourceCols(child, c, ans, comment)
@@ -1364,31 +1876,268 @@ func (s *testPlanSuite) TestConstantPropagation(c *C) {
 		sql   string
 		after string
 	}{
-		// FIXME: There is some bug in constant propagation when we no longer reuse the columns' pointers.
 		{
 			sql:   "a = b and b = c and c = d and d = 1",
 			after: "eq(test.t.a, 1), eq(test.t.b, 1), eq(test.t.c, 1), eq(test.t.d, 1)",
 		},
-		//{
-		//	sql:   "a = b and b = 1 and a = null and c = d and c > 2 and c != 4 and d != 5",
-		//	after: "<nil>, eq(test.t.a, 1), eq(test.t.b, 1), eq(test.t.c, test.t.d), gt(test.t.c, 2), gt(test.t.d, 2), ne(test.t.c, 4), ne(test.t.c, 5), ne(test.t.d, 4), ne(test.t.d, 5)",
-		//},
-		//{
-		//	sql:   "a = b and b > 0 and a = c",
-		//	after: "eq(test.t.a, test.t.b), eq(test.t.a, test.t.c), gt(test.t.a, 0), gt(test.t.b, 0), gt(test.t.c, 0)",
-		//},
+		{
+			sql:   "a = b and b = 1 and a = null and c = d and c > 2 and c != 4 and d != 5",
+			after: "<nil>",
+		},
+		{
+			sql:   "a = b and b = 1 and c = d and c > 2 and c != 4 and d != 5",
+			after: "eq(test.t.a, 1), eq(test.t.b, 1), eq(test.t.c, test.t.d), gt(test.t.c, 2), gt(test.t.d, 2), ne(test.t.c, 4), ne(test.t.c, 5), ne(test.t.d, 4), ne(test.t.d, 5)",
+		},
+		{
+			sql:   "a = b and b > 0 and a = c",
+			after: "eq(test.t.a, test.t.b), eq(test.t.a, test.t.c), gt(test.t.a, 0), gt(test.t.b, 0), gt(test.t.c, 0)",
+		},
 		{
 			sql:   "a = b and b = c and c LIKE 'abc%'",
 			after: "eq(test.t.a, test.t.b), eq(test.t.b, test.t.c), like(cast(test.t.c), abc%, 92)",
 		},
-		//{
-		//	sql:   "a = b and a > 2 and b > 3 and a < 1 and b < 2",
-		//	after: "eq(test.t.a, test.t.b), gt(test.t.a, 2), gt(test.t.a, 3), gt(test.t.b, 2), gt(test.t.b, 3), lt(test.t.a, 1), lt(test.t.a, 2), lt(test.t.b, 1), lt(test.t.b, 2)",
-		//},
+		{
+			sql:   "a = b and a > 2 and b > 3 and a < 1 and b < 2",
+			after: "eq(test.t.a, test.t.b), gt(test.t.a, 2), gt(test.t.a, 3), gt(test.t.b, 2), gt(test.t.b, 3), lt(test.t.a, 1), lt(test.t.a, 2), lt(test.t.b, 1), lt(test.t.b, 2)",
+		},
 		{
 			sql:   "a = null and cast(null as SIGNED) is null",
 			after: "eq(test.t.a, <nil>), isnull(cast(<nil>))",
 		},
+		{
+			sql:   "a <=> b and b <=> 1 and c <=> null",
+			after: "nulleq(test.t.a, 1), nulleq(test.t.b, 1), nulleq(test.t.c, <nil>)",
+		},
+		{
+			sql:   "a = b and a = c and b > 0 and c > 0",
+			after: "eq(test.t.a, test.t.b), eq(test.t.a, test.t.c), gt(test.t.a, 0), gt(test.t.b, 0), gt(test.t.c, 0)",
+		},
+		{
+			sql:   "a = b and b <=> c and c is true",
+			after: "eq(test.t.a, test.t.b), istrue(test.t.a), istrue(test.t.b), istrue(test.t.c), nulleq(test.t.b, test.t.c)",
+		},
+	}
+	for _, ca := range cases {
+		sql := "select * from t where " + ca.sql
+		comment := Commentf("for %s", sql)
+		stmt, err := s.ParseOneStmt(sql, "", "")
+		c.Assert(err, IsNil, comment)
+		err = mockResolve(stmt)
+		c.Assert(err, IsNil)
+		builder := &planBuilder{
+			allocator: new(idAllocator),
+			ctx:       mock.NewContext(),
+			colMapper: make(map[*ast.ColumnNameExpr]int),
+		}
+		p := builder.build(stmt)
+		c.Assert(builder.err, IsNil)
+		lp := p.(LogicalPlan)
+		_, lp, err = lp.PredicatePushDown(nil)
+		c.Assert(err, IsNil)
+		var (
+			sel    *Selection
+			ok     bool
+			result []string
+		)
+		v := lp
+		for {
+			if sel, ok = v.(*Selection); ok {
+				break
+			}
+			v = v.GetChildByIndex(0).(LogicalPlan)
+		}
+		for _, v := range sel.Conditions {
+			result = append(result, v.String())
+		}
+		sort.Strings(result)
+		c.Assert(strings.Join(result, ", "), Equals, ca.after, Commentf("for %s", ca.sql))
+	}
+}
+
+func (s *testPlanSuite) TestJoinConstantPropagation(c *C) {
+	defer testleak.AfterTest(c)()
+	cases := []struct {
+		sql   string
+		inner string
+	}{
+		{
+			sql:   "select * from t t1 left join t t2 on t1.a = t2.a where t1.a = 5",
+			inner: "eq(5, t2.a)",
+		},
+		{
+			sql:   "select * from t t1 left join t t2 on t1.a = t2.a and t1.b = t2.b and t1.b > 1 where t1.a = 5",
+			inner: "eq(5, t2.a), gt(t2.b, 1)",
+		},
+		{
+			sql:   "select * from t t1 right join t t2 on t1.c = t2.c where t2.c > 1 and t2.d = 3",
+			inner: "gt(t1.c, 1)",
+		},
+		{
+			sql:   "select * from t t1 left join t t2 on t1.a = t2.a and t2.b = 1 where t1.a = 5 or t1.b = 2",
+			inner: "eq(t2.b, 1)",
+		},
+	}
+	for _, ca := range cases {
+		comment := Commentf("for %s", ca.sql)
+		stmt, err := s.ParseOneStmt(ca.sql, "", "")
+		c.Assert(err, IsNil, comment)
+		err = mockResolve(stmt)
+		c.Assert(err, IsNil)
+		builder := &planBuilder{
+			allocator: new(idAllocator),
+			ctx:       mock.NewContext(),
+			colMapper: make(map[*ast.ColumnNameExpr]int),
+		}
+		p := builder.build(stmt)
+		c.Assert(builder.err, IsNil)
+		lp := p.(LogicalPlan)
+		_, lp, err = lp.PredicatePushDown(nil)
+		c.Assert(err, IsNil)
+		v := lp
+		for {
+			if _, ok := v.(*Join); ok {
+				break
+			}
+			v = v.GetChildByIndex(0).(LogicalPlan)
+		}
+		join := v.(*Join)
+		inner := join.GetChildByIndex(1)
+		if join.JoinType == RightOuterJoin {
+			inner = join.GetChildByIndex(0)
+		}
+		sel, ok := inner.(*Selection)
+		c.Assert(ok, IsTrue, comment)
+		var result []string
+		for _, cond := range sel.Conditions {
+			result = append(result, cond.String())
+		}
+		sort.Strings(result)
+		c.Assert(strings.Join(result, ", "), Equals, ca.inner, comment)
+	}
+}
+
+func (s *testPlanSuite) TestSimplifyBoolean(c *C) {
+	defer testleak.AfterTest(c)()
+	cases := []struct {
+		sql   string
+		after string
+	}{
+		{
+			sql:   "a > 1 and 1",
+			after: "gt(test.t.a, 1)",
+		},
+		{
+			sql:   "(a > 1 or 0) and (b < 2 or 1 = 0)",
+			after: "gt(test.t.a, 1), lt(test.t.b, 2)",
+		},
+		{
+			sql:   "(a > 1 or 1) and b < 2",
+			after: "lt(test.t.b, 2)",
+		},
+		{
+			sql:   "a > 1 and (b < 2 and 0)",
+			after: "0",
+		},
+		{
+			sql:   "not(not(a > 1))",
+			after: "gt(test.t.a, 1)",
+		},
+		{
+			sql:   "not(a > 1 and b < 2)",
+			after: "or(le(test.t.a, 1), ge(test.t.b, 2))",
+		},
+		{
+			sql:   "not(a > 1 or not(b < 2))",
+			after: "le(test.t.a, 1), lt(test.t.b, 2)",
+		},
+		{
+			sql:   "a > 1 and null",
+			after: "<nil>",
+		},
+		{
+			sql:   "a > 1 or null",
+			after: "or(gt(test.t.a, 1), <nil>)",
+		},
+		{
+			sql:   "a > 1 and b < 2 and not(a <= 1) and b < 2",
+			after: "gt(test.t.a, 1), lt(test.t.b, 2)",
+		},
+		{
+			sql:   "rand() < 0.5 and rand() < 0.5",
+			after: "lt(rand(), 0.5), lt(rand(), 0.5)",
+		},
+	}
+	for _, ca := range cases {
+		sql := "select * from t where " + ca.sql
+		comment := Commentf("for %s", sql)
+		stmt, err := s.ParseOneStmt(sql, "", "")
+		c.Assert(err, IsNil, comment)
+		err = mockResolve(stmt)
+		c.Assert(err, IsNil)
+		builder := &planBuilder{
+			allocator: new(idAllocator),
+			ctx:       mock.NewContext(),
+			colMapper: make(map[*ast.ColumnNameExpr]int),
+		}
+		p := builder.build(stmt)
+		c.Assert(builder.err, IsNil)
+		lp := p.(LogicalPlan)
+		_, lp, err = lp.PredicatePushDown(nil)
+		c.Assert(err, IsNil)
+		var (
+			sel    *Selection
+			ok     bool
+			result []string
+		)
+		v := lp
+		for {
+			if sel, ok = v.(*Selection); ok {
+				break
+			}
+			v = v.GetChildByIndex(0).(LogicalPlan)
+		}
+		for _, v := range sel.Conditions {
+			result = append(result, v.String())
+		}
+		sort.Strings(result)
+		c.Assert(strings.Join(result, ", "), Equals, ca.after, Commentf("for %s", ca.sql))
+	}
+}
+
+func (s *testPlanSuite) TestFoldNullPredicates(c *C) {
+	defer testleak.AfterTest(c)()
+	cases := []struct {
+		sql   string
+		after string
+	}{
+		{
+			sql:   "a is null or b > 1",
+			after: "gt(test.t.b, 1)",
+		},
+		{
+			sql:   "a is not null and b is null",
+			after: "isnull(test.t.b)",
+		},
+		{
+			sql:   "b > 1 and b is not null",
+			after: "gt(test.t.b, 1)",
+		},
+		{
+			sql:   "b = 1 and b is null",
+			after: "0",
+		},
+		{
+			sql:   "b < c and (c is null or b is null or d > 0)",
+			after: "gt(test.t.d, 0), lt(test.t.b, test.t.c)",
+		},
+		{
+			sql:   "c is not null and d is null",
+			after: "isnull(test.t.d), not(isnull(test.t.c))",
+		},
+		{
+			sql:   "b in (1, c) and c is null",
+			after: "in(test.t.b, 1, test.t.c), isnull(test.t.c)",
+		},
 	}
 	for _, ca := range cases {
 		sql := "select * from t where " + ca.sql
@@ -1427,6 +2176,59 @@ func (s *testPlanSuite) TestConstantPropagation(c *C) {
 	}
 }
 
+func (s *testPlanSuite) TestSortConditionsByRank(c *C) {
+	defer testleak.AfterTest(c)()
+	cases := []struct {
+		sql    string
+		result string
+	}{
+		{
+			sql:    "c_str like '%x%' and a = 1",
+			result: "eq(test.t.a, 1), like(test.t.c_str, %x%, 92)",
+		},
+		{
+			sql:    "c_str regexp 'x' and d_str like 'x%' and b > 1",
+			result: "gt(test.t.b, 1), like(test.t.d_str, x%, 92), regexp(test.t.c_str, x)",
+		},
+		{
+			sql:    "c_str = 'x' and b = 1 and a > 1",
+			result: "eq(test.t.b, 1), gt(test.t.a, 1), eq(test.t.c_str, x)",
+		},
+		{
+			sql:    "abs(a) = 1 and b = 1",
+			result: "eq(test.t.b, 1), eq(abs(test.t.a), 1)",
+		},
+		{
+			sql:    "a > 1 and b > 1",
+			result: "gt(test.t.a, 1), gt(test.t.b, 1)",
+		},
+	}
+	for _, ca := range cases {
+		sql := "select * from t where " + ca.sql
+		comment := Commentf("for %s", sql)
+		stmt, err := s.ParseOneStmt(sql, "", "")
+		c.Assert(err, IsNil, comment)
+		err = mockResolve(stmt)
+		c.Assert(err, IsNil)
+		builder := &planBuilder{
+			allocator: new(idAllocator),
+			ctx:       mock.NewContext(),
+			colMapper: make(map[*ast.ColumnNameExpr]int),
+		}
+		p := builder.build(stmt)
+		c.Assert(builder.err, IsNil)
+		var sel *Selection
+		for v := p; sel == nil; v = v.GetChildByIndex(0) {
+			sel, _ = v.(*Selection)
+		}
+		var result []string
+		for _, cond := range SortConditionsByRank(sel.Conditions) {
+			result = append(result, cond.String())
+		}
+		c.Assert(strings.Join(result, ", "), Equals, ca.result, comment)
+	}
+}
+
 func (s *testPlanSuite) TestValidate(c *C) {
 	defer testleak.AfterTest(c)()
 	cases := []struct {
@@ -1526,3 +2328,118 @@ func (s *testPlanSuite) TestValidate(c *C) {
 		}
 	}
 }
+
+func (s *testPlanSuite) TestExprInterner(c *C) {
+	defer testleak.AfterTest(c)()
+	newCol := func(fromID string, position int) *expression.Column {
+		return &expression.Column{FromID: fromID, Position: position, RetType: types.NewFieldType(mysql.TypeLonglong)}
+	}
+	newGT := func(arg expression.Expression) expression.Expression {
+		f, err := expression.NewFunction(ast.GT, types.NewFieldType(mysql.TypeLonglong), arg, &expression.Constant{Value: types.NewIntDatum(1)})
+		c.Assert(err, IsNil)
+		return f
+	}
+	in := make(exprInterner)
+	first := newGT(newCol("t", 1))
+	interned, dup := in.intern(first)
+	c.Assert(dup, IsFalse)
+	c.Assert(interned, Equals, first)
+	interned, dup = in.intern(newGT(newCol("t", 1)))
+	c.Assert(dup, IsTrue)
+	c.Assert(interned, Equals, first)
+	_, dup = in.intern(newGT(newCol("t", 2)))
+	c.Assert(dup, IsFalse)
+
+	// The cached hash codes must follow the changes of the expressions.
+	col := newCol("t", 3)
+	code := string(col.HashCode())
+	col.Position = 1
+	c.Assert(string(col.HashCode()), Not(Equals), code)
+	f := newGT(newCol("t", 3)).(*expression.ScalarFunction)
+	code = string(f.HashCode())
+	f.SetArg(0, col)
+	c.Assert(string(f.HashCode()), Not(Equals), code)
+	c.Assert(string(f.HashCode()), Equals, string(first.HashCode()))
+	f.SetArg(0, newCol("t", 4))
+	c.Assert(string(f.HashCode()), Not(Equals), string(first.HashCode()))
+
+	// The predicates derived from the duplicated inequality predicates are only added once.
+	newEQ := func(a, b expression.Expression) expression.Expression {
+		f, err := expression.NewFunction(ast.EQ, types.NewFieldType(mysql.TypeLonglong), a, b)
+		c.Assert(err, IsNil)
+		return f
+	}
+	conditions := propagateConstant([]expression.Expression{
+		newEQ(newCol("t", 1), newCol("t", 2)),
+		newEQ(newCol("t", 1), newCol("t", 3)),
+		newGT(newCol("t", 2)),
+		newGT(newCol("t", 3)),
+	})
+	c.Assert(conditions, HasLen, 5)
+
+	// The functions whose arguments aren't substituted aren't rebuilt.
+	cond := newGT(newCol("t", 1))
+	c.Assert(constantSubstitute(map[string]*expression.Constant{}, cond), Equals, cond)
+	c.Assert(nullCheckSubstitute(map[string]bool{}, cond), Equals, cond)
+	c.Assert(nullCheckSubstitute(map[string]bool{string(newCol("t", 1).HashCode()): true}, cond), Equals, cond)
+}
+
+func (s *testPlanSuite) TestDisableRules(c *C) {
+	defer testleak.AfterTest(c)()
+	cases := []struct {
+		sql  string
+		best string
+	}{
+		{
+			sql:  "select * from t t1, t t2, t t3 where t1.a = t3.a",
+			best: "LeftHashJoin{MergeJoin{Table(t)->Table(t)}(t1.a,t3.a)->Table(t)}->Projection",
+		},
+		{
+			sql:  "select /*+ DISABLE_RULES(join_reorder) */ * from t t1, t t2, t t3 where t1.a = t3.a",
+			best: "MergeJoin{LeftHashJoin{Table(t)->Table(t)}->Table(t)}(t1.a,t3.a)",
+		},
+		{
+			sql:  "select /*+ DISABLE_RULES(predicate_push_down) */ * from t t1, t t2 where t1.a = t2.a and t1.b > 1",
+			best: "LeftHashJoin{Table(t)->Table(t)}->Selection",
+		},
+		{
+			sql:  "select /*+ DISABLE_RULES(AGG_PUSHDOWN, eliminate_projection) */ sum(t1.d) from t t1, t t2 where t1.b = t2.b group by t1.c",
+			best: "LeftHashJoin{Index(t.c_d_e)[[<nil>,+inf]]->Table(t)}(t1.b,t2.b)->StreamAgg->Projection",
+		},
+		{
+			sql:  "select sum(t1.d) from t t1, t t2 where t1.b = t2.b group by t1.c",
+			best: "RightHashJoin{Table(t)->HashAgg->Table(t)}(t1.b,t2.b)->HashAgg",
+		},
+	}
+	for _, ca := range cases {
+		comment := Commentf("for %s", ca.sql)
+		stmt, err := s.ParseOneStmt(ca.sql, "", "")
+		c.Assert(err, IsNil, comment)
+
+		err = mockResolve(stmt)
+		c.Assert(err, IsNil)
+
+		builder := &planBuilder{
+			allocator: new(idAllocator),
+			ctx:       mock.NewContext(),
+			colMapper: make(map[*ast.ColumnNameExpr]int),
+		}
+		p := builder.build(stmt)
+		c.Assert(builder.err, IsNil, comment)
+		pp, err := doOptimize(p.(LogicalPlan), builder.ctx, builder.allocator, builder.disabledRules)
+		c.Assert(err, IsNil, comment)
+		c.Assert(ToString(pp), Equals, ca.best, comment)
+	}
+
+	stmt, err := s.ParseOneStmt("select /*+ DISABLE_RULES(join_reorder, no_such_rule) */ * from t", "", "")
+	c.Assert(err, IsNil)
+	err = mockResolve(stmt)
+	c.Assert(err, IsNil)
+	builder := &planBuilder{
+		allocator: new(idAllocator),
+		ctx:       mock.NewContext(),
+		colMapper: make(map[*ast.ColumnNameExpr]int),
+	}
+	builder.build(stmt)
+	c.Assert(terror.ErrorEqual(builder.err, ErrUnknownRule), IsTrue)
+}
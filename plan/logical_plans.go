@@ -14,12 +14,15 @@
 package plan
 
 import (
+	"time"
+
 	"github.com/juju/errors"
 	"github.com/pingcap/tidb/ast"
 	"github.com/pingcap/tidb/context"
 	"github.com/pingcap/tidb/expression"
 	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/plan/statistics"
+	"github.com/pingcap/tidb/util/jsonpath"
 	"github.com/pingcap/tidb/util/types"
 )
 
@@ -47,6 +50,12 @@ type Join struct {
 	anti          bool
 	reordered     bool
 	cartesianJoin bool
+	// straightJoin means the join is written as STRAIGHT_JOIN, so it isn't reordered, and the index nested loop
+	// join always looks up its right child, like MySQL reads the left table first.
+	straightJoin bool
+	// nullAware means the semi join is built from "a [not] in (subq)", a comparison of the join conditions
+	// evaluated to NULL makes the result NULL instead of false if no row matches, e.g. "1 not in (2, NULL)" is NULL.
+	nullAware bool
 
 	EqualConditions []*expression.ScalarFunction
 	LeftConditions  []expression.Expression
@@ -56,6 +65,9 @@ type Join struct {
 	// DefaultValues is only used for outer join, which stands for the default values when the outer table cannot find join partner
 	// instead of null padding.
 	DefaultValues []types.Datum
+
+	// hintInfo is the optimizer hints of the query block that the join belongs to.
+	hintInfo *tableHintInfo
 }
 
 func (p *Join) extractCorrelatedCols() []*expression.CorrelatedColumn {
@@ -141,7 +153,10 @@ type Apply struct {
 
 	InnerPlan LogicalPlan
 	Checker   *ApplyConditionChecker
-	corCols   []*expression.CorrelatedColumn
+	// Lateral means every inner row is joined with the outer row, which is used for the table functions
+	// referring to the preceding tables, otherwise the inner plan returns at most one row.
+	Lateral bool
+	corCols []*expression.CorrelatedColumn
 }
 
 func (p *Apply) extractCorrelatedCols() []*expression.CorrelatedColumn {
@@ -153,6 +168,18 @@ func (p *Apply) extractCorrelatedCols() []*expression.CorrelatedColumn {
 	return corCols
 }
 
+// canCacheInner checks if the results of the inner plan can be memoized by the values of the correlated columns,
+// i.e. the inner plan only refers to the columns of the outer child and has no non-deterministic function.
+func (p *Apply) canCacheInner() bool {
+	childSchema := p.children[0].GetSchema()
+	for _, corCol := range p.InnerPlan.extractCorrelatedCols() {
+		if childSchema.GetIndex(&corCol.Column) == -1 {
+			return false
+		}
+	}
+	return !hasDynamicFuncInPlan(p.InnerPlan)
+}
+
 // Exists checks if a query returns result.
 type Exists struct {
 	baseLogicalPlan
@@ -168,6 +195,121 @@ type TableDual struct {
 	baseLogicalPlan
 }
 
+// GenerateSeries represents the generate_series(start, stop[, step]) table function.
+// It produces a single BIGINT column holding start, start+step, ... up to stop.
+type GenerateSeries struct {
+	baseLogicalPlan
+
+	Start int64
+	Stop  int64
+	Step  int64
+}
+
+// rowCount returns the exact number of rows the series produces.
+func (p *GenerateSeries) rowCount() uint64 {
+	if p.Step > 0 && p.Start <= p.Stop {
+		return (uint64(p.Stop)-uint64(p.Start))/uint64(p.Step) + 1
+	}
+	if p.Step < 0 && p.Start >= p.Stop {
+		return (uint64(p.Start)-uint64(p.Stop))/uint64(-p.Step) + 1
+	}
+	return 0
+}
+
+// JSONTable represents the json_table(doc, path COLUMNS (...)) table function.
+// It produces a row for each value matched by RowPath in the document, and the columns of the row
+// are the values matched by the column paths in the value.
+type JSONTable struct {
+	baseLogicalPlan
+
+	Doc     expression.Expression
+	RowPath *jsonpath.Path
+	Columns []*JSONTableColumn
+}
+
+func (p *JSONTable) extractCorrelatedCols() []*expression.CorrelatedColumn {
+	return extractCorColumns(p.Doc)
+}
+
+// JSONTableColumn is a column of JSONTable.
+type JSONTableColumn struct {
+	// Path is nil for the FOR ORDINALITY column, which is the 1-based row number.
+	Path *jsonpath.Path
+	Tp   *types.FieldType
+	// OnEmpty is the response to a path matching nothing.
+	OnEmpty JSONTableResponse
+	// OnError is the response to a value which can't be converted to the column type.
+	OnError JSONTableResponse
+}
+
+// JSONTableResponse is the ON EMPTY or ON ERROR response of a JSONTable column.
+type JSONTableResponse struct {
+	Tp ast.TableFuncColumnResponseType
+	// Default is the value of DEFAULT converted to the column type.
+	Default types.Datum
+}
+
+// jsonTableRowCount is the estimated row count of a JSONTable, the document is unknown until execution.
+const jsonTableRowCount = 100
+
+// The kinds of the profiles of Profile.
+const (
+	ProfileCPU  = "cpu"
+	ProfileHeap = "heap"
+)
+
+// Profile represents the tidb_profile(kind[, seconds]) table function.
+// It captures a profile of the server and produces a row for each function in the profile.
+type Profile struct {
+	baseLogicalPlan
+
+	Kind string
+	// Duration is the duration of the CPU profile.
+	Duration time.Duration
+}
+
+// profileRowCount is the estimated row count of a Profile, the functions are unknown until execution.
+const profileRowCount = 100
+
+// RowHistory represents the tidb_row_history(table, handle) table function.
+// It produces a row for each version of the row in the storage, the latest version first.
+type RowHistory struct {
+	baseLogicalPlan
+
+	// DBID is the ID of the database of the table, the versions are decoded with the table of their commit ts.
+	DBID  int64
+	Table *model.TableInfo
+	// Columns are the public columns of the table, which follow the commit ts, the commit time and
+	// the deleted flag in the schema.
+	Columns []*model.ColumnInfo
+	Handle  int64
+}
+
+// rowHistoryRowCount is the estimated row count of a RowHistory, the versions are unknown until execution.
+const rowHistoryRowCount = 10
+
+// CTE represents a reference to a materialized common table expression.
+// All the references to the same common table expression share the Source, whose rows are produced once.
+type CTE struct {
+	baseLogicalPlan
+
+	Source *CTESource
+	// Producer is true for the reference under which the plan of the Source is explained.
+	Producer bool
+}
+
+// CTESource is the query of a materialized common table expression. It is optimized when the first reference
+// is converted to a physical plan, so the references can't change the plan of each other.
+type CTESource struct {
+	Name model.CIStr
+	// Plan is the physical plan of the query.
+	Plan PhysicalPlan
+
+	logic    LogicalPlan
+	ctx      context.Context
+	disabled ruleSet
+}
+
 // DataSource represents a tablescan without condition push down.
 type DataSource struct {
 	baseLogicalPlan
@@ -180,6 +322,8 @@ type DataSource struct {
 	ctx     context.Context
 
 	TableAsName *model.CIStr
+	// indexHints is the index hints of the table, including the ones written as optimizer hints.
+	indexHints []*ast.IndexHint
 
 	LimitCount *int64
 
@@ -194,6 +338,10 @@ type Trim struct {
 // Union represents Union plan.
 type Union struct {
 	baseLogicalPlan
+
+	// SideEffects is true if a SELECT of the union changes the state of the session, e.g. assigns a user variable,
+	// so the SELECTs can't be executed concurrently.
+	SideEffects bool
 }
 
 // Sort stands for the order by plan.
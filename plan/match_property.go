@@ -22,10 +22,11 @@ import (
 
 // matchProperty implements PhysicalPlan matchProperty interface.
 func (ts *PhysicalTableScan) matchProperty(prop *requiredProperty, infos ...*physicalPlanInfo) *physicalPlanInfo {
+	factors := ts.allocator.costFactors()
 	rowCount := float64(infos[0].count)
-	cost := rowCount * netWorkFactor
+	cost := rowCount * factors.scanRow()
 	if prop.limit != nil {
-		cost = float64(prop.limit.Count+prop.limit.Offset) * netWorkFactor
+		cost = float64(prop.limit.Count+prop.limit.Offset) * factors.scanRow()
 	}
 	if len(prop.props) == 0 {
 		newTS := *ts
@@ -48,9 +49,9 @@ func (ts *PhysicalTableScan) matchProperty(prop *requiredProperty, infos ...*phy
 		sortedTS := *ts
 		success := sortedTS.addTopN(prop)
 		if success {
-			cost += rowCount * cpuFactor
+			cost += rowCount * factors.cpu
 		} else {
-			cost = rowCount * netWorkFactor
+			cost = rowCount * factors.scanRow()
 		}
 		sortedTS.KeepOrder = true
 		p := sortedTS.tryToAddUnionScan(&sortedTS)
@@ -62,6 +63,52 @@ func (ts *PhysicalTableScan) matchProperty(prop *requiredProperty, infos ...*phy
 	return &physicalPlanInfo{p: nil, cost: math.MaxFloat64, count: infos[0].count}
 }
 
+// matchProperty implements PhysicalPlan matchProperty interface.
+// The handles are read from the indices before the rows, and the rows are returned in the handle order which
+// can't match any required order.
+func (p *PhysicalIndexMerge) matchProperty(prop *requiredProperty, infos ...*physicalPlanInfo) *physicalPlanInfo {
+	if len(prop.props) != 0 {
+		return &physicalPlanInfo{p: nil, cost: math.MaxFloat64, count: infos[0].count}
+	}
+	cost := 0.0
+	for _, partial := range p.PartialPlans {
+		// Every handle is read from the index once and then from the table once.
+		cost += float64(partial.EstimatedRowCount()) * p.allocator.costFactors().scanRow() * 2
+	}
+	np := p.tryToAddUnionScan(p)
+	return enforceProperty(prop, &physicalPlanInfo{p: np, cost: cost, count: infos[0].count})
+}
+
+// matchOrder checks if the index scan can return the rows in the order of the required property,
+// and whether the index should be scanned in the desc order.
+func (is *PhysicalIndexScan) matchOrder(prop *requiredProperty) (matched bool, desc bool) {
+	matchedIdx := 0
+	matchedList := make([]bool, len(prop.props))
+	for i, idxCol := range is.Index.Columns {
+		if idxCol.Length != types.UnspecifiedLength {
+			break
+		}
+		if idx := matchPropColumn(prop, matchedIdx, idxCol); idx >= 0 {
+			matchedList[idx] = true
+			matchedIdx++
+		} else if i >= is.accessEqualCount {
+			break
+		}
+	}
+	if !allMatch(matchedList) {
+		return false, false
+	}
+	allDesc, allAsc := true, true
+	for i := 0; i < prop.sortKeyLen; i++ {
+		if prop.props[i].desc {
+			allAsc = false
+		} else {
+			allDesc = false
+		}
+	}
+	return allAsc || allDesc, allDesc && !allAsc
+}
+
 func allMatch(matchedList []bool) bool {
 	for _, matched := range matchedList {
 		if !matched {
@@ -95,11 +142,12 @@ func matchPropColumn(prop *requiredProperty, matchedIdx int, idxCol *model.Index
 
 // matchProperty implements PhysicalPlan matchProperty interface.
 func (is *PhysicalIndexScan) matchProperty(prop *requiredProperty, infos ...*physicalPlanInfo) *physicalPlanInfo {
+	factors := is.allocator.costFactors()
 	rowCount := float64(infos[0].count)
 	if prop.limit != nil {
 		rowCount = float64(prop.limit.Count)
 	}
-	cost := rowCount * netWorkFactor
+	cost := rowCount * factors.scanRow()
 	if is.DoubleRead {
 		cost *= 2
 	}
@@ -107,48 +155,25 @@ func (is *PhysicalIndexScan) matchProperty(prop *requiredProperty, infos ...*phy
 		p := is.tryToAddUnionScan(is)
 		return enforceProperty(&requiredProperty{limit: prop.limit}, &physicalPlanInfo{p: p, cost: cost, count: infos[0].count})
 	}
-	matchedIdx := 0
-	matchedList := make([]bool, len(prop.props))
-	for i, idxCol := range is.Index.Columns {
-		if idxCol.Length != types.UnspecifiedLength {
-			break
-		}
-		if idx := matchPropColumn(prop, matchedIdx, idxCol); idx >= 0 {
-			matchedList[idx] = true
-			matchedIdx++
-		} else if i >= is.accessEqualCount {
-			break
-		}
-	}
-	if allMatch(matchedList) {
-		allDesc, allAsc := true, true
-		for i := 0; i < prop.sortKeyLen; i++ {
-			if prop.props[i].desc {
-				allAsc = false
-			} else {
-				allDesc = false
-			}
-		}
-		sortedCost := cost + rowCount*cpuFactor
-		if allAsc || allDesc {
-			sortedIS := *is
-			sortedIS.OutOfOrder = false
-			sortedIS.Desc = allDesc && !allAsc
-			sortedIS.addLimit(prop.limit)
-			p := sortedIS.tryToAddUnionScan(&sortedIS)
-			return enforceProperty(&requiredProperty{limit: prop.limit}, &physicalPlanInfo{
-				p:     p,
-				cost:  sortedCost,
-				count: infos[0].count})
-		}
+	if matched, desc := is.matchOrder(prop); matched {
+		sortedCost := cost + rowCount*factors.cpu
+		sortedIS := *is
+		sortedIS.OutOfOrder = false
+		sortedIS.Desc = desc
+		sortedIS.addLimit(prop.limit)
+		p := sortedIS.tryToAddUnionScan(&sortedIS)
+		return enforceProperty(&requiredProperty{limit: prop.limit}, &physicalPlanInfo{
+			p:     p,
+			cost:  sortedCost,
+			count: infos[0].count})
 	}
 	if prop.limit != nil {
 		sortedIS := *is
 		success := sortedIS.addTopN(prop)
 		if success {
-			cost += float64(infos[0].count) * cpuFactor
+			cost += float64(infos[0].count) * factors.cpu
 		} else {
-			cost = float64(infos[0].count) * netWorkFactor
+			cost = float64(infos[0].count) * factors.scanRow()
 		}
 		sortedIS.OutOfOrder = true
 		p := sortedIS.tryToAddUnionScan(&sortedIS)
@@ -191,7 +216,7 @@ func (p *PhysicalHashJoin) matchProperty(prop *requiredProperty, childPlanInfo .
 	np := *p
 	np.SetChildren(lRes.p, rRes.p)
 	if len(prop.props) != 0 {
-		np.Concurrency = 1
+		np.KeepOrder = true
 	}
 	cost := lRes.cost + rRes.cost
 	if p.SmallTable == 1 {
@@ -202,6 +227,32 @@ func (p *PhysicalHashJoin) matchProperty(prop *requiredProperty, childPlanInfo .
 	return &physicalPlanInfo{p: &np, cost: cost, count: estimateJoinCount(lRes.count, rRes.count)}
 }
 
+// matchProperty implements PhysicalPlan matchProperty interface.
+// The children are read once in the order of the join keys, no hash table is built.
+func (p *PhysicalMergeJoin) matchProperty(_ *requiredProperty, childPlanInfo ...*physicalPlanInfo) *physicalPlanInfo {
+	lRes, rRes := childPlanInfo[0], childPlanInfo[1]
+	np := *p
+	np.SetChildren(lRes.p, rRes.p)
+	cost := lRes.cost + rRes.cost + float64(lRes.count) + float64(rRes.count)
+	return &physicalPlanInfo{p: &np, cost: cost, count: estimateJoinCount(lRes.count, rRes.count)}
+}
+
+// matchProperty implements PhysicalPlan matchProperty interface.
+// Every outer row costs a lookup of the inner child, but only the inner rows that match the outer rows are read.
+func (p *PhysicalIndexJoin) matchProperty(_ *requiredProperty, childPlanInfo ...*physicalPlanInfo) *physicalPlanInfo {
+	outerRes, innerRes := childPlanInfo[p.OuterIndex], childPlanInfo[1-p.OuterIndex]
+	np := *p
+	np.SetChildren(childPlanInfo[0].p, childPlanInfo[1].p)
+	count := estimateJoinCount(outerRes.count, innerRes.count)
+	cost := outerRes.cost + float64(outerRes.count)*p.allocator.costFactors().seek
+	if innerRes.count > 0 {
+		// The cost of the inner child is the cost to read all its rows.
+		innerCount := math.Min(float64(count), float64(innerRes.count))
+		cost += innerRes.cost * innerCount / float64(innerRes.count)
+	}
+	return &physicalPlanInfo{p: &np, cost: cost, count: count}
+}
+
 // matchProperty implements PhysicalPlan matchProperty interface.
 func (p *Union) matchProperty(_ *requiredProperty, childPlanInfo ...*physicalPlanInfo) *physicalPlanInfo {
 	np := *p
@@ -219,6 +270,7 @@ func (p *Union) matchProperty(_ *requiredProperty, childPlanInfo ...*physicalPla
 func (p *Selection) matchProperty(prop *requiredProperty, childPlanInfo ...*physicalPlanInfo) *physicalPlanInfo {
 	if p.onTable {
 		res := p.GetChildByIndex(0).(PhysicalPlan).matchProperty(prop, childPlanInfo...)
+		setEstimatedStats(res)
 		sel := *p
 		sel.SetChildren(res.p)
 		res.p = &sel
@@ -291,6 +343,31 @@ func (p *TableDual) matchProperty(_ *requiredProperty, _ ...*physicalPlanInfo) *
 	panic("You can't call this function!")
 }
 
+// matchProperty implements PhysicalPlan matchProperty interface.
+func (p *GenerateSeries) matchProperty(_ *requiredProperty, _ ...*physicalPlanInfo) *physicalPlanInfo {
+	panic("You can't call this function!")
+}
+
+// matchProperty implements PhysicalPlan matchProperty interface.
+func (p *JSONTable) matchProperty(_ *requiredProperty, _ ...*physicalPlanInfo) *physicalPlanInfo {
+	panic("You can't call this function!")
+}
+
+// matchProperty implements PhysicalPlan matchProperty interface.
+func (p *Profile) matchProperty(_ *requiredProperty, _ ...*physicalPlanInfo) *physicalPlanInfo {
+	panic("You can't call this function!")
+}
+
+// matchProperty implements PhysicalPlan matchProperty interface.
+func (p *RowHistory) matchProperty(_ *requiredProperty, _ ...*physicalPlanInfo) *physicalPlanInfo {
+	panic("You can't call this function!")
+}
+
+// matchProperty implements PhysicalPlan matchProperty interface.
+func (p *CTE) matchProperty(_ *requiredProperty, _ ...*physicalPlanInfo) *physicalPlanInfo {
+	panic("You can't call this function!")
+}
+
 // matchProperty implements PhysicalPlan matchProperty interface.
 func (p *Sort) matchProperty(_ *requiredProperty, _ ...*physicalPlanInfo) *physicalPlanInfo {
 	panic("You can't call this function!")
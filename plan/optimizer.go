@@ -14,12 +14,16 @@
 package plan
 
 import (
+	"strconv"
+	"strings"
+
 	"github.com/juju/errors"
 	"github.com/ngaut/log"
 	"github.com/pingcap/tidb/ast"
 	"github.com/pingcap/tidb/context"
 	"github.com/pingcap/tidb/infoschema"
 	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/sessionctx/variable"
 	"github.com/pingcap/tidb/terror"
 )
 
@@ -29,55 +33,129 @@ var AllowCartesianProduct = true
 // Optimize does optimization and creates a Plan.
 // The node must be prepared first.
 func Optimize(ctx context.Context, node ast.Node, is infoschema.InfoSchema) (Plan, error) {
+	return optimize(ctx, node, is, false)
+}
+
+// optimize optimizes the node, explain tells whether the plan is built to be explained.
+func optimize(ctx context.Context, node ast.Node, is infoschema.InfoSchema, explain bool) (Plan, error) {
 	// We have to infer type again because after parameter is set, the expression type may change.
 	if err := InferType(node); err != nil {
 		return nil, errors.Trace(err)
 	}
-	allocator := new(idAllocator)
+	factors, factorsErr := loadCostFactors(ctx)
+	allocator := &idAllocator{factors: factors}
 	builder := &planBuilder{
 		ctx:       ctx,
 		is:        is,
 		colMapper: make(map[*ast.ColumnNameExpr]int),
-		allocator: allocator}
+		allocator: allocator,
+		inExplain: explain}
+	rules, err := ctx.GetSessionVars().GetTiDBSystemVar(variable.TiDBOptDisableRules)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	builder.disabledRules = make(ruleSet)
+	rulesErr := builder.disabledRules.add(strings.Split(rules, ",")...)
+	if rulesErr == nil && factorsErr == nil && !builder.disabledRules[rulePointGet] {
+		if p := tryPointGetPlan(ctx, node, allocator); p != nil {
+			return p, nil
+		}
+	}
 	p := builder.build(node)
 	if builder.err != nil {
 		return nil, errors.Trace(builder.err)
 	}
 	if logic, ok := p.(LogicalPlan); ok {
-		return doOptimize(logic, ctx, allocator)
+		// An invalid tidb_opt_disable_rules or cost factor only fails the statements to be optimized,
+		// so that it can still be corrected by a SET statement.
+		if rulesErr != nil {
+			return nil, errors.Trace(rulesErr)
+		}
+		if factorsErr != nil {
+			return nil, errors.Trace(factorsErr)
+		}
+		return doOptimize(logic, ctx, allocator, builder.disabledRules)
 	}
 	return p, nil
 }
 
-func doOptimize(logic LogicalPlan, ctx context.Context, allocator *idAllocator) (PhysicalPlan, error) {
+func doOptimize(logic LogicalPlan, ctx context.Context, allocator *idAllocator, disabled ruleSet) (PhysicalPlan, error) {
 	var err error
-	_, logic, err = logic.PredicatePushDown(nil)
-	if err != nil {
-		return nil, errors.Trace(err)
+	if disabled[ruleJoinReorder] {
+		disableJoinReorder(logic)
+	}
+	if !disabled[rulePredicatePushDown] {
+		_, logic, err = logic.PredicatePushDown(nil)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	if !disabled[ruleEliminateMaxOneRow] {
+		logic = eliminateMaxOneRow(logic)
 	}
-	solver := &aggPushDownSolver{
-		ctx:   ctx,
-		alloc: allocator,
+	if !disabled[ruleEliminateAgg] {
+		logic = eliminateAggregation(logic)
+	}
+	if !disabled[ruleAggPushDown] {
+		solver := &aggPushDownSolver{
+			ctx:   ctx,
+			alloc: allocator,
+		}
+		solver.aggPushDown(logic)
 	}
-	solver.aggPushDown(logic)
 	logic.PruneColumns(logic.GetSchema())
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
+	if !disabled[ruleEliminateProjection] {
+		mergeProjection(logic)
+	}
 	logic.ResolveIndicesAndCorCols()
-	if !AllowCartesianProduct && existsCartesianProduct(logic) {
-		return nil, ErrCartesianProductUnsupported
+	if existsCartesianProduct(logic) {
+		if err = checkCartesianProduct(ctx); err != nil {
+			return nil, errors.Trace(err)
+		}
 	}
 	info, err := logic.convert2PhysicalPlan(&requiredProperty{})
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
 	pp := info.p
-	pp = EliminateProjection(pp)
+	if !disabled[ruleEliminateProjection] {
+		pp = EliminateProjection(pp)
+	}
+	initEnforcedPlanIDs(pp, allocator)
+	if err = checkEstimatedRows(ctx, pp); err != nil {
+		return nil, errors.Trace(err)
+	}
 	log.Debugf("[PLAN] %s", ToString(pp))
 	return pp, nil
 }
 
+// initEnforcedPlanIDs allocates the IDs of the physical plans which are added without them, i.e. the sorts and
+// limits enforced by the required properties and the caches under the applies, so every plan can be identified.
+func initEnforcedPlanIDs(p Plan, allocator *idAllocator) {
+	if p.GetID() == "" {
+		switch x := p.(type) {
+		case *Sort:
+			x.tp, x.allocator = Srt, allocator
+			x.initID()
+		case *Limit:
+			x.tp, x.allocator = Lim, allocator
+			x.initID()
+		case *Cache:
+			x.tp, x.allocator = Cach, allocator
+			x.initID()
+		}
+	}
+	for _, child := range p.GetChildren() {
+		initEnforcedPlanIDs(child, allocator)
+	}
+	if apply, ok := p.(*PhysicalApply); ok {
+		initEnforcedPlanIDs(apply.InnerPlan, allocator)
+	}
+}
+
 func existsCartesianProduct(p LogicalPlan) bool {
 	if join, ok := p.(*Join); ok && len(join.EqualConditions) == 0 {
 		return join.JoinType == InnerJoin || join.JoinType == LeftOuterJoin || join.JoinType == RightOuterJoin
@@ -90,6 +168,74 @@ func existsCartesianProduct(p LogicalPlan) bool {
 	return false
 }
 
+// The values of tidb_cartesian_join.
+const (
+	cartesianJoinAllow  = "allow"
+	cartesianJoinWarn   = "warn"
+	cartesianJoinReject = "reject"
+)
+
+// checkCartesianProduct decides whether a plan with a cartesian product is allowed by AllowCartesianProduct and
+// tidb_cartesian_join.
+func checkCartesianProduct(ctx context.Context) error {
+	if !AllowCartesianProduct {
+		return ErrCartesianProductUnsupported
+	}
+	sessionVars := ctx.GetSessionVars()
+	mode, err := sessionVars.GetTiDBSystemVar(variable.TiDBCartesianJoin)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	switch strings.ToLower(mode) {
+	case cartesianJoinAllow:
+	case cartesianJoinWarn:
+		log.Warnf("[PLAN] connection %d plans a cartesian product, a join has no equal condition", sessionVars.ConnectionID)
+	case cartesianJoinReject:
+		return ErrCartesianProductRejected.Gen("Cartesian product is rejected, add an equal condition to the join or set %s to '%s'",
+			variable.TiDBCartesianJoin, cartesianJoinAllow)
+	default:
+		return ErrWrongArguments.Gen("Incorrect value '%s' for %s, it should be '%s', '%s' or '%s'",
+			mode, variable.TiDBCartesianJoin, cartesianJoinAllow, cartesianJoinWarn, cartesianJoinReject)
+	}
+	return nil
+}
+
+// checkEstimatedRows fails the plan if the estimated row count of any of its operators exceeds
+// tidb_max_estimated_rows.
+func checkEstimatedRows(ctx context.Context, p PhysicalPlan) error {
+	val, err := ctx.GetSessionVars().GetTiDBSystemVar(variable.TiDBMaxEstimatedRows)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	limit, err := strconv.ParseUint(val, 10, 64)
+	if err != nil {
+		return ErrWrongArguments.Gen("Incorrect value '%s' for %s", val, variable.TiDBMaxEstimatedRows)
+	}
+	if limit == 0 {
+		return nil
+	}
+	if over := findEstimatedRowsOver(p, limit); over != nil {
+		return ErrTooManyEstimatedRows.Gen("The estimated row count %d of %s exceeds %s %d, check the conditions of the query",
+			over.EstimatedRowCount(), over.GetID(), variable.TiDBMaxEstimatedRows, limit)
+	}
+	return nil
+}
+
+func findEstimatedRowsOver(p Plan, limit uint64) Plan {
+	if p.EstimatedRowCount() > limit {
+		return p
+	}
+	for _, child := range p.GetChildren() {
+		if over := findEstimatedRowsOver(child, limit); over != nil {
+			return over
+		}
+	}
+	if apply, ok := p.(*PhysicalApply); ok {
+		return findEstimatedRowsOver(apply.InnerPlan, limit)
+	}
+	return nil
+}
+
 // PrepareStmt prepares a raw statement parsed from parser.
 // The statement must be prepared before it can be passed to optimize function.
 // We pass InfoSchema instead of getting from Context in case it is changed after resolving name.
@@ -105,12 +251,35 @@ func PrepareStmt(is infoschema.InfoSchema, ctx context.Context, node ast.Node) e
 
 // Optimizer error codes.
 const (
-	CodeOneColumn           terror.ErrCode = 1
-	CodeSameColumns         terror.ErrCode = 2
-	CodeInvalidWildCard     terror.ErrCode = 3
-	CodeUnsupported         terror.ErrCode = 4
-	CodeInvalidGroupFuncUse terror.ErrCode = 5
-	CodeIllegalReference    terror.ErrCode = 6
+	CodeOneColumn            terror.ErrCode = 1
+	CodeSameColumns          terror.ErrCode = 2
+	CodeInvalidWildCard      terror.ErrCode = 3
+	CodeUnsupported          terror.ErrCode = 4
+	CodeInvalidGroupFuncUse  terror.ErrCode = 5
+	CodeIllegalReference     terror.ErrCode = 6
+	CodeUnknownRule          terror.ErrCode = 7
+	CodeWrongArguments       terror.ErrCode = 8
+	CodeWrongParamCount      terror.ErrCode = 9
+	CodeUnknownTableFunc     terror.ErrCode = 10
+	CodeDupFieldName         terror.ErrCode = 11
+	CodeUnknownExplainFormat terror.ErrCode = 12
+	CodeViewInvalid          terror.ErrCode = 13
+	CodeViewRecursive        terror.ErrCode = 14
+	CodeViewWrongList        terror.ErrCode = 15
+	CodeNonuniqTable         terror.ErrCode = 16
+	CodeCartesianRejected    terror.ErrCode = 17
+	CodeTooManyEstimatedRows terror.ErrCode = 18
+	CodeUnknownColumn        terror.ErrCode = 19
+	CodeAmbiguousColumn      terror.ErrCode = 20
+	CodeFieldNotInGroupBy    terror.ErrCode = 21
+	CodeMixGroupFuncFields   terror.ErrCode = 22
+	CodeOrderNotInSelect     terror.ErrCode = 23
+	CodeUnknownTable         terror.ErrCode = 24
+	CodeWrongUsage           terror.ErrCode = 25
+	CodeKeyDoesNotExist      terror.ErrCode = 26
+	CodeViewNoExplain        terror.ErrCode = 27
+	CodeSpecificAccessDenied terror.ErrCode = 28
+	CodeInvalidDefault       terror.ErrCode = 29
 )
 
 // Optimizer base errors.
@@ -121,15 +290,59 @@ var (
 	ErrCartesianProductUnsupported = terror.ClassOptimizer.New(CodeUnsupported, "Cartesian product is unsupported")
 	ErrInvalidGroupFuncUse         = terror.ClassOptimizer.New(CodeInvalidGroupFuncUse, "Invalid use of group function")
 	ErrIllegalReference            = terror.ClassOptimizer.New(CodeIllegalReference, "Illegal reference")
+	ErrUnknownRule                 = terror.ClassOptimizer.New(CodeUnknownRule, "Unknown optimizer rule")
+	ErrWrongArguments              = terror.ClassOptimizer.New(CodeWrongArguments, "Incorrect arguments")
+	ErrWrongParamCount             = terror.ClassOptimizer.New(CodeWrongParamCount, "Incorrect parameter count")
+	ErrUnknownTableFunc            = terror.ClassOptimizer.New(CodeUnknownTableFunc, "Table function does not exist")
+	ErrDupFieldName                = terror.ClassOptimizer.New(CodeDupFieldName, "Duplicate column name")
+	ErrUnknownExplainFormat        = terror.ClassOptimizer.New(CodeUnknownExplainFormat, "Unknown EXPLAIN format name")
+	ErrViewInvalid                 = terror.ClassOptimizer.New(CodeViewInvalid, "View references invalid table(s) or column(s) or function(s)")
+	ErrViewRecursive               = terror.ClassOptimizer.New(CodeViewRecursive, "View contains view recursion")
+	ErrViewWrongList               = terror.ClassOptimizer.New(CodeViewWrongList, "SELECT list and column names list have different column counts")
+	ErrNonuniqTable                = terror.ClassOptimizer.New(CodeNonuniqTable, "Not unique table/alias")
+	ErrCartesianProductRejected    = terror.ClassOptimizer.New(CodeCartesianRejected, "Cartesian product is rejected")
+	ErrTooManyEstimatedRows        = terror.ClassOptimizer.New(CodeTooManyEstimatedRows, "Too many estimated rows")
+	ErrUnknownColumn               = terror.ClassOptimizer.New(CodeUnknownColumn, "Unknown column")
+	ErrAmbiguousColumn             = terror.ClassOptimizer.New(CodeAmbiguousColumn, "Column is ambiguous")
+	ErrFieldNotInGroupBy           = terror.ClassOptimizer.New(CodeFieldNotInGroupBy, "Expression is not in GROUP BY clause")
+	ErrMixOfGroupFuncAndFields     = terror.ClassOptimizer.New(CodeMixGroupFuncFields, "Mixing of aggregated and nonaggregated columns without GROUP BY")
+	ErrFieldInOrderNotSelect       = terror.ClassOptimizer.New(CodeOrderNotInSelect, "ORDER BY expression is not in SELECT list")
+	ErrUnknownTable                = terror.ClassOptimizer.New(CodeUnknownTable, "Unknown table")
+	ErrWrongUsage                  = terror.ClassOptimizer.New(CodeWrongUsage, "Incorrect usage")
+	ErrKeyDoesNotExist             = terror.ClassOptimizer.New(CodeKeyDoesNotExist, "Key doesn't exist in table")
+	ErrViewNoExplain               = terror.ClassOptimizer.New(CodeViewNoExplain, "EXPLAIN/SHOW can not be issued; lacking privileges for underlying table")
+	ErrSpecificAccessDenied        = terror.ClassOptimizer.New(CodeSpecificAccessDenied, "Access denied; you need (at least one of) the privilege(s) for this operation")
+	ErrInvalidDefault              = terror.ClassOptimizer.New(CodeInvalidDefault, "Invalid default value")
 )
 
 func init() {
 	mySQLErrCodes := map[terror.ErrCode]uint16{
-		CodeOneColumn:           mysql.ErrOperandColumns,
-		CodeSameColumns:         mysql.ErrOperandColumns,
-		CodeInvalidWildCard:     mysql.ErrParse,
-		CodeInvalidGroupFuncUse: mysql.ErrInvalidGroupFuncUse,
-		CodeIllegalReference:    mysql.ErrIllegalReference,
+		CodeOneColumn:            mysql.ErrOperandColumns,
+		CodeSameColumns:          mysql.ErrOperandColumns,
+		CodeInvalidWildCard:      mysql.ErrParse,
+		CodeInvalidGroupFuncUse:  mysql.ErrInvalidGroupFuncUse,
+		CodeIllegalReference:     mysql.ErrIllegalReference,
+		CodeWrongArguments:       mysql.ErrWrongArguments,
+		CodeWrongParamCount:      mysql.ErrWrongParamcountToNativeFct,
+		CodeUnknownTableFunc:     mysql.ErrSpDoesNotExist,
+		CodeDupFieldName:         mysql.ErrDupFieldName,
+		CodeUnknownExplainFormat: mysql.ErrUnknownExplainFormat,
+		CodeViewInvalid:          mysql.ErrViewInvalid,
+		CodeViewRecursive:        mysql.ErrViewRecursive,
+		CodeViewWrongList:        mysql.ErrViewWrongList,
+		CodeNonuniqTable:         mysql.ErrNonuniqTable,
+		CodeTooManyEstimatedRows: mysql.ErrTooBigSelect,
+		CodeUnknownColumn:        mysql.ErrBadField,
+		CodeAmbiguousColumn:      mysql.ErrNonUniq,
+		CodeFieldNotInGroupBy:    mysql.ErrWrongFieldWithGroup,
+		CodeMixGroupFuncFields:   mysql.ErrMixOfGroupFuncAndFields,
+		CodeOrderNotInSelect:     mysql.ErrFieldInOrderNotSelect,
+		CodeUnknownTable:         mysql.ErrUnknownTable,
+		CodeWrongUsage:           mysql.ErrWrongUsage,
+		CodeKeyDoesNotExist:      mysql.ErrKeyDoesNotExits,
+		CodeViewNoExplain:        mysql.ErrViewNoExplain,
+		CodeSpecificAccessDenied: mysql.ErrSpecificAccessDenied,
+		CodeInvalidDefault:       mysql.ErrInvalidDefault,
 	}
 	terror.ErrClassToMySQLCodes[terror.ClassOptimizer] = mySQLErrCodes
 }
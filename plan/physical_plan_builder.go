@@ -18,6 +18,7 @@ import (
 
 	"github.com/juju/errors"
 	"github.com/ngaut/log"
+	"github.com/pingcap/tidb/ast"
 	"github.com/pingcap/tidb/expression"
 	"github.com/pingcap/tidb/infoschema"
 	"github.com/pingcap/tidb/kv"
@@ -29,11 +30,9 @@ import (
 )
 
 const (
-	netWorkFactor   = 1.5
 	memoryFactor    = 5.0
 	selectionFactor = 0.8
 	distinctFactor  = 0.7
-	cpuFactor       = 0.9
 	aggFactor       = 0.1
 	joinFactor      = 0.3
 )
@@ -44,6 +43,14 @@ var JoinConcurrency = 5
 func getRowCountByIndexRanges(table *statistics.Table, indexRanges []*IndexRange, indexInfo *model.IndexInfo) (uint64, error) {
 	totalCount := float64(0)
 	for _, indexRange := range indexRanges {
+		ndvCount, ok, err1 := pointRowCountByIndexNDV(table, indexRange, indexInfo)
+		if err1 != nil {
+			return 0, errors.Trace(err1)
+		}
+		if ok {
+			totalCount += ndvCount
+			continue
+		}
 		count := float64(table.Count)
 		i := len(indexRange.LowVal) - 1
 		l := indexRange.LowVal[i]
@@ -79,10 +86,12 @@ func getRowCountByIndexRanges(table *statistics.Table, indexRanges []*IndexRange
 			return 0, errors.Trace(err)
 		}
 		count = count / float64(table.Count) * float64(rowCount)
-		// If the condition is a = 1, b = 1, c = 1, d = 1, we think every a=1, b=1, c=1 only filtrate 1/100 data,
-		// so as to avoid collapsing too fast.
 		for j := 0; j < i; j++ {
-			count = count / float64(100)
+			selectivity, err := prefixSelectivity(table, indexInfo.Columns[j].Offset, indexRange.LowVal[j])
+			if err != nil {
+				return 0, errors.Trace(err)
+			}
+			count = count * selectivity
 		}
 		totalCount += count
 	}
@@ -97,6 +106,43 @@ func getRowCountByIndexRanges(table *statistics.Table, indexRanges []*IndexRange
 	return uint64(totalCount), nil
 }
 
+// pointRowCountByIndexNDV estimates the row count of a point range on all the columns of the index by the NDV of
+// the index declared by the STATS_NDV table option. It's only used when the columns have no histogram.
+func pointRowCountByIndexNDV(table *statistics.Table, indexRange *IndexRange, indexInfo *model.IndexInfo) (float64, bool, error) {
+	if indexInfo.StatsNDV <= 0 || len(indexRange.LowVal) != len(indexInfo.Columns) {
+		return 0, false, nil
+	}
+	for i, idxCol := range indexInfo.Columns {
+		if len(table.Columns[idxCol.Offset].Numbers) > 0 {
+			return 0, false, nil
+		}
+		compare, err := indexRange.LowVal[i].CompareDatum(indexRange.HighVal[i])
+		if err != nil {
+			return 0, false, errors.Trace(err)
+		}
+		if compare != 0 {
+			return 0, false, nil
+		}
+	}
+	return float64(table.Count) / float64(indexInfo.StatsNDV), true, nil
+}
+
+// prefixSelectivity estimates the selectivity of the equal condition on a prefix column of an index range by the
+// histogram of the column. If the condition is a = 1, b = 1, c = 1, d = 1, we think every a=1, b=1, c=1 filtrates
+// at most 99/100 data so as to avoid collapsing too fast, and it's the selectivity used without histogram.
+func prefixSelectivity(table *statistics.Table, offset int, value types.Datum) (float64, error) {
+	const minSelectivity = 1.0 / 100
+	col := table.Columns[offset]
+	if len(col.Numbers) == 0 || table.Count == 0 {
+		return minSelectivity, nil
+	}
+	rowCount, err := col.EqualRowCount(value)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	return math.Min(math.Max(float64(rowCount)/float64(table.Count), minSelectivity), 1), nil
+}
+
 func getRowCountByTableRange(statsTbl *statistics.Table, ranges []TableRange, offset int) (uint64, error) {
 	var rowCount uint64
 	for _, rg := range ranges {
@@ -126,20 +172,17 @@ func getRowCountByTableRange(statsTbl *statistics.Table, ranges []TableRange, of
 	return rowCount, nil
 }
 
-func (p *DataSource) convert2TableScan(prop *requiredProperty) (*physicalPlanInfo, error) {
-	table := p.Table
-	client := p.ctx.GetClient()
+func (p *DataSource) newTableScan() (*PhysicalTableScan, error) {
 	txn, err := p.ctx.GetTxn(false)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
-	var resultPlan PhysicalPlan
 	ts := &PhysicalTableScan{
 		Table:               p.Table,
 		Columns:             p.Columns,
 		TableAsName:         p.TableAsName,
 		DBName:              p.DBName,
-		physicalTableSource: physicalTableSource{client: client},
+		physicalTableSource: physicalTableSource{client: p.ctx.GetClient()},
 	}
 	ts.tp = "TableScan"
 	ts.allocator = p.allocator
@@ -150,7 +193,17 @@ func (p *DataSource) convert2TableScan(prop *requiredProperty) (*physicalPlanInf
 		ts.readOnly = true
 	}
 	ts.SetSchema(p.GetSchema())
-	resultPlan = ts
+	return ts, nil
+}
+
+func (p *DataSource) convert2TableScan(prop *requiredProperty) (*physicalPlanInfo, error) {
+	table := p.Table
+	client := p.ctx.GetClient()
+	ts, err := p.newTableScan()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	var resultPlan PhysicalPlan = ts
 	if sel, ok := p.GetParentByIndex(0).(*Selection); ok {
 		newSel := *sel
 		conds := make([]expression.Expression, 0, len(sel.Conditions))
@@ -161,7 +214,7 @@ func (p *DataSource) convert2TableScan(prop *requiredProperty) (*physicalPlanInf
 		if client != nil {
 			memDB := infoschema.IsMemoryDB(p.DBName.L)
 			if !memDB && client.SupportRequestType(kv.ReqTypeSelect, 0) {
-				ts.ConditionPBExpr, ts.conditions, newSel.Conditions = expressionsToPB(newSel.Conditions, client)
+				ts.ConditionPBExpr, ts.conditions, newSel.Conditions = ExpressionsToPB(newSel.Conditions, client)
 			}
 		}
 		err := buildTableRange(ts)
@@ -203,10 +256,7 @@ func (p *DataSource) convert2TableScan(prop *requiredProperty) (*physicalPlanInf
 	return resultPlan.matchProperty(prop, &physicalPlanInfo{count: rowCount}), nil
 }
 
-func (p *DataSource) convert2IndexScan(prop *requiredProperty, index *model.IndexInfo) (*physicalPlanInfo, error) {
-	statsTbl := p.statisticTable
-	var resultPlan PhysicalPlan
-	client := p.ctx.GetClient()
+func (p *DataSource) newIndexScan(index *model.IndexInfo) (*PhysicalIndexScan, error) {
 	txn, err := p.ctx.GetTxn(false)
 	if err != nil {
 		return nil, errors.Trace(err)
@@ -218,7 +268,7 @@ func (p *DataSource) convert2IndexScan(prop *requiredProperty, index *model.Inde
 		TableAsName:         p.TableAsName,
 		OutOfOrder:          true,
 		DBName:              p.DBName,
-		physicalTableSource: physicalTableSource{client: client},
+		physicalTableSource: physicalTableSource{client: p.ctx.GetClient()},
 	}
 	is.tp = "IndexScan"
 	is.allocator = p.allocator
@@ -229,6 +279,17 @@ func (p *DataSource) convert2IndexScan(prop *requiredProperty, index *model.Inde
 		is.readOnly = true
 	}
 	is.SetSchema(p.schema)
+	return is, nil
+}
+
+func (p *DataSource) convert2IndexScan(prop *requiredProperty, index *model.IndexInfo) (*physicalPlanInfo, error) {
+	statsTbl := p.statisticTable
+	var resultPlan PhysicalPlan
+	client := p.ctx.GetClient()
+	is, err := p.newIndexScan(index)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
 	rowCount := uint64(statsTbl.Count)
 	resultPlan = is
 	if sel, ok := p.GetParentByIndex(0).(*Selection); ok {
@@ -241,7 +302,7 @@ func (p *DataSource) convert2IndexScan(prop *requiredProperty, index *model.Inde
 		if client != nil {
 			memDB := infoschema.IsMemoryDB(p.DBName.L)
 			if !memDB && client.SupportRequestType(kv.ReqTypeIndex, 0) {
-				is.ConditionPBExpr, is.conditions, newSel.Conditions = expressionsToPB(newSel.Conditions, client)
+				is.ConditionPBExpr, is.conditions, newSel.Conditions = ExpressionsToPB(newSel.Conditions, client)
 			}
 		}
 		err := buildIndexRange(is)
@@ -268,6 +329,129 @@ func (p *DataSource) convert2IndexScan(prop *requiredProperty, index *model.Inde
 	return resultPlan.matchProperty(prop, &physicalPlanInfo{count: rowCount}), nil
 }
 
+// convert2IndexMerge tries to read the rows that satisfy a DNF condition like "a = 1 or b = 2" by the union of the
+// handles read from an index for every DNF item, so a table lookup can replace the table scan even if there is no
+// single index that covers all the items. It returns nil if there is no such condition.
+func (p *DataSource) convert2IndexMerge(prop *requiredProperty, indices []*model.IndexInfo) (*physicalPlanInfo, error) {
+	sel, ok := p.GetParentByIndex(0).(*Selection)
+	if !ok {
+		return nil, nil
+	}
+	var partialPlans []*PhysicalIndexScan
+	var handleCount uint64
+	for _, cond := range sel.Conditions {
+		f, ok := cond.(*expression.ScalarFunction)
+		if !ok || f.FuncName.L != ast.OrOr {
+			continue
+		}
+		partials, count, err := p.buildPartialIndexScans(expression.SplitDNFItems(f), indices)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if partials != nil && (partialPlans == nil || count < handleCount) {
+			partialPlans, handleCount = partials, count
+		}
+	}
+	if partialPlans == nil {
+		return nil, nil
+	}
+	client := p.ctx.GetClient()
+	txn, err := p.ctx.GetTxn(false)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	im := &PhysicalIndexMerge{
+		PartialPlans:        partialPlans,
+		Table:               p.Table,
+		Columns:             p.Columns,
+		TableAsName:         p.TableAsName,
+		DBName:              p.DBName,
+		physicalTableSource: physicalTableSource{client: client},
+	}
+	im.tp = IdxMerge
+	im.allocator = p.allocator
+	im.initID()
+	if txn != nil {
+		im.readOnly = txn.IsReadOnly()
+	} else {
+		im.readOnly = true
+	}
+	im.SetSchema(p.schema)
+	// The index ranges may cover more rows than the DNF items, so all the conditions are checked again.
+	newSel := *sel
+	newSel.Conditions = make([]expression.Expression, 0, len(sel.Conditions))
+	for _, cond := range sel.Conditions {
+		newSel.Conditions = append(newSel.Conditions, cond.Clone())
+	}
+	if client != nil {
+		memDB := infoschema.IsMemoryDB(p.DBName.L)
+		if !memDB && client.SupportRequestType(kv.ReqTypeSelect, 0) {
+			im.ConditionPBExpr, im.conditions, newSel.Conditions = ExpressionsToPB(newSel.Conditions, client)
+		}
+	}
+	var resultPlan PhysicalPlan = im
+	if len(newSel.Conditions) > 0 {
+		newSel.SetChildren(im)
+		newSel.onTable = true
+		resultPlan = &newSel
+	}
+	rowCount := handleCount
+	if rowCount > uint64(p.statisticTable.Count) {
+		rowCount = uint64(p.statisticTable.Count)
+	}
+	if im.ConditionPBExpr != nil {
+		rowCount = uint64(float64(rowCount) * selectionFactor)
+	}
+	return resultPlan.matchProperty(prop, &physicalPlanInfo{count: rowCount}), nil
+}
+
+// buildPartialIndexScans builds the index scan with the lowest row count for every DNF item. It returns nil if one
+// of the items can't be used to build the range of any index.
+func (p *DataSource) buildPartialIndexScans(items []expression.Expression, indices []*model.IndexInfo) ([]*PhysicalIndexScan, uint64, error) {
+	partials := make([]*PhysicalIndexScan, 0, len(items))
+	var totalCount uint64
+	for _, item := range items {
+		var best *PhysicalIndexScan
+		for _, index := range indices {
+			is, err := p.newIndexScan(index)
+			if err != nil {
+				return nil, 0, errors.Trace(err)
+			}
+			var conds []expression.Expression
+			for _, cond := range expression.SplitCNFItems(item) {
+				conds = append(conds, cond.Clone())
+			}
+			is.AccessCondition, _ = detachIndexScanConditions(conds, is)
+			if len(is.AccessCondition) == 0 {
+				continue
+			}
+			err = buildIndexRange(is)
+			if err != nil {
+				if !terror.ErrorEqual(err, types.ErrTruncated) {
+					return nil, 0, errors.Trace(err)
+				}
+				log.Warn("truncate error in buildIndexRange")
+			}
+			count, err := getRowCountByIndexRanges(p.statisticTable, is.Ranges, is.Index)
+			if err != nil {
+				return nil, 0, errors.Trace(err)
+			}
+			if best == nil || count < best.count {
+				// Only the handles are read from the index.
+				is.DoubleRead = true
+				is.setStats(count, float64(count)*p.allocator.costFactors().scanRow())
+				best = is
+			}
+		}
+		if best == nil {
+			return nil, 0, nil
+		}
+		partials = append(partials, best)
+		totalCount += best.count
+	}
+	return partials, totalCount, nil
+}
+
 func isCoveringIndex(columns []*model.ColumnInfo, indexColumns []*model.IndexColumn, pkIsHandle bool) bool {
 	for _, colInfo := range columns {
 		if pkIsHandle && mysql.HasPriKeyFlag(colInfo.Flag) {
@@ -304,22 +488,33 @@ func (p *DataSource) convert2PhysicalPlan(prop *requiredProperty) (*physicalPlan
 	if info != nil || err != nil {
 		return info, errors.Trace(err)
 	}
-	indices, includeTableScan := availableIndices(p.table)
+	indices, includeTableScan := availableIndices(p.indexHints, p.Table)
 	if includeTableScan {
 		info, err = p.convert2TableScan(prop)
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
 	}
-	for _, index := range indices {
+	// The index merge reads the indices by the DNF items, so it still considers all the indices.
+	for _, index := range p.skylinePruning(prop, indices, includeTableScan) {
 		indexInfo, err := p.convert2IndexScan(prop, index)
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
 		if info == nil || indexInfo.cost < info.cost {
 			info = indexInfo
+		} else {
+			log.Debugf("[PLAN] index %s of table %s is rejected, its cost %v isn't lower than %v",
+				index.Name.O, p.Table.Name.O, indexInfo.cost, info.cost)
 		}
 	}
+	mergeInfo, err := p.convert2IndexMerge(prop, indices)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if mergeInfo != nil && (info == nil || mergeInfo.cost < info.cost) {
+		info = mergeInfo
+	}
 	return info, errors.Trace(p.storePlanInfo(prop, info))
 }
 
@@ -350,19 +545,117 @@ func (p *DataSource) tryToConvert2DummyScan(prop *requiredProperty) (*physicalPl
 	return nil, nil
 }
 
+// convert2PhysicalPlan implements the LogicalPlan convert2PhysicalPlan interface.
+// The series is generated in the order of its step, so an ordering on its column in the same direction
+// needs no extra sort.
+func (p *GenerateSeries) convert2PhysicalPlan(prop *requiredProperty) (*physicalPlanInfo, error) {
+	info, err := p.getPlanInfo(prop)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if info != nil {
+		return info, nil
+	}
+	count := p.rowCount()
+	info = &physicalPlanInfo{p: p, count: count, cost: float64(count) * p.allocator.costFactors().cpu}
+	if len(prop.props) == 1 && prop.props[0].col.Equal(p.schema[0]) && prop.props[0].desc == (p.Step < 0) {
+		info = enforceProperty(&requiredProperty{limit: prop.limit}, info)
+	} else {
+		info = enforceProperty(prop, info)
+	}
+	return info, errors.Trace(p.storePlanInfo(prop, info))
+}
+
+// convert2PhysicalPlan implements the LogicalPlan convert2PhysicalPlan interface.
+func (p *JSONTable) convert2PhysicalPlan(prop *requiredProperty) (*physicalPlanInfo, error) {
+	info, err := p.getPlanInfo(prop)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if info != nil {
+		return info, nil
+	}
+	info = &physicalPlanInfo{p: p, count: jsonTableRowCount, cost: jsonTableRowCount * p.allocator.costFactors().cpu}
+	info = enforceProperty(prop, info)
+	return info, errors.Trace(p.storePlanInfo(prop, info))
+}
+
+// convert2PhysicalPlan implements the LogicalPlan convert2PhysicalPlan interface.
+func (p *Profile) convert2PhysicalPlan(prop *requiredProperty) (*physicalPlanInfo, error) {
+	info, err := p.getPlanInfo(prop)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if info != nil {
+		return info, nil
+	}
+	info = &physicalPlanInfo{p: p, count: profileRowCount, cost: profileRowCount * p.allocator.costFactors().cpu}
+	info = enforceProperty(prop, info)
+	return info, errors.Trace(p.storePlanInfo(prop, info))
+}
+
+// convert2PhysicalPlan implements the LogicalPlan convert2PhysicalPlan interface.
+func (p *RowHistory) convert2PhysicalPlan(prop *requiredProperty) (*physicalPlanInfo, error) {
+	info, err := p.getPlanInfo(prop)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if info != nil {
+		return info, nil
+	}
+	info = &physicalPlanInfo{p: p, count: rowHistoryRowCount, cost: rowHistoryRowCount * p.allocator.costFactors().cpu}
+	info = enforceProperty(prop, info)
+	return info, errors.Trace(p.storePlanInfo(prop, info))
+}
+
+// convert2PhysicalPlan implements the LogicalPlan convert2PhysicalPlan interface.
+// The source of the CTE is optimized by the first reference, and its cost is not counted by the references
+// because the rows are produced only once.
+func (p *CTE) convert2PhysicalPlan(prop *requiredProperty) (*physicalPlanInfo, error) {
+	info, err := p.getPlanInfo(prop)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if info != nil {
+		return info, nil
+	}
+	src := p.Source
+	if src.Plan == nil {
+		src.Plan, err = doOptimize(src.logic, src.ctx, p.allocator, src.disabled)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		p.Producer = true
+	}
+	count := uint64(estimateRowCount(src.logic))
+	info = &physicalPlanInfo{p: p, count: count, cost: float64(count) * p.allocator.costFactors().cpu}
+	info = enforceProperty(prop, info)
+	return info, errors.Trace(p.storePlanInfo(prop, info))
+}
+
 // addPlanToResponse creates a *physicalPlanInfo that adds p as the parent of info.
 func addPlanToResponse(parent PhysicalPlan, info *physicalPlanInfo) *physicalPlanInfo {
+	setEstimatedStats(info)
 	np := parent.Copy()
 	np.SetChildren(info.p)
 	return &physicalPlanInfo{p: np, cost: info.cost, count: info.count}
 }
 
+// setEstimatedStats records the row count and the cost of info on its plan. It's called when the plan is stored or
+// becomes a child of another plan, then they are final.
+func setEstimatedStats(info *physicalPlanInfo) {
+	if info.p != nil {
+		info.p.setStats(info.count, info.cost)
+	}
+}
+
 // enforceProperty creates a *physicalPlanInfo that satisfies the required property by adding
 // sort or limit as the parent of the given physical plan.
 func enforceProperty(prop *requiredProperty, info *physicalPlanInfo) *physicalPlanInfo {
 	if info.p == nil {
 		return info
 	}
+	factors := info.p.costFactors()
 	if len(prop.props) != 0 {
 		items := make([]*ByItems, 0, len(prop.props))
 		for _, col := range prop.props {
@@ -379,7 +672,7 @@ func enforceProperty(prop *requiredProperty, info *physicalPlanInfo) *physicalPl
 		if prop.limit != nil {
 			count = prop.limit.Offset + prop.limit.Count
 		}
-		info.cost += sortCost(count)
+		info.cost += sortCost(factors, count)
 	} else if prop.limit != nil {
 		limit := prop.limit.Copy().(*Limit)
 		limit.SetSchema(info.p.GetSchema())
@@ -392,12 +685,12 @@ func enforceProperty(prop *requiredProperty, info *physicalPlanInfo) *physicalPl
 	return info
 }
 
-func sortCost(cnt uint64) float64 {
+func sortCost(factors *costFactors, cnt uint64) float64 {
 	if cnt == 0 {
 		// If cnt is 0, the log(cnt) will be NAN.
 		return 0.0
 	}
-	return float64(cnt)*math.Log2(float64(cnt))*cpuFactor + memoryFactor*float64(cnt)
+	return float64(cnt)*math.Log2(float64(cnt))*factors.cpu + memoryFactor*float64(cnt)
 }
 
 // removeLimit removes the limit from prop.
@@ -462,6 +755,7 @@ func (p *Join) convert2PhysicalPlanSemi(prop *requiredProperty) (*physicalPlanIn
 		RightConditions: p.RightConditions,
 		OtherConditions: p.OtherConditions,
 		Anti:            p.anti,
+		NullAware:       p.nullAware,
 	}
 	join.tp = "HashSemiJoin"
 	join.allocator = p.allocator
@@ -646,6 +940,264 @@ func (p *Join) convert2PhysicalPlanRight(prop *requiredProperty, innerJoin bool)
 	return resultInfo, nil
 }
 
+// convert2PhysicalPlanMerge converts the join to the sort merge join *physicalPlanInfo, both children are required
+// to be ordered by the join keys. Sorting a huge child costs more than building a hash table on it, so the children
+// must be ordered by themselves, e.g. by scanning the indices on the join keys, unless the MERGE_JOIN hint is written.
+// It returns an info without plan if the join can't be converted.
+func (p *Join) convert2PhysicalPlanMerge(prop *requiredProperty) (*physicalPlanInfo, error) {
+	noPlan := &physicalPlanInfo{cost: math.MaxFloat64}
+	if len(p.EqualConditions) == 0 {
+		return noPlan, nil
+	}
+	lChild := p.GetChildByIndex(0).(LogicalPlan)
+	rChild := p.GetChildByIndex(1).(LogicalPlan)
+	lKeys := make([]*expression.Column, 0, len(p.EqualConditions))
+	rKeys := make([]*expression.Column, 0, len(p.EqualConditions))
+	for _, eqCond := range p.EqualConditions {
+		lKey, rKey := eqCond.Args[0].(*expression.Column), eqCond.Args[1].(*expression.Column)
+		// The keys are compared without conversion, so they must be ordered in the same way.
+		class := resultTypeClass(lKey.GetType())
+		if class == resultClassUnknown || class != resultTypeClass(rKey.GetType()) {
+			return noPlan, nil
+		}
+		lKeys = append(lKeys, lKey)
+		rKeys = append(rKeys, rKey)
+	}
+	hinted := p.preferMergeJoin()
+	lInfo, err := convert2OrderedPhysicalPlan(lChild, lKeys, hinted)
+	if err != nil || lInfo.p == nil {
+		return noPlan, errors.Trace(err)
+	}
+	rInfo, err := convert2OrderedPhysicalPlan(rChild, rKeys, hinted)
+	if err != nil || rInfo.p == nil {
+		return noPlan, errors.Trace(err)
+	}
+	join := &PhysicalMergeJoin{
+		JoinType:        p.JoinType,
+		EqualConditions: p.EqualConditions,
+		LeftConditions:  p.LeftConditions,
+		RightConditions: p.RightConditions,
+		OtherConditions: p.OtherConditions,
+		DefaultValues:   p.DefaultValues,
+	}
+	join.tp = "MergeJoin"
+	join.allocator = p.allocator
+	join.initID()
+	join.SetSchema(p.schema)
+	join.correlated = p.IsCorrelated() || lInfo.p.IsCorrelated() || rInfo.p.IsCorrelated()
+	resultInfo := join.matchProperty(prop, lInfo, rInfo)
+	// The rows are output in the order of the keys of the outer child, and of the keys of both children for
+	// the inner join.
+	var outerKeys, innerKeys []*expression.Column
+	switch p.JoinType {
+	case LeftOuterJoin:
+		outerKeys = lKeys
+	case RightOuterJoin:
+		outerKeys = rKeys
+	default:
+		outerKeys, innerKeys = lKeys, rKeys
+	}
+	if matchJoinKeys(prop, outerKeys, innerKeys) {
+		return enforceProperty(limitProperty(prop.limit), resultInfo), nil
+	}
+	return enforceProperty(prop, resultInfo), nil
+}
+
+// convert2OrderedPhysicalPlan converts p to the *physicalPlanInfo ordered by the keys. Without force, it returns
+// an info without plan if p can't be ordered without a sort.
+func convert2OrderedPhysicalPlan(p LogicalPlan, keys []*expression.Column, force bool) (*physicalPlanInfo, error) {
+	prop := &requiredProperty{props: make([]*columnProp, 0, len(keys)), sortKeyLen: len(keys)}
+	for _, key := range keys {
+		prop.props = append(prop.props, &columnProp{col: key})
+	}
+	prop = replaceColsInPropBySchema(prop, p.GetSchema())
+	info, err := p.convert2PhysicalPlan(prop)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if info.p != nil && !hasEnforcedSort(info.p) {
+		return info, nil
+	}
+	if !force {
+		return &physicalPlanInfo{cost: math.MaxFloat64}, nil
+	}
+	if info.p != nil {
+		return info, nil
+	}
+	info, err = p.convert2PhysicalPlan(&requiredProperty{})
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return enforceProperty(prop, info), nil
+}
+
+// hasEnforcedSort checks whether the order of p is made by a sort, instead of the order in which the rows are
+// read. Only the operators with a single child are walked through, since they keep the order of their children.
+func hasEnforcedSort(p Plan) bool {
+	for p != nil {
+		if _, ok := p.(*Sort); ok {
+			return true
+		}
+		if len(p.GetChildren()) != 1 {
+			return false
+		}
+		p = p.GetChildByIndex(0)
+	}
+	return false
+}
+
+// matchJoinKeys checks whether the rows ordered by the join keys satisfy the order of the required property.
+func matchJoinKeys(prop *requiredProperty, outerKeys, innerKeys []*expression.Column) bool {
+	if len(prop.props) > len(outerKeys) {
+		return false
+	}
+	for i, col := range prop.props {
+		if col.desc {
+			return false
+		}
+		if !col.col.Equal(outerKeys[i]) && (innerKeys == nil || !col.col.Equal(innerKeys[i])) {
+			return false
+		}
+	}
+	return true
+}
+
+// tryToConvert2MergeJoin returns the sort merge join *physicalPlanInfo if it's preferred to the hash join one.
+func (p *Join) tryToConvert2MergeJoin(prop *requiredProperty, hashInfo *physicalPlanInfo) (*physicalPlanInfo, error) {
+	if p.preferHashJoin() {
+		return hashInfo, nil
+	}
+	mergeInfo, err := p.convert2PhysicalPlanMerge(prop)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if mergeInfo.p != nil && (p.preferMergeJoin() || mergeInfo.cost < hashInfo.cost) {
+		return mergeInfo, nil
+	}
+	return hashInfo, nil
+}
+
+// convert2PhysicalPlanIndex converts the join to the index nested loop join *physicalPlanInfo whose outer child is
+// the child of outerIdx. The inner child must read a table that can be looked up by one of the join keys.
+// It returns an info without plan if the join can't be converted.
+func (p *Join) convert2PhysicalPlanIndex(prop *requiredProperty, outerIdx int) (*physicalPlanInfo, error) {
+	noPlan := &physicalPlanInfo{cost: math.MaxFloat64}
+	outerChild := p.GetChildByIndex(outerIdx).(LogicalPlan)
+	innerChild := p.GetChildByIndex(1 - outerIdx).(LogicalPlan)
+	ds := getLookUpDataSource(innerChild)
+	if ds == nil {
+		return noPlan, nil
+	}
+	var innerInfo *physicalPlanInfo
+	eqConds := make([]*expression.ScalarFunction, 0, len(p.EqualConditions))
+	for i, eqCond := range p.EqualConditions {
+		outerKey, innerKey := eqCond.Args[outerIdx].(*expression.Column), eqCond.Args[1-outerIdx].(*expression.Column)
+		// The outer keys are converted to the type of the inner key to build the ranges, so they must be
+		// ordered in the same way.
+		class := resultTypeClass(innerKey.GetType())
+		if class == resultClassUnknown || class != resultTypeClass(outerKey.GetType()) {
+			continue
+		}
+		info, err := ds.convert2LookUpScan(innerKey)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if info != nil && info.p != nil {
+			innerInfo = info
+			// The inner child is looked up by the first equal condition.
+			eqConds = append(eqConds, eqCond)
+			eqConds = append(eqConds, p.EqualConditions[:i]...)
+			eqConds = append(eqConds, p.EqualConditions[i+1:]...)
+			break
+		}
+	}
+	if innerInfo == nil {
+		return noPlan, nil
+	}
+	allOuter := true
+	for _, col := range prop.props {
+		if outerChild.GetSchema().GetIndex(col.col) == -1 {
+			allOuter = false
+		}
+	}
+	outerProp := &requiredProperty{}
+	if allOuter {
+		outerProp = replaceColsInPropBySchema(prop, outerChild.GetSchema())
+	}
+	if p.JoinType == InnerJoin {
+		outerProp = removeLimit(outerProp)
+	} else {
+		outerProp = convertLimitOffsetToCount(outerProp)
+	}
+	outerInfo, err := outerChild.convert2PhysicalPlan(outerProp)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if outerInfo.p == nil {
+		return noPlan, nil
+	}
+	join := &PhysicalIndexJoin{
+		JoinType:        p.JoinType,
+		OuterIndex:      outerIdx,
+		EqualConditions: eqConds,
+		LeftConditions:  p.LeftConditions,
+		RightConditions: p.RightConditions,
+		OtherConditions: p.OtherConditions,
+		DefaultValues:   p.DefaultValues,
+	}
+	join.tp = "IndexJoin"
+	join.allocator = p.allocator
+	join.initID()
+	join.SetSchema(p.schema)
+	join.correlated = p.IsCorrelated() || outerInfo.p.IsCorrelated() || innerInfo.p.IsCorrelated()
+	var resultInfo *physicalPlanInfo
+	if outerIdx == 0 {
+		resultInfo = join.matchProperty(prop, outerInfo, innerInfo)
+	} else {
+		resultInfo = join.matchProperty(prop, innerInfo, outerInfo)
+	}
+	// The rows are output in the order of the outer child.
+	if allOuter {
+		return enforceProperty(limitProperty(prop.limit), resultInfo), nil
+	}
+	return enforceProperty(prop, resultInfo), nil
+}
+
+// tryToConvert2IndexJoin returns the index nested loop join *physicalPlanInfo if it's preferred to the given one.
+// The INL_JOIN hint makes the join look up the hinted child regardless of the cost.
+func (p *Join) tryToConvert2IndexJoin(prop *requiredProperty, info *physicalPlanInfo) (*physicalPlanInfo, error) {
+	if len(p.EqualConditions) == 0 || p.preferHashJoin() || p.preferMergeJoin() {
+		return info, nil
+	}
+	var outerIdxs []int
+	switch p.JoinType {
+	case LeftOuterJoin:
+		outerIdxs = []int{0}
+	case RightOuterJoin:
+		outerIdxs = []int{1}
+	default:
+		outerIdxs = []int{0, 1}
+		if p.straightJoin {
+			outerIdxs = []int{0}
+		}
+	}
+	hinted := false
+	for _, outerIdx := range outerIdxs {
+		indexInfo, err := p.convert2PhysicalPlanIndex(prop, outerIdx)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if indexInfo.p == nil {
+			continue
+		}
+		preferred := p.preferINLJoin(1 - outerIdx)
+		if (preferred && !hinted) || (preferred == hinted && indexInfo.cost < info.cost) {
+			info, hinted = indexInfo, preferred
+		}
+	}
+	return info, nil
+}
+
 // convert2PhysicalPlan implements the LogicalPlan convert2PhysicalPlan interface.
 func (p *Join) convert2PhysicalPlan(prop *requiredProperty) (*physicalPlanInfo, error) {
 	info, err := p.getPlanInfo(prop)
@@ -666,11 +1218,27 @@ func (p *Join) convert2PhysicalPlan(prop *requiredProperty) (*physicalPlanInfo,
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
+		info, err = p.tryToConvert2MergeJoin(prop, info)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		info, err = p.tryToConvert2IndexJoin(prop, info)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
 	case RightOuterJoin:
 		info, err = p.convert2PhysicalPlanRight(prop, false)
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
+		info, err = p.tryToConvert2MergeJoin(prop, info)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		info, err = p.tryToConvert2IndexJoin(prop, info)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
 	default:
 		lInfo, err := p.convert2PhysicalPlanLeft(prop, true)
 		if err != nil {
@@ -680,10 +1248,26 @@ func (p *Join) convert2PhysicalPlan(prop *requiredProperty) (*physicalPlanInfo,
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
-		if rInfo.cost < lInfo.cost {
+		// The left hash join builds the hash table on the right child, and the right one on the left child.
+		switch p.getHashJoinBuildSide() {
+		case 0:
 			info = rInfo
-		} else {
+		case 1:
 			info = lInfo
+		default:
+			if rInfo.cost < lInfo.cost {
+				info = rInfo
+			} else {
+				info = lInfo
+			}
+		}
+		info, err = p.tryToConvert2MergeJoin(prop, info)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		info, err = p.tryToConvert2IndexJoin(prop, info)
+		if err != nil {
+			return nil, errors.Trace(err)
 		}
 	}
 	p.storePlanInfo(prop, info)
@@ -708,7 +1292,6 @@ func (p *Aggregation) convert2PhysicalPlanStream(prop *requiredProperty) (*physi
 	agg.correlated = p.IsCorrelated()
 	agg.HasGby = len(p.GroupByItems) > 0
 	agg.SetSchema(p.schema)
-	// TODO: Consider distinct key.
 	info := &physicalPlanInfo{cost: math.MaxFloat64}
 	gbyCols := p.groupByCols
 	if len(gbyCols) != len(p.GroupByItems) {
@@ -734,16 +1317,61 @@ func (p *Aggregation) convert2PhysicalPlanStream(prop *requiredProperty) (*physi
 			newProp.props = append(newProp.props, &columnProp{col: col})
 		}
 	}
-	childInfo, err := p.children[0].(LogicalPlan).convert2PhysicalPlan(newProp)
+	child := p.children[0].(LogicalPlan)
+	childInfo, err := child.convert2PhysicalPlan(newProp)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
+	// If the input can also be sorted by the argument of the distinct aggregate functions in every group,
+	// we can check the distinct values by comparing with the previous one instead of remembering all of them.
+	if distinctCol := p.getDistinctArgCol(); distinctCol != nil {
+		sortedProp := &requiredProperty{
+			props: make([]*columnProp, 0, len(newProp.props)+1),
+		}
+		sortedProp.props = append(sortedProp.props, newProp.props...)
+		desc := newProp.sortKeyLen > 0 && newProp.props[0].desc
+		sortedProp.props = append(sortedProp.props, &columnProp{col: distinctCol, desc: desc})
+		sortedProp.sortKeyLen = len(sortedProp.props)
+		sortedInfo, err := child.convert2PhysicalPlan(sortedProp)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if sortedInfo.cost <= childInfo.cost {
+			childInfo = sortedInfo
+			agg.DistinctSorted = true
+		}
+	}
 	info = addPlanToResponse(agg, childInfo)
-	info.cost += float64(info.count) * cpuFactor
+	info.cost += float64(info.count) * p.allocator.costFactors().cpu
 	info.count = uint64(float64(info.count) * aggFactor)
 	return info, nil
 }
 
+// getDistinctArgCol returns the column which is the only argument of all the distinct aggregate functions.
+// It returns nil if there is no distinct aggregate function, or their arguments are not the same column,
+// or the column is a group-by column.
+func (p *Aggregation) getDistinctArgCol() *expression.Column {
+	var distinctCol *expression.Column
+	for _, fun := range p.AggFuncs {
+		if !fun.IsDistinct() {
+			continue
+		}
+		args := fun.GetArgs()
+		if len(args) != 1 {
+			return nil
+		}
+		col, ok := args[0].(*expression.Column)
+		if !ok || (distinctCol != nil && !distinctCol.Equal(col)) {
+			return nil
+		}
+		distinctCol = col
+	}
+	if distinctCol != nil && expression.Schema(p.groupByCols).GetIndex(distinctCol) != -1 {
+		return nil
+	}
+	return distinctCol
+}
+
 // convert2PhysicalPlanFinalHash converts the logical aggregation to the final hash aggregation *physicalPlanInfo.
 func (p *Aggregation) convert2PhysicalPlanFinalHash(x physicalDistSQLPlan, childInfo *physicalPlanInfo) *physicalPlanInfo {
 	agg := &PhysicalAggregation{
@@ -788,6 +1416,107 @@ func (p *Aggregation) convert2PhysicalPlanCompleteHash(childInfo *physicalPlanIn
 	return info
 }
 
+// getDistinctPushDownCols returns the group-by columns and the argument of the distinct aggregate functions, if all
+// the aggregate functions can be computed on the distinct rows of them. It returns nil otherwise.
+func (p *Aggregation) getDistinctPushDownCols() []*expression.Column {
+	distinctCol := p.getDistinctArgCol()
+	if distinctCol == nil || len(p.groupByCols) != len(p.GroupByItems) {
+		return nil
+	}
+	cols := make([]*expression.Column, 0, len(p.groupByCols)+1)
+	for _, col := range p.groupByCols {
+		if expression.Schema(cols).GetIndex(col) == -1 {
+			cols = append(cols, col)
+		}
+	}
+	cols = append(cols, distinctCol)
+	for _, fun := range p.AggFuncs {
+		if fun.IsDistinct() {
+			continue
+		}
+		// The duplicated rows don't change the result of firstrow, max and min on these columns.
+		switch fun.GetName() {
+		case ast.AggFuncFirstRow, ast.AggFuncMax, ast.AggFuncMin:
+		default:
+			return nil
+		}
+		col, ok := fun.GetArgs()[0].(*expression.Column)
+		if !ok || expression.Schema(cols).GetIndex(col) == -1 {
+			return nil
+		}
+	}
+	return cols
+}
+
+// convert2PhysicalPlanDistinctHash converts the logical aggregation with distinct aggregate functions to a complete
+// hash aggregation over the distinct rows of the group-by columns and the distinct argument. The distinct rows are
+// collected by every region in the coprocessor and merged by a final hash aggregation, so much fewer rows are read
+// than the complete aggregation over the whole table. It returns nil if the aggregation can't be pushed down.
+func (p *Aggregation) convert2PhysicalPlanDistinctHash(x physicalDistSQLPlan, childInfo *physicalPlanInfo) *physicalPlanInfo {
+	cols := p.getDistinctPushDownCols()
+	if cols == nil {
+		return nil
+	}
+	partialAgg := &PhysicalAggregation{
+		AggType:      FinalAgg,
+		AggFuncs:     make([]expression.AggregationFunction, 0, len(cols)),
+		GroupByItems: make([]expression.Expression, 0, len(cols)),
+		HasGby:       true,
+	}
+	partialAgg.tp = "HashAgg"
+	partialAgg.allocator = p.allocator
+	partialAgg.initID()
+	partialAgg.correlated = p.IsCorrelated()
+	partialSchema := make(expression.Schema, 0, len(cols))
+	for i, col := range cols {
+		partialAgg.AggFuncs = append(partialAgg.AggFuncs, expression.NewAggFunction(ast.AggFuncFirstRow, []expression.Expression{col}, false))
+		partialAgg.GroupByItems = append(partialAgg.GroupByItems, col)
+		partialSchema = append(partialSchema, &expression.Column{
+			FromID:   partialAgg.id,
+			ColName:  col.ColName,
+			TblName:  col.TblName,
+			DBName:   col.DBName,
+			RetType:  col.RetType,
+			Position: i,
+			Index:    i,
+		})
+	}
+	partialAgg.SetSchema(partialSchema)
+	schema := x.addAggregation(partialAgg)
+	if len(schema) == 0 {
+		return nil
+	}
+	x.(PhysicalPlan).SetSchema(schema)
+	info := addPlanToResponse(partialAgg, childInfo)
+	info.count = uint64(float64(info.count) * distinctFactor)
+
+	// The arguments and the group-by items of the complete aggregation refer to the output of the final aggregation.
+	agg := &PhysicalAggregation{
+		AggType:      CompleteAgg,
+		AggFuncs:     make([]expression.AggregationFunction, 0, len(p.AggFuncs)),
+		GroupByItems: make([]expression.Expression, 0, len(p.groupByCols)),
+	}
+	agg.tp = "HashAgg"
+	agg.allocator = p.allocator
+	agg.initID()
+	agg.correlated = p.IsCorrelated()
+	agg.HasGby = len(p.GroupByItems) > 0
+	agg.SetSchema(p.schema)
+	for _, fun := range p.AggFuncs {
+		newFun := fun.Clone()
+		newFun.SetArgs([]expression.Expression{partialSchema[expression.Schema(cols).GetIndex(fun.GetArgs()[0].(*expression.Column))]})
+		agg.AggFuncs = append(agg.AggFuncs, newFun)
+	}
+	for _, col := range p.groupByCols {
+		agg.GroupByItems = append(agg.GroupByItems, partialSchema[expression.Schema(cols).GetIndex(col)])
+	}
+	info = addPlanToResponse(agg, info)
+	info.count = uint64(float64(info.count) * aggFactor)
+	// The child plan has been changed to do the aggregation, so it must be the best plan.
+	info.cost = 0
+	return info
+}
+
 // convert2PhysicalPlanHash converts the logical aggregation to the physical hash aggregation.
 func (p *Aggregation) convert2PhysicalPlanHash() (*physicalPlanInfo, error) {
 	childInfo, err := p.children[0].(LogicalPlan).convert2PhysicalPlan(&requiredProperty{})
@@ -801,12 +1530,15 @@ func (p *Aggregation) convert2PhysicalPlanHash() (*physicalPlanInfo, error) {
 			break
 		}
 	}
-	if !distinct {
-		if x, ok := childInfo.p.(physicalDistSQLPlan); ok {
-			info := p.convert2PhysicalPlanFinalHash(x, childInfo)
-			if info != nil {
-				return info, nil
-			}
+	if x, ok := childInfo.p.(physicalDistSQLPlan); ok {
+		var info *physicalPlanInfo
+		if distinct {
+			info = p.convert2PhysicalPlanDistinctHash(x, childInfo)
+		} else {
+			info = p.convert2PhysicalPlanFinalHash(x, childInfo)
+		}
+		if info != nil {
+			return info, nil
 		}
 	}
 	return p.convert2PhysicalPlanCompleteHash(childInfo), nil
@@ -829,6 +1561,9 @@ func (p *Aggregation) convert2PhysicalPlan(prop *requiredProperty) (*physicalPla
 		}
 	}
 	streamInfo, err := p.convert2PhysicalPlanStream(removeLimit(prop))
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
 	if planInfo == nil || streamInfo.cost < planInfo.cost {
 		planInfo = streamInfo
 	}
@@ -846,26 +1581,40 @@ func (p *Union) convert2PhysicalPlan(prop *requiredProperty) (*physicalPlanInfo,
 	if info != nil {
 		return info, nil
 	}
-	limit := prop.limit
+	// The rows of the children are returned one child after another, so the order is always enforced above the union.
+	// With a limit, each child only needs to return its first offset + count rows in the order, so the order and the
+	// limit are pushed down to build a top-n in every child.
 	childInfos := make([]*physicalPlanInfo, 0, len(p.children))
 	var count uint64
 	for _, child := range p.GetChildren() {
 		newProp := convertLimitOffsetToCount(prop)
 		newProp.props = make([]*columnProp, 0, len(prop.props))
-		for _, c := range prop.props {
-			idx := p.GetSchema().GetIndex(c.col)
-			newProp.props = append(newProp.props, &columnProp{col: child.GetSchema()[idx], desc: c.desc})
+		if prop.limit != nil {
+			for _, c := range prop.props {
+				idx := p.GetSchema().GetIndex(c.col)
+				newProp.props = append(newProp.props, &columnProp{col: child.GetSchema()[idx], desc: c.desc})
+			}
 		}
+		newProp.sortKeyLen = len(newProp.props)
 		info, err = child.(LogicalPlan).convert2PhysicalPlan(newProp)
-		count += info.count
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
+		if info.cost == math.MaxFloat64 && len(newProp.props) > 0 {
+			// The child can't return the rows in the order, e.g. the order is on a scalar function of a projection,
+			// so the top-n is enforced on the top of the child.
+			info, err = child.(LogicalPlan).convert2PhysicalPlan(&requiredProperty{})
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			info = enforceProperty(newProp, info)
+		}
+		count += info.count
 		childInfos = append(childInfos, info)
 	}
 	info = p.matchProperty(prop, childInfos...)
-	info = enforceProperty(limitProperty(limit), info)
 	info.count = count
+	info = enforceProperty(prop, info)
 	p.storePlanInfo(prop, info)
 	return info, nil
 }
@@ -1039,7 +1788,7 @@ func (p *Sort) convert2PhysicalPlan(prop *requiredProperty) (*physicalPlanInfo,
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
-	sortCost := sortCost(unSortedPlanInfo.count)
+	sortCost := sortCost(p.allocator.costFactors(), unSortedPlanInfo.count)
 	if len(selfProp.props) == 0 {
 		np := p.Copy().(*Sort)
 		np.ExecLimit = prop.limit
@@ -1069,6 +1818,8 @@ func addCachePlan(p PhysicalPlan) PhysicalPlan {
 			newChildren = append(newChildren, addCachePlan(child.(PhysicalPlan)))
 		} else {
 			newChild := &Cache{}
+			newChild.count = child.EstimatedRowCount()
+			newChild.cost = child.EstimatedCost()
 			addChild(newChild, child)
 			newChild.SetSchema(child.GetSchema())
 			newChild.SetParents(np)
@@ -1106,7 +1857,9 @@ func (p *Apply) convert2PhysicalPlan(prop *requiredProperty) (*physicalPlanInfo,
 	np := &PhysicalApply{
 		OuterSchema: p.corCols,
 		Checker:     p.Checker,
+		Lateral:     p.Lateral,
 		InnerPlan:   innerInfo.p,
+		CacheInner:  p.canCacheInner(),
 	}
 	np.tp = "PhysicalAppy"
 	np.allocator = p.allocator
@@ -1125,7 +1878,6 @@ func (p *Apply) convert2PhysicalPlan(prop *requiredProperty) (*physicalPlanInfo,
 }
 
 // convert2PhysicalPlan implements the LogicalPlan convert2PhysicalPlan interface.
-// TODO: support streaming distinct.
 func (p *Distinct) convert2PhysicalPlan(prop *requiredProperty) (*physicalPlanInfo, error) {
 	info, err := p.getPlanInfo(prop)
 	if err != nil {
@@ -1141,8 +1893,45 @@ func (p *Distinct) convert2PhysicalPlan(prop *requiredProperty) (*physicalPlanIn
 		return nil, errors.Trace(err)
 	}
 	info = addPlanToResponse(p, info)
+	info.cost += float64(info.count) * memoryFactor
+	// If the input can also be sorted by all the columns, the duplicated rows are adjacent, so they can be removed by
+	// comparing with the previous row instead of remembering all the distinct rows.
+	childInfo, err := child.convert2PhysicalPlan(p.getSortedProperty(prop))
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if childInfo.cost != math.MaxFloat64 {
+		sorted := p.Copy().(*Distinct)
+		sorted.Sorted = true
+		sortedInfo := addPlanToResponse(sorted, childInfo)
+		sortedInfo.cost += float64(sortedInfo.count) * p.allocator.costFactors().cpu
+		if sortedInfo.cost < info.cost {
+			info = sortedInfo
+		}
+	}
 	info.count = uint64(float64(info.count) * distinctFactor)
 	info = enforceProperty(limitProperty(limit), info)
 	p.storePlanInfo(prop, info)
 	return info, nil
 }
+
+// getSortedProperty returns the property which sorts the input by the required columns, then the other columns.
+func (p *Distinct) getSortedProperty(prop *requiredProperty) *requiredProperty {
+	sortedProp := &requiredProperty{
+		props: make([]*columnProp, 0, len(p.schema)),
+	}
+	sortedCols := make(expression.Schema, 0, len(p.schema))
+	for _, pro := range prop.props {
+		sortedProp.props = append(sortedProp.props, pro)
+		sortedCols = append(sortedCols, pro.col)
+	}
+	desc := len(prop.props) > 0 && prop.props[0].desc
+	for _, col := range p.schema {
+		if sortedCols.GetIndex(col) == -1 {
+			sortedProp.props = append(sortedProp.props, &columnProp{col: col, desc: desc})
+			sortedCols = append(sortedCols, col)
+		}
+	}
+	sortedProp.sortKeyLen = len(sortedProp.props)
+	return sortedProp
+}
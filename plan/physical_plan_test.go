@@ -15,14 +15,20 @@ package plan
 
 import (
 	"fmt"
+	"math"
+	"strings"
 
 	. "github.com/pingcap/check"
 	"github.com/pingcap/tidb/ast"
 	"github.com/pingcap/tidb/expression"
 	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/plan/statistics"
+	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/terror"
 	"github.com/pingcap/tidb/util/mock"
 	"github.com/pingcap/tidb/util/testleak"
+	"github.com/pingcap/tidb/util/types"
 )
 
 func (s *testPlanSuite) TestPushDownOrderbyAndLimit(c *C) {
@@ -199,6 +205,28 @@ func (s *testPlanSuite) TestPushDownExpression(c *C) {
 			sql:  "b is null",
 			cond: "isnull(test.t.b)",
 		},
+		// The results of the control functions have the same type.
+		{
+			sql:  "c_str = ifnull(d_str, 'x')",
+			cond: "eq(test.t.c_str, ifnull(test.t.d_str, x))",
+		},
+		{
+			sql:  "a = case when b then null else c end",
+			cond: "eq(test.t.a, case(test.t.b, <nil>, test.t.c))",
+		},
+		// The results which need a conversion are not pushed down.
+		{
+			sql:  "b = 1 and a = if(a, 1, c_str)",
+			cond: "eq(test.t.b, 1)",
+		},
+		{
+			sql:  "b = 1 and c_str = coalesce(c_str, a)",
+			cond: "eq(test.t.b, 1)",
+		},
+		{
+			sql:  "b = 1 and a = case when a then 1 else 'x' end",
+			cond: "eq(test.t.b, 1)",
+		},
 	}
 	for _, ca := range cases {
 		sql := "select * from t where " + ca.sql
@@ -280,6 +308,42 @@ func (s *testPlanSuite) TestCBO(c *C) {
 			sql:  "select * from t t1 where 1 = 0",
 			best: "Dummy",
 		},
+		{
+			sql:  "select * from t where f = 1 or g = 2",
+			best: "IndexMerge(t){Index(t.f)[[1,1]],Index(t.g)[[2,2]]}->Selection",
+		},
+		{
+			sql:  "select * from t where (c = 1 and d = 2) or g = 3 or f in (4, 5)",
+			best: "IndexMerge(t){Index(t.c_d_e)[[1 2,1 2]],Index(t.g)[[3,3]],Index(t.f)[[4,4] [5,5]]}->Selection",
+		},
+		{
+			sql:  "select * from t where g = 1 or c > 2",
+			best: "IndexMerge(t){Index(t.g)[[1,1]],Index(t.c_d_e)[(2,+inf]]}->Selection",
+		},
+		{
+			sql:  "select * from t where c = 1 or c = 2",
+			best: "Index(t.c_d_e)[[1,1] [2,2]]",
+		},
+		{
+			sql:  "select * from t where c <=> 1 and d <=> null and e > 2",
+			best: "Index(t.c_d_e)[(1 <nil> 2,1 <nil> +inf]]",
+		},
+		{
+			sql:  "select * from t where c is false or c <=> null",
+			best: "Index(t.c_d_e)[[<nil>,<nil>] [0,0]]",
+		},
+		{
+			sql:  "select * from t where c = d and d <=> 3",
+			best: "Index(t.c_d_e)[[3 3,3 3]]",
+		},
+		{
+			sql:  "select * from t where f = 1 or b = 2",
+			best: "Table(t)->Selection",
+		},
+		{
+			sql:  "select * from t where f = 1 or g = 2 order by b limit 1",
+			best: "IndexMerge(t){Index(t.f)[[1,1]],Index(t.g)[[2,2]]}->Selection->Sort + Limit(1) + Offset(0)",
+		},
 		{
 			sql:  "select * from t t1 where c in (1,2,3,4,5,6,7,8,9,0)",
 			best: "Index(t.c_d_e)[[0,0] [1,1] [2,2] [3,3] [4,4] [5,5] [6,6] [7,7] [8,8] [9,9]]",
@@ -325,6 +389,22 @@ func (s *testPlanSuite) TestCBO(c *C) {
 			sql:  "select count(distinct e), sum(distinct c) from t where c = 1 group by d",
 			best: "Index(t.c_d_e)[[1,1]]->StreamAgg",
 		},
+		{
+			sql:  "select distinct c from t",
+			best: "Index(t.c_d_e)[[<nil>,+inf]]->StreamDistinct",
+		},
+		{
+			sql:  "select distinct d from t where c = 1",
+			best: "Index(t.c_d_e)[[1,1]]->Projection->StreamDistinct",
+		},
+		{
+			sql:  "select distinct c, d from t order by c desc limit 2",
+			best: "Index(t.c_d_e)[[<nil>,+inf]]->StreamDistinct->Limit",
+		},
+		{
+			sql:  "select distinct b from t",
+			best: "Table(t)->Distinct",
+		},
 		{
 			sql:  "select * from t a where a.c = 1 order by a.d limit 2",
 			best: "Index(t.c_d_e)[[1,1]]",
@@ -375,7 +455,23 @@ func (s *testPlanSuite) TestCBO(c *C) {
 		},
 		{
 			sql:  "select * from (select t.a from t union select t.d from t where t.c = 1 union select t.c from t) k order by a limit 1",
-			best: "UnionAll{Table(t)->Index(t.c_d_e)[[1,1]]->Projection->Index(t.c_d_e)[[<nil>,+inf]]}->Distinct->Limit",
+			best: "UnionAll{Table(t)->Index(t.c_d_e)[[1,1]]->Projection->Table(t)}->Distinct->Sort + Limit(1) + Offset(0)",
+		},
+		{
+			sql:  "select * from (select * from t union all select * from t) k order by a",
+			best: "UnionAll{Table(t)->Table(t)}->Sort",
+		},
+		{
+			sql:  "select * from (select * from t union all select * from t) k order by c limit 1",
+			best: "UnionAll{Index(t.c_d_e)[[<nil>,+inf]]->Limit->Index(t.c_d_e)[[<nil>,+inf]]->Limit}->Sort + Limit(1) + Offset(0)",
+		},
+		{
+			sql:  "select x from (select a + 1 as x from t union all select c from t) k order by x limit 2",
+			best: "UnionAll{Table(t)->Projection->Sort + Limit(2) + Offset(0)->Index(t.c_d_e)[[<nil>,+inf]]->Limit}->Sort + Limit(2) + Offset(0)",
+		},
+		{
+			sql:  "select a + 1 from (select a, b from t union all select c, d from t) k where b < 3 limit 1",
+			best: "UnionAll{Table(t)->Selection->Limit->Projection->Table(t)->Selection->Limit->Projection}->Limit->Projection",
 		},
 		{
 			sql:  "select * from (select t.a from t union select t.d from t union select t.c from t) k order by a limit 1",
@@ -409,96 +505,89 @@ func (s *testPlanSuite) TestCBO(c *C) {
 	}
 }
 
-func (s *testPlanSuite) TestProjectionElimination(c *C) {
+func (s *testPlanSuite) TestOptimizerHints(c *C) {
 	defer testleak.AfterTest(c)()
 	cases := []struct {
-		sql string
-		ans string
+		sql  string
+		best string
 	}{
-		// projection can be eliminated in following cases.
-		{
-			sql: "select a from t",
-			ans: "Table(t)",
-		},
 		{
-			sql: "select a from t where a > 1",
-			ans: "Table(t)",
+			sql:  "select /*+ USE_INDEX(t1, c_d_e) */ * from t t1",
+			best: "Index(t.c_d_e)[[<nil>,+inf]]",
 		},
 		{
-			sql: "select a from t where a is null",
-			ans: "Table(t)",
+			sql:  "select /*+ USE_INDEX(t1) */ * from t t1 where c < 0",
+			best: "Table(t)->Selection",
 		},
 		{
-			sql: "select a, b from t where b > 0",
-			ans: "Table(t)->Selection",
+			sql:  "select /*+ IGNORE_INDEX(t, c_d_e) */ * from t where c < 0",
+			best: "Table(t)->Selection",
 		},
 		{
-			sql: "select a as c1, b as c2 from t where a = 3",
-			ans: "Table(t)",
+			sql:  "select /*+ USE_INDEX(t1, e) IGNORE_INDEX(t1, e) */ * from t t1 where c < 0",
+			best: "Table(t)->Selection",
 		},
 		{
-			sql: "select a as c1, b as c2 from t as t1 where t1.a = 0",
-			ans: "Table(t)",
+			sql:  "select /*+ IGNORE_INDEX(t2, c_d_e) */ * from t t1 where c < 0",
+			best: "Index(t.c_d_e)[[-inf,0)]",
 		},
 		{
-			sql: "select a from t where exists(select 1 from t as x where x.a < t.a)",
-			ans: "SemiJoin{Table(t)->Table(t)}",
+			sql:  "select /*+ IGNORE_INDEX(t1, c_d_e) */ * from (select * from t t1 where c < 0) t2",
+			best: "Index(t.c_d_e)[[-inf,0)]",
 		},
 		{
-			sql: "select a from (select d as a from t where d = 0) k where k.a = 5",
-			ans: "Dummy",
+			sql:  "select * from t a join t b on a.c = b.c",
+			best: "LeftHashJoin{Table(t)->Table(t)}(a.c,b.c)",
 		},
 		{
-			sql: "select t1.a from t t1 where t1.a in (select t2.a from t t2 where t2.a > 1)",
-			ans: "SemiJoin{Table(t)->Table(t)}",
+			sql:  "select /*+ HASH_JOIN(a) */ * from t a join t b on a.c = b.c",
+			best: "RightHashJoin{Table(t)->Table(t)}(a.c,b.c)",
 		},
 		{
-			sql: "select t1.a, t2.b from t t1, t t2 where t1.a > 0 and t2.b < 0",
-			ans: "RightHashJoin{Table(t)->Table(t)->Selection}",
+			sql:  "select * from t a join t b on a.c = b.c where a.d = 1",
+			best: "RightHashJoin{Table(t)->Selection->Table(t)}(a.c,b.c)",
 		},
 		{
-			sql: "select t1.a, t1.b, t2.a, t2.b from t t1, t t2 where t1.a > 0 and t2.b < 0",
-			ans: "RightHashJoin{Table(t)->Table(t)->Selection}",
+			sql:  "select /*+ HASH_JOIN(b) */ * from t a join t b on a.c = b.c where a.d = 1",
+			best: "LeftHashJoin{Table(t)->Selection->Table(t)}(a.c,b.c)",
 		},
 		{
-			sql: "select * from (t t1 join t t2) join (t t3 join t t4)",
-			ans: "LeftHashJoin{LeftHashJoin{Table(t)->Table(t)}->LeftHashJoin{Table(t)->Table(t)}}",
+			sql:  "select /*+ HASH_JOIN(b) */ * from t a left join t b on a.c = b.c",
+			best: "LeftHashJoin{Table(t)->Table(t)}(a.c,b.c)",
 		},
-		// projection can not be eliminated in following cases.
 		{
-			sql: "select t1.b, t1.a, t2.b, t2.a from t t1, t t2 where t1.a > 0 and t2.b < 0",
-			ans: "RightHashJoin{Table(t)->Table(t)->Selection}->Projection",
+			sql:  "select /*+ MERGE_JOIN(a) INL_JOIN(b) */ * from t a join t b on a.c = b.c",
+			best: "MergeJoin{Index(t.c_d_e)[[<nil>,+inf]]->Index(t.c_d_e)[[<nil>,+inf]]}(a.c,b.c)",
 		},
 		{
-			sql: "select d, c, b, a from t where a = b and b = 1",
-			ans: "Table(t)->Selection->Projection",
+			sql:  "select /*+ IGNORE_INDEX(@sub t1, c_d_e) */ * from (select /*+ QB_NAME(sub) */ * from t t1 where c < 0) t2",
+			best: "Table(t)->Selection",
 		},
 		{
-			sql: "select d as a, b as c from t as t1 where d > 0 and b < 0",
-			ans: "Table(t)->Selection->Projection",
+			sql:  "select /*+ IGNORE_INDEX(t1@sub, c_d_e) */ * from (select /*+ QB_NAME(sub) */ * from t t1 where c < 0) t2",
+			best: "Table(t)->Selection",
 		},
 		{
-			sql: "select c as a, c as b from t",
-			ans: "Table(t)->Projection",
+			sql:  "select /*+ IGNORE_INDEX(@sub2 t1, c_d_e) */ * from (select /*+ QB_NAME(sub) */ * from t t1 where c < 0) t2",
+			best: "Index(t.c_d_e)[[-inf,0)]",
 		},
 		{
-			sql: "select c as a, c as b from t where d > 0",
-			ans: "Table(t)->Selection->Projection",
+			sql:  "select /*+ IGNORE_INDEX(@sub t1, c_d_e) */ * from t t1 where c < 0 union all select /*+ QB_NAME(sub) */ * from t t1 where c < 0",
+			best: "UnionAll{Index(t.c_d_e)[[-inf,0)]->Table(t)->Selection}",
 		},
 		{
-			sql: "select t1.a, t2.b, t2.a, t1.b from t t1, t t2 where t1.a > 0 and t2.b < 0",
-			ans: "RightHashJoin{Table(t)->Table(t)->Selection}->Projection",
+			sql:  "select /*+ HASH_JOIN(a) */ * from (select /*+ QB_NAME(sub) */ a.c from t a join t b on a.c = b.c) x",
+			best: "MergeJoin{Index(t.c_d_e)[[<nil>,+inf]]->Index(t.c_d_e)[[<nil>,+inf]]}(a.c,b.c)->Projection",
 		},
 		{
-			sql: "select t1.a from t t1 where t1.a in (select t2.a from t t2 where t1.a > 1)",
-			ans: "Table(t)->Apply(Table(t)->Cache->Selection->Projection)->Selection->Projection",
+			sql:  "select /*+ HASH_JOIN(a@sub) */ * from (select /*+ QB_NAME(sub) */ a.c from t a join t b on a.c = b.c) x",
+			best: "RightHashJoin{Table(t)->Table(t)}(a.c,b.c)->Projection",
 		},
 	}
 	for _, ca := range cases {
 		comment := Commentf("for %s", ca.sql)
 		stmt, err := s.ParseOneStmt(ca.sql, "", "")
 		c.Assert(err, IsNil, comment)
-		ast.SetFlag(stmt)
 
 		err = mockResolve(stmt)
 		c.Assert(err, IsNil)
@@ -506,53 +595,1089 @@ func (s *testPlanSuite) TestProjectionElimination(c *C) {
 		builder := &planBuilder{
 			allocator: new(idAllocator),
 			ctx:       mock.NewContext(),
+			colMapper: make(map[*ast.ColumnNameExpr]int),
 		}
 		p := builder.build(stmt)
 		c.Assert(builder.err, IsNil)
 		lp := p.(LogicalPlan)
+
 		_, lp, err = lp.PredicatePushDown(nil)
 		c.Assert(err, IsNil)
 		lp.PruneColumns(lp.GetSchema())
 		lp.ResolveIndicesAndCorCols()
 		info, err := lp.convert2PhysicalPlan(&requiredProperty{})
-		p = EliminateProjection(info.p)
-		c.Assert(ToString(p), Equals, ca.ans, Commentf("for %s", ca.sql))
+		c.Assert(err, IsNil)
+		c.Assert(ToString(EliminateProjection(info.p)), Equals, ca.best, Commentf("for %s", ca.sql))
 	}
 }
 
-func (s *testPlanSuite) TestCoveringIndex(c *C) {
+func (s *testPlanSuite) TestGenHints(c *C) {
+	defer testleak.AfterTest(c)()
 	cases := []struct {
-		columnNames []string
-		indexNames  []string
-		indexLens   []int
-		isCovering  bool
+		sql   string
+		hints string
 	}{
-		{[]string{"a"}, []string{"a"}, []int{-1}, true},
-		{[]string{"a"}, []string{"a", "b"}, []int{-1, -1}, true},
-		{[]string{"a", "b"}, []string{"b", "a"}, []int{-1, -1}, true},
-		{[]string{"a", "b"}, []string{"b", "c"}, []int{-1, -1}, false},
-		{[]string{"a", "b"}, []string{"a", "b"}, []int{50, -1}, false},
-		{[]string{"a", "b"}, []string{"a", "c"}, []int{-1, -1}, false},
-		{[]string{"id", "a"}, []string{"a", "b"}, []int{-1, -1}, true},
+		{
+			sql:   "select * from t t1 where c < 0",
+			hints: "use_index(t1, c_d_e)",
+		},
+		{
+			sql:   "select * from t where d = 1",
+			hints: "use_index(t)",
+		},
+		{
+			sql:   "select * from t a join t b on a.c = b.c where a.d = 1",
+			hints: "use_index(a), use_index(b), hash_join(a), leading(a, b)",
+		},
+		{
+			sql:   "select * from t a join t b on a.c = b.c",
+			hints: "use_index(a), use_index(b), hash_join(b), leading(a, b)",
+		},
+		{
+			sql:   "select * from t a where a.c in (select b.c from t b)",
+			hints: "use_index(a)",
+		},
+		{
+			sql:   "select * from t a, t b, t c where a.c = b.c and a.c = c.c and a.d = 1",
+			hints: "use_index(a), use_index(c), hash_join(a), use_index(b), hash_join(b), leading(a, c, b)",
+		},
+		{
+			// The join order of a bushy tree is left to the optimizer.
+			sql:   "select * from t a, t b, t c where a.c = b.c and b.c = c.c and c.d = 1",
+			hints: "use_index(a), use_index(b), use_index(c), hash_join(c), hash_join(a)",
+		},
+		{
+			// The alias t is read twice, so it's skipped.
+			sql:   "select * from t where t.c in (select t.c from t where t.d = 1)",
+			hints: "",
+		},
+		{
+			sql:   "select * from t `my t` where c < 0",
+			hints: "use_index(`my t`, c_d_e)",
+		},
+	}
+	buildPlan := func(sql string) Plan {
+		stmt, err := s.ParseOneStmt(sql, "", "")
+		c.Assert(err, IsNil, Commentf("for %s", sql))
+		err = mockResolve(stmt)
+		c.Assert(err, IsNil)
+		builder := &planBuilder{
+			allocator: new(idAllocator),
+			ctx:       mock.NewContext(),
+			colMapper: make(map[*ast.ColumnNameExpr]int),
+		}
+		p := builder.build(stmt)
+		c.Assert(builder.err, IsNil)
+		lp := p.(LogicalPlan)
+		_, lp, err = lp.PredicatePushDown(nil)
+		c.Assert(err, IsNil)
+		lp.PruneColumns(lp.GetSchema())
+		lp.ResolveIndicesAndCorCols()
+		info, err := lp.convert2PhysicalPlan(&requiredProperty{})
+		c.Assert(err, IsNil)
+		return EliminateProjection(info.p)
 	}
 	for _, ca := range cases {
-		var columns []*model.ColumnInfo
-		var pkIsHandle bool
-		for _, cn := range ca.columnNames {
-			col := &model.ColumnInfo{Name: model.NewCIStr(cn)}
-			if cn == "id" {
-				pkIsHandle = true
-				col.Flag = mysql.PriKeyFlag
-			}
-			columns = append(columns, col)
+		p := buildPlan(ca.sql)
+		hints := GenHints(p)
+		c.Assert(hints, Equals, ca.hints, Commentf("for %s", ca.sql))
+		if hints == "" {
+			continue
 		}
-		var indexCols []*model.IndexColumn
-		for i := range ca.indexNames {
-			icn := ca.indexNames[i]
-			icl := ca.indexLens[i]
-			indexCols = append(indexCols, &model.IndexColumn{Name: model.NewCIStr(icn), Length: icl})
+		// The hints reproduce the plan.
+		hinted := buildPlan(strings.Replace(ca.sql, "select", "select /*+ "+hints+" */", 1))
+		c.Assert(ToString(hinted), Equals, ToString(p), Commentf("for %s", ca.sql))
+		c.Assert(GenHints(hinted), Equals, hints, Commentf("for %s", ca.sql))
+	}
+}
+
+func (s *testPlanSuite) TestMergeJoin(c *C) {
+	defer testleak.AfterTest(c)()
+	cases := []struct {
+		sql  string
+		best string
+	}{
+		{
+			sql:  "select * from t t1 join t t2 on t1.a = t2.a",
+			best: "MergeJoin{Table(t)->Table(t)}(t1.a,t2.a)",
+		},
+		{
+			sql:  "select * from t t1 left join t t2 on t1.a = t2.a",
+			best: "MergeJoin{Table(t)->Table(t)}(t1.a,t2.a)",
+		},
+		{
+			sql:  "select * from t t1 right join t t2 on t1.a = t2.a",
+			best: "MergeJoin{Table(t)->Table(t)}(t1.a,t2.a)",
+		},
+		{
+			sql:  "select * from t t1 join t t2 on t1.a = t2.a order by t2.a",
+			best: "MergeJoin{Table(t)->Table(t)}(t1.a,t2.a)",
+		},
+		{
+			sql:  "select * from t t1 left join t t2 on t1.a = t2.a order by t2.a",
+			best: "MergeJoin{Table(t)->Table(t)}(t1.a,t2.a)->Sort",
+		},
+		{
+			sql:  "select * from t t1 join t t2 on t1.a = t2.a order by t1.b",
+			best: "MergeJoin{Table(t)->Table(t)}(t1.a,t2.a)->Sort",
+		},
+		{
+			sql:  "select t1.c, t2.c from t t1 join t t2 on t1.c = t2.c",
+			best: "MergeJoin{Index(t.c_d_e)[[<nil>,+inf]]->Index(t.c_d_e)[[<nil>,+inf]]}(t1.c,t2.c)",
+		},
+		{
+			sql:  "select * from t t1 join t t2 on t1.b = t2.b",
+			best: "LeftHashJoin{Table(t)->Table(t)}(t1.b,t2.b)",
+		},
+		{
+			sql:  "select * from t t1 join t t2 on t1.a = t2.c_str",
+			best: "LeftHashJoin{Table(t)->Table(t)}(t1.a,t2.c_str)",
+		},
+		{
+			sql:  "select /*+ MERGE_JOIN(t1) */ * from t t1 join t t2 on t1.b = t2.b",
+			best: "MergeJoin{Table(t)->Sort->Table(t)->Sort}(t1.b,t2.b)",
+		},
+		{
+			sql:  "select /*+ HASH_JOIN(t1) */ * from t t1 join t t2 on t1.a = t2.a",
+			best: "RightHashJoin{Table(t)->Table(t)}(t1.a,t2.a)",
+		},
+	}
+	for _, ca := range cases {
+		comment := Commentf("for %s", ca.sql)
+		stmt, err := s.ParseOneStmt(ca.sql, "", "")
+		c.Assert(err, IsNil, comment)
+
+		err = mockResolve(stmt)
+		c.Assert(err, IsNil)
+
+		builder := &planBuilder{
+			allocator: new(idAllocator),
+			ctx:       mock.NewContext(),
+			colMapper: make(map[*ast.ColumnNameExpr]int),
 		}
-		covering := isCoveringIndex(columns, indexCols, pkIsHandle)
-		c.Assert(covering, Equals, ca.isCovering)
+		p := builder.build(stmt)
+		c.Assert(builder.err, IsNil)
+		lp := p.(LogicalPlan)
+
+		_, lp, err = lp.PredicatePushDown(nil)
+		c.Assert(err, IsNil)
+		lp.PruneColumns(lp.GetSchema())
+		lp.ResolveIndicesAndCorCols()
+		info, err := lp.convert2PhysicalPlan(&requiredProperty{})
+		c.Assert(err, IsNil)
+		c.Assert(ToString(EliminateProjection(info.p)), Equals, ca.best, Commentf("for %s", ca.sql))
+	}
+}
+
+func (s *testPlanSuite) TestIndexJoin(c *C) {
+	defer testleak.AfterTest(c)()
+	cases := []struct {
+		sql  string
+		best string
+	}{
+		{
+			sql:  "select * from t t1 join t t2 on t1.b = t2.a where t1.a = 1",
+			best: "LeftIndexJoin{Table(t)->Table(t)}(t1.b,t2.a)",
+		},
+		{
+			sql:  "select * from t t1 join t t2 on t1.b = t2.c where t1.a in (1, 2)",
+			best: "RightHashJoin{Table(t)->Table(t)}(t1.b,t2.c)",
+		},
+		{
+			sql:  "select t1.a, t2.c from t t1 join t t2 on t1.b = t2.c where t1.a in (1, 2)",
+			best: "LeftIndexJoin{Table(t)->Index(t.c_d_e)[[<nil>,+inf]]}(t1.b,t2.c)->Projection",
+		},
+		{
+			sql:  "select * from t t1 left join t t2 on t1.b = t2.a where t1.a = 1",
+			best: "LeftIndexJoin{Table(t)->Table(t)}(t1.b,t2.a)",
+		},
+		{
+			sql:  "select * from t t1 right join t t2 on t1.a = t2.b where t2.a = 1",
+			best: "RightIndexJoin{Table(t)->Table(t)}(t1.a,t2.b)",
+		},
+		{
+			sql:  "select * from t t1 join t t2 on t1.b = t2.a and t1.c = t2.c where t1.a = 1 and t2.d > 1",
+			best: "LeftIndexJoin{Table(t)->Table(t)}(t1.b,t2.a)(t1.c,t2.c)",
+		},
+		{
+			sql:  "select * from t t1 join t t2 on t1.b = t2.a where t1.a < 3 order by t1.a",
+			best: "LeftIndexJoin{Table(t)->Table(t)}(t1.b,t2.a)",
+		},
+		{
+			sql:  "select * from t t1 join t t2 on t1.b = t2.b where t1.a = 1",
+			best: "RightHashJoin{Table(t)->Table(t)}(t1.b,t2.b)",
+		},
+		{
+			sql:  "select * from t t1 join t t2 on t1.b = t2.c",
+			best: "LeftHashJoin{Table(t)->Table(t)}(t1.b,t2.c)",
+		},
+		{
+			sql:  "select /*+ INL_JOIN(t2) */ * from t t1 join t t2 on t1.b = t2.c",
+			best: "LeftIndexJoin{Table(t)->Index(t.c_d_e)[[<nil>,+inf]]}(t1.b,t2.c)",
+		},
+		{
+			sql:  "select /*+ INL_JOIN(t1) */ * from t t1 join t t2 on t1.c = t2.c where t1.a = 1",
+			best: "RightIndexJoin{Index(t.c_d_e)[[<nil>,+inf]]->Table(t)}(t1.c,t2.c)",
+		},
+		{
+			sql:  "select /*+ HASH_JOIN(t1) */ * from t t1 join t t2 on t1.b = t2.a where t1.a = 1",
+			best: "RightHashJoin{Table(t)->Table(t)}(t1.b,t2.a)",
+		},
+		{
+			sql:  "select * from t t1 join t t2 on t1.b = t2.c_str where t1.a = 1",
+			best: "RightHashJoin{Table(t)->Table(t)}(t1.b,t2.c_str)",
+		},
+	}
+	for _, ca := range cases {
+		comment := Commentf("for %s", ca.sql)
+		stmt, err := s.ParseOneStmt(ca.sql, "", "")
+		c.Assert(err, IsNil, comment)
+
+		err = mockResolve(stmt)
+		c.Assert(err, IsNil)
+
+		builder := &planBuilder{
+			allocator: new(idAllocator),
+			ctx:       mockContext(),
+			colMapper: make(map[*ast.ColumnNameExpr]int),
+		}
+		p := builder.build(stmt)
+		c.Assert(builder.err, IsNil)
+		lp := p.(LogicalPlan)
+
+		_, lp, err = lp.PredicatePushDown(nil)
+		c.Assert(err, IsNil)
+		lp.PruneColumns(lp.GetSchema())
+		lp.ResolveIndicesAndCorCols()
+		info, err := lp.convert2PhysicalPlan(&requiredProperty{})
+		c.Assert(err, IsNil)
+		c.Assert(ToString(EliminateProjection(info.p)), Equals, ca.best, comment)
+	}
+}
+
+func (s *testPlanSuite) TestCostFactors(c *C) {
+	defer testleak.AfterTest(c)()
+	ctx := mockContext()
+	factors, err := loadCostFactors(ctx)
+	c.Assert(err, IsNil)
+	c.Assert(factors, DeepEquals, defaultCostFactors)
+	sessionVars := ctx.GetSessionVars()
+	c.Assert(sessionVars.SetSystemVar(variable.TiDBOptSeekFactor, types.NewStringDatum("1000000")), IsNil)
+	factors, err = loadCostFactors(ctx)
+	c.Assert(err, IsNil)
+	c.Assert(factors.seek, Equals, 1000000.0)
+	for _, val := range []string{"abc", "-1"} {
+		c.Assert(sessionVars.SetSystemVar(variable.TiDBOptCPUFactor, types.NewStringDatum(val)), IsNil)
+		_, err = loadCostFactors(ctx)
+		c.Assert(ErrWrongArguments.Equal(err), IsTrue, Commentf("for %s", val))
+	}
+
+	cases := []struct {
+		sql     string
+		factors *costFactors
+		best    string
+	}{
+		{
+			sql:     "select * from t t1 join t t2 on t1.b = t2.a where t1.a = 1",
+			factors: defaultCostFactors,
+			best:    "LeftIndexJoin{Table(t)->Table(t)}(t1.b,t2.a)",
+		},
+		// The lookups of the index join are too expensive.
+		{
+			sql:     "select * from t t1 join t t2 on t1.b = t2.a where t1.a = 1",
+			factors: &costFactors{scan: 0.5, network: 1, seek: 1000000, cpu: 0.9},
+			best:    "RightHashJoin{Table(t)->Table(t)}(t1.b,t2.a)",
+		},
+	}
+	for _, ca := range cases {
+		comment := Commentf("for %s", ca.sql)
+		stmt, err := s.ParseOneStmt(ca.sql, "", "")
+		c.Assert(err, IsNil, comment)
+
+		err = mockResolve(stmt)
+		c.Assert(err, IsNil)
+
+		builder := &planBuilder{
+			allocator: &idAllocator{factors: ca.factors},
+			ctx:       mockContext(),
+			colMapper: make(map[*ast.ColumnNameExpr]int),
+		}
+		p := builder.build(stmt)
+		c.Assert(builder.err, IsNil)
+		lp := p.(LogicalPlan)
+
+		_, lp, err = lp.PredicatePushDown(nil)
+		c.Assert(err, IsNil)
+		lp.PruneColumns(lp.GetSchema())
+		lp.ResolveIndicesAndCorCols()
+		info, err := lp.convert2PhysicalPlan(&requiredProperty{})
+		c.Assert(err, IsNil)
+		c.Assert(ToString(EliminateProjection(info.p)), Equals, ca.best, comment)
+	}
+}
+
+// collectApplyCacheInner collects whether the inner results of the applies in the plan are cached, in pre-order.
+func collectApplyCacheInner(p Plan) []bool {
+	var cacheInner []bool
+	if apply, ok := p.(*PhysicalApply); ok {
+		cacheInner = append(cacheInner, apply.CacheInner)
+		cacheInner = append(cacheInner, collectApplyCacheInner(apply.InnerPlan)...)
+	}
+	for _, child := range p.GetChildren() {
+		cacheInner = append(cacheInner, collectApplyCacheInner(child)...)
+	}
+	return cacheInner
+}
+
+func (s *testPlanSuite) TestApplyCacheInner(c *C) {
+	defer testleak.AfterTest(c)()
+	cases := []struct {
+		sql        string
+		cacheInner []bool
+	}{
+		{
+			sql:        "select a, (select count(*) from t t2 where t2.b < t1.b) from t t1",
+			cacheInner: []bool{true},
+		},
+		{
+			sql:        "select a from t t1 where a > all (select t2.a from t t2 where t2.b < t1.b)",
+			cacheInner: []bool{true},
+		},
+		{
+			sql:        "select * from t, json_table(t.c_str, '$[*]' columns (x int path '$')) j",
+			cacheInner: []bool{true},
+		},
+		// The results of rand() differ for the same outer values.
+		{
+			sql:        "select a, (select count(*) from t t2 where t2.b < t1.b and rand() < 0.5) from t t1",
+			cacheInner: []bool{false},
+		},
+		// The innermost subquery refers to t1, which isn't the outer child of its apply, but the results of
+		// the outer apply only depend on t1.
+		{
+			sql:        "select a, (select count(*) from t t2 where t2.b < t1.b and t2.d > all (select t3.d from t t3 where t3.c < t1.c)) from t t1",
+			cacheInner: []bool{true, false},
+		},
+	}
+	for _, ca := range cases {
+		comment := Commentf("for %s", ca.sql)
+		stmt, err := s.ParseOneStmt(ca.sql, "", "")
+		c.Assert(err, IsNil, comment)
+
+		err = mockResolve(stmt)
+		c.Assert(err, IsNil)
+
+		builder := &planBuilder{
+			allocator: new(idAllocator),
+			ctx:       mock.NewContext(),
+			colMapper: make(map[*ast.ColumnNameExpr]int),
+		}
+		p := builder.build(stmt)
+		c.Assert(builder.err, IsNil)
+		lp := p.(LogicalPlan)
+
+		_, lp, err = lp.PredicatePushDown(nil)
+		c.Assert(err, IsNil)
+		lp.PruneColumns(lp.GetSchema())
+		lp.ResolveIndicesAndCorCols()
+		info, err := lp.convert2PhysicalPlan(&requiredProperty{})
+		c.Assert(err, IsNil)
+		c.Assert(collectApplyCacheInner(info.p), DeepEquals, ca.cacheInner, comment)
+	}
+}
+
+func (s *testPlanSuite) TestGenerateSeries(c *C) {
+	defer testleak.AfterTest(c)()
+	cases := []struct {
+		sql   string
+		best  string
+		count uint64
+	}{
+		{
+			sql:   "select * from generate_series(1, 10) order by generate_series",
+			best:  "Series(1,10,1)",
+			count: 10,
+		},
+		{
+			sql:   "select * from generate_series(1, 10, 4) s order by s.generate_series desc",
+			best:  "Series(1,10,4)->Sort",
+			count: 3,
+		},
+		{
+			sql:   "select * from generate_series(10, 1, -1) order by generate_series desc limit 2",
+			best:  "Series(10,1,-1)->Limit",
+			count: 2,
+		},
+		{
+			sql:   "select * from generate_series(1, -1)",
+			best:  "Series(1,-1,1)",
+			count: 0,
+		},
+		{
+			sql:   "select * from generate_series(-9223372036854775808, 9223372036854775807, 9223372036854775807)",
+			best:  "Series(-9223372036854775808,9223372036854775807,9223372036854775807)",
+			count: 3,
+		},
+		{
+			sql:   "select * from t, generate_series(1, 100) g where t.a = g.generate_series",
+			best:  "MergeJoin{Table(t)->Series(1,100,1)}(test.t.a,g.generate_series)",
+			count: 300000000,
+		},
+		{
+			sql:   "select * from t, generate_series(1, 1e8) g where t.a = g.generate_series",
+			best:  "MergeJoin{Table(t)->Series(1,100000000,1)}(test.t.a,g.generate_series)",
+			count: math.MaxInt32,
+		},
+	}
+	for _, ca := range cases {
+		comment := Commentf("for %s", ca.sql)
+		stmt, err := s.ParseOneStmt(ca.sql, "", "")
+		c.Assert(err, IsNil, comment)
+
+		err = mockResolve(stmt)
+		c.Assert(err, IsNil)
+
+		builder := &planBuilder{
+			allocator: new(idAllocator),
+			ctx:       mock.NewContext(),
+			colMapper: make(map[*ast.ColumnNameExpr]int),
+		}
+		p := builder.build(stmt)
+		c.Assert(builder.err, IsNil)
+		lp := p.(LogicalPlan)
+
+		_, lp, err = lp.PredicatePushDown(nil)
+		c.Assert(err, IsNil)
+		lp.PruneColumns(lp.GetSchema())
+		lp.ResolveIndicesAndCorCols()
+		info, err := lp.convert2PhysicalPlan(&requiredProperty{})
+		c.Assert(err, IsNil)
+		c.Assert(ToString(EliminateProjection(info.p)), Equals, ca.best, comment)
+		c.Assert(info.count, Equals, ca.count, comment)
+	}
+}
+
+func (s *testPlanSuite) TestJSONTable(c *C) {
+	defer testleak.AfterTest(c)()
+	cases := []struct {
+		sql  string
+		best string
+	}{
+		{
+			sql:  "select * from json_table('[1, 2]', '$[*]' columns (a int path '$')) j",
+			best: "JSONTable($[*])",
+		},
+		{
+			sql:  "select * from json_table('[1, 2]', '$[*]' columns (a int path '$')) j order by a",
+			best: "JSONTable($[*])->Sort",
+		},
+		{
+			sql:  "select * from t, json_table('[1, 2]', '$[*]' columns (a int path '$')) j where t.a = j.a",
+			best: "LeftHashJoin{Table(t)->JSONTable($[*])}(test.t.a,j.a)",
+		},
+		{
+			sql:  "select * from t, json_table(t.c, '$[*]' columns (n for ordinality, a int path '$')) j where t.b = 1 and j.a > t.a",
+			best: "Table(t)->Apply(JSONTable($[*]))->Selection",
+		},
+		{
+			sql:  "select * from t join json_table(t.c, '$[*]' columns (a int path '$')) j on j.a = t.a",
+			best: "Table(t)->Apply(JSONTable($[*]))->Selection",
+		},
+	}
+	for _, ca := range cases {
+		comment := Commentf("for %s", ca.sql)
+		stmt, err := s.ParseOneStmt(ca.sql, "", "")
+		c.Assert(err, IsNil, comment)
+
+		err = mockResolve(stmt)
+		c.Assert(err, IsNil)
+
+		builder := &planBuilder{
+			allocator: new(idAllocator),
+			ctx:       mock.NewContext(),
+			colMapper: make(map[*ast.ColumnNameExpr]int),
+		}
+		p := builder.build(stmt)
+		c.Assert(builder.err, IsNil)
+		lp := p.(LogicalPlan)
+
+		_, lp, err = lp.PredicatePushDown(nil)
+		c.Assert(err, IsNil)
+		lp.PruneColumns(lp.GetSchema())
+		lp.ResolveIndicesAndCorCols()
+		info, err := lp.convert2PhysicalPlan(&requiredProperty{})
+		c.Assert(err, IsNil)
+		c.Assert(ToString(EliminateProjection(info.p)), Equals, ca.best, comment)
+	}
+}
+
+func (s *testPlanSuite) TestProfile(c *C) {
+	defer testleak.AfterTest(c)()
+	cases := []struct {
+		sql  string
+		best string
+	}{
+		{
+			sql:  "select * from tidb_profile('heap')",
+			best: "Profile(heap)",
+		},
+		{
+			sql:  "select name, flat from tidb_profile('CPU', 2) p where flat > 0 order by cum",
+			best: "Profile(cpu)->Selection->Projection->Sort->Trim",
+		},
+	}
+	for _, ca := range cases {
+		comment := Commentf("for %s", ca.sql)
+		stmt, err := s.ParseOneStmt(ca.sql, "", "")
+		c.Assert(err, IsNil, comment)
+
+		err = mockResolve(stmt)
+		c.Assert(err, IsNil)
+
+		builder := &planBuilder{
+			allocator: new(idAllocator),
+			ctx:       mock.NewContext(),
+			colMapper: make(map[*ast.ColumnNameExpr]int),
+		}
+		p := builder.build(stmt)
+		c.Assert(builder.err, IsNil)
+		lp := p.(LogicalPlan)
+
+		_, lp, err = lp.PredicatePushDown(nil)
+		c.Assert(err, IsNil)
+		lp.PruneColumns(lp.GetSchema())
+		lp.ResolveIndicesAndCorCols()
+		info, err := lp.convert2PhysicalPlan(&requiredProperty{})
+		c.Assert(err, IsNil)
+		c.Assert(ToString(EliminateProjection(info.p)), Equals, ca.best, comment)
+	}
+}
+
+func (s *testPlanSuite) TestRowHistoryUnsupported(c *C) {
+	defer testleak.AfterTest(c)()
+	stmt, err := s.ParseOneStmt("select * from tidb_row_history('t', 1)", "", "")
+	c.Assert(err, IsNil)
+	err = mockResolve(stmt)
+	c.Assert(err, IsNil)
+
+	// The mock context has no storage to read the history versions, the statement fails before it's executed.
+	builder := &planBuilder{
+		allocator: new(idAllocator),
+		ctx:       mock.NewContext(),
+		colMapper: make(map[*ast.ColumnNameExpr]int),
+	}
+	builder.build(stmt)
+	c.Assert(terror.ErrorEqual(builder.err, ErrUnsupportedType), IsTrue, Commentf("%v", builder.err))
+}
+
+func (s *testPlanSuite) TestDistinctAggPushDown(c *C) {
+	defer testleak.AfterTest(c)()
+	cases := []struct {
+		sql  string
+		best string
+		// aggFuncs and gbyItems are the aggregation pushed to the coprocessor.
+		aggFuncs string
+		gbyItems string
+	}{
+		{
+			sql:      "select count(distinct b) from t",
+			best:     "Table(t)->HashAgg->HashAgg",
+			aggFuncs: "[firstrow(test.t.b)]",
+			gbyItems: "[test.t.b]",
+		},
+		{
+			sql:      "select a, count(distinct b), sum(distinct b), max(b) from t group by a, c",
+			best:     "Table(t)->HashAgg->HashAgg",
+			aggFuncs: "[firstrow(test.t.a) firstrow(test.t.c) firstrow(test.t.b)]",
+			gbyItems: "[test.t.a test.t.c test.t.b]",
+		},
+		{
+			sql:      "select count(distinct b), count(c) from t group by a",
+			best:     "Table(t)->StreamAgg",
+			aggFuncs: "[]",
+			gbyItems: "[]",
+		},
+		{
+			sql:      "select count(distinct b) from t group by a + c",
+			best:     "Table(t)->HashAgg",
+			aggFuncs: "[]",
+			gbyItems: "[]",
+		},
+		{
+			sql:      "select count(distinct b), count(distinct c) from t",
+			best:     "Table(t)->StreamAgg",
+			aggFuncs: "[]",
+			gbyItems: "[]",
+		},
+	}
+	for _, ca := range cases {
+		comment := Commentf("for %s", ca.sql)
+		stmt, err := s.ParseOneStmt(ca.sql, "", "")
+		c.Assert(err, IsNil, comment)
+
+		err = mockResolve(stmt)
+		c.Assert(err, IsNil)
+
+		builder := &planBuilder{
+			allocator: new(idAllocator),
+			ctx:       mockContext(),
+			colMapper: make(map[*ast.ColumnNameExpr]int),
+		}
+		p := builder.build(stmt)
+		c.Assert(builder.err, IsNil)
+		lp := p.(LogicalPlan)
+
+		_, lp, err = lp.PredicatePushDown(nil)
+		c.Assert(err, IsNil)
+		lp.PruneColumns(lp.GetSchema())
+		lp.ResolveIndicesAndCorCols()
+		info, err := lp.convert2PhysicalPlan(&requiredProperty{})
+		c.Assert(err, IsNil)
+		pp := EliminateProjection(info.p)
+		c.Assert(ToString(pp), Equals, ca.best, comment)
+		for ; len(pp.GetChildren()) > 0; pp = pp.GetChildByIndex(0).(PhysicalPlan) {
+		}
+		ts := pp.(*PhysicalTableScan)
+		c.Assert(fmt.Sprint(ts.aggFuncs), Equals, ca.aggFuncs, comment)
+		c.Assert(fmt.Sprint(ts.gbyItems), Equals, ca.gbyItems, comment)
+	}
+}
+
+func (s *testPlanSuite) TestStreamAggDistinctSorted(c *C) {
+	defer testleak.AfterTest(c)()
+	cases := []struct {
+		sql            string
+		best           string
+		distinctSorted bool
+	}{
+		{
+			sql:            "select count(distinct e) from t where c = 1 group by d",
+			best:           "Index(t.c_d_e)[[1,1]]->StreamAgg",
+			distinctSorted: true,
+		},
+		{
+			sql:            "select count(distinct e), sum(distinct e), count(b) from t where c = 1 group by d",
+			best:           "Index(t.c_d_e)[[1,1]]->StreamAgg",
+			distinctSorted: true,
+		},
+		{
+			sql:            "select count(distinct c) from t",
+			best:           "Table(t)->StreamAgg",
+			distinctSorted: false,
+		},
+		{
+			sql:            "select count(distinct e), sum(distinct b) from t where c = 1 group by d",
+			best:           "Index(t.c_d_e)[[1,1]]->StreamAgg",
+			distinctSorted: false,
+		},
+		{
+			sql:            "select count(distinct d) from t where c = 1 group by d",
+			best:           "Index(t.c_d_e)[[1,1]]->StreamAgg",
+			distinctSorted: false,
+		},
+	}
+	for _, ca := range cases {
+		comment := Commentf("for %s", ca.sql)
+		stmt, err := s.ParseOneStmt(ca.sql, "", "")
+		c.Assert(err, IsNil, comment)
+
+		err = mockResolve(stmt)
+		c.Assert(err, IsNil)
+
+		builder := &planBuilder{
+			allocator: new(idAllocator),
+			ctx:       mock.NewContext(),
+			colMapper: make(map[*ast.ColumnNameExpr]int),
+		}
+		p := builder.build(stmt)
+		c.Assert(builder.err, IsNil)
+		lp := p.(LogicalPlan)
+
+		_, lp, err = lp.PredicatePushDown(nil)
+		c.Assert(err, IsNil)
+		lp.PruneColumns(lp.GetSchema())
+		lp.ResolveIndicesAndCorCols()
+		info, err := lp.convert2PhysicalPlan(&requiredProperty{})
+		c.Assert(err, IsNil)
+		pp := EliminateProjection(info.p)
+		c.Assert(ToString(pp), Equals, ca.best, comment)
+		for ; len(pp.GetChildren()) > 0; pp = pp.GetChildByIndex(0).(PhysicalPlan) {
+			if agg, ok := pp.(*PhysicalAggregation); ok {
+				c.Assert(agg.DistinctSorted, Equals, ca.distinctSorted, comment)
+				break
+			}
+		}
+	}
+}
+
+func (s *testPlanSuite) TestProjectionElimination(c *C) {
+	defer testleak.AfterTest(c)()
+	cases := []struct {
+		sql string
+		ans string
+	}{
+		// projection can be eliminated in following cases.
+		{
+			sql: "select a from t",
+			ans: "Table(t)",
+		},
+		{
+			sql: "select a from t where a > 1",
+			ans: "Table(t)",
+		},
+		{
+			sql: "select a from t where a is null",
+			ans: "Dummy",
+		},
+		{
+			sql: "select a, b from t where b > 0",
+			ans: "Table(t)->Selection",
+		},
+		{
+			sql: "select a as c1, b as c2 from t where a = 3",
+			ans: "Table(t)",
+		},
+		{
+			sql: "select a as c1, b as c2 from t as t1 where t1.a = 0",
+			ans: "Table(t)",
+		},
+		{
+			sql: "select a from t where exists(select 1 from t as x where x.a < t.a)",
+			ans: "SemiJoin{Table(t)->Table(t)}",
+		},
+		{
+			sql: "select a from (select d as a from t where d = 0) k where k.a = 5",
+			ans: "Dummy",
+		},
+		{
+			sql: "select t1.a from t t1 where t1.a in (select t2.a from t t2 where t2.a > 1)",
+			ans: "SemiJoin{Table(t)->Table(t)}",
+		},
+		{
+			sql: "select t1.a, t2.b from t t1, t t2 where t1.a > 0 and t2.b < 0",
+			ans: "RightHashJoin{Table(t)->Table(t)->Selection}",
+		},
+		{
+			sql: "select t1.a, t1.b, t2.a, t2.b from t t1, t t2 where t1.a > 0 and t2.b < 0",
+			ans: "RightHashJoin{Table(t)->Table(t)->Selection}",
+		},
+		{
+			sql: "select * from (t t1 join t t2) join (t t3 join t t4)",
+			ans: "LeftHashJoin{LeftHashJoin{Table(t)->Table(t)}->LeftHashJoin{Table(t)->Table(t)}}",
+		},
+		// projection can not be eliminated in following cases.
+		{
+			sql: "select t1.b, t1.a, t2.b, t2.a from t t1, t t2 where t1.a > 0 and t2.b < 0",
+			ans: "RightHashJoin{Table(t)->Table(t)->Selection}->Projection",
+		},
+		{
+			sql: "select d, c, b, a from t where a = b and b = 1",
+			ans: "Table(t)->Selection->Projection",
+		},
+		{
+			sql: "select d as a, b as c from t as t1 where d > 0 and b < 0",
+			ans: "Table(t)->Selection->Projection",
+		},
+		{
+			sql: "select c as a, c as b from t",
+			ans: "Table(t)->Projection",
+		},
+		{
+			sql: "select c as a, c as b from t where d > 0",
+			ans: "Table(t)->Selection->Projection",
+		},
+		{
+			sql: "select t1.a, t2.b, t2.a, t1.b from t t1, t t2 where t1.a > 0 and t2.b < 0",
+			ans: "RightHashJoin{Table(t)->Table(t)->Selection}->Projection",
+		},
+		{
+			sql: "select t1.a from t t1 where t1.a in (select t2.a from t t2 where t1.a > 1)",
+			ans: "Table(t)->Apply(Table(t)->Cache->Selection->Projection)->Selection->Projection",
+		},
+	}
+	for _, ca := range cases {
+		comment := Commentf("for %s", ca.sql)
+		stmt, err := s.ParseOneStmt(ca.sql, "", "")
+		c.Assert(err, IsNil, comment)
+		ast.SetFlag(stmt)
+
+		err = mockResolve(stmt)
+		c.Assert(err, IsNil)
+
+		builder := &planBuilder{
+			allocator: new(idAllocator),
+			ctx:       mock.NewContext(),
+		}
+		p := builder.build(stmt)
+		c.Assert(builder.err, IsNil)
+		lp := p.(LogicalPlan)
+		_, lp, err = lp.PredicatePushDown(nil)
+		c.Assert(err, IsNil)
+		lp.PruneColumns(lp.GetSchema())
+		lp.ResolveIndicesAndCorCols()
+		info, err := lp.convert2PhysicalPlan(&requiredProperty{})
+		p = EliminateProjection(info.p)
+		c.Assert(ToString(p), Equals, ca.ans, Commentf("for %s", ca.sql))
+	}
+}
+
+func (s *testPlanSuite) TestCoveringIndex(c *C) {
+	cases := []struct {
+		columnNames []string
+		indexNames  []string
+		indexLens   []int
+		isCovering  bool
+	}{
+		{[]string{"a"}, []string{"a"}, []int{-1}, true},
+		{[]string{"a"}, []string{"a", "b"}, []int{-1, -1}, true},
+		{[]string{"a", "b"}, []string{"b", "a"}, []int{-1, -1}, true},
+		{[]string{"a", "b"}, []string{"b", "c"}, []int{-1, -1}, false},
+		{[]string{"a", "b"}, []string{"a", "b"}, []int{50, -1}, false},
+		{[]string{"a", "b"}, []string{"a", "c"}, []int{-1, -1}, false},
+		{[]string{"id", "a"}, []string{"a", "b"}, []int{-1, -1}, true},
+	}
+	for _, ca := range cases {
+		var columns []*model.ColumnInfo
+		var pkIsHandle bool
+		for _, cn := range ca.columnNames {
+			col := &model.ColumnInfo{Name: model.NewCIStr(cn)}
+			if cn == "id" {
+				pkIsHandle = true
+				col.Flag = mysql.PriKeyFlag
+			}
+			columns = append(columns, col)
+		}
+		var indexCols []*model.IndexColumn
+		for i := range ca.indexNames {
+			icn := ca.indexNames[i]
+			icl := ca.indexLens[i]
+			indexCols = append(indexCols, &model.IndexColumn{Name: model.NewCIStr(icn), Length: icl})
+		}
+		covering := isCoveringIndex(columns, indexCols, pkIsHandle)
+		c.Assert(covering, Equals, ca.isCovering)
+	}
+}
+
+func (s *testPlanSuite) TestSkylinePruning(c *C) {
+	defer testleak.AfterTest(c)()
+	cases := []struct {
+		sql     string
+		orderBy string
+		indices []string
+	}{
+		{
+			sql:     "select * from t",
+			indices: []string{},
+		},
+		{
+			sql:     "select * from t where f = 1",
+			indices: []string{"f", "f_g"},
+		},
+		{
+			sql:     "select * from t where f = 1 and g = 1",
+			indices: []string{"f_g"},
+		},
+		{
+			sql:     "select * from t where f = 1 and g > 1",
+			indices: []string{"f_g"},
+		},
+		{
+			sql:     "select * from t where a = 1 and f = 1",
+			indices: []string{"f", "f_g"},
+		},
+		{
+			sql:     "select f, g from t",
+			indices: []string{"f_g"},
+		},
+		{
+			sql:     "select f from t where g = 1",
+			indices: []string{"g", "f_g"},
+		},
+		{
+			sql:     "select * from t",
+			orderBy: "c",
+			indices: []string{"c_d_e"},
+		},
+		{
+			sql:     "select * from t where c = 1",
+			orderBy: "d",
+			indices: []string{"c_d_e"},
+		},
+	}
+	for _, ca := range cases {
+		comment := Commentf("for %s", ca.sql)
+		stmt, err := s.ParseOneStmt(ca.sql, "", "")
+		c.Assert(err, IsNil, comment)
+		ast.SetFlag(stmt)
+
+		err = mockResolve(stmt)
+		c.Assert(err, IsNil)
+		builder := &planBuilder{
+			allocator: new(idAllocator),
+			ctx:       mockContext(),
+			colMapper: make(map[*ast.ColumnNameExpr]int),
+		}
+		p := builder.build(stmt)
+		c.Assert(builder.err, IsNil)
+		lp := p.(LogicalPlan)
+
+		_, lp, err = lp.PredicatePushDown(nil)
+		c.Assert(err, IsNil)
+		lp.PruneColumns(lp.GetSchema())
+		lp.ResolveIndicesAndCorCols()
+		for {
+			if _, ok := lp.(*DataSource); ok {
+				break
+			}
+			lp = lp.GetChildByIndex(0).(LogicalPlan)
+		}
+		ds := lp.(*DataSource)
+		prop := &requiredProperty{}
+		if ca.orderBy != "" {
+			for _, col := range ds.GetSchema() {
+				if col.ColName.L == ca.orderBy {
+					prop.props = []*columnProp{{col: col}}
+					prop.sortKeyLen = 1
+				}
+			}
+			c.Assert(prop.props, HasLen, 1, comment)
+		}
+		indices, includeTableScan := availableIndices(ds.indexHints, ds.Table)
+		kept := ds.skylinePruning(prop, indices, includeTableScan)
+		names := make([]string, 0, len(kept))
+		for _, index := range kept {
+			names = append(names, index.Name.L)
+		}
+		c.Assert(names, DeepEquals, ca.indices, comment)
+	}
+}
+
+func (s *testPlanSuite) TestPrefixSelectivity(c *C) {
+	defer testleak.AfterTest(c)()
+	tblInfo := &model.TableInfo{
+		ID: 1,
+		Columns: []*model.ColumnInfo{
+			{ID: 1, FieldType: *types.NewFieldType(mysql.TypeLonglong)},
+			{ID: 2, FieldType: *types.NewFieldType(mysql.TypeLonglong)},
+		},
+	}
+	// Column 0 has two values, and column 1 has 1000 distinct values.
+	samples := make([][]types.Datum, 2)
+	for i := 0; i < 1000; i++ {
+		samples[0] = append(samples[0], types.NewIntDatum(int64(i/500)))
+		samples[1] = append(samples[1], types.NewIntDatum(int64(i)))
+	}
+	statsTbl, err := statistics.NewTable(tblInfo, 1, 1000, 256, samples)
+	c.Assert(err, IsNil)
+
+	selectivity, err := prefixSelectivity(statsTbl, 0, types.NewIntDatum(1))
+	c.Assert(err, IsNil)
+	c.Assert(selectivity, Equals, 0.5)
+	// The selectivity doesn't collapse too fast.
+	selectivity, err = prefixSelectivity(statsTbl, 1, types.NewIntDatum(500))
+	c.Assert(err, IsNil)
+	c.Assert(selectivity, Equals, 0.01)
+	// There is no histogram in the pseudo statistics.
+	selectivity, err = prefixSelectivity(statistics.PseudoTable(tblInfo), 0, types.NewIntDatum(1))
+	c.Assert(err, IsNil)
+	c.Assert(selectivity, Equals, 0.01)
+}
+
+func (s *testPlanSuite) TestPointGetPlan(c *C) {
+	defer testleak.AfterTest(c)()
+	cases := []struct {
+		sql  string
+		best string
+	}{
+		{
+			sql:  "select * from t where a = 1",
+			best: "PointGet(t, 1)",
+		},
+		{
+			sql:  "select b, c as x from t t1 where (1 = t1.a)",
+			best: "PointGet(t, 1)",
+		},
+		{
+			sql:  "select * from t where a = 1 and a = 1",
+			best: "PointGet(t, 1)",
+		},
+		{
+			sql:  "select * from t where a in (3, 1, 2)",
+			best: "BatchPointGet(t, [3 1 2])",
+		},
+		{
+			sql:  "select b from t where (a in (1, null, 1))",
+			best: "BatchPointGet(t, [1 1])",
+		},
+		{
+			sql:  "select * from t where a in (1, '2')",
+			best: "BatchPointGet(t, [1 2])",
+		},
+		// The following queries go through the general optimization.
+		{
+			sql: "select * from t where a = 1 and a = 2",
+		},
+		{
+			sql: "select * from t where a = 1 and b = 1",
+		},
+		{
+			sql: "select * from t where a = 1.5",
+		},
+		{
+			sql: "select * from t where a = '1'",
+		},
+		{
+			sql: "select * from t where a = null",
+		},
+		{
+			sql: "select * from t where a > 1",
+		},
+		{
+			sql: "select b + 1 from t where a = 1",
+		},
+		{
+			sql: "select * from t where a = 1 for update",
+		},
+		{
+			sql: "select * from t where a = 1 order by b limit 1",
+		},
+		{
+			sql: "select * from t use index(c_d_e) where a = 1",
+		},
+		{
+			sql: "select /*+ DISABLE_RULES(point_get) */ * from t where a = 1",
+		},
+		{
+			sql: "select * from t where c = 1 and d = 1 and e = 1",
+		},
+		{
+			sql: "select * from t where a not in (1, 2)",
+		},
+		{
+			sql: "select * from t where a in (1, 1.5)",
+		},
+		{
+			sql: "select * from t where a in (1, b)",
+		},
+		{
+			sql: "select * from t where a in (select a from t)",
+		},
+		{
+			sql: "select * from t where f in (1, 2)",
+		},
+	}
+	for _, ca := range cases {
+		comment := Commentf("for %s", ca.sql)
+		stmt, err := s.ParseOneStmt(ca.sql, "", "")
+		c.Assert(err, IsNil, comment)
+		err = mockResolve(stmt)
+		c.Assert(err, IsNil, comment)
+		p := tryPointGetPlan(mockContext(), stmt, new(idAllocator))
+		if ca.best == "" {
+			c.Assert(p, IsNil, comment)
+			continue
+		}
+		c.Assert(p, NotNil, comment)
+		c.Assert(ToString(p), Equals, ca.best, comment)
 	}
 }
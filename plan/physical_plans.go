@@ -68,22 +68,23 @@ type physicalDistSQLPlan interface {
 }
 
 func (p *PhysicalIndexScan) calculateCost(count uint64) float64 {
+	factors := p.allocator.costFactors()
 	cnt := float64(count)
-	// network cost
-	cost := cnt * netWorkFactor
+	// scan and network cost
+	cost := cnt * factors.scanRow()
 	if p.DoubleRead {
 		cost *= 2
 	}
 	// sort cost
 	if !p.OutOfOrder && p.DoubleRead {
-		cost += float64(count) * cpuFactor
+		cost += float64(count) * factors.cpu
 	}
 	return cost
 }
 
 func (p *PhysicalTableScan) calculateCost(count uint64) float64 {
 	cnt := float64(count)
-	return cnt * netWorkFactor
+	return cnt * p.allocator.costFactors().scanRow()
 }
 
 type physicalTableSource struct {
@@ -302,6 +303,21 @@ type PhysicalTableScan struct {
 	KeepOrder bool
 }
 
+// PhysicalIndexMerge represents an index merge plan. It reads the handles from the index of every partial plan,
+// then reads the rows of the union of these handles from the table in the handle order.
+type PhysicalIndexMerge struct {
+	basePlan
+	physicalTableSource
+
+	// PartialPlans are the index scans that only read the handles.
+	PartialPlans []*PhysicalIndexScan
+	Table        *model.TableInfo
+	Columns      []*model.ColumnInfo
+	DBName       *model.CIStr
+
+	TableAsName *model.CIStr
+}
+
 // PhysicalDummyScan is a dummy table that returns nothing.
 type PhysicalDummyScan struct {
 	basePlan
@@ -314,6 +330,9 @@ type PhysicalApply struct {
 	InnerPlan   PhysicalPlan
 	OuterSchema []*expression.CorrelatedColumn
 	Checker     *ApplyConditionChecker
+	Lateral     bool
+	// CacheInner means the inner results can be memoized by the values of the OuterSchema.
+	CacheInner bool
 }
 
 // PhysicalHashJoin represents hash join for inner/ outer join.
@@ -328,6 +347,42 @@ type PhysicalHashJoin struct {
 	OtherConditions []expression.Expression
 	SmallTable      int
 	Concurrency     int
+	// KeepOrder means the rows are output in the order of the big table, the join workers still probe
+	// concurrently, and their results are merged by the order of the big table batches.
+	KeepOrder bool
+
+	DefaultValues []types.Datum
+}
+
+// PhysicalMergeJoin represents sort merge join for inner/ outer join, both children are ordered by the join keys.
+type PhysicalMergeJoin struct {
+	basePlan
+
+	JoinType JoinType
+
+	EqualConditions []*expression.ScalarFunction
+	LeftConditions  []expression.Expression
+	RightConditions []expression.Expression
+	OtherConditions []expression.Expression
+
+	DefaultValues []types.Datum
+}
+
+// PhysicalIndexJoin represents index nested loop join for inner/ outer join. The outer rows are read in batches,
+// and the inner rows of a batch are read by looking up the index or the handle of the inner table with the join keys
+// of the batch, instead of reading the whole inner table.
+type PhysicalIndexJoin struct {
+	basePlan
+
+	JoinType JoinType
+	// OuterIndex is the index of the outer child.
+	OuterIndex int
+
+	// EqualConditions are the equal conditions of the join, the inner child is looked up by the first one.
+	EqualConditions []*expression.ScalarFunction
+	LeftConditions  []expression.Expression
+	RightConditions []expression.Expression
+	OtherConditions []expression.Expression
 
 	DefaultValues []types.Datum
 }
@@ -338,6 +393,8 @@ type PhysicalHashSemiJoin struct {
 
 	WithAux bool
 	Anti    bool
+	// NullAware means a NULL comparison of the join conditions makes the result NULL if no row matches.
+	NullAware bool
 
 	EqualConditions []*expression.ScalarFunction
 	LeftConditions  []expression.Expression
@@ -365,6 +422,9 @@ type PhysicalAggregation struct {
 	AggType      AggregationType
 	AggFuncs     []expression.AggregationFunction
 	GroupByItems []expression.Expression
+	// DistinctSorted means the input of a stream aggregation is also sorted by the argument of the distinct
+	// aggregate functions in every group.
+	DistinctSorted bool
 }
 
 // PhysicalUnionScan represents a union scan operator.
@@ -428,6 +488,36 @@ func (p *PhysicalTableScan) MarshalJSON() ([]byte, error) {
 	return buffer.Bytes(), nil
 }
 
+// Copy implements the PhysicalPlan Copy interface.
+func (p *PhysicalIndexMerge) Copy() PhysicalPlan {
+	np := *p
+	return &np
+}
+
+// MarshalJSON implements json.Marshaler interface.
+func (p *PhysicalIndexMerge) MarshalJSON() ([]byte, error) {
+	pushDownInfo, err := json.Marshal(&p.physicalTableSource)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	partialPlans := make([]string, 0, len(p.PartialPlans))
+	for _, partial := range p.PartialPlans {
+		partialPlans = append(partialPlans, partial.GetID())
+	}
+	partials, err := json.Marshal(partialPlans)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	buffer := bytes.NewBufferString("{")
+	buffer.WriteString(fmt.Sprintf(
+		" \"db\": \"%s\","+
+			"\n \"table\": \"%s\","+
+			"\n \"partial plans\": %s,"+
+			"\n \"push down info\": %s}",
+		p.DBName.O, p.Table.Name.O, partials, pushDownInfo))
+	return buffer.Bytes(), nil
+}
+
 // Copy implements the PhysicalPlan Copy interface.
 func (p *PhysicalApply) Copy() PhysicalPlan {
 	np := *p
@@ -444,7 +534,8 @@ func (p *PhysicalApply) MarshalJSON() ([]byte, error) {
 	buffer.WriteString(fmt.Sprintf(
 		"\"innerPlan\": \"%s\",\n "+
 			"\"outerPlan\": \"%s\",\n "+
-			"\"condition\": %s\n}", p.InnerPlan.GetID(), p.children[0].GetID(), checker))
+			"\"cacheInner\": %v,\n "+
+			"\"condition\": %s\n}", p.InnerPlan.GetID(), p.children[0].GetID(), p.CacheInner, checker))
 	return buffer.Bytes(), nil
 }
 
@@ -478,6 +569,7 @@ func (p *PhysicalHashSemiJoin) MarshalJSON() ([]byte, error) {
 	buffer.WriteString(fmt.Sprintf(
 		"\"with aux\": %v,"+
 			"\"anti\": %v,"+
+			"\"nullAware\": %v,"+
 			"\"eqCond\": %s,\n "+
 			"\"leftCond\": %s,\n "+
 			"\"rightCond\": %s,\n "+
@@ -485,7 +577,7 @@ func (p *PhysicalHashSemiJoin) MarshalJSON() ([]byte, error) {
 			"\"leftPlan\": \"%s\",\n "+
 			"\"rightPlan\": \"%s\""+
 			"}",
-		p.WithAux, p.Anti, eqConds, leftConds, rightConds, otherConds, leftChild.GetID(), rightChild.GetID()))
+		p.WithAux, p.Anti, p.NullAware, eqConds, leftConds, rightConds, otherConds, leftChild.GetID(), rightChild.GetID()))
 	return buffer.Bytes(), nil
 }
 
@@ -528,6 +620,85 @@ func (p *PhysicalHashJoin) MarshalJSON() ([]byte, error) {
 	return buffer.Bytes(), nil
 }
 
+// Copy implements the PhysicalPlan Copy interface.
+func (p *PhysicalMergeJoin) Copy() PhysicalPlan {
+	np := *p
+	return &np
+}
+
+// MarshalJSON implements json.Marshaler interface.
+func (p *PhysicalMergeJoin) MarshalJSON() ([]byte, error) {
+	leftChild := p.children[0].(PhysicalPlan)
+	rightChild := p.children[1].(PhysicalPlan)
+	eqConds, err := json.Marshal(p.EqualConditions)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	leftConds, err := json.Marshal(p.LeftConditions)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	rightConds, err := json.Marshal(p.RightConditions)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	otherConds, err := json.Marshal(p.OtherConditions)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	buffer := bytes.NewBufferString("{")
+	buffer.WriteString(fmt.Sprintf(
+		"\"eqCond\": %s,\n "+
+			"\"leftCond\": %s,\n "+
+			"\"rightCond\": %s,\n "+
+			"\"otherCond\": %s,\n"+
+			"\"leftPlan\": \"%s\",\n "+
+			"\"rightPlan\": \"%s\""+
+			"}",
+		eqConds, leftConds, rightConds, otherConds, leftChild.GetID(), rightChild.GetID()))
+	return buffer.Bytes(), nil
+}
+
+// Copy implements the PhysicalPlan Copy interface.
+func (p *PhysicalIndexJoin) Copy() PhysicalPlan {
+	np := *p
+	return &np
+}
+
+// MarshalJSON implements json.Marshaler interface.
+func (p *PhysicalIndexJoin) MarshalJSON() ([]byte, error) {
+	leftChild := p.children[0].(PhysicalPlan)
+	rightChild := p.children[1].(PhysicalPlan)
+	eqConds, err := json.Marshal(p.EqualConditions)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	leftConds, err := json.Marshal(p.LeftConditions)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	rightConds, err := json.Marshal(p.RightConditions)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	otherConds, err := json.Marshal(p.OtherConditions)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	buffer := bytes.NewBufferString("{")
+	buffer.WriteString(fmt.Sprintf(
+		"\"eqCond\": %s,\n "+
+			"\"leftCond\": %s,\n "+
+			"\"rightCond\": %s,\n "+
+			"\"otherCond\": %s,\n"+
+			"\"outerPlan\": \"%s\",\n "+
+			"\"leftPlan\": \"%s\",\n "+
+			"\"rightPlan\": \"%s\""+
+			"}",
+		eqConds, leftConds, rightConds, otherConds, p.children[p.OuterIndex].GetID(), leftChild.GetID(), rightChild.GetID()))
+	return buffer.Bytes(), nil
+}
+
 // Copy implements the PhysicalPlan Copy interface.
 func (p *Distinct) Copy() PhysicalPlan {
 	np := *p
@@ -598,12 +769,15 @@ func (p *Limit) Copy() PhysicalPlan {
 
 // MarshalJSON implements json.Marshaler interface.
 func (p *Limit) MarshalJSON() ([]byte, error) {
-	child := p.children[0].(PhysicalPlan)
 	buffer := bytes.NewBufferString("{")
 	buffer.WriteString(fmt.Sprintf(
 		" \"limit\": %d,\n"+
-			" \"offset\": %d,\n"+
-			" \"child\": \"%s\"}", p.Count, p.Offset, child.GetID()))
+			" \"offset\": %d", p.Count, p.Offset))
+	// The ExecLimit of a Sort has no child.
+	if len(p.children) > 0 {
+		buffer.WriteString(fmt.Sprintf(",\n \"child\": \"%s\"", p.children[0].GetID()))
+	}
+	buffer.WriteString("}")
 	return buffer.Bytes(), nil
 }
 
@@ -613,6 +787,32 @@ func (p *Union) Copy() PhysicalPlan {
 	return &np
 }
 
+// MarshalJSON implements json.Marshaler interface.
+func (p *Union) MarshalJSON() ([]byte, error) {
+	children := make([]string, 0, len(p.children))
+	for _, child := range p.children {
+		children = append(children, child.GetID())
+	}
+	childrenStrs, err := json.Marshal(children)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	resultTypes := make([]string, 0, len(p.schema))
+	for _, col := range p.schema {
+		resultTypes = append(resultTypes, col.RetType.String())
+	}
+	typeStrs, err := json.Marshal(resultTypes)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	buffer := bytes.NewBufferString("{")
+	buffer.WriteString(fmt.Sprintf(
+		" \"children\": %s,\n"+
+			" \"result types\": %s", childrenStrs, typeStrs))
+	buffer.WriteString("}")
+	return buffer.Bytes(), nil
+}
+
 // Copy implements the PhysicalPlan Copy interface.
 func (p *Sort) Copy() PhysicalPlan {
 	np := *p
@@ -643,6 +843,36 @@ func (p *TableDual) Copy() PhysicalPlan {
 	return &np
 }
 
+// Copy implements the PhysicalPlan Copy interface.
+func (p *GenerateSeries) Copy() PhysicalPlan {
+	np := *p
+	return &np
+}
+
+// Copy implements the PhysicalPlan Copy interface.
+func (p *JSONTable) Copy() PhysicalPlan {
+	np := *p
+	return &np
+}
+
+// Copy implements the PhysicalPlan Copy interface.
+func (p *Profile) Copy() PhysicalPlan {
+	np := *p
+	return &np
+}
+
+// Copy implements the PhysicalPlan Copy interface.
+func (p *RowHistory) Copy() PhysicalPlan {
+	np := *p
+	return &np
+}
+
+// Copy implements the PhysicalPlan Copy interface.
+func (p *CTE) Copy() PhysicalPlan {
+	np := *p
+	return &np
+}
+
 // Copy implements the PhysicalPlan Copy interface.
 func (p *Trim) Copy() PhysicalPlan {
 	np := *p
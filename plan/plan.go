@@ -39,6 +39,8 @@ const (
 	Ts = "TableScan"
 	// Idx is the type of IndexScan.
 	Idx = "IndexScan"
+	// IdxMerge is the type of IndexMerge.
+	IdxMerge = "IndexMerge"
 	// Srt is the type of Sort.
 	Srt = "Sort"
 	// Lim is the type of Limit.
@@ -55,6 +57,18 @@ const (
 	Ext = "Exists"
 	// Dual is the type of TableDual.
 	Dual = "TableDual"
+	// Series is the type of GenerateSeries.
+	Series = "GenerateSeries"
+	// Cach is the type of Cache.
+	Cach = "Cache"
+	// JSONTbl is the type of JSONTable.
+	JSONTbl = "JSONTable"
+	// ProfileTbl is the type of Profile.
+	ProfileTbl = "Profile"
+	// RowHistoryTbl is the type of RowHistory.
+	RowHistoryTbl = "RowHistory"
+	// CTETbl is the type of CTE.
+	CTETbl = "CTE"
 	// Lock is the type of SelectLock.
 	Lock = "SelectLock"
 	// Load is the type of LoadData.
@@ -95,6 +109,10 @@ type Plan interface {
 	GetID() string
 	// Check whether this plan is correlated or not.
 	IsCorrelated() bool
+	// EstimatedRowCount returns the row count of the physical plan estimated by the optimizer.
+	EstimatedRowCount() uint64
+	// EstimatedCost returns the cost of the physical plan and its children estimated by the optimizer.
+	EstimatedCost() float64
 	// SetParents sets the parents for the plan.
 	SetParents(...Plan)
 	// SetParents sets the children for the plan.
@@ -176,6 +194,12 @@ type PhysicalPlan interface {
 
 	// Copy copies the current plan.
 	Copy() PhysicalPlan
+
+	// setStats sets the estimated row count and cost of the plan.
+	setStats(count uint64, cost float64)
+
+	// costFactors returns the cost factors of the optimization the plan is built in.
+	costFactors() *costFactors
 }
 
 type baseLogicalPlan struct {
@@ -219,6 +243,7 @@ func (p *baseLogicalPlan) storePlanInfo(prop *requiredProperty, info *physicalPl
 	}
 	newInfo := *info // copy it
 	p.planMap[string(key)] = &newInfo
+	setEstimatedStats(info)
 	return nil
 }
 
@@ -248,6 +273,7 @@ func (p *baseLogicalPlan) PredicatePushDown(predicates []expression.Expression)
 			return nil, nil, errors.Trace(err)
 		}
 	}
+	p.updateCorrelated()
 	return nil, p.self, nil
 }
 
@@ -277,6 +303,10 @@ func (p *baseLogicalPlan) PruneColumns(parentUsedCols []*expression.Column) {
 	p.SetSchema(child.GetSchema())
 }
 
+func (p *basePlan) costFactors() *costFactors {
+	return p.allocator.costFactors()
+}
+
 func (p *basePlan) initID() {
 	p.id = p.tp + p.allocator.allocID()
 }
@@ -293,6 +323,10 @@ type basePlan struct {
 	tp        string
 	id        string
 	allocator *idAllocator
+
+	// count and cost are estimated by the optimizer, they're set when the physical plan is chosen.
+	count uint64
+	cost  float64
 }
 
 // MarshalJSON implements json.Marshaler interface.
@@ -316,11 +350,34 @@ func (p *basePlan) IsCorrelated() bool {
 	return p.correlated
 }
 
+// updateCorrelated marks the plan correlated if any of its children is, it's called when the correlated conditions
+// may be pushed down to the children, so the plan isn't cached as an uncorrelated one.
+func (p *basePlan) updateCorrelated() {
+	for _, child := range p.children {
+		p.correlated = p.correlated || child.IsCorrelated()
+	}
+}
+
 // GetID implements Plan GetID interface.
 func (p *basePlan) GetID() string {
 	return p.id
 }
 
+// EstimatedRowCount implements Plan EstimatedRowCount interface.
+func (p *basePlan) EstimatedRowCount() uint64 {
+	return p.count
+}
+
+// EstimatedCost implements Plan EstimatedCost interface.
+func (p *basePlan) EstimatedCost() float64 {
+	return p.cost
+}
+
+func (p *basePlan) setStats(count uint64, cost float64) {
+	p.count = count
+	p.cost = cost
+}
+
 // SetSchema implements Plan SetSchema interface.
 func (p *basePlan) SetSchema(schema expression.Schema) {
 	p.schema = schema
@@ -14,6 +14,10 @@
 package plan
 
 import (
+	"math"
+	"strings"
+	"time"
+
 	"github.com/juju/errors"
 	"github.com/pingcap/tidb/ast"
 	"github.com/pingcap/tidb/context"
@@ -51,9 +55,24 @@ type planBuilder struct {
 	outerSchemas []expression.Schema
 	// colMapper stores the column that must be pre-resolved.
 	colMapper map[*ast.ColumnNameExpr]int
+	// disabledRules stores the logical rules disabled by the session variable or the hints.
+	disabledRules ruleSet
+	// tableHintInfo is the stack of the optimizer hints of the query blocks being built.
+	tableHintInfo []*tableHintInfo
+	// qbHints are the hints written for the query blocks named by QB_NAME.
+	qbHints *queryBlockHints
+	// cteSources stores the sources of the materialized common table expressions built so far.
+	cteSources map[*ast.CommonTableExpr]*CTESource
+	// viewScopes is the stack of the views being built.
+	viewScopes []viewScope
+	// inExplain tells whether the plan is built to be explained, which shows the tables read by the views.
+	inExplain bool
 }
 
 func (b *planBuilder) build(node ast.Node) Plan {
+	if b.qbHints == nil {
+		b.collectQueryBlockHints(node)
+	}
 	switch x := node.(type) {
 	case *ast.AdminStmt:
 		return b.buildAdmin(x)
@@ -65,6 +84,8 @@ func (b *planBuilder) build(node ast.Node) Plan {
 		return b.buildDDL(x)
 	case *ast.CreateTableStmt:
 		return b.buildDDL(x)
+	case *ast.CreateViewStmt:
+		return b.buildDDL(x)
 	case *ast.DeallocateStmt:
 		return &Deallocate{Name: x.Name}
 	case *ast.DeleteStmt:
@@ -86,6 +107,9 @@ func (b *planBuilder) build(node ast.Node) Plan {
 	case *ast.PrepareStmt:
 		return b.buildPrepare(x)
 	case *ast.SelectStmt:
+		if x.IntoOutfile != nil {
+			return b.buildSelectInto(x)
+		}
 		return b.buildSelect(x)
 	case *ast.UnionStmt:
 		return b.buildUnion(x)
@@ -94,7 +118,8 @@ func (b *planBuilder) build(node ast.Node) Plan {
 	case *ast.ShowStmt:
 		return b.buildShow(x)
 	case *ast.AnalyzeTableStmt, *ast.BinlogStmt, *ast.FlushTableStmt, *ast.UseStmt, *ast.SetStmt, *ast.DoStmt, *ast.BeginStmt,
-		*ast.CommitStmt, *ast.RollbackStmt, *ast.CreateUserStmt, *ast.SetPwdStmt, *ast.GrantStmt, *ast.DropUserStmt:
+		*ast.CommitStmt, *ast.RollbackStmt, *ast.CreateUserStmt, *ast.SetPwdStmt, *ast.GrantStmt, *ast.DropUserStmt,
+		*ast.CreateBindingStmt, *ast.DropBindingStmt, *ast.KillStmt:
 		return b.buildSimple(node.(ast.StmtNode))
 	case *ast.TruncateTableStmt:
 		return b.buildDDL(x)
@@ -178,15 +203,15 @@ func (b *planBuilder) extractSelectAgg(sel *ast.SelectStmt) []*ast.AggregateFunc
 	return extractor.AggFuncs
 }
 
-func availableIndices(table *ast.TableName) (indices []*model.IndexInfo, includeTableScan bool) {
+func availableIndices(hints []*ast.IndexHint, tableInfo *model.TableInfo) (indices []*model.IndexInfo, includeTableScan bool) {
 	var usableHints []*ast.IndexHint
-	for _, hint := range table.IndexHints {
+	for _, hint := range hints {
 		if hint.HintScope == ast.HintForScan {
 			usableHints = append(usableHints, hint)
 		}
 	}
-	publicIndices := make([]*model.IndexInfo, 0, len(table.TableInfo.Indices))
-	for _, index := range table.TableInfo.Indices {
+	publicIndices := make([]*model.IndexInfo, 0, len(tableInfo.Indices))
+	for _, index := range tableInfo.Indices {
 		if index.State == model.StatePublic {
 			publicIndices = append(publicIndices, index)
 		}
@@ -251,9 +276,10 @@ func findIndexByName(indices []*model.IndexInfo, name model.CIStr) *model.IndexI
 	return nil
 }
 
-func (b *planBuilder) buildSelectLock(src Plan, lock ast.SelectLockType) *SelectLock {
+func (b *planBuilder) buildSelectLock(src Plan, lock ast.SelectLockType, tables []*ast.TableName) *SelectLock {
 	selectLock := &SelectLock{
 		Lock:            lock,
+		Tables:          tables,
 		baseLogicalPlan: newBaseLogicalPlan(Lock, b.allocator),
 	}
 	selectLock.self = selectLock
@@ -264,15 +290,11 @@ func (b *planBuilder) buildSelectLock(src Plan, lock ast.SelectLockType) *Select
 }
 
 func (b *planBuilder) buildPrepare(x *ast.PrepareStmt) Plan {
-	p := &Prepare{
-		Name: x.Name,
+	return &Prepare{
+		Name:    x.Name,
+		SQLText: x.SQLText,
+		SQLVar:  x.SQLVar,
 	}
-	if x.SQLVar != nil {
-		p.SQLText, _ = x.SQLVar.GetValue().(string)
-	} else {
-		p.SQLText = x.SQLText
-	}
-	return p
 }
 
 func (b *planBuilder) buildAdmin(as *ast.AdminStmt) Plan {
@@ -284,6 +306,30 @@ func (b *planBuilder) buildAdmin(as *ast.AdminStmt) Plan {
 	case ast.AdminShowDDL:
 		p = &ShowDDL{}
 		p.SetSchema(buildShowDDLFields())
+	case ast.AdminShowDDLJobs:
+		p = &ShowDDLJobs{}
+		p.SetSchema(buildShowDDLJobsFields())
+	case ast.AdminShowDeleteRanges:
+		p = &ShowDeleteRanges{}
+		p.SetSchema(buildShowDeleteRangesFields())
+	case ast.AdminShowSlowPlans:
+		p = &ShowSlowPlans{}
+		p.SetSchema(buildShowSlowPlansFields())
+	case ast.AdminDiagnose:
+		p = &Diagnose{Query: as.Query}
+		p.SetSchema(buildDiagnoseFields())
+	case ast.AdminThrottleTable:
+		p = b.buildThrottleTable(as)
+	case ast.AdminSetStats:
+		p = b.buildSetStats(as)
+	case ast.AdminResetStats:
+		tables := make([]*model.TableInfo, 0, len(as.Tables))
+		for _, tn := range as.Tables {
+			tables = append(tables, tn.TableInfo)
+		}
+		p = &ResetStats{Tables: tables}
+	case ast.AdminRecoverIndex, ast.AdminCleanupIndex:
+		p = b.buildRepairIndex(as)
 	default:
 		b.err = ErrUnsupportedType.Gen("Unsupported type %T", as)
 	}
@@ -291,6 +337,75 @@ func (b *planBuilder) buildAdmin(as *ast.AdminStmt) Plan {
 	return p
 }
 
+// throttleUnits are the time units supported by the duration of ADMIN THROTTLE TABLE.
+var throttleUnits = map[string]time.Duration{
+	"SECOND": time.Second,
+	"MINUTE": time.Minute,
+	"HOUR":   time.Hour,
+	"DAY":    24 * time.Hour,
+}
+
+func (b *planBuilder) buildThrottleTable(as *ast.AdminStmt) Plan {
+	p := &ThrottleTable{Table: as.Tables[0].TableInfo, Limit: as.ThrottleLimit}
+	if as.ThrottleFor > 0 {
+		unit, ok := throttleUnits[strings.ToUpper(as.ThrottleUnit)]
+		if !ok {
+			b.err = ErrWrongArguments.Gen("Unsupported time unit %s of the throttle duration", as.ThrottleUnit)
+			return nil
+		}
+		p.Duration = time.Duration(as.ThrottleFor) * unit
+	}
+	return p
+}
+
+func (b *planBuilder) buildSetStats(as *ast.AdminStmt) Plan {
+	if as.StatsValue > math.MaxInt64 {
+		b.err = ErrWrongArguments.Gen("The statistics value %d is out of range", as.StatsValue)
+		return nil
+	}
+	p := &SetStats{Table: as.Tables[0].TableInfo, Value: int64(as.StatsValue)}
+	if as.StatsColumn != "" {
+		for _, col := range p.Table.Columns {
+			if col.State == model.StatePublic && col.Name.L == strings.ToLower(as.StatsColumn) {
+				p.Column = col
+				break
+			}
+		}
+		if p.Column == nil {
+			b.err = ErrUnknownColumn.Gen("Unknown column '%s' in '%s'", as.StatsColumn, p.Table.Name.O)
+			return nil
+		}
+	}
+	return p
+}
+
+func (b *planBuilder) buildRepairIndex(as *ast.AdminStmt) Plan {
+	tblInfo := as.Tables[0].TableInfo
+	var idxInfo *model.IndexInfo
+	for _, idx := range tblInfo.Indices {
+		if idx.State == model.StatePublic && idx.Name.L == strings.ToLower(as.Index) {
+			idxInfo = idx
+			break
+		}
+	}
+	if idxInfo == nil {
+		b.err = ErrKeyDoesNotExist.Gen("Key '%s' doesn't exist in table '%s'", as.Index, tblInfo.Name.O)
+		return nil
+	}
+	schema := make(expression.Schema, 0, 2)
+	var p Plan
+	if as.Tp == ast.AdminRecoverIndex {
+		p = &RecoverIndex{Table: tblInfo, Index: idxInfo}
+		schema = append(schema, buildColumn("", "ADDED_COUNT", mysql.TypeLonglong, 4))
+	} else {
+		p = &CleanupIndex{Table: tblInfo, Index: idxInfo}
+		schema = append(schema, buildColumn("", "REMOVED_COUNT", mysql.TypeLonglong, 4))
+	}
+	schema = append(schema, buildColumn("", "SCAN_COUNT", mysql.TypeLonglong, 4))
+	p.SetSchema(schema)
+	return p
+}
+
 func buildShowDDLFields() expression.Schema {
 	schema := make(expression.Schema, 0, 6)
 	schema = append(schema, buildColumn("", "SCHEMA_VER", mysql.TypeLonglong, 4))
@@ -303,6 +418,60 @@ func buildShowDDLFields() expression.Schema {
 	return schema
 }
 
+func buildShowDDLJobsFields() expression.Schema {
+	schema := make(expression.Schema, 0, 10)
+	schema = append(schema, buildColumn("", "JOB_ID", mysql.TypeLonglong, 4))
+	schema = append(schema, buildColumn("", "JOB_TYPE", mysql.TypeVarchar, 64))
+	schema = append(schema, buildColumn("", "SCHEMA_STATE", mysql.TypeVarchar, 64))
+	schema = append(schema, buildColumn("", "SCHEMA_ID", mysql.TypeLonglong, 4))
+	schema = append(schema, buildColumn("", "TABLE_ID", mysql.TypeLonglong, 4))
+	schema = append(schema, buildColumn("", "ROW_COUNT", mysql.TypeLonglong, 4))
+	schema = append(schema, buildColumn("", "HANDLE_RANGE", mysql.TypeVarchar, 64))
+	schema = append(schema, buildColumn("", "SPEED", mysql.TypeDouble, 8))
+	schema = append(schema, buildColumn("", "ETA", mysql.TypeVarchar, 64))
+	schema = append(schema, buildColumn("", "STATE", mysql.TypeVarchar, 64))
+
+	return schema
+}
+
+func buildShowDeleteRangesFields() expression.Schema {
+	schema := make(expression.Schema, 0, 6)
+	schema = append(schema, buildColumn("", "JOB_ID", mysql.TypeLonglong, 4))
+	schema = append(schema, buildColumn("", "JOB_TYPE", mysql.TypeVarchar, 64))
+	schema = append(schema, buildColumn("", "TABLE_ID", mysql.TypeLonglong, 4))
+	schema = append(schema, buildColumn("", "STATE", mysql.TypeVarchar, 64))
+	schema = append(schema, buildColumn("", "HAS_DATA", mysql.TypeLonglong, 1))
+	schema = append(schema, buildColumn("", "ORPHAN", mysql.TypeLonglong, 1))
+
+	return schema
+}
+
+// buildShowSlowPlansFields builds the schema of ADMIN SHOW SLOW PLANS, the latencies are in seconds.
+func buildShowSlowPlansFields() expression.Schema {
+	schema := make(expression.Schema, 0, 10)
+	schema = append(schema, buildColumn("", "DIGEST", mysql.TypeVarchar, 64))
+	schema = append(schema, buildColumn("", "PLAN_DIGEST", mysql.TypeVarchar, 64))
+	schema = append(schema, buildColumn("", "NORMALIZED_SQL", mysql.TypeVarchar, 4096))
+	schema = append(schema, buildColumn("", "SAMPLE_SQL", mysql.TypeVarchar, 4096))
+	schema = append(schema, buildColumn("", "PLAN_COUNT", mysql.TypeLonglong, 4))
+	schema = append(schema, buildColumn("", "EXEC_COUNT", mysql.TypeLonglong, 4))
+	schema = append(schema, buildColumn("", "AVG_LATENCY", mysql.TypeDouble, 8))
+	schema = append(schema, buildColumn("", "MAX_LATENCY", mysql.TypeDouble, 8))
+	schema = append(schema, buildColumn("", "FIRST_SEEN", mysql.TypeDatetime, 19))
+	schema = append(schema, buildColumn("", "LAST_SEEN", mysql.TypeDatetime, 19))
+
+	return schema
+}
+
+func buildDiagnoseFields() expression.Schema {
+	schema := make(expression.Schema, 0, 3)
+	schema = append(schema, buildColumn("", "CATEGORY", mysql.TypeVarchar, 64))
+	schema = append(schema, buildColumn("", "NAME", mysql.TypeVarchar, 64))
+	schema = append(schema, buildColumn("", "VALUE", mysql.TypeVarchar, 4096))
+
+	return schema
+}
+
 func buildColumn(tableName, name string, tp byte, size int) *expression.Column {
 	cs := charset.CharsetBin
 	cl := charset.CharsetBin
@@ -358,6 +527,7 @@ func (b *planBuilder) buildShow(show *ast.ShowStmt) Plan {
 		Flag:            show.Flag,
 		Full:            show.Full,
 		User:            show.User,
+		Digest:          show.Digest,
 		baseLogicalPlan: newBaseLogicalPlan("Show", b.allocator),
 	}
 	resultPlan = p
@@ -418,6 +588,7 @@ func (b *planBuilder) buildInsert(insert *ast.InsertStmt) Plan {
 		Lists:           insert.Lists,
 		Setlist:         insert.Setlist,
 		OnDuplicate:     insert.OnDuplicate,
+		ValuesStream:    insert.ValuesStream,
 		IsReplace:       insert.IsReplace,
 		Priority:        insert.Priority,
 		Ignore:          insert.Ignore,
@@ -437,15 +608,35 @@ func (b *planBuilder) buildInsert(insert *ast.InsertStmt) Plan {
 
 func (b *planBuilder) buildLoadData(ld *ast.LoadDataStmt) Plan {
 	p := &LoadData{
-		IsLocal:    ld.IsLocal,
-		Path:       ld.Path,
-		Table:      ld.Table,
-		FieldsInfo: ld.FieldsInfo,
-		LinesInfo:  ld.LinesInfo,
+		IsLocal:     ld.IsLocal,
+		Path:        ld.Path,
+		Table:       ld.Table,
+		FieldsInfo:  ld.FieldsInfo,
+		LinesInfo:   ld.LinesInfo,
+		IgnoreLines: ld.IgnoreLines,
 	}
 	return p
 }
 
+func (b *planBuilder) buildSelectInto(sel *ast.SelectStmt) Plan {
+	// The query is optimized as the select statement without the INTO clause.
+	query := *sel
+	query.IntoOutfile = nil
+	targetPlan, err := Optimize(b.ctx, &query, b.is)
+	if err != nil {
+		b.err = errors.Trace(err)
+		return nil
+	}
+	p := &SelectInto{
+		Target:     targetPlan,
+		Path:       sel.IntoOutfile.Path,
+		FieldsInfo: sel.IntoOutfile.FieldsInfo,
+		LinesInfo:  sel.IntoOutfile.LinesInfo,
+	}
+	addChild(p, targetPlan)
+	return p
+}
+
 func (b *planBuilder) buildDDL(node ast.DDLNode) Plan {
 	return &DDL{Statement: node}
 }
@@ -454,14 +645,31 @@ func (b *planBuilder) buildExplain(explain *ast.ExplainStmt) Plan {
 	if show, ok := explain.Stmt.(*ast.ShowStmt); ok {
 		return b.buildShow(show)
 	}
-	targetPlan, err := Optimize(b.ctx, explain.Stmt, b.is)
+	format := explain.Format
+	switch format {
+	case "":
+		format = ast.ExplainFormatRow
+	case ast.ExplainFormatRow, ast.ExplainFormatJSON, ast.ExplainFormatDOT:
+	default:
+		b.err = ErrUnknownExplainFormat.Gen("Unknown EXPLAIN format name: '%s'", format)
+		return nil
+	}
+	targetPlan, err := optimize(b.ctx, explain.Stmt, b.is, true)
 	if err != nil {
 		b.err = errors.Trace(err)
 		return nil
 	}
-	p := &Explain{StmtPlan: targetPlan}
+	p := &Explain{StmtPlan: targetPlan, Analyze: explain.Analyze, Format: format}
 	addChild(p, targetPlan)
-	schema := make(expression.Schema, 0, 3)
+	if format != ast.ExplainFormatRow {
+		// The whole plan tree is output as a single value.
+		p.SetSchema(expression.Schema{&expression.Column{
+			ColName: model.NewCIStr("EXPLAIN"),
+			RetType: types.NewFieldType(mysql.TypeString),
+		}})
+		return p
+	}
+	schema := make(expression.Schema, 0, 7)
 	schema = append(schema, &expression.Column{
 		ColName: model.NewCIStr("ID"),
 		RetType: types.NewFieldType(mysql.TypeString),
@@ -474,6 +682,24 @@ func (b *planBuilder) buildExplain(explain *ast.ExplainStmt) Plan {
 		ColName: model.NewCIStr("ParentID"),
 		RetType: types.NewFieldType(mysql.TypeString),
 	})
+	if explain.Analyze {
+		schema = append(schema, &expression.Column{
+			ColName: model.NewCIStr("EstRows"),
+			RetType: types.NewFieldType(mysql.TypeLonglong),
+		})
+		schema = append(schema, &expression.Column{
+			ColName: model.NewCIStr("ActRows"),
+			RetType: types.NewFieldType(mysql.TypeLonglong),
+		})
+		schema = append(schema, &expression.Column{
+			ColName: model.NewCIStr("Loops"),
+			RetType: types.NewFieldType(mysql.TypeLonglong),
+		})
+		schema = append(schema, &expression.Column{
+			ColName: model.NewCIStr("Time"),
+			RetType: types.NewFieldType(mysql.TypeString),
+		})
+	}
 	p.SetSchema(schema)
 	return p
 }
@@ -16,8 +16,10 @@ package plan
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/util/types"
 )
 
@@ -32,6 +34,60 @@ type ShowDDL struct {
 	basePlan
 }
 
+// ShowDDLJobs is for showing the DDL jobs in the queue and their progress.
+type ShowDDLJobs struct {
+	basePlan
+}
+
+// ShowDeleteRanges is for showing the ranges deleted by the background jobs and whether they are orphans,
+// built from the 'admin show delete ranges' statement.
+type ShowDeleteRanges struct {
+	basePlan
+}
+
+// ShowSlowPlans is for showing the slow queries grouped by the statement and the plan, built from the
+// 'admin show slow plans' statement.
+type ShowSlowPlans struct {
+	basePlan
+}
+
+// Diagnose is for bundling the diagnostic information of a statement, built from the 'admin diagnose' statement.
+type Diagnose struct {
+	basePlan
+
+	// Query is the text or the digest of the statement to diagnose.
+	Query string
+}
+
+// ThrottleTable is for throttling the writes of a table temporarily, built from the 'admin throttle table' statement.
+type ThrottleTable struct {
+	basePlan
+
+	Table *model.TableInfo
+	// Limit is the max number of rows written to the table per second, 0 removes the throttle.
+	Limit uint64
+	// Duration is how long the table is throttled, 0 means the default duration.
+	Duration time.Duration
+}
+
+// SetStats is for overriding the statistics of a table in the session, built from the 'admin set stats' statement.
+type SetStats struct {
+	basePlan
+
+	Table *model.TableInfo
+	// Column is the column whose NDV is set, nil means the row count of the table is set.
+	Column *model.ColumnInfo
+	Value  int64
+}
+
+// ResetStats is for removing the statistics overrides of the session, built from the 'admin reset stats' statement.
+type ResetStats struct {
+	basePlan
+
+	// Tables are the tables whose overrides are removed, empty means all the tables.
+	Tables []*model.TableInfo
+}
+
 // CheckTable is used for checking table data, built from the 'admin check table' statement.
 type CheckTable struct {
 	basePlan
@@ -39,6 +95,23 @@ type CheckTable struct {
 	Tables []*ast.TableName
 }
 
+// RecoverIndex is for adding the missing index entries of the rows, built from the 'admin recover index' statement.
+type RecoverIndex struct {
+	basePlan
+
+	Table *model.TableInfo
+	Index *model.IndexInfo
+}
+
+// CleanupIndex is for removing the index entries which don't match the rows, built from the
+// 'admin cleanup index' statement.
+type CleanupIndex struct {
+	basePlan
+
+	Table *model.TableInfo
+	Index *model.IndexInfo
+}
+
 // IndexRange represents an index range to be scanned.
 type IndexRange struct {
 	LowVal      []types.Datum
@@ -101,6 +174,8 @@ type SelectLock struct {
 	baseLogicalPlan
 
 	Lock ast.SelectLockType
+	// Tables are the tables of "FOR UPDATE OF", only the rows from them are locked. Empty means all the tables.
+	Tables []*ast.TableName
 }
 
 // Limit represents offset and limit plan.
@@ -114,6 +189,9 @@ type Limit struct {
 // Distinct represents Distinct plan.
 type Distinct struct {
 	baseLogicalPlan
+
+	// Sorted means the input is sorted by all the columns, so the duplicated rows are adjacent.
+	Sorted bool
 }
 
 // Prepare represents prepare plan.
@@ -122,6 +200,8 @@ type Prepare struct {
 
 	Name    string
 	SQLText string
+	// SQLVar is the user variable whose value is the statement, it's evaluated when the statement is prepared.
+	SQLVar *ast.VariableExpr
 }
 
 // Execute represents prepare plan.
@@ -151,6 +231,7 @@ type Show struct {
 	Flag   int             // Some flag parsed from sql, such as FULL.
 	Full   bool
 	User   string // Used for show grants.
+	Digest string // Used for show plan for.
 
 	// Used by show variables
 	GlobalScope bool
@@ -172,6 +253,8 @@ type Insert struct {
 	Lists       [][]ast.ExprNode
 	Setlist     []*ast.Assignment
 	OnDuplicate []*ast.Assignment
+	// ValuesStream reads the rows after Lists, see ast.ValuesStream.
+	ValuesStream ast.ValuesStream
 
 	IsReplace bool
 	Priority  int
@@ -182,9 +265,22 @@ type Insert struct {
 type LoadData struct {
 	basePlan
 
-	IsLocal    bool
+	IsLocal     bool
+	Path        string
+	Table       *ast.TableName
+	FieldsInfo  *ast.FieldsClause
+	LinesInfo   *ast.LinesClause
+	IgnoreLines uint64
+}
+
+// SelectInto is for exporting the result of a query to a file on the server, built from the
+// 'select ... into outfile' statement.
+type SelectInto struct {
+	basePlan
+
+	// Target is the plan of the query.
+	Target     Plan
 	Path       string
-	Table      *ast.TableName
 	FieldsInfo *ast.FieldsClause
 	LinesInfo  *ast.LinesClause
 }
@@ -201,4 +297,8 @@ type Explain struct {
 	basePlan
 
 	StmtPlan Plan
+	// Analyze means the statement is executed to collect the runtime statistics of the operators.
+	Analyze bool
+	// Format is the output format, see ast.ExplainFormatRow, ast.ExplainFormatJSON and ast.ExplainFormatDOT.
+	Format string
 }
@@ -0,0 +1,360 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"math"
+
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/expression"
+	"github.com/pingcap/tidb/infoschema"
+	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/parser/opcode"
+	"github.com/pingcap/tidb/util/types"
+)
+
+// PointGet reads at most one row of a table with a single KV get, by the handle or by the key of a unique index.
+// It's built for the queries like "select ... from t where pk = ?" without the logical and physical optimizations
+// and the distributed SQL layer, because planning such a query costs more than executing it.
+type PointGet struct {
+	basePlan
+
+	DBName model.CIStr
+	Table  *model.TableInfo
+	// Columns are the table columns in the order of the schema.
+	Columns []*model.ColumnInfo
+	// Index is nil if the row is read by Handle, otherwise the handle is read from the entry of the unique index
+	// whose key is made of IndexValues.
+	Index       *model.IndexInfo
+	IndexValues []types.Datum
+	Handle      int64
+}
+
+// BatchPointGet reads the rows of a table with a single KV batch get, by the handles or by the keys of a unique index.
+// It's built for the queries like "select ... from t where pk in (?, ?, ...)" like PointGet.
+type BatchPointGet struct {
+	basePlan
+
+	DBName  model.CIStr
+	Table   *model.TableInfo
+	Columns []*model.ColumnInfo
+	// Index is nil if the rows are read by Handles, otherwise the handles are read from the entries of the unique
+	// index whose keys are made of IndexValues.
+	Index       *model.IndexInfo
+	IndexValues [][]types.Datum
+	Handles     []int64
+}
+
+// tryPointGetPlan returns a PointGet plan if the statement is a single table select that only reads the row
+// identified by the equal conditions on the handle or on all the columns of a unique index, or a BatchPointGet plan
+// if it reads the rows identified by an IN list on the handle or on the single column of a unique index. Otherwise it
+// returns nil and the statement goes through the general optimization, which also reports the errors of the statement.
+func tryPointGetPlan(ctx context.Context, node ast.Node, allocator *idAllocator) Plan {
+	sel, ok := node.(*ast.SelectStmt)
+	if !ok || sel.From == nil || sel.Where == nil || sel.Distinct || sel.GroupBy != nil || sel.Having != nil ||
+		sel.OrderBy != nil || sel.Limit != nil || sel.LockTp != ast.SelectLockNone || len(sel.TableHints) > 0 ||
+		sel.IntoOutfile != nil {
+		return nil
+	}
+	// The history data is only read by the distributed SQL layer.
+	if ctx.GetSessionVars().SnapshotTS != 0 {
+		return nil
+	}
+	if sel.From.TableRefs.Right != nil {
+		return nil
+	}
+	ts, ok := sel.From.TableRefs.Left.(*ast.TableSource)
+	if !ok {
+		return nil
+	}
+	tn, ok := ts.Source.(*ast.TableName)
+	if !ok || len(tn.IndexHints) > 0 || tn.DBInfo == nil || infoschema.IsMemoryDB(tn.DBInfo.Name.L) {
+		return nil
+	}
+	tblInfo := tn.TableInfo
+	p := &PointGet{
+		DBName: tn.DBInfo.Name,
+		Table:  tblInfo,
+	}
+	if eqValues := getPointGetValues(sel.Where, tblInfo); eqValues != nil {
+		if !p.buildSchema(sel.Fields.Fields, ts, tn) || !p.findAccessKey(eqValues) {
+			return nil
+		}
+		p.initPointGet("PointGet", allocator)
+		return p
+	}
+	col, inValues := getBatchPointGetValues(sel.Where, tblInfo)
+	if col == nil || !p.buildSchema(sel.Fields.Fields, ts, tn) {
+		return nil
+	}
+	bp := &BatchPointGet{
+		DBName:  p.DBName,
+		Table:   tblInfo,
+		Columns: p.Columns,
+	}
+	if !bp.findAccessKeys(col, inValues) {
+		return nil
+	}
+	bp.SetSchema(p.GetSchema())
+	bp.initPointGet("BatchPointGet", allocator)
+	return bp
+}
+
+// initPointGet initializes the ID of a point get plan, which is the source of its columns.
+func (p *basePlan) initPointGet(tp string, allocator *idAllocator) {
+	p.tp = tp
+	p.allocator = allocator
+	p.initID()
+	for _, col := range p.schema {
+		col.FromID = p.id
+	}
+}
+
+// getPointGetValues returns the values of the columns if the condition is a conjunction of "column = value",
+// otherwise it returns nil.
+func getPointGetValues(cond ast.ExprNode, tblInfo *model.TableInfo) map[string]types.Datum {
+	eqValues := make(map[string]types.Datum)
+	conds := []ast.ExprNode{cond}
+	for len(conds) > 0 {
+		expr := getInnerFromParentheses(conds[0])
+		conds = conds[1:]
+		binop, ok := expr.(*ast.BinaryOperationExpr)
+		if !ok {
+			return nil
+		}
+		if binop.Op == opcode.AndAnd {
+			conds = append(conds, binop.L, binop.R)
+			continue
+		}
+		if binop.Op != opcode.EQ {
+			return nil
+		}
+		col, value := getColumnAndValue(binop.L, binop.R)
+		if col == nil {
+			col, value = getColumnAndValue(binop.R, binop.L)
+		}
+		if col == nil || col.Refer == nil || col.Refer.Table != tblInfo || col.Refer.Column == nil {
+			return nil
+		}
+		// The condition is always false for NULL, let the general plan handle it.
+		if value.IsNull() {
+			return nil
+		}
+		name := col.Refer.Column.Name.L
+		if old, ok := eqValues[name]; ok {
+			if cmp, err := old.CompareDatum(*value); err != nil || cmp != 0 {
+				return nil
+			}
+		}
+		eqValues[name] = *value
+	}
+	return eqValues
+}
+
+// getBatchPointGetValues returns the column and the values if the condition is "column IN (value, ...)", otherwise
+// it returns nil. The NULL values are skipped because they never match.
+func getBatchPointGetValues(cond ast.ExprNode, tblInfo *model.TableInfo) (*model.ColumnInfo, []types.Datum) {
+	in, ok := getInnerFromParentheses(cond).(*ast.PatternInExpr)
+	if !ok || in.Not || in.Sel != nil || len(in.List) == 0 {
+		return nil, nil
+	}
+	var col *ast.ColumnNameExpr
+	values := make([]types.Datum, 0, len(in.List))
+	for _, item := range in.List {
+		var value *types.Datum
+		col, value = getColumnAndValue(in.Expr, item)
+		if col == nil || col.Refer == nil || col.Refer.Table != tblInfo || col.Refer.Column == nil {
+			return nil, nil
+		}
+		if !value.IsNull() {
+			values = append(values, *value)
+		}
+	}
+	return col.Refer.Column, values
+}
+
+func getColumnAndValue(l, r ast.ExprNode) (*ast.ColumnNameExpr, *types.Datum) {
+	col, ok := getInnerFromParentheses(l).(*ast.ColumnNameExpr)
+	if !ok {
+		return nil, nil
+	}
+	switch x := getInnerFromParentheses(r).(type) {
+	case *ast.ValueExpr:
+		return col, x.GetDatum()
+	case *ast.ParamMarkerExpr:
+		return col, x.GetDatum()
+	}
+	return nil, nil
+}
+
+// buildSchema builds the output columns of the select fields, it returns false if a field isn't a column.
+// The columns are named like the ones built by buildProjection.
+func (p *PointGet) buildSchema(fields []*ast.SelectField, ts *ast.TableSource, tn *ast.TableName) bool {
+	tblName := tn.TableInfo.Name
+	if ts.AsName.L != "" {
+		tblName = ts.AsName
+	}
+	schema := make(expression.Schema, 0, len(fields))
+	for i, field := range fields {
+		if field.WildCard != nil {
+			if (field.WildCard.Table.L == "" && i > 0) || (field.WildCard.Table.L != "" && field.WildCard.Table.L != tblName.L) {
+				return false
+			}
+			for _, rf := range tn.GetResultFields() {
+				p.Columns = append(p.Columns, rf.Column)
+				schema = append(schema, &expression.Column{
+					ColName: rf.Column.Name,
+					TblName: tblName,
+					DBName:  p.DBName,
+					RetType: &rf.Column.FieldType,
+				})
+			}
+			continue
+		}
+		col, ok := getInnerFromParentheses(field.Expr).(*ast.ColumnNameExpr)
+		if !ok || col.Refer == nil || col.Refer.Table != tn.TableInfo || col.Refer.Column == nil {
+			return false
+		}
+		colName := col.Name.Name
+		if field.AsName.L != "" {
+			colName = field.AsName
+		}
+		p.Columns = append(p.Columns, col.Refer.Column)
+		schema = append(schema, &expression.Column{
+			ColName: colName,
+			TblName: col.Name.Table,
+			RetType: &col.Refer.Column.FieldType,
+		})
+	}
+	for i, col := range schema {
+		col.Position = i + 1
+	}
+	p.SetSchema(schema)
+	return true
+}
+
+// findAccessKey finds the handle or the unique index whose columns are exactly the columns of the equal conditions.
+func (p *PointGet) findAccessKey(eqValues map[string]types.Datum) bool {
+	if p.Table.PKIsHandle && len(eqValues) == 1 {
+		for _, col := range p.Table.Columns {
+			if !mysql.HasPriKeyFlag(col.Flag) {
+				continue
+			}
+			value, ok := eqValues[col.Name.L]
+			if !ok {
+				break
+			}
+			value, ok = convertPointGetValue(value, col)
+			if !ok {
+				return false
+			}
+			p.Handle = value.GetInt64()
+			if value.Kind() == types.KindUint64 {
+				p.Handle = int64(value.GetUint64())
+			}
+			return true
+		}
+	}
+	for _, index := range p.Table.Indices {
+		if !index.Unique || index.State != model.StatePublic || len(index.Columns) != len(eqValues) {
+			continue
+		}
+		values := make([]types.Datum, 0, len(index.Columns))
+		for _, idxCol := range index.Columns {
+			value, ok := eqValues[idxCol.Name.L]
+			if !ok || idxCol.Length != types.UnspecifiedLength {
+				break
+			}
+			value, ok = convertPointGetValue(value, p.Table.Columns[idxCol.Offset])
+			if !ok {
+				return false
+			}
+			values = append(values, value)
+		}
+		if len(values) == len(index.Columns) {
+			p.Index, p.IndexValues = index, values
+			return true
+		}
+	}
+	return false
+}
+
+// findAccessKeys finds the handle or the unique index whose only column is the column of the IN list.
+func (p *BatchPointGet) findAccessKeys(col *model.ColumnInfo, values []types.Datum) bool {
+	if p.Table.PKIsHandle && mysql.HasPriKeyFlag(col.Flag) {
+		p.Handles = make([]int64, 0, len(values))
+		for _, value := range values {
+			value, ok := convertPointGetValue(value, col)
+			if !ok {
+				return false
+			}
+			handle := value.GetInt64()
+			if value.Kind() == types.KindUint64 {
+				handle = int64(value.GetUint64())
+			}
+			p.Handles = append(p.Handles, handle)
+		}
+		return true
+	}
+	for _, index := range p.Table.Indices {
+		if !index.Unique || index.State != model.StatePublic || len(index.Columns) != 1 ||
+			index.Columns[0].Name.L != col.Name.L || index.Columns[0].Length != types.UnspecifiedLength {
+			continue
+		}
+		p.IndexValues = make([][]types.Datum, 0, len(values))
+		for _, value := range values {
+			value, ok := convertPointGetValue(value, col)
+			if !ok {
+				return false
+			}
+			p.IndexValues = append(p.IndexValues, []types.Datum{value})
+		}
+		p.Index = index
+		return true
+	}
+	return false
+}
+
+// convertPointGetValue converts the value to the datum kind stored for the column, it returns false if the value
+// may not be compared with the column as it is, e.g. the value is a float and the column is an integer.
+func convertPointGetValue(value types.Datum, col *model.ColumnInfo) (types.Datum, bool) {
+	switch col.Tp {
+	case mysql.TypeTiny, mysql.TypeShort, mysql.TypeInt24, mysql.TypeLong, mysql.TypeLonglong:
+		unsigned := mysql.HasUnsignedFlag(col.Flag)
+		switch value.Kind() {
+		case types.KindInt64:
+			if !unsigned {
+				return value, true
+			}
+			if value.GetInt64() >= 0 {
+				return types.NewUintDatum(uint64(value.GetInt64())), true
+			}
+		case types.KindUint64:
+			if unsigned {
+				return value, true
+			}
+			if value.GetUint64() <= math.MaxInt64 {
+				return types.NewIntDatum(int64(value.GetUint64())), true
+			}
+		}
+	case mysql.TypeVarchar, mysql.TypeString, mysql.TypeVarString:
+		switch value.Kind() {
+		case types.KindString, types.KindBytes:
+			return value, true
+		}
+	}
+	return types.Datum{}, false
+}
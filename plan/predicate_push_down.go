@@ -31,6 +31,19 @@ var (
 		ast.NE:   ast.NE,
 		ast.Like: ast.Like,
 	}
+	// nullRejectArgs maps a function to the number of its leading arguments that make it never true when they are NULL.
+	nullRejectArgs = map[string]int{
+		ast.EQ:        2,
+		ast.NE:        2,
+		ast.LT:        2,
+		ast.LE:        2,
+		ast.GT:        2,
+		ast.GE:        2,
+		ast.Like:      2,
+		ast.In:        1,
+		ast.IsTruth:   1,
+		ast.IsFalsity: 1,
+	}
 )
 
 func addSelection(p Plan, child LogicalPlan, conditions []expression.Expression, allocator *idAllocator) error {
@@ -59,7 +72,7 @@ func columnSubstitute(expr expression.Expression, schema expression.Schema, newE
 		return newExprs[id]
 	case *expression.ScalarFunction:
 		for i, arg := range v.Args {
-			v.Args[i] = columnSubstitute(arg, schema, newExprs)
+			v.SetArg(i, columnSubstitute(arg, schema, newExprs))
 		}
 	}
 	return expr
@@ -109,7 +122,7 @@ func propagateConstant(conditions []expression.Expression) []expression.Expressi
 				newExpression := propagateConstant(expression.SplitCNFItems(conditions[i]))
 				conditions[i] = expression.ComposeCNFCondition(newExpression)
 				isSource[i] = true
-			case ast.EQ:
+			case ast.EQ, ast.NullEQ:
 				var (
 					col *expression.Column
 					val *expression.Constant
@@ -127,6 +140,10 @@ func propagateConstant(conditions []expression.Expression) []expression.Expressi
 				} else {
 					continue
 				}
+				// "a <=> NULL" means a is NULL rather than a equals to a value.
+				if expr.FuncName.L == ast.NullEQ && val.Value.IsNull() {
+					continue
+				}
 				equalities[string(col.HashCode())] = val
 				isSource[i] = true
 				getOneEquality = true
@@ -155,10 +172,12 @@ func propagateConstant(conditions []expression.Expression) []expression.Expressi
 	//    ATTENTION: here column 'e' doesn't belong to any mep, so we skip "e != 0".
 	// 3. propagate constants in these inequality predicates, and we finally get:
 	//    "a = b and c = d and a = c and e = f and g = h and e != 0 and a > 0 and b > 0 and c > 0 and d > 0 and g like 'abc' and h like 'abc' ".
+	// "a <=> b" joins a multiple equality predicate too, because the inequality predicates are never true if a
+	// column is NULL, and "a is true" and "a is false" are propagated like the inequality predicates.
 	multipleEqualities := make(map[*expression.Column]*expression.Column, 0)
 	for _, cond := range conditions { // build multiple equality predicates.
 		expr, ok := cond.(*expression.ScalarFunction)
-		if ok && expr.FuncName.L == ast.EQ {
+		if ok && (expr.FuncName.L == ast.EQ || expr.FuncName.L == ast.NullEQ) {
 			left, ok1 := expr.Args[0].(*expression.Column)
 			right, ok2 := expr.Args[1].(*expression.Column)
 			if ok1 && ok2 {
@@ -187,6 +206,21 @@ func propagateConstant(conditions []expression.Expression) []expression.Expressi
 		if !ok {
 			continue
 		}
+		if expr.FuncName.L == ast.IsTruth || expr.FuncName.L == ast.IsFalsity {
+			column, ok = expr.Args[0].(*expression.Column)
+			if !ok {
+				continue
+			}
+			equalCol, ok = multipleEqualities[equalityMember(column, multipleEqualities)]
+			if !ok {
+				continue
+			}
+			colHashCode := string(equalCol.HashCode())
+			inequalities[colHashCode] = append(inequalities[colHashCode], inequalityFactor{FuncName: expr.FuncName.L})
+			conditions = append(conditions[:i], conditions[i+1:]...)
+			i--
+			continue
+		}
 		funcName, ok = inequalityFuncs[expr.FuncName.L]
 		if !ok {
 			continue
@@ -204,7 +238,7 @@ func propagateConstant(conditions []expression.Expression) []expression.Expressi
 		} else {
 			continue
 		}
-		equalCol, ok = multipleEqualities[column]
+		equalCol, ok = multipleEqualities[equalityMember(column, multipleEqualities)]
 		if !ok { // no need to propagate inequality predicates whose column is only equal to itself.
 			continue
 		}
@@ -220,18 +254,29 @@ func propagateConstant(conditions []expression.Expression) []expression.Expressi
 	if len(inequalities) == 0 {
 		return conditions
 	}
+	// The same predicate is derived for a column from the duplicated inequality predicates, e.g. "b > 0" and
+	// "c > 0" derive "a > 0" twice if "a = b and a = c", it's only added once.
+	derived := make(exprInterner)
+	appendDerived := func(newFunc expression.Expression) {
+		if _, dup := derived.intern(newFunc); !dup {
+			conditions = append(conditions, newFunc)
+		}
+	}
 	for k, v := range multipleEqualities { // propagate constants in inequality predicates.
 		for _, x := range inequalities[string(v.HashCode())] {
 			funcName, factors := x.FuncName, x.Factor
-			if funcName == ast.Like {
+			if len(factors) == 0 {
+				newFunc, _ := expression.NewFunction(funcName, types.NewFieldType(mysql.TypeLonglong), k)
+				appendDerived(newFunc)
+			} else if funcName == ast.Like {
 				for i := 0; i < len(factors); i += 2 {
 					newFunc, _ := expression.NewFunction(funcName, types.NewFieldType(mysql.TypeTiny), k, factors[i], factors[i+1])
-					conditions = append(conditions, newFunc)
+					appendDerived(newFunc)
 				}
 			} else {
 				for i := 0; i < len(factors); i += 2 {
 					newFunc, _ := expression.NewFunction(funcName, types.NewFieldType(mysql.TypeTiny), k, factors[i])
-					conditions = append(conditions, newFunc)
+					appendDerived(newFunc)
 				}
 			}
 		}
@@ -239,8 +284,24 @@ func propagateConstant(conditions []expression.Expression) []expression.Expressi
 	return conditions
 }
 
+// equalityMember returns the column in the multiple equality predicates which equals to col, the columns of
+// the same name aren't the same pointers after they are resolved. It returns col if there is no such column.
+func equalityMember(col *expression.Column, multipleEqualities map[*expression.Column]*expression.Column) *expression.Column {
+	if _, ok := multipleEqualities[col]; ok {
+		return col
+	}
+	for k := range multipleEqualities {
+		if k.Equal(col) {
+			return k
+		}
+	}
+	return col
+}
+
 // UnionColumns uses union-find to build multiple equality predicates.
 func UnionColumns(leftExpr *expression.Column, rightExpr *expression.Column, multipleEqualities map[*expression.Column]*expression.Column) {
+	leftExpr = equalityMember(leftExpr, multipleEqualities)
+	rightExpr = equalityMember(rightExpr, multipleEqualities)
 	rootOfLeftExpr, ok1 := multipleEqualities[leftExpr]
 	rootOfRightExpr, ok2 := multipleEqualities[rightExpr]
 	if !ok1 && !ok2 {
@@ -267,10 +328,13 @@ func constantSubstitute(equalities map[string]*expression.Constant, condition ex
 			return v
 		}
 	case *expression.ScalarFunction:
+		changed := false
 		for i, arg := range expr.Args {
-			expr.Args[i] = constantSubstitute(equalities, arg)
+			expr.SetArg(i, constantSubstitute(equalities, arg))
+			changed = changed || expr.Args[i] != arg
 		}
-		if _, ok := evaluator.Funcs[expr.FuncName.L]; ok {
+		// The function is rebuilt to fold the substituted constants, the unchanged ones are kept.
+		if _, ok := evaluator.Funcs[expr.FuncName.L]; ok && changed {
 			condition, _ = expression.NewFunction(expr.FuncName.L, expr.RetType, expr.Args...)
 		}
 		return condition
@@ -278,9 +342,61 @@ func constantSubstitute(equalities map[string]*expression.Constant, condition ex
 	return condition
 }
 
+// foldNullPredicates folds "col is null" to false and "col is not null" to true when col is known to be not null.
+// The not null facts come from notNullCols and from the CNF items that compare a column with other expressions,
+// because such items can never be true when the column is NULL.
+func foldNullPredicates(conditions []expression.Expression, notNullCols map[string]bool) []expression.Expression {
+	facts := make(map[string]bool, len(notNullCols))
+	for key := range notNullCols {
+		facts[key] = true
+	}
+	for _, cond := range conditions {
+		expr, ok := cond.(*expression.ScalarFunction)
+		if !ok {
+			continue
+		}
+		for _, arg := range expr.Args[:nullRejectArgs[expr.FuncName.L]] {
+			if col, ok := arg.(*expression.Column); ok {
+				facts[string(col.HashCode())] = true
+			}
+		}
+	}
+	if len(facts) == 0 {
+		return conditions
+	}
+	for i, cond := range conditions {
+		conditions[i] = nullCheckSubstitute(facts, cond)
+	}
+	return simplifyBoolean(conditions)
+}
+
+// nullCheckSubstitute substitutes "col is null" in a condition by false if col is not null.
+func nullCheckSubstitute(notNullCols map[string]bool, condition expression.Expression) expression.Expression {
+	expr, ok := condition.(*expression.ScalarFunction)
+	if !ok {
+		return condition
+	}
+	if expr.FuncName.L == ast.IsNull {
+		if col, ok := expr.Args[0].(*expression.Column); ok && notNullCols[string(col.HashCode())] {
+			return &expression.Constant{Value: types.NewIntDatum(0), RetType: types.NewFieldType(mysql.TypeTiny)}
+		}
+		return condition
+	}
+	changed := false
+	for i, arg := range expr.Args {
+		expr.SetArg(i, nullCheckSubstitute(notNullCols, arg))
+		changed = changed || expr.Args[i] != arg
+	}
+	if _, ok := evaluator.Funcs[expr.FuncName.L]; ok && changed {
+		condition, _ = expression.NewFunction(expr.FuncName.L, expr.RetType, expr.Args...)
+	}
+	return condition
+}
+
 // PredicatePushDown implements LogicalPlan PredicatePushDown interface.
 func (p *Selection) PredicatePushDown(predicates []expression.Expression) (ret []expression.Expression, retP LogicalPlan, err error) {
-	retConditions, child, err1 := p.GetChildByIndex(0).(LogicalPlan).PredicatePushDown(propagateConstant(append(p.Conditions, predicates...)))
+	conditions := propagateConstant(simplifyBoolean(append(p.Conditions, predicates...)))
+	retConditions, child, err1 := p.GetChildByIndex(0).(LogicalPlan).PredicatePushDown(foldNullPredicates(conditions, nil))
 	if err1 != nil {
 		return nil, nil, errors.Trace(err1)
 	}
@@ -299,7 +415,13 @@ func (p *Selection) PredicatePushDown(predicates []expression.Expression) (ret [
 
 // PredicatePushDown implements LogicalPlan PredicatePushDown interface.
 func (p *DataSource) PredicatePushDown(predicates []expression.Expression) ([]expression.Expression, LogicalPlan, error) {
-	return predicates, p, nil
+	notNullCols := make(map[string]bool)
+	for _, col := range p.schema {
+		if mysql.HasNotNullFlag(col.RetType.Flag) || mysql.HasPriKeyFlag(col.RetType.Flag) {
+			notNullCols[string(col.HashCode())] = true
+		}
+	}
+	return foldNullPredicates(predicates, notNullCols), p, nil
 }
 
 // PredicatePushDown implements LogicalPlan PredicatePushDown interface.
@@ -307,6 +429,32 @@ func (p *TableDual) PredicatePushDown(predicates []expression.Expression) ([]exp
 	return predicates, p, nil
 }
 
+// PredicatePushDown implements LogicalPlan PredicatePushDown interface.
+func (p *GenerateSeries) PredicatePushDown(predicates []expression.Expression) ([]expression.Expression, LogicalPlan, error) {
+	return predicates, p, nil
+}
+
+// PredicatePushDown implements LogicalPlan PredicatePushDown interface.
+func (p *JSONTable) PredicatePushDown(predicates []expression.Expression) ([]expression.Expression, LogicalPlan, error) {
+	return predicates, p, nil
+}
+
+// PredicatePushDown implements LogicalPlan PredicatePushDown interface.
+func (p *Profile) PredicatePushDown(predicates []expression.Expression) ([]expression.Expression, LogicalPlan, error) {
+	return predicates, p, nil
+}
+
+// PredicatePushDown implements LogicalPlan PredicatePushDown interface.
+func (p *RowHistory) PredicatePushDown(predicates []expression.Expression) ([]expression.Expression, LogicalPlan, error) {
+	return predicates, p, nil
+}
+
+// PredicatePushDown implements LogicalPlan PredicatePushDown interface.
+// The predicates are not pushed into the source, which is shared by all the references.
+func (p *CTE) PredicatePushDown(predicates []expression.Expression) ([]expression.Expression, LogicalPlan, error) {
+	return predicates, p, nil
+}
+
 // PredicatePushDown implements LogicalPlan PredicatePushDown interface.
 func (p *Join) PredicatePushDown(predicates []expression.Expression) (ret []expression.Expression, retPlan LogicalPlan, err error) {
 	err = outerJoinSimplify(p, predicates)
@@ -315,7 +463,7 @@ func (p *Join) PredicatePushDown(predicates []expression.Expression) (ret []expr
 	}
 	groups, valid := tryToGetJoinGroup(p)
 	if valid {
-		e := joinReOrderSolver{allocator: p.allocator}
+		e := joinReOrderSolver{allocator: p.allocator, hintInfo: p.hintInfo}
 		e.reorderJoin(groups, predicates)
 		newJoin := e.resultJoin
 		parent := p.parents[0]
@@ -341,30 +489,45 @@ func (p *Join) PredicatePushDown(predicates []expression.Expression) (ret []expr
 		tempCond = append(tempCond, p.OtherConditions...)
 		if len(tempCond) != 0 {
 			tempCond = append(tempCond, predicates...)
-			equalCond, leftPushCond, rightPushCond, otherCond = extractOnCondition(propagateConstant(tempCond), leftPlan, rightPlan)
+			equalCond, leftPushCond, rightPushCond, otherCond = extractOnCondition(propagateConstant(simplifyBoolean(tempCond)), leftPlan, rightPlan)
 		} else { // "on" is not used.
 			equalCond, leftPushCond, rightPushCond, otherCond = extractOnCondition(predicates, leftPlan, rightPlan)
 		}
 	}
 	switch p.JoinType {
 	case LeftOuterJoin, SemiJoinWithAux:
-		rightCond = p.RightConditions
-		p.RightConditions = nil
+		// The inner rows of a null-aware semi join which make its right conditions NULL can't be filtered out.
+		if !p.nullAware {
+			rightCond = p.RightConditions
+			p.RightConditions = nil
+		}
 		leftCond = leftPushCond
 		ret = append(expression.ScalarFuncs2Exprs(equalCond), otherCond...)
 		ret = append(ret, rightPushCond...)
+		if p.JoinType == LeftOuterJoin {
+			outerCond := append(append([]expression.Expression(nil), p.LeftConditions...), leftPushCond...)
+			rightCond = append(rightCond, deriveInnerConditions(p.EqualConditions, outerCond, rightPlan)...)
+		}
 	case RightOuterJoin:
 		leftCond = p.LeftConditions
 		p.LeftConditions = nil
+		outerCond := append(append([]expression.Expression(nil), p.RightConditions...), rightPushCond...)
+		leftCond = append(leftCond, deriveInnerConditions(p.EqualConditions, outerCond, leftPlan)...)
 		rightCond = rightPushCond
 		ret = append(expression.ScalarFuncs2Exprs(equalCond), otherCond...)
 		ret = append(ret, leftPushCond...)
 	case SemiJoin:
 		equalCond, leftPushCond, rightPushCond, otherCond = extractOnCondition(predicates, leftPlan, rightPlan)
-		leftCond = propagateConstant(append(p.LeftConditions, leftPushCond...))
-		rightCond = propagateConstant(append(p.RightConditions, rightPushCond...))
+		leftCond = propagateConstant(simplifyBoolean(append(p.LeftConditions, leftPushCond...)))
 		p.LeftConditions = nil
-		p.RightConditions = nil
+		// The inner rows which make the right conditions NULL make the result of a null-aware anti semi join NULL,
+		// so they can't be filtered out.
+		if p.anti && p.nullAware {
+			rightCond = rightPushCond
+		} else {
+			rightCond = propagateConstant(simplifyBoolean(append(p.RightConditions, rightPushCond...)))
+			p.RightConditions = nil
+		}
 	case InnerJoin:
 		p.LeftConditions = nil
 		p.RightConditions = nil
@@ -393,15 +556,52 @@ func (p *Join) PredicatePushDown(predicates []expression.Expression) (ret []expr
 			return nil, nil, errors.Trace(err2)
 		}
 	}
+	p.updateCorrelated()
 	return
 }
 
+// deriveInnerConditions derives the conditions on the inner child of an outer join by propagating the constants in the
+// conditions on the outer child through the equal conditions of the join, e.g. "t2.a = 5" is derived from
+// "t1 left join t2 on t1.a = t2.a where t1.a = 5". The inner rows which don't satisfy the derived conditions never
+// match an outer row, so they can be filtered out below the join.
+func deriveInnerConditions(equalCond []*expression.ScalarFunction, outerCond []expression.Expression,
+	innerPlan LogicalPlan) []expression.Expression {
+	if len(equalCond) == 0 || len(outerCond) == 0 {
+		return nil
+	}
+	// The conditions are cloned, because propagateConstant substitutes the columns in place.
+	conditions := make([]expression.Expression, 0, len(equalCond)+len(outerCond))
+	for _, cond := range equalCond {
+		conditions = append(conditions, cond.Clone())
+	}
+	for _, cond := range outerCond {
+		conditions = append(conditions, cond.Clone())
+	}
+	var derived []expression.Expression
+	for _, cond := range propagateConstant(conditions) {
+		columns := extractColumns(cond)
+		if len(columns) == 0 {
+			continue
+		}
+		fromInner := true
+		for _, col := range columns {
+			if innerPlan.GetSchema().GetIndex(col) == -1 {
+				fromInner = false
+				break
+			}
+		}
+		if fromInner {
+			derived = append(derived, cond)
+		}
+	}
+	return derived
+}
+
 // outerJoinSimplify simplifies outer join.
 func outerJoinSimplify(p *Join, predicates []expression.Expression) error {
 	var innerTable, outerTable LogicalPlan
 	child1 := p.GetChildByIndex(0).(LogicalPlan)
 	child2 := p.GetChildByIndex(1).(LogicalPlan)
-	var fullConditions []expression.Expression
 	if p.JoinType == LeftOuterJoin {
 		innerTable = child2
 		outerTable = child1
@@ -414,18 +614,20 @@ func outerJoinSimplify(p *Join, predicates []expression.Expression) error {
 	// first simplify embedded outer join.
 	// When trying to simplify an embedded outer join operation in a query,
 	// we must take into account the join condition for the embedding outer join together with the WHERE condition.
+	// But the join condition of an outer join doesn't filter its outer table, so only the WHERE condition applies there.
+	fullConditions := concatOnAndWhereConds(p, predicates)
 	if innerPlan, ok := innerTable.(*Join); ok {
-		fullConditions = concatOnAndWhereConds(p, predicates)
 		err := outerJoinSimplify(innerPlan, fullConditions)
 		if err != nil {
 			return errors.Trace(err)
 		}
 	}
 	if outerPlan, ok := outerTable.(*Join); ok {
-		if fullConditions != nil {
-			fullConditions = concatOnAndWhereConds(p, predicates)
+		outerConditions := predicates
+		if p.JoinType == InnerJoin {
+			outerConditions = fullConditions
 		}
-		err := outerJoinSimplify(outerPlan, fullConditions)
+		err := outerJoinSimplify(outerPlan, outerConditions)
 		if err != nil {
 			return errors.Trace(err)
 		}
@@ -433,6 +635,13 @@ func outerJoinSimplify(p *Join, predicates []expression.Expression) error {
 	if p.JoinType == InnerJoin {
 		return nil
 	}
+	// The unmatched rows are not null-extended when some default values are not NULL,
+	// e.g. the COUNT of a decorrelated scalar subquery, so a null-rejected condition may still accept them.
+	for _, value := range p.DefaultValues {
+		if !value.IsNull() {
+			return nil
+		}
+	}
 	// then simplify embedding outer join.
 	canBeSimplified := false
 	for _, expr := range predicates {
@@ -457,12 +666,42 @@ func outerJoinSimplify(p *Join, predicates []expression.Expression) error {
 // If it is a conjunction containing a null-rejected condition as a conjunct.
 // If it is a disjunction of null-rejected conditions.
 func isNullRejected(schema expression.Schema, expr expression.Expression) (bool, error) {
+	// The other conjuncts or disjuncts may refer to the outer table and can't be folded to a constant,
+	// so check the operands of AND and OR one by one.
+	if f, ok := expr.(*expression.ScalarFunction); ok {
+		switch f.FuncName.L {
+		case ast.AndAnd:
+			for _, arg := range f.Args {
+				isOk, err := isNullRejected(schema, arg)
+				if err != nil || isOk {
+					return isOk, errors.Trace(err)
+				}
+			}
+		case ast.OrOr:
+			for _, arg := range f.Args {
+				isOk, err := isNullRejected(schema, arg)
+				if err != nil || !isOk {
+					return false, errors.Trace(err)
+				}
+			}
+			return true, nil
+		}
+	}
 	result, err := expression.EvaluateExprWithNull(schema, expr)
 	if err != nil {
 		return false, errors.Trace(err)
 	}
 	x, ok := result.(*expression.Constant)
 	if !ok {
+		// The comparison can't be folded when it refers to the other tables, e.g. "inner.a = outer.a",
+		// but it is never true when one of the compared arguments is NULL.
+		if f, ok := result.(*expression.ScalarFunction); ok {
+			for _, arg := range f.Args[:nullRejectArgs[f.FuncName.L]] {
+				if con, ok := arg.(*expression.Constant); ok && con.Value.IsNull() {
+					return true, nil
+				}
+			}
+		}
 		return false, nil
 	}
 	if x.Value.IsNull() {
@@ -518,6 +757,7 @@ func (p *Projection) PredicatePushDown(predicates []expression.Expression) (ret
 			return nil, nil, errors.Trace(err1)
 		}
 	}
+	p.updateCorrelated()
 	return
 }
 
@@ -538,6 +778,7 @@ func (p *Union) PredicatePushDown(predicates []expression.Expression) (ret []exp
 			addSelection(p, proj.(LogicalPlan), retCond, p.allocator)
 		}
 	}
+	p.updateCorrelated()
 	return
 }
 
@@ -21,6 +21,7 @@ import (
 	"github.com/juju/errors"
 	"github.com/pingcap/tidb/ast"
 	"github.com/pingcap/tidb/expression"
+	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/util/types"
 )
 
@@ -162,11 +163,15 @@ func (r *rangeBuilder) buildFormBinOp(expr *expression.ScalarFunction) []rangePo
 		op = expr.FuncName.L
 	}
 	if value.IsNull() {
+		if op == ast.NullEQ {
+			// col <=> NULL is true only if col is NULL.
+			return []rangePoint{{start: true}, {}}
+		}
 		return nil
 	}
 
 	switch op {
-	case ast.EQ:
+	case ast.EQ, ast.NullEQ:
 		startPoint := rangePoint{value: value, start: true}
 		endPoint := rangePoint{value: value}
 		return []rangePoint{startPoint, endPoint}
@@ -237,48 +242,46 @@ func (r *rangeBuilder) buildFromIsFalse(expr *expression.ScalarFunction, isNot i
 }
 
 func (r *rangeBuilder) newBuildFromIn(expr *expression.ScalarFunction) []rangePoint {
-	var rangePoints []rangePoint
 	list := expr.Args[1:]
+	values := make([]types.Datum, 0, len(list))
 	for _, e := range list {
 		v, ok := e.(*expression.Constant)
 		if !ok {
 			r.err = ErrUnsupportedType.Gen("expr:%v is not constant", e)
 			return fullRange
 		}
-		startPoint := rangePoint{value: types.NewDatum(v.Value.GetValue()), start: true}
-		endPoint := rangePoint{value: types.NewDatum(v.Value.GetValue())}
-		rangePoints = append(rangePoints, startPoint, endPoint)
-	}
-	sorter := rangePointSorter{points: rangePoints}
-	sort.Sort(&sorter)
-	if sorter.err != nil {
-		r.err = sorter.err
-	}
-	// check duplicates
-	hasDuplicate := false
-	isStart := false
-	for _, v := range rangePoints {
-		if isStart == v.start {
-			hasDuplicate = true
-			break
+		// NULL never equals to the column, so it leads to no range.
+		if v.Value.IsNull() {
+			continue
 		}
-		isStart = v.start
+		values = append(values, types.NewDatum(v.Value.GetValue()))
 	}
-	if !hasDuplicate {
-		return rangePoints
+	return r.buildFromValues(values)
+}
+
+// buildFromValues builds the point ranges of the values, the duplicated values lead to one range.
+// The values are sorted in place.
+func (r *rangeBuilder) buildFromValues(values []types.Datum) []rangePoint {
+	// Sort the values instead of the range points, so a long list is sorted only once.
+	if err := types.SortDatums(values); err != nil {
+		r.err = errors.Trace(err)
+		return fullRange
 	}
-	// remove duplicates
-	distinctRangePoints := make([]rangePoint, 0, len(rangePoints))
-	isStart = false
-	for i := 0; i < len(rangePoints); i++ {
-		current := rangePoints[i]
-		if isStart == current.start {
-			continue
+	rangePoints := make([]rangePoint, 0, 2*len(values))
+	for i, v := range values {
+		if i > 0 {
+			cmp, err := values[i-1].CompareDatum(v)
+			if err != nil {
+				r.err = errors.Trace(err)
+				return fullRange
+			}
+			if cmp == 0 {
+				continue
+			}
 		}
-		distinctRangePoints = append(distinctRangePoints, current)
-		isStart = current.start
+		rangePoints = append(rangePoints, rangePoint{value: v, start: true}, rangePoint{value: v})
 	}
-	return distinctRangePoints
+	return rangePoints
 }
 
 func (r *rangeBuilder) newBuildFromPatternLike(expr *expression.ScalarFunction) []rangePoint {
@@ -367,13 +370,24 @@ func (r *rangeBuilder) buildFromNot(expr *expression.ScalarFunction) []rangePoin
 		startPoint := rangePoint{value: types.MinNotNullDatum(), start: true}
 		endPoint := rangePoint{value: types.MaxValueDatum()}
 		return []rangePoint{startPoint, endPoint}
+	case ast.NullEQ:
+		// NOT (col <=> NULL) range is [-inf, +inf], NOT (col <=> v) range is {[null, null], [-inf, v), (v, +inf]}.
+		eqPoints := r.buildFormBinOp(expr)
+		startPoint := rangePoint{value: types.MinNotNullDatum(), start: true}
+		endPoint := rangePoint{value: types.MaxValueDatum()}
+		if eqPoints[0].value.IsNull() {
+			return []rangePoint{startPoint, endPoint}
+		}
+		value := eqPoints[0].value
+		return []rangePoint{{start: true}, {}, startPoint, {value: value, excl: true},
+			{value: value, start: true, excl: true}, endPoint}
 	}
 	return nil
 }
 
 func (r *rangeBuilder) buildFromScalarFunc(expr *expression.ScalarFunction) []rangePoint {
 	switch op := expr.FuncName.L; op {
-	case ast.GE, ast.GT, ast.LT, ast.LE, ast.EQ, ast.NE:
+	case ast.GE, ast.GT, ast.LT, ast.LE, ast.EQ, ast.NullEQ, ast.NE:
 		return r.buildFormBinOp(expr)
 	case ast.AndAnd:
 		return r.intersection(r.build(expr.Args[0]), r.build(expr.Args[1]))
@@ -447,8 +461,14 @@ func (r *rangeBuilder) merge(a, b []rangePoint, union bool) []rangePoint {
 func (r *rangeBuilder) buildIndexRanges(rangePoints []rangePoint, tp *types.FieldType) []*IndexRange {
 	indexRanges := make([]*IndexRange, 0, len(rangePoints)/2)
 	for i := 0; i < len(rangePoints); i += 2 {
-		startPoint := r.convertPoint(rangePoints[i], tp)
-		endPoint := r.convertPoint(rangePoints[i+1], tp)
+		startPoint, ok := r.convertPoint(rangePoints[i], tp)
+		if !ok {
+			continue
+		}
+		endPoint, ok := r.convertPoint(rangePoints[i+1], tp)
+		if !ok {
+			continue
+		}
 		less, err := rangePointLess(startPoint, endPoint)
 		if err != nil {
 			r.err = errors.Trace(err)
@@ -464,18 +484,32 @@ func (r *rangeBuilder) buildIndexRanges(rangePoints []rangePoint, tp *types.Fiel
 		}
 		indexRanges = append(indexRanges, ir)
 	}
+	if isEnumOrSet(tp) {
+		indexRanges = r.sortEnumRanges(indexRanges)
+	}
 	return indexRanges
 }
 
-func (r *rangeBuilder) convertPoint(point rangePoint, tp *types.FieldType) rangePoint {
+// convertPoint converts the value of the point to the type of the index column, it returns false if the point
+// doesn't match any value of the column.
+func (r *rangeBuilder) convertPoint(point rangePoint, tp *types.FieldType) (rangePoint, bool) {
 	switch point.value.Kind() {
 	case types.KindMaxValue, types.KindMinNotNull:
-		return point
+		return point, true
+	}
+	if isEnumOrSet(tp) {
+		return r.convertEnumPoint(point, tp)
 	}
 	casted, err := point.value.ConvertTo(tp)
 	if err != nil {
 		r.err = errors.Trace(err)
 	}
+	return r.castPoint(point, casted), true
+}
+
+// castPoint replaces the value of the point with the casted value, and includes or excludes the point by how the
+// value is rounded.
+func (r *rangeBuilder) castPoint(point rangePoint, casted types.Datum) rangePoint {
 	valCmpCasted, err := point.value.CompareDatum(casted)
 	if err != nil {
 		r.err = errors.Trace(err)
@@ -512,6 +546,102 @@ func (r *rangeBuilder) convertPoint(point rangePoint, tp *types.FieldType) range
 	return point
 }
 
+func isEnumOrSet(tp *types.FieldType) bool {
+	return tp.Tp == mysql.TypeEnum || tp.Tp == mysql.TypeSet
+}
+
+// convertEnumPoint converts the point of an enum or set column to its numeric value, which is the order of the
+// column in the index. A string is converted to the member of the same name, because the column is compared with
+// a string by its name, so it returns false if there isn't such a member, e.g. 'b,a' for set('a', 'b'). Only the
+// points of "=", "<=>" and "in" are strings, see conditionChecker.
+func (r *rangeBuilder) convertEnumPoint(point rangePoint, tp *types.FieldType) (rangePoint, bool) {
+	switch point.value.Kind() {
+	case types.KindNull:
+		return point, true
+	case types.KindString, types.KindBytes:
+		casted, err := point.value.ConvertTo(tp)
+		if err != nil {
+			return point, false
+		}
+		var name string
+		var number float64
+		if casted.Kind() == types.KindMysqlEnum {
+			name, number = casted.GetMysqlEnum().String(), casted.GetMysqlEnum().ToNumber()
+		} else {
+			name, number = casted.GetMysqlSet().String(), casted.GetMysqlSet().ToNumber()
+		}
+		if name != point.value.GetString() {
+			return point, false
+		}
+		point.value = types.NewUintDatum(uint64(number))
+		return point, true
+	}
+	f, err := point.value.ToFloat64()
+	if err != nil {
+		r.err = errors.Trace(err)
+		return point, true
+	}
+	var casted types.Datum
+	switch {
+	case f <= 0:
+		casted = types.NewUintDatum(0)
+	case f >= math.MaxUint64:
+		casted = types.NewUintDatum(math.MaxUint64)
+	default:
+		casted = types.NewUintDatum(uint64(types.RoundFloat(f)))
+	}
+	return r.castPoint(point, casted), true
+}
+
+// sortEnumRanges sorts the ranges by the values of their last column, which is an enum or set column, the values
+// of the other columns of the ranges are the same. The points of the strings are sorted by the names before they
+// are converted, so the ranges may be out of order, and a string and a number may be the same member, e.g.
+// "e in ('a', 1)" for enum('a'), so the duplicated points are removed.
+func (r *rangeBuilder) sortEnumRanges(ranges []*IndexRange) []*IndexRange {
+	sorter := &enumRangeSorter{ranges: ranges}
+	sort.Sort(sorter)
+	if sorter.err != nil {
+		r.err = errors.Trace(sorter.err)
+	}
+	result := ranges[:0]
+	for _, ran := range ranges {
+		if n := len(result); n > 0 && ran.IsPoint() && result[n-1].IsPoint() {
+			last := result[n-1].LowVal
+			cmp, err := last[len(last)-1].CompareDatum(ran.LowVal[len(ran.LowVal)-1])
+			if err != nil {
+				r.err = errors.Trace(err)
+			}
+			if cmp == 0 {
+				continue
+			}
+		}
+		result = append(result, ran)
+	}
+	return result
+}
+
+type enumRangeSorter struct {
+	ranges []*IndexRange
+	err    error
+}
+
+func (r *enumRangeSorter) Len() int {
+	return len(r.ranges)
+}
+
+func (r *enumRangeSorter) Less(i, j int) bool {
+	a, b := r.ranges[i].LowVal, r.ranges[j].LowVal
+	cmp, err := a[len(a)-1].CompareDatum(b[len(b)-1])
+	if err != nil {
+		r.err = err
+	}
+	return cmp < 0
+}
+
+func (r *enumRangeSorter) Swap(i, j int) {
+	r.ranges[i], r.ranges[j] = r.ranges[j], r.ranges[i]
+}
+
 // appendIndexRanges appends additional column ranges for multi-column index.
 // The additional column ranges can only be appended to point ranges.
 // for example we have an index (a, b), if the condition is (a > 1 and b = 2)
@@ -532,8 +662,14 @@ func (r *rangeBuilder) appendIndexRanges(origin []*IndexRange, rangePoints []ran
 func (r *rangeBuilder) appendIndexRange(origin *IndexRange, rangePoints []rangePoint, ft *types.FieldType) []*IndexRange {
 	newRanges := make([]*IndexRange, 0, len(rangePoints)/2)
 	for i := 0; i < len(rangePoints); i += 2 {
-		startPoint := r.convertPoint(rangePoints[i], ft)
-		endPoint := r.convertPoint(rangePoints[i+1], ft)
+		startPoint, ok := r.convertPoint(rangePoints[i], ft)
+		if !ok {
+			continue
+		}
+		endPoint, ok := r.convertPoint(rangePoints[i+1], ft)
+		if !ok {
+			continue
+		}
 		less, err := rangePointLess(startPoint, endPoint)
 		if err != nil {
 			r.err = errors.Trace(err)
@@ -558,6 +694,9 @@ func (r *rangeBuilder) appendIndexRange(origin *IndexRange, rangePoints []rangeP
 		}
 		newRanges = append(newRanges, ir)
 	}
+	if isEnumOrSet(ft) {
+		newRanges = r.sortEnumRanges(newRanges)
+	}
 	return newRanges
 }
 
@@ -607,5 +746,83 @@ func (r *rangeBuilder) buildTableRanges(rangePoints []rangePoint) []TableRange {
 		}
 		tableRanges = append(tableRanges, TableRange{LowVal: startInt, HighVal: endInt})
 	}
-	return tableRanges
+	if len(tableRanges) <= fuseRangesThreshold {
+		return tableRanges
+	}
+	// The adjacent ranges are fused, e.g. "id in (1, 2, 3)" is [1, 3] rather than three point ranges.
+	fused := tableRanges[:1]
+	for _, ran := range tableRanges[1:] {
+		last := &fused[len(fused)-1]
+		if last.HighVal != math.MaxInt64 && last.HighVal+1 == ran.LowVal {
+			last.HighVal = ran.HighVal
+			continue
+		}
+		fused = append(fused, ran)
+	}
+	return fused
+}
+
+// fuseRangesThreshold is the number of ranges above which the adjacent ranges are fused. Point ranges are estimated
+// more precisely, so a few of them are kept as they are.
+const fuseRangesThreshold = 64
+
+// fuseIndexRanges fuses the adjacent index ranges whose last column is an integer column, e.g. for the index (a, b),
+// "a = 1 and b in (1, 2, 3)" is [1 1, 1 3] rather than three point ranges. The ranges should be sorted and the values
+// of the other columns of the fused ranges should be the same.
+func (r *rangeBuilder) fuseIndexRanges(ranges []*IndexRange, tp *types.FieldType) []*IndexRange {
+	if len(ranges) <= fuseRangesThreshold {
+		return ranges
+	}
+	switch tp.Tp {
+	case mysql.TypeTiny, mysql.TypeShort, mysql.TypeInt24, mysql.TypeLong, mysql.TypeLonglong:
+	default:
+		return ranges
+	}
+	fused := ranges[:0]
+	for _, ran := range ranges {
+		if n := len(fused); n > 0 && r.isNextIndexRange(fused[n-1], ran) {
+			fused[n-1].HighVal = ran.HighVal
+			fused[n-1].HighExclude = ran.HighExclude
+			continue
+		}
+		fused = append(fused, ran)
+	}
+	return fused
+}
+
+// isNextIndexRange checks if the range b follows the range a without any gap.
+func (r *rangeBuilder) isNextIndexRange(a, b *IndexRange) bool {
+	n := len(a.HighVal)
+	if a.HighExclude || b.LowExclude || n == 0 || len(a.LowVal) != n || len(b.LowVal) != n || len(b.HighVal) != n {
+		return false
+	}
+	for i := 0; i < n-1; i++ {
+		for _, v := range []types.Datum{a.HighVal[i], b.LowVal[i], b.HighVal[i]} {
+			cmp, err := a.LowVal[i].CompareDatum(v)
+			if err != nil {
+				r.err = errors.Trace(err)
+				return false
+			}
+			if cmp != 0 || v.Kind() == types.KindMinNotNull || v.Kind() == types.KindMaxValue {
+				return false
+			}
+		}
+	}
+	return isNextInteger(a.HighVal[n-1], b.LowVal[n-1])
+}
+
+// isNextInteger checks if b is the integer next to a.
+func isNextInteger(a, b types.Datum) bool {
+	switch a.Kind() {
+	case types.KindInt64:
+		switch b.Kind() {
+		case types.KindInt64:
+			return a.GetInt64() != math.MaxInt64 && a.GetInt64()+1 == b.GetInt64()
+		case types.KindUint64:
+			return a.GetInt64() == math.MaxInt64 && b.GetUint64() == math.MaxInt64+1
+		}
+	case types.KindUint64:
+		return b.Kind() == types.KindUint64 && a.GetUint64() != math.MaxUint64 && a.GetUint64()+1 == b.GetUint64()
+	}
+	return false
 }
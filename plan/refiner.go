@@ -56,6 +56,15 @@ func buildIndexRange(p *PhysicalIndexScan) error {
 		tp := &p.Table.Columns[colOff].FieldType
 		p.Ranges = rb.appendIndexRanges(p.Ranges, rangePoints, tp)
 	}
+	// A long IN list on the last column leads to many point ranges, so fuse the adjacent ones.
+	if len(p.AccessCondition) > 0 {
+		lastOff := p.accessInAndEqCount
+		if lastOff == len(p.AccessCondition) {
+			lastOff--
+		}
+		colOff := p.Index.Columns[lastOff].Offset
+		p.Ranges = rb.fuseIndexRanges(p.Ranges, &p.Table.Columns[colOff].FieldType)
+	}
 
 	// Take prefix index into consideration.
 	if p.Index.HasPrefixIndex() {
@@ -88,11 +97,11 @@ func refineRangeDatum(v *types.Datum, ic *model.IndexColumn) {
 	}
 }
 
-// getEQFunctionOffset judge if the expression is a eq function like A = 1 where a is an index.
+// getEQFunctionOffset judge if the expression is a eq function like A = 1 or A <=> 1 where a is an index.
 // If so, it will return the offset of A in index columns. e.g. for index(C,B,A), A's offset is 2.
 func getEQFunctionOffset(expr expression.Expression, cols []*model.IndexColumn) int {
 	f, ok := expr.(*expression.ScalarFunction)
-	if !ok || f.FuncName.L != ast.EQ {
+	if !ok || (f.FuncName.L != ast.EQ && f.FuncName.L != ast.NullEQ) {
 		return -1
 	}
 	if c, ok := f.Args[0].(*expression.Column); ok {
@@ -293,10 +302,18 @@ func (c *conditionChecker) findEqOrInFunc(conditions []expression.Expression) in
 }
 
 func (c *conditionChecker) checkScalarFunction(scalar *expression.ScalarFunction) bool {
+	switch scalar.FuncName.L {
+	case ast.EQ, ast.NullEQ, ast.In:
+	default:
+		// An enum or set column is compared with a string by its name, which isn't the order of the index.
+		if hasEnumStringComparison(scalar) {
+			return false
+		}
+	}
 	switch scalar.FuncName.L {
 	case ast.OrOr, ast.AndAnd:
 		return c.check(scalar.Args[0]) && c.check(scalar.Args[1])
-	case ast.EQ, ast.NE, ast.GE, ast.GT, ast.LE, ast.LT:
+	case ast.EQ, ast.NullEQ, ast.NE, ast.GE, ast.GT, ast.LE, ast.LT:
 		if _, ok := scalar.Args[0].(*expression.Constant); ok {
 			return c.checkColumn(scalar.Args[1])
 		}
@@ -390,6 +407,28 @@ func (c *conditionChecker) checkColumn(expr expression.Expression) bool {
 	return true
 }
 
+// hasEnumStringComparison checks if the expression compares an enum or set column with a string constant.
+func hasEnumStringComparison(expr expression.Expression) bool {
+	scalar, ok := expr.(*expression.ScalarFunction)
+	if !ok {
+		return false
+	}
+	var hasEnum, hasString bool
+	for _, arg := range scalar.Args {
+		switch x := arg.(type) {
+		case *expression.Column:
+			hasEnum = hasEnum || x.RetType.Tp == mysql.TypeEnum || x.RetType.Tp == mysql.TypeSet
+		case *expression.Constant:
+			hasString = hasString || x.Value.Kind() == types.KindString || x.Value.Kind() == types.KindBytes
+		case *expression.ScalarFunction:
+			if hasEnumStringComparison(x) {
+				return true
+			}
+		}
+	}
+	return hasEnum && hasString
+}
+
 var oppositeOp = map[string]string{
 	ast.LT: ast.GE,
 	ast.GE: ast.LT,
@@ -410,33 +449,31 @@ func pushDownNot(expr expression.Expression, not bool) expression.Expression {
 				return nf
 			}
 			for i, arg := range f.Args {
-				f.Args[i] = pushDownNot(arg, false)
+				f.SetArg(i, pushDownNot(arg, false))
 			}
 			return f
 		case ast.AndAnd:
 			if not {
-				args := f.Args
-				for i, a := range args {
-					args[i] = pushDownNot(a, true)
+				for i, a := range f.Args {
+					f.SetArg(i, pushDownNot(a, true))
 				}
-				nf, _ := expression.NewFunction(ast.OrOr, f.GetType(), args...)
+				nf, _ := expression.NewFunction(ast.OrOr, f.GetType(), f.Args...)
 				return nf
 			}
 			for i, arg := range f.Args {
-				f.Args[i] = pushDownNot(arg, false)
+				f.SetArg(i, pushDownNot(arg, false))
 			}
 			return f
 		case ast.OrOr:
 			if not {
-				args := f.Args
-				for i, a := range args {
-					args[i] = pushDownNot(a, true)
+				for i, a := range f.Args {
+					f.SetArg(i, pushDownNot(a, true))
 				}
-				nf, _ := expression.NewFunction(ast.AndAnd, f.GetType(), args...)
+				nf, _ := expression.NewFunction(ast.AndAnd, f.GetType(), f.Args...)
 				return nf
 			}
 			for i, arg := range f.Args {
-				f.Args[i] = pushDownNot(arg, false)
+				f.SetArg(i, pushDownNot(arg, false))
 			}
 			return f
 		}
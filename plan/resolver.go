@@ -22,6 +22,7 @@ import (
 	"github.com/pingcap/tidb/infoschema"
 	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/parser"
 	"github.com/pingcap/tidb/table"
 	"github.com/pingcap/tidb/util/types"
 )
@@ -30,14 +31,14 @@ import (
 // It generates ResultFields for ResultSetNode and resolves ColumnNameExpr to a ResultField.
 func ResolveName(node ast.Node, info infoschema.InfoSchema, ctx context.Context) error {
 	defaultSchema := ctx.GetSessionVars().CurrentDB
-	resolver := nameResolver{Info: info, Ctx: ctx, DefaultSchema: model.NewCIStr(defaultSchema)}
+	resolver := nameResolver{Info: info, Ctx: ctx, DefaultSchema: model.NewTableCIStr(defaultSchema)}
 	node.Accept(&resolver)
 	return errors.Trace(resolver.Err)
 }
 
 // MockResolveName only serves for test.
 func MockResolveName(node ast.Node, info infoschema.InfoSchema, defaultSchema string, ctx context.Context) error {
-	resolver := nameResolver{Info: info, Ctx: ctx, DefaultSchema: model.NewCIStr(defaultSchema)}
+	resolver := nameResolver{Info: info, Ctx: ctx, DefaultSchema: model.NewTableCIStr(defaultSchema)}
 	node.Accept(&resolver)
 	return resolver.Err
 }
@@ -59,6 +60,26 @@ type nameResolver struct {
 	useOuterContext bool
 
 	contextStack []*resolverContext
+	viewStack    []*viewContext
+	cteScopes    []*cteScope
+}
+
+// cteScope stores the common table expressions defined by a WITH clause.
+type cteScope struct {
+	with *ast.WithClause
+	// visible is the number of the CTEs that can be referred to, a CTE can only refer to the ones defined before it.
+	visible int
+}
+
+// viewContext stores the information of a view that is expanded to a derived table.
+type viewContext struct {
+	source  *ast.TableSource
+	dbName  model.CIStr
+	tblInfo *model.TableInfo
+	// outerSchema is the default schema of the statement referring to the view.
+	outerSchema model.CIStr
+	// outerCTEScopes are the CTE scopes of the statement referring to the view, which are invisible in the view.
+	outerCTEScopes []*cteScope
 }
 
 // resolverContext stores information in a single level of select statement
@@ -84,6 +105,8 @@ type resolverContext struct {
 	// When visiting TableRefs, tables in this context are not available
 	// because it is being collected.
 	inTableRefs bool
+	// When visiting the arguments of a table function, the tables on its left are available.
+	inTableFuncArgs bool
 	// When visiting on condition only tables in current join node are available.
 	inOnCondition bool
 	// When visiting field list, fieldList in this context are not available.
@@ -102,10 +125,45 @@ type resolverContext struct {
 	useOuterContext bool
 	// When visiting multi-table delete stmt table list.
 	inDeleteTableList bool
+	// When visiting the table list of "SELECT ... FOR UPDATE OF".
+	inSelectLockTableList bool
 	// When visiting create/drop table statement.
 	inCreateOrDropTable bool
+	// When visiting select statement, the views in the from clause are expanded.
+	inSelect bool
 	// When visiting show statement.
 	inShow bool
+	// When visiting the WITH clause, the common table expressions can't refer to the outer query.
+	inWithClause bool
+}
+
+// clauseName returns the name of the clause being visited, it is used in the error messages.
+func (ctx *resolverContext) clauseName() string {
+	switch {
+	case ctx.inOnCondition:
+		return "on clause"
+	case ctx.inFieldList:
+		return "field list"
+	case ctx.inGroupBy:
+		return "group statement"
+	case ctx.inHaving:
+		return "having clause"
+	case ctx.inOrderBy:
+		return "order clause"
+	}
+	return "where clause"
+}
+
+// columnNameString returns the column name as it is written in the statement.
+func columnNameString(name *ast.ColumnName) string {
+	str := name.Name.O
+	if name.Table.L != "" {
+		str = name.Table.O + "." + str
+		if name.Schema.L != "" {
+			str = name.Schema.O + "." + str
+		}
+	}
+	return str
 }
 
 // currentContext gets the current resolverContext.
@@ -174,6 +232,9 @@ func (nr *nameResolver) Enter(inNode ast.Node) (outNode ast.Node, skipChildren b
 	case *ast.CreateTableStmt:
 		nr.pushContext()
 		nr.currentContext().inCreateOrDropTable = true
+	case *ast.CreateViewStmt:
+		nr.pushContext()
+		nr.currentContext().inCreateOrDropTable = true
 	case *ast.DeleteStmt:
 		nr.pushContext()
 	case *ast.DeleteTableList:
@@ -203,6 +264,9 @@ func (nr *nameResolver) Enter(inNode ast.Node) (outNode ast.Node, skipChildren b
 		nr.currentContext().inOrderBy = true
 	case *ast.SelectStmt:
 		nr.pushContext()
+		nr.currentContext().inSelect = true
+	case *ast.SelectLockTableList:
+		nr.currentContext().inSelectLockTableList = true
 	case *ast.SetStmt:
 		for _, assign := range v.Variables {
 			if cn, ok := assign.Value.(*ast.ColumnNameExpr); ok && cn.Name.Table.L == "" {
@@ -215,14 +279,32 @@ func (nr *nameResolver) Enter(inNode ast.Node) (outNode ast.Node, skipChildren b
 		nr.pushContext()
 		nr.currentContext().inShow = true
 		nr.fillShowFields(v)
+	case *ast.TableFunc:
+		nr.currentContext().inTableFuncArgs = true
 	case *ast.TableRefsClause:
 		nr.currentContext().inTableRefs = true
+	case *ast.TableSource:
+		if v.IsView {
+			restoreView(v)
+		}
+		if nr.expandCTE(v) {
+			break
+		}
+		nr.expandView(v)
+		if nr.Err != nil {
+			return inNode, true
+		}
 	case *ast.TruncateTableStmt:
 		nr.pushContext()
 	case *ast.UnionStmt:
 		nr.pushContext()
 	case *ast.UpdateStmt:
 		nr.pushContext()
+	case *ast.WithClause:
+		nr.handleWithClause(v)
+		if nr.Err != nil {
+			return inNode, true
+		}
 	}
 	return inNode, false
 }
@@ -243,12 +325,21 @@ func (nr *nameResolver) Leave(inNode ast.Node) (node ast.Node, ok bool) {
 		nr.popContext()
 	case *ast.TableName:
 		nr.handleTableName(v)
+	case *ast.TableFunc:
+		nr.currentContext().inTableFuncArgs = false
+		nr.handleTableFunc(v)
 	case *ast.ColumnNameExpr:
 		nr.handleColumnName(v)
+	case *ast.CommonTableExpr:
+		nr.handleCommonTableExpr(v)
+	case *ast.CTETable:
+		nr.handleCTETable(v)
 	case *ast.CreateIndexStmt:
 		nr.popContext()
 	case *ast.CreateTableStmt:
 		nr.popContext()
+	case *ast.CreateViewStmt:
+		nr.popContext()
 	case *ast.DeleteTableList:
 		nr.currentContext().inDeleteTableList = false
 	case *ast.DoStmt:
@@ -258,6 +349,10 @@ func (nr *nameResolver) Leave(inNode ast.Node) (node ast.Node, ok bool) {
 	case *ast.DropTableStmt:
 		nr.popContext()
 	case *ast.TableSource:
+		nr.finishView(v)
+		if nr.Err != nil {
+			return inNode, false
+		}
 		nr.handleTableSource(v)
 	case *ast.OnCondition:
 		nr.currentContext().inOnCondition = false
@@ -286,12 +381,17 @@ func (nr *nameResolver) Leave(inNode ast.Node) (node ast.Node, ok bool) {
 		nr.currentContext().inByItemExpression = false
 	case *ast.PositionExpr:
 		nr.handlePosition(v)
+	case *ast.SelectLockTableList:
+		nr.currentContext().inSelectLockTableList = false
 	case *ast.SelectStmt:
 		ctx := nr.currentContext()
 		v.SetResultFields(ctx.fieldList)
 		if ctx.useOuterContext {
 			nr.useOuterContext = true
 		}
+		if v.With != nil {
+			nr.cteScopes = nr.cteScopes[:len(nr.cteScopes)-1]
+		}
 		nr.popContext()
 	case *ast.SetStmt:
 		nr.popContext()
@@ -312,6 +412,9 @@ func (nr *nameResolver) Leave(inNode ast.Node) (node ast.Node, ok bool) {
 		if ctx.useOuterContext {
 			nr.useOuterContext = true
 		}
+		if v.With != nil {
+			nr.cteScopes = nr.cteScopes[:len(nr.cteScopes)-1]
+		}
 		nr.popContext()
 	case *ast.UnionSelectList:
 		nr.handleUnionSelectList(v)
@@ -323,6 +426,8 @@ func (nr *nameResolver) Leave(inNode ast.Node) (node ast.Node, ok bool) {
 		nr.popContext()
 	case *ast.UpdateStmt:
 		nr.popContext()
+	case *ast.WithClause:
+		nr.currentContext().inWithClause = false
 	}
 	return inNode, nr.Err == nil
 }
@@ -338,8 +443,13 @@ func (nr *nameResolver) handleTableName(tn *ast.TableName) {
 		// Skip resolving the table to avoid error.
 		return
 	}
-	if ctx.inDeleteTableList {
+	// The tables in the delete table list and the lock table list refer to the tables in the from clause.
+	if ctx.inDeleteTableList || ctx.inSelectLockTableList {
 		idx, ok := ctx.tableMap[nr.tableUniqueName(tn.Schema, tn.Name)]
+		if !ok && ctx.inDeleteTableList {
+			nr.Err = ErrUnknownTable.Gen("Unknown table '%s' in MULTI DELETE", tn.Name.O)
+			return
+		}
 		if !ok {
 			nr.Err = errors.Errorf("Unknown table %s", tn.Name.O)
 			return
@@ -356,6 +466,11 @@ func (nr *nameResolver) handleTableName(tn *ast.TableName) {
 		nr.Err = errors.Trace(err)
 		return
 	}
+	// The views in the select statements are expanded already, and the others can't be read or written.
+	if table.Meta().IsView() && !ctx.inShow {
+		nr.Err = infoschema.ErrWrongObject.Gen("'%s.%s' is not BASE TABLE", tn.Schema, tn.Name)
+		return
+	}
 	tn.TableInfo = table.Meta()
 	dbInfo, _ := nr.Info.SchemaByName(tn.Schema)
 	tn.DBInfo = dbInfo
@@ -394,6 +509,221 @@ func (nr *nameResolver) handleTableName(tn *ast.TableName) {
 	return
 }
 
+// expandView replaces the view in the from clause of a select statement with the select statement of the view,
+// so the view is resolved and planned as a derived table that the optimizer can merge into the outer query.
+func (nr *nameResolver) expandView(ts *ast.TableSource) {
+	tn, ok := ts.Source.(*ast.TableName)
+	if !ok || !nr.currentContext().inSelect {
+		return
+	}
+	schema := tn.Schema
+	if schema.L == "" {
+		schema = nr.DefaultSchema
+	}
+	tbl, err := nr.Info.TableByName(schema, tn.Name)
+	if err != nil || !tbl.Meta().IsView() {
+		// The error is reported when the table name is resolved.
+		return
+	}
+	tblInfo := tbl.Meta()
+	for _, vc := range nr.viewStack {
+		if vc.tblInfo.ID == tblInfo.ID {
+			nr.Err = ErrViewRecursive.Gen("`%s`.`%s` contains view recursion", schema, tblInfo.Name)
+			return
+		}
+	}
+	// The privileges to read the view and the tables it reads are checked when the statement is planned.
+	tn.DBInfo, _ = nr.Info.SchemaByName(schema)
+	tn.TableInfo = tblInfo
+	stmt, err := parser.New().ParseOneStmt(tblInfo.View.SelectStmt, tblInfo.Charset, tblInfo.Collate)
+	if err != nil {
+		nr.Err = errors.Trace(err)
+		return
+	}
+	sel, ok := stmt.(ast.ResultSetNode)
+	if !ok {
+		nr.Err = ErrViewInvalid.Gen("View '%s.%s' is not defined by a select statement", schema, tblInfo.Name)
+		return
+	}
+	nr.viewStack = append(nr.viewStack, &viewContext{
+		source:         ts,
+		dbName:         schema,
+		tblInfo:        tblInfo,
+		outerSchema:    nr.DefaultSchema,
+		outerCTEScopes: nr.cteScopes,
+	})
+	// The table names in the view refer to the database of the view by default.
+	nr.DefaultSchema = schema
+	nr.cteScopes = nil
+	ts.Source = sel
+	ts.IsView = true
+	ts.ViewName = tn
+	if ts.AsName.L == "" {
+		ts.AsName = tblInfo.Name
+	}
+}
+
+// restoreView puts the view back to the table source expanded when the statement is resolved before, so the view is
+// expanded from its current definition rather than the select statement resolved against the old schema.
+func restoreView(ts *ast.TableSource) {
+	if ts.AsName.L == ts.ViewName.Name.L {
+		ts.AsName = model.CIStr{}
+	}
+	ts.Source = ts.ViewName
+	ts.IsView = false
+	ts.ViewRefs = nil
+}
+
+// finishView names the result fields of the expanded view by the columns of the view and restores the default
+// schema of the outer query.
+func (nr *nameResolver) finishView(ts *ast.TableSource) {
+	n := len(nr.viewStack)
+	if n == 0 || nr.viewStack[n-1].source != ts {
+		return
+	}
+	vc := nr.viewStack[n-1]
+	nr.viewStack = nr.viewStack[:n-1]
+	nr.DefaultSchema = vc.outerSchema
+	nr.cteScopes = vc.outerCTEScopes
+	rfs := ts.GetResultFields()
+	// The columns of the select statement may be changed if the view selects "*" from a table altered later.
+	if len(rfs) != len(vc.tblInfo.Columns) {
+		nr.Err = ErrViewInvalid.Gen("View '%s.%s' references invalid table(s) or column(s) or function(s)",
+			vc.dbName, vc.tblInfo.Name)
+		return
+	}
+	for i, rf := range rfs {
+		rf.ColumnAsName = vc.tblInfo.Columns[i].Name
+	}
+	ts.ViewRefs = collectViewRefs(ts.Source)
+}
+
+// handleWithClause checks the names of the common table expressions and makes them visible to the statement.
+// The references are counted again every time the statement is resolved.
+func (nr *nameResolver) handleWithClause(with *ast.WithClause) {
+	names := make(map[string]struct{}, len(with.CTEs))
+	for _, cte := range with.CTEs {
+		if _, ok := names[cte.Name.L]; ok {
+			nr.Err = ErrNonuniqTable.Gen("Not unique table/alias: '%s'", cte.Name.O)
+			return
+		}
+		names[cte.Name.L] = struct{}{}
+		cte.RefCount = 0
+	}
+	nr.cteScopes = append(nr.cteScopes, &cteScope{with: with})
+	nr.currentContext().inWithClause = true
+}
+
+// handleCommonTableExpr checks the column list of the common table expression and makes it visible to the
+// following ones.
+func (nr *nameResolver) handleCommonTableExpr(cte *ast.CommonTableExpr) {
+	if cte.ColNames != nil && len(cte.ColNames) != len(cte.Query.GetResultFields()) {
+		nr.Err = ErrViewWrongList.Gen("In definition of common table expression '%s', SELECT list and column names list have different column counts", cte.Name.O)
+		return
+	}
+	nr.cteScopes[len(nr.cteScopes)-1].visible++
+}
+
+// findCTE looks up the visible common table expression by name from the innermost WITH clause.
+func (nr *nameResolver) findCTE(name model.CIStr) *ast.CommonTableExpr {
+	for i := len(nr.cteScopes) - 1; i >= 0; i-- {
+		scope := nr.cteScopes[i]
+		for _, cte := range scope.with.CTEs[:scope.visible] {
+			if cte.Name.L == name.L {
+				return cte
+			}
+		}
+	}
+	return nil
+}
+
+// expandCTE replaces the table name referring to a common table expression with a CTE table, and counts the
+// reference. It returns true if the table source refers to a common table expression.
+func (nr *nameResolver) expandCTE(ts *ast.TableSource) bool {
+	if ct, ok := ts.Source.(*ast.CTETable); ok {
+		// The statement is resolved again, e.g. a prepared statement is executed after the schema changes.
+		ct.CTE.RefCount++
+		return true
+	}
+	tn, ok := ts.Source.(*ast.TableName)
+	if !ok || tn.Schema.L != "" {
+		return false
+	}
+	cte := nr.findCTE(tn.Name)
+	if cte == nil {
+		return false
+	}
+	cte.RefCount++
+	ts.Source = &ast.CTETable{CTE: cte}
+	if ts.AsName.L == "" {
+		ts.AsName = cte.Name
+	}
+	return true
+}
+
+// handleCTETable sets the result fields of the CTE table, which are named by the column list of the
+// common table expression if there is one.
+func (nr *nameResolver) handleCTETable(ct *ast.CTETable) {
+	cte := ct.CTE
+	qrfs := cte.Query.GetResultFields()
+	rfs := make([]*ast.ResultField, 0, len(qrfs))
+	tblInfo := &model.TableInfo{Name: cte.Name}
+	for i, qrf := range qrfs {
+		name := qrf.ColumnAsName
+		if cte.ColNames != nil {
+			name = cte.ColNames[i]
+		} else if name.L == "" {
+			name = qrf.Column.Name
+		}
+		col := *qrf.Column
+		col.Name = name
+		rfs = append(rfs, &ast.ResultField{
+			Column:       &col,
+			ColumnAsName: name,
+			Table:        tblInfo,
+			Expr:         qrf.Expr,
+		})
+	}
+	ct.SetResultFields(rfs)
+}
+
+// handleTableFunc checks the table function call and sets its result fields.
+func (nr *nameResolver) handleTableFunc(tf *ast.TableFunc) {
+	fn, ok := tableFunctions[tf.FnName.L]
+	if !ok {
+		nr.Err = ErrUnknownTableFunc.Gen("FUNCTION %s does not exist", tf.FnName.O)
+		return
+	}
+	if len(tf.Args) < fn.minArgs || len(tf.Args) > fn.maxArgs {
+		nr.Err = ErrWrongParamCount.Gen("Incorrect parameter count in the call to native function '%s'", tf.FnName.O)
+		return
+	}
+	if fn.withColumns && tf.Columns == nil {
+		nr.Err = ErrWrongArguments.Gen("Incorrect arguments to %s, the COLUMNS clause is required", tf.FnName.O)
+		return
+	}
+	if !fn.withColumns && tf.Columns != nil {
+		nr.Err = ErrWrongArguments.Gen("Incorrect arguments to %s, the COLUMNS clause is not allowed", tf.FnName.O)
+		return
+	}
+	cols, err := fn.columns(nr, tf)
+	if err != nil {
+		nr.Err = errors.Trace(err)
+		return
+	}
+	rfs := make([]*ast.ResultField, 0, len(cols))
+	for _, col := range cols {
+		expr := &ast.ValueExpr{}
+		expr.SetType(&col.FieldType)
+		rfs = append(rfs, &ast.ResultField{
+			Column: col,
+			Table:  &model.TableInfo{Name: tf.FnName},
+			Expr:   expr,
+		})
+	}
+	tf.SetResultFields(rfs)
+}
+
 // handleTableSources checks name duplication
 // and puts the table source in current resolverContext.
 // Note:
@@ -419,7 +749,21 @@ func (nr *nameResolver) handleTableSource(ts *ast.TableSource) {
 			return
 		}
 		ctx.tableMap[name] = len(ctx.tables)
-	case *ast.SelectStmt:
+	case *ast.TableFunc:
+		// A table function without alias is referred to by its function name.
+		if ts.AsName.L == "" {
+			ts.AsName = ts.Source.(*ast.TableFunc).FnName
+			for _, v := range ts.GetResultFields() {
+				v.TableAsName = ts.AsName
+			}
+		}
+		name := ts.AsName.L
+		if _, ok := ctx.derivedTableMap[name]; ok {
+			nr.Err = errors.Errorf("duplicated table/alias name %s", name)
+			return
+		}
+		ctx.derivedTableMap[name] = len(ctx.tables)
+	case *ast.SelectStmt, *ast.CTETable:
 		name := ts.AsName.L
 		if _, ok := ctx.derivedTableMap[name]; ok {
 			nr.Err = errors.Errorf("duplicated table/alias name %s", name)
@@ -471,6 +815,10 @@ func (nr *nameResolver) handleColumnName(cn *ast.ColumnNameExpr) {
 
 	// Try to resolve the column name form top to bottom in the context stack.
 	for i := len(nr.contextStack) - 1; i >= 0; i-- {
+		if nr.contextStack[i].inWithClause {
+			// The common table expressions are not correlated to the outer query.
+			break
+		}
 		if nr.resolveColumnNameInContext(nr.contextStack[i], cn) {
 			// Column is already resolved or encountered an error.
 			if i < len(nr.contextStack)-1 {
@@ -480,12 +828,16 @@ func (nr *nameResolver) handleColumnName(cn *ast.ColumnNameExpr) {
 			return
 		}
 	}
-	nr.Err = errors.Errorf("unknown column %s", cn.Name.Name.L)
+	nr.Err = ErrUnknownColumn.Gen("Unknown column '%s' in '%s'", columnNameString(cn.Name), ctx.clauseName())
 }
 
 // resolveColumnNameInContext looks up and sets ResultField for a column with the ctx.
 func (nr *nameResolver) resolveColumnNameInContext(ctx *resolverContext, cn *ast.ColumnNameExpr) bool {
 	if ctx.inTableRefs {
+		if ctx.inTableFuncArgs {
+			// The tables collected so far are the ones on the left of the table function.
+			return nr.resolveColumnInTableSources(cn, ctx.tables)
+		}
 		// In TableRefsClause, column reference only in join on condition which is handled before.
 		return false
 	}
@@ -580,7 +932,7 @@ func (nr *nameResolver) resolveColumnNameInOnCondition(cn *ast.ColumnNameExpr) {
 	join := ctx.joinNodeStack[len(ctx.joinNodeStack)-1]
 	tableSources := appendTableSources(nil, join)
 	if !nr.resolveColumnInTableSources(cn, tableSources) {
-		nr.Err = errors.Errorf("unknown column name %s", cn.Name.Name.O)
+		nr.Err = ErrUnknownColumn.Gen("Unknown column '%s' in '%s'", columnNameString(cn.Name), ctx.clauseName())
 	}
 }
 
@@ -626,7 +978,7 @@ func (nr *nameResolver) resolveColumnInTableSources(cn *ast.ColumnNameExpr, tabl
 				matchColumnName := rf.ColumnAsName.L == "" && rf.Column.Name.L == columnNameL
 				if matchAsName || matchColumnName {
 					if matchedResultField != nil {
-						nr.Err = errors.Errorf("column %s is ambiguous.", cn.Name.Name.O)
+						nr.Err = ErrAmbiguousColumn.Gen("Column '%s' in %s is ambiguous", cn.Name.Name.O, nr.currentContext().clauseName())
 						return true
 					}
 					matchedResultField = rf
@@ -675,7 +1027,7 @@ func (nr *nameResolver) resolveColumnInResultFields(ctx *resolverContext, cn *as
 			} else {
 				sameColumn := matched.TableName == rf.TableName && matched.Column.Name.L == rf.Column.Name.L
 				if !sameColumn {
-					nr.Err = errors.Errorf("column %s is ambiguous.", cn.Name.Name.O)
+					nr.Err = ErrAmbiguousColumn.Gen("Column '%s' in %s is ambiguous", cn.Name.Name.O, nr.currentContext().clauseName())
 					return true
 				}
 			}
@@ -773,6 +1125,8 @@ func (nr *nameResolver) createResultFields(field *ast.SelectField) (rfs []*ast.R
 		rf.Table = v.Refer.Table
 		rf.DBName = v.Refer.DBName
 		rf.TableName = v.Refer.TableName
+		// Keep the alias of the table like the wildcard fields, "order by x.a" only matches the column of "t as x".
+		rf.TableAsName = v.Refer.TableAsName
 		rf.Expr = v
 	default:
 		rf.Column = &model.ColumnInfo{} // Empty column info.
@@ -820,7 +1174,7 @@ func (nr *nameResolver) tableUniqueName(schema, table model.CIStr) string {
 func (nr *nameResolver) handlePosition(pos *ast.PositionExpr) {
 	ctx := nr.currentContext()
 	if pos.N < 1 || pos.N > len(ctx.fieldList) {
-		nr.Err = errors.Errorf("Unknown column '%d'", pos.N)
+		nr.Err = ErrUnknownColumn.Gen("Unknown column '%d' in '%s'", pos.N, ctx.clauseName())
 		return
 	}
 	matched := ctx.fieldList[pos.N-1]
@@ -865,7 +1219,7 @@ func (nr *nameResolver) fillShowFields(s *ast.ShowStmt) {
 			s.DBName = nr.DefaultSchema.O
 		}
 	} else if s.Table != nil && s.Table.Schema.L == "" {
-		s.Table.Schema = model.NewCIStr(s.DBName)
+		s.Table.Schema = model.NewTableCIStr(s.DBName)
 	}
 	var fields []*ast.ResultField
 	var (
@@ -932,6 +1286,12 @@ func (nr *nameResolver) fillShowFields(s *ast.ShowStmt) {
 		names = []string{"Id", "User", "Host", "db", "Command", "Time", "State", "Info"}
 		ftypes = []byte{mysql.TypeLonglong, mysql.TypeVarchar, mysql.TypeVarchar,
 			mysql.TypeVarchar, mysql.TypeVarchar, mysql.TypeLong, mysql.TypeVarchar, mysql.TypeString}
+	case ast.ShowPlanForDigest:
+		// The latencies are in seconds.
+		names = []string{"Digest", "User", "Plan_digest", "Sample_SQL", "Exec_count", "Avg_latency", "Max_latency",
+			"First_seen", "Last_seen", "Plan"}
+		ftypes = []byte{mysql.TypeVarchar, mysql.TypeVarchar, mysql.TypeVarchar, mysql.TypeBlob, mysql.TypeLonglong,
+			mysql.TypeDouble, mysql.TypeDouble, mysql.TypeDatetime, mysql.TypeDatetime, mysql.TypeBlob}
 	}
 	for i, name := range names {
 		f := &ast.ResultField{
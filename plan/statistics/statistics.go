@@ -60,6 +60,20 @@ type Column struct {
 	Numbers []int64
 	Values  []types.Datum
 	Repeats []int64
+
+	// pseudoCount is the row count which the estimations without histogram are based on, 0 means the pseudo row
+	// count. fixedNDV means the NDV is set by the STATS_NDV table option or OverriddenTable, so the equal condition
+	// selects 1/NDV of the rows.
+	pseudoCount int64
+	fixedNDV    bool
+}
+
+// pseudoRows returns the row count which the estimations without histogram are based on.
+func (c *Column) pseudoRows() int64 {
+	if c.pseudoCount > 0 {
+		return c.pseudoCount
+	}
+	return pseudoRowCount
 }
 
 func (c *Column) String() string {
@@ -75,7 +89,10 @@ func (c *Column) String() string {
 // EqualRowCount estimates the row count where the column equals to value.
 func (c *Column) EqualRowCount(value types.Datum) (int64, error) {
 	if len(c.Numbers) == 0 {
-		return pseudoRowCount / pseudoEqualRate, nil
+		if c.fixedNDV {
+			return c.pseudoRows() / c.NDV, nil
+		}
+		return c.pseudoRows() / pseudoEqualRate, nil
 	}
 	index, match, err := c.search(value)
 	if err != nil {
@@ -94,7 +111,7 @@ func (c *Column) EqualRowCount(value types.Datum) (int64, error) {
 // GreaterRowCount estimates the row count where the column greater than value.
 func (c *Column) GreaterRowCount(value types.Datum) (int64, error) {
 	if len(c.Numbers) == 0 {
-		return pseudoRowCount / pseudoLessRate, nil
+		return c.pseudoRows() / pseudoLessRate, nil
 	}
 	index, match, err := c.search(value)
 	if err != nil {
@@ -118,7 +135,7 @@ func (c *Column) GreaterRowCount(value types.Datum) (int64, error) {
 // LessRowCount estimates the row count where the column less than value.
 func (c *Column) LessRowCount(value types.Datum) (int64, error) {
 	if len(c.Numbers) == 0 {
-		return pseudoRowCount / pseudoLessRate, nil
+		return c.pseudoRows() / pseudoLessRate, nil
 	}
 	index, match, err := c.search(value)
 	if err != nil {
@@ -142,7 +159,7 @@ func (c *Column) LessRowCount(value types.Datum) (int64, error) {
 // BetweenRowCount estimates the row count where column greater or equal to a and less than b.
 func (c *Column) BetweenRowCount(a, b types.Datum) (int64, error) {
 	if len(c.Numbers) == 0 {
-		return pseudoRowCount / pseudoBetweenRate, nil
+		return c.pseudoRows() / pseudoBetweenRate, nil
 	}
 	lessCountA, err := c.LessRowCount(a)
 	if err != nil {
@@ -185,6 +202,24 @@ func (c *Column) search(target types.Datum) (index int, match bool, err error) {
 	return
 }
 
+// AvgSize estimates the average size in bytes of the column values by the bucket values of the histogram.
+func (c *Column) AvgSize() float64 {
+	if len(c.Values) == 0 {
+		return 0
+	}
+	var total int
+	for _, v := range c.Values {
+		switch v.Kind() {
+		case types.KindNull:
+		case types.KindString, types.KindBytes:
+			total += len(v.GetBytes())
+		default:
+			total += 8
+		}
+	}
+	return float64(total) / float64(len(c.Values))
+}
+
 // Table represents statistics for a table.
 type Table struct {
 	info    *model.TableInfo
@@ -203,6 +238,34 @@ func (t *Table) String() string {
 	return strings.Join(strs, "\n")
 }
 
+// AvgRowSize estimates the average size in bytes of a row in the table.
+func (t *Table) AvgRowSize() float64 {
+	var size float64
+	for _, col := range t.Columns {
+		size += col.AvgSize()
+	}
+	return size
+}
+
+// IndexCardinality estimates the number of distinct values of the first prefixLen columns of an index,
+// offsets are the offsets of the index columns in the table.
+// Since we only have the NDV of every single column, the NDV of the prefix is at least the max NDV of its columns.
+func (t *Table) IndexCardinality(offsets []int, prefixLen int, unique bool) int64 {
+	if unique && prefixLen == len(offsets) {
+		return t.Count
+	}
+	var ndv int64
+	for _, offset := range offsets[:prefixLen] {
+		if offset < len(t.Columns) && t.Columns[offset].NDV > ndv {
+			ndv = t.Columns[offset].NDV
+		}
+	}
+	if ndv > t.Count {
+		ndv = t.Count
+	}
+	return ndv
+}
+
 // ToPB converts Table to TablePB.
 func (t *Table) ToPB() (*TablePB, error) {
 	tblPB := &TablePB{
@@ -369,6 +432,7 @@ func TableFromPB(ti *model.TableInfo, tpb *TablePB) (*Table, error) {
 }
 
 // PseudoTable creates a pseudo table statistics when statistic can not be found in KV store.
+// The columns with the STATS_NDV table option have the declared NDVs.
 func PseudoTable(ti *model.TableInfo) *Table {
 	t := &Table{info: ti}
 	t.TS = pseudoTimestamp
@@ -379,7 +443,32 @@ func PseudoTable(ti *model.TableInfo) *Table {
 			ID:  v.ID,
 			NDV: pseudoRowCount / 2,
 		}
+		if v.StatsNDV > 0 {
+			c.NDV = v.StatsNDV
+			c.fixedNDV = true
+		}
 		t.Columns[i] = c
 	}
 	return t
 }
+
+// OverriddenTable creates a pseudo table statistics whose row count and column NDVs are set by the user, so the
+// plans for a table of that size can be checked without loading the data. ndvs are the NDVs by the column IDs,
+// the other columns have the NDVs declared by the STATS_NDV table option or the pseudo NDV in proportion to the
+// row count.
+func OverriddenTable(ti *model.TableInfo, count int64, ndvs map[int64]int64) *Table {
+	t := PseudoTable(ti)
+	if count > 0 {
+		t.Count = count
+	}
+	for _, c := range t.Columns {
+		c.pseudoCount = t.Count
+		if ndv, ok := ndvs[c.ID]; ok && ndv > 0 {
+			c.NDV = ndv
+			c.fixedNDV = true
+		} else if !c.fixedNDV {
+			c.NDV = t.Count / 2
+		}
+	}
+	return t
+}
@@ -129,3 +129,65 @@ func (s *testStatisticsSuite) TestPseudoTable(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(count, Equals, int64(2500000))
 }
+
+func (s *testStatisticsSuite) TestOverriddenTable(c *C) {
+	ti := &model.TableInfo{}
+	for id := int64(1); id <= 2; id++ {
+		ti.Columns = append(ti.Columns, &model.ColumnInfo{
+			ID:        id,
+			FieldType: *types.NewFieldType(mysql.TypeLonglong),
+		})
+	}
+	tbl := OverriddenTable(ti, 120000, map[int64]int64{2: 40})
+	c.Assert(tbl.Count, Equals, int64(120000))
+	// The pseudo estimations scale with the row count.
+	col := tbl.Columns[0]
+	c.Assert(col.NDV, Equals, int64(60000))
+	count, err := col.EqualRowCount(types.NewIntDatum(1))
+	c.Assert(err, IsNil)
+	c.Assert(count, Equals, int64(120))
+	count, err = col.LessRowCount(types.NewIntDatum(100))
+	c.Assert(err, IsNil)
+	c.Assert(count, Equals, int64(40000))
+	count, err = col.BetweenRowCount(types.NewIntDatum(1), types.NewIntDatum(5))
+	c.Assert(err, IsNil)
+	c.Assert(count, Equals, int64(30000))
+	// The equal condition selects 1/NDV of the rows if the NDV is set.
+	col = tbl.Columns[1]
+	c.Assert(col.NDV, Equals, int64(40))
+	count, err = col.EqualRowCount(types.NewIntDatum(1))
+	c.Assert(err, IsNil)
+	c.Assert(count, Equals, int64(3000))
+	c.Assert(tbl.IndexCardinality([]int{1}, 1, false), Equals, int64(40))
+
+	// The row count isn't overridden.
+	tbl = OverriddenTable(ti, 0, map[int64]int64{1: 10})
+	c.Assert(tbl.Count, Equals, PseudoTable(ti).Count)
+
+	// The NDVs declared by the STATS_NDV table option are used unless they're overridden.
+	ti.Columns[1].StatsNDV = 20
+	col = PseudoTable(ti).Columns[1]
+	c.Assert(col.NDV, Equals, int64(20))
+	count, err = col.EqualRowCount(types.NewIntDatum(1))
+	c.Assert(err, IsNil)
+	c.Assert(count, Equals, int64(500000))
+	c.Assert(OverriddenTable(ti, 1000, nil).Columns[1].NDV, Equals, int64(20))
+	c.Assert(OverriddenTable(ti, 1000, map[int64]int64{2: 5}).Columns[1].NDV, Equals, int64(5))
+}
+
+func (s *testStatisticsSuite) TestIndexCardinality(c *C) {
+	tbl := &Table{
+		Count: 100,
+		Columns: []*Column{
+			{NDV: 10, Values: []types.Datum{types.NewIntDatum(1), types.NewIntDatum(2)}},
+			{NDV: 50, Values: []types.Datum{types.NewStringDatum("ab"), types.NewStringDatum("abcd")}},
+			{NDV: 200, Values: []types.Datum{{}, types.NewStringDatum("abcd")}},
+		},
+	}
+	c.Assert(tbl.Columns[1].AvgSize(), Equals, float64(3))
+	c.Assert(tbl.AvgRowSize(), Equals, float64(13))
+	c.Assert(tbl.IndexCardinality([]int{0, 1}, 1, false), Equals, int64(10))
+	c.Assert(tbl.IndexCardinality([]int{0, 1}, 2, false), Equals, int64(50))
+	c.Assert(tbl.IndexCardinality([]int{0, 1}, 2, true), Equals, int64(100))
+	c.Assert(tbl.IndexCardinality([]int{2}, 1, false), Equals, int64(100))
+}
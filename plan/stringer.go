@@ -26,7 +26,7 @@ func ToString(p Plan) string {
 
 func toString(in Plan, strs []string, idxs []int) ([]string, []int) {
 	switch in.(type) {
-	case *Join, *Union, *PhysicalHashJoin, *PhysicalHashSemiJoin:
+	case *Join, *Union, *PhysicalHashJoin, *PhysicalMergeJoin, *PhysicalIndexJoin, *PhysicalHashSemiJoin:
 		idxs = append(idxs, len(strs))
 	}
 
@@ -42,6 +42,12 @@ func toString(in Plan, strs []string, idxs []int) ([]string, []int) {
 		str = fmt.Sprintf("Index(%s.%s)%v", x.Table.Name.L, x.Index.Name.L, x.Ranges)
 	case *PhysicalTableScan:
 		str = fmt.Sprintf("Table(%s)", x.Table.Name.L)
+	case *PhysicalIndexMerge:
+		partials := make([]string, 0, len(x.PartialPlans))
+		for _, partial := range x.PartialPlans {
+			partials = append(partials, ToString(partial))
+		}
+		str = fmt.Sprintf("IndexMerge(%s){%s}", x.Table.Name.L, strings.Join(partials, ","))
 	case *PhysicalDummyScan:
 		str = "Dummy"
 	case *PhysicalHashJoin:
@@ -60,6 +66,35 @@ func toString(in Plan, strs []string, idxs []int) ([]string, []int) {
 			r := eq.Args[1].String()
 			str += fmt.Sprintf("(%s,%s)", l, r)
 		}
+	case *PhysicalMergeJoin:
+		last := len(idxs) - 1
+		idx := idxs[last]
+		children := strs[idx:]
+		strs = strs[:idx]
+		idxs = idxs[:last]
+		str = "MergeJoin{" + strings.Join(children, "->") + "}"
+		for _, eq := range x.EqualConditions {
+			l := eq.Args[0].String()
+			r := eq.Args[1].String()
+			str += fmt.Sprintf("(%s,%s)", l, r)
+		}
+	case *PhysicalIndexJoin:
+		last := len(idxs) - 1
+		idx := idxs[last]
+		children := strs[idx:]
+		strs = strs[:idx]
+		idxs = idxs[:last]
+		// The left index join looks up the right child for the rows of the left child.
+		if x.OuterIndex == 0 {
+			str = "LeftIndexJoin{" + strings.Join(children, "->") + "}"
+		} else {
+			str = "RightIndexJoin{" + strings.Join(children, "->") + "}"
+		}
+		for _, eq := range x.EqualConditions {
+			l := eq.Args[0].String()
+			r := eq.Args[1].String()
+			str += fmt.Sprintf("(%s,%s)", l, r)
+		}
 	case *PhysicalHashSemiJoin:
 		last := len(idxs) - 1
 		idx := idxs[last]
@@ -79,12 +114,54 @@ func toString(in Plan, strs []string, idxs []int) ([]string, []int) {
 		str = "Exists"
 	case *MaxOneRow:
 		str = "MaxOneRow"
+	case *GenerateSeries:
+		str = fmt.Sprintf("Series(%d,%d,%d)", x.Start, x.Stop, x.Step)
+	case *JSONTable:
+		str = fmt.Sprintf("JSONTable(%s)", x.RowPath)
+	case *Profile:
+		str = fmt.Sprintf("Profile(%s)", x.Kind)
+	case *RowHistory:
+		str = fmt.Sprintf("RowHistory(%s,%d)", x.Table.Name, x.Handle)
+	case *CTE:
+		str = fmt.Sprintf("CTE(%s)", x.Source.Name)
 	case *Limit:
 		str = "Limit"
 	case *SelectLock:
 		str = "Lock"
 	case *ShowDDL:
 		str = "ShowDDL"
+	case *ShowDDLJobs:
+		str = "ShowDDLJobs"
+	case *SelectInto:
+		str = "SelectInto"
+	case *ShowDeleteRanges:
+		str = "ShowDeleteRanges"
+	case *ShowSlowPlans:
+		str = "ShowSlowPlans"
+	case *Diagnose:
+		str = "Diagnose"
+	case *ThrottleTable:
+		str = "ThrottleTable"
+	case *SetStats:
+		str = "SetStats"
+	case *ResetStats:
+		str = "ResetStats"
+	case *RecoverIndex:
+		str = fmt.Sprintf("RecoverIndex(%s.%s)", x.Table.Name.L, x.Index.Name.L)
+	case *CleanupIndex:
+		str = fmt.Sprintf("CleanupIndex(%s.%s)", x.Table.Name.L, x.Index.Name.L)
+	case *PointGet:
+		if x.Index != nil {
+			str = fmt.Sprintf("PointGet(%s.%s)", x.Table.Name.L, x.Index.Name.L)
+		} else {
+			str = fmt.Sprintf("PointGet(%s, %d)", x.Table.Name.L, x.Handle)
+		}
+	case *BatchPointGet:
+		if x.Index != nil {
+			str = fmt.Sprintf("BatchPointGet(%s.%s)", x.Table.Name.L, x.Index.Name.L)
+		} else {
+			str = fmt.Sprintf("BatchPointGet(%s, %v)", x.Table.Name.L, x.Handles)
+		}
 	case *Sort:
 		str = "Sort"
 		if x.ExecLimit != nil {
@@ -127,7 +204,11 @@ func toString(in Plan, strs []string, idxs []int) ([]string, []int) {
 		}
 		str += ")"
 	case *Distinct:
-		str = "Distinct"
+		if x.Sorted {
+			str = "StreamDistinct"
+		} else {
+			str = "Distinct"
+		}
 	case *Trim:
 		str = "Trim"
 	case *Cache:
@@ -0,0 +1,487 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"strings"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/expression"
+	"github.com/pingcap/tidb/infoschema"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/privilege"
+	"github.com/pingcap/tidb/table"
+	"github.com/pingcap/tidb/util/jsonpath"
+	"github.com/pingcap/tidb/util/types"
+)
+
+// The names of the table functions.
+const (
+	// generateSeriesFunc generates a series of integers.
+	generateSeriesFunc = "generate_series"
+	// jsonTableFunc maps a JSON document to rows.
+	jsonTableFunc = "json_table"
+	// profileFunc captures a profile of the server.
+	profileFunc = "tidb_profile"
+	// rowHistoryFunc reads the history versions of a row.
+	rowHistoryFunc = "tidb_row_history"
+)
+
+// tableFunction describes a function which returns a set of rows and is used as a table in the FROM clause.
+// A new table function is added by registering it in tableFunctions, and implementing the logical plan it builds
+// and the executor of the plan.
+type tableFunction struct {
+	minArgs int
+	maxArgs int
+	// withColumns means the result columns are defined by the COLUMNS clause of the call.
+	withColumns bool
+	// columns returns the result columns of a call for the name resolver.
+	columns func(nr *nameResolver, tf *ast.TableFunc) ([]*model.ColumnInfo, error)
+	// build builds the logical plan of a call, the schema of the plan is in the order of the result columns.
+	build func(b *planBuilder, tf *ast.TableFunc) LogicalPlan
+}
+
+var tableFunctions map[string]*tableFunction
+
+func init() {
+	// tableFunctions is initialized here because the builders refer to it indirectly.
+	tableFunctions = map[string]*tableFunction{
+		generateSeriesFunc: {
+			minArgs: 2,
+			maxArgs: 3,
+			columns: generateSeriesColumns,
+			build:   (*planBuilder).buildGenerateSeries,
+		},
+		jsonTableFunc: {
+			minArgs:     2,
+			maxArgs:     2,
+			withColumns: true,
+			columns:     jsonTableColumns,
+			build:       (*planBuilder).buildJSONTable,
+		},
+		profileFunc: {
+			minArgs: 1,
+			maxArgs: 2,
+			columns: profileColumns,
+			build:   (*planBuilder).buildProfile,
+		},
+		rowHistoryFunc: {
+			minArgs: 2,
+			maxArgs: 2,
+			columns: rowHistoryColumns,
+			build:   (*planBuilder).buildRowHistory,
+		},
+	}
+}
+
+// buildTableFunc builds the plan of a table function in the FROM clause, e.g. "generate_series(1, 10)".
+func (b *planBuilder) buildTableFunc(tf *ast.TableFunc) LogicalPlan {
+	return tableFunctions[tf.FnName.L].build(b, tf)
+}
+
+// buildTableFuncSchema builds the schema of a table function plan by the result fields of the call.
+func buildTableFuncSchema(p LogicalPlan, tf *ast.TableFunc) expression.Schema {
+	rfs := tf.GetResultFields()
+	schema := make(expression.Schema, 0, len(rfs))
+	for i, rf := range rfs {
+		schema = append(schema, &expression.Column{
+			FromID:   p.GetID(),
+			ColName:  rf.Column.Name,
+			TblName:  tf.FnName,
+			RetType:  &rf.Column.FieldType,
+			Position: i,
+		})
+	}
+	return schema
+}
+
+// rewriteTableFuncArg rewrites an argument of a table function, which can only refer to the outer columns.
+func (b *planBuilder) rewriteTableFuncArg(arg ast.ExprNode) (expression.Expression, error) {
+	expr, _, err := b.rewrite(arg, b.buildTableDual(), nil, true)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return expr, nil
+}
+
+// evalTableFuncConstArg evaluates a constant argument of a table function, which must not be NULL.
+func (b *planBuilder) evalTableFuncConstArg(tf *ast.TableFunc, arg ast.ExprNode) (types.Datum, error) {
+	expr, err := b.rewriteTableFuncArg(arg)
+	if err != nil {
+		return types.Datum{}, errors.Trace(err)
+	}
+	if expr.IsCorrelated() || len(extractColumns(expr)) > 0 {
+		return types.Datum{}, ErrWrongArguments.Gen("Incorrect arguments to %s, arguments must be constant", tf.FnName.O)
+	}
+	d, err := expr.Eval(nil, b.ctx)
+	if err != nil {
+		return types.Datum{}, errors.Trace(err)
+	}
+	if d.IsNull() {
+		return types.Datum{}, ErrWrongArguments.Gen("Incorrect arguments to %s, arguments must not be NULL", tf.FnName.O)
+	}
+	return d, nil
+}
+
+func generateSeriesColumns(_ *nameResolver, tf *ast.TableFunc) ([]*model.ColumnInfo, error) {
+	colInfo := &model.ColumnInfo{Name: tf.FnName}
+	colInfo.FieldType = *types.NewFieldType(mysql.TypeLonglong)
+	colInfo.Flag |= mysql.NotNullFlag
+	return []*model.ColumnInfo{colInfo}, nil
+}
+
+// buildGenerateSeries builds the plan of "generate_series(start, stop[, step])".
+// The arguments are evaluated here, so the planner knows the exact cardinality of the result.
+func (b *planBuilder) buildGenerateSeries(tf *ast.TableFunc) LogicalPlan {
+	args := make([]int64, 0, len(tf.Args))
+	for _, arg := range tf.Args {
+		d, err := b.evalTableFuncConstArg(tf, arg)
+		if err != nil {
+			b.err = errors.Trace(err)
+			return nil
+		}
+		v, err := d.ToInt64()
+		if err != nil {
+			b.err = errors.Trace(err)
+			return nil
+		}
+		args = append(args, v)
+	}
+	p := &GenerateSeries{
+		baseLogicalPlan: newBaseLogicalPlan(Series, b.allocator),
+		Start:           args[0],
+		Stop:            args[1],
+		Step:            1,
+	}
+	if len(args) > 2 {
+		p.Step = args[2]
+	}
+	if p.Step == 0 {
+		b.err = ErrWrongArguments.Gen("Incorrect arguments to %s, step cannot be zero", tf.FnName.O)
+		return nil
+	}
+	p.self = p
+	p.initID()
+	p.SetSchema(buildTableFuncSchema(p, tf))
+	return p
+}
+
+func jsonTableColumns(_ *nameResolver, tf *ast.TableFunc) ([]*model.ColumnInfo, error) {
+	cols := make([]*model.ColumnInfo, 0, len(tf.Columns))
+	names := make(map[string]bool, len(tf.Columns))
+	for _, c := range tf.Columns {
+		if names[c.Name.L] {
+			return nil, ErrDupFieldName.Gen("Duplicate column name '%s'", c.Name.O)
+		}
+		names[c.Name.L] = true
+		colInfo := &model.ColumnInfo{Name: c.Name}
+		if c.ForOrdinality {
+			colInfo.FieldType = *types.NewFieldType(mysql.TypeLonglong)
+			colInfo.Flag |= mysql.UnsignedFlag | mysql.NotNullFlag
+		} else {
+			colInfo.FieldType = *c.Tp
+		}
+		cols = append(cols, colInfo)
+	}
+	return cols, nil
+}
+
+// buildJSONTable builds the plan of "json_table(doc, path COLUMNS (...))". The document can refer to the
+// columns of the tables on its left in the FROM clause, then the plan is correlated and joined by a lateral apply.
+func (b *planBuilder) buildJSONTable(tf *ast.TableFunc) LogicalPlan {
+	doc, err := b.rewriteTableFuncArg(tf.Args[0])
+	if err != nil {
+		b.err = errors.Trace(err)
+		return nil
+	}
+	rowPath, err := b.evalJSONPath(tf, tf.Args[1])
+	if err != nil {
+		b.err = errors.Trace(err)
+		return nil
+	}
+	p := &JSONTable{
+		baseLogicalPlan: newBaseLogicalPlan(JSONTbl, b.allocator),
+		Doc:             doc,
+		RowPath:         rowPath,
+		Columns:         make([]*JSONTableColumn, 0, len(tf.Columns)),
+	}
+	p.self = p
+	p.initID()
+	p.correlated = doc.IsCorrelated()
+	for i, c := range tf.Columns {
+		col := &JSONTableColumn{Tp: &tf.GetResultFields()[i].Column.FieldType}
+		if !c.ForOrdinality {
+			col.Path, err = jsonpath.Parse(c.Path)
+			if err != nil {
+				b.err = ErrWrongArguments.Gen("Incorrect arguments to %s, %s", tf.FnName.O, err)
+				return nil
+			}
+			col.OnEmpty, err = buildJSONTableResponse(c, c.OnEmpty, col.Tp)
+			if err != nil {
+				b.err = errors.Trace(err)
+				return nil
+			}
+			col.OnError, err = buildJSONTableResponse(c, c.OnError, col.Tp)
+			if err != nil {
+				b.err = errors.Trace(err)
+				return nil
+			}
+		}
+		p.Columns = append(p.Columns, col)
+	}
+	p.SetSchema(buildTableFuncSchema(p, tf))
+	return p
+}
+
+// buildJSONTableResponse builds the response of a json_table column, the DEFAULT value must be convertible to the
+// column type.
+func buildJSONTableResponse(c *ast.TableFuncColumn, r ast.TableFuncColumnResponse, tp *types.FieldType) (JSONTableResponse, error) {
+	resp := JSONTableResponse{Tp: r.Tp}
+	if r.Tp != ast.TableFuncColumnResponseDefault {
+		return resp, nil
+	}
+	d := types.NewStringDatum(r.Default)
+	d, err := d.ConvertTo(tp)
+	if err != nil {
+		return resp, ErrInvalidDefault.Gen("Invalid default value for '%s'", c.Name.O)
+	}
+	resp.Default = d
+	return resp, nil
+}
+
+// evalJSONPath evaluates a constant argument of a table function as a JSON path.
+func (b *planBuilder) evalJSONPath(tf *ast.TableFunc, arg ast.ExprNode) (*jsonpath.Path, error) {
+	expr, err := b.rewriteTableFuncArg(arg)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if _, ok := expr.(*expression.Constant); !ok {
+		return nil, ErrWrongArguments.Gen("Incorrect arguments to %s, the path must be a string literal", tf.FnName.O)
+	}
+	d, err := expr.Eval(nil, b.ctx)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if d.IsNull() {
+		return nil, ErrWrongArguments.Gen("Incorrect arguments to %s, the path must not be NULL", tf.FnName.O)
+	}
+	str, err := d.ToString()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	path, err := jsonpath.Parse(str)
+	if err != nil {
+		return nil, ErrWrongArguments.Gen("Incorrect arguments to %s, %s", tf.FnName.O, err)
+	}
+	return path, nil
+}
+
+func profileColumns(_ *nameResolver, tf *ast.TableFunc) ([]*model.ColumnInfo, error) {
+	cols := []struct {
+		name string
+		tp   byte
+	}{
+		{"name", mysql.TypeVarchar},
+		{"file", mysql.TypeVarchar},
+		{"flat", mysql.TypeLonglong},
+		{"flat_percent", mysql.TypeDouble},
+		{"cum", mysql.TypeLonglong},
+		{"cum_percent", mysql.TypeDouble},
+	}
+	infos := make([]*model.ColumnInfo, 0, len(cols))
+	for _, col := range cols {
+		colInfo := &model.ColumnInfo{Name: model.NewCIStr(col.name)}
+		colInfo.FieldType = *types.NewFieldType(col.tp)
+		if col.tp == mysql.TypeVarchar {
+			colInfo.Charset, colInfo.Collate = mysql.DefaultCharset, mysql.DefaultCollationName
+		}
+		colInfo.Flag |= mysql.NotNullFlag
+		infos = append(infos, colInfo)
+	}
+	return infos, nil
+}
+
+// maxProfileDuration is the max duration of a CPU profile captured by tidb_profile. Only one CPU profile is captured
+// by the server at a time, and the profiling slows down the server.
+const maxProfileDuration = 10 * time.Second
+
+// buildProfile builds the plan of "tidb_profile(kind[, seconds])". The kind is 'cpu' or 'heap', the CPU profile
+// is captured in the seconds, 1 by default, the heap profile is the in-use memory at the last garbage collection.
+// The profiles reveal the internals of the server, so SUPER or PROCESS is required.
+func (b *planBuilder) buildProfile(tf *ast.TableFunc) LogicalPlan {
+	hasPriv, err := privilege.CheckGlobal(b.ctx, mysql.SuperPriv, mysql.ProcessPriv)
+	if err != nil {
+		b.err = errors.Trace(err)
+		return nil
+	}
+	if !hasPriv {
+		b.err = ErrSpecificAccessDenied.Gen("Access denied; you need (at least one of) the %s privilege(s) for this operation", "SUPER, PROCESS")
+		return nil
+	}
+	d, err := b.evalTableFuncConstArg(tf, tf.Args[0])
+	if err != nil {
+		b.err = errors.Trace(err)
+		return nil
+	}
+	kind, err := d.ToString()
+	if err != nil {
+		b.err = errors.Trace(err)
+		return nil
+	}
+	p := &Profile{
+		baseLogicalPlan: newBaseLogicalPlan(ProfileTbl, b.allocator),
+		Kind:            strings.ToLower(kind),
+		Duration:        time.Second,
+	}
+	switch p.Kind {
+	case ProfileCPU:
+		if len(tf.Args) > 1 {
+			d, err = b.evalTableFuncConstArg(tf, tf.Args[1])
+			if err != nil {
+				b.err = errors.Trace(err)
+				return nil
+			}
+			seconds, err := d.ToFloat64()
+			if err != nil {
+				b.err = errors.Trace(err)
+				return nil
+			}
+			p.Duration = time.Duration(seconds * float64(time.Second))
+			if p.Duration <= 0 || p.Duration > maxProfileDuration {
+				b.err = ErrWrongArguments.Gen("Incorrect arguments to %s, the seconds must be in (0, %v]",
+					tf.FnName.O, maxProfileDuration.Seconds())
+				return nil
+			}
+		}
+	case ProfileHeap:
+		if len(tf.Args) > 1 {
+			b.err = ErrWrongArguments.Gen("Incorrect arguments to %s, the heap profile has no duration", tf.FnName.O)
+			return nil
+		}
+		p.Duration = 0
+	default:
+		b.err = ErrWrongArguments.Gen("Incorrect arguments to %s, unknown profile kind '%s'", tf.FnName.O, kind)
+		return nil
+	}
+	p.self = p
+	p.initID()
+	p.SetSchema(buildTableFuncSchema(p, tf))
+	return p
+}
+
+// The result columns of tidb_row_history before the columns of the table.
+const (
+	rowHistoryCommitTS   = "_tidb_commit_ts"
+	rowHistoryCommitTime = "_tidb_commit_time"
+	rowHistoryDeleted    = "_tidb_deleted"
+)
+
+// rowHistoryTable gets the table of "tidb_row_history(table, handle)". The table name is a string literal in the
+// form of "tbl" or "db.tbl", so the name resolver knows the columns of the table.
+func rowHistoryTable(is infoschema.InfoSchema, defaultSchema model.CIStr, tf *ast.TableFunc) (*model.DBInfo, table.Table, error) {
+	v, ok := tf.Args[0].(*ast.ValueExpr)
+	if !ok || v.GetDatum().Kind() != types.KindString {
+		return nil, nil, ErrWrongArguments.Gen("Incorrect arguments to %s, the table name must be a string literal",
+			tf.FnName.O)
+	}
+	schema, name := defaultSchema, v.GetDatum().GetString()
+	if idx := strings.Index(name, "."); idx >= 0 {
+		schema, name = model.NewTableCIStr(name[:idx]), name[idx+1:]
+	}
+	tbl, err := is.TableByName(schema, model.NewTableCIStr(name))
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	if tbl.Meta().IsView() {
+		return nil, nil, infoschema.ErrWrongObject.Gen("'%s.%s' is not BASE TABLE", schema, name)
+	}
+	dbInfo, _ := is.SchemaByName(schema)
+	return dbInfo, tbl, nil
+}
+
+func rowHistoryColumns(nr *nameResolver, tf *ast.TableFunc) ([]*model.ColumnInfo, error) {
+	dbInfo, tbl, err := rowHistoryTable(nr.Info, nr.DefaultSchema, tf)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if checker := privilege.GetPrivilegeChecker(nr.Ctx); checker != nil {
+		hasPriv, err := checker.Check(nr.Ctx, dbInfo, tbl.Meta(), mysql.SelectPriv)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if !hasPriv {
+			return nil, errors.Errorf("You do not have the privilege to select from table %s.%s.", dbInfo.Name, tbl.Meta().Name)
+		}
+	}
+	commitTS := &model.ColumnInfo{Name: model.NewCIStr(rowHistoryCommitTS)}
+	commitTS.FieldType = *types.NewFieldType(mysql.TypeLonglong)
+	commitTS.Flag |= mysql.UnsignedFlag | mysql.NotNullFlag
+	commitTime := &model.ColumnInfo{Name: model.NewCIStr(rowHistoryCommitTime)}
+	commitTime.FieldType = *types.NewFieldType(mysql.TypeDatetime)
+	commitTime.Decimal = 3
+	commitTime.Flag |= mysql.NotNullFlag
+	deleted := &model.ColumnInfo{Name: model.NewCIStr(rowHistoryDeleted)}
+	deleted.FieldType = *types.NewFieldType(mysql.TypeTiny)
+	deleted.Flag |= mysql.NotNullFlag
+	cols := []*model.ColumnInfo{commitTS, commitTime, deleted}
+	for _, col := range tbl.Cols() {
+		colInfo := *col.ToInfo()
+		// The columns of the deleted versions are NULL.
+		colInfo.Flag &^= mysql.NotNullFlag
+		cols = append(cols, &colInfo)
+	}
+	return cols, nil
+}
+
+// buildRowHistory builds the plan of "tidb_row_history(table, handle)", the handle is the integer primary key or
+// the hidden row ID of the row.
+func (b *planBuilder) buildRowHistory(tf *ast.TableFunc) LogicalPlan {
+	if client := b.ctx.GetClient(); client == nil || !client.SupportRequestType(kv.ReqTypeHistory, 0) {
+		b.err = ErrUnsupportedType.Gen("%s is not supported by the storage", tf.FnName.O)
+		return nil
+	}
+	dbInfo, tbl, err := rowHistoryTable(b.is, model.NewTableCIStr(b.ctx.GetSessionVars().CurrentDB), tf)
+	if err != nil {
+		b.err = errors.Trace(err)
+		return nil
+	}
+	d, err := b.evalTableFuncConstArg(tf, tf.Args[1])
+	if err != nil {
+		b.err = errors.Trace(err)
+		return nil
+	}
+	handle, err := d.ToInt64()
+	if err != nil {
+		b.err = errors.Trace(err)
+		return nil
+	}
+	p := &RowHistory{
+		baseLogicalPlan: newBaseLogicalPlan(RowHistoryTbl, b.allocator),
+		DBID:            dbInfo.ID,
+		Table:           tbl.Meta(),
+		Columns:         make([]*model.ColumnInfo, 0, len(tbl.Cols())),
+		Handle:          handle,
+	}
+	for _, col := range tbl.Cols() {
+		p.Columns = append(p.Columns, col.ToInfo())
+	}
+	p.self = p
+	p.initID()
+	p.SetSchema(buildTableFuncSchema(p, tf))
+	return p
+}
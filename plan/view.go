@@ -0,0 +1,193 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/infoschema"
+	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/privilege"
+)
+
+// viewRefCollector collects the tables and the columns read by the select statement of a view. The nested views
+// are skipped, they're checked against the privileges of the definer of the view when they're built.
+type viewRefCollector struct {
+	tables  []*ast.TableName
+	columns []*ast.ResultField
+}
+
+func (c *viewRefCollector) Enter(in ast.Node) (ast.Node, bool) {
+	switch x := in.(type) {
+	case *ast.TableSource:
+		return in, x.IsView
+	case *ast.TableName:
+		if x.TableInfo != nil && x.DBInfo != nil && !infoschema.IsMemoryDB(x.DBInfo.Name.L) {
+			c.tables = append(c.tables, x)
+		}
+	case *ast.ColumnNameExpr:
+		c.columns = append(c.columns, x.Refer)
+	case *ast.SelectStmt:
+		// The columns selected by the wildcards are only in the result fields.
+		for _, rf := range x.GetResultFields() {
+			if cn, ok := rf.Expr.(*ast.ColumnNameExpr); ok {
+				c.columns = append(c.columns, cn.Refer)
+			}
+		}
+	}
+	return in, false
+}
+
+func (c *viewRefCollector) Leave(in ast.Node) (ast.Node, bool) {
+	return in, true
+}
+
+// collectViewRefs returns the tables read by the resolved select statement of a view, with the columns it reads.
+func collectViewRefs(sel ast.ResultSetNode) []*ast.ViewRef {
+	c := &viewRefCollector{}
+	sel.Accept(c)
+	refs := make([]*ast.ViewRef, 0, len(c.tables))
+	refMap := make(map[*ast.TableName]*ast.ViewRef, len(c.tables))
+	for _, tn := range c.tables {
+		ref := &ast.ViewRef{Table: tn}
+		refs = append(refs, ref)
+		refMap[tn] = ref
+	}
+	for _, rf := range c.columns {
+		if rf == nil || rf.Column == nil {
+			continue
+		}
+		ref, ok := refMap[rf.TableName]
+		if !ok {
+			continue
+		}
+		dup := false
+		for _, col := range ref.Columns {
+			dup = dup || col.ID == rf.Column.ID
+		}
+		if !dup {
+			ref.Columns = append(ref.Columns, rf.Column)
+		}
+	}
+	return refs
+}
+
+// viewScope is a view being built, checker is the Checker of the user reading its tables.
+type viewScope struct {
+	name    *ast.TableName
+	checker privilege.Checker
+}
+
+// viewChecker returns the Checker of the user reading the view being built, which is the user reading the tables of
+// the view enclosing it, or the current user. It returns nil if no Checker is bound.
+func (b *planBuilder) viewChecker() privilege.Checker {
+	if n := len(b.viewScopes); n > 0 {
+		return b.viewScopes[n-1].checker
+	}
+	return privilege.GetPrivilegeChecker(b.ctx)
+}
+
+func viewInvalidError(tn *ast.TableName) error {
+	return ErrViewInvalid.Gen("View '%s.%s' references invalid table(s) or column(s) or function(s) or definer/invoker of view lack rights to use them",
+		tn.DBInfo.Name, tn.TableInfo.Name)
+}
+
+// checkView checks the privilege to read the view and the privileges of the definer of the view, or of the user
+// reading a view with SQL SECURITY INVOKER, to read the tables and columns, every time the statement is planned.
+// It returns the Checker of the user reading the tables, which reads the views nested in the view.
+func (b *planBuilder) checkView(ts *ast.TableSource) privilege.Checker {
+	checker := b.viewChecker()
+	if checker == nil {
+		return nil
+	}
+	tn := ts.ViewName
+	hasPriv, err := checker.Check(b.ctx, tn.DBInfo, tn.TableInfo, mysql.SelectPriv)
+	if err != nil {
+		b.err = errors.Trace(err)
+		return nil
+	}
+	if !hasPriv {
+		if n := len(b.viewScopes); n > 0 {
+			// The definer of the enclosing view can't read the view.
+			b.err = viewInvalidError(b.viewScopes[n-1].name)
+			return nil
+		}
+		b.err = errors.Errorf("You do not have the privilege to select from view %s.%s.", tn.DBInfo.Name, tn.TableInfo.Name)
+		return nil
+	}
+	if b.inExplain {
+		if err = b.checkExplainView(ts); err != nil {
+			b.err = errors.Trace(err)
+			return nil
+		}
+	}
+	// The view with SQL SECURITY INVOKER reads the tables as the user reading it.
+	reader := checker
+	if tn.TableInfo.View.Security == model.SecurityDefiner {
+		reader = privilege.GetPrivilegeChecker(b.ctx).ForUser(tn.TableInfo.View.Definer)
+	}
+	for _, ref := range ts.ViewRefs {
+		hasPriv, err = b.checkViewRef(reader, ref)
+		if err != nil {
+			b.err = errors.Trace(err)
+			return nil
+		}
+		if !hasPriv {
+			b.err = viewInvalidError(tn)
+			return nil
+		}
+	}
+	return reader
+}
+
+// checkExplainView checks that the current user can show the view and read the tables of it, since the explained
+// plan of a view reveals them.
+func (b *planBuilder) checkExplainView(ts *ast.TableSource) error {
+	checker := privilege.GetPrivilegeChecker(b.ctx)
+	tn := ts.ViewName
+	hasPriv, err := checker.Check(b.ctx, tn.DBInfo, tn.TableInfo, mysql.ShowViewPriv)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for _, ref := range ts.ViewRefs {
+		if !hasPriv {
+			break
+		}
+		hasPriv, err = b.checkViewRef(checker, ref)
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
+	if !hasPriv {
+		return ErrViewNoExplain.Gen("EXPLAIN/SHOW can not be issued; lacking privileges for underlying table")
+	}
+	return nil
+}
+
+// checkViewRef checks whether the table read by a view can be read, or all the columns of it read by the view.
+func (b *planBuilder) checkViewRef(checker privilege.Checker, ref *ast.ViewRef) (bool, error) {
+	tn := ref.Table
+	hasPriv, err := checker.Check(b.ctx, tn.DBInfo, tn.TableInfo, mysql.SelectPriv)
+	if err != nil || hasPriv || len(ref.Columns) == 0 {
+		return hasPriv, errors.Trace(err)
+	}
+	for _, col := range ref.Columns {
+		hasPriv, err = checker.CheckColumn(b.ctx, tn.DBInfo, tn.TableInfo, col, mysql.SelectPriv)
+		if err != nil || !hasPriv {
+			return hasPriv, errors.Trace(err)
+		}
+	}
+	return true, nil
+}
@@ -28,9 +28,14 @@ func (k keyType) String() string {
 // Checker is the interface for check privileges.
 type Checker interface {
 	// Check checks privilege.
+	// If db is nil, only check global scope privileges.
 	// If tbl is nil, only check global/db scope privileges.
 	// If tbl is not nil, check global/db/table scope privileges.
 	Check(ctx context.Context, db *model.DBInfo, tbl *model.TableInfo, privilege mysql.PrivilegeType) (bool, error)
+	// CheckColumn checks the privilege of the column, it's granted if the global/db/table scope privilege is.
+	CheckColumn(ctx context.Context, db *model.DBInfo, tbl *model.TableInfo, col *model.ColumnInfo, privilege mysql.PrivilegeType) (bool, error)
+	// ForUser returns the Checker of another user, e.g. the definer of a view. An empty user has all the privileges.
+	ForUser(user string) Checker
 	// Show granted privileges for user.
 	ShowGrants(ctx context.Context, user string) ([]string, error)
 }
@@ -49,3 +54,19 @@ func GetPrivilegeChecker(ctx context.Context) Checker {
 	}
 	return nil
 }
+
+// CheckGlobal checks whether the current user has any of the global privileges, they're granted if no Checker is
+// bound.
+func CheckGlobal(ctx context.Context, privs ...mysql.PrivilegeType) (bool, error) {
+	checker := GetPrivilegeChecker(ctx)
+	if checker == nil {
+		return true, nil
+	}
+	for _, priv := range privs {
+		ok, err := checker.Check(ctx, nil, nil, priv)
+		if err != nil || ok {
+			return ok, err
+		}
+	}
+	return false, nil
+}
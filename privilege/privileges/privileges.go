@@ -133,6 +133,8 @@ type userPrivileges struct {
 	DBPrivs map[string]*privileges
 	// DBName-TableName-privileges
 	TablePrivs map[string]map[string]*privileges
+	// DBName-TableName-ColumnName-privileges
+	ColumnPrivs map[string]map[string]map[string]*privileges
 }
 
 func (ps *userPrivileges) ShowGrants() []string {
@@ -169,6 +171,43 @@ func (ps *userPrivileges) ShowGrants() []string {
 type UserPrivileges struct {
 	User  string
 	privs *userPrivileges
+	// others caches the checkers of the other users for the session, like the privileges of the user.
+	others map[string]*UserPrivileges
+}
+
+// ForUser implements Checker.ForUser interface.
+func (p *UserPrivileges) ForUser(user string) privilege.Checker {
+	if c, ok := p.others[user]; ok {
+		return c
+	}
+	c := &UserPrivileges{User: user}
+	if len(user) == 0 {
+		// The user is created in embedded db mode, where the user doesn't need to login.
+		all := &privileges{Level: ast.GrantLevelGlobal}
+		for _, priv := range mysql.AllGlobalPrivs {
+			all.add(priv)
+		}
+		c.privs = &userPrivileges{GlobalPrivs: all}
+	}
+	if p.others == nil {
+		p.others = make(map[string]*UserPrivileges)
+	}
+	p.others[user] = c
+	return c
+}
+
+// CheckColumn implements Checker.CheckColumn interface.
+func (p *UserPrivileges) CheckColumn(ctx context.Context, db *model.DBInfo, tbl *model.TableInfo, col *model.ColumnInfo, privilege mysql.PrivilegeType) (bool, error) {
+	ok, err := p.Check(ctx, db, tbl, privilege)
+	if err != nil || ok || p.privs == nil {
+		// The privileges aren't loaded if the user doesn't need to login.
+		return ok, errors.Trace(err)
+	}
+	colp, ok := p.privs.ColumnPrivs[db.Name.O][tbl.Name.O][col.Name.L]
+	if !ok {
+		return false, nil
+	}
+	return colp.contain(privilege), nil
 }
 
 // Check implements Checker.Check interface.
@@ -194,6 +233,9 @@ func (p *UserPrivileges) Check(ctx context.Context, db *model.DBInfo, tbl *model
 	if ok {
 		return true, nil
 	}
+	if db == nil {
+		return false, nil
+	}
 	// Check db scope privileges.
 	dbp, ok := p.privs.DBPrivs[db.Name.O]
 	if ok {
@@ -240,8 +282,8 @@ func (p *UserPrivileges) loadPrivileges(ctx context.Context) error {
 	if err != nil {
 		return errors.Trace(err)
 	}
-	// TODO: consider column scope privilege latter.
-	return nil
+	err = p.loadColumnScopePrivileges(ctx)
+	return errors.Trace(err)
 }
 
 // mysql.User/mysql.DB table privilege columns start from index 3.
@@ -362,8 +404,11 @@ func (p *UserPrivileges) loadTableScopePrivileges(ctx context.Context) error {
 			ps[dbStr] = make(map[string]*privileges)
 		}
 		ps[dbStr][tblStr] = &privileges{Level: ast.GrantLevelTable}
-		// Table_priv
+		// Table_priv, the privileges of a table are empty if only the privileges of its columns are granted.
 		tblPrivs := row.Data[6].GetMysqlSet()
+		if tblPrivs.Name == "" {
+			continue
+		}
 		pvs := strings.Split(tblPrivs.Name, ",")
 		for _, d := range pvs {
 			p, ok := mysql.SetStr2Priv[d]
@@ -377,6 +422,51 @@ func (p *UserPrivileges) loadTableScopePrivileges(ctx context.Context) error {
 	return nil
 }
 
+func (p *UserPrivileges) loadColumnScopePrivileges(ctx context.Context) error {
+	sql := fmt.Sprintf(`SELECT * FROM %s.%s WHERE User="%s" AND (Host="%s" OR Host="%%");`,
+		mysql.SystemDB, mysql.ColumnPrivTable, p.privs.User, p.privs.Host)
+	rs, err := ctx.(sqlexec.RestrictedSQLExecutor).ExecRestrictedSQL(ctx, sql)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer rs.Close()
+	ps := make(map[string]map[string]map[string]*privileges)
+	for {
+		row, err := rs.Next()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if row == nil {
+			break
+		}
+		// DB, Table_name and Column_name
+		dbStr, tblStr := row.Data[1].GetString(), row.Data[3].GetString()
+		colStr := strings.ToLower(row.Data[4].GetString())
+		if _, ok := ps[dbStr]; !ok {
+			ps[dbStr] = make(map[string]map[string]*privileges)
+		}
+		if _, ok := ps[dbStr][tblStr]; !ok {
+			ps[dbStr][tblStr] = make(map[string]*privileges)
+		}
+		colp := &privileges{Level: ast.GrantLevelTable}
+		ps[dbStr][tblStr][colStr] = colp
+		// Column_priv, the privileges of a column are empty after they're all revoked.
+		colPrivs := row.Data[6].GetMysqlSet()
+		if colPrivs.Name == "" {
+			continue
+		}
+		for _, d := range strings.Split(colPrivs.Name, ",") {
+			p, ok := mysql.SetStr2Priv[d]
+			if !ok {
+				return errInvalidPrivilegeType.Gen("Unknown Privilege Type!")
+			}
+			colp.add(p)
+		}
+	}
+	p.privs.ColumnPrivs = ps
+	return nil
+}
+
 // ShowGrants implements privilege.Checker ShowGrants interface.
 func (p *UserPrivileges) ShowGrants(ctx context.Context, user string) ([]string, error) {
 	// If user is current user
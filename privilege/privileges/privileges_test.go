@@ -122,6 +122,30 @@ func (s *testPrivilegeSuite) TestCheckDBPrivilege(c *C) {
 	c.Assert(r, IsTrue)
 }
 
+func (s *testPrivilegeSuite) TestCheckGlobalPrivilege(c *C) {
+	defer testleak.AfterTest(c)()
+	se := newSession(c, s.store, s.dbName)
+	mustExec(c, se, `CREATE USER 'super'@'localhost' identified by '123';`)
+	ctx, _ := se.(context.Context)
+	ctx.GetSessionVars().User = "super@localhost"
+	pc := &privileges.UserPrivileges{}
+	r, err := pc.Check(ctx, nil, nil, mysql.SuperPriv)
+	c.Assert(err, IsNil)
+	c.Assert(r, IsFalse)
+
+	mustExec(c, se, `GRANT PROCESS, SUPER ON *.* TO  'super'@'localhost';`)
+	pc = &privileges.UserPrivileges{}
+	r, err = pc.Check(ctx, nil, nil, mysql.SuperPriv)
+	c.Assert(err, IsNil)
+	c.Assert(r, IsTrue)
+	r, err = pc.Check(ctx, nil, nil, mysql.FilePriv)
+	c.Assert(err, IsNil)
+	c.Assert(r, IsFalse)
+	gs, err := pc.ShowGrants(ctx, `super@localhost`)
+	c.Assert(err, IsNil)
+	c.Assert(gs, DeepEquals, []string{`GRANT Process,Super ON *.* TO 'super'@'localhost'`})
+}
+
 func (s *testPrivilegeSuite) TestCheckTablePrivilege(c *C) {
 	defer testleak.AfterTest(c)()
 	se := newSession(c, s.store, s.dbName)
@@ -266,6 +290,46 @@ func (s *testPrivilegeSuite) TestDropTablePriv(c *C) {
 	mustExec(c, se1, `DROP TABLE todrop;`)
 }
 
+func (s *testPrivilegeSuite) TestCheckColumnPrivilege(c *C) {
+	defer testleak.AfterTest(c)()
+	se := newSession(c, s.store, s.dbName)
+	mustExec(c, se, `CREATE USER 'col'@'localhost' identified by '123';`)
+	mustExec(c, se, `GRANT SELECT (name) ON test.test TO 'col'@'localhost';`)
+	db := &model.DBInfo{Name: model.NewCIStr("test")}
+	tbl := &model.TableInfo{Name: model.NewCIStr("test")}
+	id := &model.ColumnInfo{Name: model.NewCIStr("id")}
+	name := &model.ColumnInfo{Name: model.NewCIStr("Name")}
+	ctx, _ := se.(context.Context)
+
+	// The privileges of another user are checked regardless of the current user.
+	pc := (&privileges.UserPrivileges{}).ForUser("col@localhost")
+	r, err := pc.Check(ctx, db, tbl, mysql.SelectPriv)
+	c.Assert(err, IsNil)
+	c.Assert(r, IsFalse)
+	r, err = pc.CheckColumn(ctx, db, tbl, name, mysql.SelectPriv)
+	c.Assert(err, IsNil)
+	c.Assert(r, IsTrue)
+	r, err = pc.CheckColumn(ctx, db, tbl, id, mysql.SelectPriv)
+	c.Assert(err, IsNil)
+	c.Assert(r, IsFalse)
+	r, err = pc.CheckColumn(ctx, db, tbl, name, mysql.UpdatePriv)
+	c.Assert(err, IsNil)
+	c.Assert(r, IsFalse)
+
+	mustExec(c, se, `GRANT SELECT ON test.test TO 'col'@'localhost';`)
+	pc = (&privileges.UserPrivileges{}).ForUser("col@localhost")
+	r, err = pc.CheckColumn(ctx, db, tbl, id, mysql.SelectPriv)
+	c.Assert(err, IsNil)
+	c.Assert(r, IsTrue)
+
+	// The user created in embedded db mode has all the privileges.
+	ctx.GetSessionVars().User = "col@localhost"
+	pc = (&privileges.UserPrivileges{}).ForUser("")
+	r, err = pc.Check(ctx, nil, nil, mysql.SuperPriv)
+	c.Assert(err, IsNil)
+	c.Assert(r, IsTrue)
+}
+
 func mustExec(c *C, se tidb.Session, sql string) {
 	_, err := se.Execute(sql)
 	c.Assert(err, IsNil)
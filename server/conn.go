@@ -42,6 +42,7 @@ import (
 	"net"
 	"runtime"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/juju/errors"
@@ -75,6 +76,8 @@ type clientConn struct {
 	lastCmd      string            // latest sql query string, currently used for logging error.
 	ctx          IContext          // an interface to execute sql statements.
 	attrs        map[string]string // attributes parsed from client handshake response, not used for now.
+	// mu is held while a command is handled, the idle cursors are closed with it, see openCursor.
+	mu sync.Mutex
 }
 
 func (cc *clientConn) String() string {
@@ -120,6 +123,8 @@ func (cc *clientConn) Close() error {
 	connGauge.Set(float64(connections))
 	cc.conn.Close()
 	if cc.ctx != nil {
+		cc.mu.Lock()
+		defer cc.mu.Unlock()
 		return cc.ctx.Close()
 	}
 	return nil
@@ -291,6 +296,7 @@ func (cc *clientConn) readHandshakeResponse() error {
 		cc.Close()
 		return errors.Trace(err)
 	}
+	cc.ctx.SetSessionManager(cc.server)
 	if !cc.server.skipAuth() {
 		// Do Auth
 		addr := cc.conn.RemoteAddr().String()
@@ -331,7 +337,10 @@ func (cc *clientConn) Run() {
 			return
 		}
 
-		if err := cc.dispatch(data); err != nil {
+		cc.mu.Lock()
+		err = cc.dispatch(data)
+		cc.mu.Unlock()
+		if err != nil {
 			if terror.ErrorEqual(err, io.EOF) {
 				return
 			}
@@ -397,8 +406,12 @@ func (cc *clientConn) dispatch(data []byte) error {
 		return cc.handleStmtSendLongData(data)
 	case mysql.ComStmtReset:
 		return cc.handleStmtReset(data)
+	case mysql.ComStmtFetch:
+		return cc.handleStmtFetch(data)
 	case mysql.ComSetOption:
 		return cc.handleSetOption(data)
+	case mysql.ComProcessKill:
+		return cc.handleProcessKill(data)
 	default:
 		return mysql.NewErrf(mysql.ErrUnknown, "command %d not supported now", cmd)
 	}
@@ -415,6 +428,18 @@ func (cc *clientConn) useDB(db string) (err error) {
 	return
 }
 
+// handleProcessKill kills the connection whose ID is in the payload by KILL CONNECTION, which checks the privilege.
+func (cc *clientConn) handleProcessKill(data []byte) error {
+	if len(data) < 4 {
+		return mysql.ErrMalformPacket
+	}
+	connectionID := binary.LittleEndian.Uint32(data)
+	if _, err := cc.ctx.Execute(fmt.Sprintf("KILL CONNECTION %d", connectionID)); err != nil {
+		return errors.Trace(err)
+	}
+	return cc.writeOK()
+}
+
 func (cc *clientConn) flush() error {
 	return cc.pkt.flush()
 }
@@ -489,6 +514,18 @@ func (cc *clientConn) writeEOF(more bool) error {
 	return errors.Trace(err)
 }
 
+// writeEOFWithStatus writes an EOF packet with the status, e.g. the cursor status of COM_STMT_EXECUTE and
+// COM_STMT_FETCH. It won't flush the stream either.
+func (cc *clientConn) writeEOFWithStatus(status uint16) error {
+	data := cc.alloc.AllocWithLen(4, 9)
+	data = append(data, mysql.EOFHeader)
+	if cc.capability&mysql.ClientProtocol41 > 0 {
+		data = append(data, dumpUint16(cc.ctx.WarningCount())...)
+		data = append(data, dumpUint16(status)...)
+	}
+	return errors.Trace(cc.writePacket(data))
+}
+
 func (cc *clientConn) writeReq(filePath string) error {
 	data := cc.alloc.AllocWithLen(4, 5+len(filePath))
 	data = append(data, mysql.LocalInFileHeader)
@@ -565,6 +602,9 @@ func (cc *clientConn) handleLoadData(loadDataInfo *executor.LoadDataInfo) error
 
 const queryLogMaxLen = 2048
 
+// slowQueryThreshold is the execution time of a query to be logged as a slow query.
+var slowQueryThreshold = time.Second
+
 // handleQuery executes the sql query string and writes result set or result ok to the client.
 // As the execution time of this function represents the performance of TiDB, we do time log and metrics here.
 // There is a special query `load data` that does not return result, which is handled differently.
@@ -580,6 +620,7 @@ func (cc *clientConn) handleQuery(sql string) (err error) {
 		queryCounter.WithLabelValues(label).Inc()
 	}()
 
+	cc.ctx.SetValue(executor.LastPlanVarKey, nil)
 	rs, err := cc.ctx.Execute(sql)
 	if err != nil {
 		return errors.Trace(err)
@@ -600,10 +641,21 @@ func (cc *clientConn) handleQuery(sql string) (err error) {
 		err = cc.writeOK()
 	}
 	costTime := time.Since(startTS)
+	if costTime >= slowQueryThreshold {
+		executor.SlowQueryLog.Add(&executor.SlowQuery{
+			Start:      startTS,
+			Duration:   costTime,
+			ConnID:     uint64(cc.connectionID),
+			User:       cc.user,
+			DB:         cc.ctx.CurrentDB(),
+			SQL:        sql,
+			PlanDigest: executor.LastPlanDigest(cc.ctx.Value(executor.LastPlanVarKey)),
+		})
+	}
 	if len(sql) > queryLogMaxLen {
 		sql = sql[:queryLogMaxLen] + fmt.Sprintf("(len:%d)", len(sql))
 	}
-	if costTime < time.Second {
+	if costTime < slowQueryThreshold {
 		log.Debugf("[%d][TIME_QUERY] %v\n%s", cc.connectionID, costTime, sql)
 	} else {
 		log.Warnf("[%d][TIME_QUERY] %v\n%s", cc.connectionID, costTime, sql)
@@ -650,25 +702,14 @@ func (cc *clientConn) writeResultset(rs ResultSet, binary bool, more bool) error
 	if err != nil {
 		return errors.Trace(err)
 	}
-	columnLen := dumpLengthEncodedInt(uint64(len(columns)))
-	data := cc.alloc.AllocWithLen(4, 1024)
-	data = append(data, columnLen...)
-	if err = cc.writePacket(data); err != nil {
+	if err = cc.writeColumnInfo(columns); err != nil {
 		return errors.Trace(err)
 	}
-
-	for _, v := range columns {
-		data = data[0:4]
-		data = append(data, v.Dump(cc.alloc)...)
-		if err = cc.writePacket(data); err != nil {
-			return errors.Trace(err)
-		}
-	}
-
 	if err = cc.writeEOF(false); err != nil {
 		return errors.Trace(err)
 	}
 
+	data := cc.alloc.AllocWithLen(4, 1024)
 	for {
 		if err != nil {
 			return errors.Trace(err)
@@ -713,6 +754,24 @@ func (cc *clientConn) writeResultset(rs ResultSet, binary bool, more bool) error
 	return errors.Trace(cc.flush())
 }
 
+// writeColumnInfo writes the column count and the column definitions of a result set, the EOF packet after them
+// isn't written.
+func (cc *clientConn) writeColumnInfo(columns []*ColumnInfo) error {
+	data := cc.alloc.AllocWithLen(4, 1024)
+	data = append(data, dumpLengthEncodedInt(uint64(len(columns)))...)
+	if err := cc.writePacket(data); err != nil {
+		return errors.Trace(err)
+	}
+	for _, v := range columns {
+		data = data[0:4]
+		data = append(data, v.Dump(cc.alloc)...)
+		if err := cc.writePacket(data); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
 func (cc *clientConn) writeMultiResultset(rss []ResultSet, binary bool) error {
 	for _, rs := range rss {
 		if err := cc.writeResultset(rs, binary, true); err != nil {
@@ -38,10 +38,13 @@ import (
 	"encoding/binary"
 	"math"
 	"strconv"
+	"time"
 
 	"github.com/juju/errors"
+	"github.com/ngaut/log"
 	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/util/hack"
+	"github.com/pingcap/tidb/util/types"
 )
 
 func (cc *clientConn) handleStmtPrepare(sql string) error {
@@ -118,10 +121,12 @@ func (cc *clientConn) handleStmtExecute(data []byte) (err error) {
 
 	flag := data[pos]
 	pos++
-	//now we only support CURSOR_TYPE_NO_CURSOR flag
-	if flag != 0 {
+	// We only support the CURSOR_TYPE_NO_CURSOR and CURSOR_TYPE_READ_ONLY flags.
+	if flag != mysql.CursorTypeNoCursor && flag != mysql.CursorTypeReadOnly {
 		return mysql.NewErrf(mysql.ErrUnknown, "unsupported flag %d", flag)
 	}
+	// Executing the statement again closes its open cursor, the same as MySQL.
+	stmt.StoreResultSet(nil)
 
 	//skip iteration-count, always 1
 	pos += 4
@@ -150,8 +155,14 @@ func (cc *clientConn) handleStmtExecute(data []byte) (err error) {
 
 			paramTypes = data[pos : pos+(numParams<<1)]
 			pos += (numParams << 1)
-			paramValues = data[pos:]
+			stmt.SetParamsType(paramTypes)
+		} else {
+			pos++
+			// The client only sends the types in the first execution or when they are changed,
+			// the values are always sent and they are of the types of the last execution.
+			paramTypes = stmt.GetParamsType()
 		}
+		paramValues = data[pos:]
 
 		err = parseStmtArgs(args, stmt.BoundParams(), nullBitmaps, paramTypes, paramValues)
 		if err != nil {
@@ -165,10 +176,167 @@ func (cc *clientConn) handleStmtExecute(data []byte) (err error) {
 	if rs == nil {
 		return errors.Trace(cc.writeOK())
 	}
+	if flag == mysql.CursorTypeReadOnly {
+		return errors.Trace(cc.openCursor(stmt, rs))
+	}
 
 	return errors.Trace(cc.writeResultset(rs, true, false))
 }
 
+// cursorIdleTimeout is how long an open cursor is kept without being fetched, the idle cursor is closed to release
+// the memory of its executor.
+var cursorIdleTimeout = 10 * time.Minute
+
+// suspendable is a result set whose statement is suspended between the fetches of an open cursor, see
+// executor.SuspendableRecordSet.
+type suspendable interface {
+	Suspend()
+	Resume()
+}
+
+// openCursor writes the columns of rs and keeps it open in stmt instead of writing its rows, the executor of rs is
+// suspended until the rows are fetched by COM_STMT_FETCH, so the server needn't buffer a huge result set.
+func (cc *clientConn) openCursor(stmt IStatement, rs ResultSet) error {
+	// We need to call Next before we get columns, see writeResultset.
+	row, err := rs.Next()
+	if err != nil {
+		rs.Close()
+		return errors.Trace(err)
+	}
+	columns, err := rs.Columns()
+	if err != nil {
+		rs.Close()
+		return errors.Trace(err)
+	}
+	crs := &cursorResultSet{ResultSet: rs, columns: columns, row: row}
+	stmt.StoreResultSet(crs)
+	cc.suspendCursor(stmt, crs)
+	if err = cc.writeColumnInfo(columns); err != nil {
+		return errors.Trace(err)
+	}
+	if err = cc.writeEOFWithStatus(cc.ctx.Status() | mysql.ServerStatusCursorExists); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(cc.flush())
+}
+
+// suspendCursor suspends the statement of the open cursor of stmt until it's fetched again, the cursor is closed if
+// it isn't fetched in cursorIdleTimeout.
+func (cc *clientConn) suspendCursor(stmt IStatement, crs *cursorResultSet) {
+	if rs, ok := crs.ResultSet.(suspendable); ok {
+		rs.Suspend()
+	}
+	crs.timer = time.AfterFunc(cursorIdleTimeout, func() {
+		// The timer runs out of the connection goroutine, mu makes sure the session is idle.
+		cc.mu.Lock()
+		defer cc.mu.Unlock()
+		if stmt.GetResultSet() == crs {
+			log.Infof("[%d] close the cursor of the statement %d idle for %v", cc.connectionID, stmt.ID(),
+				cursorIdleTimeout)
+			stmt.StoreResultSet(nil)
+		}
+	})
+}
+
+// resumeCursor resumes the statement of the open cursor before its rows are fetched.
+func (cc *clientConn) resumeCursor(crs *cursorResultSet) {
+	crs.timer.Stop()
+	if rs, ok := crs.ResultSet.(suspendable); ok {
+		rs.Resume()
+	}
+}
+
+// cursorResultSet is the result set of an open cursor, it returns the row read before the columns first.
+type cursorResultSet struct {
+	ResultSet
+	columns []*ColumnInfo
+	row     []types.Datum
+	// timer closes the cursor when it's idle, see suspendCursor.
+	timer *time.Timer
+}
+
+// Columns implements ResultSet Columns method.
+func (rs *cursorResultSet) Columns() ([]*ColumnInfo, error) {
+	return rs.columns, nil
+}
+
+// Next implements ResultSet Next method.
+func (rs *cursorResultSet) Next() ([]types.Datum, error) {
+	if row := rs.row; row != nil {
+		rs.row = nil
+		return row, nil
+	}
+	return rs.ResultSet.Next()
+}
+
+// Close implements ResultSet Close method.
+func (rs *cursorResultSet) Close() error {
+	if rs.timer != nil {
+		rs.timer.Stop()
+	}
+	return rs.ResultSet.Close()
+}
+
+// handleStmtFetch writes at most the requested number of the rows of the open cursor of the statement.
+// See https://dev.mysql.com/doc/internals/en/com-stmt-fetch.html
+func (cc *clientConn) handleStmtFetch(data []byte) (err error) {
+	if len(data) < 8 {
+		return mysql.ErrMalformPacket
+	}
+
+	stmtID := binary.LittleEndian.Uint32(data[0:4])
+	fetchRows := binary.LittleEndian.Uint32(data[4:8])
+	stmt := cc.ctx.GetStatement(int(stmtID))
+	if stmt == nil {
+		return mysql.NewErr(mysql.ErrUnknownStmtHandler,
+			strconv.FormatUint(uint64(stmtID), 10), "stmt_fetch")
+	}
+	rs, ok := stmt.GetResultSet().(*cursorResultSet)
+	if !ok {
+		return mysql.NewErrf(mysql.ErrStmtHasNoOpenCursor, "The statement (%d) has no open cursor.", stmtID)
+	}
+	columns, err := rs.Columns()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	cc.resumeCursor(rs)
+	defer func() {
+		if stmt.GetResultSet() == rs {
+			cc.suspendCursor(stmt, rs)
+		}
+	}()
+
+	status := mysql.ServerStatusCursorExists
+	data = cc.alloc.AllocWithLen(4, 1024)
+	for i := uint32(0); i < fetchRows; i++ {
+		row, err := rs.Next()
+		if err != nil {
+			stmt.StoreResultSet(nil)
+			return errors.Trace(err)
+		}
+		if row == nil {
+			// The cursor is closed after the last row is sent.
+			stmt.StoreResultSet(nil)
+			status = mysql.ServerStatusLastRowSend
+			break
+		}
+		rowData, err := dumpRowValuesBinary(cc.alloc, columns, row)
+		if err != nil {
+			stmt.StoreResultSet(nil)
+			return errors.Trace(err)
+		}
+		data = append(data[0:4], rowData...)
+		if err = cc.writePacket(data); err != nil {
+			return errors.Trace(err)
+		}
+	}
+
+	if err = cc.writeEOFWithStatus(cc.ctx.Status() | status); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(cc.flush())
+}
+
 func parseStmtArgs(args []interface{}, boundParams [][]byte, nullBitmap, paramTypes, paramValues []byte) (err error) {
 	pos := 0
 	var v []byte
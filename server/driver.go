@@ -16,6 +16,7 @@ package server
 import (
 	"fmt"
 
+	"github.com/pingcap/tidb/sessionctx"
 	"github.com/pingcap/tidb/util/types"
 )
 
@@ -69,6 +70,12 @@ type IContext interface {
 	// FieldList returns columns of a table.
 	FieldList(tableName string) (columns []*ColumnInfo, err error)
 
+	// SetSessionManager sets the manager of the sessions of all the connections.
+	SetSessionManager(sm sessionctx.SessionManager)
+
+	// Kill interrupts the running statement.
+	Kill()
+
 	// Close closes the IContext.
 	Close() error
 
@@ -93,7 +100,20 @@ type IStatement interface {
 	// BoundParams returns bound parameters.
 	BoundParams() [][]byte
 
-	// Reset removes all bound parameters.
+	// SetParamsType sets the types of the parameters, they are sent by the client in the first execution.
+	SetParamsType([]byte)
+
+	// GetParamsType returns the types of the parameters of the last execution.
+	GetParamsType() []byte
+
+	// StoreResultSet keeps the result set of the open cursor of the statement, its rows are fetched by
+	// COM_STMT_FETCH. The result set of the last open cursor is closed, nil only closes it.
+	StoreResultSet(rs ResultSet)
+
+	// GetResultSet returns the result set of the open cursor of the statement, nil if there is no open cursor.
+	GetResultSet() ResultSet
+
+	// Reset removes all bound parameters and closes the open cursor.
 	Reset()
 
 	// Close closes the statement.
@@ -17,10 +17,13 @@ import (
 	"fmt"
 
 	"github.com/juju/errors"
+	"github.com/ngaut/log"
 	"github.com/pingcap/tidb"
 	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/executor"
 	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/sessionctx"
 	"github.com/pingcap/tidb/util/types"
 )
 
@@ -39,10 +42,9 @@ func NewTiDBDriver(store kv.Storage) *TiDBDriver {
 
 // TiDBContext implements IContext.
 type TiDBContext struct {
-	session      tidb.Session
-	currentDB    string
-	warningCount uint16
-	stmts        map[int]*TiDBStatement
+	session   tidb.Session
+	currentDB string
+	stmts     map[int]*TiDBStatement
 }
 
 // TiDBStatement implements IStatement.
@@ -50,7 +52,9 @@ type TiDBStatement struct {
 	id          uint32
 	numParams   int
 	boundParams [][]byte
+	paramsType  []byte
 	ctx         *TiDBContext
+	rs          ResultSet
 }
 
 // ID implements IStatement ID method.
@@ -92,15 +96,42 @@ func (ts *TiDBStatement) BoundParams() [][]byte {
 	return ts.boundParams
 }
 
+// SetParamsType implements IStatement SetParamsType method.
+func (ts *TiDBStatement) SetParamsType(paramsType []byte) {
+	ts.paramsType = append(ts.paramsType[:0], paramsType...)
+}
+
+// GetParamsType implements IStatement GetParamsType method.
+func (ts *TiDBStatement) GetParamsType() []byte {
+	return ts.paramsType
+}
+
+// StoreResultSet implements IStatement StoreResultSet method.
+func (ts *TiDBStatement) StoreResultSet(rs ResultSet) {
+	if ts.rs != nil {
+		if err := ts.rs.Close(); err != nil {
+			log.Errorf("close the cursor of the statement %d failed: %v", ts.id, err)
+		}
+	}
+	ts.rs = rs
+}
+
+// GetResultSet implements IStatement GetResultSet method.
+func (ts *TiDBStatement) GetResultSet() ResultSet {
+	return ts.rs
+}
+
 // Reset implements IStatement Reset method.
 func (ts *TiDBStatement) Reset() {
 	for i := range ts.boundParams {
 		ts.boundParams[i] = nil
 	}
+	ts.StoreResultSet(nil)
 }
 
 // Close implements IStatement Close method.
 func (ts *TiDBStatement) Close() error {
+	ts.StoreResultSet(nil)
 	//TODO close at tidb level
 	err := ts.ctx.session.DropPreparedStmt(ts.id)
 	if err != nil {
@@ -174,7 +205,7 @@ func (tc *TiDBContext) CurrentDB() string {
 
 // WarningCount implements IContext WarningCount method.
 func (tc *TiDBContext) WarningCount() uint16 {
-	return tc.warningCount
+	return tc.session.WarningCount()
 }
 
 // Execute implements IContext Execute method.
@@ -200,8 +231,21 @@ func (tc *TiDBContext) SetClientCapability(flags uint32) {
 	tc.session.SetClientCapability(flags)
 }
 
+// SetSessionManager implements IContext SetSessionManager method.
+func (tc *TiDBContext) SetSessionManager(sm sessionctx.SessionManager) {
+	tc.session.SetSessionManager(sm)
+}
+
+// Kill implements IContext Kill method.
+func (tc *TiDBContext) Kill() {
+	tc.session.Kill()
+}
+
 // Close implements IContext Close method.
 func (tc *TiDBContext) Close() (err error) {
+	for _, stmt := range tc.stmts {
+		stmt.StoreResultSet(nil)
+	}
 	return tc.session.Close()
 }
 
@@ -278,6 +322,20 @@ func (trs *tidbResultSet) Close() error {
 	return trs.recordSet.Close()
 }
 
+// Suspend implements the suspendable Suspend interface.
+func (trs *tidbResultSet) Suspend() {
+	if rs, ok := trs.recordSet.(executor.SuspendableRecordSet); ok {
+		rs.Suspend()
+	}
+}
+
+// Resume implements the suspendable Resume interface.
+func (trs *tidbResultSet) Resume() {
+	if rs, ok := trs.recordSet.(executor.SuspendableRecordSet); ok {
+		rs.Resume()
+	}
+}
+
 func (trs *tidbResultSet) Columns() ([]*ColumnInfo, error) {
 	fields, err := trs.recordSet.Fields()
 	if err != nil {
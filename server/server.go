@@ -206,6 +206,33 @@ func (s *Server) onConn(c net.Conn) {
 	conn.Run()
 }
 
+// Kill implements sessionctx.SessionManager Kill interface.
+func (s *Server) Kill(connectionID uint64, query bool) bool {
+	s.rwlock.RLock()
+	conn, ok := s.clients[uint32(connectionID)]
+	s.rwlock.RUnlock()
+	if !ok {
+		return false
+	}
+	conn.ctx.Kill()
+	if !query {
+		// The connection quits once its running statement is interrupted and it fails to read the next command.
+		conn.conn.Close()
+	}
+	return true
+}
+
+// ConnectionUser implements the sessionctx.SessionManager ConnectionUser interface.
+func (s *Server) ConnectionUser(connectionID uint64) (string, bool) {
+	s.rwlock.RLock()
+	conn, ok := s.clients[uint32(connectionID)]
+	s.rwlock.RUnlock()
+	if !ok {
+		return "", false
+	}
+	return conn.user, true
+}
+
 var once sync.Once
 
 const defaultStatusAddr = ":10080"
@@ -14,6 +14,7 @@
 package server
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
@@ -450,6 +451,27 @@ func runTestErrorCode(c *C) {
 	})
 }
 
+func runTestKill(c *C) {
+	runTests(c, dsn, func(dbt *DBTest) {
+		conn, err := dbt.db.Conn(context.Background())
+		c.Assert(err, IsNil)
+		defer conn.Close()
+		var connectionID uint64
+		err = conn.QueryRowContext(context.Background(), "select connection_id()").Scan(&connectionID)
+		c.Assert(err, IsNil)
+
+		_, err = dbt.db.Exec("kill 0")
+		checkErrorCode(c, err, tmysql.ErrNoSuchThread)
+		dbt.mustExec(fmt.Sprintf("kill query %d", connectionID))
+		// KILL QUERY keeps the connection.
+		_, err = conn.ExecContext(context.Background(), "do 1")
+		c.Assert(err, IsNil)
+		dbt.mustExec(fmt.Sprintf("kill connection %d", connectionID))
+		_, err = conn.ExecContext(context.Background(), "do 1")
+		c.Assert(err, NotNil)
+	})
+}
+
 func checkErrorCode(c *C, e error, code uint16) {
 	me, ok := e.(*mysql.MySQLError)
 	c.Assert(ok, IsTrue, Commentf("err: %v", e))
@@ -15,12 +15,19 @@
 package server
 
 import (
+	"bufio"
+	"bytes"
 	"database/sql"
+	"encoding/binary"
 	"time"
 
 	"github.com/ngaut/log"
 	. "github.com/pingcap/check"
 	"github.com/pingcap/tidb"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/terror"
+	"github.com/pingcap/tidb/util/arena"
 )
 
 type TidbTestSuite struct {
@@ -101,6 +108,10 @@ func (ts *TidbTestSuite) TestErrorCode(c *C) {
 	runTestErrorCode(c)
 }
 
+func (ts *TidbTestSuite) TestKill(c *C) {
+	runTestKill(c)
+}
+
 func (ts *TidbTestSuite) TestAuth(c *C) {
 	runTestAuth(c)
 }
@@ -144,3 +155,115 @@ func (ts *TidbTestSuite) TestSocket(c *C) {
 	dsn = tcpDsn
 	server.Close()
 }
+
+func (ts *TidbTestSuite) TestCursorFetch(c *C) {
+	c.Parallel()
+	ctx, err := ts.tidbdrv.OpenCtx(0, mysql.ClientProtocol41, mysql.DefaultCollationID, "")
+	c.Assert(err, IsNil)
+	defer ctx.Close()
+	var buf bytes.Buffer
+	cc := &clientConn{
+		pkt:        &packetIO{wb: bufio.NewWriter(&buf)},
+		capability: mysql.ClientProtocol41,
+		alloc:      arena.NewAllocator(1024),
+		ctx:        ctx,
+	}
+	_, err = ctx.Execute("create database cursor_test")
+	c.Assert(err, IsNil)
+	_, err = ctx.Execute("create table cursor_test.t (a int)")
+	c.Assert(err, IsNil)
+	_, err = ctx.Execute("insert cursor_test.t values (1), (2), (3), (4), (5)")
+	c.Assert(err, IsNil)
+	stmt, _, _, err := ctx.Prepare("select a from cursor_test.t order by a")
+	c.Assert(err, IsNil)
+
+	// readPackets returns the payloads of the packets written to buf.
+	readPackets := func() [][]byte {
+		var packets [][]byte
+		data := buf.Bytes()
+		for len(data) > 0 {
+			length := int(uint32(data[0]) | uint32(data[1])<<8 | uint32(data[2])<<16)
+			packets = append(packets, data[4:4+length])
+			data = data[4+length:]
+		}
+		buf.Reset()
+		return packets
+	}
+	eofStatus := func(packet []byte) uint16 {
+		c.Assert(packet[0], Equals, mysql.EOFHeader)
+		return binary.LittleEndian.Uint16(packet[3:5])
+	}
+	fetch := func(rows uint32) error {
+		data := append(dumpUint32(uint32(stmt.ID())), dumpUint32(rows)...)
+		return cc.handleStmtFetch(data)
+	}
+
+	execute := append(dumpUint32(uint32(stmt.ID())), mysql.CursorTypeReadOnly, 1, 0, 0, 0)
+	c.Assert(cc.handleStmtExecute(execute), IsNil)
+	// The column count, the column definition and the EOF.
+	packets := readPackets()
+	c.Assert(packets, HasLen, 3)
+	c.Assert(eofStatus(packets[2])&mysql.ServerStatusCursorExists, Not(Equals), uint16(0))
+	c.Assert(stmt.GetResultSet(), NotNil)
+
+	c.Assert(fetch(2), IsNil)
+	packets = readPackets()
+	c.Assert(packets, HasLen, 3)
+	// The binary row is the header, the NULL bitmap and the value.
+	c.Assert(packets[0][2:], DeepEquals, []byte{1, 0, 0, 0})
+	c.Assert(packets[1][2:], DeepEquals, []byte{2, 0, 0, 0})
+	c.Assert(eofStatus(packets[2])&mysql.ServerStatusCursorExists, Not(Equals), uint16(0))
+
+	c.Assert(fetch(5), IsNil)
+	packets = readPackets()
+	c.Assert(packets, HasLen, 4)
+	c.Assert(packets[2][2:], DeepEquals, []byte{5, 0, 0, 0})
+	c.Assert(eofStatus(packets[3])&mysql.ServerStatusLastRowSend, Not(Equals), uint16(0))
+	c.Assert(stmt.GetResultSet(), IsNil)
+	err = fetch(1)
+	c.Assert(err, NotNil)
+	c.Assert(err.(*mysql.SQLError).Code, Equals, uint16(mysql.ErrStmtHasNoOpenCursor))
+
+	// Resetting the statement closes the open cursor.
+	c.Assert(cc.handleStmtExecute(execute), IsNil)
+	readPackets()
+	c.Assert(stmt.GetResultSet(), NotNil)
+	c.Assert(cc.handleStmtReset(dumpUint32(uint32(stmt.ID()))), IsNil)
+	readPackets()
+	c.Assert(stmt.GetResultSet(), IsNil)
+
+	// The idle cursor isn't interrupted by KILL QUERY or the statements after it.
+	c.Assert(cc.handleStmtExecute(execute), IsNil)
+	readPackets()
+	ctx.Kill()
+	_, err = ctx.Execute("select 1")
+	c.Assert(err, IsNil)
+	c.Assert(fetch(1), IsNil)
+	c.Assert(readPackets()[0][2:], DeepEquals, []byte{1, 0, 0, 0})
+	// KILL QUERY interrupts the cursor while its rows are fetched, and the cursor is closed.
+	cc.resumeCursor(stmt.GetResultSet().(*cursorResultSet))
+	ctx.Kill()
+	err = fetch(1)
+	c.Assert(terror.ErrorEqual(err, kv.ErrQueryInterrupted), IsTrue)
+	c.Assert(stmt.GetResultSet(), IsNil)
+	_, err = ctx.Execute("select 1")
+	c.Assert(err, IsNil)
+
+	// The idle cursor is closed after cursorIdleTimeout.
+	cursorIdleTimeout = 10 * time.Millisecond
+	defer func() { cursorIdleTimeout = 10 * time.Minute }()
+	c.Assert(cc.handleStmtExecute(execute), IsNil)
+	readPackets()
+	time.Sleep(100 * time.Millisecond)
+	cc.mu.Lock()
+	c.Assert(stmt.GetResultSet(), IsNil)
+	cc.mu.Unlock()
+
+	// The rows are written at once without a cursor.
+	execute[4] = mysql.CursorTypeNoCursor
+	c.Assert(cc.handleStmtExecute(execute), IsNil)
+	c.Assert(readPackets(), HasLen, 9)
+	c.Assert(stmt.GetResultSet(), IsNil)
+	execute[4] = mysql.CursorTypeScrollable
+	c.Assert(cc.handleStmtExecute(execute), NotNil)
+}
@@ -21,6 +21,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"math"
 	"strings"
 	"sync"
 	"time"
@@ -54,6 +55,7 @@ type Session interface {
 	Status() uint16                               // Flag of current status, such as autocommit.
 	LastInsertID() uint64                         // Last inserted auto_increment id.
 	AffectedRows() uint64                         // Affected rows by latest executed stmt.
+	WarningCount() uint16                         // Warnings of latest executed stmt.
 	SetValue(key fmt.Stringer, value interface{}) // SetValue saves a value associated with this session for key.
 	Value(key fmt.Stringer) interface{}           // Value returns the value associated with this session for key.
 	Execute(sql string) ([]ast.RecordSet, error)  // Execute a sql statement.
@@ -67,9 +69,16 @@ type Session interface {
 	DropPreparedStmt(stmtID uint32) error
 	SetClientCapability(uint32) // Set client capability flags.
 	SetConnectionID(uint64)
+	// SetSessionManager sets the manager of the sessions of all the connections, which the KILL statement goes through.
+	SetSessionManager(sessionctx.SessionManager)
+	// Kill interrupts the running statement of the session.
+	Kill()
 	Close() error
 	Retry() error
 	Auth(user string, auth []byte, salt []byte) bool
+	// NormalizeDigest returns the normalized text and the digest of the statement being executed or executed last,
+	// see parser.NormalizeDigest.
+	NormalizeDigest() (normalized, digest string)
 }
 
 var (
@@ -125,6 +134,13 @@ type session struct {
 	parser    *parser.Parser
 
 	sessionVars *variable.SessionVars
+
+	// stmtDigest caches the result of NormalizeDigest for the statement of the text.
+	stmtDigest struct {
+		text       string
+		normalized string
+		digest     string
+	}
 }
 
 func (s *session) cleanRetryInfo() {
@@ -165,6 +181,13 @@ func (s *session) AffectedRows() uint64 {
 	return s.sessionVars.AffectedRows
 }
 
+func (s *session) WarningCount() uint16 {
+	if len(s.sessionVars.Warnings) > math.MaxUint16 {
+		return math.MaxUint16
+	}
+	return uint16(len(s.sessionVars.Warnings))
+}
+
 func (s *session) resetHistory() {
 	s.ClearValue(forupdate.ForUpdateKey)
 	s.history.reset()
@@ -178,6 +201,14 @@ func (s *session) SetConnectionID(connectionID uint64) {
 	s.sessionVars.ConnectionID = connectionID
 }
 
+func (s *session) SetSessionManager(sm sessionctx.SessionManager) {
+	sessionctx.BindSessionManager(s, sm)
+}
+
+func (s *session) Kill() {
+	s.sessionVars.Kill()
+}
+
 func (s *session) finishTxn(rollback bool) error {
 	// transaction has already been committed or rolled back
 	if s.txn == nil {
@@ -185,6 +216,7 @@ func (s *session) finishTxn(rollback bool) error {
 	}
 	defer func() {
 		s.ClearValue(executor.DirtyDBKey)
+		s.ClearValue(executor.BatchDMLKey)
 		s.txn = nil
 		s.sessionVars.SetStatusFlag(mysql.ServerStatusInTrans, false)
 		binloginfo.ClearBinlog(s)
@@ -210,6 +242,7 @@ func (s *session) finishTxn(rollback bool) error {
 		}
 	}
 	err := s.txn.Commit()
+	executor.RecordTxnWrites(s, err != nil && kv.IsRetryableError(err))
 	if err != nil {
 		if !s.sessionVars.RetryInfo.Retrying && kv.IsRetryableError(err) {
 			err = s.Retry()
@@ -287,6 +320,9 @@ func (s *session) Retry() error {
 	if forUpdate := s.Value(forupdate.ForUpdateKey); forUpdate != nil {
 		return errors.Errorf("can not retry select for update statement")
 	}
+	if batched := s.Value(executor.BatchDMLKey); batched != nil {
+		return errors.Errorf("can not retry the statement committed in batches")
+	}
 	var err error
 	retryCnt := 0
 	for {
@@ -425,14 +461,35 @@ func (s *session) ParseSQL(sql, charset, collation string) ([]ast.StmtNode, erro
 	return s.parser.Parse(sql, charset, collation)
 }
 
+// parseStmts parses the statements to execute, the rows of a huge INSERT statement are parsed while it's executed.
+func (s *session) parseStmts(sql, charset, collation string) ([]ast.StmtNode, error) {
+	stmt, ok, err := executor.ParseStreamInsert(s, s.parser, sql, charset, collation)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if ok {
+		return []ast.StmtNode{stmt}, nil
+	}
+	return s.ParseSQL(sql, charset, collation)
+}
+
 func (s *session) Execute(sql string) ([]ast.RecordSet, error) {
+	s.resetKilled()
 	if err := s.checkSchemaValidOrRollback(); err != nil {
 		return nil, errors.Trace(err)
 	}
+	// The common global variables include the cost factors, they're loaded before the statements are compiled.
+	// It's done before parsing the statements, because the parser is reused by the restricted SQL which loads them.
+	if err := s.loadCommonGlobalVariablesIfNeeded(); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if err := executor.CheckStatementSize(s, sql); err != nil {
+		return nil, errors.Trace(err)
+	}
 	startTS := time.Now()
 	charset, collation := s.sessionVars.GetCharsetInfo()
 	connID := s.sessionVars.ConnectionID
-	rawStmts, err := s.ParseSQL(sql, charset, collation)
+	rawStmts, err := s.parseStmts(sql, charset, collation)
 	if err != nil {
 		log.Warnf("[%d] parse error:\n%v\n%s", connID, err, sql)
 		return nil, errors.Trace(err)
@@ -473,6 +530,11 @@ func (s *session) Execute(sql string) ([]ast.RecordSet, error) {
 	return rs, nil
 }
 
+// resetKilled clears the kill signal left by KILL QUERY, which only interrupts the running statement.
+func (s *session) resetKilled() {
+	s.sessionVars.ResetKilled()
+}
+
 // For execute prepare statement in binary protocol
 func (s *session) PrepareStmt(sql string) (stmtID uint32, paramCount int, fields []*ast.ResultField, err error) {
 	if err := s.checkSchemaValidOrRollback(); err != nil {
@@ -535,6 +597,7 @@ func checkArgs(args ...interface{}) error {
 
 // ExecutePreparedStmt executes a prepared statement.
 func (s *session) ExecutePreparedStmt(stmtID uint32, args ...interface{}) (ast.RecordSet, error) {
+	s.resetKilled()
 	if err := s.checkSchemaValidOrRollback(); err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -543,10 +606,21 @@ func (s *session) ExecutePreparedStmt(stmtID uint32, args ...interface{}) (ast.R
 		return nil, errors.Trace(err)
 	}
 	st := executor.CompileExecutePreparedStmt(s, stmtID, args...)
+	s.SetValue(context.QueryString, st.OriginText())
 	r, err := runStmt(s, st, args...)
 	return r, errors.Trace(err)
 }
 
+// NormalizeDigest implements the Session NormalizeDigest interface, the statement is normalized at most once.
+func (s *session) NormalizeDigest() (normalized, digest string) {
+	text, _ := s.Value(context.QueryString).(string)
+	if s.stmtDigest.digest == "" || s.stmtDigest.text != text {
+		s.stmtDigest.text = text
+		s.stmtDigest.normalized, s.stmtDigest.digest = parser.NormalizeDigest(text)
+	}
+	return s.stmtDigest.normalized, s.stmtDigest.digest
+}
+
 func (s *session) DropPreparedStmt(stmtID uint32) error {
 	if err := s.checkSchemaValidOrRollback(); err != nil {
 		return errors.Trace(err)
@@ -743,7 +817,7 @@ func CreateSession(store kv.Storage) (Session, error) {
 
 const (
 	notBootstrapped         = 0
-	currentBootstrapVersion = 3
+	currentBootstrapVersion = 7
 )
 
 func getStoreBootstrapVersion(store kv.Storage) int64 {
@@ -791,7 +865,11 @@ const loadCommonGlobalVarsSQL = "select * from mysql.global_variables where vari
 	variable.AutocommitVar + "', '" +
 	variable.SQLModeVar + "', '" +
 	variable.DistSQLJoinConcurrencyVar + "', '" +
-	variable.DistSQLScanConcurrencyVar + "')"
+	variable.DistSQLScanConcurrencyVar + "', '" +
+	variable.TiDBOptScanFactor + "', '" +
+	variable.TiDBOptNetworkFactor + "', '" +
+	variable.TiDBOptSeekFactor + "', '" +
+	variable.TiDBOptCPUFactor + "')"
 
 // LoadCommonGlobalVariableIfNeeded loads and applies commonly used global variables for the session
 // right before creating a transaction for the first time.
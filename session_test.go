@@ -23,8 +23,10 @@ import (
 	"github.com/juju/errors"
 	. "github.com/pingcap/check"
 	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/executor"
 	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/parser"
 	"github.com/pingcap/tidb/plan"
 	"github.com/pingcap/tidb/sessionctx"
 	"github.com/pingcap/tidb/sessionctx/variable"
@@ -860,6 +862,39 @@ func (s *testSessionSuite) TestSelectForUpdate(c *C) {
 
 	mustExecSQL(c, se1, "commit")
 
+	// not conflict, only the rows of the tables in the "for update of" list are locked.
+	mustExecSQL(c, se1, "begin")
+	rs, err = exec(se1, "select * from t join t1 on t.c1 = t1.c1 for update of t1")
+	c.Assert(err, IsNil)
+	_, err = GetRows(rs)
+	c.Assert(err, IsNil)
+
+	mustExecSQL(c, se2, "begin")
+	mustExecSQL(c, se2, "update t set c2=23 where c1=11")
+	mustExecSQL(c, se2, "commit")
+
+	mustExecSQL(c, se1, "commit")
+
+	// conflict on the table in the "for update of" list, which is referred by its alias.
+	mustExecSQL(c, se1, "begin")
+	rs, err = exec(se1, "select * from t as a join t1 as b on a.c1 = b.c1 for update of b")
+	c.Assert(err, IsNil)
+	_, err = GetRows(rs)
+	c.Assert(err, IsNil)
+
+	mustExecSQL(c, se2, "begin")
+	mustExecSQL(c, se2, "update t1 set c1=111 where c1=11")
+	mustExecSQL(c, se2, "commit")
+
+	_, err = exec(se1, "commit")
+	c.Assert(err, NotNil)
+
+	// the table in the "for update of" list must be in the from clause.
+	mustExecSQL(c, se1, "begin")
+	_, err = exec(se1, "select * from t for update of t1")
+	c.Assert(err, NotNil)
+	mustExecSQL(c, se1, "rollback")
+
 	// not conflict, auto commit
 	mustExecSQL(c, se1, "set @@autocommit=1;")
 	rs, err = exec(se1, "select * from t where c1=11 for update")
@@ -2372,6 +2407,36 @@ func newSessionWithoutInit(c *C, store kv.Storage) *session {
 	return s
 }
 
+func (s *testSessionSuite) TestRetryBatchDML(c *C) {
+	defer testleak.AfterTest(c)()
+	store := newStore(c, s.dbName)
+	se := newSession(c, store, s.dbName)
+	se1 := newSession(c, store, s.dbName)
+
+	mustExecSQL(c, se, "drop table if exists t")
+	mustExecSQL(c, se, "create table t (c1 int primary key, c2 int)")
+	mustExecSQL(c, se, "insert t values (1, 1)")
+
+	// The transaction is retried with the statement without the batches.
+	mustExecSQL(c, se1, "begin")
+	mustExecSQL(c, se1, "update t set c2 = c2 + 1 where c1 = 1")
+	mustExecSQL(c, se, "update t set c2 = 10 where c1 = 1")
+	mustExecSQL(c, se1, "commit")
+	mustExecMatch(c, se, "select c2 from t", [][]interface{}{{11}})
+
+	// The transaction of a statement committed in batches isn't retried.
+	mustExecSQL(c, se1, "begin")
+	mustExecSQL(c, se1, "update t set c2 = c2 + 1 where c1 = 1")
+	mustExecSQL(c, se, "update t set c2 = 20 where c1 = 1")
+	se1.SetValue(executor.BatchDMLKey, true)
+	_, err := exec(se1, "commit")
+	c.Assert(err, NotNil)
+	mustExecMatch(c, se, "select c2 from t", [][]interface{}{{20}})
+	c.Assert(se1.Value(executor.BatchDMLKey), IsNil)
+
+	mustExecSQL(c, se, s.dropDBSQL)
+}
+
 func (s *testSessionSuite) TestRetryAttempts(c *C) {
 	defer testleak.AfterTest(c)()
 	store := kv.NewMockStorage()
@@ -2475,6 +2540,28 @@ func (s *testSessionSuite) TestQueryString(c *C) {
 	c.Assert(queryStr, Equals, "create table multi2 (a int)")
 }
 
+func (s *testSessionSuite) TestNormalizeDigest(c *C) {
+	store := newStore(c, s.dbName)
+	se := newSession(c, store, s.dbName)
+	mustExecute(se, "use "+s.dbName)
+	mustExecute(se, "create table digest (a int, b varchar(10))")
+	mustExecute(se, "SELECT a FROM digest WHERE b = 'x' AND a > 10")
+	normalized, digest := se.NormalizeDigest()
+	c.Assert(normalized, Equals, "select a from digest where b = ? and a > ?")
+	c.Assert(digest, Equals, parser.Digest("select a from digest where b = 'y' and a > 20"))
+
+	// The prepared statements have the digest of their text.
+	mustExecSQL(c, se, "select a from digest where b = ? and a > ?", "x", 10)
+	normalized2, digest2 := se.NormalizeDigest()
+	c.Assert(normalized2, Equals, normalized)
+	c.Assert(digest2, Equals, digest)
+
+	mustExecute(se, "insert digest values (1, 'x')")
+	normalized, digest = se.NormalizeDigest()
+	c.Assert(normalized, Equals, "insert digest values ( ? , ? )")
+	c.Assert(digest, Not(Equals), digest2)
+}
+
 // Test that the auto_increment ID does not reuse the old table's allocator.
 func (s *testSessionSuite) TestTruncateAlloc(c *C) {
 	store := newStore(c, s.dbName)
@@ -2490,3 +2577,19 @@ func (s *testSessionSuite) TestTruncateAlloc(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(row, IsNil)
 }
+
+func (s *testSessionSuite) TestCostFactorGlobalVars(c *C) {
+	defer testleak.AfterTest(c)()
+	store := newStore(c, s.dbName)
+	se := newSession(c, store, s.dbName)
+	mustExecSQL(c, se, "set global tidb_opt_scan_factor = 0.2")
+	mustExecSQL(c, se, "set @@tidb_opt_seek_factor = 20")
+
+	// The global cost factors are loaded by the new sessions.
+	se1 := newSession(c, store, s.dbName)
+	mustExecMatch(c, se1, "select @@tidb_opt_scan_factor, @@tidb_opt_seek_factor", [][]interface{}{{"0.2", "10"}})
+	mustExecSQL(c, se, "set global tidb_opt_scan_factor = 0.5")
+
+	err := store.Close()
+	c.Assert(err, IsNil)
+}
@@ -0,0 +1,51 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sessionctx
+
+import (
+	"github.com/pingcap/tidb/context"
+)
+
+// SessionManager is the interface to manage the sessions of all the connections.
+type SessionManager interface {
+	// Kill interrupts the running statement of the connection, and closes the connection too if query is false.
+	// It returns false if the connection is not found.
+	Kill(connectionID uint64, query bool) bool
+	// ConnectionUser returns the user name of the connection, it returns false if the connection is not found.
+	ConnectionUser(connectionID uint64) (string, bool)
+}
+
+// A dummy type to avoid naming collision in context.
+type sessionManagerKeyType int
+
+// String defines a Stringer function for debugging and pretty printing.
+func (k sessionManagerKeyType) String() string {
+	return "session manager"
+}
+
+const sessionManagerKey sessionManagerKeyType = 0
+
+// BindSessionManager binds session manager to context.
+func BindSessionManager(ctx context.Context, sm SessionManager) {
+	ctx.SetValue(sessionManagerKey, sm)
+}
+
+// GetSessionManager gets session manager from context.
+func GetSessionManager(ctx context.Context) SessionManager {
+	v, ok := ctx.Value(sessionManagerKey).(SessionManager)
+	if !ok {
+		return nil
+	}
+	return v
+}
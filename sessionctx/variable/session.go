@@ -14,7 +14,10 @@
 package variable
 
 import (
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/juju/errors"
@@ -72,6 +75,15 @@ func (r *RetryInfo) GetCurrAutoIncrementID() (int64, error) {
 	return id, nil
 }
 
+// StatsOverride is the fake statistics of a table which the optimizer of the session uses instead of the real
+// ones, so the plans for a table of that size can be checked without loading the data.
+type StatsOverride struct {
+	// Count is the row count of the table, 0 means it isn't overridden.
+	Count int64
+	// NDVs are the number of distinct values of the columns by the column IDs.
+	NDVs map[int64]int64
+}
+
 // SessionVars is to handle user-defined or global variables in current session.
 type SessionVars struct {
 	// user-defined variables
@@ -91,6 +103,8 @@ type SessionVars struct {
 	Status       uint16
 	LastInsertID uint64
 	AffectedRows uint64
+	// Warnings are the warnings of the latest executed stmt, shown by SHOW WARNINGS.
+	Warnings []error
 
 	// Client capability
 	ClientCapability uint32
@@ -98,6 +112,13 @@ type SessionVars struct {
 	// Connection ID
 	ConnectionID uint64
 
+	// Killed is the kill signal of the running statement of the session, it's set to 1 by KILL QUERY or
+	// KILL CONNECTION and accessed atomically. Every statement has its own signal, see ResetKilled, so a statement
+	// whose rows are still read after it returns, e.g. by an open cursor, isn't interrupted or resumed by the
+	// statements after it. It's only replaced by ResetKilled and SetKillSignal.
+	Killed *uint32
+	killMu sync.Mutex
+
 	// Found rows
 	FoundRows uint64
 
@@ -110,6 +131,10 @@ type SessionVars struct {
 	// Strict SQL mode
 	StrictSQLMode bool
 
+	// OnlyFullGroupBy is true when the SQL mode contains ONLY_FULL_GROUP_BY, the select list, having clause and
+	// order by clause of the aggregated queries can't refer to the nonaggregated columns not in the group by clause.
+	OnlyFullGroupBy bool
+
 	// CommonGlobalLoaded indicates if common global variable has been loaded for this session.
 	CommonGlobalLoaded bool
 
@@ -129,6 +154,23 @@ type SessionVars struct {
 	// SkipConstraintCheck is true when importing data.
 	SkipConstraintCheck bool
 
+	// PlanBaseline is true when the plan baselines are captured and used, see TiDBPlanBaseline.
+	PlanBaseline bool
+
+	// BatchInsert and BatchDelete split the rows of the INSERT and DELETE statements into the transactions,
+	// see TiDBBatchInsert and TiDBBatchDelete.
+	BatchInsert bool
+	BatchDelete bool
+
+	// DDLShadowCopy changes the columns by copying the table into a shadow table, see TiDBDDLShadowCopy.
+	DDLShadowCopy bool
+
+	// CompatVersion is the MySQL version emulated by the builtin functions, see TiDBCompatVersion.
+	CompatVersion CompatVersion
+
+	// StatsOverrides are the fake statistics of the tables by the table IDs, set by ADMIN SET STATS.
+	StatsOverrides map[int64]*StatsOverride
+
 	// GlobalAccessor is used to set and get global variables.
 	GlobalVarsAccessor GlobalVarAccessor
 }
@@ -143,9 +185,31 @@ func NewSessionVars() *SessionVars {
 		RetryInfo:            &RetryInfo{},
 		StrictSQLMode:        true,
 		Status:               mysql.ServerStatusAutocommit,
+		Killed:               new(uint32),
 	}
 }
 
+// Kill interrupts the running statement of the session, it's called by the other sessions.
+func (s *SessionVars) Kill() {
+	s.killMu.Lock()
+	atomic.StoreUint32(s.Killed, 1)
+	s.killMu.Unlock()
+}
+
+// ResetKilled gives the next statement of the session a new kill signal, so the kill signal left by KILL QUERY,
+// which only interrupts the running statement, doesn't interrupt the next one.
+func (s *SessionVars) ResetKilled() {
+	s.SetKillSignal(new(uint32))
+}
+
+// SetKillSignal makes killed the kill signal of the running statement, it's used to resume the statement of an
+// open cursor whose rows are fetched.
+func (s *SessionVars) SetKillSignal(killed *uint32) {
+	s.killMu.Lock()
+	s.Killed = killed
+	s.killMu.Unlock()
+}
+
 const (
 	characterSetConnection = "character_set_connection"
 	collationConnection    = "collation_connection"
@@ -177,6 +241,11 @@ func (s *SessionVars) SetAffectedRows(affectedRows uint64) {
 	s.AffectedRows = affectedRows
 }
 
+// AppendWarning appends a warning to the warnings of the current stmt.
+func (s *SessionVars) AppendWarning(warn error) {
+	s.Warnings = append(s.Warnings, warn)
+}
+
 // AddAffectedRows adds affected rows with the argument rows.
 func (s *SessionVars) AddAffectedRows(rows uint64) {
 	s.AffectedRows += rows
@@ -230,6 +299,16 @@ const (
 	characterSetResults = "character_set_results"
 )
 
+// nonNegativeVars are the session variables of the sizes and the limits read by every statement, they are checked
+// when they are set, otherwise an invalid value fails all the later statements, including the SET fixing it.
+var nonNegativeVars = map[string]bool{
+	TiDBMemQuotaQuery:      true,
+	TiDBMaxChunkSize:       true,
+	TiDBMaxStatementSize:   true,
+	TiDBMaxStatementParams: true,
+	TiDBStreamInsertSize:   true,
+}
+
 // SetSystemVar sets a system variable.
 func (s *SessionVars) SetSystemVar(key string, value types.Datum) error {
 	key = strings.ToLower(key)
@@ -244,6 +323,11 @@ func (s *SessionVars) SetSystemVar(key string, value types.Datum) error {
 	if err != nil {
 		return errors.Trace(err)
 	}
+	if nonNegativeVars[key] {
+		if v, err1 := strconv.ParseInt(sVal, 10, 64); err1 != nil || v < 0 {
+			return ErrWrongValueForVar.Gen("Variable '%s' can't be set to the value of '%s'", key, sVal)
+		}
+	}
 	switch key {
 	case SQLModeVar:
 		sVal = strings.ToUpper(sVal)
@@ -252,6 +336,7 @@ func (s *SessionVars) SetSystemVar(key string, value types.Datum) error {
 		} else {
 			s.StrictSQLMode = false
 		}
+		s.OnlyFullGroupBy = strings.Contains(sVal, "ONLY_FULL_GROUP_BY")
 	case TiDBSnapshot:
 		err = s.setSnapshotTS(sVal)
 		if err != nil {
@@ -262,6 +347,20 @@ func (s *SessionVars) SetSystemVar(key string, value types.Datum) error {
 		s.SetStatusFlag(mysql.ServerStatusAutocommit, isAutocommit)
 	case TiDBSkipConstraintCheck:
 		s.setSkipConstraintCheck(sVal)
+	case TiDBPlanBaseline:
+		s.PlanBaseline = strings.EqualFold(sVal, "ON") || sVal == "1"
+	case TiDBBatchInsert:
+		s.BatchInsert = strings.EqualFold(sVal, "ON") || sVal == "1"
+	case TiDBBatchDelete:
+		s.BatchDelete = strings.EqualFold(sVal, "ON") || sVal == "1"
+	case TiDBDDLShadowCopy:
+		s.DDLShadowCopy = strings.EqualFold(sVal, "ON") || sVal == "1"
+	case TiDBCompatVersion:
+		v, err := ParseCompatVersion(sVal)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		s.CompatVersion = v
 	}
 	s.systems[key] = sVal
 	return nil
@@ -284,6 +383,33 @@ func (s *SessionVars) setSnapshotTS(sVal string) error {
 	return nil
 }
 
+// CompatVersion is a MySQL version emulated by the builtin functions.
+type CompatVersion int
+
+// The MySQL versions which can be emulated, CompatNative keeps the TiDB behavior.
+const (
+	CompatNative  CompatVersion = 0
+	CompatMySQL56 CompatVersion = 50600
+	CompatMySQL57 CompatVersion = 50700
+	CompatMySQL80 CompatVersion = 80000
+)
+
+var compatVersions = map[string]CompatVersion{
+	"":    CompatNative,
+	"5.6": CompatMySQL56,
+	"5.7": CompatMySQL57,
+	"8.0": CompatMySQL80,
+}
+
+// ParseCompatVersion parses the value of TiDBCompatVersion.
+func ParseCompatVersion(sVal string) (CompatVersion, error) {
+	v, ok := compatVersions[strings.TrimSpace(sVal)]
+	if !ok {
+		return CompatNative, ErrWrongValueForVar.Gen("Variable '%s' can't be set to the value of '%s'", TiDBCompatVersion, sVal)
+	}
+	return v, nil
+}
+
 func (s *SessionVars) setSkipConstraintCheck(sVal string) {
 	if sVal == "1" {
 		s.SkipConstraintCheck = true
@@ -300,9 +426,19 @@ func (s *SessionVars) GetSystemVar(key string) types.Datum {
 	if ok {
 		d.SetString(sVal)
 	} else {
-		// TiDBSkipConstraintCheck is a session scope vars. We do not store it in the global table.
-		if key == TiDBSkipConstraintCheck {
-			d.SetString(SysVars[TiDBSkipConstraintCheck].Value)
+		// TiDBSkipConstraintCheck, TiDBOptDisableRules, TiDBPrepareExcludedDDL, the ANALYZE limits, the join and
+		// aggregation options, TiDBApplyCacheCapacity, the memory quotas, the plan guards, TiDBPlanBaseline and the
+		// DML batch options are session scope vars.
+		// We do not store them in the global table.
+		switch key {
+		case TiDBSkipConstraintCheck, TiDBOptDisableRules, TiDBAnalyzeMaxExecutionTime, TiDBAnalyzeMaxCopLatency,
+			TiDBAnalyzeMaxCopPending, TiDBPrepareExcludedDDL, TiDBIndexJoinBatchSize, TiDBIndexLookUpJoinConcurrency,
+			TiDBHashJoinConcurrency, TiDBHashAggConcurrency, TiDBHashJoinRuntimeFilter, TiDBApplyCacheCapacity,
+			TiDBMemQuotaSort, TiDBMemQuotaQuery, TiDBMaxChunkSize, TiDBCartesianJoin, TiDBMaxEstimatedRows,
+			TiDBPlanBaseline, TiDBDMLBatchSize, TiDBBatchInsert, TiDBBatchDelete, TiDBDDLShadowCopy,
+			TiDBDDLShadowCopyThrottle, TiDBMaxStatementSize, TiDBMaxStatementParams, TiDBStreamInsertSize,
+			TiDBCompatVersion, TiDBUnionConcurrency, TiDBIndexLookupConcurrency, TiDBIndexLookupSize:
+			d.SetString(SysVars[key].Value)
 		}
 	}
 	return d
@@ -56,8 +56,13 @@ func (*testSessionSuite) TestSession(c *C) {
 	val = v.GetSystemVar("sql_mode")
 	c.Assert(val.GetString(), Equals, "STRICT_TRANS_TABLES")
 	c.Assert(v.StrictSQLMode, IsTrue)
+	c.Assert(v.OnlyFullGroupBy, IsFalse)
+	v.SetSystemVar("sql_mode", types.NewStringDatum("only_full_group_by,strict_trans_tables"))
+	c.Assert(v.StrictSQLMode, IsTrue)
+	c.Assert(v.OnlyFullGroupBy, IsTrue)
 	v.SetSystemVar("sql_mode", types.NewStringDatum(""))
 	c.Assert(v.StrictSQLMode, IsFalse)
+	c.Assert(v.OnlyFullGroupBy, IsFalse)
 
 	v.SetSystemVar("character_set_connection", types.NewStringDatum("utf8"))
 	v.SetSystemVar("collation_connection", types.NewStringDatum("utf8_general_ci"))
@@ -84,4 +89,12 @@ func (*testSessionSuite) TestSession(c *C) {
 	v.SetSystemVar(variable.TiDBSkipConstraintCheck, types.NewStringDatum("1"))
 	d = v.GetSystemVar(variable.TiDBSkipConstraintCheck)
 	c.Assert(d.GetString(), Equals, "1")
+
+	// Test case for tidb_opt_disable_rules session variable.
+	d = v.GetSystemVar(variable.TiDBOptDisableRules)
+	c.Assert(d.GetString(), Equals, "")
+	v.SetSystemVar(variable.TiDBOptDisableRules, types.NewStringDatum("join_reorder,agg_pushdown"))
+	rules, err := v.GetTiDBSystemVar(variable.TiDBOptDisableRules)
+	c.Assert(err, IsNil)
+	c.Assert(rules, Equals, "join_reorder,agg_pushdown")
 }
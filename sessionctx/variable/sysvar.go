@@ -57,6 +57,7 @@ func GetSysVar(name string) *SysVar {
 const (
 	CodeUnknownStatusVar terror.ErrCode = 1
 	CodeUnknownSystemVar terror.ErrCode = 1193
+	CodeWrongValueForVar terror.ErrCode = 1231
 )
 
 var tidbSysVars map[string]bool
@@ -65,6 +66,8 @@ var tidbSysVars map[string]bool
 var (
 	UnknownStatusVar = terror.ClassVariable.New(CodeUnknownStatusVar, "unknown status variable")
 	UnknownSystemVar = terror.ClassVariable.New(CodeUnknownSystemVar, "unknown system variable")
+	// ErrWrongValueForVar is returned when a variable is set to an invalid value.
+	ErrWrongValueForVar = terror.ClassVariable.New(CodeWrongValueForVar, "wrong value for variable")
 )
 
 func init() {
@@ -84,8 +87,44 @@ func init() {
 	tidbSysVars[DistSQLJoinConcurrencyVar] = true
 	tidbSysVars[TiDBSnapshot] = true
 	tidbSysVars[TiDBSkipConstraintCheck] = true
+	tidbSysVars[TiDBOptDisableRules] = true
+	tidbSysVars[TiDBAnalyzeMaxExecutionTime] = true
+	tidbSysVars[TiDBAnalyzeMaxCopLatency] = true
+	tidbSysVars[TiDBAnalyzeMaxCopPending] = true
+	tidbSysVars[TiDBPrepareExcludedDDL] = true
+	tidbSysVars[TiDBIndexJoinBatchSize] = true
+	tidbSysVars[TiDBIndexLookUpJoinConcurrency] = true
+	tidbSysVars[TiDBHashJoinConcurrency] = true
+	tidbSysVars[TiDBHashAggConcurrency] = true
+	tidbSysVars[TiDBHashJoinRuntimeFilter] = true
+	tidbSysVars[TiDBApplyCacheCapacity] = true
+	tidbSysVars[TiDBMemQuotaSort] = true
+	tidbSysVars[TiDBMemQuotaQuery] = true
+	tidbSysVars[TiDBMaxChunkSize] = true
+	tidbSysVars[TiDBCartesianJoin] = true
+	tidbSysVars[TiDBMaxEstimatedRows] = true
+	tidbSysVars[TiDBPlanBaseline] = true
+	tidbSysVars[TiDBOptScanFactor] = true
+	tidbSysVars[TiDBOptNetworkFactor] = true
+	tidbSysVars[TiDBOptSeekFactor] = true
+	tidbSysVars[TiDBOptCPUFactor] = true
+	tidbSysVars[TiDBDMLBatchSize] = true
+	tidbSysVars[TiDBBatchInsert] = true
+	tidbSysVars[TiDBBatchDelete] = true
+	tidbSysVars[TiDBDDLShadowCopy] = true
+	tidbSysVars[TiDBDDLShadowCopyThrottle] = true
+	tidbSysVars[TiDBMaxStatementSize] = true
+	tidbSysVars[TiDBMaxStatementParams] = true
+	tidbSysVars[TiDBStreamInsertSize] = true
+	tidbSysVars[TiDBCompatVersion] = true
+	tidbSysVars[TiDBUnionConcurrency] = true
+	tidbSysVars[TiDBIndexLookupConcurrency] = true
+	tidbSysVars[TiDBIndexLookupSize] = true
 }
 
+// LowerCaseTableNames is the name of the variable which shows the lower_case_table_names mode of the server.
+const LowerCaseTableNames = "lower_case_table_names"
+
 // we only support MySQL now
 var defaultSysVars = []*SysVar{
 	{ScopeGlobal, "gtid_mode", "OFF"},
@@ -330,7 +369,7 @@ var defaultSysVars = []*SysVar{
 	{ScopeNone, "port", "3306"},
 	{ScopeNone, "performance_schema_digests_size", "10000"},
 	{ScopeGlobal | ScopeSession, "profiling", "OFF"},
-	{ScopeNone, "lower_case_table_names", "2"},
+	{ScopeNone, LowerCaseTableNames, "2"},
 	{ScopeSession, "rand_seed1", ""},
 	{ScopeGlobal, "sha256_password_proxy_users", ""},
 	{ScopeGlobal | ScopeSession, "sql_quote_show_create", "ON"},
@@ -591,6 +630,39 @@ var defaultSysVars = []*SysVar{
 	{ScopeGlobal | ScopeSession, DistSQLScanConcurrencyVar, "10"},
 	{ScopeGlobal | ScopeSession, DistSQLJoinConcurrencyVar, "5"},
 	{ScopeSession, TiDBSkipConstraintCheck, "0"},
+	{ScopeSession, TiDBOptDisableRules, ""},
+	{ScopeSession, TiDBAnalyzeMaxExecutionTime, "0"},
+	{ScopeSession, TiDBAnalyzeMaxCopLatency, "0"},
+	{ScopeSession, TiDBAnalyzeMaxCopPending, "0"},
+	{ScopeSession, TiDBPrepareExcludedDDL, "create_database,drop_database,create_table,drop_table,create_index,drop_index,alter_table,truncate_table"},
+	{ScopeSession, TiDBIndexJoinBatchSize, "1024"},
+	{ScopeSession, TiDBIndexLookUpJoinConcurrency, "4"},
+	{ScopeSession, TiDBHashJoinConcurrency, "0"},
+	{ScopeSession, TiDBHashAggConcurrency, "1"},
+	{ScopeSession, TiDBHashJoinRuntimeFilter, "1024"},
+	{ScopeSession, TiDBApplyCacheCapacity, "33554432"},
+	{ScopeSession, TiDBMemQuotaSort, "1073741824"},
+	{ScopeSession, TiDBMemQuotaQuery, "34359738368"},
+	{ScopeSession, TiDBMaxChunkSize, "1024"},
+	{ScopeSession, TiDBCartesianJoin, "allow"},
+	{ScopeSession, TiDBMaxEstimatedRows, "0"},
+	{ScopeSession, TiDBPlanBaseline, "0"},
+	{ScopeGlobal | ScopeSession, TiDBOptScanFactor, "0.5"},
+	{ScopeGlobal | ScopeSession, TiDBOptNetworkFactor, "1"},
+	{ScopeGlobal | ScopeSession, TiDBOptSeekFactor, "10"},
+	{ScopeGlobal | ScopeSession, TiDBOptCPUFactor, "0.9"},
+	{ScopeSession, TiDBDMLBatchSize, "20000"},
+	{ScopeSession, TiDBBatchInsert, "0"},
+	{ScopeSession, TiDBBatchDelete, "0"},
+	{ScopeSession, TiDBDDLShadowCopy, "0"},
+	{ScopeSession, TiDBDDLShadowCopyThrottle, "0"},
+	{ScopeSession, TiDBMaxStatementSize, "67108864"},
+	{ScopeSession, TiDBMaxStatementParams, "65535"},
+	{ScopeSession, TiDBStreamInsertSize, "1048576"},
+	{ScopeSession, TiDBCompatVersion, ""},
+	{ScopeSession, TiDBUnionConcurrency, "4"},
+	{ScopeSession, TiDBIndexLookupConcurrency, "4"},
+	{ScopeSession, TiDBIndexLookupSize, "20480"},
 }
 
 // TiDB system variables
@@ -599,6 +671,105 @@ const (
 	DistSQLScanConcurrencyVar = "tidb_distsql_scan_concurrency"
 	DistSQLJoinConcurrencyVar = "tidb_distsql_join_concurrency"
 	TiDBSkipConstraintCheck   = "tidb_skip_constraint_check"
+	TiDBOptDisableRules       = "tidb_opt_disable_rules"
+	// TiDBAnalyzeMaxExecutionTime is the time limit of ANALYZE TABLE in milliseconds, 0 means no limit.
+	// An ANALYZE exceeding the limit is interrupted, and the next ANALYZE of the table resumes from where it stopped.
+	TiDBAnalyzeMaxExecutionTime = "tidb_analyze_max_execution_time"
+	// TiDBAnalyzeMaxCopLatency pauses ANALYZE TABLE while the average coprocessor latency exceeds it in milliseconds.
+	TiDBAnalyzeMaxCopLatency = "tidb_analyze_max_cop_latency"
+	// TiDBAnalyzeMaxCopPending pauses ANALYZE TABLE while the coprocessor requests in flight exceed it.
+	TiDBAnalyzeMaxCopPending = "tidb_analyze_max_cop_pending"
+	// TiDBPrepareExcludedDDL is the comma separated list of the DDL statements which can't be prepared,
+	// e.g. "create_table,drop_table". All the DDL statements are excluded by default, and the DDL statements
+	// which can't be listed, e.g. CREATE VIEW, can never be prepared.
+	TiDBPrepareExcludedDDL = "tidb_prepare_excluded_ddl"
+	// TiDBIndexJoinBatchSize is the number of the outer rows whose inner rows are looked up together by the index
+	// nested loop join.
+	TiDBIndexJoinBatchSize = "tidb_index_join_batch_size"
+	// TiDBIndexLookUpJoinConcurrency is the number of the workers which look up the inner rows for the index nested
+	// loop join concurrently.
+	TiDBIndexLookUpJoinConcurrency = "tidb_index_lookup_join_concurrency"
+	// TiDBHashJoinConcurrency is the number of the workers which probe the hash table of the hash join concurrently,
+	// 0 means the -join-concurrency of tidb-server.
+	TiDBHashJoinConcurrency = "tidb_hash_join_concurrency"
+	// TiDBHashAggConcurrency is the number of the workers of the hash aggregation with group by, the groups are
+	// output in no particular order if it's greater than 1.
+	TiDBHashAggConcurrency = "tidb_hash_agg_concurrency"
+	// TiDBHashJoinRuntimeFilter is the max number of the distinct join keys of the small table of a hash join
+	// which filter the scan of the big table as an IN list, the min and max keys filter the scan if it's exceeded,
+	// 0 disables the filtering.
+	TiDBHashJoinRuntimeFilter = "tidb_hash_join_runtime_filter"
+	// TiDBApplyCacheCapacity is the max memory in bytes of the inner results memoized by each correlated
+	// subquery, 0 disables the memoization.
+	TiDBApplyCacheCapacity = "tidb_apply_cache_capacity"
+	// TiDBMemQuotaSort is the max memory in bytes of the rows buffered by each sort, the rows are sorted and
+	// spilled to a temporary file when it's exceeded, 0 disables the spilling.
+	TiDBMemQuotaSort = "tidb_mem_quota_sort"
+	// TiDBMemQuotaQuery is the max memory in bytes of the executors of each statement, the statement fails when
+	// it's exceeded unless the executors can spill to temporary files, 0 means no limit.
+	TiDBMemQuotaQuery = "tidb_mem_quota_query"
+	// TiDBMaxChunkSize is the max number of the rows the executors pass to each other in a chunk, 0 passes the
+	// rows one by one.
+	TiDBMaxChunkSize = "tidb_max_chunk_size"
+	// TiDBCartesianJoin decides what to do with the joins without any equal condition, "allow" plans them,
+	// "warn" plans them and logs a warning, "reject" fails the statement.
+	TiDBCartesianJoin = "tidb_cartesian_join"
+	// TiDBMaxEstimatedRows fails the statement if the estimated row count of any operator in its plan exceeds it,
+	// 0 means no limit.
+	TiDBMaxEstimatedRows = "tidb_max_estimated_rows"
+	// TiDBPlanBaseline enables the plan baselines of the SELECT statements. The plans of the frequent statements
+	// are captured as their baselines, and a statement falls back to its baseline plan whenever it's planned by
+	// a new plan which ran drastically slower.
+	TiDBPlanBaseline = "tidb_plan_baseline"
+	// TiDBOptScanFactor is the cost of reading a row in TiKV, the cost factors are relative to each other, so the
+	// optimizer can be recalibrated for the hardware, e.g. a lower scan factor for an all-flash TiKV cluster.
+	TiDBOptScanFactor = "tidb_opt_scan_factor"
+	// TiDBOptNetworkFactor is the cost of sending a row from TiKV to TiDB.
+	TiDBOptNetworkFactor = "tidb_opt_network_factor"
+	// TiDBOptSeekFactor is the cost of looking up the inner rows of an outer row by the index nested loop join.
+	TiDBOptSeekFactor = "tidb_opt_seek_factor"
+	// TiDBOptCPUFactor is the cost of processing a row in TiDB, e.g. sorting or aggregating it.
+	TiDBOptCPUFactor = "tidb_opt_cpu_factor"
+	// TiDBDMLBatchSize is the number of the rows LOAD DATA, and INSERT and DELETE with TiDBBatchInsert and
+	// TiDBBatchDelete, write in each transaction, the rows are committed every time it's reached, 0 writes all the
+	// rows in a single transaction.
+	TiDBDMLBatchSize = "tidb_dml_batch_size"
+	// TiDBBatchInsert splits the rows of an INSERT statement into the transactions of TiDBDMLBatchSize rows, so a
+	// huge INSERT doesn't exceed the size limit of a transaction. The statement isn't atomic then, the committed
+	// rows are kept if it fails. The rows are inserted in a single transaction in an explicit transaction.
+	TiDBBatchInsert = "tidb_batch_insert"
+	// TiDBBatchDelete splits the rows of a DELETE statement into the transactions of TiDBDMLBatchSize rows like
+	// TiDBBatchInsert.
+	TiDBBatchDelete = "tidb_batch_delete"
+	// TiDBDDLShadowCopy makes MODIFY COLUMN copy the table into a shadow table with the new column type if the
+	// column can't be changed in place. The rows written during the copy are written to the shadow table as well,
+	// and the shadow table replaces the table when the copy is done.
+	TiDBDDLShadowCopy = "tidb_ddl_shadow_copy"
+	// TiDBDDLShadowCopyThrottle is the milliseconds the shadow copy pauses after each transaction of the copied rows,
+	// so the copy of a huge table doesn't take up the storage.
+	TiDBDDLShadowCopyThrottle = "tidb_ddl_shadow_copy_throttle"
+	// TiDBMaxStatementSize is the max size in bytes of the text of the statements executed or prepared at once,
+	// 0 means no limit.
+	TiDBMaxStatementSize = "tidb_max_statement_size"
+	// TiDBMaxStatementParams is the max number of the placeholders of a prepared statement, 0 means no limit.
+	TiDBMaxStatementParams = "tidb_max_statement_params"
+	// TiDBStreamInsertSize is the size in bytes of the INSERT and REPLACE ... VALUES statements whose rows are parsed,
+	// evaluated and written chunk by chunk, so the AST of all the rows of a huge statement isn't materialized at once.
+	// 0 disables it.
+	TiDBStreamInsertSize = "tidb_stream_insert_size"
+	// TiDBCompatVersion is the MySQL version whose behavior is emulated by the builtin functions which changed between
+	// the MySQL versions, "5.6", "5.7" or "8.0". The empty value keeps the TiDB behavior. See evaluator/compat.go for
+	// the affected functions.
+	TiDBCompatVersion = "tidb_compat_version"
+	// TiDBUnionConcurrency is the max number of the children of a UNION executed concurrently, the rows are still
+	// returned in the order of the children. 1 executes them one by one.
+	TiDBUnionConcurrency = "tidb_union_concurrency"
+	// TiDBIndexLookupConcurrency is the number of the workers which read the table rows of the handles read from
+	// an index concurrently, when the index doesn't cover all the needed columns.
+	TiDBIndexLookupConcurrency = "tidb_index_lookup_concurrency"
+	// TiDBIndexLookupSize is the max number of the handles whose table rows are read by a request of an index
+	// lookup worker.
+	TiDBIndexLookupSize = "tidb_index_lookup_size"
 )
 
 // SetNamesVariables is the system variable names related to set names statements.
@@ -14,6 +14,7 @@
 package localstore
 
 import (
+	"bytes"
 	"net/url"
 	"path/filepath"
 	"runtime/debug"
@@ -24,12 +25,15 @@ import (
 	"github.com/ngaut/log"
 	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/store/localstore/engine"
+	"github.com/pingcap/tidb/terror"
+	"github.com/pingcap/tidb/util/codec"
 	"github.com/pingcap/tidb/util/segmentmap"
 	"github.com/twinj/uuid"
 )
 
 var (
-	_ kv.Storage = (*dbStore)(nil)
+	_ kv.Storage       = (*dbStore)(nil)
+	_ kv.HistoryReader = (*dbStore)(nil)
 )
 
 const (
@@ -354,6 +358,50 @@ func (s *dbStore) CurrentVersion() (kv.Version, error) {
 	return globalVersionProvider.CurrentVersion()
 }
 
+// GetHistory implements the kv.HistoryReader interface.
+func (s *dbStore) GetHistory(key kv.Key, ver kv.Version) ([]kv.KeyVersion, error) {
+	currentVer, err := globalVersionProvider.CurrentVersion()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if ver.Cmp(currentVer) > 0 {
+		ver = currentVer
+	}
+	// The versions of a key are encoded in descending order, see dbSnapshot.mvccSeek.
+	var versions []kv.KeyVersion
+	prefix := codec.EncodeBytes(nil, key)
+	for {
+		mvccK, v, err := s.Seek([]byte(MvccEncodeVersionKey(key, ver)), ver.Ver)
+		if terror.ErrorEqual(err, engine.ErrNotFound) {
+			break
+		}
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		// The keys after the versions of the key may not be encoded by MvccEncodeVersionKey.
+		if !bytes.HasPrefix(mvccK, prefix) {
+			break
+		}
+		k, kver, err := MvccDecode(mvccK)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if key.Cmp(k) != 0 {
+			break
+		}
+		version := kv.KeyVersion{Version: kver}
+		if !isTombstone(v) {
+			version.Value = append([]byte(nil), v...)
+		}
+		versions = append(versions, version)
+		if kver.Ver == 0 {
+			break
+		}
+		ver = kv.Version{Ver: kver.Ver - 1}
+	}
+	return versions, nil
+}
+
 // Begin transaction
 func (s *dbStore) Begin() (kv.Transaction, error) {
 	s.mu.RLock()
@@ -16,6 +16,7 @@ type dbClient struct {
 func (c *dbClient) Send(req *kv.Request) kv.Response {
 	it := &response{
 		client:      c,
+		req:         req,
 		concurrency: req.Concurrency,
 	}
 	it.tasks = buildRegionTasks(c, req)
@@ -45,6 +46,8 @@ func (c *dbClient) SupportRequestType(reqType, subType int64) bool {
 		default:
 			return supportExpr(tipb.ExprType(subType))
 		}
+	case kv.ReqTypeHistory:
+		return true
 	}
 	return false
 }
@@ -82,6 +85,9 @@ func supportExpr(exprType tipb.ExprType) bool {
 	// other functions
 	case tipb.ExprType_Coalesce, tipb.ExprType_IsNull:
 		return true
+	// time functions
+	case tipb.ExprType_DateAdd, tipb.ExprType_DateSub:
+		return true
 	case kv.ReqSubTypeDesc:
 		return true
 	default:
@@ -117,6 +123,7 @@ func (r *localResponseReader) Close() error {
 
 type response struct {
 	client      *dbClient
+	req         *kv.Request
 	reqSent     int
 	respGot     int
 	concurrency int
@@ -137,6 +144,10 @@ func (it *response) Next() (resp io.ReadCloser, err error) {
 	if it.finished {
 		return nil, nil
 	}
+	if it.req.IsKilled() {
+		it.Close()
+		return nil, errors.Trace(kv.ErrQueryInterrupted)
+	}
 	var regionResp *regionResponse
 	select {
 	case regionResp = <-it.respChan:
@@ -194,6 +194,41 @@ func (t *testMvccSuite) TestSnapshotGet(c *C) {
 	c.Assert(err, NotNil)
 }
 
+func (t *testMvccSuite) TestGetHistory(c *C) {
+	lastVer, err := globalVersionProvider.CurrentVersion()
+	c.Assert(err, IsNil)
+	tx, _ := t.s.Begin()
+	err = tx.Delete(encodeInt(1))
+	c.Assert(err, IsNil)
+	err = tx.Commit()
+	c.Assert(err, IsNil)
+	tx, _ = t.s.Begin()
+	err = tx.Set(encodeInt(1), []byte("new"))
+	c.Assert(err, IsNil)
+	err = tx.Commit()
+	c.Assert(err, IsNil)
+
+	reader := t.s.(kv.HistoryReader)
+	versions, err := reader.GetHistory(encodeInt(1), kv.MaxVersion)
+	c.Assert(err, IsNil)
+	c.Assert(versions, HasLen, 3)
+	c.Assert(string(versions[0].Value), Equals, "new")
+	c.Assert(versions[1].Value, IsNil)
+	c.Assert(versions[2].Value, DeepEquals, encodeInt(1))
+	c.Assert(versions[0].Version.Cmp(versions[1].Version), Greater, 0)
+	c.Assert(versions[1].Version.Cmp(versions[2].Version), Greater, 0)
+
+	// The versions committed after the version are not read.
+	versions, err = reader.GetHistory(encodeInt(1), lastVer)
+	c.Assert(err, IsNil)
+	c.Assert(versions, HasLen, 1)
+	c.Assert(versions[0].Value, DeepEquals, encodeInt(1))
+
+	versions, err = reader.GetHistory(encodeInt(1024), kv.MaxVersion)
+	c.Assert(err, IsNil)
+	c.Assert(versions, HasLen, 0)
+}
+
 func (t *testMvccSuite) getSnapshot(c *C, ver kv.Version) *dbSnapshot {
 	snapshot, err := t.s.GetSnapshot(ver)
 	c.Assert(err, IsNil)
@@ -54,6 +54,10 @@ func (txn *dbTxn) Get(k kv.Key) ([]byte, error) {
 	return txn.us.Get(k)
 }
 
+func (txn *dbTxn) BatchGet(keys []kv.Key) (map[string][]byte, error) {
+	return txn.us.BatchGet(keys)
+}
+
 func (txn *dbTxn) Set(k kv.Key, data []byte) error {
 	txn.dirty = true
 	return txn.us.Set(k, data)
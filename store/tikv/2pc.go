@@ -261,7 +261,7 @@ func (c *twoPhaseCommitter) prewriteSingleBatch(bo *Backoffer, batch batchKeys)
 	}
 
 	for {
-		resp, err := c.store.SendKVReq(bo, req, batch.region, readTimeoutShort)
+		resp, err := c.store.SendKVReq(bo, req, batch.region)
 		if err != nil {
 			return errors.Trace(err)
 		}
@@ -317,7 +317,7 @@ func (c *twoPhaseCommitter) commitSingleBatch(bo *Backoffer, batch batchKeys) er
 		},
 	}
 
-	resp, err := c.store.SendKVReq(bo, req, batch.region, readTimeoutShort)
+	resp, err := c.store.SendKVReq(bo, req, batch.region)
 	if err != nil {
 		return errors.Trace(err)
 	}
@@ -365,7 +365,7 @@ func (c *twoPhaseCommitter) cleanupSingleBatch(bo *Backoffer, batch batchKeys) e
 			StartVersion: c.startTS,
 		},
 	}
-	resp, err := c.store.SendKVReq(bo, req, batch.region, readTimeoutShort)
+	resp, err := c.store.SendKVReq(bo, req, batch.region)
 	if err != nil {
 		return errors.Trace(err)
 	}
@@ -112,6 +112,7 @@ func (t backoffType) String() string {
 const (
 	copBuildTaskMaxBackoff  = 5000
 	tsoMaxBackoff           = 5000
+	splitKeysMaxBackoff     = 5000
 	scannerNextMaxBackoff   = 5000
 	batchGetMaxBackoff      = 10000
 	copNextMaxBackoff       = 10000
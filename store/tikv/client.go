@@ -24,6 +24,7 @@ import (
 	"github.com/pingcap/kvproto/pkg/kvrpcpb"
 	"github.com/pingcap/kvproto/pkg/msgpb"
 	"github.com/pingcap/kvproto/pkg/util"
+	"github.com/pingcap/tipb/go-tipb"
 )
 
 // Client is a client that sends RPC.
@@ -35,6 +36,16 @@ type Client interface {
 	SendKVReq(addr string, req *kvrpcpb.Request, timeout time.Duration) (*kvrpcpb.Response, error)
 	// SendCopReq sends coprocessor request.
 	SendCopReq(addr string, req *coprocessor.Request, timeout time.Duration) (*coprocessor.Response, error)
+	// SendCopReqs sends coprocessor requests to the same store in a batch, the responses are in the order of
+	// the requests.
+	SendCopReqs(addr string, reqs []*coprocessor.Request, timeout time.Duration) ([]*coprocessor.Response, error)
+}
+
+// ExprSupporter is implemented by the Clients whose stores evaluate more expressions in the coprocessor than the
+// ones every TiKV version does, like the control functions. The other expressions are evaluated by TiDB.
+type ExprSupporter interface {
+	// SupportExpr checks whether the coprocessor of the stores evaluates exprType.
+	SupportExpr(exprType tipb.ExprType) bool
 }
 
 const (
@@ -86,6 +97,40 @@ func (c *rpcClient) SendCopReq(addr string, req *coprocessor.Request, timeout ti
 	return msg.GetCopResp(), nil
 }
 
+// SendCopReqs writes the Requests to co-processor on a connection at once and receives the Responses, so the
+// requests to the regions on the same store share a network round trip.
+func (c *rpcClient) SendCopReqs(addr string, reqs []*coprocessor.Request, timeout time.Duration) ([]*coprocessor.Response, error) {
+	start := time.Now()
+	defer func() { sendReqHistogram.WithLabelValues("cop_batch").Observe(time.Since(start).Seconds()) }()
+
+	conn, err := c.p.GetConn(addr)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	defer c.p.PutConn(conn)
+	msgs := make([]*msgpb.Message, 0, len(reqs))
+	for _, req := range reqs {
+		msgs = append(msgs, &msgpb.Message{
+			MsgType: msgpb.MessageType_CopReq,
+			CopReq:  req,
+		})
+	}
+	msgs, err = c.doSendBatch(conn, msgs, writeTimeout, timeout)
+	if err != nil {
+		conn.Close()
+		return nil, errors.Trace(err)
+	}
+	resps := make([]*coprocessor.Response, 0, len(msgs))
+	for _, msg := range msgs {
+		if msg.GetMsgType() != msgpb.MessageType_CopResp || msg.GetCopResp() == nil {
+			conn.Close()
+			return nil, errors.Trace(errInvalidResponse)
+		}
+		resps = append(resps, msg.GetCopResp())
+	}
+	return resps, nil
+}
+
 // SendKVReq sends a Request to kv server and receives Response.
 func (c *rpcClient) SendKVReq(addr string, req *kvrpcpb.Request, timeout time.Duration) (*kvrpcpb.Response, error) {
 	start := time.Now()
@@ -133,6 +178,40 @@ func (c *rpcClient) doSend(conn *Conn, msg *msgpb.Message, writeTimeout time.Dur
 	return nil
 }
 
+// doSendBatch writes all the messages before reading any response. The responses may arrive in any order, they are
+// matched with the messages by the message IDs.
+func (c *rpcClient) doSendBatch(conn *Conn, msgs []*msgpb.Message, writeTimeout time.Duration, readTimeout time.Duration) ([]*msgpb.Message, error) {
+	idx := make(map[uint64]int, len(msgs))
+	conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	for i, msg := range msgs {
+		msgID := atomic.AddUint64(&c.msgID, 1)
+		idx[msgID] = i
+		if err := util.WriteMessage(conn, msgID, msg); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	if err := conn.Flush(); err != nil {
+		return nil, errors.Trace(err)
+	}
+	conn.SetReadDeadline(time.Now().Add(readTimeout))
+	resps := make([]*msgpb.Message, len(msgs))
+	for range msgs {
+		resp := new(msgpb.Message)
+		msgID, err := util.ReadMessage(conn.BufioReader(), resp)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		i, ok := idx[msgID]
+		if !ok {
+			log.Errorf("Recv unexpected msgID[%d]", msgID)
+			return nil, errors.Trace(errInvalidResponse)
+		}
+		delete(idx, msgID)
+		resps[i] = resp
+	}
+	return resps, nil
+}
+
 func (c *rpcClient) Close() error {
 	c.p.Close()
 	return nil
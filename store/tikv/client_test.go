@@ -19,6 +19,7 @@ import (
 
 	"github.com/ngaut/log"
 	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/coprocessor"
 	pb "github.com/pingcap/kvproto/pkg/kvrpcpb"
 	"github.com/pingcap/kvproto/pkg/msgpb"
 	"github.com/pingcap/kvproto/pkg/util"
@@ -145,6 +146,46 @@ func (s *testClientSuite) TestWrongMessageID(c *C) {
 	c.Assert(resp.GetType(), Equals, req.GetType())
 }
 
+// The responses of a batch are matched with the requests even if they are sent back in the reverse order.
+func (s *testClientSuite) TestSendCopReqs(c *C) {
+	l := startServer(":61238", c, func(conn net.Conn, c *C) {
+		defer conn.Close()
+		var (
+			msgIDs []uint64
+			datas  [][]byte
+		)
+		for i := 0; i < 3; i++ {
+			var msg msgpb.Message
+			msgID, err := util.ReadMessage(conn, &msg)
+			c.Assert(err, IsNil)
+			c.Assert(msg.GetMsgType(), Equals, msgpb.MessageType_CopReq)
+			msgIDs = append(msgIDs, msgID)
+			datas = append(datas, msg.GetCopReq().GetData())
+		}
+		for i := len(msgIDs) - 1; i >= 0; i-- {
+			resp := msgpb.Message{
+				MsgType: msgpb.MessageType_CopResp,
+				CopResp: &coprocessor.Response{Data: datas[i]},
+			}
+			err := util.WriteMessage(conn, msgIDs[i], &resp)
+			c.Assert(err, IsNil)
+		}
+	})
+	defer l.Close()
+	cli := newRPCClient()
+	reqs := []*coprocessor.Request{
+		{Data: []byte("a")},
+		{Data: []byte("b")},
+		{Data: []byte("c")},
+	}
+	resps, err := cli.SendCopReqs(":61238", reqs, readTimeoutShort)
+	c.Assert(err, IsNil)
+	c.Assert(resps, HasLen, len(reqs))
+	for i, resp := range resps {
+		c.Assert(resp.GetData(), DeepEquals, reqs[i].GetData())
+	}
+}
+
 func startServer(host string, c *C, handleFunc func(net.Conn, *C)) net.Listener {
 	l, err := net.Listen("tcp", host)
 	c.Assert(err, IsNil)
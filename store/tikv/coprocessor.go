@@ -19,6 +19,7 @@ import (
 	"io"
 	"io/ioutil"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/juju/errors"
@@ -34,21 +35,58 @@ type CopClient struct {
 	store *tikvStore
 }
 
+// Load implements the kv.LoadReporter interface.
+func (c *CopClient) Load() (time.Duration, int64) {
+	return c.store.copLoad.get()
+}
+
+// copLoad tracks the load of the coprocessor requests sent by a store.
+type copLoad struct {
+	// latency is the moving average of the request latency in nanoseconds.
+	latency int64
+	// pending is the number of requests in flight.
+	pending int64
+}
+
+func (l *copLoad) begin() time.Time {
+	atomic.AddInt64(&l.pending, 1)
+	return time.Now()
+}
+
+func (l *copLoad) end(start time.Time) {
+	atomic.AddInt64(&l.pending, -1)
+	d := int64(time.Since(start))
+	for {
+		old := atomic.LoadInt64(&l.latency)
+		// The weight of the latest request is 1/8, so a single slow request doesn't make the store look busy.
+		if atomic.CompareAndSwapInt64(&l.latency, old, old+(d-old)/8) {
+			return
+		}
+	}
+}
+
+func (l *copLoad) get() (time.Duration, int64) {
+	return time.Duration(atomic.LoadInt64(&l.latency)), atomic.LoadInt64(&l.pending)
+}
+
 // SupportRequestType checks whether reqType is supported.
 func (c *CopClient) SupportRequestType(reqType, subType int64) bool {
 	switch reqType {
 	case kv.ReqTypeSelect, kv.ReqTypeIndex:
 		switch subType {
-		case kv.ReqSubTypeGroupBy, kv.ReqSubTypeBasic:
+		case kv.ReqSubTypeGroupBy, kv.ReqSubTypeBasic, kv.ReqSubTypeTopN:
 			return true
 		default:
-			return supportExpr(tipb.ExprType(subType))
+			return c.supportExpr(tipb.ExprType(subType))
 		}
+	case kv.ReqTypeHistory:
+		_, ok := c.store.historyReader()
+		return ok
 	}
 	return false
 }
 
-func supportExpr(exprType tipb.ExprType) bool {
+func (c *CopClient) supportExpr(exprType tipb.ExprType) bool {
 	switch exprType {
 	case tipb.ExprType_Null, tipb.ExprType_Int64, tipb.ExprType_Uint64, tipb.ExprType_String, tipb.ExprType_Bytes,
 		tipb.ExprType_MysqlDuration, tipb.ExprType_MysqlTime, tipb.ExprType_MysqlDecimal,
@@ -63,6 +101,10 @@ func supportExpr(exprType tipb.ExprType) bool {
 		return true
 	case tipb.ExprType_Count, tipb.ExprType_First, tipb.ExprType_Max, tipb.ExprType_Min, tipb.ExprType_Sum, tipb.ExprType_Avg:
 		return true
+	case tipb.ExprType_Case, tipb.ExprType_If, tipb.ExprType_IfNull, tipb.ExprType_Coalesce:
+		// The stores of older versions can't evaluate the control functions, which are evaluated by TiDB then.
+		supporter, ok := c.store.exprSupporter()
+		return ok && supporter.SupportExpr(exprType)
 	case kv.ReqSubTypeDesc:
 		return true
 	default:
@@ -92,6 +134,7 @@ func (c *CopClient) Send(req *kv.Request) kv.Response {
 		// Make sure that there is at least one worker.
 		it.concurrency = 1
 	}
+	it.limiter = newCopLimiter(it.concurrency)
 	if !it.req.KeepOrder {
 		it.respChan = make(chan *coprocessor.Response, it.concurrency)
 	}
@@ -299,41 +342,138 @@ type copIterator struct {
 	}
 	respChan chan *coprocessor.Response
 	errChan  chan error
+	// limiter adapts the number of requests in flight to the latency and the errors of the responses.
+	limiter *copLimiter
 }
 
-// Pick the next new copTask and send request to tikv-server.
+// copMaxBatchSize is the maximum number of the tasks on the same store sent in a batch.
+const copMaxBatchSize = 16
+
+// Pick the next new copTasks and send requests to tikv-server.
 func (it *copIterator) work() {
 	for {
-		it.mu.Lock()
-		if it.mu.finished {
-			it.mu.Unlock()
+		// Stop sending the requests once the statement is killed.
+		if it.req.IsKilled() {
+			it.errChan <- errors.Trace(kv.ErrQueryInterrupted)
 			break
 		}
-		// Find the next task to send.
-		var task *copTask
-		for _, t := range it.mu.tasks {
-			if t.status == taskNew {
-				task = t
-				break
-			}
-		}
-		if task == nil {
-			it.mu.Unlock()
+		tasks := it.pickTasks()
+		if len(tasks) == 0 {
 			break
 		}
-		task.status = taskRunning
-		it.mu.Unlock()
 		bo := NewBackoffer(copNextMaxBackoff, context.Background())
-		resp, err := it.handleTask(bo, task)
+		var err error
+		if len(tasks) == 1 {
+			var resp *coprocessor.Response
+			resp, err = it.handleTask(bo, tasks[0])
+			if err == nil {
+				it.sendResp(tasks[0], resp)
+			}
+		} else {
+			err = it.handleBatch(bo, tasks)
+		}
 		if err != nil {
 			it.errChan <- err
 			break
 		}
-		if !it.req.KeepOrder {
-			it.respChan <- resp
+	}
+}
+
+// pickTasks picks the next new task and marks it running. When there are more new tasks than the workers, e.g.
+// a full table scan over many regions, the following new tasks on the same store are picked together to be sent
+// in a batch, so there are fewer RPCs.
+func (it *copIterator) pickTasks() []*copTask {
+	it.mu.Lock()
+	defer it.mu.Unlock()
+	if it.mu.finished {
+		return nil
+	}
+	var (
+		tasks   []*copTask
+		pending int
+	)
+	for _, t := range it.mu.tasks {
+		if t.status == taskNew {
+			pending++
+		}
+	}
+	batchSize := pending / it.concurrency
+	if batchSize > copMaxBatchSize {
+		batchSize = copMaxBatchSize
+	}
+	for _, t := range it.mu.tasks {
+		if t.status != taskNew {
+			continue
+		}
+		if len(tasks) > 0 && t.region.GetAddress() != tasks[0].region.GetAddress() {
+			continue
+		}
+		t.status = taskRunning
+		tasks = append(tasks, t)
+		if len(tasks) >= batchSize {
+			break
+		}
+	}
+	return tasks
+}
+
+// handleBatch sends the requests of the tasks on the same store in a batch. The tasks failed in the batch, e.g.
+// the region is stale or the rows are locked, are handled one by one to be retried.
+func (it *copIterator) handleBatch(bo *Backoffer, tasks []*copTask) error {
+	coprocessorCounter.WithLabelValues("handle_batch").Inc()
+	reqs := make([]*coprocessor.Request, 0, len(tasks))
+	for _, task := range tasks {
+		reqs = append(reqs, &coprocessor.Request{
+			Context: task.region.GetContext(),
+			Tp:      it.req.Tp,
+			Data:    it.req.Data,
+			Ranges:  task.ranges.toPBRanges(),
+		})
+	}
+	if !it.limiter.acquire() {
+		return nil
+	}
+	start := it.store.copLoad.begin()
+	resps, err := it.store.client.SendCopReqs(tasks[0].region.GetAddress(), reqs, rpcTimeout(rpcCop))
+	it.store.copLoad.end(start)
+	busy := false
+	for _, resp := range resps {
+		if resp.GetRegionError().GetServerIsBusy() != nil {
+			busy = true
+		}
+	}
+	it.limiter.release(time.Since(start), err != nil || busy)
+	if err != nil {
+		log.Warnf("send batch coprocessor request error: %v, send the requests one by one", err)
+	}
+	for i, task := range tasks {
+		it.mu.RLock()
+		finished := it.mu.finished
+		it.mu.RUnlock()
+		if finished {
+			return nil
+		}
+		var resp *coprocessor.Response
+		if err == nil && resps[i].GetRegionError() == nil && resps[i].GetLocked() == nil && resps[i].GetOtherError() == "" {
+			resp = resps[i]
 		} else {
-			task.respChan <- resp
+			var err1 error
+			resp, err1 = it.handleTask(bo, task)
+			if err1 != nil {
+				return errors.Trace(err1)
+			}
 		}
+		it.sendResp(task, resp)
+	}
+	return nil
+}
+
+// sendResp sends the response of the task to Next.
+func (it *copIterator) sendResp(task *copTask, resp *coprocessor.Response) {
+	if !it.req.KeepOrder {
+		it.respChan <- resp
+	} else {
+		task.respChan <- resp
 	}
 }
 
@@ -354,6 +494,10 @@ func (it *copIterator) Next() (io.ReadCloser, error) {
 		return nil, nil
 	}
 	it.mu.RUnlock()
+	if it.req.IsKilled() {
+		it.Close()
+		return nil, errors.Trace(kv.ErrQueryInterrupted)
+	}
 	var (
 		resp *coprocessor.Response
 		err  error
@@ -418,6 +562,10 @@ func (it *copIterator) handleTask(bo *Backoffer, task *copTask) (*coprocessor.Re
 			return nil, nil
 		}
 		it.mu.RUnlock()
+		// The task isn't retried after the statement is killed.
+		if it.req.IsKilled() {
+			return nil, errors.Trace(kv.ErrQueryInterrupted)
+		}
 
 		req := &coprocessor.Request{
 			Context: task.region.GetContext(),
@@ -425,7 +573,13 @@ func (it *copIterator) handleTask(bo *Backoffer, task *copTask) (*coprocessor.Re
 			Data:    it.req.Data,
 			Ranges:  task.ranges.toPBRanges(),
 		}
-		resp, err := it.store.client.SendCopReq(task.region.GetAddress(), req, readTimeoutMedium)
+		if !it.limiter.acquire() {
+			return nil, nil
+		}
+		start := it.store.copLoad.begin()
+		resp, err := it.store.client.SendCopReq(task.region.GetAddress(), req, rpcTimeout(rpcCop))
+		it.store.copLoad.end(start)
+		it.limiter.release(time.Since(start), err != nil || resp.GetRegionError().GetServerIsBusy() != nil)
 		if err != nil {
 			it.store.regionCache.NextPeer(task.region.VerID())
 			err = bo.Backoff(boTiKVRPC, err)
@@ -529,9 +683,116 @@ func (it *copIterator) Close() error {
 	it.mu.Lock()
 	it.mu.finished = true
 	it.mu.Unlock()
+	it.limiter.close()
 	return nil
 }
 
+// copLimiterSlowRatio is how many times slower than the fastest response the responses are considered slow,
+// which means the requests queue up in TiKV.
+const copLimiterSlowRatio = 2
+
+// copLimiterMinLatencyDecay is the weight of a slower response when it raises the min latency, so the min latency
+// follows the latency of the requests of the statement when it shifts, and a single fast response, e.g. of an empty
+// region, doesn't make all the later responses look slow.
+const copLimiterMinLatencyDecay = 16
+
+// copLimiter limits the coprocessor requests in flight of a statement. It starts with the maximum concurrency,
+// halves the limit when a request fails or TiKV is busy, decreases the limit by one when the responses become
+// slow, and increases the limit by one otherwise, so TiKV isn't overwhelmed by the retries during incidents.
+type copLimiter struct {
+	max int
+
+	mu struct {
+		sync.Mutex
+		cond     *sync.Cond
+		limit    int
+		inflight int
+		closed   bool
+		// latency is the moving average of the response latency.
+		latency time.Duration
+		// minLatency is the latency of the fastest response, it decays towards the slower responses.
+		minLatency time.Duration
+	}
+}
+
+func newCopLimiter(max int) *copLimiter {
+	l := &copLimiter{max: max}
+	l.mu.cond = sync.NewCond(&l.mu)
+	l.mu.limit = max
+	return l
+}
+
+// acquire waits until a request can be sent, it returns false if the limiter is closed.
+func (l *copLimiter) acquire() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for !l.mu.closed && l.mu.inflight >= l.mu.limit {
+		l.mu.cond.Wait()
+	}
+	if l.mu.closed {
+		return false
+	}
+	l.mu.inflight++
+	return true
+}
+
+// release records the result of a request and adjusts the limit.
+func (l *copLimiter) release(latency time.Duration, failed bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.mu.inflight--
+	defer l.mu.cond.Broadcast()
+	if failed {
+		l.setLimit(l.mu.limit / 2)
+		return
+	}
+	if l.mu.minLatency == 0 || latency < l.mu.minLatency {
+		l.mu.minLatency = latency
+	} else {
+		l.mu.minLatency += (latency - l.mu.minLatency) / copLimiterMinLatencyDecay
+	}
+	if l.mu.latency == 0 {
+		l.mu.latency = latency
+	} else {
+		// The weight of the latest response is 1/4.
+		l.mu.latency += (latency - l.mu.latency) / 4
+	}
+	if l.mu.latency > l.mu.minLatency*copLimiterSlowRatio {
+		l.setLimit(l.mu.limit - 1)
+	} else {
+		l.setLimit(l.mu.limit + 1)
+	}
+}
+
+func (l *copLimiter) setLimit(limit int) {
+	if limit < 1 {
+		limit = 1
+	}
+	if limit > l.max {
+		limit = l.max
+	}
+	if limit != l.mu.limit {
+		log.Debugf("[coprocessor] change the concurrency limit from %d to %d, latency %v, min latency %v",
+			l.mu.limit, limit, l.mu.latency, l.mu.minLatency)
+	}
+	l.mu.limit = limit
+}
+
+// getLimit returns the current limit.
+func (l *copLimiter) getLimit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.mu.limit
+}
+
+// close wakes up all the waiting requests, which won't be sent any more.
+func (l *copLimiter) close() {
+	l.mu.Lock()
+	l.mu.closed = true
+	l.mu.Unlock()
+	l.mu.cond.Broadcast()
+}
+
 // copErrorResponse returns error when calling Next()
 type copErrorResponse struct{ error }
 
@@ -14,9 +14,19 @@
 package tikv
 
 import (
+	"fmt"
+	"io/ioutil"
+	"sync"
+	"sync/atomic"
+	"time"
+
 	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/coprocessor"
+	"github.com/pingcap/kvproto/pkg/errorpb"
+	"github.com/pingcap/kvproto/pkg/kvrpcpb"
 	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/store/tikv/mock-tikv"
+	"github.com/pingcap/tipb/go-tipb"
 	"golang.org/x/net/context"
 )
 
@@ -188,3 +198,226 @@ func (s *testCoprocessorSuite) checkEqual(c *C, copRanges *copRanges, ranges []k
 		}
 	}
 }
+
+func (s *testCoprocessorSuite) TestCopLoad(c *C) {
+	var l copLoad
+	start := l.begin()
+	_, pending := l.get()
+	c.Assert(pending, Equals, int64(1))
+	l.end(start.Add(-800 * time.Millisecond))
+	latency, pending := l.get()
+	c.Assert(pending, Equals, int64(0))
+	// A single slow request only moves the average by 1/8.
+	c.Assert(latency >= 100*time.Millisecond && latency < 200*time.Millisecond, IsTrue)
+}
+
+func (s *testCoprocessorSuite) TestCopLimiter(c *C) {
+	l := newCopLimiter(8)
+	c.Assert(l.getLimit(), Equals, 8)
+	for i := 0; i < 8; i++ {
+		c.Assert(l.acquire(), IsTrue)
+	}
+	// The 9th request waits until a request finishes.
+	acquired := make(chan bool, 1)
+	go func() {
+		acquired <- l.acquire()
+	}()
+	select {
+	case <-acquired:
+		c.Fatal("the limit is exceeded")
+	case <-time.After(10 * time.Millisecond):
+	}
+	l.release(10*time.Millisecond, false)
+	c.Assert(<-acquired, IsTrue)
+
+	// The limit is halved when a request fails.
+	l.release(10*time.Millisecond, true)
+	c.Assert(l.getLimit(), Equals, 4)
+	// The limit decreases when the responses are slow.
+	for i := 0; i < 7; i++ {
+		l.release(100*time.Millisecond, false)
+	}
+	c.Assert(l.getLimit(), Equals, 1)
+	// The limit increases when the responses are fast again, up to the maximum.
+	for i := 0; i < 20; i++ {
+		c.Assert(l.acquire(), IsTrue)
+		l.release(10*time.Millisecond, false)
+	}
+	c.Assert(l.getLimit(), Equals, 8)
+
+	// The limit recovers when the latency shifts and stays, e.g. the later regions are larger, since the min
+	// latency decays towards the new latency.
+	l = newCopLimiter(8)
+	c.Assert(l.acquire(), IsTrue)
+	l.release(time.Millisecond, false)
+	limits := make([]int, 0, 60)
+	for i := 0; i < 60; i++ {
+		c.Assert(l.acquire(), IsTrue)
+		l.release(50*time.Millisecond, false)
+		limits = append(limits, l.getLimit())
+	}
+	c.Assert(limits[5] < 8, IsTrue, Commentf("limits %v", limits))
+	c.Assert(l.getLimit(), Equals, 8, Commentf("limits %v", limits))
+	// A single fast response doesn't make the later responses slow for long.
+	c.Assert(l.acquire(), IsTrue)
+	l.release(time.Millisecond, false)
+	for i := 0; i < 60; i++ {
+		c.Assert(l.acquire(), IsTrue)
+		l.release(50*time.Millisecond, false)
+	}
+	c.Assert(l.getLimit(), Equals, 8)
+
+	// Closing the limiter wakes up the waiting requests.
+	l = newCopLimiter(1)
+	c.Assert(l.acquire(), IsTrue)
+	go func() {
+		acquired <- l.acquire()
+	}()
+	l.close()
+	c.Assert(<-acquired, IsFalse)
+}
+
+// batchClient returns the region ID as the data of the coprocessor responses and counts the RPCs.
+type batchClient struct {
+	mu struct {
+		sync.Mutex
+		single  int
+		batches []int
+	}
+	// failRegion is the region which returns a region error in the batches.
+	failRegion uint64
+}
+
+func (c *batchClient) Close() error {
+	return nil
+}
+
+func (c *batchClient) SendKVReq(addr string, req *kvrpcpb.Request, timeout time.Duration) (*kvrpcpb.Response, error) {
+	return &kvrpcpb.Response{Type: req.GetType()}, nil
+}
+
+func (c *batchClient) SendCopReq(addr string, req *coprocessor.Request, timeout time.Duration) (*coprocessor.Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.mu.single++
+	return &coprocessor.Response{Data: []byte(fmt.Sprint(req.GetContext().GetRegionId()))}, nil
+}
+
+func (c *batchClient) SendCopReqs(addr string, reqs []*coprocessor.Request, timeout time.Duration) ([]*coprocessor.Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.mu.batches = append(c.mu.batches, len(reqs))
+	resps := make([]*coprocessor.Response, 0, len(reqs))
+	for _, req := range reqs {
+		regionID := req.GetContext().GetRegionId()
+		if regionID == c.failRegion {
+			resps = append(resps, &coprocessor.Response{RegionError: &errorpb.Error{StaleEpoch: &errorpb.StaleEpoch{}}})
+			continue
+		}
+		resps = append(resps, &coprocessor.Response{Data: []byte(fmt.Sprint(regionID))})
+	}
+	return resps, nil
+}
+
+func (s *testCoprocessorSuite) TestBatchTasks(c *C) {
+	// nil --- 'b' --- 'c' --- ... --- 'j' --- nil, all the regions are on the same store.
+	var splitKeys [][]byte
+	for k := 'b'; k <= 'j'; k++ {
+		splitKeys = append(splitKeys, []byte{byte(k)})
+	}
+	cluster := mocktikv.NewCluster()
+	_, regionIDs, _ := mocktikv.BootstrapWithMultiRegions(cluster, splitKeys...)
+	client := &batchClient{failRegion: regionIDs[3]}
+	store, err := newTikvStore("mock-tikv-store", mocktikv.NewPDClient(cluster), client, false)
+	c.Assert(err, IsNil)
+	defer store.Close()
+
+	copClient := &CopClient{store: store}
+	req := &kv.Request{
+		Tp:          kv.ReqTypeSelect,
+		KeyRanges:   []kv.KeyRange{{StartKey: []byte("a"), EndKey: []byte("z")}},
+		KeepOrder:   true,
+		Concurrency: 2,
+	}
+	resp := copClient.Send(req)
+	for _, regionID := range regionIDs {
+		r, err := resp.Next()
+		c.Assert(err, IsNil)
+		c.Assert(r, NotNil)
+		data, err := ioutil.ReadAll(r)
+		c.Assert(err, IsNil)
+		c.Assert(string(data), Equals, fmt.Sprint(regionID))
+	}
+	r, err := resp.Next()
+	c.Assert(err, IsNil)
+	c.Assert(r, IsNil)
+
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	var batched int
+	for _, size := range client.mu.batches {
+		c.Assert(size > 1, IsTrue)
+		batched += size
+	}
+	c.Assert(len(client.mu.batches) < len(regionIDs), IsTrue)
+	// The failed region in the batch is retried alone, and so are the last regions.
+	c.Assert(batched+client.mu.single, Equals, len(regionIDs)+1)
+}
+
+func (s *testCoprocessorSuite) TestKilledRequest(c *C) {
+	var splitKeys [][]byte
+	for k := 'b'; k <= 'j'; k++ {
+		splitKeys = append(splitKeys, []byte{byte(k)})
+	}
+	cluster := mocktikv.NewCluster()
+	mocktikv.BootstrapWithMultiRegions(cluster, splitKeys...)
+	store, err := newTikvStore("mock-tikv-store", mocktikv.NewPDClient(cluster), &batchClient{}, false)
+	c.Assert(err, IsNil)
+	defer store.Close()
+
+	copClient := &CopClient{store: store}
+	var killed uint32
+	req := &kv.Request{
+		Tp:          kv.ReqTypeSelect,
+		KeyRanges:   []kv.KeyRange{{StartKey: []byte("a"), EndKey: []byte("z")}},
+		KeepOrder:   true,
+		Concurrency: 2,
+		Killed:      &killed,
+	}
+	resp := copClient.Send(req)
+	r, err := resp.Next()
+	c.Assert(err, IsNil)
+	c.Assert(r, NotNil)
+	atomic.StoreUint32(&killed, 1)
+	_, err = resp.Next()
+	c.Assert(kv.ErrQueryInterrupted.Equal(err), IsTrue)
+	c.Assert(resp.Close(), IsNil)
+
+	// The killed request isn't sent at all.
+	resp = copClient.Send(req)
+	_, err = resp.Next()
+	c.Assert(kv.ErrQueryInterrupted.Equal(err), IsTrue)
+	c.Assert(resp.Close(), IsNil)
+}
+
+func (s *testCoprocessorSuite) TestSupportControlFuncs(c *C) {
+	cluster := mocktikv.NewCluster()
+	mocktikv.BootstrapWithSingleStore(cluster)
+	// The stores which don't tell they evaluate the control functions don't get them pushed down.
+	store, err := newTikvStore("mock-tikv-store", mocktikv.NewPDClient(cluster), &batchClient{}, false)
+	c.Assert(err, IsNil)
+	defer store.Close()
+	copClient := &CopClient{store: store}
+	c.Assert(copClient.SupportRequestType(kv.ReqTypeSelect, int64(tipb.ExprType_EQ)), IsTrue)
+	c.Assert(copClient.SupportRequestType(kv.ReqTypeSelect, int64(tipb.ExprType_If)), IsFalse)
+	c.Assert(copClient.SupportRequestType(kv.ReqTypeSelect, int64(tipb.ExprType_Coalesce)), IsFalse)
+
+	client := mocktikv.NewRPCClient(cluster, mocktikv.NewMvccStore())
+	mockStore, err := newTikvStore("mock-tikv-store", mocktikv.NewPDClient(cluster), client, false)
+	c.Assert(err, IsNil)
+	defer mockStore.Close()
+	copClient = &CopClient{store: mockStore}
+	c.Assert(copClient.SupportRequestType(kv.ReqTypeSelect, int64(tipb.ExprType_If)), IsTrue)
+	c.Assert(copClient.SupportRequestType(kv.ReqTypeSelect, int64(tipb.ExprType_Coalesce)), IsTrue)
+	c.Assert(copClient.SupportRequestType(kv.ReqTypeSelect, int64(tipb.ExprType_NullIf)), IsFalse)
+}
@@ -269,7 +269,7 @@ func (w *GCWorker) resolveLocks(safePoint uint64) error {
 		if err != nil {
 			return errors.Trace(err)
 		}
-		resp, err := w.store.SendKVReq(bo, req, region.VerID(), readTimeoutMedium)
+		resp, err := w.store.SendKVReq(bo, req, region.VerID())
 		if err != nil {
 			return errors.Trace(err)
 		}
@@ -343,7 +343,7 @@ func (w *GCWorker) DoGC(safePoint uint64) error {
 		if err != nil {
 			return errors.Trace(err)
 		}
-		resp, err := w.store.SendKVReq(bo, req, region.VerID(), readTimeoutLong)
+		resp, err := w.store.SendKVReq(bo, req, region.VerID())
 		if err != nil {
 			return errors.Trace(err)
 		}
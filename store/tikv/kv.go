@@ -14,6 +14,7 @@
 package tikv
 
 import (
+	"bytes"
 	"fmt"
 	"math/rand"
 	"net/url"
@@ -66,7 +67,7 @@ func (d Driver) Open(path string) (kv.Storage, error) {
 		return store, nil
 	}
 
-	s, err := newTikvStore(uuid, &codecPDClient{pdCli}, newRPCClient(), !disableGC)
+	s, err := newTikvStore(uuid, &codecPDClient{&timeoutPDClient{pdCli}}, newRPCClient(), !disableGC)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -85,6 +86,7 @@ type tikvStore struct {
 	regionCache  *RegionCache
 	lockResolver *LockResolver
 	gcWorker     *GCWorker
+	copLoad      copLoad
 }
 
 func newTikvStore(uuid string, pdClient pd.Client, client Client, enableGC bool) (*tikvStore, error) {
@@ -97,7 +99,7 @@ func newTikvStore(uuid string, pdClient pd.Client, client Client, enableGC bool)
 		clusterID:   pdClient.GetClusterID(),
 		uuid:        uuid,
 		oracle:      oracle,
-		client:      client,
+		client:      newBreakerClient(client),
 		regionCache: NewRegionCache(pdClient),
 	}
 	store.lockResolver = newLockResolver(store)
@@ -183,10 +185,61 @@ func (s *tikvStore) GetClient() kv.Client {
 	}
 }
 
+// SplitKeys implements the kv.RegionSplitter interface.
+func (s *tikvStore) SplitKeys(startKey, endKey kv.Key) ([]kv.Key, error) {
+	bo := NewBackoffer(splitKeysMaxBackoff, context.Background())
+	var keys []kv.Key
+	key := startKey
+	for {
+		region, err := s.regionCache.GetRegion(bo, key)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		key = region.EndKey()
+		if len(key) == 0 || bytes.Compare(key, endKey) >= 0 {
+			return keys, nil
+		}
+		keys = append(keys, kv.Key(key))
+	}
+}
+
+// GetHistory implements the kv.HistoryReader interface.
+// TiKV doesn't provide an RPC to read the versions of a key yet, so only the mock store supports it, the client
+// reports it with kv.ReqTypeHistory so the statements reading the history fail before they're executed.
+func (s *tikvStore) GetHistory(key kv.Key, ver kv.Version) ([]kv.KeyVersion, error) {
+	reader, ok := s.historyReader()
+	if !ok {
+		return nil, errors.Trace(kv.ErrNotImplemented)
+	}
+	versions, err := reader.GetHistory(key, ver)
+	return versions, errors.Trace(err)
+}
+
+// historyReader returns the RPC client which reads the history versions of the keys, only the mock client does.
+func (s *tikvStore) historyReader() (kv.HistoryReader, bool) {
+	reader, ok := s.rpcClient().(kv.HistoryReader)
+	return reader, ok
+}
+
+// exprSupporter returns the RPC client which tells the extra expressions evaluated by the stores.
+func (s *tikvStore) exprSupporter() (ExprSupporter, bool) {
+	supporter, ok := s.rpcClient().(ExprSupporter)
+	return supporter, ok
+}
+
+// rpcClient returns the RPC client wrapped by the circuit breaker.
+func (s *tikvStore) rpcClient() Client {
+	if c, ok := s.client.(*breakerClient); ok {
+		return c.Client
+	}
+	return s.client
+}
+
 // sendKVReq sends req to tikv server. It will retry internally to find the right
 // region leader if i) fails to establish a connection to server or ii) server
-// returns `NotLeader`.
-func (s *tikvStore) SendKVReq(bo *Backoffer, req *pb.Request, regionID RegionVerID, timeout time.Duration) (*pb.Response, error) {
+// returns `NotLeader`. The timeout is configured by the type of req, see SetRPCTimeouts.
+func (s *tikvStore) SendKVReq(bo *Backoffer, req *pb.Request, regionID RegionVerID) (*pb.Response, error) {
+	timeout := kvRPCTimeout(req.GetType())
 	for {
 		select {
 		case <-bo.ctx.Done():
@@ -207,7 +207,7 @@ func (lr *LockResolver) getTxnStatus(bo *Backoffer, txnID uint64, primary []byte
 		if err != nil {
 			return status, errors.Trace(err)
 		}
-		resp, err := lr.store.SendKVReq(bo, req, region.VerID(), readTimeoutShort)
+		resp, err := lr.store.SendKVReq(bo, req, region.VerID())
 		if err != nil {
 			return status, errors.Trace(err)
 		}
@@ -255,7 +255,7 @@ func (lr *LockResolver) resolveLock(bo *Backoffer, l *Lock, status TxnStatus, cl
 		if status.IsCommitted() {
 			req.GetCmdResolveLockReq().CommitVersion = status.CommitTS()
 		}
-		resp, err := lr.store.SendKVReq(bo, req, region.VerID(), readTimeoutShort)
+		resp, err := lr.store.SendKVReq(bo, req, region.VerID())
 		if err != nil {
 			return errors.Trace(err)
 		}
@@ -193,7 +193,7 @@ func (s *testLockSuite) mustGetLock(c *C, key []byte) *Lock {
 	}
 	region, err := s.store.regionCache.GetRegion(bo, key)
 	c.Assert(err, IsNil)
-	resp, err := s.store.SendKVReq(bo, req, region.VerID(), readTimeoutShort)
+	resp, err := s.store.SendKVReq(bo, req, region.VerID())
 	c.Assert(err, IsNil)
 	cmdGetResp := resp.GetCmdGetResp()
 	c.Assert(cmdGetResp, NotNil)
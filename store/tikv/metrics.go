@@ -142,6 +142,14 @@ var (
 			Help:      "Counter of region errors.",
 		}, []string{"type"})
 
+	storeBreakerCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "tidb",
+			Subsystem: "tikvclient",
+			Name:      "store_breaker_total",
+			Help:      "Counter of store circuit breaker actions.",
+		}, []string{"type"})
+
 	txnWriteKVCountHistogram = prometheus.NewHistogram(
 		prometheus.HistogramOpts{
 			Namespace: "tidb",
@@ -193,6 +201,7 @@ func init() {
 	prometheus.MustRegister(gcHistogram)
 	prometheus.MustRegister(lockResolverCounter)
 	prometheus.MustRegister(regionErrorCounter)
+	prometheus.MustRegister(storeBreakerCounter)
 	prometheus.MustRegister(txnWriteKVCountHistogram)
 	prometheus.MustRegister(txnWriteSizeHistogram)
 }
@@ -37,12 +37,17 @@ type selectContext struct {
 	eval         *xeval.Evaluator
 	whereColumns map[int64]*tipb.ColumnInfo
 	aggColumns   map[int64]*tipb.ColumnInfo
+	topnColumns  map[int64]*tipb.ColumnInfo
 	groups       map[string]bool
 	groupKeys    [][]byte
 	aggregates   []*aggregateFuncExpr
-	aggregate    bool
+	topnHeap     *topnHeap
 	keyRanges    []*coprocessor.KeyRange
 
+	aggregate bool
+	descScan  bool
+	topn      bool
+
 	// Use for DecodeRow.
 	colTps map[int64]*types.FieldType
 }
@@ -71,6 +76,30 @@ func (h *rpcHandler) handleCopRequest(req *coprocessor.Request) (*coprocessor.Re
 			ctx.whereColumns = make(map[int64]*tipb.ColumnInfo)
 			collectColumnsInExpr(sel.Where, ctx, ctx.whereColumns)
 		}
+		if len(sel.OrderBy) > 0 {
+			if sel.OrderBy[0].Expr == nil {
+				ctx.descScan = sel.OrderBy[0].Desc
+			} else {
+				if sel.Limit == nil {
+					return nil, errors.New("we don't support pushing down Sort without Limit")
+				}
+				ctx.topn = true
+				ctx.topnHeap = &topnHeap{
+					totalCount: int(sel.GetLimit()),
+					topnSorter: topnSorter{
+						orderByItems: sel.OrderBy,
+					},
+				}
+				ctx.topnColumns = make(map[int64]*tipb.ColumnInfo)
+				for _, item := range sel.OrderBy {
+					collectColumnsInExpr(item.Expr, ctx, ctx.topnColumns)
+				}
+				for k := range ctx.whereColumns {
+					// It will be handled in where.
+					delete(ctx.topnColumns, k)
+				}
+			}
+		}
 		ctx.aggregate = len(sel.Aggregates) > 0 || len(sel.GetGroupBy()) > 0
 		if ctx.aggregate {
 			// compose aggregateFuncExpr
@@ -208,7 +237,8 @@ func (h *rpcHandler) getChunksFromSelectReq(ctx *selectContext) ([]tipb.Chunk, e
 
 	kvRanges, desc := h.extractKVRanges(ctx)
 	limit := int64(-1)
-	if ctx.sel.Limit != nil {
+	// The rows are filtered by the topn heap, so the limit doesn't stop the scan.
+	if ctx.sel.Limit != nil && !ctx.topn {
 		limit = ctx.sel.GetLimit()
 	}
 
@@ -226,12 +256,14 @@ func (h *rpcHandler) getChunksFromSelectReq(ctx *selectContext) ([]tipb.Chunk, e
 	if ctx.aggregate {
 		return h.getRowsFromAgg(ctx)
 	}
+	if ctx.topn {
+		return h.getRowsFromTopN(ctx)
+	}
 	return chunks, nil
 }
 
 // extractKVRanges extracts kv.KeyRanges slice from a SelectRequest, and also returns if it is in descending order.
 func (h *rpcHandler) extractKVRanges(ctx *selectContext) (kvRanges []kv.KeyRange, desc bool) {
-	sel := ctx.sel
 	for _, kran := range ctx.keyRanges {
 		upperKey := kran.GetEnd()
 		if bytes.Compare(upperKey, h.startKey) <= 0 {
@@ -246,9 +278,7 @@ func (h *rpcHandler) extractKVRanges(ctx *selectContext) (kvRanges []kv.KeyRange
 		kvr.EndKey = kv.Key(minEndKey(upperKey, h.endKey))
 		kvRanges = append(kvRanges, kvr)
 	}
-	if sel.OrderBy != nil {
-		desc = sel.OrderBy[0].Desc
-	}
+	desc = ctx.descScan
 	if desc {
 		reverseKVRanges(kvRanges)
 	}
@@ -349,7 +379,7 @@ func (h *rpcHandler) getRowsFromRange(ctx *selectContext, ran kv.KeyRange, limit
 // handleRowData deals with raw row data:
 //	1. Decodes row from raw byte slice.
 //	2. Checks if it fit where condition.
-//	3. Update aggregate functions.
+//	3. Update aggregate functions or the topn heap.
 func (h *rpcHandler) handleRowData(ctx *selectContext, handle int64, value []byte) ([]byte, error) {
 	columns := ctx.sel.TableInfo.Columns
 	values, err := h.getRowData(value, ctx.colTps)
@@ -401,6 +431,9 @@ func (h *rpcHandler) valuesToRow(ctx *selectContext, handle int64, values map[in
 	if !match {
 		return nil, nil
 	}
+	if ctx.topn {
+		return nil, errors.Trace(h.evalTopN(ctx, handle, values, columns))
+	}
 	data := dummySlice
 	if ctx.aggregate {
 		// Update aggregate functions.
@@ -475,7 +508,8 @@ func (h *rpcHandler) evalWhereForRow(ctx *selectContext, handle int64, row map[i
 func (h *rpcHandler) getChunksFromIndexReq(ctx *selectContext) ([]tipb.Chunk, error) {
 	kvRanges, desc := h.extractKVRanges(ctx)
 	limit := int64(-1)
-	if ctx.sel.Limit != nil {
+	// The rows are filtered by the topn heap, so the limit doesn't stop the scan.
+	if ctx.sel.Limit != nil && !ctx.topn {
 		limit = ctx.sel.GetLimit()
 	}
 	var chunks []tipb.Chunk
@@ -492,6 +526,9 @@ func (h *rpcHandler) getChunksFromIndexReq(ctx *selectContext) ([]tipb.Chunk, er
 	if ctx.aggregate {
 		return h.getRowsFromAgg(ctx)
 	}
+	if ctx.topn {
+		return h.getRowsFromTopN(ctx)
+	}
 	return chunks, nil
 }
 
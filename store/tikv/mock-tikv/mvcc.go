@@ -20,6 +20,7 @@ import (
 	"github.com/juju/errors"
 	"github.com/petar/GoLLRB/llrb"
 	"github.com/pingcap/kvproto/pkg/kvrpcpb"
+	"github.com/pingcap/tidb/kv"
 )
 
 type mvccValueType int
@@ -208,6 +209,30 @@ func (s *MvccStore) get(key []byte, startTS uint64) ([]byte, error) {
 	return entry.(*mvccEntry).Get(startTS)
 }
 
+// History reads the versions of a key committed no later than ts, the latest version first.
+// The rollbacks and the uncommitted lock are not versions.
+func (s *MvccStore) History(key []byte, ts uint64) []kv.KeyVersion {
+	s.RLock()
+	defer s.RUnlock()
+
+	entry := s.tree.Get(newEntry(key))
+	if entry == nil {
+		return nil
+	}
+	var versions []kv.KeyVersion
+	for _, v := range entry.(*mvccEntry).values {
+		if v.commitTS > ts || v.valueType == typeRollback {
+			continue
+		}
+		version := kv.KeyVersion{Version: kv.Version{Ver: v.commitTS}}
+		if v.valueType == typePut {
+			version.Value = append([]byte(nil), v.value...)
+		}
+		versions = append(versions, version)
+	}
+	return versions
+}
+
 // A Pair is a KV pair read from MvccStore or an error if any occurs.
 type Pair struct {
 	Key   []byte
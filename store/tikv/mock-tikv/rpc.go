@@ -22,7 +22,9 @@ import (
 	"github.com/pingcap/kvproto/pkg/errorpb"
 	"github.com/pingcap/kvproto/pkg/kvrpcpb"
 	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/util/codec"
+	"github.com/pingcap/tipb/go-tipb"
 )
 
 type rpcHandler struct {
@@ -328,6 +330,33 @@ func (c *RPCClient) SendCopReq(addr string, req *coprocessor.Request, timeout ti
 	return handler.handleCopRequest(req)
 }
 
+// SendCopReqs sends coprocessor requests to mock cluster one by one.
+func (c *RPCClient) SendCopReqs(addr string, reqs []*coprocessor.Request, timeout time.Duration) ([]*coprocessor.Response, error) {
+	resps := make([]*coprocessor.Response, 0, len(reqs))
+	for _, req := range reqs {
+		resp, err := c.SendCopReq(addr, req, timeout)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		resps = append(resps, resp)
+	}
+	return resps, nil
+}
+
+// GetHistory implements the kv.HistoryReader interface.
+func (c *RPCClient) GetHistory(key kv.Key, ver kv.Version) ([]kv.KeyVersion, error) {
+	return c.mvccStore.History(key, ver.Ver), nil
+}
+
+// SupportExpr implements the tikv.ExprSupporter interface. The mock coprocessor evaluates the control functions.
+func (c *RPCClient) SupportExpr(exprType tipb.ExprType) bool {
+	switch exprType {
+	case tipb.ExprType_Case, tipb.ExprType_If, tipb.ExprType_IfNull, tipb.ExprType_Coalesce:
+		return true
+	}
+	return false
+}
+
 // Close closes the client.
 func (c *RPCClient) Close() error {
 	return nil
@@ -0,0 +1,189 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tikv
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/kvproto/pkg/kvrpcpb"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/pd/pd-client"
+)
+
+// The names of the RPC types whose timeouts are configurable.
+const (
+	rpcGet           = "get"
+	rpcScan          = "scan"
+	rpcBatchGet      = "batch_get"
+	rpcPrewrite      = "prewrite"
+	rpcCommit        = "commit"
+	rpcCleanup       = "cleanup"
+	rpcBatchRollback = "batch_rollback"
+	rpcScanLock      = "scan_lock"
+	rpcResolveLock   = "resolve_lock"
+	rpcGC            = "gc"
+	rpcCop           = "cop"
+	rpcPD            = "pd"
+)
+
+// pdTimeout is the default timeout of the requests to PD.
+const pdTimeout = 3 * time.Second
+
+var rpcTimeouts = struct {
+	sync.RWMutex
+	m map[string]time.Duration
+}{
+	m: map[string]time.Duration{
+		rpcGet:           readTimeoutShort,
+		rpcScan:          readTimeoutMedium,
+		rpcBatchGet:      readTimeoutMedium,
+		rpcPrewrite:      readTimeoutShort,
+		rpcCommit:        readTimeoutShort,
+		rpcCleanup:       readTimeoutShort,
+		rpcBatchRollback: readTimeoutShort,
+		rpcScanLock:      readTimeoutMedium,
+		rpcResolveLock:   readTimeoutShort,
+		rpcGC:            readTimeoutLong,
+		rpcCop:           readTimeoutMedium,
+		rpcPD:            pdTimeout,
+	},
+}
+
+// SetRPCTimeouts sets the timeouts of the RPC types in the spec, e.g. "get=5s,cop=30s,pd=1s", the others are kept.
+// The RPC types are get, scan, batch_get, prewrite, commit, cleanup, batch_rollback, scan_lock, resolve_lock, gc,
+// cop and pd.
+func SetRPCTimeouts(spec string) error {
+	rpcTimeouts.Lock()
+	defer rpcTimeouts.Unlock()
+	timeouts := make(map[string]time.Duration)
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		kv := strings.SplitN(item, "=", 2)
+		if len(kv) != 2 {
+			return errors.Errorf("invalid rpc timeout %q, should be like get=5s", item)
+		}
+		name := strings.ToLower(strings.TrimSpace(kv[0]))
+		if _, ok := rpcTimeouts.m[name]; !ok {
+			return errors.Errorf("unknown rpc type %q", name)
+		}
+		timeout, err := time.ParseDuration(strings.TrimSpace(kv[1]))
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if timeout <= 0 {
+			return errors.Errorf("the timeout of rpc type %q should be positive", name)
+		}
+		timeouts[name] = timeout
+	}
+	// The timeouts are set only if the whole spec is valid.
+	for name, timeout := range timeouts {
+		rpcTimeouts.m[name] = timeout
+	}
+	return nil
+}
+
+func rpcTimeout(name string) time.Duration {
+	rpcTimeouts.RLock()
+	defer rpcTimeouts.RUnlock()
+	return rpcTimeouts.m[name]
+}
+
+// kvRPCTimeout returns the timeout of the kv request by its type.
+func kvRPCTimeout(typ kvrpcpb.MessageType) time.Duration {
+	switch typ {
+	case kvrpcpb.MessageType_CmdGet:
+		return rpcTimeout(rpcGet)
+	case kvrpcpb.MessageType_CmdScan:
+		return rpcTimeout(rpcScan)
+	case kvrpcpb.MessageType_CmdBatchGet:
+		return rpcTimeout(rpcBatchGet)
+	case kvrpcpb.MessageType_CmdPrewrite:
+		return rpcTimeout(rpcPrewrite)
+	case kvrpcpb.MessageType_CmdCommit:
+		return rpcTimeout(rpcCommit)
+	case kvrpcpb.MessageType_CmdCleanup:
+		return rpcTimeout(rpcCleanup)
+	case kvrpcpb.MessageType_CmdBatchRollback:
+		return rpcTimeout(rpcBatchRollback)
+	case kvrpcpb.MessageType_CmdScanLock:
+		return rpcTimeout(rpcScanLock)
+	case kvrpcpb.MessageType_CmdResolveLock:
+		return rpcTimeout(rpcResolveLock)
+	case kvrpcpb.MessageType_CmdGC:
+		return rpcTimeout(rpcGC)
+	}
+	return readTimeoutShort
+}
+
+// timeoutPDClient fails the requests to PD which don't respond in the pd rpc timeout, so the callers can back off
+// and retry instead of waiting for PD forever.
+type timeoutPDClient struct {
+	pd.Client
+}
+
+type pdResult struct {
+	physical, logical int64
+	region            *metapb.Region
+	leader            *metapb.Peer
+	store             *metapb.Store
+	err               error
+}
+
+func (c *timeoutPDClient) do(name string, f func() pdResult) pdResult {
+	// The channel is buffered so the goroutine exits when PD responds after the timeout.
+	ch := make(chan pdResult, 1)
+	go func() {
+		ch <- f()
+	}()
+	timeout := rpcTimeout(rpcPD)
+	select {
+	case res := <-ch:
+		return res
+	case <-time.After(timeout):
+		return pdResult{err: errors.Errorf("pd %s request timeout after %v", name, timeout)}
+	}
+}
+
+// GetTS implements pd.Client GetTS interface.
+func (c *timeoutPDClient) GetTS() (int64, int64, error) {
+	res := c.do("tso", func() pdResult {
+		physical, logical, err := c.Client.GetTS()
+		return pdResult{physical: physical, logical: logical, err: err}
+	})
+	return res.physical, res.logical, errors.Trace(res.err)
+}
+
+// GetRegion implements pd.Client GetRegion interface.
+func (c *timeoutPDClient) GetRegion(key []byte) (*metapb.Region, *metapb.Peer, error) {
+	res := c.do("get_region", func() pdResult {
+		region, leader, err := c.Client.GetRegion(key)
+		return pdResult{region: region, leader: leader, err: err}
+	})
+	return res.region, res.leader, errors.Trace(res.err)
+}
+
+// GetStore implements pd.Client GetStore interface.
+func (c *timeoutPDClient) GetStore(storeID uint64) (*metapb.Store, error) {
+	res := c.do("get_store", func() pdResult {
+		store, err := c.Client.GetStore(storeID)
+		return pdResult{store: store, err: err}
+	})
+	return res.store, errors.Trace(res.err)
+}
@@ -0,0 +1,73 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tikv
+
+import (
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/kvrpcpb"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/pd/pd-client"
+)
+
+type testRPCTimeoutSuite struct{}
+
+var _ = Suite(&testRPCTimeoutSuite{})
+
+func (s *testRPCTimeoutSuite) TestSetRPCTimeouts(c *C) {
+	defer SetRPCTimeouts("get=20s,cop=60s,pd=3s")
+
+	c.Assert(kvRPCTimeout(kvrpcpb.MessageType_CmdGet), Equals, readTimeoutShort)
+	c.Assert(kvRPCTimeout(kvrpcpb.MessageType_CmdGC), Equals, readTimeoutLong)
+	c.Assert(SetRPCTimeouts(" get=5s, COP=30s,"), IsNil)
+	c.Assert(kvRPCTimeout(kvrpcpb.MessageType_CmdGet), Equals, 5*time.Second)
+	c.Assert(rpcTimeout(rpcCop), Equals, 30*time.Second)
+	c.Assert(kvRPCTimeout(kvrpcpb.MessageType_CmdScan), Equals, readTimeoutMedium)
+
+	// Nothing is set if the spec is invalid.
+	for _, spec := range []string{"get", "get=1s,foo=1s", "get=1s,cop=abc", "get=-1s"} {
+		c.Assert(SetRPCTimeouts(spec), NotNil, Commentf("spec %s", spec))
+		c.Assert(kvRPCTimeout(kvrpcpb.MessageType_CmdGet), Equals, 5*time.Second)
+	}
+}
+
+// hangPDClient doesn't respond until it's closed.
+type hangPDClient struct {
+	pd.Client
+	closed chan struct{}
+}
+
+func (c *hangPDClient) GetTS() (int64, int64, error) {
+	<-c.closed
+	return 0, 0, nil
+}
+
+func (c *hangPDClient) GetRegion(key []byte) (*metapb.Region, *metapb.Peer, error) {
+	<-c.closed
+	return nil, nil, nil
+}
+
+func (s *testRPCTimeoutSuite) TestPDTimeout(c *C) {
+	c.Assert(SetRPCTimeouts("pd=10ms"), IsNil)
+	defer SetRPCTimeouts("pd=3s")
+
+	hang := &hangPDClient{closed: make(chan struct{})}
+	defer close(hang.closed)
+	client := &timeoutPDClient{hang}
+	_, _, err := client.GetTS()
+	c.Assert(err, NotNil)
+	_, _, err = client.GetRegion([]byte("a"))
+	c.Assert(err, NotNil)
+}
@@ -143,7 +143,7 @@ func (s *Scanner) getData(bo *Backoffer) error {
 				Version:  s.startTS(),
 			},
 		}
-		resp, err := s.snapshot.store.SendKVReq(bo, req, region.VerID(), readTimeoutMedium)
+		resp, err := s.snapshot.store.SendKVReq(bo, req, region.VerID())
 		if err != nil {
 			return errors.Trace(err)
 		}
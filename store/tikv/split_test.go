@@ -99,3 +99,25 @@ func (s *testSplitSuite) TestStaleEpoch(c *C) {
 	_, err = txn.Get([]byte("c"))
 	c.Assert(err, IsNil)
 }
+
+func (s *testSplitSuite) TestSplitKeys(c *C) {
+	firstRegion, err := s.store.regionCache.GetRegion(s.bo, []byte("a"))
+	c.Assert(err, IsNil)
+	// split to ['' - 'b' - 'd' - '']
+	s.split(c, firstRegion.GetID(), []byte("b"))
+	s.store.regionCache.DropRegion(firstRegion.VerID())
+	secondRegion, err := s.store.regionCache.GetRegion(s.bo, []byte("b"))
+	c.Assert(err, IsNil)
+	s.split(c, secondRegion.GetID(), []byte("d"))
+	s.store.regionCache.DropRegion(secondRegion.VerID())
+
+	keys, err := s.store.SplitKeys(kv.Key("a"), kv.Key("z"))
+	c.Assert(err, IsNil)
+	c.Assert(keys, DeepEquals, []kv.Key{kv.Key("b"), kv.Key("d")})
+	keys, err = s.store.SplitKeys(kv.Key("a"), kv.Key("d"))
+	c.Assert(err, IsNil)
+	c.Assert(keys, DeepEquals, []kv.Key{kv.Key("b")})
+	keys, err = s.store.SplitKeys(kv.Key("b"), kv.Key("c"))
+	c.Assert(err, IsNil)
+	c.Assert(keys, HasLen, 0)
+}
@@ -0,0 +1,188 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tikv
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+	"github.com/pingcap/kvproto/pkg/coprocessor"
+	"github.com/pingcap/kvproto/pkg/kvrpcpb"
+)
+
+const (
+	// DefaultBreakerThreshold is the default number of the consecutive timeouts which open the breaker of a store.
+	DefaultBreakerThreshold = 3
+	// DefaultBreakerCooldown is the default duration the breaker of a store keeps open before a probe is allowed.
+	DefaultBreakerCooldown = 10 * time.Second
+)
+
+var breakerConfig = struct {
+	sync.RWMutex
+	threshold int
+	cooldown  time.Duration
+}{
+	threshold: DefaultBreakerThreshold,
+	cooldown:  DefaultBreakerCooldown,
+}
+
+// SetStoreBreaker sets the number of the consecutive timeouts which open the breaker of a store and how long the
+// breaker keeps open before a probe request is sent to the store, a threshold no more than 0 disables the breakers.
+func SetStoreBreaker(threshold int, cooldown time.Duration) {
+	breakerConfig.Lock()
+	defer breakerConfig.Unlock()
+	breakerConfig.threshold = threshold
+	breakerConfig.cooldown = cooldown
+}
+
+func getBreakerConfig() (int, time.Duration) {
+	breakerConfig.RLock()
+	defer breakerConfig.RUnlock()
+	return breakerConfig.threshold, breakerConfig.cooldown
+}
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	// breakerHalfOpen means the cooldown is over and a probe request is in flight.
+	breakerHalfOpen
+)
+
+// storeBreaker is the circuit breaker of a store.
+type storeBreaker struct {
+	state     breakerState
+	timeouts  int
+	openUntil time.Time
+}
+
+// breakerClient wraps a Client and fails the requests to the stores which time out repeatedly right away, so the
+// callers try the other peers instead of waiting for the sick store until the timeouts. After the cooldown, a
+// single request is let through to probe the store, the breaker is closed if it doesn't time out.
+type breakerClient struct {
+	Client
+	// now is replaceable in tests.
+	now func() time.Time
+
+	mu struct {
+		sync.Mutex
+		breakers map[string]*storeBreaker
+	}
+}
+
+func newBreakerClient(client Client) *breakerClient {
+	c := &breakerClient{Client: client, now: time.Now}
+	c.mu.breakers = make(map[string]*storeBreaker)
+	return c
+}
+
+// SendKVReq implements Client SendKVReq interface.
+func (c *breakerClient) SendKVReq(addr string, req *kvrpcpb.Request, timeout time.Duration) (*kvrpcpb.Response, error) {
+	if err := c.allow(addr); err != nil {
+		return nil, errors.Trace(err)
+	}
+	resp, err := c.Client.SendKVReq(addr, req, timeout)
+	c.onResult(addr, err)
+	return resp, errors.Trace(err)
+}
+
+// SendCopReq implements Client SendCopReq interface.
+func (c *breakerClient) SendCopReq(addr string, req *coprocessor.Request, timeout time.Duration) (*coprocessor.Response, error) {
+	if err := c.allow(addr); err != nil {
+		return nil, errors.Trace(err)
+	}
+	resp, err := c.Client.SendCopReq(addr, req, timeout)
+	c.onResult(addr, err)
+	return resp, errors.Trace(err)
+}
+
+// SendCopReqs implements Client SendCopReqs interface.
+func (c *breakerClient) SendCopReqs(addr string, reqs []*coprocessor.Request, timeout time.Duration) ([]*coprocessor.Response, error) {
+	if err := c.allow(addr); err != nil {
+		return nil, errors.Trace(err)
+	}
+	resps, err := c.Client.SendCopReqs(addr, reqs, timeout)
+	c.onResult(addr, err)
+	return resps, errors.Trace(err)
+}
+
+// allow returns an error if the breaker of the store is open.
+func (c *breakerClient) allow(addr string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	b, ok := c.mu.breakers[addr]
+	if !ok {
+		return nil
+	}
+	switch b.state {
+	case breakerOpen:
+		if c.now().Before(b.openUntil) {
+			storeBreakerCounter.WithLabelValues("reject").Inc()
+			return errors.Errorf("store %s is unavailable after %d timeouts", addr, b.timeouts)
+		}
+		storeBreakerCounter.WithLabelValues("probe").Inc()
+		b.state = breakerHalfOpen
+	case breakerHalfOpen:
+		storeBreakerCounter.WithLabelValues("reject").Inc()
+		return errors.Errorf("store %s is being probed after %d timeouts", addr, b.timeouts)
+	}
+	return nil
+}
+
+// onResult updates the breaker of the store by the result of a request. Only the timeouts count, the other errors
+// tell the store is responding.
+func (c *breakerClient) onResult(addr string, err error) {
+	threshold, cooldown := getBreakerConfig()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	b, ok := c.mu.breakers[addr]
+	if !isTimeout(err) {
+		if ok {
+			if b.state != breakerClosed {
+				log.Infof("[tikv] store %s recovers, close its breaker", addr)
+				storeBreakerCounter.WithLabelValues("close").Inc()
+			}
+			delete(c.mu.breakers, addr)
+		}
+		return
+	}
+	if threshold <= 0 {
+		return
+	}
+	if !ok {
+		b = &storeBreaker{}
+		c.mu.breakers[addr] = b
+	}
+	b.timeouts++
+	if b.state == breakerHalfOpen || b.timeouts >= threshold {
+		if b.state == breakerClosed {
+			log.Warnf("[tikv] store %s times out %d times, open its breaker for %v", addr, b.timeouts, cooldown)
+		}
+		storeBreakerCounter.WithLabelValues("open").Inc()
+		b.state = breakerOpen
+		b.openUntil = c.now().Add(cooldown)
+	}
+}
+
+func isTimeout(err error) bool {
+	if err == nil {
+		return false
+	}
+	netErr, ok := errors.Cause(err).(net.Error)
+	return ok && netErr.Timeout()
+}
@@ -0,0 +1,155 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tikv
+
+import (
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/coprocessor"
+	"github.com/pingcap/kvproto/pkg/kvrpcpb"
+)
+
+type testStoreBreakerSuite struct{}
+
+var _ = Suite(&testStoreBreakerSuite{})
+
+type timeoutError struct{}
+
+func (e timeoutError) Error() string   { return "i/o timeout" }
+func (e timeoutError) Timeout() bool   { return true }
+func (e timeoutError) Temporary() bool { return true }
+
+// sickClient times out the requests to the sick stores.
+type sickClient struct {
+	mu struct {
+		sync.Mutex
+		sick  map[string]bool
+		calls map[string]int
+	}
+}
+
+func newSickClient() *sickClient {
+	c := &sickClient{}
+	c.mu.sick = make(map[string]bool)
+	c.mu.calls = make(map[string]int)
+	return c
+}
+
+func (c *sickClient) setSick(addr string, sick bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.mu.sick[addr] = sick
+}
+
+func (c *sickClient) calls(addr string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.mu.calls[addr]
+}
+
+func (c *sickClient) send(addr string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.mu.calls[addr]++
+	if c.mu.sick[addr] {
+		return errors.Trace(timeoutError{})
+	}
+	return nil
+}
+
+func (c *sickClient) Close() error {
+	return nil
+}
+
+func (c *sickClient) SendKVReq(addr string, req *kvrpcpb.Request, timeout time.Duration) (*kvrpcpb.Response, error) {
+	if err := c.send(addr); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &kvrpcpb.Response{Type: req.GetType()}, nil
+}
+
+func (c *sickClient) SendCopReq(addr string, req *coprocessor.Request, timeout time.Duration) (*coprocessor.Response, error) {
+	if err := c.send(addr); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &coprocessor.Response{}, nil
+}
+
+func (c *sickClient) SendCopReqs(addr string, reqs []*coprocessor.Request, timeout time.Duration) ([]*coprocessor.Response, error) {
+	if err := c.send(addr); err != nil {
+		return nil, errors.Trace(err)
+	}
+	resps := make([]*coprocessor.Response, 0, len(reqs))
+	for range reqs {
+		resps = append(resps, &coprocessor.Response{})
+	}
+	return resps, nil
+}
+
+func (s *testStoreBreakerSuite) TestBreaker(c *C) {
+	SetStoreBreaker(2, time.Second)
+	defer SetStoreBreaker(DefaultBreakerThreshold, DefaultBreakerCooldown)
+
+	sick := newSickClient()
+	client := newBreakerClient(sick)
+	now := time.Unix(1000, 0)
+	client.now = func() time.Time { return now }
+	req := &kvrpcpb.Request{Type: kvrpcpb.MessageType_CmdGet}
+
+	sick.setSick("s1", true)
+	for i := 0; i < 2; i++ {
+		_, err := client.SendKVReq("s1", req, time.Second)
+		c.Assert(isTimeout(err), IsTrue)
+	}
+	// The breaker is open, the requests fail without being sent.
+	_, err := client.SendKVReq("s1", req, time.Second)
+	c.Assert(err, NotNil)
+	c.Assert(isTimeout(err), IsFalse)
+	_, err = client.SendCopReq("s1", &coprocessor.Request{}, time.Second)
+	c.Assert(err, NotNil)
+	c.Assert(sick.calls("s1"), Equals, 2)
+	// The other stores aren't affected.
+	_, err = client.SendKVReq("s2", req, time.Second)
+	c.Assert(err, IsNil)
+
+	// The probe times out, the breaker is open again.
+	now = now.Add(time.Second)
+	_, err = client.SendKVReq("s1", req, time.Second)
+	c.Assert(isTimeout(err), IsTrue)
+	c.Assert(sick.calls("s1"), Equals, 3)
+	_, err = client.SendKVReq("s1", req, time.Second)
+	c.Assert(err, NotNil)
+	c.Assert(sick.calls("s1"), Equals, 3)
+
+	// The probe succeeds, the breaker is closed.
+	now = now.Add(time.Second)
+	sick.setSick("s1", false)
+	_, err = client.SendKVReq("s1", req, time.Second)
+	c.Assert(err, IsNil)
+	_, err = client.SendKVReq("s1", req, time.Second)
+	c.Assert(err, IsNil)
+	c.Assert(sick.calls("s1"), Equals, 5)
+
+	// The breakers are disabled.
+	SetStoreBreaker(0, time.Second)
+	sick.setSick("s1", true)
+	for i := 0; i < 3; i++ {
+		_, err = client.SendKVReq("s1", req, time.Second)
+		c.Assert(isTimeout(err), IsTrue)
+	}
+	c.Assert(sick.calls("s1"), Equals, 8)
+}
@@ -272,6 +272,24 @@ func (c *busyClient) SendCopReq(addr string, req *coprocessor.Request, timeout t
 	return c.client.SendCopReq(addr, req, timeout)
 }
 
+func (c *busyClient) SendCopReqs(addr string, reqs []*coprocessor.Request, timeout time.Duration) ([]*coprocessor.Response, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.mu.isBusy {
+		resps := make([]*coprocessor.Response, 0, len(reqs))
+		for range reqs {
+			resps = append(resps, &coprocessor.Response{
+				RegionError: &errorpb.Error{
+					ServerIsBusy: &errorpb.ServerIsBusy{},
+				},
+			})
+		}
+		return resps, nil
+	}
+	return c.client.SendCopReqs(addr, reqs, timeout)
+}
+
 type mockPDClient struct {
 	sync.RWMutex
 	client pd.Client
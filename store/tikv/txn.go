@@ -67,6 +67,18 @@ func (txn *tikvTxn) Get(k kv.Key) ([]byte, error) {
 	return ret, nil
 }
 
+func (txn *tikvTxn) BatchGet(keys []kv.Key) (map[string][]byte, error) {
+	txnCmdCounter.WithLabelValues("batch_get").Inc()
+	start := time.Now()
+	defer func() { txnCmdHistogram.WithLabelValues("batch_get").Observe(time.Since(start).Seconds()) }()
+
+	ret, err := txn.us.BatchGet(keys)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return ret, nil
+}
+
 func (txn *tikvTxn) Set(k kv.Key, v []byte) error {
 	txnCmdCounter.WithLabelValues("set").Inc()
 
@@ -208,6 +208,32 @@ func (t *TxStructure) HGetAll(key []byte) ([]HashPair, error) {
 	return res, errors.Trace(err)
 }
 
+// HGetRange gets at most limit fields and values in a hash from the field start, in the order of the fields.
+func (t *TxStructure) HGetRange(key []byte, start []byte, limit int) ([]HashPair, error) {
+	dataPrefix := t.hashDataKeyPrefix(key)
+	it, err := t.reader.Seek(t.encodeHashDataKey(key, start))
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	defer it.Close()
+
+	var res []HashPair
+	for len(res) < limit && it.Valid() && it.Key().HasPrefix(dataPrefix) {
+		_, field, err := t.decodeHashDataKey(it.Key())
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		res = append(res, HashPair{
+			Field: append([]byte{}, field...),
+			Value: append([]byte{}, it.Value()...),
+		})
+		if err = it.Next(); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	return res, nil
+}
+
 // HClear removes the hash value of the key.
 func (t *TxStructure) HClear(key []byte) error {
 	metaKey := t.encodeHashMetaKey(key)
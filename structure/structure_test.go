@@ -210,6 +210,13 @@ func (s *testTxStructureSuite) TestHash(c *C) {
 		{[]byte("1"), []byte("1")},
 		{[]byte("2"), []byte("2")}})
 
+	res, err = tx.HGetRange(key, []byte("11"), 1)
+	c.Assert(err, IsNil)
+	c.Assert(res, DeepEquals, []HashPair{{[]byte("2"), []byte("2")}})
+	res, err = tx.HGetRange(key, []byte("3"), 1)
+	c.Assert(err, IsNil)
+	c.Assert(res, HasLen, 0)
+
 	err = tx.HDel(key, []byte("1"))
 	c.Assert(err, IsNil)
 
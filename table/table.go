@@ -53,6 +53,9 @@ var (
 	ErrIndexStateCantNone = terror.ClassTable.New(codeIndexStateCantNone, "index can not be in none state")
 	// ErrInvalidRecordKey returns for invalid record key.
 	ErrInvalidRecordKey = terror.ClassTable.New(codeInvalidRecordKey, "invalid record key")
+	// ErrTruncatedWrongValue returns for the value which can't be converted to the type of the column when the
+	// rows are copied.
+	ErrTruncatedWrongValue = terror.ClassTable.New(codeTruncatedWrongValue, "truncated wrong value")
 )
 
 // RecordIterFunc is used for low-level record iteration.
@@ -122,7 +125,8 @@ type Table interface {
 // Currently, it is assigned to tables.TableFromMeta in tidb package's init function.
 var TableFromMeta func(alloc autoid.Allocator, tblInfo *model.TableInfo) (Table, error)
 
-// MockTableFromMeta only serves for test.
+// MockTableFromMeta builds a table.Table without an allocator from *model.TableInfo.
+// It only serves for test and the schema tracked without a storage.
 var MockTableFromMeta func(tableInfo *model.TableInfo) Table
 
 // Table error codes.
@@ -137,10 +141,11 @@ const (
 	codeIndexStateCantNone   = 8
 	codeInvalidRecordKey     = 9
 
-	codeColumnCantNull  = 1048
-	codeUnknownColumn   = 1054
-	codeDuplicateColumn = 1110
-	codeNoDefaultValue  = 1364
+	codeColumnCantNull      = 1048
+	codeUnknownColumn       = 1054
+	codeDuplicateColumn     = 1110
+	codeTruncatedWrongValue = 1292
+	codeNoDefaultValue      = 1364
 )
 
 // Slice is used for table sorting.
@@ -156,10 +161,11 @@ func (s Slice) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
 
 func init() {
 	tableMySQLErrCodes := map[terror.ErrCode]uint16{
-		codeColumnCantNull:  mysql.ErrBadNull,
-		codeUnknownColumn:   mysql.ErrBadField,
-		codeDuplicateColumn: mysql.ErrFieldSpecifiedTwice,
-		codeNoDefaultValue:  mysql.ErrNoDefaultForField,
+		codeColumnCantNull:      mysql.ErrBadNull,
+		codeUnknownColumn:       mysql.ErrBadField,
+		codeDuplicateColumn:     mysql.ErrFieldSpecifiedTwice,
+		codeTruncatedWrongValue: mysql.ErrTruncatedWrongValue,
+		codeNoDefaultValue:      mysql.ErrNoDefaultForField,
 	}
 	terror.ErrClassToMySQLCodes[terror.ClassTable] = tableMySQLErrCodes
 }
@@ -19,6 +19,7 @@ import (
 	"io"
 
 	"github.com/juju/errors"
+	"github.com/pingcap/tidb/context"
 	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/table"
@@ -28,12 +29,14 @@ import (
 )
 
 func encodeHandle(h int64) []byte {
-	buf := &bytes.Buffer{}
-	err := binary.Write(buf, binary.BigEndian, h)
-	if err != nil {
-		panic(err)
-	}
-	return buf.Bytes()
+	return appendHandle(nil, h)
+}
+
+// appendHandle appends the big endian encoded handle to b.
+func appendHandle(b []byte, h int64) []byte {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], uint64(h))
+	return append(b, buf[:]...)
 }
 
 func decodeHandle(data []byte) (int64, error) {
@@ -72,8 +75,8 @@ func (c *indexIter) Next() (val []types.Datum, h int64, err error) {
 	if err != nil {
 		return nil, 0, errors.Trace(err)
 	}
-	// if index is *not* unique, the handle is in keybuf
-	if !c.idx.idxInfo.Unique {
+	// if index is *not* unique, or the indexed values of a unique index have null, the handle is in keybuf
+	if !c.idx.idxInfo.Unique || len(vv) > len(c.idx.idxInfo.Columns) {
 		h = vv[len(vv)-1].GetInt64()
 		val = vv[0 : len(vv)-1]
 	} else {
@@ -86,6 +89,10 @@ func (c *indexIter) Next() (val []types.Datum, h int64, err error) {
 	}
 	// update new iter to next
 	err = c.it.Next()
+	// The iterator of a snapshot returns kv.ErrNotExist at the end of the data.
+	if kv.IsErrNotFound(err) {
+		err = nil
+	}
 	if err != nil {
 		return nil, 0, errors.Trace(err)
 	}
@@ -117,6 +124,11 @@ func (c *index) Meta() *model.IndexInfo {
 // GenIndexKey generates storage key for index values. Returned distinct indicates whether the
 // indexed values should be distinct in storage (i.e. whether handle is encoded in the key).
 func (c *index) GenIndexKey(indexedValues []types.Datum, h int64) (key []byte, distinct bool, err error) {
+	return c.genIndexKey(nil, indexedValues, h)
+}
+
+// genIndexKey is like GenIndexKey, but it appends the key to buf.
+func (c *index) genIndexKey(buf []byte, indexedValues []types.Datum, h int64) (key []byte, distinct bool, err error) {
 	if c.idxInfo.Unique {
 		// See https://dev.mysql.com/doc/refman/5.7/en/create-index.html
 		// A UNIQUE index creates a constraint such that all values in the index must be distinct.
@@ -144,11 +156,10 @@ func (c *index) GenIndexKey(indexedValues []types.Datum, h int64) (key []byte, d
 		}
 	}
 
-	key = append(key, []byte(c.prefix)...)
-	if distinct {
-		key, err = codec.EncodeKey(key, indexedValues...)
-	} else {
-		key, err = codec.EncodeKey(key, append(indexedValues, types.NewDatum(h))...)
+	key = append(buf, []byte(c.prefix)...)
+	key, err = codec.EncodeKey(key, indexedValues...)
+	if err == nil && !distinct {
+		key, err = codec.EncodeKey(key, types.NewIntDatum(h))
 	}
 	if err != nil {
 		return nil, false, errors.Trace(err)
@@ -156,23 +167,45 @@ func (c *index) GenIndexKey(indexedValues []types.Datum, h int64) (key []byte, d
 	return
 }
 
+// nonUniqueIndexValue is the value of the index entries whose keys contain the handle.
+// TODO: reconsider value
+var nonUniqueIndexValue = []byte("timestamp?")
+
 // Create creates a new entry in the kvIndex data.
 // If the index is unique and there is an existing entry with the same key,
 // Create will return the existing entry's handle as the first return value, ErrKeyExists as the second return value.
 func (c *index) Create(rm kv.RetrieverMutator, indexedValues []types.Datum, h int64) (int64, error) {
-	key, distinct, err := c.GenIndexKey(indexedValues, h)
+	return c.create(rm, indexedValues, h, nil)
+}
+
+// create is like Create, but it encodes the index KV in buf if buf isn't nil.
+func (c *index) create(rm kv.RetrieverMutator, indexedValues []types.Datum, h int64, buf *indexKVBuffer) (int64, error) {
+	var key []byte
+	if buf != nil {
+		key = buf.key[:0]
+	}
+	key, distinct, err := c.genIndexKey(key, indexedValues, h)
 	if err != nil {
 		return 0, errors.Trace(err)
 	}
+	if buf != nil {
+		buf.key = key
+	}
 	if !distinct {
-		// TODO: reconsider value
-		err = rm.Set(key, []byte("timestamp?"))
+		err = rm.Set(key, nonUniqueIndexValue)
 		return 0, errors.Trace(err)
 	}
 
 	value, err := rm.Get(key)
 	if kv.IsErrNotFound(err) {
-		err = rm.Set(key, encodeHandle(h))
+		var handle []byte
+		if buf != nil {
+			buf.handle = appendHandle(buf.handle[:0], h)
+			handle = buf.handle
+		} else {
+			handle = encodeHandle(h)
+		}
+		err = rm.Set(key, handle)
 		return 0, errors.Trace(err)
 	}
 	handle, err := decodeHandle(value)
@@ -184,10 +217,22 @@ func (c *index) Create(rm kv.RetrieverMutator, indexedValues []types.Datum, h in
 
 // Delete removes the entry for handle h and indexdValues from KV index.
 func (c *index) Delete(m kv.Mutator, indexedValues []types.Datum, h int64) error {
-	key, _, err := c.GenIndexKey(indexedValues, h)
+	return c.delete(m, indexedValues, h, nil)
+}
+
+// delete is like Delete, but it encodes the index key in buf if buf isn't nil.
+func (c *index) delete(m kv.Mutator, indexedValues []types.Datum, h int64, buf *indexKVBuffer) error {
+	var key []byte
+	if buf != nil {
+		key = buf.key[:0]
+	}
+	key, _, err := c.genIndexKey(key, indexedValues, h)
 	if err != nil {
 		return errors.Trace(err)
 	}
+	if buf != nil {
+		buf.key = key
+	}
 	err = m.Delete(key)
 	return errors.Trace(err)
 }
@@ -276,13 +321,78 @@ func (c *index) Exist(rm kv.RetrieverMutator, indexedValues []types.Datum, h int
 }
 
 func (c *index) FetchValues(r []types.Datum) ([]types.Datum, error) {
-	vals := make([]types.Datum, len(c.idxInfo.Columns))
-	for i, ic := range c.idxInfo.Columns {
+	return c.fetchValues(r, make([]types.Datum, 0, len(c.idxInfo.Columns)))
+}
+
+// fetchValues is like FetchValues, but it appends the values to vals[:0].
+func (c *index) fetchValues(r []types.Datum, vals []types.Datum) ([]types.Datum, error) {
+	vals = vals[:0]
+	for _, ic := range c.idxInfo.Columns {
 		if ic.Offset < 0 || ic.Offset >= len(r) {
 			return nil, table.ErrIndexOutBound.Gen("Index column %s offset out of bound, offset: %d, row: %v",
 				ic.Name, ic.Offset, r)
 		}
-		vals[i] = r[ic.Offset]
+		vals = append(vals, r[ic.Offset])
 	}
 	return vals, nil
 }
+
+// indexKVBuffer holds the buffers to encode the index KVs of the rows a session writes, so writing a row to a table
+// with many indices doesn't allocate for every index KV. A buffer can be reused once its KV is set, because the
+// mutators copy the keys and values.
+type indexKVBuffer struct {
+	key    []byte
+	handle []byte
+	vals   []types.Datum
+}
+
+type indexKVBufferKeyType int
+
+func (k indexKVBufferKeyType) String() string {
+	return "indexKVBufferKeyType"
+}
+
+// indexKVBufferKey is the key to *indexKVBuffer for a context.
+const indexKVBufferKey indexKVBufferKeyType = 0
+
+func getIndexKVBuffer(ctx context.Context) *indexKVBuffer {
+	if buf, ok := ctx.Value(indexKVBufferKey).(*indexKVBuffer); ok {
+		return buf
+	}
+	buf := &indexKVBuffer{}
+	ctx.SetValue(indexKVBufferKey, buf)
+	return buf
+}
+
+// fetchValues fetches the index column values of a row into the buffer.
+func (buf *indexKVBuffer) fetchValues(idx table.Index, r []types.Datum) ([]types.Datum, error) {
+	c, ok := idx.(*index)
+	if !ok {
+		vals, err := idx.FetchValues(r)
+		return vals, errors.Trace(err)
+	}
+	vals, err := c.fetchValues(r, buf.vals)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	buf.vals = vals
+	return vals, nil
+}
+
+// create creates the index entry by the buffer, see Index.Create.
+func (buf *indexKVBuffer) create(rm kv.RetrieverMutator, idx table.Index, vals []types.Datum, h int64) (int64, error) {
+	if c, ok := idx.(*index); ok {
+		dupHandle, err := c.create(rm, vals, h, buf)
+		return dupHandle, errors.Trace(err)
+	}
+	dupHandle, err := idx.Create(rm, vals, h)
+	return dupHandle, errors.Trace(err)
+}
+
+// delete removes the index entry by the buffer, see Index.Delete.
+func (buf *indexKVBuffer) delete(m kv.Mutator, idx table.Index, vals []types.Datum, h int64) error {
+	if c, ok := idx.(*index); ok {
+		return errors.Trace(c.delete(m, vals, h, buf))
+	}
+	return errors.Trace(idx.Delete(m, vals, h))
+}
@@ -167,6 +167,24 @@ func (s *testIndexSuite) TestIndex(c *C) {
 	c.Assert(h, Equals, int64(1))
 	c.Assert(exist, IsTrue)
 
+	// The values with null aren't distinct, the handle is in the key.
+	nullValues := types.MakeDatums(nil, 2)
+	_, err = index.Create(txn, nullValues, 3)
+	c.Assert(err, IsNil)
+	_, err = index.Create(txn, nullValues, 4)
+	c.Assert(err, IsNil)
+	it, err = index.SeekFirst(txn)
+	c.Assert(err, IsNil)
+	for _, handle := range []int64{3, 4, 1} {
+		getValues, getHandle, err1 := it.Next()
+		c.Assert(err1, IsNil)
+		c.Assert(getHandle, Equals, handle)
+		c.Assert(getValues, HasLen, 2)
+	}
+	_, _, err = it.Next()
+	c.Assert(terror.ErrorEqual(err, io.EOF), IsTrue)
+	it.Close()
+
 	err = txn.Commit()
 	c.Assert(err, IsNil)
 
@@ -0,0 +1,269 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tables
+
+import (
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/table"
+	"github.com/pingcap/tidb/tablecodec"
+	"github.com/pingcap/tidb/terror"
+	"github.com/pingcap/tidb/util/types"
+)
+
+// A shadow table is the copy of a table with the altered columns, which is built by a shadow copy DDL job.
+// The rows of the table are copied to the shadow table with the same handles, and the rows written to the table
+// during the copy are written to the shadow table as well, like an index of the table:
+//  1. In delete only state, the rows removed from the table are removed from the shadow table.
+//  2. In write only and write reorganization states, the rows added or updated in the table are converted to the
+//     column types of the shadow table and written to it too. A row which can't be written marks the shadow table
+//     broken rather than failing the statement, and the DDL job checks the mark.
+
+// writeShadow writes the row of the handle to the shadow table if the table is being copied, the old row of the
+// handle in the shadow table is replaced. The statement never fails because of the shadow table: if a value can't be
+// converted or the row breaks a unique index of the shadow table, the row isn't written and the shadow table is
+// marked broken instead, so the job is rolled back.
+func (t *Table) writeShadow(ctx context.Context, h int64, r []types.Datum) error {
+	if t.shadow == nil {
+		return nil
+	}
+	txn, err := ctx.GetTxn(false)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if err = t.shadow.removeShadowRow(txn, h); err != nil {
+		return errors.Trace(err)
+	}
+	if t.shadow.meta.State == model.StateDeleteOnly {
+		return nil
+	}
+	row, err := t.convertShadowRow(r)
+	if err != nil {
+		return errors.Trace(t.shadow.markShadowBroken(txn, err))
+	}
+	for _, idx := range t.shadow.indices {
+		if !idx.Meta().Unique {
+			continue
+		}
+		idxVals, err := idx.FetchValues(row)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		_, _, err = idx.Exist(txn, idxVals, h)
+		if terror.ErrorEqual(err, kv.ErrKeyExists) {
+			return errors.Trace(t.shadow.markShadowBroken(txn, t.shadow.dupEntryError(idx, idxVals)))
+		}
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return errors.Trace(t.shadow.setShadowRow(txn, h, row))
+}
+
+// convertShadowRow converts the row of the table to the column types of the shadow table. It returns
+// table.ErrTruncatedWrongValue if a value can't be converted.
+func (t *Table) convertShadowRow(r []types.Datum) ([]types.Datum, error) {
+	row := make([]types.Datum, len(t.shadow.Columns))
+	for i, col := range t.shadow.Columns {
+		var err error
+		row[i], err = r[col.Offset].ConvertTo(&col.FieldType)
+		if err != nil {
+			str, _ := r[col.Offset].ToString()
+			return nil, table.ErrTruncatedWrongValue.Gen("Truncated incorrect %s value: '%s' for column '%s'",
+				col.FieldType.CompactStr(), str, col.Name)
+		}
+	}
+	return row, nil
+}
+
+// Shadow broken marks.
+const (
+	shadowBrokenDupEntry   byte = 'd'
+	shadowBrokenWrongValue byte = 'v'
+)
+
+// shadowBrokenKey returns the key of the mark of the shadow table, which records the first row a statement couldn't
+// write to the shadow table. The key is in the key range of the shadow table, so it's deleted with the shadow table.
+func (t *Table) shadowBrokenKey() kv.Key {
+	return append(tablecodec.EncodeTablePrefix(t.ID), '_', 'b')
+}
+
+// markShadowBroken marks the shadow table broken by the error, the first mark is kept.
+func (t *Table) markShadowBroken(rm kv.RetrieverMutator, cause error) error {
+	key := t.shadowBrokenKey()
+	_, err := rm.Get(key)
+	if err == nil {
+		return nil
+	}
+	if !terror.ErrorEqual(err, kv.ErrNotExist) {
+		return errors.Trace(err)
+	}
+	kind := shadowBrokenWrongValue
+	if terror.ErrorEqual(cause, kv.ErrKeyExists) {
+		kind = shadowBrokenDupEntry
+	}
+	log.Warnf("[tables] can't write the row to the shadow table %d: %v, the DDL job will be rolled back", t.ID, cause)
+	return errors.Trace(rm.Set(key, append([]byte{kind}, cause.Error()...)))
+}
+
+// CheckShadowBroken returns the error of the row which a statement couldn't write to the shadow table of the table,
+// kv.ErrKeyExists or table.ErrTruncatedWrongValue, or nil if all the rows have been written. The mark is locked, so
+// the transaction conflicts with the statements which mark the shadow table broken meanwhile.
+func CheckShadowBroken(txn kv.Transaction, t table.Table) error {
+	tbl, ok := t.(*Table)
+	if !ok || tbl.shadow == nil {
+		return errors.Errorf("table %s isn't being copied", t.Meta().Name)
+	}
+	key := tbl.shadow.shadowBrokenKey()
+	if err := txn.LockKeys(key); err != nil {
+		return errors.Trace(err)
+	}
+	value, err := txn.Get(key)
+	if terror.ErrorEqual(err, kv.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if len(value) > 0 && value[0] == shadowBrokenDupEntry {
+		return kv.ErrKeyExists.FastGen("%s", value[1:])
+	}
+	return table.ErrTruncatedWrongValue.Gen("%s", value[1:])
+}
+
+// removeShadow removes the row of the handle from the shadow table if the table is being copied.
+func (t *Table) removeShadow(ctx context.Context, h int64) error {
+	if t.shadow == nil {
+		return nil
+	}
+	txn, err := ctx.GetTxn(false)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(t.shadow.removeShadowRow(txn, h))
+}
+
+// setShadowRow sets the row of the handle and its index entries in the shadow table.
+func (t *Table) setShadowRow(rm kv.RetrieverMutator, h int64, row []types.Datum) error {
+	colIDs := make([]int64, 0, len(row))
+	vals := make([]types.Datum, 0, len(row))
+	for _, col := range t.Columns {
+		if col.IsPKHandleColumn(t.meta) {
+			continue
+		}
+		if col.DefaultValue == nil && row[col.Offset].IsNull() {
+			continue
+		}
+		colIDs = append(colIDs, col.ID)
+		vals = append(vals, row[col.Offset])
+	}
+	value, err := tablecodec.EncodeRow(vals, colIDs)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if err = rm.Set(t.RecordKey(h), value); err != nil {
+		return errors.Trace(err)
+	}
+	for _, idx := range t.indices {
+		idxVals, err := idx.FetchValues(row)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if _, err = idx.Create(rm, idxVals, h); err != nil {
+			if terror.ErrorEqual(err, kv.ErrKeyExists) {
+				return t.dupEntryError(idx, idxVals)
+			}
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+// dupEntryError returns the kv.ErrKeyExists of the values of the unique index.
+func (t *Table) dupEntryError(idx table.Index, idxVals []types.Datum) error {
+	entryKey, err := t.genIndexKeyStr(idxVals)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return kv.ErrKeyExists.FastGen("Duplicate entry '%s' for key '%s'", entryKey, idx.Meta().Name)
+}
+
+// removeShadowRow removes the row of the handle and its index entries from the shadow table, if the row has been
+// copied.
+func (t *Table) removeShadowRow(rm kv.RetrieverMutator, h int64) error {
+	key := t.RecordKey(h)
+	value, err := rm.Get(key)
+	if terror.ErrorEqual(err, kv.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return errors.Trace(err)
+	}
+	row, err := DecodeRawRowData(t.meta, h, t.Columns, value)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for _, idx := range t.indices {
+		idxVals, err := idx.FetchValues(row)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if err = idx.Delete(rm, idxVals, h); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return errors.Trace(rm.Delete(key))
+}
+
+// CopyShadowRecord copies the row of the handle in the table to the shadow table of the table, the values are
+// converted to the column types of the shadow table. It returns table.ErrTruncatedWrongValue if a value can't be
+// converted. The row is skipped if it has been removed from the table, or it has been written to the shadow table by
+// a statement.
+func CopyShadowRecord(txn kv.Transaction, t table.Table, h int64) error {
+	tbl, ok := t.(*Table)
+	if !ok || tbl.shadow == nil {
+		return errors.Errorf("table %s isn't being copied", t.Meta().Name)
+	}
+	rowKey := tbl.RecordKey(h)
+	value, err := txn.Get(rowKey)
+	if terror.ErrorEqual(err, kv.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return errors.Trace(err)
+	}
+	_, err = txn.Get(tbl.shadow.RecordKey(h))
+	if err == nil {
+		return nil
+	}
+	if !terror.ErrorEqual(err, kv.ErrNotExist) {
+		return errors.Trace(err)
+	}
+	r, err := DecodeRawRowData(tbl.meta, h, tbl.Columns, value)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	row, err := tbl.convertShadowRow(r)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	// The row is locked, so the transaction conflicts with the statements which change the row meanwhile.
+	if err = txn.LockKeys(rowKey); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(tbl.shadow.setShadowRow(txn, h, row))
+}
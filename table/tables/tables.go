@@ -51,11 +51,23 @@ type Table struct {
 	indexPrefix     kv.Key
 	alloc           autoid.Allocator
 	meta            *model.TableInfo
+	// shadow is the shadow table which the table is being copied to, see shadow.go.
+	shadow *Table
 }
 
-// MockTableFromMeta only serves for test.
+// MockTableFromMeta creates a Table instance from model.TableInfo without an allocator, so the rows can't be
+// added. It only serves for test and the schema tracked without a storage.
 func MockTableFromMeta(tableInfo *model.TableInfo) table.Table {
-	return &Table{ID: 0, meta: tableInfo}
+	columns := make([]*table.Column, 0, len(tableInfo.Columns))
+	for _, colInfo := range tableInfo.Columns {
+		columns = append(columns, table.ToColumn(colInfo))
+	}
+	t := newTable(tableInfo.ID, columns, nil)
+	for _, idxInfo := range tableInfo.Indices {
+		t.indices = append(t.indices, NewIndex(tableInfo, idxInfo))
+	}
+	t.meta = tableInfo
+	return t
 }
 
 // TableFromMeta creates a Table instance from model.TableInfo.
@@ -85,6 +97,14 @@ func TableFromMeta(alloc autoid.Allocator, tblInfo *model.TableInfo) (table.Tabl
 		t.indices = append(t.indices, idx)
 	}
 
+	if tblInfo.Shadow != nil {
+		shadow, err := TableFromMeta(nil, tblInfo.Shadow)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		t.shadow = shadow.(*Table)
+	}
+
 	t.meta = tblInfo
 	return t, nil
 }
@@ -224,7 +244,7 @@ func (t *Table) UpdateRecord(ctx context.Context, h int64, oldData []types.Datum
 	}
 
 	// rebuild index
-	if err = t.rebuildIndices(bs, h, touched, oldData, currentData); err != nil {
+	if err = t.rebuildIndices(bs, h, touched, oldData, currentData, getIndexKVBuffer(ctx)); err != nil {
 		return errors.Trace(err)
 	}
 
@@ -232,6 +252,9 @@ func (t *Table) UpdateRecord(ctx context.Context, h int64, oldData []types.Datum
 	if err != nil {
 		return errors.Trace(err)
 	}
+	if err = t.writeShadow(ctx, h, currentData); err != nil {
+		return errors.Trace(err)
+	}
 	if shouldWriteBinlog(ctx) {
 		t.addUpdateBinlog(ctx, h, oldData, value, colIDs)
 	}
@@ -265,7 +288,7 @@ func (t *Table) composeNewData(touched map[int]bool, newData []types.Datum, oldD
 	return
 }
 
-func (t *Table) rebuildIndices(rm kv.RetrieverMutator, h int64, touched map[int]bool, oldData []types.Datum, newData []types.Datum) error {
+func (t *Table) rebuildIndices(rm kv.RetrieverMutator, h int64, touched map[int]bool, oldData []types.Datum, newData []types.Datum, kvBuf *indexKVBuffer) error {
 	for _, idx := range t.Indices() {
 		idxTouched := false
 		for _, ic := range idx.Meta().Columns {
@@ -278,21 +301,21 @@ func (t *Table) rebuildIndices(rm kv.RetrieverMutator, h int64, touched map[int]
 			continue
 		}
 
-		oldVs, err := idx.FetchValues(oldData)
+		oldVs, err := kvBuf.fetchValues(idx, oldData)
 		if err != nil {
 			return errors.Trace(err)
 		}
 
-		if t.removeRowIndex(rm, h, oldVs, idx); err != nil {
+		if t.removeRowIndex(rm, h, oldVs, idx, kvBuf); err != nil {
 			return errors.Trace(err)
 		}
 
-		newVs, err := idx.FetchValues(newData)
+		newVs, err := kvBuf.fetchValues(idx, newData)
 		if err != nil {
 			return errors.Trace(err)
 		}
 
-		if err := t.buildIndexForRow(rm, h, newVs, idx); err != nil {
+		if err := t.buildIndexForRow(rm, h, newVs, idx, kvBuf); err != nil {
 			return errors.Trace(err)
 		}
 	}
@@ -361,6 +384,9 @@ func (t *Table) AddRecord(ctx context.Context, r []types.Datum) (recordID int64,
 	if err = bs.SaveTo(txn); err != nil {
 		return 0, errors.Trace(err)
 	}
+	if err = t.writeShadow(ctx, recordID, r); err != nil {
+		return 0, errors.Trace(err)
+	}
 	if shouldWriteBinlog(ctx) {
 		mutation := t.getMutation(ctx)
 		// prepend handle to the row value
@@ -400,6 +426,7 @@ func (t *Table) addIndices(ctx context.Context, recordID int64, r []types.Datum,
 	// Clean up lazy check error environment
 	defer txn.DelOption(kv.PresumeKeyNotExistsError)
 	skipCheck := ctx.GetSessionVars().SkipConstraintCheck
+	kvBuf := getIndexKVBuffer(ctx)
 	if t.meta.PKIsHandle && !skipCheck {
 		// Check key exists.
 		recordKey := t.RecordKey(recordID)
@@ -419,7 +446,7 @@ func (t *Table) addIndices(ctx context.Context, recordID int64, r []types.Datum,
 			// if index is in delete only or delete reorganization state, we can't add it.
 			continue
 		}
-		colVals, err2 := v.FetchValues(r)
+		colVals, err2 := kvBuf.fetchValues(v, r)
 		if err2 != nil {
 			return 0, errors.Trace(err2)
 		}
@@ -432,7 +459,7 @@ func (t *Table) addIndices(ctx context.Context, recordID int64, r []types.Datum,
 			dupKeyErr = kv.ErrKeyExists.FastGen("Duplicate entry '%s' for key '%s'", entryKey, v.Meta().Name)
 			txn.SetOption(kv.PresumeKeyNotExistsError, dupKeyErr)
 		}
-		if dupHandle, err := v.Create(bs, colVals, recordID); err != nil {
+		if dupHandle, err := kvBuf.create(bs, v, colVals, recordID); err != nil {
 			if terror.ErrorEqual(err, kv.ErrKeyExists) {
 				return dupHandle, errors.Trace(dupKeyErr)
 			}
@@ -455,14 +482,20 @@ func (t *Table) RowWithCols(ctx context.Context, h int64, cols []*table.Column)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
-	// Decode raw row data.
+	v, err := DecodeRawRowData(t.meta, h, cols, value)
+	return v, errors.Trace(err)
+}
+
+// DecodeRawRowData decodes the raw data of the row of the handle to the datums of the columns, the nil columns are
+// skipped.
+func DecodeRawRowData(meta *model.TableInfo, h int64, cols []*table.Column, value []byte) ([]types.Datum, error) {
 	v := make([]types.Datum, len(cols))
 	colTps := make(map[int64]*types.FieldType, len(cols))
 	for i, col := range cols {
 		if col == nil {
 			continue
 		}
-		if col.IsPKHandleColumn(t.meta) {
+		if col.IsPKHandleColumn(meta) {
 			if mysql.HasUnsignedFlag(col.Flag) {
 				v[i].SetUint64(uint64(h))
 			} else {
@@ -480,7 +513,7 @@ func (t *Table) RowWithCols(ctx context.Context, h int64, cols []*table.Column)
 		if col == nil {
 			continue
 		}
-		if col.IsPKHandleColumn(t.meta) {
+		if col.IsPKHandleColumn(meta) {
 			continue
 		}
 		ri, ok := row[col.ID]
@@ -512,6 +545,9 @@ func (t *Table) RemoveRecord(ctx context.Context, h int64, r []types.Datum) erro
 	if err != nil {
 		return errors.Trace(err)
 	}
+	if err = t.removeShadow(ctx, h); err != nil {
+		return errors.Trace(err)
+	}
 	if shouldWriteBinlog(ctx) {
 		err = t.addDeleteBinlog(ctx, h, r)
 	}
@@ -629,21 +665,21 @@ func (t *Table) removeRowIndices(ctx context.Context, h int64, rec []types.Datum
 }
 
 // RemoveRowIndex implements table.Table RemoveRowIndex interface.
-func (t *Table) removeRowIndex(rm kv.RetrieverMutator, h int64, vals []types.Datum, idx table.Index) error {
-	if err := idx.Delete(rm, vals, h); err != nil {
+func (t *Table) removeRowIndex(rm kv.RetrieverMutator, h int64, vals []types.Datum, idx table.Index, kvBuf *indexKVBuffer) error {
+	if err := kvBuf.delete(rm, idx, vals, h); err != nil {
 		return errors.Trace(err)
 	}
 	return nil
 }
 
 // BuildIndexForRow implements table.Table BuildIndexForRow interface.
-func (t *Table) buildIndexForRow(rm kv.RetrieverMutator, h int64, vals []types.Datum, idx table.Index) error {
+func (t *Table) buildIndexForRow(rm kv.RetrieverMutator, h int64, vals []types.Datum, idx table.Index, kvBuf *indexKVBuffer) error {
 	if idx.Meta().State == model.StateDeleteOnly || idx.Meta().State == model.StateDeleteReorganization {
 		// If the index is in delete only or write reorganization state, we can not add index.
 		return nil
 	}
 
-	if _, err := idx.Create(rm, vals, h); err != nil {
+	if _, err := kvBuf.create(rm, idx, vals, h); err != nil {
 		return errors.Trace(err)
 	}
 	return nil
@@ -27,6 +27,7 @@ import (
 	"github.com/pingcap/tidb/table"
 	"github.com/pingcap/tidb/table/tables"
 	"github.com/pingcap/tidb/tablecodec"
+	"github.com/pingcap/tidb/terror"
 	"github.com/pingcap/tidb/util"
 	"github.com/pingcap/tidb/util/testleak"
 	"github.com/pingcap/tidb/util/types"
@@ -207,6 +208,59 @@ func (ts *testSuite) TestUniqueIndexMultipleNullEntries(c *C) {
 	c.Assert(err, IsNil)
 }
 
+func (ts *testSuite) TestMultipleIndices(c *C) {
+	_, err := ts.se.Execute("CREATE TABLE test.t (a int primary key, b varchar(255) unique, c int, d varchar(10), index (c, d), index (d))")
+	c.Assert(err, IsNil)
+	ctx := ts.se.(context.Context)
+	dom := sessionctx.GetDomain(ctx)
+	tb, err := dom.InfoSchema().TableByName(model.NewCIStr("test"), model.NewCIStr("t"))
+	c.Assert(err, IsNil)
+
+	// The index KVs of the rows are encoded in the same buffer, every entry must be kept.
+	rows := [][]types.Datum{
+		types.MakeDatums(1, "a", 10, "x"),
+		types.MakeDatums(2, "bb", 20, "yy"),
+		types.MakeDatums(3, "ccc", nil, "zzz"),
+	}
+	for _, row := range rows {
+		_, err = tb.AddRecord(ctx, row)
+		c.Assert(err, IsNil)
+	}
+	txn, err := ctx.GetTxn(false)
+	c.Assert(err, IsNil)
+	for _, row := range rows {
+		h := row[0].GetInt64()
+		for _, idx := range tb.Indices() {
+			vals, err1 := idx.FetchValues(row)
+			c.Assert(err1, IsNil)
+			exist, existH, err1 := idx.Exist(txn, vals, h)
+			c.Assert(err1, IsNil)
+			c.Assert(exist, IsTrue)
+			c.Assert(existH, Equals, h)
+		}
+	}
+	_, err = tb.AddRecord(ctx, types.MakeDatums(4, "bb", 40, "w"))
+	c.Assert(terror.ErrorEqual(err, kv.ErrKeyExists), IsTrue)
+
+	newRow := types.MakeDatums(2, "dddd", 20, "v")
+	c.Assert(tb.UpdateRecord(ctx, 2, rows[1], newRow, map[int]bool{1: true, 3: true}), IsNil)
+	for _, idx := range tb.Indices() {
+		vals, err1 := idx.FetchValues(rows[1])
+		c.Assert(err1, IsNil)
+		exist, _, err1 := idx.Exist(txn, vals, 2)
+		c.Assert(err1, IsNil)
+		c.Assert(exist, IsFalse)
+		vals, err1 = idx.FetchValues(newRow)
+		c.Assert(err1, IsNil)
+		exist, _, err1 = idx.Exist(txn, vals, 2)
+		c.Assert(err1, IsNil)
+		c.Assert(exist, IsTrue)
+	}
+
+	_, err = ts.se.Execute("drop table test.t")
+	c.Assert(err, IsNil)
+}
+
 func (ts *testSuite) TestRowKeyCodec(c *C) {
 	table := []struct {
 		tableID int64
@@ -180,6 +180,28 @@ func DecodeValues(data []byte, fts []*types.FieldType, inIndex bool) ([]types.Da
 	return values, nil
 }
 
+// CutValues cuts a byte slice into the encoded values of the columns without decoding them, the values are
+// appended to vals and can be decoded by DecodeColumnValue later.
+func CutValues(data []byte, fts []*types.FieldType, vals [][]byte) ([][]byte, error) {
+	cnt := 0
+	for len(data) > 0 {
+		if cnt == len(fts) {
+			return nil, errInvalidColumnCount.Gen("invalid column count %d is less than value count %d", len(fts), cnt+1)
+		}
+		var (
+			val []byte
+			err error
+		)
+		val, data, err = codec.CutOne(data)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		vals = append(vals, val)
+		cnt++
+	}
+	return vals, nil
+}
+
 // DecodeColumnValue decodes data to a Datum according to the column info.
 func DecodeColumnValue(data []byte, ft *types.FieldType) (types.Datum, error) {
 	_, d, err := codec.DecodeOne(data)
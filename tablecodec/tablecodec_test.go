@@ -132,6 +132,27 @@ func (s *testTableCodecSuite) TestRowCodec(c *C) {
 	c.Assert(r, IsNil)
 }
 
+func (s *testTableCodecSuite) TestCutValues(c *C) {
+	defer testleak.AfterTest(c)()
+
+	fts := []*types.FieldType{types.NewFieldType(mysql.TypeLonglong), types.NewFieldType(mysql.TypeVarchar)}
+	bs, err := codec.EncodeValue(nil, types.NewIntDatum(100), types.NewBytesDatum([]byte("abc")))
+	c.Assert(err, IsNil)
+	vals, err := CutValues(bs, fts, nil)
+	c.Assert(err, IsNil)
+	c.Assert(vals, HasLen, 2)
+	d, err := DecodeColumnValue(vals[1], fts[1])
+	c.Assert(err, IsNil)
+	c.Assert(d.GetBytes(), BytesEquals, []byte("abc"))
+	d, err = DecodeColumnValue(vals[0], fts[0])
+	c.Assert(err, IsNil)
+	c.Assert(d.GetInt64(), Equals, int64(100))
+
+	// The values are more than the columns.
+	_, err = CutValues(bs, fts[:1], nil)
+	c.Assert(err, NotNil)
+}
+
 func (s *testTableCodecSuite) TestTimeCodec(c *C) {
 	defer testleak.AfterTest(c)()
 
@@ -20,6 +20,7 @@ import (
 	"os"
 	"os/signal"
 	"runtime"
+	"strconv"
 	"syscall"
 	"time"
 
@@ -27,14 +28,20 @@ import (
 	"github.com/ngaut/log"
 	"github.com/ngaut/systimemon"
 	"github.com/pingcap/tidb"
+	"github.com/pingcap/tidb/executor"
 	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/perfschema"
 	"github.com/pingcap/tidb/plan"
 	"github.com/pingcap/tidb/server"
 	"github.com/pingcap/tidb/sessionctx/binloginfo"
+	"github.com/pingcap/tidb/sessionctx/variable"
 	"github.com/pingcap/tidb/store/localstore/boltdb"
 	"github.com/pingcap/tidb/store/tikv"
+	"github.com/pingcap/tidb/util/admission"
+	"github.com/pingcap/tidb/util/memory"
 	"github.com/pingcap/tidb/util/printer"
+	"github.com/pingcap/tidb/util/stmtsummary"
 	"github.com/pingcap/tipb/go-binlog"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/push"
@@ -59,6 +66,16 @@ var (
 	metricsAddr     = flag.String("metrics-addr", "", "prometheus pushgateway address, leaves it empty will disable prometheus push.")
 	metricsInterval = flag.Int("metrics-interval", 15, "prometheus client push interval in second, set \"0\" to disable prometheus push.")
 	binlogSocket    = flag.String("binlog-socket", "", "socket file to write binlog")
+	memQuotaServer  = flag.Int64("mem-quota-server", 0, "the memory limit in bytes of the executors of all the statements, the sorts of the statement using the most memory spill when 80% of it is used, and it's canceled when the limit is exceeded, set \"0\" to disable the limit.")
+	maxRunning      = flag.Int("max-running-statements", 0, "the max number of the running statements which may scan many rows, the others are queued when it's reached, set \"0\" to disable the limit.")
+	queueTimeout    = flag.Duration("statement-queue-timeout", admission.DefaultQueueTimeout, "how long a statement waits in the queue of the running statements before it fails, set \"0\" to fail it right away.")
+	sortSpillPath   = flag.String("sort-spill-path", "", "the directory of the temporary files which the sorts exceeding tidb_mem_quota_sort spill their rows to, leave it empty to use the default directory for temporary files.")
+	secureFilePriv  = flag.String("secure-file-priv", "", "the directory of the files LOAD DATA INFILE reads and SELECT ... INTO OUTFILE writes on the server, leave it empty to disable them.")
+	stmtSummarySize = flag.Int("stmt-summary-max-statements", stmtsummary.DefaultMaxStatements, "the max number of the statements whose executions and sample plans are summarized in memory, set \"0\" to disable the statement summary.")
+	rpcTimeouts     = flag.String("tikv-rpc-timeouts", "", "the timeouts of the RPCs to TiKV and PD by their types, e.g. \"get=5s,cop=30s,pd=1s\", the types are get, scan, batch_get, prewrite, commit, cleanup, batch_rollback, scan_lock, resolve_lock, gc, cop and pd.")
+	breakerLimit    = flag.Int("tikv-breaker-threshold", tikv.DefaultBreakerThreshold, "the number of the consecutive timeouts of a TiKV store which make the requests to it fail fast until it's probed healthy, set \"0\" to disable it.")
+	breakerCooldown = flag.Duration("tikv-breaker-cooldown", tikv.DefaultBreakerCooldown, "how long the requests to a TiKV store fail fast before it's probed again.")
+	lowerCaseNames  = flag.Int("lower-case-table-names", model.CaseInsensitiveNames, "how the database names, the table names and the table aliases are stored and compared, 0 stores them as given and compares them case sensitively, 1 stores them in lowercase, 2 stores them as given and compares them case insensitively, don't change it after the databases are created.")
 )
 
 func main() {
@@ -97,6 +114,20 @@ func main() {
 		plan.JoinConcurrency = *joinCon
 	}
 	plan.AllowCartesianProduct = *crossJoin
+	memory.GlobalArbiter.SetLimit(*memQuotaServer)
+	admission.GlobalController.SetLimit(*maxRunning)
+	admission.GlobalController.SetQueueTimeout(*queueTimeout)
+	executor.SortSpillDir = *sortSpillPath
+	executor.SecureFilePriv = *secureFilePriv
+	stmtsummary.GlobalSummary.SetMaxStatements(*stmtSummarySize)
+	if err := tikv.SetRPCTimeouts(*rpcTimeouts); err != nil {
+		log.Fatal(errors.ErrorStack(err))
+	}
+	tikv.SetStoreBreaker(*breakerLimit, *breakerCooldown)
+	if err := model.SetLowerCaseTableNames(*lowerCaseNames); err != nil {
+		log.Fatal(errors.ErrorStack(err))
+	}
+	variable.SysVars[variable.LowerCaseTableNames].Value = strconv.Itoa(*lowerCaseNames)
 	// Call this before setting log level to make sure that TiDB info could be printed.
 	printer.PrintTiDBInfo()
 	log.SetLevelByString(cfg.LogLevel)
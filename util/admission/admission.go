@@ -0,0 +1,218 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package admission limits the number of the expensive statements running in the server. When the limit is
+// reached, the incoming expensive statements wait in a FIFO queue until a running one finishes or the queue
+// timeout expires, so the server isn't thrashed by too many concurrent scans under overload. The cheap
+// statements, e.g. the point reads, are always admitted right away.
+package admission
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// DefaultQueueTimeout is how long a statement waits in the queue by default.
+const DefaultQueueTimeout = 10 * time.Second
+
+// interruptCheckInterval is how often a queued statement checks whether it's interrupted.
+const interruptCheckInterval = 10 * time.Millisecond
+
+// GlobalController admits the statements of the server, it has no limit by default.
+var GlobalController = NewController(0, DefaultQueueTimeout)
+
+// Class is the admission class of a statement.
+type Class int
+
+const (
+	// ClassExempt is for the statements which are never queued or counted, e.g. SET, SHOW and the DDLs,
+	// so the server is still manageable under overload.
+	ClassExempt Class = iota
+	// ClassPoint is for the statements which only read or write a few rows by the handles or the unique keys.
+	// They're admitted right away and aren't counted toward the limit.
+	ClassPoint
+	// ClassScan is for the statements which may scan many rows, they're counted toward the limit and queued
+	// when it's reached.
+	ClassScan
+)
+
+// String implements the fmt.Stringer interface.
+func (c Class) String() string {
+	switch c {
+	case ClassExempt:
+		return "exempt"
+	case ClassPoint:
+		return "point"
+	default:
+		return "scan"
+	}
+}
+
+// Stats is the statistics of a Controller.
+type Stats struct {
+	Limit   int
+	Running int
+	Queued  int
+	// Admitted, Waited and TimedOut are the total numbers of the ClassScan statements admitted, admitted after
+	// waiting in the queue and failed for the queue timeout.
+	Admitted uint64
+	Waited   uint64
+	TimedOut uint64
+}
+
+type waiter struct {
+	ch      chan struct{}
+	granted bool
+	elem    *list.Element
+}
+
+// Controller admits the statements by their classes.
+type Controller struct {
+	mu struct {
+		sync.Mutex
+		limit   int
+		timeout time.Duration
+		running int
+		// waiters is the queue of *waiter, a slot released is handed over to the first waiter.
+		waiters list.List
+		stats   Stats
+	}
+}
+
+// NewController creates a Controller, a limit no more than 0 means no limit.
+func NewController(limit int, timeout time.Duration) *Controller {
+	c := &Controller{}
+	c.mu.limit = limit
+	c.mu.timeout = timeout
+	return c
+}
+
+// SetLimit sets the max number of the running ClassScan statements, a limit no more than 0 means no limit.
+// The queued statements are admitted if the new limit allows.
+func (c *Controller) SetLimit(limit int) {
+	c.mu.Lock()
+	c.mu.limit = limit
+	for c.mu.waiters.Len() > 0 && (limit <= 0 || c.mu.running < limit) {
+		c.mu.running++
+		c.grantFirst()
+	}
+	c.mu.Unlock()
+}
+
+// SetQueueTimeout sets how long a statement waits in the queue, a timeout no more than 0 means the statements
+// are rejected right away instead of being queued.
+func (c *Controller) SetQueueTimeout(timeout time.Duration) {
+	c.mu.Lock()
+	c.mu.timeout = timeout
+	c.mu.Unlock()
+}
+
+// Admit admits a statement of the class, it blocks while the statement is queued. It returns false if the statement
+// isn't admitted before the queue timeout, or interrupted returns true while it's queued, e.g. it's killed.
+// A nil interrupted never interrupts the statement. The returned function must be called when the admitted
+// statement finishes, calling it more than once has no effect.
+func (c *Controller) Admit(class Class, interrupted func() bool) (release func(), ok bool) {
+	if class != ClassScan {
+		return func() {}, true
+	}
+	c.mu.Lock()
+	if c.mu.limit <= 0 || (c.mu.running < c.mu.limit && c.mu.waiters.Len() == 0) {
+		c.mu.running++
+		c.mu.stats.Admitted++
+		c.mu.Unlock()
+		return c.releaseFunc(), true
+	}
+	timeout := c.mu.timeout
+	if timeout <= 0 {
+		c.mu.stats.TimedOut++
+		c.mu.Unlock()
+		return nil, false
+	}
+	w := &waiter{ch: make(chan struct{})}
+	w.elem = c.mu.waiters.PushBack(w)
+	c.mu.Unlock()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	var tick <-chan time.Time
+	if interrupted != nil {
+		ticker := time.NewTicker(interruptCheckInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	timedOut := false
+WAIT:
+	for {
+		select {
+		case <-w.ch:
+			return c.releaseFunc(), true
+		case <-timer.C:
+			timedOut = true
+			break WAIT
+		case <-tick:
+			if interrupted() {
+				break WAIT
+			}
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// The slot may be handed over after the timer fires or the statement is interrupted.
+	if w.granted {
+		return c.releaseFunc(), true
+	}
+	c.mu.waiters.Remove(w.elem)
+	if timedOut {
+		c.mu.stats.TimedOut++
+	}
+	return nil, false
+}
+
+func (c *Controller) releaseFunc() func() {
+	var once sync.Once
+	return func() {
+		once.Do(c.release)
+	}
+}
+
+func (c *Controller) release() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.mu.waiters.Len() > 0 && (c.mu.limit <= 0 || c.mu.running <= c.mu.limit) {
+		// The slot is handed over, so the number of the running statements is unchanged.
+		c.grantFirst()
+		return
+	}
+	c.mu.running--
+}
+
+// grantFirst admits the first waiter, the caller must hold the lock and have counted it as running.
+func (c *Controller) grantFirst() {
+	w := c.mu.waiters.Remove(c.mu.waiters.Front()).(*waiter)
+	w.granted = true
+	c.mu.stats.Admitted++
+	c.mu.stats.Waited++
+	close(w.ch)
+}
+
+// Stats returns the statistics of the Controller.
+func (c *Controller) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.mu.stats
+	stats.Limit = c.mu.limit
+	stats.Running = c.mu.running
+	stats.Queued = c.mu.waiters.Len()
+	return stats
+}
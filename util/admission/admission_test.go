@@ -0,0 +1,137 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admission
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/util/testleak"
+)
+
+func TestT(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&testAdmissionSuite{})
+
+type testAdmissionSuite struct{}
+
+func (s *testAdmissionSuite) TestAdmit(c *C) {
+	defer testleak.AfterTest(c)()
+	ctrl := NewController(1, time.Second)
+	release1, ok := ctrl.Admit(ClassScan, nil)
+	c.Assert(ok, IsTrue)
+	// The cheap statements aren't limited.
+	for _, class := range []Class{ClassExempt, ClassPoint} {
+		release, ok := ctrl.Admit(class, nil)
+		c.Assert(ok, IsTrue)
+		release()
+	}
+	c.Assert(ctrl.Stats().Running, Equals, 1)
+
+	admitted := make(chan func())
+	go func() {
+		release, ok := ctrl.Admit(ClassScan, nil)
+		c.Check(ok, IsTrue)
+		admitted <- release
+	}()
+	for ctrl.Stats().Queued == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	select {
+	case <-admitted:
+		c.Fatal("the statement should be queued")
+	case <-time.After(10 * time.Millisecond):
+	}
+	release1()
+	// Releasing twice doesn't release the slot of the queued statement.
+	release1()
+	release2 := <-admitted
+	stats := ctrl.Stats()
+	c.Assert(stats.Running, Equals, 1)
+	c.Assert(stats.Queued, Equals, 0)
+	c.Assert(stats.Admitted, Equals, uint64(2))
+	c.Assert(stats.Waited, Equals, uint64(1))
+	release2()
+	c.Assert(ctrl.Stats().Running, Equals, 0)
+}
+
+func (s *testAdmissionSuite) TestTimeout(c *C) {
+	defer testleak.AfterTest(c)()
+	ctrl := NewController(1, 10*time.Millisecond)
+	release, ok := ctrl.Admit(ClassScan, nil)
+	c.Assert(ok, IsTrue)
+	_, ok = ctrl.Admit(ClassScan, nil)
+	c.Assert(ok, IsFalse)
+	c.Assert(ctrl.Stats().Queued, Equals, 0)
+
+	// The statements are rejected right away without the queue timeout.
+	ctrl.SetQueueTimeout(0)
+	_, ok = ctrl.Admit(ClassScan, nil)
+	c.Assert(ok, IsFalse)
+	c.Assert(ctrl.Stats().TimedOut, Equals, uint64(2))
+
+	// The interrupted statements leave the queue before the timeout.
+	ctrl.SetQueueTimeout(time.Minute)
+	var interrupted int32
+	go func() {
+		for ctrl.Stats().Queued == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		atomic.StoreInt32(&interrupted, 1)
+	}()
+	_, ok = ctrl.Admit(ClassScan, func() bool { return atomic.LoadInt32(&interrupted) == 1 })
+	c.Assert(ok, IsFalse)
+	stats := ctrl.Stats()
+	c.Assert(stats.Queued, Equals, 0)
+	c.Assert(stats.TimedOut, Equals, uint64(2))
+	release()
+	c.Assert(ctrl.Stats().Running, Equals, 0)
+}
+
+func (s *testAdmissionSuite) TestSetLimit(c *C) {
+	defer testleak.AfterTest(c)()
+	ctrl := NewController(1, time.Second)
+	release1, ok := ctrl.Admit(ClassScan, nil)
+	c.Assert(ok, IsTrue)
+	admitted := make(chan func(), 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			release, ok := ctrl.Admit(ClassScan, nil)
+			c.Check(ok, IsTrue)
+			admitted <- release
+		}()
+	}
+	for ctrl.Stats().Queued < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	// Raising the limit admits the queued statements.
+	ctrl.SetLimit(0)
+	release2, release3 := <-admitted, <-admitted
+	c.Assert(ctrl.Stats().Running, Equals, 3)
+
+	// The statements over a lowered limit aren't replaced after they finish.
+	ctrl.SetLimit(1)
+	release1()
+	release2()
+	c.Assert(ctrl.Stats().Running, Equals, 1)
+	ctrl.SetQueueTimeout(10 * time.Millisecond)
+	_, ok = ctrl.Admit(ClassScan, nil)
+	c.Assert(ok, IsFalse)
+	release3()
+	c.Assert(ctrl.Stats().Running, Equals, 0)
+}
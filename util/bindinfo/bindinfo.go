@@ -0,0 +1,153 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bindinfo caches the SQL bindings stored in mysql.bind_info. A binding makes the statements which have the
+// same normalized text in the same default database planned by the hints of the bound statement. The bindings are
+// created and dropped by any server, so every server reloads them after they expire in the cache.
+package bindinfo
+
+import (
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/parser"
+)
+
+// DefaultLease is how long the cached bindings are used before they are reloaded.
+const DefaultLease = 3 * time.Second
+
+// GlobalHandle caches the bindings of the store used by the server.
+var GlobalHandle = NewHandle(DefaultLease)
+
+// Binding is a row of mysql.bind_info.
+type Binding struct {
+	// OriginalSQL is the normalized text of the statement, see parser.Normalize.
+	OriginalSQL string
+	BindSQL     string
+	DefaultDB   string
+	CreateTime  time.Time
+	UpdateTime  time.Time
+
+	hints []*ast.TableOptimizerHint
+}
+
+// Hints returns the hints of the bound statement.
+func (b *Binding) Hints() []*ast.TableOptimizerHint {
+	return b.hints
+}
+
+// NewBinding creates a Binding, the hints are parsed from the bound statement.
+func NewBinding(originalSQL, bindSQL, defaultDB string, createTime, updateTime time.Time) (*Binding, error) {
+	stmt, err := parser.New().ParseOneStmt(bindSQL, "", "")
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	sel, ok := stmt.(*ast.SelectStmt)
+	if !ok {
+		return nil, errors.Errorf("the bound statement %s is not a SELECT statement", bindSQL)
+	}
+	return &Binding{
+		OriginalSQL: originalSQL,
+		BindSQL:     bindSQL,
+		DefaultDB:   defaultDB,
+		CreateTime:  createTime,
+		UpdateTime:  updateTime,
+		hints:       sel.TableHints,
+	}, nil
+}
+
+type bindingKey struct {
+	originalSQL string
+	defaultDB   string
+}
+
+// Handle caches the bindings.
+type Handle struct {
+	lease time.Duration
+	// now is replaceable in tests.
+	now func() time.Time
+
+	mu struct {
+		sync.RWMutex
+		// storeID is the UUID of the store whose bindings are cached.
+		storeID  string
+		bindings map[bindingKey]*Binding
+		// expire is when the bindings should be reloaded, the zero time means they should be reloaded right now.
+		expire  time.Time
+		loading bool
+	}
+}
+
+// NewHandle creates a Handle which reloads the bindings after the lease.
+func NewHandle(lease time.Duration) *Handle {
+	h := &Handle{lease: lease, now: time.Now}
+	h.mu.bindings = make(map[bindingKey]*Binding)
+	return h
+}
+
+// StartLoading checks if the bindings of the store should be reloaded. If it returns true, the caller should load
+// the bindings and call FinishLoading, the other callers keep using the cached bindings in the meantime.
+func (h *Handle) StartLoading(store kv.Storage) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.mu.loading || (h.mu.storeID == store.UUID() && h.now().Before(h.mu.expire)) {
+		return false
+	}
+	h.mu.loading = true
+	return true
+}
+
+// FinishLoading replaces the cached bindings with the loaded ones. If the bindings failed to be loaded, the cached
+// ones are kept until the lease expires again.
+func (h *Handle) FinishLoading(store kv.Storage, bindings []*Binding, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.mu.loading = false
+	h.mu.expire = h.now().Add(h.lease)
+	if err != nil {
+		return
+	}
+	h.mu.storeID = store.UUID()
+	h.mu.bindings = make(map[bindingKey]*Binding, len(bindings))
+	for _, b := range bindings {
+		h.mu.bindings[bindingKey{originalSQL: b.OriginalSQL, defaultDB: b.DefaultDB}] = b
+	}
+}
+
+// Invalidate makes the bindings reloaded by the next statement, it's called after the bindings are changed by
+// the server.
+func (h *Handle) Invalidate() {
+	h.mu.Lock()
+	h.mu.expire = time.Time{}
+	h.mu.Unlock()
+}
+
+// IsEmpty checks if the store has no binding, so the statements needn't be normalized to match the bindings.
+func (h *Handle) IsEmpty(store kv.Storage) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.mu.storeID != store.UUID() || len(h.mu.bindings) == 0
+}
+
+// Match returns the binding of the normalized text of a statement in the default database, or nil if there isn't.
+func (h *Handle) Match(store kv.Storage, originalSQL, defaultDB string) *Binding {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if h.mu.storeID != store.UUID() {
+		return nil
+	}
+	return h.mu.bindings[bindingKey{originalSQL: originalSQL, defaultDB: defaultDB}]
+}
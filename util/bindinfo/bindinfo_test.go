@@ -0,0 +1,100 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bindinfo
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/store/localstore"
+	"github.com/pingcap/tidb/store/localstore/goleveldb"
+	"github.com/pingcap/tidb/util/testleak"
+)
+
+func TestT(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&testBindInfoSuite{})
+
+type testBindInfoSuite struct{}
+
+func newStore(c *C, path string) kv.Storage {
+	driver := localstore.Driver{Driver: goleveldb.MemoryDriver{}}
+	store, err := driver.Open(path)
+	c.Assert(err, IsNil)
+	return store
+}
+
+func (s *testBindInfoSuite) TestNewBinding(c *C) {
+	defer testleak.AfterTest(c)()
+	b, err := NewBinding("select * from t where a = ?", "select /*+ use_index(t, a) */ * from t where a = 1", "test",
+		time.Unix(1000, 0), time.Unix(1000, 0))
+	c.Assert(err, IsNil)
+	c.Assert(b.Hints(), HasLen, 1)
+	c.Assert(b.Hints()[0].HintName.L, Equals, "use_index")
+
+	_, err = NewBinding("delete from t", "delete from t", "test", time.Unix(1000, 0), time.Unix(1000, 0))
+	c.Assert(err, NotNil)
+	_, err = NewBinding("select", "select", "test", time.Unix(1000, 0), time.Unix(1000, 0))
+	c.Assert(err, NotNil)
+}
+
+func (s *testBindInfoSuite) TestHandle(c *C) {
+	defer testleak.AfterTest(c)()
+	store1, store2 := newStore(c, "memory1"), newStore(c, "memory2")
+	defer store1.Close()
+	defer store2.Close()
+	now := time.Unix(1000, 0)
+	h := NewHandle(time.Second)
+	h.now = func() time.Time { return now }
+	b, err := NewBinding("select * from t", "select /*+ use_index(t) */ * from t", "test", now, now)
+	c.Assert(err, IsNil)
+
+	c.Assert(h.IsEmpty(store1), IsTrue)
+	c.Assert(h.StartLoading(store1), IsTrue)
+	// Only one caller loads the bindings.
+	c.Assert(h.StartLoading(store1), IsFalse)
+	h.FinishLoading(store1, []*Binding{b}, nil)
+	c.Assert(h.IsEmpty(store1), IsFalse)
+	c.Assert(h.Match(store1, "select * from t", "test"), Equals, b)
+	c.Assert(h.Match(store1, "select * from t", "mysql"), IsNil)
+	c.Assert(h.Match(store1, "select * from t where a = ?", "test"), IsNil)
+	// The bindings of another store aren't used.
+	c.Assert(h.IsEmpty(store2), IsTrue)
+	c.Assert(h.Match(store2, "select * from t", "test"), IsNil)
+
+	// The bindings are reloaded after the lease.
+	c.Assert(h.StartLoading(store1), IsFalse)
+	now = now.Add(time.Second)
+	c.Assert(h.StartLoading(store1), IsTrue)
+	// The cached bindings are kept if they failed to be loaded.
+	h.FinishLoading(store1, nil, errors.New("mock error"))
+	c.Assert(h.Match(store1, "select * from t", "test"), Equals, b)
+	c.Assert(h.StartLoading(store1), IsFalse)
+
+	h.Invalidate()
+	c.Assert(h.StartLoading(store1), IsTrue)
+	h.FinishLoading(store1, nil, nil)
+	c.Assert(h.IsEmpty(store1), IsTrue)
+
+	// The bindings of another store are loaded right now.
+	c.Assert(h.StartLoading(store2), IsTrue)
+	h.FinishLoading(store2, []*Binding{b}, nil)
+	c.Assert(h.Match(store2, "select * from t", "test"), Equals, b)
+	c.Assert(h.IsEmpty(store1), IsTrue)
+}
@@ -0,0 +1,287 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package chunk stores a batch of rows by columns, so the executors can pass the rows in batches instead of
+// one by one, and reuse the memory of the rows across the batches.
+package chunk
+
+import (
+	"math"
+
+	"github.com/pingcap/tidb/util/types"
+)
+
+// Chunk stores a batch of rows by columns. The memory of the chunk is reused after it's reset.
+type Chunk struct {
+	columns []*column
+	// numVirtualRows is the number of the rows if the chunk has no column, e.g. the rows of a dual table.
+	numVirtualRows int
+	capacity       int
+}
+
+// New creates a chunk of numCols columns, which is full when it has capacity rows.
+func New(numCols, capacity int) *Chunk {
+	c := &Chunk{
+		columns:  make([]*column, numCols),
+		capacity: capacity,
+	}
+	for i := range c.columns {
+		c.columns[i] = &column{}
+	}
+	return c
+}
+
+// NumCols returns the number of the columns.
+func (c *Chunk) NumCols() int {
+	return len(c.columns)
+}
+
+// NumRows returns the number of the rows.
+func (c *Chunk) NumRows() int {
+	if len(c.columns) == 0 {
+		return c.numVirtualRows
+	}
+	return c.columns[0].len()
+}
+
+// Capacity returns the number of the rows of the chunk when it's full.
+func (c *Chunk) Capacity() int {
+	return c.capacity
+}
+
+// IsFull checks if the chunk has reached its capacity.
+func (c *Chunk) IsFull() bool {
+	return c.NumRows() >= c.capacity
+}
+
+// Reset removes all the rows, the datums got from the chunk become invalid after it's reset.
+func (c *Chunk) Reset() {
+	for _, col := range c.columns {
+		col.reset()
+	}
+	c.numVirtualRows = 0
+}
+
+// GetRow returns the row at idx.
+func (c *Chunk) GetRow(idx int) Row {
+	return Row{c: c, idx: idx}
+}
+
+// AppendDatums appends a row of the datums, the datums are copied.
+func (c *Chunk) AppendDatums(datums []types.Datum) {
+	if len(c.columns) == 0 {
+		c.numVirtualRows++
+		return
+	}
+	for i, col := range c.columns {
+		col.appendDatum(datums[i])
+	}
+}
+
+// AppendRow appends a row of another chunk with the same columns.
+func (c *Chunk) AppendRow(row Row) {
+	if len(c.columns) == 0 {
+		c.numVirtualRows++
+		return
+	}
+	for i, col := range c.columns {
+		col.appendDatum(row.c.columns[i].getDatum(row.idx))
+	}
+}
+
+// AppendDatum appends a datum to the column at colIdx, a row is appended after all its columns are appended.
+func (c *Chunk) AppendDatum(colIdx int, d types.Datum) {
+	c.columns[colIdx].appendDatum(d)
+}
+
+// Row is a row of a chunk, it's only valid until the chunk is reset.
+type Row struct {
+	c   *Chunk
+	idx int
+}
+
+// Idx returns the index of the row in the chunk.
+func (r Row) Idx() int {
+	return r.idx
+}
+
+// Len returns the number of the columns of the row.
+func (r Row) Len() int {
+	return len(r.c.columns)
+}
+
+// IsNull checks if the column at colIdx is null.
+func (r Row) IsNull(colIdx int) bool {
+	return r.c.columns[colIdx].nulls[r.idx]
+}
+
+// GetDatum returns the datum of the column at colIdx. The bytes of the datum are the memory of the chunk, so it
+// must be copied to be used after the chunk is reset.
+func (r Row) GetDatum(colIdx int) types.Datum {
+	return r.c.columns[colIdx].getDatum(r.idx)
+}
+
+// GetDatums appends the datums of the columns to buf, the bytes of the datums are the memory of the chunk.
+func (r Row) GetDatums(buf []types.Datum) []types.Datum {
+	for _, col := range r.c.columns {
+		buf = append(buf, col.getDatum(r.idx))
+	}
+	return buf
+}
+
+// GetDatumRow returns a copy of the datums of the row, which can be used after the chunk is reset.
+func (r Row) GetDatumRow() []types.Datum {
+	datums := make([]types.Datum, len(r.c.columns))
+	for i, col := range r.c.columns {
+		datums[i] = col.getDatum(r.idx)
+		switch datums[i].Kind() {
+		case types.KindString:
+			datums[i].SetBytesAsString(append([]byte(nil), datums[i].GetBytes()...))
+		case types.KindBytes:
+			datums[i].SetBytes(append([]byte(nil), datums[i].GetBytes()...))
+		}
+	}
+	return datums
+}
+
+// kindDatums is the kind of a column which stores the datums as they are, because the values of the column
+// have different kinds, or any of them is not plain, e.g. a decimal or a string with a collation.
+const kindDatums = types.KindMaxValue + 1
+
+// column stores the values of a column. The plain integers, floats, strings and bytes are stored in the typed
+// slices if all the values have the same kind, the other values are stored as datums.
+type column struct {
+	// kind is the kind of the non-null values, it's types.KindNull if there is no non-null value.
+	kind  byte
+	nulls []bool
+	// ints stores the integers, the unsigned integers and the bits of the floats.
+	ints []int64
+	// offsets are the end offsets of the strings and the bytes in data.
+	offsets []int
+	data    []byte
+	datums  []types.Datum
+}
+
+func (c *column) len() int {
+	return len(c.nulls)
+}
+
+func (c *column) reset() {
+	c.kind = types.KindNull
+	c.nulls = c.nulls[:0]
+	c.ints = c.ints[:0]
+	c.offsets = c.offsets[:0]
+	c.data = c.data[:0]
+	c.datums = c.datums[:0]
+}
+
+// storedKind returns the kind a datum is stored as, kindDatums if it's not a plain value.
+func storedKind(d types.Datum) byte {
+	if d.Collation() != 0 || d.Frac() != 0 || d.Length() != 0 {
+		return kindDatums
+	}
+	switch k := d.Kind(); k {
+	case types.KindInt64, types.KindUint64, types.KindFloat64, types.KindString, types.KindBytes:
+		return k
+	}
+	return kindDatums
+}
+
+func (c *column) appendDatum(d types.Datum) {
+	if d.IsNull() {
+		c.nulls = append(c.nulls, true)
+		c.appendPlaceholder()
+		return
+	}
+	if kind := storedKind(d); kind != c.kind {
+		if c.kind == types.KindNull {
+			c.setKind(kind)
+		} else if c.kind != kindDatums {
+			c.setKind(kindDatums)
+		}
+	}
+	c.nulls = append(c.nulls, false)
+	switch c.kind {
+	case types.KindInt64, types.KindUint64, types.KindFloat64:
+		c.ints = append(c.ints, d.GetInt64())
+	case types.KindString, types.KindBytes:
+		c.data = append(c.data, d.GetBytes()...)
+		c.offsets = append(c.offsets, len(c.data))
+	default:
+		c.datums = append(c.datums, d)
+	}
+}
+
+// appendPlaceholder appends a placeholder of a null value to the typed slice of the column.
+func (c *column) appendPlaceholder() {
+	switch c.kind {
+	case types.KindInt64, types.KindUint64, types.KindFloat64:
+		c.ints = append(c.ints, 0)
+	case types.KindString, types.KindBytes:
+		c.offsets = append(c.offsets, len(c.data))
+	case kindDatums:
+		c.datums = append(c.datums, types.Datum{})
+	}
+}
+
+// setKind changes the kind of the column, the values of the column are moved to the typed slice of the kind,
+// which must be able to store them.
+func (c *column) setKind(kind byte) {
+	var datums []types.Datum
+	if kind == kindDatums {
+		datums = make([]types.Datum, 0, cap(c.nulls))
+		for i := range c.nulls {
+			datums = append(datums, c.getDatum(i))
+		}
+		// The datums refer to the memory of data, so it can't be reused.
+		c.data = nil
+	}
+	c.kind = kind
+	c.ints, c.offsets, c.datums = c.ints[:0], c.offsets[:0], c.datums[:0]
+	if datums != nil {
+		c.datums = datums
+		return
+	}
+	for range c.nulls {
+		c.appendPlaceholder()
+	}
+}
+
+func (c *column) getDatum(idx int) types.Datum {
+	var d types.Datum
+	if c.nulls[idx] {
+		return d
+	}
+	switch c.kind {
+	case types.KindInt64:
+		d.SetInt64(c.ints[idx])
+	case types.KindUint64:
+		d.SetUint64(uint64(c.ints[idx]))
+	case types.KindFloat64:
+		d.SetFloat64(math.Float64frombits(uint64(c.ints[idx])))
+	case types.KindString, types.KindBytes:
+		start := 0
+		if idx > 0 {
+			start = c.offsets[idx-1]
+		}
+		end := c.offsets[idx]
+		if c.kind == types.KindString {
+			d.SetBytesAsString(c.data[start:end:end])
+		} else {
+			d.SetBytes(c.data[start:end:end])
+		}
+	default:
+		d = c.datums[idx]
+	}
+	return d
+}
@@ -0,0 +1,105 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chunk
+
+import (
+	"testing"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/util/testleak"
+	"github.com/pingcap/tidb/util/types"
+)
+
+func TestT(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&testChunkSuite{})
+
+type testChunkSuite struct{}
+
+func (s *testChunkSuite) TestAppendAndGet(c *C) {
+	defer testleak.AfterTest(c)()
+	decimal := types.NewDecimalDatum(types.NewDecFromInt(12))
+	str := types.NewStringDatum("abc")
+	str.SetCollation(1)
+	rows := [][]types.Datum{
+		types.MakeDatums(nil, 1, uint64(2), 1.5, "a", []byte("x"), nil),
+		types.MakeDatums(int64(-3), nil, uint64(4), nil, "", []byte("yz"), 3),
+		{types.NewIntDatum(5), types.NewDatum(nil), types.NewUintDatum(6), types.NewFloat64Datum(-2.5), str, types.NewBytesDatum(nil), decimal},
+		types.MakeDatums(7, 8, nil, 3.5, nil, nil, "mixed"),
+	}
+	chk := New(7, 3)
+	c.Assert(chk.NumCols(), Equals, 7)
+	for i, row := range rows {
+		c.Assert(chk.IsFull(), Equals, i >= 3)
+		chk.AppendDatums(row)
+	}
+	c.Assert(chk.NumRows(), Equals, 4)
+	kinds := []byte{types.KindInt64, types.KindInt64, types.KindUint64, types.KindFloat64, kindDatums, types.KindBytes, kindDatums}
+	for i, kind := range kinds {
+		c.Assert(chk.columns[i].kind, Equals, kind, Commentf("column %d", i))
+	}
+	check := func(chk *Chunk) {
+		for i, row := range rows {
+			got := chk.GetRow(i).GetDatumRow()
+			c.Assert(got, HasLen, len(row))
+			for j := range row {
+				c.Assert(got[j].Kind(), Equals, row[j].Kind(), Commentf("row %d column %d", i, j))
+				c.Assert(got[j].Collation(), Equals, row[j].Collation())
+				cmp, err := got[j].CompareDatum(row[j])
+				c.Assert(err, IsNil)
+				c.Assert(cmp, Equals, 0, Commentf("row %d column %d", i, j))
+				c.Assert(chk.GetRow(i).IsNull(j), Equals, row[j].IsNull())
+			}
+		}
+	}
+	check(chk)
+
+	other := New(7, 4)
+	for i := 0; i < chk.NumRows(); i++ {
+		other.AppendRow(chk.GetRow(i))
+	}
+	// The rows are copied.
+	chk.Reset()
+	c.Assert(chk.NumRows(), Equals, 0)
+	chk.AppendDatums(types.MakeDatums(9, 9, uint64(9), 9.5, "zzzz", []byte("zzzz"), 9))
+	check(other)
+}
+
+func (s *testChunkSuite) TestDatumRowIsCopied(c *C) {
+	defer testleak.AfterTest(c)()
+	chk := New(1, 2)
+	chk.AppendDatums(types.MakeDatums("abc"))
+	row := chk.GetRow(0)
+	copied := row.GetDatumRow()
+	referred := row.GetDatums(nil)
+	chk.Reset()
+	chk.AppendDatums(types.MakeDatums("xyz"))
+	c.Assert(copied[0].GetString(), Equals, "abc")
+	c.Assert(referred[0].GetString(), Equals, "xyz")
+}
+
+func (s *testChunkSuite) TestVirtualRows(c *C) {
+	defer testleak.AfterTest(c)()
+	chk := New(0, 2)
+	chk.AppendDatums(nil)
+	c.Assert(chk.IsFull(), IsFalse)
+	chk.AppendRow(chk.GetRow(0))
+	c.Assert(chk.NumRows(), Equals, 2)
+	c.Assert(chk.IsFull(), IsTrue)
+	c.Assert(chk.GetRow(1).GetDatumRow(), HasLen, 0)
+	chk.Reset()
+	c.Assert(chk.NumRows(), Equals, 0)
+}
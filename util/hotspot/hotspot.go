@@ -0,0 +1,320 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package hotspot counts the writes and the write conflicts of the tables and indices in the server, so the hottest
+// ones can be found when diagnosing a write hotspot. It also throttles the writes of a table temporarily to mitigate
+// an incident. The counters and the throttles are kept in memory, so they are local to the server.
+package hotspot
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultWindow is the time window in which the write rates are computed.
+	DefaultWindow = 10 * time.Second
+	// DefaultThrottleDuration is how long a table is throttled if the duration isn't specified.
+	DefaultThrottleDuration = 10 * time.Minute
+	// shardCount is the number of the shards of the counters, the writes of different tables and indices
+	// rarely contend on the same lock.
+	shardCount = 32
+	// staleWindows is how many windows a counter is kept without any write, so the counters of the dropped
+	// tables and the cold ones don't pile up.
+	staleWindows = 10
+)
+
+// GlobalRecorder records the writes of all the sessions in the server.
+var GlobalRecorder = NewRecorder(DefaultWindow)
+
+// Key identifies the counters of a table or one of its indices.
+type Key struct {
+	TableID int64
+	// IndexID is 0 for the rows of the table.
+	IndexID int64
+}
+
+// Stats is the write statistics of a table or an index.
+type Stats struct {
+	// Writes and WriteBytes are the number and the size of the rows or index entries written since the counter is
+	// created, the counter is removed if the table or the index isn't written for 10 windows.
+	Writes     uint64
+	WriteBytes uint64
+	// WriteQPS and WriteBytesPerSec are the write rates in the last complete window.
+	WriteQPS         float64
+	WriteBytesPerSec float64
+	// Txns is the number of the transactions committed with the writes of the table, Conflicts is the number
+	// of those failed by write conflicts. They are only counted for the rows of the table.
+	Txns      uint64
+	Conflicts uint64
+}
+
+// Throttle is the limit of the write rate of a table.
+type Throttle struct {
+	// Limit is the max number of rows written to the table per second.
+	Limit  uint64
+	Expire time.Time
+}
+
+type throttle struct {
+	Throttle
+	mu sync.Mutex
+	// next is the time when the rows reserved so far would have been written at the limit.
+	next time.Time
+}
+
+// counter is updated atomically, so the writers of the same key only share a read lock of its shard.
+type counter struct {
+	writes     uint64
+	writeBytes uint64
+	txns       uint64
+	conflicts  uint64
+	// window is the index of the current window, windowWrites and windowBytes are counted in it, and lastWrites
+	// and lastBytes are counted in the window before it.
+	window       int64
+	windowWrites uint64
+	windowBytes  uint64
+	lastWrites   uint64
+	lastBytes    uint64
+	// lastSeen is the time in unix nanoseconds when the counter is updated last.
+	lastSeen int64
+}
+
+type shard struct {
+	sync.RWMutex
+	counters  map[Key]*counter
+	lastSweep time.Time
+}
+
+// Recorder records the writes of the tables and the indices, and throttles the writes of the tables.
+type Recorder struct {
+	window time.Duration
+	// start is when the first window starts.
+	start time.Time
+	// now is replaceable in tests.
+	now func() time.Time
+
+	shards [shardCount]shard
+
+	// throttled is the number of the throttles, so the writes aren't blocked by throttleMu if there is none.
+	throttled  int32
+	throttleMu sync.RWMutex
+	throttles  map[int64]*throttle
+}
+
+// NewRecorder creates a Recorder which computes the write rates in the window.
+func NewRecorder(window time.Duration) *Recorder {
+	r := &Recorder{window: window, now: time.Now}
+	r.start = r.now()
+	for i := range r.shards {
+		r.shards[i].counters = make(map[Key]*counter)
+	}
+	r.throttles = make(map[int64]*throttle)
+	return r
+}
+
+// RecordWrite records a row or an index entry of the size written.
+func (r *Recorder) RecordWrite(key Key, size uint64) {
+	now := r.now()
+	c := r.getCounter(key, now)
+	c.rotate(r.windowOf(now))
+	addSaturated(&c.writes, 1)
+	addSaturated(&c.writeBytes, size)
+	addSaturated(&c.windowWrites, 1)
+	addSaturated(&c.windowBytes, size)
+}
+
+// RecordTxn records a transaction committed with the writes of the tables, conflict is true if it failed by
+// a write conflict.
+func (r *Recorder) RecordTxn(tableIDs []int64, conflict bool) {
+	now := r.now()
+	for _, id := range tableIDs {
+		c := r.getCounter(Key{TableID: id}, now)
+		addSaturated(&c.txns, 1)
+		if conflict {
+			addSaturated(&c.conflicts, 1)
+		}
+	}
+}
+
+// Stats returns the statistics of all the tables and indices written.
+func (r *Recorder) Stats() map[Key]Stats {
+	now := r.now()
+	w := r.windowOf(now)
+	seconds := r.window.Seconds()
+	stats := make(map[Key]Stats)
+	for i := range r.shards {
+		sh := &r.shards[i]
+		sh.Lock()
+		r.sweep(sh, now)
+		for key, c := range sh.counters {
+			c.rotate(w)
+			stats[key] = Stats{
+				Writes:           atomic.LoadUint64(&c.writes),
+				WriteBytes:       atomic.LoadUint64(&c.writeBytes),
+				WriteQPS:         float64(atomic.LoadUint64(&c.lastWrites)) / seconds,
+				WriteBytesPerSec: float64(atomic.LoadUint64(&c.lastBytes)) / seconds,
+				Txns:             atomic.LoadUint64(&c.txns),
+				Conflicts:        atomic.LoadUint64(&c.conflicts),
+			}
+		}
+		sh.Unlock()
+	}
+	return stats
+}
+
+// SetThrottle limits the rows written to the table per second until the duration elapses, a limit of 0 removes
+// the throttle of the table.
+func (r *Recorder) SetThrottle(tableID int64, limit uint64, d time.Duration) {
+	now := r.now()
+	r.throttleMu.Lock()
+	defer r.throttleMu.Unlock()
+	for id, t := range r.throttles {
+		if !now.Before(t.Expire) {
+			delete(r.throttles, id)
+		}
+	}
+	if limit == 0 {
+		delete(r.throttles, tableID)
+	} else {
+		r.throttles[tableID] = &throttle{Throttle: Throttle{Limit: limit, Expire: now.Add(d)}}
+	}
+	atomic.StoreInt32(&r.throttled, int32(len(r.throttles)))
+}
+
+// Throttles returns the throttles of the tables which haven't expired.
+func (r *Recorder) Throttles() map[int64]Throttle {
+	now := r.now()
+	r.throttleMu.RLock()
+	defer r.throttleMu.RUnlock()
+	throttles := make(map[int64]Throttle, len(r.throttles))
+	for id, t := range r.throttles {
+		if now.Before(t.Expire) {
+			throttles[id] = t.Throttle
+		}
+	}
+	return throttles
+}
+
+// Reserve reserves the write of a row to the table, it returns false if the row can't be written without exceeding
+// the limit of the throttle of the table. The writes may burst to the rows of one second at the limit, so the writer
+// is told to back off instead of waiting in its transaction.
+func (r *Recorder) Reserve(tableID int64) bool {
+	if atomic.LoadInt32(&r.throttled) == 0 {
+		return true
+	}
+	r.throttleMu.RLock()
+	t, ok := r.throttles[tableID]
+	r.throttleMu.RUnlock()
+	if !ok {
+		return true
+	}
+	now := r.now()
+	if !now.Before(t.Expire) {
+		return true
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	next := t.next
+	if next.Before(now) {
+		next = now
+	}
+	if next.Sub(now) >= time.Second {
+		return false
+	}
+	t.next = next.Add(time.Second / time.Duration(t.Limit))
+	return true
+}
+
+func (r *Recorder) windowOf(now time.Time) int64 {
+	return int64(now.Sub(r.start) / r.window)
+}
+
+func (r *Recorder) getCounter(key Key, now time.Time) *counter {
+	sh := &r.shards[shardOf(key)]
+	sh.RLock()
+	c, ok := sh.counters[key]
+	sh.RUnlock()
+	if !ok {
+		sh.Lock()
+		if c, ok = sh.counters[key]; !ok {
+			r.sweep(sh, now)
+			c = &counter{window: r.windowOf(now)}
+			sh.counters[key] = c
+		}
+		sh.Unlock()
+	}
+	atomic.StoreInt64(&c.lastSeen, now.UnixNano())
+	return c
+}
+
+// sweep removes the counters of the shard which aren't updated for staleWindows, at most once in a window.
+// The shard must be locked.
+func (r *Recorder) sweep(sh *shard, now time.Time) {
+	if now.Sub(sh.lastSweep) < r.window {
+		return
+	}
+	sh.lastSweep = now
+	staleBefore := now.Add(-staleWindows * r.window).UnixNano()
+	for key, c := range sh.counters {
+		if atomic.LoadInt64(&c.lastSeen) < staleBefore {
+			delete(sh.counters, key)
+		}
+	}
+}
+
+func shardOf(key Key) uint64 {
+	h := uint64(key.TableID)*31 + uint64(key.IndexID)
+	return h % shardCount
+}
+
+// rotate moves the counts of the current window to the last one if the window w has started. The writes racing
+// with the rotation may be counted in either window, which is fine for the rates.
+func (c *counter) rotate(w int64) {
+	for {
+		old := atomic.LoadInt64(&c.window)
+		if old >= w {
+			return
+		}
+		if !atomic.CompareAndSwapInt64(&c.window, old, w) {
+			continue
+		}
+		writes := atomic.SwapUint64(&c.windowWrites, 0)
+		bytes := atomic.SwapUint64(&c.windowBytes, 0)
+		// No write is in the last window if more than one window has elapsed.
+		if w-old > 1 {
+			writes, bytes = 0, 0
+		}
+		atomic.StoreUint64(&c.lastWrites, writes)
+		atomic.StoreUint64(&c.lastBytes, bytes)
+		return
+	}
+}
+
+// addSaturated adds delta to the counter, it stays at the max value instead of wrapping around.
+func addSaturated(addr *uint64, delta uint64) {
+	for {
+		old := atomic.LoadUint64(addr)
+		if old > math.MaxUint64-delta {
+			if old == math.MaxUint64 || atomic.CompareAndSwapUint64(addr, old, math.MaxUint64) {
+				return
+			}
+			continue
+		}
+		if atomic.CompareAndSwapUint64(addr, old, old+delta) {
+			return
+		}
+	}
+}
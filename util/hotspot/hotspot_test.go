@@ -0,0 +1,178 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hotspot
+
+import (
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/util/testleak"
+)
+
+func TestT(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&testHotspotSuite{})
+
+type testHotspotSuite struct{}
+
+type mockClock struct {
+	now time.Time
+}
+
+func (c *mockClock) Now() time.Time {
+	return c.now
+}
+
+func newMockRecorder(window time.Duration) (*Recorder, *mockClock) {
+	clock := &mockClock{now: time.Unix(1000, 0)}
+	r := NewRecorder(window)
+	r.now = clock.Now
+	r.start = clock.now
+	return r, clock
+}
+
+func (s *testHotspotSuite) TestRecordWrite(c *C) {
+	defer testleak.AfterTest(c)()
+	r, clock := newMockRecorder(10 * time.Second)
+	t1, idx1, t2 := Key{TableID: 1}, Key{TableID: 1, IndexID: 1}, Key{TableID: 2}
+	for i := 0; i < 20; i++ {
+		r.RecordWrite(t1, 100)
+		r.RecordWrite(idx1, 10)
+	}
+	r.RecordWrite(t2, 50)
+	stats := r.Stats()
+	c.Assert(stats, HasLen, 3)
+	c.Assert(stats[t1].Writes, Equals, uint64(20))
+	c.Assert(stats[t1].WriteBytes, Equals, uint64(2000))
+	c.Assert(stats[idx1].WriteBytes, Equals, uint64(200))
+	// The rates are computed when the window ends.
+	c.Assert(stats[t1].WriteQPS, Equals, float64(0))
+
+	clock.now = clock.now.Add(12 * time.Second)
+	r.RecordWrite(t2, 50)
+	stats = r.Stats()
+	c.Assert(stats[t1].WriteQPS, Equals, float64(2))
+	c.Assert(stats[t1].WriteBytesPerSec, Equals, float64(200))
+	c.Assert(stats[idx1].WriteQPS, Equals, float64(2))
+	c.Assert(stats[t2].WriteQPS, Equals, 0.1)
+	c.Assert(stats[t2].Writes, Equals, uint64(2))
+
+	// The second write of t2 is in the window ending at 20s, no write is in the window ending at 30s.
+	clock.now = clock.now.Add(10 * time.Second)
+	stats = r.Stats()
+	c.Assert(stats[t2].WriteQPS, Equals, 0.1)
+	c.Assert(stats[t1].WriteQPS, Equals, float64(0))
+	clock.now = clock.now.Add(30 * time.Second)
+	stats = r.Stats()
+	c.Assert(stats[t2].WriteQPS, Equals, float64(0))
+	c.Assert(stats[t2].Writes, Equals, uint64(2))
+
+	r.RecordTxn([]int64{1, 2}, false)
+	r.RecordTxn([]int64{1}, true)
+	stats = r.Stats()
+	c.Assert(stats[t1].Txns, Equals, uint64(2))
+	c.Assert(stats[t1].Conflicts, Equals, uint64(1))
+	c.Assert(stats[t2].Txns, Equals, uint64(1))
+	c.Assert(stats[t2].Conflicts, Equals, uint64(0))
+}
+
+func (s *testHotspotSuite) TestEvictAndSaturate(c *C) {
+	defer testleak.AfterTest(c)()
+	r, clock := newMockRecorder(10 * time.Second)
+	t1, t2 := Key{TableID: 1}, Key{TableID: 2}
+	r.RecordWrite(t1, math.MaxUint64-1)
+	r.RecordWrite(t1, 10)
+	stats := r.Stats()
+	c.Assert(stats[t1].Writes, Equals, uint64(2))
+	c.Assert(stats[t1].WriteBytes, Equals, uint64(math.MaxUint64))
+
+	// t1 isn't written for 10 windows and is removed, t2 is kept.
+	clock.now = clock.now.Add(95 * time.Second)
+	r.RecordWrite(t2, 10)
+	clock.now = clock.now.Add(10 * time.Second)
+	stats = r.Stats()
+	c.Assert(stats, HasLen, 1)
+	c.Assert(stats[t2].Writes, Equals, uint64(1))
+	// A transaction keeps the counter of the table too.
+	clock.now = clock.now.Add(90 * time.Second)
+	r.RecordTxn([]int64{2}, false)
+	clock.now = clock.now.Add(20 * time.Second)
+	stats = r.Stats()
+	c.Assert(stats[t2].Txns, Equals, uint64(1))
+	c.Assert(stats[t2].Writes, Equals, uint64(1))
+
+	// The writes of many keys are counted concurrently.
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				r.RecordWrite(Key{TableID: int64(100 + j%4), IndexID: int64(i % 2)}, 1)
+			}
+		}(i)
+	}
+	wg.Wait()
+	stats = r.Stats()
+	var total uint64
+	for key, st := range stats {
+		if key.TableID >= 100 {
+			total += st.Writes
+		}
+	}
+	c.Assert(total, Equals, uint64(8000))
+}
+
+func (s *testHotspotSuite) TestThrottle(c *C) {
+	defer testleak.AfterTest(c)()
+	r, clock := newMockRecorder(10 * time.Second)
+	c.Assert(r.Reserve(1), IsTrue)
+
+	r.SetThrottle(1, 4, time.Minute)
+	c.Assert(r.Throttles(), HasLen, 1)
+	c.Assert(r.Throttles()[1].Limit, Equals, uint64(4))
+	// The writes burst to the limit, the next one is rejected until the rate allows it.
+	for i := 0; i < 4; i++ {
+		c.Assert(r.Reserve(1), IsTrue)
+	}
+	c.Assert(r.Reserve(1), IsFalse)
+	c.Assert(r.Reserve(2), IsTrue)
+	clock.now = clock.now.Add(250 * time.Millisecond)
+	c.Assert(r.Reserve(1), IsTrue)
+	c.Assert(r.Reserve(1), IsFalse)
+	// The unused rate isn't saved for later writes.
+	clock.now = clock.now.Add(10 * time.Second)
+	for i := 0; i < 4; i++ {
+		c.Assert(r.Reserve(1), IsTrue)
+	}
+	c.Assert(r.Reserve(1), IsFalse)
+
+	clock.now = clock.now.Add(time.Minute)
+	c.Assert(r.Throttles(), HasLen, 0)
+	c.Assert(r.Reserve(1), IsTrue)
+	c.Assert(r.Reserve(1), IsTrue)
+
+	r.SetThrottle(1, 1, time.Minute)
+	c.Assert(r.Reserve(1), IsTrue)
+	c.Assert(r.Reserve(1), IsFalse)
+	r.SetThrottle(1, 0, 0)
+	c.Assert(r.Throttles(), HasLen, 0)
+	c.Assert(r.Reserve(1), IsTrue)
+	c.Assert(r.throttled, Equals, int32(0))
+}
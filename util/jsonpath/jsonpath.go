@@ -0,0 +1,212 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package jsonpath implements the subset of the MySQL JSON path language used by JSON_TABLE.
+// A path starts with "$" which is the document itself, followed by member legs like ".name",
+// ".\"quoted name\"" and ".*", and array legs like "[1]" and "[*]".
+// See https://dev.mysql.com/doc/refman/5.7/en/json-path-syntax.html
+package jsonpath
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/juju/errors"
+)
+
+type legType int
+
+const (
+	legMember legType = iota
+	legMemberWildcard
+	legIndex
+	legIndexWildcard
+)
+
+type pathLeg struct {
+	tp    legType
+	key   string
+	index int
+}
+
+// Path is a parsed JSON path.
+type Path struct {
+	text string
+	legs []pathLeg
+}
+
+// Parse parses a JSON path.
+func Parse(text string) (*Path, error) {
+	p := &Path{text: text}
+	s := strings.TrimSpace(text)
+	if !strings.HasPrefix(s, "$") {
+		return nil, errors.Errorf("Invalid JSON path expression %s", text)
+	}
+	s = strings.TrimLeft(s[1:], " \t")
+	for len(s) > 0 {
+		var leg pathLeg
+		var err error
+		switch s[0] {
+		case '.':
+			leg, s, err = parseMember(strings.TrimLeft(s[1:], " \t"))
+		case '[':
+			leg, s, err = parseIndex(strings.TrimLeft(s[1:], " \t"))
+		default:
+			err = errors.New("unexpected character")
+		}
+		if err != nil {
+			return nil, errors.Errorf("Invalid JSON path expression %s: %s", text, err)
+		}
+		p.legs = append(p.legs, leg)
+		s = strings.TrimLeft(s, " \t")
+	}
+	return p, nil
+}
+
+// parseMember parses the member leg after '.', and returns the rest of the path.
+func parseMember(s string) (pathLeg, string, error) {
+	if strings.HasPrefix(s, "*") {
+		return pathLeg{tp: legMemberWildcard}, s[1:], nil
+	}
+	if strings.HasPrefix(s, `"`) {
+		end := 1
+		for ; end < len(s) && s[end] != '"'; end++ {
+			if s[end] == '\\' {
+				end++
+			}
+		}
+		if end >= len(s) {
+			return pathLeg{}, "", errors.New("unterminated quoted member")
+		}
+		key, err := strconv.Unquote(s[:end+1])
+		if err != nil {
+			return pathLeg{}, "", errors.Trace(err)
+		}
+		return pathLeg{tp: legMember, key: key}, s[end+1:], nil
+	}
+	end := strings.IndexAny(s, ".[ \t")
+	if end == -1 {
+		end = len(s)
+	}
+	if end == 0 {
+		return pathLeg{}, "", errors.New("empty member name")
+	}
+	return pathLeg{tp: legMember, key: s[:end]}, s[end:], nil
+}
+
+// parseIndex parses the array leg after '[', and returns the rest of the path.
+func parseIndex(s string) (pathLeg, string, error) {
+	end := strings.IndexByte(s, ']')
+	if end == -1 {
+		return pathLeg{}, "", errors.New("missing ']'")
+	}
+	text := strings.TrimSpace(s[:end])
+	if text == "*" {
+		return pathLeg{tp: legIndexWildcard}, s[end+1:], nil
+	}
+	index, err := strconv.Atoi(text)
+	if err != nil || index < 0 {
+		return pathLeg{}, "", errors.Errorf("invalid array index %s", text)
+	}
+	return pathLeg{tp: legIndex, index: index}, s[end+1:], nil
+}
+
+// String implements fmt.Stringer interface.
+func (p *Path) String() string {
+	return p.text
+}
+
+// Eval returns the values matched by the path in a document decoded by Decode, in document order.
+// Like MySQL, an array leg [0] on a value that is not an array matches the value itself.
+func (p *Path) Eval(doc interface{}) []interface{} {
+	values := []interface{}{doc}
+	for _, leg := range p.legs {
+		var next []interface{}
+		for _, value := range values {
+			next = leg.eval(value, next)
+		}
+		if len(next) == 0 {
+			return nil
+		}
+		values = next
+	}
+	return values
+}
+
+func (leg pathLeg) eval(value interface{}, result []interface{}) []interface{} {
+	switch leg.tp {
+	case legMember:
+		if obj, ok := value.(map[string]interface{}); ok {
+			if v, ok := obj[leg.key]; ok {
+				result = append(result, v)
+			}
+		}
+	case legMemberWildcard:
+		if obj, ok := value.(map[string]interface{}); ok {
+			// Objects are iterated in the order of their keys, so the result is deterministic.
+			for _, key := range sortedKeys(obj) {
+				result = append(result, obj[key])
+			}
+		}
+	case legIndex:
+		if arr, ok := value.([]interface{}); ok {
+			if leg.index < len(arr) {
+				result = append(result, arr[leg.index])
+			}
+		} else if leg.index == 0 {
+			result = append(result, value)
+		}
+	case legIndexWildcard:
+		if arr, ok := value.([]interface{}); ok {
+			result = append(result, arr...)
+		}
+	}
+	return result
+}
+
+func sortedKeys(obj map[string]interface{}) []string {
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Decode decodes a JSON document, the numbers are decoded as json.Number to keep their precision.
+func Decode(doc string) (interface{}, error) {
+	decoder := json.NewDecoder(strings.NewReader(doc))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, errors.Errorf("Invalid JSON text: %s", err)
+	}
+	if decoder.More() {
+		return nil, errors.New("Invalid JSON text: The document root must not be followed by other values.")
+	}
+	return value, nil
+}
+
+// Encode encodes a decoded value back to JSON text.
+func Encode(value interface{}) (string, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return "", errors.Trace(err)
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
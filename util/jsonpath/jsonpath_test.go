@@ -0,0 +1,91 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonpath
+
+import (
+	"testing"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/util/testleak"
+)
+
+func TestT(t *testing.T) {
+	CustomVerboseFlag = true
+	TestingT(t)
+}
+
+var _ = Suite(&testJSONPathSuite{})
+
+type testJSONPathSuite struct {
+}
+
+func (s *testJSONPathSuite) TestEval(c *C) {
+	defer testleak.AfterTest(c)()
+	doc := `{"a": [1, {"b": "x"}, [2, 3]], "c d": true, "e": null, "f": {"h": 1.50, "g": "y"}}`
+	table := []struct {
+		path   string
+		expect string
+	}{
+		{"$", `[{"a":[1,{"b":"x"},[2,3]],"c d":true,"e":null,"f":{"g":"y","h":1.50}}]`},
+		{"$.a", `[[1,{"b":"x"},[2,3]]]`},
+		{"$.a[0]", `[1]`},
+		{"$.a[1].b", `["x"]`},
+		{"$.a[3]", `null`},
+		{"$.a[*]", `[1,{"b":"x"},[2,3]]`},
+		{"$.a[*][*]", `[2,3]`},
+		{"$.a[*].b", `["x"]`},
+		{`$."c d"`, `[true]`},
+		{"$.e", `[null]`},
+		{"$.f.*", `["y",1.50]`},
+		{"$.f.h[0]", `[1.50]`},
+		{"$.f.h[1]", `null`},
+		{"$ . a [ 2 ] [1]", `[3]`},
+		{"$.x", `null`},
+		{"$.a.b", `null`},
+	}
+	value, err := Decode(doc)
+	c.Assert(err, IsNil)
+	for _, t := range table {
+		path, err := Parse(t.path)
+		c.Assert(err, IsNil, Commentf("for %s", t.path))
+		c.Assert(path.String(), Equals, t.path)
+		result, err := Encode(path.Eval(value))
+		c.Assert(err, IsNil)
+		c.Assert(result, Equals, t.expect, Commentf("for %s", t.path))
+	}
+}
+
+func (s *testJSONPathSuite) TestParseError(c *C) {
+	defer testleak.AfterTest(c)()
+	paths := []string{"", "a", "$a", "$.", "$[", "$[a]", "$[-1]", `$."a`, "$..a"}
+	for _, path := range paths {
+		_, err := Parse(path)
+		c.Assert(err, NotNil, Commentf("for %s", path))
+	}
+}
+
+func (s *testJSONPathSuite) TestDecode(c *C) {
+	defer testleak.AfterTest(c)()
+	_, err := Decode(`{"a": 1}`)
+	c.Assert(err, IsNil)
+	_, err = Decode(`{"a": 1`)
+	c.Assert(err, NotNil)
+	_, err = Decode(`{"a": 1} 2`)
+	c.Assert(err, NotNil)
+	_, err = Decode(``)
+	c.Assert(err, NotNil)
+	str, err := Encode("<a & b>")
+	c.Assert(err, IsNil)
+	c.Assert(str, Equals, `"<a & b>"`)
+}
@@ -0,0 +1,340 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package memory tracks the memory used by the executors of the running statements, and keeps the total
+// memory of the server under a limit by asking the statements which use the most memory to spill, and canceling
+// them if the memory still exceeds the limit.
+package memory
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ngaut/log"
+)
+
+// spillPercent is the percentage of the limit, the statement which consumes the most memory is asked to spill once
+// the server consumes more memory than it.
+const spillPercent = 80
+
+// spillCheckInterval is the minimum interval between the checks of which statement should spill, unless the last
+// spill request is taken, so the trackers aren't scanned under the lock on every allocation above spillPercent.
+const spillCheckInterval = 100 * time.Millisecond
+
+// GlobalArbiter tracks the memory of all the statements in the server, it has no limit by default.
+var GlobalArbiter = NewArbiter(0)
+
+// Arbiter tracks the total memory consumed by the trackers created from it. When the total memory exceeds
+// spillPercent of the limit, the statement which consumes the most memory is asked to spill. When the total memory
+// exceeds the limit, the statement which consumes the most memory is canceled, so the server isn't killed by
+// the OS for being out of memory.
+type Arbiter struct {
+	limit    int64
+	consumed int64
+	// lastSpillCheck is the time in nanoseconds when the trackers are last checked for the spill,
+	// 0 means they should be checked on the next allocation above spillPercent.
+	lastSpillCheck int64
+
+	mu struct {
+		sync.Mutex
+		trackers map[*Tracker]struct{}
+	}
+}
+
+// NewArbiter creates an Arbiter, a limit no more than 0 means no limit.
+func NewArbiter(limit int64) *Arbiter {
+	a := &Arbiter{limit: limit}
+	a.mu.trackers = make(map[*Tracker]struct{})
+	return a
+}
+
+// SetLimit sets the memory limit in bytes, a limit no more than 0 means no limit.
+func (a *Arbiter) SetLimit(limit int64) {
+	atomic.StoreInt64(&a.limit, limit)
+}
+
+// Limit returns the memory limit in bytes.
+func (a *Arbiter) Limit() int64 {
+	return atomic.LoadInt64(&a.limit)
+}
+
+// BytesConsumed returns the total memory consumed by all the trackers.
+func (a *Arbiter) BytesConsumed() int64 {
+	return atomic.LoadInt64(&a.consumed)
+}
+
+// NewTracker creates a Tracker for a statement, the label is used to identify the statement in the log.
+// The Tracker must be closed when the statement finishes.
+func (a *Arbiter) NewTracker(label string) *Tracker {
+	t := &Tracker{label: label, arbiter: a}
+	a.mu.Lock()
+	a.mu.trackers[t] = struct{}{}
+	a.mu.Unlock()
+	return t
+}
+
+func (a *Arbiter) consume(bytes int64) {
+	total := atomic.AddInt64(&a.consumed, bytes)
+	limit := a.Limit()
+	if bytes <= 0 || limit <= 0 {
+		return
+	}
+	if total > limit {
+		a.cancelLargest(limit)
+	} else if total > limit/100*spillPercent && a.spillCheckDue() {
+		a.spillLargest()
+	}
+}
+
+// spillCheckDue returns whether the trackers should be checked for the spill. Only one of the concurrent
+// allocations checks them in every spillCheckInterval.
+func (a *Arbiter) spillCheckDue() bool {
+	last := atomic.LoadInt64(&a.lastSpillCheck)
+	now := time.Now().UnixNano()
+	if last != 0 && now-last < int64(spillCheckInterval) {
+		return false
+	}
+	return atomic.CompareAndSwapInt64(&a.lastSpillCheck, last, now)
+}
+
+// spillTaken lets the trackers be checked again on the next allocation, because the spill request is taken or
+// the statement asked to spill is closed.
+func (a *Arbiter) spillTaken() {
+	atomic.StoreInt64(&a.lastSpillCheck, 0)
+}
+
+// spillLargest asks the tracker which consumes the most memory to spill, it's asked again if the server still
+// consumes too much memory after the spill.
+func (a *Arbiter) spillLargest() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	largest := a.largest()
+	if largest == nil || !atomic.CompareAndSwapInt32(&largest.spill, 0, 1) {
+		return
+	}
+	log.Infof("[memory] the server consumes %d bytes which approaches the limit %d, the statement consuming %d bytes should spill: %s",
+		a.BytesConsumed(), a.Limit(), largest.BytesConsumed(), largest.label)
+}
+
+// largest returns the tracker which consumes the most memory, or nil if a tracker is canceled, because
+// its memory is about to be released.
+func (a *Arbiter) largest() *Tracker {
+	var largest *Tracker
+	for t := range a.mu.trackers {
+		if t.Canceled() {
+			return nil
+		}
+		if largest == nil || t.BytesConsumed() > largest.BytesConsumed() {
+			largest = t
+		}
+	}
+	return largest
+}
+
+// cancelLargest cancels the tracker which consumes the most memory. If a canceled tracker hasn't been closed,
+// nothing is canceled, because its memory is about to be released.
+func (a *Arbiter) cancelLargest(limit int64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.BytesConsumed() <= limit {
+		return
+	}
+	largest := a.largest()
+	if largest == nil {
+		return
+	}
+	atomic.StoreInt32(&largest.canceled, 1)
+	log.Warnf("[memory] the server consumes %d bytes which exceeds the limit %d, cancel the statement consuming %d bytes: %s",
+		a.BytesConsumed(), limit, largest.BytesConsumed(), largest.label)
+}
+
+func (a *Arbiter) remove(t *Tracker) {
+	a.mu.Lock()
+	delete(a.mu.trackers, t)
+	a.mu.Unlock()
+}
+
+// Tracker tracks the memory consumed by the executors of a statement. The tracker of a statement is the root of
+// a tree, its children track the memory consumed by the executors, and the memory consumed by a child is
+// consumed by its ancestors too. It's safe for concurrent use, and a nil Tracker tracks nothing.
+type Tracker struct {
+	label    string
+	consumed int64
+	canceled int32
+	// spill is 1 if the Arbiter asks the statement to spill, only the root has it.
+	spill   int32
+	closed  int32
+	arbiter *Arbiter
+	// quota is the memory limit in bytes of the statement, only the root has it, 0 means no limit.
+	quota  int64
+	parent *Tracker
+
+	mu struct {
+		sync.Mutex
+		children []*Tracker
+	}
+}
+
+// NewChild returns the child tracker with the label, it's created if there isn't one. The executors which are
+// rebuilt many times, such as the inner executors of the index lookup join, share the child of the same label.
+func (t *Tracker) NewChild(label string) *Tracker {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, child := range t.mu.children {
+		if child.label == label {
+			return child
+		}
+	}
+	child := &Tracker{label: label, parent: t}
+	t.mu.children = append(t.mu.children, child)
+	return child
+}
+
+func (t *Tracker) root() *Tracker {
+	for t.parent != nil {
+		t = t.parent
+	}
+	return t
+}
+
+// Consume records that the statement consumes more memory, a negative bytes means the memory is released.
+func (t *Tracker) Consume(bytes int64) {
+	if t == nil || bytes == 0 {
+		return
+	}
+	// The memory of a closed statement or a detached child is released already.
+	var root *Tracker
+	for p := t; p != nil; p = p.parent {
+		if atomic.LoadInt32(&p.closed) == 1 {
+			return
+		}
+		root = p
+	}
+	for ; t != nil; t = t.parent {
+		atomic.AddInt64(&t.consumed, bytes)
+	}
+	root.arbiter.consume(bytes)
+}
+
+// BytesConsumed returns the memory consumed by the tracker and its descendants.
+func (t *Tracker) BytesConsumed() int64 {
+	if t == nil {
+		return 0
+	}
+	return atomic.LoadInt64(&t.consumed)
+}
+
+// Canceled returns whether the statement is canceled by the Arbiter, a canceled statement should stop
+// executing and return an error.
+func (t *Tracker) Canceled() bool {
+	return t != nil && atomic.LoadInt32(&t.root().canceled) == 1
+}
+
+// SpillRequested returns whether the Arbiter asks the statement to spill because the server is running out of
+// memory. The request is taken by the executor which spills, so the other executors don't spill for it.
+func (t *Tracker) SpillRequested() bool {
+	if t == nil {
+		return false
+	}
+	root := t.root()
+	if !atomic.CompareAndSwapInt32(&root.spill, 1, 0) {
+		return false
+	}
+	root.arbiter.spillTaken()
+	return true
+}
+
+// SetQuota sets the memory quota in bytes of the statement, a quota no more than 0 means no limit.
+func (t *Tracker) SetQuota(quota int64) {
+	if t == nil {
+		return
+	}
+	atomic.StoreInt64(&t.root().quota, quota)
+}
+
+// QuotaExceeded returns whether the statement consumes more memory than its quota. Unlike Canceled, it's
+// false again after the memory is released, so an executor can release its memory instead of failing.
+func (t *Tracker) QuotaExceeded() bool {
+	if t == nil {
+		return false
+	}
+	root := t.root()
+	quota := atomic.LoadInt64(&root.quota)
+	return quota > 0 && root.BytesConsumed() > quota
+}
+
+// String returns the memory in bytes consumed by the tracker and its descendants,
+// e.g. "select ...: 1024{HashJoin_3: 768, Sort_5: 256}".
+func (t *Tracker) String() string {
+	if t == nil {
+		return ""
+	}
+	var buf bytes.Buffer
+	t.format(&buf)
+	return buf.String()
+}
+
+func (t *Tracker) format(buf *bytes.Buffer) {
+	fmt.Fprintf(buf, "%s: %d", t.label, t.BytesConsumed())
+	t.mu.Lock()
+	children := t.mu.children
+	t.mu.Unlock()
+	if len(children) == 0 {
+		return
+	}
+	buf.WriteByte('{')
+	for i, child := range children {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		child.format(buf)
+	}
+	buf.WriteByte('}')
+}
+
+// Detach removes the child from its parent when its executor is closed, the memory it still consumes is released
+// from its ancestors. It does nothing to the tracker of a statement, which is closed by Close.
+func (t *Tracker) Detach() {
+	if t == nil || t.parent == nil || !atomic.CompareAndSwapInt32(&t.closed, 0, 1) {
+		return
+	}
+	parent := t.parent
+	parent.mu.Lock()
+	for i, child := range parent.mu.children {
+		if child == t {
+			parent.mu.children = append(parent.mu.children[:i], parent.mu.children[i+1:]...)
+			break
+		}
+	}
+	parent.mu.Unlock()
+	parent.Consume(-atomic.SwapInt64(&t.consumed, 0))
+}
+
+// Close releases all the memory consumed by the statement from the Arbiter, it must be called on the tracker
+// of the statement.
+func (t *Tracker) Close() {
+	if t == nil || t.parent != nil || !atomic.CompareAndSwapInt32(&t.closed, 0, 1) {
+		return
+	}
+	t.arbiter.remove(t)
+	if atomic.SwapInt32(&t.spill, 0) == 1 {
+		t.arbiter.spillTaken()
+	}
+	t.arbiter.consume(-atomic.SwapInt64(&t.consumed, 0))
+}
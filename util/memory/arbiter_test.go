@@ -0,0 +1,189 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/util/testleak"
+)
+
+func TestT(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&testArbiterSuite{})
+
+type testArbiterSuite struct{}
+
+func (s *testArbiterSuite) TestConsume(c *C) {
+	defer testleak.AfterTest(c)()
+	a := NewArbiter(0)
+	t1 := a.NewTracker("t1")
+	t2 := a.NewTracker("t2")
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				t1.Consume(10)
+				t2.Consume(1)
+			}
+		}()
+	}
+	wg.Wait()
+	c.Assert(t1.BytesConsumed(), Equals, int64(10000))
+	c.Assert(t2.BytesConsumed(), Equals, int64(1000))
+	c.Assert(a.BytesConsumed(), Equals, int64(11000))
+
+	t1.Consume(-5000)
+	c.Assert(a.BytesConsumed(), Equals, int64(6000))
+	t1.Close()
+	c.Assert(t1.BytesConsumed(), Equals, int64(0))
+	c.Assert(a.BytesConsumed(), Equals, int64(1000))
+	// A closed tracker doesn't consume any more.
+	t1.Consume(100)
+	t1.Close()
+	c.Assert(a.BytesConsumed(), Equals, int64(1000))
+	t2.Close()
+	c.Assert(a.BytesConsumed(), Equals, int64(0))
+	c.Assert(t1.Canceled() || t2.Canceled(), IsFalse)
+
+	// A nil tracker tracks nothing.
+	var t3 *Tracker
+	t3.Consume(100)
+	c.Assert(t3.BytesConsumed(), Equals, int64(0))
+	c.Assert(t3.Canceled(), IsFalse)
+	t3.Close()
+}
+
+func (s *testArbiterSuite) TestCancelLargest(c *C) {
+	defer testleak.AfterTest(c)()
+	a := NewArbiter(100)
+	small := a.NewTracker("small")
+	large := a.NewTracker("large")
+	small.Consume(30)
+	large.Consume(60)
+	c.Assert(small.Canceled() || large.Canceled(), IsFalse)
+
+	// The tracker which consumes the most memory is canceled, even if it isn't the one exceeding the limit.
+	small.Consume(20)
+	c.Assert(small.Canceled(), IsFalse)
+	c.Assert(large.Canceled(), IsTrue)
+
+	// Nothing more is canceled until the canceled tracker is closed.
+	small.Consume(100)
+	c.Assert(small.Canceled(), IsFalse)
+	large.Close()
+	c.Assert(a.BytesConsumed(), Equals, int64(150))
+	small.Consume(1)
+	c.Assert(small.Canceled(), IsTrue)
+	small.Close()
+	c.Assert(a.BytesConsumed(), Equals, int64(0))
+
+	// No tracker is canceled without a limit.
+	a.SetLimit(0)
+	t := a.NewTracker("t")
+	t.Consume(1000)
+	c.Assert(t.Canceled(), IsFalse)
+	t.Close()
+}
+
+func (s *testArbiterSuite) TestSpillLargest(c *C) {
+	defer testleak.AfterTest(c)()
+	a := NewArbiter(100)
+	small := a.NewTracker("small")
+	large := a.NewTracker("large")
+	small.Consume(30)
+	large.Consume(50)
+	c.Assert(small.SpillRequested() || large.SpillRequested(), IsFalse)
+
+	// The tracker which consumes the most memory is asked to spill once 80% of the limit is consumed.
+	small.Consume(10)
+	c.Assert(small.SpillRequested(), IsFalse)
+	c.Assert(large.NewChild("sort").SpillRequested(), IsTrue)
+	// The request is taken, it's asked again if the memory isn't released.
+	c.Assert(large.SpillRequested(), IsFalse)
+	small.Consume(1)
+	c.Assert(large.SpillRequested(), IsTrue)
+	large.Consume(-50)
+	small.Consume(1)
+	c.Assert(small.SpillRequested() || large.SpillRequested(), IsFalse)
+	c.Assert(small.Canceled() || large.Canceled(), IsFalse)
+
+	// The trackers aren't checked again while the request isn't taken, until spillCheckInterval passes.
+	large.Consume(44)
+	small.Consume(4)
+	c.Assert(small.SpillRequested(), IsFalse)
+	atomic.AddInt64(&a.lastSpillCheck, -int64(spillCheckInterval))
+	small.Consume(1)
+	c.Assert(small.SpillRequested(), IsTrue)
+	c.Assert(large.SpillRequested(), IsTrue)
+	// Closing the statement asked to spill lets the trackers be checked again.
+	small.Consume(1)
+	small.Close()
+	large.Consume(40)
+	c.Assert(large.SpillRequested(), IsTrue)
+	large.Close()
+}
+
+func (s *testArbiterSuite) TestChildAndQuota(c *C) {
+	defer testleak.AfterTest(c)()
+	a := NewArbiter(0)
+	t := a.NewTracker("stmt")
+	t.SetQuota(100)
+	join := t.NewChild("join")
+	sort := t.NewChild("sort")
+	c.Assert(t.NewChild("join"), Equals, join)
+	join.Consume(60)
+	sort.Consume(30)
+	c.Assert(t.BytesConsumed(), Equals, int64(90))
+	c.Assert(a.BytesConsumed(), Equals, int64(90))
+	c.Assert(sort.QuotaExceeded(), IsFalse)
+	c.Assert(t.String(), Equals, "stmt: 90{join: 60, sort: 30}")
+
+	// The quota is exceeded until the memory is released.
+	sort.Consume(20)
+	c.Assert(join.QuotaExceeded(), IsTrue)
+	c.Assert(sort.Canceled(), IsFalse)
+	sort.Consume(-50)
+	c.Assert(join.QuotaExceeded(), IsFalse)
+
+	// Closing a child does nothing, the memory is released when the statement is closed.
+	join.Close()
+	c.Assert(a.BytesConsumed(), Equals, int64(60))
+
+	// A detached child is removed from the statement with its memory.
+	join.Detach()
+	c.Assert(t.String(), Equals, "stmt: 0{sort: 0}")
+	c.Assert(a.BytesConsumed(), Equals, int64(0))
+	join.Consume(10)
+	c.Assert(t.BytesConsumed(), Equals, int64(0))
+	c.Assert(t.NewChild("join"), Not(Equals), join)
+	join = t.NewChild("join")
+	join.Consume(60)
+	c.Assert(a.BytesConsumed(), Equals, int64(60))
+	t.Close()
+	c.Assert(a.BytesConsumed(), Equals, int64(0))
+	join.Consume(10)
+	c.Assert(a.BytesConsumed(), Equals, int64(0))
+
+	var nilTracker *Tracker
+	c.Assert(nilTracker.NewChild("child"), IsNil)
+	c.Assert(nilTracker.QuotaExceeded(), IsFalse)
+}
@@ -0,0 +1,351 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package planbaseline keeps the best known plans of the frequent statements as their baselines, and detects the
+// plan regressions against them. A plan is identified by the optimizer hints which reproduce it. After a plan of a
+// statement is executed MinExecutions times, it becomes the baseline of the statement, and it's replaced by a new plan
+// which runs faster on average. If a new plan runs drastically slower than the baseline, it's a regressed plan, and
+// every execution of the statement for which the optimizer chooses a regressed plan falls back to the baseline plan
+// by its hints. The statement isn't pinned to the baseline, the executions planned differently, e.g. after the
+// statistics are updated, aren't changed. The baselines are persisted in the meta of the store, the executions and
+// the regressed plans are kept in memory, so they are local to the server.
+package planbaseline
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/meta"
+	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/parser"
+)
+
+const (
+	// DefaultMinExecutions is the number of the executions of a plan before it's compared with the baseline.
+	DefaultMinExecutions = 5
+	// DefaultRegressionRatio is how many times slower than the baseline a plan is regarded as a regression.
+	DefaultRegressionRatio = 3
+	// DefaultMinRegression is the least latency increase regarded as a regression, so the statements which run
+	// in a few milliseconds don't fall back by the noise.
+	DefaultMinRegression = 10 * time.Millisecond
+
+	// maxStatements is the max number of the statements tracked, the new statements are ignored after it's reached.
+	maxStatements = 4096
+	// eventLogSize is the number of the recent events kept in memory.
+	eventLogSize = 1024
+)
+
+// The types of the events.
+const (
+	// EventCapture means a plan becomes the first baseline of a statement.
+	EventCapture = "capture"
+	// EventEvolve means a faster plan replaces the baseline.
+	EventEvolve = "evolve"
+	// EventFallback means a plan regresses, and the statement falls back to the baseline plan when it's planned by
+	// the regressed plan.
+	EventFallback = "fallback"
+)
+
+// GlobalManager manages the baselines of the statements executed in the server.
+var GlobalManager = NewManager()
+
+// Baseline is the baseline of a statement.
+type Baseline struct {
+	model.PlanBaseline
+	// Fallback is true if the statement falls back to the baseline when it's planned by a regressed plan.
+	Fallback bool
+}
+
+// Event is a change of the baseline of a statement.
+type Event struct {
+	Time   time.Time
+	Digest string
+	SQL    string
+	Type   string
+	// Hints and Latency are of the plan which causes the event, BaselineHints and BaselineLatency are of the
+	// baseline before the event. The baseline is empty for EventCapture.
+	Hints           string
+	Latency         time.Duration
+	BaselineHints   string
+	BaselineLatency time.Duration
+}
+
+type planStats struct {
+	executions uint64
+	total      time.Duration
+}
+
+func (s *planStats) latency() time.Duration {
+	return s.total / time.Duration(s.executions)
+}
+
+type statement struct {
+	sql      string
+	baseline *model.PlanBaseline
+	// plans are the statistics of the plans other than the baseline, by their hints.
+	plans map[string]*planStats
+	// regressed are the hints of the regressed plans.
+	regressed map[string]struct{}
+}
+
+// Manager manages the baselines of the statements.
+type Manager struct {
+	MinExecutions   uint64
+	RegressionRatio float64
+	MinRegression   time.Duration
+
+	// now is replaceable in tests.
+	now func() time.Time
+
+	mu struct {
+		sync.Mutex
+		// storeID is the UUID of the store whose baselines are loaded.
+		storeID    string
+		statements map[string]*statement
+		events     []*Event
+		// next is the position of the next event, the oldest event is overwritten when the buffer is full.
+		next int
+	}
+}
+
+// NewManager creates a Manager with the default thresholds.
+func NewManager() *Manager {
+	m := &Manager{
+		MinExecutions:   DefaultMinExecutions,
+		RegressionRatio: DefaultRegressionRatio,
+		MinRegression:   DefaultMinRegression,
+		now:             time.Now,
+	}
+	m.mu.statements = make(map[string]*statement)
+	m.mu.events = make([]*Event, eventLogSize)
+	return m
+}
+
+// Load loads the baselines persisted in the store if they aren't loaded. The baselines and the events of the store
+// loaded before are discarded.
+func (m *Manager) Load(store kv.Storage) error {
+	m.mu.Lock()
+	loaded := m.mu.storeID == store.UUID()
+	m.mu.Unlock()
+	if loaded {
+		return nil
+	}
+	var baselines []*model.PlanBaseline
+	err := kv.RunInNewTxn(store, false, func(txn kv.Transaction) error {
+		var err error
+		baselines, err = meta.NewMeta(txn).ListPlanBaselines()
+		return errors.Trace(err)
+	})
+	if err != nil {
+		return errors.Trace(err)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.mu.storeID == store.UUID() {
+		return nil
+	}
+	m.mu.storeID = store.UUID()
+	m.mu.statements = make(map[string]*statement, len(baselines))
+	for _, b := range baselines {
+		m.mu.statements[b.Digest] = newStatement(b.SQL, b)
+	}
+	m.mu.events = make([]*Event, eventLogSize)
+	m.mu.next = 0
+	return nil
+}
+
+func newStatement(sql string, baseline *model.PlanBaseline) *statement {
+	return &statement{
+		sql:       sql,
+		baseline:  baseline,
+		plans:     make(map[string]*planStats),
+		regressed: make(map[string]struct{}),
+	}
+}
+
+// FallbackHints returns the hints of the baseline plan if the statement of the digest is planned by a regressed
+// plan of the hints, otherwise it returns nil. The hints are parsed for each call, so they belong to the caller.
+func (m *Manager) FallbackHints(digest, hints string) ([]*ast.TableOptimizerHint, error) {
+	m.mu.Lock()
+	stmt, ok := m.mu.statements[digest]
+	var baselineHints string
+	if ok && stmt.baseline != nil {
+		if _, regressed := stmt.regressed[hints]; regressed {
+			baselineHints = stmt.baseline.Hints
+		}
+	}
+	m.mu.Unlock()
+	if baselineHints == "" {
+		return nil, nil
+	}
+	fallbackHints, err := parseHints(baselineHints)
+	return fallbackHints, errors.Trace(err)
+}
+
+// Observe records an execution of the statement by the plan of the hints, sql is the text of the statement. It
+// captures, replaces or falls back to the baseline of the statement, and persists the new baseline in the store.
+func (m *Manager) Observe(store kv.Storage, digest, sql, hints string, latency time.Duration) {
+	m.mu.Lock()
+	stmt, ok := m.mu.statements[digest]
+	if !ok {
+		if len(m.mu.statements) >= maxStatements {
+			m.mu.Unlock()
+			return
+		}
+		stmt = newStatement(parser.Normalize(sql), nil)
+		m.mu.statements[digest] = stmt
+	}
+	if stmt.baseline != nil && stmt.baseline.Hints == hints {
+		// The latency of the baseline is the average of all its executions.
+		b := stmt.baseline
+		b.Executions++
+		b.Latency += (latency - b.Latency) / time.Duration(b.Executions)
+		m.mu.Unlock()
+		return
+	}
+	ps, ok := stmt.plans[hints]
+	if !ok {
+		ps = &planStats{}
+		stmt.plans[hints] = ps
+	}
+	ps.executions++
+	ps.total += latency
+	if ps.executions < m.MinExecutions {
+		m.mu.Unlock()
+		return
+	}
+	event := &Event{
+		Time:    m.now(),
+		Digest:  digest,
+		SQL:     stmt.sql,
+		Hints:   hints,
+		Latency: ps.latency(),
+	}
+	if stmt.baseline != nil {
+		event.BaselineHints = stmt.baseline.Hints
+		event.BaselineLatency = stmt.baseline.Latency
+	}
+	switch {
+	case stmt.baseline == nil:
+		event.Type = EventCapture
+	case event.Latency < stmt.baseline.Latency:
+		event.Type = EventEvolve
+	case float64(event.Latency) >= m.RegressionRatio*float64(stmt.baseline.Latency) &&
+		event.Latency-stmt.baseline.Latency >= m.MinRegression:
+		event.Type = EventFallback
+		stmt.regressed[hints] = struct{}{}
+		delete(stmt.plans, hints)
+	default:
+		m.mu.Unlock()
+		return
+	}
+	var newBaseline *model.PlanBaseline
+	if event.Type != EventFallback {
+		newBaseline = &model.PlanBaseline{
+			Digest:     digest,
+			SQL:        stmt.sql,
+			Hints:      hints,
+			Latency:    event.Latency,
+			Executions: ps.executions,
+			UpdateTime: event.Time,
+		}
+		stmt.baseline = newBaseline
+		delete(stmt.plans, hints)
+		newBaseline = cloneBaseline(newBaseline)
+	}
+	m.addEvent(event)
+	m.mu.Unlock()
+
+	if event.Type == EventFallback {
+		log.Warnf("[PLAN BASELINE] %s regresses from %v to %v by the plan %s, it falls back to the baseline plan %s when it's planned so",
+			digest, event.BaselineLatency, event.Latency, hints, event.BaselineHints)
+		return
+	}
+	log.Infof("[PLAN BASELINE] %s %s the baseline plan %s of %v", digest, event.Type, hints, event.Latency)
+	err := kv.RunInNewTxn(store, true, func(txn kv.Transaction) error {
+		return errors.Trace(meta.NewMeta(txn).SetPlanBaseline(newBaseline))
+	})
+	if err != nil {
+		log.Errorf("[PLAN BASELINE] failed to persist the baseline of %s: %v", digest, err)
+	}
+}
+
+// Baselines returns the baselines of the statements ordered by their SQL.
+func (m *Manager) Baselines() []Baseline {
+	m.mu.Lock()
+	baselines := make([]Baseline, 0, len(m.mu.statements))
+	for _, stmt := range m.mu.statements {
+		if stmt.baseline != nil {
+			baselines = append(baselines, Baseline{PlanBaseline: *stmt.baseline, Fallback: len(stmt.regressed) > 0})
+		}
+	}
+	m.mu.Unlock()
+	sort.Sort(baselinesBySQL(baselines))
+	return baselines
+}
+
+// Events returns the recent events, the latest one comes first.
+func (m *Manager) Events() []*Event {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var events []*Event
+	for i := 1; i <= len(m.mu.events); i++ {
+		e := m.mu.events[(m.mu.next-i+len(m.mu.events))%len(m.mu.events)]
+		if e == nil {
+			break
+		}
+		events = append(events, e)
+	}
+	return events
+}
+
+func (m *Manager) addEvent(e *Event) {
+	m.mu.events[m.mu.next] = e
+	m.mu.next = (m.mu.next + 1) % len(m.mu.events)
+}
+
+func cloneBaseline(b *model.PlanBaseline) *model.PlanBaseline {
+	nb := *b
+	return &nb
+}
+
+// parseHints parses the hints of a baseline, e.g. "use_index(t, idx), hash_join(t1)".
+func parseHints(hints string) ([]*ast.TableOptimizerHint, error) {
+	stmt, err := parser.New().ParseOneStmt("select /*+ "+hints+" */ 1", "", "")
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return stmt.(*ast.SelectStmt).TableHints, nil
+}
+
+type baselinesBySQL []Baseline
+
+func (s baselinesBySQL) Len() int {
+	return len(s)
+}
+
+func (s baselinesBySQL) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+func (s baselinesBySQL) Less(i, j int) bool {
+	if s[i].SQL != s[j].SQL {
+		return s[i].SQL < s[j].SQL
+	}
+	return s[i].Digest < s[j].Digest
+}
@@ -0,0 +1,173 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planbaseline
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/store/localstore"
+	"github.com/pingcap/tidb/store/localstore/goleveldb"
+	"github.com/pingcap/tidb/util/testleak"
+)
+
+func TestT(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&testPlanBaselineSuite{})
+
+type testPlanBaselineSuite struct{}
+
+func newMockManager(c *C) (*Manager, kv.Storage) {
+	driver := localstore.Driver{Driver: goleveldb.MemoryDriver{}}
+	store, err := driver.Open("memory")
+	c.Assert(err, IsNil)
+	m := NewManager()
+	m.MinExecutions = 2
+	m.now = func() time.Time { return time.Unix(1000, 0) }
+	c.Assert(m.Load(store), IsNil)
+	return m, store
+}
+
+func (s *testPlanBaselineSuite) TestCaptureAndEvolve(c *C) {
+	defer testleak.AfterTest(c)()
+	m, store := newMockManager(c)
+	defer store.Close()
+	sql := "select * from t where a = 1"
+
+	m.Observe(store, "d1", sql, "use_index(t)", 20*time.Millisecond)
+	c.Assert(m.Baselines(), HasLen, 0)
+	m.Observe(store, "d1", sql, "use_index(t)", 40*time.Millisecond)
+	baselines := m.Baselines()
+	c.Assert(baselines, HasLen, 1)
+	c.Assert(baselines[0].SQL, Equals, "select * from t where a = ?")
+	c.Assert(baselines[0].Hints, Equals, "use_index(t)")
+	c.Assert(baselines[0].Latency, Equals, 30*time.Millisecond)
+	c.Assert(baselines[0].Executions, Equals, uint64(2))
+	c.Assert(baselines[0].Fallback, IsFalse)
+
+	// The executions of the baseline plan update its latency.
+	m.Observe(store, "d1", sql, "use_index(t)", 60*time.Millisecond)
+	baselines = m.Baselines()
+	c.Assert(baselines[0].Latency, Equals, 40*time.Millisecond)
+	c.Assert(baselines[0].Executions, Equals, uint64(3))
+
+	// A faster plan replaces the baseline.
+	m.Observe(store, "d1", sql, "use_index(t, a)", time.Millisecond)
+	m.Observe(store, "d1", sql, "use_index(t, a)", 3*time.Millisecond)
+	baselines = m.Baselines()
+	c.Assert(baselines[0].Hints, Equals, "use_index(t, a)")
+	c.Assert(baselines[0].Latency, Equals, 2*time.Millisecond)
+
+	events := m.Events()
+	c.Assert(events, HasLen, 2)
+	c.Assert(events[0].Type, Equals, EventEvolve)
+	c.Assert(events[0].BaselineHints, Equals, "use_index(t)")
+	c.Assert(events[0].BaselineLatency, Equals, 40*time.Millisecond)
+	c.Assert(events[1].Type, Equals, EventCapture)
+	c.Assert(events[1].BaselineHints, Equals, "")
+
+	// The baselines are persisted.
+	m = NewManager()
+	c.Assert(m.Load(store), IsNil)
+	baselines = m.Baselines()
+	c.Assert(baselines, HasLen, 1)
+	c.Assert(baselines[0].Hints, Equals, "use_index(t, a)")
+	c.Assert(baselines[0].SQL, Equals, "select * from t where a = ?")
+	c.Assert(m.Events(), HasLen, 0)
+}
+
+func (s *testPlanBaselineSuite) TestFallback(c *C) {
+	defer testleak.AfterTest(c)()
+	m, store := newMockManager(c)
+	defer store.Close()
+	sql := "select * from t where a = 1"
+	m.Observe(store, "d1", sql, "use_index(t, a)", 5*time.Millisecond)
+	m.Observe(store, "d1", sql, "use_index(t, a)", 5*time.Millisecond)
+	fallbackHints := func(hints string) []*ast.TableOptimizerHint {
+		fallback, err := m.FallbackHints("d1", hints)
+		c.Assert(err, IsNil)
+		return fallback
+	}
+	c.Assert(fallbackHints("use_index(t, a)"), IsNil)
+
+	// A slower plan which isn't slow enough is neither a regression nor a better plan.
+	m.Observe(store, "d1", sql, "use_index(t, b)", 10*time.Millisecond)
+	m.Observe(store, "d1", sql, "use_index(t, b)", 10*time.Millisecond)
+	c.Assert(fallbackHints("use_index(t, b)"), IsNil)
+
+	// The latency increase must be more than MinRegression.
+	m.MinRegression = 100 * time.Millisecond
+	m.Observe(store, "d1", sql, "use_index(t)", 50*time.Millisecond)
+	m.Observe(store, "d1", sql, "use_index(t)", 50*time.Millisecond)
+	c.Assert(fallbackHints("use_index(t)"), IsNil)
+
+	m.MinRegression = DefaultMinRegression
+	m.Observe(store, "d1", sql, "use_index(t)", 50*time.Millisecond)
+	hints := fallbackHints("use_index(t)")
+	c.Assert(hints, HasLen, 1)
+	c.Assert(hints[0].HintName.L, Equals, "use_index")
+	c.Assert(hints[0].Args[0].L, Equals, "t")
+	c.Assert(hints[0].Args[1].L, Equals, "a")
+	baselines := m.Baselines()
+	c.Assert(baselines[0].Fallback, IsTrue)
+	c.Assert(baselines[0].Hints, Equals, "use_index(t, a)")
+	// The hints are parsed for each call, so changing them doesn't change the next fallback.
+	hints[0].Args = nil
+	c.Assert(fallbackHints("use_index(t)")[0].Args, HasLen, 2)
+	// Only the executions planned by the regressed plan fall back.
+	c.Assert(fallbackHints("use_index(t, a)"), IsNil)
+	c.Assert(fallbackHints("use_index(t, b)"), IsNil)
+	c.Assert(fallbackHints("use_index(t, c)"), IsNil)
+	// The other plans are still observed.
+	m.Observe(store, "d1", sql, "use_index(t, c)", time.Millisecond)
+	m.Observe(store, "d1", sql, "use_index(t, c)", time.Millisecond)
+	c.Assert(m.Baselines()[0].Hints, Equals, "use_index(t, c)")
+	c.Assert(fallbackHints("use_index(t)")[0].Args[1].L, Equals, "c")
+
+	events := m.Events()
+	c.Assert(events, HasLen, 3)
+	events = events[1:]
+	c.Assert(events[0].Type, Equals, EventFallback)
+	c.Assert(events[0].Hints, Equals, "use_index(t)")
+	c.Assert(events[0].Latency, Equals, 50*time.Millisecond)
+	c.Assert(events[0].BaselineHints, Equals, "use_index(t, a)")
+	c.Assert(events[0].BaselineLatency, Equals, 5*time.Millisecond)
+
+	// The fallback isn't persisted.
+	m = NewManager()
+	c.Assert(m.Load(store), IsNil)
+	c.Assert(fallbackHints("use_index(t)"), IsNil)
+}
+
+func (s *testPlanBaselineSuite) TestEventsWrapAround(c *C) {
+	defer testleak.AfterTest(c)()
+	m, store := newMockManager(c)
+	defer store.Close()
+	m.MinExecutions = 1
+	for i := 0; i < eventLogSize+1; i++ {
+		// Each plan runs faster than the previous one.
+		hints := fmt.Sprintf("use_index(t, i%d)", i)
+		m.Observe(store, "d1", "select 1", hints, time.Duration(eventLogSize+1-i)*time.Millisecond)
+	}
+	events := m.Events()
+	c.Assert(events, HasLen, eventLogSize)
+	c.Assert(events[0].Latency, Equals, time.Millisecond)
+	c.Assert(events[eventLogSize-1].Latency, Equals, time.Duration(eventLogSize)*time.Millisecond)
+}
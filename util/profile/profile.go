@@ -0,0 +1,409 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package profile captures the CPU and heap profiles of the process and summarizes them by the functions, so the
+// profiles can be read without the pprof tool, e.g. by a SQL query.
+package profile
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"runtime/pprof"
+	"sort"
+	"time"
+
+	"github.com/juju/errors"
+)
+
+// Frame is the statistics of a function in a profile.
+type Frame struct {
+	Name string
+	File string
+	// Flat is the value of the samples in which the function is running.
+	Flat int64
+	// Cum is the value of the samples in which the function is running or calling the other functions.
+	Cum int64
+}
+
+// Profile is a profile summarized by the functions.
+type Profile struct {
+	// Unit is the unit of the values, e.g. nanoseconds or bytes.
+	Unit string
+	// Total is the value of all the samples.
+	Total int64
+	// Frames are sorted by the flat values, then the cumulative values, in descending order.
+	Frames []*Frame
+}
+
+// interruptCheckInterval is how often the capture of the CPU profile checks whether it's interrupted.
+const interruptCheckInterval = 10 * time.Millisecond
+
+// CPU captures the CPU profile of the process in the duration. It fails if the CPU profile is being captured by
+// another caller, e.g. the pprof HTTP handler. The capture stops early once interrupted returns true, a nil
+// interrupted never stops it.
+func CPU(d time.Duration, interrupted func() bool) (*Profile, error) {
+	var buf bytes.Buffer
+	if err := pprof.StartCPUProfile(&buf); err != nil {
+		return nil, errors.Trace(err)
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	ticker := time.NewTicker(interruptCheckInterval)
+	defer ticker.Stop()
+CAPTURE:
+	for {
+		select {
+		case <-timer.C:
+			break CAPTURE
+		case <-ticker.C:
+			if interrupted != nil && interrupted() {
+				break CAPTURE
+			}
+		}
+	}
+	pprof.StopCPUProfile()
+	return parse(buf.Bytes(), "cpu")
+}
+
+// Heap captures the heap profile of the in-use memory, which is updated by the garbage collections.
+func Heap() (*Profile, error) {
+	var buf bytes.Buffer
+	if err := pprof.Lookup("heap").WriteTo(&buf, 0); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return parse(buf.Bytes(), "inuse_space")
+}
+
+// The field numbers of the messages in profile.proto of pprof, only the fields used to summarize the profiles.
+// See https://github.com/google/pprof/blob/master/proto/profile.proto
+const (
+	profileSampleType  = 1
+	profileSample      = 2
+	profileLocation    = 4
+	profileFunction    = 5
+	profileStringTable = 6
+
+	valueTypeType = 1
+	valueTypeUnit = 2
+
+	sampleLocationID = 1
+	sampleValue      = 2
+
+	locationID   = 1
+	locationLine = 4
+
+	lineFunctionID = 1
+
+	functionID       = 1
+	functionName     = 2
+	functionFilename = 4
+)
+
+type function struct {
+	name, file int64
+}
+
+// parse summarizes the gzipped protobuf profile by the values of the sample type.
+func parse(data []byte, sampleType string) (*Profile, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	data, err = ioutil.ReadAll(r)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	var (
+		types, units []int64
+		samples      [][]byte
+		strs         []string
+		// locations maps the IDs of the locations to their functions, the first one is the innermost one if the
+		// functions are inlined.
+		locations = make(map[uint64][]uint64)
+		functions = make(map[uint64]function)
+	)
+	d := &decoder{data: data}
+	for !d.done() {
+		num, wireType := d.key()
+		switch {
+		case num == profileSampleType && wireType == wireBytes:
+			m := &decoder{data: d.bytes()}
+			var tp, unit int64
+			for !m.done() {
+				switch num, wireType := m.key(); {
+				case num == valueTypeType && wireType == wireVarint:
+					tp = int64(m.varint())
+				case num == valueTypeUnit && wireType == wireVarint:
+					unit = int64(m.varint())
+				default:
+					m.skip(wireType)
+				}
+			}
+			types, units = append(types, tp), append(units, unit)
+			d.err = m.firstErr(d.err)
+		case num == profileSample && wireType == wireBytes:
+			// The samples are decoded after the sample types are known.
+			samples = append(samples, d.bytes())
+		case num == profileLocation && wireType == wireBytes:
+			m := &decoder{data: d.bytes()}
+			var id uint64
+			var funcs []uint64
+			for !m.done() {
+				switch num, wireType := m.key(); {
+				case num == locationID && wireType == wireVarint:
+					id = m.varint()
+				case num == locationLine && wireType == wireBytes:
+					line := &decoder{data: m.bytes()}
+					for !line.done() {
+						switch num, wireType := line.key(); {
+						case num == lineFunctionID && wireType == wireVarint:
+							funcs = append(funcs, line.varint())
+						default:
+							line.skip(wireType)
+						}
+					}
+					m.err = line.firstErr(m.err)
+				default:
+					m.skip(wireType)
+				}
+			}
+			locations[id] = funcs
+			d.err = m.firstErr(d.err)
+		case num == profileFunction && wireType == wireBytes:
+			m := &decoder{data: d.bytes()}
+			var id uint64
+			var fn function
+			for !m.done() {
+				switch num, wireType := m.key(); {
+				case num == functionID && wireType == wireVarint:
+					id = m.varint()
+				case num == functionName && wireType == wireVarint:
+					fn.name = int64(m.varint())
+				case num == functionFilename && wireType == wireVarint:
+					fn.file = int64(m.varint())
+				default:
+					m.skip(wireType)
+				}
+			}
+			functions[id] = fn
+			d.err = m.firstErr(d.err)
+		case num == profileStringTable && wireType == wireBytes:
+			strs = append(strs, string(d.bytes()))
+		default:
+			d.skip(wireType)
+		}
+	}
+	if d.err != nil {
+		return nil, errors.Trace(d.err)
+	}
+	str := func(idx int64) string {
+		if idx < 0 || idx >= int64(len(strs)) {
+			return ""
+		}
+		return strs[idx]
+	}
+	valueIdx := -1
+	for i, tp := range types {
+		if str(tp) == sampleType {
+			valueIdx = i
+		}
+	}
+	if valueIdx < 0 {
+		return nil, errors.Errorf("sample type %s isn't found in the profile", sampleType)
+	}
+
+	p := &Profile{Unit: str(units[valueIdx])}
+	frames := make(map[string]*Frame)
+	frame := func(id uint64) *Frame {
+		fn := functions[id]
+		name := str(fn.name)
+		f, ok := frames[name]
+		if !ok {
+			f = &Frame{Name: name, File: str(fn.file)}
+			frames[name] = f
+		}
+		return f
+	}
+	for _, sample := range samples {
+		m := &decoder{data: sample}
+		var locs []uint64
+		var values []int64
+		for !m.done() {
+			switch num, wireType := m.key(); {
+			case num == sampleLocationID:
+				locs = m.appendVarints(locs, wireType)
+			case num == sampleValue:
+				for _, v := range m.appendVarints(nil, wireType) {
+					values = append(values, int64(v))
+				}
+			default:
+				m.skip(wireType)
+			}
+		}
+		if m.err != nil {
+			return nil, errors.Trace(m.err)
+		}
+		if valueIdx >= len(values) || len(locs) == 0 {
+			continue
+		}
+		value := values[valueIdx]
+		p.Total += value
+		// A function is counted once in a sample even if it's called recursively.
+		counted := make(map[*Frame]bool)
+		for i, loc := range locs {
+			for j, id := range locations[loc] {
+				f := frame(id)
+				if i == 0 && j == 0 {
+					f.Flat += value
+				}
+				if !counted[f] {
+					f.Cum += value
+					counted[f] = true
+				}
+			}
+		}
+	}
+	p.Frames = make([]*Frame, 0, len(frames))
+	for _, f := range frames {
+		p.Frames = append(p.Frames, f)
+	}
+	sort.Sort(byFlat(p.Frames))
+	return p, nil
+}
+
+type byFlat []*Frame
+
+func (s byFlat) Len() int {
+	return len(s)
+}
+
+func (s byFlat) Less(i, j int) bool {
+	if s[i].Flat != s[j].Flat {
+		return s[i].Flat > s[j].Flat
+	}
+	if s[i].Cum != s[j].Cum {
+		return s[i].Cum > s[j].Cum
+	}
+	return s[i].Name < s[j].Name
+}
+
+func (s byFlat) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+// The wire types of protobuf.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+var errTruncated = errors.New("the profile is truncated")
+
+// decoder decodes a protobuf message, it stops at the first error.
+type decoder struct {
+	data []byte
+	err  error
+}
+
+func (d *decoder) done() bool {
+	return d.err != nil || len(d.data) == 0
+}
+
+func (d *decoder) firstErr(err error) error {
+	if err != nil {
+		return err
+	}
+	return d.err
+}
+
+func (d *decoder) varint() uint64 {
+	var v uint64
+	for i := uint(0); i < 64; i += 7 {
+		if len(d.data) == 0 {
+			break
+		}
+		b := d.data[0]
+		d.data = d.data[1:]
+		v |= uint64(b&0x7f) << i
+		if b < 0x80 {
+			return v
+		}
+	}
+	d.err = errTruncated
+	d.data = nil
+	return 0
+}
+
+// key decodes the field number and the wire type of the next field.
+func (d *decoder) key() (int, int) {
+	k := d.varint()
+	return int(k >> 3), int(k & 7)
+}
+
+func (d *decoder) bytes() []byte {
+	n := d.varint()
+	if d.err != nil || uint64(len(d.data)) < n {
+		d.err = errTruncated
+		d.data = nil
+		return nil
+	}
+	b := d.data[:n]
+	d.data = d.data[n:]
+	return b
+}
+
+// appendVarints decodes a repeated integer field, which is packed if the wire type is bytes.
+func (d *decoder) appendVarints(vals []uint64, wireType int) []uint64 {
+	if wireType == wireVarint {
+		return append(vals, d.varint())
+	}
+	if wireType != wireBytes {
+		d.skip(wireType)
+		return vals
+	}
+	packed := &decoder{data: d.bytes()}
+	for !packed.done() {
+		vals = append(vals, packed.varint())
+	}
+	d.err = packed.firstErr(d.err)
+	return vals
+}
+
+func (d *decoder) skip(wireType int) {
+	var n int
+	switch wireType {
+	case wireVarint:
+		d.varint()
+		return
+	case wireBytes:
+		d.bytes()
+		return
+	case wireFixed64:
+		n = 8
+	case wireFixed32:
+		n = 4
+	default:
+		d.err = errors.Errorf("unknown wire type %d in the profile", wireType)
+		d.data = nil
+		return
+	}
+	if len(d.data) < n {
+		d.err = errTruncated
+		d.data = nil
+		return
+	}
+	d.data = d.data[n:]
+}
@@ -0,0 +1,136 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package profile
+
+import (
+	"bytes"
+	"compress/gzip"
+	"runtime"
+	"runtime/pprof"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/util/testleak"
+)
+
+func TestT(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&testProfileSuite{})
+
+type testProfileSuite struct{}
+
+var sink []byte
+
+func findFrame(p *Profile, suffix string) *Frame {
+	for _, f := range p.Frames {
+		if strings.HasSuffix(f.Name, suffix) {
+			return f
+		}
+	}
+	return nil
+}
+
+func burnCPU(stop *int32) {
+	x := 0
+	for atomic.LoadInt32(stop) == 0 {
+		for i := 0; i < 1000; i++ {
+			x += i * i
+		}
+	}
+	sink = append(sink[:0], byte(x))
+}
+
+func (s *testProfileSuite) TestCPU(c *C) {
+	defer testleak.AfterTest(c)()
+	var stop int32
+	done := make(chan struct{})
+	go func() {
+		burnCPU(&stop)
+		close(done)
+	}()
+	p, err := CPU(500*time.Millisecond, nil)
+	atomic.StoreInt32(&stop, 1)
+	<-done
+	c.Assert(err, IsNil)
+	c.Assert(p.Unit, Equals, "nanoseconds")
+	c.Assert(p.Total, Greater, int64(0))
+	f := findFrame(p, ".burnCPU")
+	c.Assert(f, NotNil)
+	c.Assert(f.Flat, Greater, int64(0))
+	c.Assert(f.Cum >= f.Flat, IsTrue)
+	c.Assert(strings.HasSuffix(f.File, "profile_test.go"), IsTrue)
+	for i := 1; i < len(p.Frames); i++ {
+		c.Assert(p.Frames[i-1].Flat >= p.Frames[i].Flat, IsTrue)
+	}
+
+	// Only one CPU profile can be captured at a time.
+	var buf bytes.Buffer
+	c.Assert(pprof.StartCPUProfile(&buf), IsNil)
+	_, err = CPU(time.Millisecond, nil)
+	c.Assert(err, NotNil)
+	pprof.StopCPUProfile()
+	_, err = CPU(time.Millisecond, nil)
+	c.Assert(err, IsNil)
+
+	// The capture stops once it's interrupted.
+	start := time.Now()
+	_, err = CPU(time.Minute, func() bool { return time.Since(start) > 50*time.Millisecond })
+	c.Assert(err, IsNil)
+	c.Assert(time.Since(start) < 10*time.Second, IsTrue)
+}
+
+func allocHeap() {
+	sink = make([]byte, 64<<20)
+}
+
+func (s *testProfileSuite) TestHeap(c *C) {
+	defer testleak.AfterTest(c)()
+	allocHeap()
+	// The in-use memory of the profile is updated by the garbage collections.
+	runtime.GC()
+	p, err := Heap()
+	c.Assert(err, IsNil)
+	c.Assert(p.Unit, Equals, "bytes")
+	f := findFrame(p, ".allocHeap")
+	c.Assert(f, NotNil)
+	c.Assert(f.Flat >= 64<<20, IsTrue)
+	c.Assert(p.Total >= f.Flat, IsTrue)
+	sink = nil
+}
+
+func (s *testProfileSuite) TestParseError(c *C) {
+	defer testleak.AfterTest(c)()
+	_, err := parse([]byte("not gzipped"), "cpu")
+	c.Assert(err, NotNil)
+
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	// A sample type whose length is larger than the data.
+	_, err = w.Write([]byte{profileSampleType<<3 | wireBytes, 10, 1})
+	c.Assert(err, IsNil)
+	c.Assert(w.Close(), IsNil)
+	_, err = parse(buf.Bytes(), "cpu")
+	c.Assert(err, NotNil)
+
+	buf.Reset()
+	w = gzip.NewWriter(&buf)
+	c.Assert(w.Close(), IsNil)
+	_, err = parse(buf.Bytes(), "cpu")
+	c.Assert(err, ErrorMatches, ".*sample type cpu isn't found.*")
+}
@@ -0,0 +1,225 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package stmtsummary summarizes the executions of the statements by their digests and their users in memory. A sample
+// of every distinct plan of a statement is kept with its execution statistics, so the plans of a statement can be
+// compared after a regression even if the statement isn't running anymore. The statements are summarized by user,
+// so the sample of a user isn't shown to the others. The least recently executed statements are evicted when the
+// summary is full.
+package stmtsummary
+
+import (
+	"container/list"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+)
+
+const (
+	// DefaultMaxStatements is the number of the statements kept by default.
+	DefaultMaxStatements = 1024
+	// maxPlans is the number of the plans kept for a statement, the least recently executed one is evicted.
+	maxPlans = 4
+)
+
+// GlobalSummary summarizes the statements executed by the server.
+var GlobalSummary = NewSummary(DefaultMaxStatements)
+
+// Execution is a finished execution of a statement.
+type Execution struct {
+	// Digest and NormalizedSQL identify the statement, see parser.NormalizeDigest.
+	Digest        string
+	NormalizedSQL string
+	SQL           string
+	// User is the name of the user who executes the statement.
+	User string
+	// PlanDigest identifies the plan of the execution, EncodePlan returns the sample plan, it's only called for
+	// the plan new to the statement.
+	PlanDigest string
+	EncodePlan func() (string, error)
+	Latency    time.Duration
+}
+
+// Plan is the summary of the executions of a statement of a user by a plan.
+type Plan struct {
+	Digest        string
+	NormalizedSQL string
+	User          string
+	PlanDigest    string
+	// Plan and SampleSQL are taken from the first execution by the plan.
+	Plan       string
+	SampleSQL  string
+	ExecCount  uint64
+	SumLatency time.Duration
+	MaxLatency time.Duration
+	FirstSeen  time.Time
+	LastSeen   time.Time
+}
+
+// stmtRecord is the summary of a statement of a user, the plans are sorted by LastSeen in descending order.
+type stmtRecord struct {
+	key   recordKey
+	plans []*Plan
+}
+
+type recordKey struct {
+	digest string
+	user   string
+}
+
+// Summary keeps the summaries of the statements.
+type Summary struct {
+	// now is replaceable in tests.
+	now func() time.Time
+
+	mu struct {
+		sync.Mutex
+		maxStatements int
+		// lru is the list of *stmtRecord, the most recently executed statement comes first.
+		lru     list.List
+		records map[recordKey]*list.Element
+	}
+}
+
+// NewSummary creates a Summary which keeps at most maxStatements statements, no more than 0 disables it.
+func NewSummary(maxStatements int) *Summary {
+	s := &Summary{now: time.Now}
+	s.mu.maxStatements = maxStatements
+	s.mu.records = make(map[recordKey]*list.Element)
+	return s
+}
+
+// SetMaxStatements sets the number of the statements kept, no more than 0 disables the summary and clears it.
+func (s *Summary) SetMaxStatements(maxStatements int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mu.maxStatements = maxStatements
+	s.evict()
+}
+
+// Enabled checks if the executions should be observed, so the callers can skip computing the digests.
+func (s *Summary) Enabled() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.mu.maxStatements > 0
+}
+
+// Observe records an execution.
+func (s *Summary) Observe(e *Execution) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.mu.maxStatements <= 0 {
+		return nil
+	}
+	var rec *stmtRecord
+	key := recordKey{digest: e.Digest, user: e.User}
+	if elem, ok := s.mu.records[key]; ok {
+		s.mu.lru.MoveToFront(elem)
+		rec = elem.Value.(*stmtRecord)
+	}
+
+	now := s.now()
+	var p *Plan
+	if rec != nil {
+		for i, plan := range rec.plans {
+			if plan.PlanDigest == e.PlanDigest {
+				p = plan
+				copy(rec.plans[1:i+1], rec.plans[:i])
+				rec.plans[0] = p
+				break
+			}
+		}
+	}
+	if p == nil {
+		encoded, err := e.EncodePlan()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if rec == nil {
+			rec = &stmtRecord{key: key}
+			s.mu.records[key] = s.mu.lru.PushFront(rec)
+			s.evict()
+		}
+		p = &Plan{
+			Digest:        e.Digest,
+			NormalizedSQL: e.NormalizedSQL,
+			User:          e.User,
+			PlanDigest:    e.PlanDigest,
+			Plan:          encoded,
+			SampleSQL:     e.SQL,
+			FirstSeen:     now,
+		}
+		if len(rec.plans) == maxPlans {
+			rec.plans = rec.plans[:maxPlans-1]
+		}
+		rec.plans = append([]*Plan{p}, rec.plans...)
+	}
+	p.ExecCount++
+	p.SumLatency += e.Latency
+	if e.Latency > p.MaxLatency {
+		p.MaxLatency = e.Latency
+	}
+	p.LastSeen = now
+	return nil
+}
+
+// evict evicts the least recently executed statements over the limit, the caller must hold the lock.
+func (s *Summary) evict() {
+	for s.mu.lru.Len() > 0 && s.mu.lru.Len() > s.mu.maxStatements {
+		rec := s.mu.lru.Remove(s.mu.lru.Back()).(*stmtRecord)
+		delete(s.mu.records, rec.key)
+	}
+}
+
+// Plans returns the summaries of the plans of the statement executed by the user, or by all the users if the user
+// is empty. The most recently executed one comes first.
+func (s *Summary) Plans(digest, user string) []Plan {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var plans []Plan
+	if user != "" {
+		if elem, ok := s.mu.records[recordKey{digest: digest, user: user}]; ok {
+			for _, p := range elem.Value.(*stmtRecord).plans {
+				plans = append(plans, *p)
+			}
+		}
+		return plans
+	}
+	for elem := s.mu.lru.Front(); elem != nil; elem = elem.Next() {
+		rec := elem.Value.(*stmtRecord)
+		if rec.key.digest != digest {
+			continue
+		}
+		for _, p := range rec.plans {
+			plans = append(plans, *p)
+		}
+	}
+	sort.Stable(plansByLastSeen(plans))
+	return plans
+}
+
+type plansByLastSeen []Plan
+
+func (s plansByLastSeen) Len() int {
+	return len(s)
+}
+
+func (s plansByLastSeen) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+func (s plansByLastSeen) Less(i, j int) bool {
+	return s[i].LastSeen.After(s[j].LastSeen)
+}
@@ -0,0 +1,134 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stmtsummary
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/util/testleak"
+)
+
+func TestT(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&testStmtSummarySuite{})
+
+type testStmtSummarySuite struct{}
+
+func newExecution(digest, planDigest string, latency time.Duration) *Execution {
+	return &Execution{
+		Digest:        digest,
+		NormalizedSQL: "select * from t where a = ?",
+		SQL:           fmt.Sprintf("select * from t where a = %d", latency),
+		PlanDigest:    planDigest,
+		EncodePlan:    func() (string, error) { return "plan of " + planDigest, nil },
+		Latency:       latency,
+	}
+}
+
+func (s *testStmtSummarySuite) TestObserve(c *C) {
+	defer testleak.AfterTest(c)()
+	sum := NewSummary(10)
+	now := time.Unix(1000, 0)
+	sum.now = func() time.Time { return now }
+
+	c.Assert(sum.Observe(newExecution("d1", "p1", 10)), IsNil)
+	now = now.Add(time.Second)
+	c.Assert(sum.Observe(newExecution("d1", "p1", 30)), IsNil)
+	plans := sum.Plans("d1", "")
+	c.Assert(plans, HasLen, 1)
+	p := plans[0]
+	c.Assert(p.Plan, Equals, "plan of p1")
+	// The sample is the first execution.
+	c.Assert(p.SampleSQL, Equals, "select * from t where a = 10")
+	c.Assert(p.ExecCount, Equals, uint64(2))
+	c.Assert(p.SumLatency, Equals, time.Duration(40))
+	c.Assert(p.MaxLatency, Equals, time.Duration(30))
+	c.Assert(p.FirstSeen, Equals, time.Unix(1000, 0))
+	c.Assert(p.LastSeen, Equals, time.Unix(1001, 0))
+	c.Assert(sum.Plans("d2", ""), HasLen, 0)
+
+	// The plans are kept separately, the most recent one comes first.
+	for i := 2; i <= maxPlans+1; i++ {
+		c.Assert(sum.Observe(newExecution("d1", fmt.Sprintf("p%d", i), 10)), IsNil)
+	}
+	c.Assert(sum.Observe(newExecution("d1", "p3", 10)), IsNil)
+	plans = sum.Plans("d1", "")
+	c.Assert(plans, HasLen, maxPlans)
+	c.Assert(plans[0].PlanDigest, Equals, "p3")
+	c.Assert(plans[0].ExecCount, Equals, uint64(2))
+	c.Assert(plans[1].PlanDigest, Equals, fmt.Sprintf("p%d", maxPlans+1))
+	// The least recently executed plan is evicted.
+	for _, p := range plans {
+		c.Assert(p.PlanDigest, Not(Equals), "p1")
+	}
+
+	// The execution isn't recorded if its plan fails to be encoded.
+	e := newExecution("d2", "p1", 10)
+	e.EncodePlan = func() (string, error) { return "", errors.New("mock error") }
+	c.Assert(sum.Observe(e), NotNil)
+	c.Assert(sum.Plans("d2", ""), HasLen, 0)
+}
+
+func (s *testStmtSummarySuite) TestEvict(c *C) {
+	defer testleak.AfterTest(c)()
+	sum := NewSummary(2)
+	c.Assert(sum.Observe(newExecution("d1", "p1", 10)), IsNil)
+	c.Assert(sum.Observe(newExecution("d2", "p1", 10)), IsNil)
+	c.Assert(sum.Observe(newExecution("d1", "p1", 10)), IsNil)
+	c.Assert(sum.Observe(newExecution("d3", "p1", 10)), IsNil)
+	// d2 is the least recently executed one.
+	c.Assert(sum.Plans("d1", ""), HasLen, 1)
+	c.Assert(sum.Plans("d2", ""), HasLen, 0)
+	c.Assert(sum.Plans("d3", ""), HasLen, 1)
+
+	sum.SetMaxStatements(1)
+	c.Assert(sum.Plans("d1", ""), HasLen, 0)
+	c.Assert(sum.Plans("d3", ""), HasLen, 1)
+
+	// The summary is disabled.
+	sum.SetMaxStatements(0)
+	c.Assert(sum.Enabled(), IsFalse)
+	c.Assert(sum.Plans("d3", ""), HasLen, 0)
+	c.Assert(sum.Observe(newExecution("d1", "p1", 10)), IsNil)
+	c.Assert(sum.Plans("d1", ""), HasLen, 0)
+}
+
+func (s *testStmtSummarySuite) TestPlansByUser(c *C) {
+	defer testleak.AfterTest(c)()
+	sum := NewSummary(10)
+	now := time.Unix(1000, 0)
+	sum.now = func() time.Time { return now }
+	for _, user := range []string{"u1", "u2", "u1"} {
+		e := newExecution("d1", "p1", 10)
+		e.User = user
+		c.Assert(sum.Observe(e), IsNil)
+		now = now.Add(time.Second)
+	}
+	plans := sum.Plans("d1", "u1")
+	c.Assert(plans, HasLen, 1)
+	c.Assert(plans[0].User, Equals, "u1")
+	c.Assert(plans[0].ExecCount, Equals, uint64(2))
+	c.Assert(sum.Plans("d1", "u3"), HasLen, 0)
+	// The plans of all the users, the most recent one comes first.
+	plans = sum.Plans("d1", "")
+	c.Assert(plans, HasLen, 2)
+	c.Assert(plans[0].User, Equals, "u1")
+	c.Assert(plans[1].User, Equals, "u2")
+}
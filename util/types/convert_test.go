@@ -300,6 +300,19 @@ func (s *testTypeConvertSuite) TestConvertToString(c *C) {
 	c.Assert(terror.ErrorEqual(err, ErrTruncated), IsTrue)
 	testToString(c, v, "3.14159")
 
+	// The quotient keeps more digits than its result frac, they're rounded without an error.
+	quo := new(MyDecimal)
+	err = DecimalDiv(NewDecFromInt(7), NewDecFromInt(3), quo, DivFracIncr)
+	c.Assert(err, IsNil)
+	ft.Decimal = 4
+	v, err = Convert(quo, ft)
+	c.Assert(err, IsNil)
+	testToString(c, v, "2.3333")
+	ft.Decimal = 3
+	v, err = Convert(quo, ft)
+	c.Assert(terror.ErrorEqual(err, ErrTruncated), IsTrue)
+	testToString(c, v, "2.333")
+
 	_, err = ToString(&invalidMockType{})
 	c.Assert(err, NotNil)
 
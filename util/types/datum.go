@@ -980,8 +980,11 @@ func (d *Datum) convertToMysqlDecimal(target *FieldType) (Datum, error) {
 			dec = NewMaxOrMinDec(dec.IsNegative(), target.Flen, target.Decimal)
 			err = errors.Trace(ErrOverflow)
 		} else if frac != target.Decimal {
+			// A quotient keeps more digits than its result frac, which it's rounded to when it's shown, only the
+			// digits of the result frac are truncated.
+			resultFrac := int(dec.resultFrac)
 			dec.Round(dec, target.Decimal)
-			if frac > target.Decimal {
+			if frac > target.Decimal && resultFrac > target.Decimal {
 				err = errors.Trace(ErrTruncated)
 			}
 		}
@@ -1150,6 +1153,12 @@ func (d *Datum) ToBool() (int64, error) {
 // ConvertDatumToDecimal converts datum to decimal.
 func ConvertDatumToDecimal(d Datum) (*MyDecimal, error) {
 	dec := new(MyDecimal)
+	err := ConvertDatumToDecimalTo(d, dec)
+	return dec, err
+}
+
+// ConvertDatumToDecimalTo converts datum to the decimal dec.
+func ConvertDatumToDecimalTo(d Datum, dec *MyDecimal) error {
 	var err error
 	switch d.Kind() {
 	case KindInt64:
@@ -1175,7 +1184,7 @@ func ConvertDatumToDecimal(d Datum) (*MyDecimal, error) {
 	default:
 		err = fmt.Errorf("can't convert %v to decimal", d.GetValue())
 	}
-	return dec, err
+	return err
 }
 
 // ToDecimal converts to a decimal.
@@ -1365,6 +1374,13 @@ func (d *Datum) convergeType(hasUint, hasDecimal, hasFloat *bool) (x Datum) {
 // Else if a or b is Decimal, changes the both to Decimal.
 // Else if a or b is Uint and op is not div, mod, or intDiv changes the both to Uint.
 func CoerceDatum(a, b Datum) (x, y Datum, err error) {
+	return CoerceDatumWithBuffer(a, b, nil)
+}
+
+// CoerceDatumWithBuffer is like CoerceDatum, but if decs is not nil, the values are converted to the decimals in it
+// instead of the new ones and the decimal values are kept as they are, so the results are only valid until decs are
+// reused and must not be modified.
+func CoerceDatumWithBuffer(a, b Datum, decs *[2]MyDecimal) (x, y Datum, err error) {
 	if a.IsNull() || b.IsNull() {
 		return x, y, nil
 	}
@@ -1417,21 +1433,36 @@ func CoerceDatum(a, b Datum) (x, y Datum, err error) {
 			y.SetFloat64(fval)
 		}
 	} else if hasDecimal {
-		var dec *MyDecimal
-		dec, err = ConvertDatumToDecimal(x)
-		if err != nil {
+		if err = x.coerceToDecimal(decs, 0); err != nil {
 			return x, y, errors.Trace(err)
 		}
-		x.SetMysqlDecimal(dec)
-		dec, err = ConvertDatumToDecimal(y)
-		if err != nil {
+		if err = y.coerceToDecimal(decs, 1); err != nil {
 			return x, y, errors.Trace(err)
 		}
-		y.SetMysqlDecimal(dec)
 	}
 	return
 }
 
+// coerceToDecimal converts the datum to a decimal, which is decs[i] if decs is not nil.
+func (d *Datum) coerceToDecimal(decs *[2]MyDecimal, i int) error {
+	if decs == nil {
+		dec, err := ConvertDatumToDecimal(*d)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		d.SetMysqlDecimal(dec)
+		return nil
+	}
+	if d.Kind() == KindMysqlDecimal {
+		return nil
+	}
+	if err := ConvertDatumToDecimalTo(*d, &decs[i]); err != nil {
+		return errors.Trace(err)
+	}
+	d.SetMysqlDecimal(&decs[i])
+	return nil
+}
+
 // NewDatum creates a new Datum from an interface{}.
 func NewDatum(in interface{}) (d Datum) {
 	switch x := in.(type) {
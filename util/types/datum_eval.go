@@ -72,6 +72,11 @@ func CoerceArithmetic(a Datum) (d Datum, err error) {
 
 // ComputePlus computes the result of a+b.
 func ComputePlus(a, b Datum) (d Datum, err error) {
+	return ComputePlusTo(a, b, nil)
+}
+
+// ComputePlusTo is like ComputePlus, but a decimal result is computed in to unless it is nil.
+func ComputePlusTo(a, b Datum, to *MyDecimal) (d Datum, err error) {
 	switch a.Kind() {
 	case KindInt64:
 		switch b.Kind() {
@@ -105,7 +110,10 @@ func ComputePlus(a, b Datum) (d Datum, err error) {
 	case KindMysqlDecimal:
 		switch b.Kind() {
 		case KindMysqlDecimal:
-			r := new(MyDecimal)
+			r := to
+			if r == nil {
+				r = new(MyDecimal)
+			}
 			err = DecimalAdd(a.GetMysqlDecimal(), b.GetMysqlDecimal(), r)
 			d.SetMysqlDecimal(r)
 			return d, err
@@ -117,6 +125,11 @@ func ComputePlus(a, b Datum) (d Datum, err error) {
 
 // ComputeMinus computes the result of a-b.
 func ComputeMinus(a, b Datum) (d Datum, err error) {
+	return ComputeMinusTo(a, b, nil)
+}
+
+// ComputeMinusTo is like ComputeMinus, but a decimal result is computed in to unless it is nil.
+func ComputeMinusTo(a, b Datum, to *MyDecimal) (d Datum, err error) {
 	switch a.Kind() {
 	case KindInt64:
 		switch b.Kind() {
@@ -150,7 +163,10 @@ func ComputeMinus(a, b Datum) (d Datum, err error) {
 	case KindMysqlDecimal:
 		switch b.Kind() {
 		case KindMysqlDecimal:
-			r := new(MyDecimal)
+			r := to
+			if r == nil {
+				r = new(MyDecimal)
+			}
 			err = DecimalSub(a.GetMysqlDecimal(), b.GetMysqlDecimal(), r)
 			d.SetMysqlDecimal(r)
 			return d, err
@@ -162,6 +178,11 @@ func ComputeMinus(a, b Datum) (d Datum, err error) {
 
 // ComputeMul computes the result of a*b.
 func ComputeMul(a, b Datum) (d Datum, err error) {
+	return ComputeMulTo(a, b, nil)
+}
+
+// ComputeMulTo is like ComputeMul, but a decimal result is computed in to unless it is nil.
+func ComputeMulTo(a, b Datum, to *MyDecimal) (d Datum, err error) {
 	switch a.Kind() {
 	case KindInt64:
 		switch b.Kind() {
@@ -195,7 +216,10 @@ func ComputeMul(a, b Datum) (d Datum, err error) {
 	case KindMysqlDecimal:
 		switch b.Kind() {
 		case KindMysqlDecimal:
-			r := new(MyDecimal)
+			r := to
+			if r == nil {
+				r = new(MyDecimal)
+			}
 			err = DecimalMul(a.GetMysqlDecimal(), b.GetMysqlDecimal(), r)
 			d.SetMysqlDecimal(r)
 			return d, nil
@@ -208,6 +232,11 @@ func ComputeMul(a, b Datum) (d Datum, err error) {
 
 // ComputeDiv computes the result of a/b.
 func ComputeDiv(a, b Datum) (d Datum, err error) {
+	return ComputeDivTo(a, b, nil)
+}
+
+// ComputeDivTo is like ComputeDiv, but a decimal result is computed in to unless it is nil.
+func ComputeDivTo(a, b Datum, to *MyDecimal) (d Datum, err error) {
 	// MySQL support integer division Div and division operator /
 	// we use opcode.Div for division operator and will use another for integer division later.
 	// for division operator, we will use float64 for calculation.
@@ -229,17 +258,19 @@ func ComputeDiv(a, b Datum) (d Datum, err error) {
 		// the scale of the result is the scale of the first operand plus
 		// the value of the div_precision_increment system variable (which is 4 by default)
 		// we will use 4 here
-		xa, err1 := a.ToDecimal()
+		xa, err1 := divOperand(a)
 		if err != nil {
 			return d, errors.Trace(err1)
 		}
 
-		xb, err1 := b.ToDecimal()
+		xb, err1 := divOperand(b)
 		if err1 != nil {
 			return d, errors.Trace(err1)
 		}
 		// division by zero return null
-		to := new(MyDecimal)
+		if to == nil {
+			to = new(MyDecimal)
+		}
 		err = DecimalDiv(xa, xb, to, DivFracIncr)
 		if err != ErrDivByZero {
 			d.SetMysqlDecimal(to)
@@ -250,6 +281,15 @@ func ComputeDiv(a, b Datum) (d Datum, err error) {
 	}
 }
 
+// divOperand converts an operand of the division to a decimal, a decimal operand is used as it is since the division
+// doesn't modify it.
+func divOperand(d Datum) (*MyDecimal, error) {
+	if d.Kind() == KindMysqlDecimal {
+		return d.GetMysqlDecimal(), nil
+	}
+	return d.ToDecimal()
+}
+
 // ComputeMod computes the result of a mod b.
 func ComputeMod(a, b Datum) (d Datum, err error) {
 	switch a.Kind() {
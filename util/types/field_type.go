@@ -205,6 +205,208 @@ func getFieldTypeIndex(tp byte) int {
 	return fieldTypeTearFrom + itp - fieldTypeTearTo - 1
 }
 
+// AggFieldType aggregates the types of the values in the same column, e.g. the columns of the SELECTs in a UNION,
+// by the rules of MySQL. The type is merged by MergeFieldType, an integer is unsigned only if all the integers are
+// unsigned, and the length, the decimal and the charset are large enough to hold all the values.
+// See https://github.com/mysql/mysql-server/blob/5.7/sql/item.cc Item_type_holder::join_types
+func AggFieldType(tps []*FieldType) *FieldType {
+	var known []*FieldType
+	notNull := true
+	for _, tp := range tps {
+		notNull = notNull && mysql.HasNotNullFlag(tp.Flag)
+		// The NULL values can be converted to any type, the types unknown yet are decided by the others.
+		if tp.Tp != mysql.TypeNull && tp.Tp != mysql.TypeUnspecified {
+			known = append(known, tp)
+		}
+	}
+	if len(known) == 0 {
+		ft := *tps[0]
+		return &ft
+	}
+	ft := NewFieldType(known[0].Tp)
+	for _, tp := range known[1:] {
+		ft.Tp = MergeFieldType(ft.Tp, tp.Tp)
+	}
+	if notNull {
+		ft.Flag |= mysql.NotNullFlag
+	}
+	switch {
+	case IsTypeInteger(ft.Tp):
+		aggIntegerType(ft, known)
+	case ft.Tp == mysql.TypeNewDecimal:
+		aggDecimalType(ft, known)
+	case ft.Tp == mysql.TypeDate || ft.Tp == mysql.TypeDatetime || ft.Tp == mysql.TypeTimestamp ||
+		ft.Tp == mysql.TypeDuration:
+		ft.Decimal = 0
+		for _, tp := range known {
+			if tp.Decimal > ft.Decimal {
+				ft.Decimal = tp.Decimal
+			}
+		}
+	case isTypeString(ft.Tp):
+		aggStringType(ft, known)
+		return ft
+	}
+	ft.Charset, ft.Collate = charset.CharsetBin, charset.CollationBin
+	return ft
+}
+
+// maxDecimalWidth is the max precision of the decimal type.
+const maxDecimalWidth = 65
+
+// integerTypes are the integer types ordered by their ranges.
+var integerTypes = []byte{mysql.TypeTiny, mysql.TypeShort, mysql.TypeInt24, mysql.TypeLong, mysql.TypeLonglong}
+
+// IsTypeInteger returns a boolean indicating whether the tp is an integer type.
+func IsTypeInteger(tp byte) bool {
+	return integerTypeRank(tp) >= 0
+}
+
+func integerTypeRank(tp byte) int {
+	for i, t := range integerTypes {
+		if t == tp {
+			return i
+		}
+	}
+	return -1
+}
+
+func isTypeString(tp byte) bool {
+	return IsTypeChar(tp) || IsTypeBlob(tp) || tp == mysql.TypeVarString
+}
+
+// aggIntegerType decides the signedness and the length of the integer type. If the signed and the unsigned
+// integers are mixed, the type is widened to hold both of them, the unsigned BIGINTs make it a DECIMAL.
+func aggIntegerType(ft *FieldType, tps []*FieldType) {
+	unsigned, signed := true, false
+	rank := integerTypeRank(ft.Tp)
+	for _, tp := range tps {
+		if mysql.HasUnsignedFlag(tp.Flag) {
+			continue
+		}
+		unsigned = false
+		signed = signed || IsTypeInteger(tp.Tp)
+	}
+	if !unsigned && signed {
+		for _, tp := range tps {
+			if r := integerTypeRank(tp.Tp); r >= 0 && mysql.HasUnsignedFlag(tp.Flag) && r >= rank {
+				rank = r + 1
+			}
+		}
+		if rank >= len(integerTypes) {
+			ft.Tp = mysql.TypeNewDecimal
+			aggDecimalType(ft, tps)
+			return
+		}
+		ft.Tp = integerTypes[rank]
+	}
+	if unsigned {
+		ft.Flag |= mysql.UnsignedFlag
+	}
+	ft.Flen = 0
+	for _, tp := range tps {
+		ft.Flen = myMax(ft.Flen, DisplayLength(tp))
+	}
+}
+
+// aggDecimalType decides the precision and the scale of the decimal type, the integer part and the scale are
+// the max of the values. They're unspecified if any of the values has an unspecified one.
+func aggDecimalType(ft *FieldType, tps []*FieldType) {
+	intPart, frac := 0, 0
+	for _, tp := range tps {
+		var i, f int
+		switch {
+		case IsTypeInteger(tp.Tp):
+			i = DisplayLength(tp)
+		case tp.Tp == mysql.TypeNewDecimal && tp.Flen != UnspecifiedLength && tp.Decimal != UnspecifiedLength:
+			i, f = tp.Flen-tp.Decimal, tp.Decimal
+		default:
+			ft.Flen, ft.Decimal = UnspecifiedLength, UnspecifiedLength
+			return
+		}
+		intPart, frac = myMax(intPart, i), myMax(frac, f)
+	}
+	frac = myMin(frac, MaxFraction)
+	// The integer part takes precedence when the precision exceeds the max.
+	ft.Flen = myMin(intPart+frac, maxDecimalWidth)
+	ft.Decimal = myMin(frac, ft.Flen-intPart)
+	if ft.Decimal < 0 {
+		ft.Decimal = 0
+	}
+}
+
+// aggStringType decides the length and the charset of the string type. The length is the max display length of
+// the values. The charset is binary if any string is binary, otherwise the charset of the strings if they're the
+// same, or the default charset.
+func aggStringType(ft *FieldType, tps []*FieldType) {
+	ft.Flen = 0
+	for _, tp := range tps {
+		l := DisplayLength(tp)
+		if l == UnspecifiedLength {
+			ft.Flen = UnspecifiedLength
+			break
+		}
+		ft.Flen = myMax(ft.Flen, l)
+	}
+	for _, tp := range tps {
+		if !isTypeString(tp.Tp) {
+			continue
+		}
+		if tp.Charset == charset.CharsetBin {
+			ft.Charset, ft.Collate = charset.CharsetBin, charset.CollationBin
+			break
+		}
+		if ft.Charset == "" {
+			ft.Charset, ft.Collate = tp.Charset, tp.Collate
+		} else if ft.Charset != tp.Charset {
+			ft.Charset, ft.Collate = mysql.DefaultCharset, mysql.DefaultCollationName
+		} else if ft.Collate != tp.Collate {
+			ft.Collate, _ = charset.GetDefaultCollation(ft.Charset)
+		}
+	}
+	if ft.Charset == "" {
+		ft.Charset, ft.Collate = mysql.DefaultCharset, mysql.DefaultCollationName
+	}
+	if ft.Charset == charset.CharsetBin {
+		ft.Flag |= mysql.BinaryFlag
+	}
+}
+
+// DisplayLength returns the max length of the values of the type when they're converted to strings.
+func DisplayLength(tp *FieldType) int {
+	switch {
+	case isTypeString(tp.Tp):
+		return tp.Flen
+	case IsTypeInteger(tp.Tp):
+		if tp.Flen == UnspecifiedLength {
+			return mysql.GetDefaultFieldLength(tp.Tp)
+		}
+		return tp.Flen
+	case tp.Tp == mysql.TypeNewDecimal:
+		if tp.Flen == UnspecifiedLength {
+			return UnspecifiedLength
+		}
+		// The sign and the decimal point.
+		return tp.Flen + 2
+	case tp.Tp == mysql.TypeYear:
+		return 4
+	case tp.Tp == mysql.TypeDate:
+		return 10
+	case tp.Tp == mysql.TypeDatetime || tp.Tp == mysql.TypeTimestamp:
+		return 19 + fspLength(tp.Decimal)
+	case tp.Tp == mysql.TypeDuration:
+		return 10 + fspLength(tp.Decimal)
+	}
+	return UnspecifiedLength
+}
+
+func fspLength(fsp int) int {
+	if fsp <= 0 {
+		return 0
+	}
+	return fsp + 1
+}
+
 const (
 	fieldTypeTearFrom = int(mysql.TypeBit) + 1
 	fieldTypeTearTo   = int(mysql.TypeNewDecimal) - 1
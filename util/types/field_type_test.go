@@ -135,3 +135,63 @@ func (s *testFieldTypeSuite) TestDefaultTypeForValue(c *C) {
 		c.Assert(ft.Tp, Equals, ca.tp, Commentf("%v %v", ft, ca))
 	}
 }
+
+func (s *testFieldTypeSuite) TestAggFieldType(c *C) {
+	defer testleak.AfterTest(c)()
+	newType := func(tp byte, flen, decimal int, flag uint, chs string) *FieldType {
+		ft := &FieldType{Tp: tp, Flen: flen, Decimal: decimal, Flag: flag, Charset: chs}
+		if chs != "" {
+			ft.Collate = chs + "_bin"
+		}
+		return ft
+	}
+	tiny := newType(mysql.TypeTiny, 4, UnspecifiedLength, 0, "")
+	uTiny := newType(mysql.TypeTiny, 3, UnspecifiedLength, mysql.UnsignedFlag, "")
+	long := newType(mysql.TypeLong, 11, UnspecifiedLength, 0, "")
+	uLong := newType(mysql.TypeLong, 10, UnspecifiedLength, mysql.UnsignedFlag|mysql.NotNullFlag, "")
+	uBigint := newType(mysql.TypeLonglong, 20, UnspecifiedLength, mysql.UnsignedFlag, "")
+	dec52 := newType(mysql.TypeNewDecimal, 5, 2, 0, "")
+	dec101 := newType(mysql.TypeNewDecimal, 10, 1, 0, "")
+	double := newType(mysql.TypeDouble, UnspecifiedLength, UnspecifiedLength, 0, "")
+	varchar3 := newType(mysql.TypeVarchar, 3, UnspecifiedLength, 0, "utf8")
+	varString5 := newType(mysql.TypeVarString, 5, UnspecifiedLength, 0, "utf8")
+	latin := newType(mysql.TypeVarchar, 8, UnspecifiedLength, 0, "latin1")
+	binary := newType(mysql.TypeVarchar, 6, UnspecifiedLength, mysql.BinaryFlag, "binary")
+	text := newType(mysql.TypeBlob, UnspecifiedLength, UnspecifiedLength, 0, "utf8")
+	datetime3 := newType(mysql.TypeDatetime, 23, 3, 0, "")
+	date := newType(mysql.TypeDate, 10, 0, 0, "")
+	null := newType(mysql.TypeNull, UnspecifiedLength, UnspecifiedLength, 0, "")
+
+	cases := []struct {
+		tps    []*FieldType
+		result string
+	}{
+		{[]*FieldType{tiny, long}, "int(11)"},
+		{[]*FieldType{uLong, uTiny}, "int(10) UNSIGNED"},
+		// The mixed signed and unsigned integers are widened.
+		{[]*FieldType{uTiny, tiny}, "smallint(4)"},
+		{[]*FieldType{uLong, tiny}, "bigint(10)"},
+		{[]*FieldType{tiny, uBigint}, "decimal(20,0)"},
+		{[]*FieldType{dec52, dec101}, "decimal(11,2)"},
+		{[]*FieldType{long, dec52}, "decimal(13,2)"},
+		{[]*FieldType{dec52, double}, "double"},
+		{[]*FieldType{varchar3, long}, "varchar(11) CHARACTER SET utf8 COLLATE utf8_bin"},
+		{[]*FieldType{varchar3, varString5}, "varchar(5) CHARACTER SET utf8 COLLATE utf8_bin"},
+		{[]*FieldType{varchar3, latin}, "varchar(8) CHARACTER SET utf8 COLLATE utf8_general_ci"},
+		{[]*FieldType{varchar3, binary, latin}, "varbinary(8) BINARY"},
+		{[]*FieldType{varchar3, double}, "varchar CHARACTER SET utf8 COLLATE utf8_bin"},
+		{[]*FieldType{varchar3, text}, "text CHARACTER SET utf8 COLLATE utf8_bin"},
+		{[]*FieldType{datetime3, date}, "datetime(3)"},
+		{[]*FieldType{date, varchar3}, "varchar(10) CHARACTER SET utf8 COLLATE utf8_bin"},
+		// The NULLs are converted to the type of the others.
+		{[]*FieldType{null, varchar3}, "varchar(3) CHARACTER SET utf8 COLLATE utf8_bin"},
+		{[]*FieldType{null, uLong}, "int(10) UNSIGNED"},
+		{[]*FieldType{null, null}, "null"},
+	}
+	for _, ca := range cases {
+		ft := AggFieldType(ca.tps)
+		c.Assert(ft.String(), Equals, ca.result, Commentf("for %v", ca.tps))
+	}
+	c.Assert(mysql.HasNotNullFlag(AggFieldType([]*FieldType{uLong, uLong}).Flag), IsTrue)
+	c.Assert(mysql.HasNotNullFlag(AggFieldType([]*FieldType{uLong, long}).Flag), IsFalse)
+}
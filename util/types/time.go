@@ -17,6 +17,7 @@ import (
 	"bytes"
 	"fmt"
 	"math"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -1117,20 +1118,44 @@ func ExtractTimeNum(unit string, t Time) (int64, error) {
 
 func extractSingleTimeValue(unit string, format string) (int64, int64, int64, time.Duration, error) {
 	iv, err := strconv.ParseInt(format, 10, 64)
+	if strings.ToUpper(unit) == "SECOND" && (err != nil || strings.Contains(format, ".")) {
+		// SECOND interval can have a fractional part.
+		fv, err := strconv.ParseFloat(format, 64)
+		if err != nil {
+			return 0, 0, 0, 0, errors.Errorf("invalid time format - %s", format)
+		}
+		days := math.Trunc(fv / secondsPerDay)
+		if math.Abs(days) > maxIntervalDays {
+			// The result is out of range anyway, don't let the days overflow.
+			return 0, 0, int64(math.Copysign(maxIntervalDays+1, days)), 0, nil
+		}
+		micros := math.Floor((fv-days*secondsPerDay)*1e6 + 0.5)
+		return 0, 0, int64(days), time.Duration(micros) * time.Microsecond, nil
+	}
+	if numErr, ok := err.(*strconv.NumError); ok && numErr.Err == strconv.ErrRange {
+		// The result is out of range anyway.
+		if strings.HasPrefix(strings.TrimSpace(format), "-") {
+			return 0, 0, -maxIntervalDays - 1, 0, nil
+		}
+		return 0, 0, maxIntervalDays + 1, 0, nil
+	}
 	if err != nil {
 		return 0, 0, 0, 0, errors.Errorf("invalid time format - %s", format)
 	}
 
-	v := time.Duration(iv)
 	switch strings.ToUpper(unit) {
 	case "MICROSECOND":
-		return 0, 0, 0, v * time.Microsecond, nil
+		days, d := splitDays(iv, time.Microsecond)
+		return 0, 0, days, d, nil
 	case "SECOND":
-		return 0, 0, 0, v * time.Second, nil
+		days, d := splitDays(iv, time.Second)
+		return 0, 0, days, d, nil
 	case "MINUTE":
-		return 0, 0, 0, v * time.Minute, nil
+		days, d := splitDays(iv, time.Minute)
+		return 0, 0, days, d, nil
 	case "HOUR":
-		return 0, 0, 0, v * time.Hour, nil
+		days, d := splitDays(iv, time.Hour)
+		return 0, 0, days, d, nil
 	case "DAY":
 		return 0, 0, iv, 0, nil
 	case "WEEK":
@@ -1146,266 +1171,279 @@ func extractSingleTimeValue(unit string, format string) (int64, int64, int64, ti
 	return 0, 0, 0, 0, errors.Errorf("invalid singel timeunit - %s", unit)
 }
 
-// Format is `SS.FFFFFF`.
-func extractSecondMicrosecond(format string) (int64, int64, int64, time.Duration, error) {
-	fields := strings.Split(format, ".")
-	if len(fields) != 2 {
-		return 0, 0, 0, 0, errors.Errorf("invalid time format - %s", format)
-	}
-
-	seconds, err := strconv.ParseInt(fields[0], 10, 64)
-	if err != nil {
-		return 0, 0, 0, 0, errors.Errorf("invalid time format - %s", format)
-	}
-
-	microseconds, err := strconv.ParseInt(alignFrac(fields[1], MaxFsp), 10, 64)
-	if err != nil {
-		return 0, 0, 0, 0, errors.Errorf("invalid time format - %s", format)
-	}
+const (
+	secondsPerDay = 24 * 60 * 60
+	// maxIntervalDays is more than the days between the minimum and the maximum date, a larger interval always
+	// makes the result out of range.
+	maxIntervalDays = 3660000
+)
 
-	return 0, 0, 0, time.Duration(seconds)*time.Second + time.Duration(microseconds)*time.Microsecond, nil
+// splitDays splits v units of time into the days and the rest less than a day, so a large interval of a clock unit
+// doesn't overflow time.Duration.
+func splitDays(v int64, unit time.Duration) (int64, time.Duration) {
+	perDay := int64(24 * time.Hour / unit)
+	return v / perDay, time.Duration(v%perDay) * unit
 }
 
-// Format is `MM:SS.FFFFFF`.
-func extractMinuteMicrosecond(format string) (int64, int64, int64, time.Duration, error) {
-	fields := strings.Split(format, ":")
-	if len(fields) != 2 {
-		return 0, 0, 0, 0, errors.Errorf("invalid time format - %s", format)
-	}
-
-	minutes, err := strconv.ParseInt(fields[0], 10, 64)
-	if err != nil {
-		return 0, 0, 0, 0, errors.Errorf("invalid time format - %s", format)
-	}
-
-	_, _, _, value, err := extractSecondMicrosecond(fields[1])
-	if err != nil {
-		return 0, 0, 0, 0, errors.Trace(err)
-	}
-
-	return 0, 0, 0, time.Duration(minutes)*time.Minute + value, nil
+// intervalUnitFields lists the fields of the compound interval units, from the leftmost to the rightmost.
+// See https://dev.mysql.com/doc/refman/5.7/en/date-and-time-functions.html#function_date-add
+var intervalUnitFields = map[string][]string{
+	"SECOND_MICROSECOND": {"SECOND", "MICROSECOND"},
+	"MINUTE_MICROSECOND": {"MINUTE", "SECOND", "MICROSECOND"},
+	"MINUTE_SECOND":      {"MINUTE", "SECOND"},
+	"HOUR_MICROSECOND":   {"HOUR", "MINUTE", "SECOND", "MICROSECOND"},
+	"HOUR_SECOND":        {"HOUR", "MINUTE", "SECOND"},
+	"HOUR_MINUTE":        {"HOUR", "MINUTE"},
+	"DAY_MICROSECOND":    {"DAY", "HOUR", "MINUTE", "SECOND", "MICROSECOND"},
+	"DAY_SECOND":         {"DAY", "HOUR", "MINUTE", "SECOND"},
+	"DAY_MINUTE":         {"DAY", "HOUR", "MINUTE"},
+	"DAY_HOUR":           {"DAY", "HOUR"},
+	"YEAR_MONTH":         {"YEAR", "MONTH"},
 }
 
-// Format is `MM:SS`.
-func extractMinuteSecond(format string) (int64, int64, int64, time.Duration, error) {
-	fields := strings.Split(format, ":")
-	if len(fields) != 2 {
-		return 0, 0, 0, 0, errors.Errorf("invalid time format - %s", format)
-	}
-
-	minutes, err := strconv.ParseInt(fields[0], 10, 64)
-	if err != nil {
-		return 0, 0, 0, 0, errors.Errorf("invalid time format - %s", format)
-	}
-
-	seconds, err := strconv.ParseInt(fields[1], 10, 64)
-	if err != nil {
-		return 0, 0, 0, 0, errors.Errorf("invalid time format - %s", format)
-	}
-
-	return 0, 0, 0, time.Duration(minutes)*time.Minute + time.Duration(seconds)*time.Second, nil
+var clockUnitDurations = map[string]time.Duration{
+	"HOUR":        time.Hour,
+	"MINUTE":      time.Minute,
+	"SECOND":      time.Second,
+	"MICROSECOND": time.Microsecond,
 }
 
-// Format is `HH:MM:SS.FFFFFF`.
-func extractHourMicrosecond(format string) (int64, int64, int64, time.Duration, error) {
-	fields := strings.Split(format, ":")
-	if len(fields) != 3 {
-		return 0, 0, 0, 0, errors.Errorf("invalid time format - %s", format)
-	}
-
-	hours, err := strconv.ParseInt(fields[0], 10, 64)
-	if err != nil {
-		return 0, 0, 0, 0, errors.Errorf("invalid time format - %s", format)
-	}
-
-	minutes, err := strconv.ParseInt(fields[1], 10, 64)
-	if err != nil {
-		return 0, 0, 0, 0, errors.Errorf("invalid time format - %s", format)
+// extractCompoundTimeValue extracts time value from a compound unit like `DAY_MICROSECOND`.
+// Like MySQL, any non-digit characters can be used as the delimiter, a leading '-' makes the whole interval
+// negative, and if there are fewer values than the fields, the leftmost fields are assumed to be omitted.
+// The MICROSECOND field is the fraction of a second, so `1.1` SECOND_MICROSECOND means 1.1 seconds.
+func extractCompoundTimeValue(unit string, format string) (int64, int64, int64, time.Duration, error) {
+	fields := intervalUnitFields[unit]
+	s := strings.TrimSpace(format)
+	negative := strings.HasPrefix(s, "-")
+	if negative {
+		s = s[1:]
 	}
-
-	_, _, _, value, err := extractSecondMicrosecond(fields[2])
-	if err != nil {
-		return 0, 0, 0, 0, errors.Trace(err)
-	}
-
-	return 0, 0, 0, time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute + value, nil
-}
-
-// Format is `HH:MM:SS`.
-func extractHourSecond(format string) (int64, int64, int64, time.Duration, error) {
-	fields := strings.Split(format, ":")
-	if len(fields) != 3 {
-		return 0, 0, 0, 0, errors.Errorf("invalid time format - %s", format)
+	var (
+		values    []int64
+		lastWidth int
+	)
+	for i := 0; i < len(s); {
+		if !unicode.IsDigit(rune(s[i])) {
+			i++
+			continue
+		}
+		j := i
+		for j < len(s) && unicode.IsDigit(rune(s[j])) {
+			j++
+		}
+		v, err := strconv.ParseInt(s[i:j], 10, 64)
+		if err != nil {
+			return 0, 0, 0, 0, errors.Errorf("invalid time format - %s", format)
+		}
+		values = append(values, v)
+		lastWidth = j - i
+		i = j
 	}
-
-	hours, err := strconv.ParseInt(fields[0], 10, 64)
-	if err != nil {
+	if len(values) == 0 || len(values) > len(fields) {
 		return 0, 0, 0, 0, errors.Errorf("invalid time format - %s", format)
 	}
-
-	minutes, err := strconv.ParseInt(fields[1], 10, 64)
-	if err != nil {
-		return 0, 0, 0, 0, errors.Errorf("invalid time format - %s", format)
+	if fields[len(fields)-1] == "MICROSECOND" && lastWidth < MaxFsp {
+		values[len(values)-1] *= int64(math.Pow10(MaxFsp - lastWidth))
 	}
-
-	seconds, err := strconv.ParseInt(fields[2], 10, 64)
-	if err != nil {
-		return 0, 0, 0, 0, errors.Errorf("invalid time format - %s", format)
+	// Align the values to the rightmost fields.
+	fields = fields[len(fields)-len(values):]
+	var (
+		years, months, days int64
+		duration            time.Duration
+	)
+	for i, field := range fields {
+		v := values[i]
+		if negative {
+			v = -v
+		}
+		switch field {
+		case "YEAR":
+			years = v
+		case "MONTH":
+			months = v
+		case "DAY":
+			days += v
+		case "HOUR", "MINUTE", "SECOND", "MICROSECOND":
+			d, rest := splitDays(v, clockUnitDurations[field])
+			days += d
+			duration += rest
+		}
 	}
-
-	return 0, 0, 0, time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute + time.Duration(seconds)*time.Second, nil
+	return years, months, days, duration, nil
 }
 
-// Format is `HH:MM`.
-func extractHourMinute(format string) (int64, int64, int64, time.Duration, error) {
-	fields := strings.Split(format, ":")
-	if len(fields) != 2 {
-		return 0, 0, 0, 0, errors.Errorf("invalid time format - %s", format)
+// ExtractTimeValue extracts time value from time unit and format.
+func ExtractTimeValue(unit string, format string) (int64, int64, int64, time.Duration, error) {
+	unit = strings.ToUpper(unit)
+	switch unit {
+	case "MICROSECOND", "SECOND", "MINUTE", "HOUR", "DAY", "WEEK", "MONTH", "QUARTER", "YEAR":
+		return extractSingleTimeValue(unit, format)
 	}
-
-	hours, err := strconv.ParseInt(fields[0], 10, 64)
-	if err != nil {
-		return 0, 0, 0, 0, errors.Errorf("invalid time format - %s", format)
+	if _, ok := intervalUnitFields[unit]; ok {
+		return extractCompoundTimeValue(unit, format)
 	}
-
-	minutes, err := strconv.ParseInt(fields[1], 10, 64)
-	if err != nil {
-		return 0, 0, 0, 0, errors.Errorf("invalid time format - %s", format)
-	}
-
-	return 0, 0, 0, time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute, nil
+	return 0, 0, 0, 0, errors.Errorf("invalid singel timeunit - %s", unit)
 }
 
-// Format is `DD HH:MM:SS.FFFFFF`.
-func extractDayMicrosecond(format string) (int64, int64, int64, time.Duration, error) {
-	fields := strings.Split(format, " ")
-	if len(fields) != 2 {
-		return 0, 0, 0, 0, errors.Errorf("invalid time format - %s", format)
+// DateArith adds the interval of the unit to the date like the DATE_ADD function of MySQL, or subtracts it if sub
+// is true. The result is NULL if the date or the interval is NULL, or if the result is out of the range from
+// '0000-01-01' to '9999-12-31'. When the interval has years or months, the day is clamped to the last day of the
+// result month, e.g. '2017-01-31' + INTERVAL 1 MONTH is '2017-02-28'.
+// See https://dev.mysql.com/doc/refman/5.7/en/date-and-time-functions.html#function_date-add
+func DateArith(date Datum, interval Datum, unit string, sub bool) (d Datum, err error) {
+	if date.IsNull() || interval.IsNull() {
+		return d, nil
 	}
-
-	days, err := strconv.ParseInt(fields[0], 10, 64)
+	iv, err := ParseDateInterval(interval, unit)
 	if err != nil {
-		return 0, 0, 0, 0, errors.Errorf("invalid time format - %s", format)
-	}
-
-	_, _, _, value, err := extractHourMicrosecond(fields[1])
-	if err != nil {
-		return 0, 0, 0, 0, errors.Errorf("invalid time format - %s", format)
+		return d, errors.Trace(err)
 	}
-
-	return 0, 0, days, value, nil
+	return DateArithInterval(date, iv, unit, sub)
 }
 
-// Format is `DD HH:MM:SS`.
-func extractDaySecond(format string) (int64, int64, int64, time.Duration, error) {
-	fields := strings.Split(format, " ")
-	if len(fields) != 2 {
-		return 0, 0, 0, 0, errors.Errorf("invalid time format - %s", format)
-	}
-
-	days, err := strconv.ParseInt(fields[0], 10, 64)
-	if err != nil {
-		return 0, 0, 0, 0, errors.Errorf("invalid time format - %s", format)
-	}
+// DateInterval is an interval of the date arithmetic parsed by ParseDateInterval.
+type DateInterval struct {
+	Years    int64
+	Months   int64
+	Days     int64
+	Duration time.Duration
+}
 
-	_, _, _, value, err := extractHourSecond(fields[1])
-	if err != nil {
-		return 0, 0, 0, 0, errors.Errorf("invalid time format - %s", format)
+// ParseDateInterval parses the non-NULL interval of the unit for the date arithmetic, so a constant interval can be
+// parsed once for all the dates.
+func ParseDateInterval(interval Datum, unit string) (iv DateInterval, err error) {
+	var format string
+	if strings.ToUpper(unit) == "DAY" {
+		day, err1 := parseDayInterval(interval)
+		if err1 != nil {
+			return iv, errors.Errorf("invalid day interval %v", interval.GetValue())
+		}
+		format = strconv.FormatInt(day, 10)
+	} else if interval.Kind() == KindString {
+		format = interval.GetString()
+	} else if keepIntervalFraction(unit) {
+		// e.g. "INTERVAL 1.5 SECOND" is 1.5 seconds and "INTERVAL 1.5 HOUR_MINUTE" is 1 hour and 5 minutes.
+		format, err = interval.ToString()
+		if err != nil {
+			return iv, errors.Trace(err)
+		}
+	} else {
+		i, err1 := interval.ToInt64()
+		if err1 != nil {
+			return iv, errors.Trace(err1)
+		}
+		format = strconv.FormatInt(i, 10)
 	}
-
-	return 0, 0, days, value, nil
+	iv.Years, iv.Months, iv.Days, iv.Duration, err = ExtractTimeValue(unit, format)
+	return iv, errors.Trace(err)
 }
 
-// Format is `DD HH:MM`.
-func extractDayMinute(format string) (int64, int64, int64, time.Duration, error) {
-	fields := strings.Split(format, " ")
-	if len(fields) != 2 {
-		return 0, 0, 0, 0, errors.Errorf("invalid time format - %s", format)
+// DateArithInterval is like DateArith, but the interval is parsed by ParseDateInterval.
+func DateArithInterval(date Datum, iv DateInterval, unit string, sub bool) (d Datum, err error) {
+	if date.IsNull() {
+		return d, nil
 	}
-
-	days, err := strconv.ParseInt(fields[0], 10, 64)
-	if err != nil {
-		return 0, 0, 0, 0, errors.Errorf("invalid time format - %s", format)
+	tp := mysql.TypeDate
+	switch date.Kind() {
+	case KindMysqlTime:
+		x := date.GetMysqlTime()
+		if x.Type == mysql.TypeDatetime || x.Type == mysql.TypeTimestamp {
+			tp = mysql.TypeDatetime
+		}
+	case KindString:
+		if !IsDateFormat(date.GetString()) {
+			tp = mysql.TypeDatetime
+		}
+	case KindInt64:
+		if t, err1 := ParseTimeFromInt64(date.GetInt64()); err1 == nil {
+			if t.Type == mysql.TypeDatetime || t.Type == mysql.TypeTimestamp {
+				tp = mysql.TypeDatetime
+			}
+		}
 	}
-
-	_, _, _, value, err := extractHourMinute(fields[1])
-	if err != nil {
-		return 0, 0, 0, 0, errors.Errorf("invalid time format - %s", format)
+	if IsClockUnit(unit) {
+		tp = mysql.TypeDatetime
 	}
-
-	return 0, 0, days, value, nil
-}
-
-// Format is `DD HH`.
-func extractDayHour(format string) (int64, int64, int64, time.Duration, error) {
-	fields := strings.Split(format, " ")
-	if len(fields) != 2 {
-		return 0, 0, 0, 0, errors.Errorf("invalid time format - %s", format)
+	ft := NewFieldType(tp)
+	ft.Decimal = MaxFsp
+	value, err := date.ConvertTo(ft)
+	if err != nil || value.Kind() != KindMysqlTime {
+		return d, errors.Errorf("invalid date %v for date arithmetic", date.GetValue())
 	}
+	result := value.GetMysqlTime()
 
-	days, err := strconv.ParseInt(fields[0], 10, 64)
-	if err != nil {
-		return 0, 0, 0, 0, errors.Errorf("invalid time format - %s", format)
+	years, months, days, duration := iv.Years, iv.Months, iv.Days, iv.Duration
+	if sub {
+		years, months, days, duration = -years, -months, -days, -duration
 	}
-
-	hours, err := strconv.ParseInt(fields[1], 10, 64)
-	if err != nil {
-		return 0, 0, 0, 0, errors.Errorf("invalid time format - %s", format)
+	t, ok := addDateInterval(result.Time, years, months, days, duration)
+	if !ok {
+		return d, nil
 	}
-
-	return 0, 0, days, time.Duration(hours) * time.Hour, nil
+	result.Time = t
+	if date.Kind() == KindMysqlTime {
+		// The result keeps the fsp of the date, unless the interval has fractional seconds.
+		result.Fsp = date.GetMysqlTime().Fsp
+		if duration%time.Second != 0 {
+			result.Fsp = MaxFsp
+		}
+	} else if result.Time.Nanosecond() == 0 {
+		result.Fsp = 0
+	}
+	d.SetMysqlTime(result)
+	return d, nil
 }
 
-// Format is `YYYY-MM`.
-func extractYearMonth(format string) (int64, int64, int64, time.Duration, error) {
-	fields := strings.Split(format, "-")
-	if len(fields) != 2 {
-		return 0, 0, 0, 0, errors.Errorf("invalid time format - %s", format)
+// addDateInterval adds the interval to t, it returns false if the result is out of range.
+func addDateInterval(t time.Time, years, months, days int64, duration time.Duration) (time.Time, bool) {
+	if years < -10000 || years > 10000 || months < -120000 || months > 120000 ||
+		days < -maxIntervalDays || days > maxIntervalDays {
+		return t, false
 	}
-
-	years, err := strconv.ParseInt(fields[0], 10, 64)
-	if err != nil {
-		return 0, 0, 0, 0, errors.Errorf("invalid time format - %s", format)
+	if years != 0 || months != 0 {
+		totalMonths := int64(t.Year())*12 + int64(t.Month()) - 1 + years*12 + months
+		if totalMonths < 0 {
+			return t, false
+		}
+		year, month := int(totalMonths/12), time.Month(totalMonths%12+1)
+		day := t.Day()
+		// The day of the next month's 0th day is the last day of the month.
+		if lastDay := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day(); day > lastDay {
+			day = lastDay
+		}
+		t = time.Date(year, month, day, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
 	}
-
-	months, err := strconv.ParseInt(fields[1], 10, 64)
-	if err != nil {
-		return 0, 0, 0, 0, errors.Errorf("invalid time format - %s", format)
+	t = t.AddDate(0, 0, int(days)).Add(duration)
+	if t.Year() < 0 || t.Year() > 9999 {
+		return t, false
 	}
+	return t, true
+}
 
-	return years, months, 0, 0, nil
+// keepIntervalFraction returns true if the fractional part of a numeric interval is meaningful for the unit,
+// in which case the interval should be converted to string instead of int.
+func keepIntervalFraction(unit string) bool {
+	unit = strings.ToUpper(unit)
+	return unit == "SECOND" || strings.Contains(unit, "_")
 }
 
-// ExtractTimeValue extracts time value from time unit and format.
-func ExtractTimeValue(unit string, format string) (int64, int64, int64, time.Duration, error) {
-	switch strings.ToUpper(unit) {
-	case "MICROSECOND", "SECOND", "MINUTE", "HOUR", "DAY", "WEEK", "MONTH", "QUARTER", "YEAR":
-		return extractSingleTimeValue(unit, format)
-	case "SECOND_MICROSECOND":
-		return extractSecondMicrosecond(format)
-	case "MINUTE_MICROSECOND":
-		return extractMinuteMicrosecond(format)
-	case "MINUTE_SECOND":
-		return extractMinuteSecond(format)
-	case "HOUR_MICROSECOND":
-		return extractHourMicrosecond(format)
-	case "HOUR_SECOND":
-		return extractHourSecond(format)
-	case "HOUR_MINUTE":
-		return extractHourMinute(format)
-	case "DAY_MICROSECOND":
-		return extractDayMicrosecond(format)
-	case "DAY_SECOND":
-		return extractDaySecond(format)
-	case "DAY_MINUTE":
-		return extractDayMinute(format)
-	case "DAY_HOUR":
-		return extractDayHour(format)
-	case "YEAR_MONTH":
-		return extractYearMonth(format)
-	default:
-		return 0, 0, 0, 0, errors.Errorf("invalid singel timeunit - %s", unit)
+// dayIntervalRegexp matches the number in a DAY interval string, the other characters are ignored like MySQL.
+var dayIntervalRegexp = regexp.MustCompile(`-?\d+`)
+
+func parseDayInterval(value Datum) (int64, error) {
+	switch value.Kind() {
+	case KindString:
+		vs := value.GetString()
+		s := strings.ToLower(vs)
+		if s == "false" {
+			return 0, nil
+		} else if s == "true" {
+			return 1, nil
+		}
+		value.SetString(dayIntervalRegexp.FindString(vs))
 	}
+	return value.ToInt64()
 }
 
 // IsClockUnit returns true when unit is interval unit with hour, minute or second.